@@ -0,0 +1,412 @@
+// Package client provides a facade to build all clients of 0g storage network from a single Config, so that
+// applications need not wire the fullnode, storage nodes, indexer, KV node and transfer settings by hand, e.g.
+//
+//	c, err := client.New(client.Config{
+//		URL:     "https://evmrpc-testnet.0g.ai",
+//		Key:     os.Getenv("PRIVATE_KEY"),
+//		Indexer: "https://indexer-storage-testnet-turbo.0g.ai",
+//		Profile: transfer.ProfileNameReliable,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//
+//	uploader, err := c.Uploader(ctx)
+//
+// The granular constructors of each package remain available for advanced usage.
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/common/rpc"
+	"github.com/0glabs/0g-storage-client/indexer"
+	"github.com/0glabs/0g-storage-client/kv"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/crypto"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/openweb3/web3go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Version returns the version of client library, which is set by ldflags at build time, or the module version
+// resolved from build info, see common.Version.
+func Version() string {
+	return zg_common.Version()
+}
+
+// Config is the config to build all clients, see New. Only URL along with Key, and either Indexer or Nodes are
+// required to upload, while Key and URL could be omitted to download only.
+type Config struct {
+	URL string // fullnode RPC URL to send transactions, e.g. https://evmrpc-testnet.0g.ai
+	Key string // private key in hex to sign transactions, required along with URL
+
+	Indexer string   // indexer URL to select storage nodes, e.g. https://indexer-storage-testnet-turbo.0g.ai
+	Nodes   []string // storage node URLs if indexer not specified, e.g. http://127.0.0.1:5678
+	KvNode  string   // KV node URL to read KV streams, optional
+
+	// Name of predefined transfer profile applied to uploader and downloader, see transfer.LookupProfile. Default
+	// settings of each package are applied if empty.
+	Profile string
+
+	ProviderOption providers.Option    // RPC option of all clients, e.g. timeout and retries
+	Proxy          *rpc.ProxyOption    // proxy option to connect to all RPC servers, rpc.DefaultProxy if nil
+	LogOption      zg_common.LogOption // log option of all clients, logs are discarded if Logger not specified
+}
+
+// WithProfile sets the name of predefined transfer profile.
+func (config Config) WithProfile(name string) Config {
+	config.Profile = name
+	return config
+}
+
+// WithLogger sets the logger of all clients.
+func (config Config) WithLogger(logger *logrus.Logger) Config {
+	config.LogOption.Logger = logger
+	return config
+}
+
+// WithProxy sets the proxy option to connect to all RPC servers.
+func (config Config) WithProxy(proxy rpc.ProxyOption) Config {
+	config.Proxy = &proxy
+	return config
+}
+
+func (config Config) proxy() rpc.ProxyOption {
+	if config.Proxy == nil {
+		return rpc.DefaultProxy
+	}
+
+	return *config.Proxy
+}
+
+// Validate checks the config, and returns an error that names the invalid field along with an example if any.
+func (config Config) Validate() error {
+	if len(config.URL) > 0 {
+		if err := validateURL("URL", config.URL, "https://evmrpc-testnet.0g.ai"); err != nil {
+			return err
+		}
+
+		if len(config.Key) == 0 {
+			return zg_common.NewOptionError("Key", "should be specified along with URL to sign transactions, "+
+				"e.g. a private key of 64 hex characters with optional 0x prefix")
+		}
+	} else if len(config.Key) > 0 {
+		return zg_common.NewOptionError("URL", "should be specified along with Key to send transactions, "+
+			"e.g. https://evmrpc-testnet.0g.ai")
+	}
+
+	if len(config.Key) > 0 {
+		if _, err := crypto.HexToECDSA(strings.TrimPrefix(config.Key, "0x")); err != nil {
+			return zg_common.NewOptionError("Key", "should be a private key of 64 hex characters with optional "+
+				"0x prefix, e.g. 0x%v, got invalid key: %v", strings.Repeat("ab", 32), err)
+		}
+	}
+
+	switch {
+	case len(config.Indexer) > 0 && len(config.Nodes) > 0:
+		return zg_common.NewOptionError("Nodes", "should not be specified along with Indexer, "+
+			"e.g. either Indexer: \"https://indexer-storage-testnet-turbo.0g.ai\" or Nodes: [\"http://127.0.0.1:5678\"]")
+	case len(config.Indexer) > 0:
+		if err := validateURL("Indexer", config.Indexer, "https://indexer-storage-testnet-turbo.0g.ai"); err != nil {
+			return err
+		}
+	case len(config.Nodes) > 0:
+		for i, nodeURL := range config.Nodes {
+			if err := validateURL(fmt.Sprintf("Nodes[%v]", i), nodeURL, "http://127.0.0.1:5678"); err != nil {
+				return err
+			}
+		}
+	default:
+		return zg_common.NewOptionError("Indexer", "either Indexer or Nodes should be specified, "+
+			"e.g. Indexer: \"https://indexer-storage-testnet-turbo.0g.ai\" or Nodes: [\"http://127.0.0.1:5678\"]")
+	}
+
+	if len(config.KvNode) > 0 {
+		if err := validateURL("KvNode", config.KvNode, "http://127.0.0.1:6789"); err != nil {
+			return err
+		}
+	}
+
+	if len(config.Profile) > 0 {
+		if _, err := transfer.LookupProfile(config.Profile); err != nil {
+			return zg_common.NewOptionError("Profile", "should be one of %v, e.g. %v, got %q",
+				transfer.ProfileNames(), transfer.ProfileNameReliable, config.Profile)
+		}
+	}
+
+	return nil
+}
+
+// validateURL checks that value is an absolute HTTP or WebSocket URL.
+func validateURL(field, value, example string) error {
+	u, err := url.Parse(value)
+	if err != nil || len(u.Host) == 0 {
+		return zg_common.NewOptionError(field, "should be an absolute URL, e.g. %v, got %q", example, value)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+		return nil
+	default:
+		return zg_common.NewOptionError(field, "should be an HTTP or WebSocket URL, e.g. %v, got %q", example, value)
+	}
+}
+
+// Client is the handle of all clients built from Config, which is safe for concurrent use. Clients are owned by
+// the handle, and closed along with Close.
+type Client struct {
+	config  Config
+	profile *transfer.Profile // nil if not specified
+
+	w3Client *web3go.Client    // nil if URL not specified
+	indexer  *indexer.Client   // nil if Nodes specified
+	nodes    []*node.ZgsClient // nil if Indexer specified
+	kvNode   *node.KvClient    // nil if KvNode not specified
+	kv       *kv.Client
+
+	downloader *transfer.Downloader // nil if Indexer specified
+
+	mu       sync.Mutex
+	selected []*node.ZgsClient // storage nodes selected from indexer to upload
+	uploader *transfer.Uploader
+	closed   bool
+}
+
+// New validates the config, and builds all clients with the same RPC, proxy and log options.
+func New(config Config) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	c := &Client{config: config}
+
+	if len(config.Profile) > 0 {
+		profile, _ := transfer.LookupProfile(config.Profile)
+		c.profile = &profile
+	}
+
+	if err := c.build(); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// build connects to all RPC servers specified in config.
+func (c *Client) build() (err error) {
+	config := &c.config
+
+	if len(config.URL) > 0 {
+		if c.w3Client, err = blockchain.NewWeb3WithProxy(config.URL, config.Key, config.proxy(), config.ProviderOption); err != nil {
+			return errors.WithMessagef(err, "Failed to connect to fullnode %v", config.URL)
+		}
+	}
+
+	if len(config.Indexer) > 0 {
+		c.indexer, err = indexer.NewClient(config.Indexer, indexer.IndexerClientOption{
+			ProviderOption: config.ProviderOption,
+			Proxy:          config.Proxy,
+			LogOption:      config.LogOption,
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "Failed to connect to indexer %v", config.Indexer)
+		}
+	}
+
+	for _, nodeURL := range config.Nodes {
+		client, err := node.NewZgsClientWithProxy(nodeURL, config.proxy(), config.ProviderOption)
+		if err != nil {
+			return errors.WithMessagef(err, "Failed to connect to storage node %v", nodeURL)
+		}
+		c.nodes = append(c.nodes, client)
+	}
+
+	if len(c.nodes) > 0 {
+		if c.downloader, err = transfer.NewDownloader(c.nodes, config.LogOption); err != nil {
+			return errors.WithMessage(err, "Failed to create downloader")
+		}
+
+		if c.profile != nil {
+			c.downloader.WithProfile(*c.profile)
+		}
+	}
+
+	if len(config.KvNode) > 0 {
+		if c.kvNode, err = node.NewKvClientWithProxy(config.KvNode, config.proxy(), config.ProviderOption); err != nil {
+			return errors.WithMessagef(err, "Failed to connect to KV node %v", config.KvNode)
+		}
+		c.kv = kv.NewClient(c.kvNode)
+	}
+
+	return nil
+}
+
+// Config returns the config that client built from.
+func (c *Client) Config() Config {
+	return c.config
+}
+
+// Web3 returns the fullnode client to send transactions, or nil if URL not specified.
+func (c *Client) Web3() *web3go.Client {
+	return c.w3Client
+}
+
+// Indexer returns the indexer client, or nil if storage nodes specified instead.
+func (c *Client) Indexer() *indexer.Client {
+	return c.indexer
+}
+
+// Kv returns the client to read KV streams, or nil if KvNode not specified.
+func (c *Client) Kv() *kv.Client {
+	return c.kv
+}
+
+// Downloader returns the downloader, which downloads from storage nodes located by indexer if specified.
+func (c *Client) Downloader() transfer.IDownloader {
+	if c.indexer != nil {
+		return c.indexer
+	}
+
+	return c.downloader
+}
+
+// storageNodes returns the storage nodes to upload, which are selected from indexer once if specified.
+func (c *Client) storageNodes(ctx context.Context) ([]*node.ZgsClient, error) {
+	if c.indexer == nil {
+		return c.nodes, nil
+	}
+
+	if c.selected != nil {
+		return c.selected, nil
+	}
+
+	replica := uint(1)
+	if c.profile != nil && c.profile.Upload.ExpectedReplica > 0 {
+		replica = c.profile.Upload.ExpectedReplica
+	}
+
+	selected, err := c.indexer.SelectNodes(ctx, 0, replica, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to select storage nodes from indexer")
+	}
+	c.selected = selected
+
+	return selected, nil
+}
+
+// Uploader returns the uploader, which is created on first use, along with storage nodes selected from indexer
+// if specified. The uploader is owned by client, and should not be closed by caller.
+func (c *Client) Uploader(ctx context.Context) (*transfer.Uploader, error) {
+	if c.w3Client == nil {
+		return nil, zg_common.NewOptionError("URL", "should be specified along with Key to upload, "+
+			"e.g. https://evmrpc-testnet.0g.ai")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, errors.New("client closed")
+	}
+
+	if c.uploader != nil {
+		return c.uploader, nil
+	}
+
+	clients, err := c.storageNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uploader, err := transfer.NewUploader(ctx, c.w3Client, clients, c.config.LogOption)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create uploader")
+	}
+
+	if c.profile != nil {
+		uploader.WithProfile(*c.profile)
+	}
+	c.uploader = uploader
+
+	return uploader, nil
+}
+
+// NewKvBatcher creates a batcher to write KV streams of the specified version to the storage nodes that uploader
+// uploads to.
+func (c *Client) NewKvBatcher(ctx context.Context, version uint64) (*kv.Batcher, error) {
+	if c.w3Client == nil {
+		return nil, zg_common.NewOptionError("URL", "should be specified along with Key to write KV streams, "+
+			"e.g. https://evmrpc-testnet.0g.ai")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, errors.New("client closed")
+	}
+
+	clients, err := c.storageNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return kv.NewBatcher(version, clients, c.w3Client, c.config.LogOption), nil
+}
+
+// Close closes all clients, and returns the first error if any. It is safe to close for multiple times.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	var errs []error
+	closeAll := func(closers ...interface{ Close() error }) {
+		for _, closer := range closers {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	// close uploader and downloader at first, which wait for in-flight transfers
+	if c.uploader != nil {
+		closeAll(c.uploader)
+	}
+	if c.downloader != nil {
+		closeAll(c.downloader)
+	}
+
+	for _, client := range c.selected {
+		client.Close()
+	}
+	for _, client := range c.nodes {
+		client.Close()
+	}
+	if c.kvNode != nil {
+		c.kvNode.Close()
+	}
+	if c.indexer != nil {
+		c.indexer.Close()
+	}
+	if c.w3Client != nil {
+		c.w3Client.Close()
+	}
+
+	return zg_common.FirstError(errs...)
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{
+		URL:   "http://127.0.0.1:8545",
+		Key:   testutil.PrivateKey,
+		Nodes: []string{"http://127.0.0.1:5678"},
+	}
+	assert.NoError(t, valid.Validate())
+
+	for _, c := range []struct {
+		field   string
+		example string
+		modify  func(config *Config)
+	}{
+		{"Key", "64 hex characters", func(config *Config) { config.Key = "" }},
+		{"Key", "0xabab", func(config *Config) { config.Key = "0x1234" }},
+		{"URL", "https://evmrpc-testnet.0g.ai", func(config *Config) { config.URL = "" }},
+		{"URL", "https://evmrpc-testnet.0g.ai", func(config *Config) { config.URL = "127.0.0.1:8545" }},
+		{"Indexer", "https://indexer-storage-testnet-turbo.0g.ai", func(config *Config) { config.Nodes = nil }},
+		{"Nodes", "either Indexer", func(config *Config) { config.Indexer = "http://127.0.0.1:12345" }},
+		{"Nodes[1]", "http://127.0.0.1:5678", func(config *Config) { config.Nodes = append(config.Nodes, "ftp://node") }},
+		{"KvNode", "http://127.0.0.1:6789", func(config *Config) { config.KvNode = "kv" }},
+		{"Profile", transfer.ProfileNameReliable, func(config *Config) { config.Profile = "unknown" }},
+	} {
+		config := valid
+		config.Nodes = append([]string(nil), valid.Nodes...)
+		c.modify(&config)
+
+		err := config.Validate()
+		var optionErr *zg_common.OptionError
+		if assert.True(t, errors.As(err, &optionErr), "%v", c.field) {
+			assert.Equal(t, c.field, optionErr.Field)
+			assert.Contains(t, optionErr.Reason, c.example)
+		}
+
+		_, err = New(config)
+		assert.Error(t, err)
+	}
+
+	// download only
+	assert.NoError(t, Config{Indexer: "https://indexer-storage-testnet-turbo.0g.ai"}.WithProfile(transfer.ProfileNameFast).Validate())
+}
+
+func TestClientWithNodes(t *testing.T) {
+	network := testutil.NewNetwork(t)
+
+	c, err := New(Config{
+		URL:     network.ChainURL,
+		Key:     testutil.PrivateKey,
+		Nodes:   network.NodeURLs,
+		KvNode:  network.KvURL,
+		Profile: transfer.ProfileNameCheap,
+	})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	assert.NotNil(t, c.Web3())
+	assert.Nil(t, c.Indexer())
+	assert.NotNil(t, c.Kv())
+
+	uploader, err := c.Uploader(context.Background())
+	assert.NoError(t, err)
+	same, err := c.Uploader(context.Background())
+	assert.NoError(t, err)
+	assert.Same(t, uploader, same)
+
+	data, err := core.NewDataInMemory([]byte("hello, facade"))
+	assert.NoError(t, err)
+	_, root, err := uploader.Upload(context.Background(), data)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "downloaded")
+	assert.NoError(t, c.Downloader().Download(context.Background(), root.Hex(), path, true))
+
+	batcher, err := c.NewKvBatcher(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.NotNil(t, batcher)
+
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+	_, err = c.Uploader(context.Background())
+	assert.Error(t, err)
+}
+
+func TestClientWithIndexer(t *testing.T) {
+	network := testutil.NewNetwork(t)
+
+	c, err := New(Config{
+		URL:     network.ChainURL,
+		Key:     testutil.PrivateKey,
+		Indexer: network.IndexerURL,
+	})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	assert.NotNil(t, c.Indexer())
+	assert.Nil(t, c.Kv())
+
+	uploader, err := c.Uploader(context.Background())
+	assert.NoError(t, err)
+
+	data, err := core.NewDataInMemory([]byte("hello, indexer"))
+	assert.NoError(t, err)
+	_, root, err := uploader.Upload(context.Background(), data)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "downloaded")
+	assert.NoError(t, c.Downloader().Download(context.Background(), root.Hex(), path, true))
+
+	// download only
+	readonly, err := New(Config{Indexer: network.IndexerURL})
+	assert.NoError(t, err)
+	defer readonly.Close()
+
+	_, err = readonly.Uploader(context.Background())
+	assert.Error(t, err)
+}
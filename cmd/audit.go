@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditArgs struct {
+		roots string
+		state string
+		nodes []string
+
+		samples  uint
+		interval time.Duration
+	}
+
+	auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Audit storage nodes periodically by sampling random segments of files and validating merkle proofs",
+		Run:   audit,
+	}
+)
+
+func init() {
+	auditCmd.Flags().StringVar(&auditArgs.roots, "roots", "", "File of roots to audit, one root per line")
+	auditCmd.MarkFlagRequired("roots")
+	auditCmd.Flags().StringVar(&auditArgs.state, "state", "", "File to persist audit state so that sampling coverage accumulates across runs, default \"<roots>.audit\"")
+
+	auditCmd.Flags().StringSliceVar(&auditArgs.nodes, "node", []string{}, "ZeroGStorage storage node URL to audit. Multiple nodes could be specified and separated by comma, e.g. url1,url2,url3")
+	auditCmd.MarkFlagRequired("node")
+
+	auditCmd.Flags().UintVar(&auditArgs.samples, "samples", 1, "Number of segments sampled per file on each node in a round")
+	auditCmd.Flags().DurationVar(&auditArgs.interval, "interval", 0, "Interval between audit rounds, e.g. 6h, 0 to audit only once")
+
+	rootCmd.AddCommand(auditCmd)
+}
+
+func audit(*cobra.Command, []string) {
+	ctx := context.Background()
+
+	roots, err := readRoots(ctx, auditArgs.roots)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to read roots")
+	}
+
+	clients := node.MustNewZgsClients(auditArgs.nodes, providerOption)
+	targets := make([]transfer.AuditTarget, len(clients))
+	for i, client := range clients {
+		defer client.Close()
+		targets[i] = client
+	}
+
+	state := auditArgs.state
+	if state == "" {
+		state = auditArgs.roots + ".audit"
+	}
+
+	auditor, err := transfer.NewAuditor(targets, roots, transfer.AuditOption{
+		Samples:   auditArgs.samples,
+		Interval:  auditArgs.interval,
+		StateFile: state,
+	}, zg_common.LogOption{Logger: logrus.StandardLogger()})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize auditor")
+	}
+
+	if err = auditor.Run(ctx); err != nil {
+		logrus.WithError(err).Fatal("Failed to audit storage nodes")
+	}
+}
+
+// readRoots reads all roots from the specified file, one hex encoded root per line.
+func readRoots(ctx context.Context, path string) ([]common.Hash, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var roots []common.Hash
+	source := transfer.RootsFromReader(file)
+	for {
+		root, err := source.Next(ctx)
+		if err == io.EOF {
+			return roots, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		roots = append(roots, root)
+	}
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/0glabs/0g-storage-client/transfer/bundle"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundlePrepareArgs struct {
+		bundle  string
+		files   []string
+		tags    string
+		extract bool
+	}
+
+	bundleCommitArgs struct {
+		uploadArgument
+
+		bundle string
+		force  bool
+	}
+
+	bundleCmd = &cobra.Command{
+		Use:   "bundle",
+		Short: "Prepare uploads offline into a portable bundle, and commit the bundle on a connected machine",
+	}
+
+	bundlePrepareCmd = &cobra.Command{
+		Use:   "prepare",
+		Short: "Prepare files into a bundle without network access",
+		Run:   prepareBundle,
+	}
+
+	bundleCommitCmd = &cobra.Command{
+		Use:   "commit",
+		Short: "Submit and upload files prepared in a bundle, and record outcomes into the bundle",
+		Run:   commitBundle,
+	}
+)
+
+func init() {
+	bundlePrepareCmd.Flags().StringVar(&bundlePrepareArgs.bundle, "bundle", "", "Bundle directory to create, which must be empty if exists")
+	bundlePrepareCmd.MarkFlagRequired("bundle")
+	bundlePrepareCmd.Flags().StringSliceVar(&bundlePrepareArgs.files, "file", []string{}, "Files to prepare, separated by comma")
+	bundlePrepareCmd.MarkFlagRequired("file")
+	bundlePrepareCmd.Flags().StringVar(&bundlePrepareArgs.tags, "tags", "0x", "Tags of files")
+	bundlePrepareCmd.Flags().BoolVar(&bundlePrepareArgs.extract, "extract", false, "Copy file data into bundle, so that source files are not required to commit")
+
+	bundleCommitCmd.Flags().StringVar(&bundleCommitArgs.bundle, "bundle", "", "Bundle directory to commit")
+	bundleCommitCmd.MarkFlagRequired("bundle")
+	bundleCommitCmd.Flags().BoolVar(&bundleCommitArgs.force, "force", false, "Commit files again even if committed successfully before")
+
+	bundleCommitCmd.Flags().StringSliceVar(&bundleCommitArgs.node, "node", []string{}, "ZeroGStorage storage node URL")
+	bundleCommitCmd.Flags().StringVar(&bundleCommitArgs.indexer, "indexer", "", "ZeroGStorage indexer URL")
+	bundleCommitCmd.MarkFlagsOneRequired("indexer", "node")
+	bundleCommitCmd.MarkFlagsMutuallyExclusive("indexer", "node")
+
+	bundleCommitCmd.Flags().UintVar(&bundleCommitArgs.expectedReplica, "expected-replica", 1, "expected number of replications to upload")
+	bundleCommitCmd.Flags().BoolVar(&bundleCommitArgs.skipTx, "skip-tx", true, "Skip sending the transaction on chain if already exists")
+	bundleCommitCmd.Flags().BoolVar(&bundleCommitArgs.finalityRequired, "finality-required", false, "Wait for file finality on nodes to upload")
+	bundleCommitCmd.Flags().UintVar(&bundleCommitArgs.taskSize, "task-size", 10, "Number of segments to upload in single rpc request")
+	bundleCommitCmd.Flags().DurationVar(&bundleCommitArgs.timeout, "timeout", 0, "cli task timeout, 0 for no timeout")
+
+	bindTransactionFlags(bundleCommitCmd, &bundleCommitArgs.transactionArgument)
+
+	bundleCmd.AddCommand(bundlePrepareCmd, bundleCommitCmd)
+	rootCmd.AddCommand(bundleCmd)
+}
+
+func prepareBundle(*cobra.Command, []string) {
+	tags, err := hexutil.Decode(bundlePrepareArgs.tags)
+	if err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid tags")
+	}
+
+	b, err := bundle.Prepare(bundlePrepareArgs.bundle, bundlePrepareArgs.files, bundle.PrepareOption{
+		Tags:            tags,
+		ExtractSegments: bundlePrepareArgs.extract,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to prepare bundle")
+	}
+
+	for _, file := range b.Manifest().Files {
+		logrus.WithFields(logrus.Fields{
+			"name":     file.Name,
+			"size":     file.Size,
+			"segments": file.NumSegments,
+		}).Infof("File prepared, root = %v", file.Root)
+	}
+}
+
+func commitBundle(*cobra.Command, []string) {
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if bundleCommitArgs.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, bundleCommitArgs.timeout)
+		defer cancel()
+	}
+
+	b, err := bundle.Open(bundleCommitArgs.bundle)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open bundle")
+	}
+
+	w3client := blockchain.MustNewWeb3(bundleCommitArgs.url, bundleCommitArgs.key, providerOption)
+	defer w3client.Close()
+
+	var fee *big.Int
+	if bundleCommitArgs.fee > 0 {
+		feeInA0GI := big.NewFloat(bundleCommitArgs.fee)
+		fee, _ = feeInA0GI.Mul(feeInA0GI, big.NewFloat(1e18)).Int(nil)
+	}
+	var nonce *big.Int
+	if bundleCommitArgs.nonce > 0 {
+		nonce = big.NewInt(int64(bundleCommitArgs.nonce))
+	}
+	finalityRequired := transfer.TransactionPacked
+	if bundleCommitArgs.finalityRequired {
+		finalityRequired = transfer.FileFinalized
+	}
+	opt := transfer.UploadOption{
+		FinalityRequired: finalityRequired,
+		TaskSize:         bundleCommitArgs.taskSize,
+		ExpectedReplica:  bundleCommitArgs.expectedReplica,
+		SkipTx:           bundleCommitArgs.skipTx,
+		Fee:              fee,
+		Nonce:            nonce,
+	}
+
+	var numSegments uint64
+	for _, file := range b.Manifest().Files {
+		numSegments = max(numSegments, file.NumSegments)
+	}
+
+	uploader, closer, err := newUploader(ctx, numSegments, bundleCommitArgs.uploadArgument, w3client, opt)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize uploader")
+	}
+	defer closer()
+
+	start := time.Now()
+	outcomes, err := uploader.CommitBundle(ctx, b, transfer.CommitBundleOption{
+		Upload: opt,
+		Force:  bundleCommitArgs.force,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to commit bundle")
+	}
+
+	logrus.WithField("duration", time.Since(start)).Infof("Bundle committed, %v files uploaded", len(outcomes))
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cleanArgs struct {
+		dest      string
+		olderThan time.Duration
+	}
+
+	cleanCmd = &cobra.Command{
+		Use:   "clean",
+		Short: "Remove stale temporary files left by crashed downloads",
+		Run:   clean,
+	}
+)
+
+func init() {
+	cleanCmd.Flags().StringVar(&cleanArgs.dest, "dest", "", "Destination directory to clean temporary files recursively")
+	cleanCmd.MarkFlagRequired("dest")
+	cleanCmd.Flags().DurationVar(&cleanArgs.olderThan, "older-than", 24*time.Hour, "Only remove temporary files not modified in the duration")
+
+	rootCmd.AddCommand(cleanCmd)
+}
+
+func clean(*cobra.Command, []string) {
+	removed, err := transfer.CleanOrphans(cleanArgs.dest, cleanArgs.olderThan)
+	for _, path := range removed {
+		logrus.WithField("file", path).Info("Orphan temporary file removed")
+	}
+
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to clean orphan temporary files")
+	}
+
+	logrus.Infof("Total %v orphan temporary files removed", len(removed))
+}
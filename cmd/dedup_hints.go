@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dedupHintsArgs struct {
+		downloadArgument
+
+		trees     string
+		saveTrees bool
+		plan      string
+	}
+
+	dedupHintsCmd = &cobra.Command{
+		Use:   "dedup-hints",
+		Short: "Analyze identical segments between a directory on ZeroGStorage network and the new local version",
+		Run:   dedupHints,
+	}
+)
+
+func init() {
+	bindDownloadFlags(dedupHintsCmd, &dedupHintsArgs.downloadArgument)
+
+	dedupHintsCmd.Flags().StringVar(&dedupHintsArgs.trees, "trees", "", "Directory of persisted merkle trees of files, named by merkle root")
+	dedupHintsCmd.MarkFlagRequired("trees")
+	dedupHintsCmd.Flags().BoolVar(&dedupHintsArgs.saveTrees, "save-trees", false, "Whether to persist merkle trees of changed and added files to analyze the next version")
+	dedupHintsCmd.Flags().StringVar(&dedupHintsArgs.plan, "plan", "", "File to write the plan to append files to the old version")
+
+	rootCmd.AddCommand(dedupHintsCmd)
+}
+
+func dedupHints(cmd *cobra.Command, _ []string) {
+	args := &dedupHintsArgs.downloadArgument
+	profile := resolveDownloadProfile(cmd, args)
+
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if args.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, args.timeout)
+		defer cancel()
+	}
+
+	downloader, closer, err := newDownloader(*args, profile)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize downloader")
+	}
+	defer closer()
+
+	old, err := transfer.BuildFileTree(ctx, downloader, args.root, args.proof)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to build file tree from ZeroGStorage network")
+	}
+
+	store := transfer.NewAppendTreeStore(dedupHintsArgs.trees)
+	report, err := transfer.AnalyzeDedup(old, args.file, store, dedupHintsArgs.saveTrees)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to analyze deduplication")
+	}
+
+	content, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(content))
+
+	logrus.WithFields(logrus.Fields{
+		"unchanged":     report.Unchanged,
+		"changed":       len(report.Files),
+		"added":         report.Added,
+		"removed":       report.Removed,
+		"changedSize":   zg_common.ByteSize(report.ChangedSize),
+		"appendSavings": zg_common.ByteSize(report.AppendSavings),
+		"packSavings":   zg_common.ByteSize(report.PackSavings),
+	}).Info("Deduplication analyzed")
+
+	if dedupHintsArgs.plan != "" {
+		if err = report.Plan().Save(dedupHintsArgs.plan); err != nil {
+			logrus.WithError(err).Fatal("Failed to write plan")
+		}
+	}
+}
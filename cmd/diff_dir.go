@@ -25,7 +25,9 @@ func init() {
 	rootCmd.AddCommand(diffDirCmd)
 }
 
-func diffDir(*cobra.Command, []string) {
+func diffDir(cmd *cobra.Command, _ []string) {
+	profile := resolveDownloadProfile(cmd, &diffDirArgs)
+
 	ctx := context.Background()
 	var cancel context.CancelFunc
 	if diffDirArgs.timeout > 0 {
@@ -38,7 +40,7 @@ func diffDir(*cobra.Command, []string) {
 		logrus.WithError(err).Fatal("Failed to build local file tree")
 	}
 
-	downloader, closer, err := newDownloader(diffDirArgs)
+	downloader, closer, err := newDownloader(diffDirArgs, profile)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to initialize downloader")
 	}
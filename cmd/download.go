@@ -9,6 +9,7 @@ import (
 	"github.com/0glabs/0g-storage-client/indexer"
 	"github.com/0glabs/0g-storage-client/node"
 	"github.com/0glabs/0g-storage-client/transfer"
+	zg_download "github.com/0glabs/0g-storage-client/transfer/download"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -17,16 +18,31 @@ import (
 type downloadArgument struct {
 	file string
 
-	indexer string
-	nodes   []string
+	indexer  string
+	nodes    []string
+	strategy string
 
 	root  string
 	roots []string
 	proof bool
 
+	verifyAgainstChain bool
+	url                string
+
 	routines int
 
+	noSync   bool
+	directIO bool
+	force    bool
+
+	failOnWarning []string
+
+	profile string
+
 	timeout time.Duration
+
+	noProgress bool
+	onProgress func(p transfer.Progress) // callback to report progress, nil if not reported
 }
 
 func bindDownloadFlags(cmd *cobra.Command, args *downloadArgument) {
@@ -36,6 +52,7 @@ func bindDownloadFlags(cmd *cobra.Command, args *downloadArgument) {
 	cmd.Flags().StringSliceVar(&args.nodes, "node", []string{}, "ZeroGStorage storage node URL. Multiple nodes could be specified and separated by comma, e.g. url1,url2,url3")
 	cmd.Flags().StringVar(&args.indexer, "indexer", "", "ZeroGStorage indexer URL")
 	cmd.MarkFlagsOneRequired("indexer", "node")
+	cmd.Flags().StringVar(&args.strategy, "node-strategy", string(indexer.StrategyRandom), "Strategy to select storage nodes to download from, options: random, consistent (same nodes for the same root to keep caches hot)")
 
 	cmd.Flags().StringVar(&args.root, "root", "", "Merkle root to download file")
 	cmd.Flags().StringSliceVar(&args.roots, "roots", []string{}, "Merkle roots to download fragments")
@@ -44,11 +61,29 @@ func bindDownloadFlags(cmd *cobra.Command, args *downloadArgument) {
 
 	cmd.Flags().BoolVar(&args.proof, "proof", false, "Whether to download with merkle proof for validation")
 
+	cmd.Flags().BoolVar(&args.verifyAgainstChain, "verify-against-chain", false, "Whether to cross-check file size and root reported by storage nodes with the submission on chain, which requires an archive fullnode for files submitted long ago")
+	cmd.Flags().StringVar(&args.url, "url", "", "Fullnode URL to verify file against chain")
+	cmd.MarkFlagsRequiredTogether("verify-against-chain", "url")
+	cmd.MarkFlagsMutuallyExclusive("verify-against-chain", "indexer")
+
 	cmd.Flags().IntVar(&args.routines, "routines", runtime.GOMAXPROCS(0), "number of go routines for downloading simutanously")
 
+	cmd.Flags().BoolVar(&args.noSync, "no-sync", false, "Skip fsync once file downloaded for throughput, which trades crash safety: file may be corrupted if the host crashes shortly after download")
+	cmd.Flags().BoolVar(&args.directIO, "direct-io", false, "Write file with O_DIRECT to bypass the page cache on linux, and fall back to buffered I/O if not supported")
+
+	cmd.Flags().BoolVar(&args.force, "force", false, "Overwrite the existing file that differs from the file to download, which fails by default")
+
+	cmd.Flags().StringSliceVar(&args.failOnWarning, "fail-on-warning", []string{}, "Warning codes to be treated as failure, e.g. SEGMENT_REROUTED")
+
+	bindProfileFlag(cmd, &args.profile)
+
 	cmd.Flags().DurationVar(&args.timeout, "timeout", 0, "cli task timeout, 0 for no timeout")
 }
 
+func (args *downloadArgument) writeOption() zg_download.WriteOption {
+	return zg_download.WriteOption{NoSync: args.noSync, Direct: args.directIO}
+}
+
 var (
 	downloadArgs downloadArgument
 
@@ -61,11 +96,14 @@ var (
 
 func init() {
 	bindDownloadFlags(downloadCmd, &downloadArgs)
+	downloadCmd.Flags().BoolVar(&downloadArgs.noProgress, "no-progress", false, "Do not show the progress bar, which is shown only if stderr is a terminal")
 
 	rootCmd.AddCommand(downloadCmd)
 }
 
-func download(*cobra.Command, []string) {
+func download(cmd *cobra.Command, _ []string) {
+	profile := resolveDownloadProfile(cmd, &downloadArgs)
+
 	ctx := context.Background()
 	var cancel context.CancelFunc
 	if downloadArgs.timeout > 0 {
@@ -73,7 +111,10 @@ func download(*cobra.Command, []string) {
 		defer cancel()
 	}
 
-	downloader, closer, err := newDownloader(downloadArgs)
+	recordOption("profile", profile)
+
+	downloadArgs.onProgress = newProgressBar(downloadArgs.noProgress)
+	downloader, closer, err := newDownloader(downloadArgs, profile)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to initialize downloader")
 	}
@@ -88,19 +129,31 @@ func download(*cobra.Command, []string) {
 			logrus.WithError(err).Fatal("Failed to download file")
 		}
 	}
+
+	reportWarnings(downloader, downloadArgs.failOnWarning)
+
+	logrus.WithField("profile", profileField(profile)).Info("File downloaded")
 }
 
-func newDownloader(args downloadArgument) (transfer.IDownloader, func(), error) {
+func newDownloader(args downloadArgument, profile *transfer.Profile) (transfer.IDownloader, func(), error) {
+	if profile != nil {
+		logrus.WithField("profile", profileField(profile)).Info("Transfer settings resolved")
+	}
+
 	if args.indexer != "" {
 		indexerClient, err := indexer.NewClient(args.indexer, indexer.IndexerClientOption{
 			ProviderOption: providerOption,
 			LogOption:      common.LogOption{Logger: logrus.StandardLogger()},
+			WriteOption:    args.writeOption(),
+			Overwrite:      args.force,
+			Strategy:       indexer.SelectionStrategy(args.strategy),
+			OnProgress:     args.onProgress,
 		})
 		if err != nil {
 			return nil, nil, errors.WithMessage(err, "failed to initialize indexer client")
 		}
 
-		return indexerClient, indexerClient.Close, nil
+		return indexerClient, func() { indexerClient.Close() }, nil
 	}
 
 	clients := node.MustNewZgsClients(args.nodes, providerOption)
@@ -115,7 +168,31 @@ func newDownloader(args downloadArgument) (transfer.IDownloader, func(), error)
 		closer()
 		return nil, nil, err
 	}
-	downloader.WithRoutines(downloadArgs.routines)
+
+	downloader.WithWriteOption(args.writeOption()).WithOverwrite(args.force).WithProgress(args.onProgress)
+
+	strategy := indexer.SelectionStrategy(args.strategy)
+	if err := strategy.Validate(); err != nil {
+		closer()
+		return nil, nil, err
+	}
+	downloader.WithConsistentNodes(strategy == indexer.StrategyConsistent)
+
+	if args.verifyAgainstChain {
+		filter, filterCloser := newSubmitLogFilter(context.Background(), args.url, "", args.nodes[0])
+		downloader.WithVerifyAgainstChain(filter)
+
+		clientsCloser := closer
+		closer = func() {
+			clientsCloser()
+			filterCloser()
+		}
+	}
+	if profile != nil {
+		downloader.WithProfile(*profile)
+	} else {
+		downloader.WithRoutines(args.routines)
+	}
 
 	return downloader, closer, nil
 }
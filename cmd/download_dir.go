@@ -2,14 +2,21 @@ package cmd
 
 import (
 	"context"
+	"time"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var (
 	downloadDirArgs downloadArgument
+	downloadDirLock transfer.LockOption
+
+	downloadDirPublisher string
+	downloadDirAttempts  int
 
 	downloadDirCmd = &cobra.Command{
 		Use:   "download-dir",
@@ -20,11 +27,19 @@ var (
 
 func init() {
 	bindDownloadFlags(downloadDirCmd, &downloadDirArgs)
+	downloadDirCmd.Flags().StringVar(&downloadDirLock.Path, "lock-path", "", "Lock file to prevent concurrent downloads to the same directory, default \"<file>.lock\"")
+	downloadDirCmd.Flags().DurationVar(&downloadDirLock.Timeout, "lock-timeout", 0, "Max time to wait if directory locked by another process, 0 to fail immediately")
+	downloadDirCmd.Flags().DurationVar(&downloadDirLock.StaleAfter, "lock-stale-after", 24*time.Hour, "Steal the lock not updated in the duration if holder process not alive, 0 to never steal")
+
+	downloadDirCmd.Flags().StringVar(&downloadDirPublisher, "expected-publisher", "", "Address that must have signed the directory metadata, not verified if not specified")
+	downloadDirCmd.Flags().IntVar(&downloadDirAttempts, "manifest-attempts", 3, "Max number of storage nodes to fetch the directory metadata from, if failed or mismatched the root")
 
 	rootCmd.AddCommand(downloadDirCmd)
 }
 
-func downloadDir(*cobra.Command, []string) {
+func downloadDir(cmd *cobra.Command, _ []string) {
+	profile := resolveDownloadProfile(cmd, &downloadDirArgs)
+
 	ctx := context.Background()
 	var cancel context.CancelFunc
 	if downloadDirArgs.timeout > 0 {
@@ -32,15 +47,28 @@ func downloadDir(*cobra.Command, []string) {
 		defer cancel()
 	}
 
-	downloader, closer, err := newDownloader(downloadDirArgs)
+	dirOpt := transfer.DownloadDirOption{Lock: downloadDirLock, Overwrite: downloadDirArgs.force, ManifestAttempts: downloadDirAttempts}
+	if len(downloadDirPublisher) > 0 {
+		if !common.IsHexAddress(downloadDirPublisher) {
+			logrus.WithField("publisher", downloadDirPublisher).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid publisher address")
+		}
+
+		publisher := common.HexToAddress(downloadDirPublisher)
+		dirOpt.ExpectedPublisher = &publisher
+	}
+
+	downloader, closer, err := newDownloader(downloadDirArgs, profile)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to initialize downloader")
 	}
 	defer closer()
 
 	// Download the entire directory structure.
-	err = transfer.DownloadDir(ctx, downloader, downloadDirArgs.root, downloadDirArgs.file, downloadDirArgs.proof)
+	err = transfer.DownloadDir(ctx, downloader, downloadDirArgs.root, downloadDirArgs.file, downloadDirArgs.proof, dirOpt)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to download folder")
 	}
+	reportWarnings(downloader, downloadDirArgs.failOnWarning)
+
+	logrus.WithField("profile", profileField(profile)).Info("Directory downloaded")
 }
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	duArgs struct {
+		maxDepth int
+		json     bool
+	}
+
+	duCmd = &cobra.Command{
+		Use:   "du <directory>",
+		Short: "Summarize the total size and number of files of directory to upload, along with the storage footprint",
+		Args:  cobra.ExactArgs(1),
+		Run:   du,
+	}
+)
+
+// duEntry is the summary of a directory printed by du.
+type duEntry struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Files int    `json:"files"`
+}
+
+func init() {
+	duCmd.Flags().IntVar(&duArgs.maxDepth, "max-depth", 1, "Print sub directories at most the depth below the directory, 0 for the directory only")
+	duCmd.Flags().BoolVar(&duArgs.json, "json", false, "Print summary in JSON format")
+
+	duCmd.Flags().StringSliceVar(&ignoreArgs.patterns, "ignore", nil, "Gitignore-style patterns of files not to upload, e.g. .git/,node_modules/")
+	duCmd.Flags().BoolVar(&ignoreArgs.file, "ignore-file", true, "Ignore files matching the patterns in "+dir.IgnoreFileName+" at the root of directory")
+
+	rootCmd.AddCommand(duCmd)
+}
+
+func du(_ *cobra.Command, args []string) {
+	if duArgs.maxDepth < 0 {
+		logrus.WithField("maxDepth", duArgs.maxDepth).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid --max-depth")
+	}
+
+	folder := args[0]
+	// file names not portable are rejected on upload by default, but only warned here to summarize anyway
+	root, err := dir.BuildFileTreeWithOption(folder, dir.BuildOption{
+		Ignore:        mustLoadIgnorePatterns(folder),
+		PortableNames: dir.PortableNamesWarn,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to build file tree")
+	}
+	root.ComputeAggregates()
+
+	// directories in pre-order, of which sub directories printed before the parent as du does
+	var entries []duEntry
+	root.Walk(func(path string, node *dir.FsNode) error {
+		if node.Type == dir.FileTypeDirectory && depthOf(path) <= duArgs.maxDepth {
+			entries = append(entries, duEntry{Path: filepath.Join(folder, path), Size: node.TotalSize, Files: node.FileCount})
+		}
+		return nil
+	})
+
+	// storage footprint to estimate fee, where embedded files are not taken into account
+	footprint := root.Footprint()
+
+	if duArgs.json {
+		content, err := json.MarshalIndent(map[string]interface{}{
+			"directories": entries,
+			"footprint":   footprint,
+		}, "", "    ")
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to marshal summary")
+		}
+		fmt.Println(string(content))
+		return
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		fmt.Printf("%v\t%v files\t%v\n", zg_common.ByteSize(entries[i].Size), entries[i].Files, entries[i].Path)
+	}
+	fmt.Printf("Storage footprint: %v sectors, %v padded\n", footprint.Sectors, zg_common.ByteSize(footprint.PaddedSize))
+}
+
+// depthOf returns the depth of slash-separated path relative to the root, which is 0 for the root itself.
+func depthOf(path string) int {
+	if len(path) == 0 {
+		return 0
+	}
+
+	return strings.Count(path, "/") + 1
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/indexer"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	existsArgs struct {
+		input      string
+		output     string
+		checkpoint string
+
+		nodes   []string
+		indexer string
+
+		batchSize         int
+		concurrency       int
+		requestsPerSecond float64
+
+		timeout time.Duration
+	}
+
+	existsCmd = &cobra.Command{
+		Use:   "exists",
+		Short: "Check whether files exist and finalized on ZeroGStorage network in bulk",
+		Run:   exists,
+	}
+)
+
+func init() {
+	existsCmd.Flags().StringVar(&existsArgs.input, "input", "", "File of roots to check, one root per line")
+	existsCmd.MarkFlagRequired("input")
+	existsCmd.Flags().StringVar(&existsArgs.output, "output", "", "CSV file to write results, which is appended to if resumed")
+	existsCmd.MarkFlagRequired("output")
+	existsCmd.Flags().StringVar(&existsArgs.checkpoint, "checkpoint", "", "File to persist progress so as to resume after interruption, default \"<output>.checkpoint\"")
+
+	existsCmd.Flags().StringSliceVar(&existsArgs.nodes, "node", []string{}, "ZeroGStorage storage node URL. Multiple nodes could be specified and separated by comma, e.g. url1,url2,url3")
+	existsCmd.Flags().StringVar(&existsArgs.indexer, "indexer", "", "ZeroGStorage indexer URL")
+	existsCmd.MarkFlagsOneRequired("indexer", "node")
+	existsCmd.MarkFlagsMutuallyExclusive("indexer", "node")
+
+	existsCmd.Flags().IntVar(&existsArgs.batchSize, "batch-size", 100, "Number of roots to query in a single batch RPC")
+	existsCmd.Flags().IntVar(&existsArgs.concurrency, "concurrency", 4, "Number of batches to query in parallel")
+	existsCmd.Flags().Float64Var(&existsArgs.requestsPerSecond, "rps", 0, "Max number of batch RPCs per second, 0 for unlimited")
+
+	existsCmd.Flags().DurationVar(&existsArgs.timeout, "timeout", 0, "cli task timeout, 0 for no timeout")
+
+	rootCmd.AddCommand(existsCmd)
+}
+
+func exists(*cobra.Command, []string) {
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if existsArgs.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, existsArgs.timeout)
+		defer cancel()
+	}
+
+	var querier transfer.ExistenceQuerier
+	if existsArgs.indexer != "" {
+		indexerClient, err := indexer.NewClient(existsArgs.indexer, indexer.IndexerClientOption{ProviderOption: providerOption})
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to initialize indexer client")
+		}
+		defer indexerClient.Close()
+
+		querier = indexerClient
+	} else {
+		clients := node.MustNewZgsClients(existsArgs.nodes, providerOption)
+		for _, client := range clients {
+			defer client.Close()
+		}
+
+		querier = transfer.NewNodeExistenceQuerier(clients)
+	}
+
+	input, err := os.Open(existsArgs.input)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open input file")
+	}
+	defer input.Close()
+
+	checkpoint := existsArgs.checkpoint
+	if checkpoint == "" {
+		checkpoint = existsArgs.output + ".checkpoint"
+	}
+
+	// results are appended to output if resumed
+	if _, err = os.Stat(checkpoint); os.IsNotExist(err) {
+		err = os.Truncate(existsArgs.output, 0)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to prepare output file")
+	}
+
+	output, err := os.OpenFile(existsArgs.output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open output file")
+	}
+	defer output.Close()
+
+	offset, err := output.Seek(0, io.SeekEnd)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to seek output file")
+	}
+
+	sink, err := transfer.NewCSVExistenceSink(output, offset == 0)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to write output file")
+	}
+
+	stats, err := transfer.CheckExistence(ctx, querier, transfer.RootsFromReader(input), sink, transfer.ExistenceCheckOption{
+		BatchSize:         existsArgs.batchSize,
+		Concurrency:       existsArgs.concurrency,
+		RequestsPerSecond: existsArgs.requestsPerSecond,
+		Checkpoint:        checkpoint,
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("stats", stats).Fatal("Failed to check existence, rerun to resume")
+	}
+
+	// completed, so no need to resume
+	if err = os.Remove(checkpoint); err != nil && !os.IsNotExist(err) {
+		logrus.WithError(err).Warn("Failed to remove checkpoint file")
+	}
+
+	logrus.WithField("stats", stats).Info("Existence check completed")
+}
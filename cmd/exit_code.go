@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/sirupsen/logrus"
+)
+
+// Exit codes of the CLI, which are stable so that automation could react to failures accordingly.
+const (
+	ExitSuccess      = 0
+	ExitFailure      = 1 // unclassified failure
+	ExitUsage        = 2
+	ExitNetwork      = 3
+	ExitTransaction  = 4
+	ExitVerification = 5
+	ExitPartial      = 6
+	ExitCancelled    = 7
+	ExitRejected     = 8
+)
+
+// errorClassField is the log field of error class in the fatal log.
+const errorClassField = "errorClass"
+
+const exitCodesHelp = `
+Exit Codes:
+  0  success
+  1  unclassified failure
+  2  usage error, e.g. invalid flags or options
+  3  network error, e.g. fullnode or storage node unreachable
+  4  on-chain transaction failure
+  5  verification or integrity failure
+  6  partial success, e.g. some fragments uploaded, or failed due to warnings
+  7  cancelled or timed out
+  8  rejected by storage node, e.g. invalid data
+`
+
+var exitCodes = map[zg_common.ErrorClass]int{
+	zg_common.ErrorClassUnknown:      ExitFailure,
+	zg_common.ErrorClassUsage:        ExitUsage,
+	zg_common.ErrorClassNetwork:      ExitNetwork,
+	zg_common.ErrorClassTransaction:  ExitTransaction,
+	zg_common.ErrorClassVerification: ExitVerification,
+	zg_common.ErrorClassPartial:      ExitPartial,
+	zg_common.ErrorClassCancelled:    ExitCancelled,
+	zg_common.ErrorClassRejected:     ExitRejected,
+}
+
+// exitCodeOf returns the exit code of the error class.
+func exitCodeOf(class zg_common.ErrorClass) int {
+	if code, ok := exitCodes[class]; ok {
+		return code
+	}
+
+	return ExitFailure
+}
+
+// exitCodeHook classifies the error of fatal log, which is attached to the log entry, and determines the exit
+// code accordingly. The error class could be specified by the errorClass field explicitly if no error attached.
+type exitCodeHook struct {
+	code int
+}
+
+func (hook *exitCodeHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.FatalLevel}
+}
+
+func (hook *exitCodeHook) Fire(entry *logrus.Entry) error {
+	class, ok := entry.Data[errorClassField].(zg_common.ErrorClass)
+	if !ok {
+		err, _ := entry.Data[logrus.ErrorKey].(error)
+		class = zg_common.ClassOf(err)
+	}
+
+	entry.Data[errorClassField] = class
+	hook.code = exitCodeOf(class)
+
+	return nil
+}
+
+// installExitCodeHook makes the logger exit with the classified exit code on fatal log.
+func installExitCodeHook(logger *logrus.Logger, exit func(code int)) {
+	hook := exitCodeHook{code: ExitFailure}
+	logger.AddHook(&hook)
+	logger.ExitFunc = func(int) {
+		exit(hook.code)
+	}
+}
+
+func init() {
+	installExitCodeHook(logrus.StandardLogger(), os.Exit)
+}
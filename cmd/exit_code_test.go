@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCodeHook(t *testing.T) {
+	var out bytes.Buffer
+	var code int
+
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	installExitCodeHook(logger, func(c int) { code = c })
+
+	for _, tc := range []struct {
+		entry        *logrus.Entry
+		expectedCode int
+		expected     zg_common.ErrorClass
+	}{
+		{logger.WithError(errors.New("test")), ExitFailure, zg_common.ErrorClassUnknown},
+		{logger.WithError(zg_common.RequirePositive("Routines", 0)), ExitUsage, zg_common.ErrorClassUsage},
+		{logger.WithError(zg_common.WithErrorClass(errors.New("test"), zg_common.ErrorClassNetwork)), ExitNetwork, zg_common.ErrorClassNetwork},
+		{logger.WithError(zg_common.WithErrorClass(errors.New("test"), zg_common.ErrorClassTransaction)), ExitTransaction, zg_common.ErrorClassTransaction},
+		{logger.WithError(zg_common.WithErrorClass(errors.New("test"), zg_common.ErrorClassVerification)), ExitVerification, zg_common.ErrorClassVerification},
+		{logger.WithError(zg_common.WithErrorClass(errors.New("test"), zg_common.ErrorClassPartial)), ExitPartial, zg_common.ErrorClassPartial},
+		{logger.WithError(errors.WithMessage(context.Canceled, "test")), ExitCancelled, zg_common.ErrorClassCancelled},
+		{logger.WithError(zg_common.WithErrorClass(errors.New("test"), zg_common.ErrorClassRejected)), ExitRejected, zg_common.ErrorClassRejected},
+		// specified explicitly
+		{logger.WithField(errorClassField, zg_common.ErrorClassUsage), ExitUsage, zg_common.ErrorClassUsage},
+		{logger.WithField("n", 1), ExitFailure, zg_common.ErrorClassUnknown},
+	} {
+		out.Reset()
+		code = 0
+
+		tc.entry.Fatal("Failed")
+		assert.Equal(t, tc.expectedCode, code)
+
+		var output map[string]interface{}
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &output))
+		assert.Equal(t, string(tc.expected), output[errorClassField])
+	}
+}
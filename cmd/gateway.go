@@ -1,14 +1,28 @@
 package cmd
 
 import (
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/blockchain"
 	"github.com/0glabs/0g-storage-client/gateway"
 	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/openweb3/web3go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var (
 	gatewayArgs struct {
 		nodes []string
+		url   string
+		key   string
+
+		readOnly bool
+
+		tenantKeys           map[string]string
+		tenantMaxConcurrency int
+		tenantMaxBytesPerDay zg_common.ByteSize
 	}
 
 	gatewayCmd = &cobra.Command{
@@ -24,12 +38,61 @@ func init() {
 		"http://127.0.0.1:5679",
 		"http://127.0.0.1:5680",
 	}, "Storage node list separated by comma")
+	gatewayCmd.Flags().StringVar(&gatewayArgs.url, "url", "", "Fullnode URL to submit flow transactions of uploads, uploads not supported if not specified")
+	gatewayCmd.Flags().StringVar(&gatewayArgs.key, "key", "", "Private key to submit flow transactions of uploads")
+	gatewayCmd.MarkFlagsRequiredTogether("url", "key")
+	gatewayCmd.Flags().BoolVar(&gatewayArgs.readOnly, "read-only", false, "Serve without upload APIs, in which case --url and --key are not allowed")
 	gatewayCmd.Flags().StringVar(&gateway.LocalFileRepo, "repo", "", "Local file repository")
+	gatewayCmd.Flags().Var(&gateway.MaxUploadSize, "max-upload-size", "Max size of data to upload via HTTP body, which is spooled in temp directory before uploading, e.g. 512MiB, uploads via HTTP body rejected if 0")
+	gatewayCmd.Flags().StringToStringVar(&gatewayArgs.tenantKeys, "tenant-keys", nil, "API keys of tenants to authenticate by Authorization header with Bearer scheme, in format of key=tenant separated by comma")
+	gatewayCmd.Flags().IntVar(&gatewayArgs.tenantMaxConcurrency, "tenant-max-concurrency", 0, "Max number of concurrent uploads of each tenant, including the anonymous tenant without API key, 0 for unlimited")
+	gatewayCmd.Flags().Var(&gatewayArgs.tenantMaxBytesPerDay, "tenant-max-bytes-per-day", "Max size of data to upload by each tenant in a day of UTC, e.g. 10GiB, 0 for unlimited")
 
 	rootCmd.AddCommand(gatewayCmd)
 }
 
+// newGatewayWeb3 creates the blockchain client with private key to submit flow transactions of uploads.
+var newGatewayWeb3 = blockchain.MustNewWeb3
+
+// gatewayWeb3Client returns the blockchain client to submit flow transactions of uploads, or nil if uploads not
+// supported. In read-only mode, neither blockchain client nor signer is created.
+func gatewayWeb3Client() (*web3go.Client, error) {
+	if gatewayArgs.readOnly {
+		if len(gatewayArgs.url) > 0 || len(gatewayArgs.key) > 0 {
+			return nil, errors.New("--url and --key not allowed in read-only mode")
+		}
+
+		return nil, nil
+	}
+
+	if len(gatewayArgs.url) == 0 {
+		return nil, nil
+	}
+
+	return newGatewayWeb3(gatewayArgs.url, gatewayArgs.key, providerOption), nil
+}
+
 func startGateway(*cobra.Command, []string) {
-	nodes := node.MustNewZgsClients(gatewayArgs.nodes)
-	gateway.MustServeLocal(nodes)
+	gateway.TenantKeys = gatewayArgs.tenantKeys
+
+	if gatewayArgs.tenantMaxConcurrency > 0 || gatewayArgs.tenantMaxBytesPerDay > 0 {
+		gateway.Tenants = transfer.NewTenants(transfer.TenantLimits{
+			MaxConcurrency: gatewayArgs.tenantMaxConcurrency,
+			MaxBytesPerDay: uint64(gatewayArgs.tenantMaxBytesPerDay),
+		}, nil)
+	}
+
+	w3client, err := gatewayWeb3Client()
+	if err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid gateway options")
+	}
+
+	if w3client != nil {
+		defer w3client.Close()
+	}
+
+	gateway.ReadOnly = gatewayArgs.readOnly
+
+	nodes := node.MustNewZgsClients(gatewayArgs.nodes, providerOption)
+	gateway.MustServeLocal(nodes, w3client)
 }
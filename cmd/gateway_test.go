@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"testing"
+
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/openweb3/web3go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatewayWeb3ClientReadOnly(t *testing.T) {
+	oldArgs, oldFactory := gatewayArgs, newGatewayWeb3
+	defer func() { gatewayArgs, newGatewayWeb3 = oldArgs, oldFactory }()
+
+	var created int
+	newGatewayWeb3 = func(url, key string, opt ...providers.Option) *web3go.Client {
+		created++
+		return &web3go.Client{}
+	}
+
+	// neither blockchain client nor signer created
+	gatewayArgs.readOnly = true
+	client, err := gatewayWeb3Client()
+	assert.NoError(t, err)
+	assert.Nil(t, client)
+	assert.Equal(t, 0, created)
+
+	// blockchain options rejected at startup
+	gatewayArgs.url, gatewayArgs.key = "http://127.0.0.1:8545", "0x1234"
+	_, err = gatewayWeb3Client()
+	assert.Error(t, err)
+	gatewayArgs.url = ""
+	_, err = gatewayWeb3Client()
+	assert.Error(t, err)
+	assert.Equal(t, 0, created)
+
+	// created to submit transactions if not read-only
+	gatewayArgs.readOnly, gatewayArgs.url = false, "http://127.0.0.1:8545"
+	client, err = gatewayWeb3Client()
+	assert.NoError(t, err)
+	assert.NotNil(t, client)
+	assert.Equal(t, 1, created)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"time"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/core"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -12,7 +13,7 @@ import (
 
 var (
 	genFileArgs struct {
-		size      uint64
+		size      zg_common.ByteSize
 		file      string
 		overwrite bool
 	}
@@ -25,7 +26,7 @@ var (
 )
 
 func init() {
-	genFileCmd.Flags().Uint64Var(&genFileArgs.size, "size", 0, "File size in bytes (default \"[1M, 10M)\")")
+	genFileCmd.Flags().Var(&genFileArgs.size, "size", "File size, e.g. 4MiB or 4194304 in bytes (default \"[1M, 10M)\")")
 	genFileCmd.Flags().StringVar(&genFileArgs.file, "file", "tmp123456", "File name to generate")
 	genFileCmd.Flags().BoolVar(&genFileArgs.overwrite, "overwrite", false, "Whether to overwrite existing file")
 
@@ -51,7 +52,7 @@ func generateTempFile(*cobra.Command, []string) {
 
 	if genFileArgs.size == 0 {
 		// [1M, 10M)
-		genFileArgs.size = 1024*1024 + uint64(9.0*1024*1024*r.Float64())
+		genFileArgs.size = zg_common.MiB + zg_common.ByteSize(9.0*float64(zg_common.MiB)*r.Float64())
 	}
 
 	data := make([]byte, genFileArgs.size)
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyArgs struct {
+		ledger string
+		since  string
+		root   string
+		path   string
+		failed bool
+		limit  int
+	}
+
+	historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Query uploads recorded in local ledger, see --ledger of upload commands",
+	}
+
+	historyListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List uploads recorded in local ledger in JSON format",
+		Run:   queryHistory,
+	}
+
+	historySearchCmd = &cobra.Command{
+		Use:   "search",
+		Short: "Search uploads recorded in local ledger by root or path in JSON format",
+		Run:   queryHistory,
+	}
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{historyListCmd, historySearchCmd} {
+		cmd.Flags().StringVar(&historyArgs.ledger, "ledger", transfer.DefaultLedgerDir(), "Directory of local ledger")
+		cmd.Flags().StringVar(&historyArgs.since, "since", "", "Uploads started since the time in RFC3339 format, or the duration ago, e.g. 24h")
+		cmd.Flags().BoolVar(&historyArgs.failed, "failed", false, "Failed uploads only")
+		cmd.Flags().IntVar(&historyArgs.limit, "limit", 0, "Max number of latest uploads to output, 0 for unlimited")
+	}
+
+	historySearchCmd.Flags().StringVar(&historyArgs.root, "root", "", "Storage root of file or directory metadata")
+	historySearchCmd.Flags().StringVar(&historyArgs.path, "path", "", "Part of local path or URL uploaded")
+	historySearchCmd.MarkFlagsOneRequired("root", "path")
+
+	historyCmd.AddCommand(historyListCmd, historySearchCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+// parseSince parses the time in RFC3339 format, or the duration ago.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid time %q, expected RFC3339 format or duration", value)
+	}
+
+	return since, nil
+}
+
+func mustHistoryFilter() transfer.LedgerFilter {
+	since, err := parseSince(historyArgs.since, time.Now())
+	if err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid --since")
+	}
+
+	filter := transfer.LedgerFilter{
+		Since: since,
+		Path:  historyArgs.path,
+		Limit: historyArgs.limit,
+	}
+
+	if len(historyArgs.root) > 0 {
+		root, err := hexutil.Decode(historyArgs.root)
+		if err != nil || len(root) != common.HashLength {
+			logrus.WithField("root", historyArgs.root).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid storage root")
+		}
+		filter.Root = common.BytesToHash(root)
+	}
+
+	if historyArgs.failed {
+		filter.Result = transfer.LedgerFailed
+	}
+
+	return filter
+}
+
+func queryHistory(*cobra.Command, []string) {
+	filter := mustHistoryFilter()
+	ctx := context.Background()
+
+	ledger, err := transfer.OpenFileLedger(ctx, historyArgs.ledger, zg_common.LogOption{Logger: logrus.StandardLogger()})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open ledger")
+	}
+
+	records, err := ledger.Query(ctx, filter)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to query ledger")
+	}
+
+	content, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to marshal records")
+	}
+
+	fmt.Println(string(content))
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"time"
 
+	"github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/rpc"
 	"github.com/0glabs/0g-storage-client/indexer"
 	"github.com/0glabs/0g-storage-client/indexer/gateway"
@@ -16,7 +17,12 @@ var (
 		nodes               indexer.NodeManagerConfig
 		locations           indexer.IPLocationConfig
 		locationCache       indexer.FileLocationCacheConfig
-		maxDownloadFileSize uint64
+		maxDownloadFileSize common.ByteSize
+		readOnly            bool
+		cache               gateway.CacheConfig
+		accessLog           gateway.AccessLogConfig
+		listing             gateway.ListingConfig
+		adminToken          string
 	}
 
 	indexerCmd = &cobra.Command{
@@ -43,7 +49,25 @@ func init() {
 	indexerCmd.Flags().DurationVar(&indexerArgs.locationCache.Expiry, "file-location-cache-expiry", 24*time.Hour, "Validity period of location information")
 	indexerCmd.Flags().IntVar(&indexerArgs.locationCache.CacheSize, "file-location-cache-size", 100000, "size of file location cache")
 
-	indexerCmd.Flags().Uint64Var(&indexerArgs.maxDownloadFileSize, "max-download-file-size", 100*1024*1024, "Maximum file size in bytes to download")
+	indexerArgs.maxDownloadFileSize = 100 * common.MiB
+	indexerCmd.Flags().Var(&indexerArgs.maxDownloadFileSize, "max-download-file-size", "Maximum file size to download, e.g. 100MiB")
+
+	indexerCmd.Flags().BoolVar(&indexerArgs.readOnly, "read-only", false, "Serve as a read-only gateway, which disables all routes to write data")
+
+	indexerCmd.Flags().Var(&indexerArgs.cache.MaxSize, "gateway-cache-size", "Maximum size of in-memory cache for manifests and small files, e.g. 256MiB, disabled by default")
+	indexerCmd.Flags().Var(&indexerArgs.cache.MaxItemSize, "gateway-cache-item-size", "Maximum size of file to cache in memory, defaults to 1/64 of cache size")
+	indexerCmd.Flags().DurationVar(&indexerArgs.cache.TTL, "gateway-cache-ttl", 10*time.Minute, "Duration that cached manifests are fresh, after which refreshed in background")
+	indexerCmd.Flags().StringVar(&indexerArgs.adminToken, "gateway-admin-token", "", "Bearer token required by admin routes of gateway, e.g. to purge cache, which are disabled if not specified")
+
+	indexerCmd.Flags().IntVar(&indexerArgs.listing.DefaultPageSize, "gateway-listing-page-size", 1000, "Number of entries per page of directory listing if limit not specified")
+	indexerCmd.Flags().IntVar(&indexerArgs.listing.MaxPageSize, "gateway-listing-max-page-size", 10000, "Maximum number of entries per page of directory listing")
+
+	indexerCmd.Flags().BoolVar(&indexerArgs.accessLog.Enabled, "gateway-access-log", false, "Log requests to gateway, where failed requests are always logged")
+	indexerCmd.Flags().Float64Var(&indexerArgs.accessLog.SampleRate, "gateway-access-log-sample-rate", 1, "Fraction of successful requests to log in [0, 1]")
+	indexerCmd.Flags().BoolVar(&indexerArgs.accessLog.TruncateIP, "gateway-access-log-truncate-ip", false, "Truncate client IP in access logs to /24 for IPv4 and /48 for IPv6")
+	indexerCmd.Flags().StringVar(&indexerArgs.accessLog.PrincipalHeader, "gateway-access-log-principal-header", "", "Request header of auth principal set by upstream proxy to log, e.g. X-Forwarded-User")
+	indexerCmd.Flags().BoolVar(&indexerArgs.accessLog.HashPrincipal, "gateway-access-log-hash-principal", false, "Log the salted hash of auth principal instead of plain text")
+	indexerCmd.Flags().StringVar(&indexerArgs.accessLog.PrincipalSalt, "gateway-access-log-principal-salt", "", "Salt to hash auth principal in access logs")
 
 	indexerCmd.MarkFlagsOneRequired("trusted", "node")
 
@@ -51,10 +75,20 @@ func init() {
 }
 
 func startIndexer(*cobra.Command, []string) {
+	if err := indexerArgs.accessLog.Validate(); err != nil {
+		logrus.WithError(err).WithField(errorClassField, common.ErrorClassUsage).Fatal("Invalid access log config")
+	}
+
+	if err := indexerArgs.listing.Validate(); err != nil {
+		logrus.WithError(err).WithField(errorClassField, common.ErrorClassUsage).Fatal("Invalid directory listing config")
+	}
+
 	indexerArgs.locationCache.DiscoveryNode = indexerArgs.nodes.DiscoveryNode
 	indexerArgs.locationCache.DiscoveryPorts = indexerArgs.nodes.DiscoveryPorts
 
-	indexer.InitDefaultIPLocationManager(indexerArgs.locations)
+	if err := indexer.InitDefaultIPLocationManager(indexerArgs.locations); err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize the default IP location manager")
+	}
 
 	nodeManager, err := indexer.InitDefaultNodeManager(indexerArgs.nodes)
 	if err != nil {
@@ -76,8 +110,13 @@ func startIndexer(*cobra.Command, []string) {
 	}).Info("Starting indexer service ...")
 
 	gateway.MustServeWithRPC(nodeManager, fileLocationCache, gateway.Config{
-		Endpoint:            indexerArgs.endpoint,
-		MaxDownloadFileSize: indexerArgs.maxDownloadFileSize,
+		Endpoint:        indexerArgs.endpoint,
+		MaxDownloadSize: indexerArgs.maxDownloadFileSize,
+		ReadOnly:        indexerArgs.readOnly,
+		Cache:           indexerArgs.cache,
+		AccessLog:       indexerArgs.accessLog,
+		Listing:         indexerArgs.listing,
+		AdminToken:      indexerArgs.adminToken,
 		RPCHandler: rpc.MustNewHandler(map[string]interface{}{
 			api.Namespace: api,
 		}),
@@ -39,6 +39,9 @@ var (
 		fee   float64
 		nonce uint
 
+		maxTxSize zg_common.ByteSize
+		strict    bool
+
 		timeout time.Duration
 	}
 
@@ -80,6 +83,9 @@ func init() {
 	kvWriteCmd.Flags().Float64Var(&kvWriteArgs.fee, "fee", 0, "fee paid in a0gi")
 	kvWriteCmd.Flags().UintVar(&kvWriteArgs.nonce, "nonce", 0, "nonce of upload transaction")
 
+	kvWriteCmd.Flags().Var(&kvWriteArgs.maxTxSize, "max-tx-size", "Maximum size of KV operations in a transaction, e.g. 4MiB, and larger batch is split into multiple transactions. 0 for unlimited")
+	kvWriteCmd.Flags().BoolVar(&kvWriteArgs.strict, "strict", false, "Fail if KV operations could not be written in a single transaction instead of splitting")
+
 	rootCmd.AddCommand(kvWriteCmd)
 }
 
@@ -131,7 +137,7 @@ func kvWrite(*cobra.Command, []string) {
 	}
 	if len(clients) == 0 {
 		if len(kvWriteArgs.node) == 0 {
-			logrus.Fatal("At least one of --node and --indexer should not be empty")
+			logrus.WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("At least one of --node and --indexer should not be empty")
 		}
 		clients = node.MustNewZgsClients(kvWriteArgs.node, providerOption)
 		for _, client := range clients {
@@ -139,12 +145,14 @@ func kvWrite(*cobra.Command, []string) {
 		}
 	}
 
-	batcher := kv.NewBatcher(kvWriteArgs.version, clients, w3client, zg_common.LogOption{Logger: logrus.StandardLogger()})
+	batcher := kv.NewBatcher(kvWriteArgs.version, clients, w3client, zg_common.LogOption{Logger: logrus.StandardLogger()}).
+		WithMaxTxSize(int(kvWriteArgs.maxTxSize)).
+		WithStrict(kvWriteArgs.strict)
 	if len(kvWriteArgs.keys) != len(kvWriteArgs.values) {
-		logrus.Fatal("keys and values length mismatch")
+		logrus.WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("keys and values length mismatch")
 	}
 	if len(kvWriteArgs.keys) == 0 {
-		logrus.Fatal("no keys to write")
+		logrus.WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("no keys to write")
 	}
 	streamId := common.HexToHash(kvWriteArgs.streamId)
 
@@ -155,7 +163,17 @@ func kvWrite(*cobra.Command, []string) {
 		)
 	}
 
-	_, err := batcher.Exec(ctx, opt)
+	result, err := batcher.ExecAll(ctx, opt)
+	if result != nil {
+		for i, tx := range result.Txs {
+			logrus.WithFields(logrus.Fields{
+				"txHash": tx.TxHash,
+				"root":   tx.Root,
+				"size":   tx.Size,
+				"writes": tx.Writes,
+			}).Infof("KV transaction %v executed", i+1)
+		}
+	}
 	if err != nil {
 		logrus.WithError(err).Fatal("fail to execute kv batch")
 	}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/common/rpc"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/openweb3/web3go"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listUploadsArgs struct {
+		url  string
+		flow string
+		node string
+
+		sender     string
+		tagPrefix  string
+		sinceBlock uint64
+		toBlock    uint64
+		blockRange uint64
+
+		index string
+
+		timeout time.Duration
+	}
+
+	listUploadsCmd = &cobra.Command{
+		Use:   "list-uploads",
+		Short: "List files submitted to ZeroGStorage network filtered by sender and tags",
+		Run:   listUploads,
+	}
+)
+
+func init() {
+	listUploadsCmd.Flags().StringVar(&listUploadsArgs.url, "url", "", "Fullnode URL to retrieve logs of ZeroGStorage smart contract")
+	listUploadsCmd.MarkFlagRequired("url")
+	listUploadsCmd.Flags().StringVar(&listUploadsArgs.flow, "flow", "", "Flow contract address")
+	listUploadsCmd.Flags().StringVar(&listUploadsArgs.node, "node", "", "ZeroGStorage storage node URL to retrieve flow contract address")
+	listUploadsCmd.MarkFlagsOneRequired("flow", "node")
+	listUploadsCmd.MarkFlagsMutuallyExclusive("flow", "node")
+
+	listUploadsCmd.Flags().StringVar(&listUploadsArgs.sender, "sender", "", "Sender address of files, files of any sender if not specified")
+	listUploadsCmd.Flags().StringVar(&listUploadsArgs.tagPrefix, "tag-prefix", "0x", "Prefix of file tags in HEX format")
+	listUploadsCmd.Flags().Uint64Var(&listUploadsArgs.sinceBlock, "since-block", 0, "Block number to scan from")
+	listUploadsCmd.Flags().Uint64Var(&listUploadsArgs.toBlock, "to-block", 0, "Block number to scan to, 0 for the latest block")
+	listUploadsCmd.Flags().Uint64Var(&listUploadsArgs.blockRange, "block-range", 1000, "Number of blocks to retrieve logs in a single RPC")
+
+	listUploadsCmd.Flags().StringVar(&listUploadsArgs.index, "index", "", "Directory of local index to avoid rescanning history, which is updated incrementally")
+
+	listUploadsCmd.Flags().DurationVar(&listUploadsArgs.timeout, "timeout", 0, "cli task timeout, 0 for no timeout")
+
+	rootCmd.AddCommand(listUploadsCmd)
+}
+
+func listUploads(*cobra.Command, []string) {
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if listUploadsArgs.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, listUploadsArgs.timeout)
+		defer cancel()
+	}
+
+	query := transfer.UploadQuery{
+		FromBlock:  listUploadsArgs.sinceBlock,
+		ToBlock:    listUploadsArgs.toBlock,
+		BlockRange: listUploadsArgs.blockRange,
+	}
+
+	if len(listUploadsArgs.sender) > 0 {
+		if !common.IsHexAddress(listUploadsArgs.sender) {
+			logrus.WithField("sender", listUploadsArgs.sender).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid sender address")
+		}
+
+		sender := common.HexToAddress(listUploadsArgs.sender)
+		query.Sender = &sender
+	}
+
+	tagPrefix, err := hexutil.Decode(listUploadsArgs.tagPrefix)
+	if err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid tag prefix")
+	}
+	query.TagPrefix = tagPrefix
+
+	filter, closer := newSubmitLogFilter(ctx, listUploadsArgs.url, listUploadsArgs.flow, listUploadsArgs.node)
+	defer closer()
+
+	printer := func(record transfer.UploadRecord) error {
+		content, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(content))
+
+		return nil
+	}
+
+	if len(listUploadsArgs.index) == 0 {
+		cursor, err := transfer.ListUploads(ctx, filter, query, printer)
+		if err != nil {
+			logrus.WithError(err).WithField("nextBlock", cursor.NextBlock).Fatal("Failed to list uploads, rerun with --since-block to resume")
+		}
+
+		logrus.WithField("nextBlock", cursor.NextBlock).Debug("Uploads listed")
+
+		return
+	}
+
+	index, err := transfer.OpenUploadIndex(listUploadsArgs.index)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to open local index")
+	}
+	defer index.Close()
+
+	cursor, err := index.Update(ctx, filter, query)
+	if err != nil {
+		logrus.WithError(err).WithField("nextBlock", cursor.NextBlock).Fatal("Failed to update local index, rerun to resume")
+	}
+
+	records, err := index.List(query)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to list uploads from local index")
+	}
+
+	for _, record := range records {
+		if err = printer(record); err != nil {
+			logrus.WithError(err).Fatal("Failed to print upload")
+		}
+	}
+
+	logrus.WithField("nextBlock", cursor.NextBlock).Debug("Uploads listed from local index")
+}
+
+// newSubmitLogFilter creates a filter of flow contract, whose address is retrieved from the storage node if
+// nodeURL specified.
+func newSubmitLogFilter(ctx context.Context, url, flow, nodeURL string) (*transfer.FlowSubmitFilter, func()) {
+	w3client, err := blockchain.NewWeb3Client(url, rpc.DefaultProxy, web3go.ClientOption{Option: providerOption})
+	if err != nil {
+		logrus.WithError(zg_common.ClassifyError(err, zg_common.ErrorClassNetwork)).WithField("url", url).Fatal("Failed to connect to fullnode")
+	}
+
+	return newFlowSubmitFilter(ctx, w3client, flow, nodeURL), w3client.Close
+}
+
+// newFlowSubmitFilter creates a filter of flow contract with the specified fullnode client.
+func newFlowSubmitFilter(ctx context.Context, w3client *web3go.Client, flow, nodeURL string) *transfer.FlowSubmitFilter {
+	flowAddress := common.HexToAddress(flow)
+	if len(nodeURL) > 0 {
+		client := node.MustNewZgsClient(nodeURL, providerOption)
+		defer client.Close()
+
+		status, err := client.GetStatus(ctx)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to get status from storage node")
+		}
+
+		flowAddress = status.NetworkIdentity.FlowContractAddress
+	}
+
+	filter, err := transfer.NewFlowSubmitFilter(w3client, flowAddress)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to create filter of flow contract")
+	}
+
+	return filter
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateManifestArgs struct {
+		upload uploadArgument
+
+		root            string
+		verify          bool
+		manifestKey     manifestKeyArgument
+		mappingFilename string
+	}
+
+	migrateManifestCmd = &cobra.Command{
+		Use:   "migrate-manifest",
+		Short: "Migrate published directory metadata to the canonical form, without uploading files again",
+		Run:   migrateManifest,
+	}
+)
+
+func init() {
+	args := &migrateManifestArgs.upload
+
+	bindTransactionFlags(migrateManifestCmd, &args.transactionArgument)
+	migrateManifestCmd.Flags().StringVar(&args.tags, "tags", "0x", "Tags of the migrated directory metadata")
+	migrateManifestCmd.Flags().StringSliceVar(&args.node, "node", []string{}, "ZeroGStorage storage node URL")
+	migrateManifestCmd.Flags().StringVar(&args.indexer, "indexer", "", "ZeroGStorage indexer URL")
+	migrateManifestCmd.MarkFlagsOneRequired("indexer", "node")
+	migrateManifestCmd.MarkFlagsMutuallyExclusive("indexer", "node")
+	migrateManifestCmd.Flags().UintVar(&args.expectedReplica, "expected-replica", 1, "expected number of replications to upload")
+	migrateManifestCmd.Flags().BoolVar(&args.skipTx, "skip-tx", true, "Skip sending the transaction on chain if already exists")
+	migrateManifestCmd.Flags().BoolVar(&args.finalityRequired, "finality-required", false, "Wait for file finality on nodes to upload")
+	migrateManifestCmd.Flags().UintVar(&args.taskSize, "task-size", 10, "Number of segments to upload in single rpc request")
+	migrateManifestCmd.Flags().DurationVar(&args.timeout, "timeout", 0, "cli task timeout, 0 for no timeout")
+
+	migrateManifestCmd.Flags().StringVar(&migrateManifestArgs.root, "root", "", "Merkle root of directory metadata to migrate")
+	migrateManifestCmd.MarkFlagRequired("root")
+	migrateManifestCmd.Flags().BoolVar(&migrateManifestArgs.verify, "verify", false, "Download the migrated directory metadata, and verify that all files of the old one are reachable along with the publisher if signed")
+	bindManifestKeyFlags(migrateManifestCmd, &migrateManifestArgs.manifestKey)
+	migrateManifestCmd.Flags().StringVar(&migrateManifestArgs.mappingFilename, "mapping", "", "File to write the mapping document between old and new roots in JSON, printed to stdout by default")
+
+	rootCmd.AddCommand(migrateManifestCmd)
+}
+
+func migrateManifest(*cobra.Command, []string) {
+	args := migrateManifestArgs.upload
+
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if args.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, args.timeout)
+		defer cancel()
+	}
+
+	oldRoot, err := hexutil.Decode(migrateManifestArgs.root)
+	if err != nil || len(oldRoot) != common.HashLength {
+		logrus.WithField("root", migrateManifestArgs.root).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid merkle root of directory metadata")
+	}
+
+	w3client := blockchain.MustNewWeb3(args.url, args.key, providerOption)
+	defer w3client.Close()
+
+	var fee *big.Int
+	if args.fee > 0 {
+		feeInA0GI := big.NewFloat(args.fee)
+		fee, _ = feeInA0GI.Mul(feeInA0GI, big.NewFloat(1e18)).Int(nil)
+	}
+	var nonce *big.Int
+	if args.nonce > 0 {
+		nonce = big.NewInt(int64(args.nonce))
+	}
+	finalityRequired := transfer.TransactionPacked
+	if args.finalityRequired {
+		finalityRequired = transfer.FileFinalized
+	}
+	opt := transfer.UploadOption{
+		Tags:             hexutil.MustDecode(args.tags),
+		FinalityRequired: finalityRequired,
+		TaskSize:         args.taskSize,
+		ExpectedReplica:  args.expectedReplica,
+		SkipTx:           args.skipTx,
+		Fee:              fee,
+		Nonce:            nonce,
+	}
+
+	uploader, uploaderCloser, err := newUploader(ctx, 0, args, w3client, opt)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize uploader")
+	}
+	defer uploaderCloser()
+
+	if key := mustParseManifestKey(migrateManifestArgs.manifestKey, args.key); key != nil {
+		uploader.WithManifestSigner(key)
+	}
+
+	downloader, downloaderCloser, err := newDownloader(downloadArgument{
+		indexer: args.indexer,
+		nodes:   args.node,
+	}, nil)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize downloader")
+	}
+	defer downloaderCloser()
+
+	result, err := dir.Migrate(ctx, downloader, uploader.ManifestUploader(opt), common.BytesToHash(oldRoot), dir.MigrateOption{
+		Verify: migrateManifestArgs.verify,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate directory metadata")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"oldRoot":   result.OldRoot,
+		"newRoot":   result.NewRoot,
+		"txnHash":   result.TxHash,
+		"files":     len(result.Files),
+		"unchanged": result.Unchanged,
+		"verified":  result.Verified,
+	}).Info("Directory metadata migrated")
+
+	content, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to marshal mapping document")
+	}
+
+	if len(migrateManifestArgs.mappingFilename) == 0 {
+		fmt.Println(string(content))
+	} else if err := os.WriteFile(migrateManifestArgs.mappingFilename, content, 0644); err != nil {
+		logrus.WithError(err).Fatal("Failed to write mapping document")
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func bindProfileFlag(cmd *cobra.Command, profile *string) {
+	cmd.Flags().StringVar(profile, "profile", "", "Transfer profile to start from, one of "+strings.Join(transfer.ProfileNames(), ", ")+". Explicitly specified flags take precedence")
+}
+
+// resolveUploadProfile applies the specified transfer profile to upload arguments unless flags explicitly
+// specified, and returns the resolved profile or nil if profile not specified.
+func resolveUploadProfile(cmd *cobra.Command, args *uploadArgument) *transfer.Profile {
+	if args.profile == "" {
+		return nil
+	}
+
+	profile, err := transfer.LookupProfile(args.profile)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to resolve transfer profile")
+	}
+
+	flags := cmd.Flags()
+	if !flags.Changed("routines") {
+		args.routines = profile.Routines
+	}
+	if !flags.Changed("expected-replica") {
+		args.expectedReplica = profile.Upload.ExpectedReplica
+	}
+	if !flags.Changed("skip-tx") {
+		args.skipTx = profile.Upload.SkipTx
+	}
+	if !flags.Changed("finality-required") {
+		args.finalityRequired = profile.Upload.FinalityRequired == transfer.FileFinalized
+	}
+	if !flags.Changed("verify-samples") {
+		args.verifySamples = profile.Upload.VerifySamples
+	}
+	if !flags.Changed("task-size") {
+		args.taskSize = profile.Upload.TaskSize
+	}
+
+	profile.Routines = args.routines
+
+	if err = profile.Validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid transfer settings")
+	}
+
+	return &profile
+}
+
+// resolveDownloadProfile applies the specified transfer profile to download arguments unless flags explicitly
+// specified, and returns the resolved profile or nil if profile not specified.
+func resolveDownloadProfile(cmd *cobra.Command, args *downloadArgument) *transfer.Profile {
+	if args.profile == "" {
+		return nil
+	}
+
+	profile, err := transfer.LookupProfile(args.profile)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to resolve transfer profile")
+	}
+
+	flags := cmd.Flags()
+	if !flags.Changed("routines") {
+		args.routines = profile.Routines
+	}
+	if !flags.Changed("proof") {
+		args.proof = profile.WithProof
+	}
+
+	profile.Routines = args.routines
+	profile.WithProof = args.proof
+
+	if err = profile.Validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid transfer settings")
+	}
+
+	return &profile
+}
+
+// profileField returns the resolved profile in JSON format for logging, so that it is clear what settings ran.
+func profileField(profile *transfer.Profile) string {
+	if profile == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return err.Error()
+	}
+
+	return string(data)
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// progressBarWidth is the number of cells of the terminal progress bar.
+const progressBarWidth = 30
+
+// newProgressBar returns a callback to render the progress of files uploaded or downloaded as a progress bar on
+// stderr, or nil if disabled or stderr is not a terminal, e.g. redirected to a log file.
+func newProgressBar(disabled bool) func(p transfer.Progress) {
+	if disabled || !isTerminal(os.Stderr) {
+		return nil
+	}
+
+	bar := &progressBar{out: os.Stderr}
+	logrus.AddHook(bar)
+
+	return bar.render
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progressBar rewrites a single line of terminal for the file in progress, and starts a new line for the next file,
+// e.g. the next fragment of a large file. Besides, it is a logrus hook to clear the line before logs written to
+// terminal, and the line is rendered again on the next progress.
+type progressBar struct {
+	out io.Writer
+
+	mu     sync.Mutex
+	root   common.Hash // root of file in progress
+	inline bool        // whether the line of file in progress is not terminated yet
+}
+
+// Levels implements the logrus.Hook interface.
+func (bar *progressBar) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements the logrus.Hook interface.
+func (bar *progressBar) Fire(*logrus.Entry) error {
+	bar.mu.Lock()
+	defer bar.mu.Unlock()
+
+	if bar.inline {
+		fmt.Fprint(bar.out, "\r\x1b[K")
+		bar.inline = false
+	}
+
+	return nil
+}
+
+func (bar *progressBar) render(p transfer.Progress) {
+	bar.mu.Lock()
+	defer bar.mu.Unlock()
+
+	if bar.inline && p.Root != bar.root {
+		fmt.Fprintln(bar.out)
+	}
+	bar.root, bar.inline = p.Root, true
+
+	// clear the line in case that shorter than before
+	fmt.Fprintf(bar.out, "\r\x1b[K%v", formatProgress(p))
+
+	if p.Phase == transfer.ProgressCompleted {
+		fmt.Fprintln(bar.out)
+		bar.inline = false
+	}
+}
+
+// formatProgress formats the progress in line, e.g. "0x1234…abcd [=====     ] 16.7% 2/12 segments 512.0KiB, ETA 5s".
+func formatProgress(p transfer.Progress) string {
+	root := p.Root.Hex()
+	root = root[:6] + "…" + root[len(root)-4:]
+
+	// segments to transfer unknown until transferring
+	if p.Phase == transfer.ProgressSubmitting || p.Phase == transfer.ProgressWaitingLogEntry {
+		return fmt.Sprintf("%v %v ...", root, p.Phase)
+	}
+
+	percent := p.Percent()
+	filled := min(progressBarWidth, int(percent*progressBarWidth/100))
+	line := fmt.Sprintf("%v [%v%v] %5.1f%% %v/%v segments %v", root, strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+		percent, p.CompletedSegments, p.TotalSegments, formatBytes(p.Bytes))
+
+	switch {
+	case p.Phase == transfer.ProgressCompleted:
+		return fmt.Sprintf("%v, completed in %v", line, p.Elapsed.Round(time.Millisecond))
+	case p.Phase != transfer.ProgressTransferring:
+		return fmt.Sprintf("%v, %v ...", line, p.Phase)
+	case p.ETA > 0:
+		return fmt.Sprintf("%v, ETA %v", line, p.ETA.Round(time.Second))
+	default:
+		return line
+	}
+}
+
+// formatBytes formats size in binary units with one decimal, e.g. 1.5MiB.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%vB", size)
+	}
+
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%ciB", value, "KMGT"[exp])
+}
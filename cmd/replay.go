@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"github.com/0glabs/0g-storage-client/common/replay"
+	"github.com/0glabs/0g-storage-client/common/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	replayLogFile    string
+	replayLogPayload bool
+
+	replayRecorder *replay.Recorder
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&replayLogFile, "replay-log", "", "File to record rpc metadata, options and random seeds for debugging failed transfers, where private keys and credentials are never recorded")
+	rootCmd.PersistentFlags().BoolVar(&replayLogPayload, "replay-log-payload", false, "Record file data, e.g. segments, into replay log as well")
+}
+
+// initReplayLog starts to record replay log if enabled, which should be called before any rpc client created.
+func initReplayLog() {
+	if len(replayLogFile) == 0 {
+		return
+	}
+
+	recorder, err := replay.CreateRecorder(replayLogFile, replay.RecorderOption{IncludePayload: replayLogPayload})
+	if err != nil {
+		logrus.WithError(err).WithField("file", replayLogFile).Fatal("Failed to create replay log")
+	}
+
+	recorder.Install()
+	replayRecorder = recorder
+
+	// flush replay log even if failed
+	logrus.RegisterExitHandler(closeReplayLog)
+
+	recordOption("rpc", providerOption)
+	recordOption("proxy", rpc.DefaultProxy)
+}
+
+func closeReplayLog() {
+	if replayRecorder == nil {
+		return
+	}
+
+	if err := replayRecorder.Close(); err != nil {
+		logrus.WithError(err).WithField("file", replayLogFile).Warn("Failed to write replay log")
+	}
+
+	replayRecorder = nil
+}
+
+// recordOption records the option snapshot into replay log if enabled.
+func recordOption(name string, option interface{}) {
+	if replayRecorder != nil {
+		replayRecorder.RecordOption(name, option)
+	}
+}
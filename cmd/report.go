@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/common/rpc"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/openweb3/web3go"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportSpendArgs struct {
+		url  string
+		flow string
+		node string
+
+		sender     string
+		fromBlock  uint64
+		toBlock    uint64
+		blockRange uint64
+		byTag      bool
+
+		json bool
+		csv  bool
+
+		cache string
+
+		timeout time.Duration
+	}
+
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "Report historical usage of ZeroGStorage network from chain data",
+	}
+
+	reportSpendCmd = &cobra.Command{
+		Use:   "spend",
+		Short: "Report gas costs and storage endowments paid by sender to submit files, grouped by day and optionally by tags",
+		Run:   reportSpend,
+	}
+)
+
+func init() {
+	reportSpendCmd.Flags().StringVar(&reportSpendArgs.url, "url", "", "Fullnode URL to retrieve logs of ZeroGStorage smart contract and transaction receipts")
+	reportSpendCmd.MarkFlagRequired("url")
+	reportSpendCmd.Flags().StringVar(&reportSpendArgs.flow, "flow", "", "Flow contract address")
+	reportSpendCmd.Flags().StringVar(&reportSpendArgs.node, "node", "", "ZeroGStorage storage node URL to retrieve flow contract address")
+	reportSpendCmd.MarkFlagsOneRequired("flow", "node")
+	reportSpendCmd.MarkFlagsMutuallyExclusive("flow", "node")
+
+	reportSpendCmd.Flags().StringVar(&reportSpendArgs.sender, "sender", "", "Sender address of files")
+	reportSpendCmd.MarkFlagRequired("sender")
+	reportSpendCmd.Flags().Uint64Var(&reportSpendArgs.fromBlock, "from-block", 0, "Block number to report from")
+	reportSpendCmd.Flags().Uint64Var(&reportSpendArgs.toBlock, "to-block", 0, "Block number to report to, 0 for the latest block")
+	reportSpendCmd.Flags().Uint64Var(&reportSpendArgs.blockRange, "block-range", 1000, "Number of blocks to retrieve logs in a single RPC")
+	reportSpendCmd.Flags().BoolVar(&reportSpendArgs.byTag, "by-tag", false, "Group by file tags besides day")
+
+	reportSpendCmd.Flags().BoolVar(&reportSpendArgs.json, "json", false, "Print report in JSON format, which is the default")
+	reportSpendCmd.Flags().BoolVar(&reportSpendArgs.csv, "csv", false, "Print report entries in CSV format, where amounts are in wei")
+	reportSpendCmd.MarkFlagsMutuallyExclusive("json", "csv")
+
+	reportSpendCmd.Flags().StringVar(&reportSpendArgs.cache, "cache", "", "Directory of local cache of transaction receipts to speed up re-runs")
+
+	reportSpendCmd.Flags().DurationVar(&reportSpendArgs.timeout, "timeout", 0, "cli task timeout, 0 for no timeout")
+
+	reportCmd.AddCommand(reportSpendCmd)
+	rootCmd.AddCommand(reportCmd)
+}
+
+func reportSpend(*cobra.Command, []string) {
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if reportSpendArgs.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, reportSpendArgs.timeout)
+		defer cancel()
+	}
+
+	if !common.IsHexAddress(reportSpendArgs.sender) {
+		logrus.WithField("sender", reportSpendArgs.sender).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid sender address")
+	}
+
+	w3client, err := blockchain.NewWeb3Client(reportSpendArgs.url, rpc.DefaultProxy, web3go.ClientOption{Option: providerOption})
+	if err != nil {
+		logrus.WithError(zg_common.ClassifyError(err, zg_common.ErrorClassNetwork)).WithField("url", reportSpendArgs.url).Fatal("Failed to connect to fullnode")
+	}
+	defer w3client.Close()
+
+	filter := newFlowSubmitFilter(ctx, w3client, reportSpendArgs.flow, reportSpendArgs.node)
+
+	var costs transfer.TxCostSource = transfer.NewChainTxCostSource(w3client)
+	if len(reportSpendArgs.cache) > 0 {
+		cache, err := transfer.OpenTxCostCache(reportSpendArgs.cache, costs)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to open local cache")
+		}
+		defer cache.Close()
+
+		costs = cache
+	}
+
+	report, err := transfer.ReportSpend(ctx, filter, costs, transfer.SpendQuery{
+		Sender:     common.HexToAddress(reportSpendArgs.sender),
+		FromBlock:  reportSpendArgs.fromBlock,
+		ToBlock:    reportSpendArgs.toBlock,
+		BlockRange: reportSpendArgs.blockRange,
+		ByTag:      reportSpendArgs.byTag,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to report spend")
+	}
+
+	if reportSpendArgs.csv {
+		if err = report.WriteCSV(os.Stdout); err != nil {
+			logrus.WithError(err).Fatal("Failed to print report")
+		}
+
+		return
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to marshal report")
+	}
+
+	fmt.Println(string(content))
+}
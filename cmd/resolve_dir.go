@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resolveDirArgs struct {
+		url  string
+		flow string
+
+		nodes   []string
+		indexer string
+
+		publisher       string
+		name            string
+		atBlock         uint64
+		fromBlock       uint64
+		blockRange      uint64
+		maxManifestSize zg_common.ByteSize
+
+		timeout time.Duration
+	}
+
+	resolveDirCmd = &cobra.Command{
+		Use:   "resolve-dir",
+		Short: "Resolve the directory published by address as of a block from ZeroGStorage network",
+		Run:   resolveDir,
+	}
+)
+
+func init() {
+	resolveDirCmd.Flags().StringVar(&resolveDirArgs.url, "url", "", "Fullnode URL to retrieve logs of ZeroGStorage smart contract, which requires an archive fullnode for old blocks")
+	resolveDirCmd.MarkFlagRequired("url")
+	resolveDirCmd.Flags().StringVar(&resolveDirArgs.flow, "flow", "", "Flow contract address, retrieved from the first storage node if not specified")
+
+	resolveDirCmd.Flags().StringSliceVar(&resolveDirArgs.nodes, "node", []string{}, "ZeroGStorage storage node URL. Multiple nodes could be specified and separated by comma, e.g. url1,url2,url3")
+	resolveDirCmd.Flags().StringVar(&resolveDirArgs.indexer, "indexer", "", "ZeroGStorage indexer URL")
+	resolveDirCmd.MarkFlagsOneRequired("indexer", "node")
+	resolveDirCmd.MarkFlagsOneRequired("flow", "node")
+
+	resolveDirCmd.Flags().StringVar(&resolveDirArgs.publisher, "publisher", "", "Address that published the directory")
+	resolveDirCmd.MarkFlagRequired("publisher")
+	resolveDirCmd.Flags().StringVar(&resolveDirArgs.name, "name", "", "Name or HEX tags with 0x prefix of the directory, which matches the tags of submission exactly")
+	resolveDirCmd.Flags().Uint64Var(&resolveDirArgs.atBlock, "at-block", 0, "Block number to resolve the directory as of")
+	resolveDirCmd.MarkFlagRequired("at-block")
+	resolveDirCmd.Flags().Uint64Var(&resolveDirArgs.fromBlock, "from-block", 0, "Earliest block number to scan")
+	resolveDirCmd.Flags().Uint64Var(&resolveDirArgs.blockRange, "block-range", 1000, "Number of blocks to retrieve logs in a single RPC")
+	resolveDirCmd.Flags().Var(&resolveDirArgs.maxManifestSize, "max-manifest-size", "Files larger than this are not regarded as directory manifest, e.g. 16MiB, 0 for no limit")
+
+	resolveDirCmd.Flags().DurationVar(&resolveDirArgs.timeout, "timeout", 0, "cli task timeout, 0 for no timeout")
+
+	rootCmd.AddCommand(resolveDirCmd)
+}
+
+func resolveDir(*cobra.Command, []string) {
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if resolveDirArgs.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, resolveDirArgs.timeout)
+		defer cancel()
+	}
+
+	if !common.IsHexAddress(resolveDirArgs.publisher) {
+		logrus.WithField("publisher", resolveDirArgs.publisher).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid publisher address")
+	}
+
+	var nodeURL string
+	if len(resolveDirArgs.flow) == 0 {
+		nodeURL = resolveDirArgs.nodes[0]
+	}
+
+	filter, filterCloser := newSubmitLogFilter(ctx, resolveDirArgs.url, resolveDirArgs.flow, nodeURL)
+	defer filterCloser()
+
+	downloader, closer, err := newDownloader(downloadArgument{
+		indexer: resolveDirArgs.indexer,
+		nodes:   resolveDirArgs.nodes,
+	}, nil)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize downloader")
+	}
+	defer closer()
+
+	tree, provenance, err := dir.ResolveAt(ctx, transfer.NewSubmissionLog(filter), downloader,
+		common.HexToAddress(resolveDirArgs.publisher), resolveDirArgs.name, resolveDirArgs.atBlock, dir.ResolveOption{
+			FromBlock:       resolveDirArgs.fromBlock,
+			BlockRange:      resolveDirArgs.blockRange,
+			MaxManifestSize: uint64(resolveDirArgs.maxManifestSize),
+		})
+	var skipped *dir.SkippedSubmissionsError
+	if errors.As(err, &skipped) && tree != nil {
+		for _, s := range skipped.Skipped {
+			logrus.WithError(s.Err).WithFields(logrus.Fields{
+				"root":  s.Submission.Root,
+				"txSeq": s.Submission.TxSeq,
+				"block": s.Submission.BlockNumber,
+			}).Warn("Skipped newer submission failed to resolve")
+		}
+	} else if err != nil {
+		logrus.WithError(err).Fatal("Failed to resolve directory")
+	}
+
+	content, err := json.MarshalIndent(struct {
+		Provenance *dir.Submission `json:"provenance"`
+		Tree       *dir.FsNode     `json:"tree"`
+	}{provenance, tree}, "", "  ")
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to marshal directory")
+	}
+
+	fmt.Println(string(content))
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 	"time"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/common/rpc"
 	"github.com/mcuadros/go-defaults"
 	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
 	"github.com/sirupsen/logrus"
@@ -15,6 +17,7 @@ import (
 var (
 	logLevel         string
 	logColorDisabled bool
+	logFormat        string
 
 	providerOption providers.Option
 
@@ -24,6 +27,15 @@ var (
 		PersistentPreRun: func(*cobra.Command, []string) {
 			initLog()
 			defaults.SetDefaults(&providerOption)
+
+			if err := rpc.DefaultProxy.Validate(); err != nil {
+				logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid proxy settings")
+			}
+
+			initReplayLog()
+		},
+		PersistentPostRun: func(*cobra.Command, []string) {
+			closeReplayLog()
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Help()
@@ -34,30 +46,46 @@ var (
 func init() {
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", logrus.InfoLevel.String(), "Log level")
 	rootCmd.PersistentFlags().BoolVar(&logColorDisabled, "log-color-disabled", false, "Force to disable colorful logs")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format, text or json, and the fatal log carries the errorClass field")
 	rootCmd.PersistentFlags().Uint64Var(&blockchain.CustomGasPrice, "gas-price", 0, "Custom gas price to send transaction")
 	rootCmd.PersistentFlags().Uint64Var(&blockchain.CustomGasLimit, "gas-limit", 0, "Custom gas limit to send transaction")
 	rootCmd.PersistentFlags().BoolVar(&blockchain.Web3LogEnabled, "web3-log-enabled", false, "Enable log for web3 RPC")
 	rootCmd.PersistentFlags().IntVar(&providerOption.RetryCount, "rpc-retry-count", 5, "Retry count for rpc request")
 	rootCmd.PersistentFlags().DurationVar(&providerOption.RetryInterval, "rpc-retry-interval", 5*time.Second, "Retry interval for rpc request")
 	rootCmd.PersistentFlags().DurationVar(&providerOption.RequestTimeout, "rpc-timeout", 30*time.Second, "Timeout for single rpc request")
+	rootCmd.PersistentFlags().Float64Var(&blockchain.DefaultRateLimit.Rate, "rpc-rate-limit", 0, "Max requests per second to fullnode shared by all contract interactions, 0 for unlimited")
+	rootCmd.PersistentFlags().IntVar(&blockchain.DefaultRateLimit.Burst, "rpc-rate-burst", 1, "Max requests sent to fullnode at once without waiting for rate limit")
+	rootCmd.PersistentFlags().StringVar(&rpc.DefaultProxy.URL, "proxy", "", "Proxy URL for rpc requests, e.g. socks5://127.0.0.1:1080 or http://127.0.0.1:3128, which overrides HTTP_PROXY and HTTPS_PROXY environment variables")
+	rootCmd.PersistentFlags().BoolVar(&rpc.DefaultProxy.Direct, "no-proxy", false, "Connect to rpc servers directly, ignoring proxy environment variables")
+	rootCmd.PersistentFlags().StringVar(&rpc.DefaultProxy.DoH, "doh", "", "DNS-over-HTTPS resolver URL to resolve hostnames of rpc servers, e.g. https://1.1.1.1/dns-query")
+
+	rootCmd.Version = zg_common.Version()
+	rootCmd.SetUsageTemplate(rootCmd.UsageTemplate() + exitCodesHelp)
 }
 
 func initLog() {
-	formatter := logrus.TextFormatter{
-		FullTimestamp: true,
-	}
+	switch logFormat {
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		formatter := logrus.TextFormatter{
+			FullTimestamp: true,
+		}
 
-	if logColorDisabled {
-		formatter.DisableColors = true
-	} else {
-		formatter.ForceColors = true
-	}
+		if logColorDisabled {
+			formatter.DisableColors = true
+		} else {
+			formatter.ForceColors = true
+		}
 
-	logrus.SetFormatter(&formatter)
+		logrus.SetFormatter(&formatter)
+	default:
+		logrus.WithField("format", logFormat).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid log format")
+	}
 
 	level, err := logrus.ParseLevel(logLevel)
 	if err != nil {
-		logrus.WithError(err).WithField("level", logLevel).Fatal("Failed to parse log level")
+		logrus.WithError(err).WithField("level", logLevel).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Failed to parse log level")
 	}
 
 	logrus.SetLevel(level)
@@ -65,8 +93,15 @@ func initLog() {
 
 // Execute is the command line entrypoint.
 func Execute() {
+	// errors returned by cobra are usage errors, e.g. unknown command or invalid flags, while other errors are
+	// reported by fatal log, which exits with the classified exit code
 	if err := rootCmd.Execute(); err != nil {
+		if logFormat == "json" {
+			logrus.SetFormatter(&logrus.JSONFormatter{})
+			logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid usage")
+		}
+
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(ExitUsage)
 	}
 }
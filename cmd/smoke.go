@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/indexer"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/smoke"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// smokeConfig is the config file of network to run smoke test against, e.g.
+//
+//	url: https://evmrpc-testnet.0g.ai
+//	indexer: https://indexer-storage-testnet-turbo.0g.ai
+//	kvNode: http://127.0.0.1:6789
+//	stream: my-smoke-stream
+type smokeConfig struct {
+	URL     string   `yaml:"url"`     // fullnode URL
+	Key     string   `yaml:"key"`     // private key to send transactions, which could be specified by --key instead
+	Indexer string   `yaml:"indexer"` // indexer URL to select storage nodes
+	Nodes   []string `yaml:"nodes"`   // storage node URLs if indexer not specified
+	KvNode  string   `yaml:"kvNode"`  // KV node URL
+	Stream  string   `yaml:"stream"`  // name of KV stream to write test keys, optional
+}
+
+func loadSmokeConfig(path string) (smokeConfig, error) {
+	var config smokeConfig
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return config, errors.WithMessagef(err, "failed to read config file %v", path)
+	}
+
+	if err = yaml.Unmarshal(content, &config); err != nil {
+		return config, errors.WithMessagef(err, "failed to parse config file %v", path)
+	}
+
+	switch {
+	case len(config.URL) == 0:
+		return config, errors.New("url not specified in config file")
+	case len(config.Indexer) == 0 && len(config.Nodes) == 0:
+		return config, errors.New("at least one of indexer and nodes should be specified in config file")
+	case len(config.KvNode) == 0:
+		return config, errors.New("kvNode not specified in config file")
+	}
+
+	return config, nil
+}
+
+var (
+	smokeArgs struct {
+		config string
+		key    string
+		budget float64
+		stream string
+
+		fileSize    zg_common.ByteSize
+		stepTimeout time.Duration
+		output      string
+
+		timeout time.Duration
+	}
+
+	smokeCmd = &cobra.Command{
+		Use:   "smoke",
+		Short: "Run end to end smoke test against a live network with a strict budget cap",
+		Long: `Run end to end smoke test against a live network: upload a small file of random content, wait for file
+finalized, download back and compare, then write a random key to KV stream and read back, and clean up local files.
+
+Transactions that may cost more than the remaining budget are not sent. A machine-readable report of each step is
+printed in JSON format, along with the root and transaction hash involved, and the command fails if any step failed.`,
+		Run: runSmoke,
+	}
+)
+
+func init() {
+	smokeCmd.Flags().StringVar(&smokeArgs.config, "config", "", "Config file in YAML format of network, including url, key, indexer or nodes, kvNode and stream")
+	smokeCmd.MarkFlagRequired("config")
+	smokeCmd.Flags().StringVar(&smokeArgs.key, "key", "", "Private key to send transactions, which overrides the one in config file")
+	smokeCmd.Flags().Float64Var(&smokeArgs.budget, "budget", 0, "Max fee paid in a0gi of all transactions, including gas and storage fee")
+	smokeCmd.MarkFlagRequired("budget")
+	smokeCmd.Flags().StringVar(&smokeArgs.stream, "stream", "", "Name of KV stream to write test keys, which overrides the one in config file")
+
+	smokeCmd.Flags().Var(&smokeArgs.fileSize, "file-size", "Size of random file to upload, e.g. 4KiB, 1KiB if not specified")
+	smokeCmd.Flags().DurationVar(&smokeArgs.stepTimeout, "step-timeout", 10*time.Minute, "Timeout of each step")
+	smokeCmd.Flags().StringVar(&smokeArgs.output, "output", "", "File to write report, which is printed to stdout if not specified")
+
+	smokeCmd.Flags().DurationVar(&smokeArgs.timeout, "timeout", 0, "cli task timeout, 0 for no timeout")
+
+	rootCmd.AddCommand(smokeCmd)
+}
+
+func runSmoke(*cobra.Command, []string) {
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if smokeArgs.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, smokeArgs.timeout)
+		defer cancel()
+	}
+
+	config, err := loadSmokeConfig(smokeArgs.config)
+	if err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid config file")
+	}
+
+	if len(smokeArgs.key) > 0 {
+		config.Key = smokeArgs.key
+	}
+	if len(config.Key) == 0 {
+		logrus.WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Private key not specified in config file or by --key")
+	}
+	if len(smokeArgs.stream) > 0 {
+		config.Stream = smokeArgs.stream
+	}
+
+	budgetInA0GI := big.NewFloat(smokeArgs.budget)
+	budget, _ := budgetInA0GI.Mul(budgetInA0GI, big.NewFloat(1e18)).Int(nil)
+	opt := smoke.Option{
+		Budget:      budget,
+		FileSize:    int64(smokeArgs.fileSize),
+		StreamName:  config.Stream,
+		StepTimeout: smokeArgs.stepTimeout,
+	}
+	if err = opt.Validate(); err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid smoke test option")
+	}
+
+	w3client := blockchain.MustNewWeb3(config.URL, config.Key, providerOption)
+	defer w3client.Close()
+
+	var clients []*node.ZgsClient
+	if len(config.Indexer) > 0 {
+		indexerClient, err := indexer.NewClient(config.Indexer, indexer.IndexerClientOption{
+			ProviderOption: providerOption,
+			LogOption:      zg_common.LogOption{Logger: logrus.StandardLogger()},
+		})
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to initialize indexer client")
+		}
+		defer indexerClient.Close()
+
+		if clients, err = indexerClient.SelectNodes(ctx, 0, 1, []string{}); err != nil {
+			logrus.WithError(zg_common.ClassifyError(err, zg_common.ErrorClassNetwork)).Fatal("Failed to select nodes from indexer")
+		}
+	} else {
+		clients = node.MustNewZgsClients(config.Nodes, providerOption)
+	}
+	for _, client := range clients {
+		defer client.Close()
+	}
+
+	kvClient := node.MustNewKvClient(config.KvNode, providerOption)
+	defer kvClient.Close()
+
+	report, err := smoke.Run(ctx, smoke.Network{
+		W3Client: w3client,
+		Clients:  clients,
+		KvClient: kvClient,
+	}, opt)
+	if report == nil {
+		logrus.WithError(err).Fatal("Failed to run smoke test")
+	}
+
+	content, marshalErr := json.MarshalIndent(report, "", "  ")
+	if marshalErr != nil {
+		logrus.WithError(marshalErr).Fatal("Failed to marshal report")
+	}
+
+	if len(smokeArgs.output) > 0 {
+		if writeErr := os.WriteFile(smokeArgs.output, content, 0644); writeErr != nil {
+			logrus.WithError(writeErr).Fatal("Failed to write report")
+		}
+	} else {
+		fmt.Println(string(content))
+	}
+
+	if err != nil {
+		logrus.WithError(err).Fatal("Smoke test failed")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"elapsed": report.Elapsed,
+		"spent":   report.Spent,
+	}).Info("Smoke test passed")
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncArgs struct {
+		uploadArgument
+
+		name            string
+		watch           bool
+		debounce        time.Duration
+		verifyInterval  time.Duration
+		abortOnShutdown bool
+	}
+
+	syncCmd = &cobra.Command{
+		Use:   "sync <directory>",
+		Short: "Upload directory, and keep publishing changes of directory if --watch specified",
+		Args:  cobra.ExactArgs(1),
+		Run:   syncDir,
+	}
+)
+
+func init() {
+	bindUploadFlags(syncCmd, &syncArgs.uploadArgument)
+	syncCmd.Flags().StringVar(&syncArgs.url, "url", "", "Fullnode URL to interact with ZeroGStorage smart contract")
+	syncCmd.MarkFlagRequired("url")
+	syncCmd.Flags().StringVar(&syncArgs.key, "key", "", "Private key to interact with smart contract")
+	syncCmd.MarkFlagRequired("key")
+
+	bindDirUploaderFlags(syncCmd)
+
+	syncCmd.Flags().StringVar(&syncArgs.name, "name", "", "Name or HEX tags with 0x prefix to publish directory metadata under, which overrides --tags, see resolve-dir")
+	syncCmd.Flags().BoolVar(&syncArgs.watch, "watch", false, "Keep watching the directory to publish changes until interrupted")
+	syncCmd.Flags().DurationVar(&syncArgs.debounce, "debounce", transfer.DefaultSyncDebounce, "Quiet period to coalesce bursts of changes before publishing")
+	syncCmd.Flags().DurationVar(&syncArgs.verifyInterval, "verify-interval", time.Hour, "Interval to hash the whole directory again to publish changes missed by watcher, 0 to disable")
+	syncCmd.Flags().BoolVar(&syncArgs.abortOnShutdown, "abort-on-shutdown", false, "Abort the in-flight publish when interrupted instead of waiting for it to complete")
+
+	rootCmd.AddCommand(syncCmd)
+}
+
+func syncDir(cmd *cobra.Command, args []string) {
+	profile := resolveUploadProfile(cmd, &syncArgs.uploadArgument)
+	retention := syncArgs.mustLoadRetention()
+	syncArgs.file = args[0]
+
+	// stops watching when interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if syncArgs.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, syncArgs.timeout)
+		defer cancel()
+	}
+
+	w3client := blockchain.MustNewWeb3(syncArgs.url, syncArgs.key, providerOption)
+	defer w3client.Close()
+
+	finalityRequired := transfer.TransactionPacked
+	if syncArgs.finalityRequired {
+		finalityRequired = transfer.FileFinalized
+	}
+	var reporter interface{} // uploader to report warnings once created
+	opt := transfer.SyncOption{
+		Upload: transfer.UploadOption{
+			Tags:             hexutil.MustDecode(syncArgs.tags),
+			FinalityRequired: finalityRequired,
+			TaskSize:         syncArgs.taskSize,
+			ExpectedReplica:  syncArgs.expectedReplica,
+			ShardReplicas:    mustParseShardReplicas(syncArgs.shardReplicas),
+			SkipTx:           syncArgs.skipTx,
+			SkipPreflight:    syncArgs.skipPreflight,
+			VerifySamples:    syncArgs.verifySamples,
+		},
+		Name:            syncArgs.name,
+		Watch:           syncArgs.watch,
+		Debounce:        syncArgs.debounce,
+		VerifyInterval:  syncArgs.verifyInterval,
+		AbortOnShutdown: syncArgs.abortOnShutdown,
+		OnPublish: func(result transfer.SyncResult) {
+			// nothing published if no changes
+			if result.Err == nil && result.Root != (common.Hash{}) {
+				fmt.Println(result.Root.Hex())
+			}
+
+			// warnings of each publish reported separately
+			reportWarnings(reporter, syncArgs.failOnWarning)
+		},
+	}
+	syncArgs.applyRetention(&opt.Upload)
+	if err := opt.Validate(); err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid sync option")
+	}
+
+	uploader, closer, err := newUploader(ctx, 0, syncArgs.uploadArgument, w3client, opt.Upload)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize uploader")
+	}
+	defer closer()
+	reporter = uploader
+	applyUploaderProfile(uploader, profile, syncArgs.routines, opt.Upload)
+	applyUploaderLedger(ctx, uploader, syncArgs.ledger)
+	uploader.WithHashOption(syncArgs.hashOption())
+	uploader.WithMmap(syncArgs.mmap)
+	uploader.WithRetentionPolicies(retention)
+	applyDirUploaderFlags(uploader, syncArgs.file, syncArgs.key)
+
+	if err := transfer.Sync(ctx, uploader, syncArgs.file, opt); err != nil {
+		logrus.WithError(err).Fatal("Failed to sync directory")
+	}
+	reportWarnings(uploader, syncArgs.failOnWarning)
+
+	logrus.WithField("profile", profileField(uploader.Profile())).Info("Directory sync done")
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	treeArgs struct {
+		maxDepth   int
+		ndjson     bool
+		hideHashes bool
+		sortSize   bool
+	}
+
+	treeCmd = &cobra.Command{
+		Use:   "tree <directory>",
+		Short: "Print the file tree of directory to upload along with sizes and merkle roots",
+		Args:  cobra.ExactArgs(1),
+		Run:   printTree,
+	}
+)
+
+func init() {
+	treeCmd.Flags().IntVar(&treeArgs.maxDepth, "max-depth", 0, "Print entries at most the depth below the directory, 0 for no limit")
+	treeCmd.Flags().BoolVar(&treeArgs.ndjson, "ndjson", false, "Print one entry in JSON per line instead of tree view, e.g. to pipe into jq")
+	treeCmd.Flags().BoolVar(&treeArgs.hideHashes, "hide-hashes", false, "Do not print merkle roots of files")
+	treeCmd.Flags().BoolVar(&treeArgs.sortSize, "sort-size", false, "Sort entries by size in descending order instead of by name")
+
+	treeCmd.Flags().StringSliceVar(&ignoreArgs.patterns, "ignore", nil, "Gitignore-style patterns of files not to upload, e.g. .git/,node_modules/")
+	treeCmd.Flags().BoolVar(&ignoreArgs.file, "ignore-file", true, "Ignore files matching the patterns in "+dir.IgnoreFileName+" at the root of directory")
+
+	rootCmd.AddCommand(treeCmd)
+}
+
+func printTree(_ *cobra.Command, args []string) {
+	if treeArgs.maxDepth < 0 {
+		logrus.WithField("maxDepth", treeArgs.maxDepth).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid --max-depth")
+	}
+
+	folder := args[0]
+	// file names not portable are rejected on upload by default, but only warned here to print anyway
+	root, err := dir.BuildFileTreeWithOption(folder, dir.BuildOption{
+		Ignore:        mustLoadIgnorePatterns(folder),
+		PortableNames: dir.PortableNamesWarn,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to build file tree")
+	}
+	root.Name = folder
+
+	opts := dir.RenderOptions{
+		Format:     dir.RenderFormatTree,
+		MaxDepth:   treeArgs.maxDepth,
+		HideHashes: treeArgs.hideHashes,
+		SortBySize: treeArgs.sortSize,
+	}
+	if treeArgs.ndjson {
+		opts.Format = dir.RenderFormatNDJSON
+	}
+
+	if err := root.Render(os.Stdout, opts); err != nil {
+		logrus.WithError(err).Fatal("Failed to print file tree")
+	}
+}
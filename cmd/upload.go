@@ -9,6 +9,7 @@ import (
 
 	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/common/shard"
 	"github.com/0glabs/0g-storage-client/core"
 	"github.com/0glabs/0g-storage-client/indexer"
 	"github.com/0glabs/0g-storage-client/node"
@@ -42,28 +43,48 @@ func bindTransactionFlags(cmd *cobra.Command, args *transactionArgument) {
 type uploadArgument struct {
 	transactionArgument
 
-	file string
-	tags string
+	file       string
+	tags       string
+	resumeFile string
+	noProgress bool
+
+	retention       string
+	retentionConfig string
+	retentionCheck  string
 
 	node    []string
 	indexer string
 
 	expectedReplica uint
+	shardReplicas   []string
 
 	skipTx           bool
+	skipPreflight    bool
 	finalityRequired bool
+	verifySamples    uint
 	taskSize         uint
 	routines         int
 
-	fragmentSize int64
+	fragmentSize zg_common.ByteSize
+
+	hashBufferSize zg_common.ByteSize
+	hashReadahead  int
+	mmap           bool
+
+	failOnWarning []string
+
+	profile string
+	ledger  string
 
 	timeout time.Duration
 }
 
 func bindUploadFlags(cmd *cobra.Command, args *uploadArgument) {
-	cmd.Flags().StringVar(&args.file, "file", "", "File name to upload")
-	cmd.MarkFlagRequired("file")
 	cmd.Flags().StringVar(&args.tags, "tags", "0x", "Tags of the file")
+	cmd.Flags().StringVar(&args.retention, "retention", "", "Retention policy to tag the submission, which is defined in --retention-config")
+	cmd.Flags().StringVar(&args.retentionConfig, "retention-config", "", "JSON file of retention policies by name, e.g. {\"hot\": {\"tags\": \"0x01\", \"class\": \"hot\"}}")
+	cmd.Flags().StringVar(&args.retentionCheck, "retention-check", string(transfer.RetentionCheckNone), "Policy to check the retention class reported by storage nodes once finalized, one of none, warn and fail")
+	cmd.MarkFlagsRequiredTogether("retention", "retention-config")
 
 	cmd.Flags().StringSliceVar(&args.node, "node", []string{}, "ZeroGStorage storage node URL")
 	cmd.Flags().StringVar(&args.indexer, "indexer", "", "ZeroGStorage indexer URL")
@@ -71,15 +92,31 @@ func bindUploadFlags(cmd *cobra.Command, args *uploadArgument) {
 	cmd.MarkFlagsMutuallyExclusive("indexer", "node")
 
 	cmd.Flags().UintVar(&args.expectedReplica, "expected-replica", 1, "expected number of replications to upload")
+	cmd.Flags().StringSliceVar(&args.shardReplicas, "shard-replica", []string{}, "Expected number of replications for specific shards on top of --expected-replica, in format <shardId>/<numShard>=<replica>, e.g. 1/4=3")
 
 	cmd.Flags().BoolVar(&args.skipTx, "skip-tx", true, "Skip sending the transaction on chain if already exists")
+	cmd.Flags().BoolVar(&args.skipPreflight, "skip-preflight", false, "Skip checks of max file size of network and balance of account before uploading")
 	cmd.Flags().BoolVar(&args.finalityRequired, "finality-required", false, "Wait for file finality on nodes to upload")
+	cmd.Flags().UintVar(&args.verifySamples, "verify-samples", 0, "Number of segments sampled on each storage node to verify with merkle proof once uploaded, 0 to disable")
 	cmd.Flags().UintVar(&args.taskSize, "task-size", 10, "Number of segments to upload in single rpc request")
 
-	cmd.Flags().Int64Var(&args.fragmentSize, "fragment-size", 1024*1024*1024*4, "the size of fragment to split into when file is too large")
+	args.fragmentSize = 4 * zg_common.GiB
+	cmd.Flags().Var(&args.fragmentSize, "fragment-size", "the size of fragment to split into when file is too large, e.g. 4GiB")
 
 	cmd.Flags().IntVar(&args.routines, "routines", runtime.GOMAXPROCS(0), "number of go routines for uploading simutanously")
 
+	args.hashBufferSize = core.DefaultSegmentSize
+	cmd.Flags().Var(&args.hashBufferSize, "hash-buffer-size", "the size to read file at a time when calculating merkle root, e.g. 16MiB for spinning disks")
+	cmd.Flags().IntVar(&args.hashReadahead, "hash-readahead", 0, "number of buffers to prefetch to read file sequentially, e.g. 4 for spinning disks, 0 to read in parallel")
+	cmd.Flags().BoolVar(&args.mmap, "mmap", false, "map files into memory to read with fewer syscalls and copies, e.g. for huge files on fast disks")
+
+	cmd.Flags().StringSliceVar(&args.failOnWarning, "fail-on-warning", []string{}, "Warning codes to be treated as failure, e.g. SEGMENT_RETRIED,SUBMIT_RETRIED")
+
+	bindProfileFlag(cmd, &args.profile)
+
+	cmd.Flags().StringVar(&args.ledger, "ledger", "", "Directory of local ledger to record uploads, "+transfer.DefaultLedgerDir()+" if specified without value, disabled if not specified")
+	cmd.Flags().Lookup("ledger").NoOptDefVal = transfer.DefaultLedgerDir()
+
 	cmd.Flags().DurationVar(&args.timeout, "timeout", 0, "cli task timeout, 0 for no timeout")
 }
 
@@ -95,12 +132,20 @@ var (
 
 func init() {
 	bindUploadFlags(uploadCmd, &uploadArgs)
+	uploadCmd.Flags().StringVar(&uploadArgs.file, "file", "", "File name to upload")
+	uploadCmd.MarkFlagRequired("file")
+	uploadCmd.Flags().Lookup("file").Usage = "File name to upload, or HTTP URL that supports range requests"
+	uploadCmd.Flags().StringVar(&uploadArgs.resumeFile, "resume-file", "", "Checkpoint file to resume the upload if interrupted, which is removed once completed, and ignored for files larger than --fragment-size")
+	uploadCmd.Flags().BoolVar(&uploadArgs.noProgress, "no-progress", false, "Do not show the progress bar, which is shown only if stderr is a terminal")
 	bindTransactionFlags(uploadCmd, &uploadArgs.transactionArgument)
 
 	rootCmd.AddCommand(uploadCmd)
 }
 
-func upload(*cobra.Command, []string) {
+func upload(cmd *cobra.Command, _ []string) {
+	profile := resolveUploadProfile(cmd, &uploadArgs)
+	retention := uploadArgs.mustLoadRetention()
+
 	ctx := context.Background()
 	var cancel context.CancelFunc
 	if uploadArgs.timeout > 0 {
@@ -129,12 +174,20 @@ func upload(*cobra.Command, []string) {
 		FinalityRequired: finalityRequired,
 		TaskSize:         uploadArgs.taskSize,
 		ExpectedReplica:  uploadArgs.expectedReplica,
+		ShardReplicas:    mustParseShardReplicas(uploadArgs.shardReplicas),
 		SkipTx:           uploadArgs.skipTx,
+		SkipPreflight:    uploadArgs.skipPreflight,
+		VerifySamples:    uploadArgs.verifySamples,
 		Fee:              fee,
 		Nonce:            nonce,
+		ResumeFile:       uploadArgs.resumeFile,
 	}
 
-	file, err := core.Open(uploadArgs.file)
+	uploadArgs.applyRetention(&opt)
+
+	recordOption("upload", opt)
+
+	file, err := openUploadFile(ctx, uploadArgs.file, uploadArgs.mmap)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to open file")
 	}
@@ -145,21 +198,156 @@ func upload(*cobra.Command, []string) {
 		logrus.WithError(err).Fatal("Failed to initialize uploader")
 	}
 	defer closer()
-	uploader.WithRoutines(uploadArgs.routines)
+	applyUploaderProfile(uploader, profile, uploadArgs.routines, opt)
+	applyUploaderLedger(ctx, uploader, uploadArgs.ledger)
+	uploader.WithHashOption(uploadArgs.hashOption())
+	uploader.WithRetentionPolicies(retention)
+	uploader.WithProgress(newProgressBar(uploadArgs.noProgress))
 
-	_, roots, err := uploader.SplitableUpload(ctx, file, uploadArgs.fragmentSize, opt)
+	_, roots, err := uploader.SplitableUpload(transfer.WithLedgerPaths(ctx, uploadArgs.file), file, int64(uploadArgs.fragmentSize), opt)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to upload file")
 	}
+	reportWarnings(uploader, uploadArgs.failOnWarning)
+	logger := logrus.WithFields(logrus.Fields{
+		"profile":   profileField(uploader.Profile()),
+		"footprint": fragmentsFootprint(file.Size(), int64(uploadArgs.fragmentSize)),
+	})
 	if len(roots) == 1 {
-		logrus.Infof("file uploaded, root = %v", roots[0])
+		logger.Infof("file uploaded, root = %v", roots[0])
 	} else {
 		s := make([]string, len(roots))
 		for i, root := range roots {
 			s[i] = root.String()
 		}
-		logrus.Infof("file uploaded in %v fragments, roots = %v", len(roots), strings.Join(s, ","))
+		logger.Infof("file uploaded in %v fragments, roots = %v", len(roots), strings.Join(s, ","))
+	}
+}
+
+// fragmentsFootprint returns the storage footprint of file of size uploaded in fragments as
+// transfer.Uploader.SplitableUpload does, where each fragment is padded separately.
+func fragmentsFootprint(size, fragmentSize int64) core.Footprint {
+	fragmentSize = int64(core.NextPow2(uint64(max(fragmentSize, core.DefaultChunkSize))))
+
+	var footprint core.Footprint
+	for ; size > fragmentSize; size -= fragmentSize {
+		footprint = footprint.Add(core.StorageFootprint(fragmentSize))
 	}
+
+	return footprint.Add(core.StorageFootprint(size))
+}
+
+// uploadFile is the data to upload, which is a file on disk or an HTTP URL.
+type uploadFile interface {
+	core.IterableData
+	Close() error
+}
+
+// openUploadFile opens the file on disk, or the HTTP URL that supports range requests, so that data need not be
+// spooled on local disk before uploading. File on disk is memory-mapped if mmap enabled.
+func openUploadFile(ctx context.Context, name string, mmap bool) (uploadFile, error) {
+	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+		return core.OpenURL(ctx, name, core.HTTPOption{Prefetch: 4})
+	}
+
+	if mmap {
+		return core.NewDataInFileMmap(name)
+	}
+
+	return core.Open(name)
+}
+
+// mustParseShardReplicas parses the shard replicas of --shard-replica flag.
+func mustParseShardReplicas(values []string) []shard.ShardReplica {
+	var replicas []shard.ShardReplica
+	for _, value := range values {
+		sr, err := shard.ParseShardReplica(value)
+		if err != nil {
+			logrus.WithError(err).Fatal("Failed to parse --shard-replica")
+		}
+		replicas = append(replicas, sr)
+	}
+
+	return replicas
+}
+
+// hashOption returns the option to read file when calculating merkle root.
+func (args *uploadArgument) hashOption() core.HashOption {
+	opt := core.HashOption{
+		BufferSize: int64(args.hashBufferSize),
+		Readahead:  args.hashReadahead,
+	}
+
+	if err := opt.Validate(); err != nil {
+		logrus.WithError(err).Fatal("Invalid hash option")
+	}
+
+	return opt
+}
+
+// mustLoadRetention loads the retention policies if configured, and validates the retention flags, so that unknown
+// policy fails before connecting to the network.
+func (args *uploadArgument) mustLoadRetention() transfer.RetentionPolicies {
+	opt := transfer.UploadOption{FinalityRequired: transfer.TransactionPacked}
+	if args.finalityRequired {
+		opt.FinalityRequired = transfer.FileFinalized
+	}
+	args.applyRetention(&opt)
+	if err := opt.Validate(); err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid retention option")
+	}
+
+	if len(args.retentionConfig) == 0 {
+		return nil
+	}
+
+	policies, err := transfer.LoadRetentionPolicies(args.retentionConfig)
+	if err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Failed to load retention policies")
+	}
+
+	if len(args.retention) > 0 {
+		if _, err = policies.Lookup(args.retention); err != nil {
+			logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid retention option")
+		}
+	}
+
+	return policies
+}
+
+// applyRetention sets the retention policy of option.
+func (args *uploadArgument) applyRetention(opt *transfer.UploadOption) {
+	opt.Retention = args.retention
+	opt.RetentionCheck = transfer.RetentionCheck(args.retentionCheck)
+}
+
+// applyUploaderProfile applies the resolved profile if any, otherwise only routines.
+func applyUploaderProfile(uploader *transfer.Uploader, profile *transfer.Profile, routines int, opt transfer.UploadOption) {
+	if profile == nil {
+		uploader.WithRoutines(routines)
+		return
+	}
+
+	profile.Upload = opt
+	uploader.WithProfile(*profile)
+
+	logrus.WithField("profile", profileField(uploader.Profile())).Info("Transfer settings resolved")
+}
+
+// applyUploaderLedger records uploads in the local ledger if specified, and uploads are not blocked if failed to
+// open the ledger.
+func applyUploaderLedger(ctx context.Context, uploader *transfer.Uploader, dir string) {
+	if len(dir) == 0 {
+		return
+	}
+
+	ledger, err := transfer.OpenFileLedger(ctx, dir, zg_common.LogOption{Logger: logrus.StandardLogger()})
+	if err != nil {
+		logrus.WithError(err).WithField("ledger", dir).Warn("Failed to open ledger, and uploads are not recorded")
+		return
+	}
+
+	uploader.WithLedger(ledger)
 }
 
 func newUploader(ctx context.Context, segNum uint64, args uploadArgument, w3client *web3go.Client, opt transfer.UploadOption) (*transfer.Uploader, func(), error) {
@@ -172,12 +360,17 @@ func newUploader(ctx context.Context, segNum uint64, args uploadArgument, w3clie
 			return nil, nil, errors.WithMessage(err, "failed to initialize indexer client")
 		}
 
-		up, err := indexerClient.NewUploaderFromIndexerNodes(ctx, segNum, w3client, opt.ExpectedReplica, nil)
+		up, err := indexerClient.NewUploaderByRequirement(ctx, segNum, w3client, shard.UniformReplica(1).Merge(opt.ReplicaRequirement()), nil)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		return up, indexerClient.Close, nil
+		closer := func() {
+			up.Close()
+			indexerClient.Close()
+		}
+
+		return up, closer, nil
 	}
 
 	clients := node.MustNewZgsClients(args.node, providerOption)
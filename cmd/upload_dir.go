@@ -2,10 +2,20 @@ package cmd
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/blockchain"
 	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/openweb3/web3go"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -13,6 +23,40 @@ import (
 var (
 	uploadDirArgs uploadArgument
 
+	embedArgs struct {
+		maxFileSize  zg_common.ByteSize
+		maxTotalSize zg_common.ByteSize
+	}
+
+	nameEncoding     string
+	symlinkPolicy    string
+	portableNames    string
+	specialFiles     string
+	preserveMetadata bool
+
+	signManifestArgs manifestKeyArgument
+
+	manifestSizeArgs struct {
+		maxSize  zg_common.ByteSize
+		chunking bool
+	}
+
+	summaryFormat string
+
+	continueOnFileError bool
+
+	treeWorkers int
+
+	senderArgs struct {
+		keys     []string
+		strategy string
+	}
+
+	ignoreArgs struct {
+		patterns []string
+		file     bool
+	}
+
 	uploadDirCmd = &cobra.Command{
 		Use:   "upload-dir",
 		Short: "Upload directory to ZeroGStorage network",
@@ -22,15 +66,31 @@ var (
 
 func init() {
 	bindUploadFlags(uploadDirCmd, &uploadDirArgs)
+	uploadDirCmd.Flags().StringVar(&uploadDirArgs.file, "file", "", "File name to upload")
+	uploadDirCmd.MarkFlagRequired("file")
 	uploadDirCmd.Flags().StringVar(&uploadDirArgs.url, "url", "", "Fullnode URL to interact with ZeroGStorage smart contract")
 	uploadDirCmd.MarkFlagRequired("url")
 	uploadDirCmd.Flags().StringVar(&uploadDirArgs.key, "key", "", "Private key to interact with smart contract")
 	uploadDirCmd.MarkFlagRequired("key")
 
+	uploadDirCmd.Flags().Var(&embedArgs.maxFileSize, "embed-max-file-size", "Embed content of files not larger than the size in directory metadata instead of uploading separately, e.g. 1KiB, 0 to disable")
+	embedArgs.maxTotalSize = zg_common.MiB
+	uploadDirCmd.Flags().Var(&embedArgs.maxTotalSize, "embed-max-total-size", "Max total size of file content embedded in directory metadata")
+
+	bindDirUploaderFlags(uploadDirCmd)
+
+	uploadDirCmd.Flags().StringSliceVar(&senderArgs.keys, "sender-keys", nil, "Additional private keys to spread flow submissions across along with --key, so that files are submitted concurrently")
+	uploadDirCmd.Flags().StringVar(&senderArgs.strategy, "sender-strategy", string(transfer.SenderLeastPending), "Strategy to select the account to submit files, options: round-robin, least-pending")
+
+	uploadDirCmd.Flags().StringVar(&summaryFormat, "summary", "text", "Format to print the summary of uploaded, skipped, reused and failed files, options: text, json, none")
+
 	rootCmd.AddCommand(uploadDirCmd)
 }
 
-func uploadDir(*cobra.Command, []string) {
+func uploadDir(cmd *cobra.Command, _ []string) {
+	profile := resolveUploadProfile(cmd, &uploadDirArgs)
+	retention := uploadDirArgs.mustLoadRetention()
+
 	ctx := context.Background()
 	var cancel context.CancelFunc
 	if uploadDirArgs.timeout > 0 {
@@ -50,23 +110,216 @@ func uploadDir(*cobra.Command, []string) {
 		FinalityRequired: finalityRequired,
 		TaskSize:         uploadDirArgs.taskSize,
 		ExpectedReplica:  uploadDirArgs.expectedReplica,
+		ShardReplicas:    mustParseShardReplicas(uploadDirArgs.shardReplicas),
 		SkipTx:           uploadDirArgs.skipTx,
+		SkipPreflight:    uploadDirArgs.skipPreflight,
+		VerifySamples:    uploadDirArgs.verifySamples,
 	}
 
+	uploadDirArgs.applyRetention(&opt)
+
 	uploader, closer, err := newUploader(ctx, 0, uploadDirArgs, w3client, opt)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to initialize uploader")
 	}
 	defer closer()
-	uploader.WithRoutines(uploadArgs.routines)
+	applyUploaderProfile(uploader, profile, uploadDirArgs.routines, opt)
+	applyUploaderLedger(ctx, uploader, uploadDirArgs.ledger)
+	uploader.WithHashOption(uploadDirArgs.hashOption())
+	uploader.WithMmap(uploadDirArgs.mmap)
+	uploader.WithRetentionPolicies(retention)
+	uploader.WithEmbedding(dir.EmbedOption{
+		MaxFileSize:  int64(embedArgs.maxFileSize),
+		MaxTotalSize: int64(embedArgs.maxTotalSize),
+	})
+
+	applyDirUploaderFlags(uploader, uploadDirArgs.file, uploadDirArgs.key)
 
-	txnHash, rootHash, err := uploader.UploadDir(ctx, uploadDirArgs.file, opt)
+	if len(senderArgs.keys) > 0 {
+		senders, closeSenders := mustNewSenderPool(uploadDirArgs.url, append([]string{uploadDirArgs.key}, senderArgs.keys...))
+		defer closeSenders()
+		uploader.WithSenders(senders)
+		defer logSenderStats(senders)
+	}
+
+	switch summaryFormat {
+	case "text", "json", "none":
+	default:
+		logrus.WithField("summary", summaryFormat).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid summary format")
+	}
+
+	summary, err := uploader.UploadDirWithSummary(ctx, uploadDirArgs.file, opt)
+	printDirUploadSummary(summary)
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to upload directory")
 	}
+	reportWarnings(uploader, uploadDirArgs.failOnWarning)
 
 	logrus.WithFields(logrus.Fields{
-		"txnHash":  txnHash,
-		"rootHash": rootHash,
+		"txnHash":  summary.TxHash,
+		"rootHash": summary.Root,
+		"uploaded": summary.Uploaded.Files,
+		"skipped":  summary.Skipped.Files,
+		"reused":   summary.Reused.Files,
+		"profile":  profileField(uploader.Profile()),
 	}).Info("Directory uploaded done")
 }
+
+// bindDirUploaderFlags binds the flags shared by commands to upload directory, see applyDirUploaderFlags.
+func bindDirUploaderFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&nameEncoding, "name-encoding", string(dir.NameEncodingReplace), "Policy to encode file names that are not valid UTF-8, options: replace (with U+FFFD), percent (restored on download), error (reject)")
+	cmd.Flags().StringVar(&symlinkPolicy, "symlinks", string(dir.SymlinkReject), "Policy to handle symbolic links that escape the directory or form a cycle, options: reject, skip, keep (stored as is)")
+	cmd.Flags().StringVar(&specialFiles, "special-files", string(dir.SpecialFilesSkip), "Policy to handle special files, e.g. named pipes, sockets and device nodes, options: skip (reported as warnings), reject")
+	cmd.Flags().StringVar(&portableNames, "portable-names", string(dir.PortableNamesReject), "Policy to handle file names not portable to Windows or macOS, e.g. colliding case-insensitively or reserved like CON, options: reject, warn (for Linux targets only)")
+
+	cmd.Flags().BoolVar(&preserveMetadata, "preserve-metadata", false, "Record permissions and modification times of files in directory metadata, which are restored on download")
+
+	bindManifestKeyFlags(cmd, &signManifestArgs)
+
+	cmd.Flags().Var(&manifestSizeArgs.maxSize, "manifest-max-size", "Max size of directory metadata to upload as a single file, e.g. 64MiB, 0 for half of the max file size of network")
+	cmd.Flags().BoolVar(&manifestSizeArgs.chunking, "manifest-chunking", false, "Upload directory metadata in chunks if exceeds --manifest-max-size, otherwise fail before uploading files")
+
+	cmd.Flags().BoolVar(&continueOnFileError, "continue-on-error", false, "Continue uploading the other files once any file failed to upload, otherwise stop at the first failure, and the directory metadata is not uploaded either way")
+
+	cmd.Flags().IntVar(&treeWorkers, "tree-workers", runtime.NumCPU(), "Number of files hashed concurrently when building the file tree of directory")
+
+	cmd.Flags().StringSliceVar(&ignoreArgs.patterns, "ignore", nil, "Gitignore-style patterns of files not to upload, e.g. .git/,node_modules/")
+	cmd.Flags().BoolVar(&ignoreArgs.file, "ignore-file", true, "Ignore files matching the patterns in "+dir.IgnoreFileName+" at the root of directory")
+}
+
+// applyDirUploaderFlags configures the uploader to build the file tree of folder and sign directory metadata as
+// specified by the flags shared by commands to upload directory.
+func applyDirUploaderFlags(uploader *transfer.Uploader, folder, txKey string) {
+	if err := dir.NameEncoding(nameEncoding).Validate(); err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid name encoding")
+	}
+	uploader.WithNameEncoding(dir.NameEncoding(nameEncoding))
+
+	if err := dir.SymlinkPolicy(symlinkPolicy).Validate(); err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid symbolic link policy")
+	}
+	uploader.WithSymlinkPolicy(dir.SymlinkPolicy(symlinkPolicy))
+
+	if err := dir.SpecialFilePolicy(specialFiles).Validate(); err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid special file policy")
+	}
+	uploader.WithSpecialFilePolicy(dir.SpecialFilePolicy(specialFiles))
+
+	if err := dir.PortableNamePolicy(portableNames).Validate(); err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid portable name policy")
+	}
+	uploader.WithPortableNamePolicy(dir.PortableNamePolicy(portableNames))
+	uploader.WithMetadata(preserveMetadata)
+	uploader.WithTreeWorkers(treeWorkers)
+	uploader.WithIgnore(mustLoadIgnorePatterns(folder))
+	uploader.WithManifestSizeLimit(int64(manifestSizeArgs.maxSize))
+	uploader.WithManifestChunking(manifestSizeArgs.chunking)
+	uploader.WithContinueOnFileError(continueOnFileError)
+
+	if key := mustParseManifestKey(signManifestArgs, txKey); key != nil {
+		uploader.WithManifestSigner(key)
+	}
+}
+
+// printDirUploadSummary prints the summary of directory upload to stdout in the specified format.
+func printDirUploadSummary(summary *transfer.DirUploadSummary) {
+	switch summaryFormat {
+	case "text":
+		if err := summary.WriteText(os.Stdout); err != nil {
+			logrus.WithError(err).Warn("Failed to print summary")
+		}
+	case "json":
+		content, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to marshal summary")
+			return
+		}
+
+		fmt.Println(string(content))
+	}
+}
+
+// manifestKeyArgument is the argument to sign directory metadata.
+type manifestKeyArgument struct {
+	enabled bool
+	key     string
+}
+
+func bindManifestKeyFlags(cmd *cobra.Command, args *manifestKeyArgument) {
+	cmd.Flags().BoolVar(&args.enabled, "sign-manifest", false, "Sign directory metadata with the private key to interact with smart contract, so that consumers could verify the publisher")
+	cmd.Flags().StringVar(&args.key, "manifest-key", "", "Dedicated private key to sign directory metadata, which implies --sign-manifest")
+}
+
+// mustParseManifestKey returns the private key to sign directory metadata, or nil if signing disabled. The
+// private key to interact with smart contract is used unless a dedicated key specified.
+func mustParseManifestKey(args manifestKeyArgument, txKey string) *ecdsa.PrivateKey {
+	hexKey := args.key
+	if len(hexKey) == 0 {
+		if !args.enabled {
+			return nil
+		}
+
+		hexKey = txKey
+	}
+
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid private key to sign directory metadata")
+	}
+
+	return key
+}
+
+// mustLoadIgnorePatterns loads the ignore file of directory if enabled, along with the patterns of --ignore.
+func mustLoadIgnorePatterns(folder string) *dir.IgnorePatterns {
+	var ignore *dir.IgnorePatterns
+	if ignoreArgs.file {
+		var err error
+		if ignore, err = dir.LoadIgnoreFile(folder); err != nil {
+			logrus.WithError(err).Fatal("Failed to load ignore file")
+		}
+	}
+
+	patterns, err := dir.ParseIgnorePatterns(ignoreArgs.patterns...)
+	if err != nil {
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid --ignore")
+	}
+
+	return ignore.Append(patterns)
+}
+
+// mustNewSenderPool creates the pool of accounts to spread flow submissions, along with the function to close web3
+// clients of accounts.
+func mustNewSenderPool(url string, keys []string) (*transfer.SenderPool, func()) {
+	var clients []*web3go.Client
+	closer := func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}
+
+	for _, key := range keys {
+		clients = append(clients, blockchain.MustNewWeb3(url, key, providerOption))
+	}
+
+	pool, err := transfer.NewSenderPool(clients, transfer.SenderStrategy(senderArgs.strategy))
+	if err != nil {
+		closer()
+		logrus.WithError(err).WithField(errorClassField, zg_common.ErrorClassUsage).Fatal("Invalid senders")
+	}
+
+	return pool, closer
+}
+
+// logSenderStats logs the flow submissions and fee paid by each account of sender pool.
+func logSenderStats(pool *transfer.SenderPool) {
+	for _, stats := range pool.Stats() {
+		logrus.WithFields(logrus.Fields{
+			"sender":    stats.Address,
+			"submitted": stats.Submitted,
+			"files":     stats.Files,
+			"fee":       stats.Value,
+			"removed":   stats.Removed,
+		}).Info("Sender stats")
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/sirupsen/logrus"
+)
+
+// warningReporter is implemented by uploader and downloader to aggregate non-fatal issues.
+type warningReporter interface {
+	Warnings() *transfer.Warnings
+}
+
+// reportWarnings prints the aggregated warnings in a summary section, and terminates the
+// program if any warning of codes in failOn occurred. Warnings reported are reset, so that
+// the next operation of the same reporter is reported separately.
+func reportWarnings(reporter interface{}, failOn []string) {
+	r, ok := reporter.(warningReporter)
+	if !ok {
+		return
+	}
+
+	warnings := r.Warnings()
+	defer warnings.Reset()
+
+	if items := warnings.List(); len(items) > 0 {
+		logrus.Warnf("Total %v warnings occurred:", len(items)+warnings.Overflow())
+		for _, item := range items {
+			logrus.WithFields(logrus.Fields(item.Fields)).Warnf("[%v] %v", item.Code, item.Message)
+		}
+		if overflow := warnings.Overflow(); overflow > 0 {
+			logrus.Warnf("... and %v more warnings omitted", overflow)
+		}
+	}
+
+	for _, code := range failOn {
+		if warnings.Has(transfer.WarningCode(code)) {
+			logrus.WithField("code", code).WithField(errorClassField, zg_common.ErrorClassPartial).Fatal("Failed due to warning occurred")
+		}
+	}
+}
@@ -11,6 +11,19 @@ const httpStatusCodeInternalError = 600
 
 var ErrHandled = new(BusinessError)
 
+// contextKeyError is the key of error responded by controller in gin context.
+const contextKeyError = "api.error"
+
+// ErrorOf returns the error responded by controller wrapped by Wrap, including business errors responded with
+// HTTP 200, or nil if succeeded.
+func ErrorOf(c *gin.Context) error {
+	if err, ok := c.Get(contextKeyError); ok {
+		return err.(error)
+	}
+
+	return nil
+}
+
 func Wrap(controller func(c *gin.Context) (interface{}, error)) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		result, err := controller(c)
@@ -19,6 +32,8 @@ func Wrap(controller func(c *gin.Context) (interface{}, error)) gin.HandlerFunc
 		}
 
 		if err != nil {
+			c.Set(contextKeyError, err)
+
 			switch e := err.(type) {
 			case *BusinessError:
 				// custom business error
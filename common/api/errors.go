@@ -5,6 +5,7 @@ var (
 	ErrNil        = NewBusinessError(0, "Success")
 	ErrValidation = NewBusinessError(1, "Invalid parameter")
 	ErrInternal   = NewBusinessError(2, "Internal server error")
+	ErrAuth       = NewBusinessError(3, "Unauthorized")
 )
 
 type BusinessError struct {
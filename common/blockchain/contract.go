@@ -136,3 +136,22 @@ func (c *Contract) CreateTransactOpts(ctx context.Context) (*bind.TransactOpts,
 func (c *Contract) WaitForReceipt(ctx context.Context, txHash common.Hash, successRequired bool, opts ...RetryOption) (*types.Receipt, error) {
 	return WaitForReceipt(ctx, c.client, txHash, successRequired, opts...)
 }
+
+// Account returns the account to send transaction.
+func (c *Contract) Account() common.Address {
+	return c.account
+}
+
+// Balance returns the latest balance of the account to send transaction.
+func (c *Contract) Balance(ctx context.Context) (*big.Int, error) {
+	return c.client.WithContext(ctx).Eth.Balance(c.account, nil)
+}
+
+// GasPrice returns the gas price to send transaction, which is CustomGasPrice if specified.
+func (c *Contract) GasPrice(ctx context.Context) (*big.Int, error) {
+	if CustomGasPrice > 0 {
+		return new(big.Int).SetUint64(CustomGasPrice), nil
+	}
+
+	return c.client.WithContext(ctx).Eth.GasPrice()
+}
@@ -0,0 +1,220 @@
+package blockchain
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	gorpc "github.com/openweb3/go-rpc-provider"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/sirupsen/logrus"
+)
+
+// rateLimitWarnInterval is the min interval to warn that demand exceeds the rate limit of fullnode.
+const rateLimitWarnInterval = time.Minute
+
+// RateLimit is the limit of requests to fullnode, e.g. the quota of free-tier RPC providers.
+type RateLimit struct {
+	Rate  float64 // requests per second, 0 for unlimited
+	Burst int     // max requests sent at once without waiting, 1 if 0
+}
+
+// Validate checks the rate limit, and returns an error that names the invalid field if any.
+func (limit RateLimit) Validate() error {
+	return zg_common.FirstError(
+		zg_common.RequireNonNegative("Rate", limit.Rate),
+		zg_common.RequireNonNegative("Burst", limit.Burst),
+	)
+}
+
+// RateLimiterStats is the statistics of requests limited by RateLimiter.
+type RateLimiterStats struct {
+	Requests  uint64        // number of requests, where each one in batch is counted
+	Delayed   uint64        // number of requests that waited for quota
+	TotalWait time.Duration // total time waited for quota
+	MaxWait   time.Duration // max time a request waited for quota
+}
+
+// RateLimiter limits requests to fullnode by token bucket, which is safe for concurrent use. Requests exceeding
+// the burst wait in order of arrival, and a warning is logged if requests wait longer than the time to refill the
+// burst, which indicates that sustained demand exceeds the rate.
+type RateLimiter struct {
+	url   string
+	limit RateLimit
+
+	mu       sync.Mutex
+	tokens   float64   // tokens available, negative if requests queued
+	last     time.Time // time that tokens refilled
+	stats    RateLimiterStats
+	lastWarn time.Time
+}
+
+// NewRateLimiter creates a rate limiter of requests to the specified fullnode.
+func NewRateLimiter(url string, limit RateLimit) (*RateLimiter, error) {
+	if err := limit.Validate(); err != nil {
+		return nil, err
+	}
+
+	if limit.Burst == 0 {
+		limit.Burst = 1
+	}
+
+	return &RateLimiter{
+		url:    url,
+		limit:  limit,
+		tokens: float64(limit.Burst),
+		last:   time.Now(),
+	}, nil
+}
+
+// reserve takes n tokens, and returns the time to wait for them.
+func (limiter *RateLimiter) reserve(n int) time.Duration {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := time.Now()
+	limiter.tokens = min(float64(limiter.limit.Burst), limiter.tokens+now.Sub(limiter.last).Seconds()*limiter.limit.Rate)
+	limiter.last = now
+	limiter.tokens -= float64(n)
+
+	var wait time.Duration
+	if limiter.tokens < 0 {
+		wait = time.Duration(-limiter.tokens / limiter.limit.Rate * float64(time.Second))
+	}
+
+	limiter.stats.Requests += uint64(n)
+	if wait > 0 {
+		limiter.stats.Delayed += uint64(n)
+		limiter.stats.TotalWait += wait
+		limiter.stats.MaxWait = max(limiter.stats.MaxWait, wait)
+	}
+
+	// requests queued more than a burst
+	if refill := time.Duration(float64(limiter.limit.Burst) / limiter.limit.Rate * float64(time.Second)); wait > refill && now.Sub(limiter.lastWarn) > rateLimitWarnInterval {
+		limiter.lastWarn = now
+		logrus.WithFields(logrus.Fields{
+			"url":   limiter.url,
+			"rate":  limiter.limit.Rate,
+			"burst": limiter.limit.Burst,
+			"wait":  wait,
+		}).Warn("Requests to fullnode exceed the rate limit, consider to raise the quota or reduce concurrency")
+	}
+
+	return wait
+}
+
+// cancel returns n tokens reserved but not used.
+func (limiter *RateLimiter) cancel(n int) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	limiter.tokens += float64(n)
+}
+
+// Wait waits until n requests allowed, or context done.
+func (limiter *RateLimiter) Wait(ctx context.Context, n int) error {
+	if limiter.limit.Rate == 0 {
+		return nil
+	}
+
+	wait := limiter.reserve(n)
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		limiter.cancel(n)
+		return ctx.Err()
+	}
+}
+
+// Stats returns the statistics of requests limited so far.
+func (limiter *RateLimiter) Stats() RateLimiterStats {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	return limiter.stats
+}
+
+// CallMiddleware limits each call of RPC.
+func (limiter *RateLimiter) CallMiddleware(handler providers.CallContextFunc) providers.CallContextFunc {
+	return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+		if err := limiter.Wait(ctx, 1); err != nil {
+			return err
+		}
+
+		return handler(ctx, result, method, args...)
+	}
+}
+
+// BatchCallMiddleware limits batch call of RPC, where each request in batch is counted.
+func (limiter *RateLimiter) BatchCallMiddleware(handler providers.BatchCallContextFunc) providers.BatchCallContextFunc {
+	return func(ctx context.Context, b []gorpc.BatchElem) error {
+		if err := limiter.Wait(ctx, len(b)); err != nil {
+			return err
+		}
+
+		return handler(ctx, b)
+	}
+}
+
+var (
+	rateLimitsMu sync.Mutex
+	rateLimits   = make(map[string]RateLimit)    // rate limits of fullnodes configured by SetRateLimit
+	rateLimiters = make(map[string]*RateLimiter) // rate limiters shared by clients of the same fullnode
+
+	// DefaultRateLimit is the rate limit of fullnodes not configured by SetRateLimit, which is unlimited by default.
+	DefaultRateLimit RateLimit
+)
+
+// SetRateLimit sets the rate limit of requests to the specified fullnode, which is shared by all web3 clients
+// created afterwards in the process, e.g. to upload and poll receipts in parallel.
+func SetRateLimit(url string, limit RateLimit) error {
+	if err := limit.Validate(); err != nil {
+		return err
+	}
+
+	rateLimitsMu.Lock()
+	defer rateLimitsMu.Unlock()
+
+	rateLimits[url] = limit
+	delete(rateLimiters, url)
+
+	return nil
+}
+
+// RateLimiterOf returns the rate limiter shared by web3 clients of the specified fullnode, or nil if unlimited.
+func RateLimiterOf(url string) *RateLimiter {
+	rateLimitsMu.Lock()
+	defer rateLimitsMu.Unlock()
+
+	if limiter, ok := rateLimiters[url]; ok {
+		return limiter
+	}
+
+	limit, ok := rateLimits[url]
+	if !ok {
+		limit = DefaultRateLimit
+	}
+
+	if limit.Rate == 0 {
+		return nil
+	}
+
+	limiter, err := NewRateLimiter(url, limit)
+	if err != nil {
+		logrus.WithError(err).WithField("url", url).Warn("Invalid rate limit of fullnode, ignored")
+		return nil
+	}
+
+	rateLimiters[url] = limiter
+
+	return limiter
+}
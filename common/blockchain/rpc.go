@@ -2,8 +2,11 @@ package blockchain
 
 import (
 	"context"
+	"math/rand"
 	"time"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/rpc"
 	"github.com/0glabs/0g-storage-client/common/util"
 	"github.com/ethereum/go-ethereum/common"
 	gethTypes "github.com/ethereum/go-ethereum/core/types"
@@ -11,6 +14,7 @@ import (
 	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
 	"github.com/openweb3/web3go"
 	"github.com/openweb3/web3go/interfaces"
+	web3providers "github.com/openweb3/web3go/providers"
 	"github.com/openweb3/web3go/signers"
 	"github.com/openweb3/web3go/types"
 	"github.com/pkg/errors"
@@ -27,13 +31,19 @@ type RetryOption struct {
 func MustNewWeb3(url, key string, opt ...providers.Option) *web3go.Client {
 	client, err := NewWeb3(url, key, opt...)
 	if err != nil {
-		logrus.WithError(err).WithField("url", url).Fatal("Failed to connect to fullnode")
+		logrus.WithError(zg_common.ClassifyError(err, zg_common.ErrorClassNetwork)).WithField("url", url).Fatal("Failed to connect to fullnode")
 	}
 
 	return client
 }
 
 func NewWeb3(url, key string, opt ...providers.Option) (*web3go.Client, error) {
+	return NewWeb3WithProxy(url, key, rpc.DefaultProxy, opt...)
+}
+
+// NewWeb3WithProxy creates a web3 client with the specified private key to sign transactions, which connects to
+// fullnode with the specified proxy option.
+func NewWeb3WithProxy(url, key string, proxy rpc.ProxyOption, opt ...providers.Option) (*web3go.Client, error) {
 	sm := signers.MustNewSignerManagerByPrivateKeyStrings([]string{key})
 
 	option := new(web3go.ClientOption)
@@ -47,7 +57,7 @@ func NewWeb3(url, key string, opt ...providers.Option) (*web3go.Client, error) {
 		option = option.WithLooger(logrus.StandardLogger().Out)
 	}
 
-	return web3go.NewClientWithOption(url, *option)
+	return NewWeb3Client(url, proxy, *option)
 }
 
 func NewWeb3WithOption(url, key string, option ...providers.Option) (*web3go.Client, error) {
@@ -59,9 +69,42 @@ func NewWeb3WithOption(url, key string, option ...providers.Option) (*web3go.Cli
 
 	sm := signers.MustNewSignerManagerByPrivateKeyStrings([]string{key})
 
-	return web3go.NewClientWithOption(url, *opt.WithSignerManager(sm))
+	return NewWeb3Client(url, rpc.DefaultProxy, *opt.WithSignerManager(sm))
+}
+
+// NewWeb3Client creates a web3 client in the same way as web3go.NewClientWithOption, but connects to fullnode
+// with the specified proxy option.
+func NewWeb3Client(url string, proxy rpc.ProxyOption, option web3go.ClientOption) (*web3go.Client, error) {
+	client, err := rpc.NewClientWithProxy(url, proxy, option.Option)
+	if err != nil {
+		return nil, err
+	}
+
+	// requests to the same fullnode are limited in process, see SetRateLimit
+	if limiter := RateLimiterOf(url); limiter != nil {
+		client.HookCallContext(limiter.CallMiddleware)
+		client.HookBatchCallContext(limiter.BatchCallMiddleware)
+	}
+
+	// client option is required to bind contracts with signer, which could only be set by web3go.NewClientWithOption,
+	// so creates client with a placeholder HTTP url that never dialed, and then replaces the provider.
+	w3client, err := web3go.NewClientWithOption(placeholderURL, option)
+	if err != nil {
+		return nil, err
+	}
+
+	if option.SignerManager == nil {
+		w3client.SetProvider(client.MiddlewarableProvider)
+	} else {
+		w3client.SetProvider(web3providers.NewSignableProvider(client.MiddlewarableProvider, option.SignerManager))
+	}
+
+	return w3client, nil
 }
 
+// placeholderURL is the url to create web3 client, which is lazy to connect over HTTP.
+const placeholderURL = "http://127.0.0.1"
+
 func WaitForReceipt(ctx context.Context, client *web3go.Client, txHash common.Hash, successRequired bool, opts ...RetryOption) (receipt *types.Receipt, err error) {
 	var opt RetryOption
 	if len(opts) > 0 {
@@ -81,7 +124,7 @@ func WaitForReceipt(ctx context.Context, client *web3go.Client, txHash common.Ha
 			reminder.RemindWith("Transaction not executed yet", "hash", txHash)
 		}
 
-		time.Sleep(opt.Interval)
+		time.Sleep(jitter(opt.Interval))
 	}
 
 	if receipt.Status == nil {
@@ -97,15 +140,21 @@ func WaitForReceipt(ctx context.Context, client *web3go.Client, txHash common.Ha
 		}
 
 		if receipt.TxExecErrorMsg == nil {
-			return nil, errors.New("Transaction execution failed")
+			return nil, zg_common.WithErrorClass(errors.New("Transaction execution failed"), zg_common.ErrorClassTransaction)
 		}
 
-		return nil, errors.Errorf("Transaction execution failed, %v", *receipt.TxExecErrorMsg)
+		return nil, zg_common.WithErrorClass(errors.Errorf("Transaction execution failed, %v", *receipt.TxExecErrorMsg), zg_common.ErrorClassTransaction)
 	default:
 		return nil, errors.Errorf("Unknown receipt status %v", *receipt.Status)
 	}
 }
 
+// jitter returns the interval randomized by 20%, so that concurrent pollings spread over time instead of bursting
+// the fullnode in the same instant.
+func jitter(interval time.Duration) time.Duration {
+	return interval + time.Duration((rand.Float64()-0.5)*0.4*float64(interval))
+}
+
 func defaultSigner(clientWithSigner *web3go.Client) (interfaces.Signer, error) {
 	sm, err := clientWithSigner.GetSignerManager()
 	if err != nil {
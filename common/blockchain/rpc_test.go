@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/rpc"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/openweb3/web3go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// newReceiptServer creates a fullnode that responds the receipt of specified status, and calls onRequest if any
+// upon each request.
+func newReceiptServer(t *testing.T, txHash common.Hash, status string, onRequest func()) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if onRequest != nil {
+			onRequest()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result": map[string]interface{}{
+				"transactionHash":   txHash,
+				"transactionIndex":  "0x0",
+				"blockHash":         common.HexToHash("0x5678"),
+				"blockNumber":       "0x10",
+				"from":              common.Address{},
+				"cumulativeGasUsed": "0x5208",
+				"gasUsed":           "0x5208",
+				"logs":              []interface{}{},
+				"logsBloom":         "0x" + strings.Repeat("0", 512),
+				"status":            status,
+				"txExecErrorMsg":    "out of gas",
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestWaitForReceiptErrorClass(t *testing.T) {
+	txHash := common.HexToHash("0x1234")
+
+	// fullnode that reports the transaction failed
+	server := newReceiptServer(t, txHash, "0x0", nil)
+
+	client, err := NewWeb3Client(server.URL, rpc.ProxyOption{}, web3go.ClientOption{})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	opt := RetryOption{Interval: time.Millisecond, logger: logrus.StandardLogger()}
+
+	_, err = WaitForReceipt(context.Background(), client, txHash, true, opt)
+	assert.ErrorContains(t, err, "Transaction execution failed, out of gas")
+	assert.Equal(t, zg_common.ErrorClassTransaction, zg_common.ClassOf(err))
+
+	// failure tolerated
+	receipt, err := WaitForReceipt(context.Background(), client, txHash, false, opt)
+	assert.NoError(t, err)
+	assert.Equal(t, txHash, receipt.TransactionHash)
+}
+
+func TestNewWeb3SignerManager(t *testing.T) {
+	key := "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+	client, err := NewWeb3WithProxy("http://127.0.0.1:8545", key, rpc.ProxyOption{})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	// required to bind contracts with signer
+	sm, err := client.GetSignerManager()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sm.List()))
+
+	_, signer := client.ToClientForContract()
+	assert.NotNil(t, signer)
+}
+
+func TestRateLimitReceiptQueries(t *testing.T) {
+	txHash := common.HexToHash("0x1234")
+
+	var mu sync.Mutex
+	var requests []time.Time
+	server := newReceiptServer(t, txHash, "0x1", func() {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, time.Now())
+	})
+
+	assert.Error(t, SetRateLimit(server.URL, RateLimit{Rate: -1}))
+	assert.NoError(t, SetRateLimit(server.URL, RateLimit{Rate: 20, Burst: 2}))
+
+	// limiter shared by clients of the same fullnode
+	var clients []*web3go.Client
+	for i := 0; i < 2; i++ {
+		client, err := NewWeb3Client(server.URL, rpc.ProxyOption{}, web3go.ClientOption{})
+		assert.NoError(t, err)
+		defer client.Close()
+		clients = append(clients, client)
+	}
+
+	// burst of concurrent receipt queries
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(client *web3go.Client) {
+			defer wg.Done()
+			_, err := WaitForReceipt(context.Background(), client, txHash, true, RetryOption{Interval: time.Millisecond})
+			assert.NoError(t, err)
+		}(clients[i%2])
+	}
+	wg.Wait()
+
+	// 2 requests at once, and then 1 request per 50ms
+	assert.Equal(t, 10, len(requests))
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Before(requests[j]) })
+	assert.GreaterOrEqual(t, requests[9].Sub(requests[0]), 350*time.Millisecond)
+
+	stats := RateLimiterOf(server.URL).Stats()
+	assert.Equal(t, uint64(10), stats.Requests)
+	assert.Equal(t, uint64(8), stats.Delayed)
+	assert.GreaterOrEqual(t, stats.MaxWait, 350*time.Millisecond)
+
+	// waiting aborted once context done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		RateLimiterOf(server.URL).reserve(1)
+	}
+	assert.ErrorIs(t, RateLimiterOf(server.URL).Wait(ctx, 1), context.DeadlineExceeded)
+}
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ByteSize is a size in bytes, which could be parsed from human friendly string, e.g. "512MiB", "1.5GB" or "100".
+//
+// It could be used as CLI flag directly, and unmarshaled from text in config file.
+type ByteSize uint64
+
+// Units of byte size.
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000
+	MB          = 1000 * KB
+	GB          = 1000 * MB
+	TB          = 1000 * GB
+
+	KiB ByteSize = 1024
+	MiB          = 1024 * KiB
+	GiB          = 1024 * MiB
+	TiB          = 1024 * GiB
+)
+
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"k":   KiB,
+	"kb":  KB,
+	"kib": KiB,
+	"m":   MiB,
+	"mb":  MB,
+	"mib": MiB,
+	"g":   GiB,
+	"gb":  GB,
+	"gib": GiB,
+	"t":   TiB,
+	"tb":  TB,
+	"tib": TiB,
+}
+
+// ParseByteSize parses the byte size from string, which is a non-negative number with optional unit, e.g.
+// "100", "4KiB", "1.5 GB". Note, single letter unit is binary unit, e.g. "4k" is the same as "4KiB", and fraction
+// should result in a whole number of bytes, e.g. "1.5B" is rejected.
+func ParseByteSize(s string) (ByteSize, error) {
+	trimmed := strings.TrimSpace(s)
+
+	// split number and unit
+	i := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(trimmed)
+	}
+
+	number, unit := trimmed[:i], strings.ToLower(strings.TrimSpace(trimmed[i:]))
+
+	multiplier, ok := byteSizeUnits[unit]
+	if !ok {
+		return 0, errors.Errorf("invalid byte size %q, unknown unit %q", s, unit)
+	}
+
+	// integer without precision loss
+	if n, err := strconv.ParseUint(number, 10, 64); err == nil {
+		if n > math.MaxUint64/uint64(multiplier) {
+			return 0, errors.Errorf("invalid byte size %q, overflow", s)
+		}
+
+		return ByteSize(n) * multiplier, nil
+	}
+
+	// fraction is parsed exactly, and should result in a whole number of bytes
+	r, ok := new(big.Rat).SetString(number)
+	if !ok {
+		return 0, errors.Errorf("invalid byte size %q", s)
+	}
+
+	r.Mul(r, new(big.Rat).SetUint64(uint64(multiplier)))
+	if !r.IsInt() {
+		return 0, errors.Errorf("invalid byte size %q, not a whole number of bytes", s)
+	}
+
+	if !r.Num().IsUint64() {
+		return 0, errors.Errorf("invalid byte size %q, overflow", s)
+	}
+
+	return ByteSize(r.Num().Uint64()), nil
+}
+
+// String implements the fmt.Stringer interface, which formats in the largest binary unit without precision loss.
+func (size ByteSize) String() string {
+	for _, unit := range []struct {
+		size ByteSize
+		name string
+	}{{TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}} {
+		if size >= unit.size && size%unit.size == 0 {
+			return fmt.Sprintf("%v%v", uint64(size/unit.size), unit.name)
+		}
+	}
+
+	return fmt.Sprintf("%vB", uint64(size))
+}
+
+// Set implements the pflag.Value interface.
+func (size *ByteSize) Set(s string) error {
+	parsed, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+
+	*size = parsed
+
+	return nil
+}
+
+// Type implements the pflag.Value interface.
+func (size *ByteSize) Type() string {
+	return "bytes"
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (size ByteSize) MarshalText() ([]byte, error) {
+	return []byte(size.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (size *ByteSize) UnmarshalText(text []byte) error {
+	return size.Set(string(text))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, which accepts both number in bytes and string.
+func (size *ByteSize) UnmarshalJSON(data []byte) error {
+	if unquoted, err := strconv.Unquote(string(data)); err == nil {
+		return size.Set(unquoted)
+	}
+
+	return size.Set(string(data))
+}
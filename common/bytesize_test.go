@@ -0,0 +1,73 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseByteSize(t *testing.T) {
+	for input, expected := range map[string]ByteSize{
+		"0":        0,
+		"100":      100,
+		"100B":     100,
+		"4k":       4 * KiB,
+		"4KB":      4 * KB,
+		"512MiB":   512 * MiB,
+		"512 mib":  512 * MiB,
+		"1.5GB":    1500 * MB,
+		"1.5GiB":   1536 * MiB,
+		" 2TiB ":   2 * TiB,
+		"16777216": 16 * MiB,
+		"1.5MB":    1500 * KB,
+		"0.5k":     512,
+		"2.0B":     2,
+	} {
+		actual, err := ParseByteSize(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, actual, input)
+	}
+
+	for _, input := range []string{"", "-1", "1.2.3KB", "10XB", "MiB", "16777216TiB", ".", "1.5B", "1.1KiB", "0.3MiB"} {
+		_, err := ParseByteSize(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	assert.Equal(t, "0B", ByteSize(0).String())
+	assert.Equal(t, "1000B", KB.String())
+	assert.Equal(t, "512MiB", (512 * MiB).String())
+	assert.Equal(t, "1025KiB", (MiB + KiB).String())
+	assert.Equal(t, "4GiB", (4 * GiB).String())
+}
+
+func TestByteSizeJSON(t *testing.T) {
+	var config struct {
+		Size ByteSize `json:"size"`
+	}
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"size":"100MiB"}`), &config))
+	assert.Equal(t, 100*MiB, config.Size)
+
+	// compatible with number in bytes
+	assert.NoError(t, json.Unmarshal([]byte(`{"size":1024}`), &config))
+	assert.Equal(t, KiB, config.Size)
+
+	content, err := json.Marshal(config)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"size":"1KiB"}`, string(content))
+}
+
+func TestOptionError(t *testing.T) {
+	assert.NoError(t, RequirePositive("Routines", 1))
+	assert.EqualError(t, RequirePositive("Routines", 0), "invalid option Routines: should be positive, got 0")
+	assert.NoError(t, RequireNonNegative("Timeout", 0))
+	assert.Error(t, RequireNonNegative("Timeout", -1))
+
+	err := FirstError(nil, RequirePositive("BatchSize", -1), RequirePositive("Concurrency", 0))
+	var optErr *OptionError
+	assert.ErrorAs(t, err, &optErr)
+	assert.Equal(t, "BatchSize", optErr.Field)
+}
@@ -0,0 +1,222 @@
+// Package chaos injects faults into RPCs to storage nodes, e.g. failed segment uploads, slow RPCs, corrupted
+// segments and dropped connections, so as to verify the retry, failover and resume logic against realistic
+// failures without hand-writing mock nodes.
+//
+// Faults are only injected into clients explicitly injected, see Injector.Inject, so that there is no overhead
+// in production. Note, batch calls are not intercepted.
+package chaos
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/node"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/pkg/errors"
+)
+
+// ErrInjected is the default error of RPC failed by ActionFail.
+var ErrInjected = errors.New("fault injected")
+
+// Action is the fault to inject into RPC.
+type Action string
+
+const (
+	ActionFail    Action = "fail"    // fail the RPC without sending to storage node
+	ActionDelay   Action = "delay"   // delay the RPC before sending to storage node
+	ActionCorrupt Action = "corrupt" // flip a byte of the segment responded by storage node
+	ActionDrop    Action = "drop"    // send the RPC, but reset the connection before response received
+)
+
+// Fault is a fault to inject into the matched RPCs.
+type Fault struct {
+	Methods []string // RPC methods to match, e.g. zgs_uploadSegmentsByTxSeq, all methods if empty
+	Nth     int      // inject from the Nth matched RPC (1-based), 1 if 0
+	Times   int      // number of RPCs to inject since the Nth, 0 for all the following RPCs
+
+	Action Action
+	Delay  time.Duration // delay of ActionDelay
+	Err    error         // error of ActionFail, ErrInjected if nil
+}
+
+// Scenario is a named set of faults, which are evaluated against each RPC independently.
+type Scenario struct {
+	Name   string
+	Faults []Fault
+}
+
+// Validate checks the scenario, and returns an error if any fault is invalid.
+func (scenario Scenario) Validate() error {
+	for i, fault := range scenario.Faults {
+		if fault.Nth < 0 || fault.Times < 0 || fault.Delay < 0 {
+			return errors.Errorf("invalid fault #%v of scenario %v, negative value not allowed", i, scenario.Name)
+		}
+
+		switch fault.Action {
+		case ActionFail, ActionDelay, ActionCorrupt, ActionDrop:
+		default:
+			return errors.Errorf("invalid fault #%v of scenario %v, unknown action %q", i, scenario.Name, fault.Action)
+		}
+	}
+
+	return nil
+}
+
+// Injector injects the faults of scenario into RPCs of storage node clients, which counts the matched RPCs across
+// all injected clients.
+type Injector struct {
+	scenario Scenario
+
+	mu       sync.Mutex
+	matched  []int          // fault index -> number of matched RPCs
+	injected map[Action]int // action -> number of RPCs injected
+}
+
+// New creates an injector of the specified scenario.
+func New(scenario Scenario) (*Injector, error) {
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+
+	injector := Injector{
+		scenario: scenario,
+		matched:  make([]int, len(scenario.Faults)),
+		injected: make(map[Action]int),
+	}
+
+	return &injector, nil
+}
+
+// MustNew creates an injector of the specified scenario, and panics if the scenario is invalid.
+func MustNew(scenario Scenario) *Injector {
+	injector, err := New(scenario)
+	if err != nil {
+		panic(err)
+	}
+
+	return injector
+}
+
+// Inject injects faults into RPCs of the specified clients, which are wrapped as transport or application errors
+// in the same way as real failures, see node.RPCError.
+func (injector *Injector) Inject(clients ...*node.ZgsClient) {
+	for _, client := range clients {
+		client.HookCallContext(injector.Middleware)
+	}
+}
+
+// Hook returns the middleware to inject faults, which could be set by rpc.SetCallHook to inject faults into all
+// clients created afterwards.
+func (injector *Injector) Hook(url string) providers.CallContextMiddleware {
+	return injector.Middleware
+}
+
+// Injected returns the number of RPCs injected by action.
+func (injector *Injector) Injected() map[Action]int {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+
+	injected := make(map[Action]int, len(injector.injected))
+	for action, n := range injector.injected {
+		injected[action] = n
+	}
+
+	return injected
+}
+
+// Middleware injects faults into RPCs of the handler.
+func (injector *Injector) Middleware(handler providers.CallContextFunc) providers.CallContextFunc {
+	return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+		faults := injector.match(method)
+
+		for _, fault := range faults {
+			switch fault.Action {
+			case ActionDelay:
+				select {
+				case <-time.After(fault.Delay):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			case ActionFail:
+				if fault.Err != nil {
+					return fault.Err
+				}
+
+				return errors.WithMessagef(ErrInjected, "method = %v", method)
+			}
+		}
+
+		if err := handler(ctx, result, method, args...); err != nil {
+			return err
+		}
+
+		for _, fault := range faults {
+			switch fault.Action {
+			case ActionCorrupt:
+				corrupt(result)
+			case ActionDrop:
+				return &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+			}
+		}
+
+		return nil
+	}
+}
+
+// match returns the faults to inject into the RPC of the specified method.
+func (injector *Injector) match(method string) []*Fault {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+
+	var faults []*Fault
+	for i := range injector.scenario.Faults {
+		fault := &injector.scenario.Faults[i]
+		if !fault.matches(method) {
+			continue
+		}
+
+		injector.matched[i]++
+
+		nth := max(fault.Nth, 1)
+		if n := injector.matched[i]; n < nth || (fault.Times > 0 && n >= nth+fault.Times) {
+			continue
+		}
+
+		injector.injected[fault.Action]++
+		faults = append(faults, fault)
+	}
+
+	return faults
+}
+
+func (fault *Fault) matches(method string) bool {
+	if len(fault.Methods) == 0 {
+		return true
+	}
+
+	for _, m := range fault.Methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// corrupt flips the first byte of segment data in result if any.
+func corrupt(result interface{}) {
+	switch v := result.(type) {
+	case **node.SegmentWithProof:
+		if *v != nil && len((*v).Data) > 0 {
+			(*v).Data[0] ^= 0xff
+		}
+	case *[]byte:
+		if len(*v) > 0 {
+			(*v)[0] ^= 0xff
+		}
+	}
+}
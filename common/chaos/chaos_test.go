@@ -0,0 +1,54 @@
+package chaos
+
+import (
+	"context"
+	"syscall"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInjector(t *testing.T) {
+	injector := MustNew(Scenario{Faults: []Fault{
+		{Methods: []string{"zgs_uploadSegments"}, Nth: 2, Times: 2, Action: ActionFail},
+		{Methods: []string{"zgs_getFileInfo"}, Action: ActionDrop},
+		{Methods: []string{"zgs_downloadSegmentWithProof"}, Action: ActionCorrupt},
+	}})
+
+	var calls int
+	handler := injector.Middleware(func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+		calls++
+		if segment, ok := result.(**node.SegmentWithProof); ok {
+			*segment = &node.SegmentWithProof{Data: []byte{1, 2}}
+		}
+		return nil
+	})
+
+	// the 2nd and 3rd RPCs failed without sending
+	var errs []error
+	for i := 0; i < 4; i++ {
+		errs = append(errs, handler(context.Background(), nil, "zgs_uploadSegments"))
+	}
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], ErrInjected)
+	assert.ErrorIs(t, errs[2], ErrInjected)
+	assert.NoError(t, errs[3])
+	assert.Equal(t, 2, calls)
+
+	// sent but connection reset
+	assert.ErrorIs(t, handler(context.Background(), nil, "zgs_getFileInfo"), syscall.ECONNRESET)
+	assert.Equal(t, 3, calls)
+
+	var segment *node.SegmentWithProof
+	assert.NoError(t, handler(context.Background(), &segment, "zgs_downloadSegmentWithProof"))
+	assert.Equal(t, []byte{0xfe, 2}, segment.Data)
+
+	// other methods unaffected
+	assert.NoError(t, handler(context.Background(), nil, "zgs_getStatus"))
+
+	assert.Equal(t, map[Action]int{ActionFail: 2, ActionDrop: 1, ActionCorrupt: 1}, injector.Injected())
+
+	_, err := New(Scenario{Faults: []Fault{{Action: "unknown"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package chaos
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+var (
+	// SegmentUploadMethods are the RPC methods to upload segments.
+	SegmentUploadMethods = []string{"zgs_uploadSegment", "zgs_uploadSegments", "zgs_uploadSegmentByTxSeq", "zgs_uploadSegmentsByTxSeq"}
+
+	// SegmentDownloadMethods are the RPC methods to download segments.
+	SegmentDownloadMethods = []string{"zgs_downloadSegment", "zgs_downloadSegmentByTxSeq", "zgs_downloadSegmentWithProof", "zgs_downloadSegmentWithProofByTxSeq"}
+
+	// FileInfoMethods are the RPC methods to query file info, e.g. polled to wait for finality.
+	FileInfoMethods = []string{"zgs_getFileInfo", "zgs_getFileInfoByTxSeq"}
+)
+
+// FailNthSegmentUpload fails the Nth RPC to upload segments once.
+func FailNthSegmentUpload(n int) Scenario {
+	return Scenario{
+		Name:   "fail-nth-segment-upload",
+		Faults: []Fault{{Methods: SegmentUploadMethods, Nth: n, Times: 1, Action: ActionFail}},
+	}
+}
+
+// DelayRPC delays all RPCs of the specified methods, or all methods if not specified.
+func DelayRPC(delay time.Duration, methods ...string) Scenario {
+	return Scenario{
+		Name:   "delay-rpc",
+		Faults: []Fault{{Methods: methods, Action: ActionDelay, Delay: delay}},
+	}
+}
+
+// CorruptSegments corrupts all the segments downloaded, so that proof verification fails.
+func CorruptSegments() Scenario {
+	return Scenario{
+		Name:   "corrupt-segments",
+		Faults: []Fault{{Methods: SegmentDownloadMethods, Action: ActionCorrupt}},
+	}
+}
+
+// DropDuringFinality resets the connection of the Nth and following RPCs to query file info for the specified
+// times, e.g. when waiting for file finality after segments uploaded.
+func DropDuringFinality(n, times int) Scenario {
+	return Scenario{
+		Name:   "drop-during-finality",
+		Faults: []Fault{{Methods: FileInfoMethods, Nth: n, Times: times, Action: ActionDrop}},
+	}
+}
+
+// NodeDown refuses the connection of all RPCs, as if the storage node is down.
+func NodeDown() Scenario {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+	return Scenario{
+		Name:   "node-down",
+		Faults: []Fault{{Action: ActionFail, Err: refused}},
+	}
+}
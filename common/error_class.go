@@ -0,0 +1,88 @@
+package common
+
+import (
+	"context"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorClass classifies errors by the cause, so that automation could react to failures accordingly, e.g. retry
+// on network errors, but not on verification errors.
+type ErrorClass string
+
+const (
+	ErrorClassUnknown      ErrorClass = "unknown"      // not classified
+	ErrorClassUsage        ErrorClass = "usage"        // invalid arguments or options
+	ErrorClassNetwork      ErrorClass = "network"      // fullnode or storage node unreachable, or RPC failed
+	ErrorClassTransaction  ErrorClass = "transaction"  // failed to send or execute transaction on chain
+	ErrorClassVerification ErrorClass = "verification" // data integrity or proof verification failed
+	ErrorClassPartial      ErrorClass = "partial"      // partially succeeded, e.g. some fragments uploaded
+	ErrorClassCancelled    ErrorClass = "cancelled"    // cancelled or timed out
+	ErrorClassRejected     ErrorClass = "rejected"     // rejected by storage node, e.g. invalid segment
+)
+
+// ErrorClassifier is implemented by errors that know their class.
+type ErrorClassifier interface {
+	ErrorClass() ErrorClass
+}
+
+// classifiedError attaches a class to the underlying error.
+type classifiedError struct {
+	error
+	class ErrorClass
+}
+
+func (e *classifiedError) ErrorClass() ErrorClass { return e.class }
+
+func (e *classifiedError) Unwrap() error { return e.error }
+
+func (e *classifiedError) Cause() error { return e.error }
+
+// WithErrorClass returns an error of the specified class that wraps err, and the class overrides any class of err.
+// It returns nil if err is nil.
+func WithErrorClass(err error, class ErrorClass) error {
+	if err == nil {
+		return nil
+	}
+
+	return &classifiedError{err, class}
+}
+
+// ClassifyError returns an error of the specified class that wraps err, unless err is already classified.
+func ClassifyError(err error, class ErrorClass) error {
+	if err == nil || ClassOf(err) != ErrorClassUnknown {
+		return err
+	}
+
+	return &classifiedError{err, class}
+}
+
+// ClassOf returns the class of error, which is determined by the outermost ErrorClassifier in the error chain,
+// or by the well known errors of standard library, e.g. context cancelled or network errors.
+func ClassOf(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassUnknown
+	}
+
+	var classifier ErrorClassifier
+	if errors.As(err, &classifier) {
+		return classifier.ErrorClass()
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassCancelled
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorClassNetwork
+	}
+
+	return ErrorClassUnknown
+}
+
+// ErrorClass implements the ErrorClassifier interface.
+func (e *OptionError) ErrorClass() ErrorClass {
+	return ErrorClassUsage
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassOf(t *testing.T) {
+	_, dialErr := net.Dial("tcp", "127.0.0.1:1")
+
+	for _, tc := range []struct {
+		err      error
+		expected ErrorClass
+	}{
+		{nil, ErrorClassUnknown},
+		{errors.New("test"), ErrorClassUnknown},
+		{RequirePositive("Routines", 0), ErrorClassUsage},
+		{errors.WithMessage(context.Canceled, "Failed to upload"), ErrorClassCancelled},
+		{errors.WithMessage(context.DeadlineExceeded, "Failed to upload"), ErrorClassCancelled},
+		{errors.WithMessage(dialErr, "Failed to connect"), ErrorClassNetwork},
+		{errors.WithMessage(WithErrorClass(errors.New("test"), ErrorClassTransaction), "Failed to upload"), ErrorClassTransaction},
+		// outermost class wins
+		{WithErrorClass(errors.WithMessage(context.Canceled, "test"), ErrorClassPartial), ErrorClassPartial},
+		{WithErrorClass(WithErrorClass(errors.New("test"), ErrorClassVerification), ErrorClassPartial), ErrorClassPartial},
+	} {
+		assert.Equal(t, tc.expected, ClassOf(tc.err), "%v", tc.err)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	assert.Nil(t, ClassifyError(nil, ErrorClassTransaction))
+	assert.Nil(t, WithErrorClass(nil, ErrorClassTransaction))
+
+	cause := errors.New("test")
+	err := ClassifyError(cause, ErrorClassTransaction)
+	assert.Equal(t, ErrorClassTransaction, ClassOf(err))
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, cause, errors.Cause(err))
+	assert.Equal(t, "test", err.Error())
+
+	// already classified
+	err = ClassifyError(errors.WithMessage(context.Canceled, "test"), ErrorClassTransaction)
+	assert.Equal(t, ErrorClassCancelled, ClassOf(err))
+}
@@ -0,0 +1,193 @@
+package parallel
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// PriorityPool limits the number of tasks running concurrently, which could be shared by multiple transfers.
+// Tasks waiting for a slot are queued in lanes by priority, and a freed slot is granted to the lane of highest
+// priority, but in-flight tasks are never preempted. To avoid starvation, a lane that runs fewer tasks than its
+// floor is served first regardless of priority.
+//
+// PriorityPool is safe for concurrent use.
+type PriorityPool struct {
+	mu      sync.Mutex
+	size    int
+	running int
+	lanes   map[int]*lane
+}
+
+type lane struct {
+	floor   int
+	running int
+	waiters *list.List // of chan struct{}, closed when granted
+}
+
+// LaneStats is the statistics of a lane in priority pool.
+type LaneStats struct {
+	Priority int `json:"priority"`
+	Floor    int `json:"floor"`   // minimum number of running tasks guaranteed if queued
+	Running  int `json:"running"` // number of running tasks
+	Queued   int `json:"queued"`  // number of tasks waiting for slot, a.k.a. queue depth
+}
+
+// NewPriorityPool creates a pool of the specified size, along with the concurrency floors by priority. The sum
+// of floors should be less than size, so that there is always a slot for higher priorities.
+func NewPriorityPool(size int, floors ...map[int]int) (*PriorityPool, error) {
+	if size <= 0 {
+		return nil, errors.Errorf("pool size should be positive, got %v", size)
+	}
+
+	pool := &PriorityPool{
+		size:  size,
+		lanes: make(map[int]*lane),
+	}
+
+	var sum int
+	for _, m := range floors {
+		for priority, floor := range m {
+			if floor < 0 {
+				return nil, errors.Errorf("floor of priority %v should not be negative, got %v", priority, floor)
+			}
+
+			pool.lane(priority).floor = floor
+			sum += floor
+		}
+	}
+
+	if sum >= size {
+		return nil, errors.Errorf("sum of floors should be less than pool size %v, got %v", size, sum)
+	}
+
+	return pool, nil
+}
+
+// lane returns the lane of specified priority, which is created if not exists. Requires lock held.
+func (pool *PriorityPool) lane(priority int) *lane {
+	l, ok := pool.lanes[priority]
+	if !ok {
+		l = &lane{waiters: list.New()}
+		pool.lanes[priority] = l
+	}
+
+	return l
+}
+
+// Acquire waits for a slot to run task of the specified priority, and returns the function to release the slot
+// once task completed. Returns the context error if cancelled while waiting.
+func (pool *PriorityPool) Acquire(ctx context.Context, priority int) (func(), error) {
+	pool.mu.Lock()
+
+	l := pool.lane(priority)
+	release := func() { pool.release(l) }
+
+	if pool.running < pool.size && pool.nextLane() == nil {
+		pool.running++
+		l.running++
+		pool.mu.Unlock()
+		return release, nil
+	}
+
+	granted := make(chan struct{})
+	elem := l.waiters.PushBack(granted)
+	pool.mu.Unlock()
+
+	select {
+	case <-granted:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	select {
+	case <-granted:
+		// granted along with cancellation, pass the slot on
+		pool.releaseLocked(l)
+	default:
+		l.waiters.Remove(elem)
+	}
+
+	return nil, ctx.Err()
+}
+
+func (pool *PriorityPool) release(l *lane) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.releaseLocked(l)
+}
+
+// releaseLocked releases a slot of lane, and grants it to the next waiter if any. Requires lock held.
+func (pool *PriorityPool) releaseLocked(l *lane) {
+	pool.running--
+	l.running--
+
+	if next := pool.nextLane(); next != nil {
+		granted := next.waiters.Remove(next.waiters.Front()).(chan struct{})
+		pool.running++
+		next.running++
+		close(granted)
+	}
+}
+
+// nextLane returns the lane to grant a slot, or nil if no task queued. Lanes below floor take precedence, and
+// then the lane of highest priority. Requires lock held.
+func (pool *PriorityPool) nextLane() *lane {
+	var next *lane
+	nextPriority := 0
+
+	for priority, l := range pool.lanes {
+		if l.waiters.Len() == 0 {
+			continue
+		}
+
+		if l.running < l.floor {
+			return l
+		}
+
+		if next == nil || priority > nextPriority {
+			next, nextPriority = l, priority
+		}
+	}
+
+	return next
+}
+
+// Stats returns the statistics of all lanes in order of priority from high to low.
+func (pool *PriorityPool) Stats() []LaneStats {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	stats := make([]LaneStats, 0, len(pool.lanes))
+	for priority, l := range pool.lanes {
+		stats = append(stats, LaneStats{
+			Priority: priority,
+			Floor:    l.floor,
+			Running:  l.running,
+			Queued:   l.waiters.Len(),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Priority > stats[j].Priority })
+
+	return stats
+}
+
+// QueueDepth returns the number of tasks of the specified priority waiting for slot.
+func (pool *PriorityPool) QueueDepth(priority int) int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if l, ok := pool.lanes[priority]; ok {
+		return l.waiters.Len()
+	}
+
+	return 0
+}
@@ -0,0 +1,112 @@
+package parallel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// acquireAsync acquires a slot in another goroutine, and returns the channel to receive the release function.
+func acquireAsync(pool *PriorityPool, priority int) <-chan func() {
+	ch := make(chan func(), 1)
+	go func() {
+		release, _ := pool.Acquire(context.Background(), priority)
+		ch <- release
+	}()
+	return ch
+}
+
+// waitQueued waits until the specified number of tasks queued in lane.
+func waitQueued(t *testing.T, pool *PriorityPool, priority, queued int) {
+	assert.Eventually(t, func() bool { return pool.QueueDepth(priority) == queued }, time.Second, time.Millisecond)
+}
+
+func TestNewPriorityPool(t *testing.T) {
+	_, err := NewPriorityPool(0)
+	assert.Error(t, err)
+
+	_, err = NewPriorityPool(2, map[int]int{-1: 1, 0: 1})
+	assert.Error(t, err)
+
+	_, err = NewPriorityPool(2, map[int]int{-1: -1})
+	assert.Error(t, err)
+
+	pool, err := NewPriorityPool(2, map[int]int{-1: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []LaneStats{{Priority: -1, Floor: 1}}, pool.Stats())
+}
+
+func TestPriorityPoolOrder(t *testing.T) {
+	pool, err := NewPriorityPool(1)
+	assert.NoError(t, err)
+
+	release, err := pool.Acquire(context.Background(), 0)
+	assert.NoError(t, err)
+
+	low := acquireAsync(pool, -1)
+	waitQueued(t, pool, -1, 1)
+	high := acquireAsync(pool, 1)
+	waitQueued(t, pool, 1, 1)
+
+	assert.Equal(t, []LaneStats{
+		{Priority: 1, Queued: 1},
+		{Priority: 0, Running: 1},
+		{Priority: -1, Queued: 1},
+	}, pool.Stats())
+
+	// higher priority granted first, though queued later
+	release()
+	release = <-high
+	assert.Equal(t, 1, pool.QueueDepth(-1))
+
+	release()
+	release = <-low
+	release()
+
+	assert.Equal(t, []LaneStats{{Priority: 1}, {Priority: 0}, {Priority: -1}}, pool.Stats())
+}
+
+func TestPriorityPoolFloor(t *testing.T) {
+	pool, err := NewPriorityPool(2, map[int]int{-1: 1})
+	assert.NoError(t, err)
+
+	release1, _ := pool.Acquire(context.Background(), 1)
+	release2, _ := pool.Acquire(context.Background(), 1)
+
+	low := acquireAsync(pool, -1)
+	waitQueued(t, pool, -1, 1)
+	high := acquireAsync(pool, 1)
+	waitQueued(t, pool, 1, 1)
+
+	// background lane below floor is served first
+	release1()
+	releaseLow := <-low
+
+	// background lane reached floor, and higher priority served
+	release2()
+	releaseHigh := <-high
+
+	releaseLow()
+	releaseHigh()
+}
+
+func TestPriorityPoolCancel(t *testing.T) {
+	pool, err := NewPriorityPool(1)
+	assert.NoError(t, err)
+
+	release, _ := pool.Acquire(context.Background(), 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = pool.Acquire(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, pool.QueueDepth(1))
+
+	// slot is available once released
+	release()
+	release, err = pool.Acquire(context.Background(), -1)
+	assert.NoError(t, err)
+	release()
+}
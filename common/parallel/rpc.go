@@ -22,7 +22,7 @@ type RpcResult[T any] struct {
 }
 
 type closable interface {
-	Close()
+	Close() error
 }
 
 // rpcExecutor is used for RPC execution in parallel.
@@ -0,0 +1,147 @@
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// EntryKind is the kind of entry in replay log.
+type EntryKind string
+
+const (
+	KindRPC    EntryKind = "rpc"    // attempt of RPC call
+	KindOption EntryKind = "option" // snapshot of options
+	KindSeed   EntryKind = "seed"   // seed of random number generator
+)
+
+// Entry is a line of replay log in JSON format.
+type Entry struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Kind EntryKind `json:"kind"`
+
+	// RPC metadata, where params are recorded only when payload enabled.
+	URL        string          `json:"url,omitempty"`
+	Method     string          `json:"method,omitempty"`
+	ParamsHash string          `json:"paramsHash,omitempty"`
+	ParamsSize int             `json:"paramsSize,omitempty"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	ResultSize int             `json:"resultSize,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	ErrorCode  int             `json:"errorCode,omitempty"` // JSON-RPC error code if any
+	Duration   time.Duration   `json:"duration,omitempty"`
+
+	// option snapshot or random seed
+	Name  string          `json:"name,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Seed  uint64          `json:"seed,omitempty"`
+}
+
+// HashParams returns the hash and size of RPC params in JSON format, so as to match RPC calls when replayed.
+func HashParams(args []interface{}) (string, int, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return "", 0, err
+	}
+
+	hash := sha256.Sum256(encoded)
+
+	return hex.EncodeToString(hash[:]), len(encoded), nil
+}
+
+// IsPayloadMethod indicates whether the RPC method carries file data, e.g. upload or download segments, which is
+// not recorded unless payload enabled.
+func IsPayloadMethod(method string) bool {
+	return strings.Contains(strings.ToLower(method), "segment")
+}
+
+// isSensitiveMethod indicates whether the RPC method may carry credentials in params or result, which is never
+// recorded.
+func isSensitiveMethod(method string) bool {
+	return strings.HasPrefix(method, "personal_") || strings.Contains(strings.ToLower(method), "sign")
+}
+
+// RedactURL removes credentials from URL, including the user info, e.g. basic auth credentials, values of query,
+// e.g. "?apikey=xxx", and path segments of API keys, e.g. "/v3/<key>" of RPC providers.
+func RedactURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || len(u.Host) == 0 {
+		return rawurl
+	}
+
+	u.User = nil
+
+	if len(u.RawQuery) > 0 {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			if name, _, found := strings.Cut(param, "="); found {
+				params[i] = name + "=" + redacted
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	if segments := strings.Split(u.EscapedPath(), "/"); len(segments) > 1 {
+		for i := 1; i < len(segments); i++ {
+			if isKeySegment(segments[i-1], segments[i]) {
+				segments[i] = redacted
+			}
+		}
+		u.RawPath = strings.Join(segments, "/")
+		u.Path, _ = url.PathUnescape(u.RawPath)
+	}
+
+	return u.String()
+}
+
+// isKeySegment indicates whether the path segment of URL is likely an API key, i.e. follows a version or key
+// segment, e.g. "/v3/<key>", or looks like a random token of letters and digits.
+func isKeySegment(prev, segment string) bool {
+	if len(segment) == 0 {
+		return false
+	}
+
+	return keyPrefixSegment.MatchString(prev) || (keySegment.MatchString(segment) && strings.ContainsAny(segment, "0123456789"))
+}
+
+const redacted = "[REDACTED]"
+
+var (
+	sensitiveField   = regexp.MustCompile(`(?i)key|secret|passw|token|auth|credential|mnemonic|private`)
+	keyPrefixSegment = regexp.MustCompile(`(?i)^(v\d+|key|api-?key|token)$`)
+	keySegment       = regexp.MustCompile(`^[A-Za-z0-9_-]{24,}$`)
+)
+
+// redact replaces values of sensitive fields with placeholder, and removes user info of URLs recursively.
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, field := range v {
+			if sensitiveField.MatchString(name) {
+				v[name] = redacted
+			} else {
+				v[name] = redact(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	case string:
+		if strings.Contains(v, "://") {
+			return RedactURL(v)
+		}
+	}
+
+	return value
+}
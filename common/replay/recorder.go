@@ -0,0 +1,183 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/rpc"
+	"github.com/0glabs/0g-storage-client/common/util"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/pkg/errors"
+)
+
+// RecorderOption is the option to record replay log.
+type RecorderOption struct {
+	// IncludePayload records params and results of RPCs that carry file data, e.g. segments, which may lead
+	// to a huge log file.
+	IncludePayload bool
+}
+
+// Recorder records RPC metadata, option snapshots and random seeds into replay log line by line, so as to
+// debug failed transfers deterministically. Note, private keys and auth credentials are never recorded.
+type Recorder struct {
+	option RecorderOption
+
+	mu      sync.Mutex
+	seq     uint64
+	encoder *json.Encoder
+	closer  io.Closer
+	err     error // first write error
+}
+
+// NewRecorder creates a recorder that writes replay log to the specified writer.
+func NewRecorder(w io.Writer, option ...RecorderOption) *Recorder {
+	var opt RecorderOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	return &Recorder{
+		option:  opt,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// CreateRecorder creates a recorder that writes replay log to the specified file, which is truncated if exists.
+func CreateRecorder(path string, option ...RecorderOption) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create replay log file")
+	}
+
+	recorder := NewRecorder(file, option...)
+	recorder.closer = file
+
+	return recorder, nil
+}
+
+// Install records RPCs of clients created afterwards and random seeds, and returns a function to uninstall.
+func (recorder *Recorder) Install() (uninstall func()) {
+	restoreHook := rpc.SetCallHook(recorder.Hook)
+	restoreSeed := util.WrapSeedFunc(func(prev func(name string) uint64) func(name string) uint64 {
+		return func(name string) uint64 {
+			seed := prev(name)
+			recorder.RecordSeed(name, seed)
+			return seed
+		}
+	})
+
+	return func() {
+		restoreHook()
+		restoreSeed()
+	}
+}
+
+// Hook returns the middleware to record RPCs sent to the specified URL, which could be set by rpc.SetCallHook.
+func (recorder *Recorder) Hook(url string) providers.CallContextMiddleware {
+	url = RedactURL(url)
+
+	return func(handler providers.CallContextFunc) providers.CallContextFunc {
+		return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+			start := time.Now()
+			err := handler(ctx, result, method, args...)
+			recorder.recordRPC(url, method, args, result, err, time.Since(start))
+			return err
+		}
+	}
+}
+
+func (recorder *Recorder) recordRPC(url, method string, args []interface{}, result interface{}, callErr error, duration time.Duration) {
+	entry := Entry{
+		Kind:     KindRPC,
+		URL:      url,
+		Method:   method,
+		Duration: duration,
+	}
+
+	var err error
+	if entry.ParamsHash, entry.ParamsSize, err = HashParams(args); err != nil {
+		entry.Error = errors.WithMessage(err, "Failed to encode params").Error()
+		recorder.write(&entry)
+		return
+	}
+
+	sensitive := isSensitiveMethod(method)
+	payload := IsPayloadMethod(method)
+
+	if recorder.option.IncludePayload && payload && !sensitive {
+		entry.Params, _ = json.Marshal(args)
+	}
+
+	if callErr != nil {
+		entry.Error = callErr.Error()
+		if rpcErr, ok := callErr.(interface{ ErrorCode() int }); ok {
+			entry.ErrorCode = rpcErr.ErrorCode()
+		}
+	} else if encoded, err := json.Marshal(result); err == nil {
+		entry.ResultSize = len(encoded)
+		if !sensitive && (!payload || recorder.option.IncludePayload) {
+			entry.Result = encoded
+		}
+	}
+
+	recorder.write(&entry)
+}
+
+// RecordOption records the snapshot of option with the specified name, where sensitive fields, e.g. private key,
+// are redacted.
+func (recorder *Recorder) RecordOption(name string, option interface{}) {
+	entry := Entry{Kind: KindOption, Name: name}
+
+	// encode twice to redact fields in a generic way
+	encoded, err := json.Marshal(option)
+	if err == nil {
+		var value interface{}
+		if err = json.Unmarshal(encoded, &value); err == nil {
+			entry.Value, err = json.Marshal(redact(value))
+		}
+	}
+
+	if err != nil {
+		entry.Error = errors.WithMessage(err, "Failed to encode option").Error()
+	}
+
+	recorder.write(&entry)
+}
+
+// RecordSeed records the seed of random number generator with the specified name.
+func (recorder *Recorder) RecordSeed(name string, seed uint64) {
+	recorder.write(&Entry{Kind: KindSeed, Name: name, Seed: seed})
+}
+
+func (recorder *Recorder) write(entry *Entry) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.seq++
+	entry.Seq = recorder.seq
+	entry.Time = time.Now()
+
+	if err := recorder.encoder.Encode(entry); err != nil && recorder.err == nil {
+		recorder.err = err
+	}
+}
+
+// Close closes the replay log file if created by recorder, and returns the first write error if any.
+func (recorder *Recorder) Close() error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if recorder.closer != nil {
+		if err := recorder.closer.Close(); err != nil && recorder.err == nil {
+			recorder.err = err
+		}
+
+		recorder.closer = nil
+	}
+
+	return recorder.err
+}
@@ -1,31 +1,229 @@
 package rpc
 
 import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common"
+	"github.com/gorilla/websocket"
+	gorpc "github.com/openweb3/go-rpc-provider"
 	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/valyala/fasthttp"
+)
+
+// Default values of provider option, which is consistent with providers.NewProviderWithOption.
+const (
+	defaultRetryInterval  = time.Second
+	defaultRequestTimeout = 30 * time.Second
+	wsBufferSize          = 1024
 )
 
 // Client is a base class of any RPC client.
 type Client struct {
 	*providers.MiddlewarableProvider
 	url string
+
+	owned      bool             // whether the underlying provider is created and owned by client
+	httpClient *fasthttp.Client // owned HTTP transport, nil for other protocols
+	closeOnce  sync.Once
+}
+
+var (
+	callHookMu sync.RWMutex
+	callHook   func(url string) providers.CallContextMiddleware
+)
+
+// SetCallHook sets the hook to intercept every attempt of RPC calls of clients created afterwards, e.g. to record or
+// replay RPCs for debugging, and returns a function to restore the previous hook. Clients keep the hook at creation,
+// so it should be set before any client created. Note, batch calls are not intercepted. It is safe for concurrent
+// use with clients created.
+func SetCallHook(hook func(url string) providers.CallContextMiddleware) (restore func()) {
+	callHookMu.Lock()
+	defer callHookMu.Unlock()
+
+	prev := callHook
+	callHook = hook
+
+	return func() {
+		callHookMu.Lock()
+		defer callHookMu.Unlock()
+
+		callHook = prev
+	}
 }
 
-// NewClient creates a new client instance.
+// NewClient creates a new client instance, which connects to RPC server with DefaultProxy.
 func NewClient(url string, option ...providers.Option) (*Client, error) {
+	return NewClientWithProxy(url, DefaultProxy, option...)
+}
+
+// NewClientWithProxy creates a new client instance, which connects to RPC server with the specified proxy
+// option over HTTP or websocket.
+func NewClientWithProxy(url string, proxy ProxyOption, option ...providers.Option) (*Client, error) {
 	var opt providers.Option
 	if len(option) > 0 {
 		opt = option[0]
 	}
 
-	provider, err := providers.NewProviderWithOption(url, opt)
+	if err := ValidateOption(opt); err != nil {
+		return nil, err
+	}
+
+	dial, err := proxy.dialer(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if isWebsocket(url) && dial != nil {
+		inner, err := gorpc.DialWebsocketWithDialer(context.Background(), url, "", websocket.Dialer{
+			NetDialContext:  dial,
+			ReadBufferSize:  wsBufferSize,
+			WriteBufferSize: wsBufferSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &Client{
+			MiddlewarableProvider: wrapProvider(hookProvider(providers.NewMiddlewarableProvider(inner), url), opt),
+			url:                   url,
+			owned:                 true,
+		}, nil
+	}
+
+	if !isHTTP(url) {
+		provider, err := providers.NewBaseProvider(context.Background(), url)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Client{MiddlewarableProvider: wrapProvider(hookProvider(provider, url), opt), url: url, owned: true}, nil
+	}
+
+	// create HTTP transport explicitly, so as to close idle connections when client closed, and send the User-Agent
+	// of client, so that RPCs could be correlated with the client version by servers that log user agents
+	httpClient := &fasthttp.Client{Name: common.UserAgent(), MaxConnsPerHost: opt.MaxConnectionPerHost}
+	if dial != nil {
+		httpClient.Dial = func(addr string) (net.Conn, error) {
+			return dial(context.Background(), "tcp", addr)
+		}
+	}
+
+	inner, err := gorpc.DialHTTPWithClient(url, httpClient)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{provider, url}, nil
+	return &Client{
+		MiddlewarableProvider: wrapProvider(hookProvider(providers.NewMiddlewarableProvider(inner), url), opt),
+		url:                   url,
+		owned:                 true,
+		httpClient:            httpClient,
+	}, nil
+}
+
+// NewClientWithProvider creates a new client instance with the specified provider, which is owned by caller.
+// So, the provider will not be closed when client closed, and caller should close it once no longer in use.
+func NewClientWithProvider(url string, provider *providers.MiddlewarableProvider) *Client {
+	return &Client{MiddlewarableProvider: provider, url: url}
 }
 
 // URL Get the RPC server URL the client connected to.
 func (c *Client) URL() string {
 	return c.url
 }
+
+// Close closes the underlying provider and idle connections if owned by client. It is safe to close for
+// multiple times.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		if !c.owned {
+			return
+		}
+
+		c.MiddlewarableProvider.Close()
+
+		if c.httpClient != nil {
+			c.httpClient.CloseIdleConnections()
+		}
+	})
+
+	return nil
+}
+
+// ValidateOption checks the provider option, and returns an error that names the invalid field if any.
+func ValidateOption(option providers.Option) error {
+	return common.FirstError(
+		common.RequireNonNegative("RetryCount", option.RetryCount),
+		common.RequireNonNegative("RetryInterval", option.RetryInterval),
+		common.RequireNonNegative("RequestTimeout", option.RequestTimeout),
+		common.RequireNonNegative("MaxConnectionPerHost", option.MaxConnectionPerHost),
+	)
+}
+
+func isHTTP(rawurl string) bool {
+	u, err := url.Parse(rawurl)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+func isWebsocket(rawurl string) bool {
+	u, err := url.Parse(rawurl)
+	return err == nil && (u.Scheme == "ws" || u.Scheme == "wss")
+}
+
+// hookProvider hooks the call hook if any to the base provider, so that each retry is intercepted separately.
+func hookProvider(p *providers.MiddlewarableProvider, url string) *providers.MiddlewarableProvider {
+	callHookMu.RLock()
+	hook := callHook
+	callHookMu.RUnlock()
+
+	if hook != nil {
+		p.HookCallContext(hook(url))
+	}
+
+	return p
+}
+
+// wrapProvider wraps provider with middlewares in the same way as providers.NewProviderWithOption.
+func wrapProvider(p *providers.MiddlewarableProvider, option providers.Option) *providers.MiddlewarableProvider {
+	if option.RetryInterval == 0 {
+		option.RetryInterval = defaultRetryInterval
+	}
+
+	if option.RequestTimeout == 0 {
+		option.RequestTimeout = defaultRequestTimeout
+	}
+
+	if option.CircuitBreaker != nil {
+		p = providers.NewCircuitBreakerProvider(p, option.CircuitBreaker)
+	}
+
+	p = providers.NewTimeoutableProvider(p, option.RequestTimeout)
+	p = providers.NewRetriableProvider(p, option.RetryCount, option.RetryInterval)
+	p = providers.NewLoggerProvider(p, option.Logger)
+
+	return p
+}
+
+// jsonError is a JSON-RPC error responded by server. Note, it is named after the one in go-rpc-provider, so that
+// it is not retried by providers.NewRetriableProvider either.
+type jsonError struct {
+	Code    int
+	Message string
+}
+
+// NewJSONError creates a JSON-RPC error with the specified code and message, e.g. to replay RPC errors.
+func NewJSONError(code int, message string) error {
+	return &jsonError{code, message}
+}
+
+func (err *jsonError) Error() string {
+	return err.Message
+}
+
+func (err *jsonError) ErrorCode() int {
+	return err.Code
+}
@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common"
+	gorpc "github.com/openweb3/go-rpc-provider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientUserAgent(t *testing.T) {
+	server := gorpc.NewServer()
+	assert.NoError(t, server.RegisterName("test", testService{}))
+	defer server.Stop()
+
+	var mu sync.Mutex
+	var agents []string
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents = append(agents, r.UserAgent())
+		mu.Unlock()
+		server.ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	client, err := NewClientWithProxy(httpServer.URL, ProxyOption{Direct: true})
+	assert.NoError(t, err)
+	defer client.Close()
+	assertEcho(t, client)
+
+	// other RPC clients in process not affected
+	other, err := gorpc.DialHTTP(httpServer.URL)
+	assert.NoError(t, err)
+	defer other.Close()
+	var result string
+	assert.NoError(t, other.Call(&result, "test_echo", "hello"))
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Equal(t, 2, len(agents)) {
+		assert.Regexp(t, regexp.MustCompile(`^0g-storage-client/\S+ \(go[^;]+; \w+/\w+\)$`), agents[0])
+		assert.Equal(t, common.UserAgent(), agents[0])
+		assert.NotContains(t, agents[1], common.ClientName)
+	}
+}
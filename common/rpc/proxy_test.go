@@ -0,0 +1,293 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	gorpc "github.com/openweb3/go-rpc-provider"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type testService struct{}
+
+func (testService) Echo(s string) string { return s }
+
+// newTestRPCServers starts RPC servers over HTTP and websocket.
+func newTestRPCServers(t *testing.T) (httpURL, wsURL string) {
+	server := gorpc.NewServer()
+	assert.NoError(t, server.RegisterName("test", testService{}))
+
+	httpServer := httptest.NewServer(server)
+	wsServer := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	t.Cleanup(func() {
+		httpServer.Close()
+		wsServer.Close()
+		server.Stop()
+	})
+
+	return httpServer.URL, "ws" + strings.TrimPrefix(wsServer.URL, "http")
+}
+
+// testProxy records the target addresses that clients connect to through proxy.
+type testProxy struct {
+	mu      sync.Mutex
+	targets []string
+}
+
+func (proxy *testProxy) connect(target string) (net.Conn, error) {
+	proxy.mu.Lock()
+	proxy.targets = append(proxy.targets, target)
+	proxy.mu.Unlock()
+
+	return net.Dial("tcp", target)
+}
+
+func (proxy *testProxy) Targets() []string {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+
+	return append([]string(nil), proxy.targets...)
+}
+
+func pipe(a, b net.Conn) {
+	go func() {
+		io.Copy(a, b)
+		a.Close()
+	}()
+
+	io.Copy(b, a)
+	b.Close()
+}
+
+// newTestSocksProxy starts a SOCKS5 proxy that supports CONNECT command without authentication.
+func newTestSocksProxy(t *testing.T) (*testProxy, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	proxy := testProxy{}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go proxy.serveSocks(conn)
+		}
+	}()
+
+	return &proxy, listener.Addr().String()
+}
+
+func (proxy *testProxy) serveSocks(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	// greeting: VER, NMETHODS, METHODS
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(reader, make([]byte, header[1])); err != nil {
+		return
+	}
+	conn.Write([]byte{5, 0})
+
+	// request: VER, CMD, RSV, ATYP, DST.ADDR, DST.PORT
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(reader, request); err != nil {
+		return
+	}
+
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(reader, ip)
+		host = net.IP(ip).String()
+	case 3:
+		length, _ := reader.ReadByte()
+		name := make([]byte, length)
+		io.ReadFull(reader, name)
+		host = string(name)
+	default:
+		return
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(reader, port); err != nil {
+		return
+	}
+
+	target, err := proxy.connect(net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	pipe(target, conn)
+}
+
+// newTestHTTPProxy starts an HTTP proxy that supports CONNECT method.
+func newTestHTTPProxy(t *testing.T) (*testProxy, string) {
+	proxy := testProxy{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+
+		target, err := proxy.connect(r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		pipe(target, conn)
+	}))
+	t.Cleanup(server.Close)
+
+	return &proxy, server.URL
+}
+
+func assertEcho(t *testing.T, client *Client) {
+	var result string
+	assert.NoError(t, client.CallContext(context.Background(), &result, "test_echo", "hello"))
+	assert.Equal(t, "hello", result)
+}
+
+func TestClientWithSocksProxy(t *testing.T) {
+	httpURL, wsURL := newTestRPCServers(t)
+
+	for _, rawurl := range []string{httpURL, wsURL} {
+		proxy, proxyAddr := newTestSocksProxy(t)
+
+		client, err := NewClientWithProxy(rawurl, ProxyOption{URL: "socks5://" + proxyAddr})
+		assert.NoError(t, err)
+		assertEcho(t, client)
+		client.Close()
+
+		u, _ := url.Parse(rawurl)
+		assert.Equal(t, []string{u.Host}, proxy.Targets(), rawurl)
+	}
+}
+
+func TestClientWithHTTPProxy(t *testing.T) {
+	httpURL, wsURL := newTestRPCServers(t)
+
+	for _, rawurl := range []string{httpURL, wsURL} {
+		proxy, proxyURL := newTestHTTPProxy(t)
+
+		client, err := NewClientWithProxy(rawurl, ProxyOption{URL: proxyURL})
+		assert.NoError(t, err)
+		assertEcho(t, client)
+		client.Close()
+
+		u, _ := url.Parse(rawurl)
+		assert.Equal(t, []string{u.Host}, proxy.Targets(), rawurl)
+	}
+}
+
+func TestClientWithDoH(t *testing.T) {
+	httpURL, _ := newTestRPCServers(t)
+
+	var queries []string
+	resolver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		var query dnsmessage.Message
+		assert.NoError(t, query.Unpack(body))
+		queries = append(queries, query.Questions[0].Name.String())
+
+		answer := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true},
+			Questions: query.Questions,
+		}
+		if query.Questions[0].Type == dnsmessage.TypeA {
+			answer.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: query.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+				Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+			}}
+		}
+
+		content, err := answer.Pack()
+		assert.NoError(t, err)
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(content)
+	}))
+	defer resolver.Close()
+
+	u, _ := url.Parse(httpURL)
+	client, err := NewClientWithProxy("http://rpc.test:"+u.Port(), ProxyOption{Direct: true, DoH: resolver.URL})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assertEcho(t, client)
+	assert.Equal(t, []string{"rpc.test."}, queries)
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "socks5://proxy.example.com:1080")
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("NO_PROXY", "internal.example.com")
+
+	for rawurl, expected := range map[string]string{
+		"https://node.example.com":     "socks5://proxy.example.com:1080",
+		"wss://node.example.com":       "socks5://proxy.example.com:1080",
+		"http://node.example.com":      "",
+		"https://internal.example.com": "",
+	} {
+		u, _ := url.Parse(rawurl)
+		proxyURL, err := ProxyOption{}.proxyURL(u)
+		assert.NoError(t, err)
+
+		if len(expected) == 0 {
+			assert.Nil(t, proxyURL, rawurl)
+		} else {
+			assert.Equal(t, expected, proxyURL.String(), rawurl)
+		}
+	}
+
+	// overridden by explicit option
+	u, _ := url.Parse("https://node.example.com")
+	proxyURL, err := ProxyOption{URL: "http://127.0.0.1:3128"}.proxyURL(u)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:3128", proxyURL.String())
+
+	proxyURL, err = ProxyOption{Direct: true}.proxyURL(u)
+	assert.NoError(t, err)
+	assert.Nil(t, proxyURL)
+}
+
+func TestProxyOptionValidate(t *testing.T) {
+	assert.NoError(t, ProxyOption{}.Validate())
+	assert.NoError(t, ProxyOption{URL: "socks5://127.0.0.1:1080", DoH: "https://1.1.1.1/dns-query"}.Validate())
+	assert.ErrorContains(t, ProxyOption{URL: "ftp://127.0.0.1"}.Validate(), "invalid option URL")
+	assert.ErrorContains(t, ProxyOption{DoH: "udp://1.1.1.1"}.Validate(), "invalid option DoH")
+
+	_, err := NewClientWithProxy("http://127.0.0.1:1234", ProxyOption{URL: "ftp://127.0.0.1"})
+	assert.ErrorContains(t, err, "unsupported proxy scheme")
+}
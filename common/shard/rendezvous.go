@@ -0,0 +1,44 @@
+package shard
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// RendezvousScore returns the score of node identified by id for the key, e.g. file root, by rendezvous hashing.
+// Nodes sorted by score in descending order are preferred for the key, and removing a node does not change the
+// order of the others, so that the same key always prefers the same nodes across processes.
+func RendezvousScore(key common.Hash, id string) uint64 {
+	return binary.BigEndian.Uint64(crypto.Keccak256(key.Bytes(), []byte(id)))
+}
+
+// SortRendezvous sorts nodes in place by rendezvous score of URL for the key in descending order, see
+// RendezvousScore.
+func SortRendezvous(key common.Hash, nodes []*ShardedNode) []*ShardedNode {
+	scores := make(map[*ShardedNode]uint64, len(nodes))
+	for _, node := range nodes {
+		scores[node] = RendezvousScore(key, node.URL)
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return scores[nodes[i]] > scores[nodes[j]]
+	})
+
+	return nodes
+}
+
+// SelectConsistent is similar to SelectPreferred, but supplements the preferred nodes with the given nodes in
+// rendezvous order for the key, see SortRendezvous, so that the same subset of nodes is selected for the same key
+// deterministically, and only the nodes for shards of a removed node change.
+func SelectConsistent(key common.Hash, preferred, nodes []*ShardedNode, requirement ReplicaRequirement) ([]*ShardedNode, error) {
+	if err := requirement.Validate(); err != nil {
+		return nil, err
+	}
+
+	nodes = SortRendezvous(key, nodes)
+
+	return selectInOrder(append(append([]*ShardedNode{}, preferred...), nodes...), requirement)
+}
@@ -0,0 +1,92 @@
+package shard
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gotest.tools/assert"
+)
+
+// makeRendezvousNodes returns nodes of n URLs for each of the shards.
+func makeRendezvousNodes(numShard uint64, n int) []*ShardedNode {
+	var nodes []*ShardedNode
+	for shardId := uint64(0); shardId < numShard; shardId++ {
+		for i := 0; i < n; i++ {
+			nodes = append(nodes, &ShardedNode{
+				URL:    fmt.Sprintf("http://node-%v-%v:5678", shardId, i),
+				Config: ShardConfig{NumShard: numShard, ShardId: shardId},
+			})
+		}
+	}
+
+	return nodes
+}
+
+func urlsOf(nodes []*ShardedNode) []string {
+	urls := make([]string, len(nodes))
+	for i, node := range nodes {
+		urls[i] = node.URL
+	}
+
+	return urls
+}
+
+func TestSelectConsistentStable(t *testing.T) {
+	key := common.HexToHash("0x1234")
+	requirement := UniformReplica(2)
+
+	expected, err := SelectConsistent(key, nil, makeRendezvousNodes(4, 5), requirement)
+	assert.NilError(t, err)
+	assert.Equal(t, len(expected), 8)
+
+	// stable regardless of the order of nodes
+	for i := 0; i < 10; i++ {
+		nodes := makeRendezvousNodes(4, 5)
+		prepareSelectionNodes(nodes, true)
+		selected, err := SelectConsistent(key, nil, nodes, requirement)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, urlsOf(selected), urlsOf(expected))
+	}
+
+	// different keys prefer different nodes
+	other, err := SelectConsistent(common.HexToHash("0x5678"), nil, makeRendezvousNodes(4, 5), requirement)
+	assert.NilError(t, err)
+	assert.Assert(t, fmt.Sprint(urlsOf(other)) != fmt.Sprint(urlsOf(expected)))
+}
+
+func TestSelectConsistentNodeRemoved(t *testing.T) {
+	requirement := UniformReplica(2)
+	for i := 0; i < 20; i++ {
+		root := common.BigToHash(big.NewInt(int64(i)))
+
+		nodes := makeRendezvousNodes(4, 5)
+		selected, err := SelectConsistent(root, nil, nodes, requirement)
+		assert.NilError(t, err)
+
+		// remove a selected node, and only the node is replaced
+		removed := selected[i%len(selected)].URL
+		var remaining []*ShardedNode
+		for _, node := range makeRendezvousNodes(4, 5) {
+			if node.URL != removed {
+				remaining = append(remaining, node)
+			}
+		}
+		reselected, err := SelectConsistent(root, nil, remaining, requirement)
+		assert.NilError(t, err)
+		assert.Equal(t, len(reselected), len(selected))
+
+		kept := make(map[string]bool)
+		for _, url := range urlsOf(reselected) {
+			kept[url] = true
+		}
+		var changed int
+		for _, url := range urlsOf(selected) {
+			if !kept[url] {
+				changed++
+			}
+		}
+		assert.Equal(t, changed, 1)
+	}
+}
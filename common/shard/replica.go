@@ -0,0 +1,181 @@
+package shard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/0glabs/0g-storage-client/common"
+	"github.com/pkg/errors"
+)
+
+// ShardReplica is the number of replicas of segments in a shard, i.e. segments whose index in flow modulo
+// NumShard equals to ShardId.
+type ShardReplica struct {
+	Shard   ShardConfig `json:"shard"`
+	Replica uint        `json:"replica"`
+}
+
+func (sr ShardReplica) String() string {
+	return fmt.Sprintf("%v/%v=%v", sr.Shard.ShardId, sr.Shard.NumShard, sr.Replica)
+}
+
+// ParseShardReplica parses shard replica in format "<shardId>/<numShard>=<replica>", e.g. "1/4=3".
+func ParseShardReplica(s string) (ShardReplica, error) {
+	var sr ShardReplica
+
+	shard, replica, ok := strings.Cut(s, "=")
+	if !ok {
+		return sr, errors.Errorf("Invalid shard replica %q, expected format <shardId>/<numShard>=<replica>", s)
+	}
+
+	shardId, numShard, ok := strings.Cut(shard, "/")
+	if !ok {
+		return sr, errors.Errorf("Invalid shard replica %q, expected format <shardId>/<numShard>=<replica>", s)
+	}
+
+	var err error
+	if sr.Shard.ShardId, err = strconv.ParseUint(strings.TrimSpace(shardId), 10, 64); err != nil {
+		return sr, errors.WithMessagef(err, "Invalid shard id of %q", s)
+	}
+
+	if sr.Shard.NumShard, err = strconv.ParseUint(strings.TrimSpace(numShard), 10, 64); err != nil {
+		return sr, errors.WithMessagef(err, "Invalid number of shards of %q", s)
+	}
+
+	replicas, err := strconv.ParseUint(strings.TrimSpace(replica), 10, 32)
+	if err != nil {
+		return sr, errors.WithMessagef(err, "Invalid replica of %q", s)
+	}
+	sr.Replica = uint(replicas)
+
+	if !sr.Shard.IsValid() {
+		return sr, errors.Errorf("Invalid shard of %q, number of shards should be power of 2 and larger than shard id", s)
+	}
+
+	return sr, nil
+}
+
+// ReplicaRequirement is the number of replicas required for segments by shard. Each segment is required to be
+// replicated for the max replica of shards that contain the segment, or Default if not contained by any shard.
+//
+// For example, {Default: 1, Shards: [1/4=3]} requires 3 replicas for segments whose index in flow modulo 4
+// equals to 1, and 1 replica elsewhere.
+type ReplicaRequirement struct {
+	Default uint           `json:"default"`
+	Shards  []ShardReplica `json:"shards,omitempty"`
+}
+
+// UniformReplica requires the same number of replicas for all segments.
+func UniformReplica(replica uint) ReplicaRequirement {
+	return ReplicaRequirement{Default: replica}
+}
+
+// Validate checks the requirement, and returns an error that names the invalid field if any.
+func (requirement ReplicaRequirement) Validate() error {
+	for _, sr := range requirement.Shards {
+		if !sr.Shard.IsValid() {
+			return common.NewOptionError("Shards", "number of shards should be power of 2 and larger than shard id, got %v/%v",
+				sr.Shard.ShardId, sr.Shard.NumShard)
+		}
+	}
+
+	return nil
+}
+
+// Max returns the max number of replicas required by any segment.
+func (requirement ReplicaRequirement) Max() uint {
+	return requirement.maxReplica(ShardConfig{NumShard: 1})
+}
+
+// Merge returns a requirement that satisfies both requirements.
+func (requirement ReplicaRequirement) Merge(other ReplicaRequirement) ReplicaRequirement {
+	return ReplicaRequirement{
+		Default: max(requirement.Default, other.Default),
+		Shards:  append(append([]ShardReplica(nil), requirement.Shards...), other.Shards...),
+	}
+}
+
+// maxReplica returns the max number of replicas required by segments in the specified shard.
+func (requirement ReplicaRequirement) maxReplica(shard ShardConfig) uint {
+	replica := requirement.Default
+	for _, sr := range requirement.Shards {
+		if overlapped(sr.Shard, shard) {
+			replica = max(replica, sr.Replica)
+		}
+	}
+
+	return replica
+}
+
+// overlapped returns whether two shards contain any segment in common.
+func overlapped(a, b ShardConfig) bool {
+	return a.ShardId%min(a.NumShard, b.NumShard) == b.ShardId%min(a.NumShard, b.NumShard)
+}
+
+// maxAchievedNumShard is the finest granularity to count achieved replicas.
+const maxAchievedNumShard = 1 << 20
+
+// Achieved returns the number of replicas achieved by the given shard configs in the same form of requirement,
+// where Default is the min replicas of all segments, and the min replicas of segments in each shard of the
+// requirement respectively.
+func (requirement ReplicaRequirement) Achieved(shardConfigs []*ShardConfig) ReplicaRequirement {
+	// count replicas at the finest granularity, which is bounded to avoid huge memory allocation
+	numShard := uint64(1)
+	for _, config := range shardConfigs {
+		if config.IsValid() {
+			numShard = max(numShard, config.NumShard)
+		}
+	}
+	for _, sr := range requirement.Shards {
+		numShard = max(numShard, sr.Shard.NumShard)
+	}
+	numShard = min(numShard, maxAchievedNumShard)
+
+	replicas := make([]uint, numShard)
+	for _, config := range shardConfigs {
+		// storage nodes of shards finer than granularity are ignored conservatively
+		if !config.IsValid() || config.NumShard > numShard {
+			continue
+		}
+		for shardId := config.ShardId; shardId < numShard; shardId += config.NumShard {
+			replicas[shardId]++
+		}
+	}
+
+	// shards finer than granularity are regarded as the coarser one that contains it
+	minReplica := func(shard ShardConfig) uint {
+		step := min(shard.NumShard, numShard)
+		result := replicas[shard.ShardId%step]
+		for shardId := shard.ShardId % step; shardId < numShard; shardId += step {
+			result = min(result, replicas[shardId])
+		}
+		return result
+	}
+
+	achieved := ReplicaRequirement{Default: minReplica(ShardConfig{NumShard: 1})}
+	for _, sr := range requirement.Shards {
+		achieved.Shards = append(achieved.Shards, ShardReplica{sr.Shard, minReplica(sr.Shard)})
+	}
+
+	return achieved
+}
+
+// unsatisfied returns an error that describes the shards of which replicas are insufficient.
+func (requirement ReplicaRequirement) unsatisfied(shardConfigs []*ShardConfig) error {
+	achieved := requirement.Achieved(shardConfigs)
+
+	var reasons []string
+	if achieved.Default < requirement.Default {
+		reasons = append(reasons, fmt.Sprintf("all segments require %v replicas, but only %v available", requirement.Default, achieved.Default))
+	}
+
+	for i, sr := range requirement.Shards {
+		if achieved.Shards[i].Replica < sr.Replica {
+			reasons = append(reasons, fmt.Sprintf("shard %v/%v requires %v replicas, but only %v available",
+				sr.Shard.ShardId, sr.Shard.NumShard, sr.Replica, achieved.Shards[i].Replica))
+		}
+	}
+
+	return errors.Errorf("Insufficient storage nodes to meet the replica requirement: %v", strings.Join(reasons, "; "))
+}
@@ -0,0 +1,145 @@
+package shard
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// oracleLeaves is the finest granularity of shards generated in property tests.
+const oracleLeaves = 32
+
+// oracleReplicas counts replicas of each shard at the finest granularity by brute force.
+func oracleReplicas(nodes []*ShardedNode) []uint {
+	replicas := make([]uint, oracleLeaves)
+	for leaf := uint64(0); leaf < oracleLeaves; leaf++ {
+		for _, node := range nodes {
+			if leaf%node.Config.NumShard == node.Config.ShardId {
+				replicas[leaf]++
+			}
+		}
+	}
+	return replicas
+}
+
+// oracleMin returns the min replicas of segments in the specified shard by brute force.
+func oracleMin(replicas []uint, shard ShardConfig) uint {
+	result := ^uint(0)
+	for leaf := uint64(0); leaf < oracleLeaves; leaf++ {
+		if leaf%shard.NumShard == shard.ShardId {
+			result = min(result, replicas[leaf])
+		}
+	}
+	return result
+}
+
+func randomShard(rng *rand.Rand, maxNumShard int) ShardConfig {
+	numShard := uint64(1) << rng.Intn(maxNumShard)
+	return ShardConfig{NumShard: numShard, ShardId: rng.Uint64() % numShard}
+}
+
+func TestSelectByRequirementProperty(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for round := 0; round < 2000; round++ {
+		nodes := make([]*ShardedNode, rng.Intn(12))
+		for i := range nodes {
+			nodes[i] = &ShardedNode{URL: fmt.Sprint(i), Config: randomShard(rng, 5)}
+		}
+
+		requirement := ReplicaRequirement{Default: uint(rng.Intn(3))}
+		for i := rng.Intn(4); i > 0; i-- {
+			requirement.Shards = append(requirement.Shards, ShardReplica{randomShard(rng, 6), uint(rng.Intn(5))})
+		}
+
+		// expected shortfalls by brute force
+		all := oracleReplicas(nodes)
+		var reasons []string
+		if oracleMin(all, ShardConfig{NumShard: 1}) < requirement.Default {
+			reasons = append(reasons, fmt.Sprintf("all segments require %v replicas", requirement.Default))
+		}
+		for _, sr := range requirement.Shards {
+			if oracleMin(all, sr.Shard) < sr.Replica {
+				reasons = append(reasons, fmt.Sprintf("shard %v/%v requires %v replicas", sr.Shard.ShardId, sr.Shard.NumShard, sr.Replica))
+			}
+		}
+
+		// achieved replicas agree with brute force
+		configs := make([]*ShardConfig, len(nodes))
+		for i, node := range nodes {
+			configs[i] = &node.Config
+		}
+		achieved := requirement.Achieved(configs)
+		assert.Equal(t, oracleMin(all, ShardConfig{NumShard: 1}), achieved.Default)
+		for i, sr := range requirement.Shards {
+			assert.Equal(t, oracleMin(all, sr.Shard), achieved.Shards[i].Replica)
+		}
+
+		selected, err := SelectByRequirement(nodes, requirement, rng.Intn(2) == 0)
+		if len(reasons) > 0 {
+			// errors precisely with all unsatisfied shards
+			assert.Assert(t, err != nil, "round %v", round)
+			assert.Equal(t, len(reasons), strings.Count(err.Error(), "replicas, but only"), "round %v: %v", round, err)
+			for _, reason := range reasons {
+				assert.ErrorContains(t, err, reason, "round %v", round)
+			}
+			continue
+		}
+
+		// selected nodes satisfy the requirement
+		assert.NilError(t, err, "round %v", round)
+		replicas := oracleReplicas(selected)
+		for leaf := uint64(0); leaf < oracleLeaves; leaf++ {
+			assert.Assert(t, replicas[leaf] >= requirement.maxReplica(ShardConfig{NumShard: oracleLeaves, ShardId: leaf}), "round %v", round)
+		}
+	}
+}
+
+func TestSelectByRequirement(t *testing.T) {
+	// 3 replicas for hot shard 1/4, and 1 elsewhere
+	requirement := ReplicaRequirement{Default: 1, Shards: []ShardReplica{{ShardConfig{NumShard: 4, ShardId: 1}, 3}}}
+	nodes := []*ShardedNode{
+		makeShardNode(1, 0),
+		makeShardNode(4, 1),
+		makeShardNode(4, 1),
+		makeShardNode(4, 3),
+		makeShardNode(2, 1),
+		makeShardNode(2, 0),
+	}
+
+	selected, err := SelectByRequirement(nodes, requirement, false)
+	assert.NilError(t, err)
+	assert.Equal(t, len(selected), 3)
+	assert.DeepEqual(t, selected[0], makeShardNode(1, 0))
+	assert.DeepEqual(t, selected[1], makeShardNode(2, 1))
+	assert.DeepEqual(t, selected[2], makeShardNode(4, 1))
+
+	// uniform requirement is the same as before
+	selected, err = SelectByRequirement(nodes, UniformReplica(2), false)
+	assert.NilError(t, err)
+	assert.Equal(t, len(selected), 3)
+
+	// insufficient
+	requirement.Shards = append(requirement.Shards, ShardReplica{ShardConfig{NumShard: 2, ShardId: 0}, 3})
+	_, err = SelectByRequirement(nodes, requirement, false)
+	assert.Error(t, err, "Insufficient storage nodes to meet the replica requirement: shard 0/2 requires 3 replicas, but only 2 available")
+
+	// invalid shard
+	_, err = SelectByRequirement(nodes, ReplicaRequirement{Shards: []ShardReplica{{ShardConfig{NumShard: 3, ShardId: 1}, 1}}}, false)
+	assert.ErrorContains(t, err, "invalid option Shards")
+}
+
+func TestParseShardReplica(t *testing.T) {
+	sr, err := ParseShardReplica("1/4=3")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sr, ShardReplica{ShardConfig{NumShard: 4, ShardId: 1}, 3})
+	assert.Equal(t, sr.String(), "1/4=3")
+
+	for _, s := range []string{"1/4", "4=3", "a/4=3", "4/4=3", "1/3=3", "1/4=-1"} {
+		_, err = ParseShardReplica(s)
+		assert.Assert(t, err != nil, s)
+	}
+}
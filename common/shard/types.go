@@ -52,78 +52,131 @@ func NewShardNodesFromConfig(configs []*ShardConfig) []*ShardedNode {
 
 type shardSegmentTreeNode struct {
 	childs   []*shardSegmentTreeNode
-	numShard uint
+	numShard uint64
+	shardId  uint64
 	lazyTags uint
-	replica  uint
+	replica  uint // replicas inserted to the whole subtree before childs created
+	deficit  int  // max number of replicas still required by segments in subtree
 }
 
-func (node *shardSegmentTreeNode) pushdown() {
+func newShardSegmentTreeNode(numShard, shardId uint64, replica uint, requirement *ReplicaRequirement) *shardSegmentTreeNode {
+	return &shardSegmentTreeNode{
+		numShard: numShard,
+		shardId:  shardId,
+		replica:  replica,
+		deficit:  int(requirement.maxReplica(ShardConfig{ShardId: shardId, NumShard: numShard})) - int(replica),
+	}
+}
+
+// add inserts replicas to the whole subtree.
+func (node *shardSegmentTreeNode) add(replica uint) {
+	node.deficit -= int(replica)
+	if node.childs == nil {
+		node.replica += replica
+	} else {
+		node.lazyTags += replica
+	}
+}
+
+func (node *shardSegmentTreeNode) pushdown(requirement *ReplicaRequirement) {
 	if node.childs == nil {
 		node.childs = make([]*shardSegmentTreeNode, 2)
-		for i := 0; i < 2; i += 1 {
-			node.childs[i] = &shardSegmentTreeNode{
-				numShard: node.numShard << 1,
-				replica:  0,
-				lazyTags: 0,
-			}
+		for i := uint64(0); i < 2; i += 1 {
+			node.childs[i] = newShardSegmentTreeNode(node.numShard<<1, node.shardId+i*node.numShard, node.replica, requirement)
 		}
+		return
 	}
 	for i := 0; i < 2; i += 1 {
-		node.childs[i].replica += node.lazyTags
-		node.childs[i].lazyTags += node.lazyTags
+		node.childs[i].add(node.lazyTags)
 	}
 	node.lazyTags = 0
 }
 
 // insert a shard if it contributes to the replica
-func (node *shardSegmentTreeNode) insert(numShard uint, shardId uint, expectedReplica uint) bool {
-	if node.replica >= expectedReplica {
+func (node *shardSegmentTreeNode) insert(numShard uint64, shardId uint64, requirement *ReplicaRequirement) bool {
+	if node.deficit <= 0 {
 		return false
 	}
 	if node.numShard == numShard {
-		node.replica += 1
-		node.lazyTags += 1
+		node.add(1)
 		return true
 	}
-	node.pushdown()
-	inserted := node.childs[shardId%2].insert(numShard, shardId>>1, expectedReplica)
-	node.replica = min(node.childs[0].replica, node.childs[1].replica)
+	node.pushdown(requirement)
+	inserted := node.childs[(shardId/node.numShard)%2].insert(numShard, shardId, requirement)
+	node.deficit = max(node.childs[0].deficit, node.childs[1].deficit)
 	return inserted
 }
 
 // select a set of given sharded node and make the data is replicated at least expctedReplica times
 // return the selected nodes and if selection is successful
 func Select(nodes []*ShardedNode, expectedReplica uint, random bool) ([]*ShardedNode, bool) {
-	selected := make([]*ShardedNode, 0)
-	if expectedReplica == 0 {
-		return selected, true
+	selected, err := SelectByRequirement(nodes, UniformReplica(expectedReplica), random)
+	if err != nil {
+		return make([]*ShardedNode, 0), false
+	}
+	return selected, true
+}
+
+// SelectByRequirement selects a set of given sharded nodes, so that segments of each shard are replicated as
+// required. Returns an error that describes the unsatisfied shards if the given nodes are insufficient.
+func SelectByRequirement(nodes []*ShardedNode, requirement ReplicaRequirement, random bool) ([]*ShardedNode, error) {
+	if err := requirement.Validate(); err != nil {
+		return nil, err
 	}
 
 	// shuffle or sort nodes before selection
+	return selectInOrder(prepareSelectionNodes(nodes, random), requirement)
+}
+
+// SelectPreferred is similar to SelectByRequirement, but selects the preferred nodes in order at first, e.g. the
+// storage node co-located, and then supplements with the given nodes for shards not covered by preferred nodes.
+func SelectPreferred(preferred, nodes []*ShardedNode, requirement ReplicaRequirement, random bool) ([]*ShardedNode, error) {
+	if err := requirement.Validate(); err != nil {
+		return nil, err
+	}
+
 	nodes = prepareSelectionNodes(nodes, random)
 
-	// build segment tree to select proper nodes by shard configs
-	root := shardSegmentTreeNode{
-		numShard: 1,
-		replica:  0,
-		lazyTags: 0,
+	return selectInOrder(append(append([]*ShardedNode{}, preferred...), nodes...), requirement)
+}
+
+// selectInOrder selects nodes in order until segments of each shard are replicated as required.
+func selectInOrder(nodes []*ShardedNode, requirement ReplicaRequirement) ([]*ShardedNode, error) {
+	selected := make([]*ShardedNode, 0)
+	root := newShardSegmentTreeNode(1, 0, 0, &requirement)
+	if root.deficit <= 0 {
+		return selected, nil
 	}
 
+	// build segment tree to select proper nodes by shard configs
 	for _, node := range nodes {
-		if root.insert(uint(node.Config.NumShard), uint(node.Config.ShardId), expectedReplica) {
+		if !node.Config.IsValid() {
+			continue
+		}
+		if root.insert(node.Config.NumShard, node.Config.ShardId, &requirement) {
 			selected = append(selected, node)
 		}
-		if root.replica >= expectedReplica {
-			return selected, true
+		if root.deficit <= 0 {
+			return selected, nil
 		}
 	}
-	return make([]*ShardedNode, 0), false
+
+	configs := make([]*ShardConfig, len(nodes))
+	for i, node := range nodes {
+		configs[i] = &node.Config
+	}
+	return nil, requirement.unsatisfied(configs)
 }
 
 func CheckReplica(shardConfigs []*ShardConfig, expectedReplica uint) bool {
-	shardedNodes := NewShardNodesFromConfig(shardConfigs)
-	_, ok := Select(shardedNodes, expectedReplica, false)
-	return ok
+	return CheckRequirement(shardConfigs, UniformReplica(expectedReplica)) == nil
+}
+
+// CheckRequirement returns an error that describes the unsatisfied shards if segments replicated by the
+// given shard configs do not meet the requirement.
+func CheckRequirement(shardConfigs []*ShardConfig, requirement ReplicaRequirement) error {
+	_, err := SelectByRequirement(NewShardNodesFromConfig(shardConfigs), requirement, false)
+	return err
 }
 
 // Helper function to pre-process (sort or shuffle) the nodes before selection
@@ -123,3 +123,23 @@ func TestNextSegmentIndex(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectPreferred(t *testing.T) {
+	shardedNodes := []*ShardedNode{
+		makeShardNode(1, 0),
+		makeShardNode(2, 0),
+		makeShardNode(2, 1),
+	}
+
+	// preferred node selected first, and supplemented for the other shard
+	selected, err := SelectPreferred([]*ShardedNode{makeShardNode(2, 1)}, shardedNodes, UniformReplica(1), false)
+	assert.NilError(t, err)
+	assert.Equal(t, len(selected), 2)
+	assert.DeepEqual(t, selected[0], makeShardNode(2, 1))
+	assert.DeepEqual(t, selected[1], makeShardNode(1, 0))
+
+	// preferred node not selected if not required
+	selected, err = SelectPreferred([]*ShardedNode{makeShardNode(2, 1)}, shardedNodes, UniformReplica(0), false)
+	assert.NilError(t, err)
+	assert.Equal(t, len(selected), 0)
+}
@@ -0,0 +1,597 @@
+package testutil
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/contract"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/openweb3/web3go/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultChainId is the chain id of Chain by default.
+	DefaultChainId uint64 = 31337
+
+	// PrivateKey is a private key to send transactions to Chain, since balance is not required.
+	PrivateKey = "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+)
+
+var (
+	// FlowAddress is the address of flow contract on Chain.
+	FlowAddress = common.HexToAddress("0x0000000000000000000000000000000000000f10")
+	// MarketAddress is the address of market contract on Chain.
+	MarketAddress = common.HexToAddress("0x00000000000000000000000000000000000a4e70")
+
+	// DefaultPricePerSector is the storage price per sector on Chain by default.
+	DefaultPricePerSector = big.NewInt(1_000_000_000)
+
+	// DefaultBalance is the balance of accounts on Chain unless specified, which is 1 million a0gi.
+	DefaultBalance = new(big.Int).Mul(big.NewInt(1_000_000), big.NewInt(1e18))
+)
+
+// ErrReverted is returned by submit hook to revert transaction.
+var ErrReverted = errors.New("execution reverted")
+
+// Chain is an in-memory blockchain that hosts the flow and market contracts of 0g storage, and implements the eth
+// RPCs used by this client. Each transaction is executed once received in a new block, and files submitted to the
+// flow contract are appended to the connected storage nodes as if synchronized from chain.
+//
+// Note, only submit and batchSubmit of flow contract are supported to send transactions, and balance of sender
+// is reported by eth_getBalance only, which is neither checked nor charged by transactions.
+type Chain struct {
+	mu sync.Mutex
+
+	chainId        uint64
+	pricePerSector *big.Int
+	blockNumber    uint64
+	nextPos        uint64 // flow position of next submission in entries
+	nonces         map[common.Address]uint64
+	balances       map[common.Address]*big.Int // DefaultBalance if not specified
+	receipts       map[common.Hash]*types.Receipt
+	txs            map[common.Hash]*types.TransactionDetail
+	logs           []*types.Log
+	submissions    []contract.Submission
+	nodes          []*ZgsService
+	onSubmit       func(sender common.Address, submission contract.Submission) error
+	onSend         func(sender common.Address) error
+}
+
+// NewChain creates a blockchain of DefaultChainId, which is empty except for the genesis block.
+func NewChain() *Chain {
+	return &Chain{
+		chainId:        DefaultChainId,
+		pricePerSector: DefaultPricePerSector,
+		nonces:         make(map[common.Address]uint64),
+		balances:       make(map[common.Address]*big.Int),
+		receipts:       make(map[common.Hash]*types.Receipt),
+		txs:            make(map[common.Hash]*types.TransactionDetail),
+	}
+}
+
+// ChainId returns the chain id.
+func (chain *Chain) ChainId() uint64 {
+	return chain.chainId
+}
+
+// SetPricePerSector sets the storage price per sector of market contract.
+func (chain *Chain) SetPricePerSector(price *big.Int) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.pricePerSector = new(big.Int).Set(price)
+}
+
+// SetBalance sets the balance of account reported by eth_getBalance.
+func (chain *Chain) SetBalance(account common.Address, balance *big.Int) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.balances[account] = new(big.Int).Set(balance)
+}
+
+// SetSubmitHook sets the hook called before each file submitted to flow contract, which reverts the transaction
+// if error returned.
+func (chain *Chain) SetSubmitHook(hook func(sender common.Address, submission contract.Submission) error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.onSubmit = hook
+}
+
+// SetSendHook sets the hook called before each transaction executed, which rejects the transaction without
+// consuming nonce if error returned, e.g. to simulate insufficient funds of sender.
+func (chain *Chain) SetSendHook(hook func(sender common.Address) error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.onSend = hook
+}
+
+// Connect connects storage nodes to sync files from the flow contract, including the files submitted before.
+func (chain *Chain) Connect(nodes ...*ZgsService) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	for _, node := range nodes {
+		node.mu.Lock()
+		node.identity.ChainId = chain.chainId
+		node.identity.FlowContractAddress = FlowAddress
+		node.mu.Unlock()
+
+		for _, submission := range chain.submissions {
+			node.append(submission.Root(), submission.Length.Uint64(), submission.Tags)
+		}
+	}
+
+	chain.nodes = append(chain.nodes, nodes...)
+}
+
+// Submit submits the content with tags to flow contract without transaction, e.g. to test uploads with
+// transaction skipped, and returns the merkle root.
+func (chain *Chain) Submit(content, tags []byte) (common.Hash, error) {
+	data, err := core.NewDataInMemory(content)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	submission, err := core.NewSubmission(data, tags)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.appendLocked(*submission)
+
+	return submission.Root(), nil
+}
+
+// Submissions returns all files submitted to flow contract in order of tx seq.
+func (chain *Chain) Submissions() []contract.Submission {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return append([]contract.Submission(nil), chain.submissions...)
+}
+
+func (chain *Chain) appendLocked(submission contract.Submission) uint64 {
+	seq := uint64(len(chain.submissions))
+	chain.submissions = append(chain.submissions, submission)
+	chain.nextPos += flowEntries(submission.Length.Uint64())
+
+	for _, node := range chain.nodes {
+		node.append(submission.Root(), submission.Length.Uint64(), submission.Tags)
+	}
+
+	return seq
+}
+
+// execute executes the signed transaction in a new block.
+func (chain *Chain) execute(tx *ethtypes.Transaction) (common.Hash, error) {
+	sender, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(new(big.Int).SetUint64(chain.chainId)), tx)
+	if err != nil {
+		return common.Hash{}, errors.WithMessage(err, "invalid sender")
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	if chain.onSend != nil {
+		if err := chain.onSend(sender); err != nil {
+			return common.Hash{}, err
+		}
+	}
+
+	if nonce := chain.nonces[sender]; tx.Nonce() < nonce {
+		return common.Hash{}, errors.Errorf("nonce too low: address %v, tx: %v state: %v", sender, tx.Nonce(), nonce)
+	} else if tx.Nonce() > nonce {
+		return common.Hash{}, errors.Errorf("nonce too high: address %v, tx: %v state: %v", sender, tx.Nonce(), nonce)
+	}
+
+	if _, ok := chain.receipts[tx.Hash()]; ok {
+		return common.Hash{}, errors.New("already known")
+	}
+
+	submissions, err := chain.decodeSubmissions(tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	chain.nonces[sender]++
+	chain.blockNumber++
+
+	status := ethtypes.ReceiptStatusSuccessful
+	logs, err := chain.submitLocked(sender, tx, submissions)
+	if err != nil {
+		status, logs = ethtypes.ReceiptStatusFailed, []*types.Log{}
+	}
+
+	chain.receipts[tx.Hash()] = &types.Receipt{
+		BlockHash:         chain.blockHash(chain.blockNumber),
+		BlockNumber:       chain.blockNumber,
+		CumulativeGasUsed: tx.Gas(),
+		EffectiveGasPrice: tx.GasPrice().Uint64(),
+		From:              sender,
+		GasUsed:           tx.Gas(),
+		Logs:              logs,
+		Status:            &status,
+		To:                tx.To(),
+		TransactionHash:   tx.Hash(),
+	}
+
+	blockHash := chain.blockHash(chain.blockNumber)
+	v, r, s := tx.RawSignatureValues()
+	chain.txs[tx.Hash()] = &types.TransactionDetail{
+		BlockHash:   &blockHash,
+		BlockNumber: new(big.Int).SetUint64(chain.blockNumber),
+		From:        sender,
+		Gas:         tx.Gas(),
+		GasPrice:    tx.GasPrice(),
+		Hash:        tx.Hash(),
+		Input:       tx.Data(),
+		Nonce:       tx.Nonce(),
+		R:           r,
+		S:           s,
+		To:          tx.To(),
+		V:           v,
+		Value:       tx.Value(),
+	}
+
+	return tx.Hash(), nil
+}
+
+// decodeSubmissions decodes the files to submit in transaction to flow contract.
+func (chain *Chain) decodeSubmissions(tx *ethtypes.Transaction) ([]contract.Submission, error) {
+	if tx.To() == nil || *tx.To() != FlowAddress {
+		return nil, errors.Errorf("transaction to %v not supported", tx.To())
+	}
+
+	flowAbi, err := contract.FlowMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tx.Data()) < 4 {
+		return nil, errors.New("method not specified")
+	}
+
+	method, err := flowAbi.MethodById(tx.Data()[:4])
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := method.Inputs.Unpack(tx.Data()[4:])
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to decode arguments of %v", method.Name)
+	}
+
+	switch method.Name {
+	case "submit":
+		return []contract.Submission{*abi.ConvertType(args[0], new(contract.Submission)).(*contract.Submission)}, nil
+	case "batchSubmit":
+		return *abi.ConvertType(args[0], new([]contract.Submission)).(*[]contract.Submission), nil
+	default:
+		return nil, errors.Errorf("method %v not supported", method.Name)
+	}
+}
+
+// submitLocked appends the files if fee paid enough, and returns the Submit event logs.
+func (chain *Chain) submitLocked(sender common.Address, tx *ethtypes.Transaction, submissions []contract.Submission) ([]*types.Log, error) {
+	fee := new(big.Int)
+	for _, submission := range submissions {
+		fee.Add(fee, submission.Fee(chain.pricePerSector))
+	}
+
+	if tx.Value().Cmp(fee) < 0 {
+		return nil, errors.WithMessagef(ErrReverted, "fee not enough, expected %v, actual %v", fee, tx.Value())
+	}
+
+	if chain.onSubmit != nil {
+		for _, submission := range submissions {
+			if err := chain.onSubmit(sender, submission); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	flowAbi, err := contract.FlowMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	event := flowAbi.Events["Submit"]
+
+	var logs []*types.Log
+	for _, submission := range submissions {
+		startPos := new(big.Int).SetUint64(chain.nextPos)
+		seq := chain.appendLocked(submission)
+		length := new(big.Int).SetUint64(flowEntries(submission.Length.Uint64()))
+
+		data, err := event.Inputs.NonIndexed().Pack(new(big.Int).SetUint64(seq), startPos, length, submission)
+		if err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, &types.Log{
+			Address:     FlowAddress,
+			BlockHash:   chain.blockHash(chain.blockNumber),
+			BlockNumber: chain.blockNumber,
+			Data:        data,
+			Index:       uint(len(chain.logs)),
+			Topics:      []common.Hash{event.ID, common.BytesToHash(sender.Bytes()), submission.Root()},
+			TxHash:      tx.Hash(),
+		})
+		chain.logs = append(chain.logs, logs[len(logs)-1])
+	}
+
+	return logs, nil
+}
+
+func (chain *Chain) blockHash(number uint64) common.Hash {
+	return crypto.Keccak256Hash(new(big.Int).SetUint64(chain.chainId).Bytes(), new(big.Int).SetUint64(number).Bytes())
+}
+
+// call executes the read-only contract call.
+func (chain *Chain) call(to *common.Address, data []byte) ([]byte, error) {
+	if to == nil || len(data) < 4 {
+		return nil, ErrReverted
+	}
+
+	var metadata = contract.MarketMetaData
+	if *to == FlowAddress {
+		metadata = contract.FlowMetaData
+	} else if *to != MarketAddress {
+		return nil, nil
+	}
+
+	contractAbi, err := metadata.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := contractAbi.MethodById(data[:4])
+	if err != nil {
+		return nil, errors.WithMessage(ErrReverted, err.Error())
+	}
+
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	switch {
+	case *to == FlowAddress && method.Name == "market":
+		return method.Outputs.Pack(MarketAddress)
+	case *to == MarketAddress && method.Name == "pricePerSector":
+		return method.Outputs.Pack(chain.pricePerSector)
+	default:
+		return nil, errors.WithMessagef(ErrReverted, "method %v not supported", method.Name)
+	}
+}
+
+// ethService implements the eth RPCs of Chain.
+type ethService struct {
+	chain *Chain
+}
+
+// callArgs is the transaction call arguments, of which only the necessary fields are decoded.
+type callArgs struct {
+	To    *common.Address `json:"to"`
+	Data  hexutil.Bytes   `json:"data"`
+	Input hexutil.Bytes   `json:"input"`
+}
+
+func (args *callArgs) data() []byte {
+	if len(args.Input) > 0 {
+		return args.Input
+	}
+
+	return args.Data
+}
+
+// filterArgs is the log filter arguments.
+type filterArgs struct {
+	FromBlock *string         `json:"fromBlock"`
+	ToBlock   *string         `json:"toBlock"`
+	Address   json.RawMessage `json:"address"`
+	Topics    []interface{}   `json:"topics"`
+}
+
+// parseBlockNumber parses the block number in hex, and returns the latest one for tags, e.g. latest or pending.
+func parseBlockNumber(number *string, latest uint64) (uint64, error) {
+	if number == nil || !strings.HasPrefix(*number, "0x") {
+		return latest, nil
+	}
+
+	return hexutil.DecodeUint64(*number)
+}
+
+func (service *ethService) ChainId() hexutil.Uint64 {
+	return hexutil.Uint64(service.chain.chainId)
+}
+
+func (service *ethService) BlockNumber() hexutil.Uint64 {
+	service.chain.mu.Lock()
+	defer service.chain.mu.Unlock()
+
+	return hexutil.Uint64(service.chain.blockNumber)
+}
+
+func (service *ethService) GetBlockByNumber(number string, full bool) (*types.Block, error) {
+	service.chain.mu.Lock()
+	defer service.chain.mu.Unlock()
+
+	blockNumber, err := parseBlockNumber(&number, service.chain.blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if blockNumber > service.chain.blockNumber {
+		return nil, nil
+	}
+
+	var mixHash common.Hash
+	var nonce ethtypes.BlockNonce
+
+	return &types.Block{
+		Difficulty:   new(big.Int),
+		GasLimit:     30_000_000,
+		Hash:         service.chain.blockHash(blockNumber),
+		MixHash:      &mixHash,
+		Nonce:        &nonce,
+		Number:       new(big.Int).SetUint64(blockNumber),
+		ParentHash:   service.chain.blockHash(blockNumber - 1),
+		Timestamp:    uint64(time.Now().Unix()),
+		Transactions: *types.NewTxOrHashListByHashes([]common.Hash{}),
+		Uncles:       []common.Hash{},
+	}, nil
+}
+
+func (service *ethService) GetCode(account common.Address, block json.RawMessage) (hexutil.Bytes, error) {
+	if account == FlowAddress || account == MarketAddress {
+		// any non-empty code for contracts
+		return hexutil.Bytes{0x60, 0x80}, nil
+	}
+
+	return hexutil.Bytes{}, nil
+}
+
+func (service *ethService) GetTransactionCount(account common.Address, block json.RawMessage) (hexutil.Uint64, error) {
+	service.chain.mu.Lock()
+	defer service.chain.mu.Unlock()
+
+	return hexutil.Uint64(service.chain.nonces[account]), nil
+}
+
+func (service *ethService) GetBalance(account common.Address, block json.RawMessage) (*hexutil.Big, error) {
+	service.chain.mu.Lock()
+	defer service.chain.mu.Unlock()
+
+	if balance, ok := service.chain.balances[account]; ok {
+		return (*hexutil.Big)(new(big.Int).Set(balance)), nil
+	}
+
+	return (*hexutil.Big)(new(big.Int).Set(DefaultBalance)), nil
+}
+
+func (service *ethService) GasPrice() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(1_000_000_000))
+}
+
+func (service *ethService) MaxPriorityFeePerGas() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(1_000_000_000))
+}
+
+func (service *ethService) EstimateGas(args callArgs, block *json.RawMessage) (hexutil.Uint64, error) {
+	return hexutil.Uint64(1_000_000), nil
+}
+
+func (service *ethService) Call(args callArgs, block *json.RawMessage) (hexutil.Bytes, error) {
+	return service.chain.call(args.To, args.data())
+}
+
+func (service *ethService) SendRawTransaction(encoded hexutil.Bytes) (common.Hash, error) {
+	var tx ethtypes.Transaction
+	if err := tx.UnmarshalBinary(encoded); err != nil {
+		return common.Hash{}, errors.WithMessage(err, "invalid transaction")
+	}
+
+	return service.chain.execute(&tx)
+}
+
+func (service *ethService) GetTransactionReceipt(hash common.Hash) (*types.Receipt, error) {
+	service.chain.mu.Lock()
+	defer service.chain.mu.Unlock()
+
+	return service.chain.receipts[hash], nil
+}
+
+func (service *ethService) GetTransactionByHash(hash common.Hash) (*types.TransactionDetail, error) {
+	service.chain.mu.Lock()
+	defer service.chain.mu.Unlock()
+
+	return service.chain.txs[hash], nil
+}
+
+func (service *ethService) GetLogs(filter filterArgs) ([]*types.Log, error) {
+	var addresses []common.Address
+	if len(filter.Address) > 0 && string(filter.Address) != "null" {
+		if err := json.Unmarshal(filter.Address, &addresses); err != nil {
+			var address common.Address
+			if err = json.Unmarshal(filter.Address, &address); err != nil {
+				return nil, errors.WithMessage(err, "invalid address")
+			}
+			addresses = []common.Address{address}
+		}
+	}
+
+	topics := make([][]common.Hash, len(filter.Topics))
+	for i, v := range filter.Topics {
+		encoded, _ := json.Marshal(v)
+		if err := json.Unmarshal(encoded, &topics[i]); err != nil {
+			var topic common.Hash
+			if err = json.Unmarshal(encoded, &topic); err != nil {
+				return nil, errors.WithMessage(err, "invalid topics")
+			}
+			topics[i] = []common.Hash{topic}
+		}
+	}
+
+	service.chain.mu.Lock()
+	defer service.chain.mu.Unlock()
+
+	fromBlock, err := parseBlockNumber(filter.FromBlock, service.chain.blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	toBlock, err := parseBlockNumber(filter.ToBlock, service.chain.blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := []*types.Log{}
+	for _, log := range service.chain.logs {
+		if log.BlockNumber >= fromBlock && log.BlockNumber <= toBlock && matchLog(log, addresses, topics) {
+			logs = append(logs, log)
+		}
+	}
+
+	return logs, nil
+}
+
+func matchLog(log *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 && !containsValue(addresses, log.Address) {
+		return false
+	}
+
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+
+	for i, candidates := range topics {
+		if len(candidates) > 0 && !containsValue(candidates, log.Topics[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsValue[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
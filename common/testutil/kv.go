@@ -0,0 +1,299 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// Results of kv transactions replayed by KvService.
+const (
+	KvResultCommit             = "Commit"
+	KvResultDataParseError     = "DataParseError"
+	KvResultVersionConfliction = "VersionConfliction"
+)
+
+var errKvDataTruncated = errors.New("kv stream data truncated")
+
+// kvVersion is a value of key written at version, i.e. the tx seq.
+type kvVersion struct {
+	version uint64
+	data    []byte
+}
+
+// KvService is an in-memory kv node that implements the kv RPCs used by this client, and is safe for concurrent
+// use. It replays the kv stream data in files finalized on the storage node in order of tx seq, and waits for the
+// file finalized before replaying subsequent ones.
+//
+// Note, access control operations are not enforced, and a transaction is aborted only if any key read or written
+// is updated after the version of stream data.
+type KvService struct {
+	mu sync.Mutex
+
+	zgs     *ZgsService
+	next    uint64                                 // next tx seq to replay
+	results map[uint64]string                      // replay results by tx seq
+	streams map[common.Hash]map[string][]kvVersion // key versions of each stream in order
+}
+
+// NewKvService creates a kv node to replay kv stream data from the specified storage node.
+func NewKvService(zgs *ZgsService) *KvService {
+	return &KvService{
+		zgs:     zgs,
+		results: make(map[uint64]string),
+		streams: make(map[common.Hash]map[string][]kvVersion),
+	}
+}
+
+// replayLocked replays the finalized files on storage node in order.
+func (service *KvService) replayLocked() {
+	for {
+		tx, data, ok := service.zgs.transaction(service.next)
+		if !ok {
+			return
+		}
+
+		if len(tx.StreamIds) > 0 {
+			if data == nil {
+				// wait for file finalized
+				return
+			}
+
+			service.results[tx.Seq] = service.applyLocked(tx.Seq, data)
+		}
+
+		service.next++
+	}
+}
+
+// streamWrite is a key written in kv stream data.
+type streamWrite struct {
+	streamId common.Hash
+	key      []byte
+	data     []byte
+}
+
+// applyLocked applies the kv stream data, and returns the replay result.
+func (service *KvService) applyLocked(txSeq uint64, data []byte) string {
+	version, keys, writes, err := decodeStreamData(data)
+	if err != nil {
+		return KvResultDataParseError
+	}
+
+	for _, key := range keys {
+		if versions := service.streams[key.streamId][string(key.key)]; len(versions) > 0 && versions[len(versions)-1].version > version {
+			return KvResultVersionConfliction
+		}
+	}
+
+	for _, write := range writes {
+		stream, ok := service.streams[write.streamId]
+		if !ok {
+			stream = make(map[string][]kvVersion)
+			service.streams[write.streamId] = stream
+		}
+
+		stream[string(write.key)] = append(stream[string(write.key)], kvVersion{txSeq, write.data})
+	}
+
+	return KvResultCommit
+}
+
+// decodeStreamData decodes the version, keys to read or write, and writes of kv stream data.
+func decodeStreamData(data []byte) (version uint64, keys, writes []streamWrite, err error) {
+	reader := bytes.NewReader(data)
+	read := func(size int) []byte {
+		buf := make([]byte, size)
+		if _, e := io.ReadFull(reader, buf); e != nil && err == nil {
+			err = errKvDataTruncated
+		}
+
+		return buf
+	}
+	readKey := func() (common.Hash, []byte) {
+		streamId := common.BytesToHash(read(common.HashLength))
+		size := read(3)
+
+		return streamId, read(int(size[0])<<16 | int(size[1])<<8 | int(size[2]))
+	}
+
+	version = binary.BigEndian.Uint64(read(8))
+
+	numReads := binary.BigEndian.Uint32(read(4))
+	for i := uint32(0); i < numReads && err == nil; i++ {
+		streamId, key := readKey()
+		keys = append(keys, streamWrite{streamId: streamId, key: key})
+	}
+
+	numWrites := binary.BigEndian.Uint32(read(4))
+	var sizes []uint64
+	for i := uint32(0); i < numWrites && err == nil; i++ {
+		streamId, key := readKey()
+		writes = append(writes, streamWrite{streamId: streamId, key: key})
+		sizes = append(sizes, binary.BigEndian.Uint64(read(8)))
+	}
+
+	for i := 0; i < len(writes) && err == nil; i++ {
+		if sizes[i] > uint64(reader.Len()) {
+			return 0, nil, nil, errKvDataTruncated
+		}
+
+		writes[i].data = read(int(sizes[i]))
+	}
+
+	// access control operations followed are ignored
+
+	return version, append(keys, writes...), writes, err
+}
+
+// latestLocked returns the latest value of key at the specified version, and nil if not found.
+func (service *KvService) latestLocked(streamId common.Hash, key string, version *uint64) *kvVersion {
+	versions := service.streams[streamId][key]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if version == nil || versions[i].version <= *version {
+			return &versions[i]
+		}
+	}
+
+	return nil
+}
+
+// sortedKeysLocked returns the keys of stream that available at the specified version in order.
+func (service *KvService) sortedKeysLocked(streamId common.Hash, version *uint64) []string {
+	var keys []string
+	for key := range service.streams[streamId] {
+		if value := service.latestLocked(streamId, key, version); value != nil && len(value.data) > 0 {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func paginate(data []byte, startIndex, length uint64) []byte {
+	start := min(startIndex, uint64(len(data)))
+	end := min(start+length, uint64(len(data)))
+
+	return data[start:end]
+}
+
+func (service *KvService) keyValueLocked(streamId common.Hash, keys []string, index int, startIndex, length uint64, version *uint64) *node.KeyValue {
+	if index < 0 || index >= len(keys) {
+		return nil
+	}
+
+	value := service.latestLocked(streamId, keys[index], version)
+
+	return &node.KeyValue{
+		Version: value.version,
+		Key:     []byte(keys[index]),
+		Data:    paginate(value.data, startIndex, length),
+		Size:    uint64(len(value.data)),
+	}
+}
+
+// GetValue implements the kv_getValue RPC.
+func (service *KvService) GetValue(streamId common.Hash, key []byte, startIndex, length uint64, version *uint64) (*node.Value, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.replayLocked()
+
+	value := service.latestLocked(streamId, string(key), version)
+	if value == nil {
+		return &node.Value{Data: []byte{}}, nil
+	}
+
+	return &node.Value{
+		Version: value.version,
+		Data:    paginate(value.data, startIndex, length),
+		Size:    uint64(len(value.data)),
+	}, nil
+}
+
+// GetFirst implements the kv_getFirst RPC.
+func (service *KvService) GetFirst(streamId common.Hash, startIndex, length uint64, version *uint64) (*node.KeyValue, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.replayLocked()
+	keys := service.sortedKeysLocked(streamId, version)
+
+	return service.keyValueLocked(streamId, keys, 0, startIndex, length, version), nil
+}
+
+// GetLast implements the kv_getLast RPC.
+func (service *KvService) GetLast(streamId common.Hash, startIndex, length uint64, version *uint64) (*node.KeyValue, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.replayLocked()
+	keys := service.sortedKeysLocked(streamId, version)
+
+	return service.keyValueLocked(streamId, keys, len(keys)-1, startIndex, length, version), nil
+}
+
+// GetNext implements the kv_getNext RPC.
+func (service *KvService) GetNext(streamId common.Hash, key []byte, startIndex, length uint64, inclusive bool, version *uint64) (*node.KeyValue, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.replayLocked()
+	keys := service.sortedKeysLocked(streamId, version)
+	index := sort.Search(len(keys), func(i int) bool {
+		cmp := bytes.Compare([]byte(keys[i]), key)
+		return cmp > 0 || (inclusive && cmp == 0)
+	})
+
+	return service.keyValueLocked(streamId, keys, index, startIndex, length, version), nil
+}
+
+// GetPrev implements the kv_getPrev RPC.
+func (service *KvService) GetPrev(streamId common.Hash, key []byte, startIndex, length uint64, inclusive bool, version *uint64) (*node.KeyValue, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.replayLocked()
+	keys := service.sortedKeysLocked(streamId, version)
+	index := sort.Search(len(keys), func(i int) bool {
+		cmp := bytes.Compare([]byte(keys[i]), key)
+		return cmp > 0 || (!inclusive && cmp == 0)
+	})
+
+	return service.keyValueLocked(streamId, keys, index-1, startIndex, length, version), nil
+}
+
+// GetTransactionResult implements the kv_getTransactionResult RPC, and returns empty if not replayed yet.
+func (service *KvService) GetTransactionResult(txSeq uint64) (string, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.replayLocked()
+
+	return service.results[txSeq], nil
+}
+
+// GetHoldingStreamIds implements the kv_getHoldingStreamIds RPC.
+func (service *KvService) GetHoldingStreamIds() ([]common.Hash, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.replayLocked()
+
+	ids := []common.Hash{}
+	for id := range service.streams {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+
+	return ids, nil
+}
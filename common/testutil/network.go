@@ -0,0 +1,200 @@
+package testutil
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	gorpc "github.com/openweb3/go-rpc-provider"
+	"github.com/openweb3/web3go"
+	"github.com/pkg/errors"
+)
+
+// Serve serves the mock services over HTTP in process, which are registered by RPC namespace, e.g. "zgs" for
+// ZgsService and "kv" for KvService. Returns the server URL, and the server is closed when test completed.
+func Serve(t testing.TB, services map[string]interface{}) string {
+	server := gorpc.NewServer()
+	for namespace, service := range services {
+		if err := server.RegisterName(namespace, service); err != nil {
+			t.Fatalf("Failed to register %v service: %v", namespace, err)
+		}
+	}
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+
+	return httpServer.URL
+}
+
+// ServeChain serves the eth RPCs of chain over HTTP in process, and returns the server URL.
+func ServeChain(t testing.TB, chain *Chain) string {
+	return Serve(t, map[string]interface{}{"eth": &ethService{chain}})
+}
+
+// IndexerService is an indexer that implements the indexer RPCs used by this client, and is safe for concurrent
+// use. All storage nodes are trusted, and file locations are queried from storage nodes on demand.
+type IndexerService struct {
+	mu    sync.Mutex
+	urls  []string
+	nodes []*ZgsService
+}
+
+// NewIndexerService creates an indexer without any storage node.
+func NewIndexerService() *IndexerService {
+	return &IndexerService{}
+}
+
+// AddNode adds a trusted storage node served at the specified URL.
+func (service *IndexerService) AddNode(url string, node *ZgsService) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.urls = append(service.urls, url)
+	service.nodes = append(service.nodes, node)
+}
+
+// shardedNodes is the same as indexer.ShardedNodes, since package indexer depends on this package for tests.
+type shardedNodes struct {
+	Trusted    []*shard.ShardedNode `json:"trusted"`
+	Discovered []*shard.ShardedNode `json:"discovered"`
+}
+
+// GetShardedNodes implements the indexer_getShardedNodes RPC.
+func (service *IndexerService) GetShardedNodes() (shardedNodes, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	result := shardedNodes{Trusted: []*shard.ShardedNode{}, Discovered: []*shard.ShardedNode{}}
+	for i, url := range service.urls {
+		result.Trusted = append(result.Trusted, &shard.ShardedNode{URL: url, Config: service.nodes[i].shard})
+	}
+
+	return result, nil
+}
+
+// GetNodeLocations implements the indexer_getNodeLocations RPC, and IP locations are always unknown.
+func (service *IndexerService) GetNodeLocations() (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+// GetFileLocations implements the indexer_getFileLocations RPC, which returns the storage nodes that the file
+// finalized on.
+func (service *IndexerService) GetFileLocations(root string) ([]*shard.ShardedNode, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	var found bool
+	locations := []*shard.ShardedNode{}
+	for i, node := range service.nodes {
+		info, _ := node.GetFileInfo(common.HexToHash(root))
+		if info == nil {
+			continue
+		}
+
+		found = true
+		if info.Finalized {
+			locations = append(locations, &shard.ShardedNode{URL: service.urls[i], Config: node.shard})
+		}
+	}
+
+	if !found {
+		return nil, errors.New("file not found")
+	}
+
+	return locations, nil
+}
+
+// Network is an in-process 0g storage network for end-to-end tests without any external dependency, which
+// consists of a blockchain, storage nodes connected to the chain, a kv node that replays from the first storage
+// node, and an indexer that trusts all storage nodes. All services are served over HTTP, and closed when test
+// completed.
+type Network struct {
+	Chain   *Chain
+	Nodes   []*ZgsService
+	Kv      *KvService
+	Indexer *IndexerService
+
+	ChainURL   string
+	NodeURLs   []string
+	KvURL      string
+	IndexerURL string
+
+	t testing.TB
+}
+
+// NewNetwork creates a network with storage nodes of the specified shard configs, and a single storage node of
+// all shards if not specified.
+func NewNetwork(t testing.TB, shards ...shard.ShardConfig) *Network {
+	if len(shards) == 0 {
+		shards = []shard.ShardConfig{{ShardId: 0, NumShard: 1}}
+	}
+
+	network := Network{
+		Chain:   NewChain(),
+		Indexer: NewIndexerService(),
+		t:       t,
+	}
+	network.ChainURL = ServeChain(t, network.Chain)
+
+	for _, config := range shards {
+		service := NewZgsServiceWithShard(config)
+		url := Serve(t, map[string]interface{}{"zgs": service})
+
+		network.Chain.Connect(service)
+		network.Indexer.AddNode(url, service)
+		network.Nodes = append(network.Nodes, service)
+		network.NodeURLs = append(network.NodeURLs, url)
+	}
+
+	network.Kv = NewKvService(network.Nodes[0])
+	network.KvURL = Serve(t, map[string]interface{}{"kv": network.Kv})
+	network.IndexerURL = Serve(t, map[string]interface{}{"indexer": network.Indexer})
+
+	return &network
+}
+
+// Web3 returns a client connected to the chain, which sends transactions signed by PrivateKey. The client is closed
+// when test completed.
+func (network *Network) Web3() *web3go.Client {
+	client, err := blockchain.NewWeb3(network.ChainURL, PrivateKey)
+	if err != nil {
+		network.t.Fatalf("Failed to connect to chain: %v", err)
+	}
+	network.t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+// ZgsClients returns clients connected to all storage nodes, which are closed when test completed.
+func (network *Network) ZgsClients() []*node.ZgsClient {
+	var clients []*node.ZgsClient
+	for _, url := range network.NodeURLs {
+		client, err := node.NewZgsClient(url)
+		if err != nil {
+			network.t.Fatalf("Failed to connect to storage node: %v", err)
+		}
+		network.t.Cleanup(func() { client.Close() })
+
+		clients = append(clients, client)
+	}
+
+	return clients
+}
+
+// KvClient returns a client connected to the kv node, which is closed when test completed.
+func (network *Network) KvClient() *node.KvClient {
+	client, err := node.NewKvClient(network.KvURL)
+	if err != nil {
+		network.t.Fatalf("Failed to connect to kv node: %v", err)
+	}
+	network.t.Cleanup(func() { client.Close() })
+
+	return client
+}
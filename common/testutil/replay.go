@@ -0,0 +1,201 @@
+// Package testutil provides utilities to test client logic without storage nodes or blockchain, including the
+// replay of recorded RPCs, and an in-process network of mock storage nodes, kv node, indexer and blockchain for
+// end-to-end tests, see Network.
+package testutil
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/0glabs/0g-storage-client/common/replay"
+	"github.com/0glabs/0g-storage-client/common/rpc"
+	"github.com/0glabs/0g-storage-client/common/util"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/pkg/errors"
+)
+
+// ErrNotRecorded is returned when replaying an RPC that is not recorded in replay log, or whose result is not
+// recorded, e.g. segments recorded without payload.
+var ErrNotRecorded = errors.New("RPC not recorded in replay log")
+
+type rpcKey struct {
+	url        string
+	method     string
+	paramsHash string
+}
+
+// Replayer stubs RPC clients with responses recorded in replay log, so as to re-run client logic
+// deterministically. Recorded RPCs with the same URL, method and params are replayed in order.
+type Replayer struct {
+	mu      sync.Mutex
+	rpcs    map[rpcKey][]*replay.Entry
+	seeds   map[string][]uint64
+	options map[string]json.RawMessage
+}
+
+// LoadReplay loads the replay log from the specified file.
+func LoadReplay(path string) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to open replay log file")
+	}
+	defer file.Close()
+
+	return NewReplayer(file)
+}
+
+// NewReplayer creates a replayer from the replay log of the specified reader.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	replayer := Replayer{
+		rpcs:    make(map[rpcKey][]*replay.Entry),
+		seeds:   make(map[string][]uint64),
+		options: make(map[string]json.RawMessage),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<30) // payload may be recorded
+
+	for line := 1; scanner.Scan(); line++ {
+		var entry replay.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, errors.WithMessagef(err, "Failed to decode replay log at line %v", line)
+		}
+
+		switch entry.Kind {
+		case replay.KindRPC:
+			key := rpcKey{entry.URL, entry.Method, entry.ParamsHash}
+			replayer.rpcs[key] = append(replayer.rpcs[key], &entry)
+		case replay.KindSeed:
+			replayer.seeds[entry.Name] = append(replayer.seeds[entry.Name], entry.Seed)
+		case replay.KindOption:
+			replayer.options[entry.Name] = entry.Value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithMessage(err, "Failed to read replay log")
+	}
+
+	return &replayer, nil
+}
+
+// Install stubs RPC clients created afterwards and restores recorded random seeds, and returns a function to
+// uninstall. Note, clients over websocket still connect to server when created, so HTTP is recommended.
+func (replayer *Replayer) Install() (uninstall func()) {
+	restoreHook := rpc.SetCallHook(replayer.Hook)
+	restoreSeed := util.WrapSeedFunc(func(prev func(name string) uint64) func(name string) uint64 {
+		return func(name string) uint64 {
+			if seed, ok := replayer.nextSeed(name); ok {
+				return seed
+			}
+
+			return prev(name)
+		}
+	})
+
+	return func() {
+		restoreHook()
+		restoreSeed()
+	}
+}
+
+// Hook returns the middleware that responds RPCs sent to the specified URL with recorded ones instead of
+// connecting to server, which could be set by rpc.SetCallHook.
+func (replayer *Replayer) Hook(url string) providers.CallContextMiddleware {
+	url = replay.RedactURL(url)
+
+	return func(providers.CallContextFunc) providers.CallContextFunc {
+		return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+			return replayer.respond(url, method, args, result)
+		}
+	}
+}
+
+func (replayer *Replayer) respond(url, method string, args []interface{}, result interface{}) error {
+	paramsHash, _, err := replay.HashParams(args)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to encode params")
+	}
+
+	entry, ok := replayer.nextRPC(rpcKey{url, method, paramsHash})
+	if !ok {
+		return errors.WithMessagef(ErrNotRecorded, "%v of %v", method, url)
+	}
+
+	if len(entry.Error) > 0 {
+		if entry.ErrorCode != 0 {
+			return rpc.NewJSONError(entry.ErrorCode, entry.Error)
+		}
+
+		return errors.New(entry.Error)
+	}
+
+	if entry.Result == nil {
+		return errors.WithMessagef(ErrNotRecorded, "Result of %v (seq %v) without payload", method, entry.Seq)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(entry.Result, result)
+}
+
+func (replayer *Replayer) nextRPC(key rpcKey) (*replay.Entry, bool) {
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	entries := replayer.rpcs[key]
+	if len(entries) == 0 {
+		return nil, false
+	}
+
+	replayer.rpcs[key] = entries[1:]
+
+	return entries[0], true
+}
+
+func (replayer *Replayer) nextSeed(name string) (uint64, bool) {
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	seeds := replayer.seeds[name]
+	if len(seeds) == 0 {
+		return 0, false
+	}
+
+	replayer.seeds[name] = seeds[1:]
+
+	return seeds[0], true
+}
+
+// Option decodes the option snapshot with the specified name into value, and returns false if not recorded.
+// Note, sensitive fields are redacted when recorded.
+func (replayer *Replayer) Option(name string, value interface{}) (bool, error) {
+	replayer.mu.Lock()
+	encoded, ok := replayer.options[name]
+	replayer.mu.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(encoded, value)
+}
+
+// Remaining returns the number of recorded RPCs that are not replayed yet.
+func (replayer *Replayer) Remaining() int {
+	replayer.mu.Lock()
+	defer replayer.mu.Unlock()
+
+	var remaining int
+	for _, entries := range replayer.rpcs {
+		remaining += len(entries)
+	}
+
+	return remaining
+}
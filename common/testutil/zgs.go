@@ -0,0 +1,569 @@
+package testutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/core/merkle"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// streamDomain is the leading tag of files that contain kv stream data.
+var streamDomain = common.Hash(sha256.Sum256([]byte("STREAM")))
+
+// zgsFile is a file submitted on chain, of which the segments are uploaded to mock storage node.
+type zgsFile struct {
+	tx        node.Transaction
+	tags      []byte
+	data      []byte                  // padded to chunks, and filled when segments uploaded
+	proofs    map[uint64]merkle.Proof // proofs of uploaded segments
+	finalized bool
+	pruned    bool
+}
+
+// ZgsService is an in-memory storage node that implements the zgs RPCs used by this client, and is safe for
+// concurrent use. Files are submitted either by Submit directly, or by transactions sent to the Chain that the
+// node connects to.
+//
+// A file is finalized once all segments in shard uploaded, unless auto finalization disabled, in which case
+// Finalize is required. Uploaded segments are validated against the file merkle root, and downloaded along with
+// the uploaded proofs.
+type ZgsService struct {
+	mu sync.Mutex
+
+	identity node.NetworkIdentity
+	shard    shard.ShardConfig
+	files    []*zgsFile                 // indexed by tx seq
+	byRoot   map[common.Hash][]*zgsFile // files of the same root in order of tx seq
+	nextPos  uint64                     // flow position of next submission in chunks
+	maxSize  uint64                     // max file size advertised in status, 0 if not advertised
+	hooks    ZgsHooks
+}
+
+// ZgsHooks are optional hooks to drive the mock storage node in tests.
+type ZgsHooks struct {
+	// ManualFinalize disables to finalize file automatically once all segments uploaded.
+	ManualFinalize bool
+	// BeforeUpload is called before a segment uploaded, which rejects the segment if error returned.
+	BeforeUpload func(txSeq, index uint64) error
+	// DownloadDelay is the delay to download each segment.
+	DownloadDelay time.Duration
+	// NoProofs serves segments without proofs as storage nodes of earlier versions, where the RPCs to download
+	// segment with proof are not found.
+	NoProofs bool
+	// RetentionClass reports the retention class of file by submission tags as nodes of private networks do, and
+	// not reported if nil.
+	RetentionClass func(tags []byte) string
+}
+
+// methodNotFoundError is the JSON-RPC error of method not available on storage node.
+type methodNotFoundError struct {
+	method string
+}
+
+func (e *methodNotFoundError) Error() string {
+	return fmt.Sprintf("the method %v does not exist/is not available", e.method)
+}
+
+// ErrorCode implements the rpc.Error interface.
+func (e *methodNotFoundError) ErrorCode() int {
+	return node.ErrorCodeMethodNotFound
+}
+
+// NewZgsService creates a storage node of a single shard, which holds all files.
+func NewZgsService() *ZgsService {
+	return NewZgsServiceWithShard(shard.ShardConfig{ShardId: 0, NumShard: 1})
+}
+
+// NewZgsServiceWithShard creates a storage node that only stores segments in the specified shard.
+func NewZgsServiceWithShard(config shard.ShardConfig) *ZgsService {
+	return &ZgsService{
+		shard:  config,
+		byRoot: make(map[common.Hash][]*zgsFile),
+	}
+}
+
+// SetHooks sets the hooks of storage node, which takes effect for subsequent RPCs.
+func (service *ZgsService) SetHooks(hooks ZgsHooks) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.hooks = hooks
+}
+
+// SetMaxFileSize sets the max file size advertised in status, and 0 to not advertise.
+func (service *ZgsService) SetMaxFileSize(size uint64) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.maxSize = size
+}
+
+// Submit submits the content with tags as if a transaction executed on chain, and returns the merkle root. It is
+// used to test without blockchain, e.g. upload with transaction skipped, and should not be used if the node
+// connected to a chain, in which case submit via chain instead.
+func (service *ZgsService) Submit(content, tags []byte) (common.Hash, error) {
+	data, err := core.NewDataInMemory(content)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	tree, err := core.MerkleTree(data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.appendLocked(tree.Root(), uint64(len(content)), tags)
+
+	return tree.Root(), nil
+}
+
+// append appends the file submitted on chain.
+func (service *ZgsService) append(root common.Hash, size uint64, tags []byte) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	service.appendLocked(root, size, tags)
+}
+
+func (service *ZgsService) appendLocked(root common.Hash, size uint64, tags []byte) {
+	numChunks := core.NumSplits(int64(size), core.DefaultChunkSize)
+
+	file := &zgsFile{
+		tx: node.Transaction{
+			StreamIds:       parseStreamIds(tags),
+			DataMerkleRoot:  root,
+			StartEntryIndex: service.nextPos,
+			Size:            size,
+			Seq:             uint64(len(service.files)),
+		},
+		tags:   common.CopyBytes(tags),
+		data:   make([]byte, numChunks*core.DefaultChunkSize),
+		proofs: make(map[uint64]merkle.Proof),
+	}
+
+	service.files = append(service.files, file)
+	service.byRoot[root] = append(service.byRoot[root], file)
+
+	service.nextPos += flowEntries(size)
+
+	// nothing to upload for empty file or segments not in shard
+	if !service.hooks.ManualFinalize && service.completeLocked(file) {
+		file.finalized = true
+	}
+}
+
+// flowEntries returns the number of entries occupied in flow by file of specified size, where each file starts at
+// a new segment in flow for simplicity.
+func flowEntries(size uint64) uint64 {
+	numSegments := core.NumSplits(int64(size), core.DefaultSegmentSize)
+
+	return max(numSegments, 1) * core.DefaultSegmentMaxChunks
+}
+
+// parseStreamIds returns the stream ids in tags of kv stream data.
+func parseStreamIds(tags []byte) []*hexutil.Big {
+	if len(tags) < common.HashLength || len(tags)%common.HashLength != 0 || !bytes.Equal(tags[:common.HashLength], streamDomain[:]) {
+		return []*hexutil.Big{}
+	}
+
+	var ids []*hexutil.Big
+	for offset := common.HashLength; offset < len(tags); offset += common.HashLength {
+		ids = append(ids, (*hexutil.Big)(common.BytesToHash(tags[offset:offset+common.HashLength]).Big()))
+	}
+
+	return ids
+}
+
+// Finalize finalizes the file of specified root, which requires all segments in shard uploaded.
+func (service *ZgsService) Finalize(root common.Hash) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	files := service.byRoot[root]
+	if len(files) == 0 {
+		return errors.Errorf("file %v not found", root)
+	}
+
+	for _, file := range files {
+		if !service.completeLocked(file) {
+			return errors.Errorf("file %v not uploaded completely, tx seq = %v", root, file.tx.Seq)
+		}
+
+		file.finalized = true
+	}
+
+	return nil
+}
+
+// Prune prunes the file data of specified root, so that segments could not be uploaded or downloaded any more.
+func (service *ZgsService) Prune(root common.Hash) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	files := service.byRoot[root]
+	if len(files) == 0 {
+		return errors.Errorf("file %v not found", root)
+	}
+
+	for _, file := range files {
+		file.pruned, file.finalized = true, false
+		file.data, file.proofs = nil, nil
+	}
+
+	return nil
+}
+
+// Wipe wipes the uploaded segments of specified root as if data lost, so that segments should be uploaded again.
+func (service *ZgsService) Wipe(root common.Hash) error {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	files := service.byRoot[root]
+	if len(files) == 0 {
+		return errors.Errorf("file %v not found", root)
+	}
+
+	for _, file := range files {
+		file.finalized, file.pruned = false, false
+		file.data = make([]byte, core.NumSplits(int64(file.tx.Size), core.DefaultChunkSize)*core.DefaultChunkSize)
+		file.proofs = make(map[uint64]merkle.Proof)
+	}
+
+	return nil
+}
+
+// segments returns the file segment indexes in shard of the storage node.
+func (service *ZgsService) segments(file *zgsFile) []uint64 {
+	startSegmentIndex := file.tx.StartEntryIndex / core.DefaultSegmentMaxChunks
+	numSegments := core.NumSplits(int64(file.tx.Size), core.DefaultSegmentSize)
+
+	var indexes []uint64
+	for i := uint64(0); i < numSegments; i++ {
+		if service.shard.HasSegment(startSegmentIndex + i) {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
+
+func (service *ZgsService) completeLocked(file *zgsFile) bool {
+	if file.pruned {
+		return false
+	}
+
+	for _, index := range service.segments(file) {
+		if _, ok := file.proofs[index]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (service *ZgsService) infoLocked(file *zgsFile) *node.FileInfo {
+	info := &node.FileInfo{
+		Tx:             file.tx,
+		Finalized:      file.finalized,
+		UploadedSegNum: uint64(len(file.proofs)),
+		Pruned:         file.pruned,
+	}
+
+	if service.hooks.RetentionClass != nil {
+		info.RetentionClass = service.hooks.RetentionClass(file.tags)
+	}
+
+	return info
+}
+
+// latestLocked returns the latest file of specified root, and nil if not found.
+func (service *ZgsService) latestLocked(root common.Hash) *zgsFile {
+	files := service.byRoot[root]
+	if len(files) == 0 {
+		return nil
+	}
+
+	return files[len(files)-1]
+}
+
+func (service *ZgsService) fileLocked(txSeq uint64) (*zgsFile, error) {
+	if txSeq >= uint64(len(service.files)) {
+		return nil, errors.Errorf("tx seq %v not found", txSeq)
+	}
+
+	return service.files[txSeq], nil
+}
+
+// transaction returns the transaction of specified tx seq, and the file data if finalized.
+func (service *ZgsService) transaction(txSeq uint64) (tx node.Transaction, data []byte, ok bool) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	file, err := service.fileLocked(txSeq)
+	if err != nil {
+		return tx, nil, false
+	}
+
+	if file.finalized {
+		data = file.data[:file.tx.Size]
+	}
+
+	return file.tx, data, true
+}
+
+// GetStatus implements the zgs_getStatus RPC.
+func (service *ZgsService) GetStatus() (node.Status, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	return node.Status{
+		NextTxSeq:       uint64(len(service.files)),
+		NetworkIdentity: service.identity,
+		MaxFileSize:     service.maxSize,
+	}, nil
+}
+
+// GetShardConfig implements the zgs_getShardConfig RPC.
+func (service *ZgsService) GetShardConfig() (shard.ShardConfig, error) {
+	return service.shard, nil
+}
+
+// GetFileInfo implements the zgs_getFileInfo RPC, and returns nil if file not found.
+func (service *ZgsService) GetFileInfo(root common.Hash) (*node.FileInfo, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if file := service.latestLocked(root); file != nil {
+		return service.infoLocked(file), nil
+	}
+
+	return nil, nil
+}
+
+// GetFileInfoByTxSeq implements the zgs_getFileInfoByTxSeq RPC, and returns nil if file not found.
+func (service *ZgsService) GetFileInfoByTxSeq(txSeq uint64) (*node.FileInfo, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if file, err := service.fileLocked(txSeq); err == nil {
+		return service.infoLocked(file), nil
+	}
+
+	return nil, nil
+}
+
+// CheckFileFinalized implements the zgs_checkFileFinalized RPC with tx seq or root, and returns nil if file not
+// found.
+func (service *ZgsService) CheckFileFinalized(txSeqOrRoot json.RawMessage) (*bool, error) {
+	var info *node.FileInfo
+	var txSeq uint64
+	var root common.Hash
+	if err := json.Unmarshal(txSeqOrRoot, &txSeq); err == nil {
+		info, _ = service.GetFileInfoByTxSeq(txSeq)
+	} else if err = json.Unmarshal(txSeqOrRoot, &root); err == nil {
+		info, _ = service.GetFileInfo(root)
+	} else {
+		return nil, errors.Errorf("invalid tx seq or root %v", string(txSeqOrRoot))
+	}
+
+	if info == nil {
+		return nil, nil
+	}
+
+	return &info.Finalized, nil
+}
+
+// UploadSegment implements the zgs_uploadSegment RPC.
+func (service *ZgsService) UploadSegment(segment node.SegmentWithProof) (int, error) {
+	return service.UploadSegments([]node.SegmentWithProof{segment})
+}
+
+// UploadSegments implements the zgs_uploadSegments RPC.
+func (service *ZgsService) UploadSegments(segments []node.SegmentWithProof) (int, error) {
+	for _, segment := range segments {
+		service.mu.Lock()
+		file := service.latestLocked(segment.Root)
+		service.mu.Unlock()
+
+		if file == nil {
+			return 0, errors.Errorf("file %v not found", segment.Root)
+		}
+
+		if _, err := service.UploadSegmentsByTxSeq([]node.SegmentWithProof{segment}, file.tx.Seq); err != nil {
+			return 0, err
+		}
+	}
+
+	return 0, nil
+}
+
+// UploadSegmentByTxSeq implements the zgs_uploadSegmentByTxSeq RPC.
+func (service *ZgsService) UploadSegmentByTxSeq(segment node.SegmentWithProof, txSeq uint64) (int, error) {
+	return service.UploadSegmentsByTxSeq([]node.SegmentWithProof{segment}, txSeq)
+}
+
+// UploadSegmentsByTxSeq implements the zgs_uploadSegmentsByTxSeq RPC.
+func (service *ZgsService) UploadSegmentsByTxSeq(segments []node.SegmentWithProof, txSeq uint64) (int, error) {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	file, err := service.fileLocked(txSeq)
+	if err != nil {
+		return 0, err
+	}
+
+	if file.pruned {
+		return 0, errors.Errorf("file of tx seq %v pruned", txSeq)
+	}
+
+	for _, segment := range segments {
+		if service.hooks.BeforeUpload != nil {
+			if err := service.hooks.BeforeUpload(txSeq, segment.Index); err != nil {
+				return 0, err
+			}
+		}
+
+		if err := validateSegment(file.tx, &segment); err != nil {
+			return 0, errors.WithMessagef(err, "invalid segment %v of tx seq %v", segment.Index, txSeq)
+		}
+
+		copy(file.data[segment.Index*core.DefaultSegmentSize:], segment.Data)
+		file.proofs[segment.Index] = segment.Proof
+	}
+
+	if !service.hooks.ManualFinalize && service.completeLocked(file) {
+		file.finalized = true
+	}
+
+	return 0, nil
+}
+
+// validateSegment validates the segment data and proof against the file merkle root.
+func validateSegment(tx node.Transaction, segment *node.SegmentWithProof) error {
+	if segment.Root != tx.DataMerkleRoot {
+		return errors.Errorf("root mismatch, expected %v, actual %v", tx.DataMerkleRoot, segment.Root)
+	}
+
+	numChunks := core.NumSplits(int64(tx.Size), core.DefaultChunkSize)
+	startIndex := segment.Index * core.DefaultSegmentMaxChunks
+	if startIndex >= numChunks {
+		return errors.Errorf("segment index out of bound, number of chunks = %v", numChunks)
+	}
+
+	endIndex := min(startIndex+core.DefaultSegmentMaxChunks, numChunks)
+	if expected := (endIndex - startIndex) * core.DefaultChunkSize; uint64(len(segment.Data)) != expected {
+		return errors.Errorf("data length mismatch, expected %v, actual %v", expected, len(segment.Data))
+	}
+
+	segmentRoot, numSegmentsFlowPadded := core.PaddedSegmentRoot(segment.Index, segment.Data, int64(tx.Size))
+
+	return segment.Proof.ValidateHash(tx.DataMerkleRoot, segmentRoot, segment.Index, numSegmentsFlowPadded)
+}
+
+// DownloadSegment implements the zgs_downloadSegment RPC, where indexes are in chunks.
+func (service *ZgsService) DownloadSegment(root common.Hash, startIndex, endIndex uint64) ([]byte, error) {
+	service.mu.Lock()
+	file := service.latestLocked(root)
+	service.mu.Unlock()
+
+	if file == nil {
+		return nil, nil
+	}
+
+	return service.DownloadSegmentByTxSeq(file.tx.Seq, startIndex, endIndex)
+}
+
+// DownloadSegmentByTxSeq implements the zgs_downloadSegmentByTxSeq RPC, where indexes are in chunks.
+func (service *ZgsService) DownloadSegmentByTxSeq(txSeq, startIndex, endIndex uint64) ([]byte, error) {
+	service.mu.Lock()
+	delay := service.hooks.DownloadDelay
+	service.mu.Unlock()
+
+	time.Sleep(delay)
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	file, err := service.fileLocked(txSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	if startIndex >= endIndex || endIndex*core.DefaultChunkSize > uint64(len(file.data)) {
+		return nil, errors.Errorf("invalid chunk range [%v, %v)", startIndex, endIndex)
+	}
+
+	for index := startIndex / core.DefaultSegmentMaxChunks; index <= (endIndex-1)/core.DefaultSegmentMaxChunks; index++ {
+		if _, ok := file.proofs[index]; !ok {
+			return nil, errors.Errorf("segment %v not uploaded", index)
+		}
+	}
+
+	return common.CopyBytes(file.data[startIndex*core.DefaultChunkSize : endIndex*core.DefaultChunkSize]), nil
+}
+
+// DownloadSegmentWithProof implements the zgs_downloadSegmentWithProof RPC.
+func (service *ZgsService) DownloadSegmentWithProof(root common.Hash, index uint64) (*node.SegmentWithProof, error) {
+	service.mu.Lock()
+	file, noProofs := service.latestLocked(root), service.hooks.NoProofs
+	service.mu.Unlock()
+
+	if noProofs {
+		return nil, &methodNotFoundError{"zgs_downloadSegmentWithProof"}
+	}
+
+	if file == nil {
+		return nil, nil
+	}
+
+	return service.DownloadSegmentWithProofByTxSeq(file.tx.Seq, index)
+}
+
+// DownloadSegmentWithProofByTxSeq implements the zgs_downloadSegmentWithProofByTxSeq RPC.
+func (service *ZgsService) DownloadSegmentWithProofByTxSeq(txSeq, index uint64) (*node.SegmentWithProof, error) {
+	service.mu.Lock()
+	delay := service.hooks.DownloadDelay
+	service.mu.Unlock()
+
+	time.Sleep(delay)
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+
+	if service.hooks.NoProofs {
+		return nil, &methodNotFoundError{"zgs_downloadSegmentWithProofByTxSeq"}
+	}
+
+	file, err := service.fileLocked(txSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	proof, ok := file.proofs[index]
+	if !ok {
+		return nil, errors.Errorf("segment %v not uploaded", index)
+	}
+
+	end := min(uint64(len(file.data)), (index+1)*core.DefaultSegmentSize)
+
+	return &node.SegmentWithProof{
+		Root:     file.tx.DataMerkleRoot,
+		Data:     common.CopyBytes(file.data[index*core.DefaultSegmentSize : end]),
+		Index:    index,
+		Proof:    proof,
+		FileSize: file.tx.Size,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package testutil
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSegments returns random content of 1.5 segments, and the segments with proofs to upload.
+func newTestSegments(t *testing.T) ([]byte, []node.SegmentWithProof) {
+	content := make([]byte, core.DefaultSegmentSize+core.DefaultSegmentSize/2)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(t, err)
+
+	var segments []node.SegmentWithProof
+	for i := 0; i < 2; i++ {
+		segments = append(segments, node.SegmentWithProof{
+			Root:     tree.Root(),
+			Data:     content[i*core.DefaultSegmentSize : min(len(content), (i+1)*core.DefaultSegmentSize)],
+			Index:    uint64(i),
+			Proof:    tree.ProofAt(i),
+			FileSize: uint64(len(content)),
+		})
+	}
+
+	return content, segments
+}
+
+func newTestZgsClient(t *testing.T, service *ZgsService) *node.ZgsClient {
+	client, err := node.NewZgsClient(Serve(t, map[string]interface{}{"zgs": service}))
+	assert.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestZgsServiceUploadDownload(t *testing.T) {
+	service := NewZgsService()
+	client := newTestZgsClient(t, service)
+	ctx := context.Background()
+
+	content, segments := newTestSegments(t)
+	root, err := service.Submit(content, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, segments[0].Root, root)
+
+	// invalid segment rejected
+	invalid := segments[0]
+	invalid.Data = make([]byte, len(invalid.Data))
+	_, err = client.UploadSegmentByTxSeq(ctx, invalid, 0)
+	assert.ErrorContains(t, err, "invalid segment 0")
+
+	// not finalized until all segments uploaded
+	_, err = client.UploadSegment(ctx, segments[0])
+	assert.NoError(t, err)
+	finalized, err := client.CheckFileFinalized(ctx, node.TxSeqOrRoot{Root: root})
+	assert.NoError(t, err)
+	assert.False(t, *finalized)
+
+	_, err = client.DownloadSegment(ctx, root, 0, uint64(len(content))/core.DefaultChunkSize)
+	assert.ErrorContains(t, err, "segment 1 not uploaded")
+
+	_, err = client.UploadSegmentsByTxSeq(ctx, segments[1:], 0)
+	assert.NoError(t, err)
+	finalized, err = client.CheckFileFinalized(ctx, node.TxSeqOrRoot{TxSeq: 0})
+	assert.NoError(t, err)
+	assert.True(t, *finalized)
+
+	// downloaded with proof
+	data, err := client.DownloadSegmentByTxSeq(ctx, 0, 0, uint64(len(content))/core.DefaultChunkSize)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	segment, err := client.DownloadSegmentWithProof(ctx, root, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, segments[1].Data, segment.Data)
+	assert.NoError(t, validateSegment(node.Transaction{DataMerkleRoot: root, Size: uint64(len(content))}, segment))
+
+	// pruned
+	assert.NoError(t, service.Prune(root))
+	info, err := client.GetFileInfo(ctx, root)
+	assert.NoError(t, err)
+	assert.True(t, info.Pruned)
+	_, err = client.UploadSegment(ctx, segments[0])
+	assert.ErrorContains(t, err, "pruned")
+
+	// file not found
+	info, err = client.GetFileInfo(ctx, common.HexToHash("0x01"))
+	assert.NoError(t, err)
+	assert.Nil(t, info)
+}
+
+func TestZgsServiceHooks(t *testing.T) {
+	service := NewZgsService()
+	client := newTestZgsClient(t, service)
+	ctx := context.Background()
+
+	content, segments := newTestSegments(t)
+	root, err := service.Submit(content, nil)
+	assert.NoError(t, err)
+
+	var uploaded []uint64
+	busy := true
+	service.SetHooks(ZgsHooks{
+		ManualFinalize: true,
+		BeforeUpload: func(txSeq, index uint64) error {
+			if index == 1 && busy {
+				busy = false
+				return errors.New("node busy")
+			}
+
+			uploaded = append(uploaded, index)
+			return nil
+		},
+	})
+
+	// rejected by hook, and retried
+	_, err = client.UploadSegmentsByTxSeq(ctx, segments, 0)
+	assert.ErrorContains(t, err, "node busy")
+	_, err = client.UploadSegmentsByTxSeq(ctx, segments[1:], 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{0, 1}, uploaded)
+
+	// finalized by test
+	info, err := client.GetFileInfo(ctx, root)
+	assert.NoError(t, err)
+	assert.False(t, info.Finalized)
+	assert.Equal(t, uint64(2), info.UploadedSegNum)
+
+	assert.NoError(t, service.Finalize(root))
+	info, err = client.GetFileInfo(ctx, root)
+	assert.NoError(t, err)
+	assert.True(t, info.Finalized)
+}
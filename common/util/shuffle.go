@@ -1,13 +1,49 @@
 package util
 
 import (
+	"sync"
 	"time"
 
 	"golang.org/x/exp/rand"
 )
 
+var (
+	seedMu   sync.RWMutex
+	seedFunc = func(name string) uint64 {
+		return uint64(time.Now().UnixNano())
+	}
+)
+
+// Seed returns the seed of random number generator for the specified purpose, which is based on the current time
+// by default, see WrapSeedFunc. It is safe for concurrent use.
+func Seed(name string) uint64 {
+	seedMu.RLock()
+	fn := seedFunc
+	seedMu.RUnlock()
+
+	return fn(name)
+}
+
+// WrapSeedFunc replaces the function to generate random seeds with the one wrapping the current function, e.g. to
+// record or replay random seeds for debugging, and returns a function to restore the previous one. It is safe for
+// concurrent use with Seed.
+func WrapSeedFunc(wrap func(prev func(name string) uint64) func(name string) uint64) (restore func()) {
+	seedMu.Lock()
+	defer seedMu.Unlock()
+
+	prev := seedFunc
+	seedFunc = wrap(prev)
+
+	return func() {
+		seedMu.Lock()
+		defer seedMu.Unlock()
+
+		seedFunc = prev
+	}
+}
+
 func Shuffle[T any](items []T) {
-	rng := rand.New(rand.NewSource(uint64(time.Now().UnixNano())))
+	rng := rand.New(rand.NewSource(Seed("shuffle")))
 	for i := range items {
 		j := rng.Intn(i + 1)
 		items[i], items[j] = items[j], items[i]
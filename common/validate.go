@@ -0,0 +1,68 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// OptionError is returned when an option is invalid or conflicts with other options, which names the field
+// so that misconfiguration could be located easily.
+type OptionError struct {
+	Field  string
+	Reason string
+}
+
+// NewOptionError creates an error for the invalid option field.
+func NewOptionError(field string, format string, args ...interface{}) *OptionError {
+	return &OptionError{
+		Field:  field,
+		Reason: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("invalid option %v: %v", e.Field, e.Reason)
+}
+
+type number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~float32 | ~float64
+}
+
+// RequirePositive returns an error if the option value is not positive.
+func RequirePositive[T number](field string, value T) error {
+	if value > 0 {
+		return nil
+	}
+
+	return NewOptionError(field, "should be positive, got %v", value)
+}
+
+// RequireNonNegative returns an error if the option value is negative.
+func RequireNonNegative[T number](field string, value T) error {
+	if value >= 0 {
+		return nil
+	}
+
+	return NewOptionError(field, "should not be negative, got %v", value)
+}
+
+// RequireNotShorter returns an error if the duration is positive but shorter than the min duration, e.g. a
+// timeout that shorter than the poll interval will never poll again.
+func RequireNotShorter(field string, value time.Duration, min time.Duration) error {
+	if value <= 0 || value >= min {
+		return nil
+	}
+
+	return NewOptionError(field, "should not be shorter than %v, got %v", min, value)
+}
+
+// FirstError returns the first non-nil error, which is used to validate option fields one by one.
+func FirstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
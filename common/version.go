@@ -0,0 +1,167 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ClientName is the name of client library reported in User-Agent of RPCs and results, e.g. upload summary.
+const ClientName = "0g-storage-client"
+
+// modulePath is the path of this module, of which the version is resolved from build info if not set by ldflags.
+const modulePath = "github.com/0glabs/0g-storage-client"
+
+// develVersion is the version of client built from source without version information.
+const develVersion = "(devel)"
+
+// Features of client reported in results of operations that use them, see UseFeature.
+const (
+	FeatureChunkedManifest = "chunked-manifest" // directory metadata uploaded in chunks
+	FeatureBundle          = "bundle"           // files packed in bundle before committed
+	FeatureResume          = "resume"           // upload resumed from checkpoint file
+)
+
+// version is the client version set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/0glabs/0g-storage-client/common.version=v1.2.3"
+//
+// Otherwise, the module version is resolved from build info, e.g. when built as a dependency of applications.
+var version string
+
+var (
+	versionOnce     sync.Once
+	resolvedVersion string
+
+	userAgentOnce sync.Once
+	userAgent     string
+)
+
+// Version returns the version of client library, which is set by ldflags or resolved from build info, and
+// "(devel)" if neither available.
+func Version() string {
+	versionOnce.Do(func() {
+		resolvedVersion = resolveVersion()
+	})
+
+	return resolvedVersion
+}
+
+func resolveVersion() string {
+	if len(version) > 0 {
+		return version
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return develVersion
+	}
+
+	module := &info.Main
+	if module.Path != modulePath {
+		if module = findModule(info.Deps, modulePath); module == nil {
+			return develVersion
+		}
+	}
+
+	if module.Replace != nil && len(module.Replace.Version) > 0 {
+		module = module.Replace
+	}
+
+	if len(module.Version) == 0 {
+		return develVersion
+	}
+
+	return module.Version
+}
+
+func findModule(deps []*debug.Module, path string) *debug.Module {
+	for _, dep := range deps {
+		if dep.Path == path {
+			return dep
+		}
+	}
+
+	return nil
+}
+
+// featureSet is the features in use by an operation, which are also recorded into the enclosing operation if any.
+type featureSet struct {
+	mu       sync.Mutex
+	features []string // sorted
+	parent   *featureSet
+}
+
+type featureSetKey struct{}
+
+// WithFeatureTracking returns a copy of context that tracks features in use by an operation, see UseFeature and
+// FeaturesInUse. Nested operations inherit the features in use by enclosing operations, and features used by
+// nested operations are tracked by enclosing operations as well.
+func WithFeatureTracking(ctx context.Context) context.Context {
+	parent, _ := ctx.Value(featureSetKey{}).(*featureSet)
+	return context.WithValue(ctx, featureSetKey{}, &featureSet{features: FeaturesInUse(ctx), parent: parent})
+}
+
+// UseFeature records that the feature is in use by the operation of context, which is reported in results of the
+// operation, so that issues could be triaged by the features in play. It takes no effect if not tracked.
+func UseFeature(ctx context.Context, feature string) {
+	set, _ := ctx.Value(featureSetKey{}).(*featureSet)
+	for ; set != nil; set = set.parent {
+		set.mu.Lock()
+		if i, found := slices.BinarySearch(set.features, feature); !found {
+			set.features = slices.Insert(set.features, i, feature)
+		}
+		set.mu.Unlock()
+	}
+}
+
+// FeaturesInUse returns the features in use by the operation of context in alphabetical order.
+func FeaturesInUse(ctx context.Context) []string {
+	set, _ := ctx.Value(featureSetKey{}).(*featureSet)
+	if set == nil {
+		return nil
+	}
+
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	return slices.Clone(set.features)
+}
+
+// ClientInfo identifies the client library that produced a result, e.g. upload summary or ledger record.
+type ClientInfo struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Features []string `json:"features,omitempty"` // features in use by the operation, see UseFeature
+}
+
+// CurrentClient returns the client info of this process, along with the features in use by the operation if any,
+// e.g. FeaturesInUse.
+func CurrentClient(extra ...string) ClientInfo {
+	var inUse []string
+	for _, feature := range extra {
+		if i, found := slices.BinarySearch(inUse, feature); !found {
+			inUse = slices.Insert(inUse, i, feature)
+		}
+	}
+
+	return ClientInfo{
+		Name:     ClientName,
+		Version:  Version(),
+		Features: inUse,
+	}
+}
+
+// UserAgent returns the User-Agent of RPCs in format "<name>/<version> (<go version>; <os>/<arch>)".
+func UserAgent() string {
+	userAgentOnce.Do(func() {
+		comments := []string{runtime.Version(), runtime.GOOS + "/" + runtime.GOARCH}
+		userAgent = fmt.Sprintf("%v/%v (%v)", ClientName, Version(), strings.Join(comments, "; "))
+	})
+
+	return userAgent
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersion(t *testing.T) {
+	// resolved from build info, which is not versioned when testing
+	assert.Equal(t, develVersion, resolveVersion())
+	assert.Equal(t, Version(), CurrentClient().Version)
+
+	// set by ldflags
+	version = "v1.2.3"
+	defer func() { version = "" }()
+	assert.Equal(t, "v1.2.3", resolveVersion())
+}
+
+func TestUserAgent(t *testing.T) {
+	pattern := regexp.MustCompile(`^0g-storage-client/\S+ \(go[^;]+; \w+/\w+\)$`)
+	assert.Regexp(t, pattern, UserAgent())
+}
+
+func TestFeaturesInUse(t *testing.T) {
+	// not tracked
+	UseFeature(context.Background(), FeatureResume)
+	assert.Empty(t, FeaturesInUse(context.Background()))
+	assert.Empty(t, CurrentClient().Features)
+
+	outer := WithFeatureTracking(context.Background())
+	UseFeature(outer, FeatureResume)
+
+	// nested operation tracked by enclosing operation as well
+	inner := WithFeatureTracking(outer)
+	UseFeature(inner, FeatureBundle)
+	UseFeature(inner, FeatureBundle)
+	assert.Equal(t, []string{FeatureBundle, FeatureResume}, FeaturesInUse(inner))
+	assert.Equal(t, []string{FeatureBundle, FeatureResume}, FeaturesInUse(outer))
+
+	// features in use by enclosing operation only after nested operation created
+	UseFeature(outer, FeatureChunkedManifest)
+	assert.Equal(t, []string{FeatureBundle, FeatureResume}, FeaturesInUse(inner))
+
+	// sibling operation not affected
+	assert.Empty(t, FeaturesInUse(WithFeatureTracking(context.Background())))
+
+	// features of operation merged
+	client := CurrentClient(append(FeaturesInUse(inner), FeatureChunkedManifest, FeatureBundle)...)
+	assert.Equal(t, ClientName, client.Name)
+	assert.Equal(t, []string{FeatureBundle, FeatureChunkedManifest, FeatureResume}, client.Features)
+}
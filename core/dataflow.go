@@ -1,10 +1,10 @@
 package core
 
 import (
-	"context"
 	"errors"
+	"math/bits"
+	"sync"
 
-	"github.com/0glabs/0g-storage-client/common/parallel"
 	"github.com/0glabs/0g-storage-client/core/merkle"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -37,24 +37,57 @@ type IterableData interface {
 	Split(fragmentSize int64) []IterableData
 }
 
-// MerkleTree create merkle tree of the data.
-func MerkleTree(data IterableData) (*merkle.Tree, error) {
-	var builder merkle.TreeBuilder
-	initializer := &TreeBuilderInitializer{
-		data:    data,
-		offset:  0,
-		batch:   DefaultSegmentSize,
-		builder: &builder,
+// KnownSegments is optionally implemented by IterableData, of which the leading segments are already known,
+// e.g. data appended to an existing file. Roots of known segments are used directly without reading and
+// hashing the segment data again. Note, known segments are still read to upload to storage nodes, since the
+// data is submitted as a new file.
+type KnownSegments interface {
+	// KnownSegmentRoots returns the roots of leading segments that fully filled with data.
+	KnownSegmentRoots() []common.Hash
+}
+
+// NumKnownSegments returns the number of leading segments that already known of the data.
+func NumKnownSegments(data IterableData) uint64 {
+	if known, ok := data.(KnownSegments); ok {
+		return uint64(len(known.KnownSegmentRoots()))
 	}
 
-	err := parallel.Serial(context.Background(), initializer, NumSegmentsPadded(data))
-	if err != nil {
+	return 0
+}
+
+// MerkleTree create merkle tree of the data, which is read as specified by option if any. Segments and interior
+// nodes of large trees are hashed by HashOption.Routines goroutines in parallel.
+func MerkleTree(data IterableData, option ...HashOption) (*merkle.Tree, error) {
+	var opt HashOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	var builder merkle.TreeBuilder
+	builder.WithMaxWorkers(opt.routines())
+	if err := newSegmentHasher(data, opt, &builder).build(opt); err != nil {
 		return nil, err
 	}
 
 	return builder.Build(), nil
 }
 
+// MerkleRootData returns the merkle root hash of the data, which is read as specified by option if any. Unlike
+// MerkleTree, the tree is not retained, so that memory is bounded regardless of the data size.
+func MerkleRootData(data IterableData, option ...HashOption) (common.Hash, error) {
+	var opt HashOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	var builder merkle.RootBuilder
+	if err := newSegmentHasher(data, opt, &builder).build(opt); err != nil {
+		return common.Hash{}, err
+	}
+
+	return builder.Root(), nil
+}
+
 func NumSplits(total int64, unit int) uint64 {
 	return uint64((total-1)/int64(unit) + 1)
 }
@@ -64,9 +97,27 @@ func NumSegmentsPadded(data IterableData) int {
 	return int((data.PaddedSize()-1)/DefaultSegmentSize + 1)
 }
 
-// SegmentRoot return the merkle root of given chunks
+// EmptyChunkRoots returns the roots of subtrees of empty chunks, where the i-th hash is the root of 2^i empty chunks
+// up to a segment. Since constant, empty chunks padded are hashed in logarithmic time, see SegmentRoot.
+func EmptyChunkRoots() []common.Hash {
+	return append([]common.Hash(nil), emptyChunkRoots()...)
+}
+
+var emptyChunkRoots = sync.OnceValue(func() []common.Hash {
+	return merkle.RepeatedRoots(EmptyChunkHash, bits.TrailingZeros(DefaultSegmentMaxChunks))
+})
+
+// dataChunksLen returns the length of the leading n bytes at offset that overlap with data, which is rounded up to
+// chunks, so that the following chunks are known as empty chunks padded.
+func dataChunksLen(data IterableData, offset int64, n int) int {
+	dataLen := (data.Size() - offset + DefaultChunkSize - 1) / DefaultChunkSize * DefaultChunkSize
+	return int(max(0, min(int64(n), dataLen)))
+}
+
+// SegmentRoot return the merkle root of given chunks, which is hashed without building the tree, since segments
+// are hashed in parallel. Empty chunks padded are not hashed one by one, see EmptyChunkRoots.
 func SegmentRoot(chunks []byte, emptyChunksPadded ...uint64) common.Hash {
-	var builder merkle.TreeBuilder
+	var builder merkle.RootBuilder
 
 	// append chunks
 	for offset, dataLen := 0, len(chunks); offset < dataLen; offset += DefaultChunkSize {
@@ -76,22 +127,25 @@ func SegmentRoot(chunks []byte, emptyChunksPadded ...uint64) common.Hash {
 
 	// append empty chunks
 	if len(emptyChunksPadded) > 0 && emptyChunksPadded[0] > 0 {
-		for i := uint64(0); i < emptyChunksPadded[0]; i++ {
-			builder.AppendHash(EmptyChunkHash)
-		}
-	}
-
-	if tree := builder.Build(); tree != nil {
-		return tree.Root()
+		builder.AppendRepeated(emptyChunkRoots(), emptyChunksPadded[0])
 	}
 
-	return common.Hash{}
+	return builder.Root()
 }
 
 // PaddedSegmentRoot calculates the Merkle root for a given segment based on its index, the chunk data,
 // and the file size. It handles the logic of padding empty chunks if the segment is the last one
 // and doesn't have enough data to form a complete segment.
 func PaddedSegmentRoot(segmentIndex uint64, chunks []byte, fileSize int64) (common.Hash, uint64) {
+	emptyChunksPadded, numSegmentsFlowPadded := segmentEmptyChunksPadded(segmentIndex, fileSize)
+
+	// compute and return the Merkle root for the segment, considering any padding
+	return SegmentRoot(chunks, emptyChunksPadded), numSegmentsFlowPadded
+}
+
+// segmentEmptyChunksPadded returns the number of empty chunks padded to the specified segment of file, along with
+// the number of segments padded for flow.
+func segmentEmptyChunksPadded(segmentIndex uint64, fileSize int64) (uint64, uint64) {
 	numChunks := NumSplits(fileSize, DefaultChunkSize)
 	numChunksFlowPadded, _ := ComputePaddedSize(numChunks)
 	numSegmentsFlowPadded := (numChunksFlowPadded-1)/DefaultSegmentMaxChunks + 1
@@ -114,8 +168,7 @@ func PaddedSegmentRoot(segmentIndex uint64, chunks []byte, fileSize int64) (comm
 		}
 	}
 
-	// compute and return the Merkle root for the segment, considering any padding
-	return SegmentRoot(chunks, emptyChunksPadded), numSegmentsFlowPadded
+	return emptyChunksPadded, numSegmentsFlowPadded
 }
 
 func paddingZeros(buf []byte, startOffset int, length int) {
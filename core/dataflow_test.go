@@ -1,11 +1,14 @@
 package core
 
 import (
+	"bytes"
 	"math/rand"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/0glabs/0g-storage-client/core/merkle"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,3 +41,106 @@ func TestFileAndInMemoryData(t *testing.T) {
 
 	assert.Equal(t, fileTree.Root(), inMemTree.Root())
 }
+
+// hashPaddedChunks calculates the merkle root and segment roots by hashing all chunks of the padded data one by one,
+// including empty chunks padded.
+func hashPaddedChunks(content []byte) (common.Hash, []common.Hash) {
+	padded := make([]byte, IteratorPaddedSize(int64(len(content)), true))
+	copy(padded, content)
+
+	var fileBuilder merkle.TreeBuilder
+	var segmentRoots []common.Hash
+	for offset := 0; offset < len(padded); offset += DefaultSegmentSize {
+		var segmentBuilder merkle.TreeBuilder
+		for chunk := offset; chunk < min(offset+DefaultSegmentSize, len(padded)); chunk += DefaultChunkSize {
+			segmentBuilder.Append(padded[chunk : chunk+DefaultChunkSize])
+		}
+
+		segmentRoots = append(segmentRoots, segmentBuilder.Build().Root())
+		fileBuilder.AppendHash(segmentRoots[len(segmentRoots)-1])
+	}
+
+	return fileBuilder.Build().Root(), segmentRoots
+}
+
+func TestEmptyChunksPadded(t *testing.T) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for _, segments := range []int{0, 1, 2, 5, 16, 33} {
+		for _, remainder := range []int{1, DefaultChunkSize - 1, DefaultChunkSize, DefaultChunkSize + 1, DefaultSegmentSize/2 + 1, DefaultSegmentSize} {
+			size := segments*DefaultSegmentSize + remainder
+			content := make([]byte, size)
+			r.Read(content)
+			expected, segmentRoots := hashPaddedChunks(content)
+
+			data, err := NewDataInMemory(content)
+			assert.NoError(t, err)
+
+			for _, opt := range []HashOption{{}, {BufferSize: DefaultSegmentSize * 4, Readahead: 2}} {
+				tree, err := MerkleTree(data, opt)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, tree.Root(), "size = %v, option = %+v", size, opt)
+			}
+
+			root, err := MerkleRootData(data)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, root, "size = %v", size)
+
+			root, err = MerkleRootReader(bytes.NewReader(content), int64(size))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, root, "size = %v", size)
+
+			submission, err := NewFlow(data, nil).CreateSubmission()
+			assert.NoError(t, err)
+			assert.Equal(t, expected, submission.Root(), "size = %v", size)
+
+			// the last segment of data verified by proof
+			index := (size - 1) / DefaultSegmentSize
+			chunks := make([]byte, (size-index*DefaultSegmentSize+DefaultChunkSize-1)/DefaultChunkSize*DefaultChunkSize)
+			copy(chunks, content[index*DefaultSegmentSize:])
+			segmentRoot, numSegments := PaddedSegmentRoot(uint64(index), chunks, int64(size))
+			assert.Equal(t, segmentRoots[index], segmentRoot, "size = %v", size)
+			assert.Equal(t, uint64(len(segmentRoots)), numSegments, "size = %v", size)
+		}
+	}
+
+	assert.Len(t, EmptyChunkRoots(), 11)
+	assert.Equal(t, EmptyChunkHash, EmptyChunkRoots()[0])
+	assert.Equal(t, SegmentRoot(make([]byte, DefaultSegmentSize)), EmptyChunkRoots()[10])
+}
+
+func BenchmarkEmptyChunksPadded(b *testing.B) {
+	// a file one byte over a segment boundary, of which the last segment is padded with 127 empty chunks
+	size := DefaultSegmentSize + 1
+	content := make([]byte, size)
+	rand.Read(content)
+	data, err := NewDataInMemory(content)
+	assert.NoError(b, err)
+
+	chunks := make([]byte, DefaultChunkSize)
+	chunks[0] = content[DefaultSegmentSize]
+	padded := make([]byte, IteratorPaddedSize(int64(size), true)-DefaultSegmentSize)
+	copy(padded, chunks)
+	expected, _ := PaddedSegmentRoot(1, chunks, int64(size))
+	assert.Equal(b, SegmentRoot(padded), expected)
+
+	b.Run("segment,zeros", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			SegmentRoot(padded)
+		}
+	})
+
+	b.Run("segment,padded", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			PaddedSegmentRoot(1, chunks, int64(size))
+		}
+	})
+
+	b.Run("file", func(b *testing.B) {
+		b.SetBytes(int64(size))
+		for i := 0; i < b.N; i++ {
+			_, err := MerkleRootData(data)
+			assert.NoError(b, err)
+		}
+	})
+}
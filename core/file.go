@@ -28,9 +28,17 @@ type File struct {
 var _ IterableData = (*File)(nil)
 
 func (file *File) Read(buf []byte, offset int64) (int, error) {
+	// never read beyond the file, e.g. data of the next fragment
+	if offset >= file.size {
+		return 0, nil
+	}
+	if remaining := file.size - offset; int64(len(buf)) > remaining {
+		buf = buf[:remaining]
+	}
+
 	n, err := file.underlying.ReadAt(buf, file.offset+offset)
 	// unexpected IO error
-	if !errors.Is(err, io.EOF) {
+	if err != nil && !errors.Is(err, io.EOF) {
 		return 0, err
 	}
 	return n, nil
@@ -80,22 +88,35 @@ func Open(name string) (*File, error) {
 	}, nil
 }
 
-// MerkleRoot returns the merkle root hash of a file on disk
-func MerkleRoot(filename string) (common.Hash, error) {
+// MerkleRoot returns the merkle root hash of a file on disk, which is read as specified by option if any.
+func MerkleRoot(filename string, option ...HashOption) (common.Hash, error) {
 	file, err := Open(filename)
 	if err != nil {
 		return common.Hash{}, errors.WithMessage(err, "failed to open file")
 	}
 	defer file.Close()
 
-	// Generate the Merkle tree from the file content
-	tree, err := MerkleTree(file)
+	root, err := MerkleRootData(file, option...)
 	if err != nil {
-		return common.Hash{}, errors.WithMessage(err, "failed to create merkle tree")
+		return common.Hash{}, errors.WithMessage(err, "failed to calculate merkle root")
 	}
 
-	// Return the root hash of the Merkle tree
-	return tree.Root(), nil
+	return root, nil
+}
+
+// Tail returns the data of file from the specified offset to the end, which shares the underlying file.
+func (file *File) Tail(offset int64) (*File, error) {
+	if offset < 0 || offset >= file.size {
+		return nil, errors.Errorf("offset out of range, offset = %v, size = %v", offset, file.size)
+	}
+
+	return &File{
+		FileInfo:   file.FileInfo,
+		underlying: file.underlying,
+		offset:     file.offset + offset,
+		size:       file.size - offset,
+		paddedSize: IteratorPaddedSize(file.size-offset, true),
+	}, nil
 }
 
 func (file *File) Close() error {
@@ -125,7 +146,8 @@ func (file *File) Offset() int64 {
 func (file *File) Split(fragmentSize int64) []IterableData {
 	fragments := make([]IterableData, 0)
 	for offset := file.offset; offset < file.offset+file.size; offset += fragmentSize {
-		size := min(file.size-offset, fragmentSize)
+		// offset is absolute in the underlying file, e.g. file returned by Tail
+		size := min(file.offset+file.size-offset, fragmentSize)
 		fragment := &File{
 			FileInfo:   file.FileInfo,
 			underlying: file.underlying,
@@ -73,23 +73,15 @@ func ComputePaddedSize(chunks uint64) (uint64, uint64) {
 	return paddedChunks, chunksNextPow2
 }
 
-// e.g. 64, 32, 1 in chunks
+// e.g. 64, 32, 1 in chunks, see SubmissionNodeSizes
 func (flow *Flow) splitNodes() []int64 {
 	var nodes []int64
-
-	chunks := flow.data.NumChunks()
-	paddedChunks, chunksNextPow2 := ComputePaddedSize(chunks)
-	nextChunkSize := chunksNextPow2
-
-	for paddedChunks > 0 {
-		if paddedChunks >= nextChunkSize {
-			paddedChunks -= nextChunkSize
-			nodes = append(nodes, int64(nextChunkSize))
-		}
-		nextChunkSize /= 2
+	for _, chunks := range SubmissionNodeSizes(flow.data.Size()) {
+		nodes = append(nodes, int64(chunks))
 	}
+
 	flow.logger.WithFields(logrus.Fields{
-		"chunks":   chunks,
+		"chunks":   flow.data.NumChunks(),
 		"nodeSize": nodes,
 	}).Debug("SplitNodes")
 
@@ -0,0 +1,40 @@
+package core
+
+// Footprint is the storage footprint of data on 0g storage network, which is the single source of
+// the padding math for fee estimation and quota systems.
+type Footprint struct {
+	Size       int64  `json:"size"`       // original data size in bytes
+	Chunks     uint64 `json:"chunks"`     // number of chunks of the original data
+	Segments   uint64 `json:"segments"`   // number of segments of the original data
+	Sectors    uint64 `json:"sectors"`    // number of sectors charged, i.e. the padded chunks in flow
+	PaddedSize uint64 `json:"paddedSize"` // padded data size in bytes stored in flow
+}
+
+// StorageFootprint returns the storage footprint of data with the specified size.
+func StorageFootprint(size int64) Footprint {
+	if size <= 0 {
+		return Footprint{}
+	}
+
+	chunks := NumChunks(size)
+	sectors, _ := ComputePaddedSize(chunks)
+
+	return Footprint{
+		Size:       size,
+		Chunks:     chunks,
+		Segments:   NumSegments(size),
+		Sectors:    sectors,
+		PaddedSize: PaddedSize(size),
+	}
+}
+
+// Add returns the total footprint of two data.
+func (fp Footprint) Add(other Footprint) Footprint {
+	return Footprint{
+		Size:       fp.Size + other.Size,
+		Chunks:     fp.Chunks + other.Chunks,
+		Segments:   fp.Segments + other.Segments,
+		Sectors:    fp.Sectors + other.Sectors,
+		PaddedSize: fp.PaddedSize + other.PaddedSize,
+	}
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageFootprint(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected Footprint
+	}{
+		{0, Footprint{}},
+		{1, Footprint{Size: 1, Chunks: 1, Segments: 1, Sectors: 1, PaddedSize: 256}},
+		{256, Footprint{Size: 256, Chunks: 1, Segments: 1, Sectors: 1, PaddedSize: 256}},
+		{257, Footprint{Size: 257, Chunks: 2, Segments: 1, Sectors: 2, PaddedSize: 512}},
+		{256 * 17, Footprint{Size: 256 * 17, Chunks: 17, Segments: 1, Sectors: 18, PaddedSize: 256 * 18}},
+		{DefaultSegmentSize, Footprint{Size: DefaultSegmentSize, Chunks: 1024, Segments: 1, Sectors: 1024, PaddedSize: DefaultSegmentSize}},
+		{DefaultSegmentSize + 1, Footprint{Size: DefaultSegmentSize + 1, Chunks: 1025, Segments: 2, Sectors: 1152, PaddedSize: 1152 * 256}},
+		{DefaultSegmentSize * 3, Footprint{Size: DefaultSegmentSize * 3, Chunks: 3072, Segments: 3, Sectors: 3072, PaddedSize: DefaultSegmentSize * 3}},
+		{DefaultSegmentSize*4 - 1, Footprint{Size: DefaultSegmentSize*4 - 1, Chunks: 4096, Segments: 4, Sectors: 4096, PaddedSize: DefaultSegmentSize * 4}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, StorageFootprint(tt.size), "size = %v", tt.size)
+
+		if tt.size > 0 {
+			// consistent with the padding of flow submission
+			assert.Equal(t, IteratorPaddedSize(tt.size, true), tt.expected.PaddedSize)
+		}
+	}
+}
+
+func TestFootprintAdd(t *testing.T) {
+	total := StorageFootprint(1).Add(StorageFootprint(257))
+	assert.Equal(t, Footprint{Size: 258, Chunks: 3, Segments: 2, Sectors: 3, PaddedSize: 768}, total)
+}
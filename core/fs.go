@@ -0,0 +1,142 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// FSFile implement of IterableData, the underlying is a file of io/fs.FS, e.g. embed.FS or fstest.MapFS, so that
+// generated content could be uploaded without writing to disk.
+type FSFile struct {
+	underlying io.ReaderAt
+	closer     io.Closer // nil if loaded into memory
+	offset     int64
+	size       int64
+	paddedSize uint64
+}
+
+var _ IterableData = (*FSFile)(nil)
+
+// OpenFS creates a FSFile from a file of fsys. The file is read at offsets if it implements io.ReaderAt, e.g. files
+// of os.DirFS, embed.FS and fstest.MapFS, and otherwise loaded into memory, e.g. files of zip archives.
+func OpenFS(fsys fs.FS, name string) (*FSFile, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if info.IsDir() {
+		file.Close()
+		return nil, ErrFileRequired
+	}
+
+	if info.Size() == 0 {
+		file.Close()
+		return nil, ErrFileEmpty
+	}
+
+	underlying, ok := file.(io.ReaderAt)
+	if !ok {
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if int64(len(content)) != info.Size() {
+			return nil, errors.Errorf("file size mismatch, expected %v, actual %v", info.Size(), len(content))
+		}
+
+		return newFSFile(bytes.NewReader(content), nil, info.Size()), nil
+	}
+
+	return newFSFile(underlying, file, info.Size()), nil
+}
+
+func newFSFile(underlying io.ReaderAt, closer io.Closer, size int64) *FSFile {
+	return &FSFile{
+		underlying: underlying,
+		closer:     closer,
+		size:       size,
+		paddedSize: IteratorPaddedSize(size, true),
+	}
+}
+
+// MerkleRootFS returns the merkle root hash of a file of fsys, which is read as specified by option if any.
+func MerkleRootFS(fsys fs.FS, name string, option ...HashOption) (common.Hash, error) {
+	file, err := OpenFS(fsys, name)
+	if err != nil {
+		return common.Hash{}, errors.WithMessage(err, "failed to open file")
+	}
+	defer file.Close()
+
+	root, err := MerkleRootData(file, option...)
+	if err != nil {
+		return common.Hash{}, errors.WithMessage(err, "failed to calculate merkle root")
+	}
+
+	return root, nil
+}
+
+func (file *FSFile) Read(buf []byte, offset int64) (int, error) {
+	n, err := file.underlying.ReadAt(buf, file.offset+offset)
+	// unexpected IO error
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (file *FSFile) Close() error {
+	if file.closer == nil {
+		return nil
+	}
+
+	return file.closer.Close()
+}
+
+func (file *FSFile) NumChunks() uint64 {
+	return NumSplits(file.Size(), DefaultChunkSize)
+}
+
+func (file *FSFile) NumSegments() uint64 {
+	return NumSplits(file.Size(), DefaultSegmentSize)
+}
+
+func (file *FSFile) PaddedSize() uint64 {
+	return file.paddedSize
+}
+
+func (file *FSFile) Size() int64 {
+	return file.size
+}
+
+func (file *FSFile) Offset() int64 {
+	return file.offset
+}
+
+func (file *FSFile) Split(fragmentSize int64) []IterableData {
+	fragments := make([]IterableData, 0)
+	for offset := file.offset; offset < file.offset+file.size; offset += fragmentSize {
+		size := min(file.size-offset, fragmentSize)
+		fragment := &FSFile{
+			underlying: file.underlying,
+			closer:     file.closer,
+			offset:     offset,
+			size:       size,
+			paddedSize: IteratorPaddedSize(size, true),
+		}
+		fragments = append(fragments, fragment)
+	}
+	return fragments
+}
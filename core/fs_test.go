@@ -0,0 +1,109 @@
+package core
+
+import (
+	"crypto/rand"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readerFS hides io.ReaderAt of files, e.g. files of zip archives.
+type readerFS struct {
+	fstest.MapFS
+}
+
+func (fsys readerFS) Open(name string) (fs.File, error) {
+	file, err := fsys.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return struct{ fs.File }{file}, nil
+}
+
+func TestMerkleRootFS(t *testing.T) {
+	content := make([]byte, 3*DefaultSegmentSize+100)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "data")
+	assert.NoError(t, os.WriteFile(path, content, 0644))
+	expected, err := MerkleRoot(path)
+	assert.NoError(t, err)
+
+	fsys := fstest.MapFS{
+		"data":  {Data: content},
+		"empty": {Data: []byte{}},
+		"dir/a": {Data: []byte("a")},
+	}
+
+	for _, fsys := range []fs.FS{fsys, readerFS{fsys}} {
+		root, err := MerkleRootFS(fsys, "data")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, root)
+
+		file, err := OpenFS(fsys, "data")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(content)), file.Size())
+		assert.Equal(t, uint64(4), file.NumSegments())
+
+		fragments := file.Split(DefaultSegmentSize * 2)
+		assert.Equal(t, 2, len(fragments))
+		buf := make([]byte, 100)
+		n, err := fragments[1].Read(buf, DefaultSegmentSize)
+		assert.NoError(t, err)
+		assert.Equal(t, 100, n)
+		assert.Equal(t, content[3*DefaultSegmentSize:], buf)
+		assert.NoError(t, file.Close())
+	}
+
+	_, err = OpenFS(fsys, "empty")
+	assert.ErrorIs(t, err, ErrFileEmpty)
+	_, err = OpenFS(fsys, "dir")
+	assert.ErrorIs(t, err, ErrFileRequired)
+	_, err = OpenFS(fsys, "missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestFileTailSplit(t *testing.T) {
+	content := make([]byte, 5*DefaultSegmentSize+100)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "data")
+	assert.NoError(t, os.WriteFile(path, content, 0644))
+	file, err := Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	offset := int64(DefaultSegmentSize + 50)
+	tail, err := file.Tail(offset)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content))-offset, tail.Size())
+
+	// fragments cover the tail exactly, and never read beyond
+	fragments := tail.Split(2 * DefaultSegmentSize)
+	assert.Equal(t, 3, len(fragments))
+
+	var joined []byte
+	for _, fragment := range fragments {
+		buf := make([]byte, fragment.Size()+100)
+		n, err := fragment.Read(buf, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int(fragment.Size()), n)
+		joined = append(joined, buf[:n]...)
+	}
+	assert.Equal(t, content[offset:], joined)
+
+	expected, err := NewDataInMemory(content[offset : offset+2*DefaultSegmentSize])
+	assert.NoError(t, err)
+	expectedRoot, err := MerkleRootData(expected)
+	assert.NoError(t, err)
+	root, err := MerkleRootData(fragments[0])
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRoot, root)
+}
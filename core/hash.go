@@ -0,0 +1,233 @@
+package core
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/parallel"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// HashOption is the option to read data when calculating merkle tree.
+//
+// By default, data is read by segment in parallel, which is efficient on SSD. On spinning disks, e.g. HDD-backed
+// NAS, parallel and small reads are seek-bound, so a larger BufferSize along with Readahead to read sequentially
+// is recommended, where segments of each buffer are still hashed in parallel.
+type HashOption struct {
+	BufferSize int64 // bytes to read at a time, rounded up to multiple of segment size, default DefaultSegmentSize
+	Readahead  int   // number of buffers prefetched in background to hash sequentially, 0 to disable
+	Routines   int   // number of goroutines to hash in parallel, which also read in parallel unless readahead enabled
+}
+
+// Validate checks the option, and returns an error that names the invalid field if any.
+func (opt HashOption) Validate() error {
+	if err := zg_common.RequireNonNegative("BufferSize", opt.BufferSize); err != nil {
+		return err
+	}
+
+	if err := zg_common.RequireNonNegative("Readahead", opt.Readahead); err != nil {
+		return err
+	}
+
+	return zg_common.RequireNonNegative("Routines", opt.Routines)
+}
+
+// bufferSize returns the buffer size rounded up to multiple of segment size.
+func (opt HashOption) bufferSize() int64 {
+	if opt.BufferSize <= DefaultSegmentSize {
+		return DefaultSegmentSize
+	}
+
+	return (opt.BufferSize + DefaultSegmentSize - 1) / DefaultSegmentSize * DefaultSegmentSize
+}
+
+// routines returns the number of goroutines to hash in parallel.
+func (opt HashOption) routines() int {
+	if opt.Routines > 0 {
+		return opt.Routines
+	}
+
+	return runtime.GOMAXPROCS(0)
+}
+
+// hashAppender accepts segment roots in order, e.g. merkle.TreeBuilder to build the whole tree, or
+// merkle.RootBuilder to calculate the root only.
+type hashAppender interface {
+	AppendHash(hash common.Hash)
+}
+
+// segmentHasher reads data by buffer, and calculates the roots of segments in buffer.
+type segmentHasher struct {
+	data       IterableData
+	bufferSize int64
+	builder    hashAppender
+	workers    int // number of goroutines to hash segments of a buffer
+}
+
+var _ parallel.Interface = (*segmentHasher)(nil)
+
+func newSegmentHasher(data IterableData, opt HashOption, builder hashAppender) *segmentHasher {
+	return &segmentHasher{
+		data:       data,
+		bufferSize: opt.bufferSize(),
+		builder:    builder,
+		workers:    1,
+	}
+}
+
+// numBuffers returns the number of buffers of padded data.
+func (hasher *segmentHasher) numBuffers() int {
+	return int((hasher.data.PaddedSize()-1)/uint64(hasher.bufferSize) + 1)
+}
+
+// hashBuffer is the data read from a buffer, of which the leading segment roots are already known.
+type hashBuffer struct {
+	knownRoots []common.Hash
+	data       []byte
+	offset     int64 // offset of data
+}
+
+// read reads the data of specified buffer, except the segments of which the roots are already known.
+func (hasher *segmentHasher) read(index int) (*hashBuffer, error) {
+	offset := int64(index) * hasher.bufferSize
+	size := min(uint64(hasher.bufferSize), hasher.data.PaddedSize()-uint64(offset))
+
+	var buf hashBuffer
+	if known, ok := hasher.data.(KnownSegments); ok {
+		roots := known.KnownSegmentRoots()
+		for segIndex := offset / DefaultSegmentSize; segIndex < int64(len(roots)) && uint64(len(buf.knownRoots)*DefaultSegmentSize) < size; segIndex++ {
+			buf.knownRoots = append(buf.knownRoots, roots[segIndex])
+		}
+	}
+
+	knownSize := uint64(len(buf.knownRoots) * DefaultSegmentSize)
+	if knownSize >= size {
+		return &buf, nil
+	}
+
+	var err error
+	buf.offset = offset + int64(knownSize)
+	buf.data, err = ReadAt(hasher.data, int(size-knownSize), buf.offset, hasher.data.PaddedSize())
+
+	return &buf, err
+}
+
+// hash returns the segment roots in buffer, where segments are hashed by workers in parallel if more than 1.
+func (hasher *segmentHasher) hash(buf *hashBuffer) []common.Hash {
+	numKnown := len(buf.knownRoots)
+	roots := make([]common.Hash, numKnown+(len(buf.data)+DefaultSegmentSize-1)/DefaultSegmentSize)
+	copy(roots, buf.knownRoots)
+
+	hashSegment := func(i int) {
+		start := (i - numKnown) * DefaultSegmentSize
+		end := min(start+DefaultSegmentSize, len(buf.data))
+		dataEnd := start + dataChunksLen(hasher.data, buf.offset+int64(start), end-start)
+		roots[i] = SegmentRoot(buf.data[start:dataEnd], uint64(end-dataEnd)/DefaultChunkSize)
+	}
+
+	workers := min(hasher.workers, len(roots)-numKnown)
+	if workers <= 1 {
+		for i := numKnown; i < len(roots); i++ {
+			hashSegment(i)
+		}
+
+		return roots
+	}
+
+	var wg sync.WaitGroup
+	var next atomic.Int64
+	next.Store(int64(numKnown))
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < len(roots); i = int(next.Add(1) - 1) {
+				hashSegment(i)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return roots
+}
+
+func (hasher *segmentHasher) append(roots []common.Hash) {
+	for _, root := range roots {
+		hasher.builder.AppendHash(root)
+	}
+}
+
+// ParallelDo implements parallel.Interface.
+func (hasher *segmentHasher) ParallelDo(ctx context.Context, routine int, task int) (interface{}, error) {
+	buf, err := hasher.read(task)
+	if err != nil {
+		return nil, err
+	}
+
+	return hasher.hash(buf), nil
+}
+
+// ParallelCollect implements parallel.Interface.
+func (hasher *segmentHasher) ParallelCollect(result *parallel.Result) error {
+	hasher.append(result.Value.([]common.Hash))
+	return nil
+}
+
+// readahead hashes buffers sequentially, while the next buffers are prefetched in background.
+func (hasher *segmentHasher) readahead(readahead int) error {
+	type prefetched struct {
+		buf *hashBuffer
+		err error
+	}
+
+	numBuffers := hasher.numBuffers()
+	bufCh := make(chan prefetched, readahead)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(bufCh)
+
+		for i := 0; i < numBuffers; i++ {
+			buf, err := hasher.read(i)
+
+			select {
+			case bufCh <- prefetched{buf, err}:
+			case <-done:
+				return
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for item := range bufCh {
+		if item.err != nil {
+			return item.err
+		}
+
+		hasher.append(hasher.hash(item.buf))
+	}
+
+	return nil
+}
+
+// build calculates the merkle tree of data. If readahead enabled, buffers are read sequentially, and segments of
+// each buffer are hashed in parallel. Otherwise, buffers are read and hashed in parallel.
+func (hasher *segmentHasher) build(opt HashOption) error {
+	if opt.Readahead > 0 {
+		hasher.workers = opt.routines()
+		return hasher.readahead(opt.Readahead)
+	}
+
+	return parallel.Serial(context.Background(), hasher, hasher.numBuffers(), parallel.SerialOption{
+		Routines: opt.routines(),
+	})
+}
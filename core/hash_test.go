@@ -0,0 +1,151 @@
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// knownData is in-memory data of which the leading segments are known, and fails to read known segments.
+type knownData struct {
+	*DataInMemory
+	roots []common.Hash
+}
+
+func (data *knownData) KnownSegmentRoots() []common.Hash {
+	return data.roots
+}
+
+func (data *knownData) Read(buf []byte, offset int64) (int, error) {
+	if offset < int64(len(data.roots))*DefaultSegmentSize {
+		return 0, errors.Errorf("known segment read at %v", offset)
+	}
+
+	return data.DataInMemory.Read(buf, offset)
+}
+
+// failedData fails to read since the specified offset.
+type failedData struct {
+	*DataInMemory
+	failedOffset int64
+}
+
+func (data *failedData) Read(buf []byte, offset int64) (int, error) {
+	if offset+int64(len(buf)) > data.failedOffset {
+		return 0, errors.New("disk failure")
+	}
+
+	return data.DataInMemory.Read(buf, offset)
+}
+
+func TestMerkleTreeHashOption(t *testing.T) {
+	content := make([]byte, DefaultSegmentSize*9+1000)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	data, err := NewDataInMemory(content)
+	assert.NoError(t, err)
+	expected, err := MerkleTree(data)
+	assert.NoError(t, err)
+
+	var roots []common.Hash
+	for i := 0; i < 4; i++ {
+		roots = append(roots, SegmentRoot(content[i*DefaultSegmentSize:(i+1)*DefaultSegmentSize]))
+	}
+	known := &knownData{data, roots}
+
+	for _, opt := range []HashOption{
+		{BufferSize: DefaultSegmentSize * 4},
+		{BufferSize: DefaultSegmentSize*2 + 1000}, // rounded up to 3 segments
+		{BufferSize: DefaultSegmentSize * 3, Readahead: 2},
+		{BufferSize: DefaultSegmentSize * 3, Readahead: 2, Routines: 1},
+		{BufferSize: DefaultSegmentSize * 3, Readahead: 2, Routines: 4}, // segments of buffer hashed in parallel
+		{Routines: 1},
+		{BufferSize: DefaultSegmentSize * 64, Readahead: 4},
+	} {
+		tree, err := MerkleTree(data, opt)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.Root(), tree.Root(), "%+v", opt)
+
+		// known segments not read even if in the same buffer of unknown segments
+		tree, err = MerkleTree(known, opt)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.Root(), tree.Root(), "%+v", opt)
+
+		// read error returned
+		_, err = MerkleTree(&failedData{data, DefaultSegmentSize * 5}, opt)
+		assert.ErrorContains(t, err, "disk failure", "%+v", opt)
+	}
+
+	assert.NoError(t, HashOption{}.Validate())
+	assert.ErrorContains(t, HashOption{Readahead: -1}.Validate(), "invalid option Readahead")
+}
+
+func BenchmarkMerkleRoot(b *testing.B) {
+	// file is likely served from page cache, so this benchmark mainly measures the overhead of buffer sizes and
+	// readahead, rather than the seek-bound throughput on spinning disks
+	path := filepath.Join(b.TempDir(), "large")
+	content := make([]byte, 64*1024*1024)
+	_, err := rand.Read(content)
+	assert.NoError(b, err)
+	assert.NoError(b, os.WriteFile(path, content, 0644))
+
+	for _, opt := range []HashOption{
+		{},
+		{Routines: 1},
+		{BufferSize: 1024 * 1024, Routines: 1},
+		{BufferSize: 4 * 1024 * 1024, Readahead: 4},
+		{BufferSize: 16 * 1024 * 1024, Readahead: 2},
+		{BufferSize: 4 * 1024 * 1024},
+	} {
+		b.Run(fmt.Sprintf("buffer=%v,readahead=%v,routines=%v", opt.bufferSize(), opt.Readahead, opt.Routines), func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			for i := 0; i < b.N; i++ {
+				_, err := MerkleRoot(path, opt)
+				assert.NoError(b, err)
+			}
+		})
+	}
+}
+
+func BenchmarkMerkleTreeWorkers(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "large")
+	content := make([]byte, 16*1024*1024)
+	_, err := rand.Read(content)
+	assert.NoError(b, err)
+	assert.NoError(b, os.WriteFile(path, content, 0644))
+
+	file, err := Open(path)
+	assert.NoError(b, err)
+	defer file.Close()
+	readerAt, err := NewDataFromReaderAt(bytes.NewReader(content), int64(len(content)))
+	assert.NoError(b, err)
+
+	for _, source := range []struct {
+		name string
+		data IterableData
+	}{{"file", file}, {"readerAt", readerAt}} {
+		for _, opt := range []HashOption{
+			{Routines: 1},
+			{},
+			{BufferSize: 4 * 1024 * 1024, Readahead: 2, Routines: 1},
+			{BufferSize: 4 * 1024 * 1024, Readahead: 2},
+		} {
+			b.Run(fmt.Sprintf("%v,readahead=%v,routines=%v", source.name, opt.Readahead, opt.Routines), func(b *testing.B) {
+				b.SetBytes(int64(len(content)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					_, err := MerkleTree(source.data, opt)
+					assert.NoError(b, err)
+				}
+			})
+		}
+	}
+}
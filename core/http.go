@@ -0,0 +1,461 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultHTTPRetries is the default max number of retries of a failed HTTP request.
+	DefaultHTTPRetries = 3
+
+	// DefaultHTTPRetryInterval is the default interval to retry a failed HTTP request.
+	DefaultHTTPRetryInterval = time.Second
+)
+
+var (
+	// ErrSourceModified is returned when the HTTP source changed since opened, e.g. ETag or size changed, so
+	// that the data read before does not belong to the same content.
+	ErrSourceModified = errors.New("http source modified")
+
+	// ErrRangeNotSupported is returned when the HTTP server does not support range requests.
+	ErrRangeNotSupported = errors.New("http range requests not supported")
+)
+
+// HTTPOption is the option to read data from HTTP source.
+type HTTPOption struct {
+	Client        *http.Client  // HTTP client to send requests, http.DefaultClient by default
+	Header        http.Header   // additional headers of requests, e.g. authorization
+	Size          int64         // size of data, retrieved by a HEAD request if 0
+	MaxRetries    int           // max number of retries of a failed request, DefaultHTTPRetries if 0
+	RetryInterval time.Duration // interval to retry a failed request, DefaultHTTPRetryInterval if 0
+	Prefetch      int           // number of segments prefetched in background ahead of reading, 0 to disable
+}
+
+// Validate checks the option, and returns an error that names the invalid field if any.
+func (opt HTTPOption) Validate() error {
+	return zg_common.FirstError(
+		zg_common.RequireNonNegative("Size", opt.Size),
+		zg_common.RequireNonNegative("MaxRetries", opt.MaxRetries),
+		zg_common.RequireNonNegative("RetryInterval", opt.RetryInterval),
+		zg_common.RequireNonNegative("Prefetch", opt.Prefetch),
+	)
+}
+
+// httpStatusError is returned when the HTTP server responds an unexpected status code.
+type httpStatusError struct {
+	code int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected http status %v", e.code)
+}
+
+// retriable returns whether the failed request should be retried, i.e. network errors, server errors and
+// throttled requests.
+func retriable(err error) bool {
+	if errors.Is(err, ErrSourceModified) || errors.Is(err, ErrRangeNotSupported) {
+		return false
+	}
+
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= 500 || statusErr.code == http.StatusRequestTimeout || statusErr.code == http.StatusTooManyRequests
+	}
+
+	return true
+}
+
+// httpSegment is a segment fetched or being fetched from HTTP source.
+type httpSegment struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// httpSource reads data from HTTP URL by ranged requests, which is shared by fragments.
+type httpSource struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	url    string
+	opt    HTTPOption
+	size   int64
+
+	mu           sync.Mutex
+	etag         string                 // ETag of the content when opened, empty if not provided by server
+	lastModified string                 // Last-Modified of the content when opened, empty if not provided by server
+	prefetched   map[int64]*httpSegment // segment index -> segment prefetched in background
+}
+
+// HTTPData implements IterableData, the underlying is an HTTP URL that supports range requests, so that data
+// could be uploaded without being spooled on local disk.
+//
+// Data is read by segment with ranged GET requests, which are retried upon network errors or server errors. To
+// detect the source changed during upload, requests are validated against the ETag or Last-Modified of the
+// content when opened via If-Range, and ErrSourceModified is returned if changed.
+type HTTPData struct {
+	source     *httpSource
+	offset     int64
+	size       int64
+	paddedSize uint64
+}
+
+var _ IterableData = (*HTTPData)(nil)
+
+// OpenURL creates HTTPData from the specified HTTP URL, of which the size is retrieved by a HEAD request unless
+// specified in option. Note, the context applies to all requests to read data, including those in background.
+func OpenURL(ctx context.Context, url string, opt HTTPOption) (*HTTPData, error) {
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+
+	if opt.Client == nil {
+		opt.Client = http.DefaultClient
+	}
+
+	if opt.MaxRetries == 0 {
+		opt.MaxRetries = DefaultHTTPRetries
+	}
+
+	if opt.RetryInterval == 0 {
+		opt.RetryInterval = DefaultHTTPRetryInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	source := httpSource{
+		ctx:        ctx,
+		cancel:     cancel,
+		url:        url,
+		opt:        opt,
+		size:       opt.Size,
+		prefetched: make(map[int64]*httpSegment),
+	}
+
+	if source.size == 0 {
+		err := source.retry(func() error { return source.head() })
+		if err != nil {
+			cancel()
+			return nil, errors.WithMessage(err, "failed to retrieve size of http source")
+		}
+	}
+
+	if source.size == 0 {
+		cancel()
+		return nil, ErrFileEmpty
+	}
+
+	return &HTTPData{
+		source:     &source,
+		size:       source.size,
+		paddedSize: IteratorPaddedSize(source.size, true),
+	}, nil
+}
+
+// Close cancels the requests in background, which is shared by fragments.
+func (data *HTTPData) Close() error {
+	data.source.cancel()
+	return nil
+}
+
+func (data *HTTPData) Read(buf []byte, offset int64) (int, error) {
+	if offset < 0 || offset >= data.size {
+		return 0, nil
+	}
+
+	start := data.offset + offset
+	end := data.offset + min(offset+int64(len(buf)), data.size)
+
+	var n int
+	for pos := start; pos < end; {
+		index := pos / DefaultSegmentSize
+		segment, err := data.source.segment(index)
+		if err != nil {
+			return 0, err
+		}
+
+		segStart := index * DefaultSegmentSize
+		copied := copy(buf[n:end-start], segment[pos-segStart:])
+		n += copied
+		pos += int64(copied)
+	}
+
+	return n, nil
+}
+
+func (data *HTTPData) NumChunks() uint64 {
+	return NumSplits(data.size, DefaultChunkSize)
+}
+
+func (data *HTTPData) NumSegments() uint64 {
+	return NumSplits(data.size, DefaultSegmentSize)
+}
+
+func (data *HTTPData) Size() int64 {
+	return data.size
+}
+
+func (data *HTTPData) Offset() int64 {
+	return data.offset
+}
+
+func (data *HTTPData) PaddedSize() uint64 {
+	return data.paddedSize
+}
+
+func (data *HTTPData) Split(fragmentSize int64) []IterableData {
+	fragments := make([]IterableData, 0)
+	for offset := data.offset; offset < data.offset+data.size; offset += fragmentSize {
+		size := min(data.size-offset, fragmentSize)
+		fragment := &HTTPData{
+			source:     data.source,
+			offset:     offset,
+			size:       size,
+			paddedSize: IteratorPaddedSize(size, true),
+		}
+		fragments = append(fragments, fragment)
+	}
+	return fragments
+}
+
+// segment returns the data of segment at the specified index of source, which is prefetched if available, and
+// then prefetches the following segments in background.
+func (source *httpSource) segment(index int64) ([]byte, error) {
+	source.mu.Lock()
+	segment, ok := source.prefetched[index]
+	delete(source.prefetched, index)
+	source.prefetchLocked(index)
+	source.mu.Unlock()
+
+	if !ok {
+		segment = source.fetch(index)
+	}
+
+	select {
+	case <-segment.done:
+		return segment.data, segment.err
+	case <-source.ctx.Done():
+		return nil, source.ctx.Err()
+	}
+}
+
+// prefetchLocked prefetches segments following the specified index in background, and discards prefetched
+// segments out of window, e.g. not read due to upload failed.
+func (source *httpSource) prefetchLocked(index int64) {
+	if source.opt.Prefetch == 0 {
+		return
+	}
+
+	for i := range source.prefetched {
+		if i < index-int64(source.opt.Prefetch) || i > index+int64(source.opt.Prefetch) {
+			delete(source.prefetched, i)
+		}
+	}
+
+	numSegments := NumSplits(source.size, DefaultSegmentSize)
+	for i := index + 1; i <= index+int64(source.opt.Prefetch) && uint64(i) < numSegments; i++ {
+		if _, ok := source.prefetched[i]; !ok {
+			source.prefetched[i] = source.fetch(i)
+		}
+	}
+}
+
+// fetch fetches the segment at the specified index in background.
+func (source *httpSource) fetch(index int64) *httpSegment {
+	segment := httpSegment{done: make(chan struct{})}
+
+	go func() {
+		defer close(segment.done)
+
+		start := index * DefaultSegmentSize
+		end := min(start+DefaultSegmentSize, source.size)
+
+		segment.err = source.retry(func() (err error) {
+			segment.data, err = source.get(start, end)
+			return err
+		})
+		if segment.err != nil {
+			segment.err = errors.WithMessagef(segment.err, "failed to read http source, range = [%v, %v)", start, end)
+		}
+	}()
+
+	return &segment
+}
+
+// retry calls the function until succeeded, or the error is not retriable, or max retries reached.
+func (source *httpSource) retry(f func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= source.opt.MaxRetries || !retriable(err) || source.ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(source.opt.RetryInterval):
+		case <-source.ctx.Done():
+			return source.ctx.Err()
+		}
+	}
+}
+
+func (source *httpSource) newRequest(method string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(source.ctx, method, source.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", zg_common.UserAgent())
+	for key, values := range source.opt.Header {
+		req.Header[key] = values
+	}
+
+	return req, nil
+}
+
+// head retrieves the size and validators of content.
+func (source *httpSource) head() error {
+	req, err := source.newRequest(http.MethodHead)
+	if err != nil {
+		return err
+	}
+
+	resp, err := source.opt.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &httpStatusError{resp.StatusCode}
+	}
+
+	if resp.ContentLength < 0 {
+		return errors.New("content length not provided")
+	}
+
+	source.size = resp.ContentLength
+
+	return source.validate(resp.Header)
+}
+
+// validate checks the validators of response against those when opened, which are pinned from the first
+// response if not yet. Validators not provided in either response are not compared.
+func (source *httpSource) validate(header http.Header) error {
+	etag, lastModified := header.Get("ETag"), header.Get("Last-Modified")
+
+	source.mu.Lock()
+	defer source.mu.Unlock()
+
+	if len(source.etag) == 0 && len(source.lastModified) == 0 {
+		source.etag, source.lastModified = etag, lastModified
+		return nil
+	}
+
+	if changed(etag, source.etag) || changed(lastModified, source.lastModified) {
+		return errors.WithMessagef(ErrSourceModified, "etag = %q, lastModified = %q, expected etag = %q, lastModified = %q",
+			etag, lastModified, source.etag, source.lastModified)
+	}
+
+	return nil
+}
+
+func changed(value, expected string) bool {
+	return len(value) > 0 && len(expected) > 0 && value != expected
+}
+
+// ifRange returns the validator for If-Range header, which must be a strong ETag or Last-Modified.
+func (source *httpSource) ifRange() string {
+	source.mu.Lock()
+	defer source.mu.Unlock()
+
+	if len(source.etag) > 0 && !strings.HasPrefix(source.etag, "W/") {
+		return source.etag
+	}
+
+	return source.lastModified
+}
+
+// get reads the data in range [start, end) of content.
+func (source *httpSource) get(start, end int64) ([]byte, error) {
+	req, err := source.newRequest(http.MethodGet)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=%v-%v", start, end-1))
+
+	ifRange := source.ifRange()
+	if len(ifRange) > 0 {
+		req.Header.Set("If-Range", ifRange)
+	}
+
+	resp, err := source.opt.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// full content responded if validator of If-Range mismatched
+		if len(ifRange) > 0 {
+			return nil, errors.WithMessagef(ErrSourceModified, "If-Range %v mismatched", ifRange)
+		}
+
+		return nil, ErrRangeNotSupported
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, errors.WithMessage(ErrSourceModified, "range not satisfiable")
+	default:
+		return nil, &httpStatusError{resp.StatusCode}
+	}
+
+	if err := source.validate(resp.Header); err != nil {
+		return nil, err
+	}
+
+	total, err := parseContentRange(resp.Header.Get("Content-Range"), start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if total >= 0 && total != source.size {
+		return nil, errors.WithMessagef(ErrSourceModified, "size = %v, expected = %v", total, source.size)
+	}
+
+	data := make([]byte, end-start)
+	if _, err := io.ReadFull(resp.Body, data); err != nil {
+		return nil, errors.WithMessage(err, "failed to read response body")
+	}
+
+	return data, nil
+}
+
+// parseContentRange parses the Content-Range header in format "bytes start-end/total", and returns the total
+// size, or -1 if unknown.
+func parseContentRange(value string, start, end int64) (int64, error) {
+	expected := fmt.Sprintf("bytes %v-%v/", start, end-1)
+	if !strings.HasPrefix(value, expected) {
+		return 0, errors.Errorf("unexpected Content-Range %q, expected %q", value, expected+"<total>")
+	}
+
+	total := value[len(expected):]
+	if total == "*" {
+		return -1, nil
+	}
+
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid size of Content-Range %q", value)
+	}
+
+	return size, nil
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtureServer serves the fixture content with ETag, and supports range requests.
+type fixtureServer struct {
+	content  atomic.Pointer[[]byte]
+	etag     atomic.Pointer[string]
+	heads    atomic.Int32
+	gets     atomic.Int32
+	failures atomic.Int32 // number of requests to fail with 503
+}
+
+func newFixtureServer(t *testing.T, content []byte) (*fixtureServer, *httptest.Server) {
+	fixture := fixtureServer{}
+	fixture.update(content, `"v1"`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			fixture.heads.Add(1)
+		} else {
+			fixture.gets.Add(1)
+		}
+
+		if fixture.failures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("ETag", *fixture.etag.Load())
+		http.ServeContent(w, r, "fixture", time.Time{}, bytes.NewReader(*fixture.content.Load()))
+	}))
+	t.Cleanup(server.Close)
+
+	return &fixture, server
+}
+
+func (fixture *fixtureServer) update(content []byte, etag string) {
+	fixture.content.Store(&content)
+	fixture.etag.Store(&etag)
+}
+
+func newFixtureFile(t *testing.T, size int) ([]byte, string) {
+	content := make([]byte, size)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "fixture")
+	assert.NoError(t, os.WriteFile(filename, content, 0644))
+
+	return content, filename
+}
+
+func TestHTTPData(t *testing.T) {
+	content, filename := newFixtureFile(t, DefaultSegmentSize*5+DefaultChunkSize*3+7)
+	fixture, server := newFixtureServer(t, content)
+
+	expected, err := MerkleRoot(filename)
+	assert.NoError(t, err)
+
+	for _, opt := range []HashOption{{}, {BufferSize: DefaultSegmentSize * 2, Readahead: 2}} {
+		for _, prefetch := range []int{0, 2} {
+			data, err := OpenURL(context.Background(), server.URL, HTTPOption{Prefetch: prefetch})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(content)), data.Size())
+
+			tree, err := MerkleTree(data, opt)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, tree.Root())
+			assert.NoError(t, data.Close())
+		}
+	}
+
+	// fragments read the same bytes as on disk
+	file, err := Open(filename)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	data, err := OpenURL(context.Background(), server.URL, HTTPOption{Size: int64(len(content))})
+	assert.NoError(t, err)
+	fragments, fileFragments := data.Split(DefaultSegmentSize*2), file.Split(DefaultSegmentSize*2)
+	assert.Equal(t, len(fileFragments), len(fragments))
+	for i := range fragments {
+		tree, err := MerkleTree(fragments[i])
+		assert.NoError(t, err)
+		fileTree, err := MerkleTree(fileFragments[i])
+		assert.NoError(t, err)
+		assert.Equal(t, fileTree.Root(), tree.Root())
+	}
+
+	// no HEAD request if size specified
+	assert.Equal(t, int32(4), fixture.heads.Load())
+}
+
+func TestHTTPDataModified(t *testing.T) {
+	content, _ := newFixtureFile(t, DefaultSegmentSize*3)
+	fixture, server := newFixtureServer(t, content)
+
+	data, err := OpenURL(context.Background(), server.URL, HTTPOption{})
+	assert.NoError(t, err)
+
+	buf := make([]byte, DefaultSegmentSize)
+	n, err := data.Read(buf, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultSegmentSize, n)
+	assert.Equal(t, content[:DefaultSegmentSize], buf)
+
+	// content changed in place with a new ETag
+	changed := append([]byte(nil), content...)
+	changed[DefaultSegmentSize] ^= 0xff
+	fixture.update(changed, `"v2"`)
+
+	_, err = data.Read(buf, DefaultSegmentSize)
+	assert.ErrorIs(t, err, ErrSourceModified)
+
+	// size changed while the size specified
+	data, err = OpenURL(context.Background(), server.URL, HTTPOption{Size: int64(len(content)) + 1})
+	assert.NoError(t, err)
+	_, err = data.Read(buf, 0)
+	assert.ErrorIs(t, err, ErrSourceModified)
+}
+
+func TestHTTPDataRetry(t *testing.T) {
+	content, _ := newFixtureFile(t, DefaultSegmentSize)
+	fixture, server := newFixtureServer(t, content)
+
+	fixture.failures.Store(2)
+	data, err := OpenURL(context.Background(), server.URL, HTTPOption{RetryInterval: time.Millisecond})
+	assert.NoError(t, err)
+
+	fixture.failures.Store(2)
+	buf := make([]byte, DefaultSegmentSize)
+	_, err = data.Read(buf, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, content, buf)
+	assert.Equal(t, int32(3), fixture.gets.Load())
+
+	// too many failures
+	fixture.failures.Store(3)
+	data, err = OpenURL(context.Background(), server.URL, HTTPOption{Size: int64(len(content)), MaxRetries: 2, RetryInterval: time.Millisecond})
+	assert.NoError(t, err)
+	_, err = data.Read(buf, 0)
+	assert.Error(t, err)
+
+	_, err = OpenURL(context.Background(), server.URL, HTTPOption{Prefetch: -1})
+	assert.Error(t, err)
+}
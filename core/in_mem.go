@@ -1,7 +1,5 @@
 package core
 
-import "errors"
-
 // DataInMemory implement of IterableData, the underlying is memory data
 type DataInMemory struct {
 	underlying []byte
@@ -12,10 +10,11 @@ type DataInMemory struct {
 
 var _ IterableData = (*DataInMemory)(nil)
 
-// NewDataInMemory creates DataInMemory from given data
+// NewDataInMemory creates DataInMemory from given data, which is not copied and should not be modified during
+// upload. ErrFileEmpty is returned if data is empty.
 func NewDataInMemory(data []byte) (*DataInMemory, error) {
 	if len(data) == 0 {
-		return nil, errors.New("data is empty")
+		return nil, ErrFileEmpty
 	}
 	return &DataInMemory{
 		underlying: data,
@@ -26,16 +25,21 @@ func NewDataInMemory(data []byte) (*DataInMemory, error) {
 }
 
 func (data *DataInMemory) Read(buf []byte, offset int64) (int, error) {
-	n := copy(buf, data.underlying[data.offset+offset:])
+	if offset < 0 || offset >= data.size {
+		return 0, nil
+	}
+
+	// never read beyond the fragment
+	n := copy(buf, data.underlying[data.offset+offset:data.offset+data.size])
 	return n, nil
 }
 
 func (data *DataInMemory) NumChunks() uint64 {
-	return NumSplits(int64(len(data.underlying)), DefaultChunkSize)
+	return NumSplits(data.size, DefaultChunkSize)
 }
 
 func (data *DataInMemory) NumSegments() uint64 {
-	return NumSplits(int64(len(data.underlying)), DefaultSegmentSize)
+	return NumSplits(data.size, DefaultSegmentSize)
 }
 
 func (data *DataInMemory) Size() int64 {
@@ -53,7 +57,7 @@ func (data *DataInMemory) PaddedSize() uint64 {
 func (data *DataInMemory) Split(fragmentSize int64) []IterableData {
 	fragments := make([]IterableData, 0)
 	for offset := data.offset; offset < data.offset+data.size; offset += fragmentSize {
-		size := min(data.size-offset, fragmentSize)
+		size := min(data.offset+data.size-offset, fragmentSize)
 		fragment := &DataInMemory{
 			underlying: data.underlying,
 			offset:     offset,
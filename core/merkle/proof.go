@@ -62,8 +62,8 @@ func (proof *Proof) ValidateHash(root, contentHash common.Hash, position, numLea
 		return errProofContentMismatch
 	}
 
-	// root mismatch
-	if len(proof.Lemma) > 1 && root.Hex() != proof.Lemma[len(proof.Lemma)-1].Hex() {
+	// root mismatch, including the proof of single leaf node, of which the content hash is the root
+	if root.Hex() != proof.Lemma[len(proof.Lemma)-1].Hex() {
 		return errProofRootMismatch
 	}
 
@@ -0,0 +1,101 @@
+package merkle
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// RootBuilder calculates the root of the merkle tree built by TreeBuilder of the same leaf nodes, without retaining
+// the leaf nodes, so that memory is logarithmic to the number of leaf nodes.
+type RootBuilder struct {
+	// pending nodes at each level of the complete subtrees, where the node at level i covers 2^i leaf nodes
+	pending []*common.Hash
+	count   uint64
+}
+
+func (builder *RootBuilder) Append(content []byte) {
+	builder.AppendHash(crypto.Keccak256Hash(content))
+}
+
+func (builder *RootBuilder) AppendHash(hash common.Hash) {
+	builder.count++
+	builder.appendAt(0, hash)
+}
+
+// AppendRepeated appends count leaf nodes of the same hash, e.g. empty chunks padded, where roots[i] is the root of
+// 2^i such leaf nodes, see RepeatedRoots. Complete subtrees aligned to the leaf nodes appended are appended at once,
+// so that the work is logarithmic instead of linear to count.
+func (builder *RootBuilder) AppendRepeated(roots []common.Hash, count uint64) {
+	for count > 0 {
+		level := 0
+		for next := uint64(1) << (level + 1); level+1 < len(roots) && count >= next && builder.count%next == 0; next <<= 1 {
+			level++
+		}
+
+		builder.appendAt(level, roots[level])
+		builder.count += 1 << level
+		count -= 1 << level
+	}
+}
+
+// appendAt appends the root of a complete subtree at the specified level, which requires that no pending nodes at
+// lower levels, i.e. the subtree is aligned to the leaf nodes appended.
+func (builder *RootBuilder) appendAt(level int, hash common.Hash) {
+	for len(builder.pending) < level {
+		builder.pending = append(builder.pending, nil)
+	}
+
+	// merge with the pending left sibling at each level, as carried in binary addition
+	for ; ; level++ {
+		if level == len(builder.pending) {
+			builder.pending = append(builder.pending, nil)
+		}
+
+		left := builder.pending[level]
+		if left == nil {
+			builder.pending[level] = &hash
+			return
+		}
+
+		builder.pending[level] = nil
+		hash = crypto.Keccak256Hash(left.Bytes(), hash.Bytes())
+	}
+}
+
+// Count returns the number of leaf nodes appended.
+func (builder *RootBuilder) Count() uint64 {
+	return builder.count
+}
+
+// Root returns the merkle root, which is zero if no leaf node appended. As TreeBuilder does, the last single node
+// at each level is promoted to the next level, and paired with the pending node there if any.
+func (builder *RootBuilder) Root() common.Hash {
+	var carry *common.Hash
+	for _, pending := range builder.pending {
+		switch {
+		case pending == nil:
+		case carry == nil:
+			carry = pending
+		default:
+			hash := crypto.Keccak256Hash(pending.Bytes(), carry.Bytes())
+			carry = &hash
+		}
+	}
+
+	if carry == nil {
+		return common.Hash{}
+	}
+
+	return *carry
+}
+
+// RepeatedRoots returns the roots of complete subtrees of the same leaf node at each level up to the specified
+// height, i.e. the i-th hash is the root of 2^i leaf nodes.
+func RepeatedRoots(leaf common.Hash, height int) []common.Hash {
+	roots := []common.Hash{leaf}
+	for i := 1; i <= height; i++ {
+		roots = append(roots, crypto.Keccak256Hash(roots[i-1].Bytes(), roots[i-1].Bytes()))
+	}
+
+	return roots
+}
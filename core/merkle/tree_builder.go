@@ -1,14 +1,27 @@
 package merkle
 
 import (
-	"container/list"
+	"runtime"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 )
 
+// minNodesPerWorker is the min number of interior nodes hashed by a worker, so that small levels, e.g. of a
+// segment, are hashed without the overhead of goroutines.
+const minNodesPerWorker = 1024
+
 // TreeBuilder is used to build complete binary merkle tree.
 type TreeBuilder struct {
-	leafNodes []*node
+	leafNodes  []*node
+	maxWorkers int
+}
+
+// WithMaxWorkers sets the max number of goroutines to hash interior nodes of each level in parallel, which is
+// runtime.GOMAXPROCS(0) by default, and 1 to build serially. The tree is the same regardless of the workers.
+func (builder *TreeBuilder) WithMaxWorkers(workers int) *TreeBuilder {
+	builder.maxWorkers = workers
+	return builder
 }
 
 func (builder *TreeBuilder) Append(content []byte) {
@@ -27,43 +40,61 @@ func (builder *TreeBuilder) Build() *Tree {
 		return nil
 	}
 
-	queue := list.New()
+	// hash level by level, where only the nodes of current level are referenced besides the tree itself
+	nodes := builder.leafNodes
+	for len(nodes) > 1 {
+		parents := make([]*node, (len(nodes)+1)/2)
+		builder.hashLevel(nodes, parents)
 
-	for i := 0; i < numLeafNodes; i += 2 {
-		// last single leaf node
-		if i == numLeafNodes-1 {
-			queue.PushBack(builder.leafNodes[i])
-			continue
+		// last single node promoted to the next level
+		if len(nodes)%2 > 0 {
+			parents[len(parents)-1] = nodes[len(nodes)-1]
 		}
 
-		left, right := builder.leafNodes[i], builder.leafNodes[i+1]
+		nodes = parents
+	}
 
-		node := newInteriorNode(left, right)
-		queue.PushBack(node)
+	return &Tree{
+		root:      nodes[0],
+		leafNodes: builder.leafNodes,
 	}
+}
 
-	for {
-		numNodes := queue.Len()
-		if numNodes <= 1 {
-			break
-		}
+// workers returns the number of workers to hash the specified number of interior nodes.
+func (builder *TreeBuilder) workers(numNodes int) int {
+	workers := builder.maxWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
 
-		for i := 0; i < numNodes/2; i++ {
-			left := queue.Remove(queue.Front()).(*node)
-			right := queue.Remove(queue.Front()).(*node)
+	return max(1, min(workers, numNodes/minNodesPerWorker))
+}
 
-			node := newInteriorNode(left, right)
-			queue.PushBack(node)
-		}
+// hashLevel hashes pairs of nodes into parents, where each worker hashes a contiguous range of pairs, so that
+// parent pointers of children are never written concurrently.
+func (builder *TreeBuilder) hashLevel(nodes, parents []*node) {
+	numPairs := len(nodes) / 2
+	workers := builder.workers(numPairs)
 
-		// last single node
-		if numNodes%2 > 0 {
-			queue.MoveToBack(queue.Front())
+	hashRange := func(start, end int) {
+		for i := start; i < end; i++ {
+			parents[i] = newInteriorNode(nodes[2*i], nodes[2*i+1])
 		}
 	}
 
-	return &Tree{
-		root:      queue.Front().Value.(*node),
-		leafNodes: builder.leafNodes,
+	if workers == 1 {
+		hashRange(0, numPairs)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			hashRange(start, end)
+		}(numPairs*w/workers, numPairs*(w+1)/workers)
 	}
+
+	wg.Wait()
 }
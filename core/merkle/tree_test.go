@@ -29,6 +29,21 @@ func TestTreeRoot(t *testing.T) {
 	assert.Equal(t, "0xca80116fb7fb8d6ef4a47e322f22e94ae8beb03e6fcbf8ab59c4d6f54fe42c4d", createTreeByChunks(7).Root().Hex())
 }
 
+func TestRootBuilder(t *testing.T) {
+	var empty RootBuilder
+	assert.Equal(t, common.Hash{}, empty.Root())
+
+	for numChunks := 1; numChunks <= 100; numChunks++ {
+		var builder RootBuilder
+		for i := 0; i < numChunks; i++ {
+			builder.Append(createChunkData(i))
+		}
+
+		assert.Equal(t, createTreeByChunks(numChunks).Root(), builder.Root(), "chunks = %v", numChunks)
+		assert.Equal(t, uint64(numChunks), builder.Count())
+	}
+}
+
 func TestTreeProof(t *testing.T) {
 	for numChunks := 1; numChunks <= 32; numChunks++ {
 		tree := createTreeByChunks(numChunks)
@@ -75,3 +90,74 @@ func TestRootBySegment(t *testing.T) {
 		assert.Equal(t, root2, root3)
 	}
 }
+
+func TestTreeBuilderWorkers(t *testing.T) {
+	for _, numChunks := range []int{1, 2, 3, 4*minNodesPerWorker - 1, 4 * minNodesPerWorker, 9*minNodesPerWorker + 1} {
+		serial := new(TreeBuilder).WithMaxWorkers(1)
+		parallel := new(TreeBuilder).WithMaxWorkers(4)
+		var rootBuilder RootBuilder
+		for i := 0; i < numChunks; i++ {
+			serial.Append(createChunkData(i))
+			parallel.Append(createChunkData(i))
+			rootBuilder.Append(createChunkData(i))
+		}
+
+		expected, actual := serial.Build(), parallel.Build()
+		assert.Equal(t, expected.Root(), actual.Root(), "chunks = %v", numChunks)
+		assert.Equal(t, rootBuilder.Root(), actual.Root(), "chunks = %v", numChunks)
+
+		for _, i := range []int{0, numChunks / 2, numChunks - 1} {
+			assert.Equal(t, expected.ProofAt(i), actual.ProofAt(i), "chunks = %v, index = %v", numChunks, i)
+		}
+	}
+}
+
+func BenchmarkTreeBuild(b *testing.B) {
+	// leaf hashes of a 64 GB file, or of the chunks of a 256 MB file
+	var leaves []common.Hash
+	for i := 0; i < 1<<20; i++ {
+		leaves = append(leaves, common.BytesToHash(createChunkData(i)))
+	}
+
+	for _, workers := range []int{1, 0} {
+		b.Run(fmt.Sprintf("workers=%v", workers), func(b *testing.B) {
+			builder := new(TreeBuilder).WithMaxWorkers(workers)
+			for _, leaf := range leaves {
+				builder.AppendHash(leaf)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				builder.Build()
+			}
+		})
+	}
+}
+
+func TestRootBuilderAppendRepeated(t *testing.T) {
+	leaf := common.BytesToHash(createChunkData(0))
+	roots := RepeatedRoots(leaf, 4)
+	assert.Len(t, roots, 5)
+
+	for prefix := 0; prefix <= 9; prefix++ {
+		for count := 0; count <= 40; count++ {
+			var expected TreeBuilder
+			var builder RootBuilder
+			for i := 0; i < prefix; i++ {
+				expected.Append(createChunkData(i + 1))
+				builder.Append(createChunkData(i + 1))
+			}
+
+			for i := 0; i < count; i++ {
+				expected.AppendHash(leaf)
+			}
+			builder.AppendRepeated(roots, uint64(count))
+
+			assert.Equal(t, uint64(prefix+count), builder.Count())
+			if tree := expected.Build(); tree != nil {
+				assert.Equal(t, tree.Root(), builder.Root(), "prefix = %v, count = %v", prefix, count)
+			}
+		}
+	}
+}
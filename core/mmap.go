@@ -0,0 +1,164 @@
+package core
+
+import (
+	"math"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// ErrFileChanged is returned when reading memory-mapped file that shrinks after opened, since the data hashed or
+// uploaded so far is inconsistent.
+var ErrFileChanged = errors.New("file changed after opened")
+
+// FileData is IterableData backed by a file on disk, which holds the file until closed.
+type FileData interface {
+	IterableData
+	Close() error
+}
+
+var (
+	_ FileData = (*File)(nil)
+	_ FileData = (*MmapFile)(nil)
+)
+
+// mmapRegion is the whole file mapped into memory, which is shared by fragments and released once.
+type mmapRegion struct {
+	file   *os.File
+	data   []byte
+	closed atomic.Bool
+	once   sync.Once
+	err    error
+}
+
+func (region *mmapRegion) close() error {
+	region.once.Do(func() {
+		region.closed.Store(true)
+		region.err = munmap(region.data)
+		if err := region.file.Close(); region.err == nil {
+			region.err = err
+		}
+	})
+
+	return region.err
+}
+
+// validate checks the mapping not released, which is cheap enough to check on every read. Note, the size of file
+// is checked once opened rather than on every read, since data appended is not mapped and never read, and
+// accessing pages beyond the end of a shrunk file faults, which is recovered as ErrFileChanged.
+func (region *mmapRegion) validate() error {
+	if region.closed.Load() {
+		return os.ErrClosed
+	}
+
+	return nil
+}
+
+// MmapFile implement of IterableData, the underlying is a file on disk mapped into memory, so that data is copied
+// from page cache directly without a syscall per read, which is much faster to hash and upload huge files.
+type MmapFile struct {
+	os.FileInfo
+	region     *mmapRegion
+	offset     int64
+	size       int64
+	paddedSize uint64
+}
+
+// NewDataInFileMmap opens the file on disk and maps it into memory, see MmapFile. Falls back to File transparently
+// if mmap not supported, e.g. on Windows, or failed. Either way, the returned data should be closed once done.
+func NewDataInFileMmap(path string) (FileData, error) {
+	file, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// too large to map into address space, e.g. on 32-bit platforms
+	if file.size > math.MaxInt {
+		return file, nil
+	}
+
+	data, err := mmap(file.underlying, int(file.size))
+	if err != nil {
+		return file, nil
+	}
+
+	return &MmapFile{
+		FileInfo:   file.FileInfo,
+		region:     &mmapRegion{file: file.underlying, data: data},
+		size:       file.size,
+		paddedSize: file.paddedSize,
+	}, nil
+}
+
+// Read reads data at offset, and never reads beyond the fragment. ErrFileChanged is returned if the file shrinks
+// after opened, while data appended after opened is ignored as File does.
+func (file *MmapFile) Read(buf []byte, offset int64) (n int, err error) {
+	if offset < 0 || offset >= file.size {
+		return 0, nil
+	}
+
+	if err = file.region.validate(); err != nil {
+		return 0, err
+	}
+
+	// file may be truncated after opened, and the fault is recovered as an error. Note, the setting is per goroutine,
+	// and restored only if changed, since reads are usually from a few goroutines that read repeatedly.
+	if !debug.SetPanicOnFault(true) {
+		defer debug.SetPanicOnFault(false)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(interface{ Addr() uintptr }); !ok {
+				panic(r)
+			}
+
+			n, err = 0, errors.WithMessagef(ErrFileChanged, "fault to read at offset %v", file.offset+offset)
+		}
+	}()
+
+	return copy(buf, file.region.data[file.offset+offset:file.offset+file.size]), nil
+}
+
+// Close releases the mapping and closes the file, which is shared by fragments.
+func (file *MmapFile) Close() error {
+	return file.region.close()
+}
+
+func (file *MmapFile) NumChunks() uint64 {
+	return NumSplits(file.size, DefaultChunkSize)
+}
+
+func (file *MmapFile) NumSegments() uint64 {
+	return NumSplits(file.size, DefaultSegmentSize)
+}
+
+func (file *MmapFile) PaddedSize() uint64 {
+	return file.paddedSize
+}
+
+func (file *MmapFile) Size() int64 {
+	return file.size
+}
+
+func (file *MmapFile) Offset() int64 {
+	return file.offset
+}
+
+func (file *MmapFile) Split(fragmentSize int64) []IterableData {
+	fragments := make([]IterableData, 0)
+	for offset := file.offset; offset < file.offset+file.size; offset += fragmentSize {
+		size := min(file.offset+file.size-offset, fragmentSize)
+		fragment := &MmapFile{
+			FileInfo:   file.FileInfo,
+			region:     file.region,
+			offset:     offset,
+			size:       size,
+			paddedSize: IteratorPaddedSize(size, true),
+		}
+		fragments = append(fragments, fragment)
+	}
+	return fragments
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package core
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// mmap is not supported except on unix, and falls back to File.
+func mmap(file *os.File, size int) ([]byte, error) {
+	return nil, errors.New("mmap not supported")
+}
+
+func munmap(data []byte) error {
+	return nil
+}
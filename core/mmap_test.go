@@ -0,0 +1,118 @@
+package core
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataInFileMmap(t *testing.T) {
+	content := make([]byte, 3*DefaultSegmentSize+100)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "data")
+	assert.NoError(t, os.WriteFile(path, content, 0644))
+
+	expected, err := MerkleRoot(path)
+	assert.NoError(t, err)
+
+	data, err := NewDataInFileMmap(path)
+	assert.NoError(t, err)
+	defer data.Close()
+	if runtime.GOOS != "windows" {
+		assert.IsType(t, &MmapFile{}, data)
+	}
+
+	root, err := MerkleRootData(data)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, root)
+
+	// never read beyond the fragment
+	fragments := data.Split(DefaultSegmentSize * 2)
+	assert.Equal(t, 2, len(fragments))
+	buf := make([]byte, 200)
+	n, err := fragments[0].Read(buf, 2*DefaultSegmentSize-100)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, content[2*DefaultSegmentSize-100:2*DefaultSegmentSize], buf[:n])
+	n, err = fragments[1].Read(buf, DefaultSegmentSize)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, content[3*DefaultSegmentSize:], buf[:n])
+
+	_, err = NewDataInFileMmap(filepath.Dir(path))
+	assert.ErrorIs(t, err, ErrFileRequired)
+}
+
+func TestDataInFileMmapChanged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mmap not supported")
+	}
+
+	path := filepath.Join(t.TempDir(), "data")
+	assert.NoError(t, os.WriteFile(path, make([]byte, 2*DefaultSegmentSize), 0644))
+
+	data, err := NewDataInFileMmap(path)
+	assert.NoError(t, err)
+	buf := make([]byte, DefaultSegmentSize)
+
+	// shrunk
+	assert.NoError(t, os.Truncate(path, DefaultSegmentSize))
+	_, err = data.Read(buf, DefaultSegmentSize)
+	assert.ErrorIs(t, err, ErrFileChanged)
+
+	// grown, and data appended is ignored
+	assert.NoError(t, os.Truncate(path, 3*DefaultSegmentSize))
+	n, err := data.Read(buf, DefaultSegmentSize)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultSegmentSize, n)
+	n, err = data.Read(buf, 2*DefaultSegmentSize)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// closed once, which is shared by fragments
+	assert.NoError(t, data.Close())
+	assert.NoError(t, data.Close())
+	_, err = data.Split(DefaultSegmentSize)[1].Read(buf, 0)
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+// BenchmarkUploadSegments reads segments to upload from a multi-GB sparse file, where segments are read one by one
+// as uploaded to storage nodes, so that the overhead of syscalls and copies is measured.
+func BenchmarkUploadSegments(b *testing.B) {
+	const size = 4 << 30
+	path := filepath.Join(b.TempDir(), "sparse")
+	file, err := os.Create(path)
+	assert.NoError(b, err)
+	assert.NoError(b, file.Truncate(size))
+	assert.NoError(b, file.Close())
+
+	for _, c := range []struct {
+		name string
+		open func(string) (FileData, error)
+	}{
+		{"file", func(path string) (FileData, error) { return Open(path) }},
+		{"mmap", NewDataInFileMmap},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			data, err := c.open(path)
+			assert.NoError(b, err)
+			defer data.Close()
+
+			buf := make([]byte, DefaultSegmentSize)
+			numSegments := data.NumSegments()
+			b.SetBytes(DefaultSegmentSize)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := ReadSegment(data, uint64(i)%numSegments, buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
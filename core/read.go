@@ -0,0 +1,54 @@
+package core
+
+import "github.com/pkg/errors"
+
+// ReadChunk reads the chunk of data at the specified index, where the last chunk is padded with zeros, i.e. the
+// chunk hashed as a leaf node by merkle tree.
+//
+// If buf is specified with capacity of at least DefaultChunkSize, it is reused to read the chunk, so that reading
+// chunks one by one does not allocate. Note, the returned chunk is overwritten once buf reused.
+func ReadChunk(data IterableData, index uint64, buf ...[]byte) ([]byte, error) {
+	size := data.Size()
+	if size <= 0 {
+		return nil, errors.Errorf("invalid data size %v", size)
+	}
+
+	if numChunks := NumSplits(size, DefaultChunkSize); index >= numChunks {
+		return nil, errors.Errorf("chunk index out of bounds, index = %v, chunks = %v", index, numChunks)
+	}
+
+	return readPadded(data, int64(index)*DefaultChunkSize, DefaultChunkSize, buf)
+}
+
+// ReadSegment reads the segment of data at the specified index, which holds the data padded with zeros to a
+// multiple of DefaultChunkSize, i.e. the segment data uploaded to storage nodes, see SegmentToByteRange.
+//
+// If buf is specified with capacity of at least the segment length, it is reused as ReadChunk does.
+func ReadSegment(data IterableData, index uint64, buf ...[]byte) ([]byte, error) {
+	offset, length, err := SegmentToByteRange(index, data.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	return readPadded(data, offset, int(length), buf)
+}
+
+// readPadded reads length bytes of data at offset, where bytes beyond data are padded with zeros.
+func readPadded(data IterableData, offset int64, length int, buf [][]byte) ([]byte, error) {
+	var result []byte
+	if len(buf) > 0 && cap(buf[0]) >= length {
+		result = buf[0][:length]
+	} else {
+		result = make([]byte, length)
+	}
+
+	dataLen := int(min(int64(length), data.Size()-offset))
+	if _, err := data.Read(result[:dataLen], offset); err != nil {
+		return nil, errors.WithMessagef(err, "failed to read data at offset %v", offset)
+	}
+
+	// reused buffer may be dirty
+	clear(result[dataLen:])
+
+	return result, nil
+}
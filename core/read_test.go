@@ -0,0 +1,92 @@
+package core
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slicePadded slices the raw file content, and pads zeros up to the specified length.
+func slicePadded(content []byte, offset, length int) []byte {
+	result := make([]byte, length)
+	copy(result, content[offset:min(offset+length, len(content))])
+	return result
+}
+
+func TestReadChunkAndSegment(t *testing.T) {
+	for _, size := range []int{1, DefaultChunkSize, DefaultSegmentSize, 2*DefaultSegmentSize + 1000} {
+		content := make([]byte, size)
+		_, err := rand.Read(content)
+		assert.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "file")
+		assert.NoError(t, os.WriteFile(path, content, 0644))
+		file, err := Open(path)
+		assert.NoError(t, err)
+		defer file.Close()
+
+		// dirty buffer reused
+		buf := make([]byte, DefaultSegmentSize)
+		for i := range buf {
+			buf[i] = 0xff
+		}
+
+		numChunks := (size + DefaultChunkSize - 1) / DefaultChunkSize
+		for i := 0; i < numChunks; i++ {
+			chunk, err := ReadChunk(file, uint64(i))
+			assert.NoError(t, err)
+			assert.Equal(t, slicePadded(content, i*DefaultChunkSize, DefaultChunkSize), chunk, "size = %v, chunk = %v", size, i)
+
+			chunk, err = ReadChunk(file, uint64(i), buf)
+			assert.NoError(t, err)
+			assert.Equal(t, slicePadded(content, i*DefaultChunkSize, DefaultChunkSize), chunk, "size = %v, chunk = %v", size, i)
+			assert.Same(t, &buf[0], &chunk[0])
+		}
+
+		_, err = ReadChunk(file, uint64(numChunks))
+		assert.Error(t, err)
+
+		data, err := NewDataInMemory(content)
+		assert.NoError(t, err)
+		tree, err := NewFileTree(data)
+		assert.NoError(t, err)
+
+		numSegments := (size + DefaultSegmentSize - 1) / DefaultSegmentSize
+		for i := 0; i < numSegments; i++ {
+			length := min(DefaultSegmentSize, numChunks*DefaultChunkSize-i*DefaultSegmentSize)
+			segment, err := ReadSegment(file, uint64(i), buf)
+			assert.NoError(t, err)
+			assert.Equal(t, slicePadded(content, i*DefaultSegmentSize, length), segment, "size = %v, segment = %v", size, i)
+
+			// consistent with merkle tree
+			proof, err := tree.ProofAt(uint64(i))
+			assert.NoError(t, err)
+			assert.NoError(t, tree.ValidateSegment(tree.Root(), segment, uint64(i), proof))
+		}
+
+		_, err = ReadSegment(file, uint64(numSegments))
+		assert.Error(t, err)
+	}
+}
+
+func TestReadChunkFragment(t *testing.T) {
+	content := make([]byte, 3*DefaultSegmentSize+100)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+	data, err := NewDataInMemory(content)
+	assert.NoError(t, err)
+
+	fragments := data.Split(2 * DefaultSegmentSize)
+	assert.Len(t, fragments, 2)
+
+	chunk, err := ReadChunk(fragments[1], 0)
+	assert.NoError(t, err)
+	assert.Equal(t, slicePadded(content, 2*DefaultSegmentSize, DefaultChunkSize), chunk)
+
+	segment, err := ReadSegment(fragments[1], 1)
+	assert.NoError(t, err)
+	assert.Equal(t, slicePadded(content, 3*DefaultSegmentSize, DefaultChunkSize), segment)
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ReaderAtData implement of IterableData, the underlying is an io.ReaderAt of known size, e.g. a bytes.Reader or an
+// object of another service read at offsets, so that data could be uploaded without writing a temp file.
+type ReaderAtData struct {
+	underlying io.ReaderAt
+	offset     int64
+	size       int64
+	paddedSize uint64
+}
+
+var _ IterableData = (*ReaderAtData)(nil)
+
+// NewDataFromReaderAt creates ReaderAtData of the specified size from r, which is read concurrently at offsets and
+// should not be modified during upload. ErrFileEmpty is returned if size is 0.
+func NewDataFromReaderAt(r io.ReaderAt, size int64) (*ReaderAtData, error) {
+	if size == 0 {
+		return nil, ErrFileEmpty
+	}
+
+	if size < 0 {
+		return nil, errors.Errorf("invalid size %v", size)
+	}
+
+	return &ReaderAtData{
+		underlying: r,
+		size:       size,
+		paddedSize: IteratorPaddedSize(size, true),
+	}, nil
+}
+
+// Read reads data at offset, and never reads beyond the fragment. Unlike files on disk, io.ErrUnexpectedEOF is
+// returned if the underlying data is shorter than the size specified.
+func (data *ReaderAtData) Read(buf []byte, offset int64) (int, error) {
+	if offset < 0 || offset >= data.size {
+		return 0, nil
+	}
+
+	buf = buf[:min(int64(len(buf)), data.size-offset)]
+	n, err := data.underlying.ReadAt(buf, data.offset+offset)
+	if n == len(buf) {
+		return n, nil
+	}
+
+	if err == nil || errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return 0, errors.WithMessagef(err, "failed to read %v bytes at offset %v", len(buf), data.offset+offset)
+}
+
+func (data *ReaderAtData) NumChunks() uint64 {
+	return NumSplits(data.size, DefaultChunkSize)
+}
+
+func (data *ReaderAtData) NumSegments() uint64 {
+	return NumSplits(data.size, DefaultSegmentSize)
+}
+
+func (data *ReaderAtData) Size() int64 {
+	return data.size
+}
+
+func (data *ReaderAtData) Offset() int64 {
+	return data.offset
+}
+
+func (data *ReaderAtData) PaddedSize() uint64 {
+	return data.paddedSize
+}
+
+func (data *ReaderAtData) Split(fragmentSize int64) []IterableData {
+	fragments := make([]IterableData, 0)
+	for offset := data.offset; offset < data.offset+data.size; offset += fragmentSize {
+		size := min(data.offset+data.size-offset, fragmentSize)
+		fragment := &ReaderAtData{
+			underlying: data.underlying,
+			offset:     offset,
+			size:       size,
+			paddedSize: IteratorPaddedSize(size, true),
+		}
+		fragments = append(fragments, fragment)
+	}
+	return fragments
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataFromReaderAt(t *testing.T) {
+	_, err := NewDataFromReaderAt(bytes.NewReader(nil), 0)
+	assert.ErrorIs(t, err, ErrFileEmpty)
+	_, err = NewDataInMemory(nil)
+	assert.ErrorIs(t, err, ErrFileEmpty)
+	_, err = NewDataFromReaderAt(bytes.NewReader(nil), -1)
+	assert.Error(t, err)
+
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{1, DefaultChunkSize, DefaultSegmentSize + 1, 3*DefaultSegmentSize + 100} {
+		content := make([]byte, size)
+		r.Read(content)
+
+		filename := filepath.Join(t.TempDir(), "data")
+		assert.NoError(t, os.WriteFile(filename, content, 0644))
+		file, err := Open(filename)
+		assert.NoError(t, err)
+		defer file.Close()
+
+		inMem, err := NewDataInMemory(content)
+		assert.NoError(t, err)
+		readerAt, err := NewDataFromReaderAt(bytes.NewReader(content), int64(size))
+		assert.NoError(t, err)
+
+		// identical to file on disk
+		fileTree, err := MerkleTree(file)
+		assert.NoError(t, err)
+		for _, data := range []IterableData{inMem, readerAt} {
+			assert.Equal(t, file.NumChunks(), data.NumChunks(), size)
+			assert.Equal(t, file.NumSegments(), data.NumSegments(), size)
+			assert.Equal(t, file.PaddedSize(), data.PaddedSize(), size)
+
+			tree, err := MerkleTree(data)
+			assert.NoError(t, err)
+			assert.Equal(t, fileTree.Root(), tree.Root(), size)
+
+			// fragments never read beyond the fragment
+			fragmentSize := int64(DefaultSegmentSize)
+			fragments := data.Split(fragmentSize)
+			assert.Len(t, fragments, int(NumSplits(int64(size), DefaultSegmentSize)))
+			for i, fragment := range fragments {
+				offset := int64(i) * fragmentSize
+				assert.Equal(t, offset, fragment.Offset())
+				assert.Equal(t, min(int64(size)-offset, fragmentSize), fragment.Size())
+
+				buf, err := ReadAt(fragment, int(fragment.PaddedSize()), 0, fragment.PaddedSize())
+				assert.NoError(t, err)
+				expected := make([]byte, fragment.PaddedSize())
+				copy(expected, content[offset:offset+fragment.Size()])
+				assert.Equal(t, expected, buf)
+			}
+		}
+	}
+
+	// underlying data shorter than size
+	data, err := NewDataFromReaderAt(bytes.NewReader(make([]byte, 100)), 200)
+	assert.NoError(t, err)
+	_, err = MerkleTree(data)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"github.com/0glabs/0g-storage-client/core/merkle"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// Proof is the merkle proof of a segment against the file root. Proofs are encoded in JSON as below, which is
+// stable so that proofs could be shipped over HTTP, e.g.
+//
+//	{"lemma": ["0x<segment root>", "0x<sibling>", ..., "0x<file root>"], "path": [true, ...]}
+type Proof = merkle.Proof
+
+// FileTree is the merkle tree of file by segment, which generates the proofs of segments for external
+// verification, see ValidateSegment.
+type FileTree struct {
+	tree *merkle.Tree
+	size int64
+}
+
+// NewFileTree builds the merkle tree of data, which is read as specified by option if any, see MerkleTree.
+func NewFileTree(data IterableData, option ...HashOption) (*FileTree, error) {
+	tree, err := MerkleTree(data, option...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileTree{tree, data.Size()}, nil
+}
+
+// Root returns the merkle root of file.
+func (tree *FileTree) Root() common.Hash {
+	return tree.tree.Root()
+}
+
+// Size returns the file size.
+func (tree *FileTree) Size() int64 {
+	return tree.size
+}
+
+// NumSegments returns the number of segments that hold the file data, excluding segments padded for flow.
+func (tree *FileTree) NumSegments() uint64 {
+	return NumSplits(tree.size, DefaultSegmentSize)
+}
+
+// ProofAt returns the merkle proof of the specified segment.
+func (tree *FileTree) ProofAt(segmentIndex uint64) (*Proof, error) {
+	if numSegments := tree.NumSegments(); segmentIndex >= numSegments {
+		return nil, errors.Errorf("segment index out of bounds, index = %v, segments = %v", segmentIndex, numSegments)
+	}
+
+	proof := tree.tree.ProofAt(int(segmentIndex))
+
+	return &proof, nil
+}
+
+// ChunkProofAt returns the merkle proof of the specified chunk against the file root, which is made of the proof of
+// chunk within the segment followed by the proof of segment, so that a few bytes of file could be proved without
+// the whole segment. The segment holds the chunk as ValidateSegment requires, see ValidateChunk.
+func (tree *FileTree) ChunkProofAt(segment []byte, chunkIndex uint64) (*Proof, error) {
+	segmentIndex := chunkIndex / DefaultSegmentMaxChunks
+	if numSegments := tree.NumSegments(); segmentIndex >= numSegments {
+		return nil, errors.Errorf("chunk index out of bounds, index = %v, segments = %v", chunkIndex, numSegments)
+	}
+
+	_, length, err := SegmentToByteRange(segmentIndex, tree.size)
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(segment)) != length {
+		return nil, errors.Errorf("segment length mismatch, expected = %v, actual = %v", length, len(segment))
+	}
+
+	// merkle tree of chunks within segment, along with empty chunks padded
+	var builder merkle.TreeBuilder
+	for offset := 0; offset < len(segment); offset += DefaultChunkSize {
+		builder.Append(segment[offset : offset+DefaultChunkSize])
+	}
+
+	emptyChunksPadded, _ := segmentEmptyChunksPadded(segmentIndex, tree.size)
+	for i := uint64(0); i < emptyChunksPadded; i++ {
+		builder.AppendHash(EmptyChunkHash)
+	}
+
+	chunkProof := builder.Build().ProofAt(int(chunkIndex % DefaultSegmentMaxChunks))
+	segmentProof := tree.tree.ProofAt(int(segmentIndex))
+
+	// the root of chunk proof is the leaf of segment proof
+	proof := Proof{Path: append(append([]bool{}, chunkProof.Path...), segmentProof.Path...)}
+	if len(proof.Path) == 0 {
+		proof.Lemma = []common.Hash{tree.Root()}
+		return &proof, nil
+	}
+
+	proof.Lemma = append(proof.Lemma, chunkProof.Lemma[:len(chunkProof.Path)+1]...)
+	proof.Lemma = append(proof.Lemma, segmentProof.Lemma[1:len(segmentProof.Path)+1]...)
+	proof.Lemma = append(proof.Lemma, tree.Root())
+
+	return &proof, nil
+}
+
+// ValidateSegment validates the segment of file against the root by merkle proof, see the package function
+// ValidateSegment.
+func (tree *FileTree) ValidateSegment(root common.Hash, segment []byte, index uint64, proof *Proof) error {
+	return ValidateSegment(root, tree.size, segment, index, proof)
+}
+
+// ValidateSegment validates the segment of a file of the specified size against the file root by merkle proof,
+// so that segments fetched from untrusted storage nodes could be verified without the whole file.
+//
+// The segment should hold the file data padded with zeros to a multiple of DefaultChunkSize, see
+// SegmentToByteRange. Returns an error if the segment is truncated, the padding bytes are not zeros, or the proof is
+// not of the segment at the specified index.
+func ValidateSegment(root common.Hash, fileSize int64, segment []byte, index uint64, proof *Proof) error {
+	if proof == nil {
+		return errors.New("proof is nil")
+	}
+
+	offset, length, err := SegmentToByteRange(index, fileSize)
+	if err != nil {
+		return err
+	}
+
+	if int64(len(segment)) != length {
+		return errors.Errorf("segment length mismatch, expected = %v, actual = %v", length, len(segment))
+	}
+
+	// bytes beyond file data are padded with zeros
+	for i := fileSize - offset; i < length; i++ {
+		if segment[i] != 0 {
+			return errors.Errorf("padding byte at offset %v of segment is not zero", i)
+		}
+	}
+
+	segmentRoot, numSegmentsFlowPadded := PaddedSegmentRoot(index, segment, fileSize)
+	if err := proof.ValidateHash(root, segmentRoot, index, numSegmentsFlowPadded); err != nil {
+		return errors.WithMessagef(err, "failed to validate proof of segment %v", index)
+	}
+
+	return nil
+}
+
+// ValidateChunk validates the chunk of a file of the specified size against the file root by merkle proof, see
+// FileTree.ChunkProofAt. The chunk should hold the file data padded with zeros to DefaultChunkSize.
+func ValidateChunk(root common.Hash, fileSize int64, chunk []byte, index uint64, proof *Proof) error {
+	if proof == nil {
+		return errors.New("proof is nil")
+	}
+
+	if fileSize <= 0 {
+		return errors.Errorf("invalid file size %v", fileSize)
+	}
+
+	if numChunks := NumSplits(fileSize, DefaultChunkSize); index >= numChunks {
+		return errors.Errorf("chunk index out of bounds, index = %v, chunks = %v", index, numChunks)
+	}
+
+	if len(chunk) != DefaultChunkSize {
+		return errors.Errorf("chunk length mismatch, expected = %v, actual = %v", DefaultChunkSize, len(chunk))
+	}
+
+	// bytes beyond file data are padded with zeros
+	for i := fileSize - int64(index)*DefaultChunkSize; i < DefaultChunkSize; i++ {
+		if chunk[i] != 0 {
+			return errors.Errorf("padding byte at offset %v of chunk is not zero", i)
+		}
+	}
+
+	numChunksFlowPadded, _ := ComputePaddedSize(NumSplits(fileSize, DefaultChunkSize))
+	if err := proof.ValidateHash(root, crypto.Keccak256Hash(chunk), index, numChunksFlowPadded); err != nil {
+		return errors.WithMessagef(err, "failed to validate proof of chunk %v", index)
+	}
+
+	return nil
+}
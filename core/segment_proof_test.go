@@ -0,0 +1,165 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestFileTree returns the file tree of random data, along with the segments padded to chunks.
+func newTestFileTree(t *testing.T, size int) (*FileTree, [][]byte) {
+	content := make([]byte, size)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	data, err := NewDataInMemory(content)
+	assert.NoError(t, err)
+	tree, err := NewFileTree(data)
+	assert.NoError(t, err)
+
+	var segments [][]byte
+	for index := uint64(0); index < tree.NumSegments(); index++ {
+		offset, length, err := SegmentToByteRange(index, int64(size))
+		assert.NoError(t, err)
+		segment := make([]byte, length)
+		copy(segment, content[offset:])
+		segments = append(segments, segment)
+	}
+
+	return tree, segments
+}
+
+func TestSegmentProofSingleSegment(t *testing.T) {
+	tree, segments := newTestFileTree(t, 1000)
+	assert.Equal(t, uint64(1), tree.NumSegments())
+
+	proof, err := tree.ProofAt(0)
+	assert.NoError(t, err)
+	assert.NoError(t, tree.ValidateSegment(tree.Root(), segments[0], 0, proof))
+
+	// wrong root, even if proof forged for the root
+	assert.Error(t, tree.ValidateSegment(common.Hash{1}, segments[0], 0, proof))
+	forged := &Proof{Lemma: []common.Hash{SegmentRoot(segments[0])}, Path: []bool{}}
+	assert.Error(t, ValidateSegment(common.Hash{1}, 1000, segments[0], 0, forged))
+
+	// altered padding bytes of the last chunk
+	segments[0][1000]++
+	assert.ErrorContains(t, tree.ValidateSegment(tree.Root(), segments[0], 0, proof), "padding byte")
+
+	_, err = tree.ProofAt(1)
+	assert.Error(t, err)
+}
+
+func TestSegmentProofPartialLastSegment(t *testing.T) {
+	size := 2*DefaultSegmentSize + 1000
+	tree, segments := newTestFileTree(t, size)
+	assert.Equal(t, uint64(3), tree.NumSegments())
+
+	proofs := make([]*Proof, len(segments))
+	for i, segment := range segments {
+		var err error
+		proofs[i], err = tree.ProofAt(uint64(i))
+		assert.NoError(t, err)
+		assert.NoError(t, ValidateSegment(tree.Root(), int64(size), segment, uint64(i), proofs[i]))
+	}
+
+	// wrong index
+	assert.Error(t, tree.ValidateSegment(tree.Root(), segments[1], 0, proofs[1]))
+	assert.Error(t, tree.ValidateSegment(tree.Root(), segments[1], 2, proofs[1]))
+	assert.Error(t, tree.ValidateSegment(tree.Root(), segments[0], 3, proofs[0]))
+
+	// truncated
+	assert.ErrorContains(t, tree.ValidateSegment(tree.Root(), segments[2][:DefaultChunkSize*3], 2, proofs[2]), "length mismatch")
+	assert.ErrorContains(t, tree.ValidateSegment(tree.Root(), segments[1][:DefaultSegmentSize-1], 1, proofs[1]), "length mismatch")
+
+	// altered padding bytes
+	last := append([]byte(nil), segments[2]...)
+	last[len(last)-1] = 1
+	assert.ErrorContains(t, tree.ValidateSegment(tree.Root(), last, 2, proofs[2]), "padding byte")
+
+	// altered data
+	last = append([]byte(nil), segments[2]...)
+	last[0]++
+	assert.ErrorContains(t, tree.ValidateSegment(tree.Root(), last, 2, proofs[2]), "failed to validate proof")
+
+	assert.Error(t, tree.ValidateSegment(tree.Root(), segments[2], 2, nil))
+}
+
+func TestSegmentProofJSON(t *testing.T) {
+	proof := Proof{Lemma: []common.Hash{{1}, {2}, {3}}, Path: []bool{true}}
+	encoded, err := json.Marshal(proof)
+	assert.NoError(t, err)
+	zeros := strings.Repeat("0", 62)
+	assert.Equal(t, `{"lemma":["0x01`+zeros+`","0x02`+zeros+`","0x03`+zeros+`"],"path":[true]}`, string(encoded))
+
+	// proofs shipped in JSON
+	tree, segments := newTestFileTree(t, 2*DefaultSegmentSize+1000)
+	for i, segment := range segments {
+		proof, err := tree.ProofAt(uint64(i))
+		assert.NoError(t, err)
+		encoded, err := json.Marshal(proof)
+		assert.NoError(t, err)
+
+		var decoded Proof
+		assert.NoError(t, json.Unmarshal(encoded, &decoded))
+		assert.NoError(t, ValidateSegment(tree.Root(), tree.Size(), segment, uint64(i), &decoded))
+	}
+}
+
+func TestChunkProof(t *testing.T) {
+	for _, size := range []int{1, 1000, DefaultSegmentSize, 2*DefaultSegmentSize + 1000, 5*DefaultSegmentSize + 300} {
+		tree, segments := newTestFileTree(t, size)
+		numChunks := NumSplits(int64(size), DefaultChunkSize)
+
+		// first and last chunks of each segment, and the chunks around
+		var indexes []uint64
+		for i := range segments {
+			first := uint64(i) * DefaultSegmentMaxChunks
+			last := min(first+DefaultSegmentMaxChunks, numChunks) - 1
+			indexes = append(indexes, first, min(first+1, last), max(last, first+1)-1, last)
+		}
+
+		for _, index := range indexes {
+			segment := segments[index/DefaultSegmentMaxChunks]
+			chunk := segment[index%DefaultSegmentMaxChunks*DefaultChunkSize:][:DefaultChunkSize]
+
+			proof, err := tree.ChunkProofAt(segment, index)
+			assert.NoError(t, err, size)
+			assert.NoError(t, ValidateChunk(tree.Root(), int64(size), chunk, index, proof), "size %v, chunk %v", size, index)
+
+			// altered data
+			altered := append([]byte(nil), chunk...)
+			altered[0]++
+			assert.Error(t, ValidateChunk(tree.Root(), int64(size), altered, index, proof))
+
+			// wrong index, unless the only chunk
+			if numChunks > 1 {
+				assert.Error(t, ValidateChunk(tree.Root(), int64(size), chunk, (index+1)%numChunks, proof))
+			}
+
+			// altered sibling
+			if len(proof.Path) > 0 {
+				proof.Lemma[1][0]++
+				assert.Error(t, ValidateChunk(tree.Root(), int64(size), chunk, index, proof))
+			}
+		}
+
+		assert.Error(t, ValidateChunk(tree.Root(), int64(size), EmptyChunk, numChunks, &Proof{}))
+		_, err := tree.ChunkProofAt(segments[0], uint64(len(segments))*DefaultSegmentMaxChunks)
+		assert.Error(t, err)
+	}
+
+	// altered padding bytes
+	tree, segments := newTestFileTree(t, 1000)
+	proof, err := tree.ChunkProofAt(segments[0], 3)
+	assert.NoError(t, err)
+	last := append([]byte(nil), segments[0][3*DefaultChunkSize:]...)
+	assert.NoError(t, ValidateChunk(tree.Root(), 1000, last, 3, proof))
+	last[len(last)-1] = 1
+	assert.ErrorContains(t, ValidateChunk(tree.Root(), 1000, last, 3, proof), "padding byte")
+	assert.Error(t, ValidateChunk(tree.Root(), 1000, last, 3, nil))
+}
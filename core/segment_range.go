@@ -0,0 +1,56 @@
+package core
+
+import "github.com/pkg/errors"
+
+// Segments of a file hold the file data padded with zeros to a multiple of DefaultChunkSize, i.e. the last
+// segment may be shorter than DefaultSegmentSize, and ends with padding bytes unless the file size is aligned
+// with DefaultChunkSize. Segment index is relative to the file, not the flow.
+
+// chunkPaddedSize returns the size of file data held by segments, i.e. file size padded to chunks.
+func chunkPaddedSize(fileSize int64) int64 {
+	return int64(NumSplits(fileSize, DefaultChunkSize)) * DefaultChunkSize
+}
+
+// ByteRangeToSegments converts the byte range [offset, offset+length) of a file into segments, and returns the
+// index of first and last segment that the range spans, the offset of range within the first segment, and the
+// number of bytes of range within the last segment.
+//
+// The range should be non-empty, and may extend to the zero padding of last segment, but not beyond.
+func ByteRangeToSegments(offset, length, fileSize int64) (firstSeg, lastSeg uint64, firstOffsetInSeg, lastLenInSeg int64, err error) {
+	if fileSize <= 0 {
+		return 0, 0, 0, 0, errors.Errorf("invalid file size %v", fileSize)
+	}
+
+	if offset < 0 || length <= 0 {
+		return 0, 0, 0, 0, errors.Errorf("invalid range, offset = %v, length = %v", offset, length)
+	}
+
+	if paddedSize := chunkPaddedSize(fileSize); offset >= paddedSize || length > paddedSize-offset {
+		return 0, 0, 0, 0, errors.Errorf("range out of bounds, offset = %v, length = %v, fileSize = %v, paddedSize = %v",
+			offset, length, fileSize, paddedSize)
+	}
+
+	end := offset + length // exclusive
+	firstSeg = uint64(offset / DefaultSegmentSize)
+	lastSeg = uint64((end - 1) / DefaultSegmentSize)
+
+	return firstSeg, lastSeg, offset % DefaultSegmentSize, end - int64(lastSeg)*DefaultSegmentSize, nil
+}
+
+// SegmentToByteRange returns the byte range [offset, offset+length) of a file held by the specified segment,
+// which includes the zero padding of last segment. It is the inverse of ByteRangeToSegments, and length is the
+// expected data length of segment downloaded from storage node.
+func SegmentToByteRange(segmentIndex uint64, fileSize int64) (offset, length int64, err error) {
+	if fileSize <= 0 {
+		return 0, 0, errors.Errorf("invalid file size %v", fileSize)
+	}
+
+	if numSegments := NumSplits(fileSize, DefaultSegmentSize); segmentIndex >= numSegments {
+		return 0, 0, errors.Errorf("segment index out of bounds, index = %v, segments = %v", segmentIndex, numSegments)
+	}
+
+	offset = int64(segmentIndex) * DefaultSegmentSize
+	length = min(DefaultSegmentSize, chunkPaddedSize(fileSize)-offset)
+
+	return offset, length, nil
+}
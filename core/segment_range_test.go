@@ -0,0 +1,136 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteRangeToSegments(t *testing.T) {
+	const seg = DefaultSegmentSize
+
+	tests := []struct {
+		name             string
+		offset, length   int64
+		fileSize         int64
+		firstSeg         uint64
+		lastSeg          uint64
+		firstOffsetInSeg int64
+		lastLenInSeg     int64
+	}{
+		{"single byte file", 0, 1, 1, 0, 0, 0, 1},
+		{"whole first segment", 0, seg, seg * 2, 0, 0, 0, seg},
+		{"end at segment boundary", 100, seg - 100, seg * 2, 0, 0, 100, seg},
+		{"start at segment boundary", seg, 1, seg * 2, 1, 1, 0, 1},
+		{"cross segment boundary", seg - 1, 2, seg * 2, 0, 1, seg - 1, 1},
+		{"span three segments", 10, seg * 2, seg * 3, 0, 2, 10, 10},
+		{"end at file end", seg, 10, seg + 10, 1, 1, 0, 10},
+		{"whole file of aligned segments", 0, seg * 3, seg * 3, 0, 2, 0, seg},
+		{"end at chunk padding", seg, DefaultChunkSize, seg + 10, 1, 1, 0, DefaultChunkSize},
+		{"inside chunk padding", seg + 20, 100, seg + 10, 1, 1, 20, 120},
+		{"last byte of padding", DefaultChunkSize - 1, 1, 1, 0, 0, DefaultChunkSize - 1, DefaultChunkSize},
+		{"last byte of file", seg*2 - 1, 1, seg * 2, 1, 1, seg - 1, seg},
+	}
+
+	for _, tt := range tests {
+		firstSeg, lastSeg, firstOffset, lastLen, err := ByteRangeToSegments(tt.offset, tt.length, tt.fileSize)
+		if assert.NoError(t, err, tt.name) {
+			assert.Equal(t, tt.firstSeg, firstSeg, tt.name)
+			assert.Equal(t, tt.lastSeg, lastSeg, tt.name)
+			assert.Equal(t, tt.firstOffsetInSeg, firstOffset, tt.name)
+			assert.Equal(t, tt.lastLenInSeg, lastLen, tt.name)
+		}
+	}
+}
+
+func TestByteRangeToSegmentsInvalid(t *testing.T) {
+	const seg = DefaultSegmentSize
+
+	tests := []struct {
+		name                     string
+		offset, length, fileSize int64
+	}{
+		{"empty file", 0, 1, 0},
+		{"negative offset", -1, 1, seg},
+		{"empty range", 0, 0, seg},
+		{"negative length", 0, -1, seg},
+		{"offset beyond padding", DefaultChunkSize, 1, 1},
+		{"length beyond padding", 0, DefaultChunkSize + 1, 1},
+		{"offset at file end", seg * 2, 1, seg * 2},
+		{"overflow", 1, 1<<63 - 1, seg},
+	}
+
+	for _, tt := range tests {
+		_, _, _, _, err := ByteRangeToSegments(tt.offset, tt.length, tt.fileSize)
+		assert.Error(t, err, tt.name)
+	}
+}
+
+func TestSegmentToByteRange(t *testing.T) {
+	const seg = DefaultSegmentSize
+
+	tests := []struct {
+		index    uint64
+		fileSize int64
+		offset   int64
+		length   int64
+	}{
+		{0, 1, 0, DefaultChunkSize},
+		{0, DefaultChunkSize, 0, DefaultChunkSize},
+		{0, DefaultChunkSize + 1, 0, DefaultChunkSize * 2},
+		{0, seg, 0, seg},
+		{0, seg + 1, 0, seg},
+		{1, seg + 1, seg, DefaultChunkSize},
+		{2, seg * 3, seg * 2, seg},
+		{2, seg*3 - 1, seg * 2, seg},
+	}
+
+	for _, tt := range tests {
+		offset, length, err := SegmentToByteRange(tt.index, tt.fileSize)
+		if assert.NoError(t, err, "index = %v, fileSize = %v", tt.index, tt.fileSize) {
+			assert.Equal(t, tt.offset, offset, "index = %v, fileSize = %v", tt.index, tt.fileSize)
+			assert.Equal(t, tt.length, length, "index = %v, fileSize = %v", tt.index, tt.fileSize)
+		}
+	}
+
+	_, _, err := SegmentToByteRange(0, 0)
+	assert.Error(t, err)
+	_, _, err = SegmentToByteRange(1, seg)
+	assert.Error(t, err)
+	_, _, err = SegmentToByteRange(2, seg+1)
+	assert.Error(t, err)
+}
+
+func FuzzByteRangeToSegments(f *testing.F) {
+	f.Add(int64(0), int64(1), int64(1))
+	f.Add(int64(100), int64(DefaultSegmentSize-100), int64(DefaultSegmentSize*2))
+	f.Add(int64(DefaultSegmentSize+20), int64(100), int64(DefaultSegmentSize+10))
+	f.Add(int64(10), int64(DefaultSegmentSize*2), int64(DefaultSegmentSize*3))
+
+	f.Fuzz(func(t *testing.T, offset, length, fileSize int64) {
+		firstSeg, lastSeg, firstOffset, lastLen, err := ByteRangeToSegments(offset, length, fileSize)
+		if err != nil {
+			return
+		}
+
+		assert.LessOrEqual(t, firstSeg, lastSeg)
+
+		// range starts within the first segment
+		firstStart, firstLen, err := SegmentToByteRange(firstSeg, fileSize)
+		assert.NoError(t, err)
+		assert.Equal(t, offset, firstStart+firstOffset)
+		assert.Less(t, firstOffset, firstLen)
+
+		// range ends within the last segment
+		lastStart, lastSegLen, err := SegmentToByteRange(lastSeg, fileSize)
+		assert.NoError(t, err)
+		assert.Equal(t, offset+length, lastStart+lastLen)
+		assert.Positive(t, lastLen)
+		assert.LessOrEqual(t, lastLen, lastSegLen)
+
+		// segment maps back to itself
+		segFirst, segLast, segOffset, segLen, err := ByteRangeToSegments(lastStart, lastSegLen, fileSize)
+		assert.NoError(t, err)
+		assert.Equal(t, []any{lastSeg, lastSeg, int64(0), lastSegLen}, []any{segFirst, segLast, segOffset, segLen})
+	})
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/contract"
+)
+
+// NumChunks returns the number of chunks of data with the specified size, where the last chunk may be partial, and
+// 0 if size is not positive. Note, NumSplits(0, unit) returns 1 instead.
+func NumChunks(size int64) uint64 {
+	if size <= 0 {
+		return 0
+	}
+
+	return NumSplits(size, DefaultChunkSize)
+}
+
+// NumSegments returns the number of segments of data with the specified size, where the last segment may be
+// partial, and 0 if size is not positive.
+func NumSegments(size int64) uint64 {
+	if size <= 0 {
+		return 0
+	}
+
+	return NumSplits(size, DefaultSegmentSize)
+}
+
+// PaddedSize returns the size in bytes of data with the specified size once padded in flow, i.e. the sectors
+// charged by storage fee, and 0 if size is not positive, see ComputePaddedSize.
+func PaddedSize(size int64) uint64 {
+	paddedChunks, _ := ComputePaddedSize(NumChunks(size))
+	return paddedChunks * DefaultChunkSize
+}
+
+// SubmissionNodeSizes returns the number of chunks of each node to submit data with the specified size to the flow
+// contract, which are powers of 2 in descending order and sum up to the padded chunks, e.g. 1024 and 128 chunks for
+// data of 1025 chunks.
+func SubmissionNodeSizes(size int64) []uint64 {
+	paddedChunks, nextChunkSize := ComputePaddedSize(NumChunks(size))
+
+	var nodes []uint64
+	for paddedChunks > 0 {
+		if paddedChunks >= nextChunkSize {
+			paddedChunks -= nextChunkSize
+			nodes = append(nodes, nextChunkSize)
+		}
+		nextChunkSize /= 2
+	}
+
+	return nodes
+}
+
+// NewSubmission builds the submission of data with tags to the flow contract, where the merkle root of each node is
+// calculated from data, see SubmissionNodeSizes.
+func NewSubmission(data IterableData, tags []byte, opts ...common.LogOption) (*contract.Submission, error) {
+	return NewFlow(data, tags, opts...).CreateSubmission()
+}
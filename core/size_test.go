@@ -0,0 +1,101 @@
+package core
+
+import (
+	"fmt"
+	"math/bits"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizeAccessors(t *testing.T) {
+	tests := []struct {
+		size       int64
+		chunks     uint64
+		segments   uint64
+		paddedSize uint64
+		nodes      []uint64
+	}{
+		{0, 0, 0, 0, nil},
+		{1, 1, 1, 256, []uint64{1}},
+		{DefaultChunkSize - 1, 1, 1, 256, []uint64{1}},
+		{DefaultChunkSize, 1, 1, 256, []uint64{1}},
+		{DefaultChunkSize + 1, 2, 1, 512, []uint64{2}},
+		{DefaultChunkSize*3 - 1, 3, 1, 768, []uint64{2, 1}},
+		{DefaultChunkSize * 17, 17, 1, 256 * 18, []uint64{16, 2}},
+		{DefaultSegmentSize - 1, 1024, 1, DefaultSegmentSize, []uint64{1024}},
+		{DefaultSegmentSize, 1024, 1, DefaultSegmentSize, []uint64{1024}},
+		{DefaultSegmentSize + 1, 1025, 2, 1152 * 256, []uint64{1024, 128}},
+		{DefaultSegmentSize*2 - 1, 2048, 2, DefaultSegmentSize * 2, []uint64{2048}},
+		{DefaultSegmentSize * 2, 2048, 2, DefaultSegmentSize * 2, []uint64{2048}},
+		{DefaultSegmentSize*2 + 1, 2049, 3, 2304 * 256, []uint64{2048, 256}},
+		{DefaultSegmentSize*4 - 1, 4096, 4, DefaultSegmentSize * 4, []uint64{4096}},
+		{DefaultSegmentSize*4 + 1, 4097, 5, 4608 * 256, []uint64{4096, 512}},
+		{DefaultSegmentSize<<20 - 1, 1 << 30, 1 << 20, DefaultSegmentSize << 20, []uint64{1 << 30}},
+		{DefaultSegmentSize<<20 + 1, 1<<30 + 1, 1<<20 + 1, (1<<30 + 1<<27) * 256, []uint64{1 << 30, 1 << 27}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.chunks, NumChunks(tt.size), "size = %v", tt.size)
+		assert.Equal(t, tt.segments, NumSegments(tt.size), "size = %v", tt.size)
+		assert.Equal(t, tt.paddedSize, PaddedSize(tt.size), "size = %v", tt.size)
+		assert.Equal(t, tt.nodes, SubmissionNodeSizes(tt.size), "size = %v", tt.size)
+	}
+}
+
+func TestSizeAccessorsBoundaries(t *testing.T) {
+	for k := 0; k <= 30; k++ {
+		for _, base := range []int64{DefaultChunkSize << k, DefaultSegmentSize << k} {
+			for _, size := range []int64{base - 1, base, base + 1} {
+				chunks := NumChunks(size)
+				assert.Equal(t, uint64((size+DefaultChunkSize-1)/DefaultChunkSize), chunks, "size = %v", size)
+				assert.Equal(t, uint64((size+DefaultSegmentSize-1)/DefaultSegmentSize), NumSegments(size), "size = %v", size)
+
+				// padded to multiple of 1/16 of the next power of 2 chunks
+				paddedSize := PaddedSize(size)
+				paddedChunks := paddedSize / DefaultChunkSize
+				assert.GreaterOrEqual(t, paddedSize, uint64(size), "size = %v", size)
+				assert.Equal(t, paddedSize, StorageFootprint(size).PaddedSize, "size = %v", size)
+				assert.Equal(t, paddedSize, IteratorPaddedSize(size, true), "size = %v", size)
+				if nextPow2 := NextPow2(chunks); nextPow2 >= 16 {
+					assert.Zero(t, paddedChunks%(nextPow2/16), "size = %v", size)
+					assert.Less(t, paddedChunks-chunks, nextPow2/16, "size = %v", size)
+				} else {
+					assert.Equal(t, chunks, paddedChunks, "size = %v", size)
+				}
+
+				// nodes of distinct powers of 2 in descending order
+				var sum uint64
+				nodes := SubmissionNodeSizes(size)
+				for i, node := range nodes {
+					assert.Equal(t, 1, bits.OnesCount64(node), "size = %v", size)
+					if i > 0 {
+						assert.Less(t, node, nodes[i-1], "size = %v", size)
+					}
+					sum += node
+				}
+				assert.Equal(t, paddedChunks, sum, "size = %v", size)
+			}
+		}
+	}
+}
+
+func TestNewSubmission(t *testing.T) {
+	for _, size := range []int{1, DefaultChunkSize + 1, DefaultSegmentSize + 1, DefaultSegmentSize*2 + 1} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			data, err := NewDataInMemory(make([]byte, size))
+			assert.NoError(t, err)
+
+			submission, err := NewSubmission(data, []byte{1})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(size), submission.Length.Int64())
+			assert.Equal(t, []byte{1}, submission.Tags)
+
+			nodes := SubmissionNodeSizes(int64(size))
+			assert.Equal(t, len(nodes), len(submission.Nodes))
+			for i, node := range submission.Nodes {
+				assert.Equal(t, int64(bits.TrailingZeros64(nodes[i])), node.Height.Int64())
+			}
+		})
+	}
+}
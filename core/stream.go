@@ -0,0 +1,113 @@
+package core
+
+import (
+	"io"
+	"sync"
+
+	"github.com/0glabs/0g-storage-client/core/merkle"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// RootHasher calculates the merkle root of data written incrementally, e.g. data received over HTTP or generated on
+// the fly, of which the size is not necessarily known in advance. The merkle root is the same as MerkleTree of the
+// same data, and memory is bounded to a segment regardless of the data size.
+type RootHasher struct {
+	builder merkle.RootBuilder
+	segment []byte // data of the segment not completed yet
+	size    int64
+}
+
+// NewRootHasher creates a RootHasher to write data.
+func NewRootHasher() *RootHasher {
+	return &RootHasher{segment: make([]byte, 0, DefaultSegmentSize)}
+}
+
+// Write implements io.Writer, which never fails.
+func (hasher *RootHasher) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		written := min(len(p), DefaultSegmentSize-len(hasher.segment))
+		hasher.segment = append(hasher.segment, p[:written]...)
+		p = p[written:]
+
+		// segments completed are never padded
+		if len(hasher.segment) == DefaultSegmentSize {
+			hasher.builder.AppendHash(SegmentRoot(hasher.segment))
+			hasher.segment = hasher.segment[:0]
+		}
+	}
+
+	hasher.size += int64(n)
+
+	return n, nil
+}
+
+// Size returns the number of bytes written.
+func (hasher *RootHasher) Size() int64 {
+	return hasher.size
+}
+
+// Finalize pads the data written as flow does, and returns the merkle root. ErrFileEmpty is returned if nothing
+// written. The hasher should not be written any more once finalized.
+func (hasher *RootHasher) Finalize() (common.Hash, error) {
+	if hasher.size == 0 {
+		return common.Hash{}, ErrFileEmpty
+	}
+
+	paddedSize := IteratorPaddedSize(hasher.size, true)
+	offset := uint64(hasher.size) - uint64(len(hasher.segment))
+
+	// the last segment of data, of which the last chunk is padded with zeros, followed by empty chunks padded
+	if len(hasher.segment) > 0 {
+		n := min(DefaultSegmentSize, paddedSize-offset)
+		dataLen := (len(hasher.segment) + DefaultChunkSize - 1) / DefaultChunkSize * DefaultChunkSize
+		segment := hasher.segment[:dataLen]
+		clear(segment[len(hasher.segment):])
+		hasher.builder.AppendHash(SegmentRoot(segment, (n-uint64(dataLen))/DefaultChunkSize))
+		hasher.segment = hasher.segment[:0]
+		offset += n
+	}
+
+	// segments of empty chunks padded, of which only the last one may be partial
+	if n := (paddedSize - offset) / DefaultSegmentSize; n > 0 {
+		hasher.builder.AppendRepeated(emptySegmentRoots(), n)
+		offset += n * DefaultSegmentSize
+	}
+
+	if offset < paddedSize {
+		hasher.builder.AppendHash(SegmentRoot(nil, (paddedSize-offset)/DefaultChunkSize))
+	}
+
+	return hasher.builder.Root(), nil
+}
+
+// emptySegmentRoots returns the roots of subtrees of empty segments, see EmptyChunkRoots.
+var emptySegmentRoots = sync.OnceValue(func() []common.Hash {
+	return merkle.RepeatedRoots(SegmentRoot(nil, DefaultSegmentMaxChunks), 32)
+})
+
+// MerkleRootReader returns the merkle root hash of data of specified size read from r sequentially, e.g. entries of
+// tar archives, so that data could be hashed without buffered in memory or written to disk. The merkle root is the
+// same as MerkleTree of the same data, see RootHasher.
+func MerkleRootReader(r io.Reader, size int64) (common.Hash, error) {
+	if size == 0 {
+		return common.Hash{}, ErrFileEmpty
+	}
+
+	if size < 0 {
+		return common.Hash{}, errors.Errorf("invalid size %v", size)
+	}
+
+	hasher := NewRootHasher()
+	if n, err := io.CopyN(hasher, r, size); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return common.Hash{}, errors.WithMessagef(err, "failed to read data at offset %v", n)
+	}
+
+	return hasher.Finalize()
+}
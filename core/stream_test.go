@@ -0,0 +1,69 @@
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerkleRootReader(t *testing.T) {
+	for _, size := range []int{1, DefaultChunkSize, DefaultSegmentSize, DefaultSegmentSize + 1, 5*DefaultSegmentSize + 100} {
+		content := make([]byte, size)
+		_, err := rand.Read(content)
+		assert.NoError(t, err)
+
+		data, err := NewDataInMemory(content)
+		assert.NoError(t, err)
+		tree, err := MerkleTree(data)
+		assert.NoError(t, err)
+
+		// read without io.ReaderAt
+		root, err := MerkleRootReader(io.MultiReader(bytes.NewReader(content)), int64(size))
+		assert.NoError(t, err)
+		assert.Equal(t, tree.Root(), root, "size = %v", size)
+	}
+
+	_, err := MerkleRootReader(bytes.NewReader(nil), 0)
+	assert.ErrorIs(t, err, ErrFileEmpty)
+
+	_, err = MerkleRootReader(bytes.NewReader(make([]byte, 100)), 200)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestRootHasher(t *testing.T) {
+	sizes := []int{1, DefaultChunkSize - 1, DefaultChunkSize, DefaultSegmentSize - 1, DefaultSegmentSize, DefaultSegmentSize + 1,
+		3*DefaultSegmentSize + DefaultChunkSize + 7, 17*DefaultSegmentSize + 5}
+	for _, size := range sizes {
+		content := make([]byte, size)
+		_, err := rand.Read(content)
+		assert.NoError(t, err)
+
+		data, err := NewDataInMemory(content)
+		assert.NoError(t, err)
+		tree, err := MerkleTree(data)
+		assert.NoError(t, err)
+
+		// written in pieces not aligned with chunks or segments
+		hasher := NewRootHasher()
+		for offset := 0; offset < size; offset += 100_003 {
+			n, err := hasher.Write(content[offset:min(offset+100_003, size)])
+			assert.NoError(t, err)
+			assert.Equal(t, min(100_003, size-offset), n)
+		}
+		assert.Equal(t, int64(size), hasher.Size())
+
+		root, err := hasher.Finalize()
+		assert.NoError(t, err)
+		assert.Equal(t, tree.Root(), root, "size = %v", size)
+
+		root, err = MerkleRootData(data)
+		assert.NoError(t, err)
+		assert.Equal(t, tree.Root(), root, "size = %v", size)
+	}
+
+	_, err := NewRootHasher().Finalize()
+	assert.ErrorIs(t, err, ErrFileEmpty)
+}
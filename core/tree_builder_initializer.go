@@ -26,11 +26,21 @@ func (t *TreeBuilderInitializer) ParallelCollect(result *parallel.Result) error
 // ParallelDo implements parallel.Interface.
 func (t *TreeBuilderInitializer) ParallelDo(ctx context.Context, routine int, task int) (interface{}, error) {
 	offset := t.offset + int64(task)*t.batch
+
+	// use the segment root directly if known
+	if known, ok := t.data.(KnownSegments); ok && t.batch == DefaultSegmentSize && offset%DefaultSegmentSize == 0 {
+		if roots, index := known.KnownSegmentRoots(), offset/DefaultSegmentSize; index < int64(len(roots)) {
+			return roots[index], nil
+		}
+	}
+
 	buf, err := ReadAt(t.data, int(t.batch), offset, t.data.PaddedSize())
 	if err != nil {
 		return nil, err
 	}
 
-	hash := SegmentRoot(buf)
+	// empty chunks padded are not hashed one by one
+	dataLen := dataChunksLen(t.data, offset, len(buf))
+	hash := SegmentRoot(buf[:dataLen], uint64(len(buf)-dataLen)/DefaultChunkSize)
 	return hash, nil
 }
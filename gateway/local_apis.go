@@ -3,6 +3,7 @@ package gateway
 import (
 	"context"
 	"path/filepath"
+	"slices"
 
 	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/api"
@@ -17,6 +18,10 @@ import (
 
 var LocalFileRepo string = "."
 
+// UploadHints is shared by upload and download handlers, so that files uploaded recently could be downloaded from
+// the storage node uploaded to, even if another storage node not synced yet is requested.
+var UploadHints = transfer.NewUploadHints(0)
+
 func listNodes(c *gin.Context) (interface{}, error) {
 	var nodes []string
 
@@ -62,10 +67,11 @@ func getLocalFileInfo(c *gin.Context) (interface{}, error) {
 	}
 
 	return map[string]interface{}{
-		"name":     file.Name(),
-		"root":     tree.Root(),
-		"size":     file.Size(),
-		"segments": file.NumSegments(),
+		"name":      file.Name(),
+		"root":      tree.Root(),
+		"size":      file.Size(),
+		"segments":  file.NumSegments(),
+		"footprint": core.StorageFootprint(file.Size()),
 	}, nil
 }
 
@@ -123,10 +129,15 @@ func uploadLocalFile(c *gin.Context) (interface{}, error) {
 		return nil, api.ErrValidation.WithData("node index out of bound")
 	}
 
-	uploader, err := transfer.NewUploader(context.Background(), nil, []*node.ZgsClient{allClients[input.Node]}, zg_common.LogOption{Logger: logrus.StandardLogger()})
+	if w3Client == nil {
+		return nil, ErrUploadUnsupported
+	}
+
+	uploader, err := transfer.NewUploader(context.Background(), w3Client, []*node.ZgsClient{allClients[input.Node]}, zg_common.LogOption{Logger: logrus.StandardLogger()})
 	if err != nil {
 		return nil, errors.WithMessage(err, "Failed to create uploader")
 	}
+	uploader.WithUploadHints(UploadHints)
 
 	filename := getFilePath(input.Path, false)
 
@@ -146,9 +157,10 @@ func uploadLocalFile(c *gin.Context) (interface{}, error) {
 
 func downloadFileLocal(c *gin.Context) (interface{}, error) {
 	var input struct {
-		Node int    `form:"node" json:"node"`
-		Root string `form:"root" json:"root" binding:"required"`
-		Path string `form:"path" json:"path" binding:"required"`
+		Node  int    `form:"node" json:"node"`
+		Root  string `form:"root" json:"root" binding:"required"`
+		Path  string `form:"path" json:"path" binding:"required"`
+		Force bool   `form:"force" json:"force"` // overwrite the existing file that differs
 	}
 
 	if err := c.ShouldBind(&input); err != nil {
@@ -159,10 +171,12 @@ func downloadFileLocal(c *gin.Context) (interface{}, error) {
 		return nil, api.ErrValidation.WithData("node index out of bound")
 	}
 
-	downloader, err := transfer.NewDownloader([]*node.ZgsClient{allClients[input.Node]}, zg_common.LogOption{Logger: logrus.StandardLogger()})
+	client := hintedClient(common.HexToHash(input.Root), allClients[input.Node])
+	downloader, err := transfer.NewDownloader([]*node.ZgsClient{client}, zg_common.LogOption{Logger: logrus.StandardLogger()})
 	if err != nil {
 		return nil, err
 	}
+	downloader.WithOverwrite(input.Force)
 
 	filename := getFilePath(input.Path, true)
 
@@ -172,3 +186,22 @@ func downloadFileLocal(c *gin.Context) (interface{}, error) {
 
 	return nil, nil
 }
+
+// hintedClient returns the storage node known to hold the file uploaded recently, see UploadHints, or the requested
+// storage node if not hinted or hinted already.
+func hintedClient(root common.Hash, requested *node.ZgsClient) *node.ZgsClient {
+	urls := UploadHints.Nodes(root)
+	if len(urls) == 0 || slices.Contains(urls, requested.URL()) {
+		return requested
+	}
+
+	for _, url := range urls {
+		for _, client := range allClients {
+			if client.URL() == url {
+				return client
+			}
+		}
+	}
+
+	return requested
+}
@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newLocalServer serves local APIs with a mock storage node, where the file of specified content in a single
+// segment is uploaded.
+func newLocalServer(t *testing.T, content []byte) (*httptest.Server, string) {
+	service := testutil.NewZgsService()
+	client, err := node.NewZgsClient(testutil.Serve(t, map[string]interface{}{"zgs": service}))
+	assert.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	root, err := service.Submit(content, nil)
+	assert.NoError(t, err)
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(t, err)
+	_, err = client.UploadSegment(context.Background(), node.SegmentWithProof{
+		Root:     root,
+		Data:     content,
+		Index:    0,
+		Proof:    tree.ProofAt(0),
+		FileSize: uint64(len(content)),
+	})
+	assert.NoError(t, err)
+
+	oldClients, oldRepo := allClients, LocalFileRepo
+	allClients, LocalFileRepo = []*node.ZgsClient{client}, t.TempDir()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	registerLocalRoutes(router)
+	server := httptest.NewServer(router)
+
+	t.Cleanup(func() {
+		server.Close()
+		allClients, LocalFileRepo = oldClients, oldRepo
+	})
+
+	return server, root.Hex()
+}
+
+func TestDownloadFileLocalDestinationDiffers(t *testing.T) {
+	content := make([]byte, 4*core.DefaultChunkSize)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+	server, root := newLocalServer(t, content)
+
+	filename := getFilePath("file", true)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	assert.NoError(t, os.WriteFile(filename, []byte("keep"), 0644))
+
+	download := func(force string) int {
+		form := url.Values{"root": {root}, "path": {"file"}, "force": {force}}
+		resp, err := http.Post(server.URL+"/local/download", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// not overwritten unless forced
+	assert.NotEqual(t, http.StatusOK, download("false"))
+	actual, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("keep"), actual)
+
+	assert.Equal(t, http.StatusOK, download("true"))
+	actual, err = os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, actual)
+}
+
+func TestDownloadFileLocalUploadHints(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{NumShard: 1}, shard.ShardConfig{NumShard: 1})
+	clients := network.ZgsClients()
+
+	oldClients, oldRepo, oldHints := allClients, LocalFileRepo, UploadHints
+	allClients, LocalFileRepo, UploadHints = clients, t.TempDir(), transfer.NewUploadHints(0)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	registerLocalRoutes(router)
+	server := httptest.NewServer(router)
+
+	t.Cleanup(func() {
+		server.Close()
+		allClients, LocalFileRepo, UploadHints = oldClients, oldRepo, oldHints
+	})
+
+	// upload to node A only, while node B is not synced
+	content := make([]byte, 4*core.DefaultChunkSize)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	uploader, err := transfer.NewUploader(context.Background(), network.Web3(), clients[:1])
+	assert.NoError(t, err)
+	_, hash, err := uploader.WithUploadHints(UploadHints).Upload(context.Background(), data)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{clients[0].URL()}, UploadHints.Nodes(hash))
+	root := hash.Hex()
+
+	// cold downloader against node B fails
+	downloader, err := transfer.NewDownloader(clients[1:])
+	assert.NoError(t, err)
+	assert.Error(t, downloader.Download(context.Background(), root, filepath.Join(t.TempDir(), "cold"), false))
+
+	// hinted download succeeds even if node B requested
+	assert.NoError(t, os.MkdirAll(filepath.Dir(getFilePath("hinted", true)), 0755))
+	form := url.Values{"root": {root}, "path": {"hinted"}, "node": {"1"}}
+	resp, err := http.Post(server.URL+"/local/download", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	actual, err := os.ReadFile(getFilePath("hinted", true))
+	assert.NoError(t, err)
+	assert.Equal(t, content, actual)
+}
@@ -4,24 +4,43 @@ import (
 	"github.com/0glabs/0g-storage-client/common/api"
 	"github.com/0glabs/0g-storage-client/node"
 	"github.com/gin-gonic/gin"
+	"github.com/openweb3/web3go"
 	"github.com/sirupsen/logrus"
 )
 
-var allClients []*node.ZgsClient
+var (
+	allClients []*node.ZgsClient
+	w3Client   *web3go.Client // client to submit flow transactions of uploads, nil if uploads not supported
+)
+
+// ReadOnly disables the upload APIs, so that neither blockchain client nor private key is required to serve.
+var ReadOnly bool
 
-func MustServeLocal(nodes []*node.ZgsClient) {
+// MustServeLocal serves the local APIs with the specified storage nodes, and the blockchain client to submit flow
+// transactions of uploads, which could be nil if uploads of new files are not required.
+func MustServeLocal(nodes []*node.ZgsClient, w3 *web3go.Client) {
 	if len(nodes) == 0 {
 		logrus.Fatal("storage nodes not configured")
 	}
 
-	allClients = nodes
+	if ReadOnly && w3 != nil {
+		logrus.Fatal("blockchain client not allowed in read-only mode")
+	}
+
+	allClients, w3Client = nodes, w3
 
-	api.MustServe("127.0.0.1:6789", func(router *gin.Engine) {
-		localApi := router.Group("/local")
-		localApi.GET("/nodes", api.Wrap(listNodes))
-		localApi.GET("/file", api.Wrap(getLocalFileInfo))
-		localApi.GET("/status", api.Wrap(getFileStatus))
+	api.MustServe("127.0.0.1:6789", registerLocalRoutes)
+}
+
+func registerLocalRoutes(router *gin.Engine) {
+	localApi := router.Group("/local")
+	localApi.GET("/nodes", api.Wrap(listNodes))
+	localApi.GET("/file", api.Wrap(getLocalFileInfo))
+	localApi.GET("/status", api.Wrap(getFileStatus))
+	localApi.POST("/download", api.Wrap(downloadFileLocal))
+
+	if !ReadOnly {
 		localApi.POST("/upload", api.Wrap(uploadLocalFile))
-		localApi.POST("/download", api.Wrap(downloadFileLocal))
-	})
+		localApi.POST("/upload/stream", api.Wrap(uploadStream))
+	}
 }
@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/api"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// uploadBufferSize is the size of buffer to pipe request body, which bounds the memory used by each upload.
+const uploadBufferSize = 64 * 1024
+
+// MaxUploadSize is the max size of request body to upload, which bounds the disk space taken by each upload. Uploads
+// are rejected if 0, since the request body is spooled to disk.
+var MaxUploadSize = zg_common.GiB
+
+// Tenants isolates uploads of tenants authenticated by TenantKeys, and nil means not isolated.
+var Tenants *transfer.Tenants
+
+// TenantKeys maps API keys to tenants, so that the tenant to upload on behalf of is authenticated by the API key in
+// the Authorization header with Bearer scheme rather than claimed by client. Uploads without API key are admitted
+// as the anonymous tenant, and uploads with unknown API key are rejected.
+var TenantKeys map[string]string
+
+var (
+	ErrUploadEmpty         = api.NewBusinessError(101, "Upload data is empty")
+	ErrUploadTooLarge      = api.NewBusinessError(102, "Upload data too large")
+	ErrUploadQuotaExceeded = api.NewBusinessError(103, "Upload quota of tenant exceeded")
+	ErrUploadUnsupported   = api.NewBusinessError(104, "Upload not supported without blockchain RPC configured")
+	ErrUploadUnlimited     = api.NewBusinessError(105, "Upload not supported without max upload size configured")
+)
+
+// bodyUploader uploads data to 0g storage, which is implemented by transfer.Uploader.
+type bodyUploader interface {
+	Upload(ctx context.Context, data core.IterableData, option ...transfer.UploadOption) (common.Hash, common.Hash, error)
+}
+
+// newBodyUploader creates an uploader for the specified storage node, which submits flow transactions with the
+// blockchain client of gateway.
+var newBodyUploader = func(ctx context.Context, client *node.ZgsClient) (bodyUploader, error) {
+	if w3Client == nil {
+		return nil, ErrUploadUnsupported
+	}
+
+	uploader, err := transfer.NewUploader(ctx, w3Client, []*node.ZgsClient{client}, zg_common.LogOption{Logger: logrus.StandardLogger()})
+	if err != nil {
+		return nil, err
+	}
+
+	return uploader.WithTenants(Tenants).WithUploadHints(UploadHints), nil
+}
+
+// uploadStream uploads the request body as file data.
+//
+// Since the merkle root is required before submitting the flow transaction, request body is spooled into a
+// temp file under os.TempDir with bounded buffer instead of memory, so the disk space used by concurrent uploads
+// is bounded by MaxUploadSize each, and uploads are rejected if MaxUploadSize is 0. The temp file is removed once
+// the upload completed or failed. Client disconnection cancels the request context, which aborts the upload, and the flow
+// transaction will not be sent if not yet. Besides, the upload is aborted as soon as the size limit is crossed.
+func uploadStream(c *gin.Context) (interface{}, error) {
+	var input struct {
+		Node int `form:"node" json:"node"`
+	}
+
+	if err := c.ShouldBindQuery(&input); err != nil {
+		return nil, err
+	}
+
+	if input.Node < 0 || input.Node >= len(allClients) {
+		return nil, api.ErrValidation.WithData("node index out of bound")
+	}
+
+	if MaxUploadSize == 0 {
+		return nil, ErrUploadUnlimited
+	}
+
+	if c.Request.ContentLength > int64(MaxUploadSize) {
+		return nil, ErrUploadTooLarge.WithData(uint64(MaxUploadSize))
+	}
+
+	tenant, err := authenticateTenant(c)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := transfer.WithTenant(c.Request.Context(), tenant)
+
+	filename, err := pipeToTempFile(ctx, c.Request.Body, int64(MaxUploadSize))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(filename)
+
+	file, err := core.Open(filename)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to open file")
+	}
+	defer file.Close()
+
+	uploader, err := newBodyUploader(ctx, allClients[input.Node])
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create uploader")
+	}
+
+	txHash, root, err := uploader.Upload(ctx, file)
+	if err != nil {
+		var quotaErr *transfer.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			return nil, ErrUploadQuotaExceeded.WithData(map[string]interface{}{
+				"quota":   quotaErr.Quota,
+				"used":    quotaErr.Used,
+				"resetAt": quotaErr.ResetAt,
+			})
+		}
+
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"txHash": txHash,
+		"root":   root,
+		"size":   file.Size(),
+	}, nil
+}
+
+// pipeToTempFile writes data from reader into a temp file until EOF, and returns the temp file name. It fails
+// once the context is done or the size limit, if positive, is crossed, and the temp file will be removed.
+func pipeToTempFile(ctx context.Context, reader io.Reader, limit int64) (_ string, err error) {
+	file, err := os.CreateTemp("", "zg-upload-*")
+	if err != nil {
+		return "", errors.WithMessage(err, "Failed to create temp file")
+	}
+
+	defer func() {
+		file.Close()
+		if err != nil {
+			os.Remove(file.Name())
+		}
+	}()
+
+	var written int64
+	buf := make([]byte, uploadBufferSize)
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return "", errors.WithMessage(err, "Upload aborted")
+		}
+
+		n, readErr := reader.Read(buf)
+		if n > 0 {
+			if written += int64(n); limit > 0 && written > limit {
+				return "", ErrUploadTooLarge.WithData(limit)
+			}
+
+			if _, err = file.Write(buf[:n]); err != nil {
+				return "", errors.WithMessage(err, "Failed to write temp file")
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+
+		if readErr != nil {
+			return "", errors.WithMessage(readErr, "Failed to read data")
+		}
+	}
+
+	if written == 0 {
+		return "", ErrUploadEmpty
+	}
+
+	return file.Name(), nil
+}
+
+// authenticateTenant returns the tenant of API key in the Authorization header, or empty if no API key specified.
+func authenticateTenant(c *gin.Context) (string, error) {
+	auth := c.GetHeader("Authorization")
+	if len(auth) == 0 {
+		return "", nil
+	}
+
+	key, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok {
+		return "", api.ErrAuth.WithData("Bearer scheme required")
+	}
+
+	tenant, ok := TenantKeys[key]
+	if !ok {
+		return "", api.ErrAuth.WithData("unknown API key")
+	}
+
+	return tenant, nil
+}
@@ -0,0 +1,294 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/api"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockUploader records the uploaded data, and no transaction is broadcast unless Upload is called and not blocked.
+type mockUploader struct {
+	calls     atomic.Int32
+	size      atomic.Int64
+	tenant    atomic.Value
+	broadcast atomic.Int32
+
+	// if not nil, Upload is blocked until context done, and signals this channel once entered
+	blocked chan struct{}
+}
+
+func (u *mockUploader) Upload(ctx context.Context, data core.IterableData, option ...transfer.UploadOption) (common.Hash, common.Hash, error) {
+	u.calls.Add(1)
+	u.size.Store(data.Size())
+
+	if u.blocked != nil {
+		u.blocked <- struct{}{}
+		<-ctx.Done()
+		return common.Hash{}, common.Hash{}, ctx.Err()
+	}
+
+	tenant := transfer.TenantFromContext(ctx)
+	u.tenant.Store(tenant)
+	if tenant == "exhausted" {
+		return common.Hash{}, common.Hash{}, &transfer.QuotaExceededError{Tenant: tenant, Quota: 1, ResetAt: time.Unix(86400, 0)}
+	}
+
+	u.broadcast.Add(1)
+
+	return common.Hash{1}, common.Hash{2}, nil
+}
+
+// countingReader generates data of specified size, and counts the number of bytes read.
+type countingReader struct {
+	remaining int64
+	read      atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+
+	n := int64(len(p))
+	if n > r.remaining {
+		n = r.remaining
+	}
+	r.remaining -= n
+	r.read.Add(n)
+
+	return int(n), nil
+}
+
+func newUploadStreamServer(t *testing.T, maxSize zg_common.ByteSize) (*httptest.Server, *mockUploader, chan struct{}) {
+	uploader := new(mockUploader)
+	handled := make(chan struct{}, 1)
+
+	oldClients, oldFactory, oldMaxSize := allClients, newBodyUploader, MaxUploadSize
+	allClients = make([]*node.ZgsClient, 1)
+	newBodyUploader = func(context.Context, *node.ZgsClient) (bodyUploader, error) { return uploader, nil }
+	MaxUploadSize = maxSize
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		handled <- struct{}{}
+	})
+	registerLocalRoutes(router)
+
+	server := httptest.NewServer(router)
+
+	t.Cleanup(func() {
+		server.Close()
+		allClients, newBodyUploader, MaxUploadSize = oldClients, oldFactory, oldMaxSize
+	})
+
+	return server, uploader, handled
+}
+
+func waitHandled(t *testing.T, handled chan struct{}) {
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not handled in time")
+	}
+}
+
+func TestUploadStream(t *testing.T) {
+	server, uploader, handled := newUploadStreamServer(t, zg_common.MiB)
+
+	resp, err := http.Post(server.URL+"/local/upload/stream?node=0", "application/octet-stream", strings.NewReader("hello, 0g storage"))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	waitHandled(t, handled)
+
+	var body struct {
+		Code int `json:"code"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, 0, body.Code)
+	assert.Equal(t, int32(1), uploader.calls.Load())
+	assert.Equal(t, int64(17), uploader.size.Load())
+}
+
+func TestUploadStreamClientDisconnected(t *testing.T) {
+	server, uploader, handled := newUploadStreamServer(t, zg_common.MiB)
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	assert.NoError(t, err)
+
+	// declare 1 MB body, but disconnect after sending partial data
+	header := fmt.Sprintf("POST /local/upload/stream?node=0 HTTP/1.1\r\nHost: %v\r\nContent-Length: %v\r\n\r\n", server.Listener.Addr(), 1<<20)
+	_, err = conn.Write([]byte(header))
+	assert.NoError(t, err)
+	_, err = conn.Write(bytes.Repeat([]byte{1}, 100*1024))
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+
+	waitHandled(t, handled)
+
+	// no transaction broadcast
+	assert.Equal(t, int32(0), uploader.calls.Load())
+}
+
+func TestUploadStreamClientDisconnectedDuringUpload(t *testing.T) {
+	server, uploader, handled := newUploadStreamServer(t, zg_common.MiB)
+	uploader.blocked = make(chan struct{}, 1)
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	assert.NoError(t, err)
+
+	body := "hello, 0g storage"
+	request := fmt.Sprintf("POST /local/upload/stream?node=0 HTTP/1.1\r\nHost: %v\r\nContent-Length: %v\r\n\r\n%v", server.Listener.Addr(), len(body), body)
+	_, err = conn.Write([]byte(request))
+	assert.NoError(t, err)
+
+	// disconnect once the whole body received and upload started
+	select {
+	case <-uploader.blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upload not started in time")
+	}
+	assert.NoError(t, conn.Close())
+
+	// upload aborted by request context rather than blocked forever
+	waitHandled(t, handled)
+	assert.Equal(t, int32(1), uploader.calls.Load())
+	assert.Equal(t, int32(0), uploader.broadcast.Load())
+}
+
+func TestUploadStreamUnlimited(t *testing.T) {
+	server, uploader, handled := newUploadStreamServer(t, 0)
+
+	resp, err := http.Post(server.URL+"/local/upload/stream?node=0", "application/octet-stream", strings.NewReader("hello"))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	waitHandled(t, handled)
+
+	var body struct {
+		Code int `json:"code"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, ErrUploadUnlimited.Code, body.Code)
+	assert.Equal(t, int32(0), uploader.calls.Load())
+}
+
+func TestUploadStreamTooLarge(t *testing.T) {
+	server, uploader, handled := newUploadStreamServer(t, zg_common.MiB)
+
+	// unknown content length, so that the limit is checked while streaming
+	reader := &countingReader{remaining: 64 << 20}
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/local/upload/stream?node=0", io.NopCloser(reader))
+	assert.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil {
+		var body struct {
+			Code int `json:"code"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, ErrUploadTooLarge.Code, body.Code)
+		resp.Body.Close()
+	}
+	waitHandled(t, handled)
+
+	// aborted as soon as the limit crossed rather than after reading everything
+	assert.Equal(t, int32(0), uploader.calls.Load())
+	assert.Less(t, reader.read.Load(), int64(32<<20))
+}
+
+func TestUploadStreamTenant(t *testing.T) {
+	server, uploader, handled := newUploadStreamServer(t, zg_common.MiB)
+
+	oldKeys := TenantKeys
+	TenantKeys = map[string]string{"key-a": "a", "key-exhausted": "exhausted"}
+	defer func() { TenantKeys = oldKeys }()
+
+	post := func(auth string) int {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/local/upload/stream?node=0", strings.NewReader("hello"))
+		assert.NoError(t, err)
+		if len(auth) > 0 {
+			req.Header.Set("Authorization", auth)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		waitHandled(t, handled)
+
+		var body struct {
+			Code int `json:"code"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+		return body.Code
+	}
+
+	assert.Equal(t, 0, post("Bearer key-a"))
+	assert.Equal(t, "a", uploader.tenant.Load())
+
+	assert.Equal(t, ErrUploadQuotaExceeded.Code, post("Bearer key-exhausted"))
+
+	// anonymous tenant without API key
+	assert.Equal(t, 0, post(""))
+	assert.Equal(t, "", uploader.tenant.Load())
+
+	// tenant never claimed by client
+	calls := uploader.calls.Load()
+	assert.Equal(t, api.ErrAuth.Code, post("Bearer a"))
+	assert.Equal(t, api.ErrAuth.Code, post("key-a"))
+	assert.Equal(t, calls, uploader.calls.Load())
+}
+
+func TestUploadStreamWithoutBlockchain(t *testing.T) {
+	oldClient := w3Client
+	w3Client = nil
+	defer func() { w3Client = oldClient }()
+
+	// rejected before creating uploader, which requires blockchain client to submit transactions
+	_, err := newBodyUploader(context.Background(), nil)
+	assert.Equal(t, ErrUploadUnsupported, err)
+}
+
+func TestReadOnlyLocalGateway(t *testing.T) {
+	ReadOnly = true
+	defer func() { ReadOnly = false }()
+
+	server, uploader, handled := newUploadStreamServer(t, zg_common.MiB)
+
+	var created atomic.Int32
+	newBodyUploader = func(context.Context, *node.ZgsClient) (bodyUploader, error) {
+		created.Add(1)
+		return uploader, nil
+	}
+
+	for _, path := range []string{"/local/upload?path=a.txt&node=0", "/local/upload/stream?node=0"} {
+		resp, err := http.Post(server.URL+path, "application/octet-stream", strings.NewReader("hello"))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		waitHandled(t, handled)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+
+	// no uploader created, nor transaction broadcast
+	assert.Equal(t, int32(0), created.Load())
+	assert.Equal(t, int32(0), uploader.calls.Load())
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/0glabs/0g-storage-client/common"
@@ -14,7 +16,10 @@ import (
 	"github.com/0glabs/0g-storage-client/core"
 	"github.com/0glabs/0g-storage-client/node"
 	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/0glabs/0g-storage-client/transfer/download"
 	eth_common "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	gorpc "github.com/openweb3/go-rpc-provider"
 	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
 	"github.com/openweb3/web3go"
 	"github.com/pkg/errors"
@@ -26,19 +31,58 @@ var (
 	_ Interface = (*Client)(nil)
 	// Requires `Client` implements the `IDownloader` interface.
 	_ transfer.IDownloader = (*Client)(nil)
+	// Requires `Client` implements the `ExistenceQuerier` interface.
+	_ transfer.ExistenceQuerier = (*Client)(nil)
 )
 
-// Client indexer client
+// Client indexer client, which is safe for concurrent use.
 type Client struct {
 	*rpc.Client
 	option IndexerClientOption
 	logger *logrus.Logger
+	health *nodeHealth        // health of storage nodes to upload
+	local  *shard.ShardedNode // co-located storage node, nil if not specified or failed to probe
+
+	warnings *transfer.Warnings // non-fatal issues of uploaders and downloaders created by client
 }
 
 // IndexerClientOption indexer client option
 type IndexerClientOption struct {
 	ProviderOption providers.Option
-	LogOption      common.LogOption // log option when uploading data
+	Proxy          *rpc.ProxyOption          // proxy option to connect to indexer and storage nodes, rpc.DefaultProxy if nil
+	LogOption      common.LogOption          // log option when uploading data
+	LocalNode      *LocalNodeOption          // storage node co-located to prefer for covered shards, nil to disable
+	WriteOption    download.WriteOption      // option to write downloaded files, see Downloader.WithWriteOption
+	Overwrite      bool                      // replace the existing destination file that differs, see Downloader.WithOverwrite
+	Strategy       SelectionStrategy         // strategy to select storage nodes, StrategyRandom if empty
+	UploadHints    *transfer.UploadHints     // storage nodes holding files uploaded recently to download from, nil to disable
+	OnProgress     func(p transfer.Progress) // callback to report the progress of files uploaded and downloaded, see Uploader.WithProgress
+}
+
+// serializeProgress wraps the progress callback, so that callbacks of uploaders and downloaders created on demand are
+// serialized as a whole, even for concurrent uploads or downloads.
+func (opt *IndexerClientOption) serializeProgress() {
+	if opt.OnProgress == nil {
+		return
+	}
+
+	var mu sync.Mutex
+	onProgress := opt.OnProgress
+	opt.OnProgress = func(p transfer.Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		onProgress(p)
+	}
+}
+
+// proxy returns the proxy option to connect to indexer and storage nodes.
+func (opt *IndexerClientOption) proxy() rpc.ProxyOption {
+	if opt.Proxy == nil {
+		return rpc.DefaultProxy
+	}
+
+	return *opt.Proxy
 }
 
 // NewClient create new indexer client, url is indexer service url
@@ -48,35 +92,86 @@ func NewClient(url string, option ...IndexerClientOption) (*Client, error) {
 		opt = option[0]
 	}
 
-	client, err := rpc.NewClient(url, opt.ProviderOption)
+	if err := opt.Strategy.Validate(); err != nil {
+		return nil, err
+	}
+	opt.serializeProgress()
+
+	client, err := rpc.NewClientWithProxy(url, opt.proxy(), opt.ProviderOption)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{
+	c := &Client{
 		Client: client,
 		option: opt,
 		logger: common.NewLogger(opt.LogOption),
-	}, nil
+		health: newNodeHealth(),
+
+		warnings: transfer.NewWarnings(0),
+	}
+
+	// fallback to remote nodes only if local storage node unavailable
+	if opt.LocalNode != nil {
+		if c.local, err = c.probeLocalNode(*opt.LocalNode); err != nil {
+			c.logger.WithError(err).WithField("url", opt.LocalNode.URL).Warn("Local storage node unavailable, use remote nodes only")
+		} else {
+			c.logger.WithFields(logrus.Fields{
+				"url":   c.local.URL,
+				"shard": fmt.Sprintf("%v/%v", c.local.Config.ShardId, c.local.Config.NumShard),
+			}).Info("Local storage node preferred")
+		}
+	}
+
+	return c, nil
+}
+
+// Warnings returns the non-fatal issues aggregated by uploaders and downloaders created by client, e.g. when
+// uploading or downloading files via Upload or Download.
+func (c *Client) Warnings() *transfer.Warnings {
+	return c.warnings
+}
+
+// forwardWarnings reports the warnings of uploaders and downloaders created by client to client as well.
+func (c *Client) forwardWarnings(warning transfer.Warning) {
+	c.warnings.Add(warning.Code, warning.Message, warning.Fields)
 }
 
 // GetShardedNodes get node list from indexer service
 func (c *Client) GetShardedNodes(ctx context.Context) (ShardedNodes, error) {
-	return providers.CallContext[ShardedNodes](c, ctx, "indexer_getShardedNodes")
+	return providers.CallContext[ShardedNodes](c.MiddlewarableProvider, ctx, "indexer_getShardedNodes")
 }
 
 // GetNodeLocations return storage nodes with IP location information.
 func (c *Client) GetNodeLocations(ctx context.Context) (map[string]*IPLocation, error) {
-	return providers.CallContext[map[string]*IPLocation](c, ctx, "indexer_getNodeLocations")
+	return providers.CallContext[map[string]*IPLocation](c.MiddlewarableProvider, ctx, "indexer_getNodeLocations")
 }
 
 // GetFileLocations return locations info of given file.
 func (c *Client) GetFileLocations(ctx context.Context, root string) ([]*shard.ShardedNode, error) {
-	return providers.CallContext[[]*shard.ShardedNode](c, ctx, "indexer_getFileLocations", root)
+	return providers.CallContext[[]*shard.ShardedNode](c.MiddlewarableProvider, ctx, "indexer_getFileLocations", root)
 }
 
 // SelectNodes get node list from indexer service and select a subset of it, which is sufficient to store expected number of replications.
 func (c *Client) SelectNodes(ctx context.Context, segNum uint64, expectedReplica uint, dropped []string) ([]*node.ZgsClient, error) {
+	return c.SelectNodesByRequirement(ctx, segNum, shard.UniformReplica(expectedReplica), dropped)
+}
+
+// SelectNodesByRequirement get node list from indexer service and select a subset of it, which is sufficient to store
+// the required replications of each shard. Storage nodes are selected randomly, since the file root is unknown, see
+// SelectNodesForRoot.
+func (c *Client) SelectNodesByRequirement(ctx context.Context, segNum uint64, requirement shard.ReplicaRequirement, dropped []string) ([]*node.ZgsClient, error) {
+	return c.selectNodes(ctx, requirement, dropped, nil)
+}
+
+// SelectNodesForRoot is similar to SelectNodesByRequirement, but selects storage nodes for the file root as
+// specified by IndexerClientOption.Strategy, e.g. the same subset of storage nodes for StrategyConsistent.
+func (c *Client) SelectNodesForRoot(ctx context.Context, root eth_common.Hash, requirement shard.ReplicaRequirement, dropped []string) ([]*node.ZgsClient, error) {
+	return c.selectNodes(ctx, requirement, dropped, &root)
+}
+
+// selectNodes selects storage nodes in rendezvous order of root for StrategyConsistent, otherwise randomly.
+func (c *Client) selectNodes(ctx context.Context, requirement shard.ReplicaRequirement, dropped []string, root *eth_common.Hash) ([]*node.ZgsClient, error) {
 	allNodes, err := c.GetShardedNodes(ctx)
 	if err != nil {
 		return nil, err
@@ -84,10 +179,10 @@ func (c *Client) SelectNodes(ctx context.Context, segNum uint64, expectedReplica
 	// filter out nodes unable to connect
 	nodes := make([]*shard.ShardedNode, 0)
 	for _, shardedNode := range allNodes.Trusted {
-		if slices.Contains(dropped, shardedNode.URL) {
+		if slices.Contains(dropped, shardedNode.URL) || (c.local != nil && shardedNode.URL == c.local.URL) {
 			continue
 		}
-		client, err := node.NewZgsClient(shardedNode.URL, c.option.ProviderOption)
+		client, err := node.NewZgsClientWithProxy(shardedNode.URL, c.option.proxy(), c.option.ProviderOption)
 		if err != nil {
 			c.logger.Debugf("failed to initialize client of node %v, dropped.", shardedNode.URL)
 			continue
@@ -106,14 +201,23 @@ func (c *Client) SelectNodes(ctx context.Context, segNum uint64, expectedReplica
 			Latency: time.Since(start).Milliseconds(),
 		})
 	}
-	// randomly select proper subset
-	trusted, ok := shard.Select(nodes, expectedReplica, true)
-	if !ok {
-		return nil, fmt.Errorf("cannot select a subset from the returned nodes that meets the replication requirement")
+	// local storage node selected at first if available, and supplemented by a subset of the others
+	var preferred []*shard.ShardedNode
+	if local := c.localNode(ctx, dropped); local != nil {
+		preferred = append(preferred, local)
+	}
+	var trusted []*shard.ShardedNode
+	if c.option.Strategy == StrategyConsistent && root != nil {
+		trusted, err = shard.SelectConsistent(*root, preferred, nodes, requirement)
+	} else {
+		trusted, err = shard.SelectPreferred(preferred, nodes, requirement, true)
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot select a subset from the returned nodes that meets the replication requirement")
 	}
 	clients := make([]*node.ZgsClient, len(trusted))
 	for i, shardedNode := range trusted {
-		clients[i], err = node.NewZgsClient(shardedNode.URL, c.option.ProviderOption)
+		clients[i], err = node.NewZgsClientWithProxy(shardedNode.URL, c.option.proxy(), c.option.ProviderOption)
 		if err != nil {
 			return nil, errors.WithMessage(err, fmt.Sprintf("failed to initialize storage node client with %v", shardedNode.URL))
 		}
@@ -123,7 +227,18 @@ func (c *Client) SelectNodes(ctx context.Context, segNum uint64, expectedReplica
 
 // NewUploaderFromIndexerNodes return an uploader with selected storage nodes from indexer service.
 func (c *Client) NewUploaderFromIndexerNodes(ctx context.Context, segNum uint64, w3Client *web3go.Client, expectedReplica uint, dropped []string) (*transfer.Uploader, error) {
-	clients, err := c.SelectNodes(ctx, segNum, expectedReplica, dropped)
+	return c.NewUploaderByRequirement(ctx, segNum, w3Client, shard.UniformReplica(expectedReplica), dropped)
+}
+
+// NewUploaderByRequirement return an uploader with storage nodes selected from indexer service, which is sufficient
+// to store the required replications of each shard.
+func (c *Client) NewUploaderByRequirement(ctx context.Context, segNum uint64, w3Client *web3go.Client, requirement shard.ReplicaRequirement, dropped []string) (*transfer.Uploader, error) {
+	return c.newUploader(ctx, w3Client, requirement, dropped, nil)
+}
+
+// newUploader returns an uploader with storage nodes selected for the root if specified, see selectNodes.
+func (c *Client) newUploader(ctx context.Context, w3Client *web3go.Client, requirement shard.ReplicaRequirement, dropped []string, root *eth_common.Hash) (*transfer.Uploader, error) {
+	clients, err := c.selectNodes(ctx, requirement, dropped, root)
 	if err != nil {
 		return nil, err
 	}
@@ -132,27 +247,36 @@ func (c *Client) NewUploaderFromIndexerNodes(ctx context.Context, segNum uint64,
 		urls[i] = client.URL()
 	}
 	c.logger.Infof("get %v storage nodes from indexer: %v", len(urls), urls)
-	return transfer.NewUploader(ctx, w3Client, clients, c.option.LogOption)
+	uploader, err := transfer.NewUploader(ctx, w3Client, clients, c.option.LogOption)
+	if err != nil {
+		closeClients(clients)
+		return nil, err
+	}
+
+	// storage node clients are created by indexer client, and owned by uploader
+	return uploader.WithClientsOwned(true).WithUploadHints(c.option.UploadHints).WithProgress(c.option.OnProgress).WithWarningSink(c.forwardWarnings), nil
 }
 
 // Upload submit data to 0g storage contract, then transfer the data to the storage nodes selected from indexer service.
 func (c *Client) Upload(ctx context.Context, w3Client *web3go.Client, data core.IterableData, option ...transfer.UploadOption) (eth_common.Hash, error) {
-	expectedReplica := uint(1)
+	requirement := shard.UniformReplica(1)
 	if len(option) > 0 {
-		expectedReplica = max(expectedReplica, option[0].ExpectedReplica)
+		requirement = requirement.Merge(option[0].ReplicaRequirement())
+	}
+	root, err := c.selectionKey(data)
+	if err != nil {
+		return eth_common.Hash{}, err
 	}
-	dropped := make([]string, 0)
+	dropped := c.health.quarantinedNodes()
 	for {
-		uploader, err := c.NewUploaderFromIndexerNodes(ctx, data.NumSegments(), w3Client, expectedReplica, dropped)
+		uploader, err := c.newUploader(ctx, w3Client, requirement, dropped, root)
 		if err != nil {
 			return eth_common.Hash{}, err
 		}
 		txHash, _, err := uploader.Upload(ctx, data, option...)
-		var rpcError *node.RPCError
-		if errors.As(err, &rpcError) {
-			dropped = append(dropped, rpcError.URL)
-			c.logger.Infof("dropped problematic node and retry: %v", rpcError.Error())
-		} else {
+		uploader.Close()
+		var retry bool
+		if dropped, retry = c.dropOnUploadFailure(err, dropped); !retry {
 			return txHash, err
 		}
 	}
@@ -160,28 +284,26 @@ func (c *Client) Upload(ctx context.Context, w3Client *web3go.Client, data core.
 
 // BatchUpload submit multiple data to 0g storage contract batchly in single on-chain transaction, then transfer the data to the storage nodes selected from indexer service.
 func (c *Client) BatchUpload(ctx context.Context, w3Client *web3go.Client, datas []core.IterableData, option ...transfer.BatchUploadOption) (eth_common.Hash, []eth_common.Hash, error) {
-	expectedReplica := uint(1)
+	requirement := shard.UniformReplica(1)
 	if len(option) > 0 {
 		for _, opt := range option[0].DataOptions {
-			expectedReplica = max(expectedReplica, opt.ExpectedReplica)
+			requirement = requirement.Merge(opt.ReplicaRequirement())
 		}
 	}
-	var maxSegNum uint64
-	for _, data := range datas {
-		maxSegNum = max(maxSegNum, data.NumSegments())
+	root, err := c.selectionKey(datas...)
+	if err != nil {
+		return eth_common.Hash{}, nil, err
 	}
-	dropped := make([]string, 0)
+	dropped := c.health.quarantinedNodes()
 	for {
-		uploader, err := c.NewUploaderFromIndexerNodes(ctx, maxSegNum, w3Client, expectedReplica, dropped)
+		uploader, err := c.newUploader(ctx, w3Client, requirement, dropped, root)
 		if err != nil {
 			return eth_common.Hash{}, nil, err
 		}
 		hash, roots, err := uploader.BatchUpload(ctx, datas, option...)
-		var rpcError *node.RPCError
-		if errors.As(err, &rpcError) {
-			dropped = append(dropped, rpcError.URL)
-			c.logger.Infof("dropped problematic node and retry: %v", rpcError.Error())
-		} else {
+		uploader.Close()
+		var retry bool
+		if dropped, retry = c.dropOnUploadFailure(err, dropped); !retry {
 			return hash, roots, err
 		}
 	}
@@ -190,8 +312,13 @@ func (c *Client) BatchUpload(ctx context.Context, w3Client *web3go.Client, datas
 // NewUploaderFromIndexerNodes return a file segment uploader with selected storage nodes from indexer service.
 func (c *Client) NewFileSegmentUploaderFromIndexerNodes(
 	ctx context.Context, segNum uint64, expectedReplica uint, dropped []string) (*transfer.FileSegmentUploader, error) {
+	return c.newFileSegmentUploader(ctx, shard.UniformReplica(expectedReplica), dropped, nil)
+}
 
-	clients, err := c.SelectNodes(ctx, segNum, expectedReplica, dropped)
+func (c *Client) newFileSegmentUploader(
+	ctx context.Context, requirement shard.ReplicaRequirement, dropped []string, root *eth_common.Hash) (*transfer.FileSegmentUploader, error) {
+
+	clients, err := c.selectNodes(ctx, requirement, dropped, root)
 	if err != nil {
 		return nil, err
 	}
@@ -216,37 +343,126 @@ func (c *Client) UploadFileSegments(
 		return errors.New("segment data is empty")
 	}
 
-	expectedReplica := uint(1)
+	requirement := shard.UniformReplica(1)
 	if len(option) > 0 {
-		expectedReplica = max(expectedReplica, option[0].ExpectedReplica)
+		requirement = requirement.Merge(option[0].ReplicaRequirement())
 	}
 
-	numSeg := core.NumSplits(int64(fileSeg.FileInfo.Tx.Size), core.DefaultSegmentSize)
-	dropped := make([]string, 0)
+	root := fileSeg.FileInfo.Tx.DataMerkleRoot
+	dropped := c.health.quarantinedNodes()
 	for {
-		uploader, err := c.NewFileSegmentUploaderFromIndexerNodes(ctx, numSeg, expectedReplica, dropped)
+		uploader, err := c.newFileSegmentUploader(ctx, requirement, dropped, &root)
 		if err != nil {
 			return err
 		}
 
 		var rpcError *node.RPCError
 		if err := uploader.Upload(ctx, fileSeg, option...); errors.As(err, &rpcError) {
-			dropped = append(dropped, rpcError.URL)
-			c.logger.Infof("dropped problematic node and retry: %v", rpcError.Error())
+			dropped = c.dropOnFailure(rpcError, dropped)
 		} else {
 			return err
 		}
 	}
 }
 
+// selectionKey returns the key to select storage nodes for StrategyConsistent, which is the merkle root of data,
+// or the hash of merkle roots for multiple data, and nil for other strategies. Note, data is hashed once more.
+func (c *Client) selectionKey(datas ...core.IterableData) (*eth_common.Hash, error) {
+	if c.option.Strategy != StrategyConsistent || len(datas) == 0 {
+		return nil, nil
+	}
+
+	roots := make([][]byte, len(datas))
+	for i, data := range datas {
+		root, err := core.MerkleRootData(data)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to calculate merkle root to select storage nodes")
+		}
+		roots[i] = root.Bytes()
+	}
+
+	key := eth_common.BytesToHash(roots[0])
+	if len(roots) > 1 {
+		key = crypto.Keccak256Hash(roots...)
+	}
+
+	return &key, nil
+}
+
+// dropOnUploadFailure reports the RPC failures of storage nodes that failed to upload, i.e. all the storage nodes of
+// transfer.NodeErrors, and returns the storage nodes to drop and whether to retry.
+func (c *Client) dropOnUploadFailure(err error, dropped []string) ([]string, bool) {
+	var nodeErrors transfer.NodeErrors
+	if errors.As(err, &nodeErrors) {
+		var retry bool
+		for _, nodeError := range nodeErrors {
+			var rpcError *node.RPCError
+			if errors.As(nodeError.Err, &rpcError) {
+				dropped, retry = c.dropOnFailure(rpcError, dropped), true
+			}
+		}
+
+		return dropped, retry
+	}
+
+	var rpcError *node.RPCError
+	if errors.As(err, &rpcError) {
+		return c.dropOnFailure(rpcError, dropped), true
+	}
+
+	return dropped, false
+}
+
+// dropOnFailure reports the RPC failure of storage node to health scorer, and returns the storage nodes to drop
+// for retry, which always includes the failed node, so that the operation never retries on a node that failed it.
+// Besides, transport errors quarantine the node for later operations at once, while application rejections
+// quarantine the node after several failures.
+func (c *Client) dropOnFailure(rpcError *node.RPCError, dropped []string) []string {
+	if c.health.report(rpcError) {
+		c.logger.Infof("quarantined problematic node: %v", rpcError.URL)
+	}
+
+	if rpcError.IsTransport() {
+		c.logger.Infof("dropped problematic node on %v error and retry: %v", rpcError.Transport.Kind, rpcError.Error())
+	} else {
+		c.logger.Infof("dropped node on rejection and retry: %v", rpcError.Error())
+	}
+
+	if slices.Contains(dropped, rpcError.URL) {
+		return dropped
+	}
+
+	return append(dropped, rpcError.URL)
+}
+
 func (c *Client) NewDownloaderFromIndexerNodes(ctx context.Context, root string) (*transfer.Downloader, error) {
+	// download from storage nodes uploaded to recently, which may not be located by indexer yet
+	if clients := c.hintedClients(ctx, eth_common.HexToHash(root)); len(clients) > 0 {
+		return c.newDownloader(clients, "")
+	}
+
 	locations, err := c.GetFileLocations(ctx, root)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed to get file locations")
 	}
+	if len(locations) > 0 {
+		// file located by indexer, and hint is not required any more
+		c.option.UploadHints.Forget(eth_common.HexToHash(root))
+	}
 	clients := make([]*node.ZgsClient, 0)
+
+	// download from local storage node at first if it holds the file
+	var preferred string
+	if client := c.localClientHolding(ctx, root); client != nil {
+		preferred = client.URL()
+		clients = append(clients, client)
+	}
+
 	for _, location := range locations {
-		client, err := node.NewZgsClient(location.URL, c.option.ProviderOption)
+		if location.URL == preferred {
+			continue
+		}
+		client, err := node.NewZgsClientWithProxy(location.URL, c.option.proxy(), c.option.ProviderOption)
 		if err != nil {
 			c.logger.Debugf("failed to initialize client of node %v, dropped.", location.URL)
 			continue
@@ -254,6 +470,7 @@ func (c *Client) NewDownloaderFromIndexerNodes(ctx context.Context, root string)
 		config, err := client.GetShardConfig(ctx)
 		if err != nil || !config.IsValid() {
 			c.logger.Debugf("failed to get shard config of node %v, dropped.", client.URL())
+			client.Close()
 			continue
 		}
 		clients = append(clients, client)
@@ -261,18 +478,51 @@ func (c *Client) NewDownloaderFromIndexerNodes(ctx context.Context, root string)
 	if len(clients) == 0 {
 		return nil, fmt.Errorf("no node holding the file found, FindFile triggered, try again later")
 	}
+
+	return c.newDownloader(clients, preferred)
+}
+
+// newDownloader creates a downloader that owns the storage node clients, and downloads from the preferred storage node
+// at first if specified.
+func (c *Client) newDownloader(clients []*node.ZgsClient, preferred string) (*transfer.Downloader, error) {
 	downloader, err := transfer.NewDownloader(clients, c.option.LogOption)
 	if err != nil {
+		closeClients(clients)
 		return nil, err
 	}
+	downloader.WithConsistentNodes(c.option.Strategy == StrategyConsistent)
 
-	return downloader, nil
+	// storage node clients are created by indexer client, and owned by downloader
+	return downloader.WithClientsOwned(true).WithPreferred(preferred).WithWriteOption(c.option.WriteOption).WithOverwrite(c.option.Overwrite).WithProgress(c.option.OnProgress).WithWarningSink(c.forwardWarnings), nil
+}
+
+// hintedClients returns clients of the storage nodes known to hold the file of root uploaded recently, see
+// transfer.UploadHints, or nil if not hinted or none of them holds the file any more.
+func (c *Client) hintedClients(ctx context.Context, root eth_common.Hash) []*node.ZgsClient {
+	var clients []*node.ZgsClient
+	for _, url := range c.option.UploadHints.Nodes(root) {
+		client, err := node.NewZgsClientWithProxy(url, c.option.proxy(), c.option.ProviderOption)
+		if err != nil {
+			c.logger.Debugf("failed to initialize client of hinted node %v, dropped.", url)
+			continue
+		}
+
+		if info, err := client.GetFileInfo(ctx, root); err != nil || info == nil {
+			c.logger.Debugf("file not found on hinted node %v, dropped.", url)
+			client.Close()
+			continue
+		}
+
+		clients = append(clients, client)
+	}
+
+	return clients
 }
 
 func (c *Client) DownloadFragments(ctx context.Context, roots []string, filename string, withProof bool) error {
-	outFile, err := os.Create(filename)
+	outFile, err := transfer.CreateFragmentsFile(filename, c.option.Overwrite)
 	if err != nil {
-		return errors.WithMessage(err, "failed to create output file")
+		return err
 	}
 	defer outFile.Close()
 
@@ -283,6 +533,7 @@ func (c *Client) DownloadFragments(ctx context.Context, roots []string, filename
 			return err
 		}
 		err = downloader.Download(ctx, root, tempFile, withProof)
+		downloader.Close()
 		if err != nil {
 			return errors.WithMessage(err, "Failed to download file")
 		}
@@ -311,5 +562,61 @@ func (c *Client) Download(ctx context.Context, root, filename string, withProof
 	if err != nil {
 		return err
 	}
+	defer downloader.Close()
+
 	return downloader.Download(ctx, root, filename, withProof)
 }
+
+// ForEachNode implements the transfer.NodeDownloaders interface, which tries the storage nodes holding the file of
+// root in turn, so that the directory manifest is fetched again from alternate storage nodes once mismatched.
+func (c *Client) ForEachNode(ctx context.Context, root string, fn func(url string, downloader transfer.IDownloader) bool) error {
+	downloader, err := c.NewDownloaderFromIndexerNodes(ctx, root)
+	if err != nil {
+		return err
+	}
+	defer downloader.Close()
+
+	return downloader.ForEachNode(ctx, root, fn)
+}
+
+// QueryExistence implements the transfer.ExistenceQuerier interface, which queries file locations in batch.
+// File is finalized if located on any storage node.
+func (c *Client) QueryExistence(ctx context.Context, roots []eth_common.Hash) ([]transfer.FileExistence, error) {
+	locations := make([][]*shard.ShardedNode, len(roots))
+	batch := make([]gorpc.BatchElem, len(roots))
+	for i, root := range roots {
+		batch[i] = gorpc.BatchElem{
+			Method: "indexer_getFileLocations",
+			Args:   []interface{}{root.Hex()},
+			Result: &locations[i],
+		}
+	}
+
+	if err := c.MiddlewarableProvider.BatchCallContext(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	result := make([]transfer.FileExistence, len(roots))
+	for i := range batch {
+		result[i].Root = roots[i]
+
+		if err := batch[i].Error; err != nil {
+			// file not found on any trusted storage node
+			if !strings.Contains(err.Error(), "file not found") {
+				result[i].Err = err
+			}
+			continue
+		}
+
+		result[i].Exists = true
+		result[i].Finalized = len(locations[i]) > 0
+	}
+
+	return result, nil
+}
+
+func closeClients(clients []*node.ZgsClient) {
+	for _, client := range clients {
+		client.Close()
+	}
+}
@@ -0,0 +1,35 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestClientClose(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	client, err := NewClient("http://127.0.0.1:12345")
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.Close())
+	assert.NoError(t, client.Close())
+}
+
+func TestClientWarnings(t *testing.T) {
+	client, err := NewClient("http://127.0.0.1:12345")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	// warnings of downloaders created on demand are reported by client
+	clients := node.MustNewZgsClients([]string{"http://127.0.0.1:12346"})
+	downloader, err := client.newDownloader(clients, "")
+	assert.NoError(t, err)
+	defer downloader.Close()
+
+	downloader.Warnings().Add(transfer.WarningSegmentRerouted, "test", nil)
+	assert.True(t, client.Warnings().Has(transfer.WarningSegmentRerouted))
+}
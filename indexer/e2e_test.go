@@ -0,0 +1,50 @@
+package indexer
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadDownloadE2E(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{ShardId: 0, NumShard: 2}, shard.ShardConfig{ShardId: 1, NumShard: 2})
+	client, err := NewClient(network.IndexerURL)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	content := make([]byte, 3*core.DefaultSegmentSize)
+	_, err = rand.Read(content)
+	assert.NoError(t, err)
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(t, err)
+
+	_, err = client.Upload(context.Background(), network.Web3(), data)
+	assert.NoError(t, err)
+
+	// located on storage nodes of all shards
+	locations, err := client.GetFileLocations(context.Background(), tree.Root().Hex())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(locations))
+
+	filename := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, client.Download(context.Background(), tree.Root().Hex(), filename, true))
+	downloaded, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+
+	existence, err := client.QueryExistence(context.Background(), []common.Hash{tree.Root(), common.HexToHash("0x01")})
+	assert.NoError(t, err)
+	assert.True(t, existence[0].Finalized)
+	assert.False(t, existence[1].Exists)
+	assert.NoError(t, existence[1].Err)
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/shard"
 	"github.com/0glabs/0g-storage-client/core"
 	"github.com/0glabs/0g-storage-client/node"
@@ -25,6 +26,14 @@ type FileLocationCacheConfig struct {
 	DiscoveryPorts []int
 }
 
+// Validate checks the config, and returns an error that names the invalid field if any.
+func (config *FileLocationCacheConfig) Validate() error {
+	return zg_common.FirstError(
+		zg_common.RequirePositive("CacheSize", config.CacheSize),
+		zg_common.RequirePositive("Expiry", config.Expiry),
+	)
+}
+
 type successCall struct {
 	node *shard.ShardedNode
 	ts   time.Time
@@ -42,6 +51,10 @@ type FileLocationCache struct {
 var defaultFileLocationCache FileLocationCache
 
 func InitFileLocationCache(config FileLocationCacheConfig) (cache *FileLocationCache, err error) {
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
+
 	if len(config.DiscoveryNode) > 0 {
 		if defaultFileLocationCache.discoverNode, err = node.NewAdminClient(config.DiscoveryNode, defaultZgsClientOpt); err != nil {
 			return nil, errors.WithMessage(err, "Failed to create admin client to discover peers")
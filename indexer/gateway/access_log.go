@@ -0,0 +1,246 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	mathrand "math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/api"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader is the request header of request ID, which is generated if not specified by client or upstream
+// proxy, and always echoed in response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the max length of request ID specified by client, and longer ones are replaced.
+const maxRequestIDLength = 128
+
+const (
+	contextKeyRequestID = "gateway.requestID"
+	contextKeyRoot      = "gateway.root"
+	contextKeyCacheHit  = "gateway.cacheHit"
+)
+
+// AccessLogConfig is the config of access logs, which is disabled by default. Failed requests, including business
+// errors responded with HTTP 200, are always logged, while successful requests are sampled by SampleRate.
+type AccessLogConfig struct {
+	Enabled    bool
+	SampleRate float64 // fraction of successful requests logged in [0, 1], 0 to log failed requests only
+
+	// Truncates client IP to /24 for IPv4 and /48 for IPv6 in logs.
+	TruncateIP bool
+
+	// Request header of the auth principal set by upstream proxy, e.g. X-Forwarded-User, not logged if empty.
+	PrincipalHeader string
+
+	// Logs the salted SHA-256 hash of auth principal instead of plain text, so that requests of the same principal
+	// could be correlated without revealing who.
+	HashPrincipal bool
+	PrincipalSalt string
+}
+
+// Validate checks the config, and returns an error that names the invalid field if any.
+func (config AccessLogConfig) Validate() error {
+	if err := zg_common.RequireNonNegative("SampleRate", config.SampleRate); err != nil {
+		return err
+	}
+
+	if config.SampleRate > 1 {
+		return zg_common.NewOptionError("SampleRate", "should not be greater than 1, got %v", config.SampleRate)
+	}
+
+	return nil
+}
+
+// accessLogger logs requests by config.
+type accessLogger struct {
+	config AccessLogConfig
+	logger *logrus.Logger
+
+	mu     sync.Mutex
+	random func() float64 // random number in [0, 1) to sample requests
+}
+
+func newAccessLogger(config AccessLogConfig, logger *logrus.Logger) *accessLogger {
+	return &accessLogger{
+		config: config,
+		logger: logger,
+		random: mathrand.New(mathrand.NewSource(time.Now().UnixNano())).Float64,
+	}
+}
+
+// sampled returns whether to log the request of specified outcome.
+func (al *accessLogger) sampled(failed bool) bool {
+	if failed || al.config.SampleRate >= 1 {
+		return true
+	}
+
+	if al.config.SampleRate <= 0 {
+		return false
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	return al.random() < al.config.SampleRate
+}
+
+// clientIP returns the client IP, which is truncated if configured.
+func (al *accessLogger) clientIP(ip string) string {
+	if !al.config.TruncateIP {
+		return ip
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// principal returns the auth principal of request, which is hashed if configured.
+func (al *accessLogger) principal(header http.Header) string {
+	if len(al.config.PrincipalHeader) == 0 {
+		return ""
+	}
+
+	principal := header.Get(al.config.PrincipalHeader)
+	if len(principal) == 0 || !al.config.HashPrincipal {
+		return principal
+	}
+
+	hash := sha256.Sum256([]byte(al.config.PrincipalSalt + principal))
+
+	return hex.EncodeToString(hash[:8])
+}
+
+// middleware logs requests once handled.
+func (al *accessLogger) middleware(c *gin.Context) {
+	start := time.Now()
+
+	c.Next()
+
+	status := c.Writer.Status()
+	apiErr := api.ErrorOf(c)
+	if !al.sampled(status >= http.StatusBadRequest || apiErr != nil) {
+		return
+	}
+
+	fields := logrus.Fields{
+		"requestId": requestIDOf(c),
+		"method":    c.Request.Method,
+		"path":      c.Request.URL.Path,
+		"status":    status,
+		"bytes":     max(c.Writer.Size(), 0),
+		"duration":  time.Since(start),
+		"clientIp":  al.clientIP(c.ClientIP()),
+	}
+
+	if hit, ok := c.Get(contextKeyCacheHit); ok {
+		fields["cacheHit"] = hit
+	}
+
+	if root := c.GetString(contextKeyRoot); len(root) > 0 {
+		fields["root"] = root
+	}
+
+	if principal := al.principal(c.Request.Header); len(principal) > 0 {
+		fields["principal"] = principal
+	}
+
+	entry := al.logger.WithFields(fields)
+	if apiErr != nil {
+		entry.WithError(apiErr).Info("Gateway request failed")
+	} else {
+		entry.Info("Gateway request served")
+	}
+}
+
+// requestIDMiddleware propagates the request ID of client or upstream proxy, or generates a new one, which is
+// echoed in response and attached to transfer logs of the request, see transferLogger.
+func requestIDMiddleware(c *gin.Context) {
+	id := c.GetHeader(RequestIDHeader)
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		id = newRequestID()
+	}
+
+	c.Set(contextKeyRequestID, id)
+	c.Header(RequestIDHeader, id)
+
+	c.Next()
+}
+
+func newRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// requestIDOf returns the request ID of request.
+func requestIDOf(c *gin.Context) string {
+	return c.GetString(contextKeyRequestID)
+}
+
+// setAccessLogRoot records the root of file or directory served in access log, where the root of directory is
+// retained for files requested by path within the directory.
+func setAccessLogRoot(c *gin.Context, root string) {
+	if _, ok := c.Get(contextKeyRoot); !ok {
+		c.Set(contextKeyRoot, root)
+	}
+}
+
+// markCache records in access log whether the request is served from cache if ctx is the request context, where
+// the request is a cache hit only if no cache missed.
+func markCache(ctx context.Context, hit bool) {
+	c, ok := ctx.(*gin.Context)
+	if !ok {
+		return
+	}
+
+	if _, marked := c.Get(contextKeyCacheHit); !hit || !marked {
+		c.Set(contextKeyCacheHit, hit)
+	}
+}
+
+// transferLogger returns the logger of transfers on behalf of the request, which attaches the request ID to logs,
+// so that they could be linked to the access log. Only warnings and errors are logged unless debug enabled.
+func transferLogger(c *gin.Context) *logrus.Logger {
+	base := logrus.StandardLogger()
+
+	logger := logrus.New()
+	logger.SetOutput(base.Out)
+	logger.SetFormatter(base.Formatter)
+	logger.SetLevel(base.GetLevel())
+	if !base.IsLevelEnabled(logrus.DebugLevel) && base.IsLevelEnabled(logrus.InfoLevel) {
+		logger.SetLevel(logrus.WarnLevel)
+	}
+	logger.AddHook(requestIDHook(requestIDOf(c)))
+
+	return logger
+}
+
+// requestIDHook attaches the request ID to all log entries.
+type requestIDHook string
+
+func (hook requestIDHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (hook requestIDHook) Fire(entry *logrus.Entry) error {
+	entry.Data["requestId"] = string(hook)
+	return nil
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLogSampling(t *testing.T) {
+	randoms := []float64{0.1, 0.7, 0.4, 0.9}
+	al := newAccessLogger(AccessLogConfig{Enabled: true, SampleRate: 0.5}, logrus.New())
+	al.random = func() float64 {
+		r := randoms[0]
+		randoms = randoms[1:]
+		return r
+	}
+
+	// successful requests sampled, while failed requests always logged
+	assert.True(t, al.sampled(false))
+	assert.False(t, al.sampled(false))
+	assert.True(t, al.sampled(true))
+	assert.True(t, al.sampled(false))
+	assert.False(t, al.sampled(false))
+	assert.Empty(t, randoms)
+
+	al = newAccessLogger(AccessLogConfig{Enabled: true}, logrus.New())
+	assert.False(t, al.sampled(false))
+	assert.True(t, al.sampled(true))
+
+	al = newAccessLogger(AccessLogConfig{Enabled: true, SampleRate: 1}, logrus.New())
+	assert.True(t, al.sampled(false))
+
+	assert.Error(t, AccessLogConfig{SampleRate: 1.5}.Validate())
+	assert.Error(t, AccessLogConfig{SampleRate: -0.1}.Validate())
+	assert.NoError(t, AccessLogConfig{SampleRate: 0.1}.Validate())
+}
+
+func TestAccessLogRedaction(t *testing.T) {
+	al := newAccessLogger(AccessLogConfig{}, logrus.New())
+	assert.Equal(t, "192.168.1.23", al.clientIP("192.168.1.23"))
+	assert.Empty(t, al.principal(http.Header{"X-Forwarded-User": {"alice"}}))
+
+	al = newAccessLogger(AccessLogConfig{TruncateIP: true, PrincipalHeader: "X-Forwarded-User"}, logrus.New())
+	assert.Equal(t, "192.168.1.0", al.clientIP("192.168.1.23"))
+	assert.Equal(t, "2001:db8:1::", al.clientIP("2001:db8:1:2:3:4:5:6"))
+	assert.Empty(t, al.clientIP("invalid"))
+	assert.Equal(t, "alice", al.principal(http.Header{"X-Forwarded-User": {"alice"}}))
+	assert.Empty(t, al.principal(http.Header{}))
+
+	// hashed with salt, and stable for correlation
+	al.config.HashPrincipal = true
+	hashed := al.principal(http.Header{"X-Forwarded-User": {"alice"}})
+	assert.Len(t, hashed, 16)
+	assert.NotContains(t, hashed, "alice")
+	assert.Equal(t, hashed, al.principal(http.Header{"X-Forwarded-User": {"alice"}}))
+	assert.NotEqual(t, hashed, al.principal(http.Header{"X-Forwarded-User": {"bob"}}))
+
+	al.config.PrincipalSalt = "salt"
+	assert.NotEqual(t, hashed, al.principal(http.Header{"X-Forwarded-User": {"alice"}}))
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	hook := test.NewLocal(logrus.StandardLogger())
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	router := newTestRouter(Config{AccessLog: AccessLogConfig{Enabled: true, TruncateIP: true}})
+
+	// successful request not sampled, but request ID echoed
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/capabilities", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Len(t, recorder.Header().Get(RequestIDHeader), 32)
+	assert.Empty(t, hook.AllEntries())
+
+	// failed request with business error always logged along with request ID of client
+	request := httptest.NewRequest(http.MethodGet, "/file", nil)
+	request.Header.Set(RequestIDHeader, "req-1")
+	request.RemoteAddr = "10.1.2.3:4567"
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "req-1", recorder.Header().Get(RequestIDHeader))
+
+	entry := hook.LastEntry()
+	assert.NotNil(t, entry)
+	assert.Equal(t, "req-1", entry.Data["requestId"])
+	assert.Equal(t, "/file", entry.Data["path"])
+	assert.Equal(t, "10.1.2.0", entry.Data["clientIp"])
+	assert.Positive(t, entry.Data["bytes"])
+	assert.NotNil(t, entry.Data[logrus.ErrorKey])
+
+}
+
+func TestTransferLogger(t *testing.T) {
+	var buf bytes.Buffer
+	out := logrus.StandardLogger().Out
+	logrus.SetOutput(&buf)
+	defer logrus.SetOutput(out)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(contextKeyRequestID, "req-2")
+
+	// transfer logs linked by request ID, where info logs suppressed unless debug enabled
+	logger := transferLogger(c)
+	logger.Info("Transfer info")
+	logger.Warn("Transfer warning")
+	assert.NotContains(t, buf.String(), "Transfer info")
+	assert.Contains(t, buf.String(), "Transfer warning")
+	assert.Contains(t, buf.String(), "requestId=req-2")
+}
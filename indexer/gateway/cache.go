@@ -0,0 +1,340 @@
+package gateway
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/api"
+	eth_common "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultCacheTTL          = 10 * time.Minute
+	defaultCacheRefreshLimit = time.Minute // timeout to refresh stale entry in background
+)
+
+// CacheConfig is the config of read-through cache for manifests and small files.
+type CacheConfig struct {
+	MaxSize     common.ByteSize // max total size of cached data, 0 to disable cache
+	MaxItemSize common.ByteSize // max size of a single file to cache, default MaxSize/64
+	TTL         time.Duration   // duration that cached manifests are fresh, after which served stale and refreshed in background, default 10 minutes
+}
+
+func (config CacheConfig) maxItemSize() int64 {
+	if config.MaxItemSize > 0 {
+		return int64(config.MaxItemSize)
+	}
+
+	return int64(config.MaxSize) / 64
+}
+
+func (config CacheConfig) ttl() time.Duration {
+	if config.TTL > 0 {
+		return config.TTL
+	}
+
+	return defaultCacheTTL
+}
+
+// CacheStats is the statistics of cache.
+type CacheStats struct {
+	Hits      uint64  `json:"hits"`
+	StaleHits uint64  `json:"staleHits"` // hits served stale and refreshed in background, included in hits
+	Misses    uint64  `json:"misses"`
+	HitRate   float64 `json:"hitRate"`
+	Evictions uint64  `json:"evictions"`
+	Items     int     `json:"items"`
+	Size      int64   `json:"size"`
+	MaxSize   int64   `json:"maxSize"`
+}
+
+// cacheLoader loads the value to cache, along with the root that value belongs to and the estimated size.
+type cacheLoader func(ctx context.Context) (value interface{}, root string, size int64, err error)
+
+type cacheEntry struct {
+	key        string
+	root       string // normalized root hash that value belongs to
+	value      interface{}
+	size       int64
+	expiry     time.Time // zero for never stale
+	refreshing bool
+}
+
+// contentCache is a size-based LRU cache of immutable content by root, which is safe for concurrent use. Note, all
+// methods are available on a nil cache, which caches nothing.
+type contentCache struct {
+	config CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // key -> *cacheEntry
+	lru     *list.List               // front is the most recently used
+	size    int64
+	stats   CacheStats
+}
+
+// newContentCache creates a cache with the specified config, or returns nil if cache disabled.
+func newContentCache(config CacheConfig) *contentCache {
+	if config.MaxSize == 0 {
+		return nil
+	}
+
+	return &contentCache{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns the cached value if any, and whether the value is stale.
+func (cache *contentCache) get(key string) (value interface{}, stale, ok bool) {
+	if cache == nil {
+		return nil, false, false
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	elem, ok := cache.entries[key]
+	if !ok {
+		cache.stats.Misses++
+		return nil, false, false
+	}
+
+	cache.lru.MoveToFront(elem)
+	entry := elem.Value.(*cacheEntry)
+
+	cache.stats.Hits++
+	stale = !entry.expiry.IsZero() && time.Now().After(entry.expiry)
+	if stale {
+		cache.stats.StaleHits++
+	}
+
+	return entry.value, stale, true
+}
+
+// set caches the value of specified root and size, which never becomes stale if ttl is 0. Returns false if the
+// value is too large to cache.
+//
+// If the key re-resolves to a new root, e.g. manifest by tx seq refreshed, files resolved by path within the
+// previous root are invalidated.
+func (cache *contentCache) set(key, root string, value interface{}, size int64, ttl time.Duration) bool {
+	if cache == nil || size > cache.config.maxItemSize() {
+		return false
+	}
+
+	root = normalizeRoot(root)
+
+	var expiry time.Time
+	if ttl > 0 {
+		expiry = time.Now().Add(ttl)
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if elem, ok := cache.entries[key]; ok {
+		if previous := elem.Value.(*cacheEntry).root; previous != root {
+			cache.removeLocked(func(entry *cacheEntry) bool {
+				return entry.root == previous && strings.HasPrefix(entry.key, cacheKindPath+":")
+			})
+		}
+
+		// removed along with path entries if any
+		if elem, ok = cache.entries[key]; ok {
+			cache.removeElement(elem)
+		}
+	}
+
+	cache.entries[key] = cache.lru.PushFront(&cacheEntry{key: key, root: root, value: value, size: size, expiry: expiry})
+	cache.size += size
+
+	for cache.size > int64(cache.config.MaxSize) {
+		cache.removeElement(cache.lru.Back())
+		cache.stats.Evictions++
+	}
+
+	return true
+}
+
+// removeElement removes the entry from cache. Requires lock held.
+func (cache *contentCache) removeElement(elem *list.Element) {
+	entry := cache.lru.Remove(elem).(*cacheEntry)
+	delete(cache.entries, entry.key)
+	cache.size -= entry.size
+}
+
+// removeLocked removes all entries that match the filter, and returns the number of removed entries. Requires lock
+// held.
+func (cache *contentCache) removeLocked(filter func(entry *cacheEntry) bool) int {
+	var removed int
+
+	for _, elem := range cache.entries {
+		if filter(elem.Value.(*cacheEntry)) {
+			cache.removeElement(elem)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// getOrLoad returns the cached value, or loads and caches the value with ttl if missed. Stale value is served along
+// with a refresh in background, so that the loader is not on the critical path of request once cached.
+func (cache *contentCache) getOrLoad(ctx context.Context, key string, ttl time.Duration, loader cacheLoader) (interface{}, error) {
+	value, stale, ok := cache.get(key)
+	if ok {
+		markCache(ctx, true)
+
+		if stale {
+			cache.refresh(key, ttl, loader)
+		}
+
+		return value, nil
+	}
+
+	if cache != nil {
+		markCache(ctx, false)
+	}
+
+	value, root, size, err := loader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.set(key, root, value, size, ttl)
+
+	return value, nil
+}
+
+// refresh reloads the stale entry in background, unless being refreshed already.
+func (cache *contentCache) refresh(key string, ttl time.Duration, loader cacheLoader) {
+	cache.mu.Lock()
+	elem, ok := cache.entries[key]
+	if !ok || elem.Value.(*cacheEntry).refreshing {
+		cache.mu.Unlock()
+		return
+	}
+	elem.Value.(*cacheEntry).refreshing = true
+	cache.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultCacheRefreshLimit)
+		defer cancel()
+
+		value, root, size, err := loader(ctx)
+		if err != nil {
+			logrus.WithError(err).WithField("key", key).Debug("Failed to refresh stale cache entry")
+
+			// keep serving stale, and retry upon next hit
+			cache.mu.Lock()
+			if elem, ok := cache.entries[key]; ok {
+				elem.Value.(*cacheEntry).refreshing = false
+			}
+			cache.mu.Unlock()
+
+			return
+		}
+
+		cache.set(key, root, value, size, ttl)
+	}()
+}
+
+// purge removes the cached entries of the specified root, including manifest, files and files resolved by path
+// within the root. All entries are removed if root is empty. Returns the number of removed entries.
+func (cache *contentCache) purge(root string) int {
+	if cache == nil {
+		return 0
+	}
+
+	root = normalizeRoot(root)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return cache.removeLocked(func(entry *cacheEntry) bool {
+		return len(root) == 0 || entry.root == root
+	})
+}
+
+// Stats returns the statistics of cache.
+func (cache *contentCache) Stats() CacheStats {
+	if cache == nil {
+		return CacheStats{}
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	stats := cache.stats
+	stats.Items = len(cache.entries)
+	stats.Size = cache.size
+	stats.MaxSize = int64(cache.config.MaxSize)
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+
+	return stats
+}
+
+// normalizeRoot returns the root hash in lower case hex with 0x prefix, or empty if not specified.
+func normalizeRoot(root string) string {
+	if len(root) == 0 {
+		return ""
+	}
+
+	return eth_common.HexToHash(root).Hex()
+}
+
+// Cache keys are in format of "<kind>:<cid>[/<path>]", where cid is either root or tx seq.
+const (
+	cacheKindFile     = "file"
+	cacheKindManifest = "manifest"
+	cacheKindPath     = "path"
+)
+
+func cacheKey(kind string, cid Cid, path ...string) string {
+	key := kind + ":"
+	if cid.TxSeq != nil {
+		key += strconv.FormatUint(*cid.TxSeq, 10)
+	} else {
+		key += normalizeRoot(cid.Root)
+	}
+
+	if len(path) > 0 {
+		key += "/" + strings.TrimPrefix(path[0], "/")
+	}
+
+	return key
+}
+
+// getCacheStats returns the hit-rate metrics of cache.
+func (ctrl *RestController) getCacheStats(c *gin.Context) (interface{}, error) {
+	return ctrl.cache.Stats(), nil
+}
+
+// purgeCache removes the cached entries of the specified root, or all entries if root not specified.
+func (ctrl *RestController) purgeCache(c *gin.Context) (interface{}, error) {
+	var input struct {
+		Root string `form:"root" json:"root"`
+	}
+
+	if err := c.ShouldBind(&input); err != nil {
+		return nil, api.ErrValidation.WithData(err.Error())
+	}
+
+	if len(input.Root) > 0 {
+		if data, err := hexutil.Decode(input.Root); err != nil || len(data) != eth_common.HashLength {
+			return nil, api.ErrValidation.WithData("Invalid root hash")
+		}
+	}
+
+	return map[string]int{"purged": ctrl.cache.purge(input.Root)}, nil
+}
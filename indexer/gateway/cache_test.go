@@ -0,0 +1,243 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/api"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testRoot1 = "0x0101010101010101010101010101010101010101010101010101010101010101"
+	testRoot2 = "0x0202020202020202020202020202020202020202020202020202020202020202"
+)
+
+func TestCacheEvictBySize(t *testing.T) {
+	cache := newContentCache(CacheConfig{MaxSize: 100, MaxItemSize: 50})
+
+	assert.True(t, cache.set("a", testRoot1, "a", 40, 0))
+	assert.True(t, cache.set("b", testRoot1, "b", 40, 0))
+	assert.False(t, cache.set("c", testRoot1, "c", 51, 0)) // too large
+
+	// touch a, so that b is least recently used
+	_, _, ok := cache.get("a")
+	assert.True(t, ok)
+
+	assert.True(t, cache.set("d", testRoot1, "d", 40, 0))
+	_, _, ok = cache.get("b")
+	assert.False(t, ok)
+
+	stats := cache.Stats()
+	assert.Equal(t, 2, stats.Items)
+	assert.Equal(t, int64(80), stats.Size)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 0.5, stats.HitRate)
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	cache := newContentCache(CacheConfig{MaxSize: common.KiB})
+
+	var loaded atomic.Int32
+	refreshed := make(chan struct{}, 10)
+	loader := func(ctx context.Context) (interface{}, string, int64, error) {
+		n := loaded.Add(1)
+		if n > 1 {
+			defer func() { refreshed <- struct{}{} }()
+		}
+		return n, testRoot1, 1, nil
+	}
+
+	ttl := 50 * time.Millisecond
+
+	value, err := cache.getOrLoad(context.Background(), "manifest", ttl, loader)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), value)
+
+	// fresh
+	value, err = cache.getOrLoad(context.Background(), "manifest", ttl, loader)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), value)
+
+	// stale value served, and refreshed only once in background
+	time.Sleep(ttl)
+	for i := 0; i < 3; i++ {
+		value, err = cache.getOrLoad(context.Background(), "manifest", ttl, loader)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), value)
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "stale entry not refreshed")
+	}
+
+	// wait for the refreshed value to be cached
+	assert.Eventually(t, func() bool {
+		value, _, _ := cache.get("manifest")
+		return value == int32(2)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), loaded.Load())
+	assert.Equal(t, uint64(3), cache.Stats().StaleHits)
+}
+
+func TestCacheInvalidatePathsOnReresolve(t *testing.T) {
+	cache := newContentCache(CacheConfig{MaxSize: common.KiB})
+	txSeq := uint64(7)
+	cid := Cid{TxSeq: &txSeq}
+
+	cache.set(cacheKey(cacheKindManifest, cid), testRoot1, "manifest1", 1, time.Minute)
+	cache.set(cacheKey(cacheKindPath, cid, "/a/b"), testRoot1, "file1", 1, 0)
+	cache.set(cacheKey(cacheKindFile, Cid{Root: testRoot1}), testRoot1, "data1", 1, 0)
+
+	// refreshed with same root
+	cache.set(cacheKey(cacheKindManifest, cid), testRoot1, "manifest1", 1, time.Minute)
+	_, _, ok := cache.get(cacheKey(cacheKindPath, cid, "/a/b"))
+	assert.True(t, ok)
+
+	// re-resolved to a new root
+	cache.set(cacheKey(cacheKindManifest, cid), testRoot2, "manifest2", 1, time.Minute)
+	_, _, ok = cache.get(cacheKey(cacheKindPath, cid, "/a/b"))
+	assert.False(t, ok)
+
+	// immutable content of previous root retained
+	_, _, ok = cache.get(cacheKey(cacheKindFile, Cid{Root: testRoot1}))
+	assert.True(t, ok)
+
+	value, _, _ := cache.get(cacheKey(cacheKindManifest, cid))
+	assert.Equal(t, "manifest2", value)
+}
+
+func TestCachePurge(t *testing.T) {
+	cache := newContentCache(CacheConfig{MaxSize: common.KiB})
+	txSeq := uint64(7)
+
+	cache.set(cacheKey(cacheKindManifest, Cid{TxSeq: &txSeq}), testRoot1, "manifest", 1, time.Minute)
+	cache.set(cacheKey(cacheKindPath, Cid{Root: testRoot1}, "a"), testRoot1, "file", 1, 0)
+	cache.set(cacheKey(cacheKindFile, Cid{Root: testRoot2}), testRoot2, "data", 1, 0)
+
+	assert.Equal(t, 2, cache.purge(strings.ToUpper(testRoot1[2:])))
+	assert.Equal(t, 1, cache.Stats().Items)
+	assert.Equal(t, 1, cache.purge(""))
+	assert.Equal(t, int64(0), cache.Stats().Size)
+}
+
+func TestNilCache(t *testing.T) {
+	cache := newContentCache(CacheConfig{})
+	assert.Nil(t, cache)
+
+	var loaded int
+	for i := 0; i < 2; i++ {
+		value, err := cache.getOrLoad(context.Background(), "key", time.Minute, func(ctx context.Context) (interface{}, string, int64, error) {
+			loaded++
+			return "value", testRoot1, 1, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "value", value)
+	}
+
+	assert.Equal(t, 2, loaded)
+	assert.Equal(t, 0, cache.purge(""))
+	assert.Equal(t, CacheStats{}, cache.Stats())
+}
+
+func TestCacheRoutes(t *testing.T) {
+	// disabled by default
+	router := newTestRouter(Config{})
+	assert.NotContains(t, getCapabilities(t, router).Features, FeatureCache)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/cache/stats", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	// available in read-only mode, since only the in-memory cache is affected
+	router = newTestRouter(Config{ReadOnly: true, Cache: CacheConfig{MaxSize: common.MiB}, AdminToken: "secret"})
+	assert.Contains(t, getCapabilities(t, router).Features, FeatureCache)
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/cache/stats", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var stats struct {
+		Code int        `json:"code"`
+		Data CacheStats `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &stats))
+	assert.Equal(t, 0, stats.Code)
+	assert.Equal(t, int64(common.MiB), stats.Data.MaxSize)
+
+	purge := func(router *gin.Engine, root, auth string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/cache/purge", strings.NewReader(url.Values{"root": {root}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if len(auth) > 0 {
+			req.Header.Set("Authorization", auth)
+		}
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for root, code := range map[string]int{testRoot1: 0, "": 0, "0x01": 1} {
+		var resp struct {
+			Code int `json:"code"`
+		}
+		assert.NoError(t, json.Unmarshal(purge(router, root, "Bearer secret").Body.Bytes(), &resp))
+		assert.Equal(t, code, resp.Code, root)
+	}
+
+	// admin token required
+	for _, auth := range []string{"", "secret", "Bearer wrong"} {
+		var resp struct {
+			Code int `json:"code"`
+		}
+		assert.NoError(t, json.Unmarshal(purge(router, "", auth).Body.Bytes(), &resp))
+		assert.Equal(t, api.ErrAuth.Code, resp.Code, auth)
+	}
+
+	// not available if admin token not configured
+	router = newTestRouter(Config{Cache: CacheConfig{MaxSize: common.MiB}})
+	assert.Equal(t, http.StatusNotFound, purge(router, "", "Bearer ").Code)
+}
+
+func TestCacheHeaders(t *testing.T) {
+	ctrl := NewRestController(nil, nil, 0).WithCache(CacheConfig{MaxSize: common.MiB, TTL: time.Minute})
+
+	recorder := httptest.NewRecorder()
+	c, _ := ginTestContext(recorder, "")
+	ctrl.serveData(c, testRoot1, true, "a.txt", []byte("hello"))
+	assert.Equal(t, "public, max-age=31536000, immutable", recorder.Header().Get("Cache-Control"))
+	assert.Equal(t, `"`+testRoot1+`"`, recorder.Header().Get("ETag"))
+	assert.Equal(t, "hello", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	c, _ = ginTestContext(recorder, "")
+	ctrl.serveData(c, testRoot1, false, "a.txt", []byte("hello"))
+	assert.Equal(t, "public, max-age=60", recorder.Header().Get("Cache-Control"))
+
+	recorder = httptest.NewRecorder()
+	c, _ = ginTestContext(recorder, `"`+testRoot1+`"`)
+	assert.True(t, ctrl.notModified(c, testRoot1))
+	c.Writer.WriteHeaderNow()
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+}
+
+func ginTestContext(recorder *httptest.ResponseRecorder, ifNoneMatch string) (*gin.Context, *gin.Engine) {
+	c, engine := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/file", nil)
+	if len(ifNoneMatch) > 0 {
+		c.Request.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	return c, engine
+}
@@ -0,0 +1,51 @@
+package gateway
+
+import "github.com/0glabs/0g-storage-client/core"
+
+// Feature names that gateway may support.
+const (
+	FeatureDownload       = "download"        // download file by root or tx seq
+	FeatureFolderDownload = "folder_download" // download file within a folder
+	FeatureUpload         = "upload"          // upload file segments with proof
+	FeatureCache          = "cache"           // cache manifests and small files in gateway
+	FeatureRanges         = "ranges"          // download part of file by Range header
+	FeatureTarExport      = "tar_export"      // download directory as a tar archive by format=tar
+	FeatureKv             = "kv"              // read and write key-value streams, not served by this gateway yet
+)
+
+// ProtocolParams is the protocol parameters for client auto-configuration.
+type ProtocolParams struct {
+	ChunkSize        uint64 `json:"chunkSize"`
+	SegmentMaxChunks uint64 `json:"segmentMaxChunks"`
+	SegmentSize      uint64 `json:"segmentSize"`
+}
+
+// Capabilities is the features enabled on gateway, so that clients could adapt to.
+type Capabilities struct {
+	ReadOnly            bool           `json:"readOnly"`
+	Features            []string       `json:"features"`
+	MaxDownloadFileSize uint64         `json:"maxDownloadFileSize"`
+	Protocol            ProtocolParams `json:"protocol"`
+}
+
+func newCapabilities(config Config) Capabilities {
+	features := []string{FeatureDownload, FeatureFolderDownload, FeatureRanges, FeatureTarExport}
+	if config.Cache.MaxSize > 0 {
+		features = append(features, FeatureCache)
+	}
+
+	if !config.ReadOnly {
+		features = append(features, FeatureUpload)
+	}
+
+	return Capabilities{
+		ReadOnly:            config.ReadOnly,
+		Features:            features,
+		MaxDownloadFileSize: config.maxDownloadSize(),
+		Protocol: ProtocolParams{
+			ChunkSize:        core.DefaultChunkSize,
+			SegmentMaxChunks: core.DefaultSegmentMaxChunks,
+			SegmentSize:      core.DefaultSegmentSize,
+		},
+	}
+}
@@ -32,6 +32,11 @@ type RestController struct {
 	fileLocationCache *indexer.FileLocationCache
 
 	maxDownloadFileSize uint64 // max download file size
+
+	cache       *contentCache // read-through cache of manifests and small files, nil if disabled
+	cacheConfig CacheConfig
+
+	listingConfig ListingConfig
 }
 
 func NewRestController(nodeManager *indexer.NodeManager, locationCache *indexer.FileLocationCache, maxDownloadFileSize uint64) *RestController {
@@ -42,6 +47,19 @@ func NewRestController(nodeManager *indexer.NodeManager, locationCache *indexer.
 	}
 }
 
+// WithCache enables the read-through cache of manifests and small files, which is disabled if MaxSize is 0.
+func (ctrl *RestController) WithCache(config CacheConfig) *RestController {
+	ctrl.cache = newContentCache(config)
+	ctrl.cacheConfig = config
+	return ctrl
+}
+
+// WithListing specifies the page sizes of directory listing.
+func (ctrl *RestController) WithListing(config ListingConfig) *RestController {
+	ctrl.listingConfig = config
+	return ctrl
+}
+
 // getAvailableFileLocations returns a list of available file locations for a file with the given CID.
 func (ctrl *RestController) getAvailableFileLocations(ctx context.Context, cid Cid) ([]*shard.ShardedNode, error) {
 	if cid.TxSeq != nil {
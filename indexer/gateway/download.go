@@ -1,14 +1,22 @@
 package gateway
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/api"
+	"github.com/0glabs/0g-storage-client/node"
 	"github.com/0glabs/0g-storage-client/transfer"
 	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 )
@@ -28,7 +36,7 @@ func (ctrl *RestController) downloadFile(c *gin.Context) (interface{}, error) {
 		return nil, api.ErrValidation.WithData("Either 'root' or 'txSeq' must be provided")
 	}
 
-	return nil, ctrl.downloadAndServeFile(c, input.Cid, input.Name)
+	return nil, ctrl.downloadAndServeFile(c, input.Cid, input.Name, input.TxSeq == nil)
 }
 
 // downloadFileInFolder handles file downloads from a directory structure.
@@ -37,55 +45,83 @@ func (ctrl *RestController) downloadFileInFolder(c *gin.Context) (interface{}, e
 	filePath := filepath.Clean(c.Param("filePath"))
 	cid := NewCid(cidStr)
 
-	clients, err := ctrl.getAvailableStorageNodes(c, cid)
-	if err != nil {
-		return nil, errors.WithMessage(err, "Failed to get available storage nodes")
-	}
-
-	fileInfo, err := getOverallFileInfo(c, clients, cid)
-	if err != nil {
-		return nil, errors.WithMessage(err, "Failed to retrieve file info")
-	}
+	// Files resolved by path never change within the root, and are invalidated if cid re-resolves to a new root.
+	value, err := ctrl.cache.getOrLoad(c, cacheKey(cacheKindPath, cid, filePath), 0, func(ctx context.Context) (interface{}, string, int64, error) {
+		manifest, err := ctrl.loadManifest(ctx, cid)
+		if err != nil {
+			return nil, "", 0, err
+		}
 
-	if fileInfo == nil {
-		return nil, ErrFileNotFound
-	}
+		entry, err := dir.LocateEntry(ctx, manifest.source, filePath)
+		if err != nil {
+			return nil, "", 0, ErrFilePathNotFound.WithData(err.Error())
+		}
 
-	if fileInfo.Pruned {
-		return nil, ErrFilePruned
-	}
+		resolved := &resolvedFile{root: manifest.root, source: manifest.source, entry: entry}
 
-	if !fileInfo.Finalized {
-		return nil, ErrFileNotFinalized
-	}
+		size := int64(cachedNodeOverhead)
+		if entry != nil {
+			size = cachedNodeSize(entry.FsNode)
+		}
 
-	downloader, err := transfer.NewDownloader(clients)
+		return resolved, manifest.root.Hex(), size, nil
+	})
 	if err != nil {
-		return nil, errors.WithMessage(err, "Failed to create downloader")
+		return nil, err
 	}
 
-	root := fileInfo.Tx.DataMerkleRoot
+	resolved := value.(*resolvedFile)
+	root, entry := resolved.root, resolved.entry
+	etag := root.Hex() + filePath
+	setAccessLogRoot(c, root.Hex())
+	immutable := cid.TxSeq == nil
 
-	ftree, err := transfer.BuildFileTree(c, downloader, root.Hex(), true)
-	if err != nil {
-		return nil, errors.WithMessage(err, "Failed to build file tree")
-	}
+	// path of directory relative to root as listed by the tree source
+	dirPath := strings.TrimPrefix(filePath, "/")
 
-	fnode, err := ftree.Locate(filePath)
-	if err != nil {
-		return nil, ErrFilePathNotFound.WithData(err.Error())
+	// root directory is located as nil
+	fileType := dir.FileTypeDirectory
+	if entry != nil {
+		fileType = entry.Type
 	}
 
-	switch fnode.Type {
+	switch fileType {
 	case dir.FileTypeDirectory:
-		// Show the list of files in the directory.
-		return serveDirectoryListing(fnode), nil
+		if c.Query("format") == formatTar {
+			return nil, ctrl.serveTar(c, resolved.source, dirPath, entry, etag, immutable)
+		}
+
+		offset, limit, err := ctrl.parseListingPage(c)
+		if err != nil {
+			return nil, err
+		}
+
+		// pages of the same directory are cached separately by client
+		etag = fmt.Sprintf("%v?offset=%v&limit=%v", etag, offset, limit)
+		if ctrl.notModified(c, etag) {
+			return nil, api.ErrHandled
+		}
+
+		// Show a page of files in the directory, where only the chunks of page are fetched for chunked manifests.
+		listing, err := serveDirectoryListing(c, resolved.source, dirPath, entry, offset, limit)
+		if err != nil {
+			return nil, errors.WithMessage(err, "Failed to list directory")
+		}
+
+		ctrl.setCacheHeaders(c, etag, immutable)
+		return listing, nil
 	case dir.FileTypeSymbolic:
+		if ctrl.notModified(c, etag) {
+			return nil, api.ErrHandled
+		}
+
 		// If the file type is symbolic (a symlink), return the metadata of the symbolic link itself
 		// (i.e., information about the symlink, not the target it points to).
 		// This prevents the server from following the symbolic link and returning the target file's content.
-		return fnode, nil
+		ctrl.setCacheHeaders(c, etag, immutable)
+		return entry.FsNode, nil
 	case dir.FileTypeFile:
+		fnode := entry.FsNode
 		if fnode.Size > int64(ctrl.maxDownloadFileSize) {
 			return nil, ErrFileSizeTooLarge.WithData(map[string]uint64{
 				"actual": uint64(fnode.Size),
@@ -93,73 +129,233 @@ func (ctrl *RestController) downloadFileInFolder(c *gin.Context) (interface{}, e
 			})
 		}
 
-		return nil, ctrl.downloadAndServeFile(c, Cid{Root: fnode.Root}, fnode.Name)
+		if fnode.Embedded() {
+			if !ctrl.notModified(c, etag) {
+				ctrl.serveData(c, etag, immutable, fnode.Name, fnode.Data)
+			}
+
+			return nil, api.ErrHandled
+		}
+
+		return nil, ctrl.downloadAndServeFile(c, Cid{Root: fnode.Root}, fnode.Name, immutable)
 	default:
-		return nil, ErrFileTypeUnsupported.WithData(fnode.Type)
+		return nil, ErrFileTypeUnsupported.WithData(fileType)
+	}
+}
+
+// resolvedManifest is the cached directory manifest of cid, which lists directories on demand if the manifest
+// uploaded in chunks.
+type resolvedManifest struct {
+	root   common.Hash
+	source dir.PagedTreeSource
+}
+
+// resolvedFile is the cached file resolved by path within a directory.
+type resolvedFile struct {
+	root   common.Hash // root of directory
+	source dir.PagedTreeSource
+	entry  *dir.TreeEntry // nil for root directory
+}
+
+// cachedFile is the cached content of small file.
+type cachedFile struct {
+	root common.Hash
+	data []byte
+}
+
+// cachedNodeOverhead is the estimated memory size of a file node without embedded data.
+const cachedNodeOverhead = 256
+
+// cachedNodeSize returns the estimated memory size of node in cache, including the entries of directory.
+func cachedNodeSize(node *dir.FsNode) int64 {
+	return int64(cachedNodeOverhead*(1+len(node.Entries)) + len(node.Data))
+}
+
+// loadManifest returns the directory manifest of cid, which is cached and refreshed in background once stale.
+func (ctrl *RestController) loadManifest(ctx context.Context, cid Cid) (*resolvedManifest, error) {
+	value, err := ctrl.cache.getOrLoad(ctx, cacheKey(cacheKindManifest, cid), ctrl.cacheConfig.ttl(), func(ctx context.Context) (interface{}, string, int64, error) {
+		clients, err := ctrl.getAvailableStorageNodes(ctx, cid)
+		if err != nil {
+			return nil, "", 0, errors.WithMessage(err, "Failed to get available storage nodes")
+		}
+
+		fileInfo, err := getOverallFileInfo(ctx, clients, cid)
+		if err != nil {
+			return nil, "", 0, errors.WithMessage(err, "Failed to retrieve file info")
+		}
+
+		if fileInfo == nil {
+			return nil, "", 0, ErrFileNotFound
+		}
+
+		if fileInfo.Pruned {
+			return nil, "", 0, ErrFilePruned
+		}
+
+		if !fileInfo.Finalized {
+			return nil, "", 0, ErrFileNotFinalized
+		}
+
+		downloader, err := transfer.NewDownloader(clients)
+		if err != nil {
+			return nil, "", 0, errors.WithMessage(err, "Failed to create downloader")
+		}
+
+		root := fileInfo.Tx.DataMerkleRoot
+
+		// chunks of manifest are fetched on demand to list directories, instead of decoding the whole tree
+		source, err := transfer.BuildTreeSource(ctx, downloader, root.Hex(), true)
+		if err != nil {
+			return nil, "", 0, errors.WithMessage(err, "Failed to build file tree")
+		}
+
+		return &resolvedManifest{root, source}, root.Hex(), int64(fileInfo.Tx.Size), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*resolvedManifest), nil
+}
+
+// downloadAndServeFile downloads the file and serves it as an attachment. Small files are cached in memory, so
+// that served without accessing storage nodes any more.
+func (ctrl *RestController) downloadAndServeFile(c *gin.Context, cid Cid, filename string, immutable bool) error {
+	key := cacheKey(cacheKindFile, cid)
+	if value, _, ok := ctrl.cache.get(key); ok {
+		file := value.(*cachedFile)
+		markCache(c, true)
+		setAccessLogRoot(c, file.root.Hex())
+		if len(filename) == 0 {
+			filename = file.root.Hex()
+		}
+
+		if !ctrl.notModified(c, file.root.Hex()) {
+			ctrl.serveData(c, file.root.Hex(), immutable, filename, file.data)
+		}
+
+		return api.ErrHandled
+	}
+
+	if ctrl.cache != nil {
+		markCache(c, false)
+	}
+
+	clients, fileInfo, err := ctrl.getFinalizedFile(c, cid)
+	if err != nil {
+		return err
+	}
+
+	root := fileInfo.Tx.DataMerkleRoot.Hex()
+	setAccessLogRoot(c, root)
+	if ctrl.notModified(c, root) {
+		return api.ErrHandled
+	}
+
+	tmpfile, err := downloadToTempFile(c, clients, root)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpfile)
+
+	if len(filename) == 0 {
+		filename = root
+	}
+
+	if ctrl.cache != nil && int64(fileInfo.Tx.Size) <= ctrl.cacheConfig.maxItemSize() {
+		data, err := os.ReadFile(tmpfile)
+		if err != nil {
+			return errors.WithMessage(err, "Failed to read downloaded file")
+		}
+
+		ctrl.cache.set(key, root, &cachedFile{fileInfo.Tx.DataMerkleRoot, data}, int64(len(data)), 0)
+		ctrl.serveData(c, root, immutable, filename, data)
+
+		return api.ErrHandled
 	}
+
+	ctrl.setCacheHeaders(c, root, immutable)
+	c.FileAttachment(tmpfile, filename)
+
+	return api.ErrHandled
 }
 
-// downloadAndServeFile downloads the file and serves it as an attachment.
-func (ctrl *RestController) downloadAndServeFile(c *gin.Context, cid Cid, filename string) error {
+// getFinalizedFile returns the storage nodes that hold the file of cid, along with the file info, which should be
+// finalized and not pruned.
+func (ctrl *RestController) getFinalizedFile(c *gin.Context, cid Cid) ([]*node.ZgsClient, *node.FileInfo, error) {
 	clients, err := ctrl.getAvailableStorageNodes(c, cid)
 	if err != nil {
-		return errors.WithMessage(err, "Failed to get available storage nodes")
+		return nil, nil, errors.WithMessage(err, "Failed to get available storage nodes")
 	}
 
 	fileInfo, err := getOverallFileInfo(c, clients, cid)
 	if err != nil {
-		return errors.WithMessage(err, "Failed to retrieve file info")
+		return nil, nil, errors.WithMessage(err, "Failed to retrieve file info")
 	}
 
 	if fileInfo == nil {
-		return ErrFileNotFound
+		return nil, nil, ErrFileNotFound
 	}
 
 	if fileInfo.Pruned {
-		return ErrFilePruned
+		return nil, nil, ErrFilePruned
 	}
 
 	if !fileInfo.Finalized {
-		return ErrFileNotFinalized
+		return nil, nil, ErrFileNotFinalized
 	}
 
-	downloader, err := transfer.NewDownloader(clients)
+	return clients, fileInfo, nil
+}
+
+// downloadToTempFile downloads the file of root from storage nodes into a temp file with proof, and returns the
+// temp file name, which should be removed by caller.
+func downloadToTempFile(c *gin.Context, clients []*node.ZgsClient, root string) (string, error) {
+	downloader, err := transfer.NewDownloader(clients, zg_common.LogOption{Logger: transferLogger(c)})
 	if err != nil {
-		return errors.WithMessage(err, "Failed to create downloader")
+		return "", errors.WithMessage(err, "Failed to create downloader")
 	}
 
-	root := fileInfo.Tx.DataMerkleRoot.Hex()
 	tmpfile := filepath.Join(os.TempDir(), fmt.Sprintf("zgs_indexer_download_%v", root))
-	defer os.Remove(tmpfile)
-
 	if err := downloader.Download(c, root, tmpfile, true); err != nil {
-		return errors.WithMessage(err, "Failed to download file")
+		os.Remove(tmpfile)
+		return "", errors.WithMessage(err, "Failed to download file")
 	}
 
-	if len(filename) == 0 {
-		filename = root
-	}
+	return tmpfile, nil
+}
 
-	c.FileAttachment(tmpfile, filename)
+// serveData serves the file content as an attachment along with cache headers, and supports range requests.
+func (ctrl *RestController) serveData(c *gin.Context, etag string, immutable bool, filename string, data []byte) {
+	ctrl.setCacheHeaders(c, etag, immutable)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "application/octet-stream")
 
-	return api.ErrHandled
+	// serve range requests as well as files not cached, see FeatureRanges
+	http.ServeContent(c.Writer, c.Request, "", time.Time{}, bytes.NewReader(data))
 }
 
-// serveDirectoryListing serves the list of files in a directory.
-func serveDirectoryListing(dirNode *dir.FsNode) interface{} {
-	type DirListing struct {
-		Name string `json:"name"`
-		Type string `json:"type"`
-		Size uint64 `json:"size,omitempty"`
+// setCacheHeaders sets the cache-control headers of response. Content addressed by root never changes, and could
+// be cached forever. Otherwise, e.g. addressed by tx seq, the content could be cached as long as manifests in
+// gateway.
+func (ctrl *RestController) setCacheHeaders(c *gin.Context, etag string, immutable bool) {
+	c.Header("ETag", strconv.Quote(etag))
+
+	if immutable {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%v", int64(ctrl.cacheConfig.ttl().Seconds())))
 	}
+}
 
-	var dirListing []DirListing
-	for _, entry := range dirNode.Entries {
-		dirListing = append(dirListing, DirListing{
-			Name: entry.Name,
-			Type: string(entry.Type),
-			Size: uint64(entry.Size),
-		})
+// notModified responds with 304 if the content cached by client matches the etag.
+func (ctrl *RestController) notModified(c *gin.Context, etag string) bool {
+	if c.GetHeader("If-None-Match") != strconv.Quote(etag) {
+		return false
 	}
-	return dirListing
+
+	c.Header("ETag", strconv.Quote(etag))
+	c.Status(http.StatusNotModified)
+
+	return true
 }
@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/api"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultListingPageSize    = 1000
+	defaultListingMaxPageSize = 10000
+)
+
+// ListingConfig is the config of directory listing, which is paged so that huge directories are not responded
+// at once.
+type ListingConfig struct {
+	DefaultPageSize int // number of entries per page if limit not specified, default 1000
+	MaxPageSize     int // max number of entries per page, default 10000
+}
+
+// Validate checks the config, and returns an error that names the invalid field if any.
+func (config ListingConfig) Validate() error {
+	if err := zg_common.RequireNonNegative("DefaultPageSize", config.DefaultPageSize); err != nil {
+		return err
+	}
+
+	if err := zg_common.RequireNonNegative("MaxPageSize", config.MaxPageSize); err != nil {
+		return err
+	}
+
+	if config.defaultPageSize() > config.maxPageSize() {
+		return zg_common.NewOptionError("DefaultPageSize", "should not be greater than MaxPageSize %v, got %v",
+			config.maxPageSize(), config.defaultPageSize())
+	}
+
+	return nil
+}
+
+func (config ListingConfig) defaultPageSize() int {
+	if config.DefaultPageSize > 0 {
+		return config.DefaultPageSize
+	}
+
+	return min(defaultListingPageSize, config.maxPageSize())
+}
+
+func (config ListingConfig) maxPageSize() int {
+	if config.MaxPageSize > 0 {
+		return config.MaxPageSize
+	}
+
+	return defaultListingMaxPageSize
+}
+
+// DirEntry is an entry of directory listing.
+type DirEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Size uint64 `json:"size,omitempty"`
+}
+
+// DirListing is a page of directory entries in order of name. The next page could be requested by either
+// nextOffset or the opaque nextCursor, which are omitted on the last page.
+type DirListing struct {
+	Total      int        `json:"total"`
+	Offset     int        `json:"offset"`
+	Entries    []DirEntry `json:"entries"`
+	NextOffset *int       `json:"nextOffset,omitempty"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// listingPage is the page of directory listing requested by query parameters offset, limit and cursor, where
+// cursor takes precedence over offset.
+type listingPage struct {
+	Offset int    `form:"offset" binding:"min=0"`
+	Limit  int    `form:"limit" binding:"min=0"`
+	Cursor string `form:"cursor"`
+}
+
+// encodeListingCursor encodes the offset into an opaque cursor, so that clients need not depend on how the
+// position is represented, e.g. in case of chunked manifests.
+func encodeListingCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeListingCursor(cursor string) (int, bool) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, false
+	}
+
+	offset, err := strconv.Atoi(string(decoded))
+	if err != nil || offset < 0 {
+		return 0, false
+	}
+
+	return offset, true
+}
+
+// parseListingPage parses the page of directory listing from query, and returns the offset and limit, where limit
+// is capped to the max page size.
+func (ctrl *RestController) parseListingPage(c *gin.Context) (int, int, error) {
+	var page listingPage
+	if err := c.ShouldBindQuery(&page); err != nil {
+		return 0, 0, api.ErrValidation.WithData(err.Error())
+	}
+
+	if len(page.Cursor) > 0 {
+		offset, ok := decodeListingCursor(page.Cursor)
+		if !ok {
+			return 0, 0, api.ErrValidation.WithData("Invalid cursor")
+		}
+		page.Offset = offset
+	}
+
+	limit := page.Limit
+	if limit == 0 {
+		limit = ctrl.listingConfig.defaultPageSize()
+	}
+
+	return page.Offset, min(limit, ctrl.listingConfig.maxPageSize()), nil
+}
+
+// serveDirectoryListing serves a page of files in a directory, which is nil for root. Only the entries around the
+// page are decoded if the manifest uploaded in chunks, so the offset, and cursor as well, stays valid across chunk
+// boundaries.
+func serveDirectoryListing(ctx context.Context, source dir.PagedTreeSource, dirPath string, dirEntry *dir.TreeEntry, offset, limit int) (*DirListing, error) {
+	entries, total, err := source.ReadDirPage(ctx, dirPath, dirEntry, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	listing := DirListing{
+		Total:   total,
+		Offset:  offset,
+		Entries: make([]DirEntry, 0, len(entries)),
+	}
+
+	for _, entry := range entries {
+		listing.Entries = append(listing.Entries, DirEntry{
+			Name: entry.Name,
+			Type: string(entry.Type),
+			Size: uint64(entry.Size),
+		})
+	}
+
+	if next := offset + len(entries); next < total {
+		listing.NextOffset = &next
+		listing.NextCursor = encodeListingCursor(next)
+	}
+
+	return &listing, nil
+}
@@ -0,0 +1,201 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTestListingPage(ctrl *RestController, query string) (int, int, error) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/file/root/dir?"+query, nil)
+
+	return ctrl.parseListingPage(c)
+}
+
+func TestParseListingPage(t *testing.T) {
+	ctrl := NewRestController(nil, nil, 0).WithListing(ListingConfig{DefaultPageSize: 10, MaxPageSize: 100})
+
+	for _, c := range []struct {
+		query         string
+		offset, limit int
+	}{
+		{"", 0, 10},
+		{"offset=20", 20, 10},
+		{"offset=20&limit=50", 20, 50},
+		{"limit=1000", 0, 100},
+		{"offset=20&cursor=" + encodeListingCursor(30), 30, 10},
+	} {
+		offset, limit, err := parseTestListingPage(ctrl, c.query)
+		assert.NoError(t, err, c.query)
+		assert.Equal(t, c.offset, offset, c.query)
+		assert.Equal(t, c.limit, limit, c.query)
+	}
+
+	for _, query := range []string{"offset=-1", "limit=-1", "offset=abc", "cursor=invalid", "cursor=" + encodeListingCursor(-1)} {
+		_, _, err := parseTestListingPage(ctrl, query)
+		assert.Error(t, err, query)
+	}
+
+	// defaults
+	_, limit, err := parseTestListingPage(NewRestController(nil, nil, 0), "limit=100000")
+	assert.NoError(t, err)
+	assert.Equal(t, defaultListingMaxPageSize, limit)
+
+	assert.NoError(t, ListingConfig{}.Validate())
+	assert.NoError(t, ListingConfig{MaxPageSize: 10}.Validate())
+	assert.Error(t, ListingConfig{DefaultPageSize: 20, MaxPageSize: 10}.Validate())
+	assert.Error(t, ListingConfig{MaxPageSize: -1}.Validate())
+}
+
+func TestServeDirectoryListing(t *testing.T) {
+	var entries []*dir.FsNode
+	for i := 0; i < 2500; i++ {
+		entries = append(entries, &dir.FsNode{Name: fmt.Sprintf("file%05d", 2499-i), Type: dir.FileTypeFile, Size: int64(i)})
+	}
+	node := dir.NewDirFsNode("root", entries)
+
+	// walk through pages by cursor in stable order
+	var names []string
+	var pages int
+	for offset := 0; ; pages++ {
+		listing, err := serveDirectoryListing(context.Background(), dir.TreeSourceOf(node), "", nil, offset, 1000)
+		assert.NoError(t, err)
+		assert.Equal(t, 2500, listing.Total)
+		assert.Equal(t, offset, listing.Offset)
+
+		for _, entry := range listing.Entries {
+			names = append(names, entry.Name)
+		}
+
+		if listing.NextOffset == nil {
+			assert.Empty(t, listing.NextCursor)
+			break
+		}
+
+		next, ok := decodeListingCursor(listing.NextCursor)
+		assert.True(t, ok)
+		assert.Equal(t, *listing.NextOffset, next)
+		offset = next
+	}
+
+	assert.Equal(t, 2, pages)
+	assert.Equal(t, 2500, len(names))
+	for i, name := range names {
+		assert.Equal(t, fmt.Sprintf("file%05d", i), name)
+	}
+
+	// beyond the last page
+	listing, err := serveDirectoryListing(context.Background(), dir.TreeSourceOf(node), "", nil, 3000, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, 2500, listing.Total)
+	assert.NotNil(t, listing.Entries)
+	assert.Empty(t, listing.Entries)
+	assert.Nil(t, listing.NextOffset)
+}
+
+// newChunkedTestSource uploads the manifest of tree in chunks of at most maxSize into memory, and returns the tree
+// source of chunked manifest along with the end offset of each chunk within manifest.
+func newChunkedTestSource(t *testing.T, tree *dir.FsNode, maxSize int64) (dir.PagedTreeSource, []byte, []int64) {
+	manifest, err := dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+
+	root, err := dir.ManifestRoot(tree)
+	assert.NoError(t, err)
+
+	chunks := make(map[common.Hash][]byte)
+	index := dir.ManifestIndex{Root: root, Size: int64(len(manifest))}
+	var chunkEnds []int64
+	for _, chunk := range dir.SplitManifest(manifest, maxSize) {
+		data, err := core.NewDataInMemory(chunk)
+		assert.NoError(t, err)
+		chunkRoot, err := core.MerkleRootData(data)
+		assert.NoError(t, err)
+
+		chunks[chunkRoot] = chunk
+		index.Chunks = append(index.Chunks, chunkRoot)
+		chunkEnds = append(chunkEnds, int64(len(chunk)))
+		if len(chunkEnds) > 1 {
+			chunkEnds[len(chunkEnds)-1] += chunkEnds[len(chunkEnds)-2]
+		}
+	}
+
+	source, err := dir.NewManifestSource(context.Background(), &index, func(_ context.Context, root common.Hash) ([]byte, error) {
+		chunk, ok := chunks[root]
+		if !ok {
+			return nil, errors.New("chunk not found")
+		}
+		return chunk, nil
+	})
+	assert.NoError(t, err)
+
+	return source, manifest, chunkEnds
+}
+
+func TestServeDirectoryListingChunked(t *testing.T) {
+	var entries []*dir.FsNode
+	for i := 0; i < 2500; i++ {
+		entries = append(entries, &dir.FsNode{Name: fmt.Sprintf("file%05d", i), Type: dir.FileTypeFile, Size: int64(i)})
+	}
+	node := dir.NewDirFsNode("root", entries)
+
+	source, manifest, chunkEnds := newChunkedTestSource(t, node, 4096)
+	assert.Greater(t, len(chunkEnds), 10)
+
+	// walk through pages by cursor across chunk boundaries, where pages are not aligned with chunks
+	var names []string
+	for offset := 0; ; {
+		listing, err := serveDirectoryListing(context.Background(), source, "", nil, offset, 333)
+		assert.NoError(t, err)
+		assert.Equal(t, 2500, listing.Total)
+		assert.Equal(t, offset, listing.Offset)
+
+		for _, entry := range listing.Entries {
+			names = append(names, entry.Name)
+		}
+
+		if listing.NextOffset == nil {
+			break
+		}
+
+		next, ok := decodeListingCursor(listing.NextCursor)
+		assert.True(t, ok)
+		offset = next
+	}
+
+	assert.Equal(t, 2500, len(names))
+	for i, name := range names {
+		assert.Equal(t, fmt.Sprintf("file%05d", i), name)
+	}
+
+	// the cursor at each chunk boundary continues with the next entry, without gaps or duplicates
+	for _, end := range chunkEnds[:len(chunkEnds)-1] {
+		// the last entry that starts within the chunk
+		last := -1
+		for i := range entries {
+			if pos := bytes.Index(manifest, []byte(fmt.Sprintf(`"name":"file%05d"`, i))); pos >= 0 && int64(pos) < end {
+				last = i
+			}
+		}
+		assert.GreaterOrEqual(t, last, 0)
+
+		listing, err := serveDirectoryListing(context.Background(), source, "", nil, last, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []DirEntry{{Name: fmt.Sprintf("file%05d", last), Type: "file", Size: uint64(last)}}, listing.Entries)
+
+		listing, err = serveDirectoryListing(context.Background(), source, "", nil, *listing.NextOffset, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []DirEntry{{Name: fmt.Sprintf("file%05d", last+1), Type: "file", Size: uint64(last + 1)}}, listing.Entries)
+	}
+}
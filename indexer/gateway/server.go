@@ -1,32 +1,99 @@
 package gateway
 
 import (
+	"crypto/subtle"
 	"net/http"
+	"strings"
 
+	"github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/api"
 	"github.com/0glabs/0g-storage-client/indexer"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type Config struct {
-	Endpoint            string       // http endpoint
-	RPCHandler          http.Handler // enable to provide both RPC and REST API service
-	MaxDownloadFileSize uint64       // max download file size
+	Endpoint        string          // http endpoint
+	RPCHandler      http.Handler    // enable to provide both RPC and REST API service
+	MaxDownloadSize common.ByteSize // max download file size
+	ReadOnly        bool            // disable all routes that write data to storage nodes
+	Cache           CacheConfig     // read-through cache of manifests and small files, disabled by default
+	AccessLog       AccessLogConfig // access logs of requests, disabled by default
+	Listing         ListingConfig   // page sizes of directory listing
+	AdminToken      string          // bearer token required by admin routes, e.g. to purge cache, disabled if empty
+
+	// Deprecated: use MaxDownloadSize instead, which is used only if MaxDownloadSize is not specified.
+	MaxDownloadFileSize uint64
+}
+
+// maxDownloadSize returns the max download file size, which converts from the deprecated field if necessary.
+func (config Config) maxDownloadSize() uint64 {
+	if config.MaxDownloadSize > 0 {
+		return uint64(config.MaxDownloadSize)
+	}
+
+	return config.MaxDownloadFileSize
 }
 
 func MustServeWithRPC(nodeManager *indexer.NodeManager, locationCache *indexer.FileLocationCache, config Config) {
-	controller := NewRestController(nodeManager, locationCache, config.MaxDownloadFileSize)
+	controller := NewRestController(nodeManager, locationCache, config.maxDownloadSize()).WithCache(config.Cache).WithListing(config.Listing)
+
+	api.Serve(config.Endpoint, newRouteFactory(controller, config))
+}
+
+// newRouteFactory registers routes according to the config. Note, routes that write data are not
+// registered at all in read-only mode, and neither admin routes if admin token not configured, so that requests to
+// them are responded with 404.
+func newRouteFactory(controller *RestController, config Config) api.RouteFactory {
+	capabilities := newCapabilities(config)
 
-	api.Serve(config.Endpoint, func(router *gin.Engine) {
+	return func(router *gin.Engine) {
+		router.Use(requestIDMiddleware)
+		if config.AccessLog.Enabled {
+			router.Use(newAccessLogger(config.AccessLog, logrus.StandardLogger()).middleware)
+		}
+
+		router.GET("/capabilities", api.Wrap(func(c *gin.Context) (interface{}, error) {
+			return capabilities, nil
+		}))
 		router.GET("/file", api.Wrap(controller.downloadFile))
 		router.GET("/file/:cid/*filePath", api.Wrap(controller.downloadFileInFolder))
 		router.GET("/file/info/:cid", api.Wrap(controller.getFileStatus))
 		router.GET("/files/info", api.Wrap(controller.batchGetFileStatus))
 		router.GET("/node/status", api.Wrap(controller.getNodeStatus))
-		router.POST("/file/segment", api.Wrap(controller.uploadSegment))
+
+		if config.Cache.MaxSize > 0 {
+			router.GET("/cache/stats", api.Wrap(controller.getCacheStats))
+
+			// purging cache is costly for storage nodes behind, and available to admin only
+			if len(config.AdminToken) > 0 {
+				router.POST("/cache/purge", api.Wrap(requireAdminToken(config.AdminToken, controller.purgeCache)))
+			}
+		}
+
+		if !config.ReadOnly {
+			router.POST("/file/segment", api.Wrap(controller.uploadSegment))
+		}
 
 		if config.RPCHandler != nil {
 			router.POST("/", gin.WrapH(config.RPCHandler))
 		}
-	})
+	}
+}
+
+// requireAdminToken wraps the controller of admin route, which requires the admin token in the Authorization header
+// of Bearer scheme.
+func requireAdminToken(token string, controller func(c *gin.Context) (interface{}, error)) func(c *gin.Context) (interface{}, error) {
+	return func(c *gin.Context) (interface{}, error) {
+		key, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			return nil, api.ErrAuth.WithData("Bearer scheme required")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) != 1 {
+			return nil, api.ErrAuth.WithData("invalid admin token")
+		}
+
+		return controller(c)
+	}
 }
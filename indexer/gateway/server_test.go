@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(config Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	newRouteFactory(NewRestController(nil, nil, config.maxDownloadSize()).WithCache(config.Cache).WithListing(config.Listing), config)(router)
+	return router
+}
+
+func getCapabilities(t *testing.T, router *gin.Engine) Capabilities {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/capabilities", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp struct {
+		Code int          `json:"code"`
+		Data Capabilities `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, 0, resp.Code)
+
+	return resp.Data
+}
+
+func TestReadOnlyGateway(t *testing.T) {
+	router := newTestRouter(Config{ReadOnly: true, MaxDownloadSize: common.KiB})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/file/segment", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	capabilities := getCapabilities(t, router)
+	assert.True(t, capabilities.ReadOnly)
+	assert.NotContains(t, capabilities.Features, FeatureUpload)
+	assert.Contains(t, capabilities.Features, FeatureDownload)
+	assert.Contains(t, capabilities.Features, FeatureRanges)
+	assert.Contains(t, capabilities.Features, FeatureTarExport)
+	assert.NotContains(t, capabilities.Features, FeatureKv)
+	assert.Equal(t, uint64(1024), capabilities.MaxDownloadFileSize)
+	assert.Equal(t, uint64(256*1024), capabilities.Protocol.SegmentSize)
+}
+
+func TestDeprecatedMaxDownloadFileSize(t *testing.T) {
+	capabilities := getCapabilities(t, newTestRouter(Config{MaxDownloadFileSize: 2048}))
+	assert.Equal(t, uint64(2048), capabilities.MaxDownloadFileSize)
+
+	// new field takes precedence
+	capabilities = getCapabilities(t, newTestRouter(Config{MaxDownloadSize: common.MiB, MaxDownloadFileSize: 2048}))
+	assert.Equal(t, uint64(1024*1024), capabilities.MaxDownloadFileSize)
+}
+
+func TestWritableGateway(t *testing.T) {
+	router := newTestRouter(Config{})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/file/segment", nil))
+	assert.NotEqual(t, http.StatusNotFound, recorder.Code)
+
+	capabilities := getCapabilities(t, router)
+	assert.False(t, capabilities.ReadOnly)
+	assert.Contains(t, capabilities.Features, FeatureUpload)
+}
@@ -0,0 +1,173 @@
+package gateway
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/api"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// formatTar is the value of format parameter to export directory as a tar archive.
+const formatTar = "tar"
+
+// serveTar exports the directory as a tar archive, where files are downloaded from storage nodes one by one and
+// streamed into the archive, so that the memory used is bounded regardless of the directory size. Directories are
+// listed on demand by source, where dirEntry is nil for root. The total size of files is limited by the max
+// download file size.
+//
+// Since the response is streamed, files failed to download after the response started abort the connection, so
+// that clients never take a truncated archive as complete.
+func (ctrl *RestController) serveTar(c *gin.Context, source dir.TreeSource, dirPath string, dirEntry *dir.TreeEntry, etag string, immutable bool) error {
+	var total int64
+	err := walkTreeSource(c, source, dirPath, "", dirEntry, func(_ string, n *dir.FsNode) error {
+		if n.Type == dir.FileTypeFile {
+			total += n.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessage(err, "Failed to list directory")
+	}
+
+	if total > int64(ctrl.maxDownloadFileSize) {
+		return ErrFileSizeTooLarge.WithData(map[string]uint64{
+			"actual": uint64(total),
+			"max":    ctrl.maxDownloadFileSize,
+		})
+	}
+
+	etag += "?format=tar"
+	if ctrl.notModified(c, etag) {
+		return api.ErrHandled
+	}
+
+	name := "root"
+	if dirEntry != nil && dirEntry.Name != "/" && len(dirEntry.Name) > 0 {
+		name = dirEntry.Name
+	}
+
+	ctrl.setCacheHeaders(c, etag, immutable)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".tar"))
+	c.Header("Content-Type", "application/x-tar")
+	c.Status(http.StatusOK)
+
+	writer := tar.NewWriter(c.Writer)
+	err = walkTreeSource(c, source, dirPath, "", dirEntry, func(relpath string, n *dir.FsNode) error {
+		return ctrl.writeTarEntry(c, writer, relpath, n)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+
+	if err != nil {
+		logrus.WithError(err).WithField("name", name).Warn("Failed to export directory as tar")
+
+		// close the connection without terminating the response
+		if conn, _, err := c.Writer.Hijack(); err == nil {
+			conn.Close()
+		}
+	}
+
+	return api.ErrHandled
+}
+
+// walkTreeSource walks the entries of directory listed by source depth-first in pre-order, as FsNode.Walk does,
+// and calls fn with the path relative to the directory walked.
+func walkTreeSource(ctx context.Context, source dir.TreeSource, dirPath, relpath string, dirEntry *dir.TreeEntry, fn func(relpath string, node *dir.FsNode) error) error {
+	entries, err := source.ReadDir(ctx, dirPath, dirEntry)
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		entryRelpath := path.Join(relpath, entry.Name)
+		if err := fn(entryRelpath, entry.FsNode); err != nil {
+			return err
+		}
+
+		if entry.Type == dir.FileTypeDirectory {
+			if err := walkTreeSource(ctx, source, path.Join(dirPath, entry.Name), entryRelpath, entry, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeTarEntry writes the node at path into tar archive, and downloads the file content from storage nodes if not
+// embedded.
+func (ctrl *RestController) writeTarEntry(c *gin.Context, writer *tar.Writer, path string, node *dir.FsNode) error {
+	header := tar.Header{
+		Name:    path,
+		Mode:    int64(node.Mode & 0o7777),
+		ModTime: time.Unix(0, node.ModTime),
+		Format:  tar.FormatPAX,
+	}
+
+	switch node.Type {
+	case dir.FileTypeDirectory:
+		header.Typeflag, header.Name = tar.TypeDir, path+"/"
+		if header.Mode == 0 {
+			header.Mode = 0o755
+		}
+	case dir.FileTypeSymbolic:
+		header.Typeflag, header.Linkname, header.Mode = tar.TypeSymlink, node.Link, 0o777
+	case dir.FileTypeFile:
+		header.Typeflag, header.Size = tar.TypeReg, node.Size
+		if header.Mode == 0 {
+			header.Mode = 0o644
+		}
+	default:
+		return errors.Errorf("unsupported file type %v of %v", node.Type, path)
+	}
+
+	if node.ModTime == 0 {
+		header.ModTime = time.Unix(0, 0)
+	}
+
+	if err := writer.WriteHeader(&header); err != nil {
+		return err
+	}
+
+	if node.Type != dir.FileTypeFile || node.Size == 0 {
+		return nil
+	}
+
+	if node.Embedded() {
+		_, err := writer.Write(node.Data)
+		return err
+	}
+
+	clients, _, err := ctrl.getFinalizedFile(c, Cid{Root: node.Root})
+	if err != nil {
+		return errors.WithMessagef(err, "file %v", path)
+	}
+
+	tmpfile, err := downloadToTempFile(c, clients, node.Root)
+	if err != nil {
+		return errors.WithMessagef(err, "file %v", path)
+	}
+	defer os.Remove(tmpfile)
+
+	file, err := os.Open(tmpfile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(writer, file)
+
+	return err
+}
@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"archive/tar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/api"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeTar(t *testing.T) {
+	node := dir.NewDirFsNode("root", []*dir.FsNode{
+		{Name: "a.txt", Type: dir.FileTypeFile, Size: 5, Data: []byte("hello"), Mode: 0o600},
+		dir.NewDirFsNode("sub", []*dir.FsNode{
+			{Name: "b.txt", Type: dir.FileTypeFile, Size: 3, Data: []byte("foo")},
+			{Name: "empty.txt", Type: dir.FileTypeFile},
+		}),
+		dir.NewSymbolicFsNode("link", "a.txt"),
+	})
+	ctrl := NewRestController(nil, nil, 1024)
+
+	recorder := httptest.NewRecorder()
+	c, _ := ginTestContext(recorder, "")
+	assert.Error(t, ctrl.serveTar(c, dir.TreeSourceOf(node), "", nil, testRoot1, true))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/x-tar", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="root.tar"`, recorder.Header().Get("Content-Disposition"))
+	assert.Equal(t, `"`+testRoot1+`?format=tar"`, recorder.Header().Get("ETag"))
+
+	contents := make(map[string]string)
+	types := make(map[string]byte)
+	reader := tar.NewReader(recorder.Body)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+
+		data, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+
+		contents[header.Name] = string(data)
+		types[header.Name] = header.Typeflag
+
+		if header.Name == "a.txt" {
+			assert.Equal(t, int64(0o600), header.Mode)
+		}
+
+		if header.Name == "link" {
+			assert.Equal(t, "a.txt", header.Linkname)
+		}
+	}
+
+	assert.Equal(t, map[string]string{
+		"a.txt":         "hello",
+		"sub/":          "",
+		"sub/b.txt":     "foo",
+		"sub/empty.txt": "",
+		"link":          "",
+	}, contents)
+	assert.Equal(t, byte(tar.TypeDir), types["sub/"])
+	assert.Equal(t, byte(tar.TypeSymlink), types["link"])
+
+	// not modified
+	recorder = httptest.NewRecorder()
+	c, _ = ginTestContext(recorder, `"`+testRoot1+`?format=tar"`)
+	assert.Error(t, ctrl.serveTar(c, dir.TreeSourceOf(node), "", nil, testRoot1, true))
+	c.Writer.WriteHeaderNow()
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+	assert.Zero(t, recorder.Body.Len())
+
+	// total file size limited
+	ctrl = NewRestController(nil, nil, 4)
+	var bizErr *api.BusinessError
+	assert.ErrorAs(t, ctrl.serveTar(c, dir.TreeSourceOf(node), "", nil, testRoot1, true), &bizErr)
+	assert.Equal(t, ErrFileSizeTooLarge.Code, bizErr.Code)
+}
+
+func TestServeDataRange(t *testing.T) {
+	ctrl := NewRestController(nil, nil, 0)
+
+	recorder := httptest.NewRecorder()
+	c, _ := ginTestContext(recorder, "")
+	c.Request.Header.Set("Range", "bytes=1-3")
+	ctrl.serveData(c, testRoot1, true, "a.txt", []byte("hello"))
+	assert.Equal(t, http.StatusPartialContent, recorder.Code)
+	assert.Equal(t, "bytes 1-3/5", recorder.Header().Get("Content-Range"))
+	assert.Equal(t, "ell", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	c, _ = ginTestContext(recorder, "")
+	c.Request.Header.Set("Range", "bytes=10-")
+	ctrl.serveData(c, testRoot1, true, "a.txt", []byte("hello"))
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, recorder.Code)
+}
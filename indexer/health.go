@@ -0,0 +1,93 @@
+package indexer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/node"
+)
+
+const (
+	// transportPenalty is the penalty of transport errors, e.g. DNS, TLS or connection failures, which quarantines
+	// the storage node at once, since the node is likely unreachable for any request.
+	transportPenalty = 1.0
+
+	// applicationPenalty is the penalty of application rejections of storage node, which quarantines the storage
+	// node for later operations after several rejections, since the rejection may be caused by the request rather
+	// than the node. Note, the rejecting node is always dropped by the operation that failed, see dropOnFailure.
+	applicationPenalty = 1.0 / 3
+
+	// quarantineDuration is the duration that a problematic storage node is not selected for uploads, and the
+	// penalties of storage node are forgotten if no failure in the duration.
+	quarantineDuration = 5 * time.Minute
+)
+
+// nodeScore is the health score of a storage node.
+type nodeScore struct {
+	penalty     float64
+	lastFailure time.Time
+	quarantined time.Time // quarantined until
+}
+
+// nodeHealth scores storage nodes by failures of RPCs, so that problematic nodes are quarantined and not selected
+// for uploads for a while. It is safe for concurrent use.
+type nodeHealth struct {
+	mu     sync.Mutex
+	scores map[string]*nodeScore // url -> score
+	now    func() time.Time
+}
+
+func newNodeHealth() *nodeHealth {
+	return &nodeHealth{
+		scores: make(map[string]*nodeScore),
+		now:    time.Now,
+	}
+}
+
+// report reports the RPC failure of storage node, and returns whether the storage node is quarantined.
+func (health *nodeHealth) report(err *node.RPCError) bool {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+
+	now := health.now()
+
+	score, ok := health.scores[err.URL]
+	if !ok || now.Sub(score.lastFailure) > quarantineDuration {
+		score = &nodeScore{}
+		health.scores[err.URL] = score
+	}
+
+	if err.IsTransport() {
+		score.penalty += transportPenalty
+	} else {
+		score.penalty += applicationPenalty
+	}
+	score.lastFailure = now
+
+	// tolerates rounding error of accumulated penalties
+	if score.penalty < 1-1e-9 {
+		return false
+	}
+
+	score.penalty = 0
+	score.quarantined = now.Add(quarantineDuration)
+
+	return true
+}
+
+// quarantinedNodes returns the URLs of storage nodes in quarantine.
+func (health *nodeHealth) quarantinedNodes() []string {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+
+	now := health.now()
+
+	var urls []string
+	for url, score := range health.scores {
+		if now.Before(score.quarantined) {
+			urls = append(urls, url)
+		}
+	}
+
+	return urls
+}
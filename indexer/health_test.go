@@ -0,0 +1,67 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeHealth(t *testing.T) {
+	now := time.Now()
+	health := newNodeHealth()
+	health.now = func() time.Time { return now }
+
+	transport := &node.RPCError{URL: "http://node1", Transport: &node.TransportError{Kind: node.TransportErrorTLS}}
+	rejection := &node.RPCError{URL: "http://node2", Message: "invalid segment"}
+
+	// transport error quarantines at once
+	assert.True(t, health.report(transport))
+	assert.Equal(t, []string{"http://node1"}, health.quarantinedNodes())
+
+	// application rejections quarantine after several failures
+	assert.False(t, health.report(rejection))
+	assert.False(t, health.report(rejection))
+	assert.True(t, health.report(rejection))
+	assert.ElementsMatch(t, []string{"http://node1", "http://node2"}, health.quarantinedNodes())
+
+	// released after quarantine
+	now = now.Add(quarantineDuration)
+	assert.Empty(t, health.quarantinedNodes())
+
+	// penalties forgotten if no failure for a while
+	assert.False(t, health.report(rejection))
+	now = now.Add(quarantineDuration + time.Second)
+	assert.False(t, health.report(rejection))
+	assert.False(t, health.report(rejection))
+	assert.Empty(t, health.quarantinedNodes())
+}
+
+func TestDropOnUploadFailure(t *testing.T) {
+	c := &Client{logger: common.NewLogger(), health: newNodeHealth()}
+	rejection := &node.RPCError{URL: "http://node1", Message: "invalid segment"}
+
+	// rejecting node dropped by the operation at once, but not quarantined for later operations yet
+	dropped, retry := c.dropOnUploadFailure(rejection, nil)
+	assert.True(t, retry)
+	assert.Equal(t, []string{"http://node1"}, dropped)
+	assert.Empty(t, c.health.quarantinedNodes())
+
+	// all failed nodes of parallel upload dropped
+	err := transfer.NodeErrors{
+		{Node: "http://node1", Err: rejection},
+		{Node: "http://node2", Err: &node.RPCError{URL: "http://node2", Message: "invalid segment"}},
+	}
+	dropped, retry = c.dropOnUploadFailure(err, dropped)
+	assert.True(t, retry)
+	assert.Equal(t, []string{"http://node1", "http://node2"}, dropped)
+
+	// not retried on other failures
+	dropped, retry = c.dropOnUploadFailure(errors.New("insufficient balance"), dropped)
+	assert.False(t, retry)
+	assert.Equal(t, 2, len(dropped))
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/util"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -30,6 +31,11 @@ type IPLocationConfig struct {
 	AccessToken        string
 }
 
+// Validate checks the config, and returns an error that names the invalid field if any.
+func (config *IPLocationConfig) Validate() error {
+	return zg_common.RequirePositive("CacheWriteInterval", config.CacheWriteInterval)
+}
+
 // IPLocationManager manages IP locations.
 type IPLocationManager struct {
 	config IPLocationConfig
@@ -37,7 +43,11 @@ type IPLocationManager struct {
 }
 
 // InitDefaultIPLocationManager initializes the default `IPLocationManager`.
-func InitDefaultIPLocationManager(config IPLocationConfig) {
+func InitDefaultIPLocationManager(config IPLocationConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
 	defaultIPLocationManager.config = config
 
 	// try load from cached IP locations
@@ -49,6 +59,8 @@ func InitDefaultIPLocationManager(config IPLocationConfig) {
 	}
 
 	go util.Schedule(defaultIPLocationManager.write, config.CacheWriteInterval, "Failed to write IP locations once")
+
+	return nil
 }
 
 // All returns all cached IP locations.
@@ -0,0 +1,174 @@
+package indexer
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/node"
+	eth_common "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// defaultLocalProbeTimeout is the default timeout to probe the local storage node.
+const defaultLocalProbeTimeout = 5 * time.Second
+
+// LocalNodeOption is the option of storage node co-located with client on the same host, which is preferred to
+// upload and download segments of shards it covers, since it has the lowest latency without egress. Storage
+// nodes selected from indexer service supplement for the other shards.
+type LocalNodeOption struct {
+	URL          string        // URL of local storage node, e.g. http://127.0.0.1:5678
+	MaxSyncLag   uint64        // max number of blocks the log sync of local storage node lags behind trusted storage nodes, 0 for no check
+	ProbeTimeout time.Duration // timeout to probe local storage node, 5 seconds if 0
+}
+
+// probeLocalNode verifies the shard config and sync status of local storage node.
+func (c *Client) probeLocalNode(opt LocalNodeOption) (*shard.ShardedNode, error) {
+	timeout := opt.ProbeTimeout
+	if timeout == 0 {
+		timeout = defaultLocalProbeTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client, err := node.NewZgsClientWithProxy(opt.URL, c.option.proxy(), c.option.ProviderOption)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create client")
+	}
+	defer client.Close()
+
+	start := time.Now()
+	config, err := client.GetShardConfig(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to get shard config")
+	}
+
+	if !config.IsValid() {
+		return nil, errors.Errorf("invalid shard config %v/%v", config.ShardId, config.NumShard)
+	}
+
+	latency := time.Since(start).Milliseconds()
+
+	if opt.MaxSyncLag > 0 {
+		status, err := client.GetStatus(ctx)
+		if err != nil {
+			return nil, errors.WithMessage(err, "Failed to get status")
+		}
+
+		height, err := c.trustedSyncHeight(ctx, opt.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		if height > status.LogSyncHeight+opt.MaxSyncLag {
+			return nil, errors.Errorf("log sync lags behind, local = %v, trusted = %v", status.LogSyncHeight, height)
+		}
+	}
+
+	return &shard.ShardedNode{
+		URL:     opt.URL,
+		Config:  config,
+		Latency: latency,
+	}, nil
+}
+
+// trustedSyncHeight returns the max log sync height of trusted storage nodes other than the specified one.
+func (c *Client) trustedSyncHeight(ctx context.Context, except string) (uint64, error) {
+	nodes, err := c.GetShardedNodes(ctx)
+	if err != nil {
+		return 0, errors.WithMessage(err, "Failed to get trusted storage nodes")
+	}
+
+	var height uint64
+	for _, shardedNode := range nodes.Trusted {
+		if shardedNode.URL == except {
+			continue
+		}
+
+		client, err := node.NewZgsClientWithProxy(shardedNode.URL, c.option.proxy(), c.option.ProviderOption)
+		if err != nil {
+			continue
+		}
+
+		status, err := client.GetStatus(ctx)
+		client.Close()
+		if err != nil {
+			c.logger.Debugf("failed to get status of node %v, ignored.", shardedNode.URL)
+			continue
+		}
+
+		height = max(height, status.LogSyncHeight)
+	}
+
+	return height, nil
+}
+
+// LocalNode returns the local storage node in co-location mode, or nil if not specified or failed to probe.
+func (c *Client) LocalNode() *shard.ShardedNode {
+	return c.local
+}
+
+// localNode returns the local storage node if available, which is not dropped or quarantined, and responds to
+// query of shard config. Otherwise, the failure is reported to health scorer, and nil is returned.
+func (c *Client) localNode(ctx context.Context, dropped []string) *shard.ShardedNode {
+	if c.local == nil || slices.Contains(dropped, c.local.URL) || slices.Contains(c.health.quarantinedNodes(), c.local.URL) {
+		return nil
+	}
+
+	client, err := node.NewZgsClientWithProxy(c.local.URL, c.option.proxy(), c.option.ProviderOption)
+	if err != nil {
+		return nil
+	}
+	defer client.Close()
+
+	start := time.Now()
+	config, err := client.GetShardConfig(ctx)
+	if err != nil || !config.IsValid() {
+		c.reportLocalFailure(err)
+		return nil
+	}
+
+	return &shard.ShardedNode{
+		URL:     c.local.URL,
+		Config:  config,
+		Latency: time.Since(start).Milliseconds(),
+	}
+}
+
+// reportLocalFailure reports the RPC failure of local storage node to health scorer.
+func (c *Client) reportLocalFailure(err error) {
+	var rpcError *node.RPCError
+	if errors.As(err, &rpcError) {
+		c.health.report(rpcError)
+	}
+
+	c.logger.WithError(err).Debug("Local storage node unavailable, fallback to remote nodes")
+}
+
+// localClientHolding returns the client of local storage node if available and the file finalized on it, or nil.
+func (c *Client) localClientHolding(ctx context.Context, root string) *node.ZgsClient {
+	local := c.localNode(ctx, nil)
+	if local == nil {
+		return nil
+	}
+
+	client, err := node.NewZgsClientWithProxy(local.URL, c.option.proxy(), c.option.ProviderOption)
+	if err != nil {
+		return nil
+	}
+
+	info, err := client.GetFileInfo(ctx, eth_common.HexToHash(root))
+	if err != nil || info == nil || !info.Finalized {
+		if err != nil {
+			c.reportLocalFailure(err)
+		}
+
+		client.Close()
+
+		return nil
+	}
+
+	return client
+}
@@ -0,0 +1,131 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// localProxy serves the local storage node by reverse proxy on another URL, and counts segment RPCs.
+type localProxy struct {
+	uploads   atomic.Int32
+	downloads atomic.Int32
+	down      atomic.Bool // responds 502 if down
+}
+
+func newLocalProxy(t *testing.T, target string) (*localProxy, string) {
+	targetURL, err := url.Parse(target)
+	assert.NoError(t, err)
+
+	proxy := &localProxy{}
+	reverse := httputil.NewSingleHostReverseProxy(targetURL)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if proxy.down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if bytes.Contains(body, []byte("zgs_uploadSegment")) {
+			proxy.uploads.Add(1)
+		} else if bytes.Contains(body, []byte("zgs_downloadSegment")) {
+			proxy.downloads.Add(1)
+		}
+
+		reverse.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return proxy, server.URL
+}
+
+func TestLocalNodePreferred(t *testing.T) {
+	// local storage node of shard 0, and remote ones of both shards
+	network := testutil.NewNetwork(t,
+		shard.ShardConfig{ShardId: 0, NumShard: 2},
+		shard.ShardConfig{ShardId: 1, NumShard: 2},
+		shard.ShardConfig{ShardId: 0, NumShard: 2},
+	)
+	proxy, localURL := newLocalProxy(t, network.NodeURLs[0])
+
+	client, err := NewClient(network.IndexerURL, IndexerClientOption{LocalNode: &LocalNodeOption{URL: localURL, MaxSyncLag: 10}})
+	assert.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, localURL, client.LocalNode().URL)
+
+	upload := func() ([]byte, string) {
+		content := make([]byte, 3*core.DefaultSegmentSize)
+		_, err := rand.Read(content)
+		assert.NoError(t, err)
+		data, err := core.NewDataInMemory(content)
+		assert.NoError(t, err)
+		tree, err := core.MerkleTree(data)
+		assert.NoError(t, err)
+
+		_, err = client.Upload(context.Background(), network.Web3(), data)
+		assert.NoError(t, err)
+
+		return content, tree.Root().Hex()
+	}
+
+	download := func(root string, expected []byte) {
+		filename := filepath.Join(t.TempDir(), "file")
+		assert.NoError(t, client.Download(context.Background(), root, filename, true))
+		downloaded, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, downloaded)
+	}
+
+	// uploaded to local storage node of shard 0, and supplemented by the remote one of shard 1
+	content, root := upload()
+	assert.NotZero(t, proxy.uploads.Load())
+	for i, finalized := range []bool{true, true, false} {
+		info, err := network.Nodes[i].GetFileInfo(common.HexToHash(root))
+		assert.NoError(t, err)
+		assert.Equal(t, finalized, info.Finalized, i)
+	}
+
+	// segments 0 and 2 of shard 0 downloaded from local storage node
+	download(root, content)
+	assert.Equal(t, int32(2), proxy.downloads.Load())
+
+	// fallback to remote nodes once local storage node is down, including the one behind
+	proxy.down.Store(true)
+	content, root = upload()
+	download(root, content)
+	var finalized bool
+	for _, i := range []int{0, 2} {
+		info, err := network.Nodes[i].GetFileInfo(common.HexToHash(root))
+		assert.NoError(t, err)
+		finalized = finalized || info.Finalized
+	}
+	assert.True(t, finalized)
+	assert.Equal(t, int32(2), proxy.downloads.Load())
+}
+
+func TestLocalNodeUnavailable(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	proxy, localURL := newLocalProxy(t, network.NodeURLs[0])
+	proxy.down.Store(true)
+
+	client, err := NewClient(network.IndexerURL, IndexerClientOption{LocalNode: &LocalNodeOption{URL: localURL}})
+	assert.NoError(t, err)
+	defer client.Close()
+	assert.Nil(t, client.LocalNode())
+}
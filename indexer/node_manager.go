@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/parallel"
 	"github.com/0glabs/0g-storage-client/common/shard"
 	"github.com/0glabs/0g-storage-client/common/util"
@@ -41,6 +42,26 @@ type NodeManagerConfig struct {
 	UpdateInterval time.Duration
 }
 
+// Validate checks the config, and returns an error that names the invalid field if any.
+func (config *NodeManagerConfig) Validate() error {
+	if len(config.TrustedNodes) == 0 && len(config.DiscoveryNode) == 0 {
+		return zg_common.NewOptionError("TrustedNodes", "either trusted nodes or discovery node should be specified")
+	}
+
+	if len(config.DiscoveryNode) == 0 {
+		return nil
+	}
+
+	if len(config.DiscoveryPorts) == 0 {
+		return zg_common.NewOptionError("DiscoveryPorts", "should be specified to discover peers")
+	}
+
+	return zg_common.FirstError(
+		zg_common.RequirePositive("DiscoveryInterval", config.DiscoveryInterval),
+		zg_common.RequirePositive("UpdateInterval", config.UpdateInterval),
+	)
+}
+
 // NodeManager manages trusted storage nodes and auto discover peers from network.
 type NodeManager struct {
 	trusted sync.Map // url -> *node.ZgsClient
@@ -52,6 +73,10 @@ type NodeManager struct {
 
 // InitDefaultNodeManager initializes the default `NodeManager`.
 func InitDefaultNodeManager(config NodeManagerConfig) (mgr *NodeManager, err error) {
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
+
 	if len(config.DiscoveryNode) > 0 {
 		if defaultNodeManager.discoverNode, err = node.NewAdminClient(config.DiscoveryNode, defaultZgsClientOpt); err != nil {
 			return nil, errors.WithMessage(err, "Failed to create admin client to discover peers")
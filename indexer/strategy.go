@@ -0,0 +1,29 @@
+package indexer
+
+import (
+	"github.com/0glabs/0g-storage-client/common"
+)
+
+// SelectionStrategy is the strategy to select storage nodes from indexer service to upload or download files.
+type SelectionStrategy string
+
+const (
+	// StrategyRandom selects a random subset of storage nodes for each upload, which spreads load evenly, and
+	// downloads segments across storage nodes holding the file. It is the default strategy.
+	StrategyRandom SelectionStrategy = "random"
+
+	// StrategyConsistent selects storage nodes by rendezvous hashing of the file root and node URL, so that the
+	// same file deterministically prefers the same subset of storage nodes across processes and runs, and their
+	// caches stay hot. Storage nodes in quarantine are skipped, while the order of the others is stable.
+	StrategyConsistent SelectionStrategy = "consistent"
+)
+
+// Validate checks the strategy, and returns an error if unsupported. Empty strategy is StrategyRandom.
+func (strategy SelectionStrategy) Validate() error {
+	switch strategy {
+	case "", StrategyRandom, StrategyConsistent:
+		return nil
+	default:
+		return common.NewOptionError("Strategy", "unsupported strategy %q", strategy)
+	}
+}
@@ -0,0 +1,62 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrategyConsistent(t *testing.T) {
+	configs := make([]shard.ShardConfig, 5)
+	for i := range configs {
+		configs[i] = shard.ShardConfig{ShardId: 0, NumShard: 1}
+	}
+	network := testutil.NewNetwork(t, configs...)
+
+	_, err := NewClient(network.IndexerURL, IndexerClientOption{Strategy: "sticky"})
+	assert.Error(t, err)
+
+	selectURLs := func(root common.Hash, dropped []string) []string {
+		client, err := NewClient(network.IndexerURL, IndexerClientOption{Strategy: StrategyConsistent})
+		assert.NoError(t, err)
+		defer client.Close()
+
+		clients, err := client.SelectNodesForRoot(context.Background(), root, shard.UniformReplica(2), dropped)
+		assert.NoError(t, err)
+		defer closeClients(clients)
+
+		urls := make([]string, len(clients))
+		for i, c := range clients {
+			urls[i] = c.URL()
+		}
+		return urls
+	}
+
+	// stable across instantiations
+	root := common.HexToHash("0x1234")
+	expected := selectURLs(root, nil)
+	assert.Equal(t, 2, len(expected))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, expected, selectURLs(root, nil))
+	}
+
+	// nodes in quarantine skipped, while the other selected node kept
+	reselected := selectURLs(root, expected[:1])
+	assert.Equal(t, 2, len(reselected))
+	assert.NotContains(t, reselected, expected[0])
+	assert.Contains(t, reselected, expected[1])
+
+	// spread across roots
+	selected := make(map[string]bool)
+	for i := int64(0); i < 20; i++ {
+		for _, url := range selectURLs(common.BigToHash(big.NewInt(i)), nil) {
+			selected[url] = true
+		}
+	}
+	assert.Greater(t, len(selected), 2)
+}
@@ -3,6 +3,7 @@ package kv
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 
 	"github.com/0glabs/0g-storage-client/node"
 	"github.com/ethereum/go-ethereum/common"
@@ -10,98 +11,108 @@ import (
 
 var errIteratorInvalid = errors.New("iterator is invalid")
 
-// Iterator to iterate over a kv stream
+// ErrConcurrentIteration is returned when an iterator is moved by multiple goroutines at the same time.
+var ErrConcurrentIteration = errors.New("iterator moved concurrently")
+
+// Iterator to iterate over a kv stream.
+//
+// Iterator is stateful and not safe to move concurrently, so use one iterator per goroutine. Moving an iterator
+// while another goroutine is moving it fails with ErrConcurrentIteration, rather than corrupting the position.
 type Iterator struct {
 	client      *Client
 	streamId    common.Hash
 	version     uint64
-	currentPair *node.KeyValue
+	currentPair atomic.Pointer[node.KeyValue]
+	moving      atomic.Bool
 }
 
 // Valid check if current position is exist
 func (iter *Iterator) Valid() bool {
-	return iter.currentPair != nil
+	return iter.currentPair.Load() != nil
 }
 
 // KeyValue return key-value at current position
 func (iter *Iterator) KeyValue() *node.KeyValue {
-	return iter.currentPair
+	return iter.currentPair.Load()
+}
+
+// seek moves to the key-value returned by the specified function, which is called with the current position.
+func (iter *Iterator) seek(ctx context.Context, fn func(current *node.KeyValue) (*node.KeyValue, error)) error {
+	if !iter.moving.CompareAndSwap(false, true) {
+		return ErrConcurrentIteration
+	}
+	defer iter.moving.Store(false)
+
+	kv, err := fn(iter.currentPair.Load())
+	if err != nil {
+		return err
+	}
+
+	return iter.move(ctx, kv)
 }
 
 func (iter *Iterator) move(ctx context.Context, kv *node.KeyValue) error {
 	if kv == nil {
-		iter.currentPair = nil
+		iter.currentPair.Store(nil)
 		return nil
 	}
 	value, err := iter.client.GetValue(ctx, iter.streamId, kv.Key, iter.version)
 	if err != nil {
 		return err
 	}
-	iter.currentPair = &node.KeyValue{
+	iter.currentPair.Store(&node.KeyValue{
 		Version: value.Version,
 		Key:     kv.Key,
 		Data:    value.Data,
 		Size:    value.Size,
-	}
+	})
 	return nil
 }
 
 // SeekBefore seek to the position before given key(inclusive)
 func (iter *Iterator) SeekBefore(ctx context.Context, key []byte) error {
-	kv, err := iter.client.GetPrev(ctx, iter.streamId, key, 0, 0, true, iter.version)
-	if err != nil {
-		return err
-	}
-	return iter.move(ctx, kv)
+	return iter.seek(ctx, func(*node.KeyValue) (*node.KeyValue, error) {
+		return iter.client.GetPrev(ctx, iter.streamId, key, 0, 0, true, iter.version)
+	})
 }
 
 // SeekAfter seek to the position after given key(inclusive)
 func (iter *Iterator) SeekAfter(ctx context.Context, key []byte) error {
-	kv, err := iter.client.GetNext(ctx, iter.streamId, key, 0, 0, true, iter.version)
-	if err != nil {
-		return err
-	}
-	return iter.move(ctx, kv)
+	return iter.seek(ctx, func(*node.KeyValue) (*node.KeyValue, error) {
+		return iter.client.GetNext(ctx, iter.streamId, key, 0, 0, true, iter.version)
+	})
 }
 
 // SeekToFirst seek to the first position
 func (iter *Iterator) SeekToFirst(ctx context.Context) error {
-	kv, err := iter.client.GetFirst(ctx, iter.streamId, 0, 0, iter.version)
-	if err != nil {
-		return err
-	}
-	return iter.move(ctx, kv)
+	return iter.seek(ctx, func(*node.KeyValue) (*node.KeyValue, error) {
+		return iter.client.GetFirst(ctx, iter.streamId, 0, 0, iter.version)
+	})
 }
 
 // SeekToLast seek to the last position
 func (iter *Iterator) SeekToLast(ctx context.Context) error {
-	kv, err := iter.client.GetLast(ctx, iter.streamId, 0, 0, iter.version)
-	if err != nil {
-		return err
-	}
-	return iter.move(ctx, kv)
+	return iter.seek(ctx, func(*node.KeyValue) (*node.KeyValue, error) {
+		return iter.client.GetLast(ctx, iter.streamId, 0, 0, iter.version)
+	})
 }
 
 // Next move to the next position
 func (iter *Iterator) Next(ctx context.Context) error {
-	if !iter.Valid() {
-		return errIteratorInvalid
-	}
-	kv, err := iter.client.GetNext(ctx, iter.streamId, iter.currentPair.Key, 0, 0, false, iter.version)
-	if err != nil {
-		return err
-	}
-	return iter.move(ctx, kv)
+	return iter.seek(ctx, func(current *node.KeyValue) (*node.KeyValue, error) {
+		if current == nil {
+			return nil, errIteratorInvalid
+		}
+		return iter.client.GetNext(ctx, iter.streamId, current.Key, 0, 0, false, iter.version)
+	})
 }
 
 // Prev move to the prev position
 func (iter *Iterator) Prev(ctx context.Context) error {
-	if !iter.Valid() {
-		return errIteratorInvalid
-	}
-	kv, err := iter.client.GetPrev(ctx, iter.streamId, iter.currentPair.Key, 0, 0, false, iter.version)
-	if err != nil {
-		return err
-	}
-	return iter.move(ctx, kv)
+	return iter.seek(ctx, func(current *node.KeyValue) (*node.KeyValue, error) {
+		if current == nil {
+			return nil, errIteratorInvalid
+		}
+		return iter.client.GetPrev(ctx, iter.streamId, current.Key, 0, 0, false, iter.version)
+	})
 }
@@ -2,6 +2,8 @@ package kv
 
 import (
 	"context"
+	"math/big"
+	"sync"
 
 	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/core"
@@ -13,12 +15,24 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// ErrBatcherExecuted is returned when executing a batcher that is being executed or already executed successfully.
+var ErrBatcherExecuted = errors.New("batcher already executed")
+
 // Batcher struct to cache and execute KV write and access control operations.
+//
+// It is safe to cache operations concurrently. However, a batcher could only be executed once, and executing
+// again, either concurrently or after succeeded, fails with ErrBatcherExecuted. Use a new batcher instead.
 type Batcher struct {
 	*streamDataBuilder
 	clients  []*node.ZgsClient
 	w3Client *web3go.Client
 	logger   *logrus.Logger
+
+	maxTxSize int  // max serialized size of stream data in a transaction, 0 for unlimited
+	strict    bool // fail fast instead of splitting into multiple transactions
+
+	mu       sync.Mutex
+	executed bool // whether is being executed or executed successfully
 }
 
 // NewBatcher Initialize a new batcher. Version denotes the expected version of keys to read or write when the cached KV operations is settled on chain.
@@ -31,39 +45,163 @@ func NewBatcher(version uint64, clients []*node.ZgsClient, w3Client *web3go.Clie
 	}
 }
 
+// WithMaxTxSize sets the max serialized size of KV operations to execute in a single transaction, and the cached
+// operations that exceed the size are split into multiple transactions, see ExecAll. By default, the size is
+// unlimited, and only the number of operations in a transaction is limited.
+func (b *Batcher) WithMaxTxSize(size int) *Batcher {
+	b.maxTxSize = size
+	return b
+}
+
+// WithStrict requires all cached KV operations to be executed in a single transaction, and Exec fails fast with
+// ErrBatchTooLarge along with the measured size instead of splitting into multiple transactions.
+func (b *Batcher) WithStrict(strict bool) *Batcher {
+	b.strict = strict
+	return b
+}
+
+// TxResult is the result of a transaction executed by batcher.
+type TxResult struct {
+	TxHash   common.Hash // transaction hash, which is empty if transaction skipped
+	Root     common.Hash // storage root of serialized KV operations
+	Size     int         // serialized size of KV operations
+	Reads    int         // number of keys to read
+	Writes   int         // number of keys to write
+	Controls int         // number of access control operations
+}
+
+// ExecResult is the result of all transactions executed by batcher in order.
+type ExecResult struct {
+	Txs      []TxResult // transactions that executed successfully
+	Size     int        // total serialized size of KV operations
+	Reads    int        // total number of keys to read
+	Writes   int        // total number of keys to write
+	Controls int        // total number of access control operations
+}
+
+func (result *ExecResult) add(tx TxResult) {
+	result.Txs = append(result.Txs, tx)
+	result.Size += tx.Size
+	result.Reads += tx.Reads
+	result.Writes += tx.Writes
+	result.Controls += tx.Controls
+}
+
 // Exec Serialize the cached KV operations in Batcher, then submit the serialized data to 0g storage network.
 // The submission process is the same as uploading a normal file. The batcher should be dropped after execution.
 // Note, this may be time consuming operation, e.g. several seconds or even longer.
 // When it comes to a time sentitive context, it should be executed in a separate go-routine.
+//
+// If KV operations are split into multiple transactions, the hash of the last executed transaction is returned,
+// see ExecAll for more details.
 func (b *Batcher) Exec(ctx context.Context, option ...transfer.UploadOption) (common.Hash, error) {
+	result, err := b.ExecAll(ctx, option...)
+	if result == nil || len(result.Txs) == 0 {
+		return common.Hash{}, err
+	}
+
+	return result.Txs[len(result.Txs)-1].TxHash, err
+}
+
+// ExecAll is similar to Exec, but splits the cached KV operations into multiple transactions at operation
+// boundaries if they could not be executed in a single transaction, e.g. exceeds the max transaction size. A write
+// of key is never split. Transactions are executed one by one in order, and the following ones are not sent until
+// the previous one is uploaded, so that later operations see the effects of earlier ones:
+//   - access controls are executed first in the order they are cached, so that writes are permitted by the
+//     roles granted in the same batch.
+//   - reads are executed before writes, so that versions are checked as if executed in a single transaction.
+//   - writes are executed at last, sorted by stream id and key.
+//
+// All transactions are sent by the same account. If the nonce is specified in option, it is increased by one for
+// each transaction, otherwise the pending nonce is used.
+//
+// Upon failure, the results of succeeded transactions are returned along with the error, and the executed
+// operations are removed from batcher, so that executing again resumes the remaining operations.
+func (b *Batcher) ExecAll(ctx context.Context, option ...transfer.UploadOption) (result *ExecResult, err error) {
+	b.mu.Lock()
+	if b.executed {
+		b.mu.Unlock()
+		return nil, ErrBatcherExecuted
+	}
+	b.executed = true
+	b.mu.Unlock()
+
+	// allow to execute again if failed
+	defer func() {
+		if err != nil {
+			b.mu.Lock()
+			b.executed = false
+			b.mu.Unlock()
+		}
+	}()
+
 	// build stream data
-	streamData, err := b.Build()
+	streamData, err := b.build(true, false)
 	if err != nil {
-		return common.Hash{}, errors.WithMessage(err, "Failed to build stream data")
+		return nil, errors.WithMessage(err, "Failed to build stream data")
 	}
 
-	encoded, err := streamData.Encode()
+	splits, err := splitStreamData(streamData, b.maxTxSize)
 	if err != nil {
-		return common.Hash{}, errors.WithMessage(err, "Failed to encode data")
+		return nil, errors.WithMessage(err, "Failed to split stream data")
 	}
-	data, err := core.NewDataInMemory(encoded)
-	if err != nil {
-		return common.Hash{}, err
+
+	if b.strict && len(splits) > 1 {
+		err = errors.WithMessagef(ErrBatchTooLarge, "size = %v, max = %v, reads = %v, writes = %v, controls = %v, transactions = %v",
+			streamData.Size(), b.maxTxSize, len(streamData.Reads), len(streamData.Writes), len(streamData.Controls), len(splits))
+		return nil, zg_common.WithErrorClass(err, zg_common.ErrorClassUsage)
+	}
+
+	if len(splits) > 1 {
+		b.logger.WithFields(logrus.Fields{
+			"size":         streamData.Size(),
+			"transactions": len(splits),
+		}).Info("Split KV operations into multiple transactions")
 	}
 
 	// upload file
 	uploader, err := transfer.NewUploader(ctx, b.w3Client, b.clients, zg_common.LogOption{Logger: b.logger})
 	if err != nil {
-		return common.Hash{}, err
+		return nil, err
 	}
 	var opt transfer.UploadOption
 	if len(option) > 0 {
 		opt = option[0]
 	}
-	opt.Tags = b.buildTags()
-	txHash, _, err := uploader.Upload(ctx, data, opt)
-	if err != nil {
-		return txHash, errors.WithMessagef(err, "Failed to upload data")
+	nonce := opt.Nonce
+
+	result = &ExecResult{}
+	for i, split := range splits {
+		encoded, err := split.Encode()
+		if err != nil {
+			return result, errors.WithMessage(err, "Failed to encode data")
+		}
+		data, err := core.NewDataInMemory(encoded)
+		if err != nil {
+			return result, err
+		}
+
+		opt.Tags = createTags(split.streamIds()...)
+		if nonce != nil {
+			opt.Nonce = new(big.Int).Add(nonce, big.NewInt(int64(i)))
+		}
+
+		txHash, root, err := uploader.Upload(ctx, data, opt)
+		if err != nil {
+			return result, errors.WithMessagef(err, "Failed to upload data of transaction %v/%v", i+1, len(splits))
+		}
+
+		b.remove(split)
+
+		result.add(TxResult{
+			TxHash:   txHash,
+			Root:     root,
+			Size:     len(encoded),
+			Reads:    len(split.Reads),
+			Writes:   len(split.Writes),
+			Controls: len(split.Controls),
+		})
 	}
-	return txHash, nil
+
+	return result, nil
 }
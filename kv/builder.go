@@ -1,8 +1,10 @@
 package kv
 
 import (
+	"bytes"
 	"errors"
 	"sort"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -20,7 +22,9 @@ var errKeyTooLarge = errors.New("key too large")
 
 var errKeyIsEmpty = errors.New("key is empty")
 
+// streamDataBuilder caches KV operations, which is safe for concurrent use.
 type streamDataBuilder struct {
+	mu        sync.Mutex
 	version   uint64                            // The version of all read and written keys must be less than this value when the cached KV operations are settled on chain.
 	streamIds map[common.Hash]bool              // cached stream ids, used to build tags
 	controls  []accessControl                   // cached access control operations
@@ -41,15 +45,24 @@ func newStreamDataBuilder(version uint64) *streamDataBuilder {
 
 // Build serialize all cached KV operations to StreamData.
 func (builder *streamDataBuilder) Build(sorted ...bool) (*StreamData, error) {
-	var err error
+	return builder.build(len(sorted) > 0 && sorted[0], true)
+}
+
+// build serializes all cached KV operations to StreamData, and the number of operations in each set is not limited
+// unless checkSetSize is true.
+func (builder *streamDataBuilder) build(sorted, checkSetSize bool) (*StreamData, error) {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
 	data := StreamData{
 		Version: builder.version,
 	}
 
 	// controls
-	if data.Controls, err = builder.buildAccessControl(); err != nil {
-		return nil, err
+	if checkSetSize && len(builder.controls) > maxSetSize {
+		return nil, errSizeTooLarge
 	}
+	data.Controls = append(data.Controls, builder.controls...)
 
 	// reads
 	for streamId, keys := range builder.reads {
@@ -66,7 +79,7 @@ func (builder *streamDataBuilder) Build(sorted ...bool) (*StreamData, error) {
 				Key:      key,
 			})
 
-			if len(data.Reads) > maxSetSize {
+			if checkSetSize && len(data.Reads) > maxSetSize {
 				return nil, errSizeTooLarge
 			}
 		}
@@ -88,33 +101,31 @@ func (builder *streamDataBuilder) Build(sorted ...bool) (*StreamData, error) {
 				Data:     d,
 			})
 
-			if len(data.Writes) > maxSetSize {
+			if checkSetSize && len(data.Writes) > maxSetSize {
 				return nil, errSizeTooLarge
 			}
 		}
 	}
 
-	if len(sorted) > 0 {
-		if sorted[0] {
-			sort.SliceStable(data.Reads, func(i, j int) bool {
-				streamIdI := data.Reads[i].StreamId.Hex()
-				streamIdJ := data.Reads[j].StreamId.Hex()
-				if streamIdI == streamIdJ {
-					return hexutil.Encode(data.Reads[i].Key) < hexutil.Encode(data.Reads[j].Key)
-				} else {
-					return streamIdI < streamIdJ
-				}
-			})
-			sort.SliceStable(data.Writes, func(i, j int) bool {
-				streamIdI := data.Writes[i].StreamId.Hex()
-				streamIdJ := data.Writes[j].StreamId.Hex()
-				if streamIdI == streamIdJ {
-					return hexutil.Encode(data.Writes[i].Key) < hexutil.Encode(data.Writes[j].Key)
-				} else {
-					return streamIdI < streamIdJ
-				}
-			})
-		}
+	if sorted {
+		sort.SliceStable(data.Reads, func(i, j int) bool {
+			streamIdI := data.Reads[i].StreamId.Hex()
+			streamIdJ := data.Reads[j].StreamId.Hex()
+			if streamIdI == streamIdJ {
+				return hexutil.Encode(data.Reads[i].Key) < hexutil.Encode(data.Reads[j].Key)
+			} else {
+				return streamIdI < streamIdJ
+			}
+		})
+		sort.SliceStable(data.Writes, func(i, j int) bool {
+			streamIdI := data.Writes[i].StreamId.Hex()
+			streamIdJ := data.Writes[j].StreamId.Hex()
+			if streamIdI == streamIdJ {
+				return hexutil.Encode(data.Writes[i].Key) < hexutil.Encode(data.Writes[j].Key)
+			} else {
+				return streamIdI < streamIdJ
+			}
+		})
 	}
 
 	return &data, nil
@@ -124,7 +135,30 @@ func (builder *streamDataBuilder) addStreamId(streamId common.Hash) {
 	builder.streamIds[streamId] = true
 }
 
+// touchedStreamIds returns the ids of streams touched by cached KV operations, including streams only watched,
+// whose keys guard the writes and are not tagged.
+func (builder *streamDataBuilder) touchedStreamIds() []common.Hash {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
+	ids := make([]common.Hash, 0, len(builder.streamIds))
+	for id := range builder.streamIds {
+		ids = append(ids, id)
+	}
+
+	for id, keys := range builder.reads {
+		if len(keys) > 0 && !builder.streamIds[id] {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
 func (builder *streamDataBuilder) buildTags(sorted ...bool) []byte {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
 	var ids []common.Hash
 
 	for k := range builder.streamIds {
@@ -144,12 +178,18 @@ func (builder *streamDataBuilder) buildTags(sorted ...bool) []byte {
 
 // SetVersion Set the expected version of keys.
 func (builder *streamDataBuilder) SetVersion(version uint64) *streamDataBuilder {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
 	builder.version = version
 	return builder
 }
 
 // Watch Cache a read key operation.
 func (builder *streamDataBuilder) Watch(streamId common.Hash, key []byte) *streamDataBuilder {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
 	if keys, ok := builder.reads[streamId]; ok {
 		keys[hexutil.Encode(key)] = true
 	} else {
@@ -162,6 +202,9 @@ func (builder *streamDataBuilder) Watch(streamId common.Hash, key []byte) *strea
 
 // Set Cache a write key operation.
 func (builder *streamDataBuilder) Set(streamId common.Hash, key []byte, data []byte) *streamDataBuilder {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
 	builder.addStreamId(streamId)
 
 	if keys, ok := builder.writes[streamId]; ok {
@@ -174,15 +217,44 @@ func (builder *streamDataBuilder) Set(streamId common.Hash, key []byte, data []b
 	return builder
 }
 
-func (builder *streamDataBuilder) buildAccessControl() ([]accessControl, error) {
-	if len(builder.controls) > maxSetSize {
-		return nil, errSizeTooLarge
+// remove removes the executed operations from cache, so that operations cached after built are retained. Note,
+// the executed access controls must be the first ones that cached.
+func (builder *streamDataBuilder) remove(data *StreamData) {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
+	builder.controls = builder.controls[len(data.Controls):]
+
+	written := make(map[common.Hash]bool)
+	for _, v := range data.Writes {
+		written[v.StreamId] = true
+
+		// retain the value that is set again after built
+		if d, ok := builder.writes[v.StreamId][hexutil.Encode(v.Key)]; ok && bytes.Equal(d, v.Data) {
+			delete(builder.writes[v.StreamId], hexutil.Encode(v.Key))
+		}
+	}
+
+	// watched reads of streams without writes guard the remaining writes, see splitStreamData
+	remaining := false
+	for _, keys := range builder.writes {
+		if len(keys) > 0 {
+			remaining = true
+			break
+		}
 	}
 
-	return builder.controls, nil
+	for _, v := range data.Reads {
+		if written[v.StreamId] || !remaining {
+			delete(builder.reads[v.StreamId], hexutil.Encode(v.Key))
+		}
+	}
 }
 
 func (builder *streamDataBuilder) withControl(t accessControlType, streamId common.Hash, account *common.Address, key []byte) *streamDataBuilder {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
 	builder.addStreamId(streamId)
 
 	builder.controls = append(builder.controls, accessControl{
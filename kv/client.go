@@ -9,6 +9,8 @@ import (
 )
 
 // Client client to query data from 0g kv node.
+//
+// Client is stateless and safe for concurrent use by multiple goroutines, but iterators created by client are not.
 type Client struct {
 	node *node.KvClient
 }
@@ -20,6 +22,12 @@ func NewClient(node *node.KvClient) *Client {
 	}
 }
 
+// Close implements the io.Closer interface. Note, the underlying kv node client is owned by caller, and will not
+// be closed, so caller should close it once no longer in use. It is safe to close for multiple times.
+func (c *Client) Close() error {
+	return nil
+}
+
 // NewIterator creates an iterator.
 func (c *Client) NewIterator(streamId common.Hash, version ...uint64) *Iterator {
 	var v uint64
@@ -28,10 +36,9 @@ func (c *Client) NewIterator(streamId common.Hash, version ...uint64) *Iterator
 		v = version[0]
 	}
 	return &Iterator{
-		client:      c,
-		streamId:    streamId,
-		version:     v,
-		currentPair: nil,
+		client:   c,
+		streamId: streamId,
+		version:  v,
 	}
 }
 
@@ -0,0 +1,179 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	gorpc "github.com/openweb3/go-rpc-provider"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockKvService is a kv node that serves sorted keys of a single stream in memory.
+type mockKvService struct {
+	keys    [][]byte
+	values  map[string][]byte
+	blocked chan struct{} // GetFirst blocks until closed if not nil
+}
+
+func newMockKvService(numKeys int) *mockKvService {
+	service := &mockKvService{values: make(map[string][]byte)}
+
+	for i := 0; i < numKeys; i++ {
+		key := []byte(fmt.Sprintf("key-%03d", i))
+		service.keys = append(service.keys, key)
+		service.values[string(key)] = []byte(fmt.Sprintf("value-%v", i))
+	}
+
+	return service
+}
+
+func (service *mockKvService) keyValue(index int) *node.KeyValue {
+	if index < 0 || index >= len(service.keys) {
+		return nil
+	}
+
+	return &node.KeyValue{Key: service.keys[index]}
+}
+
+func (service *mockKvService) GetValue(streamId common.Hash, key []byte, startIndex, length, version uint64) (*node.Value, error) {
+	data := service.values[string(key)]
+	end := min(startIndex+length, uint64(len(data)))
+
+	return &node.Value{Version: 1, Data: data[startIndex:end], Size: uint64(len(data))}, nil
+}
+
+func (service *mockKvService) GetFirst(streamId common.Hash, startIndex, length, version uint64) (*node.KeyValue, error) {
+	if service.blocked != nil {
+		<-service.blocked
+	}
+
+	return service.keyValue(0), nil
+}
+
+func (service *mockKvService) GetNext(streamId common.Hash, key []byte, startIndex, length uint64, inclusive bool, version uint64) (*node.KeyValue, error) {
+	index := sort.Search(len(service.keys), func(i int) bool {
+		cmp := bytes.Compare(service.keys[i], key)
+		return cmp > 0 || (inclusive && cmp == 0)
+	})
+
+	return service.keyValue(index), nil
+}
+
+func newMockKvClient(t *testing.T, service *mockKvService) *Client {
+	server := gorpc.NewServer()
+	assert.NoError(t, server.RegisterName("kv", service))
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+
+	client, err := node.NewKvClient(httpServer.URL)
+	assert.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return NewClient(client)
+}
+
+func TestConcurrentIterators(t *testing.T) {
+	service := newMockKvService(20)
+	client := newMockKvClient(t, service)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var keys [][]byte
+			iter := client.NewIterator(common.Hash{})
+			for err := iter.SeekToFirst(context.Background()); iter.Valid(); err = iter.Next(context.Background()) {
+				assert.NoError(t, err)
+				keys = append(keys, iter.KeyValue().Key)
+				assert.Equal(t, service.values[string(iter.KeyValue().Key)], iter.KeyValue().Data)
+			}
+
+			assert.Equal(t, service.keys, keys)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestIteratorMovedConcurrently(t *testing.T) {
+	service := newMockKvService(3)
+	service.blocked = make(chan struct{})
+	client := newMockKvClient(t, service)
+	iter := client.NewIterator(common.Hash{})
+
+	done := make(chan error)
+	go func() {
+		done <- iter.SeekToFirst(context.Background())
+	}()
+
+	// wait for the iterator to be moving
+	for !iter.moving.Load() {
+		runtime.Gosched()
+	}
+
+	assert.ErrorIs(t, iter.SeekAfter(context.Background(), service.keys[1]), ErrConcurrentIteration)
+
+	close(service.blocked)
+	assert.NoError(t, <-done)
+	assert.Equal(t, service.keys[0], iter.KeyValue().Key)
+
+	// iterator is usable once the concurrent move completed
+	assert.NoError(t, iter.Next(context.Background()))
+	assert.Equal(t, service.keys[1], iter.KeyValue().Key)
+}
+
+func TestConcurrentBatcher(t *testing.T) {
+	batcher := NewBatcher(1, nil, nil)
+	streamId := common.HexToHash("0x01")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 10; j++ {
+				key := []byte(fmt.Sprintf("key-%v-%v", i, j))
+				batcher.Set(streamId, key, key).Watch(streamId, key)
+				batcher.GrantWriteRole(streamId, common.BigToAddress(common.Big1))
+				_, err := batcher.Build()
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := batcher.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, 80, len(data.Writes))
+	assert.Equal(t, 80, len(data.Reads))
+	assert.Equal(t, 80, len(data.Controls))
+}
+
+func TestBatcherExecuted(t *testing.T) {
+	batcher := NewBatcher(1, nil, nil)
+
+	// failed to execute, and could be executed again
+	batcher.Set(common.HexToHash("0x01"), nil, []byte("value"))
+	_, err := batcher.Exec(context.Background())
+	assert.ErrorIs(t, err, errKeyIsEmpty)
+	_, err = batcher.Exec(context.Background())
+	assert.ErrorIs(t, err, errKeyIsEmpty)
+
+	// being executed or executed successfully
+	batcher.executed = true
+	_, err = batcher.Exec(context.Background())
+	assert.ErrorIs(t, err, ErrBatcherExecuted)
+}
@@ -1,2 +1,9 @@
 // Package kv defines structures to interact with 0g storage kv.
+//
+// Multiple writers of a stream could coordinate to write one at a time by a cooperative lease, see AcquireLease.
+//
+// Batches of independent streams could be executed concurrently, while batches of the same stream are executed in
+// order, see ExecBatches.
+//
+// Client, Batcher and Lease are safe for concurrent use, while Iterator is stateful and should be used by one goroutine.
 package kv
@@ -0,0 +1,84 @@
+package kv
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteReadE2E(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	client := NewClient(network.KvClient())
+	streamId := common.HexToHash("0x01")
+
+	// files other than kv stream data are skipped
+	_, err := network.Chain.Submit([]byte("not kv"), nil)
+	assert.NoError(t, err)
+
+	batcher := NewBatcher(math.MaxUint64, network.ZgsClients(), network.Web3())
+	batcher.Set(streamId, []byte("a"), []byte("value a"))
+	batcher.Set(streamId, []byte("b"), []byte("value b"))
+	_, err = batcher.Exec(context.Background())
+	assert.NoError(t, err)
+
+	for key, expected := range map[string]string{"a": "value a", "b": "value b"} {
+		value, err := client.GetValue(context.Background(), streamId, []byte(key))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(value.Data))
+	}
+
+	ids, err := client.GetHoldingStreamIds(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []common.Hash{streamId}, ids)
+
+	// iterate in order
+	iter := client.NewIterator(streamId)
+	assert.NoError(t, iter.SeekToFirst(context.Background()))
+	var keys []string
+	for ; iter.Valid(); assert.NoError(t, iter.Next(context.Background())) {
+		keys = append(keys, string(iter.KeyValue().Key))
+	}
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	// key updated after the version to read
+	batcher = NewBatcher(0, network.ZgsClients(), network.Web3())
+	batcher.Watch(streamId, []byte("a"))
+	batcher.Set(streamId, []byte("b"), []byte("updated"))
+	result, err := batcher.ExecAll(context.Background())
+	assert.NoError(t, err)
+
+	info, err := network.Nodes[0].GetFileInfo(result.Txs[0].Root)
+	assert.NoError(t, err)
+	txResult, err := client.GetTransactionResult(context.Background(), info.Tx.Seq)
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.KvResultVersionConfliction, txResult)
+
+	value, err := client.GetValue(context.Background(), streamId, []byte("b"))
+	assert.NoError(t, err)
+	assert.Equal(t, "value b", string(value.Data))
+}
+
+func TestExecSplitE2E(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	client := NewClient(network.KvClient())
+	streamId := common.HexToHash("0x01")
+
+	// each write executed in a separate transaction
+	batcher := NewBatcher(math.MaxUint64, network.ZgsClients(), network.Web3()).WithMaxTxSize(200)
+	batcher.Set(streamId, []byte("a"), make([]byte, 50))
+	batcher.Set(streamId, []byte("b"), make([]byte, 50))
+	result, err := batcher.ExecAll(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(result.Txs))
+	assert.Equal(t, 2, len(network.Chain.Submissions()))
+
+	for _, key := range []string{"a", "b"} {
+		value, err := client.GetValue(context.Background(), streamId, []byte(key))
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(50), value.Size)
+	}
+}
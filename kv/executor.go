@@ -0,0 +1,151 @@
+package kv
+
+import (
+	"context"
+	"sync"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxInFlightBatches is the default max number of batches executed concurrently by ExecBatches.
+const DefaultMaxInFlightBatches = 4
+
+var (
+	// ErrDependencyFailed is returned for a batch that is not executed, since an earlier batch touching the same
+	// stream failed.
+	ErrDependencyFailed = errors.New("earlier batch of the same stream failed")
+
+	// ErrExecAborted is returned for a batch that is not executed, since another batch failed in fail-fast mode.
+	ErrExecAborted = errors.New("execution aborted")
+)
+
+// ExecBatchesOption is the option to execute batches in parallel.
+type ExecBatchesOption struct {
+	MaxInFlight int                   // max number of batches executed concurrently, DefaultMaxInFlightBatches if 0
+	FailFast    bool                  // abort the batches not executed yet once any batch failed
+	Upload      transfer.UploadOption // option to execute each batch, of which the nonce should not be specified
+}
+
+// Validate checks the option, and returns an error if any field is invalid.
+func (opt ExecBatchesOption) Validate() error {
+	var nonceErr error
+	if opt.Upload.Nonce != nil {
+		nonceErr = zg_common.NewOptionError("Upload.Nonce", "should not be specified, since transactions are sent concurrently with the pending nonce")
+	}
+
+	return zg_common.FirstError(
+		zg_common.RequireNonNegative("MaxInFlight", opt.MaxInFlight),
+		nonceErr,
+	)
+}
+
+// BatchResult is the result of a batch executed by ExecBatches.
+type BatchResult struct {
+	Index  int         // index of batch in the specified batchers
+	Result *ExecResult // transactions executed, which may be partial upon failure, see Batcher.ExecAll
+	Err    error
+}
+
+// ExecBatches executes the batches in parallel, and returns a channel of the result of each batch as soon as it
+// completes, which is closed once all batches completed.
+//
+// Batches touching the same stream are executed one by one in the specified order, so that later ones see the
+// effects of earlier ones, while batches of independent streams are executed concurrently. A batch is not executed
+// if any earlier batch touching the same stream failed, and fails with ErrDependencyFailed. Failure of a batch does
+// not abort batches of other streams unless FailFast specified, in which case batches not executed yet fail with
+// ErrExecAborted.
+//
+// Transactions are sent by the same account of each batch in sequence with the pending nonce, see
+// transfer.Uploader.SubmitLogEntry, so the nonce should not be specified in option.
+func ExecBatches(ctx context.Context, batchers []*Batcher, option ...ExecBatchesOption) (<-chan BatchResult, error) {
+	var opt ExecBatchesOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	if err := opt.Validate(); err != nil {
+		return nil, zg_common.WithErrorClass(err, zg_common.ErrorClassUsage)
+	}
+
+	if opt.MaxInFlight == 0 {
+		opt.MaxInFlight = DefaultMaxInFlightBatches
+	}
+
+	// each batch depends on the last earlier batch of each stream it touches
+	deps := make([][]int, len(batchers))
+	last := make(map[common.Hash]int)
+	for i, batcher := range batchers {
+		for _, streamId := range batcher.touchedStreamIds() {
+			if j, ok := last[streamId]; ok {
+				deps[i] = append(deps[i], j)
+			}
+			last[streamId] = i
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make([]chan struct{}, len(batchers))
+	errs := make([]error, len(batchers))
+	inFlight := make(chan struct{}, opt.MaxInFlight)
+	results := make(chan BatchResult, len(batchers))
+
+	var wg sync.WaitGroup
+	for i := range batchers {
+		done[i] = make(chan struct{})
+	}
+
+	for i := range batchers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+
+			result := BatchResult{Index: i}
+			result.Result, result.Err = execBatch(ctx, batchers[i], deps[i], done, errs, inFlight, opt.Upload)
+
+			errs[i] = result.Err
+			if result.Err != nil && opt.FailFast {
+				cancel(errors.WithMessagef(ErrExecAborted, "batch %v failed", i))
+			}
+
+			results <- result
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		cancel(nil)
+		close(results)
+	}()
+
+	return results, nil
+}
+
+// execBatch executes the batch once the dependent batches succeeded, and an in-flight slot acquired.
+func execBatch(ctx context.Context, batcher *Batcher, deps []int, done []chan struct{}, errs []error,
+	inFlight chan struct{}, opt transfer.UploadOption) (*ExecResult, error) {
+	for _, j := range deps {
+		<-done[j]
+
+		if errs[j] != nil {
+			return nil, errors.WithMessagef(ErrDependencyFailed, "batch = %v", j)
+		}
+	}
+
+	select {
+	case inFlight <- struct{}{}:
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+	defer func() { <-inFlight }()
+
+	// aborted while waiting for slot
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+
+	return batcher.ExecAll(ctx, opt)
+}
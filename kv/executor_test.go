@@ -0,0 +1,185 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// segmentUploadGate tracks the number of batches uploading segments concurrently, and blocks the first upload
+// until another one arrives or timeout, so that concurrent batches are observed deterministically.
+type segmentUploadGate struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	arrived     chan struct{}
+	once        sync.Once
+}
+
+func hookSegmentUploadGate(clients []*node.ZgsClient) *segmentUploadGate {
+	gate := &segmentUploadGate{arrived: make(chan struct{})}
+
+	for _, client := range clients {
+		client.HookCallContext(func(handler providers.CallContextFunc) providers.CallContextFunc {
+			return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+				if method != "zgs_uploadSegmentsByTxSeq" {
+					return handler(ctx, result, method, args...)
+				}
+
+				gate.mu.Lock()
+				gate.inFlight++
+				gate.maxInFlight = max(gate.maxInFlight, gate.inFlight)
+				if gate.inFlight > 1 {
+					gate.once.Do(func() { close(gate.arrived) })
+				}
+				gate.mu.Unlock()
+
+				select {
+				case <-gate.arrived:
+				case <-time.After(3 * time.Second):
+				case <-ctx.Done():
+				}
+
+				defer func() {
+					gate.mu.Lock()
+					gate.inFlight--
+					gate.mu.Unlock()
+				}()
+
+				return handler(ctx, result, method, args...)
+			}
+		})
+	}
+
+	return gate
+}
+
+func TestExecBatches(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	clients := network.ZgsClients()
+	gate := hookSegmentUploadGate(clients)
+	client := NewClient(network.KvClient())
+
+	// 3 batches of stream 1 and 2 batches of stream 2
+	streamIds := []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x01")}
+	var batchers []*Batcher
+	for i, streamId := range streamIds {
+		batcher := NewBatcher(math.MaxUint64, clients, network.Web3())
+		batcher.Set(streamId, []byte("key"), []byte(fmt.Sprintf("value %v", i)))
+		batchers = append(batchers, batcher)
+	}
+
+	results, err := ExecBatches(context.Background(), batchers, ExecBatchesOption{MaxInFlight: 2})
+	assert.NoError(t, err)
+
+	// batches of the same stream completed in order
+	completed := make(map[common.Hash][]int)
+	for result := range results {
+		assert.NoError(t, result.Err)
+		assert.Equal(t, 1, len(result.Result.Txs))
+		completed[streamIds[result.Index]] = append(completed[streamIds[result.Index]], result.Index)
+	}
+	assert.Equal(t, []int{0, 2, 4}, completed[streamIds[0]])
+	assert.Equal(t, []int{1, 3}, completed[streamIds[1]])
+	assert.Equal(t, 5, len(network.Chain.Submissions()))
+
+	// batches of independent streams executed concurrently
+	assert.Equal(t, 2, gate.maxInFlight)
+
+	for streamId, expected := range map[common.Hash]string{streamIds[0]: "value 4", streamIds[1]: "value 3"} {
+		value, err := client.GetValue(context.Background(), streamId, []byte("key"))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(value.Data))
+	}
+
+	// nonce not allowed
+	_, err = ExecBatches(context.Background(), batchers, ExecBatchesOption{Upload: transfer.UploadOption{Nonce: big.NewInt(1)}})
+	assert.Error(t, err)
+}
+
+func TestExecBatchesFailure(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	clients := network.ZgsClients()
+
+	newBatchers := func() []*Batcher {
+		// batch of stream 1 failed to execute in strict mode
+		failed := NewBatcher(math.MaxUint64, clients, network.Web3()).WithMaxTxSize(1).WithStrict(true)
+		failed.Set(common.HexToHash("0x01"), []byte("key"), []byte("value"))
+
+		batchers := []*Batcher{failed}
+		for _, streamId := range []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02")} {
+			batcher := NewBatcher(math.MaxUint64, clients, network.Web3())
+			batcher.Set(streamId, []byte("key"), []byte("value"))
+			batchers = append(batchers, batcher)
+		}
+
+		return batchers
+	}
+
+	collect := func(results <-chan BatchResult) []error {
+		errs := make([]error, 3)
+		for result := range results {
+			errs[result.Index] = result.Err
+		}
+		return errs
+	}
+
+	// batch of the same stream not executed, but other streams not affected
+	results, err := ExecBatches(context.Background(), newBatchers())
+	assert.NoError(t, err)
+	errs := collect(results)
+	assert.ErrorIs(t, errs[0], ErrBatchTooLarge)
+	assert.ErrorIs(t, errs[1], ErrDependencyFailed)
+	assert.NoError(t, errs[2])
+	assert.Equal(t, 1, len(network.Chain.Submissions()))
+
+	// other streams aborted in fail-fast mode, which block until aborted
+	blocked := make(chan struct{})
+	defer close(blocked)
+	clients[0].HookCallContext(func(handler providers.CallContextFunc) providers.CallContextFunc {
+		return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+			if method == "zgs_uploadSegmentsByTxSeq" {
+				select {
+				case <-blocked:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			return handler(ctx, result, method, args...)
+		}
+	})
+
+	results, err = ExecBatches(context.Background(), newBatchers(), ExecBatchesOption{FailFast: true})
+	assert.NoError(t, err)
+	errs = collect(results)
+	assert.ErrorIs(t, errs[0], ErrBatchTooLarge)
+	assert.ErrorIs(t, errs[1], ErrDependencyFailed)
+	assert.True(t, errors.Is(errs[2], ErrExecAborted) || errors.Is(errs[2], context.Canceled), errs[2])
+}
+
+func TestTouchedStreamIds(t *testing.T) {
+	written, watched := common.HexToHash("0x01"), common.HexToHash("0x02")
+
+	batcher := NewBatcher(math.MaxUint64, nil, nil)
+	batcher.Watch(watched, []byte("version"))
+	batcher.Set(written, []byte("key"), []byte("value"))
+
+	// batches that only watch a stream still depend on earlier writes of the stream
+	assert.ElementsMatch(t, []common.Hash{written, watched}, batcher.touchedStreamIds())
+
+	// watched streams are not tagged
+	assert.Equal(t, createTags(written), batcher.buildTags())
+}
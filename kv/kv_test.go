@@ -5,8 +5,10 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/0glabs/0g-storage-client/node"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
 )
 
 var hashCount *big.Int
@@ -144,3 +146,18 @@ func TestKVBuilder(t *testing.T) {
 	assert.Equal(t, true, bytes.Equal(expectedData, encoded), "chunk data not match")
 	assert.Equal(t, true, bytes.Equal(expectedTags, tags), "tags not match")
 }
+
+func TestClientClose(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	kvNode, err := node.NewKvClient("http://127.0.0.1:6789")
+	assert.NoError(t, err)
+	defer kvNode.Close()
+
+	client := NewClient(kvNode)
+	assert.NoError(t, client.Close())
+	assert.NoError(t, client.Close())
+
+	// kv node client is owned by caller, and still available
+	assert.Equal(t, "http://127.0.0.1:6789", kvNode.URL())
+}
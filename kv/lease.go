@@ -0,0 +1,288 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultLeaseClockSkew is the tolerance of clock skew between lease holders by default.
+	DefaultLeaseClockSkew = 5 * time.Second
+
+	// defaultLeasePollInterval is the interval to poll the replay result of lease record by default.
+	defaultLeasePollInterval = time.Second
+
+	// txResultCommit is the replay result of kv transaction that committed.
+	txResultCommit = "Commit"
+)
+
+var (
+	// ErrLeaseHeld is returned when acquiring a lease that is held by another owner and not expired yet.
+	ErrLeaseHeld = errors.New("lease held by another owner")
+
+	// ErrLeaseConflict is returned when the lease record is updated by others concurrently, e.g. another owner
+	// acquired the lease at the same time.
+	ErrLeaseConflict = errors.New("lease record updated concurrently")
+
+	// ErrLeaseExpired is returned when the lease of caller is expired, and should be acquired again.
+	ErrLeaseExpired = errors.New("lease expired")
+)
+
+// BatcherFactory creates a batcher with the specified expected version, e.g. bound to the storage nodes and
+// account of caller, see NewBatcher.
+type BatcherFactory func(version uint64) *Batcher
+
+// LeaseOption is the option to acquire a lease.
+type LeaseOption struct {
+	// ClockSkew is the tolerance of clock skew between lease holders, DefaultLeaseClockSkew by default. Holder
+	// considers its lease expired ClockSkew earlier, while others consider the lease expired ClockSkew later.
+	ClockSkew time.Duration
+
+	// PollInterval is the interval to poll the replay result of lease record on kv node, 1 second by default.
+	PollInterval time.Duration
+}
+
+// timeNow returns the current time, which is replaced in tests.
+var timeNow = time.Now
+
+// leaseRecord is the lease record stored in kv stream.
+type leaseRecord struct {
+	Owner  string `json:"owner"`
+	Expiry int64  `json:"expiry"` // unix time in milliseconds
+}
+
+// Lease is a cooperative lease built on kv primitives, so that multiple writers of a kv stream could coordinate
+// to write one at a time, instead of colliding with each other by version confliction.
+//
+// The lease is a record of owner and expiry stored in a key of kv stream, which is written with the version read
+// before, so that concurrent writers are detected by version confliction, and only one of them wins.
+//
+// Note, the lease is best-effort, and at most one holder is NOT guaranteed exactly:
+//   - Expiry is determined by the local clock of each holder, so clocks must not skew more than ClockSkew.
+//   - Transactions take time to settle on chain, e.g. seconds or even longer, so a batch executed right before
+//     expiry may settle after the lease is acquired by another owner. TTL should be much longer than the chain
+//     latency, and the lease should be renewed well before expiry.
+//   - Batches executed by Exec are not bound to the lease on chain, so that kv node never rejects writes of
+//     expired holders.
+//
+// So, writers should still check versions of keys to detect conflicts, and the lease only reduces collisions.
+//
+// Lease is safe for concurrent use.
+type Lease struct {
+	factory  BatcherFactory
+	client   *Client
+	streamId common.Hash
+	key      []byte
+	owner    string
+	ttl      time.Duration
+	opt      LeaseOption
+
+	mu      sync.Mutex
+	expiry  time.Time // expiry of lease, zero if released
+	version uint64    // version of lease record written by holder, i.e. tx seq
+}
+
+// AcquireLease acquires the lease of owner stored in the leaseKey of kv stream for ttl, and returns ErrLeaseHeld
+// if held by another owner, or ErrLeaseConflict if acquired by another owner concurrently. It is allowed to
+// acquire again by the same owner, e.g. after restarted.
+//
+// The lease record is written by batcher created by batcherFactory, and read from kv node by client.
+func AcquireLease(ctx context.Context, batcherFactory BatcherFactory, client *Client, streamId common.Hash,
+	leaseKey []byte, owner string, ttl time.Duration, option ...LeaseOption) (*Lease, error) {
+	if len(owner) == 0 {
+		return nil, errors.New("owner is empty")
+	}
+
+	if ttl <= 0 {
+		return nil, errors.New("ttl should be positive")
+	}
+
+	lease := &Lease{
+		factory:  batcherFactory,
+		client:   client,
+		streamId: streamId,
+		key:      leaseKey,
+		owner:    owner,
+		ttl:      ttl,
+	}
+
+	if len(option) > 0 {
+		lease.opt = option[0]
+	}
+
+	if lease.opt.ClockSkew == 0 {
+		lease.opt.ClockSkew = DefaultLeaseClockSkew
+	}
+
+	if lease.opt.PollInterval == 0 {
+		lease.opt.PollInterval = defaultLeasePollInterval
+	}
+
+	value, err := client.GetValue(ctx, streamId, leaseKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to get lease record")
+	}
+
+	if len(value.Data) > 0 {
+		var record leaseRecord
+		if err = json.Unmarshal(value.Data, &record); err != nil {
+			return nil, errors.WithMessage(err, "Failed to decode lease record")
+		}
+
+		// others consider the lease expired later to tolerate clock skew
+		expiry := time.UnixMilli(record.Expiry)
+		if record.Owner != owner && timeNow().Before(expiry.Add(lease.opt.ClockSkew)) {
+			return nil, errors.WithMessagef(ErrLeaseHeld, "owner = %v, expiry = %v", record.Owner, expiry)
+		}
+	}
+
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+
+	if err = lease.writeLocked(ctx, value.Version, lease.owner); err != nil {
+		return nil, err
+	}
+
+	if !lease.validLocked() {
+		return nil, errors.WithMessagef(ErrLeaseExpired, "acquired after expiry, ttl = %v", ttl)
+	}
+
+	return lease, nil
+}
+
+// writeLocked writes the lease record of owner with the specified version, and waits for the record replayed on
+// kv node. An empty owner releases the lease.
+func (lease *Lease) writeLocked(ctx context.Context, version uint64, owner string) error {
+	// expiry starts before executed, so that holder considers the lease expired earlier than others
+	expiry := timeNow().Add(lease.ttl)
+
+	var data []byte
+	if len(owner) > 0 {
+		data, _ = json.Marshal(leaseRecord{owner, expiry.UnixMilli()})
+	}
+
+	batcher := lease.factory(version)
+	batcher.Set(lease.streamId, lease.key, data)
+
+	result, err := batcher.ExecAll(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to write lease record")
+	}
+
+	// kv node replays transactions asynchronously, so wait for the lease record replayed
+	info, err := batcher.clients[0].GetFileInfo(ctx, result.Txs[0].Root)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to get file info of lease record")
+	}
+
+	if info == nil {
+		return errors.Errorf("File info of lease record not found, root = %v", result.Txs[0].Root)
+	}
+
+	if err = lease.waitCommitted(ctx, info.Tx.Seq); err != nil {
+		return err
+	}
+
+	// the record may be overwritten by others right after replayed
+	value, err := lease.client.GetValue(ctx, lease.streamId, lease.key)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to get lease record")
+	}
+
+	if value.Version != info.Tx.Seq {
+		return errors.WithMessagef(ErrLeaseConflict, "version = %v, expected = %v", value.Version, info.Tx.Seq)
+	}
+
+	lease.version = info.Tx.Seq
+	if len(owner) > 0 {
+		lease.expiry = expiry
+	} else {
+		lease.expiry = time.Time{}
+	}
+
+	return nil
+}
+
+// waitCommitted waits for the kv transaction replayed on kv node, and returns ErrLeaseConflict if not committed.
+func (lease *Lease) waitCommitted(ctx context.Context, txSeq uint64) error {
+	for {
+		result, err := lease.client.GetTransactionResult(ctx, txSeq)
+		if err != nil {
+			return errors.WithMessage(err, "Failed to get transaction result of lease record")
+		}
+
+		if result == txResultCommit {
+			return nil
+		}
+
+		if len(result) > 0 {
+			return errors.WithMessagef(ErrLeaseConflict, "txSeq = %v, result = %v", txSeq, result)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lease.opt.PollInterval):
+		}
+	}
+}
+
+// validLocked returns whether the lease is valid, which is considered expired ClockSkew earlier by holder.
+func (lease *Lease) validLocked() bool {
+	return timeNow().Before(lease.expiry.Add(-lease.opt.ClockSkew))
+}
+
+// Valid returns whether the lease is still held by caller, taking the clock skew into account.
+func (lease *Lease) Valid() bool {
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+
+	return lease.validLocked()
+}
+
+// Expiry returns the expiry of lease, and zero if released.
+func (lease *Lease) Expiry() time.Time {
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+
+	return lease.expiry
+}
+
+// Renew extends the lease for ttl from now, and returns ErrLeaseExpired if expired, or ErrLeaseConflict if the
+// lease record is updated by others, in which case the lease should be acquired again.
+func (lease *Lease) Renew(ctx context.Context) error {
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+
+	if !lease.validLocked() {
+		return ErrLeaseExpired
+	}
+
+	return lease.writeLocked(ctx, lease.version, lease.owner)
+}
+
+// Release releases the lease by removing the lease record, so that others could acquire without waiting for expiry.
+// It returns ErrLeaseConflict if the lease record is updated by others, e.g. acquired by another owner after
+// expired, which is not removed.
+func (lease *Lease) Release(ctx context.Context) error {
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+
+	return lease.writeLocked(ctx, lease.version, "")
+}
+
+// Exec executes the batcher as Batcher.Exec if the lease is still valid, and refuses to execute with
+// ErrLeaseExpired otherwise. Note, the lease is not renewed, and may expire before the batch settled on chain.
+func (lease *Lease) Exec(ctx context.Context, batcher *Batcher, option ...transfer.UploadOption) (common.Hash, error) {
+	if !lease.Valid() {
+		return common.Hash{}, errors.WithMessagef(ErrLeaseExpired, "owner = %v, expiry = %v", lease.owner, lease.Expiry())
+	}
+
+	return batcher.Exec(ctx, option...)
+}
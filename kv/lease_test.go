@@ -0,0 +1,109 @@
+package kv
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	leaseStreamId = common.HexToHash("0x01")
+	leaseKey      = []byte("lease")
+)
+
+func newTestBatcherFactory(network *testutil.Network) BatcherFactory {
+	return func(version uint64) *Batcher {
+		return NewBatcher(version, network.ZgsClients(), network.Web3())
+	}
+}
+
+func TestLeaseE2E(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	client := NewClient(network.KvClient())
+	factory := newTestBatcherFactory(network)
+	ctx := context.Background()
+
+	// clock advanced by test
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	a, err := AcquireLease(ctx, factory, client, leaseStreamId, leaseKey, "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, a.Valid())
+
+	// held by a
+	_, err = AcquireLease(ctx, factory, client, leaseStreamId, leaseKey, "b", time.Minute)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+
+	// renewed and executed by holder
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, a.Renew(ctx))
+	assert.Equal(t, now.Add(time.Minute), a.Expiry())
+
+	batcher := factory(math.MaxUint64)
+	batcher.Set(leaseStreamId, []byte("key"), []byte("written by a"))
+	_, err = a.Exec(ctx, batcher)
+	assert.NoError(t, err)
+
+	// expired for holder earlier than others
+	now = now.Add(time.Minute - DefaultLeaseClockSkew)
+	assert.False(t, a.Valid())
+	_, err = a.Exec(ctx, factory(math.MaxUint64))
+	assert.ErrorIs(t, err, ErrLeaseExpired)
+	assert.ErrorIs(t, a.Renew(ctx), ErrLeaseExpired)
+	_, err = AcquireLease(ctx, factory, client, leaseStreamId, leaseKey, "b", time.Minute)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+
+	// acquired by b after clock skew tolerated, and a could not release the lease of b
+	now = now.Add(2 * DefaultLeaseClockSkew)
+	b, err := AcquireLease(ctx, factory, client, leaseStreamId, leaseKey, "b", time.Minute)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, a.Release(ctx), ErrLeaseConflict)
+
+	// released by b
+	assert.NoError(t, b.Release(ctx))
+	assert.False(t, b.Valid())
+	value, err := client.GetValue(ctx, leaseStreamId, leaseKey)
+	assert.NoError(t, err)
+	assert.Empty(t, value.Data)
+
+	value, err = client.GetValue(ctx, leaseStreamId, []byte("key"))
+	assert.NoError(t, err)
+	assert.Equal(t, "written by a", string(value.Data))
+}
+
+func TestLeaseContentionE2E(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	client := NewClient(network.KvClient())
+	factory := newTestBatcherFactory(network)
+
+	// two owners acquire the free lease at the same time
+	var wg sync.WaitGroup
+	leases := make([]*Lease, 2)
+	errs := make([]error, 2)
+	for i, owner := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(i int, owner string) {
+			defer wg.Done()
+			leases[i], errs[i] = AcquireLease(context.Background(), factory, client, leaseStreamId, leaseKey, owner, time.Minute)
+		}(i, owner)
+	}
+	wg.Wait()
+
+	// only one holder
+	if errs[0] != nil {
+		leases[0], leases[1] = leases[1], leases[0]
+		errs[0], errs[1] = errs[1], errs[0]
+	}
+	assert.NoError(t, errs[0])
+	assert.True(t, leases[0].Valid())
+	assert.Nil(t, leases[1])
+	assert.ErrorIs(t, errs[1], ErrLeaseConflict)
+}
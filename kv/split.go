@@ -0,0 +1,155 @@
+package kv
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// ErrBatchTooLarge is returned when the cached KV operations could not be executed in a single transaction in
+// strict mode, or a single operation exceeds the max transaction size.
+var ErrBatchTooLarge = errors.New("batch too large")
+
+// splitStreamData splits the stream data into multiple ones at operation boundaries, so that each of them could be
+// executed in a single transaction. The serialized size of each split is not greater than maxSize if positive, and
+// the number of operations of each set in a split is not greater than maxSetSize.
+//
+// Access controls are packed first in the order they are cached, so that they are applied before any read or
+// write of the batch, e.g. write role granted in the same batch. Since reads only guard writes of the same
+// transaction, reads are never split away from the writes they guard:
+//   - reads of a stream are packed in the same split as all writes of the stream, and ErrBatchTooLarge is returned
+//     if they could not fit in a single transaction.
+//   - reads of streams without writes, e.g. keys watched to guard writes of other streams, are packed in every
+//     split with writes, so that each transaction is still guarded by them.
+func splitStreamData(data *StreamData, maxSize int) ([]*StreamData, error) {
+	splits := []*StreamData{{Version: data.Version}}
+	size := streamDataHeaderSize
+
+	// next returns the split to add an operation of specified size, which is in the set of specified size.
+	next := func(opSize, setSize int) (*StreamData, error) {
+		if maxSize > 0 && streamDataHeaderSize+opSize > maxSize {
+			return nil, errors.WithMessagef(ErrBatchTooLarge, "operation of %v bytes exceeds the max transaction size %v", opSize, maxSize)
+		}
+
+		if (maxSize > 0 && size+opSize > maxSize) || setSize >= maxSetSize {
+			splits = append(splits, &StreamData{Version: data.Version})
+			size = streamDataHeaderSize
+		}
+
+		size += opSize
+
+		return splits[len(splits)-1], nil
+	}
+
+	for i := range data.Controls {
+		split, err := next(data.Controls[i].size(), len(splits[len(splits)-1].Controls))
+		if err != nil {
+			return nil, err
+		}
+		split.Controls = append(split.Controls, data.Controls[i])
+	}
+
+	// nothing to guard if no write
+	if len(data.Writes) == 0 {
+		for i := range data.Reads {
+			split, err := next(data.Reads[i].size(), len(splits[len(splits)-1].Reads))
+			if err != nil {
+				return nil, err
+			}
+			split.Reads = append(split.Reads, data.Reads[i])
+		}
+
+		return splits, nil
+	}
+
+	units, watches := groupGuardedOps(data)
+
+	var watchSize int
+	for i := range watches {
+		watchSize += watches[i].size()
+	}
+
+	guarded := false // whether the last split has watched reads packed
+	for _, unit := range units {
+		if maxSize > 0 && streamDataHeaderSize+watchSize+unit.size > maxSize {
+			return nil, errors.WithMessagef(ErrBatchTooLarge, "%v reads and %v writes of stream %v, along with %v watched reads, exceed the max transaction size %v",
+				len(unit.reads), len(unit.writes), unit.streamId, len(watches), maxSize)
+		}
+
+		if len(watches)+len(unit.reads) > maxSetSize || len(unit.writes) > maxSetSize {
+			return nil, errors.WithMessagef(ErrBatchTooLarge, "%v reads and %v writes of stream %v, along with %v watched reads, exceed the max set size %v",
+				len(unit.reads), len(unit.writes), unit.streamId, len(watches), maxSetSize)
+		}
+
+		split := splits[len(splits)-1]
+		opSize, numReads := unit.size, len(unit.reads)
+		if !guarded {
+			opSize, numReads = opSize+watchSize, numReads+len(watches)
+		}
+
+		if (maxSize > 0 && size+opSize > maxSize) || len(split.Reads)+numReads > maxSetSize || len(split.Writes)+len(unit.writes) > maxSetSize {
+			split = &StreamData{Version: data.Version}
+			splits = append(splits, split)
+			size = streamDataHeaderSize
+			opSize, guarded = unit.size+watchSize, false
+		}
+
+		if !guarded {
+			split.Reads = append(split.Reads, watches...)
+			guarded = true
+		}
+
+		split.Reads = append(split.Reads, unit.reads...)
+		split.Writes = append(split.Writes, unit.writes...)
+		size += opSize
+	}
+
+	return splits, nil
+}
+
+// guardedOps is the operations that must be executed in the same transaction, i.e. all writes of a stream along
+// with reads of the stream if any, or a single write of stream without reads.
+type guardedOps struct {
+	streamId common.Hash
+	reads    []streamRead
+	writes   []streamWrite
+	size     int
+}
+
+// groupGuardedOps groups the writes of stream data in order along with the reads of the same stream, and returns
+// the reads of streams without writes separately.
+func groupGuardedOps(data *StreamData) (units []*guardedOps, watches []streamRead) {
+	reads := make(map[common.Hash][]streamRead)
+	for _, read := range data.Reads {
+		reads[read.StreamId] = append(reads[read.StreamId], read)
+	}
+
+	grouped := make(map[common.Hash]*guardedOps)
+	for _, write := range data.Writes {
+		if len(reads[write.StreamId]) == 0 {
+			units = append(units, &guardedOps{streamId: write.StreamId, writes: []streamWrite{write}, size: write.size()})
+			continue
+		}
+
+		unit, ok := grouped[write.StreamId]
+		if !ok {
+			unit = &guardedOps{streamId: write.StreamId, reads: reads[write.StreamId]}
+			for i := range unit.reads {
+				unit.size += unit.reads[i].size()
+			}
+
+			grouped[write.StreamId] = unit
+			units = append(units, unit)
+		}
+
+		unit.writes = append(unit.writes, write)
+		unit.size += write.size()
+	}
+
+	for _, read := range data.Reads {
+		if _, ok := grouped[read.StreamId]; !ok {
+			watches = append(watches, read)
+		}
+	}
+
+	return units, watches
+}
@@ -0,0 +1,194 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var splitTestGuardStreamId = common.HexToHash("0x04")
+
+func newSplitTestBatcher(numWrites, valueSize int) *Batcher {
+	batcher := NewBatcher(1, nil, nil)
+	streamId := common.HexToHash("0x01")
+
+	batcher.GrantWriteRole(streamId, common.HexToAddress("0x02"))
+	batcher.SetKeyToSpecial(streamId, []byte("special"))
+	batcher.Watch(splitTestGuardStreamId, []byte("version"))
+
+	for i := 0; i < numWrites; i++ {
+		batcher.Set(streamId, []byte(fmt.Sprintf("key-%03d", i)), make([]byte, valueSize))
+	}
+
+	return batcher
+}
+
+func TestSplitStreamData(t *testing.T) {
+	data, err := newSplitTestBatcher(100, 100).build(true, false)
+	assert.NoError(t, err)
+
+	maxSize := 1000
+	splits, err := splitStreamData(data, maxSize)
+	assert.NoError(t, err)
+	assert.Greater(t, len(splits), 1)
+
+	var merged StreamData
+	for i, split := range splits {
+		assert.LessOrEqual(t, split.Size(), maxSize)
+		assert.Equal(t, data.Version, split.Version)
+
+		encoded, err := split.Encode()
+		assert.NoError(t, err)
+		assert.Equal(t, split.Size(), len(encoded))
+
+		// controls before reads and writes across transactions
+		if i > 0 && len(split.Controls) > 0 {
+			assert.Empty(t, splits[i-1].Reads)
+			assert.Empty(t, splits[i-1].Writes)
+		}
+
+		// every transaction with writes guarded by the watched key
+		if len(split.Writes) > 0 {
+			assert.Equal(t, data.Reads, split.Reads)
+		}
+
+		merged.Controls = append(merged.Controls, split.Controls...)
+		merged.Writes = append(merged.Writes, split.Writes...)
+	}
+
+	// all operations executed in order
+	assert.Equal(t, data.Controls, merged.Controls)
+	assert.Equal(t, data.Writes, merged.Writes)
+
+	// not split if unlimited
+	splits, err = splitStreamData(data, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []*StreamData{data}, splits)
+}
+
+func TestSplitStreamDataBySetSize(t *testing.T) {
+	data := StreamData{Version: 1}
+	for i := 0; i < maxSetSize+1; i++ {
+		data.Reads = append(data.Reads, streamRead{Key: []byte{1}})
+	}
+
+	splits, err := splitStreamData(&data, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(splits))
+	assert.Equal(t, maxSetSize, len(splits[0].Reads))
+	assert.Equal(t, 1, len(splits[1].Reads))
+}
+
+func TestSplitStreamDataWriteTooLarge(t *testing.T) {
+	data, err := newSplitTestBatcher(1, 1000).build(true, false)
+	assert.NoError(t, err)
+
+	_, err = splitStreamData(data, 1000)
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+}
+
+func TestSplitStreamDataReadsWithWrites(t *testing.T) {
+	batcher := NewBatcher(1, nil, nil)
+	guarded, other := common.HexToHash("0x01"), common.HexToHash("0x02")
+
+	for i := 0; i < 10; i++ {
+		key := []byte(fmt.Sprintf("key-%03d", i))
+		batcher.Set(other, key, make([]byte, 100))
+		batcher.Set(guarded, key, make([]byte, 10))
+	}
+	batcher.Watch(guarded, []byte("key-009"))
+
+	data, err := batcher.build(true, false)
+	assert.NoError(t, err)
+
+	splits, err := splitStreamData(data, 1000)
+	assert.NoError(t, err)
+	assert.Greater(t, len(splits), 1)
+
+	// reads and all writes of the guarded stream in the same transaction
+	var found int
+	for _, split := range splits {
+		var writes int
+		for _, write := range split.Writes {
+			if write.StreamId == guarded {
+				writes++
+			}
+		}
+
+		if len(split.Reads) > 0 {
+			found++
+			assert.Equal(t, data.Reads, split.Reads)
+			assert.Equal(t, 10, writes)
+		} else {
+			assert.Zero(t, writes)
+		}
+	}
+	assert.Equal(t, 1, found)
+
+	// reads and writes of the guarded stream could not fit in a single transaction
+	_, err = splitStreamData(data, 500)
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+}
+
+func TestBatcherStrict(t *testing.T) {
+	batcher := newSplitTestBatcher(100, 100).WithMaxTxSize(1000).WithStrict(true)
+
+	result, err := batcher.ExecAll(context.Background())
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+	assert.Equal(t, zg_common.ErrorClassUsage, zg_common.ClassOf(err))
+	assert.Contains(t, err.Error(), "transactions = ")
+
+	// nothing executed
+	data, err := batcher.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, 100, len(data.Writes))
+}
+
+func TestBatcherRemoveExecuted(t *testing.T) {
+	batcher := newSplitTestBatcher(10, 10)
+	streamId := common.HexToHash("0x01")
+
+	data, err := batcher.build(true, false)
+	assert.NoError(t, err)
+	splits, err := splitStreamData(data, 250)
+	assert.NoError(t, err)
+	assert.Greater(t, len(splits), 2)
+	assert.Equal(t, []byte("key-000"), splits[0].Writes[0].Key)
+
+	// operations cached after built are retained
+	batcher.Set(streamId, []byte("key-000"), []byte("updated"))
+	batcher.GrantWriteRole(streamId, common.HexToAddress("0x03"))
+
+	batcher.remove(splits[0])
+	batcher.remove(splits[1])
+
+	remaining, err := batcher.build(true, false)
+	assert.NoError(t, err)
+
+	var expected StreamData
+	for _, split := range splits[2:] {
+		expected.Controls = append(expected.Controls, split.Controls...)
+		expected.Writes = append(expected.Writes, split.Writes...)
+	}
+
+	assert.Equal(t, 1, len(remaining.Controls))
+	assert.Equal(t, common.HexToAddress("0x03"), *remaining.Controls[0].Account)
+	assert.Contains(t, remaining.Writes, streamWrite{StreamId: streamId, Key: []byte("key-000"), Data: []byte("updated")})
+	assert.Equal(t, len(expected.Writes)+1, len(remaining.Writes))
+
+	// watched key retained to guard the remaining writes
+	assert.Equal(t, data.Reads, remaining.Reads)
+
+	// watched key removed once all writes executed
+	batcher.remove(remaining)
+
+	remaining, err = batcher.build(true, false)
+	assert.NoError(t, err)
+	assert.Empty(t, remaining.Writes)
+	assert.Empty(t, remaining.Reads)
+}
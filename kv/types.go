@@ -1,8 +1,10 @@
 package kv
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/binary"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -69,39 +71,71 @@ type StreamData struct {
 	Controls []accessControl
 }
 
+// streamDataHeaderSize is the serialized size of stream data without any operation: version + size of each set.
+const streamDataHeaderSize = 8 + 4 + 4 + 4
+
+// size returns the serialized size of read operation in bytes.
+func (v *streamRead) size() int {
+	return common.HashLength + 3 + len(v.Key)
+}
+
+// size returns the serialized size of write operation in bytes, including the data.
+func (v *streamWrite) size() int {
+	return common.HashLength + 3 + len(v.Key) + 8 + len(v.Data)
+}
+
+// size returns the serialized size of access control operation in bytes.
+func (v *accessControl) size() int {
+	size := 1 + common.HashLength // type + streamId
+
+	if v.Account != nil {
+		size += common.AddressLength
+	}
+
+	if v.Key != nil {
+		size += 3 + len(v.Key)
+	}
+
+	return size
+}
+
 // Size returns the serialized data size in bytes.
 func (sd *StreamData) Size() int {
-	var size int
+	size := streamDataHeaderSize
 
-	size += 8 // version
+	for i := range sd.Reads {
+		size += sd.Reads[i].size()
+	}
 
-	// reads
-	size += 4 // size
-	for _, v := range sd.Reads {
-		size += common.HashLength + 3 + len(v.Key)
+	for i := range sd.Writes {
+		size += sd.Writes[i].size()
 	}
 
-	// writes
-	size += 4 // size
-	for _, v := range sd.Writes {
-		size += common.HashLength + 3 + len(v.Key) + 8 + len(v.Data)
+	for i := range sd.Controls {
+		size += sd.Controls[i].size()
 	}
 
-	// acls
-	size += 4 // size
-	for _, v := range sd.Controls {
-		size += 1 + common.HashLength // type + streamId
+	return size
+}
 
-		if v.Account != nil {
-			size += common.AddressLength
-		}
+// streamIds returns the sorted ids of streams that written or access controlled, which are used to build tags.
+func (sd *StreamData) streamIds() []common.Hash {
+	set := make(map[common.Hash]bool)
+	for _, v := range sd.Writes {
+		set[v.StreamId] = true
+	}
+	for _, v := range sd.Controls {
+		set[v.StreamId] = true
+	}
 
-		if v.Key != nil {
-			size += 3 + len(v.Key)
-		}
+	ids := make([]common.Hash, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
 	}
 
-	return size
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i].Bytes(), ids[j].Bytes()) < 0 })
+
+	return ids
 }
 
 func (sd *StreamData) encodeSize24(size int) ([]byte, error) {
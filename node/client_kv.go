@@ -3,12 +3,13 @@ package node
 import (
 	"context"
 
+	"github.com/0glabs/0g-storage-client/common/rpc"
 	"github.com/ethereum/go-ethereum/common"
 	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
 	"github.com/sirupsen/logrus"
 )
 
-// KvClient RPC client connected to 0g kv node.
+// KvClient RPC client connected to 0g kv node, which is safe for concurrent use.
 type KvClient struct {
 	*rpcClient
 }
@@ -25,7 +26,12 @@ func MustNewKvClient(url string, option ...providers.Option) *KvClient {
 
 // NewKvClient initalize a kv client.
 func NewKvClient(url string, option ...providers.Option) (*KvClient, error) {
-	client, err := newRpcClient(url, option...)
+	return NewKvClientWithProxy(url, rpc.DefaultProxy, option...)
+}
+
+// NewKvClientWithProxy initializes a kv client with the specified proxy option.
+func NewKvClientWithProxy(url string, proxy rpc.ProxyOption, option ...providers.Option) (*KvClient, error) {
+	client, err := newRpcClient(url, proxy, option...)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +45,7 @@ func (c *KvClient) GetValue(ctx context.Context, streamId common.Hash, key []byt
 	if len(version) > 0 {
 		args = append(args, version[0])
 	}
-	return providers.CallContext[*Value](c, ctx, "kv_getValue", args...)
+	return providers.CallContext[*Value](c.MiddlewarableProvider, ctx, "kv_getValue", args...)
 }
 
 // GetNext Call kv_getNext RPC to query the next key of a given key.
@@ -48,7 +54,7 @@ func (c *KvClient) GetNext(ctx context.Context, streamId common.Hash, key []byte
 	if len(version) > 0 {
 		args = append(args, version[0])
 	}
-	return providers.CallContext[*KeyValue](c, ctx, "kv_getNext", args...)
+	return providers.CallContext[*KeyValue](c.MiddlewarableProvider, ctx, "kv_getNext", args...)
 }
 
 // GetPrev Call kv_getNext RPC to query the prev key of a given key.
@@ -57,7 +63,7 @@ func (c *KvClient) GetPrev(ctx context.Context, streamId common.Hash, key []byte
 	if len(version) > 0 {
 		args = append(args, version[0])
 	}
-	return providers.CallContext[*KeyValue](c, ctx, "kv_getPrev", args...)
+	return providers.CallContext[*KeyValue](c.MiddlewarableProvider, ctx, "kv_getPrev", args...)
 }
 
 // GetFirst Call kv_getFirst RPC to query the first key.
@@ -66,7 +72,7 @@ func (c *KvClient) GetFirst(ctx context.Context, streamId common.Hash, startInde
 	if len(version) > 0 {
 		args = append(args, version[0])
 	}
-	return providers.CallContext[*KeyValue](c, ctx, "kv_getFirst", args...)
+	return providers.CallContext[*KeyValue](c.MiddlewarableProvider, ctx, "kv_getFirst", args...)
 }
 
 // GetLast Call kv_getLast RPC to query the last key.
@@ -75,17 +81,17 @@ func (c *KvClient) GetLast(ctx context.Context, streamId common.Hash, startIndex
 	if len(version) > 0 {
 		args = append(args, version[0])
 	}
-	return providers.CallContext[*KeyValue](c, ctx, "kv_getLast", args...)
+	return providers.CallContext[*KeyValue](c.MiddlewarableProvider, ctx, "kv_getLast", args...)
 }
 
 // GetTransactionResult Call kv_getTransactionResult RPC to query the kv replay status of a given file.
 func (c *KvClient) GetTransactionResult(ctx context.Context, txSeq uint64) (string, error) {
-	return providers.CallContext[string](c, ctx, "kv_getTransactionResult", txSeq)
+	return providers.CallContext[string](c.MiddlewarableProvider, ctx, "kv_getTransactionResult", txSeq)
 }
 
 // GetHoldingStreamIds Call kv_getHoldingStreamIds RPC to query the stream ids monitered by the kv node.
 func (c *KvClient) GetHoldingStreamIds(ctx context.Context) ([]common.Hash, error) {
-	return providers.CallContext[[]common.Hash](c, ctx, "kv_getHoldingStreamIds")
+	return providers.CallContext[[]common.Hash](c.MiddlewarableProvider, ctx, "kv_getHoldingStreamIds")
 }
 
 // HasWritePermission Call kv_hasWritePermission RPC to check if the account is able to write the stream.
@@ -94,7 +100,7 @@ func (c *KvClient) HasWritePermission(ctx context.Context, account common.Addres
 	if len(version) > 0 {
 		args = append(args, version[0])
 	}
-	return providers.CallContext[bool](c, ctx, "kv_hasWritePermission", args...)
+	return providers.CallContext[bool](c.MiddlewarableProvider, ctx, "kv_hasWritePermission", args...)
 }
 
 // IsAdmin Call kv_isAdmin RPC to check if the account is the admin of the stream.
@@ -103,7 +109,7 @@ func (c *KvClient) IsAdmin(ctx context.Context, account common.Address, streamId
 	if len(version) > 0 {
 		args = append(args, version[0])
 	}
-	return providers.CallContext[bool](c, ctx, "kv_isAdmin", args...)
+	return providers.CallContext[bool](c.MiddlewarableProvider, ctx, "kv_isAdmin", args...)
 }
 
 // IsSpecialKey Call kv_isSpecialKey RPC to check if the key has unique access control.
@@ -112,7 +118,7 @@ func (c *KvClient) IsSpecialKey(ctx context.Context, streamId common.Hash, key [
 	if len(version) > 0 {
 		args = append(args, version[0])
 	}
-	return providers.CallContext[bool](c, ctx, "kv_isSpecialKey", args...)
+	return providers.CallContext[bool](c.MiddlewarableProvider, ctx, "kv_isSpecialKey", args...)
 }
 
 // IsWriterOfKey Call kv_isWriterOfKey RPC to check if the account can write the special key.
@@ -121,7 +127,7 @@ func (c *KvClient) IsWriterOfKey(ctx context.Context, account common.Address, st
 	if len(version) > 0 {
 		args = append(args, version[0])
 	}
-	return providers.CallContext[bool](c, ctx, "kv_isWriterOfKey", args...)
+	return providers.CallContext[bool](c.MiddlewarableProvider, ctx, "kv_isWriterOfKey", args...)
 }
 
 // IsWriterOfStream Call kv_isWriterOfStream RPC to check if the account is the writer of the stream.
@@ -130,5 +136,5 @@ func (c *KvClient) IsWriterOfStream(ctx context.Context, account common.Address,
 	if len(version) > 0 {
 		args = append(args, version[0])
 	}
-	return providers.CallContext[bool](c, ctx, "kv_isWriterOfStream", args...)
+	return providers.CallContext[bool](c.MiddlewarableProvider, ctx, "kv_isWriterOfStream", args...)
 }
@@ -0,0 +1,136 @@
+package node_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestClientClose(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent(),
+		// connection cleaners of HTTP transport exit in the next round once idle connections closed
+		goleak.IgnoreAnyFunction("github.com/valyala/fasthttp.(*HostClient).connsCleaner"),
+		goleak.IgnoreAnyFunction("github.com/valyala/fasthttp.(*Client).mCleaner"),
+		// process-wide DNS cache of HTTP transport
+		goleak.IgnoreAnyFunction("github.com/valyala/fasthttp.(*TCPDialer).tcpAddrsClean"),
+	)
+
+	var activeConns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"connectedPeers":3}}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			activeConns.Add(1)
+		case http.StateClosed, http.StateHijacked:
+			activeConns.Add(-1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	zgs, err := node.NewZgsClient(server.URL)
+	assert.NoError(t, err)
+	status, err := zgs.GetStatus(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), status.ConnectedPeers)
+
+	kv, err := node.NewKvClient(server.URL)
+	assert.NoError(t, err)
+
+	admin, err := node.NewAdminClient(server.URL)
+	assert.NoError(t, err)
+
+	// keep-alive connection is idle
+	assert.Equal(t, int32(1), activeConns.Load())
+
+	for _, client := range []io.Closer{zgs, kv, admin} {
+		assert.NoError(t, client.Close())
+		// safe to close again
+		assert.NoError(t, client.Close())
+	}
+
+	// idle connections closed
+	assert.Eventually(t, func() bool { return activeConns.Load() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestBatchGetFileInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []struct {
+			ID     json.RawMessage   `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&reqs))
+
+		var resps []string
+		for i, req := range reqs {
+			switch i {
+			case 0:
+				resps = append(resps, fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"finalized":true}}`, req.ID))
+			case 1:
+				resps = append(resps, fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":null}`, req.ID))
+			default:
+				resps = append(resps, fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32000,"message":"busy"}}`, req.ID))
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[" + strings.Join(resps, ",") + "]"))
+	}))
+	defer server.Close()
+
+	client, err := node.NewZgsClient(server.URL)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	infos, errs, err := client.BatchGetFileInfo(context.Background(), []common.Hash{{1}, {2}, {3}})
+	assert.NoError(t, err)
+	assert.True(t, infos[0].Finalized)
+	assert.NoError(t, errs[0])
+	assert.Nil(t, infos[1])
+	assert.NoError(t, errs[1])
+	assert.ErrorContains(t, errs[2], "busy")
+}
+
+func TestClientErrorClass(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"connectedPeers":3}}`))
+	}))
+
+	client, err := node.NewZgsClient(server.URL)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	// cancelled by caller
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.GetStatus(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, zg_common.ErrorClassCancelled, zg_common.ClassOf(err))
+
+	// storage node unreachable
+	server.Close()
+	_, err = client.GetStatus(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, zg_common.ErrorClassNetwork, zg_common.ClassOf(errors.WithMessage(err, "Failed to get status")))
+}
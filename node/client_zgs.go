@@ -3,13 +3,15 @@ package node
 import (
 	"context"
 
+	"github.com/0glabs/0g-storage-client/common/rpc"
 	"github.com/0glabs/0g-storage-client/common/shard"
 	"github.com/ethereum/go-ethereum/common"
+	gorpc "github.com/openweb3/go-rpc-provider"
 	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
 	"github.com/sirupsen/logrus"
 )
 
-// ZgsClient RPC Client connected to a 0g storage node's zgs RPC endpoint.
+// ZgsClient RPC Client connected to a 0g storage node's zgs RPC endpoint, which is safe for concurrent use.
 type ZgsClient struct {
 	*rpcClient
 }
@@ -26,7 +28,12 @@ func MustNewZgsClient(url string, option ...providers.Option) *ZgsClient {
 
 // NewZgsClient Initalize a zgs client.
 func NewZgsClient(url string, option ...providers.Option) (*ZgsClient, error) {
-	client, err := newRpcClient(url, option...)
+	return NewZgsClientWithProxy(url, rpc.DefaultProxy, option...)
+}
+
+// NewZgsClientWithProxy initializes a zgs client with the specified proxy option.
+func NewZgsClientWithProxy(url string, proxy rpc.ProxyOption, option ...providers.Option) (*ZgsClient, error) {
+	client, err := newRpcClient(url, proxy, option...)
 	if err != nil {
 		return nil, err
 	}
@@ -48,48 +55,73 @@ func MustNewZgsClients(urls []string, option ...providers.Option) []*ZgsClient {
 
 // GetStatus Call zgs_getStatus RPC to get sync status of the node.
 func (c *ZgsClient) GetStatus(ctx context.Context) (Status, error) {
-	return providers.CallContext[Status](c, ctx, "zgs_getStatus")
+	return providers.CallContext[Status](c.MiddlewarableProvider, ctx, "zgs_getStatus")
 }
 
 // CheckFileFinalized Call zgs_checkFileFinalized to check if specified file is finalized.
 // Returns nil if file not available on storage node.
 func (c *ZgsClient) CheckFileFinalized(ctx context.Context, txSeqOrRoot TxSeqOrRoot) (*bool, error) {
-	return providers.CallContext[*bool](c, ctx, "zgs_checkFileFinalized", txSeqOrRoot)
+	return providers.CallContext[*bool](c.MiddlewarableProvider, ctx, "zgs_checkFileFinalized", txSeqOrRoot)
 }
 
 // GetFileInfo Call zgs_getFileInfo RPC to get the information of a file by file data root from the node.
 func (c *ZgsClient) GetFileInfo(ctx context.Context, root common.Hash) (*FileInfo, error) {
-	return providers.CallContext[*FileInfo](c, ctx, "zgs_getFileInfo", root)
+	return providers.CallContext[*FileInfo](c.MiddlewarableProvider, ctx, "zgs_getFileInfo", root)
+}
+
+// BatchGetFileInfo Call zgs_getFileInfo RPC in batch to get the information of files by file data roots from the node.
+// Returns the file info and error of each root respectively, e.g. nil file info if not available on storage node.
+func (c *ZgsClient) BatchGetFileInfo(ctx context.Context, roots []common.Hash) ([]*FileInfo, []error, error) {
+	infos := make([]*FileInfo, len(roots))
+	batch := make([]gorpc.BatchElem, len(roots))
+	for i, root := range roots {
+		batch[i] = gorpc.BatchElem{
+			Method: "zgs_getFileInfo",
+			Args:   []interface{}{root},
+			Result: &infos[i],
+		}
+	}
+
+	if err := c.MiddlewarableProvider.BatchCallContext(ctx, batch); err != nil {
+		return nil, nil, err
+	}
+
+	errs := make([]error, len(roots))
+	for i := range batch {
+		errs[i] = c.wrapError(ctx, batch[i].Error, batch[i].Method)
+	}
+
+	return infos, errs, nil
 }
 
 // GetFileInfoByTxSeq Call zgs_getFileInfoByTxSeq RPC to get the information of a file by file sequence id from the node.
 func (c *ZgsClient) GetFileInfoByTxSeq(ctx context.Context, txSeq uint64) (*FileInfo, error) {
-	return providers.CallContext[*FileInfo](c, ctx, "zgs_getFileInfoByTxSeq", txSeq)
+	return providers.CallContext[*FileInfo](c.MiddlewarableProvider, ctx, "zgs_getFileInfoByTxSeq", txSeq)
 }
 
 // UploadSegment Call zgs_uploadSegment RPC to upload a segment to the node.
 func (c *ZgsClient) UploadSegment(ctx context.Context, segment SegmentWithProof) (int, error) {
-	return providers.CallContext[int](c, ctx, "zgs_uploadSegment", segment)
+	return providers.CallContext[int](c.MiddlewarableProvider, ctx, "zgs_uploadSegment", segment)
 }
 
 // UploadSegmentByTxSeq Call zgs_uploadSegmentByTxSeq RPC to upload a segment to the node.
 func (c *ZgsClient) UploadSegmentByTxSeq(ctx context.Context, segment SegmentWithProof, txSeq uint64) (int, error) {
-	return providers.CallContext[int](c, ctx, "zgs_uploadSegmentByTxSeq", segment, txSeq)
+	return providers.CallContext[int](c.MiddlewarableProvider, ctx, "zgs_uploadSegmentByTxSeq", segment, txSeq)
 }
 
 // UploadSegments Call zgs_uploadSegments RPC to upload a slice of segments to the node.
 func (c *ZgsClient) UploadSegments(ctx context.Context, segments []SegmentWithProof) (int, error) {
-	return providers.CallContext[int](c, ctx, "zgs_uploadSegments", segments)
+	return providers.CallContext[int](c.MiddlewarableProvider, ctx, "zgs_uploadSegments", segments)
 }
 
 // UploadSegmentsByTxSeq Call zgs_uploadSegmentsByTxSeq RPC to upload a slice of segments to the node.
 func (c *ZgsClient) UploadSegmentsByTxSeq(ctx context.Context, segments []SegmentWithProof, txSeq uint64) (int, error) {
-	return providers.CallContext[int](c, ctx, "zgs_uploadSegmentsByTxSeq", segments, txSeq)
+	return providers.CallContext[int](c.MiddlewarableProvider, ctx, "zgs_uploadSegmentsByTxSeq", segments, txSeq)
 }
 
 // DownloadSegment Call zgs_downloadSegment RPC to download a segment from the node.
 func (c *ZgsClient) DownloadSegment(ctx context.Context, root common.Hash, startIndex, endIndex uint64) ([]byte, error) {
-	data, err := providers.CallContext[[]byte](c, ctx, "zgs_downloadSegment", root, startIndex, endIndex)
+	data, err := providers.CallContext[[]byte](c.MiddlewarableProvider, ctx, "zgs_downloadSegment", root, startIndex, endIndex)
 	if len(data) == 0 {
 		return nil, err
 	}
@@ -99,7 +131,7 @@ func (c *ZgsClient) DownloadSegment(ctx context.Context, root common.Hash, start
 
 // DownloadSegmentByTxSeq Call zgs_downloadSegmentByTxSeq RPC to download a segment from the node.
 func (c *ZgsClient) DownloadSegmentByTxSeq(ctx context.Context, txSeq uint64, startIndex, endIndex uint64) ([]byte, error) {
-	data, err := providers.CallContext[[]byte](c, ctx, "zgs_downloadSegmentByTxSeq", txSeq, startIndex, endIndex)
+	data, err := providers.CallContext[[]byte](c.MiddlewarableProvider, ctx, "zgs_downloadSegmentByTxSeq", txSeq, startIndex, endIndex)
 	if len(data) == 0 {
 		return nil, err
 	}
@@ -109,20 +141,20 @@ func (c *ZgsClient) DownloadSegmentByTxSeq(ctx context.Context, txSeq uint64, st
 
 // DownloadSegmentWithProof Call zgs_downloadSegmentWithProof RPC to download a segment along with its merkle proof from the node.
 func (c *ZgsClient) DownloadSegmentWithProof(ctx context.Context, root common.Hash, index uint64) (*SegmentWithProof, error) {
-	return providers.CallContext[*SegmentWithProof](c, ctx, "zgs_downloadSegmentWithProof", root, index)
+	return providers.CallContext[*SegmentWithProof](c.MiddlewarableProvider, ctx, "zgs_downloadSegmentWithProof", root, index)
 }
 
 // DownloadSegmentWithProofByTxSeq Call zgs_downloadSegmentWithProofByTxSeq RPC to download a segment along with its merkle proof from the node.
 func (c *ZgsClient) DownloadSegmentWithProofByTxSeq(ctx context.Context, txSeq uint64, index uint64) (*SegmentWithProof, error) {
-	return providers.CallContext[*SegmentWithProof](c, ctx, "zgs_downloadSegmentWithProofByTxSeq", txSeq, index)
+	return providers.CallContext[*SegmentWithProof](c.MiddlewarableProvider, ctx, "zgs_downloadSegmentWithProofByTxSeq", txSeq, index)
 }
 
 // GetShardConfig Call zgs_getShardConfig RPC to get the current shard configuration of the node.
 func (c *ZgsClient) GetShardConfig(ctx context.Context) (shard.ShardConfig, error) {
-	return providers.CallContext[shard.ShardConfig](c, ctx, "zgs_getShardConfig")
+	return providers.CallContext[shard.ShardConfig](c.MiddlewarableProvider, ctx, "zgs_getShardConfig")
 }
 
 // GetSectorProof Call zgs_getSectorProof RPC to get the proof of a sector.
 func (c *ZgsClient) GetSectorProof(ctx context.Context, sectorIndex uint64, root *common.Hash) (FlowProof, error) {
-	return providers.CallContext[FlowProof](c, ctx, "zgs_getSectorProof", sectorIndex, root)
+	return providers.CallContext[FlowProof](c.MiddlewarableProvider, ctx, "zgs_getSectorProof", sectorIndex, root)
 }
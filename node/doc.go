@@ -1,2 +1,7 @@
 // Package node defines RPC client structures to facilitate RPC interactions with 0g storage nodes and 0g key-value (KV) nodes.
+//
+// All RPC clients are stateless and safe for concurrent use, so that a client could be shared across goroutines.
+//
+// Failed RPCs are returned as RPCError, where transport-level failures, e.g. DNS or TLS failures, are classified
+// by TransportErrorKind and counted per node, see TransportErrorCounts.
 package node
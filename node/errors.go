@@ -1,13 +1,78 @@
 package node
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
 
+	"github.com/0glabs/0g-storage-client/common"
+)
+
+// Standard JSON-RPC error codes.
+const (
+	ErrorCodeParseError     = -32700
+	ErrorCodeInvalidRequest = -32600
+	// ErrorCodeMethodNotFound is the JSON-RPC error code returned when the method is not available on storage
+	// node, e.g. RPCs introduced by later versions of storage node.
+	ErrorCodeMethodNotFound = -32601
+	ErrorCodeInvalidParams  = -32602
+	ErrorCodeInternalError  = -32603
+)
+
+// RPCError is the error of RPC to storage node, which is either a transport-level failure, e.g. DNS or TLS
+// failure, or an application rejection of storage node, e.g. invalid data.
 type RPCError struct {
-	Message string
-	Method  string
-	URL     string
+	Message   string
+	Method    string
+	URL       string
+	Code      int             // JSON-RPC error code if rejected by storage node, otherwise 0
+	Transport *TransportError // transport-level failure, nil for application rejection
 }
 
 func (e *RPCError) Error() string {
 	return fmt.Sprintf("Node: %s, Method: %s, Message: %s", e.URL, e.Method, e.Message)
 }
+
+// Unwrap returns the transport-level failure if any.
+func (e *RPCError) Unwrap() error {
+	if e.Transport == nil {
+		return nil
+	}
+
+	return e.Transport
+}
+
+// IsTransport returns whether the RPC failed at transport level, rather than rejected by storage node.
+func (e *RPCError) IsTransport() bool {
+	return e.Transport != nil
+}
+
+// IsMethodNotFound returns whether the RPC is not available on storage node.
+func (e *RPCError) IsMethodNotFound() bool {
+	return e.Code == ErrorCodeMethodNotFound
+}
+
+// IsMethodNotFound returns whether the error is an RPCError of which the method is not available on storage node,
+// e.g. storage nodes of earlier versions that do not serve segment proofs.
+func IsMethodNotFound(err error) bool {
+	var rpcErr *RPCError
+	return errors.As(err, &rpcErr) && rpcErr.IsMethodNotFound()
+}
+
+// ErrorClass implements the common.ErrorClassifier interface. Transport-level failures and internal errors of
+// storage node are classified as network errors, malformed requests as usage errors, and other application
+// rejections of storage node, e.g. invalid segment, as rejected.
+func (e *RPCError) ErrorClass() common.ErrorClass {
+	if e.IsTransport() {
+		return common.ErrorClassNetwork
+	}
+
+	switch e.Code {
+	case ErrorCodeParseError, ErrorCodeInvalidRequest, ErrorCodeMethodNotFound, ErrorCodeInvalidParams:
+		return common.ErrorClassUsage
+	case 0, ErrorCodeInternalError:
+		// e.g. HTTP status error without JSON-RPC error code
+		return common.ErrorClassNetwork
+	default:
+		return common.ErrorClassRejected
+	}
+}
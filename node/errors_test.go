@@ -6,6 +6,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/node"
 	"gotest.tools/assert"
 )
@@ -37,3 +38,19 @@ func TestErrorAs(t *testing.T) {
 		err,
 	)
 }
+
+func TestRPCErrorClass(t *testing.T) {
+	for _, tc := range []struct {
+		err      *node.RPCError
+		expected zg_common.ErrorClass
+	}{
+		{&node.RPCError{Transport: &node.TransportError{Kind: node.TransportErrorTLS}}, zg_common.ErrorClassNetwork},
+		{&node.RPCError{Message: "502 Bad Gateway"}, zg_common.ErrorClassNetwork},
+		{&node.RPCError{Code: node.ErrorCodeInternalError}, zg_common.ErrorClassNetwork},
+		{&node.RPCError{Code: node.ErrorCodeMethodNotFound}, zg_common.ErrorClassUsage},
+		{&node.RPCError{Code: node.ErrorCodeInvalidParams}, zg_common.ErrorClassUsage},
+		{&node.RPCError{Code: -32000, Message: "invalid segment"}, zg_common.ErrorClassRejected},
+	} {
+		assert.Equal(t, tc.expected, zg_common.ClassOf(errors.WithMessage(tc.err, "failed to upload")))
+	}
+}
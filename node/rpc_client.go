@@ -2,6 +2,7 @@ package node
 
 import (
 	"context"
+	"errors"
 
 	"github.com/0glabs/0g-storage-client/common/rpc"
 	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
@@ -11,8 +12,8 @@ type rpcClient struct {
 	*rpc.Client
 }
 
-func newRpcClient(url string, option ...providers.Option) (*rpcClient, error) {
-	inner, err := rpc.NewClient(url, option...)
+func newRpcClient(url string, proxy rpc.ProxyOption, option ...providers.Option) (*rpcClient, error) {
+	inner, err := rpc.NewClientWithProxy(url, proxy, option...)
 	if err != nil {
 		return nil, err
 	}
@@ -23,20 +24,48 @@ func newRpcClient(url string, option ...providers.Option) (*rpcClient, error) {
 	return &client, nil
 }
 
-func (c *rpcClient) wrapError(e error, method string) error {
+func (c *rpcClient) wrapError(ctx context.Context, e error, method string) error {
 	if e == nil {
 		return nil
 	}
+
+	// cancelled by caller, but not the fault of storage node
+	if errors.Is(e, context.Canceled) || errors.Is(e, context.DeadlineExceeded) {
+		return e
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	transport := classifyTransportError(e)
+	if transport != nil {
+		transportErrors.inc(c.URL(), transport.Kind)
+	}
+
+	var code int
+	var coded interface{ ErrorCode() int }
+	if transport == nil && errors.As(e, &coded) {
+		code = coded.ErrorCode()
+	}
+
 	return &RPCError{
-		Message: e.Error(),
-		Method:  method,
-		URL:     c.URL(),
+		Message:   e.Error(),
+		Method:    method,
+		URL:       c.URL(),
+		Code:      code,
+		Transport: transport,
 	}
 }
 
 func (c *rpcClient) rpcErrorMiddleware(handler providers.CallContextFunc) providers.CallContextFunc {
 	return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+		// underlying HTTP transport may not respect the context
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		err := handler(ctx, result, method, args...)
-		return c.wrapError(err, method)
+		return c.wrapError(ctx, err, method)
 	}
 }
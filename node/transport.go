@@ -0,0 +1,164 @@
+package node
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+)
+
+// TransportErrorKind classifies the transport-level failures of RPCs to storage node, so as to distinguish network
+// failures from application rejections of storage node.
+type TransportErrorKind string
+
+const (
+	TransportErrorDNS            TransportErrorKind = "dns"             // failed to resolve the host of node
+	TransportErrorConnectTimeout TransportErrorKind = "connect_timeout" // timed out to connect to node
+	TransportErrorTLS            TransportErrorKind = "tls"             // TLS handshake or certificate failure
+	TransportErrorResetByPeer    TransportErrorKind = "reset_by_peer"   // connection reset or closed by node
+	TransportErrorHTTPStatus     TransportErrorKind = "http_status"     // non-2xx HTTP status responded
+	TransportErrorOther          TransportErrorKind = "other"           // other network failures, e.g. refused
+)
+
+// TransportError is a transport-level failure of RPC to storage node, which is attached to RPCError.
+type TransportError struct {
+	Kind       TransportErrorKind
+	StatusCode int // HTTP status code, only for TransportErrorHTTPStatus
+	Err        error
+}
+
+func (e *TransportError) Error() string {
+	if e.Kind == TransportErrorHTTPStatus {
+		return fmt.Sprintf("%v(%v): %v", e.Kind, e.StatusCode, e.Err)
+	}
+
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// httpStatusPattern matches the error message of non-2xx HTTP status, which is formatted by go-rpc-provider as the
+// status code followed by the response body if any.
+var httpStatusPattern = regexp.MustCompile(`^([1-5][0-9]{2})(\s|$)`)
+
+// classifyTransportError returns the transport error of err, or nil if err is not a transport-level failure,
+// e.g. JSON-RPC error responded by storage node.
+func classifyTransportError(err error) *TransportError {
+	if err == nil {
+		return nil
+	}
+
+	kind, code := transportErrorKindOf(err)
+	if len(kind) == 0 {
+		return nil
+	}
+
+	return &TransportError{kind, code, err}
+}
+
+func transportErrorKindOf(err error) (TransportErrorKind, int) {
+	// JSON-RPC error responded by storage node
+	var jsonErr interface{ ErrorCode() int }
+	if errors.As(err, &jsonErr) {
+		return "", 0
+	}
+
+	// the status error is formatted without wrapping, and maybe wrapped by retry middleware
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if match := httpStatusPattern.FindStringSubmatch(e.Error()); match != nil {
+			code, _ := strconv.Atoi(match[1])
+			return TransportErrorHTTPStatus, code
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return TransportErrorDNS, 0
+	}
+
+	var opErr *net.OpError
+	if errors.Is(err, fasthttp.ErrDialTimeout) || (errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout()) {
+		return TransportErrorConnectTimeout, 0
+	}
+
+	if isTLSError(err) {
+		return TransportErrorTLS, 0
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, fasthttp.ErrConnectionClosed) {
+		return TransportErrorResetByPeer, 0
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, syscall.ECONNREFUSED) {
+		return TransportErrorOther, 0
+	}
+
+	return "", 0
+}
+
+func isTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	if errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return true
+	}
+
+	// most of handshake errors are not typed in crypto/tls
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if strings.HasPrefix(e.Error(), "tls: ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transportErrorCounter counts transport errors by kind per storage node.
+type transportErrorCounter struct {
+	mu     sync.Mutex
+	counts map[string]map[TransportErrorKind]uint64 // url -> kind -> count
+}
+
+var transportErrors = transportErrorCounter{counts: make(map[string]map[TransportErrorKind]uint64)}
+
+func (counter *transportErrorCounter) inc(url string, kind TransportErrorKind) {
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	if counter.counts[url] == nil {
+		counter.counts[url] = make(map[TransportErrorKind]uint64)
+	}
+
+	counter.counts[url][kind]++
+}
+
+// TransportErrorCounts returns the number of transport errors by kind of RPCs to the specified storage node, which
+// are counted across all clients in process, e.g. to export as metrics.
+func TransportErrorCounts(url string) map[TransportErrorKind]uint64 {
+	transportErrors.mu.Lock()
+	defer transportErrors.mu.Unlock()
+
+	counts := make(map[TransportErrorKind]uint64, len(transportErrors.counts[url]))
+	for kind, count := range transportErrors.counts[url] {
+		counts[kind] = count
+	}
+
+	return counts
+}
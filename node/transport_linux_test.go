@@ -0,0 +1,44 @@
+package node_test
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/stretchr/testify/assert"
+)
+
+// newBlackholeListener returns a listener whose accept queue is full, so that SYNs of new connections are dropped
+// and dialing times out.
+func newBlackholeListener(t *testing.T) net.Listener {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}))
+	assert.NoError(t, syscall.Listen(fd, 0))
+
+	file := os.NewFile(uintptr(fd), "blackhole")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	// fill the accept queue, which is never accepted
+	for {
+		conn, err := net.DialTimeout("tcp", listener.Addr().String(), 200*time.Millisecond)
+		if err != nil {
+			break
+		}
+		t.Cleanup(func() { conn.Close() })
+	}
+
+	return listener
+}
+
+func TestTransportErrorConnectTimeout(t *testing.T) {
+	listener := newBlackholeListener(t)
+
+	assertTransportError(t, "http://"+listener.Addr().String(), node.TransportErrorConnectTimeout)
+}
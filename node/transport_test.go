@@ -0,0 +1,112 @@
+package node_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// getStatusError returns the RPC error to get status of storage node at the specified url.
+func getStatusError(t *testing.T, url string) *node.RPCError {
+	client, err := node.NewZgsClient(url)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.GetStatus(context.Background())
+
+	var rpcError *node.RPCError
+	assert.True(t, errors.As(err, &rpcError), "RPC error expected: %v", err)
+
+	return rpcError
+}
+
+// assertTransportError asserts the RPC to storage node at url failed at transport level of the specified kind, and
+// counted in metrics.
+func assertTransportError(t *testing.T, url string, kind node.TransportErrorKind) *node.TransportError {
+	before := node.TransportErrorCounts(url)[kind]
+
+	rpcError := getStatusError(t, url)
+	assert.True(t, rpcError.IsTransport())
+	assert.Equal(t, kind, rpcError.Transport.Kind, rpcError.Message)
+
+	var transportErr *node.TransportError
+	assert.True(t, errors.As(rpcError, &transportErr))
+	assert.Equal(t, before+1, node.TransportErrorCounts(url)[kind])
+
+	return rpcError.Transport
+}
+
+func TestTransportErrorDNS(t *testing.T) {
+	assertTransportError(t, "http://nonexistent.invalid:5678", node.TransportErrorDNS)
+}
+
+func TestTransportErrorTLS(t *testing.T) {
+	// certificate signed by unknown authority
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // handshake error logged by server
+	server.StartTLS()
+	defer server.Close()
+	assertTransportError(t, server.URL, node.TransportErrorTLS)
+
+	// not a TLS server
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	assertTransportError(t, "https"+plain.URL[len("http"):], node.TransportErrorTLS)
+}
+
+func TestTransportErrorResetByPeer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		assert.NoError(t, err)
+
+		// send RST instead of FIN
+		conn.(*net.TCPConn).SetLinger(0)
+		conn.Close()
+	}))
+	defer server.Close()
+
+	assertTransportError(t, server.URL, node.TransportErrorResetByPeer)
+}
+
+func TestTransportErrorHTTPStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("overloaded"))
+	}))
+	defer server.Close()
+
+	transportErr := assertTransportError(t, server.URL, node.TransportErrorHTTPStatus)
+	assert.Equal(t, http.StatusServiceUnavailable, transportErr.StatusCode)
+}
+
+func TestTransportErrorOther(t *testing.T) {
+	// connection refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	url := "http://" + listener.Addr().String()
+	listener.Close()
+
+	assertTransportError(t, url, node.TransportErrorOther)
+}
+
+func TestApplicationError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"invalid segment"}}`))
+	}))
+	defer server.Close()
+
+	rpcError := getStatusError(t, server.URL)
+	assert.False(t, rpcError.IsTransport())
+	assert.Contains(t, rpcError.Message, "invalid segment")
+	assert.Empty(t, node.TransportErrorCounts(server.URL))
+}
@@ -2,6 +2,7 @@ package node
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/0glabs/0g-storage-client/common/shard"
 	"github.com/0glabs/0g-storage-client/core/merkle"
@@ -30,6 +31,46 @@ type Status struct {
 	LogSyncBlock    common.Hash     `json:"logSyncBlock"`
 	NextTxSeq       uint64          `json:"nextTxSeq"`
 	NetworkIdentity NetworkIdentity `json:"networkIdentity"`
+	Load            *LoadHint       `json:"load,omitempty"`        // optional, only advertised by some storage nodes
+	MaxFileSize     uint64          `json:"maxFileSize,omitempty"` // max padded size of file in bytes to submit, 0 if not advertised
+}
+
+// LoadHint capacity hint advertised by storage node about how busy it is.
+type LoadHint struct {
+	PendingSegments    uint64 `json:"pendingSegments"`        // number of segments queued to write
+	MaxPendingSegments uint64 `json:"maxPendingSegments"`     // max number of segments allowed to queue, 0 if unknown
+	Overloaded         bool   `json:"overloaded"`             // whether new segments will be rejected for now
+	RetryAfterMs       uint64 `json:"retryAfterMs,omitempty"` // suggested time in milliseconds to wait if overloaded
+}
+
+// RetryAfter returns the suggested time to wait if overloaded, and 0 if not advertised.
+func (hint *LoadHint) RetryAfter() time.Duration {
+	if hint == nil {
+		return 0
+	}
+
+	return time.Duration(hint.RetryAfterMs) * time.Millisecond
+}
+
+// Utilization returns the ratio of pending segments to capacity in range [0, 1], and 1 if overloaded.
+func (hint *LoadHint) Utilization() float64 {
+	if hint == nil {
+		return 0
+	}
+
+	if hint.Overloaded {
+		return 1
+	}
+
+	if hint.MaxPendingSegments == 0 {
+		return 0
+	}
+
+	if hint.PendingSegments >= hint.MaxPendingSegments {
+		return 1
+	}
+
+	return float64(hint.PendingSegments) / float64(hint.MaxPendingSegments)
 }
 
 // Transaction on-chain transaction about a file
@@ -44,11 +85,12 @@ type Transaction struct {
 
 // FileInfo information about a file responded from 0g storage node
 type FileInfo struct {
-	Tx             Transaction `json:"tx"`             // on-chain transaction
-	Finalized      bool        `json:"finalized"`      // whether the file has been finalized in the storage node
-	IsCached       bool        `json:"isCached"`       // whether the file is cached in the storage node
-	UploadedSegNum uint64      `json:"uploadedSegNum"` // the number of uploaded segments
-	Pruned         bool        `json:"pruned"`         // whether the file has been pruned, and mutually exclusive with Finalized
+	Tx             Transaction `json:"tx"`                       // on-chain transaction
+	Finalized      bool        `json:"finalized"`                // whether the file has been finalized in the storage node
+	IsCached       bool        `json:"isCached"`                 // whether the file is cached in the storage node
+	UploadedSegNum uint64      `json:"uploadedSegNum"`           // the number of uploaded segments
+	Pruned         bool        `json:"pruned"`                   // whether the file has been pruned, and mutually exclusive with Finalized
+	RetentionClass string      `json:"retentionClass,omitempty"` // retention class applied by submission tags, only reported by storage nodes of private networks
 }
 
 // SegmentWithProof data segment with merkle proof
@@ -0,0 +1,594 @@
+// Package smoke verifies a live ZeroGStorage network end to end, e.g. after deployment or periodically in
+// monitoring, by running the full loop of storage and KV operations with a strict budget cap, see Run.
+package smoke
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/contract"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/kv"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/openweb3/web3go"
+	"github.com/pkg/errors"
+)
+
+// Step is a step of smoke test, which runs in the order declared.
+type Step string
+
+const (
+	StepUpload   Step = "upload"   // upload a small file of random content, and wait for transaction packed
+	StepFinalize Step = "finalize" // wait for the file finalized on all storage nodes
+	StepDownload Step = "download" // download the file back with merkle proof
+	StepCompare  Step = "compare"  // compare the downloaded file with the uploaded one
+	StepKvWrite  Step = "kv-write" // write a random key to the test stream
+	StepKvRead   Step = "kv-read"  // read the key back from KV node
+	StepCleanup  Step = "cleanup"  // remove local files, which always runs
+)
+
+// Steps returns all steps of smoke test in order.
+func Steps() []Step {
+	return []Step{StepUpload, StepFinalize, StepDownload, StepCompare, StepKvWrite, StepKvRead, StepCleanup}
+}
+
+// StepStatus is the outcome of a step.
+type StepStatus string
+
+const (
+	StepPassed  StepStatus = "passed"
+	StepFailed  StepStatus = "failed"
+	StepSkipped StepStatus = "skipped" // not run since a previous step failed
+)
+
+const (
+	// DefaultFileSize is the default size of random file to upload.
+	DefaultFileSize = 1024
+
+	// DefaultStreamName is the default name of KV stream to write test keys.
+	DefaultStreamName = "0g-storage-client/smoke"
+
+	// DefaultGasAllowance is the default max gas reserved per transaction to check budget before sending, which is
+	// far more than a flow submission of small file costs.
+	DefaultGasAllowance = 1_000_000
+
+	defaultStepTimeout  = 10 * time.Minute
+	defaultPollInterval = time.Second
+)
+
+// ErrBudgetExceeded is returned when the next transaction may cost more than the remaining budget, in which case the
+// transaction is not sent.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Network is the network to run smoke test against.
+type Network struct {
+	W3Client *web3go.Client    // fullnode client with signer to send transactions
+	Clients  []*node.ZgsClient // storage nodes to upload and download
+	KvClient *node.KvClient    // KV node to read back the test key
+}
+
+// Option is the option of smoke test.
+type Option struct {
+	Budget       *big.Int      // max fee in neuron of all transactions, including gas and storage fee, required
+	FileSize     int64         // size of random file to upload, DefaultFileSize if 0
+	StreamName   string        // name of KV stream to write test keys, whose id is the keccak256 hash of name, DefaultStreamName if empty
+	GasAllowance uint64        // max gas reserved per transaction to check budget, DefaultGasAllowance if 0
+	StepTimeout  time.Duration // timeout of each step, 10 minutes if 0
+	PollInterval time.Duration // interval to poll file finality and KV value, 1 second if 0
+	WorkDir      string        // directory of local files, which is removed on cleanup, a new temporary directory if empty
+}
+
+// Validate checks the option, and returns an error that names the invalid field if any.
+func (opt *Option) Validate() error {
+	if opt.Budget == nil || opt.Budget.Sign() <= 0 {
+		return zg_common.NewOptionError("Budget", "should be positive, got %v", opt.Budget)
+	}
+
+	return zg_common.FirstError(
+		zg_common.RequireNonNegative("FileSize", opt.FileSize),
+		zg_common.RequireNonNegative("StepTimeout", opt.StepTimeout),
+		zg_common.RequireNonNegative("PollInterval", opt.PollInterval),
+	)
+}
+
+// StreamId returns the id of KV stream to write test keys.
+func (opt *Option) StreamId() common.Hash {
+	name := opt.StreamName
+	if len(name) == 0 {
+		name = DefaultStreamName
+	}
+
+	return crypto.Keccak256Hash([]byte(name))
+}
+
+// StepResult is the machine-readable outcome of a step, along with the identifiers involved if any.
+type StepResult struct {
+	Step     Step          `json:"step"`
+	Status   StepStatus    `json:"status"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	Root     *common.Hash  `json:"root,omitempty"`
+	TxHash   *common.Hash  `json:"txHash,omitempty"`
+	Cost     *big.Int      `json:"cost,omitempty"` // gas and storage fee paid by the transaction in neuron
+}
+
+// Report is the report of smoke test.
+type Report struct {
+	Passed     bool          `json:"passed"`
+	FailedStep Step          `json:"failedStep,omitempty"`
+	Started    time.Time     `json:"started"`
+	Elapsed    time.Duration `json:"elapsed"`
+	Budget     *big.Int      `json:"budget"` // in neuron
+	Spent      *big.Int      `json:"spent"`  // in neuron
+	StreamId   common.Hash   `json:"streamId"`
+	Key        string        `json:"key"` // KV key written, unique per run
+	Steps      []StepResult  `json:"steps"`
+}
+
+// StepError is returned when a step of smoke test failed.
+type StepError struct {
+	Step   Step
+	Root   *common.Hash
+	TxHash *common.Hash
+	Err    error
+}
+
+func (e *StepError) Error() string {
+	msg := fmt.Sprintf("smoke test failed at step %v", e.Step)
+	if e.Root != nil {
+		msg += fmt.Sprintf(", root %v", *e.Root)
+	}
+	if e.TxHash != nil {
+		msg += fmt.Sprintf(", tx %v", *e.TxHash)
+	}
+
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// runner runs the steps of smoke test in order.
+type runner struct {
+	network Network
+	opt     Option
+	report  *Report
+	budget  *budget
+
+	dir      string
+	content  []byte
+	root     common.Hash
+	key      []byte
+	value    []byte
+	uploaded string // path of local file uploaded
+}
+
+// Run runs the full loop of smoke test against the network: uploads a small file of random content, waits for
+// the file finalized, downloads it back and compares, then writes a random key to the test stream and reads it
+// back, and removes local files at last. Content and key are unique per run, so that it is safe to run repeatedly.
+//
+// Before sending a transaction, the storage fee and gas allowance are checked against the remaining budget, and the
+// step fails with ErrBudgetExceeded without sending if not enough. Steps after a failed one are skipped except
+// cleanup. The report is always returned along with a *StepError if any step failed.
+func Run(ctx context.Context, network Network, option Option) (*Report, error) {
+	if err := option.Validate(); err != nil {
+		return nil, err
+	}
+
+	if len(network.Clients) == 0 {
+		return nil, errors.New("Storage node not specified")
+	}
+
+	if network.KvClient == nil {
+		return nil, errors.New("KV node not specified")
+	}
+
+	if option.FileSize == 0 {
+		option.FileSize = DefaultFileSize
+	}
+	if option.GasAllowance == 0 {
+		option.GasAllowance = DefaultGasAllowance
+	}
+	if option.StepTimeout == 0 {
+		option.StepTimeout = defaultStepTimeout
+	}
+	if option.PollInterval == 0 {
+		option.PollInterval = defaultPollInterval
+	}
+
+	budget, err := newBudget(ctx, network, option)
+	if err != nil {
+		return nil, err
+	}
+
+	r := runner{
+		network: network,
+		opt:     option,
+		budget:  budget,
+		report: &Report{
+			Started:  time.Now(),
+			Budget:   new(big.Int).Set(option.Budget),
+			StreamId: option.StreamId(),
+		},
+	}
+
+	if r.content, err = randomBytes(int(option.FileSize)); err != nil {
+		return nil, err
+	}
+
+	suffix, err := randomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	r.key = []byte("smoke-" + hex.EncodeToString(suffix))
+	r.value = []byte(fmt.Sprintf("smoke test at %v", r.report.Started.UTC().Format(time.RFC3339Nano)))
+	r.report.Key = string(r.key)
+
+	steps := map[Step]func(ctx context.Context, result *StepResult) error{
+		StepUpload:   r.upload,
+		StepFinalize: r.finalize,
+		StepDownload: r.download,
+		StepCompare:  r.compare,
+		StepKvWrite:  r.kvWrite,
+		StepKvRead:   r.kvRead,
+		StepCleanup:  r.cleanup,
+	}
+
+	var failure *StepError
+	for _, step := range Steps() {
+		result := StepResult{Step: step, Status: StepSkipped}
+		if failure == nil || step == StepCleanup {
+			if err := r.run(ctx, steps[step], &result); err != nil && failure == nil {
+				failure = &StepError{Step: step, Root: result.Root, TxHash: result.TxHash, Err: err}
+				r.report.FailedStep = step
+			}
+		}
+
+		r.report.Steps = append(r.report.Steps, result)
+	}
+
+	r.report.Passed = failure == nil
+	r.report.Elapsed = time.Since(r.report.Started)
+	r.report.Spent = new(big.Int).Set(r.budget.spent)
+
+	if failure != nil {
+		return r.report, failure
+	}
+
+	return r.report, nil
+}
+
+// run runs a step with timeout, and records the outcome in result.
+func (r *runner) run(ctx context.Context, fn func(ctx context.Context, result *StepResult) error, result *StepResult) error {
+	ctx, cancel := context.WithTimeout(ctx, r.opt.StepTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx, result)
+	result.Duration = time.Since(start)
+
+	if err != nil {
+		result.Status = StepFailed
+		result.Error = err.Error()
+	} else {
+		result.Status = StepPassed
+	}
+
+	return err
+}
+
+func (r *runner) upload(ctx context.Context, result *StepResult) error {
+	dir := r.opt.WorkDir
+	if len(dir) == 0 {
+		var err error
+		if dir, err = os.MkdirTemp("", "0g-smoke-"); err != nil {
+			return errors.WithMessage(err, "Failed to create temporary directory")
+		}
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.WithMessagef(err, "Failed to create directory %v", dir)
+	}
+	r.dir = dir
+
+	r.uploaded = filepath.Join(dir, "upload.bin")
+	if err := os.WriteFile(r.uploaded, r.content, 0644); err != nil {
+		return errors.WithMessage(err, "Failed to write random file")
+	}
+
+	data, err := core.NewDataInMemory(r.content)
+	if err != nil {
+		return err
+	}
+
+	tree, err := core.MerkleTree(data)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to create data merkle tree")
+	}
+	r.root = tree.Root()
+	result.Root = &r.root
+
+	if err = r.budget.reserve(ctx, data); err != nil {
+		return err
+	}
+
+	uploader, err := transfer.NewUploader(ctx, r.network.W3Client, r.network.Clients)
+	if err != nil {
+		return err
+	}
+
+	txHash, _, err := uploader.Upload(ctx, data, transfer.UploadOption{
+		FinalityRequired: transfer.TransactionPacked,
+		ExpectedReplica:  1,
+	})
+	if txHash != (common.Hash{}) {
+		result.TxHash = &txHash
+		if result.Cost, err = r.budget.charge(ctx, txHash, err); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (r *runner) finalize(ctx context.Context, result *StepResult) error {
+	result.Root = &r.root
+
+	for {
+		finalized := true
+		for _, client := range r.network.Clients {
+			info, err := client.GetFileInfo(ctx, r.root)
+			if err != nil {
+				return errors.WithMessagef(err, "Failed to get file info from storage node %v", client.URL())
+			}
+
+			if info == nil || !info.Finalized {
+				finalized = false
+				break
+			}
+		}
+
+		if finalized {
+			return nil
+		}
+
+		if err := sleep(ctx, r.opt.PollInterval); err != nil {
+			return errors.WithMessage(err, "File not finalized on storage nodes")
+		}
+	}
+}
+
+func (r *runner) download(ctx context.Context, result *StepResult) error {
+	result.Root = &r.root
+
+	downloader, err := transfer.NewDownloader(r.network.Clients)
+	if err != nil {
+		return err
+	}
+
+	return downloader.Download(ctx, r.root.Hex(), r.downloadedPath(), true)
+}
+
+func (r *runner) downloadedPath() string {
+	return filepath.Join(r.dir, "download.bin")
+}
+
+func (r *runner) compare(ctx context.Context, result *StepResult) error {
+	result.Root = &r.root
+
+	downloaded, err := os.ReadFile(r.downloadedPath())
+	if err != nil {
+		return errors.WithMessage(err, "Failed to read downloaded file")
+	}
+
+	if len(downloaded) != len(r.content) {
+		return errors.Errorf("Size mismatch, uploaded %v, downloaded %v", len(r.content), len(downloaded))
+	}
+
+	if !bytes.Equal(downloaded, r.content) {
+		for i := range downloaded {
+			if downloaded[i] != r.content[i] {
+				return errors.Errorf("Content mismatch at offset %v", i)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *runner) kvWrite(ctx context.Context, result *StepResult) error {
+	batcher := kv.NewBatcher(math.MaxUint64, r.network.Clients, r.network.W3Client).WithStrict(true)
+	batcher.Set(r.report.StreamId, r.key, r.value)
+
+	streamData, err := batcher.Build()
+	if err != nil {
+		return errors.WithMessage(err, "Failed to build KV stream data")
+	}
+
+	encoded, err := streamData.Encode()
+	if err != nil {
+		return errors.WithMessage(err, "Failed to encode KV stream data")
+	}
+
+	data, err := core.NewDataInMemory(encoded)
+	if err != nil {
+		return err
+	}
+
+	if err = r.budget.reserve(ctx, data); err != nil {
+		return err
+	}
+
+	execResult, err := batcher.ExecAll(ctx, transfer.UploadOption{
+		FinalityRequired: transfer.TransactionPacked,
+		ExpectedReplica:  1,
+	})
+	if execResult != nil && len(execResult.Txs) > 0 {
+		tx := execResult.Txs[0]
+		result.Root, result.TxHash = &tx.Root, &tx.TxHash
+		if result.Cost, err = r.budget.charge(ctx, tx.TxHash, err); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (r *runner) kvRead(ctx context.Context, result *StepResult) error {
+	client := kv.NewClient(r.network.KvClient)
+
+	for {
+		value, err := client.GetValue(ctx, r.report.StreamId, r.key)
+		if err != nil {
+			return errors.WithMessagef(err, "Failed to read key %v", string(r.key))
+		}
+
+		if value != nil && len(value.Data) > 0 {
+			if !bytes.Equal(value.Data, r.value) {
+				return errors.Errorf("Value mismatch of key %v, written %q, read %q", string(r.key), r.value, value.Data)
+			}
+
+			return nil
+		}
+
+		if err := sleep(ctx, r.opt.PollInterval); err != nil {
+			return errors.WithMessagef(err, "Key %v not available on KV node", string(r.key))
+		}
+	}
+}
+
+func (r *runner) cleanup(ctx context.Context, result *StepResult) error {
+	if len(r.dir) == 0 {
+		return nil
+	}
+
+	return os.RemoveAll(r.dir)
+}
+
+// budget checks the fee of transactions against the budget before sending.
+type budget struct {
+	w3Client       *web3go.Client
+	costs          transfer.TxCostSource
+	pricePerSector *big.Int
+	gasAllowance   uint64
+
+	limit *big.Int
+	spent *big.Int
+}
+
+func newBudget(ctx context.Context, network Network, option Option) (*budget, error) {
+	status, err := network.Clients[0].GetStatus(ctx)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed to get status from storage node %v", network.Clients[0].URL())
+	}
+
+	flow, err := contract.NewFlowContract(status.NetworkIdentity.FlowContractAddress, network.W3Client)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create flow contract")
+	}
+
+	market, err := flow.GetMarketContract(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to get market contract")
+	}
+
+	price, err := market.PricePerSector(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to read price per sector")
+	}
+
+	return &budget{
+		w3Client:       network.W3Client,
+		costs:          transfer.NewChainTxCostSource(network.W3Client),
+		pricePerSector: price,
+		gasAllowance:   option.GasAllowance,
+		limit:          option.Budget,
+		spent:          new(big.Int),
+	}, nil
+}
+
+// reserve checks that the storage fee of data and gas allowance at the current gas price do not exceed the
+// remaining budget.
+func (b *budget) reserve(ctx context.Context, data core.IterableData) error {
+	submission, err := core.NewSubmission(data, nil)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to create flow submission")
+	}
+
+	gasPrice, err := b.w3Client.WithContext(ctx).Eth.GasPrice()
+	if err != nil {
+		return errors.WithMessage(err, "Failed to get gas price")
+	}
+
+	fee := submission.Fee(b.pricePerSector)
+	fee.Add(fee, new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(b.gasAllowance)))
+
+	if remaining := new(big.Int).Sub(b.limit, b.spent); fee.Cmp(remaining) > 0 {
+		return errors.WithMessagef(ErrBudgetExceeded, "transaction may cost up to %v neuron, but %v remaining", fee, remaining)
+	}
+
+	return nil
+}
+
+// charge records the cost of transaction sent, and returns the cost along with the error of step, or
+// ErrBudgetExceeded if the actual cost exceeds the budget.
+func (b *budget) charge(ctx context.Context, txHash common.Hash, stepErr error) (*big.Int, error) {
+	cost, err := b.costs.TxCost(ctx, txHash)
+	if err != nil {
+		if stepErr != nil {
+			return nil, stepErr
+		}
+
+		return nil, errors.WithMessage(err, "Failed to get transaction cost")
+	}
+
+	total := cost.GasCost()
+	if cost.Value != nil {
+		total.Add(total, cost.Value)
+	}
+	b.spent.Add(b.spent, total)
+
+	if stepErr != nil {
+		return total, stepErr
+	}
+
+	if b.spent.Cmp(b.limit) > 0 {
+		return total, errors.WithMessagef(ErrBudgetExceeded, "spent %v neuron in total", b.spent)
+	}
+
+	return total, nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, errors.WithMessage(err, "Failed to generate random bytes")
+	}
+
+	return buf, nil
+}
+
+// sleep waits for the duration, or returns the error of context if done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
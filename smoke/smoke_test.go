@@ -0,0 +1,111 @@
+package smoke
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestNetwork(t *testing.T) (*testutil.Network, Network) {
+	network := testutil.NewNetwork(t)
+
+	return network, Network{
+		W3Client: network.Web3(),
+		Clients:  network.ZgsClients(),
+		KvClient: network.KvClient(),
+	}
+}
+
+func newTestOption(t *testing.T, budget *big.Int) Option {
+	return Option{
+		Budget:       budget,
+		PollInterval: 10 * time.Millisecond,
+		WorkDir:      filepath.Join(t.TempDir(), "smoke"),
+	}
+}
+
+func TestRun(t *testing.T) {
+	network, target := newTestNetwork(t)
+
+	_, err := Run(context.Background(), target, Option{})
+	assert.ErrorContains(t, err, "Budget")
+
+	opt := newTestOption(t, big.NewInt(1e18))
+	report, err := Run(context.Background(), target, opt)
+	assert.NoError(t, err)
+	assert.True(t, report.Passed)
+	assert.Empty(t, report.FailedStep)
+	assert.Equal(t, opt.StreamId(), report.StreamId)
+
+	assert.Equal(t, len(Steps()), len(report.Steps))
+	spent := new(big.Int)
+	for i, result := range report.Steps {
+		assert.Equal(t, Steps()[i], result.Step)
+		assert.Equal(t, StepPassed, result.Status, result.Error)
+		if result.Cost != nil {
+			spent.Add(spent, result.Cost)
+		}
+	}
+
+	// transactions of upload and kv write
+	assert.Equal(t, 2, len(network.Chain.Submissions()))
+	assert.NotNil(t, report.Steps[0].TxHash)
+	assert.NotNil(t, report.Steps[4].TxHash)
+	assert.Positive(t, report.Spent.Sign())
+	assert.Equal(t, spent, report.Spent)
+
+	_, err = os.Stat(opt.WorkDir)
+	assert.True(t, os.IsNotExist(err))
+
+	// unique content and key in each run
+	again, err := Run(context.Background(), target, newTestOption(t, big.NewInt(1e18)))
+	assert.NoError(t, err)
+	assert.NotEqual(t, report.Key, again.Key)
+	assert.NotEqual(t, *report.Steps[0].Root, *again.Steps[0].Root)
+	assert.Equal(t, 4, len(network.Chain.Submissions()))
+}
+
+func TestRunBudgetExceeded(t *testing.T) {
+	network, target := newTestNetwork(t)
+
+	// not enough for the first transaction
+	report, err := Run(context.Background(), target, newTestOption(t, big.NewInt(1)))
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.False(t, report.Passed)
+	assert.Equal(t, StepUpload, report.FailedStep)
+	assert.Empty(t, network.Chain.Submissions())
+	assert.Zero(t, report.Spent.Sign())
+
+	var stepErr *StepError
+	assert.True(t, errors.As(err, &stepErr))
+	assert.Equal(t, StepUpload, stepErr.Step)
+	assert.Contains(t, err.Error(), report.Steps[0].Root.Hex())
+
+	// enough for upload only
+	full, err := Run(context.Background(), target, newTestOption(t, big.NewInt(1e18)))
+	assert.NoError(t, err)
+	budget := new(big.Int).Add(full.Steps[0].Cost, big.NewInt(1))
+
+	opt := newTestOption(t, budget)
+	report, err = Run(context.Background(), target, opt)
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Equal(t, StepKvWrite, report.FailedStep)
+	assert.Equal(t, full.Steps[0].Cost, report.Spent)
+	assert.Equal(t, 3, len(network.Chain.Submissions()))
+
+	var statuses []StepStatus
+	for _, result := range report.Steps {
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []StepStatus{StepPassed, StepPassed, StepPassed, StepPassed, StepFailed, StepSkipped, StepPassed}, statuses)
+
+	_, err = os.Stat(opt.WorkDir)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,290 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// AppendTree is the persisted merkle tree of a file, which is used to append data to the file without
+// reading and hashing the existing data again.
+type AppendTree struct {
+	Root         common.Hash   `json:"root"`
+	Size         int64         `json:"size"`
+	SegmentRoots []common.Hash `json:"segmentRoots"`   // roots of leading segments that fully filled with data
+	Tail         hexutil.Bytes `json:"tail,omitempty"` // data of the last segment that partially filled, if any
+}
+
+// NewAppendTree creates the merkle tree of data to append data later.
+func NewAppendTree(data core.IterableData) (*AppendTree, error) {
+	return newAppendTree(data, nil)
+}
+
+// newAppendTree creates the merkle tree of data, of which the leading segment roots are already known.
+func newAppendTree(data core.IterableData, knownRoots []common.Hash) (*AppendTree, error) {
+	numFullSegments := int(data.Size() / core.DefaultSegmentSize)
+
+	tree := AppendTree{
+		Size:         data.Size(),
+		SegmentRoots: make([]common.Hash, 0, numFullSegments),
+	}
+
+	for i := 0; i < numFullSegments; i++ {
+		if i < len(knownRoots) {
+			tree.SegmentRoots = append(tree.SegmentRoots, knownRoots[i])
+			continue
+		}
+
+		segment, err := core.ReadAt(data, core.DefaultSegmentSize, int64(i)*core.DefaultSegmentSize, data.PaddedSize())
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read segment %v", i)
+		}
+
+		tree.SegmentRoots = append(tree.SegmentRoots, core.SegmentRoot(segment))
+	}
+
+	if tailSize := data.Size() - int64(numFullSegments)*core.DefaultSegmentSize; tailSize > 0 {
+		tree.Tail = make([]byte, tailSize)
+		if _, err := data.Read(tree.Tail, int64(numFullSegments)*core.DefaultSegmentSize); err != nil {
+			return nil, errors.WithMessage(err, "failed to read the last segment")
+		}
+	}
+
+	// only the last segment and padding segments are hashed
+	var err error
+	if tree.Root, err = core.MerkleRootData(newAppendedData(&tree, nil)); err != nil {
+		return nil, errors.WithMessage(err, "failed to calculate merkle root")
+	}
+
+	return &tree, nil
+}
+
+// LoadAppendTree loads the persisted merkle tree from the specified file.
+func LoadAppendTree(path string) (*AppendTree, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read file")
+	}
+
+	var tree AppendTree
+	if err = json.Unmarshal(content, &tree); err != nil {
+		return nil, errors.WithMessage(err, "failed to unmarshal merkle tree")
+	}
+
+	if tree.Size != int64(len(tree.SegmentRoots))*core.DefaultSegmentSize+int64(len(tree.Tail)) {
+		return nil, errors.Errorf("size mismatch, size = %v, segments = %v, tail = %v", tree.Size, len(tree.SegmentRoots), len(tree.Tail))
+	}
+
+	if tree.Size == 0 {
+		return nil, errors.New("empty file")
+	}
+
+	return &tree, nil
+}
+
+// Save persists the merkle tree to the specified file.
+func (tree *AppendTree) Save(path string) error {
+	content, err := json.Marshal(tree)
+	if err != nil {
+		return errors.WithMessage(err, "failed to marshal merkle tree")
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
+
+// appendedData is the data appended to an existing file, of which the last segment that partially filled and the
+// appended data are available to read, while data of known segments is read from source if any.
+type appendedData struct {
+	base       *AppendTree
+	source     io.ReaderAt       // data of existing file to read known segments, nil if unavailable
+	tail       core.IterableData // nil if nothing appended
+	size       int64
+	paddedSize uint64
+}
+
+var _ core.IterableData = (*appendedData)(nil)
+var _ core.KnownSegments = (*appendedData)(nil)
+
+func newAppendedData(base *AppendTree, tail core.IterableData) *appendedData {
+	size := base.Size
+	if tail != nil {
+		size += tail.Size()
+	}
+
+	return &appendedData{
+		base:       base,
+		tail:       tail,
+		size:       size,
+		paddedSize: core.PaddedSize(size),
+	}
+}
+
+// KnownSegmentRoots implements the core.KnownSegments interface.
+func (data *appendedData) KnownSegmentRoots() []common.Hash {
+	return data.base.SegmentRoots
+}
+
+func (data *appendedData) NumChunks() uint64 {
+	return core.NumChunks(data.size)
+}
+
+func (data *appendedData) NumSegments() uint64 {
+	return core.NumSegments(data.size)
+}
+
+func (data *appendedData) Offset() int64 {
+	return 0
+}
+
+func (data *appendedData) Size() int64 {
+	return data.size
+}
+
+func (data *appendedData) PaddedSize() uint64 {
+	return data.paddedSize
+}
+
+func (data *appendedData) Read(buf []byte, offset int64) (int, error) {
+	knownSize := int64(len(data.base.SegmentRoots)) * core.DefaultSegmentSize
+	if offset < knownSize {
+		if data.source == nil {
+			return 0, errors.Errorf("data of known segments unavailable, offset = %v", offset)
+		}
+
+		limit := int(min(int64(len(buf)), knownSize-offset))
+		n, err := data.source.ReadAt(buf[:limit], offset)
+		if err != nil && !(err == io.EOF && n == limit) {
+			return n, errors.WithMessagef(err, "failed to read known segments, offset = %v", offset)
+		}
+		if n == len(buf) {
+			return n, nil
+		}
+
+		m, err := data.Read(buf[n:], offset+int64(n))
+		return n + m, err
+	}
+
+	var n int
+	if offset < data.base.Size {
+		n = copy(buf, data.base.Tail[offset-knownSize:])
+	}
+
+	if data.tail == nil || n == len(buf) {
+		return n, nil
+	}
+
+	tailOffset := offset + int64(n) - data.base.Size
+	if tailOffset >= data.tail.Size() {
+		return n, nil
+	}
+
+	readSize := min(int64(len(buf)-n), data.tail.Size()-tailOffset)
+	m, err := data.tail.Read(buf[n:n+int(readSize)], tailOffset)
+
+	return n + m, err
+}
+
+// Split returns the data itself, since the appended data could not be uploaded in fragments.
+func (data *appendedData) Split(fragmentSize int64) []core.IterableData {
+	return []core.IterableData{data}
+}
+
+// AppendResult is the result of appending data to an existing file.
+type AppendResult struct {
+	TxHash         common.Hash // transaction hash to submit the new file
+	Root           common.Hash // merkle root of the new file
+	ReusedSegments uint64      // number of segments of the existing file not hashed again, but fetched from storage nodes
+	PushedSegments uint64      // number of segments uploaded to storage nodes, i.e. all segments of the new file
+	Tree           *AppendTree // merkle tree of the new file, which could be persisted to append again
+}
+
+// Append submits a new file that consists of the existing file and the appended tail.
+//
+// The merkle tree of existing file is loaded from the specified path, which is persisted by AppendTree.Save,
+// so that only new segments are hashed. Since storage nodes store the new file separately, all segments are
+// uploaded, where segments of the existing file are fetched from storage nodes with merkle proofs validated
+// against the root of existing file, so that the existing data is not required locally. Besides, the merkle tree
+// of new file is returned in result, which could be persisted to append data again.
+func (uploader *Uploader) Append(ctx context.Context, baseRoot common.Hash, baseTreePath string, tail core.IterableData, option ...UploadOption) (*AppendResult, error) {
+	base, err := LoadAppendTree(baseTreePath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to load merkle tree of existing file")
+	}
+
+	data, tree, err := prepareAppend(baseRoot, base, tail)
+	if err != nil {
+		return nil, err
+	}
+
+	if core.NumKnownSegments(data) > 0 {
+		source, err := uploader.openAppendSource(ctx, baseRoot)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to open existing file on storage nodes")
+		}
+		defer source.Close()
+
+		data.source = source
+	}
+
+	txHash, root, err := uploader.Upload(ctx, data, option...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AppendResult{
+		TxHash:         txHash,
+		Root:           root,
+		ReusedSegments: core.NumKnownSegments(data),
+		PushedSegments: data.NumSegments(),
+		Tree:           tree,
+	}, nil
+}
+
+// openAppendSource opens the existing file of root on storage nodes of uploader to read the known segments.
+func (uploader *Uploader) openAppendSource(ctx context.Context, root common.Hash) (*FileReader, error) {
+	downloader, err := NewDownloader(uploader.clients)
+	if err != nil {
+		return nil, err
+	}
+	downloader.logger = uploader.logger
+
+	// segments are read sequentially by the segment uploader
+	return downloader.OpenReader(ctx, root.Hex(), ReaderOption{})
+}
+
+// prepareAppend validates the merkle tree of existing file, and returns the data to upload along with the
+// merkle tree of new file.
+func prepareAppend(baseRoot common.Hash, base *AppendTree, tail core.IterableData) (*appendedData, *AppendTree, error) {
+	if tail == nil || tail.Size() == 0 {
+		return nil, nil, errors.New("nothing to append")
+	}
+
+	if base.Root != baseRoot {
+		return nil, nil, errors.Errorf("merkle root mismatch, expected = %v, persisted = %v", baseRoot, base.Root)
+	}
+
+	// recompute the root in case of corrupted merkle tree file
+	computed, err := core.MerkleRootData(newAppendedData(base, nil))
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to calculate merkle root of existing file")
+	}
+
+	if computed != baseRoot {
+		return nil, nil, errors.Errorf("merkle root mismatch, expected = %v, computed = %v", baseRoot, computed)
+	}
+
+	data := newAppendedData(base, tail)
+
+	tree, err := newAppendTree(data, base.SegmentRoots)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to create merkle tree of new file")
+	}
+
+	return data, tree, nil
+}
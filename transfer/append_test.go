@@ -0,0 +1,173 @@
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRandomData(t *testing.T, size int) []byte {
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestAppend(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		baseSize int
+		tailSize int
+		reused   uint64
+		pushed   uint64
+	}{
+		{"aligned", 2 * core.DefaultSegmentSize, core.DefaultSegmentSize + 1000, 2, 2},
+		{"unaligned", 2*core.DefaultSegmentSize + 3000, 5000, 2, 1},
+		{"unaligned cross segment", 3*core.DefaultSegmentSize + 3000, core.DefaultSegmentSize * 2, 3, 3},
+		{"small base", 100, 300, 0, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			content := newRandomData(t, tc.baseSize+tc.tailSize)
+
+			base, err := core.NewDataInMemory(content[:tc.baseSize])
+			assert.NoError(t, err)
+			baseTree, err := core.MerkleTree(base)
+			assert.NoError(t, err)
+
+			// persist merkle tree of existing file
+			persisted, err := NewAppendTree(base)
+			assert.NoError(t, err)
+			assert.Equal(t, baseTree.Root(), persisted.Root)
+
+			path := filepath.Join(t.TempDir(), "base.tree")
+			assert.NoError(t, persisted.Save(path))
+			loaded, err := LoadAppendTree(path)
+			assert.NoError(t, err)
+			assert.Equal(t, persisted, loaded)
+
+			tail, err := core.NewDataInMemory(content[tc.baseSize:])
+			assert.NoError(t, err)
+			data, tree, err := prepareAppend(baseTree.Root(), loaded, tail)
+			assert.NoError(t, err)
+
+			// same as uploading the whole file
+			full, err := core.NewDataInMemory(content)
+			assert.NoError(t, err)
+			fullTree, err := core.MerkleTree(full)
+			assert.NoError(t, err)
+
+			mtree, err := core.MerkleTree(data)
+			assert.NoError(t, err)
+			assert.Equal(t, fullTree.Root(), mtree.Root())
+			assert.Equal(t, fullTree.Root(), tree.Root)
+
+			submission, err := core.NewFlow(data, nil).CreateSubmission()
+			assert.NoError(t, err)
+			fullSubmission, err := core.NewFlow(full, nil).CreateSubmission()
+			assert.NoError(t, err)
+			assert.Equal(t, fullSubmission, submission)
+
+			// only segments that differ are hashed and read locally
+			assert.Equal(t, tc.reused, core.NumKnownSegments(data))
+			assert.Equal(t, tc.pushed, data.NumSegments()-core.NumKnownSegments(data))
+			for i := core.NumKnownSegments(data); i < data.NumSegments(); i++ {
+				segment, err := core.ReadAt(data, core.DefaultSegmentSize, int64(i*core.DefaultSegmentSize), data.PaddedSize())
+				assert.NoError(t, err)
+				expected, err := core.ReadAt(full, core.DefaultSegmentSize, int64(i*core.DefaultSegmentSize), full.PaddedSize())
+				assert.NoError(t, err)
+				assert.Equal(t, expected, segment)
+			}
+
+			// existing data never read
+			_, err = data.Read(make([]byte, 1), 0)
+			if tc.reused > 0 {
+				assert.Error(t, err)
+			}
+
+			// append again with the merkle tree of new file
+			tail2, err := core.NewDataInMemory([]byte("hello"))
+			assert.NoError(t, err)
+			data2, _, err := prepareAppend(tree.Root, tree, tail2)
+			assert.NoError(t, err)
+
+			full2, err := core.NewDataInMemory(append(content, "hello"...))
+			assert.NoError(t, err)
+			fullTree2, err := core.MerkleTree(full2)
+			assert.NoError(t, err)
+			mtree2, err := core.MerkleTree(data2)
+			assert.NoError(t, err)
+			assert.Equal(t, fullTree2.Root(), mtree2.Root())
+		})
+	}
+}
+
+func TestAppendRootMismatch(t *testing.T) {
+	base, err := core.NewDataInMemory(newRandomData(t, core.DefaultSegmentSize+100))
+	assert.NoError(t, err)
+	tree, err := NewAppendTree(base)
+	assert.NoError(t, err)
+
+	tail, err := core.NewDataInMemory([]byte("tail"))
+	assert.NoError(t, err)
+
+	_, _, err = prepareAppend(common.HexToHash("0x1234"), tree, tail)
+	assert.ErrorContains(t, err, "mismatch")
+
+	// corrupted merkle tree
+	tree.Tail[0]++
+	_, _, err = prepareAppend(tree.Root, tree, tail)
+	assert.ErrorContains(t, err, "mismatch")
+
+	_, _, err = prepareAppend(tree.Root, tree, nil)
+	assert.ErrorContains(t, err, "nothing to append")
+}
+
+func TestUploaderAppend(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{NumShard: 2}, shard.ShardConfig{ShardId: 1, NumShard: 2})
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	content := newRandomData(t, 3*core.DefaultSegmentSize+3000)
+	baseSize := 2*core.DefaultSegmentSize + 1000
+
+	base, err := core.NewDataInMemory(content[:baseSize])
+	assert.NoError(t, err)
+	_, baseRoot, err := uploader.Upload(context.Background(), base)
+	assert.NoError(t, err)
+
+	tree, err := NewAppendTree(base)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "base.tree")
+	assert.NoError(t, tree.Save(path))
+
+	// segments of existing file fetched from storage nodes, and the new file finalized
+	tail, err := core.NewDataInMemory(content[baseSize:])
+	assert.NoError(t, err)
+	result, err := uploader.Append(context.Background(), baseRoot, path, tail, UploadOption{FinalityRequired: FileFinalized})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), result.ReusedSegments)
+	assert.Equal(t, uint64(4), result.PushedSegments)
+
+	for _, node := range network.Nodes {
+		info, err := node.GetFileInfo(result.Root)
+		assert.NoError(t, err)
+		assert.True(t, info.Finalized)
+	}
+
+	downloader, err := NewDownloader(network.ZgsClients())
+	assert.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, downloader.Download(context.Background(), result.Root.Hex(), filename, true))
+	downloaded, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+}
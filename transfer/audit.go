@@ -0,0 +1,558 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/util"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/exp/rand"
+)
+
+// AuditTarget is a storage node to audit, which is implemented by node.ZgsClient.
+type AuditTarget interface {
+	URL() string
+	GetFileInfo(ctx context.Context, root common.Hash) (*node.FileInfo, error)
+	GetShardConfig(ctx context.Context) (shard.ShardConfig, error)
+	DownloadSegmentWithProof(ctx context.Context, root common.Hash, index uint64) (*node.SegmentWithProof, error)
+}
+
+var _ AuditTarget = (*node.ZgsClient)(nil)
+
+// AuditOption is the option to audit storage nodes by sampling segments.
+type AuditOption struct {
+	Samples   uint          // number of segments sampled per file on each node in a round, default 1
+	Interval  time.Duration // interval between rounds, 0 to audit only once
+	StateFile string        // file to persist audit state so that sampling coverage accumulates across runs, empty to disable
+}
+
+// Validate checks the option, and returns an error that names the invalid field if any.
+func (opt *AuditOption) Validate() error {
+	return zg_common.RequireNonNegative("Interval", opt.Interval)
+}
+
+// AuditOutcome is the outcome of a sampled segment.
+type AuditOutcome string
+
+const (
+	AuditPassed       AuditOutcome = "passed"
+	AuditProofInvalid AuditOutcome = "proof invalid"       // segment responded but failed to validate
+	AuditUnavailable  AuditOutcome = "segment unavailable" // segment not responded, e.g. file or node unavailable
+)
+
+// AuditFailure is a failed sample of audit.
+type AuditFailure struct {
+	Node    string       `json:"node"`
+	Root    common.Hash  `json:"root"`
+	Segment int64        `json:"segment"` // segment index relative to file, or -1 if node unavailable
+	Outcome AuditOutcome `json:"outcome"`
+	Err     string       `json:"error"`
+}
+
+// NodeAuditReport is the audit statistics of a storage node.
+type NodeAuditReport struct {
+	Node    string `json:"node"`
+	Checked uint64 `json:"checked"`
+	Passed  uint64 `json:"passed"`
+	Failed  uint64 `json:"failed"`  // proof invalid
+	Missing uint64 `json:"missing"` // segment unavailable
+}
+
+func (report *NodeAuditReport) add(outcome AuditOutcome) {
+	report.Checked++
+
+	switch outcome {
+	case AuditPassed:
+		report.Passed++
+	case AuditProofInvalid:
+		report.Failed++
+	default:
+		report.Missing++
+	}
+}
+
+// AuditReport is the report of an audit round.
+type AuditReport struct {
+	Round    uint64            `json:"round"`  // accumulated across runs
+	Nodes    []NodeAuditReport `json:"nodes"`  // statistics of this round
+	Totals   []NodeAuditReport `json:"totals"` // statistics accumulated across runs
+	Failures []AuditFailure    `json:"failures"`
+	Skipped  []common.Hash     `json:"skipped"` // files not found on any storage node
+
+	Agent zg_common.ClientInfo `json:"agent"` // client library that audited
+}
+
+// auditState is persisted between runs, so that sampling coverage accumulates.
+type auditState struct {
+	Rounds uint64                     `json:"rounds"`
+	Nodes  map[string]*nodeAuditState `json:"nodes"` // by node URL
+}
+
+type nodeAuditState struct {
+	Totals NodeAuditReport                 `json:"totals"`
+	Files  map[common.Hash]*fileAuditState `json:"files"`
+}
+
+type fileAuditState struct {
+	Epoch   uint64   `json:"epoch"`   // number of times that all stored segments sampled
+	Sampled []uint64 `json:"sampled"` // segments sampled in the current epoch
+}
+
+func loadAuditState(path string) (*auditState, error) {
+	state := auditState{Nodes: make(map[string]*nodeAuditState)}
+	if path == "" {
+		return &state, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &state, nil
+	}
+
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read audit state")
+	}
+
+	if err = json.Unmarshal(content, &state); err != nil {
+		return nil, errors.WithMessage(err, "failed to unmarshal audit state")
+	}
+
+	if state.Nodes == nil {
+		state.Nodes = make(map[string]*nodeAuditState)
+	}
+
+	return &state, nil
+}
+
+func (state *auditState) save(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	// write to temporary file and rename, so that state will not be corrupted upon crash
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (state *auditState) node(url string) *nodeAuditState {
+	nodeState, ok := state.Nodes[url]
+	if !ok {
+		nodeState = &nodeAuditState{
+			Totals: NodeAuditReport{Node: url},
+			Files:  make(map[common.Hash]*fileAuditState),
+		}
+		state.Nodes[url] = nodeState
+	}
+
+	return nodeState
+}
+
+func (state *nodeAuditState) file(root common.Hash) *fileAuditState {
+	fileState, ok := state.Files[root]
+	if !ok {
+		fileState = &fileAuditState{}
+		state.Files[root] = fileState
+	}
+
+	return fileState
+}
+
+// sample randomly selects segments that not sampled in the current epoch. If fewer segments remain than the
+// number of samples, it is topped up with the other stored segments, which are sampled for the next epoch.
+func (state *fileAuditState) sample(rng *rand.Rand, stored []uint64, samples uint) []uint64 {
+	selected := selectSegments(rng, excludeSegments(stored, state.Sampled), samples)
+
+	if uint(len(selected)) < samples {
+		selected = append(selected, selectSegments(rng, excludeSegments(stored, selected), samples-uint(len(selected)))...)
+	}
+
+	return selected
+}
+
+// pass marks the segment sampled in the current epoch once it passed audit, so that segments failed to audit,
+// e.g. node temporarily unavailable, are sampled again. Once all the stored segments sampled, a new epoch starts.
+func (state *fileAuditState) pass(stored []uint64, segment uint64) {
+	if slices.Contains(state.Sampled, segment) {
+		return
+	}
+
+	state.Sampled = append(state.Sampled, segment)
+
+	if len(excludeSegments(stored, state.Sampled)) == 0 {
+		state.Epoch++
+		state.Sampled = nil
+	}
+}
+
+func excludeSegments(segments []uint64, excluded []uint64) []uint64 {
+	excludedSet := make(map[uint64]bool, len(excluded))
+	for _, segment := range excluded {
+		excludedSet[segment] = true
+	}
+
+	var result []uint64
+	for _, segment := range segments {
+		if !excludedSet[segment] {
+			result = append(result, segment)
+		}
+	}
+
+	return result
+}
+
+// selectSegments randomly selects at most n segments from candidates, which are reordered in place.
+func selectSegments(rng *rand.Rand, candidates []uint64, n uint) []uint64 {
+	n = min(n, uint(len(candidates)))
+
+	// partial shuffle to select the first n candidates
+	for i := 0; i < int(n); i++ {
+		j := i + rng.Intn(len(candidates)-i)
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	}
+
+	return candidates[:n:n]
+}
+
+// Auditor periodically samples random segments of files from storage nodes and validates them with merkle
+// proof, so as to ensure that storage nodes still hold the files without downloading everything.
+type Auditor struct {
+	targets []AuditTarget
+	roots   []common.Hash
+	option  AuditOption
+	state   *auditState
+
+	logger   *logrus.Logger
+	reporter func(*AuditReport)
+}
+
+// NewAuditor creates an auditor to audit the specified files on storage nodes. If state file specified, audit
+// state persisted by previous runs is loaded.
+func NewAuditor(targets []AuditTarget, roots []common.Hash, option AuditOption, opts ...zg_common.LogOption) (*Auditor, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("storage node not specified")
+	}
+
+	if err := option.Validate(); err != nil {
+		return nil, err
+	}
+
+	if option.Samples == 0 {
+		option.Samples = 1
+	}
+
+	state, err := loadAuditState(option.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Auditor{
+		targets: targets,
+		roots:   roots,
+		option:  option,
+		state:   state,
+		logger:  zg_common.NewLogger(opts...),
+	}, nil
+}
+
+// WithReporter sets the function to receive report of each round, which is logged by default.
+func (auditor *Auditor) WithReporter(reporter func(*AuditReport)) *Auditor {
+	auditor.reporter = reporter
+	return auditor
+}
+
+// Run audits storage nodes round by round until context cancelled, or only once if interval not specified.
+func (auditor *Auditor) Run(ctx context.Context) error {
+	for {
+		report, err := auditor.Audit(ctx)
+		if err != nil {
+			return err
+		}
+
+		if auditor.reporter != nil {
+			auditor.reporter(report)
+		} else {
+			auditor.log(report)
+		}
+
+		if auditor.option.Interval == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(auditor.option.Interval):
+		}
+	}
+}
+
+// Audit runs a single round to sample segments of all files on all storage nodes, and persists the audit
+// state if state file specified.
+func (auditor *Auditor) Audit(ctx context.Context) (*AuditReport, error) {
+	auditor.state.Rounds++
+	report := AuditReport{Round: auditor.state.Rounds, Agent: zg_common.CurrentClient()}
+
+	rng := rand.New(rand.NewSource(util.Seed("audit")))
+
+	shardConfigs := make([]*shard.ShardConfig, len(auditor.targets))
+	for i, target := range auditor.targets {
+		config, err := target.GetShardConfig(ctx)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if err != nil {
+			auditor.logger.WithError(err).WithField("node", target.URL()).Warn("Failed to get shard config")
+			continue
+		}
+
+		if !config.IsValid() {
+			err = errors.Errorf("Invalid shard config, NumShard = %v, ShardId = %v", config.NumShard, config.ShardId)
+			auditor.logger.WithError(err).WithField("node", target.URL()).Warn("Failed to get shard config")
+			continue
+		}
+
+		shardConfigs[i] = &config
+	}
+
+	nodeReports := make([]NodeAuditReport, len(auditor.targets))
+	for i, target := range auditor.targets {
+		nodeReports[i].Node = target.URL()
+	}
+
+	for _, root := range auditor.roots {
+		infos, info, err := auditor.queryFileInfo(ctx, root)
+		if err != nil {
+			return nil, err
+		}
+
+		if info == nil {
+			auditor.logger.WithField("root", root).Warn("File not found on any storage node, skip to audit")
+			report.Skipped = append(report.Skipped, root)
+			continue
+		}
+
+		for i, target := range auditor.targets {
+			nodeState := auditor.state.node(target.URL())
+
+			failures := auditor.auditFile(ctx, rng, target, shardConfigs[i], root, info, infos[i], nodeState, &nodeReports[i])
+			report.Failures = append(report.Failures, failures...)
+
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	for _, nodeReport := range nodeReports {
+		nodeState := auditor.state.node(nodeReport.Node)
+		nodeState.Totals.Checked += nodeReport.Checked
+		nodeState.Totals.Passed += nodeReport.Passed
+		nodeState.Totals.Failed += nodeReport.Failed
+		nodeState.Totals.Missing += nodeReport.Missing
+
+		report.Totals = append(report.Totals, nodeState.Totals)
+	}
+	report.Nodes = nodeReports
+
+	if err := auditor.state.save(auditor.option.StateFile); err != nil {
+		return nil, errors.WithMessage(err, "Failed to save audit state")
+	}
+
+	return &report, nil
+}
+
+// queryFileInfo returns the file info of each storage node, along with any one to locate segments of file.
+func (auditor *Auditor) queryFileInfo(ctx context.Context, root common.Hash) ([]*node.FileInfo, *node.FileInfo, error) {
+	var located *node.FileInfo
+
+	infos := make([]*node.FileInfo, len(auditor.targets))
+	for i, target := range auditor.targets {
+		info, err := target.GetFileInfo(ctx, root)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		if err != nil {
+			auditor.logger.WithError(err).WithFields(logrus.Fields{
+				"node": target.URL(),
+				"root": root,
+			}).Debug("Failed to get file info")
+			continue
+		}
+
+		if info != nil && info.Tx.DataMerkleRoot == root {
+			infos[i] = info
+			if located == nil {
+				located = info
+			}
+		}
+	}
+
+	return infos, located, nil
+}
+
+// auditFile samples segments of file stored on the specified storage node, and returns the failed samples.
+func (auditor *Auditor) auditFile(ctx context.Context, rng *rand.Rand, target AuditTarget, shardConfig *shard.ShardConfig,
+	root common.Hash, info, nodeInfo *node.FileInfo, nodeState *nodeAuditState, nodeReport *NodeAuditReport) []AuditFailure {
+	if shardConfig == nil {
+		for i := uint(0); i < auditor.option.Samples; i++ {
+			nodeReport.add(AuditUnavailable)
+		}
+
+		return []AuditFailure{{
+			Node:    target.URL(),
+			Root:    root,
+			Segment: -1,
+			Outcome: AuditUnavailable,
+			Err:     "Failed to get shard config",
+		}}
+	}
+
+	stored := storedSegments(info, shardConfig)
+	if len(stored) == 0 {
+		return nil
+	}
+
+	fileState := nodeState.file(root)
+
+	var failures []AuditFailure
+	for _, segment := range fileState.sample(rng, stored, auditor.option.Samples) {
+		outcome, err := auditor.auditSegment(ctx, target, root, int64(info.Tx.Size), segment, nodeInfo)
+		if ctx.Err() != nil {
+			return failures
+		}
+
+		nodeReport.add(outcome)
+
+		if outcome == AuditPassed {
+			fileState.pass(stored, segment)
+		} else {
+			failures = append(failures, AuditFailure{
+				Node:    target.URL(),
+				Root:    root,
+				Segment: int64(segment),
+				Outcome: outcome,
+				Err:     err.Error(),
+			})
+		}
+	}
+
+	return failures
+}
+
+func (auditor *Auditor) auditSegment(ctx context.Context, target AuditTarget, root common.Hash, fileSize int64, segmentIndex uint64, nodeInfo *node.FileInfo) (AuditOutcome, error) {
+	if nodeInfo == nil {
+		return AuditUnavailable, errors.New("File not found on storage node")
+	}
+
+	segment, err := target.DownloadSegmentWithProof(ctx, root, segmentIndex)
+	if err != nil {
+		return AuditUnavailable, errors.WithMessage(err, "Failed to download segment with proof")
+	}
+
+	if segment == nil {
+		return AuditUnavailable, errors.New("Segment not found on storage node")
+	}
+
+	if err = validateSegment(root, fileSize, segmentIndex, segment); err != nil {
+		return AuditProofInvalid, err
+	}
+
+	return AuditPassed, nil
+}
+
+// storedSegments returns the segments of file, which are stored on storage node of the specified shard config.
+func storedSegments(info *node.FileInfo, shardConfig *shard.ShardConfig) []uint64 {
+	startSegmentIndex := info.Tx.StartEntryIndex / core.DefaultSegmentMaxChunks
+	numSegments := core.NumSplits(int64(info.Tx.Size), core.DefaultSegmentSize)
+
+	var segments []uint64
+	for i := uint64(0); i < numSegments; i++ {
+		if (startSegmentIndex+i)%shardConfig.NumShard == shardConfig.ShardId {
+			segments = append(segments, i)
+		}
+	}
+
+	return segments
+}
+
+func (auditor *Auditor) log(report *AuditReport) {
+	for i, nodeReport := range report.Nodes {
+		auditor.logger.WithFields(logrus.Fields{
+			"round":  report.Round,
+			"node":   nodeReport.Node,
+			"report": nodeReport,
+			"totals": report.Totals[i],
+		}).Info("Audit round completed")
+	}
+
+	for _, failure := range report.Failures {
+		auditor.logger.WithFields(logrus.Fields{
+			"node":    failure.Node,
+			"root":    failure.Root,
+			"segment": failure.Segment,
+			"error":   failure.Err,
+		}).Warnf("Audit failed: %v", failure.Outcome)
+	}
+}
+
+// verifyUpload samples segments of the uploaded file on the specified storage nodes, and verifies them with merkle
+// proof as the VerifySamples of option requires, so that data is known to be retrievable rather than only reported
+// as uploaded.
+func (uploader *Uploader) verifyUpload(ctx context.Context, clients []*node.ZgsClient, root common.Hash, opt UploadOption) error {
+	if opt.VerifySamples == 0 {
+		return nil
+	}
+
+	targets := make([]AuditTarget, 0, len(clients))
+	for _, client := range clients {
+		targets = append(targets, client)
+	}
+
+	auditor, err := NewAuditor(targets, []common.Hash{root}, AuditOption{Samples: opt.VerifySamples}, zg_common.LogOption{Logger: uploader.logger})
+	if err != nil {
+		return err
+	}
+
+	report, err := auditor.Audit(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to verify uploaded segments")
+	}
+
+	if len(report.Skipped) > 0 {
+		return zg_common.ClassifyError(errors.Errorf("Uploaded file %v not found on any storage node", root), zg_common.ErrorClassVerification)
+	}
+
+	if len(report.Failures) > 0 {
+		failure := report.Failures[0]
+		return zg_common.ClassifyError(errors.Errorf("Failed to verify %v sampled segments of uploaded file, e.g. segment %v on node %v: %v, %v",
+			len(report.Failures), failure.Segment, failure.Node, failure.Outcome, failure.Err), zg_common.ErrorClassVerification)
+	}
+
+	uploader.logger.WithFields(logrus.Fields{
+		"root":    root,
+		"samples": opt.VerifySamples,
+		"nodes":   len(targets),
+	}).Debug("Uploaded segments verified")
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAuditTarget serves segments of file with behavior: "ok" responds valid segments, "corrupt" responds
+// tampered segments, "missing" responds nothing, and "down" fails to respond any RPC.
+type fakeAuditTarget struct {
+	url       string
+	behavior  string
+	shard     shard.ShardConfig
+	uploader  *segmentUploader
+	info      *node.FileInfo
+	downloads []uint64
+}
+
+func newFakeAuditTargets(t *testing.T, size int, behaviors ...string) ([]AuditTarget, common.Hash) {
+	content := make([]byte, size)
+	rand.Read(content)
+
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(t, err)
+
+	info := node.FileInfo{Tx: node.Transaction{DataMerkleRoot: tree.Root(), Size: uint64(size)}, Finalized: true}
+
+	var targets []AuditTarget
+	for i, behavior := range behaviors {
+		targets = append(targets, &fakeAuditTarget{
+			url:      behavior + "-" + string(rune('0'+i)),
+			behavior: behavior,
+			shard:    shard.ShardConfig{NumShard: 1},
+			uploader: &segmentUploader{data: data, tree: tree},
+			info:     &info,
+		})
+	}
+
+	return targets, tree.Root()
+}
+
+func (target *fakeAuditTarget) URL() string { return target.url }
+
+func (target *fakeAuditTarget) GetFileInfo(ctx context.Context, root common.Hash) (*node.FileInfo, error) {
+	if target.behavior == "down" {
+		return nil, errors.New("connection refused")
+	}
+
+	if target.behavior == "missing" || root != target.info.Tx.DataMerkleRoot {
+		return nil, nil
+	}
+
+	return target.info, nil
+}
+
+func (target *fakeAuditTarget) GetShardConfig(ctx context.Context) (shard.ShardConfig, error) {
+	if target.behavior == "down" {
+		return shard.ShardConfig{}, errors.New("connection refused")
+	}
+
+	return target.shard, nil
+}
+
+func (target *fakeAuditTarget) DownloadSegmentWithProof(ctx context.Context, root common.Hash, index uint64) (*node.SegmentWithProof, error) {
+	target.downloads = append(target.downloads, index)
+
+	_, segment, err := target.uploader.getSegment(index)
+	if err != nil || segment == nil {
+		return nil, err
+	}
+
+	if target.behavior == "corrupt" {
+		segment.Data = append([]byte(nil), segment.Data...)
+		segment.Data[0] ^= 0xff
+	}
+
+	return segment, nil
+}
+
+func TestAuditorOutcomes(t *testing.T) {
+	targets, root := newFakeAuditTargets(t, core.DefaultSegmentSize*5+1000, "ok", "corrupt", "missing", "down")
+
+	auditor, err := NewAuditor(targets, []common.Hash{root, common.HexToHash("0x1234")}, AuditOption{Samples: 2})
+	assert.NoError(t, err)
+
+	var reports []*AuditReport
+	auditor.WithReporter(func(report *AuditReport) { reports = append(reports, report) })
+	assert.NoError(t, auditor.Run(context.Background()))
+	assert.Equal(t, 1, len(reports))
+
+	report := reports[0]
+	assert.Equal(t, []NodeAuditReport{
+		{Node: "ok-0", Checked: 2, Passed: 2},
+		{Node: "corrupt-1", Checked: 2, Failed: 2},
+		{Node: "missing-2", Checked: 2, Missing: 2},
+		{Node: "down-3", Checked: 2, Missing: 2},
+	}, report.Nodes)
+	assert.Equal(t, report.Nodes, report.Totals)
+	assert.Equal(t, []common.Hash{common.HexToHash("0x1234")}, report.Skipped)
+
+	outcomes := make(map[string][]AuditOutcome)
+	for _, failure := range report.Failures {
+		outcomes[failure.Node] = append(outcomes[failure.Node], failure.Outcome)
+	}
+	assert.Equal(t, map[string][]AuditOutcome{
+		"corrupt-1": {AuditProofInvalid, AuditProofInvalid},
+		"missing-2": {AuditUnavailable, AuditUnavailable},
+		"down-3":    {AuditUnavailable},
+	}, outcomes)
+
+	// file not found on node, so no segment downloaded
+	assert.Empty(t, targets[2].(*fakeAuditTarget).downloads)
+}
+
+func TestAuditorCoverage(t *testing.T) {
+	targets, root := newFakeAuditTargets(t, core.DefaultSegmentSize*5+1000, "ok", "ok")
+	targets[1].(*fakeAuditTarget).shard = shard.ShardConfig{NumShard: 2, ShardId: 1}
+	stateFile := filepath.Join(t.TempDir(), "audit.json")
+
+	// coverage accumulates across runs
+	for i := 0; i < 3; i++ {
+		auditor, err := NewAuditor(targets, []common.Hash{root}, AuditOption{Samples: 2, StateFile: stateFile})
+		assert.NoError(t, err)
+
+		report, err := auditor.Audit(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(i+1), report.Round)
+		assert.Equal(t, uint64(2*(i+1)), report.Totals[0].Passed)
+	}
+
+	full := targets[0].(*fakeAuditTarget).downloads
+	sort.Slice(full, func(i, j int) bool { return full[i] < full[j] })
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5}, full)
+
+	// only segments on shard sampled, and new epoch started once all sampled
+	sharded := targets[1].(*fakeAuditTarget).downloads
+	sort.Slice(sharded, func(i, j int) bool { return sharded[i] < sharded[j] })
+	assert.Equal(t, []uint64{1, 1, 3, 3, 5, 5}, sharded)
+
+	state, err := loadAuditState(stateFile)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), state.Rounds)
+	assert.Equal(t, uint64(1), state.Nodes["ok-0"].Files[root].Epoch)
+	assert.Equal(t, uint64(2), state.Nodes["ok-1"].Files[root].Epoch)
+}
+
+func TestAuditorSampledOnSuccess(t *testing.T) {
+	targets, root := newFakeAuditTargets(t, core.DefaultSegmentSize*5+1000, "corrupt")
+	target := targets[0].(*fakeAuditTarget)
+	stateFile := filepath.Join(t.TempDir(), "audit.json")
+
+	audit := func() {
+		auditor, err := NewAuditor(targets, []common.Hash{root}, AuditOption{Samples: 2, StateFile: stateFile})
+		assert.NoError(t, err)
+		_, err = auditor.Audit(context.Background())
+		assert.NoError(t, err)
+	}
+
+	// failed samples not marked sampled
+	audit()
+	state, err := loadAuditState(stateFile)
+	assert.NoError(t, err)
+	assert.Empty(t, state.Nodes["corrupt-0"].Files[root].Sampled)
+
+	target.behavior = "down"
+	audit()
+	state, err = loadAuditState(stateFile)
+	assert.NoError(t, err)
+	assert.Empty(t, state.Nodes["corrupt-0"].Files[root].Sampled)
+
+	// all segments covered once passed
+	target.behavior = "ok"
+	target.downloads = nil
+	for i := 0; i < 3; i++ {
+		audit()
+	}
+	sort.Slice(target.downloads, func(i, j int) bool { return target.downloads[i] < target.downloads[j] })
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5}, target.downloads)
+
+	state, err = loadAuditState(stateFile)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), state.Nodes["corrupt-0"].Files[root].Epoch)
+}
+
+func TestVerifyUpload(t *testing.T) {
+	service := testutil.NewZgsService()
+	client := newMockZgsNode(t, service)
+	uploader := &Uploader{clients: []*node.ZgsClient{client}, routines: 2, logger: zg_common.NewLogger(), warnings: NewWarnings(0)}
+
+	upload := func() error {
+		content, data := newTestData(t, core.DefaultSegmentSize*3+100)
+		_, err := service.Submit(content, nil)
+		assert.NoError(t, err)
+
+		_, _, err = uploader.Upload(context.Background(), data, UploadOption{SkipTx: true, VerifySamples: 2})
+		return err
+	}
+
+	// sampled segments verified
+	assert.NoError(t, upload())
+
+	// segments not retrievable with proof
+	service.SetHooks(testutil.ZgsHooks{NoProofs: true})
+	err := upload()
+	assert.ErrorContains(t, err, "Failed to verify 2 sampled segments")
+	assert.Equal(t, zg_common.ErrorClassVerification, zg_common.ClassOf(err))
+}
@@ -0,0 +1,99 @@
+package transfer
+
+import (
+	"context"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/transfer/bundle"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// CommitBundleOption is the option to commit a bundle.
+type CommitBundleOption struct {
+	Upload UploadOption // option to upload each file, of which tags are overridden by the prepared ones
+	Force  bool         // commit files again even if committed successfully before
+}
+
+// CommitBundle submits the files prepared in bundle to the flow contract and uploads segments to storage nodes in
+// order. The data of each file is re-read from source file if present, otherwise from the data extracted in bundle,
+// and verified against the prepared segment index and submission before submitted.
+//
+// The outcome of each file is recorded into bundle for audit. Files committed successfully before are skipped unless
+// forced, so it is safe to commit again after failure. Returns the outcomes of this commit, and stops at the first
+// failure.
+func (uploader *Uploader) CommitBundle(ctx context.Context, b *bundle.Bundle, option ...CommitBundleOption) ([]bundle.Outcome, error) {
+	var opt CommitBundleOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	ctx = zg_common.WithFeatureTracking(ctx)
+	zg_common.UseFeature(ctx, zg_common.FeatureBundle)
+
+	committed, err := b.Committed()
+	if err != nil {
+		return nil, err
+	}
+
+	var outcomes []bundle.Outcome
+
+	files := b.Manifest().Files
+	for i := range files {
+		file := &files[i]
+		if committed[file.Root] && !opt.Force {
+			uploader.logger.WithField("name", file.Name).WithField("root", file.Root).Info("File already committed, skipped")
+			continue
+		}
+
+		outcome := uploader.commitBundleFile(ctx, b, file, opt.Upload)
+		if err := b.RecordOutcome(outcome); err != nil {
+			return outcomes, errors.WithMessagef(err, "failed to record outcome of %v", file.Name)
+		}
+		outcomes = append(outcomes, outcome)
+
+		if !outcome.Committed {
+			return outcomes, errors.Errorf("failed to commit %v: %v", file.Name, outcome.Error)
+		}
+
+		uploader.logger.WithFields(logrus.Fields{
+			"name":   file.Name,
+			"root":   file.Root,
+			"source": outcome.Source,
+			"txHash": outcome.TxHash,
+		}).Info("File committed")
+	}
+
+	return outcomes, nil
+}
+
+// commitBundleFile verifies and uploads the file in bundle, and returns the outcome.
+func (uploader *Uploader) commitBundleFile(ctx context.Context, b *bundle.Bundle, file *bundle.File, opt UploadOption) bundle.Outcome {
+	outcome := bundle.Outcome{Name: file.Name, Root: file.Root}
+
+	data, source, err := b.OpenData(file)
+	if err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+	defer data.Close()
+
+	outcome.Source = source
+
+	opt.Tags = file.Tags
+	txHash, root, err := uploader.Upload(ctx, data, opt)
+	outcome.TxHash = txHash
+	if err != nil {
+		outcome.Error = err.Error()
+		return outcome
+	}
+
+	if root != file.Root {
+		outcome.Error = errors.Errorf("root mismatch, expected %v, actual %v", file.Root, root).Error()
+		return outcome
+	}
+
+	outcome.Committed = true
+
+	return outcome
+}
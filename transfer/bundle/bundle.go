@@ -0,0 +1,432 @@
+// Package bundle implements a portable directory format to prepare uploads offline, e.g. on an air-gapped
+// machine, and commit them later on a machine with access to blockchain and storage nodes.
+//
+// A bundle directory is laid out as below:
+//
+//	bundle.json          manifest of files, including roots, submissions and checksums of artifacts
+//	outcomes.json        outcomes of commits for audit, which is appended upon each commit
+//	segments/<root>.idx  segment index, i.e. roots of all segments in order
+//	segments/<root>.dat  file data, which is extracted only if required
+//
+// The manifest is protected by its own checksum, and refers to the segment index by checksum. The file data,
+// either extracted into bundle or re-read from source, is verified against the segment index before commit.
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/contract"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/core/merkle"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// Version is the version of bundle format, and bundles of newer versions could not be opened.
+const Version = 1
+
+const (
+	ManifestFile = "bundle.json"
+	OutcomesFile = "outcomes.json"
+	SegmentsDir  = "segments"
+)
+
+var (
+	// ErrChecksumMismatch is returned when the bundle contents are modified or corrupted.
+	ErrChecksumMismatch = errors.New("bundle checksum mismatch")
+
+	// ErrDataMismatch is returned when the file data differs from the prepared one.
+	ErrDataMismatch = errors.New("file data mismatch")
+)
+
+// SubmissionNode is a node of submission to the flow contract.
+type SubmissionNode struct {
+	Root   common.Hash `json:"root"`
+	Height uint64      `json:"height"`
+}
+
+// Submission is the payload to submit a file to the flow contract.
+type Submission struct {
+	Length uint64           `json:"length"`
+	Tags   hexutil.Bytes    `json:"tags"`
+	Nodes  []SubmissionNode `json:"nodes"`
+}
+
+// NewSubmission converts from the submission of flow contract.
+func NewSubmission(submission *contract.Submission) Submission {
+	result := Submission{
+		Length: submission.Length.Uint64(),
+		Tags:   common.CopyBytes(submission.Tags),
+	}
+
+	for _, v := range submission.Nodes {
+		result.Nodes = append(result.Nodes, SubmissionNode{Root: v.Root, Height: v.Height.Uint64()})
+	}
+
+	return result
+}
+
+// File is a file prepared in bundle.
+type File struct {
+	Name        string        `json:"name"`   // base name of source file
+	Source      string        `json:"source"` // absolute path of source file when prepared
+	Size        int64         `json:"size"`
+	Root        common.Hash   `json:"root"`
+	Tags        hexutil.Bytes `json:"tags"`
+	Submission  Submission    `json:"submission"`
+	NumSegments uint64        `json:"numSegments"`    // number of segments of padded data
+	Index       string        `json:"index"`          // segment index file relative to bundle
+	IndexHash   common.Hash   `json:"indexHash"`      // sha256 of segment index file
+	Data        string        `json:"data,omitempty"` // extracted data file relative to bundle, empty if not extracted
+}
+
+// Manifest is the manifest of bundle.
+type Manifest struct {
+	Version  int         `json:"version"`
+	Created  time.Time   `json:"created"`
+	Files    []File      `json:"files"`
+	Checksum common.Hash `json:"checksum"` // sha256 of manifest with empty checksum
+}
+
+// checksum returns the sha256 of manifest with empty checksum.
+func (manifest Manifest) checksum() (common.Hash, error) {
+	manifest.Checksum = common.Hash{}
+
+	encoded, err := json.Marshal(&manifest)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return sha256.Sum256(encoded), nil
+}
+
+// PrepareOption is the option to prepare files into bundle.
+type PrepareOption struct {
+	Tags            []byte // tags of all files to submit
+	ExtractSegments bool   // copy file data into bundle, so that source files are not required to commit
+}
+
+// Bundle is a bundle directory opened or prepared.
+type Bundle struct {
+	dir      string
+	manifest Manifest
+}
+
+// Prepare prepares the files into a new bundle directory without any network access, which calculates the
+// merkle roots, submissions and segment indices of files. The directory will be created if not exists, and
+// must be empty if exists.
+func Prepare(dir string, files []string, option ...PrepareOption) (*Bundle, error) {
+	var opt PrepareOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no file to prepare")
+	}
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, errors.Errorf("bundle directory %v is not empty", dir)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, SegmentsDir), os.ModePerm); err != nil {
+		return nil, errors.WithMessage(err, "failed to create bundle directory")
+	}
+
+	bundle := Bundle{
+		dir: dir,
+		manifest: Manifest{
+			Version: Version,
+			Created: time.Now().UTC().Truncate(time.Second),
+		},
+	}
+
+	roots := make(map[common.Hash]string)
+	for _, name := range files {
+		file, err := bundle.prepare(name, opt)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to prepare file %v", name)
+		}
+
+		if previous, ok := roots[file.Root]; ok {
+			return nil, errors.Errorf("file %v is duplicated with %v", name, previous)
+		}
+		roots[file.Root] = name
+
+		bundle.manifest.Files = append(bundle.manifest.Files, *file)
+	}
+
+	checksum, err := bundle.manifest.checksum()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to calculate manifest checksum")
+	}
+	bundle.manifest.Checksum = checksum
+
+	if err = writeJSON(filepath.Join(dir, ManifestFile), &bundle.manifest); err != nil {
+		return nil, errors.WithMessage(err, "failed to write manifest")
+	}
+
+	return &bundle, nil
+}
+
+// prepare calculates the segment index and submission of file, and extracts the data into bundle if required.
+func (bundle *Bundle) prepare(name string, opt PrepareOption) (*File, error) {
+	source, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := core.Open(source)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to open file")
+	}
+	defer data.Close()
+
+	segments, err := SegmentRoots(data)
+	if err != nil {
+		return nil, err
+	}
+
+	submission, err := core.NewSubmission(data, opt.Tags)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create submission")
+	}
+
+	file := File{
+		Name:        filepath.Base(source),
+		Source:      source,
+		Size:        data.Size(),
+		Root:        segmentsTree(segments).Root(),
+		Tags:        common.CopyBytes(opt.Tags),
+		Submission:  NewSubmission(submission),
+		NumSegments: uint64(len(segments)),
+	}
+
+	file.Index = filepath.ToSlash(filepath.Join(SegmentsDir, file.Root.Hex()+".idx"))
+	index, err := json.Marshal(segments)
+	if err != nil {
+		return nil, err
+	}
+	file.IndexHash = sha256.Sum256(index)
+	if err = os.WriteFile(filepath.Join(bundle.dir, file.Index), index, 0644); err != nil {
+		return nil, errors.WithMessage(err, "failed to write segment index")
+	}
+
+	if opt.ExtractSegments {
+		file.Data = filepath.ToSlash(filepath.Join(SegmentsDir, file.Root.Hex()+".dat"))
+		if err = extract(data, filepath.Join(bundle.dir, file.Data)); err != nil {
+			return nil, errors.WithMessage(err, "failed to extract data")
+		}
+	}
+
+	return &file, nil
+}
+
+// extract copies the data into the specified file.
+func extract(data core.IterableData, filename string) error {
+	output, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	for offset := int64(0); offset < data.Size(); offset += core.DefaultSegmentSize {
+		size := int(min(core.DefaultSegmentSize, data.Size()-offset))
+		buf, err := core.ReadAt(data, size, offset, data.PaddedSize())
+		if err != nil {
+			return err
+		}
+
+		if _, err = output.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return output.Sync()
+}
+
+// SegmentRoots returns the roots of all segments of padded data in order, which are leaves of the file merkle tree.
+func SegmentRoots(data core.IterableData) ([]common.Hash, error) {
+	var roots []common.Hash
+
+	for offset := uint64(0); offset < data.PaddedSize(); offset += core.DefaultSegmentSize {
+		segment, err := core.ReadAt(data, int(min(core.DefaultSegmentSize, data.PaddedSize()-offset)), int64(offset), data.PaddedSize())
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read segment %v", offset/core.DefaultSegmentSize)
+		}
+
+		roots = append(roots, core.SegmentRoot(segment))
+	}
+
+	return roots, nil
+}
+
+func segmentsTree(roots []common.Hash) *merkle.Tree {
+	var builder merkle.TreeBuilder
+	for _, root := range roots {
+		builder.AppendHash(root)
+	}
+
+	return builder.Build()
+}
+
+// Open opens an existing bundle directory, and verifies the checksums of manifest and segment indices.
+func Open(dir string) (*Bundle, error) {
+	bundle := Bundle{dir: dir}
+
+	content, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read manifest")
+	}
+
+	if err = json.Unmarshal(content, &bundle.manifest); err != nil {
+		return nil, errors.WithMessage(err, "failed to unmarshal manifest")
+	}
+
+	if bundle.manifest.Version > Version {
+		return nil, errors.Errorf("unsupported bundle version %v, max supported version is %v", bundle.manifest.Version, Version)
+	}
+
+	checksum, err := bundle.manifest.checksum()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to calculate manifest checksum")
+	}
+
+	if checksum != bundle.manifest.Checksum {
+		return nil, errors.WithMessagef(ErrChecksumMismatch, "manifest checksum expected %v, actual %v", bundle.manifest.Checksum, checksum)
+	}
+
+	for i := range bundle.manifest.Files {
+		if _, err = bundle.SegmentIndex(&bundle.manifest.Files[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &bundle, nil
+}
+
+// Dir returns the bundle directory.
+func (bundle *Bundle) Dir() string {
+	return bundle.dir
+}
+
+// Manifest returns the manifest of bundle.
+func (bundle *Bundle) Manifest() *Manifest {
+	return &bundle.manifest
+}
+
+// SegmentIndex reads the segment index of file, and verifies it against the manifest.
+func (bundle *Bundle) SegmentIndex(file *File) ([]common.Hash, error) {
+	content, err := os.ReadFile(filepath.Join(bundle.dir, filepath.FromSlash(file.Index)))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to read segment index of %v", file.Name)
+	}
+
+	if checksum := common.Hash(sha256.Sum256(content)); checksum != file.IndexHash {
+		return nil, errors.WithMessagef(ErrChecksumMismatch, "segment index of %v expected %v, actual %v", file.Name, file.IndexHash, checksum)
+	}
+
+	var segments []common.Hash
+	if err = json.Unmarshal(content, &segments); err != nil {
+		return nil, errors.WithMessagef(err, "failed to unmarshal segment index of %v", file.Name)
+	}
+
+	if uint64(len(segments)) != file.NumSegments || segmentsTree(segments).Root() != file.Root {
+		return nil, errors.WithMessagef(ErrChecksumMismatch, "segment index of %v mismatches with root", file.Name)
+	}
+
+	return segments, nil
+}
+
+// DataSource is where the file data is read from upon commit.
+type DataSource string
+
+const (
+	DataSourceFile      DataSource = "source"    // source file is re-read
+	DataSourceExtracted DataSource = "extracted" // data extracted in bundle
+)
+
+// OpenData opens the file data from source file if present, otherwise from data extracted in bundle. The data is
+// verified against segment index and submission, and must be closed after used.
+func (bundle *Bundle) OpenData(file *File) (*core.File, DataSource, error) {
+	filename, source := file.Source, DataSourceFile
+	if exists, err := core.Exists(filename); err != nil || !exists {
+		if len(file.Data) == 0 {
+			return nil, "", errors.Errorf("source file %v not found and data not extracted", file.Source)
+		}
+
+		filename, source = filepath.Join(bundle.dir, filepath.FromSlash(file.Data)), DataSourceExtracted
+	}
+
+	data, err := core.Open(filename)
+	if err != nil {
+		return nil, "", errors.WithMessagef(err, "failed to open %v data", source)
+	}
+
+	if err = bundle.verify(file, data); err != nil {
+		data.Close()
+		return nil, "", errors.WithMessagef(err, "failed to verify %v data", source)
+	}
+
+	return data, source, nil
+}
+
+// verify checks that the data matches with the segment index and submission of file.
+func (bundle *Bundle) verify(file *File, data core.IterableData) error {
+	if data.Size() != file.Size {
+		return errors.WithMessagef(ErrDataMismatch, "size expected %v, actual %v", file.Size, data.Size())
+	}
+
+	expected, err := bundle.SegmentIndex(file)
+	if err != nil {
+		return err
+	}
+
+	segments, err := SegmentRoots(data)
+	if err != nil {
+		return err
+	}
+
+	if len(segments) != len(expected) {
+		return errors.WithMessagef(ErrDataMismatch, "number of segments expected %v, actual %v", len(expected), len(segments))
+	}
+
+	for i := range segments {
+		if segments[i] != expected[i] {
+			return errors.WithMessagef(ErrDataMismatch, "segment %v root expected %v, actual %v", i, expected[i], segments[i])
+		}
+	}
+
+	submission, err := core.NewSubmission(data, file.Tags)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create submission")
+	}
+
+	encoded, _ := json.Marshal(NewSubmission(submission))
+	prepared, _ := json.Marshal(file.Submission)
+	if string(encoded) != string(prepared) {
+		return errors.WithMessage(ErrDataMismatch, "submission mismatch")
+	}
+
+	return nil
+}
+
+// writeJSON writes the value to a temporary file and renames, so that the file will not be corrupted upon crash.
+func writeJSON(filename string, value interface{}) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	if err = os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
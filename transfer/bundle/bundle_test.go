@@ -0,0 +1,172 @@
+package bundle
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFiles(t *testing.T, sizes ...int) []string {
+	folder := t.TempDir()
+
+	var files []string
+	for i, size := range sizes {
+		content := make([]byte, size)
+		_, err := rand.Read(content)
+		assert.NoError(t, err)
+
+		name := filepath.Join(folder, string(rune('a'+i))+".bin")
+		assert.NoError(t, os.WriteFile(name, content, 0644))
+		files = append(files, name)
+	}
+
+	return files
+}
+
+func TestPrepare(t *testing.T) {
+	files := newTestFiles(t, 3*core.DefaultSegmentSize+100, 10)
+	dir := filepath.Join(t.TempDir(), "bundle")
+
+	prepared, err := Prepare(dir, files, PrepareOption{Tags: []byte{1, 2}})
+	assert.NoError(t, err)
+
+	b, err := Open(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, prepared.Manifest(), b.Manifest())
+	assert.Equal(t, Version, b.Manifest().Version)
+	assert.Equal(t, 2, len(b.Manifest().Files))
+
+	for i, file := range b.Manifest().Files {
+		root, err := core.MerkleRoot(files[i])
+		assert.NoError(t, err)
+		assert.Equal(t, root, file.Root)
+		assert.Equal(t, filepath.Base(files[i]), file.Name)
+
+		data, err := core.Open(files[i])
+		assert.NoError(t, err)
+		submission, err := core.NewFlow(data, []byte{1, 2}).CreateSubmission()
+		assert.NoError(t, err)
+		assert.Equal(t, NewSubmission(submission), file.Submission)
+		data.Close()
+
+		// source file is preferred
+		opened, source, err := b.OpenData(&file)
+		assert.NoError(t, err)
+		assert.Equal(t, DataSourceFile, source)
+		opened.Close()
+	}
+
+	// source file required since data not extracted
+	assert.NoError(t, os.Remove(files[0]))
+	_, _, err = b.OpenData(&b.Manifest().Files[0])
+	assert.Error(t, err)
+
+	// directory not empty
+	_, err = Prepare(dir, files[1:])
+	assert.Error(t, err)
+}
+
+func TestOpenDataExtracted(t *testing.T) {
+	files := newTestFiles(t, 2*core.DefaultSegmentSize)
+	dir := t.TempDir()
+
+	b, err := Prepare(dir, files, PrepareOption{ExtractSegments: true})
+	assert.NoError(t, err)
+	file := &b.Manifest().Files[0]
+
+	// source file absent
+	assert.NoError(t, os.Remove(files[0]))
+	data, source, err := b.OpenData(file)
+	assert.NoError(t, err)
+	assert.Equal(t, DataSourceExtracted, source)
+	data.Close()
+
+	// source file present but modified
+	content := make([]byte, 2*core.DefaultSegmentSize)
+	assert.NoError(t, os.WriteFile(files[0], content, 0644))
+	_, _, err = b.OpenData(file)
+	assert.ErrorIs(t, err, ErrDataMismatch)
+	assert.Contains(t, err.Error(), "segment 0")
+
+	// extracted data corrupted
+	assert.NoError(t, os.Remove(files[0]))
+	extracted, err := os.ReadFile(filepath.Join(dir, file.Data))
+	assert.NoError(t, err)
+	extracted[core.DefaultSegmentSize] ^= 1
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, file.Data), extracted, 0644))
+	_, _, err = b.OpenData(file)
+	assert.ErrorIs(t, err, ErrDataMismatch)
+	assert.Contains(t, err.Error(), "segment 1")
+}
+
+func TestOpenIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	b, err := Prepare(dir, newTestFiles(t, 100))
+	assert.NoError(t, err)
+
+	manifestFile := filepath.Join(dir, ManifestFile)
+	original, err := os.ReadFile(manifestFile)
+	assert.NoError(t, err)
+
+	writeManifest := func(update func(manifest *Manifest)) {
+		var manifest Manifest
+		assert.NoError(t, json.Unmarshal(original, &manifest))
+		update(&manifest)
+		assert.NoError(t, writeJSON(manifestFile, &manifest))
+	}
+
+	// manifest modified
+	writeManifest(func(manifest *Manifest) { manifest.Files[0].Size++ })
+	_, err = Open(dir)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	// newer version
+	writeManifest(func(manifest *Manifest) {
+		manifest.Version = Version + 1
+		manifest.Checksum, _ = manifest.checksum()
+	})
+	_, err = Open(dir)
+	assert.ErrorContains(t, err, "unsupported bundle version")
+
+	// segment index modified
+	assert.NoError(t, os.WriteFile(manifestFile, original, 0644))
+	index := filepath.Join(dir, b.Manifest().Files[0].Index)
+	assert.NoError(t, os.WriteFile(index, []byte(`["0x0000000000000000000000000000000000000000000000000000000000000000"]`), 0644))
+	_, err = Open(dir)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestOutcomes(t *testing.T) {
+	b, err := Prepare(t.TempDir(), newTestFiles(t, 100))
+	assert.NoError(t, err)
+
+	outcomes, err := b.Outcomes()
+	assert.NoError(t, err)
+	assert.Empty(t, outcomes)
+
+	root := b.Manifest().Files[0].Root
+	assert.NoError(t, b.RecordOutcome(Outcome{Name: "a.bin", Root: root, Error: "failed"}))
+	committed, err := b.Committed()
+	assert.NoError(t, err)
+	assert.False(t, committed[root])
+
+	assert.NoError(t, b.RecordOutcome(Outcome{Name: "a.bin", Root: root, Source: DataSourceFile, TxHash: common.HexToHash("0x01"), Committed: true}))
+	committed, err = b.Committed()
+	assert.NoError(t, err)
+	assert.True(t, committed[root])
+
+	// recorded in order for audit, and manifest not affected
+	opened, err := Open(b.Dir())
+	assert.NoError(t, err)
+	outcomes, err = opened.Outcomes()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(outcomes))
+	assert.Equal(t, "failed", outcomes[0].Error)
+	assert.False(t, outcomes[1].Time.IsZero())
+}
@@ -0,0 +1,76 @@
+package bundle
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// Outcome is the outcome to commit a file in bundle, which is recorded into bundle for audit.
+type Outcome struct {
+	Name      string      `json:"name"`
+	Root      common.Hash `json:"root"`
+	Source    DataSource  `json:"source,omitempty"` // where the data is read from, empty if failed to open
+	TxHash    common.Hash `json:"txHash"`           // empty if transaction skipped or failed
+	Committed bool        `json:"committed"`
+	Error     string      `json:"error,omitempty"`
+	Time      time.Time   `json:"time"`
+}
+
+// Outcomes returns all recorded outcomes in order, including failed ones.
+func (bundle *Bundle) Outcomes() ([]Outcome, error) {
+	content, err := os.ReadFile(filepath.Join(bundle.dir, OutcomesFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read outcomes")
+	}
+
+	var outcomes []Outcome
+	if err = json.Unmarshal(content, &outcomes); err != nil {
+		return nil, errors.WithMessage(err, "failed to unmarshal outcomes")
+	}
+
+	return outcomes, nil
+}
+
+// RecordOutcome appends the outcome into bundle.
+func (bundle *Bundle) RecordOutcome(outcome Outcome) error {
+	outcomes, err := bundle.Outcomes()
+	if err != nil {
+		return err
+	}
+
+	if outcome.Time.IsZero() {
+		outcome.Time = time.Now().UTC().Truncate(time.Second)
+	}
+
+	if err = writeJSON(filepath.Join(bundle.dir, OutcomesFile), append(outcomes, outcome)); err != nil {
+		return errors.WithMessage(err, "failed to write outcomes")
+	}
+
+	return nil
+}
+
+// Committed returns the roots of files that committed successfully.
+func (bundle *Bundle) Committed() (map[common.Hash]bool, error) {
+	outcomes, err := bundle.Outcomes()
+	if err != nil {
+		return nil, err
+	}
+
+	committed := make(map[common.Hash]bool)
+	for _, v := range outcomes {
+		if v.Committed {
+			committed[v.Root] = true
+		}
+	}
+
+	return committed, nil
+}
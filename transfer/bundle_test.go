@@ -0,0 +1,70 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer/bundle"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitBundle(t *testing.T) {
+	service := testutil.NewZgsService()
+	client := newMockZgsNode(t, service)
+	uploader := &Uploader{clients: []*node.ZgsClient{client}, routines: 4, logger: zg_common.NewLogger(), warnings: NewWarnings(0)}
+
+	// prepare offline
+	folder := t.TempDir()
+	contentA, contentB := make([]byte, 2*core.DefaultSegmentSize+10), []byte("small file")
+	contentA[0] = 1
+	fileA, fileB := filepath.Join(folder, "a.bin"), filepath.Join(folder, "b.txt")
+	assert.NoError(t, os.WriteFile(fileA, contentA, 0644))
+	assert.NoError(t, os.WriteFile(fileB, contentB, 0644))
+
+	dir := t.TempDir()
+	_, err := bundle.Prepare(dir, []string{fileA, fileB}, bundle.PrepareOption{ExtractSegments: true})
+	assert.NoError(t, err)
+
+	// commit on another machine without source files
+	assert.NoError(t, os.RemoveAll(folder))
+	rootA, rootB := submit(t, service, contentA), submit(t, service, contentB)
+
+	b, err := bundle.Open(dir)
+	assert.NoError(t, err)
+	opt := CommitBundleOption{Upload: UploadOption{SkipTx: true}}
+	outcomes, err := uploader.CommitBundle(context.Background(), b, opt)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(outcomes))
+
+	for i, root := range []common.Hash{rootA, rootB} {
+		assert.Equal(t, root, outcomes[i].Root)
+		assert.True(t, outcomes[i].Committed)
+		assert.Equal(t, bundle.DataSourceExtracted, outcomes[i].Source)
+
+		info, err := service.GetFileInfo(root)
+		assert.NoError(t, err)
+		assert.True(t, info.Finalized)
+	}
+
+	// committed files skipped
+	outcomes, err = uploader.CommitBundle(context.Background(), b, opt)
+	assert.NoError(t, err)
+	assert.Empty(t, outcomes)
+
+	// committed again if forced
+	opt.Force = true
+	outcomes, err = uploader.CommitBundle(context.Background(), b, opt)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(outcomes))
+
+	recorded, err := b.Outcomes()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(recorded))
+}
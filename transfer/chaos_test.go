@@ -0,0 +1,135 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/chaos"
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// newReplicatedNetwork creates a network with 2 storage nodes of all shards, and the file uploaded to both.
+func newReplicatedNetwork(t *testing.T) (*testutil.Network, []byte, string) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{NumShard: 1}, shard.ShardConfig{NumShard: 1})
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	content, data := newTestData(t, 3*core.DefaultSegmentSize)
+	_, root, err := uploader.Upload(context.Background(), data, UploadOption{ExpectedReplica: 2})
+	assert.NoError(t, err)
+
+	return network, content, root.Hex()
+}
+
+func TestChaosResumeUpload(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	clients := network.ZgsClients()
+	injector := chaos.MustNew(chaos.FailNthSegmentUpload(2))
+	injector.Inject(clients...)
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+	uploader.WithRoutines(1)
+
+	// failed to upload the 2nd segment
+	content, data := newTestData(t, 4*core.DefaultSegmentSize)
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{TaskSize: 1})
+	assert.ErrorContains(t, err, chaos.ErrInjected.Error())
+	assert.Equal(t, 1, injector.Injected()[chaos.ActionFail])
+
+	// resumed without submitting again
+	_, root, err := uploader.Upload(context.Background(), data, UploadOption{TaskSize: 1, SkipTx: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(network.Chain.Submissions()))
+	info, err := network.Nodes[0].GetFileInfo(root)
+	assert.NoError(t, err)
+	assert.True(t, info.Finalized)
+
+	downloaded, err := network.Nodes[0].DownloadSegment(root, 0, uint64(len(content)/core.DefaultChunkSize))
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+}
+
+func TestChaosDropDuringFinality(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	clients := network.ZgsClients()
+	chaos.MustNew(chaos.DropDuringFinality(1, 1)).Inject(clients...)
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	// connection reset while waiting for finality, which is a transport error
+	_, data := newTestData(t, 100)
+	_, _, err = uploader.Upload(context.Background(), data)
+	var rpcErr *node.RPCError
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.True(t, rpcErr.IsTransport())
+	assert.Equal(t, node.TransportErrorResetByPeer, rpcErr.Transport.Kind)
+
+	// resumed once connection recovered
+	_, root, err := uploader.Upload(context.Background(), data, UploadOption{SkipTx: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(network.Chain.Submissions()))
+	info, err := network.Nodes[0].GetFileInfo(root)
+	assert.NoError(t, err)
+	assert.True(t, info.Finalized)
+}
+
+func TestChaosDownloadFailover(t *testing.T) {
+	network, content, root := newReplicatedNetwork(t)
+
+	// segments of the first node unavailable
+	clients := network.ZgsClients()
+	chaos.MustNew(chaos.Scenario{
+		Name:   "drop-segment-downloads",
+		Faults: []chaos.Fault{{Methods: chaos.SegmentDownloadMethods, Action: chaos.ActionDrop}},
+	}).Inject(clients[0])
+
+	downloader, err := NewDownloader(clients)
+	assert.NoError(t, err)
+	defer downloader.Close()
+
+	filename := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, downloader.Download(context.Background(), root, filename, false))
+	downloaded, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+	assert.NotZero(t, downloader.Warnings().Count(WarningSegmentRerouted))
+}
+
+func TestChaosProofVerification(t *testing.T) {
+	network, content, root := newReplicatedNetwork(t)
+
+	// segments of the first node corrupted, and rejected by proof verification
+	clients := network.ZgsClients()
+	injector := chaos.MustNew(chaos.CorruptSegments())
+	injector.Inject(clients[0])
+
+	downloader, err := NewDownloader(clients)
+	assert.NoError(t, err)
+	defer downloader.Close()
+
+	filename := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, downloader.Download(context.Background(), root, filename, true))
+	downloaded, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+	assert.NotZero(t, injector.Injected()[chaos.ActionCorrupt])
+
+	// failed if all nodes corrupted
+	injector.Inject(clients[1])
+	downloader, err = NewDownloader(clients)
+	assert.NoError(t, err)
+	defer downloader.Close()
+	assert.Error(t, downloader.Download(context.Background(), root, filepath.Join(t.TempDir(), "file"), true))
+}
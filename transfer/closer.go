@@ -0,0 +1,85 @@
+package transfer
+
+import (
+	"sync"
+
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/pkg/errors"
+)
+
+// ErrClosed is returned when uploading or downloading with a closed Uploader or Downloader.
+var ErrClosed = errors.New("closed")
+
+// lifecycle tracks in-flight calls of Uploader or Downloader, so as to drain them before closing.
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+	stop     chan struct{} // closed when closing, so as to cancel background tasks, e.g. replicas to complete
+
+	ownsClients bool // whether to close storage node clients when closed
+}
+
+// acquire registers an in-flight call, which fails if already closed.
+func (l *lifecycle) acquire() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	l.inflight.Add(1)
+
+	return nil
+}
+
+func (l *lifecycle) release() {
+	l.inflight.Done()
+}
+
+// stopping returns a channel that is closed once closing starts. Background tasks should be registered with
+// acquire and cancelled upon stopping, otherwise close will wait for them to complete.
+func (l *lifecycle) stopping() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stop == nil {
+		l.stop = make(chan struct{})
+		if l.closed {
+			close(l.stop)
+		}
+	}
+
+	return l.stop
+}
+
+// close rejects new calls, cancels background tasks, waits for in-flight calls to complete, and then closes the storage node clients
+// if owned. It is safe to close for multiple times.
+func (l *lifecycle) close(clients []*node.ZgsClient) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	if l.stop != nil {
+		close(l.stop)
+	}
+	l.mu.Unlock()
+
+	l.inflight.Wait()
+
+	if !l.ownsClients {
+		return nil
+	}
+
+	var result error
+	for _, client := range clients {
+		if err := client.Close(); err != nil && result == nil {
+			result = errors.WithMessagef(err, "failed to close client %v", client.URL())
+		}
+	}
+
+	return result
+}
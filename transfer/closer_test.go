@@ -0,0 +1,67 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestUploaderClose(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	client, err := node.NewZgsClient("http://127.0.0.1:12345")
+	assert.NoError(t, err)
+
+	uploader := &Uploader{clients: []*node.ZgsClient{client}, warnings: NewWarnings(0)}
+	uploader.WithClientsOwned(true)
+
+	// in-flight upload
+	assert.NoError(t, uploader.acquire())
+	stop := uploader.stopping()
+
+	closed := make(chan error)
+	go func() { closed <- uploader.Close() }()
+
+	select {
+	case <-closed:
+		t.Fatal("closed before in-flight upload completed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// new uploads rejected while draining
+	data, err := core.NewDataInMemory([]byte("hello"))
+	assert.NoError(t, err)
+	_, _, err = uploader.Upload(context.Background(), data)
+	assert.ErrorIs(t, err, ErrClosed)
+
+	// background tasks notified to stop before draining
+	<-stop
+
+	uploader.release()
+	assert.NoError(t, <-closed)
+
+	// safe to close again
+	assert.NoError(t, uploader.Close())
+}
+
+func TestDownloaderClose(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	client, err := node.NewZgsClient("http://127.0.0.1:12345")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	downloader, err := NewDownloader([]*node.ZgsClient{client})
+	assert.NoError(t, err)
+
+	assert.NoError(t, downloader.Close())
+	assert.NoError(t, downloader.Close())
+
+	err = downloader.Download(context.Background(), "0x0", "file", false)
+	assert.ErrorIs(t, err, ErrClosed)
+}
@@ -0,0 +1,94 @@
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMockZgsNode serves the mock storage node, and returns the client to connect to it.
+func newMockZgsNode(t *testing.T, service *testutil.ZgsService) *node.ZgsClient {
+	client, err := node.NewZgsClient(testutil.Serve(t, map[string]interface{}{"zgs": service}))
+	assert.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+// submit submits the data to mock storage node without tags, and returns the merkle root.
+func submit(t *testing.T, service *testutil.ZgsService, content []byte) common.Hash {
+	root, err := service.Submit(content, nil)
+	assert.NoError(t, err)
+
+	return root
+}
+
+// TestConcurrentTransfers shares one storage node client, Uploader and Downloader across goroutines, which is
+// expected to run with -race.
+func TestConcurrentTransfers(t *testing.T) {
+	service := testutil.NewZgsService()
+	client := newMockZgsNode(t, service)
+
+	uploader := &Uploader{clients: []*node.ZgsClient{client}, routines: 4, logger: zg_common.NewLogger(), warnings: NewWarnings(0)}
+	uploader.WithSingleflight(true)
+	downloader, err := NewDownloader([]*node.ZgsClient{client})
+	assert.NoError(t, err)
+
+	const numFiles = 8
+	contents := make([][]byte, numFiles)
+	roots := make([]common.Hash, numFiles)
+	for i := range contents {
+		// files of different number of segments, and the last two are identical to deduplicate
+		if i == numFiles-1 {
+			contents[i], roots[i] = contents[i-1], roots[i-1]
+			break
+		}
+
+		contents[i] = make([]byte, core.DefaultSegmentSize*(i%3+1)+core.DefaultChunkSize*(i+1))
+		_, err = rand.Read(contents[i])
+		assert.NoError(t, err)
+		roots[i] = submit(t, service, contents[i])
+	}
+
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	for i := range contents {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			data, err := core.NewDataInMemory(contents[i])
+			assert.NoError(t, err)
+
+			_, root, err := uploader.Upload(context.Background(), data, UploadOption{SkipTx: true, ExpectedReplica: 1})
+			assert.NoError(t, err)
+			assert.Equal(t, roots[i], root)
+
+			// concurrent queries on the shared client
+			info, err := client.GetFileInfo(context.Background(), root)
+			assert.NoError(t, err)
+			assert.True(t, info.Finalized)
+
+			filename := filepath.Join(dir, root.Hex()+"-"+string(rune('a'+i)))
+			assert.NoError(t, downloader.Download(context.Background(), root.Hex(), filename, i%2 == 0))
+
+			downloaded, err := os.ReadFile(filename)
+			assert.NoError(t, err)
+			assert.Equal(t, contents[i], downloaded)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.NoError(t, uploader.Close())
+	assert.NoError(t, downloader.Close())
+}
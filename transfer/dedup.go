@@ -0,0 +1,388 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// AppendTreeStore persists merkle trees of files in a directory, which are named by the merkle root of file, so
+// that new versions of files could be compared with or appended to the old ones.
+type AppendTreeStore struct {
+	dir string
+}
+
+// NewAppendTreeStore creates a store to persist merkle trees in the specified directory.
+func NewAppendTreeStore(dir string) *AppendTreeStore {
+	return &AppendTreeStore{dir}
+}
+
+// Path returns the path of persisted merkle tree of the specified file.
+func (store *AppendTreeStore) Path(root common.Hash) string {
+	return filepath.Join(store.dir, root.Hex()+".tree")
+}
+
+// Load loads the persisted merkle tree of the specified file, and returns nil if not persisted.
+func (store *AppendTreeStore) Load(root common.Hash) (*AppendTree, error) {
+	path := store.Path(root)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	tree, err := LoadAppendTree(path)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to load merkle tree of %v", root)
+	}
+
+	if tree.Root != root {
+		return nil, errors.Errorf("merkle root mismatch, expected = %v, persisted = %v", root, tree.Root)
+	}
+
+	return tree, nil
+}
+
+// Save persists the merkle tree.
+func (store *AppendTreeStore) Save(tree *AppendTree) error {
+	if err := os.MkdirAll(store.dir, os.ModePerm); err != nil {
+		return errors.WithMessage(err, "failed to create directory")
+	}
+
+	return tree.Save(store.Path(tree.Root))
+}
+
+// DedupHint hints how to reuse the segments of old file when uploading the new version.
+type DedupHint string
+
+const (
+	DedupHintAppend DedupHint = "append"  // old file is a prefix of new file, and could be appended
+	DedupHintPack   DedupHint = "pack"    // some segments are identical, and could be reused if packed
+	DedupHintNone   DedupHint = "none"    // no segment identical
+	DedupHintNoTree DedupHint = "no-tree" // merkle tree of old file not persisted
+)
+
+// DedupFileReport is the deduplication analysis of a file that changed between dataset versions.
+//
+// Segments are compared by segment roots, and only segments fully filled with data are counted, since the last
+// segment of a file is padded and never identical to a segment of another file at the same offset unless data
+// identical.
+type DedupFileReport struct {
+	Path          string      `json:"path"`          // path relative to the dataset root
+	OldRoot       common.Hash `json:"oldRoot"`       // merkle root of old file
+	NewRoot       common.Hash `json:"newRoot"`       // merkle root of new file
+	OldSize       int64       `json:"oldSize"`       // size of old file
+	NewSize       int64       `json:"newSize"`       // size of new file
+	Segments      uint64      `json:"segments"`      // number of segments of new file
+	Aligned       uint64      `json:"aligned"`       // number of segments identical at the same index
+	Shifted       uint64      `json:"shifted"`       // number of segments identical to a segment of old file at another index
+	Appendable    bool        `json:"appendable"`    // whether old file is a prefix of new file
+	AppendSavings int64       `json:"appendSavings"` // bytes not uploaded if appended to old file
+	PackSavings   int64       `json:"packSavings"`   // bytes not uploaded if identical segments reused
+	Hint          DedupHint   `json:"hint"`
+}
+
+// DedupReport is the deduplication analysis between an old dataset manifest and a new local dataset.
+type DedupReport struct {
+	Files         []DedupFileReport `json:"files"`         // files changed between versions
+	Unchanged     int               `json:"unchanged"`     // number of files unchanged
+	Added         int               `json:"added"`         // number of files only in new version
+	Removed       int               `json:"removed"`       // number of files only in old version
+	ChangedSize   int64             `json:"changedSize"`   // total size of changed files in new version
+	AppendSavings int64             `json:"appendSavings"` // total bytes not uploaded if appendable files appended
+	PackSavings   int64             `json:"packSavings"`   // total bytes not uploaded if identical segments reused
+}
+
+// DedupAppend appends the data of a file in new version from Offset to the old file.
+type DedupAppend struct {
+	Path     string      `json:"path"`     // path relative to the dataset root
+	BaseRoot common.Hash `json:"baseRoot"` // merkle root of old file
+	NewRoot  common.Hash `json:"newRoot"`  // merkle root of new file once appended
+	Offset   int64       `json:"offset"`   // offset of the appended data, i.e. size of old file
+
+	Footprint core.Footprint `json:"footprint"` // storage footprint of the new file submitted
+}
+
+// DedupPlan is the plan to upload the new version of dataset by reusing the old one.
+type DedupPlan struct {
+	Appends []DedupAppend `json:"appends"`
+}
+
+// Plan returns the plan to upload files with deduplication hints.
+//
+// Note, only appendable files are planned, and files hinted to be packed are uploaded as a whole, since packing
+// of segments is not supported by storage nodes yet.
+func (report *DedupReport) Plan() *DedupPlan {
+	plan := DedupPlan{Appends: make([]DedupAppend, 0)}
+	for _, file := range report.Files {
+		if file.Hint == DedupHintAppend {
+			plan.Appends = append(plan.Appends, DedupAppend{
+				Path:     file.Path,
+				BaseRoot: file.OldRoot,
+				NewRoot:  file.NewRoot,
+				Offset:   file.OldSize,
+
+				Footprint: core.StorageFootprint(file.NewSize),
+			})
+		}
+	}
+
+	return &plan
+}
+
+// LoadDedupPlan loads the plan from the specified file.
+func LoadDedupPlan(path string) (*DedupPlan, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read file")
+	}
+
+	var plan DedupPlan
+	if err = json.Unmarshal(content, &plan); err != nil {
+		return nil, errors.WithMessage(err, "failed to unmarshal plan")
+	}
+
+	return &plan, nil
+}
+
+// Save persists the plan to the specified file.
+func (plan *DedupPlan) Save(path string) error {
+	content, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return errors.WithMessage(err, "failed to marshal plan")
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
+
+// AnalyzeDedup compares files present in both the old manifest and the new local dataset in folder, of which the
+// merkle roots differ, by the segment roots of the merkle trees persisted in store, and reports the potential
+// savings if the new files are appended to the old ones or the identical segments reused.
+//
+// If saveTrees specified, merkle trees of changed and added files in new version are persisted in store, so as to
+// analyze or append the next version.
+func AnalyzeDedup(old *dir.FsNode, folder string, store *AppendTreeStore, saveTrees bool) (*DedupReport, error) {
+	next, err := dir.BuildFileTree(folder)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to build file tree")
+	}
+
+	oldFiles := regularFilesOf(old)
+	newFiles := regularFilesOf(next)
+
+	report := DedupReport{Files: make([]DedupFileReport, 0)}
+	for path := range oldFiles {
+		if _, ok := newFiles[path]; !ok {
+			report.Removed++
+		}
+	}
+
+	paths := make([]string, 0, len(newFiles))
+	for path := range newFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		newFile := newFiles[path]
+
+		oldFile, ok := oldFiles[path]
+		if ok && oldFile.Root == newFile.Root {
+			report.Unchanged++
+			continue
+		}
+
+		if !ok {
+			report.Added++
+		}
+
+		if (!ok && !saveTrees) || newFile.Size == 0 {
+			continue
+		}
+
+		file, err := core.Open(filepath.Join(folder, path))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to open file %v", path)
+		}
+
+		fileReport, err := analyzeFileDedup(file, oldFile, store, saveTrees)
+		file.Close()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to analyze file %v", path)
+		}
+
+		if fileReport != nil {
+			fileReport.Path = path
+			report.Files = append(report.Files, *fileReport)
+			report.ChangedSize += fileReport.NewSize
+			report.AppendSavings += fileReport.AppendSavings
+			report.PackSavings += fileReport.PackSavings
+		}
+	}
+
+	return &report, nil
+}
+
+// analyzeFileDedup compares the new file with the old one if any, and persists the merkle tree of new file if
+// saveTree specified.
+func analyzeFileDedup(file *core.File, oldFile *dir.FsNode, store *AppendTreeStore, saveTree bool) (*DedupFileReport, error) {
+	newTree, err := NewAppendTree(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if saveTree {
+		if err = store.Save(newTree); err != nil {
+			return nil, errors.WithMessage(err, "failed to persist merkle tree")
+		}
+	}
+
+	if oldFile == nil {
+		return nil, nil
+	}
+
+	report := DedupFileReport{
+		OldRoot:  common.HexToHash(oldFile.Root),
+		NewRoot:  newTree.Root,
+		OldSize:  oldFile.Size,
+		NewSize:  newTree.Size,
+		Segments: file.NumSegments(),
+		Hint:     DedupHintNoTree,
+	}
+
+	oldTree, err := store.Load(report.OldRoot)
+	if err != nil || oldTree == nil {
+		return &report, err
+	}
+
+	// segments identical at the same index, or at another index
+	oldSegments := make(map[common.Hash]struct{}, len(oldTree.SegmentRoots))
+	for _, root := range oldTree.SegmentRoots {
+		oldSegments[root] = struct{}{}
+	}
+
+	for i, root := range newTree.SegmentRoots {
+		if i < len(oldTree.SegmentRoots) && oldTree.SegmentRoots[i] == root {
+			report.Aligned++
+		} else if _, ok := oldSegments[root]; ok {
+			report.Shifted++
+		}
+	}
+
+	report.PackSavings = int64(report.Aligned+report.Shifted) * core.DefaultSegmentSize
+
+	// old file is a prefix of new file, i.e. all full segments identical, and the data of last segment that
+	// partially filled is the prefix of the segment at the same index in new file
+	if report.Appendable, err = isAppendable(file, oldTree, report.Aligned); err != nil {
+		return nil, err
+	}
+
+	if report.Appendable {
+		report.AppendSavings = int64(len(oldTree.SegmentRoots)) * core.DefaultSegmentSize
+	}
+
+	switch {
+	case report.AppendSavings > 0:
+		report.Hint = DedupHintAppend
+	case report.PackSavings > 0:
+		report.Hint = DedupHintPack
+	default:
+		report.Hint = DedupHintNone
+	}
+
+	return &report, nil
+}
+
+// isAppendable returns whether the old file is a prefix of the new file, where the leading aligned segments
+// are already known to be identical.
+func isAppendable(file *core.File, oldTree *AppendTree, aligned uint64) (bool, error) {
+	if file.Size() <= oldTree.Size || aligned < uint64(len(oldTree.SegmentRoots)) {
+		return false, nil
+	}
+
+	if len(oldTree.Tail) == 0 {
+		return true, nil
+	}
+
+	buf := make([]byte, len(oldTree.Tail))
+	if _, err := file.Read(buf, int64(len(oldTree.SegmentRoots))*core.DefaultSegmentSize); err != nil {
+		return false, errors.WithMessage(err, "failed to read file")
+	}
+
+	return bytes.Equal(buf, oldTree.Tail), nil
+}
+
+// regularFilesOf returns the regular files of the file tree by path relative to the tree root.
+func regularFilesOf(root *dir.FsNode) map[string]*dir.FsNode {
+	files := make(map[string]*dir.FsNode)
+	for _, entry := range root.Entries {
+		nodes, relpaths := entry.Flatten(func(n *dir.FsNode) bool {
+			return n.Type == dir.FileTypeFile
+		})
+
+		for i, node := range nodes {
+			files[filepath.ToSlash(relpaths[i])] = node
+		}
+	}
+
+	return files
+}
+
+// ExecuteDedupPlan uploads the appendable files of new version in folder by appending to the old files, of which
+// the merkle trees are loaded from store. Besides, merkle trees of new files are persisted in store to append
+// the next version.
+func (uploader *Uploader) ExecuteDedupPlan(ctx context.Context, plan *DedupPlan, folder string, store *AppendTreeStore, option ...UploadOption) ([]*AppendResult, error) {
+	results := make([]*AppendResult, 0, len(plan.Appends))
+	for _, entry := range plan.Appends {
+		result, err := uploader.executeDedupAppend(ctx, entry, folder, store, option...)
+		if err != nil {
+			return results, errors.WithMessagef(err, "failed to append file %v", entry.Path)
+		}
+
+		uploader.logger.WithFields(logrus.Fields{
+			"path":   entry.Path,
+			"root":   result.Root,
+			"reused": result.ReusedSegments,
+			"pushed": result.PushedSegments,
+		}).Info("File appended")
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (uploader *Uploader) executeDedupAppend(ctx context.Context, entry DedupAppend, folder string, store *AppendTreeStore, option ...UploadOption) (*AppendResult, error) {
+	file, err := core.Open(filepath.Join(folder, filepath.FromSlash(entry.Path)))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to open file")
+	}
+	defer file.Close()
+
+	tail, err := file.Tail(entry.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := uploader.Append(ctx, entry.BaseRoot, store.Path(entry.BaseRoot), tail, option...)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Root != entry.NewRoot {
+		return result, errors.Errorf("merkle root mismatch, planned = %v, appended = %v", entry.NewRoot, result.Root)
+	}
+
+	if err = store.Save(result.Tree); err != nil {
+		return result, errors.WithMessage(err, "failed to persist merkle tree")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,141 @@
+package transfer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+const segSize = core.DefaultSegmentSize
+
+func writeDataset(t *testing.T, files map[string][]byte) string {
+	folder := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(folder, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.NoError(t, os.WriteFile(path, content, 0644))
+	}
+	return folder
+}
+
+func concat(parts ...[]byte) []byte {
+	var result []byte
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return result
+}
+
+func TestAnalyzeDedup(t *testing.T) {
+	aligned := newRandomData(t, 2*segSize)
+	unaligned := newRandomData(t, 2*segSize+3000)
+	modified := newRandomData(t, 4*segSize)
+	shifted := newRandomData(t, 3*segSize+100)
+	inserted := newRandomData(t, 3*segSize)
+	tailModified := newRandomData(t, 2*segSize+3000)
+	small := newRandomData(t, 100)
+	unchanged := newRandomData(t, 1000)
+
+	v1 := writeDataset(t, map[string][]byte{
+		"aligned.csv":           aligned,
+		"sub/unaligned.csv":     unaligned,
+		"modified.bin":          modified,
+		"shifted.bin":           shifted,
+		"inserted.bin":          inserted,
+		"tail_modified.csv":     tailModified,
+		"small.csv":             small,
+		"unchanged.txt":         unchanged,
+		"removed.txt":           []byte("removed"),
+		"sub/deeper/no_tree.db": newRandomData(t, segSize+1),
+	})
+
+	modifiedNew := append([]byte(nil), modified...)
+	modifiedNew[segSize+10] ^= 0xff
+	tailModifiedNew := concat(tailModified, newRandomData(t, 5000))
+	tailModifiedNew[2*segSize+10] ^= 0xff
+
+	v2 := writeDataset(t, map[string][]byte{
+		"aligned.csv":           concat(aligned, newRandomData(t, 1000)),
+		"sub/unaligned.csv":     concat(unaligned, newRandomData(t, segSize)),
+		"modified.bin":          modifiedNew,
+		"shifted.bin":           concat(newRandomData(t, segSize), shifted),
+		"inserted.bin":          concat([]byte("header"), inserted),
+		"tail_modified.csv":     tailModifiedNew,
+		"small.csv":             concat(small, newRandomData(t, 300)),
+		"unchanged.txt":         unchanged,
+		"added.txt":             []byte("added"),
+		"sub/deeper/no_tree.db": newRandomData(t, segSize+2),
+	})
+
+	// persist merkle trees of old version, except the one not persisted
+	store := NewAppendTreeStore(filepath.Join(t.TempDir(), "trees"))
+	old, err := dir.BuildFileTree(v1)
+	assert.NoError(t, err)
+	_, err = AnalyzeDedup(dir.NewDirFsNode("empty", nil), v1, store, true)
+	assert.NoError(t, err)
+	noTree, err := old.Locate("sub/deeper/no_tree.db")
+	assert.NoError(t, err)
+	assert.NoError(t, os.Remove(store.Path(common.HexToHash(noTree.Root))))
+
+	report, err := AnalyzeDedup(old, v2, store, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Equal(t, 1, report.Added)
+	assert.Equal(t, 1, report.Removed)
+
+	type expected struct {
+		aligned, shifted uint64
+		appendable       bool
+		hint             DedupHint
+	}
+	actual := make(map[string]expected)
+	for _, file := range report.Files {
+		actual[file.Path] = expected{file.Aligned, file.Shifted, file.Appendable, file.Hint}
+
+		assert.Equal(t, int64(file.Aligned+file.Shifted)*segSize, file.PackSavings, file.Path)
+	}
+
+	assert.Equal(t, map[string]expected{
+		"aligned.csv":           {2, 0, true, DedupHintAppend},
+		"sub/unaligned.csv":     {2, 0, true, DedupHintAppend},
+		"modified.bin":          {3, 0, false, DedupHintPack},
+		"shifted.bin":           {0, 3, false, DedupHintPack},
+		"inserted.bin":          {0, 0, false, DedupHintNone},
+		"tail_modified.csv":     {2, 0, false, DedupHintPack},
+		"small.csv":             {0, 0, true, DedupHintNone},
+		"sub/deeper/no_tree.db": {0, 0, false, DedupHintNoTree},
+	}, actual)
+
+	assert.Equal(t, int64(4*segSize), report.AppendSavings)
+	assert.Equal(t, int64(12*segSize), report.PackSavings)
+
+	// planned appends result in the same files as new version
+	path := filepath.Join(t.TempDir(), "plan.json")
+	assert.NoError(t, report.Plan().Save(path))
+	plan, err := LoadDedupPlan(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(plan.Appends))
+
+	for _, entry := range plan.Appends {
+		base, err := store.Load(entry.BaseRoot)
+		assert.NoError(t, err)
+
+		file, err := core.Open(filepath.Join(v2, filepath.FromSlash(entry.Path)))
+		assert.NoError(t, err)
+		defer file.Close()
+		tail, err := file.Tail(entry.Offset)
+		assert.NoError(t, err)
+
+		data, _, err := prepareAppend(entry.BaseRoot, base, tail)
+		assert.NoError(t, err)
+		tree, err := core.MerkleTree(data)
+		assert.NoError(t, err)
+		assert.Equal(t, entry.NewRoot, tree.Root(), entry.Path)
+	}
+}
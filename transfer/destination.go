@@ -0,0 +1,112 @@
+package transfer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/pkg/errors"
+)
+
+// ErrDestinationDiffers is matched by DestinationDiffersError with errors.Is.
+var ErrDestinationDiffers = errors.New("destination file differs")
+
+// DestinationDiffersError is returned when the destination file to download already exists with content other
+// than expected, which is never overwritten unless forced, so that a download pointed at a wrong destination does
+// not destroy data.
+type DestinationDiffersError struct {
+	Path         string
+	ExpectedSize int64 // size of the content to download
+	ActualSize   int64 // size of the existing file
+
+	// DiffersBefore is the hint of the first differing region, i.e. the existing file differs from the content at or
+	// before the offset, which is the smaller size if sizes differ. Note, the exact offset is unknown unless the
+	// content is available locally, e.g. embedded in directory metadata.
+	DiffersBefore int64
+}
+
+func (e *DestinationDiffersError) Error() string {
+	return fmt.Sprintf("%v: path = %v, expected size = %v, actual size = %v, first difference at or before offset %v, overwritten only if forced",
+		ErrDestinationDiffers, e.Path, e.ExpectedSize, e.ActualSize, e.DiffersBefore)
+}
+
+func (e *DestinationDiffersError) Unwrap() error {
+	return ErrDestinationDiffers
+}
+
+// checkDestination checks the existing destination file to download content of the specified size. If sizes
+// match, identical is called to verify the existing file, e.g. by merkle root, and returns the offset of the first
+// difference if known, or -1 if identical.
+//
+// Returns nil if destination not exists, ErrFileAlreadyExists if identical, or DestinationDiffersError otherwise.
+func checkDestination(filename string, size int64, identical func(file *core.File) (int64, error)) error {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.WithMessage(err, "Failed to stat file")
+	}
+
+	if info.IsDir() {
+		return errors.Errorf("Destination %v is a directory", filename)
+	}
+
+	differs := &DestinationDiffersError{
+		Path:          filename,
+		ExpectedSize:  size,
+		ActualSize:    info.Size(),
+		DiffersBefore: min(size, info.Size()),
+	}
+
+	if info.Size() != size {
+		return differs
+	}
+
+	// empty file is identical to empty content
+	if size == 0 {
+		return ErrFileAlreadyExists
+	}
+
+	file, err := core.Open(filename)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to open file")
+	}
+	defer file.Close()
+
+	offset, err := identical(file)
+	if err != nil {
+		return err
+	}
+
+	if offset < 0 {
+		return ErrFileAlreadyExists
+	}
+
+	if offset < size {
+		differs.DiffersBefore = offset
+	}
+
+	return differs
+}
+
+// CreateFragmentsFile creates the destination file to concatenate downloaded fragments, which fails if already
+// exists unless overwrite specified, since the content of fragments is unknown before downloaded.
+func CreateFragmentsFile(filename string, overwrite bool) (*os.File, error) {
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if !overwrite {
+		flag |= os.O_EXCL
+	}
+
+	file, err := os.OpenFile(filename, flag, 0644)
+	if os.IsExist(err) {
+		return nil, errors.Errorf("Destination file %v already exists, and overwritten only if forced", filename)
+	}
+
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create output file")
+	}
+
+	return file, nil
+}
@@ -0,0 +1,117 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadDestinationDiffers(t *testing.T) {
+	service := testutil.NewZgsService()
+	content, root, downloader := newReaderFixture(t, service, 2)
+	filename := filepath.Join(t.TempDir(), "file")
+
+	assert.NoError(t, downloader.Download(context.Background(), root, filename, false))
+	assert.ErrorIs(t, downloader.Download(context.Background(), root, filename, false), ErrFileAlreadyExists)
+
+	// size differs
+	assert.NoError(t, os.WriteFile(filename, []byte("keep"), 0644))
+	err := downloader.Download(context.Background(), root, filename, false)
+	var differs *DestinationDiffersError
+	if assert.True(t, errors.As(err, &differs)) {
+		assert.Equal(t, filename, differs.Path)
+		assert.Equal(t, int64(len(content)), differs.ExpectedSize)
+		assert.Equal(t, int64(4), differs.ActualSize)
+		assert.Equal(t, int64(4), differs.DiffersBefore)
+	}
+	assert.ErrorIs(t, err, ErrDestinationDiffers)
+
+	// content differs of the same size
+	modified := append([]byte(nil), content...)
+	modified[100]++
+	assert.NoError(t, os.WriteFile(filename, modified, 0644))
+	err = downloader.Download(context.Background(), root, filename, false)
+	if assert.True(t, errors.As(err, &differs)) {
+		assert.Equal(t, differs.ExpectedSize, differs.ActualSize)
+	}
+
+	// not overwritten unless forced
+	actual, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, modified, actual)
+
+	assert.NoError(t, downloader.WithOverwrite(true).Download(context.Background(), root, filename, false))
+	actual, err = os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, actual)
+}
+
+func TestDownloadFragmentsDestinationExists(t *testing.T) {
+	service := testutil.NewZgsService()
+	content, root, downloader := newReaderFixture(t, service, 1)
+	filename := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(filename, []byte("keep"), 0644))
+
+	assert.Error(t, downloader.DownloadFragments(context.Background(), []string{root}, filename, false))
+	actual, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("keep"), actual)
+
+	assert.NoError(t, downloader.WithOverwrite(true).DownloadFragments(context.Background(), []string{root}, filename, false))
+	actual, err = os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, actual)
+}
+
+func TestDownloadDirDestinationDiffers(t *testing.T) {
+	downloader := memDirDownloader{}
+	fileRoot := downloader.add(t, []byte("downloaded"))
+	embedded, err := dir.NewEmbeddedFileFsNode("embedded", []byte("embedded"))
+	assert.NoError(t, err)
+	tree := dir.NewDirFsNode("", []*dir.FsNode{
+		dir.NewFileFsNode("file", common.HexToHash(fileRoot), int64(len("downloaded"))),
+		embedded,
+	})
+	manifest, err := dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+	root := downloader.add(t, manifest)
+
+	target := filepath.Join(t.TempDir(), "target")
+	assert.NoError(t, DownloadDir(context.Background(), downloader, root, target, true))
+
+	// identical files are kept
+	assert.NoError(t, DownloadDir(context.Background(), downloader, root, target, true))
+
+	// size differs, and fails before the directory is moved
+	assert.NoError(t, os.WriteFile(filepath.Join(target, "file"), []byte("local"), 0644))
+	err = DownloadDir(context.Background(), downloader, root, target, true)
+	var differs *DestinationDiffersError
+	if assert.True(t, errors.As(err, &differs)) {
+		assert.Equal(t, filepath.Join(target, "file"), differs.Path)
+		assert.Equal(t, int64(len("downloaded")), differs.ExpectedSize)
+		assert.Equal(t, int64(len("local")), differs.ActualSize)
+	}
+	_, err = os.Stat(target)
+	assert.NoError(t, err)
+
+	// content of embedded file differs at the exact offset
+	assert.NoError(t, os.WriteFile(filepath.Join(target, "file"), []byte("downloaded"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(target, "embedded"), []byte("embedder"), 0644))
+	err = DownloadDir(context.Background(), downloader, root, target, true)
+	if assert.True(t, errors.As(err, &differs)) {
+		assert.Equal(t, int64(7), differs.DiffersBefore)
+	}
+
+	// overwritten if forced, where the failed download is resumed
+	assert.NoError(t, DownloadDir(context.Background(), downloader, root, target, true, DownloadDirOption{Overwrite: true}))
+	actual, err := os.ReadFile(filepath.Join(target, "embedded"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("embedded"), actual)
+}
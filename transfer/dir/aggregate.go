@@ -0,0 +1,54 @@
+package dir
+
+// ComputeAggregates populates all directories within the node with the recursive total size of regular files
+// and the number of regular files, e.g. to show the size of directory to upload before uploading. Symbolic links
+// contribute zero bytes, and empty directories report zero files.
+//
+// Aggregates are not populated when the tree is built or decoded, and should be computed again once the tree is
+// modified, e.g. patched. They are excluded from the manifest, so the manifest root is unchanged.
+func (node *FsNode) ComputeAggregates() {
+	for directory, result := range aggregatesOf(node) {
+		directory.TotalSize, directory.FileCount = result.size, result.files
+	}
+}
+
+// aggregate is the total size and number of regular files within a directory.
+type aggregate struct {
+	size  int64
+	files int
+}
+
+// aggregatesOf computes the aggregates of all directories within the node without modifying the tree.
+func aggregatesOf(node *FsNode) map[*FsNode]aggregate {
+	// collect directories in pre-order iteratively, so that deep trees never overflow the call stack
+	var dirs []*FsNode
+	stack := []*FsNode{node}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current.Type == FileTypeDirectory {
+			dirs = append(dirs, current)
+			stack = append(stack, current.Entries...)
+		}
+	}
+
+	// sub directories always come after the parent in pre-order, so aggregate in reverse order
+	aggregates := make(map[*FsNode]aggregate, len(dirs))
+	for i := len(dirs) - 1; i >= 0; i-- {
+		var result aggregate
+		for _, entry := range dirs[i].Entries {
+			switch entry.Type {
+			case FileTypeFile:
+				result.size += entry.Size
+				result.files++
+			case FileTypeDirectory:
+				result.size += aggregates[entry].size
+				result.files += aggregates[entry].files
+			}
+		}
+		aggregates[dirs[i]] = result
+	}
+
+	return aggregates
+}
@@ -0,0 +1,71 @@
+package dir_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeAggregates(t *testing.T) {
+	folder := createEmbedTestDir(t)
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub", "nested", "empty"), 0755))
+	assert.NoError(t, os.Symlink("large.bin", filepath.Join(folder, "link")))
+
+	tree, err := dir.BuildFileTree(folder)
+	assert.NoError(t, err)
+
+	expectedRoot, err := dir.ManifestRoot(tree)
+	assert.NoError(t, err)
+
+	tree.ComputeAggregates()
+
+	for path, expected := range map[string][2]int64{
+		".":                {305340, 7},
+		"sub":              {300310, 4},
+		"sub/nested":       {0, 0},
+		"sub/nested/empty": {0, 0},
+	} {
+		node, err := tree.Locate(path)
+		assert.NoError(t, err)
+		assert.Equal(t, expected[0], node.TotalSize, path)
+		assert.Equal(t, expected[1], int64(node.FileCount), path)
+	}
+
+	// symbolic links contribute zero bytes, and files are not aggregated
+	link, err := tree.Locate("link")
+	assert.NoError(t, err)
+	assert.Zero(t, link.TotalSize)
+	file, err := tree.Locate("large.bin")
+	assert.NoError(t, err)
+	assert.Zero(t, file.TotalSize)
+	assert.Zero(t, file.FileCount)
+
+	// consistent with the files in tree
+	var total int64
+	for _, leaf := range tree.Leaves() {
+		total += leaf.Node.Size
+	}
+	assert.Equal(t, total, tree.TotalSize)
+
+	// aggregates are excluded from manifest
+	root, err := dir.ManifestRoot(tree)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRoot, root)
+
+	data, err := tree.EncodeBinary()
+	assert.NoError(t, err)
+	decoded, err := dir.DecodeBinary(data)
+	assert.NoError(t, err)
+	assert.Zero(t, decoded.TotalSize)
+
+	// aggregated again once modified
+	patched, err := dir.Patch(tree, []dir.PatchOp{{Type: dir.PatchOpDelete, Path: "sub/big.bin"}})
+	assert.NoError(t, err)
+	patched.ComputeAggregates()
+	assert.Equal(t, int64(5340), patched.TotalSize)
+	assert.Equal(t, 6, patched.FileCount)
+	assert.Equal(t, int64(305340), tree.TotalSize)
+}
@@ -0,0 +1,407 @@
+package dir
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxArchiveLinkSize is the max size of symbolic link target stored as content of zip entry.
+const maxArchiveLinkSize = 4096
+
+// ErrUnsupportedEntry is returned when building file tree from archive with entries of unsupported types, e.g.
+// hard links and device nodes, unless skipped by ArchiveOption.SkipUnsupported.
+var ErrUnsupportedEntry = errors.New("unsupported archive entry")
+
+// ArchiveOption is the option to build file tree from tar or zip archive, where the build option applies as if
+// the archive extracted to disk, except the hash option, since files are hashed while read sequentially.
+type ArchiveOption struct {
+	BuildOption
+
+	// SkipUnsupported skips entries of unsupported types, e.g. hard links and device nodes, which fail to build
+	// file tree with ErrUnsupportedEntry by default.
+	SkipUnsupported bool
+
+	// OnUnsupportedSkipped is called with the slash-separated path of entry skipped, along with the reason. By
+	// default, a warning is logged.
+	OnUnsupportedSkipped func(name string, err error)
+}
+
+// BuildFileTreeFromTar builds a file tree from the tar archive, optionally compressed by gzip, which is identical
+// to the one built from the extracted directory on disk, and hence the same manifest root. Contents of files are
+// hashed while the archive read, without written to disk.
+func BuildFileTreeFromTar(r io.Reader) (*FsNode, error) {
+	return BuildFileTreeFromTarWithOption(r, ArchiveOption{})
+}
+
+// BuildFileTreeFromTarWithOption builds a file tree from the tar archive with option, see BuildFileTreeFromTar.
+func BuildFileTreeFromTarWithOption(r io.Reader, opt ArchiveOption) (*FsNode, error) {
+	reader, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+
+	source := newArchiveSource()
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to read tar archive")
+		}
+
+		var entry archiveEntry
+		switch header.Typeflag {
+		case tar.TypeDir:
+			entry.info = newArchiveFileInfo(header.Name, 0, fs.FileMode(header.Mode).Perm()|fs.ModeDir, header.ModTime)
+		case tar.TypeSymlink:
+			entry.info = newArchiveFileInfo(header.Name, 0, fs.FileMode(header.Mode).Perm()|fs.ModeSymlink, header.ModTime)
+			entry.link = header.Linkname
+		case tar.TypeReg, tar.TypeGNUSparse:
+			entry.info = newArchiveFileInfo(header.Name, header.Size, fs.FileMode(header.Mode).Perm(), header.ModTime)
+		default:
+			err = errors.WithMessagef(ErrUnsupportedEntry, "type %q", header.Typeflag)
+		}
+
+		if err == nil {
+			err = source.add(header.Name, &entry, opt.BuildOption)
+		}
+
+		if errors.Is(err, ErrUnsupportedEntry) {
+			err = opt.skip(header.Name, err)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// files are hashed in order of archive, since the archive could only be read sequentially
+		if entry.pending {
+			if entry.root, err = core.MerkleRootReader(tr, header.Size); err != nil {
+				return nil, errors.WithMessagef(err, "failed to calculate merkle root for %s", header.Name)
+			}
+		}
+	}
+
+	return source.build(opt.BuildOption)
+}
+
+// BuildFileTreeFromZip builds a file tree from the zip archive of specified size, which is identical to the one
+// built from the extracted directory on disk, and hence the same manifest root. Contents of files are hashed while
+// decompressed, without written to disk, and concurrently if multiple workers specified.
+func BuildFileTreeFromZip(r io.ReaderAt, size int64) (*FsNode, error) {
+	return BuildFileTreeFromZipWithOption(r, size, ArchiveOption{})
+}
+
+// BuildFileTreeFromZipWithOption builds a file tree from the zip archive with option, see BuildFileTreeFromZip.
+func BuildFileTreeFromZipWithOption(r io.ReaderAt, size int64, opt ArchiveOption) (*FsNode, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read zip archive")
+	}
+
+	source := newArchiveSource()
+	for _, file := range zr.File {
+		info := file.FileInfo()
+
+		var entry archiveEntry
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+			entry.info = newArchiveFileInfo(file.Name, 0, mode.Perm()|fs.ModeDir, file.Modified)
+		case mode&fs.ModeSymlink != 0:
+			entry.info = newArchiveFileInfo(file.Name, 0, mode.Perm()|fs.ModeSymlink, file.Modified)
+			entry.link, err = readZipLink(file)
+		case mode.IsRegular():
+			entry.info = newArchiveFileInfo(file.Name, int64(file.UncompressedSize64), mode.Perm(), file.Modified)
+			entry.open = file.Open
+		default:
+			err = errors.WithMessagef(ErrUnsupportedEntry, "mode %v", mode)
+		}
+
+		if err == nil {
+			err = source.add(file.Name, &entry, opt.BuildOption)
+		}
+
+		if errors.Is(err, ErrUnsupportedEntry) {
+			err = opt.skip(file.Name, err)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return source.build(opt.BuildOption)
+}
+
+// skip skips the unsupported entry if allowed by option, and returns the error otherwise.
+func (opt ArchiveOption) skip(name string, err error) error {
+	if !opt.SkipUnsupported {
+		return errors.WithMessagef(err, "failed to build entry %s", name)
+	}
+
+	if opt.OnUnsupportedSkipped != nil {
+		opt.OnUnsupportedSkipped(name, err)
+	} else {
+		logrus.WithError(err).WithField("name", name).Warn("Unsupported archive entry skipped")
+	}
+
+	return nil
+}
+
+// decompress returns the reader of decompressed data if r is compressed by gzip, otherwise r as is.
+func decompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	if magic, err := buffered.Peek(2); err != nil || !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return buffered, nil
+	}
+
+	gz, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read gzip archive")
+	}
+
+	return gz, nil
+}
+
+// readZipLink reads the target of symbolic link, which is stored as content of zip entry.
+func readZipLink(file *zip.File) (string, error) {
+	if file.UncompressedSize64 > maxArchiveLinkSize {
+		return "", errors.Errorf("symbolic link %s too long", file.Name)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed to open symbolic link %s", file.Name)
+	}
+	defer rc.Close()
+
+	link, err := io.ReadAll(rc)
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed to read symbolic link %s", file.Name)
+	}
+
+	return string(link), nil
+}
+
+// archiveEntry is an entry of archive, of which the path is relative to the archive root.
+type archiveEntry struct {
+	info    fs.FileInfo
+	link    string                        // target of symbolic link
+	entries map[string]*archiveEntry      // entries of directory by name
+	open    func() (io.ReadCloser, error) // opens the content of regular file, nil if hashed already
+	pending bool                          // whether content of regular file to hash
+	root    common.Hash                   // merkle root of regular file hashed already
+}
+
+// archiveSource is the file system of archive entries indexed in memory, where regular files are either hashed
+// while the archive read, or opened on demand.
+type archiveSource struct {
+	root *archiveEntry
+}
+
+func newArchiveSource() *archiveSource {
+	return &archiveSource{
+		root: &archiveEntry{
+			info:    newArchiveFileInfo(".", 0, fs.ModeDir|0755, time.Time{}),
+			entries: make(map[string]*archiveEntry),
+		},
+	}
+}
+
+// cleanArchivePath returns the slash-separated path relative to archive root, and rejects entries that escape the
+// root, e.g. "../passwd" or "/etc/passwd", which never appear in directories extracted safely.
+func cleanArchivePath(name string) (string, error) {
+	if strings.HasPrefix(name, "/") || strings.Contains(name, "\\") {
+		return "", errors.Errorf("invalid archive entry %q", name)
+	}
+
+	cleaned := path.Clean(name)
+	if escaped(cleaned) {
+		return "", errors.Errorf("archive entry %q escapes root directory", name)
+	}
+
+	return cleaned, nil
+}
+
+// add adds the entry of specified name, along with parent directories not archived. Entries ignored by patterns
+// are dropped, so that files ignored are not hashed. Once archived multiple times, the latter takes effect as
+// extracted.
+func (source *archiveSource) add(name string, entry *archiveEntry, opt BuildOption) error {
+	relpath, err := cleanArchivePath(name)
+	if err != nil {
+		return err
+	}
+
+	if relpath == "." {
+		if entry.info.IsDir() {
+			source.root.info = newArchiveFileInfo(".", 0, entry.info.Mode(), entry.info.ModTime())
+		}
+		return nil
+	}
+
+	parent := source.root
+	elems := strings.Split(relpath, "/")
+	for i, elem := range elems[:len(elems)-1] {
+		if opt.Ignore.Match(strings.Join(elems[:i+1], "/"), true) {
+			return nil
+		}
+
+		child, ok := parent.entries[elem]
+		if !ok {
+			child = &archiveEntry{
+				info:    newArchiveFileInfo(elem, 0, fs.ModeDir|0755, time.Time{}),
+				entries: make(map[string]*archiveEntry),
+			}
+			parent.entries[elem] = child
+		} else if !child.info.IsDir() {
+			return errors.Errorf("archive entry %q is not a directory", strings.Join(elems[:i+1], "/"))
+		}
+
+		parent = child
+	}
+
+	if opt.Ignore.Match(relpath, entry.info.IsDir()) {
+		return nil
+	}
+
+	if existing, ok := parent.entries[entry.info.Name()]; ok && existing.info.IsDir() != entry.info.IsDir() {
+		return errors.Errorf("archive entry %q conflicts with a former entry", relpath)
+	} else if ok && entry.info.IsDir() {
+		// keep the entries archived before
+		existing.info = entry.info
+		return nil
+	}
+
+	if entry.info.IsDir() {
+		entry.entries = make(map[string]*archiveEntry)
+	}
+
+	entry.pending = entry.info.Mode().IsRegular() && entry.info.Size() > 0 && entry.open == nil
+	parent.entries[entry.info.Name()] = entry
+
+	return nil
+}
+
+// build builds the file tree of indexed entries with option.
+func (source *archiveSource) build(opt BuildOption) (*FsNode, error) {
+	root, _, err := buildFileTree(source, ".", source.lstat, opt)
+	return root, err
+}
+
+// lookup returns the entry of slash-separated path relative to archive root.
+func (source *archiveSource) lookup(name string) (*archiveEntry, error) {
+	entry := source.root
+	if name == "." {
+		return entry, nil
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		child, ok := entry.entries[elem]
+		if !ok {
+			return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+		}
+		entry = child
+	}
+
+	return entry, nil
+}
+
+func (*archiveSource) join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+func (source *archiveSource) lstat(name string) (fs.FileInfo, error) {
+	entry, err := source.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.info, nil
+}
+
+func (source *archiveSource) readDir(name string) ([]fs.DirEntry, error) {
+	entry, err := source.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, 0, len(entry.entries))
+	for _, child := range entry.entries {
+		entries = append(entries, fs.FileInfoToDirEntry(child.info))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+func (source *archiveSource) readlink(name string) (string, error) {
+	entry, err := source.lookup(name)
+	if err != nil {
+		return "", err
+	}
+
+	return entry.link, nil
+}
+
+func (source *archiveSource) merkleRoot(name string, _ core.HashOption) (common.Hash, error) {
+	entry, err := source.lookup(name)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if entry.open == nil {
+		return entry.root, nil
+	}
+
+	rc, err := entry.open()
+	if err != nil {
+		return common.Hash{}, errors.WithMessage(err, "failed to open file")
+	}
+	defer rc.Close()
+
+	return core.MerkleRootReader(rc, entry.info.Size())
+}
+
+// roots returns nothing, since the archive is rooted, and absolute links always escape.
+func (*archiveSource) roots(root string) ([]string, error) {
+	return nil, nil
+}
+
+// archiveFileInfo is the fs.FileInfo of archive entry.
+type archiveFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func newArchiveFileInfo(name string, size int64, mode fs.FileMode, modTime time.Time) *archiveFileInfo {
+	return &archiveFileInfo{path.Base(name), size, mode, modTime}
+}
+
+func (info *archiveFileInfo) Name() string       { return info.name }
+func (info *archiveFileInfo) Size() int64        { return info.size }
+func (info *archiveFileInfo) Mode() fs.FileMode  { return info.mode }
+func (info *archiveFileInfo) ModTime() time.Time { return info.modTime }
+func (info *archiveFileInfo) IsDir() bool        { return info.mode.IsDir() }
+func (info *archiveFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,190 @@
+package dir_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+// newArchiveFixture creates a directory of files, symbolic link and empty directory on disk, and returns the
+// directory along with its tar and zip archives.
+func newArchiveFixture(t *testing.T) (folder string, tarball, zipball []byte) {
+	folder = t.TempDir()
+	large := make([]byte, 3*core.DefaultSegmentSize+100)
+	_, err := rand.Read(large)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub", "deep"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "empty"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "a.txt"), []byte("hello"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "zero"), nil, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "sub", "large.bin"), large, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "sub", "deep", "b.txt"), []byte("world"), 0644))
+	assert.NoError(t, os.Symlink("../a.txt", filepath.Join(folder, "sub", "link")))
+
+	var tarBuf, zipBuf bytes.Buffer
+	tw, zw := tar.NewWriter(&tarBuf), zip.NewWriter(&zipBuf)
+
+	err = filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == folder {
+			return err
+		}
+
+		name, _ := filepath.Rel(folder, path)
+		name = filepath.ToSlash(name)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if d.Type()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if d.IsDir() {
+			// parent directories of files are not archived except the empty one
+			if name != "empty" {
+				return nil
+			}
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		zipHeader, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		zipHeader.Name, zipHeader.Method = header.Name, zip.Deflate
+		writer, err := zw.CreateHeader(zipHeader)
+		if err != nil {
+			return err
+		}
+
+		var content []byte
+		switch {
+		case len(link) > 0:
+			content = []byte(link)
+		case info.Mode().IsRegular():
+			if content, err = os.ReadFile(path); err != nil {
+				return err
+			}
+			if _, err := tw.Write(content); err != nil {
+				return err
+			}
+		}
+
+		_, err = writer.Write(content)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, zw.Close())
+
+	return folder, tarBuf.Bytes(), zipBuf.Bytes()
+}
+
+func TestBuildFileTreeFromArchive(t *testing.T) {
+	folder, tarball, zipball := newArchiveFixture(t)
+
+	expected, err := dir.BuildFileTree(folder)
+	assert.NoError(t, err)
+	expectedRoot, err := dir.ManifestRoot(expected)
+	assert.NoError(t, err)
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, err = gw.Write(tarball)
+	assert.NoError(t, err)
+	assert.NoError(t, gw.Close())
+
+	fromTar, err := dir.BuildFileTreeFromTar(bytes.NewReader(tarball))
+	assert.NoError(t, err)
+	fromTarGz, err := dir.BuildFileTreeFromTar(&gzipped)
+	assert.NoError(t, err)
+	fromZip, err := dir.BuildFileTreeFromZip(bytes.NewReader(zipball), int64(len(zipball)))
+	assert.NoError(t, err)
+	fromZipWorkers, err := dir.BuildFileTreeFromZipWithOption(bytes.NewReader(zipball), int64(len(zipball)), dir.ArchiveOption{
+		BuildOption: dir.BuildOption{}.WithWorkers(4),
+	})
+	assert.NoError(t, err)
+
+	for _, tree := range []*dir.FsNode{fromTar, fromTarGz, fromZip, fromZipWorkers} {
+		assert.Equal(t, expected, tree)
+
+		root, err := dir.ManifestRoot(tree)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedRoot, root)
+	}
+
+	// ignored as on disk
+	ignore, err := dir.ParseIgnorePatterns("sub/")
+	assert.NoError(t, err)
+	tree, err := dir.BuildFileTreeFromTarWithOption(bytes.NewReader(tarball), dir.ArchiveOption{
+		BuildOption: dir.BuildOption{}.WithIgnore(ignore),
+	})
+	assert.NoError(t, err)
+	_, found := tree.Search("sub")
+	assert.False(t, found)
+	assert.Len(t, tree.Entries, 3)
+}
+
+func TestBuildFileTreeFromTarUnsupported(t *testing.T) {
+	newTar := func(headers ...*tar.Header) io.Reader {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, header := range headers {
+			assert.NoError(t, tw.WriteHeader(header))
+			_, err := tw.Write(make([]byte, header.Size))
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, tw.Close())
+
+		return &buf
+	}
+
+	file := &tar.Header{Typeflag: tar.TypeReg, Name: "file", Size: 10, Mode: 0644}
+	hardlink := &tar.Header{Typeflag: tar.TypeLink, Name: "hardlink", Linkname: "file"}
+	fifo := &tar.Header{Typeflag: tar.TypeFifo, Name: "fifo"}
+
+	_, err := dir.BuildFileTreeFromTar(newTar(file, hardlink))
+	assert.ErrorIs(t, err, dir.ErrUnsupportedEntry)
+	assert.ErrorContains(t, err, "hardlink")
+
+	var skipped []string
+	tree, err := dir.BuildFileTreeFromTarWithOption(newTar(file, hardlink, fifo), dir.ArchiveOption{
+		SkipUnsupported:      true,
+		OnUnsupportedSkipped: func(name string, err error) { skipped = append(skipped, name) },
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hardlink", "fifo"}, skipped)
+	assert.Len(t, tree.Entries, 1)
+
+	// entries escaping the root are never skipped
+	for _, name := range []string{"../escape", "/etc/passwd", "sub/../../escape"} {
+		_, err = dir.BuildFileTreeFromTarWithOption(newTar(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: 1}), dir.ArchiveOption{
+			SkipUnsupported: true,
+		})
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,443 @@
+package dir
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// BinaryVersion is the version byte of compact binary encoding of FsNode, see EncodeBinary.
+const BinaryVersion = byte(1)
+
+// ErrTruncatedBinary is returned by DecodeBinary when the input ends before the file tree is completely decoded.
+var ErrTruncatedBinary = errors.New("truncated binary")
+
+// binary codes of file types
+const (
+	binaryTypeFile byte = iota
+	binaryTypeDirectory
+	binaryTypeSymbolic
+)
+
+// EncodeBinary encodes the FsNode tree in a compact, deterministic binary form, which is much smaller than the
+// JSON metadata, so that the tree itself could be stored as a file with a stable root. The binary form is
+// defined as below:
+//
+//   - A version byte, i.e. BinaryVersion, followed by nodes in depth-first pre-order.
+//   - Each node starts with a type byte, and then the name, escaped flag and name encoding.
+//   - Regular file is followed by the merkle root, size, embedded content, mode and modification time.
+//   - Symbolic link is followed by the link target.
+//   - Directory is followed by the mode, modification time and number of entries, which are sorted by name, and
+//     duplicate names are not allowed.
+//   - Strings and byte slices are prefixed with uvarint length, sizes and modes are uvarints, and modification
+//     times are varints. Merkle root is encoded as hex decoded bytes.
+//
+// Only fields relevant to the node type are encoded, so that semantically identical trees always produce the
+// byte-identical output.
+func (node *FsNode) EncodeBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := node.EncodeBinaryTo(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// EncodeBinaryTo encodes the FsNode tree as EncodeBinary, but writes to w node by node, so that very large trees
+// need not be buffered in memory.
+func (node *FsNode) EncodeBinaryTo(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := binaryEncoder{w: bw}
+
+	enc.writeByte(BinaryVersion)
+
+	// encode iteratively with an explicit stack, so that deep trees never overflow the call stack
+	stack := []*FsNode{node}
+	for len(stack) > 0 && enc.err == nil {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		entries, err := enc.writeNode(current)
+		if err != nil {
+			return err
+		}
+
+		// push in reverse order, so that entries are popped in order of name
+		for i := len(entries) - 1; i >= 0; i-- {
+			stack = append(stack, entries[i])
+		}
+	}
+
+	if enc.err != nil {
+		return errors.WithMessage(enc.err, "failed to write binary")
+	}
+
+	if err := bw.Flush(); err != nil {
+		return errors.WithMessage(err, "failed to write binary")
+	}
+
+	return nil
+}
+
+type binaryEncoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error // first write error
+}
+
+func (enc *binaryEncoder) writeByte(b byte) {
+	if enc.err == nil {
+		enc.err = enc.w.WriteByte(b)
+	}
+}
+
+func (enc *binaryEncoder) writeUvarint(v uint64) {
+	if enc.err == nil {
+		n := binary.PutUvarint(enc.buf[:], v)
+		_, enc.err = enc.w.Write(enc.buf[:n])
+	}
+}
+
+func (enc *binaryEncoder) writeVarint(v int64) {
+	if enc.err == nil {
+		n := binary.PutVarint(enc.buf[:], v)
+		_, enc.err = enc.w.Write(enc.buf[:n])
+	}
+}
+
+func (enc *binaryEncoder) writeBytes(b []byte) {
+	enc.writeUvarint(uint64(len(b)))
+	if enc.err == nil {
+		_, enc.err = enc.w.Write(b)
+	}
+}
+
+func (enc *binaryEncoder) writeString(s string) {
+	enc.writeUvarint(uint64(len(s)))
+	if enc.err == nil {
+		_, enc.err = enc.w.WriteString(s)
+	}
+}
+
+func (enc *binaryEncoder) writeBool(v bool) {
+	if v {
+		enc.writeByte(1)
+	} else {
+		enc.writeByte(0)
+	}
+}
+
+// writeNode writes the node without entries, and returns the entries sorted by name to write subsequently.
+func (enc *binaryEncoder) writeNode(node *FsNode) ([]*FsNode, error) {
+	if node == nil {
+		return nil, errors.New("nil node")
+	}
+
+	var code byte
+	switch node.Type {
+	case FileTypeFile:
+		code = binaryTypeFile
+	case FileTypeDirectory:
+		code = binaryTypeDirectory
+	case FileTypeSymbolic:
+		code = binaryTypeSymbolic
+	default:
+		return nil, errors.Errorf("unsupported file type %q", node.Type)
+	}
+
+	enc.writeByte(code)
+	enc.writeString(node.Name)
+	enc.writeBool(node.Escaped)
+	enc.writeString(string(node.NameEncoding))
+
+	switch node.Type {
+	case FileTypeFile:
+		var root []byte
+		if len(node.Root) > 0 {
+			var err error
+			if root, err = hexutil.Decode(node.Root); err != nil {
+				return nil, errors.WithMessagef(err, "invalid merkle root of file %q", node.Name)
+			}
+		}
+
+		if node.Size < 0 {
+			return nil, errors.Errorf("negative size of file %q", node.Name)
+		}
+
+		enc.writeBytes(root)
+		enc.writeUvarint(uint64(node.Size))
+		enc.writeBytes(node.Data)
+		enc.writeUvarint(uint64(node.Mode))
+		enc.writeVarint(node.ModTime)
+	case FileTypeSymbolic:
+		enc.writeString(node.Link)
+	case FileTypeDirectory:
+		entries := append([]*FsNode(nil), node.Entries...)
+		sortEntries(entries)
+		for i := 1; i < len(entries); i++ {
+			if entries[i].Name == entries[i-1].Name {
+				return nil, errors.Errorf("duplicate entry name %q", entries[i].Name)
+			}
+		}
+
+		enc.writeUvarint(uint64(node.Mode))
+		enc.writeVarint(node.ModTime)
+		enc.writeUvarint(uint64(len(entries)))
+
+		return entries, nil
+	}
+
+	return nil, nil
+}
+
+// DecodeBinary decodes the FsNode tree from the binary form encoded by EncodeBinary. It returns ErrTruncatedBinary
+// if the data ends unexpectedly, and an error if the version byte is unsupported or any trailing data remains.
+//
+// Note, the names and embedded content are not verified, use VerifyNames, VerifyEmbedded and VerifyLimits if
+// the data is untrusted.
+func DecodeBinary(data []byte) (*FsNode, error) {
+	reader := bytes.NewReader(data)
+
+	node, err := DecodeBinaryFrom(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if reader.Len() > 0 {
+		return nil, errors.Errorf("unexpected %v bytes after file tree", reader.Len())
+	}
+
+	return node, nil
+}
+
+// DecodeBinaryFrom decodes the FsNode tree as DecodeBinary, but reads from r node by node. Note, data buffered
+// from r beyond the file tree may be consumed.
+func DecodeBinaryFrom(r io.Reader) (*FsNode, error) {
+	byteReader, ok := r.(binaryReader)
+	if !ok {
+		byteReader = bufio.NewReader(r)
+	}
+	dec := binaryDecoder{r: byteReader}
+
+	version, err := dec.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if version != BinaryVersion {
+		return nil, errors.Errorf("unsupported binary version: got %d, expected %d", version, BinaryVersion)
+	}
+
+	// decode iteratively with an explicit stack of directories that have entries remaining
+	type pending struct {
+		node      *FsNode
+		remaining uint64
+	}
+
+	var root *FsNode
+	var stack []*pending
+	for root == nil || len(stack) > 0 {
+		node, numEntries, err := dec.readNode()
+		if err != nil {
+			return nil, err
+		}
+
+		if root == nil {
+			root = node
+		} else {
+			parent := stack[len(stack)-1]
+			if n := len(parent.node.Entries); n > 0 && parent.node.Entries[n-1].Name >= node.Name {
+				return nil, errors.Errorf("entries of directory %q not sorted by name or duplicated", parent.node.Name)
+			}
+			parent.node.Entries = append(parent.node.Entries, node)
+
+			if parent.remaining--; parent.remaining == 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		if numEntries > 0 {
+			stack = append(stack, &pending{node, numEntries})
+		}
+	}
+
+	return root, nil
+}
+
+type binaryReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+type binaryDecoder struct {
+	r binaryReader
+}
+
+// wrapReadError converts unexpected EOF to ErrTruncatedBinary.
+func wrapReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncatedBinary
+	}
+
+	return errors.WithMessage(err, "failed to read binary")
+}
+
+func (dec *binaryDecoder) readByte() (byte, error) {
+	b, err := dec.r.ReadByte()
+	if err != nil {
+		return 0, wrapReadError(err)
+	}
+
+	return b, nil
+}
+
+func (dec *binaryDecoder) readUvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(dec.r)
+	if err != nil {
+		return 0, wrapReadError(err)
+	}
+
+	return v, nil
+}
+
+func (dec *binaryDecoder) readVarint() (int64, error) {
+	v, err := binary.ReadVarint(dec.r)
+	if err != nil {
+		return 0, wrapReadError(err)
+	}
+
+	return v, nil
+}
+
+func (dec *binaryDecoder) readBytes() ([]byte, error) {
+	length, err := dec.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	if length == 0 {
+		return nil, nil
+	}
+
+	if length > math.MaxInt32 {
+		return nil, errors.Errorf("length %v too large", length)
+	}
+
+	// read incrementally instead of allocating by the untrusted length
+	var buf bytes.Buffer
+	if n, err := io.CopyN(&buf, dec.r, int64(length)); err != nil {
+		if n < int64(length) && err == io.EOF {
+			return nil, ErrTruncatedBinary
+		}
+		return nil, wrapReadError(err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (dec *binaryDecoder) readString() (string, error) {
+	b, err := dec.readBytes()
+	return string(b), err
+}
+
+// readNode reads the node without entries, and returns the number of entries if it is a directory.
+func (dec *binaryDecoder) readNode() (*FsNode, uint64, error) {
+	code, err := dec.readByte()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	node := FsNode{}
+	switch code {
+	case binaryTypeFile:
+		node.Type = FileTypeFile
+	case binaryTypeDirectory:
+		node.Type = FileTypeDirectory
+	case binaryTypeSymbolic:
+		node.Type = FileTypeSymbolic
+	default:
+		return nil, 0, errors.Errorf("unsupported file type code %d", code)
+	}
+
+	if node.Name, err = dec.readString(); err != nil {
+		return nil, 0, err
+	}
+
+	escaped, err := dec.readByte()
+	if err != nil {
+		return nil, 0, err
+	}
+	if escaped > 1 {
+		return nil, 0, errors.Errorf("invalid escaped flag %d", escaped)
+	}
+	node.Escaped = escaped == 1
+
+	encoding, err := dec.readString()
+	if err != nil {
+		return nil, 0, err
+	}
+	node.NameEncoding = NameEncoding(encoding)
+
+	var numEntries uint64
+
+	switch node.Type {
+	case FileTypeFile:
+		root, err := dec.readBytes()
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(root) > 0 {
+			node.Root = hexutil.Encode(root)
+		}
+
+		size, err := dec.readUvarint()
+		if err != nil {
+			return nil, 0, err
+		}
+		if size > math.MaxInt64 {
+			return nil, 0, errors.Errorf("size %v of file %q overflows", size, node.Name)
+		}
+		node.Size = int64(size)
+
+		if node.Data, err = dec.readBytes(); err != nil {
+			return nil, 0, err
+		}
+
+		if err = dec.readMetadata(&node); err != nil {
+			return nil, 0, err
+		}
+	case FileTypeSymbolic:
+		if node.Link, err = dec.readString(); err != nil {
+			return nil, 0, err
+		}
+	case FileTypeDirectory:
+		if err = dec.readMetadata(&node); err != nil {
+			return nil, 0, err
+		}
+
+		if numEntries, err = dec.readUvarint(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return &node, numEntries, nil
+}
+
+func (dec *binaryDecoder) readMetadata(node *FsNode) error {
+	mode, err := dec.readUvarint()
+	if err != nil {
+		return err
+	}
+	if mode > math.MaxUint32 {
+		return errors.Errorf("mode %v of %q overflows", mode, node.Name)
+	}
+	node.Mode = uint32(mode)
+
+	node.ModTime, err = dec.readVarint()
+
+	return err
+}
@@ -0,0 +1,143 @@
+package dir_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	root1 := "0x" + strings.Repeat("ab", 32)
+	root2 := "0x" + strings.Repeat("cd", 32)
+
+	tree := &dir.FsNode{
+		Name:         "root",
+		Type:         dir.FileTypeDirectory,
+		Mode:         0755,
+		ModTime:      1700000000000000000,
+		NameEncoding: dir.NameEncodingPercent,
+		Entries: []*dir.FsNode{
+			{Name: "a.txt", Type: dir.FileTypeFile, Root: root1, Size: 1024, Mode: 0644, ModTime: -1},
+			{Name: "b%FF", Type: dir.FileTypeFile, Root: root2, Size: 5, Data: []byte("hello"), Escaped: true},
+			{Name: "empty", Type: dir.FileTypeDirectory},
+			{Name: "link", Type: dir.FileTypeSymbolic, Link: "../outside/target"},
+			{Name: "sub", Type: dir.FileTypeDirectory, Entries: []*dir.FsNode{
+				{Name: "c.txt", Type: dir.FileTypeFile, Root: root2},
+			}},
+		},
+	}
+
+	data, err := tree.EncodeBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, dir.BinaryVersion, data[0])
+
+	decoded, err := dir.DecodeBinary(data)
+	assert.NoError(t, err)
+	assert.Equal(t, tree, decoded)
+
+	// much smaller than JSON metadata
+	legacy, err := tree.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Less(t, len(data), len(legacy)/2)
+
+	// streamed
+	var buf bytes.Buffer
+	assert.NoError(t, tree.EncodeBinaryTo(&buf))
+	assert.Equal(t, data, buf.Bytes())
+	decoded, err = dir.DecodeBinaryFrom(struct{ *bytes.Buffer }{&buf})
+	assert.NoError(t, err)
+	assert.Equal(t, tree, decoded)
+}
+
+func TestBinaryDeterministic(t *testing.T) {
+	root := "0x" + strings.Repeat("ab", 32)
+
+	tree1 := dir.NewDirFsNode("root", []*dir.FsNode{
+		dir.NewFileFsNode("a.txt", common.HexToHash(root), 1024),
+		dir.NewSymbolicFsNode("link", "a.txt"),
+	})
+
+	// unsorted entries and irrelevant fields
+	tree2 := &dir.FsNode{
+		Name: "root",
+		Type: dir.FileTypeDirectory,
+		Size: 100,
+		Entries: []*dir.FsNode{
+			{Name: "link", Type: dir.FileTypeSymbolic, Link: "a.txt", Root: root, Mode: 0777},
+			{Name: "a.txt", Type: dir.FileTypeFile, Root: "0x" + strings.ToUpper(root[2:]), Size: 1024, Link: "x"},
+		},
+	}
+
+	data1, err := tree1.EncodeBinary()
+	assert.NoError(t, err)
+	data2, err := tree2.EncodeBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, data1, data2)
+
+	// duplicate entry names
+	tree2.Entries = append(tree2.Entries, dir.NewSymbolicFsNode("a.txt", "b.txt"))
+	_, err = tree2.EncodeBinary()
+	assert.Error(t, err)
+
+	// invalid root
+	tree1.Entries[0].Root = "not hex"
+	_, err = tree1.EncodeBinary()
+	assert.Error(t, err)
+}
+
+func TestBinaryDeepNesting(t *testing.T) {
+	// deeper than the default limit, which never overflows the call stack
+	tree := dir.NewDirFsNode("root", nil)
+	current := tree
+	for i := 0; i < 100_000; i++ {
+		child := dir.NewDirFsNode(fmt.Sprintf("d%v", i), nil)
+		current.Entries = []*dir.FsNode{child, dir.NewSymbolicFsNode(fmt.Sprintf("s%v", i), "..")}
+		current = child
+	}
+
+	data, err := tree.EncodeBinary()
+	assert.NoError(t, err)
+
+	decoded, err := dir.DecodeBinary(data)
+	assert.NoError(t, err)
+	assert.True(t, tree.Equal(decoded))
+}
+
+func TestBinaryDecodeInvalid(t *testing.T) {
+	tree := dir.NewDirFsNode("root", []*dir.FsNode{
+		dir.NewFileFsNode("a.txt", common.Hash{1}, 1024),
+		dir.NewDirFsNode("sub", []*dir.FsNode{dir.NewSymbolicFsNode("link", "../a.txt")}),
+	})
+	data, err := tree.EncodeBinary()
+	assert.NoError(t, err)
+
+	// truncated at any position
+	for i := 0; i < len(data); i++ {
+		_, err = dir.DecodeBinary(data[:i])
+		assert.ErrorIs(t, err, dir.ErrTruncatedBinary, "truncated at %v", i)
+	}
+
+	// unsupported version
+	corrupted := append([]byte{dir.BinaryVersion + 1}, data[1:]...)
+	_, err = dir.DecodeBinary(corrupted)
+	assert.ErrorContains(t, err, "unsupported binary version")
+
+	// trailing data
+	_, err = dir.DecodeBinary(append(data, 0))
+	assert.ErrorContains(t, err, "unexpected 1 bytes")
+
+	// unsupported file type
+	corrupted = append([]byte(nil), data...)
+	corrupted[1] = 0xFF
+	_, err = dir.DecodeBinary(corrupted)
+	assert.ErrorContains(t, err, "unsupported file type")
+
+	// huge length of name never allocated upfront
+	_, err = dir.DecodeBinary([]byte{dir.BinaryVersion, 0, 0xFF, 0xFF, 0xFF, 0xFF, 0x07, 'a'})
+	assert.ErrorIs(t, err, dir.ErrTruncatedBinary)
+}
@@ -0,0 +1,749 @@
+package dir
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// Multicodec and multihash codes used in CAR format.
+const (
+	cidVersion1     = 0x01
+	codecRaw        = 0x55   // raw binary for file data
+	codecDagPb      = 0x70   // dag-pb for UnixFS directory, file and symbolic link nodes
+	codecDagJSON    = 0x0129 // DAG-JSON for 0g metadata
+	multihashSha256 = 0x12
+
+	// carBlockSize is the max size of raw data block exported in CAR.
+	carBlockSize = core.DefaultSegmentSize
+
+	// carMaxBlockSize is the max size of block allowed to import from CAR.
+	carMaxBlockSize = 4 * 1024 * 1024
+)
+
+// carMaxLinks is the max number of children linked by a UnixFS file node, so that large files are
+// built as a balanced tree as IPFS does.
+const carMaxLinks = 174
+
+// Node types of UnixFS nodes in CAR.
+const (
+	carNodeDirectory = "directory"
+	carNodeFile      = "file"
+	carNodeSymlink   = "symlink"
+)
+
+var cidEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Cid is a binary encoded CIDv1 with sha2-256 multihash.
+type Cid []byte
+
+func newCid(codec uint64, data []byte) Cid {
+	digest := sha256.Sum256(data)
+
+	cid := binary.AppendUvarint(nil, cidVersion1)
+	cid = binary.AppendUvarint(cid, codec)
+	cid = binary.AppendUvarint(cid, multihashSha256)
+	cid = binary.AppendUvarint(cid, uint64(len(digest)))
+	return append(cid, digest[:]...)
+}
+
+// ParseCid parses a CIDv1 from the multibase encoded string in base32.
+func ParseCid(s string) (Cid, error) {
+	if !strings.HasPrefix(s, "b") {
+		return nil, errors.New("unsupported multibase prefix")
+	}
+
+	cid, err := cidEncoding.DecodeString(strings.ToUpper(s[1:]))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decode base32")
+	}
+
+	return parseCidBytes(cid)
+}
+
+// String returns the multibase encoded CID in base32.
+func (cid Cid) String() string {
+	return "b" + strings.ToLower(cidEncoding.EncodeToString(cid))
+}
+
+func (cid Cid) codec() uint64 {
+	// skip version
+	_, n := binary.Uvarint(cid)
+	codec, _ := binary.Uvarint(cid[n:])
+	return codec
+}
+
+// verify checks whether the CID matches the block data.
+func (cid Cid) verify(data []byte) bool {
+	return bytes.Equal(cid, newCid(cid.codec(), data))
+}
+
+// readCid reads a binary CIDv1 with sha2-256 multihash.
+func readCid(r *bufio.Reader) (Cid, error) {
+	var cid []byte
+
+	for i, expected := range []uint64{cidVersion1, 0, multihashSha256, sha256.Size} {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to read CID")
+		}
+
+		// codec is not validated here
+		if i != 1 && v != expected {
+			return nil, errors.Errorf("unsupported CID, expected %v, got %v", expected, v)
+		}
+
+		cid = binary.AppendUvarint(cid, v)
+	}
+
+	digest := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(r, digest); err != nil {
+		return nil, errors.WithMessage(err, "failed to read CID digest")
+	}
+
+	return append(cid, digest...), nil
+}
+
+// parseCidBytes parses a binary CIDv1, which should not be followed by any other data.
+func parseCidBytes(data []byte) (Cid, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+
+	cid, err := readCid(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Buffered() > 0 {
+		return nil, errors.New("unexpected data after CID")
+	}
+
+	return cid, nil
+}
+
+// CarMapping is the mapping between the 0g merkle root and generated CID of a file, directory or symbolic link.
+type CarMapping struct {
+	Path   string `json:"path"`             // slash-separated relative path to the root directory
+	Type   string `json:"type"`             // node type
+	ZgRoot string `json:"zgRoot,omitempty"` // 0g merkle root, only available for files
+	Cid    string `json:"cid"`              // CID in CAR
+}
+
+// CarExportOption is the option to export directory in CAR format.
+type CarExportOption struct {
+	// Downloader is used to download file data to be exported as raw blocks. If not specified, only the
+	// directory structure is exported, with 0g merkle roots of files recorded as metadata, and files without
+	// data could not be read by IPFS tools.
+	Downloader Downloader
+	// WithProof indicates whether to download file data with merkle proof validation.
+	WithProof bool
+}
+
+// carMetadata is the 0g metadata exported in DAG-JSON along with the UnixFS DAG, which records the original
+// 0g merkle roots of files by relative path. Note, fields are ordered by name to conform to the canonical
+// DAG-JSON encoding.
+type carMetadata struct {
+	Root    map[string]string `json:"root"`    // UnixFS root in DAG-JSON link form {"/": cid}
+	ZgRoots map[string]string `json:"zgRoots"` // 0g merkle roots of files by relative path
+}
+
+// carBlock is a block to write in CAR. Data is either in memory or read from a file.
+type carBlock struct {
+	cid Cid
+
+	data []byte
+
+	file   string
+	offset int64
+	size   int64
+}
+
+// carEntry is an exported node, with the cumulative size of its blocks to link to it.
+type carEntry struct {
+	cid      Cid
+	tsize    uint64 // size of the block and all its descendants
+	fileSize uint64 // size of file data within the node
+}
+
+// carExporter builds blocks of CAR from FsNode tree.
+type carExporter struct {
+	opt      CarExportOption
+	tmpDir   string
+	blocks   []*carBlock
+	mappings []CarMapping
+	zgRoots  map[string]string
+}
+
+// ExportCAR exports the directory tree in CARv1 format with UnixFS nodes in dag-pb and file data in raw blocks,
+// which preserves names, sizes and symbolic links, so that the CAR could be imported by IPFS tools. Besides,
+// the original 0g merkle roots of files are recorded in a DAG-JSON metadata block after the UnixFS DAG. Since
+// the hashing differs between 0g and IPLD, the mapping between 0g roots and generated CIDs is returned.
+func ExportCAR(ctx context.Context, w io.Writer, root *FsNode, option ...CarExportOption) ([]CarMapping, error) {
+	exporter := carExporter{zgRoots: make(map[string]string)}
+	if len(option) > 0 {
+		exporter.opt = option[0]
+	}
+
+	if exporter.opt.Downloader != nil {
+		tmpDir, err := os.MkdirTemp("", "zgcar-")
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to create temp directory")
+		}
+		defer os.RemoveAll(tmpDir)
+		exporter.tmpDir = tmpDir
+	}
+
+	if root.Type != FileTypeDirectory {
+		return nil, errors.New("root is not a directory")
+	}
+
+	rootEntry, err := exporter.export(ctx, root, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := json.Marshal(carMetadata{
+		Root:    map[string]string{"/": rootEntry.cid.String()},
+		ZgRoots: exporter.zgRoots,
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to marshal metadata")
+	}
+	exporter.addBlock(codecDagJSON, metadata)
+
+	bw := bufio.NewWriter(w)
+
+	if err := writeCarHeader(bw, rootEntry.cid); err != nil {
+		return nil, errors.WithMessage(err, "failed to write CAR header")
+	}
+
+	for _, block := range exporter.blocks {
+		if err := exporter.writeBlock(bw, block); err != nil {
+			return nil, errors.WithMessagef(err, "failed to write block %v", block.cid)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return nil, errors.WithMessage(err, "failed to flush CAR")
+	}
+
+	return exporter.mappings, nil
+}
+
+func (exporter *carExporter) addBlock(codec uint64, data []byte) carEntry {
+	cid := newCid(codec, data)
+	exporter.blocks = append(exporter.blocks, &carBlock{cid: cid, data: data})
+	return carEntry{cid: cid, tsize: uint64(len(data))}
+}
+
+// export exports node recursively in post order, and returns the exported entry of node.
+func (exporter *carExporter) export(ctx context.Context, node *FsNode, relpath string) (carEntry, error) {
+	var entry carEntry
+	var nodeType string
+
+	switch node.Type {
+	case FileTypeDirectory:
+		var links []pbLink
+		var tsize uint64
+		for _, child := range node.Entries {
+			childEntry, err := exporter.export(ctx, child, path.Join(relpath, child.Name))
+			if err != nil {
+				return carEntry{}, err
+			}
+			links = append(links, pbLink{Hash: childEntry.cid, Name: child.Name, Tsize: childEntry.tsize})
+			tsize += childEntry.tsize
+		}
+
+		unixfs := unixfsData{Type: unixfsDirectory}
+		entry = exporter.addBlock(codecDagPb, encodeDagPb(links, unixfs.encode()))
+		entry.tsize += tsize
+		nodeType = carNodeDirectory
+	case FileTypeFile:
+		var leaves []carEntry
+		if node.Embedded() {
+			leaves = exporter.exportEmbeddedData(node)
+		} else if exporter.opt.Downloader != nil && node.Size > 0 {
+			var err error
+			if leaves, err = exporter.exportFileData(ctx, node); err != nil {
+				return carEntry{}, errors.WithMessagef(err, "failed to export data of file %v", relpath)
+			}
+		}
+
+		if len(leaves) == 0 {
+			// empty file, or file data not exported
+			unixfs := unixfsData{Type: unixfsFile, FileSize: uint64(node.Size)}
+			entry = exporter.addBlock(codecDagPb, encodeDagPb(nil, unixfs.encode()))
+			entry.fileSize = uint64(node.Size)
+		} else {
+			entry = exporter.exportFileTree(leaves)
+		}
+
+		exporter.zgRoots[relpath] = node.Root
+		nodeType = carNodeFile
+	case FileTypeSymbolic:
+		unixfs := unixfsData{Type: unixfsSymlink, Data: []byte(node.Link)}
+		entry = exporter.addBlock(codecDagPb, encodeDagPb(nil, unixfs.encode()))
+		nodeType = carNodeSymlink
+	default:
+		return carEntry{}, errors.Errorf("unsupported file type %v", node.Type)
+	}
+
+	exporter.mappings = append(exporter.mappings, CarMapping{
+		Path:   relpath,
+		Type:   nodeType,
+		ZgRoot: exporter.zgRoots[relpath],
+		Cid:    entry.cid.String(),
+	})
+
+	return entry, nil
+}
+
+// exportFileTree links the raw data blocks of file in a balanced tree of UnixFS file nodes, and returns the
+// root. File of a single data block is linked to the raw block directly.
+func (exporter *carExporter) exportFileTree(leaves []carEntry) carEntry {
+	for len(leaves) > 1 {
+		var parents []carEntry
+
+		for start := 0; start < len(leaves); start += carMaxLinks {
+			children := leaves[start:min(start+carMaxLinks, len(leaves))]
+
+			var links []pbLink
+			var parent carEntry
+			unixfs := unixfsData{Type: unixfsFile}
+			for _, child := range children {
+				links = append(links, pbLink{Hash: child.cid, Tsize: child.tsize})
+				unixfs.BlockSizes = append(unixfs.BlockSizes, child.fileSize)
+				unixfs.FileSize += child.fileSize
+				parent.tsize += child.tsize
+			}
+
+			block := exporter.addBlock(codecDagPb, encodeDagPb(links, unixfs.encode()))
+			parent.cid = block.cid
+			parent.tsize += block.tsize
+			parent.fileSize = unixfs.FileSize
+			parents = append(parents, parent)
+		}
+
+		leaves = parents
+	}
+
+	return leaves[0]
+}
+
+// exportFileData downloads file data into temp directory, and splits it into raw blocks.
+func (exporter *carExporter) exportFileData(ctx context.Context, node *FsNode) ([]carEntry, error) {
+	filename := filepath.Join(exporter.tmpDir, node.Root)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		if err := exporter.opt.Downloader.Download(ctx, node.Root, filename, exporter.opt.WithProof); err != nil {
+			return nil, errors.WithMessage(err, "failed to download file")
+		}
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to open downloaded file")
+	}
+	defer file.Close()
+
+	var leaves []carEntry
+	buf := make([]byte, carBlockSize)
+	for offset := int64(0); offset < node.Size; offset += carBlockSize {
+		n, err := io.ReadFull(file, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, errors.WithMessage(err, "failed to read downloaded file")
+		}
+
+		cid := newCid(codecRaw, buf[:n])
+		exporter.blocks = append(exporter.blocks, &carBlock{cid: cid, file: filename, offset: offset, size: int64(n)})
+		leaves = append(leaves, carEntry{cid: cid, tsize: uint64(n), fileSize: uint64(n)})
+	}
+
+	return leaves, nil
+}
+
+// exportEmbeddedData splits the embedded file content into raw blocks, which requires no download.
+func (exporter *carExporter) exportEmbeddedData(node *FsNode) []carEntry {
+	var leaves []carEntry
+	for offset := int64(0); offset < node.Size; offset += carBlockSize {
+		leaf := exporter.addBlock(codecRaw, node.Data[offset:min(offset+carBlockSize, node.Size)])
+		leaf.fileSize = leaf.tsize
+		leaves = append(leaves, leaf)
+	}
+
+	return leaves
+}
+
+func (exporter *carExporter) writeBlock(w io.Writer, block *carBlock) error {
+	data := block.data
+	if data == nil {
+		file, err := os.Open(block.file)
+		if err != nil {
+			return errors.WithMessage(err, "failed to open file")
+		}
+		defer file.Close()
+
+		data = make([]byte, block.size)
+		if _, err := file.ReadAt(data, block.offset); err != nil {
+			return errors.WithMessage(err, "failed to read file")
+		}
+	}
+
+	return writeCarSection(w, block.cid, data)
+}
+
+// writeCarHeader writes CARv1 header in DAG-CBOR: {"roots": [cid], "version": 1}
+func writeCarHeader(w io.Writer, root Cid) error {
+	var header bytes.Buffer
+
+	writeCborHead(&header, 5, 2) // map(2)
+	writeCborHead(&header, 3, 5)
+	header.WriteString("roots")
+	writeCborHead(&header, 4, 1)  // array(1)
+	writeCborHead(&header, 6, 42) // tag(42) for CID
+	writeCborHead(&header, 2, uint64(len(root)+1))
+	header.WriteByte(0x00) // multibase identity prefix
+	header.Write(root)
+	writeCborHead(&header, 3, 7)
+	header.WriteString("version")
+	writeCborHead(&header, 0, 1)
+
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(header.Len()))); err != nil {
+		return err
+	}
+
+	_, err := w.Write(header.Bytes())
+	return err
+}
+
+func writeCarSection(w io.Writer, cid Cid, data []byte) error {
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(cid)+len(data)))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(cid); err != nil {
+		return err
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+// readCarHeader reads CARv1 header and returns the only root.
+func readCarHeader(r *bufio.Reader) (Cid, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read header length")
+	}
+
+	if size > carMaxBlockSize {
+		return nil, errors.New("header too large")
+	}
+
+	header := make([]byte, size)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.WithMessage(err, "failed to read header")
+	}
+
+	var root Cid
+	var version uint64
+
+	hr := bytes.NewReader(header)
+	entries, err := readCborHead(hr, 5)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i < entries; i++ {
+		key, err := readCborText(hr)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case "roots":
+			if n, err := readCborHead(hr, 4); err != nil || n != 1 {
+				return nil, errors.New("exactly one root is required")
+			}
+			if tag, err := readCborHead(hr, 6); err != nil || tag != 42 {
+				return nil, errors.New("invalid CID tag")
+			}
+			n, err := readCborHead(hr, 2)
+			if err != nil || n < 1 || n > uint64(hr.Len()) {
+				return nil, errors.New("invalid CID bytes")
+			}
+			buf := make([]byte, n)
+			hr.Read(buf)
+			if root, err = parseCidBytes(buf[1:]); err != nil {
+				return nil, err
+			}
+		case "version":
+			if version, err = readCborHead(hr, 0); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.Errorf("unsupported header field %v", key)
+		}
+	}
+
+	if version != 1 {
+		return nil, errors.Errorf("unsupported CAR version %v", version)
+	}
+
+	if root == nil {
+		return nil, errors.New("root not found")
+	}
+
+	return root, nil
+}
+
+// CarImportOption is the option to import directory from CAR.
+type CarImportOption struct {
+	// Folder is the local folder to write the file data available in CAR, at the same relative paths as in the
+	// imported tree, so that files not stored on the 0g storage network yet could be uploaded from the folder,
+	// e.g. by Uploader.UploadDirPatch. If not specified, file data is only used to compute the 0g merkle roots.
+	Folder string
+}
+
+// ImportCAR builds the directory tree from a CARv1 stream of UnixFS DAG, e.g. exported by ExportCAR, and returns
+// the mapping between the 0g merkle roots and CIDs. If file data is available in CAR, the 0g merkle root of file
+// is computed from data, and must match the one recorded in 0g metadata if any. Otherwise, the recorded 0g merkle
+// root is used.
+//
+// Note, all blocks are verified against their CIDs and held in memory.
+func ImportCAR(r io.Reader, option ...CarImportOption) (*FsNode, []CarMapping, error) {
+	br := bufio.NewReader(r)
+
+	root, err := readCarHeader(br)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to read CAR header")
+	}
+
+	importer := carImporter{blocks: make(map[string][]byte)}
+	if len(option) > 0 {
+		importer.opt = option[0]
+	}
+
+	for {
+		size, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "failed to read section length")
+		}
+
+		if size > carMaxBlockSize {
+			return nil, nil, errors.New("block too large")
+		}
+
+		cid, err := readCid(br)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if uint64(len(cid)) > size {
+			return nil, nil, errors.New("invalid section length")
+		}
+
+		data := make([]byte, size-uint64(len(cid)))
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, nil, errors.WithMessage(err, "failed to read block data")
+		}
+
+		if !cid.verify(data) {
+			return nil, nil, errors.Errorf("block data mismatch with CID %v", cid)
+		}
+
+		// 0g metadata of the root
+		var metadata carMetadata
+		if cid.codec() == codecDagJSON && json.Unmarshal(data, &metadata) == nil && metadata.Root["/"] == root.String() {
+			importer.zgRoots = metadata.ZgRoots
+		}
+
+		importer.blocks[string(cid)] = data
+	}
+
+	node, err := importer.build(root, "/", ".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if node.Type != FileTypeDirectory {
+		return nil, nil, errors.New("root is not a directory")
+	}
+
+	return node, importer.mappings, nil
+}
+
+type carImporter struct {
+	opt      CarImportOption
+	blocks   map[string][]byte
+	zgRoots  map[string]string
+	mappings []CarMapping
+}
+
+func (importer *carImporter) build(cid Cid, name, relpath string) (*FsNode, error) {
+	data, ok := importer.blocks[string(cid)]
+	if !ok {
+		return nil, errors.Errorf("block %v of %v not found", cid, relpath)
+	}
+
+	var links []pbLink
+	var unixfs *unixfsData
+	var err error
+
+	switch cid.codec() {
+	case codecRaw:
+		// file of a single data block
+		unixfs = &unixfsData{Type: unixfsRaw, FileSize: uint64(len(data))}
+	case codecDagPb:
+		if links, unixfs, err = decodeDagPb(data); err != nil {
+			return nil, errors.WithMessagef(err, "failed to decode node %v", relpath)
+		}
+	default:
+		return nil, errors.Errorf("unexpected codec of node %v", relpath)
+	}
+
+	var node *FsNode
+	var nodeType string
+
+	switch unixfs.Type {
+	case unixfsDirectory:
+		var entries []*FsNode
+		for _, link := range links {
+			if len(link.Name) == 0 || link.Name == "." || link.Name == ".." || strings.Contains(link.Name, "/") {
+				return nil, errors.Errorf("invalid entry name %q in %v", link.Name, relpath)
+			}
+
+			entry, err := importer.build(link.Hash, link.Name, path.Join(relpath, link.Name))
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+		node = NewDirFsNode(name, entries)
+		nodeType = carNodeDirectory
+	case unixfsFile, unixfsRaw:
+		root, err := importer.importFile(cid, relpath, links, unixfs)
+		if err != nil {
+			return nil, err
+		}
+		node = NewFileFsNode(name, root, int64(unixfs.FileSize))
+		nodeType = carNodeFile
+	case unixfsSymlink:
+		node = NewSymbolicFsNode(name, string(unixfs.Data))
+		nodeType = carNodeSymlink
+	default:
+		return nil, errors.Errorf("unsupported UnixFS type %v of %v", unixfs.Type, relpath)
+	}
+
+	importer.mappings = append(importer.mappings, CarMapping{
+		Path:   relpath,
+		Type:   nodeType,
+		ZgRoot: node.Root,
+		Cid:    cid.String(),
+	})
+
+	return node, nil
+}
+
+// importFile returns the 0g merkle root of file node, which is computed from data blocks if available, and
+// writes the file data into folder if specified.
+func (importer *carImporter) importFile(cid Cid, relpath string, links []pbLink, unixfs *unixfsData) (common.Hash, error) {
+	recorded, hasRecorded := importer.zgRoots[relpath]
+
+	// file data not exported
+	if len(links) == 0 && len(unixfs.Data) == 0 && unixfs.FileSize > 0 && cid.codec() == codecDagPb {
+		if !hasRecorded {
+			return common.Hash{}, errors.Errorf("neither data nor 0g root available for file %v", relpath)
+		}
+		return common.HexToHash(recorded), nil
+	}
+
+	content, err := importer.appendFileData(nil, cid, relpath)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if importer.opt.Folder != "" {
+		filename := filepath.Join(importer.opt.Folder, filepath.FromSlash(relpath))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return common.Hash{}, errors.WithMessagef(err, "failed to create folder of file %v", relpath)
+		}
+		if err := os.WriteFile(filename, content, 0644); err != nil {
+			return common.Hash{}, errors.WithMessagef(err, "failed to write file %v", relpath)
+		}
+	}
+
+	// empty file could not be stored on 0g storage network
+	if len(content) == 0 {
+		return common.HexToHash(recorded), nil
+	}
+
+	data, err := core.NewDataInMemory(content)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	root, err := core.MerkleRootData(data)
+	if err != nil {
+		return common.Hash{}, errors.WithMessagef(err, "failed to calculate merkle root of file %v", relpath)
+	}
+
+	if hasRecorded && common.HexToHash(recorded) != root {
+		return common.Hash{}, errors.Errorf("0g root mismatch of file %v, recorded %v, computed %v", relpath, recorded, root)
+	}
+
+	return root, nil
+}
+
+// appendFileData appends the data of UnixFS file node recursively, and checks the size of each node.
+func (importer *carImporter) appendFileData(content []byte, cid Cid, relpath string) ([]byte, error) {
+	data, ok := importer.blocks[string(cid)]
+	if !ok {
+		return nil, errors.Errorf("data block %v of file %v not found", cid, relpath)
+	}
+
+	switch cid.codec() {
+	case codecRaw:
+		return append(content, data...), nil
+	case codecDagPb:
+	default:
+		return nil, errors.Errorf("unexpected codec of data block %v of file %v", cid, relpath)
+	}
+
+	links, unixfs, err := decodeDagPb(data)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to decode data block %v of file %v", cid, relpath)
+	}
+
+	if unixfs.Type != unixfsFile && unixfs.Type != unixfsRaw {
+		return nil, errors.Errorf("unexpected UnixFS type %v of data block in file %v", unixfs.Type, relpath)
+	}
+
+	start := len(content)
+	content = append(content, unixfs.Data...)
+	for _, link := range links {
+		if content, err = importer.appendFileData(content, link.Hash, relpath); err != nil {
+			return nil, err
+		}
+	}
+
+	if size := uint64(len(content) - start); size != unixfs.FileSize {
+		return nil, errors.Errorf("size mismatch of file %v, expected %v, got %v", relpath, unixfs.FileSize, size)
+	}
+
+	return content, nil
+}
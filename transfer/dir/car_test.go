@@ -0,0 +1,131 @@
+package dir_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCarFixture(t *testing.T, downloader *memDownloader) *dir.FsNode {
+	large := bytes.Repeat([]byte("0123456789abcdef"), 40000) // spans 3 blocks
+
+	largeRoot := downloader.add(t, large)
+
+	return dir.NewDirFsNode("/", []*dir.FsNode{
+		newFileNode(t, downloader, "index.html", "<html></html>"),
+		dir.NewDirFsNode("assets", []*dir.FsNode{
+			newFileNode(t, downloader, "app.js", "console.log('hello')"),
+			{Name: "large.bin", Type: dir.FileTypeFile, Root: largeRoot, Size: int64(len(large))},
+		}),
+		dir.NewDirFsNode("empty", []*dir.FsNode{}),
+		dir.NewFileFsNode("empty.txt", [32]byte{}, 0),
+		dir.NewSymbolicFsNode("home.html", "index.html"),
+	})
+}
+
+func TestCARRoundTrip(t *testing.T) {
+	downloader := newMemDownloader()
+	tree := newCarFixture(t, downloader)
+
+	for _, tc := range []struct {
+		name     string
+		option   dir.CarExportOption
+		download int
+	}{
+		{"metadata only", dir.CarExportOption{}, 0},
+		{"with data", dir.CarExportOption{Downloader: downloader}, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			downloads := downloader.downloads
+
+			var buf bytes.Buffer
+			exported, err := dir.ExportCAR(context.Background(), &buf, tree, tc.option)
+			assert.NoError(t, err)
+			assert.Equal(t, downloads+tc.download, downloader.downloads)
+
+			folder := t.TempDir()
+			imported, mappings, err := dir.ImportCAR(&buf, dir.CarImportOption{Folder: folder})
+			assert.NoError(t, err)
+			assert.True(t, tree.Equal(imported))
+
+			// file data written into folder if available
+			for relpath, content := range map[string]string{"index.html": "<html></html>", "assets/app.js": "console.log('hello')", "empty.txt": ""} {
+				data, err := os.ReadFile(filepath.Join(folder, relpath))
+				if tc.download == 0 && len(content) > 0 {
+					assert.True(t, os.IsNotExist(err), relpath)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, content, string(data))
+				}
+			}
+
+			// both directions report the same mapping between 0g roots and CIDs
+			assert.ElementsMatch(t, exported, mappings)
+
+			byPath := make(map[string]dir.CarMapping)
+			for _, m := range mappings {
+				byPath[m.Path] = m
+			}
+			assert.Len(t, byPath, 8)
+			assert.Equal(t, "directory", byPath["."].Type)
+			assert.Equal(t, "symlink", byPath["home.html"].Type)
+			assert.Equal(t, tree.Entries[0].Entries[1].Root, byPath["assets/large.bin"].ZgRoot)
+
+			cid, err := dir.ParseCid(byPath["assets/large.bin"].Cid)
+			assert.NoError(t, err)
+			assert.Equal(t, byPath["assets/large.bin"].Cid, cid.String())
+		})
+	}
+}
+
+func TestCARImportTampered(t *testing.T) {
+	downloader := newMemDownloader()
+	tree := newCarFixture(t, downloader)
+
+	var buf bytes.Buffer
+	_, err := dir.ExportCAR(context.Background(), &buf, tree, dir.CarExportOption{Downloader: downloader})
+	assert.NoError(t, err)
+
+	data := buf.Bytes()
+	idx := bytes.Index(data, []byte("<html></html>"))
+	assert.Greater(t, idx, 0)
+	data[idx] = '['
+
+	_, _, err = dir.ImportCAR(bytes.NewReader(data))
+	assert.ErrorContains(t, err, "mismatch")
+}
+
+func TestCARLargeFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip large CAR in short mode")
+	}
+
+	// more blocks than linked by a single UnixFS node, so that file built in multiple levels
+	content := make([]byte, 200*256*1024+1)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	downloader := newMemDownloader()
+	root := downloader.add(t, content)
+	tree := dir.NewDirFsNode("/", []*dir.FsNode{{Name: "large.bin", Type: dir.FileTypeFile, Root: root, Size: int64(len(content))}})
+
+	var buf bytes.Buffer
+	exported, err := dir.ExportCAR(context.Background(), &buf, tree, dir.CarExportOption{Downloader: downloader})
+	assert.NoError(t, err)
+
+	folder := t.TempDir()
+	imported, mappings, err := dir.ImportCAR(&buf, dir.CarImportOption{Folder: folder})
+	assert.NoError(t, err)
+	assert.True(t, tree.Equal(imported))
+	assert.ElementsMatch(t, exported, mappings)
+
+	data, err := os.ReadFile(filepath.Join(folder, "large.bin"))
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(content, data))
+}
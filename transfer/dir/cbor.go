@@ -0,0 +1,77 @@
+package dir
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// writeCborHead writes the CBOR head of major type with argument in the shortest form.
+func writeCborHead(buf *bytes.Buffer, major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(major<<5 | byte(arg))
+	case arg <= 0xff:
+		buf.Write([]byte{major<<5 | 24, byte(arg)})
+	case arg <= 0xffff:
+		buf.WriteByte(major<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(arg)))
+	case arg <= 0xffffffff:
+		buf.WriteByte(major<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(arg)))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, arg))
+	}
+}
+
+// readCborHead reads the CBOR head of expected major type and returns the argument.
+func readCborHead(r *bytes.Reader, major byte) (uint64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to read CBOR")
+	}
+
+	if b>>5 != major {
+		return 0, errors.Errorf("unexpected CBOR major type %v, expected %v", b>>5, major)
+	}
+
+	info := b & 0x1f
+	if info < 24 {
+		return uint64(info), nil
+	}
+
+	if info > 27 {
+		return 0, errors.New("unsupported CBOR argument")
+	}
+
+	// 1, 2, 4 or 8 bytes argument in big endian
+	buf := make([]byte, 1<<(info-24))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, errors.WithMessage(err, "failed to read CBOR argument")
+	}
+
+	var arg uint64
+	for _, v := range buf {
+		arg = arg<<8 | uint64(v)
+	}
+
+	return arg, nil
+}
+
+func readCborText(r *bytes.Reader) (string, error) {
+	n, err := readCborHead(r, 3)
+	if err != nil {
+		return "", err
+	}
+
+	if n > uint64(r.Len()) {
+		return "", errors.New("CBOR text out of bound")
+	}
+
+	buf := make([]byte, n)
+	r.Read(buf)
+	return string(buf), nil
+}
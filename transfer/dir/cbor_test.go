@@ -0,0 +1,45 @@
+package dir
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCborHead(t *testing.T) {
+	for _, tc := range []struct {
+		arg  uint64
+		size int
+	}{
+		{0, 1},
+		{23, 1},
+		{24, 2},
+		{255, 2},
+		{256, 3},
+		{math.MaxUint16, 3},
+		{math.MaxUint16 + 1, 5},
+		{math.MaxUint32, 5},
+		{math.MaxUint32 + 1, 9},
+		{math.MaxUint64, 9},
+	} {
+		var buf bytes.Buffer
+		writeCborHead(&buf, 2, tc.arg)
+		assert.Equal(t, tc.size, buf.Len(), tc.arg)
+
+		arg, err := readCborHead(bytes.NewReader(buf.Bytes()), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.arg, arg)
+
+		// truncated argument
+		_, err = readCborHead(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), 2)
+		if tc.size > 1 {
+			assert.Error(t, err, tc.arg)
+		}
+	}
+
+	// unexpected major type
+	_, err := readCborHead(bytes.NewReader([]byte{0x01}), 2)
+	assert.Error(t, err)
+}
@@ -3,10 +3,15 @@ package dir
 import (
 	"bytes"
 	"encoding"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"math"
+	"sort"
+	"strconv"
 
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
 )
@@ -18,10 +23,16 @@ var (
 
 	CodecVersion    = uint16(1)
 	CodecMagicBytes = crypto.Keccak256([]byte("0g-storage-client-dir-codec"))
+
+	// CodecVersionEmbedded is the codec version of manifest with embedded file content, so that old clients
+	// that not aware of embedded content refuse to decode, instead of downloading embedded files by root.
+	CodecVersionEmbedded = uint16(2)
 )
 
 // MarshalBinary implements the encoding.BinaryMarshaler interface.
 // It encodes the FsNode into a binary format.
+//
+// Note, the JSON metadata follows the field order of FsNode, use CanonicalBytes to upload manifest.
 func (node *FsNode) MarshalBinary() ([]byte, error) {
 	// Serialize the FsNode to JSON
 	mdata, err := json.Marshal(node)
@@ -29,6 +40,62 @@ func (node *FsNode) MarshalBinary() ([]byte, error) {
 		return nil, errors.WithMessage(err, "failed to marshal `FsNode` to JSON")
 	}
 
+	return encodeBinary(mdata, codecVersionOf(node))
+}
+
+// CanonicalBytes encodes the FsNode into the same binary format as MarshalBinary, but with canonical JSON
+// metadata, so that semantically identical trees always produce the byte-identical manifest, and hence the
+// same storage root. The canonical JSON form is defined as below:
+//
+//   - Object keys are sorted in lexicographical order.
+//   - No insignificant whitespace.
+//   - Sizes are formatted as decimal integers.
+//   - Only fields relevant to the node type are included, and empty fields are omitted.
+//   - Directory entries are sorted by name, and duplicate names are not allowed.
+//   - Embedded file content is encoded in standard base64 with padding.
+//
+// Since the canonical form is still valid JSON metadata, UnmarshalBinary accepts both canonical and legacy forms.
+func CanonicalBytes(root *FsNode) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeCanonicalJSON(&buf, root); err != nil {
+		return nil, errors.WithMessage(err, "failed to marshal `FsNode` to canonical JSON")
+	}
+
+	return encodeBinary(buf.Bytes(), codecVersionOf(root))
+}
+
+// codecVersionOf returns the codec version to encode the FsNode, which remains the same as before if no file
+// content embedded, so that the manifest root is unchanged.
+func codecVersionOf(node *FsNode) uint16 {
+	if node.EmbedStats().Files > 0 {
+		return CodecVersionEmbedded
+	}
+
+	return CodecVersion
+}
+
+// ManifestRoot returns the storage root of manifest in canonical form.
+func ManifestRoot(root *FsNode) (common.Hash, error) {
+	data, err := CanonicalBytes(root)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	iterdata, err := core.NewDataInMemory(data)
+	if err != nil {
+		return common.Hash{}, errors.WithMessage(err, "failed to create `IterableData` in memory")
+	}
+
+	manifestRoot, err := core.MerkleRootData(iterdata)
+	if err != nil {
+		return common.Hash{}, errors.WithMessage(err, "failed to calculate merkle root")
+	}
+
+	return manifestRoot, nil
+}
+
+// encodeBinary encodes the JSON metadata into binary format with magic bytes and codec version.
+func encodeBinary(mdata []byte, version uint16) ([]byte, error) {
 	// Check if the json metadata is too large
 	if len(mdata) > math.MaxUint32 {
 		return nil, errors.New("the json marshalled data is too large")
@@ -49,7 +116,7 @@ func (node *FsNode) MarshalBinary() ([]byte, error) {
 	offset += int64(len(CodecMagicBytes))
 
 	// Write codec version
-	binary.BigEndian.PutUint16(data[offset:], CodecVersion)
+	binary.BigEndian.PutUint16(data[offset:], version)
 	offset += 2
 
 	// Write JSON data
@@ -58,8 +125,19 @@ func (node *FsNode) MarshalBinary() ([]byte, error) {
 }
 
 // UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
-// It decodes the FsNode from a binary format.
+// It decodes the FsNode from a binary format. The signature of signed manifest is ignored, use VerifyPublisher
+// to verify the signature.
+//
+// The file tree is verified against the default limits, use UnmarshalBinaryWithLimits to specify the limits.
 func (node *FsNode) UnmarshalBinary(data []byte) error {
+	return node.UnmarshalBinaryWithLimits(data, Limits{})
+}
+
+// UnmarshalBinaryWithLimits decodes the FsNode from a binary format as UnmarshalBinary, and returns ErrTreeTooDeep
+// or ErrPathTooLong if the file tree of untrusted metadata exceeds the specified limits.
+func (node *FsNode) UnmarshalBinaryWithLimits(data []byte, limits Limits) error {
+	data, _ = SplitSignature(data)
+
 	// Verify magic bytes
 	if len(data) < len(CodecMagicBytes) {
 		return errors.New("not enough data to read magic bytes")
@@ -76,14 +154,135 @@ func (node *FsNode) UnmarshalBinary(data []byte) error {
 		return errors.New("not enough data to read codec version")
 	}
 	version := binary.BigEndian.Uint16(data[:2])
-	if version != CodecVersion {
-		return errors.Errorf("unsupported codec version: got %d, expected %d", version, CodecVersion)
+	if version != CodecVersion && version != CodecVersionEmbedded {
+		return errors.Errorf("unsupported codec version: got %d, expected %d or %d", version, CodecVersion, CodecVersionEmbedded)
 	}
 	data = data[2:]
 
+	// reject deeply nested metadata before decoding, which exhausts resources
+	if err := checkJSONDepth(data, limits); err != nil {
+		return err
+	}
+
 	// Deserialize the FsNode from JSON metadata
 	if err := json.Unmarshal(data, node); err != nil {
 		return errors.WithMessage(err, "failed to unmarshal `FsNode` from JSON")
 	}
+
+	// embedded content must match the merkle root, so that it is as trustworthy as downloaded by root
+	if err := node.VerifyEmbedded(); err != nil {
+		return errors.WithMessage(err, "invalid embedded file")
+	}
+
+	// escaped names must be restored to valid file names within the directory
+	if err := node.VerifyNames(); err != nil {
+		return errors.WithMessage(err, "invalid file name")
+	}
+
+	if err := node.VerifyLimits(limits); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeCanonicalJSON writes the FsNode in canonical JSON form, see CanonicalBytes for more details.
+func writeCanonicalJSON(buf *bytes.Buffer, node *FsNode) error {
+	if node == nil {
+		return errors.New("nil node")
+	}
+
+	// keys must be written in lexicographical order: data, entries, escaped, hash, link, modTime, mode, name,
+	// nameEncoding, size, type
+	buf.WriteByte('{')
+
+	if node.Type == FileTypeFile && len(node.Data) > 0 {
+		writeCanonicalField(buf, "data", base64.StdEncoding.EncodeToString(node.Data))
+	}
+
+	switch node.Type {
+	case FileTypeDirectory:
+		if len(node.Entries) > 0 {
+			entries := append([]*FsNode(nil), node.Entries...)
+			sort.SliceStable(entries, func(i, j int) bool {
+				return entries[i].Name < entries[j].Name
+			})
+
+			buf.WriteString(`"entries":[`)
+			for i, entry := range entries {
+				if i > 0 {
+					if entry.Name == entries[i-1].Name {
+						return errors.Errorf("duplicate entry name %q", entry.Name)
+					}
+					buf.WriteByte(',')
+				}
+
+				if err := writeCanonicalJSON(buf, entry); err != nil {
+					return err
+				}
+			}
+			buf.WriteString(`],`)
+		}
+	case FileTypeFile, FileTypeSymbolic:
+	default:
+		return errors.Errorf("unsupported file type %q", node.Type)
+	}
+
+	if node.Escaped {
+		buf.WriteString(`"escaped":true,`)
+	}
+
+	if node.Type == FileTypeFile && len(node.Root) > 0 {
+		writeCanonicalField(buf, "hash", node.Root)
+	}
+
+	if node.Type == FileTypeSymbolic && len(node.Link) > 0 {
+		writeCanonicalField(buf, "link", node.Link)
+	}
+
+	// metadata only for regular files and directories, and omitted if not recorded so that the root is unchanged
+	if node.Type != FileTypeSymbolic {
+		if node.ModTime != 0 {
+			buf.WriteString(`"modTime":`)
+			buf.WriteString(strconv.FormatInt(node.ModTime, 10))
+			buf.WriteByte(',')
+		}
+
+		if node.Mode != 0 {
+			buf.WriteString(`"mode":`)
+			buf.WriteString(strconv.FormatUint(uint64(node.Mode), 10))
+			buf.WriteByte(',')
+		}
+	}
+
+	writeCanonicalField(buf, "name", node.Name)
+
+	if len(node.NameEncoding) > 0 {
+		writeCanonicalField(buf, "nameEncoding", string(node.NameEncoding))
+	}
+
+	if node.Type == FileTypeFile && node.Size != 0 {
+		buf.WriteString(`"size":`)
+		buf.WriteString(strconv.FormatInt(node.Size, 10))
+		buf.WriteByte(',')
+	}
+
+	// type is always the last field
+	value, _ := json.Marshal(string(node.Type))
+	buf.WriteString(`"type":`)
+	buf.Write(value)
+	buf.WriteByte('}')
+
 	return nil
 }
+
+func writeCanonicalField(buf *bytes.Buffer, key, value string) {
+	// marshalling string never fails
+	encoded, _ := json.Marshal(value)
+
+	buf.WriteByte('"')
+	buf.WriteString(key)
+	buf.WriteString(`":`)
+	buf.Write(encoded)
+	buf.WriteByte(',')
+}
@@ -3,9 +3,12 @@ package dir_test
 import (
 	"bytes"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestEncodeDecodeFsNode(t *testing.T) {
@@ -106,3 +109,64 @@ func TestInvalidVersion(t *testing.T) {
 		t.Fatalf("expected version error, got %v", err)
 	}
 }
+
+func TestCanonicalBytes(t *testing.T) {
+	root1 := "0x" + strings.Repeat("ab", 32)
+	root2 := "0x" + strings.Repeat("cd", 32)
+
+	// constructed with sorted entries
+	tree1 := dir.NewDirFsNode("root", []*dir.FsNode{
+		dir.NewFileFsNode("a.txt", common.HexToHash(root1), 1024),
+		dir.NewDirFsNode("empty", nil),
+		dir.NewSymbolicFsNode("link", "a.txt"),
+		dir.NewDirFsNode("sub", []*dir.FsNode{
+			dir.NewFileFsNode("b.txt", common.HexToHash(root2), 2048),
+		}),
+	})
+
+	// constructed with unsorted entries and irrelevant fields
+	tree2 := &dir.FsNode{
+		Name: "root",
+		Type: dir.FileTypeDirectory,
+		Size: 100,
+		Entries: []*dir.FsNode{
+			{Name: "sub", Type: dir.FileTypeDirectory, Entries: []*dir.FsNode{
+				{Name: "b.txt", Type: dir.FileTypeFile, Root: root2, Size: 2048},
+			}},
+			{Name: "link", Type: dir.FileTypeSymbolic, Link: "a.txt", Root: root1},
+			{Name: "empty", Type: dir.FileTypeDirectory, Entries: []*dir.FsNode{}},
+			{Name: "a.txt", Type: dir.FileTypeFile, Root: root1, Size: 1024},
+		},
+	}
+
+	data1, err := dir.CanonicalBytes(tree1)
+	assert.NoError(t, err)
+	data2, err := dir.CanonicalBytes(tree2)
+	assert.NoError(t, err)
+	assert.Equal(t, data1, data2)
+
+	assert.Equal(t, `{"entries":[{"hash":"`+root1+`","name":"a.txt","size":1024,"type":"file"},`+
+		`{"name":"empty","type":"directory"},{"link":"a.txt","name":"link","type":"symbolic"},`+
+		`{"entries":[{"hash":"`+root2+`","name":"b.txt","size":2048,"type":"file"}],"name":"sub","type":"directory"}],`+
+		`"name":"root","type":"directory"}`, string(data1[len(dir.CodecMagicBytes)+2:]))
+
+	manifestRoot1, err := dir.ManifestRoot(tree1)
+	assert.NoError(t, err)
+	manifestRoot2, err := dir.ManifestRoot(tree2)
+	assert.NoError(t, err)
+	assert.Equal(t, manifestRoot1, manifestRoot2)
+
+	// decode both canonical and legacy forms
+	legacy, err := tree1.MarshalBinary()
+	assert.NoError(t, err)
+	for _, data := range [][]byte{data1, legacy} {
+		var decoded dir.FsNode
+		assert.NoError(t, decoded.UnmarshalBinary(data))
+		assert.True(t, tree1.Equal(&decoded))
+	}
+
+	// duplicate entry names
+	tree2.Entries = append(tree2.Entries, &dir.FsNode{Name: "a.txt", Type: dir.FileTypeFile, Root: root2, Size: 1})
+	_, err = dir.CanonicalBytes(tree2)
+	assert.Error(t, err)
+}
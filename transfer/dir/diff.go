@@ -2,6 +2,8 @@ package dir
 
 import (
 	"fmt"
+	"path"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
@@ -51,98 +53,299 @@ func Diff(current, next *FsNode) (*DiffNode, error) {
 	return diff(current, next), nil
 }
 
-// diff is a recursive function that computes the differences between two directory nodes.
+// diff computes the differences between two directory nodes iteratively with an explicit stack, so that deep
+// trees never overflow the call stack.
 func diff(current, next *FsNode) *DiffNode {
+	type frame struct {
+		diff   *DiffNode // diff of directory in current tree
+		parent *DiffNode // diff of parent directory, nil for root
+		next   *FsNode   // directory in next tree
+	}
+
 	root := NewDiffNode(current, DiffStatusUnchanged)
 
-	// processes entries from the current directory.
-	for _, currentEntry := range current.Entries {
-		nextEntry, found := next.Search(currentEntry.Name)
-		if !found {
-			root.Entries.ReplaceOrInsert(NewDiffNode(currentEntry, DiffStatusRemoved))
-			root.Status = DiffStatusModified
-			continue
+	// directories in pre-order, so that parents always precede their children
+	var visited []frame
+	stack := []frame{{root, nil, next}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		visited = append(visited, top)
+
+		current, next := top.diff.Node, top.next
+
+		// processes entries from the current directory.
+		for _, currentEntry := range current.Entries {
+			nextEntry, found := next.Search(currentEntry.Name)
+			if !found {
+				top.diff.Entries.ReplaceOrInsert(NewDiffNode(currentEntry, DiffStatusRemoved))
+				top.diff.Status = DiffStatusModified
+				continue
+			}
+
+			if currentEntry.Type == FileTypeDirectory && nextEntry.Type == FileTypeDirectory {
+				// status is determined after sub directory compared
+				subDiff := NewDiffNode(currentEntry, DiffStatusUnchanged)
+				top.diff.Entries.ReplaceOrInsert(subDiff)
+				stack = append(stack, frame{subDiff, top.diff, nextEntry})
+			} else if currentEntry.Equal(nextEntry) {
+				top.diff.Entries.ReplaceOrInsert(NewDiffNode(currentEntry, DiffStatusUnchanged))
+			} else {
+				top.diff.Entries.ReplaceOrInsert(NewDiffNode(currentEntry, DiffStatusModified))
+				top.diff.Status = DiffStatusModified
+			}
 		}
 
-		if currentEntry.Type == FileTypeDirectory && nextEntry.Type == FileTypeDirectory {
-			subDiff := diff(currentEntry, nextEntry)
-			root.Entries.ReplaceOrInsert(subDiff)
-			if subDiff.Status != DiffStatusUnchanged {
-				root.Status = DiffStatusModified
+		// processes entries from the next directory that were not found in the current directory.
+		for _, nextEntry := range next.Entries {
+			if _, found := current.Search(nextEntry.Name); !found {
+				top.diff.Status = DiffStatusModified
+				top.diff.Entries.ReplaceOrInsert(NewDiffNode(nextEntry, DiffStatusAdded))
 			}
-		} else if currentEntry.Equal(nextEntry) {
-			root.Entries.ReplaceOrInsert(NewDiffNode(currentEntry, DiffStatusUnchanged))
-		} else {
-			root.Entries.ReplaceOrInsert(NewDiffNode(currentEntry, DiffStatusModified))
-			root.Status = DiffStatusModified
 		}
 	}
 
-	// processes entries from the next directory that were not found in the current directory.
-	for _, nextEntry := range next.Entries {
-		if _, found := current.Search(nextEntry.Name); !found {
-			root.Status = DiffStatusModified
-			root.Entries.ReplaceOrInsert(NewDiffNode(nextEntry, DiffStatusAdded))
+	// propagates modified status from children to parents in reverse pre-order
+	for i := len(visited) - 1; i > 0; i-- {
+		if visited[i].diff.Status != DiffStatusUnchanged {
+			visited[i].parent.Status = DiffStatusModified
 		}
 	}
 
 	return root
 }
 
-// PrettyPrint prints the DiffNode tree in a human-readable format with a tree skeleton structure.
-func PrettyPrint(root *DiffNode) {
-	prettyPrint(root, 0, false, nil)
+// DiffChange is the kind of change of a path modified between two trees.
+type DiffChange string
+
+const (
+	DiffChangeContent DiffChange = "content" // merkle root or size of regular file changed
+	DiffChangeType    DiffChange = "type"    // replaced by another type, e.g. file replaced by directory
+	DiffChangeLink    DiffChange = "link"    // target of symbolic link changed
+)
+
+// DiffPath is a path added, removed or modified between two trees.
+type DiffPath struct {
+	Path   string     `json:"path"`             // slash-separated path relative to the root
+	Old    *FsNode    `json:"-"`                // node in old tree, nil if added
+	New    *FsNode    `json:"-"`                // node in new tree, nil if removed
+	Change DiffChange `json:"change,omitempty"` // kind of change if modified
 }
 
-func prettyPrint(node *DiffNode, depth int, isLast bool, prefixes []string) {
-	if depth == 0 { // Root directory case
-		fmt.Println(node.Node.Name)
-		printChildEntries(node, 1, nil)
-		return
+// DiffResult is the flat list of paths changed between two trees, where each list is sorted by path.
+//
+// Added and removed directories are expanded to the regular files, symbolic links and empty directories within.
+// A path replaced by another type, e.g. file replaced by directory, is reported as modified only, and not expanded.
+type DiffResult struct {
+	Added    []DiffPath `json:"added"`
+	Removed  []DiffPath `json:"removed"`
+	Modified []DiffPath `json:"modified"`
+}
+
+// Empty returns true if nothing changed.
+func (result *DiffResult) Empty() bool {
+	return len(result.Added) == 0 && len(result.Removed) == 0 && len(result.Modified) == 0
+}
+
+// DiffPaths compares two directories, e.g. the previously uploaded one and the local one, and returns the paths
+// added, removed and modified. Unlike Diff, entries are compared by merging the sorted entries of directories,
+// and paths are in the form of Walk.
+func DiffPaths(old, updated *FsNode) (*DiffResult, error) {
+	if old.Type != FileTypeDirectory || updated.Type != FileTypeDirectory {
+		return nil, errors.New("diff is only supported for directories")
 	}
 
-	// Create prefix and branch for current node
-	prefix := strings.Join(prefixes, "")
-	branch := "├─"
-	if isLast {
-		branch = "└─"
+	result := DiffResult{
+		Added:    make([]DiffPath, 0),
+		Removed:  make([]DiffPath, 0),
+		Modified: make([]DiffPath, 0),
 	}
 
-	// Print node with optional status
-	coloredStatus := colorStringForDiffStatus(node.Status)
-	fmt.Printf("%s%s %s %s\n", prefix, branch, node.Node.Name, coloredStatus)
+	// compare iteratively with an explicit stack, so that deep trees never overflow the call stack
+	type frame struct {
+		path     string
+		old, new *FsNode // directories at the path
+	}
+
+	stack := []frame{{"", old, updated}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		oldEntries, newEntries := top.old.Entries, top.new.Entries
+		for i, j := 0, 0; i < len(oldEntries) || j < len(newEntries); {
+			var cmp int
+			switch {
+			case i == len(oldEntries):
+				cmp = 1
+			case j == len(newEntries):
+				cmp = -1
+			default:
+				cmp = strings.Compare(oldEntries[i].Name, newEntries[j].Name)
+			}
+
+			if cmp < 0 {
+				result.Removed = appendDiffPaths(result.Removed, path.Join(top.path, oldEntries[i].Name), oldEntries[i], false)
+				i++
+				continue
+			}
+
+			if cmp > 0 {
+				result.Added = appendDiffPaths(result.Added, path.Join(top.path, newEntries[j].Name), newEntries[j], true)
+				j++
+				continue
+			}
+
+			oldEntry, newEntry := oldEntries[i], newEntries[j]
+			entryPath := path.Join(top.path, oldEntry.Name)
+			i++
+			j++
 
-	// Update prefixes for children
-	newPrefixes := append(prefixes, "│  ")
-	if isLast {
-		newPrefixes[len(newPrefixes)-1] = "    "
+			var change DiffChange
+			switch {
+			case oldEntry.Type != newEntry.Type:
+				change = DiffChangeType
+			case oldEntry.Type == FileTypeDirectory:
+				stack = append(stack, frame{entryPath, oldEntry, newEntry})
+			case oldEntry.Type == FileTypeSymbolic:
+				if oldEntry.Link != newEntry.Link {
+					change = DiffChangeLink
+				}
+			default:
+				if oldEntry.Root != newEntry.Root || oldEntry.Size != newEntry.Size {
+					change = DiffChangeContent
+				}
+			}
+
+			if len(change) > 0 {
+				result.Modified = append(result.Modified, DiffPath{entryPath, oldEntry, newEntry, change})
+			}
+		}
 	}
 
-	// Recursively print children if it's a directory
-	if node.Node.Type == FileTypeDirectory {
-		printChildEntries(node, depth+1, newPrefixes)
+	for _, paths := range [][]DiffPath{result.Added, result.Removed, result.Modified} {
+		sort.Slice(paths, func(i, j int) bool { return paths[i].Path < paths[j].Path })
 	}
+
+	return &result, nil
 }
 
-func printChildEntries(node *DiffNode, depth int, prefixes []string) {
+// appendDiffPaths appends the regular files, symbolic links and empty directories within the node at the path.
+func appendDiffPaths(paths []DiffPath, base string, node *FsNode, added bool) []DiffPath {
+	node.Walk(func(relpath string, node *FsNode) error {
+		if node.Type == FileTypeDirectory && len(node.Entries) > 0 {
+			return nil
+		}
+
+		item := DiffPath{Path: path.Join(base, relpath)}
+		if added {
+			item.New = node
+		} else {
+			item.Old = node
+		}
+		paths = append(paths, item)
+
+		return nil
+	})
+
+	return paths
+}
+
+// PruneUnchanged returns a tree of the updated directory with only the entries added or modified since the old one,
+// along with their parent directories, e.g. to upload only the changes of a directory synchronized periodically.
+// Directories that replace other types are retained as a whole. The returned tree shares unchanged nodes of the
+// updated tree, and is an empty directory if nothing added or modified.
+func PruneUnchanged(old, updated *FsNode) (*FsNode, error) {
+	result, err := DiffPaths(old, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	root := *updated
+	root.Entries = nil
+
+	// directories copied from the updated tree by path
+	dirs := map[string]*FsNode{"": &root}
+	for _, changed := range append(result.Added, result.Modified...) {
+		parent, parentPath := &root, ""
+		parts := strings.Split(changed.Path, "/")
+		for _, part := range parts[:len(parts)-1] {
+			parentPath = path.Join(parentPath, part)
+			if copied, ok := dirs[parentPath]; ok {
+				parent = copied
+				continue
+			}
+
+			located, err := updated.Locate(parentPath)
+			if err != nil {
+				return nil, err
+			}
+
+			copied := *located
+			copied.Entries = nil
+			parent.Entries = append(parent.Entries, &copied)
+			dirs[parentPath] = &copied
+			parent = &copied
+		}
+
+		parent.Entries = append(parent.Entries, changed.New)
+	}
+
+	for _, dir := range dirs {
+		sortEntries(dir.Entries)
+	}
+
+	return &root, nil
+}
+
+// PrettyPrint prints the DiffNode tree in a human-readable format with a tree skeleton structure.
+func PrettyPrint(root *DiffNode) {
+	fmt.Println(root.Node.Name)
+
+	// print iteratively with an explicit stack, so that deep trees never overflow the call stack
+	stack := pushChildEntries(nil, root, "")
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		// Create branch for current node and prefix for children
+		branch, childPrefix := "├─", "│  "
+		if current.isLast {
+			branch, childPrefix = "└─", "    "
+		}
+
+		// Print node with optional status
+		coloredStatus := colorStringForDiffStatus(current.node.Status)
+		fmt.Printf("%s%s %s %s\n", current.prefix, branch, current.node.Node.Name, coloredStatus)
+
+		// Print children if it's a directory
+		if current.node.Node.Type == FileTypeDirectory {
+			stack = pushChildEntries(stack, current.node, current.prefix+childPrefix)
+		}
+	}
+}
+
+// printFrame is a node to print by PrettyPrint.
+type printFrame struct {
+	node   *DiffNode
+	prefix string // tree skeleton of ancestors
+	isLast bool   // whether the last entry in the directory
+}
+
+// pushChildEntries pushes child entries of the node in reverse order, so that they are popped in order.
+func pushChildEntries(stack []printFrame, node *DiffNode, prefix string) []printFrame {
 	if node.Entries == nil || node.Entries.Len() == 0 {
-		return
+		return stack
 	}
 
-	// Get the last node in a BTree efficiently by descending to the last element.
-	var last *DiffNode
+	isLast := true
 	node.Entries.Descend(func(item *DiffNode) bool {
-		last = item
-		return false // Stop the iteration as soon as we get the last node.
-	})
-
-	// Print all child entries of the current node
-	node.Entries.Ascend(func(item *DiffNode) bool {
-		// Check if this is the last entry in the directory
-		isLast := item == last
-		prettyPrint(item, depth, isLast, prefixes)
+		stack = append(stack, printFrame{item, prefix, isLast})
+		isLast = false
 		return true
 	})
+
+	return stack
 }
 
 // colorStringForDiffStatus returns a color string based on the diff status.
@@ -0,0 +1,261 @@
+package dir
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// TreeEntry is an entry listed by TreeSource, of which the entries of directory are not necessarily loaded.
+type TreeEntry struct {
+	*FsNode
+
+	// Digest of the whole directory, e.g. the storage root of a manifest chunk, so that directories of the same
+	// digest are identical without listing. Zero if unknown or not a directory.
+	Digest common.Hash
+}
+
+// TreeSource lists the directories of a tree on demand, e.g. chunks of a huge manifest loaded lazily.
+type TreeSource interface {
+	// ReadDir returns the entries of directory at the slash-separated path relative to root, which is empty for
+	// root, in order of name. The directory entry is the one listed by its parent, and nil for root.
+	ReadDir(ctx context.Context, dirPath string, dir *TreeEntry) ([]TreeEntry, error)
+}
+
+// PagedTreeSource is a TreeSource that lists directories page by page as well, so that huge directories, e.g. of
+// chunked manifests, are listed without decoding all entries.
+type PagedTreeSource interface {
+	TreeSource
+
+	// ReadDirPage returns at most limit entries of directory starting from offset in order of name, along with the
+	// total number of entries, as FsNode.EntriesPage does. The directory is specified as ReadDir does.
+	ReadDirPage(ctx context.Context, dirPath string, dir *TreeEntry, offset, limit int) ([]TreeEntry, int, error)
+}
+
+// memorySource is the TreeSource of a tree already in memory.
+type memorySource struct {
+	root *FsNode
+}
+
+// TreeSourceOf returns the TreeSource of a tree in memory, of which the directories shared with another tree, e.g.
+// by Patch or Merge, are identical without comparing entries.
+func TreeSourceOf(root *FsNode) PagedTreeSource {
+	return &memorySource{root}
+}
+
+func (source *memorySource) ReadDir(ctx context.Context, dirPath string, dir *TreeEntry) ([]TreeEntry, error) {
+	entries, _, err := source.ReadDirPage(ctx, dirPath, dir, 0, 0)
+	return entries, err
+}
+
+func (source *memorySource) ReadDirPage(_ context.Context, dirPath string, dir *TreeEntry, offset, limit int) ([]TreeEntry, int, error) {
+	node := source.root
+	if dir != nil {
+		node = dir.FsNode
+	}
+
+	if node.Type != FileTypeDirectory {
+		return nil, 0, errors.Errorf("%q is not a directory", dirPath)
+	}
+
+	page, total := node.EntriesPage(offset, limit)
+	entries := make([]TreeEntry, len(page))
+	for i, entry := range page {
+		entries[i] = TreeEntry{FsNode: entry}
+	}
+
+	return entries, total, nil
+}
+
+// LocateEntry locates the entry at the slash-separated path relative to root, as FsNode.Locate does, by binary
+// search over pages of entries, so that only a few entries of each directory along the path are decoded. It returns
+// nil for root.
+func LocateEntry(ctx context.Context, source PagedTreeSource, entryPath string) (*TreeEntry, error) {
+	var current *TreeEntry
+	var currentPath string
+	for _, part := range strings.Split(entryPath, "/") {
+		// Skip empty strings and dot current
+		if len(part) == 0 || part == "." {
+			continue
+		}
+
+		if part == ".." {
+			return nil, errors.WithMessagef(ErrInvalidPath, "cannot locate '%s'", entryPath)
+		}
+
+		if current != nil && current.Type != FileTypeDirectory {
+			return nil, errors.WithMessagef(ErrPathNotFound, "cannot locate '%s': '%s' is not a directory", part, current.Name)
+		}
+
+		entry, err := searchEntry(ctx, source, currentPath, current, part)
+		if err != nil {
+			return nil, err
+		}
+
+		current, currentPath = entry, path.Join(currentPath, part)
+	}
+
+	return current, nil
+}
+
+// searchEntry looks for the entry by name in directory by binary search over pages of a single entry.
+func searchEntry(ctx context.Context, source PagedTreeSource, dirPath string, dir *TreeEntry, name string) (*TreeEntry, error) {
+	_, total, err := source.ReadDirPage(ctx, dirPath, dir, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for low, high := 0, total; low < high; {
+		mid := int(uint(low+high) >> 1)
+		page, _, err := source.ReadDirPage(ctx, dirPath, dir, mid, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(page) == 0 {
+			break
+		}
+
+		switch cmp := strings.Compare(name, page[0].Name); {
+		case cmp == 0:
+			return &page[0], nil
+		case cmp < 0:
+			high = mid
+		default:
+			low = mid + 1
+		}
+	}
+
+	return nil, errors.WithMessagef(ErrPathNotFound, "'%s'", name)
+}
+
+// diffStreamFrame is a pair of directories at the same path to compare, along with the cursors of entries.
+type diffStreamFrame struct {
+	path     string
+	old, new []TreeEntry
+	i, j     int
+}
+
+// DiffStream compares two trees listed on demand in lockstep, and calls fn with each path changed in the order of
+// path, so that huge trees, e.g. chunked manifests of millions of entries, could be compared on small machines.
+// Paths are changed as DiffPaths does, except that directories added or removed are reported as a whole without
+// listing the entries within.
+//
+// Directories of the same digest, or the same node, are skipped without listing, and entries compared are released
+// immediately. So memory is bounded by the listings of directories along the path being compared, rather than the
+// size of trees.
+//
+// DiffStream stops once fn returns an error, and returns the error.
+func DiffStream(ctx context.Context, old, updated TreeSource, fn func(diff DiffPath) error) error {
+	oldEntries, err := old.ReadDir(ctx, "", nil)
+	if err != nil {
+		return errors.WithMessage(err, "failed to list root directory of old tree")
+	}
+
+	newEntries, err := updated.ReadDir(ctx, "", nil)
+	if err != nil {
+		return errors.WithMessage(err, "failed to list root directory of new tree")
+	}
+
+	// compare depth-first with an explicit stack, so that deep trees never overflow the call stack
+	stack := []*diffStreamFrame{{old: oldEntries, new: newEntries}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if top.i == len(top.old) && top.j == len(top.new) {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var cmp int
+		switch {
+		case top.i == len(top.old):
+			cmp = 1
+		case top.j == len(top.new):
+			cmp = -1
+		default:
+			cmp = strings.Compare(top.old[top.i].Name, top.new[top.j].Name)
+		}
+
+		var diff DiffPath
+		switch {
+		case cmp < 0:
+			diff = DiffPath{Path: path.Join(top.path, top.old[top.i].Name), Old: top.old[top.i].FsNode}
+			top.old[top.i] = TreeEntry{}
+			top.i++
+		case cmp > 0:
+			diff = DiffPath{Path: path.Join(top.path, top.new[top.j].Name), New: top.new[top.j].FsNode}
+			top.new[top.j] = TreeEntry{}
+			top.j++
+		default:
+			oldEntry, newEntry := top.old[top.i], top.new[top.j]
+			top.old[top.i], top.new[top.j] = TreeEntry{}, TreeEntry{}
+			top.i++
+			top.j++
+
+			diff = DiffPath{Path: path.Join(top.path, oldEntry.Name), Old: oldEntry.FsNode, New: newEntry.FsNode}
+			switch {
+			case oldEntry.Type != newEntry.Type:
+				diff.Change = DiffChangeType
+			case oldEntry.Type == FileTypeDirectory:
+				if identicalDir(&oldEntry, &newEntry) {
+					continue
+				}
+
+				frame, err := readDirPair(ctx, old, updated, diff.Path, &oldEntry, &newEntry)
+				if err != nil {
+					return err
+				}
+				stack = append(stack, frame)
+
+				continue
+			case oldEntry.Type == FileTypeSymbolic:
+				if oldEntry.Link == newEntry.Link {
+					continue
+				}
+				diff.Change = DiffChangeLink
+			default:
+				if oldEntry.Root == newEntry.Root && oldEntry.Size == newEntry.Size {
+					continue
+				}
+				diff.Change = DiffChangeContent
+			}
+		}
+
+		if err := fn(diff); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// identicalDir returns whether the directories are known identical without listing.
+func identicalDir(old, updated *TreeEntry) bool {
+	if old.FsNode == updated.FsNode {
+		return true
+	}
+
+	return old.Digest != (common.Hash{}) && old.Digest == updated.Digest
+}
+
+// readDirPair lists the directories at the same path of both trees to compare.
+func readDirPair(ctx context.Context, old, updated TreeSource, dirPath string, oldDir, newDir *TreeEntry) (*diffStreamFrame, error) {
+	oldEntries, err := old.ReadDir(ctx, dirPath, oldDir)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list directory %q of old tree", dirPath)
+	}
+
+	newEntries, err := updated.ReadDir(ctx, dirPath, newDir)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list directory %q of new tree", dirPath)
+	}
+
+	return &diffStreamFrame{path: dirPath, old: oldEntries, new: newEntries}, nil
+}
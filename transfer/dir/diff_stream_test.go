@@ -0,0 +1,183 @@
+package dir
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func collectDiffStream(t *testing.T, old, updated TreeSource) []DiffPath {
+	var diffs []DiffPath
+	assert.NoError(t, DiffStream(context.Background(), old, updated, func(diff DiffPath) error {
+		diffs = append(diffs, diff)
+		return nil
+	}))
+
+	return diffs
+}
+
+func TestDiffStream(t *testing.T) {
+	old := NewDirFsNode("", []*FsNode{
+		NewFileFsNode("a", common.HexToHash("0x01"), 1),
+		NewDirFsNode("dir0", []*FsNode{NewFileFsNode("file", common.HexToHash("0x02"), 2)}),
+		NewDirFsNode("dir1", []*FsNode{
+			NewFileFsNode("file0", common.HexToHash("0x03"), 3),
+			NewSymbolicFsNode("link", "file0"),
+		}),
+		NewFileFsNode("typed", common.HexToHash("0x04"), 4),
+	})
+	updated := NewDirFsNode("", []*FsNode{
+		NewFileFsNode("a", common.HexToHash("0x01"), 1),
+		NewDirFsNode("dir1", []*FsNode{
+			NewFileFsNode("file0", common.HexToHash("0x05"), 3),
+			NewSymbolicFsNode("link", "file1"),
+			NewDirFsNode("sub", []*FsNode{NewFileFsNode("file", common.HexToHash("0x06"), 6)}),
+		}),
+		NewDirFsNode("typed", nil),
+	})
+
+	var paths []string
+	for _, diff := range collectDiffStream(t, TreeSourceOf(old), TreeSourceOf(updated)) {
+		paths = append(paths, fmt.Sprintf("%v %v", diff.Path, diff.Change))
+	}
+	assert.Equal(t, []string{"dir0 ", "dir1/file0 content", "dir1/link link", "dir1/sub ", "typed type"}, paths)
+
+	// stops on error
+	err := DiffStream(context.Background(), TreeSourceOf(old), TreeSourceOf(updated), func(diff DiffPath) error {
+		return ErrPathNotFound
+	})
+	assert.ErrorIs(t, err, ErrPathNotFound)
+}
+
+// countingSource counts the directories listed.
+type countingSource struct {
+	TreeSource
+	reads int
+}
+
+func (source *countingSource) ReadDir(ctx context.Context, dirPath string, dir *TreeEntry) ([]TreeEntry, error) {
+	source.reads++
+	return source.TreeSource.ReadDir(ctx, dirPath, dir)
+}
+
+func TestDiffStreamShared(t *testing.T) {
+	old := newDeepTree(8, common.HexToHash("0x01"))
+	patched, err := Patch(old, []PatchOp{Put("d1/d1/f0", NewFileFsNode("", common.HexToHash("0x02"), 10))})
+	assert.NoError(t, err)
+
+	// directories shared by patch not listed
+	source := &countingSource{TreeSource: TreeSourceOf(old)}
+	diffs := collectDiffStream(t, source, TreeSourceOf(patched))
+	if assert.Len(t, diffs, 1) {
+		assert.Equal(t, "d1/d1/f0", diffs[0].Path)
+		assert.Equal(t, DiffChangeContent, diffs[0].Change)
+	}
+	assert.Equal(t, 3, source.reads)
+}
+
+// syntheticSource is a chunked manifest of numDirs directories of numFiles files each generated on demand, where
+// the first file of every directory changed is of another version.
+type syntheticSource struct {
+	numDirs, numFiles int
+	changed           func(dir int) bool
+	digests           bool // whether directories listed along with digests
+	reads             int
+}
+
+func (source *syntheticSource) version(dir int) int {
+	if source.changed(dir) {
+		return 1
+	}
+
+	return 0
+}
+
+func (source *syntheticSource) ReadDir(_ context.Context, dirPath string, dir *TreeEntry) ([]TreeEntry, error) {
+	source.reads++
+
+	if dir == nil {
+		entries := make([]TreeEntry, source.numDirs)
+		for i := range entries {
+			entries[i].FsNode = NewDirFsNode(fmt.Sprintf("d%07d", i), nil)
+			if source.digests {
+				entries[i].Digest = crypto.Keccak256Hash([]byte(fmt.Sprintf("%v/%v", i, source.version(i))))
+			}
+		}
+		return entries, nil
+	}
+
+	index, err := strconv.Atoi(dirPath[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]TreeEntry, source.numFiles)
+	for i := range entries {
+		content := fmt.Sprintf("%v/%v", index, i)
+		if i == 0 {
+			content += fmt.Sprintf("@%v", source.version(index))
+		}
+		entries[i].FsNode = NewFileFsNode(fmt.Sprintf("f%07d", i), crypto.Keccak256Hash([]byte(content)), int64(i))
+	}
+
+	return entries, nil
+}
+
+func TestDiffStreamLargeTree(t *testing.T) {
+	const numDirs, numFiles = 1000, 1000
+
+	every := func(n int) func(int) bool { return func(dir int) bool { return dir%n == 0 } }
+	old := &syntheticSource{numDirs: numDirs, numFiles: numFiles, changed: func(int) bool { return false }}
+	updated := &syntheticSource{numDirs: numDirs, numFiles: numFiles, changed: every(100)}
+
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	baseline, peak := stats.HeapAlloc, stats.HeapAlloc
+
+	// all the 1M entries compared without digests, where heap is sampled once directories listed
+	var diffs []string
+	sampling := &samplingSource{TreeSource: updated, sample: func() {
+		runtime.ReadMemStats(&stats)
+		peak = max(peak, stats.HeapAlloc)
+	}}
+	assert.NoError(t, DiffStream(context.Background(), old, sampling, func(diff DiffPath) error {
+		diffs = append(diffs, diff.Path)
+		return nil
+	}))
+
+	assert.Len(t, diffs, numDirs/100)
+	assert.Equal(t, "d0000000/f0000000", diffs[0])
+	assert.Equal(t, numDirs+1, updated.reads)
+	assert.Less(t, peak-baseline, uint64(32<<20), "heap grows %v bytes", peak-baseline)
+
+	// directories of the same digest not listed
+	old.digests, updated.digests = true, true
+	old.reads, updated.reads = 0, 0
+	diffs = diffs[:0]
+	assert.NoError(t, DiffStream(context.Background(), old, updated, func(diff DiffPath) error {
+		diffs = append(diffs, diff.Path)
+		return nil
+	}))
+	assert.Len(t, diffs, numDirs/100)
+	assert.Equal(t, 1+numDirs/100, old.reads)
+	assert.Equal(t, 1+numDirs/100, updated.reads)
+}
+
+// samplingSource calls sample each time a directory listed.
+type samplingSource struct {
+	TreeSource
+	sample func()
+}
+
+func (source *samplingSource) ReadDir(ctx context.Context, dirPath string, dir *TreeEntry) ([]TreeEntry, error) {
+	entries, err := source.TreeSource.ReadDir(ctx, dirPath, dir)
+	source.sample()
+	return entries, err
+}
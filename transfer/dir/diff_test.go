@@ -127,3 +127,122 @@ func findDiffNodeByName(root *dir.DiffNode, name string) *dir.DiffNode {
 	})
 	return result
 }
+
+func diffPaths(paths []dir.DiffPath) []string {
+	result := make([]string, 0, len(paths))
+	for _, p := range paths {
+		result = append(result, p.Path)
+	}
+	return result
+}
+
+func TestDiffPaths(t *testing.T) {
+	old := dir.NewDirFsNode("root", []*dir.FsNode{
+		dir.NewFileFsNode("a.txt", common.HexToHash("0x1"), 100),
+		dir.NewFileFsNode("b.txt", common.HexToHash("0x2"), 200),
+		dir.NewFileFsNode("c", common.HexToHash("0x3"), 300),
+		dir.NewSymbolicFsNode("link", "a.txt"),
+		dir.NewDirFsNode("removed", []*dir.FsNode{
+			dir.NewFileFsNode("x.txt", common.HexToHash("0x4"), 400),
+			dir.NewDirFsNode("empty", nil),
+		}),
+		dir.NewDirFsNode("sub", []*dir.FsNode{
+			dir.NewFileFsNode("same.txt", common.HexToHash("0x5"), 500),
+			dir.NewFileFsNode("resized.txt", common.HexToHash("0x6"), 600),
+			dir.NewDirFsNode("d", nil),
+		}),
+	})
+
+	updated := dir.NewDirFsNode("root", []*dir.FsNode{
+		dir.NewFileFsNode("a.txt", common.HexToHash("0x1"), 100),
+		dir.NewFileFsNode("b.txt", common.HexToHash("0x7"), 200),
+		dir.NewDirFsNode("c", []*dir.FsNode{
+			dir.NewFileFsNode("inner.txt", common.HexToHash("0x3"), 300),
+		}),
+		dir.NewSymbolicFsNode("link", "b.txt"),
+		dir.NewDirFsNode("added", []*dir.FsNode{
+			dir.NewFileFsNode("y.txt", common.HexToHash("0x8"), 800),
+		}),
+		dir.NewDirFsNode("sub", []*dir.FsNode{
+			dir.NewFileFsNode("same.txt", common.HexToHash("0x5"), 500),
+			dir.NewFileFsNode("resized.txt", common.HexToHash("0x6"), 601),
+			dir.NewFileFsNode("d", common.HexToHash("0x9"), 900),
+		}),
+	})
+
+	result, err := dir.DiffPaths(old, updated)
+	assert.NoError(t, err)
+	assert.False(t, result.Empty())
+	assert.Equal(t, []string{"added/y.txt"}, diffPaths(result.Added))
+	assert.Equal(t, []string{"removed/empty", "removed/x.txt"}, diffPaths(result.Removed))
+	assert.Equal(t, []string{"b.txt", "c", "link", "sub/d", "sub/resized.txt"}, diffPaths(result.Modified))
+
+	changes := make(map[string]dir.DiffChange)
+	for _, modified := range result.Modified {
+		changes[modified.Path] = modified.Change
+		assert.NotNil(t, modified.Old)
+		assert.NotNil(t, modified.New)
+	}
+	assert.Equal(t, map[string]dir.DiffChange{
+		"b.txt":           dir.DiffChangeContent,
+		"c":               dir.DiffChangeType, // file replaced by directory
+		"link":            dir.DiffChangeLink,
+		"sub/d":           dir.DiffChangeType, // directory replaced by file
+		"sub/resized.txt": dir.DiffChangeContent,
+	}, changes)
+
+	// nothing changed
+	result, err = dir.DiffPaths(updated, updated)
+	assert.NoError(t, err)
+	assert.True(t, result.Empty())
+
+	_, err = dir.DiffPaths(old, dir.NewFileFsNode("file", common.Hash{}, 1))
+	assert.Error(t, err)
+}
+
+func TestPruneUnchanged(t *testing.T) {
+	old := dir.NewDirFsNode("root", []*dir.FsNode{
+		dir.NewFileFsNode("a.txt", common.HexToHash("0x1"), 100),
+		dir.NewDirFsNode("sub", []*dir.FsNode{
+			dir.NewFileFsNode("same.txt", common.HexToHash("0x2"), 200),
+			dir.NewFileFsNode("changed.txt", common.HexToHash("0x3"), 300),
+		}),
+		dir.NewDirFsNode("unchanged", []*dir.FsNode{
+			dir.NewFileFsNode("b.txt", common.HexToHash("0x4"), 400),
+		}),
+	})
+
+	updated := dir.NewDirFsNode("root", []*dir.FsNode{
+		dir.NewFileFsNode("a.txt", common.HexToHash("0x1"), 100),
+		dir.NewDirFsNode("sub", []*dir.FsNode{
+			dir.NewFileFsNode("same.txt", common.HexToHash("0x2"), 200),
+			dir.NewFileFsNode("changed.txt", common.HexToHash("0x5"), 300),
+			dir.NewDirFsNode("deep", []*dir.FsNode{
+				dir.NewFileFsNode("new.txt", common.HexToHash("0x6"), 600),
+			}),
+		}),
+		dir.NewDirFsNode("unchanged", []*dir.FsNode{
+			dir.NewFileFsNode("b.txt", common.HexToHash("0x4"), 400),
+		}),
+	})
+
+	pruned, err := dir.PruneUnchanged(old, updated)
+	assert.NoError(t, err)
+	assert.True(t, pruned.Equal(dir.NewDirFsNode("root", []*dir.FsNode{
+		dir.NewDirFsNode("sub", []*dir.FsNode{
+			dir.NewFileFsNode("changed.txt", common.HexToHash("0x5"), 300),
+			dir.NewDirFsNode("deep", []*dir.FsNode{
+				dir.NewFileFsNode("new.txt", common.HexToHash("0x6"), 600),
+			}),
+		}),
+	})))
+
+	// new tree not changed
+	assert.Equal(t, 3, len(updated.Entries))
+	assert.Equal(t, 3, len(updated.Entries[1].Entries))
+
+	pruned, err = dir.PruneUnchanged(updated, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, dir.FileTypeDirectory, pruned.Type)
+	assert.Empty(t, pruned.Entries)
+}
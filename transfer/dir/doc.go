@@ -10,8 +10,31 @@
 //   - Serializing an FsNode structure into a binary format that includes a JSON representation, suitable
 //     for storage on a 0g storage node.
 //   - Deserializing the binary format back into an FsNode structure for further manipulation and operations.
+//   - Encoding an FsNode structure in a compact, versioned and deterministic binary form, e.g. to store the file
+//     tree itself as a 0g file with a stable root.
 //   - Supporting the comparison of two directory structures to identify differences such as added, removed,
-//     or modified files.
+//     or modified files, and pruning unchanged entries to synchronize a directory incrementally.
+//   - Exposing a directory stored on the 0g storage node as a read-only io/fs.FS, which lazily downloads
+//     file content upon read, by range if supported by the downloader.
+//   - Exporting a directory into CARv1 format with UnixFS nodes for IPFS tooling, and importing it back,
+//     along with the mapping between 0g merkle roots and generated CIDs.
+//   - Embedding the content of small files in the manifest, so that they need not be uploaded separately.
+//   - Encoding file names that are not valid UTF-8 by policy, e.g. percent-encoded and restored on download.
+//   - Ignoring entries by gitignore-style patterns, e.g. loaded from .0gignore, when building file tree.
+//   - Building file tree from any io/fs.FS, e.g. embed.FS, identical to the one built from local file system.
+//   - Building file tree from tar (optionally gzipped) or zip archives directly, without extracting to disk.
+//   - Recording permissions and modification times of files and directories optionally, which are restored on
+//     download.
+//   - Rejecting, skipping or keeping symbolic links that escape the root directory or form a cycle by policy.
+//   - Detecting file names not portable to Windows or macOS, e.g. colliding case-insensitively or reserved.
+//   - Limiting the depth and path length of directories, especially when decoding untrusted manifests.
+//   - Aggregating the total size and number of files of directories, e.g. to summarize a directory before upload.
+//   - Rendering a file tree as a human readable tree view, or NDJSON listing of entries for tools like jq.
+//   - Diffing huge trees listed on demand, e.g. chunked manifests, in lockstep with bounded memory.
+//   - Diffing file trees into patch operations, and reusing known merkle roots of files unchanged when building
+//     file tree, e.g. to publish local changes incrementally.
+//   - Merging an overlay tree on top of a base tree, e.g. per-locale assets on a base site, by conflict policy.
+//   - Migrating manifests published by earlier clients to the canonical form, without uploading files again.
 //
 // This package enables the 0g storage client to manage complex directory structures using an efficient
 // and extensible data format, facilitating advanced file system operations beyond single file storage.
@@ -0,0 +1,158 @@
+package dir
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// EmbedOption is the option to embed the content of small files in directory manifest, so that those files
+// need not be uploaded separately.
+type EmbedOption struct {
+	MaxFileSize  int64 // max size of a file to embed, 0 to disable embedding
+	MaxTotalSize int64 // max size of all embedded content in a manifest, which keeps the manifest reasonable
+}
+
+// EmbedStats is the statistics of embedded files.
+type EmbedStats struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// NewEmbeddedFileFsNode creates a new FsNode representing a regular file, of which the content is embedded.
+//
+// Embedded node keeps the merkle root as if the file is uploaded separately, so that comparison and diff
+// work regardless of embedding. The content is in turn covered by the merkle root of manifest.
+func NewEmbeddedFileFsNode(name string, data []byte) (*FsNode, error) {
+	root, err := embeddedRoot(data)
+	if err != nil {
+		return nil, err
+	}
+
+	node := NewFileFsNode(name, root, int64(len(data)))
+	node.Data = data
+
+	return node, nil
+}
+
+// Embedded indicates whether the file content is embedded in manifest.
+func (node *FsNode) Embedded() bool {
+	return node.Type == FileTypeFile && len(node.Data) > 0
+}
+
+// EmbedStats returns the number of embedded files and total bytes within the node.
+func (node *FsNode) EmbedStats() EmbedStats {
+	var stats EmbedStats
+
+	node.Traverse(func(n *FsNode, _ string) error {
+		if n.Embedded() {
+			stats.Files++
+			stats.Bytes += n.Size
+		}
+		return nil
+	})
+
+	return stats
+}
+
+// VerifyEmbedded verifies embedded nodes recursively. An embedded node is valid only if:
+//
+//   - It is a regular file, and the content is not empty.
+//   - The size equals to the length of content.
+//   - The merkle root equals to the one computed from the content.
+func (node *FsNode) VerifyEmbedded() error {
+	return node.Traverse(func(n *FsNode, relpath string) error {
+		if len(n.Data) == 0 {
+			return nil
+		}
+
+		if n.Type != FileTypeFile {
+			return errors.Errorf("content embedded in %v node %v", n.Type, relpath)
+		}
+
+		if n.Size != int64(len(n.Data)) {
+			return errors.Errorf("size mismatch of embedded file %v, recorded %v, actual %v", relpath, n.Size, len(n.Data))
+		}
+
+		root, err := embeddedRoot(n.Data)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to compute merkle root of embedded file %v", relpath)
+		}
+
+		if common.HexToHash(n.Root) != root {
+			return errors.Errorf("merkle root mismatch of embedded file %v, recorded %v, computed %v", relpath, n.Root, root)
+		}
+
+		return nil
+	})
+}
+
+// EmbedSmallFiles embeds the content of small files under the specified folder into the file tree built by
+// BuildFileTree. Files are embedded in ascending order of size and then path, until the total embedded size
+// reaches the limit, so that as many files as possible are embedded and the result is deterministic.
+func EmbedSmallFiles(root *FsNode, folder string, opt EmbedOption) (EmbedStats, error) {
+	var stats EmbedStats
+
+	if opt.MaxFileSize <= 0 || opt.MaxTotalSize <= 0 {
+		return stats, nil
+	}
+
+	nodes, relpaths := root.Flatten(func(n *FsNode) bool {
+		return n.Type == FileTypeFile && n.Size > 0 && n.Size <= opt.MaxFileSize && !n.Embedded()
+	})
+
+	indices := make([]int, len(nodes))
+	for i := range indices {
+		indices[i] = i
+	}
+
+	sort.SliceStable(indices, func(i, j int) bool {
+		if nodes[indices[i]].Size != nodes[indices[j]].Size {
+			return nodes[indices[i]].Size < nodes[indices[j]].Size
+		}
+		return relpaths[indices[i]] < relpaths[indices[j]]
+	})
+
+	for _, i := range indices {
+		if stats.Bytes+nodes[i].Size > opt.MaxTotalSize {
+			break
+		}
+
+		path := filepath.Join(folder, relpaths[i])
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return stats, errors.WithMessagef(err, "failed to read file %v", path)
+		}
+
+		// file changed since file tree built
+		if int64(len(data)) != nodes[i].Size {
+			return stats, errors.Errorf("size of file %v changed, expected %v, actual %v", path, nodes[i].Size, len(data))
+		}
+
+		if computed, err := embeddedRoot(data); err != nil {
+			return stats, errors.WithMessagef(err, "failed to compute merkle root of file %v", path)
+		} else if common.HexToHash(nodes[i].Root) != computed {
+			return stats, errors.Errorf("content of file %v changed since file tree built", path)
+		}
+
+		nodes[i].Data = data
+		stats.Files++
+		stats.Bytes += nodes[i].Size
+	}
+
+	return stats, nil
+}
+
+func embeddedRoot(data []byte) (common.Hash, error) {
+	iterdata, err := core.NewDataInMemory(data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return core.MerkleRootData(iterdata)
+}
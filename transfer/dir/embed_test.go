@@ -0,0 +1,155 @@
+package dir_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+func createEmbedTestDir(t *testing.T) string {
+	folder := t.TempDir()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub"), 0755))
+	for name, size := range map[string]int{
+		"a.txt":       20,
+		"b.txt":       10,
+		"sub/c.txt":   300,
+		"sub/d.txt":   10,
+		"large.bin":   5000,
+		"sub/empty":   0,
+		"sub/big.bin": 300000,
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(folder, name), []byte(strings.Repeat(name[:1], size)), 0644))
+	}
+
+	return folder
+}
+
+func codecVersion(data []byte) uint16 {
+	return binary.BigEndian.Uint16(data[len(dir.CodecMagicBytes):])
+}
+
+func TestEmbedSmallFiles(t *testing.T) {
+	folder := createEmbedTestDir(t)
+
+	plain, err := dir.BuildFileTree(folder)
+	assert.NoError(t, err)
+
+	tree, err := dir.BuildFileTree(folder)
+	assert.NoError(t, err)
+
+	// embedded in ascending order of size and path until total size reached
+	stats, err := dir.EmbedSmallFiles(tree, folder, dir.EmbedOption{MaxFileSize: 1000, MaxTotalSize: 40})
+	assert.NoError(t, err)
+	assert.Equal(t, dir.EmbedStats{Files: 3, Bytes: 40}, stats)
+	assert.Equal(t, stats, tree.EmbedStats())
+
+	for path, embedded := range map[string]bool{
+		"b.txt":     true,
+		"sub/d.txt": true,
+		"a.txt":     true,
+		"sub/c.txt": false,
+		"large.bin": false,
+		"sub/empty": false,
+	} {
+		node, err := tree.Locate(path)
+		assert.NoError(t, err)
+		assert.Equal(t, embedded, node.Embedded(), path)
+	}
+
+	// merkle roots unchanged, so that diff works regardless of embedding
+	assert.True(t, plain.Equal(tree))
+	assert.Equal(t, plain.Footprint().Sectors-3, tree.Footprint().Sectors)
+
+	// disabled
+	stats, err = dir.EmbedSmallFiles(plain, folder, dir.EmbedOption{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Files)
+}
+
+func TestEmbeddedCodec(t *testing.T) {
+	folder := createEmbedTestDir(t)
+
+	tree, err := dir.BuildFileTree(folder)
+	assert.NoError(t, err)
+
+	// codec version unchanged if nothing embedded
+	data, err := dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+	assert.Equal(t, dir.CodecVersion, codecVersion(data))
+
+	_, err = dir.EmbedSmallFiles(tree, folder, dir.EmbedOption{MaxFileSize: 1000, MaxTotalSize: 1000})
+	assert.NoError(t, err)
+
+	data, err = dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+	assert.Equal(t, dir.CodecVersionEmbedded, codecVersion(data))
+
+	var decoded dir.FsNode
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	node, err := decoded.Locate("sub/c.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("s", 300), string(node.Data))
+
+	// canonical form is stable
+	again, err := dir.CanonicalBytes(&decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, data, again)
+}
+
+func TestEmbeddedVerification(t *testing.T) {
+	node, err := dir.NewEmbeddedFileFsNode("a.txt", []byte("hello"))
+	assert.NoError(t, err)
+	assert.NoError(t, node.VerifyEmbedded())
+
+	for name, tamper := range map[string]func(n *dir.FsNode){
+		"merkle root mismatch": func(n *dir.FsNode) { n.Data = []byte("world") },
+		"size mismatch":        func(n *dir.FsNode) { n.Size = 6 },
+		"content embedded":     func(n *dir.FsNode) { n.Type = dir.FileTypeSymbolic },
+	} {
+		tampered := *node
+		tamper(&tampered)
+
+		tree := dir.NewDirFsNode("/", []*dir.FsNode{&tampered})
+		data, err := tree.MarshalBinary()
+		assert.NoError(t, err)
+
+		var decoded dir.FsNode
+		assert.ErrorContains(t, decoded.UnmarshalBinary(data), name)
+	}
+}
+
+func TestRemoteFSEmbedded(t *testing.T) {
+	downloader := newMemDownloader()
+
+	embedded, err := dir.NewEmbeddedFileFsNode("small.txt", []byte("tiny"))
+	assert.NoError(t, err)
+
+	tree := dir.NewDirFsNode("/", []*dir.FsNode{
+		embedded,
+		newFileNode(t, downloader, "large.txt", "not embedded"),
+	})
+
+	manifest, err := tree.MarshalBinary()
+	assert.NoError(t, err)
+
+	rfs, err := dir.FS(context.Background(), downloader, downloader.add(t, manifest))
+	assert.NoError(t, err)
+
+	content, err := fs.ReadFile(rfs, "small.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "tiny", string(content))
+	assert.Equal(t, 1, downloader.downloads)
+
+	content, err = fs.ReadFile(rfs, "large.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "not embedded", string(content))
+	assert.Equal(t, 2, downloader.downloads)
+}
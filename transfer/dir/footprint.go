@@ -0,0 +1,36 @@
+package dir
+
+import "github.com/0glabs/0g-storage-client/core"
+
+// Footprint returns the total storage footprint of all files within the node. Note, embedded files are
+// excluded, since they are stored within the manifest.
+func (node *FsNode) Footprint() core.Footprint {
+	var total core.Footprint
+
+	node.Traverse(func(n *FsNode, _ string) error {
+		if n.Type == FileTypeFile && !n.Embedded() {
+			total = total.Add(core.StorageFootprint(n.Size))
+		}
+		return nil
+	})
+
+	return total
+}
+
+// TopLevelFootprints returns the storage footprints aggregated by the top-level entries of a directory node,
+// so that the storage could be charged by sub directories. Note, files directly under the directory are
+// aggregated under the key ".".
+func (node *FsNode) TopLevelFootprints() map[string]core.Footprint {
+	footprints := make(map[string]core.Footprint)
+
+	for _, entry := range node.Entries {
+		key := entry.Name
+		if entry.Type != FileTypeDirectory {
+			key = "."
+		}
+
+		footprints[key] = footprints[key].Add(entry.Footprint())
+	}
+
+	return footprints
+}
@@ -1,15 +1,22 @@
 package dir
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/parallel"
 	"github.com/0glabs/0g-storage-client/core"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 // FileType represents the file type in the FsNode structure.
@@ -29,13 +36,26 @@ type FsNode struct {
 	Size    int64     `json:"size,omitempty"`    // File size in bytes (only for regular files)
 	Link    string    `json:"link,omitempty"`    // Symbolic link target (only for symbolic links)
 	Entries []*FsNode `json:"entries,omitempty"` // Directory entries (only for directories)
+	Data    []byte    `json:"data,omitempty"`    // Embedded file content (only for small regular files if embedded)
+	Escaped bool      `json:"escaped,omitempty"` // Whether the name is percent-encoded, see NameEncodingPercent
+
+	// Permission bits and modification time in Unix nanoseconds (only for regular files and directories if the
+	// tree is built with metadata, see BuildOption.WithMetadata)
+	Mode    uint32 `json:"mode,omitempty"`
+	ModTime int64  `json:"modTime,omitempty"`
+
+	// Recursive total size and number of regular files within (only for directories if aggregated, see
+	// ComputeAggregates), which are excluded from the manifest, see CanonicalBytes
+	TotalSize int64 `json:"totalSize,omitempty"`
+	FileCount int   `json:"fileCount,omitempty"`
+
+	// Policy applied to encode file names that are not valid UTF-8 (only for root directory if any name encoded)
+	NameEncoding NameEncoding `json:"nameEncoding,omitempty"`
 }
 
 // NewDirFsNode creates a new FsNode representing a directory.
 func NewDirFsNode(name string, entryNodes []*FsNode) *FsNode {
-	sort.Slice(entryNodes, func(i, j int) bool {
-		return entryNodes[i].Name < entryNodes[j].Name
-	})
+	sortEntries(entryNodes)
 
 	return &FsNode{
 		Name:    name,
@@ -44,6 +64,13 @@ func NewDirFsNode(name string, entryNodes []*FsNode) *FsNode {
 	}
 }
 
+// sortEntries sorts directory entries by name.
+func sortEntries(entries []*FsNode) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+}
+
 // NewFileFsNode creates a new FsNode representing a regular file.
 func NewFileFsNode(name string, rootHash common.Hash, size int64) *FsNode {
 	return &FsNode{
@@ -75,79 +102,102 @@ func (node *FsNode) Search(fileName string) (*FsNode, bool) {
 	return nil, false
 }
 
+// EntriesPage returns at most limit directory entries starting from offset in order of name, along with the total
+// number of entries, so that huge directories could be listed page by page. Since entries are sorted by name, the
+// order is stable across pages. Negative offset is treated as 0, and non-positive limit returns all remaining.
+func (node *FsNode) EntriesPage(offset, limit int) ([]*FsNode, int) {
+	total := len(node.Entries)
+	offset = min(max(offset, 0), total)
+
+	end := total
+	if limit > 0 && limit < total-offset {
+		end = offset + limit
+	}
+
+	return node.Entries[offset:end], total
+}
+
 // Equal compares two FsNode structures for equality.
 func (node *FsNode) Equal(rhs *FsNode) bool {
-	if node.Type != rhs.Type || node.Name != rhs.Name {
-		return false
-	}
+	// compare iteratively with an explicit stack, so that deep trees never overflow the call stack
+	type pair struct{ lhs, rhs *FsNode }
 
-	switch node.Type {
-	case FileTypeFile:
-		return node.Root == rhs.Root
-	case FileTypeSymbolic:
-		return node.Link == rhs.Link
-	case FileTypeDirectory:
-		if len(node.Entries) != len(rhs.Entries) {
+	stack := []pair{{node, rhs}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		lhs, rhs := current.lhs, current.rhs
+		if lhs.Type != rhs.Type || lhs.Name != rhs.Name || lhs.Escaped != rhs.Escaped {
 			return false
 		}
-		for i := 0; i < len(node.Entries); i++ {
-			if !node.Entries[i].Equal(rhs.Entries[i]) {
+
+		switch lhs.Type {
+		case FileTypeFile:
+			if lhs.Root != rhs.Root {
+				return false
+			}
+		case FileTypeSymbolic:
+			if lhs.Link != rhs.Link {
 				return false
 			}
+		case FileTypeDirectory:
+			if len(lhs.Entries) != len(rhs.Entries) {
+				return false
+			}
+			for i := 0; i < len(lhs.Entries); i++ {
+				stack = append(stack, pair{lhs.Entries[i], rhs.Entries[i]})
+			}
+		default:
+			return false
 		}
-		return true
-	default:
-		return false
 	}
+
+	return true
 }
 
+var (
+	// ErrPathNotFound is returned by Locate when any component of path does not exist.
+	ErrPathNotFound = errors.New("path not found")
+
+	// ErrInvalidPath is returned by Locate when path contains "..", which is not allowed to escape the node.
+	ErrInvalidPath = errors.New("invalid path")
+)
+
 // Locate finds a sub-node within the FsNode tree based on the given path.
-// The path can be a file or directory, and it should be relative to the current node.
+// The path can be a file or directory, and it should be relative to the current node and separated by "/".
+// Empty path or "." refers to the current node. Every intermediate component must be a directory, and symbolic
+// links are not followed.
 func (node *FsNode) Locate(path string) (*FsNode, error) {
-	// Split the path into parts for recursive traversal
-	parts := strings.Split(filepath.Clean(path), string(os.PathSeparator))
-	// Filter out empty strings
-	var filteredParts []string
-	for _, part := range parts {
-		if len(part) > 0 {
-			filteredParts = append(filteredParts, part)
+	current := node
+	for _, part := range strings.Split(path, "/") {
+		// Skip empty strings and dot current
+		if len(part) == 0 || part == "." {
+			continue
 		}
-	}
-
-	// Start locating the first part
-	return node.locate(filteredParts)
-}
 
-// locate is a helper function to recursively find the sub-node
-func (node *FsNode) locate(parts []string) (*FsNode, error) {
-	// Base case: if the path is empty or dot current, return the current node
-	if len(parts) == 0 || (len(parts) == 1 && parts[0] == ".") {
-		return node, nil
-	}
-
-	// The current part of the path we are looking for
-	currentPart := parts[0]
+		if part == ".." {
+			return nil, errors.WithMessagef(ErrInvalidPath, "cannot locate '%s'", path)
+		}
 
-	// If the current node is not a directory, we can't traverse further
-	if node.Type != FileTypeDirectory {
-		return nil, fmt.Errorf("cannot locate '%s': '%s' is not a directory", currentPart, node.Name)
-	}
+		// If the current node is not a directory, we can't traverse further
+		if current.Type != FileTypeDirectory {
+			return nil, errors.WithMessagef(ErrPathNotFound, "cannot locate '%s': '%s' is not a directory", part, current.Name)
+		}
 
-	// Use the binary search method (Search) to locate the current part
-	if entry, found := node.Search(currentPart); found {
-		// If this is the final part of the path, return the entry
-		if len(parts) == 1 {
-			return entry, nil
+		// Use the binary search method (Search) to locate the current part
+		entry, found := current.Search(part)
+		if !found {
+			return nil, errors.WithMessagef(ErrPathNotFound, "'%s'", part)
 		}
-		// Otherwise, recursively locate in the sub-directory
-		return entry.locate(parts[1:])
+
+		current = entry
 	}
 
-	// If no entry is found, return an error
-	return nil, errors.Errorf("path not found: '%s'", currentPart)
+	return current, nil
 }
 
-// Flatten recursively flattens the FsNode tree into a slice of FsNode pointers and a slice of relative paths.
+// Flatten flattens the FsNode tree into a slice of FsNode pointers and a slice of relative paths.
 // The filterFunc is applied to each node to determine if it should be included in the result.
 func (node *FsNode) Flatten(filterFunc ...func(*FsNode) bool) (result []*FsNode, relpaths []string) {
 	node.Traverse(func(n *FsNode, p string) error {
@@ -160,8 +210,9 @@ func (node *FsNode) Flatten(filterFunc ...func(*FsNode) bool) (result []*FsNode,
 	return result, relpaths
 }
 
-// Traverse recursively traverses the FsNode tree and applies the provided actionFunc to each node.
-// This method only requires the user to handle relative paths.
+// Traverse traverses the FsNode tree in pre-order and applies the provided actionFunc to each node, where
+// entries of directory are visited in order. This method only requires the user to handle relative paths,
+// which are on local file system, see LocalName.
 //
 // Parameters:
 //
@@ -169,94 +220,517 @@ func (node *FsNode) Flatten(filterFunc ...func(*FsNode) bool) (result []*FsNode,
 //     takes the current node and its relative path as arguments. This function can perform any necessary
 //     operations, such as collecting nodes, uploading files, or logging information.
 func (node *FsNode) Traverse(actionFunc func(node *FsNode, relativePath string) error) error {
-	return node.traverse("", actionFunc)
-}
+	// traverse iteratively with an explicit stack, so that deep trees never overflow the call stack
+	type frame struct {
+		node    *FsNode
+		baseDir string
+	}
 
-// traverse is a helper function that manages relative paths during the traversal process.
-func (node *FsNode) traverse(baseDir string, actionFunc func(node *FsNode, relativePath string) error) error {
-	relative := filepath.Join(baseDir, node.Name)
+	stack := []frame{{node, ""}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
 
-	// Apply the action function to the current node
-	if err := actionFunc(node, relative); err != nil {
-		return err
-	}
+		name, err := current.node.LocalName()
+		if err != nil {
+			return err
+		}
 
-	if node.Type != FileTypeDirectory {
-		return nil
+		relative := filepath.Join(current.baseDir, name)
+
+		// Apply the action function to the current node
+		if err := actionFunc(current.node, relative); err != nil {
+			return err
+		}
+
+		if current.node.Type != FileTypeDirectory {
+			continue
+		}
+
+		// Push entries in reverse order, so that they are popped in order
+		for i := len(current.node.Entries) - 1; i >= 0; i-- {
+			stack = append(stack, frame{current.node.Entries[i], relative})
+		}
 	}
 
-	// If the node is a directory, recursively traverse its entries
-	for _, entry := range node.Entries {
-		if err := entry.traverse(relative, actionFunc); err != nil {
+	return nil
+}
+
+// NamedFsNode is a node in file tree along with its path relative to the root of tree.
+type NamedFsNode struct {
+	Path string  // slash-separated path relative to the root of tree, e.g. "a/b.txt"
+	Node *FsNode // node at the path
+}
+
+// Walk walks the FsNode tree depth-first in pre-order, where entries of directory are visited in order, and calls
+// fn with the slash-separated path relative to this node, which is empty for this node itself. Unlike Traverse,
+// names in path are as stored in directory metadata, see LocalName to map them to local file system.
+//
+// Walk stops once fn returns an error, and returns the error.
+func (node *FsNode) Walk(fn func(path string, node *FsNode) error) error {
+	// walk iteratively with an explicit stack, so that deep trees never overflow the call stack
+	stack := []NamedFsNode{{Node: node}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if err := fn(current.Path, current.Node); err != nil {
 			return err
 		}
+
+		if current.Node.Type != FileTypeDirectory {
+			continue
+		}
+
+		// Push entries in reverse order, so that they are popped in order
+		for i := len(current.Node.Entries) - 1; i >= 0; i-- {
+			entry := current.Node.Entries[i]
+			stack = append(stack, NamedFsNode{path.Join(current.Path, entry.Name), entry})
+		}
 	}
 
 	return nil
 }
 
-// BuildFileTree recursively builds a file tree for the specified directory.
-func BuildFileTree(path string) (*FsNode, error) {
-	info, err := os.Stat(path)
+// Leaves returns all regular files and symbolic links in the FsNode tree in the order of Walk, along with the
+// slash-separated paths relative to this node.
+func (node *FsNode) Leaves() []NamedFsNode {
+	leaves := make([]NamedFsNode, 0)
+
+	node.Walk(func(path string, node *FsNode) error {
+		if node.Type == FileTypeFile || node.Type == FileTypeSymbolic {
+			leaves = append(leaves, NamedFsNode{path, node})
+		}
+		return nil
+	})
+
+	return leaves
+}
+
+// BuildOption is the option to build file tree.
+type BuildOption struct {
+	Hash         core.HashOption // option to read files when calculating merkle roots
+	NameEncoding NameEncoding    // policy to encode file names that are not valid UTF-8, replaced by default
+
+	// OnNameReplaced is called with the path of file whose name is replaced under the NameEncodingReplace policy,
+	// which is the default.
+	// By default, a warning is logged.
+	OnNameReplaced func(path string)
+
+	// Limits of depth and relative path length, which are DefaultMaxDepth and DefaultMaxPathLength by default.
+	Limits Limits
+
+	// Number of files hashed concurrently, which are hashed one by one while walking the directory if 0 or 1.
+	Workers int
+
+	// Gitignore-style patterns of entries to ignore, which never appear in the file tree, see LoadIgnoreFile.
+	Ignore *IgnorePatterns
+
+	// IgnoreFunc returns whether to ignore the entry of relative path to root directory, separated by "/", which is
+	// applied along with Ignore. Entries of an ignored directory are ignored as well.
+	IgnoreFunc func(relpath string, info os.FileInfo) bool
+
+	// Policy to handle symbolic links that escape the root directory or form a cycle, rejected by default.
+	Symlinks SymlinkPolicy
+
+	// OnSymlinkSkipped is called with the slash-separated path of symbolic link skipped under the SymlinkSkip
+	// policy, along with the reason. By default, a warning is logged.
+	OnSymlinkSkipped func(relpath string, err error)
+
+	// Policy to handle special files, e.g. named pipes, sockets and device nodes, skipped by default.
+	SpecialFiles SpecialFilePolicy
+
+	// OnSpecialFileSkipped is called with the slash-separated path of special file skipped under the
+	// SpecialFilesSkip policy, along with the file type, see SpecialFileType. By default, a warning is logged.
+	OnSpecialFileSkipped func(relpath, fileType string)
+
+	// Policy to handle file names that are not portable across common download targets, e.g. collide
+	// case-insensitively or invalid on Windows, rejected by default.
+	PortableNames PortableNamePolicy
+
+	// OnNonPortableName is called with each file name not portable under the PortableNamesWarn policy. By default,
+	// a warning is logged.
+	OnNonPortableName func(issue NameIssue)
+
+	// KnownRoot returns the merkle root of regular file at the relative path to root directory, separated by "/",
+	// if known to be unchanged, e.g. calculated by an earlier build, so that the file is not hashed again.
+	KnownRoot func(relpath string, info os.FileInfo) (common.Hash, bool)
+
+	// Metadata records the permission bits and modification time of regular files and directories, which are
+	// restored on download. Disabled by default, so that the directory metadata remains the same as before.
+	Metadata bool
+
+	// OnProgress is called with the progress of discovering and hashing files, see WithProgress. Callbacks are
+	// never invoked concurrently, even if files hashed by multiple workers.
+	OnProgress func(p BuildProgress)
+
+	// Min interval between progress callbacks, DefaultProgressInterval if 0.
+	ProgressInterval time.Duration
+}
+
+// WithMetadata records the permission bits and modification time of regular files and directories.
+func (opt BuildOption) WithMetadata() BuildOption {
+	opt.Metadata = true
+	return opt
+}
+
+// WithIgnore sets the gitignore-style patterns of entries to ignore.
+func (opt BuildOption) WithIgnore(ignore *IgnorePatterns) BuildOption {
+	opt.Ignore = ignore
+	return opt
+}
+
+// ignored returns whether the entry is ignored by patterns or predicate.
+func (opt BuildOption) ignored(relpath string, entry os.DirEntry) (bool, error) {
+	if opt.Ignore.Len() == 0 && opt.IgnoreFunc == nil {
+		return false, nil
+	}
+
+	relpath = filepath.ToSlash(relpath)
+	if opt.Ignore.Match(relpath, entry.IsDir()) {
+		return true, nil
+	}
+
+	if opt.IgnoreFunc == nil {
+		return false, nil
+	}
+
+	info, err := entry.Info()
 	if err != nil {
-		return nil, errors.WithMessagef(err, "failed to stat file %s", path)
+		return false, errors.WithMessagef(err, "failed to stat file %s", relpath)
+	}
+
+	return opt.IgnoreFunc(relpath, info), nil
+}
+
+// WithWorkers sets the number of files hashed concurrently, which speeds up building the file tree of directory
+// with many small files.
+func (opt BuildOption) WithWorkers(workers int) BuildOption {
+	opt.Workers = workers
+	return opt
+}
+
+// BuildFileTree builds a file tree for the specified directory, where files are read as specified
+// by option if any to calculate merkle roots.
+func BuildFileTree(path string, option ...core.HashOption) (*FsNode, error) {
+	var opt BuildOption
+	if len(option) > 0 {
+		opt.Hash = option[0]
+	}
+
+	return BuildFileTreeWithOption(path, opt)
+}
+
+// BuildFileTreeWithOption builds a file tree for the specified directory with option. The name encoding
+// policy is recorded in the root directory if any file name is not valid UTF-8, and ErrTreeTooDeep or
+// ErrPathTooLong is returned if the directory exceeds the limits. Symbolic links that escape the root directory
+// or form a cycle are handled by the symbolic link policy, see SymlinkPolicy, and file names not portable across
+// common download targets are handled by the portable name policy, see PortableNamePolicy.
+//
+// If more than 1 worker specified, files are hashed concurrently after the directory walked, and the resulting
+// tree is identical to the one built serially. Once any file failed to hash, files not hashed yet are abandoned.
+func BuildFileTreeWithOption(path string, opt BuildOption) (*FsNode, error) {
+	root, _, err := buildFileTree(osSource{}, path, os.Stat, opt)
+	return root, err
+}
+
+// BuildFileTreeWithStats builds a file tree for the specified directory with option as BuildFileTreeWithOption,
+// and returns the aggregate statistics of regular files along with the root node.
+func BuildFileTreeWithStats(path string, opt BuildOption) (*FsNode, BuildStats, error) {
+	return buildFileTree(osSource{}, path, os.Stat, opt)
+}
+
+// BuildFileTreeFS builds a file tree for the directory root of fsys, e.g. embed.FS, fstest.MapFS or zip archives,
+// so that generated content could be uploaded without writing to disk. The tree is identical to the one built
+// from the same layout on local file system, and hence the same manifest root.
+//
+// Symbolic links are built only if fsys implements ReadLinkFS, and skipped otherwise.
+func BuildFileTreeFS(fsys fs.FS, root string) (*FsNode, error) {
+	return BuildFileTreeFSWithOption(fsys, root, BuildOption{})
+}
+
+// BuildFileTreeFSWithOption builds a file tree for the directory root of fsys with option, see BuildFileTreeFS
+// and BuildFileTreeWithOption.
+func BuildFileTreeFSWithOption(fsys fs.FS, root string, opt BuildOption) (*FsNode, error) {
+	node, _, err := buildFileTree(fsSource{fsys}, root, func(name string) (fs.FileInfo, error) {
+		return fs.Stat(fsys, name)
+	}, opt)
+
+	return node, err
+}
+
+// buildFileTree builds a file tree for the directory of source with option.
+func buildFileTree(source treeSource, path string, stat func(name string) (fs.FileInfo, error), opt BuildOption) (*FsNode, BuildStats, error) {
+	if err := opt.NameEncoding.Validate(); err != nil {
+		return nil, BuildStats{}, err
+	}
+
+	if err := opt.Symlinks.Validate(); err != nil {
+		return nil, BuildStats{}, err
+	}
+
+	if err := opt.PortableNames.Validate(); err != nil {
+		return nil, BuildStats{}, err
+	}
+
+	if err := opt.SpecialFiles.Validate(); err != nil {
+		return nil, BuildStats{}, err
+	}
+
+	if err := zg_common.RequireNonNegative("Workers", opt.Workers); err != nil {
+		return nil, BuildStats{}, err
+	}
+
+	info, err := stat(path)
+	if err != nil {
+		return nil, BuildStats{}, errors.WithMessagef(err, "failed to stat file %s", path)
 	}
 
 	if !info.IsDir() {
-		return nil, errors.New("file tree building is only supported for directory")
+		return nil, BuildStats{}, errors.New("file tree building is only supported for directory")
 	}
 
-	root, err := build(path)
+	start := time.Now()
+	builder := treeBuilder{source: source, opt: opt, progress: newProgressTracker(opt)}
+	root, err := builder.build(path)
 	if err != nil {
-		return nil, err
+		return nil, BuildStats{}, err
+	}
+
+	if err := builder.checkSymlinks(root, path); err != nil {
+		return nil, BuildStats{}, err
+	}
+
+	if err := builder.checkPortableNames(root); err != nil {
+		return nil, BuildStats{}, err
+	}
+
+	if err := builder.hashFiles(); err != nil {
+		return nil, BuildStats{}, err
 	}
 
 	// Set root directory name
 	root.Name = "/"
+	root.Escaped = false
+	if builder.encoded {
+		root.NameEncoding = opt.NameEncoding.orDefault()
+	}
+
+	return root, builder.progress.finish(start), nil
+}
+
+// treeBuilder builds file tree, and tracks whether any file name encoded.
+type treeBuilder struct {
+	source  treeSource
+	opt     BuildOption
+	encoded bool
+	pending []pendingFile // files to hash concurrently once directory walked
+
+	progress *progressTracker
+}
+
+// pendingFile is a regular file of which the merkle root is not calculated yet.
+type pendingFile struct {
+	node *FsNode
+	path string
+}
+
+// buildFrame is a directory to read entries from when building file tree.
+type buildFrame struct {
+	node    *FsNode // directory node to fill entries
+	path    string  // path on local file system
+	relpath string  // path relative to the root directory
+	depth   int     // depth of the directory, where root directory is at depth 0
+}
+
+// build is a helper function that builds a file tree starting from the specified path iteratively with an explicit
+// stack, so that deep directories never overflow the call stack.
+func (builder *treeBuilder) build(path string) (*FsNode, error) {
+	root, err := builder.buildNode(path, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if root.Type != FileTypeDirectory {
+		return root, nil
+	}
+
+	var dirs []*FsNode
+	stack := []buildFrame{{node: root, path: path}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		dirs = append(dirs, current.node)
+
+		entries, err := builder.source.readDir(current.path)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read directory %s", current.path)
+		}
+
+		for _, entry := range entries {
+			entryPath := builder.source.join(current.path, entry.Name())
+			relpath := filepath.Join(current.relpath, entry.Name())
+			if ignored, err := builder.opt.ignored(relpath, entry); err != nil {
+				return nil, err
+			} else if ignored {
+				continue
+			}
+
+			if err := builder.opt.Limits.check(current.depth+1, relpath); err != nil {
+				return nil, err
+			}
+
+			entryNode, err := builder.buildNode(entryPath, relpath)
+			if err != nil {
+				return nil, err
+			}
+			if entryNode == nil {
+				continue
+			}
+			current.node.Entries = append(current.node.Entries, entryNode)
+
+			if entryNode.Type == FileTypeDirectory {
+				stack = append(stack, buildFrame{entryNode, entryPath, relpath, current.depth + 1})
+			}
+		}
+	}
+
+	// entries are sorted by encoded names, which may differ from the order on local file system
+	for _, directory := range dirs {
+		sortEntries(directory.Entries)
+	}
+
 	return root, nil
 }
 
-// build is a helper function that recursively builds a file tree starting from the specified path.
-func build(path string) (*FsNode, error) {
-	info, err := os.Lstat(path)
+// buildNode creates an FsNode for the specified path, where entries of directory are not filled. Returns nil if
+// the special file skipped, see SpecialFilePolicy.
+func (builder *treeBuilder) buildNode(path, relpath string) (*FsNode, error) {
+	info, err := builder.source.lstat(path)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to stat file %s", path)
 	}
 
+	var known bool
+	var knownRoot common.Hash
+	if info.Mode().IsRegular() && info.Size() > 0 && builder.opt.KnownRoot != nil {
+		knownRoot, known = builder.opt.KnownRoot(filepath.ToSlash(relpath), info)
+	}
+
+	var node *FsNode
 	switch {
 	case info.IsDir():
-		return buildDirectoryNode(path, info)
+		node = NewDirFsNode(info.Name(), nil)
 	case info.Mode()&os.ModeSymlink != 0:
-		return buildSymbolicNode(path, info)
+		node, err = builder.buildSymbolicNode(path, info)
+	case known:
+		builder.progress.discovered(path)
+		builder.progress.hashed(path, info.Size())
+		node = NewFileFsNode(info.Name(), knownRoot, info.Size())
+	case info.Mode().IsRegular() && builder.opt.Workers > 1 && info.Size() > 0:
+		builder.progress.discovered(path)
+		node = NewFileFsNode(info.Name(), common.Hash{}, info.Size())
+		builder.pending = append(builder.pending, pendingFile{node, path})
 	case info.Mode().IsRegular():
-		return buildFileNode(path, info)
+		builder.progress.discovered(path)
+		node, err = builder.buildFileNode(path, info)
 	default:
-		return nil, errors.New("unsupported file type")
+		return nil, builder.skipSpecialFile(path, relpath, info)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if builder.opt.Metadata {
+		setMetadata(node, info)
 	}
+
+	return node, builder.encodeName(node, path)
 }
 
-// buildDirectoryNode creates an FsNode for a directory, including its contents.
-func buildDirectoryNode(path string, info os.FileInfo) (*FsNode, error) {
-	entries, err := os.ReadDir(path)
+// skipSpecialFile handles the special file by policy, and returns ErrSpecialFile if rejected.
+func (builder *treeBuilder) skipSpecialFile(path, relpath string, info os.FileInfo) error {
+	fileType := SpecialFileType(info.Mode())
+	if builder.opt.SpecialFiles == SpecialFilesReject {
+		return errors.WithMessagef(ErrSpecialFile, "%s is %s", path, fileType)
+	}
+
+	relpath = filepath.ToSlash(relpath)
+	if builder.opt.OnSpecialFileSkipped != nil {
+		builder.opt.OnSpecialFileSkipped(relpath, fileType)
+	} else {
+		logrus.WithFields(logrus.Fields{
+			"path": relpath,
+			"type": fileType,
+		}).Warn("Special file skipped")
+	}
+
+	return nil
+}
+
+// setMetadata records the permission bits and modification time of regular file or directory.
+func setMetadata(node *FsNode, info os.FileInfo) {
+	if node.Type != FileTypeFile && node.Type != FileTypeDirectory {
+		return
+	}
+
+	node.Mode = uint32(info.Mode().Perm())
+	if modTime := info.ModTime(); !modTime.IsZero() {
+		node.ModTime = modTime.UnixNano()
+	}
+}
+
+// hashFiles calculates the merkle roots of pending files in parallel.
+func (builder *treeBuilder) hashFiles() error {
+	return parallel.Serial(context.Background(), builder, len(builder.pending), parallel.SerialOption{
+		Routines: builder.opt.Workers,
+	})
+}
+
+// ParallelDo implements the parallel.Interface interface.
+func (builder *treeBuilder) ParallelDo(ctx context.Context, routine, task int) (interface{}, error) {
+	file := builder.pending[task]
+
+	hash, err := builder.source.merkleRoot(file.path, builder.opt.Hash)
 	if err != nil {
-		return nil, errors.WithMessagef(err, "failed to read directory %s", path)
+		return nil, errors.WithMessagef(err, "failed to calculate merkle root for %s", file.path)
 	}
+	builder.progress.hashed(file.path, file.node.Size)
 
-	var entryNodes []*FsNode
-	for _, entry := range entries {
-		entryPath := filepath.Join(path, entry.Name())
-		entryNode, err := build(entryPath)
-		if err != nil {
-			return nil, err
+	return hash, nil
+}
+
+// ParallelCollect implements the parallel.Interface interface.
+func (builder *treeBuilder) ParallelCollect(result *parallel.Result) error {
+	builder.pending[result.Task].node.Root = result.Value.(common.Hash).Hex()
+	return nil
+}
+
+// encodeName encodes the node name by policy if not valid UTF-8.
+func (builder *treeBuilder) encodeName(node *FsNode, path string) error {
+	name, escaped, replaced, err := builder.opt.NameEncoding.encodeName(node.Name, path)
+	if err != nil {
+		return err
+	}
+
+	if replaced {
+		if builder.opt.OnNameReplaced != nil {
+			builder.opt.OnNameReplaced(path)
+		} else {
+			logrus.WithField("path", fmt.Sprintf("%q", path)).Warn("File name is not valid UTF-8, and replaced")
 		}
-		entryNodes = append(entryNodes, entryNode)
 	}
-	return NewDirFsNode(info.Name(), entryNodes), nil
+
+	builder.encoded = builder.encoded || escaped || replaced
+	node.Name, node.Escaped = name, escaped
+
+	return nil
 }
 
 // buildSymbolicNode creates an FsNode for a symbolic link.
-func buildSymbolicNode(path string, info os.FileInfo) (*FsNode, error) {
-	link, err := os.Readlink(path)
+func (builder *treeBuilder) buildSymbolicNode(path string, info os.FileInfo) (*FsNode, error) {
+	link, err := builder.source.readlink(path)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "invalid symbolic link %s", path)
 	}
@@ -265,14 +739,17 @@ func buildSymbolicNode(path string, info os.FileInfo) (*FsNode, error) {
 }
 
 // buildFileNode creates an FsNode for a regular file, including its Merkle root hash.
-func buildFileNode(path string, info os.FileInfo) (*FsNode, error) {
+func (builder *treeBuilder) buildFileNode(path string, info os.FileInfo) (*FsNode, error) {
 	if info.Size() == 0 {
+		builder.progress.hashed(path, 0)
 		return NewFileFsNode(info.Name(), common.Hash{}, 0), nil
 	}
 
-	hash, err := core.MerkleRoot(path)
+	hash, err := builder.source.merkleRoot(path, builder.opt.Hash)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed to calculate merkle root for %s", path)
 	}
+	builder.progress.hashed(path, info.Size())
+
 	return NewFileFsNode(info.Name(), hash, info.Size()), nil
 }
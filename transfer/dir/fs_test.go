@@ -1,10 +1,15 @@
 package dir_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/0glabs/0g-storage-client/core"
 	"github.com/0glabs/0g-storage-client/transfer/dir"
@@ -61,6 +66,36 @@ func TestSearch(t *testing.T) {
 	assert.Nil(t, result)
 }
 
+func TestEntriesPage(t *testing.T) {
+	var children []*dir.FsNode
+	for i := 0; i < 5; i++ {
+		children = append(children, &dir.FsNode{Name: fmt.Sprintf("child%v", 4-i)})
+	}
+	node := dir.NewDirFsNode("root", children)
+
+	for _, c := range []struct {
+		offset, limit int
+		expected      []string
+	}{
+		{0, 2, []string{"child0", "child1"}},
+		{2, 2, []string{"child2", "child3"}},
+		{4, 2, []string{"child4"}},
+		{5, 2, nil},
+		{10, 2, nil},
+		{-1, 1, []string{"child0"}},
+		{3, 0, []string{"child3", "child4"}},
+	} {
+		entries, total := node.EntriesPage(c.offset, c.limit)
+		assert.Equal(t, 5, total)
+
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name)
+		}
+		assert.Equal(t, c.expected, names, "offset %v, limit %v", c.offset, c.limit)
+	}
+}
+
 func TestFsNodeEqual(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -209,6 +244,247 @@ func TestBuildFileTree(t *testing.T) {
 	})
 }
 
+func TestBuildFileTreeMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, "run.sh"), []byte("#!/bin/sh"), 0755))
+	assert.NoError(t, os.Chmod(filepath.Join(tempDir, "run.sh"), 0750))
+	assert.NoError(t, os.Chtimes(filepath.Join(tempDir, "run.sh"), modTime, modTime))
+	assert.NoError(t, os.Mkdir(filepath.Join(tempDir, "private"), 0700))
+	assert.NoError(t, os.Chtimes(filepath.Join(tempDir, "private"), modTime, modTime))
+	assert.NoError(t, os.Symlink("run.sh", filepath.Join(tempDir, "link")))
+
+	// not recorded by default, so that the manifest root is unchanged
+	plain, err := dir.BuildFileTree(tempDir)
+	assert.NoError(t, err)
+	assert.NoError(t, plain.Walk(func(path string, node *dir.FsNode) error {
+		assert.Zero(t, node.Mode, path)
+		assert.Zero(t, node.ModTime, path)
+		return nil
+	}))
+
+	encoded, err := dir.CanonicalBytes(plain)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(encoded), `"mode"`)
+	assert.NotContains(t, string(encoded), `"modTime"`)
+
+	tree, err := dir.BuildFileTreeWithOption(tempDir, dir.BuildOption{}.WithMetadata())
+	assert.NoError(t, err)
+	assert.True(t, plain.Equal(tree))
+
+	script, err := tree.Locate("run.sh")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0750), script.Mode)
+	assert.Equal(t, modTime.UnixNano(), script.ModTime)
+
+	private, err := tree.Locate("private")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0700), private.Mode)
+	assert.Equal(t, modTime.UnixNano(), private.ModTime)
+
+	link, err := tree.Locate("link")
+	assert.NoError(t, err)
+	assert.Zero(t, link.Mode)
+	assert.Zero(t, link.ModTime)
+
+	plainRoot, err := dir.ManifestRoot(plain)
+	assert.NoError(t, err)
+	treeRoot, err := dir.ManifestRoot(tree)
+	assert.NoError(t, err)
+	assert.NotEqual(t, plainRoot, treeRoot)
+
+	// round trip in both canonical and legacy forms
+	for _, encode := range []func(*dir.FsNode) ([]byte, error){dir.CanonicalBytes, (*dir.FsNode).MarshalBinary} {
+		encoded, err := encode(tree)
+		assert.NoError(t, err)
+
+		var decoded dir.FsNode
+		assert.NoError(t, decoded.UnmarshalBinary(encoded))
+		assert.Equal(t, tree, &decoded)
+	}
+}
+
+// linkFS is os.DirFS that supports symbolic links regardless of Go version.
+type linkFS struct {
+	fs.FS
+	dir string
+}
+
+func (fsys linkFS) ReadLink(name string) (string, error) {
+	return os.Readlink(filepath.Join(fsys.dir, name))
+}
+
+func (fsys linkFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(filepath.Join(fsys.dir, name))
+}
+
+// streamFS hides io.ReaderAt of files, e.g. files of zip archives.
+type streamFS struct {
+	fs.FS
+}
+
+func (fsys streamFS) Open(name string) (fs.File, error) {
+	file, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := file.Stat(); err != nil || info.IsDir() {
+		return file, err
+	}
+
+	return struct{ fs.File }{file}, nil
+}
+
+func TestBuildFileTreeFS(t *testing.T) {
+	large := bytes.Repeat([]byte("0123456789"), 50_000)
+	files := fstest.MapFS{
+		"large.bin":     {Data: large},
+		"empty":         {Data: []byte{}},
+		"sub/small.txt": {Data: []byte("small content")},
+	}
+
+	tempDir := t.TempDir()
+	for name, file := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(tempDir, filepath.Dir(name)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(tempDir, name), file.Data, 0644))
+	}
+
+	expected, err := dir.BuildFileTree(tempDir)
+	assert.NoError(t, err)
+	expectedRoot, err := dir.ManifestRoot(expected)
+	assert.NoError(t, err)
+
+	// identical to the tree built from local file system
+	for _, fsys := range []fs.FS{files, streamFS{files}, os.DirFS(tempDir)} {
+		tree, err := dir.BuildFileTreeFS(fsys, ".")
+		assert.NoError(t, err)
+		assert.True(t, expected.Equal(tree))
+
+		root, err := dir.ManifestRoot(tree)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedRoot, root)
+	}
+
+	tree, err := dir.BuildFileTreeFSWithOption(files, ".", dir.BuildOption{}.WithWorkers(4))
+	assert.NoError(t, err)
+	assert.True(t, expected.Equal(tree))
+
+	// sub directory as root
+	expectedSub, err := dir.BuildFileTree(filepath.Join(tempDir, "sub"))
+	assert.NoError(t, err)
+	sub, err := dir.BuildFileTreeFS(files, "sub")
+	assert.NoError(t, err)
+	assert.True(t, expectedSub.Equal(sub))
+
+	_, err = dir.BuildFileTreeFS(files, "large.bin")
+	assert.Error(t, err)
+	_, err = dir.BuildFileTreeFS(files, "missing")
+	assert.Error(t, err)
+
+	// symbolic links built only if supported
+	assert.NoError(t, os.Symlink("large.bin", filepath.Join(tempDir, "link")))
+	assert.NoError(t, os.Symlink("../../escaped", filepath.Join(tempDir, "sub", "escaped")))
+
+	_, err = dir.BuildFileTreeFS(linkFS{os.DirFS(tempDir), tempDir}, ".")
+	assert.ErrorIs(t, err, dir.ErrSymlinkEscape)
+
+	assert.NoError(t, os.Remove(filepath.Join(tempDir, "sub", "escaped")))
+	expected, err = dir.BuildFileTree(tempDir)
+	assert.NoError(t, err)
+	tree, err = dir.BuildFileTreeFS(linkFS{os.DirFS(tempDir), tempDir}, ".")
+	assert.NoError(t, err)
+	assert.True(t, expected.Equal(tree))
+
+	link, err := tree.Locate("link")
+	assert.NoError(t, err)
+	assert.Equal(t, "large.bin", link.Link)
+
+	tree, err = dir.BuildFileTreeFS(struct{ fs.FS }{os.DirFS(tempDir)}, ".")
+	assert.NoError(t, err)
+	_, err = tree.Locate("link")
+	assert.ErrorIs(t, err, dir.ErrPathNotFound)
+}
+
+func TestBuildFileTreeWorkers(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// files of various sizes in nested directories, including empty ones
+	for i := 0; i < 100; i++ {
+		sub := filepath.Join(tempDir, fmt.Sprintf("dir%v", i%7), fmt.Sprintf("sub%v", i%3))
+		assert.NoError(t, os.MkdirAll(sub, 0755))
+		content := bytes.Repeat([]byte{byte(i)}, i*(core.DefaultSegmentSize/10))
+		assert.NoError(t, os.WriteFile(filepath.Join(sub, fmt.Sprintf("file%v", i)), content, 0644))
+	}
+	assert.NoError(t, os.Symlink("dir0", filepath.Join(tempDir, "link")))
+
+	serial, err := dir.BuildFileTree(tempDir)
+	assert.NoError(t, err)
+
+	parallel, err := dir.BuildFileTreeWithOption(tempDir, dir.BuildOption{}.WithWorkers(8))
+	assert.NoError(t, err)
+	assert.True(t, serial.Equal(parallel))
+
+	// identical in the encoded manifest as well
+	expected, err := serial.MarshalBinary()
+	assert.NoError(t, err)
+	actual, err := parallel.MarshalBinary()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	_, err = dir.BuildFileTreeWithOption(tempDir, dir.BuildOption{Workers: -1})
+	assert.Error(t, err)
+}
+
+func TestBuildFileTreeProgress(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var totalSize int64
+	for i := 0; i < 20; i++ {
+		sub := filepath.Join(tempDir, fmt.Sprintf("dir%v", i%3))
+		assert.NoError(t, os.MkdirAll(sub, 0755))
+		content := bytes.Repeat([]byte{byte(i)}, i*1000)
+		assert.NoError(t, os.WriteFile(filepath.Join(sub, fmt.Sprintf("file%v", i)), content, 0644))
+		totalSize += int64(len(content))
+	}
+
+	for _, workers := range []int{0, 4} {
+		// reported on every file
+		var reports []dir.BuildProgress
+		opt := dir.BuildOption{Workers: workers, ProgressInterval: time.Nanosecond}.WithProgress(func(p dir.BuildProgress) {
+			reports = append(reports, p)
+		})
+
+		_, stats, err := dir.BuildFileTreeWithStats(tempDir, opt)
+		assert.NoError(t, err)
+		assert.Equal(t, 20, stats.Files)
+		assert.Equal(t, totalSize, stats.Bytes)
+		assert.Greater(t, stats.Elapsed, time.Duration(0))
+
+		assert.Greater(t, len(reports), 20, "workers %v", workers)
+		last := reports[len(reports)-1]
+		assert.Equal(t, 20, last.FilesDiscovered)
+		assert.Equal(t, 20, last.FilesHashed)
+		assert.Equal(t, totalSize, last.BytesHashed)
+		assert.NotEmpty(t, last.Path)
+
+		for i := 1; i < len(reports); i++ {
+			assert.GreaterOrEqual(t, reports[i].FilesHashed, reports[i-1].FilesHashed)
+			assert.GreaterOrEqual(t, reports[i].BytesHashed, reports[i-1].BytesHashed)
+			assert.LessOrEqual(t, reports[i].FilesHashed, reports[i].FilesDiscovered)
+		}
+
+		// bounded rate, at least the first and final progress reported
+		reports = nil
+		opt.ProgressInterval = time.Hour
+		_, err = dir.BuildFileTreeWithOption(tempDir, opt)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(reports))
+		assert.Equal(t, 20, reports[1].FilesHashed)
+	}
+}
+
 func TestTraverse(t *testing.T) {
 	// Create a mock directory structure
 	root := &dir.FsNode{
@@ -279,6 +555,45 @@ func TestTraverse(t *testing.T) {
 	}
 }
 
+func TestWalk(t *testing.T) {
+	root := dir.NewDirFsNode("/", []*dir.FsNode{
+		dir.NewDirFsNode("b", []*dir.FsNode{
+			dir.NewFileFsNode("y.txt", common.HexToHash("0x02"), 2),
+			dir.NewDirFsNode("empty", nil),
+			dir.NewFileFsNode("x.txt", common.HexToHash("0x01"), 1),
+		}),
+		dir.NewSymbolicFsNode("a", "b/x.txt"),
+		{Name: "c", Type: dir.FileTypeDirectory},
+	})
+
+	var paths []string
+	assert.NoError(t, root.Walk(func(path string, node *dir.FsNode) error {
+		paths = append(paths, path)
+		return nil
+	}))
+	assert.Equal(t, []string{"", "a", "b", "b/empty", "b/x.txt", "b/y.txt", "c"}, paths)
+
+	var leaves []string
+	for _, leaf := range root.Leaves() {
+		leaves = append(leaves, leaf.Path)
+	}
+	assert.Equal(t, []string{"a", "b/x.txt", "b/y.txt"}, leaves)
+	assert.Empty(t, dir.NewDirFsNode("empty", nil).Leaves())
+
+	// stops at the first error
+	stop := errors.New("stop")
+	paths = nil
+	err := root.Walk(func(path string, node *dir.FsNode) error {
+		paths = append(paths, path)
+		if path == "b" {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, []string{"", "a", "b"}, paths)
+}
+
 func TestLocate(t *testing.T) {
 	// Setup a sample directory structure
 	root := &dir.FsNode{
@@ -355,6 +670,18 @@ func TestLocate(t *testing.T) {
 			expected:  root,
 			expectErr: false,
 		},
+		{
+			name:      "locate current directory",
+			path:      ".",
+			expected:  root,
+			expectErr: false,
+		},
+		{
+			name:      "locate with dot components",
+			path:      "./subdir/./innerdir/",
+			expected:  root.Entries[1].Entries[1],
+			expectErr: false,
+		},
 	}
 
 	for _, tc := range tests {
@@ -369,3 +696,59 @@ func TestLocate(t *testing.T) {
 		})
 	}
 }
+
+func TestLocateErrors(t *testing.T) {
+	root := dir.NewDirFsNode("/", []*dir.FsNode{
+		dir.NewFileFsNode("file1.txt", common.Hash{}, 1),
+		dir.NewSymbolicFsNode("link", "subdir"),
+		dir.NewDirFsNode("subdir", []*dir.FsNode{
+			dir.NewFileFsNode("file2.txt", common.Hash{}, 2),
+		}),
+	})
+
+	// missing intermediate directory
+	_, err := root.Locate("missing/subdir/file2.txt")
+	assert.ErrorIs(t, err, dir.ErrPathNotFound)
+
+	// prefix resolves to a file
+	_, err = root.Locate("file1.txt/file2.txt")
+	assert.ErrorIs(t, err, dir.ErrPathNotFound)
+	assert.ErrorContains(t, err, "not a directory")
+
+	// symbolic link not followed
+	_, err = root.Locate("link/file2.txt")
+	assert.ErrorIs(t, err, dir.ErrPathNotFound)
+	node, err := root.Locate("link")
+	assert.NoError(t, err)
+	assert.Equal(t, dir.FileTypeSymbolic, node.Type)
+
+	// not allowed to escape, even if resolved within the tree
+	_, err = root.Locate("subdir/../file1.txt")
+	assert.ErrorIs(t, err, dir.ErrInvalidPath)
+	_, err = root.Locate("..")
+	assert.ErrorIs(t, err, dir.ErrInvalidPath)
+}
+
+func TestFootprint(t *testing.T) {
+	root := dir.NewDirFsNode("/", []*dir.FsNode{
+		dir.NewFileFsNode("a.txt", common.HexToHash("0x1"), 1),
+		dir.NewFileFsNode("empty.txt", common.Hash{}, 0),
+		dir.NewSymbolicFsNode("link", "a.txt"),
+		dir.NewDirFsNode("team1", []*dir.FsNode{
+			dir.NewFileFsNode("b.txt", common.HexToHash("0x2"), 257),
+			dir.NewDirFsNode("sub", []*dir.FsNode{
+				dir.NewFileFsNode("c.txt", common.HexToHash("0x3"), core.DefaultSegmentSize+1),
+			}),
+		}),
+		dir.NewDirFsNode("team2", []*dir.FsNode{}),
+	})
+
+	footprints := root.TopLevelFootprints()
+	assert.Len(t, footprints, 3)
+	assert.Equal(t, core.StorageFootprint(1), footprints["."])
+	assert.Equal(t, core.StorageFootprint(257).Add(core.StorageFootprint(core.DefaultSegmentSize+1)), footprints["team1"])
+	assert.Equal(t, core.Footprint{}, footprints["team2"])
+
+	total := footprints["."].Add(footprints["team1"])
+	assert.Equal(t, total, root.Footprint())
+}
@@ -0,0 +1,216 @@
+package dir
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IgnoreFileName is the name of file at the root of directory, which lists the patterns of entries to ignore when
+// building file tree, see LoadIgnoreFile.
+const IgnoreFileName = ".0gignore"
+
+// ignoreRule is a gitignore-style pattern compiled into regular expression.
+type ignoreRule struct {
+	pattern string
+	regexp  *regexp.Regexp
+	negate  bool // re-include entries ignored by previous rules
+	dirOnly bool // only matches directories
+}
+
+// IgnorePatterns is a list of gitignore-style patterns to ignore entries when building file tree, of which the
+// last matched pattern takes effect:
+//
+//   - Blank lines and lines starting with "#" are skipped, and "\#" escapes the leading "#".
+//   - A pattern starting with "!" re-includes entries ignored by previous patterns, and "\!" escapes it.
+//   - A pattern ending with "/" only matches directories.
+//   - A pattern containing "/" at the beginning or middle is relative to the root directory, otherwise it matches
+//     the name of entry at any level.
+//   - "*" matches anything except "/", "?" matches any character except "/", and "[...]" matches a character range.
+//   - "**/" matches directories at any level, "/**" matches everything inside, and "/**/" matches zero or more
+//     directories.
+//
+// Like git, entries inside an ignored directory cannot be re-included, since the directory is never walked.
+type IgnorePatterns struct {
+	rules []ignoreRule
+}
+
+// ParseIgnorePatterns parses the gitignore-style patterns.
+func ParseIgnorePatterns(patterns ...string) (*IgnorePatterns, error) {
+	var ignore IgnorePatterns
+
+	for _, pattern := range patterns {
+		rule, ok, err := parseIgnoreRule(pattern)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid ignore pattern %q", pattern)
+		}
+
+		if ok {
+			ignore.rules = append(ignore.rules, rule)
+		}
+	}
+
+	return &ignore, nil
+}
+
+// LoadIgnoreFile loads the patterns from IgnoreFileName at the root of directory, and returns empty patterns if
+// the file does not exist.
+func LoadIgnoreFile(root string) (*IgnorePatterns, error) {
+	path := filepath.Join(root, IgnoreFileName)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &IgnorePatterns{}, nil
+	}
+
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to open ignore file %s", path)
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithMessagef(err, "failed to read ignore file %s", path)
+	}
+
+	return ParseIgnorePatterns(patterns...)
+}
+
+// Append returns the patterns with more patterns appended, which take precedence over the existing ones.
+func (ignore *IgnorePatterns) Append(more *IgnorePatterns) *IgnorePatterns {
+	if more == nil {
+		return ignore
+	}
+
+	var result IgnorePatterns
+	if ignore != nil {
+		result.rules = append(result.rules, ignore.rules...)
+	}
+	result.rules = append(result.rules, more.rules...)
+
+	return &result
+}
+
+// Len returns the number of patterns.
+func (ignore *IgnorePatterns) Len() int {
+	if ignore == nil {
+		return 0
+	}
+
+	return len(ignore.rules)
+}
+
+// Match returns whether the entry of relative path to root directory, separated by "/", is ignored.
+func (ignore *IgnorePatterns) Match(relpath string, isDir bool) bool {
+	if ignore == nil {
+		return false
+	}
+
+	var ignored bool
+	for _, rule := range ignore.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		if rule.regexp.MatchString(relpath) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
+
+// parseIgnoreRule parses a line of gitignore-style pattern, and returns false if it is blank or comment.
+func parseIgnoreRule(line string) (ignoreRule, bool, error) {
+	pattern := strings.TrimRight(line, " \t\r")
+	if len(pattern) == 0 || strings.HasPrefix(pattern, "#") {
+		return ignoreRule{}, false, nil
+	}
+
+	rule := ignoreRule{pattern: pattern}
+
+	switch {
+	case strings.HasPrefix(pattern, "!"):
+		rule.negate = true
+		pattern = pattern[1:]
+	case strings.HasPrefix(pattern, `\!`), strings.HasPrefix(pattern, `\#`):
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	if len(pattern) == 0 {
+		return ignoreRule{}, false, nil
+	}
+
+	// pattern without "/" except the trailing one matches at any level
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	expr := globToRegexp(pattern)
+	if !anchored && !strings.HasPrefix(expr, "(?:.*/)?") {
+		expr = "(?:.*/)?" + expr
+	}
+
+	var err error
+	if rule.regexp, err = regexp.Compile("^" + expr + "$"); err != nil {
+		return ignoreRule{}, false, err
+	}
+
+	return rule, true, nil
+}
+
+// globToRegexp converts the gitignore-style glob into regular expression.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**") && i == len(glob)-2 && i > 0 && glob[i-1] == '/':
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return sb.String()
+}
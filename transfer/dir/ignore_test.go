@@ -0,0 +1,111 @@
+package dir_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnorePatternsMatch(t *testing.T) {
+	ignore, err := dir.ParseIgnorePatterns(
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/vendor",
+		"docs/**/*.tmp",
+		"cache/**",
+		`\#hash`,
+		"file[0-9].bin",
+	)
+	assert.NoError(t, err)
+
+	for _, c := range []struct {
+		relpath string
+		isDir   bool
+		ignored bool
+	}{
+		{"a.log", false, true},
+		{"sub/a.log", false, true},
+		{"keep.log", false, false},
+		{"sub/keep.log", false, false},
+		{"build", true, true},
+		{"sub/build", true, true},
+		{"build", false, false},
+		{"vendor", true, true},
+		{"sub/vendor", true, false},
+		{"docs/a.tmp", false, true},
+		{"docs/x/y/a.tmp", false, true},
+		{"a.tmp", false, false},
+		{"cache/x", false, true},
+		{"cache", true, false},
+		{"#hash", false, true},
+		{"file1.bin", false, true},
+		{"filex.bin", false, false},
+		{"main.go", false, false},
+	} {
+		assert.Equal(t, c.ignored, ignore.Match(c.relpath, c.isDir), c.relpath)
+	}
+
+	// nil patterns ignore nothing
+	var none *dir.IgnorePatterns
+	assert.False(t, none.Match("a.log", false))
+}
+
+// manifestRoot returns the merkle root of encoded directory metadata.
+func manifestRoot(t *testing.T, root *dir.FsNode) string {
+	data, err := root.MarshalBinary()
+	assert.NoError(t, err)
+	iterdata, err := core.NewDataInMemory(data)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(iterdata)
+	assert.NoError(t, err)
+
+	return tree.Root().Hex()
+}
+
+func TestBuildFileTreeIgnore(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"main.go", ".git/HEAD", "node_modules/lib/index.js", "out/app.bin", "debug.log"} {
+		path := filepath.Join(tempDir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(name), 0644))
+	}
+	ignoreFile := strings.Join([]string{".git/", "node_modules/", "*.log"}, "\n")
+	assert.NoError(t, os.WriteFile(filepath.Join(tempDir, dir.IgnoreFileName), []byte(ignoreFile), 0644))
+
+	all, err := dir.BuildFileTree(tempDir)
+	assert.NoError(t, err)
+	assert.Len(t, all.Entries, 6)
+
+	// ignored by patterns in ignore file
+	ignore, err := dir.LoadIgnoreFile(tempDir)
+	assert.NoError(t, err)
+	filtered, err := dir.BuildFileTreeWithOption(tempDir, dir.BuildOption{}.WithIgnore(ignore))
+	assert.NoError(t, err)
+	_, relpaths := filtered.Flatten()
+	assert.Equal(t, []string{"/", "/.0gignore", "/main.go", "/out", "/out/app.bin"}, relpaths)
+	assert.NotEqual(t, manifestRoot(t, all), manifestRoot(t, filtered))
+
+	// along with predicate
+	filtered, err = dir.BuildFileTreeWithOption(tempDir, dir.BuildOption{
+		Ignore: ignore,
+		IgnoreFunc: func(relpath string, info os.FileInfo) bool {
+			return relpath == dir.IgnoreFileName || strings.HasSuffix(info.Name(), ".bin")
+		},
+	})
+	assert.NoError(t, err)
+	_, relpaths = filtered.Flatten()
+	assert.Equal(t, []string{"/", "/main.go", "/out"}, relpaths)
+
+	// no ignore file
+	ignore, err = dir.LoadIgnoreFile(t.TempDir())
+	assert.NoError(t, err)
+	assert.Zero(t, ignore.Len())
+}
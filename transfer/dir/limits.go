@@ -0,0 +1,131 @@
+package dir
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultMaxDepth is the max depth of file tree by default.
+	DefaultMaxDepth = 1024
+
+	// DefaultMaxPathLength is the max length of relative path in file tree by default, which is the same as
+	// PATH_MAX on Linux.
+	DefaultMaxPathLength = 4096
+)
+
+var (
+	// ErrTreeTooDeep is returned when the depth of file tree exceeds the limit.
+	ErrTreeTooDeep = errors.New("file tree too deep")
+
+	// ErrPathTooLong is returned when the relative path in file tree exceeds the limit.
+	ErrPathTooLong = errors.New("path too long")
+)
+
+// Limits are the limits of file tree when building from local file system or decoding from directory metadata,
+// which guard against untrusted metadata that exhausts resources.
+type Limits struct {
+	MaxDepth      int // max depth of entries, where entries of root directory are at depth 1, 0 for DefaultMaxDepth
+	MaxPathLength int // max length of relative path in bytes, 0 for DefaultMaxPathLength
+}
+
+func (limits Limits) maxDepth() int {
+	if limits.MaxDepth > 0 {
+		return limits.MaxDepth
+	}
+
+	return DefaultMaxDepth
+}
+
+func (limits Limits) maxPathLength() int {
+	if limits.MaxPathLength > 0 {
+		return limits.MaxPathLength
+	}
+
+	return DefaultMaxPathLength
+}
+
+// check checks the depth and length of relative path of an entry in file tree.
+func (limits Limits) check(depth int, relpath string) error {
+	if depth > limits.maxDepth() {
+		return errors.WithMessagef(ErrTreeTooDeep, "depth %v exceeds %v at %q", depth, limits.maxDepth(), relpath)
+	}
+
+	if len(relpath) > limits.maxPathLength() {
+		return errors.WithMessagef(ErrPathTooLong, "length %v exceeds %v at %q", len(relpath), limits.maxPathLength(), relpath)
+	}
+
+	return nil
+}
+
+// VerifyLimits verifies the depth and relative paths of file tree against the limits.
+func (node *FsNode) VerifyLimits(limits Limits) error {
+	type frame struct {
+		node    *FsNode
+		depth   int
+		relpath string
+	}
+
+	stack := []frame{{node: node}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current.depth > 0 {
+			if err := limits.check(current.depth, current.relpath); err != nil {
+				return err
+			}
+		}
+
+		if current.node.Type != FileTypeDirectory {
+			continue
+		}
+
+		for _, entry := range current.node.Entries {
+			name, err := entry.LocalName()
+			if err != nil {
+				return err
+			}
+
+			relpath := name
+			if current.depth > 0 {
+				relpath = current.relpath + "/" + name
+			}
+
+			stack = append(stack, frame{entry, current.depth + 1, relpath})
+		}
+	}
+
+	return nil
+}
+
+// checkJSONDepth checks the depth of file tree in JSON metadata before decoding, so that deeply nested metadata
+// is rejected without exhausting resources. The root directory is the outermost JSON object, and each level of
+// entries is nested in another object.
+func checkJSONDepth(data []byte, limits Limits) error {
+	var depth int
+	var inString, escaped bool
+
+	for _, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{':
+			depth++
+			if depth-1 > limits.maxDepth() {
+				return errors.WithMessagef(ErrTreeTooDeep, "depth exceeds %v", limits.maxDepth())
+			}
+		case c == '}':
+			depth--
+		}
+	}
+
+	return nil
+}
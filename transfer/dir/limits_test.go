@@ -0,0 +1,104 @@
+package dir_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDeepTree generates a file tree of nested directories "d" in the specified depth, and a file "f" in the
+// deepest directory.
+func newDeepTree(depth int, fileRoot common.Hash) *dir.FsNode {
+	node := dir.NewFileFsNode("f", fileRoot, 1)
+	for i := 0; i < depth; i++ {
+		node = dir.NewDirFsNode("d", []*dir.FsNode{node})
+	}
+	node.Name = "/"
+
+	return node
+}
+
+func TestDeepTree(t *testing.T) {
+	const depth = 10000
+	tree := newDeepTree(depth, common.HexToHash("0x01"))
+	filePath := "/" + strings.Repeat("d/", depth-1) + "f"
+
+	nodes, relpaths := tree.Flatten()
+	assert.Equal(t, depth+1, len(nodes))
+	assert.Equal(t, filePath, relpaths[depth])
+	assert.Equal(t, dir.FileTypeFile, nodes[depth].Type)
+
+	node, err := tree.Locate(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, nodes[depth], node)
+
+	assert.True(t, tree.Equal(newDeepTree(depth, common.HexToHash("0x01"))))
+	assert.False(t, tree.Equal(newDeepTree(depth, common.HexToHash("0x02"))))
+
+	// modified status propagated from the deepest file to root
+	diff, err := dir.Diff(tree, newDeepTree(depth, common.HexToHash("0x02")))
+	assert.NoError(t, err)
+	for i := 0; i < depth; i++ {
+		assert.Equal(t, dir.DiffStatusModified, diff.Status)
+		diff, _ = diff.Entries.Min()
+	}
+	assert.Equal(t, "f", diff.Node.Name)
+	assert.Equal(t, dir.DiffStatusModified, diff.Status)
+
+	diff, err = dir.Diff(tree, newDeepTree(depth, common.HexToHash("0x01")))
+	assert.NoError(t, err)
+	assert.Equal(t, dir.DiffStatusUnchanged, diff.Status)
+
+	// limits
+	assert.ErrorIs(t, tree.VerifyLimits(dir.Limits{}), dir.ErrTreeTooDeep)
+	assert.ErrorIs(t, tree.VerifyLimits(dir.Limits{MaxDepth: depth}), dir.ErrPathTooLong)
+	assert.NoError(t, tree.VerifyLimits(dir.Limits{MaxDepth: depth, MaxPathLength: len(filePath)}))
+}
+
+func TestUnmarshalDeepTree(t *testing.T) {
+	// rejected before decoding, which exceeds the max nesting depth of JSON decoder
+	data, err := dir.CanonicalBytes(newDeepTree(10000, common.HexToHash("0x01")))
+	assert.NoError(t, err)
+	var decoded dir.FsNode
+	assert.ErrorIs(t, decoded.UnmarshalBinary(data), dir.ErrTreeTooDeep)
+
+	// file at depth 4
+	tree := newDeepTree(4, common.HexToHash("0x01"))
+	data, err = dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, decoded.UnmarshalBinaryWithLimits(data, dir.Limits{MaxDepth: 3}), dir.ErrTreeTooDeep)
+	assert.ErrorIs(t, decoded.UnmarshalBinaryWithLimits(data, dir.Limits{MaxPathLength: 6}), dir.ErrPathTooLong)
+	assert.NoError(t, decoded.UnmarshalBinaryWithLimits(data, dir.Limits{MaxDepth: 4, MaxPathLength: 7}))
+	assert.True(t, tree.Equal(&decoded))
+
+	// braces in names are not nested objects
+	tree = dir.NewDirFsNode("/", []*dir.FsNode{dir.NewSymbolicFsNode(`{{"\{`, "{{{")})
+	data, err = dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+	assert.NoError(t, decoded.UnmarshalBinaryWithLimits(data, dir.Limits{MaxDepth: 1}))
+}
+
+func TestBuildFileTreeLimits(t *testing.T) {
+	// deep directory on local file system
+	folder := t.TempDir()
+	deepest := filepath.Join(folder, strings.Repeat("d"+string(os.PathSeparator), 500))
+	assert.NoError(t, os.MkdirAll(deepest, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(deepest, "f"), []byte("deep"), 0644))
+
+	root, err := dir.BuildFileTree(folder)
+	assert.NoError(t, err)
+	_, err = root.Locate(strings.Repeat("d/", 500) + "f")
+	assert.NoError(t, err)
+
+	_, err = dir.BuildFileTreeWithOption(folder, dir.BuildOption{Limits: dir.Limits{MaxDepth: 500}})
+	assert.ErrorIs(t, err, dir.ErrTreeTooDeep)
+	_, err = dir.BuildFileTreeWithOption(folder, dir.BuildOption{Limits: dir.Limits{MaxPathLength: 1000}})
+	assert.ErrorIs(t, err, dir.ErrPathTooLong)
+	_, err = dir.BuildFileTreeWithOption(folder, dir.BuildOption{Limits: dir.Limits{MaxDepth: 501, MaxPathLength: 1001}})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,158 @@
+package dir
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math/bits"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// ManifestIndexMagicBytes are the leading bytes of manifest index, which differ from CodecMagicBytes so that
+// old clients refuse to decode the index as directory manifest.
+var ManifestIndexMagicBytes = crypto.Keccak256([]byte("0g-storage-client-dir-manifest-index"))
+
+// ErrManifestIndexMismatch is returned when the manifest assembled from chunks mismatches the manifest index.
+var ErrManifestIndexMismatch = errors.New("directory manifest mismatches manifest index")
+
+// ManifestIndex is the root of directory manifest that is too large to upload as a single file, and uploaded in
+// chunks instead. The index is uploaded as a small file, so that the directory is still reachable from a single
+// storage root, and the manifest is assembled by chunks in order once the index downloaded, see AssembleManifest.
+type ManifestIndex struct {
+	Root   common.Hash   `json:"root"`   // storage root of the whole manifest
+	Size   int64         `json:"size"`   // size of the whole manifest
+	Chunks []common.Hash `json:"chunks"` // storage roots of chunks in order
+}
+
+// MarshalBinary encodes the manifest index with ManifestIndexMagicBytes followed by JSON.
+func (index *ManifestIndex) MarshalBinary() ([]byte, error) {
+	content, err := json.Marshal(index)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to marshal manifest index")
+	}
+
+	return append(bytes.Clone(ManifestIndexMagicBytes), content...), nil
+}
+
+// UnmarshalBinary decodes the manifest index encoded by MarshalBinary.
+func (index *ManifestIndex) UnmarshalBinary(data []byte) error {
+	if !IsManifestIndex(data) {
+		return errors.New("invalid magic bytes of manifest index")
+	}
+
+	if err := json.Unmarshal(data[len(ManifestIndexMagicBytes):], index); err != nil {
+		return errors.WithMessage(err, "failed to unmarshal manifest index")
+	}
+
+	if len(index.Chunks) == 0 || index.Size <= 0 {
+		return errors.New("manifest index is empty")
+	}
+
+	return nil
+}
+
+// IsManifestIndex returns whether data is a manifest index rather than a directory manifest.
+func IsManifestIndex(data []byte) bool {
+	return bytes.HasPrefix(data, ManifestIndexMagicBytes)
+}
+
+// AssembleManifest returns the directory manifest of data downloaded by the directory storage root. If data is a
+// manifest index, chunks are fetched in order and the assembled manifest is verified against the index, otherwise
+// data is returned as it is.
+//
+// Note, fetch is expected to verify the chunk against the storage root, e.g. by downloading with merkle proof.
+func AssembleManifest(data []byte, fetch func(root common.Hash) ([]byte, error)) ([]byte, error) {
+	if !IsManifestIndex(data) {
+		return data, nil
+	}
+
+	var index ManifestIndex
+	if err := index.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	manifest := make([]byte, 0, index.Size)
+	for i, chunk := range index.Chunks {
+		content, err := fetch(chunk)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to fetch chunk %v of directory manifest", i)
+		}
+
+		if int64(len(manifest)+len(content)) > index.Size {
+			err = errors.WithMessagef(ErrManifestIndexMismatch, "chunks exceed size %v", index.Size)
+			return nil, zg_common.WithErrorClass(err, zg_common.ErrorClassVerification)
+		}
+
+		manifest = append(manifest, content...)
+	}
+
+	if int64(len(manifest)) != index.Size {
+		err := errors.WithMessagef(ErrManifestIndexMismatch, "expected size %v, actual %v", index.Size, len(manifest))
+		return nil, zg_common.WithErrorClass(err, zg_common.ErrorClassVerification)
+	}
+
+	iterable, err := core.NewDataInMemory(manifest)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read directory manifest")
+	}
+
+	actual, err := core.MerkleRootData(iterable)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to calculate merkle root of directory manifest")
+	}
+
+	if actual != index.Root {
+		err = errors.WithMessagef(ErrManifestIndexMismatch, "expected root %v, actual %v", index.Root, actual)
+		return nil, zg_common.WithErrorClass(err, zg_common.ErrorClassVerification)
+	}
+
+	return manifest, nil
+}
+
+// gearTable is the random table of gear hash to determine chunk boundaries of manifest, see SplitManifest.
+var gearTable = func() (table [256]uint64) {
+	for i := range table {
+		table[i] = binary.BigEndian.Uint64(crypto.Keccak256([]byte{byte(i)}))
+	}
+
+	return table
+}()
+
+// SplitManifest splits the directory manifest into chunks of at most maxSize in order, of which the boundaries are
+// determined by content rather than offset. So, a local change of manifest, e.g. to patch a single entry, changes
+// only the chunks around it, and the other chunks are byte-identical to reuse the uploaded ones by storage root.
+func SplitManifest(manifest []byte, maxSize int64) [][]byte {
+	if int64(len(manifest)) <= maxSize {
+		return [][]byte{manifest}
+	}
+
+	// chunks are about a quarter of maxSize on average, so that chunks are rarely cut at maxSize, which is not
+	// determined by content
+	minSize := max(maxSize/8, 1)
+	maskBits := max(bits.Len64(uint64(maxSize/8))-1, 1)
+
+	var chunks [][]byte
+	for start, total := int64(0), int64(len(manifest)); start < total; {
+		end := min(start+maxSize, total)
+
+		// hash of the last 64 bytes, so that boundaries depend on content only
+		var hash uint64
+		for i := max(start+minSize-64, 0); i < end; i++ {
+			hash = hash<<1 + gearTable[manifest[i]]
+			if i+1 >= start+minSize && hash>>(64-maskBits) == 0 {
+				end = i + 1
+				break
+			}
+		}
+
+		chunks = append(chunks, manifest[start:end])
+		start = end
+	}
+
+	return chunks
+}
@@ -0,0 +1,116 @@
+package dir
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssembleManifest(t *testing.T) {
+	tree := NewDirFsNode("/", []*FsNode{NewFileFsNode("a.txt", common.Hash{1}, 100), NewFileFsNode("b.txt", common.Hash{2}, 200)})
+	manifest, err := CanonicalBytes(tree)
+	assert.NoError(t, err)
+
+	manifestRoot, err := ManifestRoot(tree)
+	assert.NoError(t, err)
+
+	// split into chunks stored by root
+	chunks := make(map[common.Hash][]byte)
+	index := ManifestIndex{Root: manifestRoot, Size: int64(len(manifest))}
+	for offset := 0; offset < len(manifest); offset += 64 {
+		chunk := manifest[offset:min(offset+64, len(manifest))]
+		data, err := core.NewDataInMemory(chunk)
+		assert.NoError(t, err)
+		root, err := core.MerkleRootData(data)
+		assert.NoError(t, err)
+
+		chunks[root] = chunk
+		index.Chunks = append(index.Chunks, root)
+	}
+
+	encoded, err := index.MarshalBinary()
+	assert.NoError(t, err)
+	assert.True(t, IsManifestIndex(encoded))
+	assert.False(t, IsManifestIndex(manifest))
+
+	fetch := func(root common.Hash) ([]byte, error) { return chunks[root], nil }
+
+	// assembled by chunks, and decoded as usual
+	assembled, err := AssembleManifest(encoded, fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest, assembled)
+
+	var decoded FsNode
+	assert.NoError(t, decoded.UnmarshalBinary(assembled))
+	assert.True(t, tree.Equal(&decoded))
+
+	// manifest returned as it is
+	assembled, err = AssembleManifest(manifest, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest, assembled)
+
+	// chunks out of order
+	index.Chunks[0], index.Chunks[1] = index.Chunks[1], index.Chunks[0]
+	encoded, err = index.MarshalBinary()
+	assert.NoError(t, err)
+	_, err = AssembleManifest(encoded, fetch)
+	assert.ErrorIs(t, err, ErrManifestIndexMismatch)
+	assert.Equal(t, zg_common.ErrorClassVerification, zg_common.ClassOf(err))
+
+	// size mismatch
+	index.Size--
+	encoded, err = index.MarshalBinary()
+	assert.NoError(t, err)
+	_, err = AssembleManifest(encoded, fetch)
+	assert.ErrorIs(t, err, ErrManifestIndexMismatch)
+
+	// manifest index is never decoded as manifest
+	assert.Error(t, decoded.UnmarshalBinary(encoded))
+}
+
+func TestSplitManifest(t *testing.T) {
+	var entries []*FsNode
+	for i := 0; i < 200; i++ {
+		entries = append(entries, NewFileFsNode(fmt.Sprintf("file%03d", i), common.BigToHash(big.NewInt(int64(i))), int64(i)))
+	}
+	tree := NewDirFsNode("/", entries)
+	manifest, err := CanonicalBytes(tree)
+	assert.NoError(t, err)
+
+	// not split within the max size
+	assert.Equal(t, [][]byte{manifest}, SplitManifest(manifest, int64(len(manifest))))
+
+	chunks := SplitManifest(manifest, 1024)
+	assert.Greater(t, len(chunks), len(manifest)/1024)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, len(chunk), 1024)
+	}
+	assert.Equal(t, manifest, bytes.Join(chunks, nil))
+
+	original := make(map[string]bool)
+	for _, chunk := range chunks {
+		original[string(chunk)] = true
+	}
+
+	// only chunks around the patched entry changed
+	for i := 0; i < len(entries); i += 10 {
+		patched, err := Patch(tree, []PatchOp{Put(entries[i].Name, NewFileFsNode("", common.Hash{1}, 1))})
+		assert.NoError(t, err)
+		patchedManifest, err := CanonicalBytes(patched)
+		assert.NoError(t, err)
+
+		var changed int
+		for _, chunk := range SplitManifest(patchedManifest, 1024) {
+			if !original[string(chunk)] {
+				changed++
+			}
+		}
+		assert.LessOrEqual(t, changed, 5, entries[i].Name)
+	}
+}
@@ -0,0 +1,507 @@
+package dir
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// manifestEntryMarkInterval is the interval of entries marked within directories, so that a page of entries is
+// decoded along with at most the same number of entries before and after the page.
+const manifestEntryMarkInterval = 64
+
+// manifestDir is the location of a directory within the chunked manifest.
+type manifestDir struct {
+	start, end               int64   // offsets of the directory object within manifest
+	entriesStart, entriesEnd int64   // offsets of the entries array within manifest
+	children                 []int   // directories within the entries array in order
+	count                    int     // number of entries
+	marks                    []int64 // offsets of every manifestEntryMarkInterval entries within manifest
+	digest                   common.Hash
+}
+
+// emptyEntriesDigest is the Digest of directories without entries in manifest.
+var emptyEntriesDigest = crypto.Keccak256Hash([]byte("[]"))
+
+// manifestSource is the TreeSource of a directory manifest uploaded in chunks, of which the directories are
+// located by scanning chunks once, and then decoded on demand by fetching the chunks of the directory only.
+type manifestSource struct {
+	chunks    []common.Hash
+	chunkEnds []int64 // end offset of each chunk within manifest
+	fetch     func(ctx context.Context, root common.Hash) ([]byte, error)
+
+	dirs []manifestDir // root directory at first
+
+	ids      map[common.Hash]int // directories by digest
+	encoding NameEncoding        // name encoding of root directory
+
+	mu     sync.Mutex
+	cached int    // index of chunk cached, or -1 if none
+	data   []byte // data of chunk cached
+}
+
+// NewManifestSource returns the TreeSource of a directory manifest uploaded in chunks, which lists directories on
+// demand. The chunks are fetched and scanned once in order to locate directories, and then only the chunks of a
+// directory are fetched again to list the directory, so that memory is bounded by the size of chunks and the number
+// of directories, rather than the size of manifest.
+//
+// Directories could be listed page by page as well, where only the chunks of entries around the page are fetched,
+// so that huge directories are paged without decoding all entries.
+//
+// Directories are listed with Digest of the entries in manifest, so that identical directories are skipped by
+// DiffStream without listing. Note, fetch is expected to verify the chunk against the storage root, e.g. by
+// downloading with merkle proof.
+func NewManifestSource(ctx context.Context, index *ManifestIndex, fetch func(ctx context.Context, root common.Hash) ([]byte, error), limits ...Limits) (PagedTreeSource, error) {
+	source := manifestSource{
+		chunks: index.Chunks,
+		fetch:  fetch,
+		ids:    make(map[common.Hash]int),
+		cached: -1,
+	}
+
+	scanner := newManifestScanner(Limits{})
+	if len(limits) > 0 {
+		scanner.limits = limits[0]
+	}
+
+	var offset int64
+	for i, chunk := range index.Chunks {
+		data, err := fetch(ctx, chunk)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to fetch chunk %v of directory manifest", i)
+		}
+
+		if err = scanner.scan(data, offset); err != nil {
+			return nil, errors.WithMessagef(err, "failed to scan chunk %v of directory manifest", i)
+		}
+
+		offset += int64(len(data))
+		source.chunkEnds = append(source.chunkEnds, offset)
+	}
+
+	if offset != index.Size {
+		err := errors.WithMessagef(ErrManifestIndexMismatch, "expected size %v, actual %v", index.Size, offset)
+		return nil, err
+	}
+
+	if !scanner.completed {
+		return nil, errors.New("incomplete directory manifest")
+	}
+
+	if len(scanner.dirs) == 0 || scanner.dirs[0].start != scanner.rootStart {
+		return nil, errors.New("manifest root is not a directory with entries")
+	}
+
+	source.dirs = scanner.dirs
+	for id, dir := range source.dirs {
+		source.ids[dir.digest] = id
+	}
+
+	// name encoding of root directory is required to verify names of entries listed
+	root, err := source.decodeHeader(ctx, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decode root directory")
+	}
+	source.encoding = root.NameEncoding
+
+	return &source, nil
+}
+
+func (source *manifestSource) ReadDir(ctx context.Context, dirPath string, dir *TreeEntry) ([]TreeEntry, error) {
+	entries, _, err := source.ReadDirPage(ctx, dirPath, dir, 0, 0)
+	return entries, err
+}
+
+func (source *manifestSource) ReadDirPage(ctx context.Context, dirPath string, dir *TreeEntry, offset, limit int) ([]TreeEntry, int, error) {
+	id := 0
+	if dir != nil {
+		if dir.Type != FileTypeDirectory {
+			return nil, 0, errors.Errorf("%q is not a directory", dirPath)
+		}
+
+		var ok bool
+		if id, ok = source.ids[dir.Digest]; !ok || dir.Digest == emptyEntriesDigest {
+			return []TreeEntry{}, 0, nil
+		}
+	}
+
+	total := source.dirs[id].count
+	offset = min(max(offset, 0), total)
+
+	end := total
+	if limit > 0 && limit < total-offset {
+		end = offset + limit
+	}
+
+	if offset == end {
+		return []TreeEntry{}, total, nil
+	}
+
+	// decode entries between the marks around the page
+	first := offset / manifestEntryMarkInterval
+	last := (end + manifestEntryMarkInterval - 1) / manifestEntryMarkInterval
+	entries, err := source.decodeEntries(ctx, id, first, last)
+	if err != nil {
+		return nil, 0, errors.WithMessagef(err, "failed to decode directory %q", dirPath)
+	}
+
+	base := first * manifestEntryMarkInterval
+
+	return entries[offset-base : end-base], total, nil
+}
+
+// decodeEntries decodes the entries of directory between the marks first and last, with entries of sub directories
+// replaced by empty arrays.
+func (source *manifestSource) decodeEntries(ctx context.Context, id, first, last int) ([]TreeEntry, error) {
+	dir := source.dirs[id]
+
+	// entries until the closing bracket, or the next mark
+	start, end := dir.marks[first], dir.entriesEnd-1
+	count := dir.count - first*manifestEntryMarkInterval
+	if last < len(dir.marks) {
+		end, count = dir.marks[last], (last-first)*manifestEntryMarkInterval
+	}
+
+	children := dir.children
+	children = children[sort.Search(len(children), func(i int) bool {
+		return source.dirs[children[i]].entriesStart >= start
+	}):]
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+
+	offset := start
+	for i, child := range children {
+		if source.dirs[child].entriesStart >= end {
+			children = children[:i]
+			break
+		}
+
+		if err := source.read(ctx, &buf, offset, source.dirs[child].entriesStart); err != nil {
+			return nil, err
+		}
+		buf.WriteString("[]")
+		offset = source.dirs[child].entriesEnd
+	}
+
+	if err := source.read(ctx, &buf, offset, end); err != nil {
+		return nil, err
+	}
+
+	// remove the separator before the next mark
+	data := append(bytes.TrimRight(buf.Bytes(), " \t\r\n,"), ']')
+
+	var nodes []*FsNode
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, errors.WithMessage(err, "failed to unmarshal entries from JSON")
+	}
+
+	if len(nodes) != count {
+		return nil, errors.Errorf("entries mismatch with manifest, expected %v, actual %v", count, len(nodes))
+	}
+
+	// verify entries listed as the whole manifest decoded, see UnmarshalBinaryWithLimits
+	listed := FsNode{Type: FileTypeDirectory, Entries: nodes, NameEncoding: source.encoding}
+	if err := listed.VerifyEmbedded(); err != nil {
+		return nil, errors.WithMessage(err, "invalid embedded file")
+	}
+
+	if err := listed.VerifyNames(); err != nil {
+		return nil, errors.WithMessage(err, "invalid file name")
+	}
+
+	entries := make([]TreeEntry, len(nodes))
+	for i, entry := range nodes {
+		entries[i] = TreeEntry{FsNode: entry}
+
+		// entries of sub directories are decoded as empty array, and omitted if no entries
+		if entry.Entries == nil {
+			if entry.Type == FileTypeDirectory {
+				entries[i].Digest = emptyEntriesDigest
+			}
+			continue
+		}
+
+		if len(children) == 0 {
+			return nil, errors.New("directories mismatch with manifest")
+		}
+
+		entry.Entries = nil
+		entries[i].Digest = source.dirs[children[0]].digest
+		children = children[1:]
+	}
+
+	return entries, nil
+}
+
+// decodeHeader decodes the directory object without entries.
+func (source *manifestSource) decodeHeader(ctx context.Context, id int) (*FsNode, error) {
+	dir := source.dirs[id]
+
+	var buf bytes.Buffer
+	if err := source.read(ctx, &buf, dir.start, dir.entriesStart); err != nil {
+		return nil, err
+	}
+	buf.WriteString("[]")
+	if err := source.read(ctx, &buf, dir.entriesEnd, dir.end); err != nil {
+		return nil, err
+	}
+
+	var node FsNode
+	if err := json.Unmarshal(buf.Bytes(), &node); err != nil {
+		return nil, errors.WithMessage(err, "failed to unmarshal `FsNode` from JSON")
+	}
+
+	if node.Type != FileTypeDirectory {
+		return nil, errors.New("not a directory")
+	}
+
+	return &node, nil
+}
+
+// read reads the manifest data within [start, end) into buf, and fetches the chunks on demand.
+func (source *manifestSource) read(ctx context.Context, buf *bytes.Buffer, start, end int64) error {
+	for start < end {
+		i := sort.Search(len(source.chunkEnds), func(i int) bool { return source.chunkEnds[i] > start })
+		data, err := source.chunk(ctx, i)
+		if err != nil {
+			return err
+		}
+
+		chunkStart := source.chunkEnds[i] - int64(len(data))
+		n := min(end, source.chunkEnds[i]) - start
+		buf.Write(data[start-chunkStart : start-chunkStart+n])
+		start += n
+	}
+
+	return nil
+}
+
+// chunk returns the data of chunk at the specified index, which is cached since adjacent directories are usually
+// listed in turn.
+func (source *manifestSource) chunk(ctx context.Context, i int) ([]byte, error) {
+	source.mu.Lock()
+	if source.cached == i {
+		data := source.data
+		source.mu.Unlock()
+		return data, nil
+	}
+	source.mu.Unlock()
+
+	data, err := source.fetch(ctx, source.chunks[i])
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to fetch chunk %v of directory manifest", i)
+	}
+
+	chunkStart := int64(0)
+	if i > 0 {
+		chunkStart = source.chunkEnds[i-1]
+	}
+	if chunkStart+int64(len(data)) != source.chunkEnds[i] {
+		return nil, errors.Errorf("size of chunk %v changed", i)
+	}
+
+	source.mu.Lock()
+	source.cached, source.data = i, data
+	source.mu.Unlock()
+
+	return data, nil
+}
+
+// manifestScanFrame is a JSON object or array being scanned.
+type manifestScanFrame struct {
+	object    bool
+	start     int64 // offset of the opening bracket
+	key       []byte
+	expectKey bool
+
+	dir    int       // for object, the directory of entries array; for array, the directory of entries, -1 if none
+	parent int       // for object, the directory of entries array that the object within, -1 if none
+	hasher hash.Hash // for entries array, the digest of entries
+	from   int       // for entries array, the position in data not hashed yet
+}
+
+// manifestScanner scans the JSON metadata of manifest in chunks to locate directories, without decoding entries.
+type manifestScanner struct {
+	limits Limits
+
+	header    []byte
+	stack     []*manifestScanFrame
+	inString  bool
+	escaped   bool
+	inKey     bool
+	completed bool
+	rootStart int64
+
+	dirs []manifestDir
+}
+
+func newManifestScanner(limits Limits) *manifestScanner {
+	return &manifestScanner{limits: limits, rootStart: -1}
+}
+
+// scan scans the data at the specified offset of manifest, which follows the data scanned before.
+func (scanner *manifestScanner) scan(data []byte, offset int64) error {
+	headerSize := len(CodecMagicBytes) + 2
+
+	for pos, c := range data {
+		if len(scanner.header) < headerSize {
+			if scanner.header = append(scanner.header, c); len(scanner.header) == headerSize {
+				if err := checkCodecHeader(scanner.header); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		// ignore the signature if any
+		if scanner.completed {
+			break
+		}
+
+		if err := scanner.next(c, offset+int64(pos), data, pos); err != nil {
+			return err
+		}
+	}
+
+	// hash the remaining data of entries arrays being scanned
+	for _, frame := range scanner.stack {
+		if frame.hasher != nil {
+			frame.hasher.Write(data[frame.from:])
+			frame.from = 0
+		}
+	}
+
+	return nil
+}
+
+// next scans the byte c at offset of manifest, which is data[pos].
+func (scanner *manifestScanner) next(c byte, offset int64, data []byte, pos int) error {
+	var top *manifestScanFrame
+	if len(scanner.stack) > 0 {
+		top = scanner.stack[len(scanner.stack)-1]
+	}
+
+	switch {
+	case scanner.escaped:
+		scanner.escaped = false
+		if scanner.inKey {
+			top.key = append(top.key, c)
+		}
+	case scanner.inString:
+		if c == '\\' {
+			scanner.escaped = true
+		} else if c == '"' {
+			scanner.inString, scanner.inKey = false, false
+		}
+
+		if scanner.inKey {
+			top.key = append(top.key, c)
+		}
+	case c == '"':
+		scanner.inString = true
+		if top != nil && top.object && top.expectKey {
+			scanner.inKey = true
+			top.key = top.key[:0]
+		}
+	case c == ':':
+		if top != nil && top.object {
+			top.expectKey = false
+		}
+	case c == ',':
+		if top != nil && top.object {
+			top.expectKey = true
+		}
+	case c == '{':
+		if depth := scanner.objectDepth(); depth > scanner.limits.maxDepth() {
+			return errors.WithMessagef(ErrTreeTooDeep, "depth exceeds %v", scanner.limits.maxDepth())
+		}
+
+		parent := -1
+		if top != nil && !top.object {
+			parent = top.dir
+
+			// mark entries of directory, so that pages of entries are decoded without decoding all entries
+			if parent >= 0 {
+				dir := &scanner.dirs[parent]
+				if dir.count%manifestEntryMarkInterval == 0 {
+					dir.marks = append(dir.marks, offset)
+				}
+				dir.count++
+			}
+		} else if top == nil {
+			scanner.rootStart = offset
+		}
+
+		scanner.stack = append(scanner.stack, &manifestScanFrame{object: true, start: offset, expectKey: true, dir: -1, parent: parent})
+	case c == '[':
+		frame := &manifestScanFrame{start: offset, dir: -1}
+		if top != nil && top.object && string(top.key) == "entries" && top.dir < 0 {
+			frame.dir = len(scanner.dirs)
+			frame.hasher = crypto.NewKeccakState()
+			frame.from = pos
+			top.dir = frame.dir
+
+			scanner.dirs = append(scanner.dirs, manifestDir{start: top.start, entriesStart: offset})
+			if top.parent >= 0 {
+				scanner.dirs[top.parent].children = append(scanner.dirs[top.parent].children, frame.dir)
+			}
+		}
+
+		scanner.stack = append(scanner.stack, frame)
+	case c == '}' || c == ']':
+		if top == nil || top.object != (c == '}') {
+			return errors.New("invalid JSON metadata")
+		}
+		scanner.stack = scanner.stack[:len(scanner.stack)-1]
+
+		if top.object && top.dir >= 0 {
+			scanner.dirs[top.dir].end = offset + 1
+		}
+
+		if top.hasher != nil {
+			top.hasher.Write(data[top.from : pos+1])
+			dir := &scanner.dirs[top.dir]
+			dir.entriesEnd = offset + 1
+			dir.digest = common.BytesToHash(top.hasher.Sum(nil))
+		}
+
+		scanner.completed = len(scanner.stack) == 0
+	}
+
+	return nil
+}
+
+// objectDepth returns the number of JSON objects being scanned.
+func (scanner *manifestScanner) objectDepth() int {
+	var depth int
+	for _, frame := range scanner.stack {
+		if frame.object {
+			depth++
+		}
+	}
+
+	return depth
+}
+
+// checkCodecHeader verifies the magic bytes and codec version of manifest.
+func checkCodecHeader(header []byte) error {
+	if !bytes.Equal(header[:len(CodecMagicBytes)], CodecMagicBytes) {
+		return errors.New("invalid magic bytes")
+	}
+
+	version := binary.BigEndian.Uint16(header[len(CodecMagicBytes):])
+	if version != CodecVersion && version != CodecVersionEmbedded {
+		return errors.Errorf("unsupported codec version: got %d, expected %d or %d", version, CodecVersion, CodecVersionEmbedded)
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package dir
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// chunkStore stores manifest chunks by storage root, and counts the chunks fetched.
+type chunkStore struct {
+	chunks  map[common.Hash][]byte
+	fetches int
+}
+
+func (store *chunkStore) fetch(_ context.Context, root common.Hash) ([]byte, error) {
+	store.fetches++
+
+	chunk, ok := store.chunks[root]
+	if !ok {
+		return nil, errors.New("chunk not found")
+	}
+
+	return chunk, nil
+}
+
+// upload splits the manifest of tree into chunks of at most maxSize, and returns the manifest index.
+func (store *chunkStore) upload(t *testing.T, tree *FsNode, maxSize int64) *ManifestIndex {
+	manifest, err := CanonicalBytes(tree)
+	assert.NoError(t, err)
+
+	root, err := ManifestRoot(tree)
+	assert.NoError(t, err)
+
+	index := ManifestIndex{Root: root, Size: int64(len(manifest))}
+	for _, chunk := range SplitManifest(manifest, maxSize) {
+		data, err := core.NewDataInMemory(chunk)
+		assert.NoError(t, err)
+		root, err := core.MerkleRootData(data)
+		assert.NoError(t, err)
+
+		store.chunks[root] = chunk
+		index.Chunks = append(index.Chunks, root)
+	}
+
+	return &index
+}
+
+func newManifestSourceTestTree(numDirs, numFiles int) *FsNode {
+	var dirs []*FsNode
+	for i := 0; i < numDirs; i++ {
+		var files []*FsNode
+		for j := 0; j < numFiles; j++ {
+			files = append(files, NewFileFsNode(fmt.Sprintf("file%03d", j), common.BytesToHash([]byte(fmt.Sprintf("%v/%v", i, j))), int64(j+1)))
+		}
+		files = append(files, NewDirFsNode("sub", []*FsNode{NewSymbolicFsNode("link", "../file000")}), NewDirFsNode("empty", nil))
+		dirs = append(dirs, NewDirFsNode(fmt.Sprintf("dir%03d", i), files))
+	}
+
+	return NewDirFsNode("/", append(dirs, NewFileFsNode("a \"quoted\" name", common.HexToHash("0x01"), 1)))
+}
+
+func TestManifestSource(t *testing.T) {
+	tree := newManifestSourceTestTree(20, 20)
+	store := &chunkStore{chunks: make(map[common.Hash][]byte)}
+	index := store.upload(t, tree, 1024)
+	assert.Greater(t, len(index.Chunks), 10)
+
+	source, err := NewManifestSource(context.Background(), index, store.fetch)
+	assert.NoError(t, err)
+
+	// directories listed the same as the tree in memory
+	var list func(dirPath string, dir *TreeEntry, expected *FsNode)
+	list = func(dirPath string, dir *TreeEntry, expected *FsNode) {
+		entries, err := source.ReadDir(context.Background(), dirPath, dir)
+		assert.NoError(t, err)
+		if !assert.Equal(t, len(expected.Entries), len(entries), dirPath) {
+			return
+		}
+
+		for i, entry := range entries {
+			assert.Equal(t, expected.Entries[i].Name, entry.Name)
+			assert.Equal(t, expected.Entries[i].Type, entry.Type)
+			assert.Equal(t, expected.Entries[i].Root, entry.Root)
+			assert.Equal(t, expected.Entries[i].Link, entry.Link)
+			assert.Nil(t, entry.Entries)
+
+			if entry.Type == FileTypeDirectory {
+				list(path.Join(dirPath, entry.Name), &entry, expected.Entries[i])
+			}
+		}
+	}
+	list("", nil, tree)
+
+	// listed by fetching the chunks of directory only
+	store.fetches = 0
+	entries, err := source.ReadDir(context.Background(), "", nil)
+	assert.NoError(t, err)
+	store.fetches = 0
+	_, err = source.ReadDir(context.Background(), "dir005", &entries[6])
+	assert.NoError(t, err)
+	assert.Less(t, store.fetches, len(index.Chunks)/10)
+
+	// not a directory
+	assert.Equal(t, FileTypeFile, entries[0].Type)
+	_, err = source.ReadDir(context.Background(), entries[0].Name, &entries[0])
+	assert.Error(t, err)
+}
+
+func TestManifestSourceInvalid(t *testing.T) {
+	tree := newManifestSourceTestTree(5, 20)
+	store := &chunkStore{chunks: make(map[common.Hash][]byte)}
+	index := store.upload(t, tree, 512)
+
+	// chunks missing
+	missing := *index
+	missing.Chunks = missing.Chunks[:len(missing.Chunks)-1]
+	missing.Size -= int64(len(store.chunks[index.Chunks[len(index.Chunks)-1]]))
+	_, err := NewManifestSource(context.Background(), &missing, store.fetch)
+	assert.Error(t, err)
+
+	// size mismatch
+	mismatch := *index
+	mismatch.Size++
+	_, err = NewManifestSource(context.Background(), &mismatch, store.fetch)
+	assert.ErrorIs(t, err, ErrManifestIndexMismatch)
+
+	// chunk not found
+	notFound := *index
+	notFound.Chunks = append([]common.Hash{{1}}, notFound.Chunks[1:]...)
+	_, err = NewManifestSource(context.Background(), &notFound, store.fetch)
+	assert.Error(t, err)
+}
+
+func TestDiffStreamManifestSource(t *testing.T) {
+	old := newManifestSourceTestTree(30, 20)
+	updated, err := Patch(old, []PatchOp{
+		Put("dir003/file005", NewFileFsNode("", common.HexToHash("0x02"), 100)),
+		Delete("dir010/file000"),
+		Put("dir020/sub/new", NewFileFsNode("", common.HexToHash("0x03"), 3)),
+		Mkdir("dir100"),
+	})
+	assert.NoError(t, err)
+
+	store := &chunkStore{chunks: make(map[common.Hash][]byte)}
+	oldSource, err := NewManifestSource(context.Background(), store.upload(t, old, 1024), store.fetch)
+	assert.NoError(t, err)
+	newSource, err := NewManifestSource(context.Background(), store.upload(t, updated, 1024), store.fetch)
+	assert.NoError(t, err)
+
+	format := func(diffs []DiffPath) []string {
+		var paths []string
+		for _, diff := range diffs {
+			paths = append(paths, fmt.Sprintf("%v %v", diff.Path, diff.Change))
+		}
+		return paths
+	}
+
+	// same as compared in memory
+	expected := format(collectDiffStream(t, TreeSourceOf(old), TreeSourceOf(updated)))
+	assert.Equal(t, []string{"dir003/file005 content", "dir010/file000 ", "dir020/sub/new ", "dir100 "}, expected)
+
+	counting := &countingSource{TreeSource: oldSource}
+	assert.Equal(t, expected, format(collectDiffStream(t, counting, newSource)))
+
+	// identical directories skipped by digest: root, dir003, dir010, dir020 and dir020/sub
+	assert.Equal(t, 5, counting.reads)
+}
+
+func TestManifestSourcePage(t *testing.T) {
+	// a flat directory of entries across chunks, along with sub directories in between
+	var entries []*FsNode
+	for i := 0; i < 1000; i++ {
+		if i%97 == 0 {
+			entries = append(entries, NewDirFsNode(fmt.Sprintf("entry%04d", i), []*FsNode{NewFileFsNode("file", common.HexToHash("0x01"), 1)}))
+		} else {
+			entries = append(entries, NewFileFsNode(fmt.Sprintf("entry%04d", i), common.BytesToHash([]byte{byte(i)}), int64(i)))
+		}
+	}
+	tree := NewDirFsNode("/", entries)
+
+	store := &chunkStore{chunks: make(map[common.Hash][]byte)}
+	index := store.upload(t, tree, 1024)
+	source, err := NewManifestSource(context.Background(), index, store.fetch)
+	assert.NoError(t, err)
+
+	all, err := source.ReadDir(context.Background(), "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, len(all))
+
+	// pages in any size are the same as listed at once
+	for _, limit := range []int{1, 7, manifestEntryMarkInterval, 100, 1000} {
+		for offset := 0; offset < len(all); offset += limit {
+			page, total, err := source.ReadDirPage(context.Background(), "", nil, offset, limit)
+			assert.NoError(t, err)
+			assert.Equal(t, 1000, total)
+			assert.Equal(t, all[offset:min(offset+limit, len(all))], page)
+		}
+	}
+
+	// beyond the last entry
+	page, total, err := source.ReadDirPage(context.Background(), "", nil, 1000, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, total)
+	assert.Empty(t, page)
+
+	// sub directories within page listed by digest
+	page, _, err = source.ReadDirPage(context.Background(), "", nil, 97, 1)
+	assert.NoError(t, err)
+	sub, err := source.ReadDir(context.Background(), "entry0097", &page[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sub))
+	assert.Equal(t, "file", sub[0].Name)
+
+	// paged by fetching the chunks around the page only
+	store.fetches = 0
+	_, _, err = source.ReadDirPage(context.Background(), "", nil, 500, 10)
+	assert.NoError(t, err)
+	assert.Less(t, store.fetches, len(index.Chunks)/4)
+}
+
+func TestLocateEntry(t *testing.T) {
+	tree := newManifestSourceTestTree(20, 20)
+	store := &chunkStore{chunks: make(map[common.Hash][]byte)}
+	chunked, err := NewManifestSource(context.Background(), store.upload(t, tree, 1024), store.fetch)
+	assert.NoError(t, err)
+
+	for _, source := range []PagedTreeSource{TreeSourceOf(tree), chunked} {
+		root, err := LocateEntry(context.Background(), source, "/")
+		assert.NoError(t, err)
+		assert.Nil(t, root)
+
+		entry, err := LocateEntry(context.Background(), source, "/dir007/file013")
+		assert.NoError(t, err)
+		assert.Equal(t, FileTypeFile, entry.Type)
+		assert.Equal(t, int64(14), entry.Size)
+
+		entry, err = LocateEntry(context.Background(), source, "dir019/sub/link")
+		assert.NoError(t, err)
+		assert.Equal(t, "../file000", entry.Link)
+
+		entry, err = LocateEntry(context.Background(), source, "a \"quoted\" name")
+		assert.NoError(t, err)
+		assert.Equal(t, FileTypeFile, entry.Type)
+
+		_, err = LocateEntry(context.Background(), source, "dir007/missing")
+		assert.ErrorIs(t, err, ErrPathNotFound)
+
+		_, err = LocateEntry(context.Background(), source, "dir007/file013/file")
+		assert.ErrorIs(t, err, ErrPathNotFound)
+
+		_, err = LocateEntry(context.Background(), source, "dir007/../dir008")
+		assert.ErrorIs(t, err, ErrInvalidPath)
+	}
+}
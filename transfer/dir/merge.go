@@ -0,0 +1,206 @@
+package dir
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// ConflictPolicy is the policy to resolve entries at the same path of both trees to merge, other than directories
+// which are always merged recursively.
+type ConflictPolicy string
+
+const (
+	// ConflictOverlay replaces the entry of base tree with the one of overlay tree, which is the default policy if
+	// not specified.
+	ConflictOverlay ConflictPolicy = "overlay"
+
+	// ConflictError fails to merge with MergeConflictError, unless the entries are identical.
+	ConflictError ConflictPolicy = "error"
+)
+
+var (
+	// ErrMergeConflict is returned when entries at the same path differ under the ConflictError policy.
+	ErrMergeConflict = errors.New("merge conflict")
+
+	// ErrTypeConflict is returned when entries at the same path are of different types, e.g. a file in one tree
+	// and a directory in the other, regardless of the policy.
+	ErrTypeConflict = errors.New("type conflict")
+)
+
+// Validate checks whether the conflict policy is supported.
+func (policy ConflictPolicy) Validate() error {
+	switch policy {
+	case "", ConflictOverlay, ConflictError:
+		return nil
+	default:
+		return errors.Errorf("unsupported conflict policy %q", string(policy))
+	}
+}
+
+// MergeConflictError is the error of entries that fail to merge at the same path.
+type MergeConflictError struct {
+	Path    string   // slash-separated path relative to the root directory
+	Base    FileType // type of entry in base tree
+	Overlay FileType // type of entry in overlay tree
+}
+
+func (e *MergeConflictError) Error() string {
+	if e.Base != e.Overlay {
+		return fmt.Sprintf("%v: %q is %v in base but %v in overlay", ErrTypeConflict, e.Path, e.Base, e.Overlay)
+	}
+
+	return fmt.Sprintf("%v: %q differs", ErrMergeConflict, e.Path)
+}
+
+// Unwrap returns ErrTypeConflict or ErrMergeConflict, so that errors.Is could be used to check the error.
+func (e *MergeConflictError) Unwrap() error {
+	if e.Base != e.Overlay {
+		return ErrTypeConflict
+	}
+
+	return ErrMergeConflict
+}
+
+// mergeFrame is a pair of directories at the same path to merge, of which the merged directory is stored in slot.
+type mergeFrame struct {
+	base, overlay *FsNode
+	path          string
+	slot          **FsNode
+	expanded      bool
+	entries       []*FsNode // merged entries, of which directories in both trees are filled once merged
+}
+
+// Merge overlays the overlay tree on top of the base tree, and returns the merged tree, e.g. to compose a site from
+// a base directory and per-locale assets published separately. Directories at the same path are merged
+// recursively, and other entries at the same path are resolved by policy. Entries of different types at the same
+// path always fail with MergeConflictError.
+//
+// Neither tree is modified. Subtrees only in either tree, or merged identically to the base tree, are shared with
+// the input trees, so that they are encoded byte-identically, and only the directories along changed paths are
+// copied. Merged directories keep the attributes, e.g. permissions, of base tree.
+func Merge(base, overlay *FsNode, policy ConflictPolicy) (*FsNode, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	if base == nil || overlay == nil || base.Type != FileTypeDirectory || overlay.Type != FileTypeDirectory {
+		return nil, errors.New("only directories could be merged")
+	}
+
+	encoding := base.NameEncoding
+	if len(encoding) == 0 {
+		encoding = overlay.NameEncoding
+	} else if len(overlay.NameEncoding) > 0 && overlay.NameEncoding != encoding {
+		return nil, errors.Errorf("name encoding mismatch, %v in base but %v in overlay", encoding, overlay.NameEncoding)
+	}
+
+	var merged *FsNode
+
+	// merge iteratively with an explicit stack, so that deep trees never overflow the call stack
+	stack := []*mergeFrame{{base: base, overlay: overlay, path: ".", slot: &merged}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+
+		// entries merged, and shares the base directory if nothing changed
+		if current.expanded {
+			stack = stack[:len(stack)-1]
+			*current.slot = mergedDir(current.base, current.entries)
+			continue
+		}
+
+		current.expanded = true
+		current.entries = make([]*FsNode, 0, len(current.base.Entries)+len(current.overlay.Entries))
+
+		// merge the sorted entries of both directories
+		lhs, rhs := current.base.Entries, current.overlay.Entries
+		for len(lhs) > 0 || len(rhs) > 0 {
+			switch {
+			case len(rhs) == 0 || len(lhs) > 0 && lhs[0].Name < rhs[0].Name:
+				current.entries = append(current.entries, lhs[0])
+				lhs = lhs[1:]
+			case len(lhs) == 0 || rhs[0].Name < lhs[0].Name:
+				current.entries = append(current.entries, rhs[0])
+				rhs = rhs[1:]
+			default:
+				entry, err := mergeEntry(lhs[0], rhs[0], path.Join(current.path, lhs[0].Name), policy)
+				if err != nil {
+					return nil, err
+				}
+
+				current.entries = append(current.entries, entry)
+				if entry == nil {
+					// directories merged later, and filled in the slot of entry
+					stack = append(stack, &mergeFrame{
+						base:    lhs[0],
+						overlay: rhs[0],
+						path:    path.Join(current.path, lhs[0].Name),
+						slot:    &current.entries[len(current.entries)-1],
+					})
+				}
+
+				lhs, rhs = lhs[1:], rhs[1:]
+			}
+		}
+	}
+
+	if merged.NameEncoding != encoding {
+		copied := *merged
+		copied.NameEncoding = encoding
+		merged = &copied
+	}
+
+	return merged, nil
+}
+
+// mergeEntry resolves the entries at the same path, and returns nil if both are directories to merge recursively.
+func mergeEntry(base, overlay *FsNode, path string, policy ConflictPolicy) (*FsNode, error) {
+	if base.Type != overlay.Type {
+		return nil, &MergeConflictError{Path: path, Base: base.Type, Overlay: overlay.Type}
+	}
+
+	if base.Type == FileTypeDirectory {
+		return nil, nil
+	}
+
+	if identicalLeaf(base, overlay) {
+		return base, nil
+	}
+
+	if policy == ConflictError {
+		return nil, &MergeConflictError{Path: path, Base: base.Type, Overlay: overlay.Type}
+	}
+
+	return overlay, nil
+}
+
+// identicalLeaf returns whether the regular files or symbolic links are identical, including the attributes.
+func identicalLeaf(lhs, rhs *FsNode) bool {
+	return lhs.Equal(rhs) && lhs.Size == rhs.Size && bytes.Equal(lhs.Data, rhs.Data) &&
+		lhs.Mode == rhs.Mode && lhs.ModTime == rhs.ModTime
+}
+
+// mergedDir returns the base directory if the merged entries are the same as base, otherwise a copy of base
+// directory with the merged entries.
+func mergedDir(base *FsNode, entries []*FsNode) *FsNode {
+	if len(entries) == len(base.Entries) {
+		unchanged := true
+		for i, entry := range entries {
+			if entry != base.Entries[i] {
+				unchanged = false
+				break
+			}
+		}
+
+		if unchanged {
+			return base
+		}
+	}
+
+	copied := *base
+	copied.Entries = entries
+
+	return &copied
+}
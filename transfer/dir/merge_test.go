@@ -0,0 +1,132 @@
+package dir
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDeepTree returns a directory of the specified depth, of which each level has 2 files and 2 sub directories
+// named "d0" and "d1", and the file "d0/.../d0/leaf" of the specified merkle root at the bottom.
+func newDeepTree(depth int, leaf common.Hash) *FsNode {
+	var build func(level int, path string) *FsNode
+	build = func(level int, path string) *FsNode {
+		entries := []*FsNode{
+			NewFileFsNode("f0", common.BytesToHash([]byte(path+"/f0")), 10),
+			NewFileFsNode("f1", common.BytesToHash([]byte(path+"/f1")), 20),
+		}
+
+		if level == depth {
+			if path == fmt.Sprintf("%0*d", depth, 0) {
+				entries = append(entries, NewFileFsNode("leaf", leaf, 30))
+			}
+			return NewDirFsNode("", entries)
+		}
+
+		for i := 0; i < 2; i++ {
+			sub := build(level+1, fmt.Sprintf("%v%v", path, i))
+			sub.Name = fmt.Sprintf("d%v", i)
+			entries = append(entries, sub)
+		}
+
+		return NewDirFsNode("", entries)
+	}
+
+	return build(0, "")
+}
+
+func TestMergeDeep(t *testing.T) {
+	base := newDeepTree(6, common.HexToHash("0x01"))
+	overlay := newDeepTree(6, common.HexToHash("0x02"))
+	baseBytes, err := CanonicalBytes(base)
+	assert.NoError(t, err)
+
+	merged, err := Merge(base, overlay, ConflictOverlay)
+	assert.NoError(t, err)
+	assert.True(t, overlay.Equal(merged))
+
+	// inputs not modified
+	encoded, err := CanonicalBytes(base)
+	assert.NoError(t, err)
+	assert.Equal(t, baseBytes, encoded)
+
+	// only directories along the changed leaf copied, and unchanged subtrees shared with base tree
+	leafDir := "d0/d0/d0/d0/d0/d0"
+	for p := leafDir; ; p = p[:len(p)-3] {
+		baseNode, _ := base.Locate(p)
+		mergedNode, _ := merged.Locate(p)
+		assert.NotSame(t, baseNode, mergedNode, p)
+
+		sibling := p[:len(p)-1] + "1"
+		baseSibling, _ := base.Locate(sibling)
+		mergedSibling, _ := merged.Locate(sibling)
+		assert.Same(t, baseSibling, mergedSibling, sibling)
+
+		if len(p) == 2 {
+			break
+		}
+	}
+
+	leaf, err := merged.Locate(leafDir + "/leaf")
+	assert.NoError(t, err)
+	assert.Equal(t, common.HexToHash("0x02").Hex(), leaf.Root)
+
+	// identical trees merged to the base tree itself
+	same, err := Merge(base, newDeepTree(6, common.HexToHash("0x01")), ConflictError)
+	assert.NoError(t, err)
+	assert.Same(t, base, same)
+
+	// conflict of the leaf
+	_, err = Merge(base, overlay, ConflictError)
+	var conflict *MergeConflictError
+	if assert.True(t, errors.As(err, &conflict)) {
+		assert.Equal(t, leafDir+"/leaf", conflict.Path)
+	}
+	assert.ErrorIs(t, err, ErrMergeConflict)
+}
+
+func TestMergeOverlay(t *testing.T) {
+	base := NewDirFsNode("", []*FsNode{
+		NewFileFsNode("index.html", common.HexToHash("0x01"), 1),
+		NewDirFsNode("assets", []*FsNode{
+			NewFileFsNode("logo.png", common.HexToHash("0x02"), 2),
+		}),
+	})
+	overlay := NewDirFsNode("", []*FsNode{
+		NewDirFsNode("assets", []*FsNode{
+			NewDirFsNode("de", []*FsNode{NewFileFsNode("strings.json", common.HexToHash("0x03"), 3)}),
+		}),
+		NewSymbolicFsNode("latest", "assets/de"),
+	})
+
+	merged, err := Merge(base, overlay, ConflictError)
+	assert.NoError(t, err)
+	assert.True(t, NewDirFsNode("", []*FsNode{
+		NewFileFsNode("index.html", common.HexToHash("0x01"), 1),
+		NewDirFsNode("assets", []*FsNode{
+			NewFileFsNode("logo.png", common.HexToHash("0x02"), 2),
+			NewDirFsNode("de", []*FsNode{NewFileFsNode("strings.json", common.HexToHash("0x03"), 3)}),
+		}),
+		NewSymbolicFsNode("latest", "assets/de"),
+	}).Equal(merged))
+	assert.Len(t, base.Entries[0].Entries, 1)
+
+	// type conflicts regardless of policy
+	overlay = NewDirFsNode("", []*FsNode{NewDirFsNode("index.html", nil)})
+	for _, policy := range []ConflictPolicy{ConflictOverlay, ConflictError} {
+		_, err = Merge(base, overlay, policy)
+		var conflict *MergeConflictError
+		if assert.True(t, errors.As(err, &conflict)) {
+			assert.Equal(t, MergeConflictError{Path: "index.html", Base: FileTypeFile, Overlay: FileTypeDirectory}, *conflict)
+		}
+		assert.ErrorIs(t, err, ErrTypeConflict)
+	}
+
+	_, err = Merge(base, overlay, "theirs")
+	assert.Error(t, err)
+	_, err = Merge(base, NewFileFsNode("file", common.HexToHash("0x01"), 1), ConflictOverlay)
+	assert.Error(t, err)
+}
@@ -0,0 +1,274 @@
+package dir
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrMigrationMismatch is returned when a file reachable from the old manifest is missing or different in the
+	// migrated manifest.
+	ErrMigrationMismatch = errors.New("migrated manifest mismatch")
+
+	// ErrProvenanceLost is returned when the old manifest is signed, but the migrated manifest is not signed by
+	// the same publisher.
+	ErrProvenanceLost = errors.New("manifest provenance lost")
+)
+
+// ManifestUploader uploads the file tree as directory manifest in canonical form, which is signed if the uploader
+// is configured with a signer. It is satisfied by the uploader returned from transfer.Uploader.ManifestUploader.
+type ManifestUploader interface {
+	UploadManifest(ctx context.Context, root *FsNode) (txHash, manifestRoot common.Hash, err error)
+}
+
+// MigrateOption is the option to migrate a directory manifest.
+type MigrateOption struct {
+	// Limits to decode the old manifest, which are DefaultMaxDepth and DefaultMaxPathLength by default.
+	Limits Limits
+
+	// Verify downloads the migrated manifest, and checks that every file reachable from the old manifest is
+	// reachable from the new one with the same root, and that the publisher is unchanged if signed.
+	Verify bool
+}
+
+// MigratedFile is an entry of the mapping between old and migrated manifests. Paths and file roots are preserved
+// by migration, so that no file data is uploaded again.
+type MigratedFile struct {
+	Path     string   `json:"path"`           // slash separated path relative to the directory
+	Type     FileType `json:"type"`           // regular file or symbolic link
+	Root     string   `json:"hash,omitempty"` // merkle root of regular file
+	Size     int64    `json:"size,omitempty"` // size of regular file
+	Link     string   `json:"link,omitempty"` // target of symbolic link
+	Embedded bool     `json:"embedded,omitempty"`
+}
+
+// MigrationResult is the result of manifest migration, which could be published as the mapping document between
+// old and new manifest roots.
+type MigrationResult struct {
+	OldRoot    common.Hash `json:"oldRoot"`
+	NewRoot    common.Hash `json:"newRoot"`
+	TxHash     common.Hash `json:"txHash"` // transaction to submit the new manifest, zero if not uploaded
+	OldVersion uint16      `json:"oldVersion"`
+	NewVersion uint16      `json:"newVersion"`
+
+	// Publisher that signed the old manifest, nil if unsigned.
+	Publisher *common.Address `json:"publisher,omitempty"`
+
+	// Unchanged is true if the old manifest is already in canonical form, and nothing uploaded.
+	Unchanged bool `json:"unchanged"`
+
+	// Verified is true if the uploaded manifest is verified against the old one, see MigrateOption.Verify.
+	Verified bool `json:"verified"`
+
+	Files []MigratedFile `json:"files"`
+}
+
+// Migrate upgrades the published directory manifest to the canonical form of the current codec, see
+// CanonicalBytes. Manifests published by earlier clients encode JSON metadata in the field order of FsNode with
+// unsorted entries, so the same directory has a different root from the canonical form, and could not be
+// compared or signed by root.
+//
+// The old manifest is downloaded with merkle proof and decoded against the limits, and its signature is verified
+// if signed. Then, the file tree is rebuilt with file roots normalized but otherwise preserved, and only the new
+// manifest is uploaded. If the old manifest is already canonical, nothing is uploaded.
+//
+// Note, the signature of old manifest is not carried over, since it signs the old root. To preserve provenance,
+// the uploader should be configured with the key of the same publisher, which is checked in verification mode.
+func Migrate(ctx context.Context, downloader Downloader, uploader ManifestUploader, oldRoot common.Hash, opts MigrateOption) (*MigrationResult, error) {
+	data, err := downloadManifest(ctx, downloader, oldRoot.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	var old FsNode
+	if err := old.UnmarshalBinaryWithLimits(data, opts.Limits); err != nil {
+		return nil, errors.WithMessage(err, "failed to decode directory metadata")
+	}
+
+	manifest, sig := SplitSignature(data)
+	result := MigrationResult{
+		OldRoot:    oldRoot,
+		OldVersion: manifestVersion(manifest),
+	}
+
+	if sig != nil {
+		if err := sig.Verify(manifest); err != nil {
+			return nil, err
+		}
+
+		result.Publisher = &sig.Signer
+	}
+
+	migrated := migrateNode(&old)
+	canonical, err := CanonicalBytes(migrated)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to encode migrated directory metadata")
+	}
+
+	result.NewVersion = manifestVersion(canonical)
+
+	if result.Files, err = reachableFiles(migrated); err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(manifest, canonical) {
+		result.NewRoot = oldRoot
+		result.Unchanged = true
+		return &result, nil
+	}
+
+	if result.TxHash, result.NewRoot, err = uploader.UploadManifest(ctx, migrated); err != nil {
+		return nil, errors.WithMessage(err, "failed to upload migrated directory metadata")
+	}
+
+	if !opts.Verify {
+		return &result, nil
+	}
+
+	if err := verifyMigrated(ctx, downloader, &old, &result, opts.Limits); err != nil {
+		return nil, err
+	}
+
+	result.Verified = true
+
+	return &result, nil
+}
+
+// verifyMigrated downloads the migrated manifest, and verifies it against the old one.
+func verifyMigrated(ctx context.Context, downloader Downloader, old *FsNode, result *MigrationResult, limits Limits) error {
+	data, err := downloadManifest(ctx, downloader, result.NewRoot.Hex())
+	if err != nil {
+		return err
+	}
+
+	var migrated FsNode
+	if err := migrated.UnmarshalBinaryWithLimits(data, limits); err != nil {
+		return errors.WithMessage(err, "failed to decode migrated directory metadata")
+	}
+
+	if err := VerifyMigration(old, &migrated); err != nil {
+		return err
+	}
+
+	if result.Publisher == nil {
+		return nil
+	}
+
+	if _, err := VerifyPublisher(data, *result.Publisher); err != nil {
+		return errors.WithMessagef(ErrProvenanceLost, "publisher = %v, %v", *result.Publisher, err)
+	}
+
+	return nil
+}
+
+// VerifyMigration checks that every file and symbolic link reachable from the old file tree is reachable from the
+// migrated one at the same path with the same root, size and link target.
+func VerifyMigration(old, migrated *FsNode) error {
+	oldFiles, err := reachableFiles(old)
+	if err != nil {
+		return err
+	}
+
+	newFiles, err := reachableFiles(migrated)
+	if err != nil {
+		return err
+	}
+
+	index := make(map[string]MigratedFile, len(newFiles))
+	for _, file := range newFiles {
+		index[file.Path] = file
+	}
+
+	for _, file := range oldFiles {
+		found, ok := index[file.Path]
+		if !ok {
+			return errors.WithMessagef(ErrMigrationMismatch, "path %q not found", file.Path)
+		}
+
+		if found.Type != file.Type || normalizeRoot(found.Root) != normalizeRoot(file.Root) || found.Size != file.Size || found.Link != file.Link {
+			return errors.WithMessagef(ErrMigrationMismatch, "path %q changed", file.Path)
+		}
+	}
+
+	return nil
+}
+
+// migrateNode returns a copy of file tree with file roots normalized, i.e. lower case hex with 0x prefix.
+func migrateNode(node *FsNode) *FsNode {
+	migrated := *node
+
+	if node.Type == FileTypeFile {
+		migrated.Root = normalizeRoot(node.Root)
+	}
+
+	if len(node.Entries) > 0 {
+		migrated.Entries = make([]*FsNode, len(node.Entries))
+		for i, entry := range node.Entries {
+			migrated.Entries[i] = migrateNode(entry)
+		}
+	}
+
+	return &migrated
+}
+
+// normalizeRoot returns the canonical hex form of merkle root, or as it is if not a valid hash.
+func normalizeRoot(root string) string {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(root, "0x"), "0X"))
+	if err != nil || len(b) != common.HashLength {
+		return root
+	}
+
+	return common.BytesToHash(b).Hex()
+}
+
+// reachableFiles returns regular files and symbolic links reachable from the directory in order.
+func reachableFiles(root *FsNode) ([]MigratedFile, error) {
+	files := []MigratedFile{}
+
+	base, err := root.LocalName()
+	if err != nil {
+		return nil, err
+	}
+
+	err = root.Traverse(func(node *FsNode, relativePath string) error {
+		if node.Type != FileTypeFile && node.Type != FileTypeSymbolic {
+			return nil
+		}
+
+		rel, err := filepath.Rel(base, relativePath)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, MigratedFile{
+			Path:     path.Clean("/" + filepath.ToSlash(rel))[1:],
+			Type:     node.Type,
+			Root:     node.Root,
+			Size:     node.Size,
+			Link:     node.Link,
+			Embedded: node.Embedded(),
+		})
+
+		return nil
+	})
+
+	return files, err
+}
+
+// manifestVersion returns the codec version of encoded manifest.
+func manifestVersion(manifest []byte) uint16 {
+	offset := len(CodecMagicBytes)
+	if len(manifest) < offset+2 {
+		return 0
+	}
+
+	return binary.BigEndian.Uint16(manifest[offset:])
+}
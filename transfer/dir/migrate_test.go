@@ -0,0 +1,174 @@
+package dir_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"strings"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// memManifestUploader uploads manifest in canonical form to memDownloader, which is signed if key specified.
+type memManifestUploader struct {
+	t       *testing.T
+	d       *memDownloader
+	key     *ecdsa.PrivateKey
+	uploads int
+}
+
+func (u *memManifestUploader) UploadManifest(ctx context.Context, root *dir.FsNode) (common.Hash, common.Hash, error) {
+	var data []byte
+	var err error
+	if u.key != nil {
+		data, err = dir.SignManifest(root, u.key)
+	} else {
+		data, err = dir.CanonicalBytes(root)
+	}
+	if err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+
+	u.uploads++
+
+	return common.Hash{1}, common.HexToHash(u.d.add(u.t, data)), nil
+}
+
+// newLegacyTree returns a file tree with unsorted entries and upper case file roots, as published by old clients.
+func newLegacyTree(t *testing.T, d *memDownloader) *dir.FsNode {
+	readme := newFileNode(t, d, "README.md", "# readme")
+	readme.Root = "0x" + strings.ToUpper(readme.Root[2:])
+
+	return &dir.FsNode{Name: "/", Type: dir.FileTypeDirectory, Entries: []*dir.FsNode{
+		readme,
+		{Name: "docs", Type: dir.FileTypeDirectory, Entries: []*dir.FsNode{
+			newFileNode(t, d, "guide.md", "# guide"),
+			{Name: "empty.txt", Type: dir.FileTypeFile},
+		}},
+		dir.NewSymbolicFsNode("LICENSE", "README.md"),
+	}}
+}
+
+// signLegacy signs the legacy manifest as old clients did.
+func signLegacy(t *testing.T, manifest []byte, key *ecdsa.PrivateKey) []byte {
+	data, err := core.NewDataInMemory(manifest)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(t, err)
+
+	signature, err := crypto.Sign(accounts.TextHash(tree.Root().Bytes()), key)
+	assert.NoError(t, err)
+
+	signed := append(append([]byte(nil), manifest...), crypto.PubkeyToAddress(key.PublicKey).Bytes()...)
+	signed = append(signed, signature...)
+
+	return append(signed, dir.SignatureMagicBytes...)
+}
+
+func TestMigrate(t *testing.T) {
+	downloader := newMemDownloader()
+	uploader := memManifestUploader{t: t, d: downloader}
+
+	tree := newLegacyTree(t, downloader)
+	legacy, err := tree.MarshalBinary()
+	assert.NoError(t, err)
+	oldRoot := common.HexToHash(downloader.add(t, legacy))
+
+	result, err := dir.Migrate(context.Background(), downloader, &uploader, oldRoot, dir.MigrateOption{Verify: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, uploader.uploads)
+	assert.False(t, result.Unchanged)
+	assert.True(t, result.Verified)
+	assert.Nil(t, result.Publisher)
+	assert.Equal(t, oldRoot, result.OldRoot)
+	assert.NotEqual(t, oldRoot, result.NewRoot)
+	assert.Equal(t, dir.CodecVersion, result.NewVersion)
+
+	// file roots preserved in canonical form
+	assert.Equal(t, []string{"README.md", "docs/guide.md", "docs/empty.txt", "LICENSE"}, migratedPaths(result.Files))
+	assert.Equal(t, strings.ToLower(tree.Entries[0].Root), result.Files[0].Root)
+	assert.Equal(t, "README.md", result.Files[3].Link)
+
+	var migrated dir.FsNode
+	assert.NoError(t, migrated.UnmarshalBinary(downloader.files[result.NewRoot.Hex()]))
+	newRoot, err := dir.ManifestRoot(&migrated)
+	assert.NoError(t, err)
+	assert.Equal(t, result.NewRoot, newRoot)
+
+	// nothing uploaded once migrated
+	result, err = dir.Migrate(context.Background(), downloader, &uploader, newRoot, dir.MigrateOption{Verify: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, uploader.uploads)
+	assert.True(t, result.Unchanged)
+	assert.Equal(t, newRoot, result.NewRoot)
+}
+
+func migratedPaths(files []dir.MigratedFile) []string {
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+func TestMigrateSigned(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	downloader := newMemDownloader()
+	legacy, err := newLegacyTree(t, downloader).MarshalBinary()
+	assert.NoError(t, err)
+	oldRoot := common.HexToHash(downloader.add(t, signLegacy(t, legacy, key)))
+
+	// provenance lost if not signed by the same publisher
+	uploader := memManifestUploader{t: t, d: downloader}
+	_, err = dir.Migrate(context.Background(), downloader, &uploader, oldRoot, dir.MigrateOption{Verify: true})
+	assert.ErrorIs(t, err, dir.ErrProvenanceLost)
+
+	uploader.key = key
+	result, err := dir.Migrate(context.Background(), downloader, &uploader, oldRoot, dir.MigrateOption{Verify: true})
+	assert.NoError(t, err)
+	assert.True(t, result.Verified)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), *result.Publisher)
+
+	_, err = dir.VerifyPublisher(downloader.files[result.NewRoot.Hex()], *result.Publisher)
+	assert.NoError(t, err)
+}
+
+func TestVerifyMigration(t *testing.T) {
+	downloader := newMemDownloader()
+	old := dir.NewDirFsNode("/", []*dir.FsNode{
+		newFileNode(t, downloader, "README.md", "# readme"),
+		dir.NewDirFsNode("docs", []*dir.FsNode{
+			newFileNode(t, downloader, "guide.md", "# guide"),
+		}),
+		dir.NewSymbolicFsNode("LICENSE", "README.md"),
+	})
+
+	assert.NoError(t, dir.VerifyMigration(old, old))
+
+	// file removed
+	removed, err := dir.Patch(old, []dir.PatchOp{dir.Delete("docs/guide.md")})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, dir.VerifyMigration(old, removed), dir.ErrMigrationMismatch)
+
+	// link changed
+	relinked, err := dir.Patch(old, []dir.PatchOp{dir.Put("LICENSE", dir.NewSymbolicFsNode("LICENSE", "docs/guide.md"))})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, dir.VerifyMigration(old, relinked), dir.ErrMigrationMismatch)
+
+	// file changed
+	changed, err := dir.Patch(old, []dir.PatchOp{dir.Put("README.md", newFileNode(t, downloader, "README.md", "changed"))})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, dir.VerifyMigration(old, changed), dir.ErrMigrationMismatch)
+
+	// new files allowed
+	added, err := dir.Patch(old, []dir.PatchOp{dir.Put("docs/new.md", newFileNode(t, downloader, "new.md", "new"))})
+	assert.NoError(t, err)
+	assert.NoError(t, dir.VerifyMigration(old, added))
+}
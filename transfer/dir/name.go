@@ -0,0 +1,172 @@
+package dir
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// NameEncoding is the policy to encode file names that are not valid UTF-8 in directory metadata, since JSON
+// only supports UTF-8 strings, and invalid bytes are replaced with U+FFFD when marshalled.
+type NameEncoding string
+
+const (
+	// NameEncodingError rejects file names that are not valid UTF-8, which should be specified explicitly for strict
+	// uploads.
+	NameEncodingError NameEncoding = "error"
+
+	// NameEncodingPercent escapes the invalid bytes and '%' of file names that are not valid UTF-8 in the form of
+	// %XX, and marks the names as escaped, so that the original bytes are restored on download, except on Windows
+	// where names are always Unicode and the escaped form is used instead.
+	NameEncodingPercent NameEncoding = "percent"
+
+	// NameEncodingReplace replaces the invalid bytes of file names with U+FFFD, where the original bytes are lost.
+	// It is the default policy if not specified, which is compatible with directories uploaded before policies
+	// introduced.
+	NameEncodingReplace NameEncoding = "replace"
+)
+
+var (
+	// ErrNonUTF8Name is returned when building file tree with file names that are not valid UTF-8 under the
+	// NameEncodingError policy.
+	ErrNonUTF8Name = errors.New("file name is not valid UTF-8")
+
+	// ErrInvalidEscapedName is returned when the escaped file name in directory metadata could not be restored to a
+	// valid file name.
+	ErrInvalidEscapedName = errors.New("invalid escaped file name")
+)
+
+// Validate checks whether the name encoding policy is supported.
+func (encoding NameEncoding) Validate() error {
+	switch encoding {
+	case "", NameEncodingError, NameEncodingPercent, NameEncodingReplace:
+		return nil
+	default:
+		return errors.Errorf("unsupported name encoding %q", string(encoding))
+	}
+}
+
+// orDefault returns the policy applied, which is NameEncodingReplace if not specified.
+func (encoding NameEncoding) orDefault() NameEncoding {
+	if len(encoding) == 0 {
+		return NameEncodingReplace
+	}
+
+	return encoding
+}
+
+// encodeName encodes the file name at path by policy, and returns the encoded name, and whether the name was
+// escaped or replaced.
+func (encoding NameEncoding) encodeName(name, path string) (encoded string, escaped, replaced bool, err error) {
+	if utf8.ValidString(name) {
+		return name, false, false, nil
+	}
+
+	switch encoding.orDefault() {
+	case NameEncodingPercent:
+		return escapeName(name), true, false, nil
+	case NameEncodingReplace:
+		return strings.ToValidUTF8(name, string(utf8.RuneError)), false, true, nil
+	case NameEncodingError:
+		return "", false, false, errors.WithMessagef(ErrNonUTF8Name, "path %q", path)
+	default:
+		return "", false, false, encoding.Validate()
+	}
+}
+
+// escapeName escapes the invalid UTF-8 bytes and '%' in the form of %XX.
+func escapeName(name string) string {
+	var builder strings.Builder
+
+	for i := 0; i < len(name); {
+		r, size := utf8.DecodeRuneInString(name[i:])
+		if (r == utf8.RuneError && size == 1) || r == '%' {
+			fmt.Fprintf(&builder, "%%%02X", name[i])
+		} else {
+			builder.WriteString(name[i : i+size])
+		}
+
+		i += size
+	}
+
+	return builder.String()
+}
+
+// unescapeName restores the original bytes of name escaped by escapeName.
+func unescapeName(name string) (string, error) {
+	var builder strings.Builder
+
+	for i := 0; i < len(name); i++ {
+		if name[i] != '%' {
+			builder.WriteByte(name[i])
+			continue
+		}
+
+		if i+2 >= len(name) || !isHex(name[i+1]) || !isHex(name[i+2]) {
+			return "", errors.WithMessagef(ErrInvalidEscapedName, "%q", name)
+		}
+
+		builder.WriteByte(unhex(name[i+1])<<4 | unhex(name[i+2]))
+		i += 2
+	}
+
+	return builder.String(), nil
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	default:
+		return c - 'a' + 10
+	}
+}
+
+// LocalName returns the name of node on local file system, which is the original bytes of escaped name, except
+// on Windows where the escaped name is used as is.
+func (node *FsNode) LocalName() (string, error) {
+	if !node.Escaped {
+		return node.Name, nil
+	}
+
+	name, err := unescapeName(node.Name)
+	if err != nil {
+		return "", err
+	}
+
+	// escaped name of untrusted metadata must not escape from the directory
+	if len(name) == 0 || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return "", errors.WithMessagef(ErrInvalidEscapedName, "%q", node.Name)
+	}
+
+	if runtime.GOOS == "windows" {
+		return node.Name, nil
+	}
+
+	return name, nil
+}
+
+// VerifyNames verifies the name encoding policy and escaped names of the file tree, e.g. decoded from untrusted
+// directory metadata.
+func (node *FsNode) VerifyNames() error {
+	if err := node.NameEncoding.Validate(); err != nil {
+		return err
+	}
+
+	return node.Traverse(func(n *FsNode, _ string) error {
+		if n.Escaped && node.NameEncoding != NameEncodingPercent {
+			return errors.WithMessagef(ErrInvalidEscapedName, "%q escaped under %q policy", n.Name, string(node.NameEncoding))
+		}
+
+		return nil
+	})
+}
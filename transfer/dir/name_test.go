@@ -0,0 +1,160 @@
+package dir_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+// newNonUTF8Folder creates a folder with file names in latin-1 and raw bytes, which is supported on Linux only.
+func newNonUTF8Folder(t *testing.T) (folder string, relpaths []string) {
+	if runtime.GOOS != "linux" {
+		t.Skip("raw-byte file names are supported on Linux only")
+	}
+
+	folder = t.TempDir()
+	relpaths = []string{
+		"caf\xe9.txt", // latin-1
+		"\xff\xfe%41", // raw bytes and '%'
+		filepath.Join("\xff\xfe%41", "r\xe9sum\xe9.txt"), // nested in non-UTF8 directory
+		"valid %41.txt", // valid UTF-8 kept as is
+	}
+
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, relpaths[0]), []byte("latin-1"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(folder, relpaths[1]), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, relpaths[2]), []byte("nested"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, relpaths[3]), []byte("valid"), 0644))
+
+	sort.Strings(relpaths)
+
+	return folder, relpaths
+}
+
+func flattenFiles(t *testing.T, root *dir.FsNode) []string {
+	_, relpaths := root.Flatten()
+	assert.Equal(t, "/", relpaths[0])
+
+	var files []string
+	for _, relpath := range relpaths[1:] {
+		rel, err := filepath.Rel("/", relpath)
+		assert.NoError(t, err)
+		files = append(files, rel)
+	}
+
+	sort.Strings(files)
+
+	return files
+}
+
+func TestBuildFileTreeNonUTF8Error(t *testing.T) {
+	folder, _ := newNonUTF8Folder(t)
+
+	_, err := dir.BuildFileTreeWithOption(folder, dir.BuildOption{NameEncoding: dir.NameEncodingError})
+	assert.ErrorIs(t, err, dir.ErrNonUTF8Name)
+	assert.ErrorContains(t, err, `caf\xe9.txt`)
+
+	_, err = dir.BuildFileTreeWithOption(folder, dir.BuildOption{NameEncoding: "unknown"})
+	assert.ErrorContains(t, err, "unsupported name encoding")
+}
+
+func TestBuildFileTreeNonUTF8Percent(t *testing.T) {
+	folder, relpaths := newNonUTF8Folder(t)
+
+	root, err := dir.BuildFileTreeWithOption(folder, dir.BuildOption{NameEncoding: dir.NameEncodingPercent})
+	assert.NoError(t, err)
+	assert.Equal(t, dir.NameEncodingPercent, root.NameEncoding)
+
+	node, found := root.Search("caf%E9.txt")
+	assert.True(t, found)
+	assert.True(t, node.Escaped)
+	node, found = root.Search("valid %41.txt")
+	assert.True(t, found)
+	assert.False(t, node.Escaped)
+	node, found = root.Search("%FF%FE%2541")
+	assert.True(t, found)
+	assert.True(t, node.Escaped)
+
+	// round trip via directory metadata, and original bytes restored
+	data, err := dir.CanonicalBytes(root)
+	assert.NoError(t, err)
+	var decoded dir.FsNode
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.True(t, root.Equal(&decoded))
+	assert.Equal(t, dir.NameEncodingPercent, decoded.NameEncoding)
+	assert.Equal(t, relpaths, flattenFiles(t, &decoded))
+
+	// materialize files at the restored paths
+	target := t.TempDir()
+	nodes, paths := decoded.Flatten()
+	for i, node := range nodes {
+		if node.Type == dir.FileTypeDirectory {
+			assert.NoError(t, os.MkdirAll(filepath.Join(target, paths[i]), 0755))
+		} else {
+			assert.NoError(t, os.WriteFile(filepath.Join(target, paths[i]), nil, 0644))
+		}
+	}
+
+	for _, relpath := range relpaths {
+		_, err := os.Stat(filepath.Join(target, relpath))
+		assert.NoError(t, err)
+	}
+}
+
+func TestBuildFileTreeNonUTF8Replace(t *testing.T) {
+	folder, _ := newNonUTF8Folder(t)
+
+	// replaced by default
+	for _, encoding := range []dir.NameEncoding{dir.NameEncodingReplace, ""} {
+		var replaced []string
+		root, err := dir.BuildFileTreeWithOption(folder, dir.BuildOption{
+			NameEncoding:   encoding,
+			OnNameReplaced: func(path string) { replaced = append(replaced, path) },
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, dir.NameEncodingReplace, root.NameEncoding)
+		assert.Equal(t, 3, len(replaced))
+
+		_, found := root.Search("caf�.txt")
+		assert.True(t, found)
+		_, err = root.Locate("�%41/r�sum�.txt") // a run of invalid bytes replaced once
+		assert.NoError(t, err)
+	}
+}
+
+func TestBuildFileTreeUTF8Unchanged(t *testing.T) {
+	folder := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "100%.txt"), []byte("valid"), 0644))
+
+	// policy not recorded if no name encoded, so that the metadata is unchanged
+	root, err := dir.BuildFileTreeWithOption(folder, dir.BuildOption{NameEncoding: dir.NameEncodingPercent})
+	assert.NoError(t, err)
+	expected, err := dir.BuildFileTree(folder)
+	assert.NoError(t, err)
+
+	assert.Empty(t, root.NameEncoding)
+	assert.Equal(t, expected, root)
+}
+
+func TestUnmarshalInvalidEscapedName(t *testing.T) {
+	for _, name := range []string{"%2F..%2Fpasswd", "..", "%2", "%zz", "a%00b"} {
+		root := dir.NewDirFsNode("/", []*dir.FsNode{{Name: name, Type: dir.FileTypeFile, Escaped: true}})
+		root.NameEncoding = dir.NameEncodingPercent
+
+		data, err := dir.CanonicalBytes(root)
+		assert.NoError(t, err)
+		var decoded dir.FsNode
+		assert.ErrorIs(t, decoded.UnmarshalBinary(data), dir.ErrInvalidEscapedName, name)
+	}
+
+	// escaped without percent policy recorded
+	root := dir.NewDirFsNode("/", []*dir.FsNode{{Name: "caf%E9", Type: dir.FileTypeFile, Escaped: true}})
+	data, err := dir.CanonicalBytes(root)
+	assert.NoError(t, err)
+	var decoded dir.FsNode
+	assert.ErrorIs(t, decoded.UnmarshalBinary(data), dir.ErrInvalidEscapedName)
+}
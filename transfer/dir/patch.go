@@ -0,0 +1,217 @@
+package dir
+
+import (
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PatchOpType is the type of operation to patch a directory.
+type PatchOpType string
+
+const (
+	PatchOpPut    PatchOpType = "put"    // add or replace the entry
+	PatchOpDelete PatchOpType = "delete" // remove the entry
+	PatchOpMkdir  PatchOpType = "mkdir"  // create an empty directory if not exists
+)
+
+// PatchOp is an operation to patch a directory, of which the path is relative to the patched directory.
+type PatchOp struct {
+	Type PatchOpType
+	Path string
+	Node *FsNode // entry to put, which is renamed as the base name of path
+}
+
+// Put returns an operation to add or replace the entry at path with the specified node.
+func Put(path string, node *FsNode) PatchOp {
+	return PatchOp{Type: PatchOpPut, Path: path, Node: node}
+}
+
+// Delete returns an operation to remove the entry at path.
+func Delete(path string) PatchOp {
+	return PatchOp{Type: PatchOpDelete, Path: path}
+}
+
+// Mkdir returns an operation to create an empty directory at path, which does nothing if the directory exists.
+func Mkdir(path string) PatchOp {
+	return PatchOp{Type: PatchOpMkdir, Path: path}
+}
+
+// splitPatchPath splits the path into non-empty parts, and the root directory is not allowed to patch.
+func splitPatchPath(p string) ([]string, error) {
+	cleaned := strings.Trim(path.Clean("/"+p), "/")
+	if len(cleaned) == 0 {
+		return nil, errors.Errorf("invalid path %q to patch", p)
+	}
+
+	return strings.Split(cleaned, "/"), nil
+}
+
+// Patch applies the operations in order to the directory, and returns the patched directory. The original
+// directory is not modified, and only the directories along the patched paths are copied, so that untouched
+// subtrees are shared with the original directory and encoded byte-identically.
+//
+// Parent directories must exist to put or create an entry, see Mkdir.
+func Patch(root *FsNode, ops []PatchOp) (*FsNode, error) {
+	if root == nil || root.Type != FileTypeDirectory {
+		return nil, errors.New("only directory could be patched")
+	}
+
+	for i, op := range ops {
+		parts, err := splitPatchPath(op.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		if root, err = patch(root, parts, &op); err != nil {
+			return nil, errors.WithMessagef(err, "failed to %v %v (op #%v)", op.Type, op.Path, i)
+		}
+	}
+
+	return root, nil
+}
+
+// patch applies the operation to the entry at the specified path parts of directory, and returns the patched copy
+// of directory.
+func patch(directory *FsNode, parts []string, op *PatchOp) (*FsNode, error) {
+	directory = directory.withSortedEntries()
+
+	name := parts[0]
+	index := sort.Search(len(directory.Entries), func(i int) bool { return directory.Entries[i].Name >= name })
+	found := index < len(directory.Entries) && directory.Entries[index].Name == name
+
+	// entry of parent directory
+	if len(parts) > 1 {
+		if !found {
+			return nil, errors.Errorf("directory %q not found", name)
+		}
+
+		if directory.Entries[index].Type != FileTypeDirectory {
+			return nil, errors.Errorf("%q is not a directory", name)
+		}
+
+		entry, err := patch(directory.Entries[index], parts[1:], op)
+		if err != nil {
+			return nil, err
+		}
+
+		return directory.withEntry(index, true, entry), nil
+	}
+
+	switch op.Type {
+	case PatchOpPut:
+		if op.Node == nil {
+			return nil, errors.New("nil node to put")
+		}
+
+		entry := *op.Node
+		entry.Name = name
+
+		return directory.withEntry(index, found, &entry), nil
+	case PatchOpDelete:
+		if !found {
+			return nil, errors.Errorf("%q not found", name)
+		}
+
+		return directory.withEntry(index, true, nil), nil
+	case PatchOpMkdir:
+		if !found {
+			return directory.withEntry(index, false, NewDirFsNode(name, nil)), nil
+		}
+
+		if directory.Entries[index].Type != FileTypeDirectory {
+			return nil, errors.Errorf("%q already exists and is not a directory", name)
+		}
+
+		return directory, nil
+	default:
+		return nil, errors.Errorf("unknown patch operation %q", op.Type)
+	}
+}
+
+// withSortedEntries returns the directory itself if entries are sorted by name, otherwise a copy of directory with
+// entries sorted, e.g. directory constructed manually or decoded from a manifest not in canonical form, so that
+// entries could be searched by name.
+func (node *FsNode) withSortedEntries() *FsNode {
+	if sort.SliceIsSorted(node.Entries, func(i, j int) bool { return node.Entries[i].Name < node.Entries[j].Name }) {
+		return node
+	}
+
+	copied := *node
+	copied.Entries = slices.Clone(node.Entries)
+	sortEntries(copied.Entries)
+
+	return &copied
+}
+
+// withEntry returns a copy of directory, of which the entry at index is replaced if exists, otherwise inserted.
+// The entry is removed if nil.
+func (node *FsNode) withEntry(index int, exists bool, entry *FsNode) *FsNode {
+	copied := *node
+	copied.Entries = make([]*FsNode, 0, len(node.Entries)+1)
+	copied.Entries = append(copied.Entries, node.Entries[:index]...)
+
+	if entry != nil {
+		copied.Entries = append(copied.Entries, entry)
+	}
+
+	if exists {
+		index++
+	}
+	copied.Entries = append(copied.Entries, node.Entries[index:]...)
+
+	return &copied
+}
+
+// DiffOps returns the operations to patch the old directory into the updated one, e.g. to publish the changes of
+// local directory incrementally by Patch. Directories added or removed are put or deleted as a whole, and entries
+// modified are put, see DiffPaths.
+func DiffOps(old, updated *FsNode) ([]PatchOp, error) {
+	result, err := DiffPaths(old, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []PatchOp
+	visited := make(map[string]bool)
+
+	for _, removed := range result.Removed {
+		if top := topMissing(updated, removed.Path); !visited[top] {
+			visited[top] = true
+			ops = append(ops, Delete(top))
+		}
+	}
+
+	for _, added := range result.Added {
+		if top := topMissing(old, added.Path); !visited[top] {
+			visited[top] = true
+
+			node, err := updated.Locate(top)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, Put(top, node))
+		}
+	}
+
+	for _, modified := range result.Modified {
+		ops = append(ops, Put(modified.Path, modified.New))
+	}
+
+	return ops, nil
+}
+
+// topMissing returns the shallowest ancestor of path, or the path itself, that does not exist in the directory.
+func topMissing(directory *FsNode, p string) string {
+	parts := strings.Split(p, "/")
+	for i := 1; i < len(parts); i++ {
+		if _, err := directory.Locate(path.Join(parts[:i]...)); err != nil {
+			return path.Join(parts[:i]...)
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,185 @@
+package dir_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPatchTestTree creates a directory of the specified depth, and each directory has the specified number of
+// files and sub directories.
+func newPatchTestTree(name string, depth, fanout int) *dir.FsNode {
+	var entries []*dir.FsNode
+	for i := 0; i < fanout; i++ {
+		entries = append(entries, dir.NewFileFsNode(fmt.Sprintf("file%v", i), common.BigToHash(common.Big1), 1))
+		if depth > 1 {
+			entries = append(entries, newPatchTestTree(fmt.Sprintf("dir%v", i), depth-1, fanout))
+		}
+	}
+
+	return dir.NewDirFsNode(name, entries)
+}
+
+// changedNodes returns the nodes in tree that are not shared with the original tree.
+func changedNodes(original, tree *dir.FsNode) (changed []string) {
+	shared := make(map[*dir.FsNode]bool)
+	original.Traverse(func(node *dir.FsNode, _ string) error {
+		shared[node] = true
+		return nil
+	})
+
+	tree.Traverse(func(node *dir.FsNode, relPath string) error {
+		if !shared[node] {
+			changed = append(changed, relPath)
+		}
+		return nil
+	})
+
+	return changed
+}
+
+func TestPatchSingleFile(t *testing.T) {
+	original := newPatchTestTree("/", 5, 4)
+	encoded, err := dir.CanonicalBytes(original)
+	assert.NoError(t, err)
+
+	file := dir.NewFileFsNode("ignored", common.BigToHash(common.Big2), 2)
+	patched, err := dir.Patch(original, []dir.PatchOp{dir.Put("dir1/dir2/dir3/file0", file)})
+	assert.NoError(t, err)
+
+	// only directories along the path and the file changed
+	assert.Equal(t, []string{"/", "/dir1", "/dir1/dir2", "/dir1/dir2/dir3", "/dir1/dir2/dir3/file0"}, changedNodes(original, patched))
+
+	located, err := patched.Locate("dir1/dir2/dir3/file0")
+	assert.NoError(t, err)
+	assert.Equal(t, "file0", located.Name)
+	assert.Equal(t, file.Root, located.Root)
+
+	// untouched subtrees are encoded byte-identically
+	for _, name := range []string{"dir0", "dir1/dir0", "dir1/dir2/dir1"} {
+		before, err := original.Locate(name)
+		assert.NoError(t, err)
+		after, err := patched.Locate(name)
+		assert.NoError(t, err)
+		assert.Same(t, before, after)
+	}
+
+	// original unchanged
+	reencoded, err := dir.CanonicalBytes(original)
+	assert.NoError(t, err)
+	assert.Equal(t, encoded, reencoded)
+}
+
+func TestPatch(t *testing.T) {
+	original := newPatchTestTree("/", 2, 2)
+	link := dir.NewSymbolicFsNode("", "file0")
+
+	patched, err := dir.Patch(original, []dir.PatchOp{
+		dir.Delete("file1"),
+		dir.Mkdir("dir0"), // exists
+		dir.Mkdir("new"),
+		dir.Put("new/link", link),
+		dir.Put("/dir1/file0/", dir.NewFileFsNode("", common.BigToHash(common.Big2), 2)),
+		dir.Delete("dir0"),
+	})
+	assert.NoError(t, err)
+
+	expected := dir.NewDirFsNode("/", []*dir.FsNode{
+		dir.NewFileFsNode("file0", common.BigToHash(common.Big1), 1),
+		dir.NewDirFsNode("dir1", []*dir.FsNode{
+			dir.NewFileFsNode("file0", common.BigToHash(common.Big2), 2),
+			dir.NewFileFsNode("file1", common.BigToHash(common.Big1), 1),
+		}),
+		dir.NewDirFsNode("new", []*dir.FsNode{dir.NewSymbolicFsNode("link", "file0")}),
+	})
+	assert.True(t, expected.Equal(patched))
+
+	// canonical form equals to the tree built from scratch
+	expectedRoot, err := dir.ManifestRoot(expected)
+	assert.NoError(t, err)
+	patchedRoot, err := dir.ManifestRoot(patched)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedRoot, patchedRoot)
+}
+
+func TestPatchUnsortedEntries(t *testing.T) {
+	// entries not sorted, e.g. constructed manually
+	original := &dir.FsNode{Name: "/", Type: dir.FileTypeDirectory, Entries: []*dir.FsNode{
+		dir.NewFileFsNode("c", common.BigToHash(common.Big1), 1),
+		dir.NewFileFsNode("a", common.BigToHash(common.Big1), 1),
+		dir.NewFileFsNode("b", common.BigToHash(common.Big1), 1),
+	}}
+
+	patched, err := dir.Patch(original, []dir.PatchOp{
+		dir.Put("c", dir.NewFileFsNode("", common.BigToHash(common.Big2), 2)),
+		dir.Delete("a"),
+	})
+	assert.NoError(t, err)
+
+	expected := dir.NewDirFsNode("/", []*dir.FsNode{
+		dir.NewFileFsNode("b", common.BigToHash(common.Big1), 1),
+		dir.NewFileFsNode("c", common.BigToHash(common.Big2), 2),
+	})
+	assert.True(t, expected.Equal(patched))
+
+	// original not modified
+	assert.Equal(t, []string{"c", "a", "b"}, []string{original.Entries[0].Name, original.Entries[1].Name, original.Entries[2].Name})
+}
+
+func TestPatchErrors(t *testing.T) {
+	original := newPatchTestTree("/", 2, 1)
+	file := dir.NewFileFsNode("", common.Hash{}, 1)
+
+	for _, op := range []dir.PatchOp{
+		dir.Put("", file),
+		dir.Put("missing/file", file),
+		dir.Put("file0/file", file),
+		dir.Put("file", nil),
+		dir.Delete("missing"),
+		dir.Mkdir("file0"),
+		dir.Mkdir("missing/dir"),
+		{Type: "move", Path: "file0"},
+	} {
+		_, err := dir.Patch(original, []dir.PatchOp{op})
+		assert.Error(t, err, "%v %v", op.Type, op.Path)
+	}
+
+	_, err := dir.Patch(dir.NewFileFsNode("file", common.Hash{}, 1), nil)
+	assert.Error(t, err)
+}
+
+func TestDiffOps(t *testing.T) {
+	original := newPatchTestTree("/", 3, 2)
+	updated, err := dir.Patch(original, []dir.PatchOp{
+		dir.Delete("dir0"),
+		dir.Delete("dir1/file1"),
+		dir.Put("dir1/dir0", dir.NewFileFsNode("", common.BigToHash(common.Big2), 2)),
+		dir.Put("dir1/file0", dir.NewFileFsNode("", common.BigToHash(common.Big3), 3)),
+		dir.Put("new", newPatchTestTree("", 2, 2)),
+		dir.Put("link", dir.NewSymbolicFsNode("", "file0")),
+	})
+	assert.NoError(t, err)
+
+	ops, err := dir.DiffOps(original, updated)
+	assert.NoError(t, err)
+
+	// directories added or removed as a whole
+	var paths []string
+	for _, op := range ops {
+		paths = append(paths, fmt.Sprintf("%v %v", op.Type, op.Path))
+	}
+	assert.Equal(t, []string{
+		"delete dir0", "delete dir1/file1", "put link", "put new", "put dir1/dir0", "put dir1/file0",
+	}, paths)
+
+	patched, err := dir.Patch(original, ops)
+	assert.NoError(t, err)
+	assert.True(t, updated.Equal(patched))
+
+	ops, err = dir.DiffOps(updated, updated)
+	assert.NoError(t, err)
+	assert.Empty(t, ops)
+}
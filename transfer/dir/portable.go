@@ -0,0 +1,166 @@
+package dir
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// PortableNamePolicy is the policy to handle file names that are not portable across common download targets when
+// building file tree, e.g. README.md and Readme.md in the same directory, of which one is silently clobbered when
+// downloaded on Windows or macOS.
+type PortableNamePolicy string
+
+const (
+	// PortableNamesReject fails to build file tree with a NonPortableNameError that lists all offending paths, which
+	// is the default policy if not specified.
+	PortableNamesReject PortableNamePolicy = "reject"
+
+	// PortableNamesWarn reports the offending paths by callback, and keeps them in the file tree as is, e.g. for
+	// directories downloaded on Linux only.
+	PortableNamesWarn PortableNamePolicy = "warn"
+)
+
+// ErrNonPortableName is returned when building file tree with file names that are not portable under the
+// PortableNamesReject policy, which is wrapped by NonPortableNameError.
+var ErrNonPortableName = errors.New("file name not portable")
+
+// Validate checks whether the portable name policy is supported.
+func (policy PortableNamePolicy) Validate() error {
+	switch policy {
+	case "", PortableNamesReject, PortableNamesWarn:
+		return nil
+	default:
+		return errors.Errorf("unsupported portable name policy %q", string(policy))
+	}
+}
+
+// NameIssue is a file name that is not portable across common download targets.
+type NameIssue struct {
+	Path     string // slash-separated path relative to the root directory
+	Reason   string // why the name is not portable
+	Conflict string // path of the entry that collides with, only for case-insensitive collision
+}
+
+func (issue NameIssue) String() string {
+	if len(issue.Conflict) > 0 {
+		return fmt.Sprintf("%q: %v with %q", issue.Path, issue.Reason, issue.Conflict)
+	}
+
+	return fmt.Sprintf("%q: %v", issue.Path, issue.Reason)
+}
+
+// NonPortableNameError is the error that lists all file names not portable in the file tree.
+type NonPortableNameError struct {
+	Issues []NameIssue
+}
+
+func (e *NonPortableNameError) Error() string {
+	issues := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		issues = append(issues, issue.String())
+	}
+
+	return fmt.Sprintf("%v: %v", ErrNonPortableName, strings.Join(issues, ", "))
+}
+
+// Unwrap returns ErrNonPortableName, so that errors.Is could be used to check the error.
+func (e *NonPortableNameError) Unwrap() error {
+	return ErrNonPortableName
+}
+
+// windowsReservedNames are device names reserved on Windows, which are reserved with any extension as well.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// nonPortableReason returns why the file name is invalid on common download targets, or empty if portable.
+func nonPortableReason(name string) string {
+	switch {
+	case strings.ContainsAny(name, "/\x00"):
+		return "contains '/' or NUL"
+	case strings.IndexFunc(name, func(r rune) bool { return r < 0x20 }) >= 0:
+		return "contains control characters"
+	case strings.ContainsAny(name, `<>:"\|?*`):
+		return `contains characters reserved on Windows <>:"\|?*`
+	case strings.HasSuffix(name, ".") || strings.HasSuffix(name, " "):
+		return "ends with dot or space"
+	}
+
+	base, _, _ := strings.Cut(name, ".")
+	if windowsReservedNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+		return "reserved name on Windows"
+	}
+
+	return ""
+}
+
+// PortableNameIssues returns all file names within the node that are not portable across common download targets,
+// including names invalid on Windows, e.g. trailing dots or spaces and reserved device names like CON, and names
+// that collide with another entry of the same directory case-insensitively, in the order of Walk. Name of the
+// node itself is not checked, since it is not used on download.
+func (node *FsNode) PortableNameIssues() []NameIssue {
+	var issues []NameIssue
+
+	node.Walk(func(relpath string, n *FsNode) error {
+		if len(relpath) > 0 {
+			if reason := nonPortableReason(n.Name); len(reason) > 0 {
+				issues = append(issues, NameIssue{Path: relpath, Reason: reason})
+			}
+		}
+
+		if n.Type != FileTypeDirectory {
+			return nil
+		}
+
+		// entries are sorted by name, so the first one in order is reported as the conflict
+		folded := make(map[string]string, len(n.Entries))
+		for _, entry := range n.Entries {
+			key := strings.ToLower(entry.Name)
+			if existing, ok := folded[key]; ok {
+				issues = append(issues, NameIssue{
+					Path:     path.Join(relpath, entry.Name),
+					Reason:   "collides case-insensitively",
+					Conflict: path.Join(relpath, existing),
+				})
+			} else {
+				folded[key] = entry.Name
+			}
+		}
+
+		return nil
+	})
+
+	return issues
+}
+
+// checkPortableNames checks the file names of tree built by the portable name policy.
+func (builder *treeBuilder) checkPortableNames(tree *FsNode) error {
+	issues := tree.PortableNameIssues()
+	if len(issues) == 0 {
+		return nil
+	}
+
+	if builder.opt.PortableNames != PortableNamesWarn {
+		return &NonPortableNameError{issues}
+	}
+
+	for _, issue := range issues {
+		if builder.opt.OnNonPortableName != nil {
+			builder.opt.OnNonPortableName(issue)
+		} else {
+			logrus.WithFields(logrus.Fields{
+				"path":     issue.Path,
+				"reason":   issue.Reason,
+				"conflict": issue.Conflict,
+			}).Warn("File name not portable")
+		}
+	}
+
+	return nil
+}
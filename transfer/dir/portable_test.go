@@ -0,0 +1,75 @@
+package dir_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortableNames(t *testing.T) {
+	folder := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub"), 0755))
+	for _, name := range []string{"README.md", "Readme.md", "sub/con.txt", "sub/trailing.", "sub/a:b", "sub/ok.txt"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(folder, name), []byte(name), 0644))
+	}
+
+	expected := []dir.NameIssue{
+		{Path: "Readme.md", Reason: "collides case-insensitively", Conflict: "README.md"},
+		{Path: "sub/a:b", Reason: `contains characters reserved on Windows <>:"\|?*`},
+		{Path: "sub/con.txt", Reason: "reserved name on Windows"},
+		{Path: "sub/trailing.", Reason: "ends with dot or space"},
+	}
+
+	// rejected by default along with all offending paths
+	_, err := dir.BuildFileTree(folder)
+	assert.ErrorIs(t, err, dir.ErrNonPortableName)
+	var nameErr *dir.NonPortableNameError
+	if assert.True(t, errors.As(err, &nameErr)) {
+		assert.Equal(t, expected, nameErr.Issues)
+	}
+	for _, issue := range expected {
+		assert.Contains(t, err.Error(), issue.Path)
+	}
+
+	// downgraded to warnings
+	var warned []dir.NameIssue
+	tree, err := dir.BuildFileTreeWithOption(folder, dir.BuildOption{
+		PortableNames:     dir.PortableNamesWarn,
+		OnNonPortableName: func(issue dir.NameIssue) { warned = append(warned, issue) },
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, warned)
+	assert.Len(t, tree.Leaves(), 6)
+
+	_, err = dir.BuildFileTreeWithOption(folder, dir.BuildOption{PortableNames: "ignore"})
+	assert.Error(t, err)
+}
+
+func TestPortableNameIssues(t *testing.T) {
+	file := func(name string) *dir.FsNode { return dir.NewSymbolicFsNode(name, "target") }
+
+	tree := dir.NewDirFsNode("Not Portable.", []*dir.FsNode{
+		file("a/b"),
+		file("nul\x00"),
+		file("tab\t"),
+		file("space "),
+		file("LPT1"),
+		file("aux .tar.gz"),
+		file("COM10"),
+		file("con-tent"),
+		dir.NewDirFsNode("Dir", []*dir.FsNode{file("x")}),
+		dir.NewDirFsNode("dir", []*dir.FsNode{file("X")}),
+		file("DIR"),
+	})
+
+	var paths []string
+	for _, issue := range tree.PortableNameIssues() {
+		paths = append(paths, issue.Path)
+	}
+
+	assert.ElementsMatch(t, []string{"a/b", "nul\x00", "tab\t", "space ", "LPT1", "aux .tar.gz", "Dir", "dir"}, paths)
+}
@@ -0,0 +1,102 @@
+package dir
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultProgressInterval is the min interval between progress callbacks when building file tree by default.
+const DefaultProgressInterval = 100 * time.Millisecond
+
+// BuildProgress is the progress of building file tree, see BuildOption.OnProgress.
+type BuildProgress struct {
+	FilesDiscovered int    // regular files found so far
+	FilesHashed     int    // regular files of which merkle roots are calculated, including empty files
+	BytesHashed     int64  // total size of files hashed
+	Path            string // path of the file discovered or hashed recently
+}
+
+// BuildStats is the aggregate statistics of file tree built, see BuildFileTreeWithStats.
+type BuildStats struct {
+	Files   int   // number of regular files
+	Bytes   int64 // total size of regular files
+	Elapsed time.Duration
+}
+
+// WithProgress sets the callback to report the progress of building file tree, which is invoked at most once per
+// DefaultProgressInterval unless ProgressInterval specified, and always once more when the file tree is built.
+func (opt BuildOption) WithProgress(onProgress func(p BuildProgress)) BuildOption {
+	opt.OnProgress = onProgress
+	return opt
+}
+
+// progressTracker tracks the progress of building file tree, which is safe for concurrent use. Callbacks are
+// serialized, so that need not be synchronized even if files hashed concurrently.
+type progressTracker struct {
+	onProgress func(p BuildProgress)
+	interval   time.Duration
+
+	mu       sync.Mutex
+	progress BuildProgress
+	last     time.Time // last time that callback invoked
+}
+
+func newProgressTracker(opt BuildOption) *progressTracker {
+	interval := opt.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	return &progressTracker{
+		onProgress: opt.OnProgress,
+		interval:   interval,
+	}
+}
+
+// discovered tracks a regular file found.
+func (tracker *progressTracker) discovered(path string) {
+	tracker.update(path, func(p *BuildProgress) {
+		p.FilesDiscovered++
+	})
+}
+
+// hashed tracks a regular file of which merkle root calculated.
+func (tracker *progressTracker) hashed(path string, size int64) {
+	tracker.update(path, func(p *BuildProgress) {
+		p.FilesHashed++
+		p.BytesHashed += size
+	})
+}
+
+func (tracker *progressTracker) update(path string, fn func(p *BuildProgress)) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	fn(&tracker.progress)
+	tracker.progress.Path = path
+
+	if tracker.onProgress == nil {
+		return
+	}
+
+	if now := time.Now(); now.Sub(tracker.last) >= tracker.interval {
+		tracker.last = now
+		tracker.onProgress(tracker.progress)
+	}
+}
+
+// finish reports the final progress, and returns the aggregate statistics.
+func (tracker *progressTracker) finish(start time.Time) BuildStats {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if tracker.onProgress != nil {
+		tracker.onProgress(tracker.progress)
+	}
+
+	return BuildStats{
+		Files:   tracker.progress.FilesDiscovered,
+		Bytes:   tracker.progress.BytesHashed,
+		Elapsed: time.Since(start),
+	}
+}
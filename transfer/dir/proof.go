@@ -0,0 +1,212 @@
+package dir
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// EntryProof proves that an entry is encoded within the directory manifest of a storage root, by the chunks of
+// manifest that hold the encoded entry along with the merkle proof of each chunk against the storage root, so that
+// membership of an entry could be verified without the whole manifest.
+type EntryProof struct {
+	ManifestSize int64         `json:"manifestSize"` // size of manifest, which determines the merkle tree
+	Offset       int64         `json:"offset"`       // offset of the encoded entry within manifest
+	Length       int64         `json:"length"`       // length of the encoded entry
+	Chunks       [][]byte      `json:"chunks"`       // chunks that hold the encoded entry in order, padded with zeros
+	Proofs       []*core.Proof `json:"proofs"`       // merkle proof of each chunk against the storage root
+}
+
+// ProofOf returns the proof of entry at the slash-separated path relative to this directory, e.g. a file name,
+// against the storage root of manifest, which is encoded by CanonicalBytes as uploaded without signature. Use
+// NewEntryProof for manifests signed.
+func (node *FsNode) ProofOf(entryPath string) (*EntryProof, error) {
+	manifest, err := CanonicalBytes(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEntryProof(manifest, entryPath)
+}
+
+// NewEntryProof returns the proof of entry at the slash-separated path relative to root directory against the
+// storage root of manifest, which is the binary encoded manifest as uploaded, e.g. signed.
+//
+// Note, manifests uploaded in chunks are proved against the storage root of chunks instead of manifest index, see
+// ManifestIndex, and entries across chunks are not supported.
+func NewEntryProof(manifest []byte, entryPath string) (*EntryProof, error) {
+	headerSize := len(CodecMagicBytes) + 2
+	if len(manifest) < headerSize {
+		return nil, errors.New("manifest too short")
+	}
+
+	if err := checkCodecHeader(manifest[:headerSize]); err != nil {
+		return nil, err
+	}
+
+	start, end, err := locateEncodedEntry(manifest, int64(headerSize), entryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := core.NewDataInMemory(manifest)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create `IterableData` in memory")
+	}
+
+	tree, err := core.NewFileTree(data)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to build merkle tree of manifest")
+	}
+
+	proof := EntryProof{
+		ManifestSize: int64(len(manifest)),
+		Offset:       start,
+		Length:       end - start,
+	}
+
+	// segments padded with zeros to chunks, see core.SegmentToByteRange
+	var segment []byte
+	segmentIndex := uint64(0)
+	for index := uint64(start / core.DefaultChunkSize); index <= uint64((end-1)/core.DefaultChunkSize); index++ {
+		if segment == nil || index/core.DefaultSegmentMaxChunks != segmentIndex {
+			segmentIndex = index / core.DefaultSegmentMaxChunks
+			offset, length, err := core.SegmentToByteRange(segmentIndex, int64(len(manifest)))
+			if err != nil {
+				return nil, err
+			}
+
+			segment = make([]byte, length)
+			copy(segment, manifest[offset:])
+		}
+
+		chunkProof, err := tree.ChunkProofAt(segment, index)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to prove chunk %v of manifest", index)
+		}
+
+		chunkOffset := index % core.DefaultSegmentMaxChunks * core.DefaultChunkSize
+		proof.Chunks = append(proof.Chunks, segment[chunkOffset:chunkOffset+core.DefaultChunkSize])
+		proof.Proofs = append(proof.Proofs, chunkProof)
+	}
+
+	return &proof, nil
+}
+
+// locateEncodedEntry returns the range of the encoded entry at path within manifest, of which the JSON metadata
+// starts at offset.
+func locateEncodedEntry(manifest []byte, offset int64, entryPath string) (int64, int64, error) {
+	start, end := offset, int64(len(manifest))
+	located := false
+	for _, part := range strings.Split(entryPath, "/") {
+		// Skip empty strings and dot current
+		if len(part) == 0 || part == "." {
+			continue
+		}
+
+		if part == ".." {
+			return 0, 0, errors.WithMessagef(ErrInvalidPath, "cannot locate '%s'", entryPath)
+		}
+
+		entryStart, entryEnd, err := searchEncodedEntry(manifest[start:end], part)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		start, end, located = start+entryStart, start+entryEnd, true
+	}
+
+	if !located {
+		return 0, 0, errors.WithMessagef(ErrInvalidPath, "no entry specified by '%s'", entryPath)
+	}
+
+	return start, end, nil
+}
+
+// searchEncodedEntry returns the range of the encoded entry by name within the JSON metadata of directory.
+func searchEncodedEntry(metadata []byte, name string) (int64, int64, error) {
+	decoder := json.NewDecoder(bytes.NewReader(metadata))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return 0, 0, errors.Errorf("invalid JSON metadata of directory to locate '%s'", name)
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return 0, 0, errors.WithMessage(err, "invalid JSON metadata")
+		}
+
+		if key != "entries" {
+			var value json.RawMessage
+			if err := decoder.Decode(&value); err != nil {
+				return 0, 0, errors.WithMessage(err, "invalid JSON metadata")
+			}
+			continue
+		}
+
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return 0, 0, errors.New("invalid entries of JSON metadata")
+		}
+
+		for decoder.More() {
+			var entry json.RawMessage
+			if err := decoder.Decode(&entry); err != nil {
+				return 0, 0, errors.WithMessage(err, "invalid entries of JSON metadata")
+			}
+
+			var named struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(entry, &named); err != nil {
+				return 0, 0, errors.WithMessage(err, "invalid entries of JSON metadata")
+			}
+
+			if named.Name == name {
+				end := decoder.InputOffset()
+				return end - int64(len(bytes.TrimLeft(entry, " \t\r\n"))), end, nil
+			}
+		}
+
+		break
+	}
+
+	return 0, 0, errors.WithMessagef(ErrPathNotFound, "'%s'", name)
+}
+
+// VerifyEntryProof verifies that the entry is encoded within the directory manifest of root by proof, where the
+// entry should be the same as encoded in canonical form, including the entries of directory if any. It returns
+// false if the entry, or any chunk or sibling of proof is tampered with.
+//
+// Note, the proof shows the entry within the tree of root, but not the path of entry within the tree.
+func VerifyEntryProof(root common.Hash, entry *FsNode, proof *EntryProof) bool {
+	if entry == nil || proof == nil || proof.Offset < 0 || proof.Length <= 0 || proof.Offset+proof.Length > proof.ManifestSize {
+		return false
+	}
+
+	first := uint64(proof.Offset / core.DefaultChunkSize)
+	last := uint64((proof.Offset + proof.Length - 1) / core.DefaultChunkSize)
+	if uint64(len(proof.Chunks)) != last-first+1 || len(proof.Proofs) != len(proof.Chunks) {
+		return false
+	}
+
+	var data bytes.Buffer
+	for i, chunk := range proof.Chunks {
+		if err := core.ValidateChunk(root, proof.ManifestSize, chunk, first+uint64(i), proof.Proofs[i]); err != nil {
+			return false
+		}
+		data.Write(chunk)
+	}
+
+	var expected bytes.Buffer
+	if err := writeCanonicalJSON(&expected, entry); err != nil {
+		return false
+	}
+
+	offset := proof.Offset % core.DefaultChunkSize
+
+	return bytes.Equal(data.Bytes()[offset:offset+proof.Length], expected.Bytes())
+}
@@ -0,0 +1,140 @@
+package dir_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newProofTestTree(numFiles int) *dir.FsNode {
+	var entries []*dir.FsNode
+	for i := 0; i < numFiles; i++ {
+		entries = append(entries, dir.NewFileFsNode(fmt.Sprintf("file%05d.html", i), common.BytesToHash([]byte(fmt.Sprint(i))), int64(i+1)))
+	}
+
+	entries = append(entries,
+		dir.NewDirFsNode("sub", []*dir.FsNode{
+			dir.NewFileFsNode("index.html", common.HexToHash("0x01"), 100),
+			dir.NewSymbolicFsNode("link", "../index.html"),
+		}),
+		dir.NewDirFsNode("empty", nil),
+	)
+
+	return dir.NewDirFsNode("/", entries)
+}
+
+func TestEntryProof(t *testing.T) {
+	// single segment, and across segments
+	for _, numFiles := range []int{10, 3000} {
+		tree := newProofTestTree(numFiles)
+		root, err := dir.ManifestRoot(tree)
+		assert.NoError(t, err)
+
+		// first, last and entries in between, of which the chunks are proved across segments
+		for _, name := range []string{"empty", "file00000.html", fmt.Sprintf("file%05d.html", numFiles/2), fmt.Sprintf("file%05d.html", numFiles-1), "sub"} {
+			entry, ok := tree.Search(name)
+			assert.True(t, ok)
+
+			proof, err := tree.ProofOf(name)
+			assert.NoError(t, err, name)
+			assert.True(t, dir.VerifyEntryProof(root, entry, proof), name)
+
+			// shipped in JSON
+			encoded, err := json.Marshal(proof)
+			assert.NoError(t, err)
+			var decoded dir.EntryProof
+			assert.NoError(t, json.Unmarshal(encoded, &decoded))
+			assert.True(t, dir.VerifyEntryProof(root, entry, &decoded), name)
+
+			// wrong root
+			assert.False(t, dir.VerifyEntryProof(common.Hash{1}, entry, proof))
+		}
+
+		// entries within sub directory
+		proof, err := tree.ProofOf("sub/index.html")
+		assert.NoError(t, err)
+		entry, err := tree.Locate("sub/index.html")
+		assert.NoError(t, err)
+		assert.True(t, dir.VerifyEntryProof(root, entry, proof))
+
+		_, err = tree.ProofOf("missing")
+		assert.ErrorIs(t, err, dir.ErrPathNotFound)
+		_, err = tree.ProofOf("file00000.html/file")
+		assert.ErrorIs(t, err, dir.ErrPathNotFound)
+		_, err = tree.ProofOf("")
+		assert.ErrorIs(t, err, dir.ErrInvalidPath)
+	}
+}
+
+func TestEntryProofTampered(t *testing.T) {
+	tree := newProofTestTree(1000)
+	root, err := dir.ManifestRoot(tree)
+	assert.NoError(t, err)
+
+	entry, _ := tree.Search("file00500.html")
+	proof, err := tree.ProofOf("file00500.html")
+	assert.NoError(t, err)
+	assert.True(t, dir.VerifyEntryProof(root, entry, proof))
+
+	// entry hash tampered
+	tampered := *entry
+	tampered.Root = common.HexToHash("0x02").Hex()
+	assert.False(t, dir.VerifyEntryProof(root, &tampered, proof))
+
+	// another entry
+	other, _ := tree.Search("file00501.html")
+	assert.False(t, dir.VerifyEntryProof(root, other, proof))
+
+	// chunk tampered
+	chunk := proof.Chunks[0][0]
+	proof.Chunks[0][0]++
+	assert.False(t, dir.VerifyEntryProof(root, entry, proof))
+	proof.Chunks[0][0] = chunk
+
+	// sibling tampered
+	for _, chunkProof := range proof.Proofs {
+		for i := 1; i < len(chunkProof.Lemma)-1; i++ {
+			sibling := chunkProof.Lemma[i]
+			chunkProof.Lemma[i][0]++
+			assert.False(t, dir.VerifyEntryProof(root, entry, proof), i)
+			chunkProof.Lemma[i] = sibling
+		}
+	}
+
+	// range tampered
+	proof.Offset++
+	assert.False(t, dir.VerifyEntryProof(root, entry, proof))
+	proof.Offset--
+	proof.Chunks, proof.Proofs = proof.Chunks[:0], proof.Proofs[:0]
+	assert.False(t, dir.VerifyEntryProof(root, entry, proof))
+	assert.False(t, dir.VerifyEntryProof(root, entry, nil))
+}
+
+func TestEntryProofSigned(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	tree := newProofTestTree(10)
+	signed, err := dir.SignManifest(tree, key)
+	assert.NoError(t, err)
+
+	data, err := core.NewDataInMemory(signed)
+	assert.NoError(t, err)
+	root, err := core.MerkleRootData(data)
+	assert.NoError(t, err)
+
+	// proved against the root of manifest uploaded with signature
+	entry, _ := tree.Search("file00003.html")
+	proof, err := dir.NewEntryProof(signed, "file00003.html")
+	assert.NoError(t, err)
+	assert.True(t, dir.VerifyEntryProof(root, entry, proof))
+
+	_, err = dir.NewEntryProof(signed[:10], "file00003.html")
+	assert.Error(t, err)
+}
@@ -0,0 +1,525 @@
+package dir
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// maxSymlinkHops is the maximum number of symbolic links followed when resolving a path.
+const maxSymlinkHops = 40
+
+// Downloader is the minimal capability required to fetch file content by root hash from the
+// ZeroGStorage network. It is satisfied by transfer.Downloader.
+type Downloader interface {
+	Download(ctx context.Context, root, filename string, withProof bool) error
+}
+
+// RangeReader reads file content at random offsets.
+type RangeReader interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// RangeDownloader is the optional capability of Downloader to open a file for random access, so that only the
+// segments read are downloaded with merkle proof validation rather than the whole file. It is satisfied by
+// transfer.Downloader.
+type RangeDownloader interface {
+	OpenRange(ctx context.Context, root string) (RangeReader, error)
+}
+
+// RemoteFS is a read-only fs.FS view over a directory manifest stored on the ZeroGStorage network.
+//
+// The directory structure and file sizes are served from the manifest, so walking the tree
+// (e.g. with fs.WalkDir) never downloads any file content. File content is only fetched with
+// Merkle proof validation upon read. If the downloader implements RangeDownloader, only the segments
+// read are fetched. Otherwise, the whole file is downloaded upon the first read of an opened file.
+type RemoteFS struct {
+	ctx        context.Context
+	downloader Downloader
+	tree       *FsNode
+}
+
+var (
+	_ fs.FS        = (*RemoteFS)(nil)
+	_ fs.StatFS    = (*RemoteFS)(nil)
+	_ fs.ReadDirFS = (*RemoteFS)(nil)
+)
+
+// FS downloads the directory manifest with the specified root hash and returns a read-only
+// fs.FS view over it.
+func FS(ctx context.Context, downloader Downloader, manifestRoot string) (*RemoteFS, error) {
+	data, err := downloadManifest(ctx, downloader, manifestRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree FsNode
+	if err := tree.UnmarshalBinary(data); err != nil {
+		return nil, errors.WithMessage(err, "failed to decode directory metadata")
+	}
+
+	return NewRemoteFS(ctx, downloader, &tree)
+}
+
+// RemoteTreeSource downloads the directory manifest with the specified root hash, and returns the TreeSource to
+// list directories on demand, e.g. to compare by DiffStream. If the manifest uploaded in chunks, only the chunks of
+// directories listed are kept in memory, see NewManifestSource.
+func RemoteTreeSource(ctx context.Context, downloader Downloader, manifestRoot string) (PagedTreeSource, error) {
+	data, err := downloadManifestFile(ctx, downloader, manifestRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if IsManifestIndex(data) {
+		var index ManifestIndex
+		if err := index.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+
+		return NewManifestSource(ctx, &index, func(ctx context.Context, root common.Hash) ([]byte, error) {
+			return downloadManifestFile(ctx, downloader, root.Hex())
+		})
+	}
+
+	var tree FsNode
+	if err := tree.UnmarshalBinary(data); err != nil {
+		return nil, errors.WithMessage(err, "failed to decode directory metadata")
+	}
+
+	if tree.Type != FileTypeDirectory {
+		return nil, errors.New("manifest root is not a directory")
+	}
+
+	return TreeSourceOf(&tree), nil
+}
+
+// downloadManifest downloads the directory manifest with merkle proof, and returns the raw data, which is assembled
+// by chunks if the manifest uploaded in chunks, see ManifestIndex.
+func downloadManifest(ctx context.Context, downloader Downloader, manifestRoot string) ([]byte, error) {
+	data, err := downloadManifestFile(ctx, downloader, manifestRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	return AssembleManifest(data, func(root common.Hash) ([]byte, error) {
+		return downloadManifestFile(ctx, downloader, root.Hex())
+	})
+}
+
+// downloadManifestFile downloads the file of directory manifest or manifest index with merkle proof.
+func downloadManifestFile(ctx context.Context, downloader Downloader, manifestRoot string) ([]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "zgfs-")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create temp directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	metapath := filepath.Join(tmpDir, manifestRoot+".zgdm")
+	if err := downloader.Download(ctx, manifestRoot, metapath, true); err != nil {
+		return nil, errors.WithMessage(err, "failed to download directory metadata")
+	}
+
+	data, err := os.ReadFile(metapath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read directory metadata")
+	}
+
+	return data, nil
+}
+
+// NewRemoteFS creates a read-only fs.FS view over an already decoded directory manifest.
+func NewRemoteFS(ctx context.Context, downloader Downloader, tree *FsNode) (*RemoteFS, error) {
+	if tree == nil || tree.Type != FileTypeDirectory {
+		return nil, errors.New("manifest root is not a directory")
+	}
+
+	return &RemoteFS{
+		ctx:        ctx,
+		downloader: downloader,
+		tree:       tree,
+	}, nil
+}
+
+// TreeSource returns the TreeSource of the directory tree, e.g. to compare with another tree by DiffStream.
+func (rfs *RemoteFS) TreeSource() TreeSource {
+	return TreeSourceOf(rfs.tree)
+}
+
+// Open implements fs.FS. Symbolic links are followed as long as they resolve within the tree.
+func (rfs *RemoteFS) Open(name string) (fs.File, error) {
+	node, err := rfs.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Type == FileTypeDirectory {
+		return &remoteDir{node: node, name: name}, nil
+	}
+
+	return &remoteFile{fs: rfs, node: node, name: name}, nil
+}
+
+// Stat implements fs.StatFS.
+func (rfs *RemoteFS) Stat(name string) (fs.FileInfo, error) {
+	node, err := rfs.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileInfo(node, name), nil
+}
+
+// Lstat returns the file info of the named file without following the final symbolic link.
+func (rfs *RemoteFS) Lstat(name string) (fs.FileInfo, error) {
+	node, err := rfs.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFileInfo(node, name), nil
+}
+
+// ReadLink returns the target of the named symbolic link.
+func (rfs *RemoteFS) ReadLink(name string) (string, error) {
+	node, err := rfs.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+
+	if node.Type != FileTypeSymbolic {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return node.Link, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (rfs *RemoteFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := rfs.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Type != FileTypeDirectory {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	return newDirEntries(node.Entries), nil
+}
+
+// resolve locates the node of the specified slash-separated path, optionally following the symbolic
+// link of the final path element.
+func (rfs *RemoteFS) resolve(op, name string, followLast bool) (*FsNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	current := name
+	for hops := 0; hops <= maxSymlinkHops; hops++ {
+		node, target, err := rfs.walk(current, followLast)
+		if err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+
+		if len(target) == 0 {
+			return node, nil
+		}
+
+		current = target
+	}
+
+	return nil, &fs.PathError{Op: op, Path: name, Err: errors.New("too many levels of symbolic links")}
+}
+
+// walk walks the slash-separated path from the tree root. If a symbolic link is encountered, the
+// rewritten path with the link resolved is returned, so that the caller could walk again.
+func (rfs *RemoteFS) walk(name string, followLast bool) (*FsNode, string, error) {
+	if name == "." {
+		return rfs.tree, "", nil
+	}
+
+	parts := strings.Split(name, "/")
+	node := rfs.tree
+	for i, part := range parts {
+		if node.Type != FileTypeDirectory {
+			return nil, "", fs.ErrNotExist
+		}
+
+		entry, found := node.Search(part)
+		if !found {
+			return nil, "", fs.ErrNotExist
+		}
+
+		last := i == len(parts)-1
+		if entry.Type == FileTypeSymbolic && (!last || followLast) {
+			// only links that resolve within the tree could be followed
+			if path.IsAbs(entry.Link) {
+				return nil, "", fs.ErrNotExist
+			}
+
+			target := path.Join(path.Join(parts[:i]...), entry.Link)
+			if !last {
+				target = path.Join(target, path.Join(parts[i+1:]...))
+			}
+
+			if !fs.ValidPath(target) {
+				return nil, "", fs.ErrNotExist
+			}
+
+			return nil, target, nil
+		}
+
+		node = entry
+	}
+
+	return node, "", nil
+}
+
+// fileInfo implements fs.FileInfo for an FsNode.
+type fileInfo struct {
+	node *FsNode
+	name string
+}
+
+func newFileInfo(node *FsNode, name string) *fileInfo {
+	return &fileInfo{node: node, name: path.Base(name)}
+}
+
+func (fi *fileInfo) Name() string { return fi.name }
+
+func (fi *fileInfo) Size() int64 { return fi.node.Size }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	switch fi.node.Type {
+	case FileTypeDirectory:
+		return fs.ModeDir | 0555
+	case FileTypeSymbolic:
+		return fs.ModeSymlink | 0777
+	default:
+		return 0444
+	}
+}
+
+func (fi *fileInfo) ModTime() time.Time { return time.Time{} }
+
+func (fi *fileInfo) IsDir() bool { return fi.node.Type == FileTypeDirectory }
+
+// Sys returns the underlying *FsNode.
+func (fi *fileInfo) Sys() any { return fi.node }
+
+// dirEntry implements fs.DirEntry for an FsNode.
+type dirEntry struct {
+	info *fileInfo
+}
+
+func newDirEntries(nodes []*FsNode) []fs.DirEntry {
+	entries := make([]fs.DirEntry, 0, len(nodes))
+	for _, node := range nodes {
+		entries = append(entries, &dirEntry{info: newFileInfo(node, node.Name)})
+	}
+	return entries
+}
+
+func (de *dirEntry) Name() string { return de.info.Name() }
+
+func (de *dirEntry) IsDir() bool { return de.info.IsDir() }
+
+func (de *dirEntry) Type() fs.FileMode { return de.info.Mode().Type() }
+
+func (de *dirEntry) Info() (fs.FileInfo, error) { return de.info, nil }
+
+// remoteDir implements fs.ReadDirFile for a directory node.
+type remoteDir struct {
+	node   *FsNode
+	name   string
+	offset int
+}
+
+func (d *remoteDir) Stat() (fs.FileInfo, error) { return newFileInfo(d.node, d.name), nil }
+
+func (d *remoteDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *remoteDir) Close() error { return nil }
+
+// ReadDir implements fs.ReadDirFile.
+func (d *remoteDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.node.Entries[d.offset:]
+	if n > 0 {
+		if len(remaining) == 0 {
+			return nil, io.EOF
+		}
+		if len(remaining) > n {
+			remaining = remaining[:n]
+		}
+	}
+
+	d.offset += len(remaining)
+	return newDirEntries(remaining), nil
+}
+
+// remoteFile implements fs.File for a regular file node, whose content is lazily read by range, or
+// downloaded into a temporary file upon the first read.
+type remoteFile struct {
+	fs   *RemoteFS
+	node *FsNode
+	name string
+
+	mu      sync.Mutex
+	content RangeReader
+	tmpDir  string
+	offset  int64
+	closed  bool
+}
+
+var (
+	_ io.ReaderAt = (*remoteFile)(nil)
+	_ io.Seeker   = (*remoteFile)(nil)
+)
+
+func (f *remoteFile) Stat() (fs.FileInfo, error) { return newFileInfo(f.node, f.name), nil }
+
+func (f *remoteFile) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, err := f.readAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *remoteFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	return f.readAt(p, off)
+}
+
+// Seek implements io.Seeker.
+func (f *remoteFile) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrClosed}
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.node.Size
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *remoteFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+
+	if f.content != nil {
+		f.content.Close()
+		f.content = nil
+	}
+
+	if len(f.tmpDir) > 0 {
+		return os.RemoveAll(f.tmpDir)
+	}
+
+	return nil
+}
+
+func (f *remoteFile) readAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrClosed}
+	}
+
+	if off >= f.node.Size {
+		return 0, io.EOF
+	}
+
+	// embedded content is verified when manifest decoded, so no need to download
+	if f.node.Embedded() {
+		n := copy(p, f.node.Data[off:])
+		if n < len(p) {
+			return n, io.EOF
+		}
+		return n, nil
+	}
+
+	if err := f.fetch(); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+
+	return f.content.ReadAt(p, off)
+}
+
+// fetch opens the file for random access if supported, or downloads the file content with proof validation
+// if not downloaded yet.
+func (f *remoteFile) fetch() error {
+	if f.content != nil {
+		return nil
+	}
+
+	if downloader, ok := f.fs.downloader.(RangeDownloader); ok {
+		content, err := downloader.OpenRange(f.fs.ctx, f.node.Root)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to open file with root %s", f.node.Root)
+		}
+
+		f.content = content
+		return nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "zgfs-")
+	if err != nil {
+		return errors.WithMessage(err, "failed to create temp directory")
+	}
+
+	filename := filepath.Join(tmpDir, f.node.Root)
+	if err := f.fs.downloader.Download(f.fs.ctx, f.node.Root, filename, true); err != nil {
+		os.RemoveAll(tmpDir)
+		return errors.WithMessagef(err, "failed to download file with root %s", f.node.Root)
+	}
+
+	content, err := os.Open(filename)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return errors.WithMessage(err, "failed to open downloaded file")
+	}
+
+	f.content = content
+	f.tmpDir = tmpDir
+
+	return nil
+}
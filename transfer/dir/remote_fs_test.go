@@ -0,0 +1,222 @@
+package dir_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// memDownloader serves file content from memory by root hash.
+type memDownloader struct {
+	files     map[string][]byte
+	downloads int
+}
+
+func newMemDownloader() *memDownloader {
+	return &memDownloader{files: make(map[string][]byte)}
+}
+
+func (d *memDownloader) add(t *testing.T, content []byte) string {
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(t, err)
+	root := tree.Root().Hex()
+	d.files[root] = content
+	return root
+}
+
+func (d *memDownloader) Download(ctx context.Context, root, filename string, withProof bool) error {
+	content, ok := d.files[root]
+	if !ok {
+		return os.ErrNotExist
+	}
+	d.downloads++
+	return os.WriteFile(filename, content, 0644)
+}
+
+// rangeDownloader serves file content from memory by range, and counts the bytes read by range.
+type rangeDownloader struct {
+	*memDownloader
+	read int
+}
+
+// rangeReader reads file content of rangeDownloader.
+type rangeReader struct {
+	*bytes.Reader
+	d *rangeDownloader
+}
+
+func (r *rangeReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.Reader.ReadAt(p, off)
+	r.d.read += n
+	return n, err
+}
+
+func (r *rangeReader) Close() error { return nil }
+
+func (d *rangeDownloader) OpenRange(ctx context.Context, root string) (dir.RangeReader, error) {
+	content, ok := d.files[root]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return &rangeReader{Reader: bytes.NewReader(content), d: d}, nil
+}
+
+func newFileNode(t *testing.T, d *memDownloader, name, content string) *dir.FsNode {
+	root := d.add(t, []byte(content))
+	return &dir.FsNode{Name: name, Type: dir.FileTypeFile, Root: root, Size: int64(len(content))}
+}
+
+func TestRemoteFS(t *testing.T) {
+	downloader := newMemDownloader()
+
+	tree := dir.NewDirFsNode("/", []*dir.FsNode{
+		newFileNode(t, downloader, "index.html", "<html></html>"),
+		dir.NewDirFsNode("assets", []*dir.FsNode{
+			newFileNode(t, downloader, "app.js", "console.log('hello')"),
+			newFileNode(t, downloader, "style.css", "body {}"),
+		}),
+		dir.NewDirFsNode("empty", []*dir.FsNode{}),
+		dir.NewSymbolicFsNode("home.html", "index.html"),
+	})
+
+	manifest, err := tree.MarshalBinary()
+	assert.NoError(t, err)
+	manifestRoot := downloader.add(t, manifest)
+
+	rfs, err := dir.FS(context.Background(), downloader, manifestRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, downloader.downloads)
+
+	t.Run("walk without prefetch", func(t *testing.T) {
+		var paths []string
+		err := fs.WalkDir(rfs, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if path == "assets/app.js" {
+				assert.Equal(t, int64(20), info.Size())
+			}
+			paths = append(paths, path)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			".", "assets", "assets/app.js", "assets/style.css", "empty", "home.html", "index.html",
+		}, paths)
+		assert.Equal(t, 1, downloader.downloads)
+	})
+
+	t.Run("symbolic link", func(t *testing.T) {
+		target, err := rfs.ReadLink("home.html")
+		assert.NoError(t, err)
+		assert.Equal(t, "index.html", target)
+
+		info, err := rfs.Lstat("home.html")
+		assert.NoError(t, err)
+		assert.Equal(t, fs.ModeSymlink, info.Mode().Type())
+
+		content, err := fs.ReadFile(rfs, "home.html")
+		assert.NoError(t, err)
+		assert.Equal(t, "<html></html>", string(content))
+	})
+
+	t.Run("lazy read", func(t *testing.T) {
+		f, err := rfs.Open("assets/style.css")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		downloads := downloader.downloads
+		content, err := io.ReadAll(f)
+		assert.NoError(t, err)
+		assert.Equal(t, "body {}", string(content))
+		assert.Equal(t, downloads+1, downloader.downloads)
+	})
+
+	t.Run("not exist", func(t *testing.T) {
+		_, err := rfs.Open("assets/missing.js")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+
+		_, err = rfs.Open("index.html/child")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("fstest", func(t *testing.T) {
+		err := fstest.TestFS(rfs, "index.html", "assets/app.js", "assets/style.css", "empty")
+		assert.NoError(t, err)
+	})
+}
+
+func TestRemoteFSRange(t *testing.T) {
+	downloader := &rangeDownloader{memDownloader: newMemDownloader()}
+
+	large := bytes.Repeat([]byte("0123456789abcdef"), 65536)
+	tree := dir.NewDirFsNode("/", []*dir.FsNode{
+		{Name: "large.bin", Type: dir.FileTypeFile, Root: downloader.add(t, large), Size: int64(len(large))},
+	})
+
+	rfs, err := dir.NewRemoteFS(context.Background(), downloader, tree)
+	assert.NoError(t, err)
+
+	f, err := rfs.Open("large.bin")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	// only the range read is fetched, without downloading the whole file
+	_, err = f.(io.Seeker).Seek(500000, io.SeekStart)
+	assert.NoError(t, err)
+	buf := make([]byte, 16)
+	_, err = io.ReadFull(f, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, large[500000:500016], buf)
+	assert.Equal(t, 0, downloader.downloads)
+	assert.Equal(t, 16, downloader.read)
+}
+
+func TestRemoteTreeSource(t *testing.T) {
+	d := newMemDownloader()
+
+	var entries []*dir.FsNode
+	for i := 0; i < 100; i++ {
+		entries = append(entries, newFileNode(t, d, fmt.Sprintf("file%03d", i), fmt.Sprintf("content %v", i)))
+	}
+	tree := dir.NewDirFsNode("/", []*dir.FsNode{dir.NewDirFsNode("sub", entries), newFileNode(t, d, "a.txt", "a")})
+
+	manifest, err := dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+	manifestRoot := d.add(t, manifest)
+
+	// manifest uploaded in chunks
+	index := dir.ManifestIndex{Root: common.HexToHash(manifestRoot), Size: int64(len(manifest))}
+	for _, chunk := range dir.SplitManifest(manifest, 1024) {
+		index.Chunks = append(index.Chunks, common.HexToHash(d.add(t, chunk)))
+	}
+	encoded, err := index.MarshalBinary()
+	assert.NoError(t, err)
+	indexRoot := d.add(t, encoded)
+
+	for _, root := range []string{manifestRoot, indexRoot} {
+		source, err := dir.RemoteTreeSource(context.Background(), d, root)
+		assert.NoError(t, err)
+
+		// identical to the tree in memory
+		assert.NoError(t, dir.DiffStream(context.Background(), source, dir.TreeSourceOf(tree), func(diff dir.DiffPath) error {
+			return fmt.Errorf("unexpected diff %v", diff.Path)
+		}))
+	}
+}
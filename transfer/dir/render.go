@@ -0,0 +1,207 @@
+package dir
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/pkg/errors"
+)
+
+// RenderFormat is the output format of Render.
+type RenderFormat string
+
+const (
+	RenderFormatTree   RenderFormat = "tree"   // human readable tree view as tree(1)
+	RenderFormatNDJSON RenderFormat = "ndjson" // one RenderEntry in JSON per line, e.g. to pipe into jq
+)
+
+// Validate checks if the render format is supported, where empty is RenderFormatTree.
+func (format RenderFormat) Validate() error {
+	switch format {
+	case "", RenderFormatTree, RenderFormatNDJSON:
+		return nil
+	default:
+		return errors.Errorf("invalid render format %q, expected %v or %v", format, RenderFormatTree, RenderFormatNDJSON)
+	}
+}
+
+// RenderOptions configures how the FsNode tree is rendered.
+type RenderOptions struct {
+	Format     RenderFormat // RenderFormatTree by default
+	MaxDepth   int          // maximum depth of entries rendered below the node, unlimited if not positive
+	HideHashes bool         // hide merkle roots of files
+	SortBySize bool         // sort entries of directory by size in descending order instead of by name
+}
+
+// RenderEntry is an entry rendered in RenderFormatNDJSON.
+type RenderEntry struct {
+	Path  string   `json:"path"`            // slash-separated path relative to the node, "." for the node itself
+	Type  FileType `json:"type"`            // file type
+	Size  int64    `json:"size"`            // size of file, or total size of regular files within directory
+	Files int      `json:"files,omitempty"` // number of regular files within directory
+	Hash  string   `json:"hash,omitempty"`  // merkle root of file unless hidden
+	Link  string   `json:"link,omitempty"`  // target of symbolic link
+}
+
+// renderFrame is an entry to render along with its position in tree.
+type renderFrame struct {
+	node   *FsNode
+	path   string
+	depth  int
+	prefix string // tree view prefix of entries within
+	last   bool   // whether the last entry of parent directory
+}
+
+// Render writes the FsNode tree to w in the format of options, where the total size and number of files of
+// directories are always computed without modifying the tree, see ComputeAggregates.
+func (node *FsNode) Render(w io.Writer, opts RenderOptions) error {
+	if err := opts.Format.Validate(); err != nil {
+		return err
+	}
+
+	aggregates := aggregatesOf(node)
+	sizeOf := func(n *FsNode) int64 {
+		if n.Type == FileTypeDirectory {
+			return aggregates[n].size
+		}
+		return n.Size
+	}
+
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	var numDirs, numFiles int
+
+	// render iteratively with an explicit stack, so that deep trees never overflow the call stack
+	stack := []renderFrame{{node: node, path: "."}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current.depth > 0 {
+			if current.node.Type == FileTypeDirectory {
+				numDirs++
+			} else {
+				numFiles++
+			}
+		}
+
+		var err error
+		if opts.Format == RenderFormatNDJSON {
+			err = encoder.Encode(newRenderEntry(current, aggregates, opts))
+		} else {
+			err = renderTreeLine(writer, current, sizeOf(current.node), opts)
+		}
+		if err != nil {
+			return errors.WithMessage(err, "failed to render entry")
+		}
+
+		if current.node.Type != FileTypeDirectory || (opts.MaxDepth > 0 && current.depth >= opts.MaxDepth) {
+			continue
+		}
+
+		entries := current.node.Entries
+		if opts.SortBySize {
+			entries = append([]*FsNode(nil), entries...)
+			sort.SliceStable(entries, func(i, j int) bool { return sizeOf(entries[i]) > sizeOf(entries[j]) })
+		}
+
+		prefix := current.prefix
+		if current.depth > 0 {
+			prefix += treeIndent(current.last)
+		}
+
+		// push entries in reverse order, so that they are popped in order
+		for i := len(entries) - 1; i >= 0; i-- {
+			stack = append(stack, renderFrame{
+				node:   entries[i],
+				path:   path.Join(current.path, entries[i].Name),
+				depth:  current.depth + 1,
+				prefix: prefix,
+				last:   i == len(entries)-1,
+			})
+		}
+	}
+
+	if opts.Format != RenderFormatNDJSON {
+		if _, err := fmt.Fprintf(writer, "\n%v directories, %v files\n", numDirs, numFiles); err != nil {
+			return errors.WithMessage(err, "failed to render summary")
+		}
+	}
+
+	return writer.Flush()
+}
+
+func newRenderEntry(frame renderFrame, aggregates map[*FsNode]aggregate, opts RenderOptions) RenderEntry {
+	entry := RenderEntry{Path: frame.path, Type: frame.node.Type}
+
+	switch frame.node.Type {
+	case FileTypeDirectory:
+		entry.Size, entry.Files = aggregates[frame.node].size, aggregates[frame.node].files
+	case FileTypeFile:
+		entry.Size = frame.node.Size
+		if !opts.HideHashes {
+			entry.Hash = frame.node.Root
+		}
+	case FileTypeSymbolic:
+		entry.Link = frame.node.Link
+	}
+
+	return entry
+}
+
+// renderTreeLine writes a line of entry in tree view, e.g. "├── a.txt  5 B  0x1a2b3c4d".
+func renderTreeLine(w io.Writer, frame renderFrame, size int64, opts RenderOptions) error {
+	name := frame.node.Name
+	if frame.node.Type == FileTypeDirectory && (frame.depth > 0 || len(name) > 0) {
+		name += "/"
+	} else if len(name) == 0 {
+		name = "."
+	}
+
+	connector := ""
+	if frame.depth > 0 {
+		connector = "├── "
+		if frame.last {
+			connector = "└── "
+		}
+	}
+
+	var line string
+	switch frame.node.Type {
+	case FileTypeDirectory:
+		line = fmt.Sprintf("%v%v%v  %v", frame.prefix, connector, name, zg_common.ByteSize(size))
+	case FileTypeSymbolic:
+		line = fmt.Sprintf("%v%v%v -> %v", frame.prefix, connector, name, frame.node.Link)
+	default:
+		line = fmt.Sprintf("%v%v%v  %v", frame.prefix, connector, name, zg_common.ByteSize(size))
+		if !opts.HideHashes && len(frame.node.Root) > 0 {
+			line += "  " + shortHash(frame.node.Root)
+		}
+	}
+
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+// treeIndent returns the indent of entries within a directory in tree view.
+func treeIndent(last bool) string {
+	if last {
+		return "    "
+	}
+
+	return "│   "
+}
+
+// shortHash abbreviates the hex merkle root, e.g. "0x1a2b3c4d" for "0x1a2b3c4d...".
+func shortHash(hash string) string {
+	if len(hash) > 10 {
+		return hash[:10]
+	}
+
+	return hash
+}
@@ -0,0 +1,87 @@
+package dir
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var hashA = common.HexToHash("0x1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b")
+
+func newRenderFixture() *FsNode {
+	return NewDirFsNode("", []*FsNode{
+		NewFileFsNode("a.txt", hashA, 5),
+		NewDirFsNode("sub", []*FsNode{
+			NewDirFsNode("deep", []*FsNode{
+				NewFileFsNode("b.txt", common.HexToHash("0x02"), 2048),
+			}),
+			NewSymbolicFsNode("link", "../a.txt"),
+			NewFileFsNode("small", common.HexToHash("0x03"), 1),
+		}),
+		NewDirFsNode("empty", nil),
+	})
+}
+
+func TestRenderTree(t *testing.T) {
+	tree := newRenderFixture()
+
+	var sb strings.Builder
+	assert.NoError(t, tree.Render(&sb, RenderOptions{}))
+	assert.Equal(t, `.  2054B
+├── a.txt  5B  0x1a2b3c4d
+├── empty/  0B
+└── sub/  2049B
+    ├── deep/  2KiB
+    │   └── b.txt  2KiB  0x00000000
+    ├── link -> ../a.txt
+    └── small  1B  0x00000000
+
+3 directories, 4 files
+`, sb.String())
+
+	// sorted by size and limited in depth without hashes
+	sb.Reset()
+	assert.NoError(t, tree.Render(&sb, RenderOptions{MaxDepth: 1, HideHashes: true, SortBySize: true}))
+	assert.Equal(t, `.  2054B
+├── sub/  2049B
+├── a.txt  5B
+└── empty/  0B
+
+2 directories, 1 files
+`, sb.String())
+
+	// aggregates not populated
+	assert.Zero(t, tree.TotalSize)
+
+	assert.Error(t, tree.Render(&sb, RenderOptions{Format: "xml"}))
+}
+
+func TestRenderNDJSON(t *testing.T) {
+	tree := newRenderFixture()
+
+	var sb strings.Builder
+	assert.NoError(t, tree.Render(&sb, RenderOptions{Format: RenderFormatNDJSON, SortBySize: true}))
+
+	var entries []RenderEntry
+	scanner := bufio.NewScanner(strings.NewReader(sb.String()))
+	for scanner.Scan() {
+		var entry RenderEntry
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+
+	assert.Equal(t, []RenderEntry{
+		{Path: ".", Type: FileTypeDirectory, Size: 2054, Files: 3},
+		{Path: "sub", Type: FileTypeDirectory, Size: 2049, Files: 2},
+		{Path: "sub/deep", Type: FileTypeDirectory, Size: 2048, Files: 1},
+		{Path: "sub/deep/b.txt", Type: FileTypeFile, Size: 2048, Hash: common.HexToHash("0x02").Hex()},
+		{Path: "sub/small", Type: FileTypeFile, Size: 1, Hash: common.HexToHash("0x03").Hex()},
+		{Path: "sub/link", Type: FileTypeSymbolic, Link: "../a.txt"},
+		{Path: "a.txt", Type: FileTypeFile, Size: 5, Hash: hashA.Hex()},
+		{Path: "empty", Type: FileTypeDirectory},
+	}, entries)
+}
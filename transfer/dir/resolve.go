@@ -0,0 +1,188 @@
+package dir
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultResolveBlockRange is the default number of blocks to retrieve submissions in a single RPC.
+const defaultResolveBlockRange = 1000
+
+// ErrManifestNotFound is returned when no manifest of publisher matches the name or tag up to the block.
+var ErrManifestNotFound = errors.New("manifest not found")
+
+// Submission is a file submitted to the flow contract, which is the provenance of a resolved manifest.
+type Submission struct {
+	Root        common.Hash   `json:"root"`
+	TxSeq       uint64        `json:"txSeq"`
+	Size        uint64        `json:"size"`
+	Tags        hexutil.Bytes `json:"tags"`
+	BlockNumber uint64        `json:"blockNumber"`
+	BlockTime   time.Time     `json:"blockTime"`
+	TxHash      common.Hash   `json:"txHash"`
+}
+
+// SkippedSubmission is a submission matched but failed to resolve, e.g. manifest unavailable on storage nodes.
+type SkippedSubmission struct {
+	Submission Submission
+	Err        error
+}
+
+// SkippedSubmissionsError is returned when submissions matched but failed to resolve, which are skipped to
+// resolve earlier submissions.
+type SkippedSubmissionsError struct {
+	Skipped []SkippedSubmission
+}
+
+func (e *SkippedSubmissionsError) Error() string {
+	first := e.Skipped[0]
+	return fmt.Sprintf("%v matched submissions skipped, e.g. tx seq %v: %v", len(e.Skipped), first.Submission.TxSeq, first.Err)
+}
+
+// SubmissionLog retrieves files submitted to the flow contract, which is implemented by transfer.SubmissionLog.
+type SubmissionLog interface {
+	// Submissions returns files submitted by the publisher in the specified block range (inclusive) in order.
+	Submissions(ctx context.Context, publisher common.Address, fromBlock, toBlock uint64) ([]Submission, error)
+}
+
+// ResolveOption is the option to resolve a directory manifest.
+type ResolveOption struct {
+	FromBlock       uint64 // earliest block to scan, inclusive
+	BlockRange      uint64 // number of blocks to retrieve submissions in a single RPC, default 1000
+	MaxManifestSize uint64 // files larger than this are not regarded as manifest, 0 for no limit
+}
+
+// ParseManifestTag returns the tags to match manifests, which is decoded from HEX if prefixed with 0x, otherwise
+// the name in bytes.
+func ParseManifestTag(nameOrTag string) ([]byte, error) {
+	if !strings.HasPrefix(nameOrTag, "0x") {
+		return []byte(nameOrTag), nil
+	}
+
+	tag, err := hexutil.Decode(nameOrTag)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid tag %v", nameOrTag)
+	}
+
+	return tag, nil
+}
+
+// ResolveAt resolves the directory published by the specified address as of the specified block, which is the
+// latest manifest submitted by publisher up to the block with tags that match the name or tag exactly. Tags are
+// decoded from HEX if nameOrTag is prefixed with 0x, otherwise the name in bytes. Submissions are scanned backward
+// from the block, and files that fail to decode as manifest, e.g. files of directory tagged the same, are skipped.
+//
+// The manifest is downloaded with merkle proof, and the result is deterministic for a fixed block. Returns
+// ErrManifestNotFound if no manifest matched. If newer submissions matched but failed to download, e.g. unavailable
+// on storage nodes, they are skipped to resolve earlier submissions, and a *SkippedSubmissionsError is returned
+// along with the earlier manifest if any, so that callers could decide whether the earlier manifest is acceptable.
+func ResolveAt(ctx context.Context, log SubmissionLog, downloader Downloader, publisher common.Address, nameOrTag string,
+	blockNumber uint64, option ...ResolveOption) (*FsNode, *Submission, error) {
+	var opt ResolveOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	tag, err := ParseManifestTag(nameOrTag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blockRange := opt.BlockRange
+	if blockRange == 0 {
+		blockRange = defaultResolveBlockRange
+	}
+
+	var skipped []SkippedSubmission
+	for to := blockNumber; to >= opt.FromBlock; to -= blockRange {
+		from := opt.FromBlock
+		if to-from >= blockRange {
+			from = to - blockRange + 1
+		}
+
+		submissions, err := log.Submissions(ctx, publisher, from, to)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed to retrieve submissions in blocks [%v, %v]", from, to)
+		}
+
+		// the latest submission first
+		for i := len(submissions) - 1; i >= 0; i-- {
+			tree, err := resolveSubmission(ctx, downloader, &submissions[i], tag, opt.MaxManifestSize)
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+
+			if err != nil {
+				skipped = append(skipped, SkippedSubmission{submissions[i], err})
+				continue
+			}
+
+			if tree != nil {
+				return tree, &submissions[i], skippedErr(skipped)
+			}
+		}
+
+		if from == opt.FromBlock {
+			break
+		}
+	}
+
+	if len(skipped) > 0 {
+		return nil, nil, skippedErr(skipped)
+	}
+
+	return nil, nil, errors.WithMessagef(ErrManifestNotFound, "publisher = %v, name or tag = %v, block = %v", publisher, nameOrTag, blockNumber)
+}
+
+func skippedErr(skipped []SkippedSubmission) error {
+	if len(skipped) == 0 {
+		return nil
+	}
+
+	return &SkippedSubmissionsError{skipped}
+}
+
+// resolveSubmission downloads and decodes the manifest of submission if matched, and returns nil if not matched
+// or not a manifest.
+func resolveSubmission(ctx context.Context, downloader Downloader, submission *Submission, tag []byte, maxSize uint64) (*FsNode, error) {
+	if !bytes.Equal(submission.Tags, tag) {
+		return nil, nil
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"root":  submission.Root,
+		"txSeq": submission.TxSeq,
+		"block": submission.BlockNumber,
+	})
+
+	if maxSize > 0 && submission.Size > maxSize {
+		logger.WithField("size", submission.Size).Debug("Skip submission too large to be manifest")
+		return nil, nil
+	}
+
+	data, err := downloadManifest(ctx, downloader, submission.Root.Hex())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to download manifest of tx seq %v", submission.TxSeq)
+	}
+
+	var tree FsNode
+	if err = tree.UnmarshalBinary(data); err != nil {
+		logger.WithError(err).Debug("Skip submission not a manifest")
+		return nil, nil
+	}
+
+	if tree.Type != FileTypeDirectory {
+		logger.Debug("Skip manifest not a directory")
+		return nil, nil
+	}
+
+	return &tree, nil
+}
@@ -0,0 +1,140 @@
+package dir_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPublisher = common.HexToAddress("0xa")
+
+// memSubmissionLog serves submissions of publishers from memory.
+type memSubmissionLog struct {
+	submissions map[common.Address][]dir.Submission
+	calls       int
+}
+
+func (log *memSubmissionLog) submit(publisher common.Address, block uint64, root string, size int, tags string) {
+	if log.submissions == nil {
+		log.submissions = make(map[common.Address][]dir.Submission)
+	}
+
+	log.submissions[publisher] = append(log.submissions[publisher], dir.Submission{
+		Root:        common.HexToHash(root),
+		TxSeq:       uint64(len(log.submissions[publisher])),
+		Size:        uint64(size),
+		Tags:        []byte(tags),
+		BlockNumber: block,
+		BlockTime:   time.Unix(int64(block), 0).UTC(),
+		TxHash:      common.BigToHash(common.Big1),
+	})
+}
+
+func (log *memSubmissionLog) Submissions(ctx context.Context, publisher common.Address, fromBlock, toBlock uint64) ([]dir.Submission, error) {
+	log.calls++
+
+	var result []dir.Submission
+	for _, submission := range log.submissions[publisher] {
+		if submission.BlockNumber >= fromBlock && submission.BlockNumber <= toBlock {
+			result = append(result, submission)
+		}
+	}
+
+	return result, nil
+}
+
+func TestResolveAt(t *testing.T) {
+	downloader := newMemDownloader()
+	var log memSubmissionLog
+
+	publish := func(block uint64, tags string, entries ...*dir.FsNode) {
+		manifest, err := dir.CanonicalBytes(dir.NewDirFsNode("", entries))
+		assert.NoError(t, err)
+		log.submit(testPublisher, block, downloader.add(t, manifest), len(manifest), tags)
+	}
+
+	// v1 in block 10, and v2 in block 30, of which the file is tagged the same in block 29
+	fileV1 := newFileNode(t, downloader, "a.txt", "v1")
+	publish(10, "site", fileV1)
+	log.submit(testPublisher, 20, downloader.add(t, []byte("others")), 6, "others")
+	fileV2 := newFileNode(t, downloader, "a.txt", "version 2")
+	log.submit(testPublisher, 29, fileV2.Root, int(fileV2.Size), "site")
+	publish(30, "site", fileV2)
+	// other publisher
+	manifest, err := dir.CanonicalBytes(dir.NewDirFsNode("", nil))
+	assert.NoError(t, err)
+	log.submit(common.HexToAddress("0xb"), 40, downloader.add(t, manifest), len(manifest), "site")
+
+	for _, tc := range []struct {
+		block    uint64
+		expected *dir.FsNode
+		resolved uint64
+	}{
+		{10, fileV1, 10},
+		{29, fileV1, 10},
+		{30, fileV2, 30},
+		{100, fileV2, 30},
+	} {
+		tree, provenance, err := dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "site", tc.block, dir.ResolveOption{BlockRange: 7})
+		assert.NoError(t, err)
+		assert.Equal(t, tc.resolved, provenance.BlockNumber)
+		assert.Equal(t, time.Unix(int64(tc.resolved), 0).UTC(), provenance.BlockTime)
+		assert.True(t, tree.Entries[0].Equal(tc.expected), "block %v", tc.block)
+
+		// deterministic
+		again, _, err := dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "site", tc.block, dir.ResolveOption{BlockRange: 3})
+		assert.NoError(t, err)
+		assert.True(t, tree.Equal(again))
+	}
+
+	// matched by HEX tag
+	_, provenance, err := dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "0x73697465", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(30), provenance.BlockNumber)
+
+	// the file tagged the same is too large to be manifest, so not downloaded
+	downloads := downloader.downloads
+	_, provenance, err = dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "site", 29, dir.ResolveOption{MaxManifestSize: 8})
+	assert.ErrorIs(t, err, dir.ErrManifestNotFound)
+	assert.Nil(t, provenance)
+	assert.Equal(t, downloads, downloader.downloads)
+
+	// not found
+	for _, block := range []uint64{0, 9} {
+		_, _, err = dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "site", block, dir.ResolveOption{BlockRange: 4})
+		assert.ErrorIs(t, err, dir.ErrManifestNotFound)
+	}
+	_, _, err = dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "site", 100, dir.ResolveOption{FromBlock: 11})
+	assert.NoError(t, err)
+	_, _, err = dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "others", 100)
+	assert.ErrorIs(t, err, dir.ErrManifestNotFound)
+
+	// scanned backward until found
+	log.calls = 0
+	_, _, err = dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "site", 1000, dir.ResolveOption{BlockRange: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, log.calls)
+
+	_, _, err = dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "0xzz", 100)
+	assert.ErrorContains(t, err, "invalid tag")
+
+	// newer manifest unavailable on storage nodes skipped, and the earlier one returned along with error
+	log.submit(testPublisher, 50, "0xff", 100, "site")
+	tree, provenance, err := dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "site", 100)
+	var skipped *dir.SkippedSubmissionsError
+	if assert.ErrorAs(t, err, &skipped) {
+		assert.Len(t, skipped.Skipped, 1)
+		assert.Equal(t, uint64(50), skipped.Skipped[0].Submission.BlockNumber)
+	}
+	assert.Equal(t, uint64(30), provenance.BlockNumber)
+	assert.True(t, tree.Entries[0].Equal(fileV2))
+
+	// only unavailable manifests matched
+	_, _, err = dir.ResolveAt(context.Background(), &log, downloader, testPublisher, "site", 100, dir.ResolveOption{FromBlock: 40})
+	assert.ErrorAs(t, err, &skipped)
+	assert.NotErrorIs(t, err, dir.ErrManifestNotFound)
+}
@@ -0,0 +1,126 @@
+package dir
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+var (
+	// SignatureMagicBytes marks the end of a signed manifest, which follows the signer address and signature.
+	SignatureMagicBytes = crypto.Keccak256([]byte("0g-storage-client-dir-signature"))
+
+	// ErrManifestUnsigned is returned when verifying the publisher of a manifest without signature.
+	ErrManifestUnsigned = errors.New("manifest not signed")
+
+	// ErrPublisherMismatch is returned when the manifest is not signed by the expected publisher.
+	ErrPublisherMismatch = errors.New("manifest publisher mismatch")
+)
+
+// signatureFooterSize is the size of footer appended to signed manifest: signer + signature + magic bytes.
+var signatureFooterSize = common.AddressLength + crypto.SignatureLength + len(SignatureMagicBytes)
+
+// ManifestSignature is the signature of manifest, which is embedded in the footer of manifest and excluded from
+// the signed bytes.
+type ManifestSignature struct {
+	Signer    common.Address `json:"signer"`
+	Signature []byte         `json:"signature"` // 65 bytes [R || S || V] signature of manifest root in EIP-191 format
+}
+
+// SignManifest encodes the FsNode in canonical form, and signs the storage root of canonical bytes with the
+// specified key. Returns the manifest to upload along with the signature footer.
+func SignManifest(root *FsNode, key *ecdsa.PrivateKey) ([]byte, error) {
+	data, err := CanonicalBytes(root)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := signedHash(data)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to sign manifest")
+	}
+
+	signed := make([]byte, 0, len(data)+signatureFooterSize)
+	signed = append(signed, data...)
+	signed = append(signed, crypto.PubkeyToAddress(key.PublicKey).Bytes()...)
+	signed = append(signed, signature...)
+	signed = append(signed, SignatureMagicBytes...)
+
+	return signed, nil
+}
+
+// signedHash returns the hash to sign for the manifest, which is the EIP-191 hash of manifest storage root.
+func signedHash(manifest []byte) ([]byte, error) {
+	iterdata, err := core.NewDataInMemory(manifest)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create `IterableData` in memory")
+	}
+
+	root, err := core.MerkleRootData(iterdata)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to calculate merkle root")
+	}
+
+	return accounts.TextHash(root.Bytes()), nil
+}
+
+// SplitSignature splits the manifest data into signed bytes and signature, which is nil if not signed.
+func SplitSignature(data []byte) ([]byte, *ManifestSignature) {
+	if len(data) < signatureFooterSize || !bytes.HasSuffix(data, SignatureMagicBytes) {
+		return data, nil
+	}
+
+	footer := data[len(data)-signatureFooterSize:]
+
+	return data[:len(data)-signatureFooterSize], &ManifestSignature{
+		Signer:    common.BytesToAddress(footer[:common.AddressLength]),
+		Signature: common.CopyBytes(footer[common.AddressLength : common.AddressLength+crypto.SignatureLength]),
+	}
+}
+
+// Verify checks that the signed bytes of manifest are signed by the signer.
+func (sig *ManifestSignature) Verify(manifest []byte) error {
+	hash, err := signedHash(manifest)
+	if err != nil {
+		return err
+	}
+
+	pubkey, err := crypto.SigToPub(hash, sig.Signature)
+	if err != nil {
+		return errors.WithMessage(err, "invalid manifest signature")
+	}
+
+	if recovered := crypto.PubkeyToAddress(*pubkey); recovered != sig.Signer {
+		return errors.Errorf("invalid manifest signature, signer = %v, recovered = %v", sig.Signer, recovered)
+	}
+
+	return nil
+}
+
+// VerifyPublisher checks that the manifest data is signed by the specified publisher, and returns the signature.
+func VerifyPublisher(data []byte, publisher common.Address) (*ManifestSignature, error) {
+	manifest, sig := SplitSignature(data)
+	if sig == nil {
+		return nil, ErrManifestUnsigned
+	}
+
+	if err := sig.Verify(manifest); err != nil {
+		return nil, err
+	}
+
+	if sig.Signer != publisher {
+		return nil, errors.WithMessagef(ErrPublisherMismatch, "expected = %v, signer = %v", publisher, sig.Signer)
+	}
+
+	return sig, nil
+}
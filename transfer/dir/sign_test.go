@@ -0,0 +1,86 @@
+package dir_test
+
+import (
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSignTestTree() *dir.FsNode {
+	return dir.NewDirFsNode("root", []*dir.FsNode{
+		{Name: "a.txt", Type: dir.FileTypeFile, Root: "0xabc123", Size: 1024},
+		{Name: "link", Type: dir.FileTypeSymbolic, Link: "a.txt"},
+	})
+}
+
+func TestSignManifest(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	publisher := crypto.PubkeyToAddress(key.PublicKey)
+
+	tree := newSignTestTree()
+	signed, err := dir.SignManifest(tree, key)
+	assert.NoError(t, err)
+
+	// signed bytes are the canonical manifest
+	unsigned, err := dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+	manifest, sig := dir.SplitSignature(signed)
+	assert.Equal(t, unsigned, manifest)
+	assert.Equal(t, publisher, sig.Signer)
+
+	verified, err := dir.VerifyPublisher(signed, publisher)
+	assert.NoError(t, err)
+	assert.Equal(t, sig, verified)
+
+	// signature is ignored when decoding
+	var decoded dir.FsNode
+	assert.NoError(t, decoded.UnmarshalBinary(signed))
+	assert.True(t, tree.Equal(&decoded))
+
+	// unsigned manifest
+	_, sig = dir.SplitSignature(unsigned)
+	assert.Nil(t, sig)
+	_, err = dir.VerifyPublisher(unsigned, publisher)
+	assert.ErrorIs(t, err, dir.ErrManifestUnsigned)
+}
+
+func TestVerifyPublisherTampered(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	publisher := crypto.PubkeyToAddress(key.PublicKey)
+
+	signed, err := dir.SignManifest(newSignTestTree(), key)
+	assert.NoError(t, err)
+
+	// tamper the manifest content, e.g. replace the file root
+	manifest, _ := dir.SplitSignature(signed)
+	tampered := append([]byte(nil), signed...)
+	tampered[len(manifest)-20] ^= 1
+	_, err = dir.VerifyPublisher(tampered, publisher)
+	assert.ErrorContains(t, err, "invalid manifest signature")
+
+	// tamper the signer in footer
+	other, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	tampered = append([]byte(nil), signed...)
+	copy(tampered[len(manifest):], crypto.PubkeyToAddress(other.PublicKey).Bytes())
+	_, err = dir.VerifyPublisher(tampered, crypto.PubkeyToAddress(other.PublicKey))
+	assert.ErrorContains(t, err, "invalid manifest signature")
+}
+
+func TestVerifyPublisherWrongSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	other, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	// validly signed, but by another key
+	signed, err := dir.SignManifest(newSignTestTree(), other)
+	assert.NoError(t, err)
+
+	_, err = dir.VerifyPublisher(signed, crypto.PubkeyToAddress(key.PublicKey))
+	assert.ErrorIs(t, err, dir.ErrPublisherMismatch)
+}
@@ -0,0 +1,127 @@
+package dir
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// ReadLinkFS is the file system that supports symbolic links, which has the same method set as fs.ReadLinkFS of
+// Go 1.25, so that file systems of the standard library satisfy it as well.
+type ReadLinkFS interface {
+	fs.FS
+
+	// ReadLink returns the destination of the named symbolic link.
+	ReadLink(name string) (string, error)
+
+	// Lstat returns the FileInfo describing the named file, which describes the link itself if symbolic link.
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// treeSource is the file system to build file tree from.
+type treeSource interface {
+	join(elem ...string) string
+	lstat(name string) (fs.FileInfo, error)
+	readDir(name string) ([]fs.DirEntry, error)
+	readlink(name string) (string, error)
+	merkleRoot(name string, opt core.HashOption) (common.Hash, error)
+
+	// roots returns the absolute paths of root directory to check symbolic links against, see symlinkResolver.
+	roots(root string) ([]string, error)
+}
+
+// osSource is the local file system.
+type osSource struct{}
+
+func (osSource) join(elem ...string) string {
+	return filepath.Join(elem...)
+}
+
+func (osSource) lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (osSource) readDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osSource) readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+func (osSource) merkleRoot(name string, opt core.HashOption) (common.Hash, error) {
+	return core.MerkleRoot(name, opt)
+}
+
+func (osSource) roots(root string) ([]string, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get absolute path of %s", root)
+	}
+
+	roots := []string{abs}
+	if real, err := filepath.EvalSymlinks(abs); err == nil && real != abs {
+		roots = append(roots, real)
+	}
+
+	return roots, nil
+}
+
+// fsSource is an io/fs.FS, where symbolic links are skipped unless it implements ReadLinkFS.
+type fsSource struct {
+	fsys fs.FS
+}
+
+func (fsSource) join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+func (source fsSource) lstat(name string) (fs.FileInfo, error) {
+	if links, ok := source.fsys.(ReadLinkFS); ok {
+		return links.Lstat(name)
+	}
+
+	return fs.Stat(source.fsys, name)
+}
+
+func (source fsSource) readDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(source.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := source.fsys.(ReadLinkFS); ok {
+		return entries, nil
+	}
+
+	retained := entries[:0]
+	for _, entry := range entries {
+		if entry.Type()&fs.ModeSymlink == 0 {
+			retained = append(retained, entry)
+		}
+	}
+
+	return retained, nil
+}
+
+func (source fsSource) readlink(name string) (string, error) {
+	if links, ok := source.fsys.(ReadLinkFS); ok {
+		return links.ReadLink(name)
+	}
+
+	return "", errors.New("symbolic link not supported")
+}
+
+func (source fsSource) merkleRoot(name string, opt core.HashOption) (common.Hash, error) {
+	return core.MerkleRootFS(source.fsys, name, opt)
+}
+
+// roots returns nothing, since the file system is rooted, and absolute links always escape.
+func (fsSource) roots(root string) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,50 @@
+package dir
+
+import (
+	"io/fs"
+
+	"github.com/pkg/errors"
+)
+
+// SpecialFilePolicy is the policy to handle special files, e.g. named pipes, sockets and device nodes, when building
+// file tree, which could not be uploaded as regular files.
+type SpecialFilePolicy string
+
+const (
+	// SpecialFilesSkip ignores the special files, which never appear in the file tree, so that the directory metadata
+	// is deterministic. This is the default policy if not specified.
+	SpecialFilesSkip SpecialFilePolicy = "skip"
+
+	// SpecialFilesReject fails to build file tree with ErrSpecialFile, for users who need completeness guarantees.
+	SpecialFilesReject SpecialFilePolicy = "reject"
+)
+
+// ErrSpecialFile is returned when building file tree with special files under the SpecialFilesReject policy.
+var ErrSpecialFile = errors.New("unsupported file type")
+
+// Validate checks whether the special file policy is supported.
+func (policy SpecialFilePolicy) Validate() error {
+	switch policy {
+	case "", SpecialFilesSkip, SpecialFilesReject:
+		return nil
+	default:
+		return errors.Errorf("unsupported special file policy %q", string(policy))
+	}
+}
+
+// SpecialFileType returns the type of special file by mode, e.g. "fifo", "socket" or "device", which is reported
+// when the special file skipped.
+func SpecialFileType(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "char device"
+	case mode&fs.ModeDevice != 0:
+		return "device"
+	default:
+		return "irregular"
+	}
+}
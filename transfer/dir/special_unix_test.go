@@ -0,0 +1,52 @@
+//go:build unix
+
+package dir_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestBuildFileTreeSpecialFiles(t *testing.T) {
+	folder := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "sub", "file.txt"), []byte("content"), 0644))
+	assert.NoError(t, unix.Mkfifo(filepath.Join(folder, "sub", "pipe"), 0644))
+
+	expected, err := dir.BuildFileTree(filepath.Join(folder, "sub"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.Remove(filepath.Join(folder, "sub", "pipe")))
+	withoutPipe, err := dir.BuildFileTree(filepath.Join(folder, "sub"))
+	assert.NoError(t, err)
+	assert.NoError(t, unix.Mkfifo(filepath.Join(folder, "sub", "pipe"), 0644))
+
+	// skipped by default, and never appear in the file tree
+	assert.True(t, expected.Equal(withoutPipe))
+
+	type skip struct{ path, fileType string }
+	var skipped []skip
+	root, err := dir.BuildFileTreeWithOption(folder, dir.BuildOption{
+		OnSpecialFileSkipped: func(relpath, fileType string) {
+			skipped = append(skipped, skip{relpath, fileType})
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []skip{{"sub/pipe", "fifo"}}, skipped)
+	_, found := root.Search("sub")
+	assert.True(t, found)
+	_, err = root.Locate("sub/pipe")
+	assert.Error(t, err)
+
+	// rejected for completeness
+	_, err = dir.BuildFileTreeWithOption(folder, dir.BuildOption{SpecialFiles: dir.SpecialFilesReject})
+	assert.ErrorIs(t, err, dir.ErrSpecialFile)
+	assert.ErrorContains(t, err, "fifo")
+
+	_, err = dir.BuildFileTreeWithOption(folder, dir.BuildOption{SpecialFiles: "unknown"})
+	assert.ErrorContains(t, err, "unsupported special file policy")
+}
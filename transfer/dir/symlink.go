@@ -0,0 +1,198 @@
+package dir
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// SymlinkPolicy is the policy to handle symbolic links that escape the root directory or form a cycle when
+// building file tree, since tools that resolve links may spin forever on cycles, and links escaping the root leak
+// local paths into directory metadata.
+type SymlinkPolicy string
+
+const (
+	// SymlinkReject fails to build file tree with a descriptive error, which is the default policy if not specified.
+	SymlinkReject SymlinkPolicy = "reject"
+
+	// SymlinkSkip ignores the offending symbolic links, which never appear in the file tree.
+	SymlinkSkip SymlinkPolicy = "skip"
+
+	// SymlinkKeep stores the offending symbolic links as is.
+	SymlinkKeep SymlinkPolicy = "keep"
+)
+
+var (
+	// ErrSymlinkEscape is returned when a symbolic link resolves outside the root directory under the
+	// SymlinkReject policy.
+	ErrSymlinkEscape = errors.New("symbolic link escapes root directory")
+
+	// ErrSymlinkCycle is returned when symbolic links form a cycle, or a symbolic link resolves to a directory
+	// that contains it, under the SymlinkReject policy.
+	ErrSymlinkCycle = errors.New("symbolic link cycle")
+)
+
+// Validate checks whether the symbolic link policy is supported.
+func (policy SymlinkPolicy) Validate() error {
+	switch policy {
+	case "", SymlinkReject, SymlinkSkip, SymlinkKeep:
+		return nil
+	default:
+		return errors.Errorf("unsupported symbolic link policy %q", string(policy))
+	}
+}
+
+// symlinkResolver resolves symbolic links within the file tree built from the root directory on local file system.
+type symlinkResolver struct {
+	tree  *FsNode
+	roots []string // absolute paths of root directory on local file system, with or without links evaluated
+}
+
+// target returns the slash-separated path relative to the tree root that the symbolic link at relpath points to,
+// or ErrSymlinkEscape if it points outside the root directory.
+func (resolver *symlinkResolver) target(relpath, link string) (string, error) {
+	if !filepath.IsAbs(link) {
+		if target := path.Join(path.Dir(relpath), filepath.ToSlash(link)); !escaped(target) {
+			return target, nil
+		}
+
+		return "", errors.WithMessagef(ErrSymlinkEscape, "%s -> %s", relpath, link)
+	}
+
+	for _, root := range resolver.roots {
+		if rel, err := filepath.Rel(root, filepath.Clean(link)); err == nil && !escaped(filepath.ToSlash(rel)) {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+
+	return "", errors.WithMessagef(ErrSymlinkEscape, "%s -> %s", relpath, link)
+}
+
+// escaped returns whether the cleaned slash-separated relative path is outside the root.
+func escaped(relpath string) bool {
+	return relpath == ".." || strings.HasPrefix(relpath, "../")
+}
+
+// check resolves the symbolic link at relpath along with links on the way, and returns ErrSymlinkEscape or
+// ErrSymlinkCycle if any. Links to targets that do not exist in the tree are allowed.
+func (resolver *symlinkResolver) check(relpath string) error {
+	visited := make(map[string]bool)
+
+	// path to resolve, where the last component is followed as well
+	current := relpath
+
+walk:
+	for {
+		node := resolver.tree
+		var parts []string
+		if current != "." {
+			parts = strings.Split(current, "/")
+		}
+
+		for i, part := range parts {
+			if node.Type != FileTypeDirectory {
+				return nil
+			}
+
+			entry, found := node.Search(part)
+			if !found {
+				return nil
+			}
+
+			if entry.Type != FileTypeSymbolic {
+				node = entry
+				continue
+			}
+
+			linkPath := path.Join(parts[:i+1]...)
+			if visited[linkPath] {
+				return errors.WithMessagef(ErrSymlinkCycle, "%s -> %s", relpath, entry.Link)
+			}
+			visited[linkPath] = true
+
+			target, err := resolver.target(linkPath, entry.Link)
+			if err != nil {
+				return err
+			}
+
+			current = path.Join(target, path.Join(parts[i+1:]...))
+			continue walk
+		}
+
+		// link to a directory that contains it, which never ends when walked with links followed
+		if node.Type == FileTypeDirectory && (current == "." || strings.HasPrefix(relpath, current+"/")) {
+			return errors.WithMessagef(ErrSymlinkCycle, "%s resolves to ancestor directory", relpath)
+		}
+
+		return nil
+	}
+}
+
+// checkSymlinks resolves all symbolic links in tree, and handles the offending ones by policy.
+func (builder *treeBuilder) checkSymlinks(tree *FsNode, root string) error {
+	if builder.opt.Symlinks == SymlinkKeep {
+		return nil
+	}
+
+	roots, err := builder.source.roots(root)
+	if err != nil {
+		return err
+	}
+
+	resolver := symlinkResolver{tree: tree, roots: roots}
+
+	// symbolic links to skip by parent directory, which are removed once all links resolved
+	skipped := make(map[*FsNode]map[*FsNode]bool)
+	err = tree.Walk(func(relpath string, node *FsNode) error {
+		if node.Type != FileTypeDirectory {
+			return nil
+		}
+
+		for _, entry := range node.Entries {
+			if entry.Type != FileTypeSymbolic {
+				continue
+			}
+
+			entryPath := path.Join(relpath, entry.Name)
+			err := resolver.check(entryPath)
+			if err == nil {
+				continue
+			}
+
+			if builder.opt.Symlinks != SymlinkSkip {
+				return err
+			}
+
+			if skipped[node] == nil {
+				skipped[node] = make(map[*FsNode]bool)
+			}
+			skipped[node][entry] = true
+
+			if builder.opt.OnSymlinkSkipped != nil {
+				builder.opt.OnSymlinkSkipped(entryPath, err)
+			} else {
+				logrus.WithError(err).WithField("path", entryPath).Warn("Symbolic link skipped")
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for parent, entries := range skipped {
+		retained := parent.Entries[:0]
+		for _, entry := range parent.Entries {
+			if !entries[entry] {
+				retained = append(retained, entry)
+			}
+		}
+		parent.Entries = retained
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package dir_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildFileTreeSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privilege on windows")
+	}
+
+	newFolder := func(t *testing.T, links map[string]string) string {
+		folder := t.TempDir()
+		assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub"), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(folder, "sub", "file.txt"), []byte("content"), 0644))
+		for name, link := range links {
+			assert.NoError(t, os.Symlink(link, filepath.Join(folder, name)))
+		}
+		return folder
+	}
+
+	tests := []struct {
+		name  string
+		links map[string]string
+		err   error
+	}{
+		{"self-referencing", map[string]string{"self": "self"}, dir.ErrSymlinkCycle},
+		{"cycle", map[string]string{"a": "b", "b": "a"}, dir.ErrSymlinkCycle},
+		{"cycle through directory", map[string]string{"a": "b/file.txt", "b": "a"}, dir.ErrSymlinkCycle},
+		{"ancestor directory", map[string]string{"sub/up": ".."}, dir.ErrSymlinkCycle},
+		{"absolute path outside", map[string]string{"passwd": "/etc/passwd"}, dir.ErrSymlinkEscape},
+		{"relative path outside", map[string]string{"parent": "../secret"}, dir.ErrSymlinkEscape},
+		{"escape through another link", map[string]string{"etc": "/etc", "passwd": "etc/passwd"}, dir.ErrSymlinkEscape},
+		{"within tree", map[string]string{"file": "sub/file.txt", "dir": "sub", "nested": "dir/file.txt"}, nil},
+		{"dangling", map[string]string{"missing": "sub/missing.txt"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folder := newFolder(t, tt.links)
+
+			// rejected by default
+			root, err := dir.BuildFileTree(folder)
+			if tt.err == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, len(tt.links)+1, len(root.Entries))
+				return
+			}
+			assert.ErrorIs(t, err, tt.err)
+
+			// skipped
+			var skipped []string
+			root, err = dir.BuildFileTreeWithOption(folder, dir.BuildOption{
+				Symlinks:         dir.SymlinkSkip,
+				OnSymlinkSkipped: func(relpath string, err error) { skipped = append(skipped, relpath) },
+			})
+			assert.NoError(t, err)
+			assert.NotEmpty(t, skipped)
+			for _, relpath := range skipped {
+				_, err := root.Locate(relpath)
+				assert.ErrorIs(t, err, dir.ErrPathNotFound)
+			}
+
+			// stored as is
+			root, err = dir.BuildFileTreeWithOption(folder, dir.BuildOption{Symlinks: dir.SymlinkKeep})
+			assert.NoError(t, err)
+			for name, link := range tt.links {
+				node, err := root.Locate(filepath.ToSlash(name))
+				assert.NoError(t, err)
+				assert.Equal(t, link, node.Link)
+			}
+		})
+	}
+
+	// absolute path within tree
+	folder := newFolder(t, nil)
+	assert.NoError(t, os.Symlink(filepath.Join(folder, "sub", "file.txt"), filepath.Join(folder, "abs")))
+	_, err := dir.BuildFileTree(folder)
+	assert.NoError(t, err)
+
+	_, err = dir.BuildFileTreeWithOption(folder, dir.BuildOption{Symlinks: "follow"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package dir
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// UnixFS data types.
+const (
+	unixfsRaw       = 0
+	unixfsDirectory = 1
+	unixfsFile      = 2
+	unixfsSymlink   = 4
+)
+
+// Protobuf wire types used in dag-pb and UnixFS.
+const (
+	pbWireVarint = 0
+	pbWireBytes  = 2
+)
+
+// pbLink is the PBLink message of dag-pb node.
+type pbLink struct {
+	Hash  Cid
+	Name  string
+	Tsize uint64 // cumulative size of the linked block and all its descendants
+}
+
+// unixfsData is the Data message of UnixFS, which is embedded in the Data field of dag-pb node.
+type unixfsData struct {
+	Type       uint64
+	Data       []byte
+	FileSize   uint64
+	BlockSizes []uint64
+}
+
+func appendPbVarint(buf []byte, field int, v uint64) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field<<3|pbWireVarint))
+	return binary.AppendUvarint(buf, v)
+}
+
+func appendPbBytes(buf []byte, field int, data []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field<<3|pbWireBytes))
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+// encodeDagPb encodes dag-pb node in the canonical form, in which links are encoded before data.
+func encodeDagPb(links []pbLink, data []byte) []byte {
+	var buf []byte
+
+	for _, link := range links {
+		encoded := appendPbBytes(nil, 1, link.Hash)
+		encoded = appendPbBytes(encoded, 2, []byte(link.Name))
+		encoded = appendPbVarint(encoded, 3, link.Tsize)
+		buf = appendPbBytes(buf, 2, encoded)
+	}
+
+	return appendPbBytes(buf, 1, data)
+}
+
+func (data *unixfsData) encode() []byte {
+	buf := appendPbVarint(nil, 1, data.Type)
+
+	if data.Data != nil {
+		buf = appendPbBytes(buf, 2, data.Data)
+	}
+
+	if data.Type == unixfsFile || data.Type == unixfsRaw {
+		buf = appendPbVarint(buf, 3, data.FileSize)
+	}
+
+	for _, size := range data.BlockSizes {
+		buf = appendPbVarint(buf, 4, size)
+	}
+
+	return buf
+}
+
+// pbField is a decoded protobuf field of either varint or bytes wire type.
+type pbField struct {
+	num    uint64
+	varint uint64
+	bytes  []byte
+}
+
+func readPbFields(data []byte) ([]pbField, error) {
+	var fields []pbField
+
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("invalid protobuf field key")
+		}
+		data = data[n:]
+
+		field := pbField{num: key >> 3}
+
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.Errorf("invalid protobuf field %v", field.num)
+		}
+		data = data[n:]
+
+		switch key & 0x7 {
+		case pbWireVarint:
+			field.varint = v
+		case pbWireBytes:
+			if v > uint64(len(data)) {
+				return nil, errors.Errorf("protobuf field %v out of bound", field.num)
+			}
+			field.bytes, data = data[:v], data[v:]
+		default:
+			return nil, errors.Errorf("unsupported protobuf wire type %v", key&0x7)
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// decodeDagPb decodes dag-pb node, and returns the links and UnixFS data.
+func decodeDagPb(data []byte) ([]pbLink, *unixfsData, error) {
+	fields, err := readPbFields(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var links []pbLink
+	var unixfs *unixfsData
+
+	for _, field := range fields {
+		switch field.num {
+		case 1:
+			if unixfs, err = decodeUnixfs(field.bytes); err != nil {
+				return nil, nil, errors.WithMessage(err, "invalid UnixFS data")
+			}
+		case 2:
+			link, err := decodePbLink(field.bytes)
+			if err != nil {
+				return nil, nil, errors.WithMessage(err, "invalid link")
+			}
+			links = append(links, link)
+		default:
+			return nil, nil, errors.Errorf("unexpected dag-pb field %v", field.num)
+		}
+	}
+
+	if unixfs == nil {
+		return nil, nil, errors.New("UnixFS data not found")
+	}
+
+	return links, unixfs, nil
+}
+
+func decodePbLink(data []byte) (pbLink, error) {
+	fields, err := readPbFields(data)
+	if err != nil {
+		return pbLink{}, err
+	}
+
+	var link pbLink
+	for _, field := range fields {
+		switch field.num {
+		case 1:
+			if link.Hash, err = parseCidBytes(field.bytes); err != nil {
+				return pbLink{}, err
+			}
+		case 2:
+			link.Name = string(field.bytes)
+		case 3:
+			link.Tsize = field.varint
+		}
+	}
+
+	if link.Hash == nil {
+		return pbLink{}, errors.New("link hash not found")
+	}
+
+	return link, nil
+}
+
+func decodeUnixfs(data []byte) (*unixfsData, error) {
+	fields, err := readPbFields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var unixfs unixfsData
+	for _, field := range fields {
+		switch field.num {
+		case 1:
+			unixfs.Type = field.varint
+		case 2:
+			unixfs.Data = field.bytes
+		case 3:
+			unixfs.FileSize = field.varint
+		case 4:
+			unixfs.BlockSizes = append(unixfs.BlockSizes, field.varint)
+		}
+	}
+
+	return &unixfs, nil
+}
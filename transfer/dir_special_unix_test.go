@@ -0,0 +1,50 @@
+//go:build unix
+
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestUploadDirSpecialFiles(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	folder := newTestFolder(t, 2)
+	assert.NoError(t, unix.Mkfifo(filepath.Join(folder, "pipe"), 0644))
+
+	// skipped by default, and reported
+	summary, err := uploader.UploadDirWithSummary(context.Background(), folder)
+	assert.NoError(t, err)
+	assert.Equal(t, []DirExcludedFile{{Path: "pipe", Type: "fifo"}}, summary.Excluded)
+	assert.Equal(t, 2, summary.Uploaded.Files)
+	assert.Equal(t, 1, uploader.Warnings().Count(WarningSpecialFileSkipped))
+
+	var text bytes.Buffer
+	assert.NoError(t, summary.WriteText(&text))
+	assert.Contains(t, text.String(), "EXCLUDED")
+
+	// same directory metadata as if the special file not exists
+	assert.NoError(t, os.Remove(filepath.Join(folder, "pipe")))
+	root, err := dir.BuildFileTree(folder)
+	assert.NoError(t, err)
+	_, expected, err := uploader.encodeManifest(root)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, summary.Root)
+
+	// rejected for completeness
+	assert.NoError(t, unix.Mkfifo(filepath.Join(folder, "pipe"), 0644))
+	_, _, err = uploader.WithSpecialFilePolicy(dir.SpecialFilesReject).UploadDir(context.Background(), folder)
+	assert.ErrorIs(t, err, dir.ErrSpecialFile)
+}
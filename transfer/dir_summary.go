@@ -0,0 +1,324 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DirFileStatus is the outcome of a file in directory upload.
+type DirFileStatus string
+
+const (
+	DirFileUploaded DirFileStatus = "uploaded" // submitted and uploaded in this run
+	DirFileSkipped  DirFileStatus = "skipped"  // file of identical root already finalized on storage node
+	DirFileReused   DirFileStatus = "reused"   // submitted by a previous partial run, and resumed in this run
+	DirFileFailed   DirFileStatus = "failed"
+	DirFilePending  DirFileStatus = "pending" // not uploaded since another file failed, see Uploader.WithContinueOnFileError
+)
+
+// DirFileResult is the outcome of a file to upload separately in directory upload.
+type DirFileResult struct {
+	Path   string         `json:"path"`
+	Root   common.Hash    `json:"root"`
+	Size   int64          `json:"size"`
+	Status DirFileStatus  `json:"status"`
+	Sender common.Address `json:"sender"` // account of SenderPool that submitted the file, zero if not
+	Error  string         `json:"error,omitempty"`
+
+	Footprint core.Footprint `json:"footprint"` // storage footprint of the file
+}
+
+// DirExcludedFile is a special file excluded from directory upload, e.g. named pipe, see dir.SpecialFilePolicy.
+type DirExcludedFile struct {
+	Path string `json:"path"`
+	Type string `json:"type"` // file type, see dir.SpecialFileType
+}
+
+// DirUploadCount is the number of files and total bytes of files.
+type DirUploadCount struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// DirUploadPhases is the wall time of each phase in directory upload. Phases of files uploaded concurrently
+// overlap, and the overlapped time is counted only once.
+type DirUploadPhases struct {
+	Hashing      time.Duration `json:"hashing"`      // merkle trees of files and directory metadata
+	Submission   time.Duration `json:"submission"`   // transactions sent and packed on storage node
+	Pushing      time.Duration `json:"pushing"`      // segments uploaded to storage nodes
+	Finalization time.Duration `json:"finalization"` // waiting for finality on storage nodes
+}
+
+// DirUploadSummary is the outcome of directory upload, where counts are consistent with the per-file results.
+// Files of empty or embedded in directory metadata are not uploaded separately, and thus not included.
+type DirUploadSummary struct {
+	TxHash common.Hash `json:"txHash"` // transaction of directory metadata, zero if skipped or failed
+	Root   common.Hash `json:"root"`   // storage root of directory metadata
+
+	// storage roots of chunks if directory metadata uploaded in chunks, see Uploader.WithManifestChunking, where
+	// TxHash and Root are of the manifest index, from which the directory is downloaded as usual
+	ManifestChunks []common.Hash `json:"manifestChunks,omitempty"`
+
+	// storage footprint of files in directory, excluding embedded files and directory metadata, and aggregated by
+	// top-level entries for chargeback, see dir.FsNode.TopLevelFootprints
+	Footprint  core.Footprint            `json:"footprint"`
+	Footprints map[string]core.Footprint `json:"footprints,omitempty"`
+
+	Uploaded DirUploadCount `json:"uploaded"`
+	Skipped  DirUploadCount `json:"skipped"`
+	Reused   DirUploadCount `json:"reused"`
+	Failed   DirUploadCount `json:"failed"`
+	Pending  DirUploadCount `json:"pending"`
+
+	Phases  DirUploadPhases `json:"phases"`
+	Elapsed time.Duration   `json:"elapsed"`
+
+	Agent zg_common.ClientInfo `json:"agent"` // client library that uploaded, even if failed
+
+	Files    []DirFileResult   `json:"files"`
+	Excluded []DirExcludedFile `json:"excluded,omitempty"` // special files not uploaded, which are not in directory metadata either
+}
+
+// add adds the outcome of a file.
+func (summary *DirUploadSummary) add(result DirFileResult) {
+	var count *DirUploadCount
+	switch result.Status {
+	case DirFileUploaded:
+		count = &summary.Uploaded
+	case DirFileSkipped:
+		count = &summary.Skipped
+	case DirFileReused:
+		count = &summary.Reused
+	case DirFilePending:
+		count = &summary.Pending
+	default:
+		count = &summary.Failed
+	}
+
+	count.Files++
+	count.Bytes += result.Size
+	summary.Files = append(summary.Files, result)
+}
+
+// WriteText writes the summary in human readable form, along with files failed to upload.
+func (summary *DirUploadSummary) WriteText(writer io.Writer) error {
+	w := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Root:\t%v\n", summary.Root)
+	fmt.Fprintf(w, "Transaction:\t%v\n", summary.TxHash)
+	fmt.Fprintf(w, "Client:\t%v/%v\n", summary.Agent.Name, summary.Agent.Version)
+	if len(summary.Agent.Features) > 0 {
+		fmt.Fprintf(w, "Features:\t%v\n", strings.Join(summary.Agent.Features, ", "))
+	}
+	for i, chunk := range summary.ManifestChunks {
+		fmt.Fprintf(w, "Manifest chunk %v:\t%v\n", i, chunk)
+	}
+	fmt.Fprintf(w, "Footprint:\t%v sectors, %v segments, %v bytes padded\n", summary.Footprint.Sectors, summary.Footprint.Segments, summary.Footprint.PaddedSize)
+	fmt.Fprintln(w)
+
+	if len(summary.Footprints) > 0 {
+		fmt.Fprintln(w, "ENTRY\tSECTORS\tSEGMENTS\tPADDED")
+		names := make([]string, 0, len(summary.Footprints))
+		for name := range summary.Footprints {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			footprint := summary.Footprints[name]
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", name, footprint.Sectors, footprint.Segments, zg_common.ByteSize(footprint.PaddedSize))
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "CATEGORY\tFILES\tBYTES")
+	for _, category := range []struct {
+		name  DirFileStatus
+		count DirUploadCount
+	}{
+		{DirFileUploaded, summary.Uploaded},
+		{DirFileSkipped, summary.Skipped},
+		{DirFileReused, summary.Reused},
+		{DirFileFailed, summary.Failed},
+		{DirFilePending, summary.Pending},
+	} {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", category.name, category.count.Files, category.count.Bytes)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "PHASE\tDURATION")
+	fmt.Fprintf(w, "hashing\t%v\n", summary.Phases.Hashing)
+	fmt.Fprintf(w, "submission\t%v\n", summary.Phases.Submission)
+	fmt.Fprintf(w, "pushing\t%v\n", summary.Phases.Pushing)
+	fmt.Fprintf(w, "finalization\t%v\n", summary.Phases.Finalization)
+	fmt.Fprintf(w, "total\t%v\n", summary.Elapsed)
+
+	if len(summary.Excluded) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "EXCLUDED\tTYPE")
+		for _, file := range summary.Excluded {
+			fmt.Fprintf(w, "%v\t%v\n", file.Path, file.Type)
+		}
+	}
+
+	if summary.Failed.Files > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "FAILED\tERROR")
+		for _, file := range summary.Files {
+			if file.Status == DirFileFailed {
+				fmt.Fprintf(w, "%v\t%v\n", file.Path, file.Error)
+			}
+		}
+	}
+
+	return w.Flush()
+}
+
+// dirUploadPhase is a phase to trace in directory upload.
+type dirUploadPhase int
+
+const (
+	dirPhaseHashing dirUploadPhase = iota
+	dirPhaseSubmission
+	dirPhasePushing
+	dirPhaseFinalization
+	numDirUploadPhases
+)
+
+// dirUploadTrace traces the wall time of phases and the existence of files on storage nodes in directory upload,
+// which is passed through context so that the uploading of each file is traced. A nil trace traces nothing.
+type dirUploadTrace struct {
+	mu       sync.Mutex
+	active   [numDirUploadPhases]int // number of ongoing phases
+	since    [numDirUploadPhases]time.Time
+	elapsed  [numDirUploadPhases]time.Duration
+	statuses map[common.Hash]DirFileStatus  // file root -> status observed on the last upload
+	senders  map[common.Hash]common.Address // file root -> account of SenderPool that submitted the file
+}
+
+type dirUploadTraceKey struct{}
+
+func withDirUploadTrace(ctx context.Context, trace *dirUploadTrace) context.Context {
+	return context.WithValue(ctx, dirUploadTraceKey{}, trace)
+}
+
+func dirUploadTraceFromContext(ctx context.Context) *dirUploadTrace {
+	trace, _ := ctx.Value(dirUploadTraceKey{}).(*dirUploadTrace)
+	return trace
+}
+
+// begin begins the phase, and returns a function to end the phase.
+func (trace *dirUploadTrace) begin(phase dirUploadPhase) func() {
+	if trace == nil {
+		return func() {}
+	}
+
+	trace.mu.Lock()
+	if trace.active[phase]++; trace.active[phase] == 1 {
+		trace.since[phase] = time.Now()
+	}
+	trace.mu.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+
+			if trace.active[phase]--; trace.active[phase] == 0 {
+				trace.elapsed[phase] += time.Since(trace.since[phase])
+			}
+		})
+	}
+}
+
+// observe records the status of file by the log entry already available on storage node before uploading.
+func (trace *dirUploadTrace) observe(root common.Hash, info *node.FileInfo, skipTx bool) {
+	if trace == nil {
+		return
+	}
+
+	status := DirFileUploaded
+	if skipTx && info != nil {
+		if info.Finalized {
+			status = DirFileSkipped
+		} else {
+			status = DirFileReused
+		}
+	}
+
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+
+	if trace.statuses == nil {
+		trace.statuses = make(map[common.Hash]DirFileStatus)
+	}
+	trace.statuses[root] = status
+}
+
+// status returns the status of file observed on the last upload, and DirFileUploaded if not observed.
+func (trace *dirUploadTrace) status(root common.Hash) DirFileStatus {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+
+	if status, ok := trace.statuses[root]; ok {
+		return status
+	}
+
+	return DirFileUploaded
+}
+
+// sign records the account of SenderPool that submitted the file.
+func (trace *dirUploadTrace) sign(root common.Hash, sender common.Address) {
+	if trace == nil {
+		return
+	}
+
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+
+	if trace.senders == nil {
+		trace.senders = make(map[common.Hash]common.Address)
+	}
+	trace.senders[root] = sender
+}
+
+// sender returns the account of SenderPool that submitted the file, or zero address if not recorded.
+func (trace *dirUploadTrace) sender(root common.Hash) common.Address {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+
+	return trace.senders[root]
+}
+
+// phases returns the wall time of phases, including ongoing ones.
+func (trace *dirUploadTrace) phases() DirUploadPhases {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+
+	var elapsed [numDirUploadPhases]time.Duration
+	for i := range elapsed {
+		elapsed[i] = trace.elapsed[i]
+		if trace.active[i] > 0 {
+			elapsed[i] += time.Since(trace.since[i])
+		}
+	}
+
+	return DirUploadPhases{
+		Hashing:      elapsed[dirPhaseHashing],
+		Submission:   elapsed[dirPhaseSubmission],
+		Pushing:      elapsed[dirPhasePushing],
+		Finalization: elapsed[dirPhaseFinalization],
+	}
+}
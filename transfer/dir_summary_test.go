@@ -0,0 +1,172 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertSummaryConsistent asserts that counts of summary are consistent with the per-file results.
+func assertSummaryConsistent(t *testing.T, summary *DirUploadSummary) {
+	counts := make(map[DirFileStatus]DirUploadCount)
+	for _, file := range summary.Files {
+		count := counts[file.Status]
+		count.Files++
+		count.Bytes += file.Size
+		counts[file.Status] = count
+	}
+
+	assert.Equal(t, counts[DirFileUploaded], summary.Uploaded)
+	assert.Equal(t, counts[DirFileSkipped], summary.Skipped)
+	assert.Equal(t, counts[DirFileReused], summary.Reused)
+	assert.Equal(t, counts[DirFileFailed], summary.Failed)
+	assert.Equal(t, counts[DirFilePending], summary.Pending)
+}
+
+func TestUploadDirWithSummary(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	clients := network.ZgsClients()
+
+	// segments of file d.txt failed to upload
+	failing := true
+	contentD := []byte("file failed to upload")
+	clients[0].HookCallContext(func(handler providers.CallContextFunc) providers.CallContextFunc {
+		return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+			if method == "zgs_uploadSegmentsByTxSeq" && failing {
+				if segments := args[0].([]node.SegmentWithProof); bytes.HasPrefix(segments[0].Data, contentD) {
+					return errors.New("segment rejected")
+				}
+			}
+
+			return handler(ctx, result, method, args...)
+		}
+	})
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	folder := t.TempDir()
+	contents := map[string][]byte{
+		"a.txt":     []byte("file already uploaded"),
+		"b.txt":     []byte("file submitted by previous run"),
+		"c.txt":     []byte("new file"),
+		"sub/d.txt": contentD,
+		"empty.txt": {},
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub"), 0755))
+	for name, content := range contents {
+		assert.NoError(t, os.WriteFile(filepath.Join(folder, name), content, 0644))
+	}
+
+	_, _, err = uploader.UploadFile(context.Background(), filepath.Join(folder, "a.txt"))
+	assert.NoError(t, err)
+	submit(t, network.Nodes[0], contents["b.txt"])
+
+	// the other files uploaded, but not the directory metadata
+	opt := UploadOption{SkipTx: true}
+	summary, err := uploader.UploadDirWithSummary(context.Background(), folder, opt)
+	assert.ErrorContains(t, err, "failed to upload 1 of 4 files")
+	assertSummaryConsistent(t, summary)
+	assert.Equal(t, common.Hash{}, summary.TxHash)
+	assert.Equal(t, zg_common.CurrentClient(), summary.Agent)
+
+	statuses := make(map[string]DirFileStatus)
+	for _, file := range summary.Files {
+		statuses[file.Path] = file.Status
+	}
+	assert.Equal(t, map[string]DirFileStatus{
+		"/a.txt":     DirFileSkipped,
+		"/b.txt":     DirFileReused,
+		"/c.txt":     DirFileUploaded,
+		"/sub/d.txt": DirFileFailed,
+	}, statuses)
+	assert.Equal(t, DirUploadCount{1, int64(len(contents["c.txt"]))}, summary.Uploaded)
+
+	// footprints returned, aggregated by top-level entries
+	for _, file := range summary.Files {
+		assert.Equal(t, core.StorageFootprint(file.Size), file.Footprint)
+	}
+	var footprint core.Footprint
+	for _, entry := range summary.Footprints {
+		footprint = footprint.Add(entry)
+	}
+	assert.Positive(t, summary.Footprint.Sectors)
+	assert.Equal(t, summary.Footprint, footprint)
+	assert.Equal(t, core.StorageFootprint(int64(len(contentD))), summary.Footprints["sub"])
+	assert.Positive(t, summary.Phases.Hashing)
+	assert.Positive(t, summary.Phases.Submission)
+	assert.Positive(t, summary.Phases.Pushing)
+	assert.GreaterOrEqual(t, summary.Elapsed, summary.Phases.Submission+summary.Phases.Pushing)
+
+	// resumed with the file submitted by the last run
+	failing = false
+	summary, err = uploader.UploadDirWithSummary(context.Background(), folder, opt)
+	assert.NoError(t, err)
+	assertSummaryConsistent(t, summary)
+	assert.NotEqual(t, common.Hash{}, summary.TxHash)
+	assert.Equal(t, 3, summary.Skipped.Files)
+	assert.Equal(t, DirUploadCount{1, int64(len(contentD))}, summary.Reused)
+	assert.Zero(t, summary.Uploaded.Files)
+	assert.Zero(t, summary.Failed.Files)
+}
+
+func TestUploadDirStopOnFileError(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	clients := network.ZgsClients()
+
+	// segments of file a.txt failed to upload
+	contentA := []byte("file failed to upload")
+	clients[0].HookCallContext(func(handler providers.CallContextFunc) providers.CallContextFunc {
+		return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+			if method == "zgs_uploadSegmentsByTxSeq" {
+				if segments := args[0].([]node.SegmentWithProof); bytes.HasPrefix(segments[0].Data, contentA) {
+					return errors.New("segment rejected")
+				}
+			}
+
+			return handler(ctx, result, method, args...)
+		}
+	})
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	folder := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "a.txt"), contentA, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "b.txt"), []byte("new file"), 0644))
+
+	statusesOf := func(summary *DirUploadSummary) map[string]DirFileStatus {
+		statuses := make(map[string]DirFileStatus)
+		for _, file := range summary.Files {
+			statuses[file.Path] = file.Status
+		}
+		return statuses
+	}
+
+	// stopped at the first failure by default
+	summary, err := uploader.UploadDirWithSummary(context.Background(), folder)
+	assert.ErrorContains(t, err, "stopped before uploading the other 1 files")
+	assertSummaryConsistent(t, summary)
+	assert.Equal(t, map[string]DirFileStatus{"/a.txt": DirFileFailed, "/b.txt": DirFilePending}, statusesOf(summary))
+
+	// the other files uploaded if continue on error
+	uploader.WithContinueOnFileError(true)
+	summary, err = uploader.UploadDirWithSummary(context.Background(), folder)
+	assert.ErrorContains(t, err, "failed to upload 1 of 2 files")
+	assertSummaryConsistent(t, summary)
+	assert.Equal(t, map[string]DirFileStatus{"/a.txt": DirFileFailed, "/b.txt": DirFileUploaded}, statusesOf(summary))
+	assert.Equal(t, common.Hash{}, summary.TxHash)
+}
@@ -1,4 +1,6 @@
 // Package transfer defines data structures and functions for transferring data between local and 0g storage.
 // It contains end-to-end interfaces for uploading and downloading data.
 // These interfaces internally utilize parallelism to leverage computational resources.
+//
+// Uploader and Downloader are safe for concurrent use once configured. See the documentation of each type.
 package transfer
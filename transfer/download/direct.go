@@ -0,0 +1,88 @@
+package download
+
+import (
+	"io"
+	"os"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+)
+
+// directAlignment is the alignment of offset, length and memory address of O_DIRECT writes, which is the
+// logical block size of most file systems.
+const directAlignment = 4096
+
+// WriteOption is the option to write the downloading file.
+type WriteOption struct {
+	// NoSync skips fsync before the downloading file is renamed to the target file, which trades crash safety for
+	// throughput: if the host crashes or loses power shortly after download, the target file may be truncated or
+	// corrupted, and should be downloaded again. A crash of the process alone is safe.
+	NoSync bool
+
+	// Direct writes file data with O_DIRECT on linux, so that large downloads do not evict the page cache of
+	// other workloads. The final partial block and metadata are still written with buffered I/O. Falls back to
+	// buffered I/O if not supported, e.g. on tmpfs or other platforms.
+	Direct bool
+}
+
+// directWriter writes block-aligned data with O_DIRECT, and the unaligned remainder with buffered I/O.
+type directWriter struct {
+	direct   io.WriterAt // file opened with O_DIRECT, nil if fallen back to buffered I/O
+	buffered io.WriterAt // file opened with buffered I/O
+	buf      []byte      // aligned buffer to copy data, since O_DIRECT requires aligned memory
+}
+
+// newDirectWriter opens the file with O_DIRECT, or returns nil if not supported.
+func newDirectWriter(path string, buffered *os.File) (*directWriter, *os.File) {
+	file, err := openDirect(path)
+	if err != nil {
+		logrus.WithError(err).WithField("file", path).Debug("Direct I/O not supported, fall back to buffered I/O")
+		return nil, nil
+	}
+
+	return &directWriter{direct: file, buffered: buffered}, file
+}
+
+// alignedBuffer returns a buffer of n bytes, whose memory address is aligned to directAlignment.
+func (writer *directWriter) alignedBuffer(n int) []byte {
+	if cap(writer.buf) < n {
+		raw := make([]byte, n+directAlignment)
+		offset := 0
+		if remainder := int(uintptr(unsafe.Pointer(&raw[0])) % directAlignment); remainder > 0 {
+			offset = directAlignment - remainder
+		}
+		writer.buf = raw[offset : offset+n]
+	}
+
+	return writer.buf[:n]
+}
+
+// WriteAt implements the io.WriterAt interface.
+func (writer *directWriter) WriteAt(p []byte, off int64) (int, error) {
+	if writer.direct == nil || off%directAlignment != 0 {
+		return writer.buffered.WriteAt(p, off)
+	}
+
+	aligned := len(p) / directAlignment * directAlignment
+	if aligned > 0 {
+		buf := writer.alignedBuffer(aligned)
+		copy(buf, p[:aligned])
+
+		if n, err := writer.direct.WriteAt(buf, off); err != nil {
+			// e.g. EINVAL if the file system requires larger alignment, and written with buffered I/O instead
+			logrus.WithError(err).Debug("Failed to write with direct I/O, fall back to buffered I/O")
+			writer.direct = nil
+			return writer.buffered.WriteAt(p, off)
+		} else if n != aligned {
+			return n, io.ErrShortWrite
+		}
+	}
+
+	if aligned == len(p) {
+		return aligned, nil
+	}
+
+	n, err := writer.buffered.WriteAt(p[aligned:], off+int64(aligned))
+
+	return aligned + n, err
+}
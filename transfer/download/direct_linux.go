@@ -0,0 +1,13 @@
+//go:build linux
+
+package download
+
+import (
+	"os"
+	"syscall"
+)
+
+// openDirect opens the file to write with O_DIRECT, which bypasses the page cache.
+func openDirect(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|syscall.O_DIRECT, 0)
+}
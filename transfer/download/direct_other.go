@@ -0,0 +1,14 @@
+//go:build !linux
+
+package download
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// openDirect is not supported except on linux, and falls back to buffered I/O.
+func openDirect(path string) (*os.File, error) {
+	return nil, errors.New("direct I/O not supported")
+}
@@ -0,0 +1,148 @@
+package download
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// segmentSize is the size of data written at a time, which is the segment size of storage nodes.
+const segmentSize = 256 * 1024
+
+// recordingWriter writes into buffer, and records the offset and length of each write.
+type recordingWriter struct {
+	data   []byte
+	writes [][2]int64
+}
+
+func (writer *recordingWriter) WriteAt(p []byte, off int64) (int, error) {
+	writer.writes = append(writer.writes, [2]int64{off, int64(len(p))})
+	copy(writer.data[off:], p)
+	return len(p), nil
+}
+
+func TestDirectWriterAlignment(t *testing.T) {
+	data := make([]byte, 2*directAlignment+100)
+	rand.Read(data)
+
+	direct := &recordingWriter{data: make([]byte, len(data))}
+	buffered := &recordingWriter{data: make([]byte, len(data))}
+	writer := directWriter{direct: direct, buffered: buffered}
+
+	// aligned blocks written directly, and the final partial block buffered
+	n, err := writer.WriteAt(data[:directAlignment+10], 0)
+	assert.NoError(t, err)
+	assert.Equal(t, directAlignment+10, n)
+	assert.Equal(t, [][2]int64{{0, directAlignment}}, direct.writes)
+	assert.Equal(t, [][2]int64{{directAlignment, 10}}, buffered.writes)
+
+	// unaligned offset buffered
+	n, err = writer.WriteAt(data[directAlignment+10:], directAlignment+10)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data)-directAlignment-10, n)
+	assert.Equal(t, 1, len(direct.writes))
+	assert.Equal(t, 2, len(buffered.writes))
+
+	// aligned memory address
+	buf := writer.alignedBuffer(directAlignment)
+	assert.Zero(t, uintptr(unsafe.Pointer(&buf[0]))%directAlignment)
+
+	merged := direct.data
+	copy(merged[directAlignment:], buffered.data[directAlignment:])
+	assert.Equal(t, sha256.Sum256(data), sha256.Sum256(merged))
+}
+
+// writeDownloadingFile downloads data in segments with the specified write option, and returns the sealed file.
+func writeDownloadingFile(tb testing.TB, dir string, data []byte, option WriteOption) string {
+	filename := filepath.Join(dir, "file")
+
+	file, err := CreateDownloadingFileWithOption(filename, testHash, int64(len(data)), option)
+	assert.NoError(tb, err)
+	defer file.Close()
+
+	for offset := 0; offset < len(data); offset += segmentSize {
+		assert.NoError(tb, file.Write(data[offset:min(offset+segmentSize, len(data))]))
+	}
+
+	assert.NoError(tb, file.Seal())
+
+	return filename
+}
+
+func TestDownloadingFileWriteOption(t *testing.T) {
+	// final partial block not aligned
+	data := make([]byte, 3*segmentSize+directAlignment+1234)
+	rand.Read(data)
+
+	for _, option := range []WriteOption{{}, {NoSync: true}, {Direct: true}, {NoSync: true, Direct: true}} {
+		t.Run(fmt.Sprintf("%+v", option), func(t *testing.T) {
+			filename := writeDownloadingFile(t, t.TempDir(), data, option)
+
+			written, err := os.ReadFile(filename)
+			assert.NoError(t, err)
+			assert.Equal(t, sha256.Sum256(data), sha256.Sum256(written))
+		})
+	}
+
+	// resumed with direct I/O
+	filename := filepath.Join(t.TempDir(), "file")
+	file, err := CreateDownloadingFile(filename, testHash, int64(len(data)))
+	assert.NoError(t, err)
+	assert.NoError(t, file.Write(data[:segmentSize]))
+	assert.NoError(t, file.Close())
+
+	file, err = CreateDownloadingFileWithOption(filename, testHash, int64(len(data)), WriteOption{Direct: true})
+	assert.NoError(t, err)
+	assert.NoError(t, file.Write(data[segmentSize:]))
+	assert.NoError(t, file.Seal())
+
+	written, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(data, written))
+}
+
+// BenchmarkDownloadingFile compares write options by throughput, where the coefficient of variation of throughput
+// across iterations approximates the page cache pressure, since buffered writes are throttled once dirty pages
+// exceed the threshold, while direct writes are steady.
+func BenchmarkDownloadingFile(b *testing.B) {
+	data := make([]byte, 64*1024*1024+1234)
+	rand.Read(data)
+
+	options := map[string]WriteOption{
+		"default": {},
+		"no-sync": {NoSync: true},
+		"direct":  {Direct: true},
+	}
+
+	for name, option := range options {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+
+			var throughputs []float64
+			for i := 0; i < b.N; i++ {
+				dir := b.TempDir()
+				start := time.Now()
+				writeDownloadingFile(b, dir, data, option)
+				throughputs = append(throughputs, float64(len(data))/time.Since(start).Seconds())
+			}
+
+			var mean, variance float64
+			for _, v := range throughputs {
+				mean += v / float64(len(throughputs))
+			}
+			for _, v := range throughputs {
+				variance += (v - mean) * (v - mean) / float64(len(throughputs))
+			}
+			b.ReportMetric(math.Sqrt(variance)/mean*100, "cv%")
+		})
+	}
+}
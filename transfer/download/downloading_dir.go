@@ -35,9 +35,15 @@ func CreateDownloadingDir(filename string) (*DownloadingDir, error) {
 	return nil, errors.WithMessage(err, "failed to rename existing directory")
 }
 
+// TmpDir returns the temporary directory to store files during downloading.
+func (directory *DownloadingDir) TmpDir() string {
+	return directory.filename + downloadingFileSuffix
+}
+
 // Add adds a file, directory, or symbolic link to the downloading directory.
 func (directory *DownloadingDir) Add(node *dir.FsNode, relpath string, persist func(path string) error) error {
-	savePath := filepath.Join(directory.filename+downloadingFileSuffix, relpath)
+	// long path prefixed on windows, so that deep files could be created
+	savePath := longPath(filepath.Join(directory.filename+downloadingFileSuffix, relpath))
 
 	// Use the custom persist function if provided
 	if persist != nil {
@@ -68,6 +74,32 @@ func (directory *DownloadingDir) Add(node *dir.FsNode, relpath string, persist f
 	return nil
 }
 
+// RestoreMetadata restores the permission bits and modification time of the file or directory added if recorded
+// in node. Since adding entries updates the modification time of directory, it should be called once entries
+// added, e.g. in reverse order of Add.
+func (directory *DownloadingDir) RestoreMetadata(node *dir.FsNode, relpath string) error {
+	if node.Type == dir.FileTypeSymbolic {
+		return nil
+	}
+
+	savePath := longPath(filepath.Join(directory.filename+downloadingFileSuffix, relpath))
+
+	if node.Mode != 0 {
+		if err := os.Chmod(savePath, os.FileMode(node.Mode).Perm()); err != nil {
+			return errors.WithMessagef(err, "failed to change mode of %s", savePath)
+		}
+	}
+
+	if node.ModTime != 0 {
+		modTime := time.Unix(0, node.ModTime)
+		if err := os.Chtimes(savePath, modTime, modTime); err != nil {
+			return errors.WithMessagef(err, "failed to change timestamps of %s", savePath)
+		}
+	}
+
+	return nil
+}
+
 // Seal finalizes the downloading process by renaming the temporary directory back to its original name.
 // It should be called after all files have been added to the directory.
 func (directory *DownloadingDir) Seal() error {
@@ -1,6 +1,7 @@
 package download
 
 import (
+	"io"
 	"os"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -10,13 +11,31 @@ import (
 const downloadingFileSuffix = ".download"
 
 type DownloadingFile struct {
-	filename   string
-	underlying *os.File
-	metadata   *Metadata
+	filename    string
+	tmpFilename string
+	underlying  *os.File
+	metadata    *Metadata
+
+	option WriteOption
+	direct *os.File    // file opened with O_DIRECT if enabled and supported
+	writer io.WriterAt // writer of file data
 }
 
+// CreateDownloadingFile creates a temporary file to download file of the specified root. If any temporary
+// file was left by a crashed process to download the same file, it will be reused to resume the download.
 func CreateDownloadingFile(filename string, root common.Hash, size int64) (*DownloadingFile, error) {
-	file, err := os.OpenFile(filename+downloadingFileSuffix, os.O_RDWR|os.O_CREATE, 0666)
+	return CreateDownloadingFileWithOption(filename, root, size, WriteOption{})
+}
+
+// CreateDownloadingFileWithOption creates a temporary file to download file of the specified root with the
+// specified write option, see CreateDownloadingFile.
+func CreateDownloadingFileWithOption(filename string, root common.Hash, size int64, option WriteOption) (*DownloadingFile, error) {
+	tmpFilename := tempFileName(filename, root, os.Getpid())
+	if _, err := os.Stat(tmpFilename); os.IsNotExist(err) {
+		adoptOrphanTempFile(filename, root, tmpFilename)
+	}
+
+	file, err := os.OpenFile(tmpFilename, os.O_RDWR|os.O_CREATE, 0666)
 	if err != nil {
 		return nil, errors.WithMessage(err, "Failed to open file")
 	}
@@ -45,7 +64,22 @@ func CreateDownloadingFile(filename string, root common.Hash, size int64) (*Down
 		return nil, errors.Errorf("File size mismatch, expected = %v, actual = %v", size, metadata.Size)
 	}
 
-	return &DownloadingFile{filename, file, metadata}, nil
+	downloading := DownloadingFile{
+		filename:    filename,
+		tmpFilename: tmpFilename,
+		underlying:  file,
+		metadata:    metadata,
+		option:      option,
+		writer:      file,
+	}
+
+	if option.Direct {
+		if writer, direct := newDirectWriter(tmpFilename, file); writer != nil {
+			downloading.direct, downloading.writer = direct, writer
+		}
+	}
+
+	return &downloading, nil
 }
 
 func (file *DownloadingFile) Metadata() *Metadata {
@@ -57,10 +91,18 @@ func (file *DownloadingFile) Write(data []byte) error {
 		return errors.New("File already sealed")
 	}
 
-	return file.metadata.Write(file.underlying, data)
+	return file.metadata.write(file.underlying, file.writer, data)
 }
 
+// Seal completes the download, and renames the temporary file to the target file.
 func (file *DownloadingFile) Seal() error {
+	return file.SealVerified(nil)
+}
+
+// SealVerified completes the download as Seal, but verifies the temporary file by the specified function before
+// renamed, so that the target file is never released if not verified, e.g. downloaded without segment proofs. Once
+// verification failed, the temporary file is removed to download again.
+func (file *DownloadingFile) SealVerified(verify func(tmpFilename string) error) error {
 	if file.metadata.Offset < file.metadata.Size {
 		return errors.Errorf("Download incompleted, offset = %v, size = %v", file.metadata.Offset, file.metadata.Size)
 	}
@@ -69,13 +111,25 @@ func (file *DownloadingFile) Seal() error {
 		return errors.WithMessage(err, "Failed to truncate metadata")
 	}
 
-	if err := file.underlying.Close(); err != nil {
+	// flush to disk before rename, so that the target file is never truncated or corrupted once the host crashed
+	if !file.option.NoSync {
+		if err := file.underlying.Sync(); err != nil {
+			return errors.WithMessage(err, "Failed to sync downloading file")
+		}
+	}
+
+	if err := file.Close(); err != nil {
 		return errors.WithMessage(err, "Failed to close downloading file")
 	}
 
-	file.underlying = nil
+	if verify != nil {
+		if err := verify(file.tmpFilename); err != nil {
+			os.Remove(file.tmpFilename)
+			return err
+		}
+	}
 
-	if err := os.Rename(file.filename+downloadingFileSuffix, file.filename); err != nil {
+	if err := os.Rename(file.tmpFilename, file.filename); err != nil {
 		return errors.WithMessage(err, "Failed to rename downloading file")
 	}
 
@@ -87,5 +141,13 @@ func (file *DownloadingFile) Close() error {
 		return nil
 	}
 
-	return file.underlying.Close()
+	if file.direct != nil {
+		file.direct.Close()
+		file.direct = nil
+	}
+
+	err := file.underlying.Close()
+	file.underlying = nil
+
+	return err
 }
@@ -0,0 +1,238 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/pkg/errors"
+)
+
+// lockFileSuffix is the suffix of default lock file next to the destination.
+const lockFileSuffix = ".lock"
+
+// lockPollInterval is the interval to check lock file when waiting for the lock.
+const lockPollInterval = 100 * time.Millisecond
+
+// ErrLocked is returned when the lock is held by another process.
+var ErrLocked = errors.New("locked by another process")
+
+// LockOption is the option to acquire a file lock.
+type LockOption struct {
+	// Path is the lock file path, by default "<destination>.lock" next to the destination.
+	Path string
+	// Timeout is the max time to wait if the lock is held by another process. Zero value fails immediately.
+	Timeout time.Duration
+	// StaleAfter allows to steal the lock which is not updated in the duration and held by a process that not
+	// alive anymore. Zero value never steals the lock.
+	StaleAfter time.Duration
+}
+
+// Validate checks the option, and returns an error that names the invalid field if any.
+func (opt *LockOption) Validate() error {
+	return zg_common.FirstError(
+		zg_common.RequireNonNegative("Timeout", opt.Timeout),
+		zg_common.RequireNotShorter("Timeout", opt.Timeout, lockPollInterval),
+		zg_common.RequireNonNegative("StaleAfter", opt.StaleAfter),
+	)
+}
+
+// LockInfo is the content of lock file to identify the lock holder.
+type LockInfo struct {
+	Pid  int       `json:"pid"`
+	Time time.Time `json:"time"`
+}
+
+// FileLock is an advisory lock across processes, which is a flock-ed lock file that records the pid and time of
+// holder. The lock file is removed once released, and the one left by a crashed holder is stolen only if stale.
+type FileLock struct {
+	path   string
+	file   *os.File
+	stolen *LockInfo
+	once   sync.Once
+}
+
+// LockPath returns the lock file path for the specified destination.
+func LockPath(destination string, option ...LockOption) string {
+	if len(option) > 0 && option[0].Path != "" {
+		return option[0].Path
+	}
+
+	return destination + lockFileSuffix
+}
+
+// AcquireLock acquires the lock of specified lock file path. If the lock is held by another process, it waits
+// until timeout or fails immediately according to the option, and returns ErrLocked in both cases.
+//
+// Note, the returned lock should be released in defer, so that it is still released upon panic.
+func AcquireLock(ctx context.Context, path string, option ...LockOption) (*FileLock, error) {
+	var opt LockOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+
+	var deadline <-chan time.Time
+	if opt.Timeout > 0 {
+		timer := time.NewTimer(opt.Timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		lock, err := tryLock(path, opt.StaleAfter)
+		if err == nil || !errors.Is(err, ErrLocked) || deadline == nil {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			return nil, errors.WithMessagef(err, "timeout to wait for lock after %v", opt.Timeout)
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// tryLock acquires the flock of lock file, which is released by OS automatically if the holder crashed. Since the
+// flock is held throughout, checking whether the lock file left by a crashed holder is stale and stealing it are
+// never raced by other processes.
+func tryLock(path string, staleAfter time.Duration) (*FileLock, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to open lock file")
+		}
+
+		locked, err := flock(file)
+		if err != nil || !locked {
+			file.Close()
+
+			if err != nil {
+				return nil, errors.WithMessage(err, "failed to flock lock file")
+			}
+
+			holder, _ := readLockInfo(path)
+			return nil, errors.WithMessagef(ErrLocked, "lock file %s held by pid %v since %v", path, holder.Pid, holder.Time)
+		}
+
+		// lock file removed by the holder in between, and retry with the new one
+		if !sameFile(file, path) {
+			file.Close()
+			continue
+		}
+
+		lock, err := takeOver(file, path, staleAfter)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		return lock, nil
+	}
+}
+
+// takeOver writes the lock file flock-ed, which may be left by a crashed holder and stolen only if stale.
+func takeOver(file *os.File, path string, staleAfter time.Duration) (*FileLock, error) {
+	var stolen *LockInfo
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to stat lock file")
+	}
+
+	if stat.Size() > 0 {
+		holder, _ := readLockInfo(path)
+		if !isStale(holder, stat.ModTime(), staleAfter) {
+			return nil, errors.WithMessagef(ErrLocked, "lock file %s held by pid %v since %v", path, holder.Pid, holder.Time)
+		}
+
+		stolen = &holder
+	}
+
+	info := LockInfo{Pid: os.Getpid(), Time: time.Now()}
+	if err = file.Truncate(0); err == nil {
+		if err = json.NewEncoder(file).Encode(info); err == nil {
+			err = file.Sync()
+		}
+	}
+
+	if err != nil {
+		// leave the lock file of crashed holder as it is
+		if stolen == nil {
+			os.Remove(path)
+		}
+
+		return nil, errors.WithMessage(err, "failed to write lock file")
+	}
+
+	return &FileLock{path: path, file: file, stolen: stolen}, nil
+}
+
+// readLockInfo returns the holder of lock file, which may be partially written and treated as unknown holder.
+func readLockInfo(path string) (holder LockInfo, err error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &holder)
+	}
+
+	return holder, err
+}
+
+// isStale returns whether the lock file left by a crashed holder is stale, i.e. not updated in the specified
+// duration and the holder not alive anymore.
+func isStale(holder LockInfo, modTime time.Time, staleAfter time.Duration) bool {
+	if staleAfter <= 0 || time.Since(modTime) < staleAfter {
+		return false
+	}
+
+	return holder.Pid <= 0 || !processAlive(holder.Pid)
+}
+
+// sameFile returns whether the opened file is still the one of specified path.
+func sameFile(file *os.File, path string) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(opened, current)
+}
+
+// Path returns the lock file path.
+func (lock *FileLock) Path() string {
+	return lock.path
+}
+
+// Stolen returns the holder of stale lock that stolen when acquired, or nil if the lock was not held.
+func (lock *FileLock) Stolen() *LockInfo {
+	return lock.stolen
+}
+
+// Release removes the lock file. It is safe to release for multiple times.
+func (lock *FileLock) Release() error {
+	var err error
+
+	lock.once.Do(func() {
+		// remove lock file before unlocking, so that the lock will not be treated as stale in between
+		err = os.Remove(lock.path)
+
+		if closeErr := lock.file.Close(); err == nil {
+			err = closeErr
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,162 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeLockFile(t *testing.T, path string, pid int, modTime time.Time) {
+	data, err := json.Marshal(LockInfo{Pid: pid, Time: modTime})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestLockContended(t *testing.T) {
+	path := LockPath(filepath.Join(t.TempDir(), "dir"))
+	ctx := context.Background()
+
+	lock, err := AcquireLock(ctx, path)
+	assert.NoError(t, err)
+	defer lock.Release()
+
+	var holder LockInfo
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &holder))
+	assert.Equal(t, os.Getpid(), holder.Pid)
+
+	// fail immediately
+	_, err = AcquireLock(ctx, path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// held by alive process and flock-ed, so never stolen
+	_, err = AcquireLock(ctx, path, LockOption{StaleAfter: time.Nanosecond})
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// wait until timeout
+	_, err = AcquireLock(ctx, path, LockOption{Timeout: 200 * time.Millisecond})
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.ErrorContains(t, err, "timeout")
+
+	// wait until released
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		lock.Release()
+	}()
+
+	lock2, err := AcquireLock(ctx, path, LockOption{Timeout: 5 * time.Second})
+	assert.NoError(t, err)
+	assert.Nil(t, lock2.Stolen())
+	assert.NoError(t, lock2.Release())
+	assert.NoError(t, lock2.Release())
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLockStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.lock")
+	ctx := context.Background()
+	old := time.Now().Add(-time.Hour)
+
+	// stealing disabled
+	writeLockFile(t, path, deadPid, old)
+	_, err := AcquireLock(ctx, path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// not stale yet
+	_, err = AcquireLock(ctx, path, LockOption{StaleAfter: 2 * time.Hour})
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// holder alive
+	writeLockFile(t, path, os.Getpid(), old)
+	_, err = AcquireLock(ctx, path, LockOption{StaleAfter: time.Minute})
+	assert.ErrorIs(t, err, ErrLocked)
+
+	// holder crashed
+	writeLockFile(t, path, deadPid, old)
+	lock, err := AcquireLock(ctx, path, LockOption{StaleAfter: time.Minute})
+	assert.NoError(t, err)
+	defer lock.Release()
+
+	assert.Equal(t, path, lock.Path())
+	assert.Equal(t, deadPid, lock.Stolen().Pid)
+}
+
+func TestLockStaleConcurrently(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.lock")
+	writeLockFile(t, path, deadPid, time.Now().Add(-time.Hour))
+
+	// only one of processes that steal the stale lock at the same time succeeds
+	locks := make(chan *FileLock, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(locks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			lock, err := AcquireLock(context.Background(), path, LockOption{StaleAfter: time.Minute})
+			if err == nil {
+				locks <- lock
+			} else {
+				assert.ErrorIs(t, err, ErrLocked)
+			}
+		}()
+	}
+	wg.Wait()
+	close(locks)
+
+	assert.Equal(t, 1, len(locks))
+	lock := <-locks
+	assert.Equal(t, deadPid, lock.Stolen().Pid)
+
+	var holder LockInfo
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &holder))
+	assert.Equal(t, os.Getpid(), holder.Pid)
+
+	// acquired again once released
+	assert.NoError(t, lock.Release())
+	lock, err = AcquireLock(context.Background(), path)
+	assert.NoError(t, err)
+	assert.Nil(t, lock.Stolen())
+	assert.NoError(t, lock.Release())
+}
+
+func TestLockReleasedOnPanic(t *testing.T) {
+	path := LockPath(filepath.Join(t.TempDir(), "dir"))
+
+	func() {
+		defer func() { assert.NotNil(t, recover()) }()
+
+		lock, err := AcquireLock(context.Background(), path)
+		assert.NoError(t, err)
+		defer lock.Release()
+
+		panic("download failed")
+	}()
+
+	lock, err := AcquireLock(context.Background(), path)
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+}
+
+func TestLockOptionValidate(t *testing.T) {
+	path := LockPath(filepath.Join(t.TempDir(), "dir"))
+
+	// never polls again before timeout
+	_, err := AcquireLock(context.Background(), path, LockOption{Timeout: time.Millisecond})
+	assert.ErrorContains(t, err, "invalid option Timeout")
+
+	_, err = AcquireLock(context.Background(), path, LockOption{StaleAfter: -time.Minute})
+	assert.ErrorContains(t, err, "invalid option StaleAfter")
+}
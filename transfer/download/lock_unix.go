@@ -0,0 +1,19 @@
+//go:build !windows
+
+package download
+
+import (
+	"os"
+	"syscall"
+)
+
+// flock places an exclusive flock on the lock file without blocking, which is released by OS automatically if
+// process crashed, and returns false if flock-ed by another process.
+func flock(file *os.File) (bool, error) {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+
+	return err == nil, err
+}
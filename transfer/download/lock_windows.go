@@ -0,0 +1,25 @@
+//go:build windows
+
+package download
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFileOffset is the offset of byte range locked, which is far beyond the content of lock file, so that the
+// holder info could still be read by other processes.
+const lockFileOffset = 0x7fffffff
+
+// flock places an exclusive lock on the lock file without blocking, which is released by OS automatically if
+// process crashed, and returns false if locked by another process.
+func flock(file *os.File) (bool, error) {
+	overlapped := windows.Overlapped{OffsetHigh: lockFileOffset}
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return false, nil
+	}
+
+	return err == nil, err
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package download
+
+// longPath returns the path as is, since there is no MAX_PATH limit except on windows.
+func longPath(path string) string {
+	return path
+}
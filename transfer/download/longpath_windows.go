@@ -0,0 +1,33 @@
+//go:build windows
+
+package download
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxShortPath is the max length of path without long path prefix on windows, which is MAX_PATH (260) minus the
+// length of 8.3 file name (12), since directories are created with the limit too.
+const maxShortPath = 248
+
+// longPath returns the path with long path prefix `\\?\` if too long, so that files of valid manifest could be
+// materialized regardless of MAX_PATH. Note, the path must be absolute and clean with the prefix, since it
+// disables path normalization on windows.
+func longPath(path string) string {
+	if len(path) < maxShortPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	// UNC path, e.g. \\server\share\dir
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+
+	return `\\?\` + abs
+}
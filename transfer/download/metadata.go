@@ -2,6 +2,7 @@ package download
 
 import (
 	"encoding/binary"
+	"io"
 	"os"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -102,13 +103,18 @@ func (md *Metadata) Extend(file *os.File) error {
 }
 
 func (md *Metadata) Write(file *os.File, data []byte) error {
+	return md.write(file, file, data)
+}
+
+// write writes data by the specified writer, e.g. with direct I/O, and updates the offset of metadata in file.
+func (md *Metadata) write(file *os.File, writer io.WriterAt, data []byte) error {
 	// check boundary
 	if md.Offset+int64(len(data)) > md.Size {
 		return errors.Errorf("Written data out of bound, offset = %v, dataLen = %v, fileSize = %v", md.Offset, len(data), md.Size)
 	}
 
 	// write data
-	n, err := file.WriteAt(data, md.Offset)
+	n, err := writer.WriteAt(data, md.Offset)
 	if err != nil {
 		return errors.WithMessage(err, "Failed to write data")
 	}
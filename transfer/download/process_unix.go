@@ -0,0 +1,19 @@
+//go:build !windows
+
+package download
+
+import (
+	"os"
+	"syscall"
+)
+
+// processAlive returns whether the process of the specified pid is still alive.
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package download
+
+// processAlive always assumes the process is alive on windows, so that temporary files of other processes
+// will never be removed.
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,144 @@
+package download
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// tempRootPrefixLen is the number of bytes of file root in temporary file name.
+const tempRootPrefixLen = 8
+
+// tempFileNamePattern matches the temporary file name: <filename>.<root prefix>.<pid>.download
+var tempFileNamePattern = regexp.MustCompile(`^(.+)\.([0-9a-f]{16})\.([0-9]+)\.download$`)
+
+// tempFileName returns the temporary file name to download file of the specified root, which includes
+// the file root and process id, so as to avoid collision between concurrent downloads.
+func tempFileName(filename string, root common.Hash, pid int) string {
+	return fmt.Sprintf("%v.%v.%v%v", filename, hex.EncodeToString(root[:tempRootPrefixLen]), pid, downloadingFileSuffix)
+}
+
+// tempFile is the parsed temporary file name.
+type tempFile struct {
+	filename   string // original file name
+	rootPrefix string // hex encoded prefix of file root
+	pid        int    // process id that created the temporary file
+}
+
+func parseTempFileName(path string) (*tempFile, bool) {
+	matches := tempFileNamePattern.FindStringSubmatch(filepath.Base(path))
+	if matches == nil {
+		return nil, false
+	}
+
+	pid, err := strconv.Atoi(matches[3])
+	if err != nil {
+		return nil, false
+	}
+
+	return &tempFile{
+		filename:   filepath.Join(filepath.Dir(path), matches[1]),
+		rootPrefix: matches[2],
+		pid:        pid,
+	}, true
+}
+
+// isOrphan returns whether the temporary file is created by a process that not alive anymore.
+func (tmp *tempFile) isOrphan() bool {
+	return tmp.pid != os.Getpid() && !processAlive(tmp.pid)
+}
+
+// adoptOrphanTempFile renames an orphan temporary file, which was downloading the same file by a crashed
+// process, to the specified temporary file name, so as to resume the download.
+func adoptOrphanTempFile(filename string, root common.Hash, tmpFilename string) {
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		return
+	}
+
+	rootPrefix := hex.EncodeToString(root[:tempRootPrefixLen])
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		path := filepath.Join(filepath.Dir(filename), entry.Name())
+		tmp, ok := parseTempFileName(path)
+		if !ok || tmp.filename != filename || tmp.rootPrefix != rootPrefix || !tmp.isOrphan() {
+			continue
+		}
+
+		if err := os.Rename(path, tmpFilename); err == nil {
+			return
+		}
+	}
+}
+
+// CleanOrphans removes the stale temporary files under the specified directory recursively, which were
+// created by crashed processes and not modified in the specified duration. Note, only files that follow
+// the temporary file naming convention and contain valid download metadata will be removed.
+func CleanOrphans(dir string, olderThan time.Duration) (removed []string, err error) {
+	deadline := time.Now().Add(-olderThan)
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		tmp, ok := parseTempFileName(path)
+		if !ok || !tmp.isOrphan() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return errors.WithMessagef(err, "failed to stat file %v", path)
+		}
+
+		if info.ModTime().After(deadline) {
+			return nil
+		}
+
+		if !isTempFileOf(path, tmp.rootPrefix) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return errors.WithMessagef(err, "failed to remove file %v", path)
+		}
+
+		removed = append(removed, path)
+
+		return nil
+	})
+
+	return removed, err
+}
+
+// isTempFileOf checks whether the metadata in file matches the root prefix in file name.
+func isTempFileOf(path string, rootPrefix string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	metadata, err := LoadMetadata(file)
+	if err != nil {
+		return false
+	}
+
+	return hex.EncodeToString(metadata.Root[:tempRootPrefixLen]) == rootPrefix
+}
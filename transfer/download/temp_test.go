@@ -0,0 +1,94 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// deadPid is a process id that exceeds the max pid on linux.
+const deadPid = 0x7ffffff0
+
+func createTempFile(t *testing.T, path string, root common.Hash, modTime time.Time) {
+	file, err := os.Create(path)
+	assert.NoError(t, err)
+	assert.NoError(t, NewMetadata(root, 16).Extend(file))
+	assert.NoError(t, file.Close())
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestTempFileNameCollision(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "file")
+	root2 := common.HexToHash("0x1234")
+
+	file1, err := CreateDownloadingFile(filename, testHash, 16)
+	assert.NoError(t, err)
+	defer file1.Close()
+
+	// concurrent download of another root to the same destination
+	file2, err := CreateDownloadingFile(filename, root2, 32)
+	assert.NoError(t, err)
+	defer file2.Close()
+
+	assert.NotEqual(t, file1.tmpFilename, file2.tmpFilename)
+
+	tmp, ok := parseTempFileName(file1.tmpFilename)
+	assert.True(t, ok)
+	assert.Equal(t, filename, tmp.filename)
+	assert.Equal(t, os.Getpid(), tmp.pid)
+}
+
+func TestResumeOrphanTempFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "file")
+	orphan := tempFileName(filename, testHash, deadPid)
+
+	file, err := os.Create(orphan)
+	assert.NoError(t, err)
+	md := NewMetadata(testHash, 16)
+	assert.NoError(t, md.Extend(file))
+	assert.NoError(t, md.Write(file, []byte("hello")))
+	assert.NoError(t, file.Close())
+
+	downloading, err := CreateDownloadingFile(filename, testHash, 16)
+	assert.NoError(t, err)
+	defer downloading.Close()
+
+	assert.Equal(t, int64(5), downloading.Metadata().Offset)
+	assert.NoFileExists(t, orphan)
+}
+
+func TestCleanOrphans(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+
+	orphan := tempFileName(filepath.Join(dir, "orphan"), testHash, deadPid)
+	createTempFile(t, orphan, testHash, old)
+
+	recent := tempFileName(filepath.Join(dir, "recent"), testHash, deadPid)
+	createTempFile(t, recent, testHash, time.Now())
+
+	active := tempFileName(filepath.Join(dir, "active"), testHash, os.Getpid())
+	createTempFile(t, active, testHash, old)
+
+	// root in metadata mismatches with name
+	mismatched := tempFileName(filepath.Join(dir, "mismatched"), common.HexToHash("0x1234"), deadPid)
+	createTempFile(t, mismatched, testHash, old)
+
+	// not follow the naming convention
+	unknown := filepath.Join(dir, "unknown.download")
+	createTempFile(t, unknown, testHash, old)
+
+	removed, err := CleanOrphans(dir, 10*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{orphan}, removed)
+
+	assert.NoFileExists(t, orphan)
+	assert.FileExists(t, recent)
+	assert.FileExists(t, active)
+	assert.FileExists(t, mismatched)
+	assert.FileExists(t, unknown)
+}
@@ -4,13 +4,58 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"time"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/core"
 	"github.com/0glabs/0g-storage-client/transfer/dir"
 	"github.com/0glabs/0g-storage-client/transfer/download"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+// DownloadDirOption is the option to download directory.
+type DownloadDirOption struct {
+	// CleanOrphansOlderThan removes the temporary files left by crashed processes and not modified
+	// in the duration from the downloading directory before downloading. Zero value disables the cleanup.
+	CleanOrphansOlderThan time.Duration
+
+	// Lock is the option of file lock, which prevents concurrent downloads to the same directory across
+	// processes. By default, the lock file is "<filename>.lock" and fails immediately if held by another process.
+	Lock LockOption
+
+	// ExpectedPublisher requires the directory manifest to be signed by the specified address, regardless of the
+	// storage node that served it. By default, the signature of manifest is not verified.
+	ExpectedPublisher *common.Address
+
+	// Limits of depth and relative path length of the untrusted directory manifest, which are dir.DefaultMaxDepth
+	// and dir.DefaultMaxPathLength by default.
+	Limits dir.Limits
+
+	// ManifestAttempts is the max number of storage nodes to fetch the directory manifest from in turn, once a
+	// storage node failed or served a manifest that mismatches the root. It applies only if the downloader
+	// implements NodeDownloaders, and is 3 by default.
+	ManifestAttempts int
+
+	// Overwrite replaces the existing files of the directory that differ from the directory metadata. By default,
+	// download fails with DestinationDiffersError, and files identical are not downloaded again either way.
+	Overwrite bool
+}
+
+// LockOption is the option to acquire a file lock across processes.
+type LockOption = download.LockOption
+
+// ErrLocked is returned when the file lock is held by another process.
+var ErrLocked = download.ErrLocked
+
+// CleanOrphans removes the stale temporary files under the specified directory recursively, which were
+// left by crashed downloads and not modified in the specified duration. Files that are being downloaded
+// by alive processes are never removed.
+func CleanOrphans(dir string, olderThan time.Duration) (removed []string, err error) {
+	return download.CleanOrphans(dir, olderThan)
+}
+
 // DownloadDir downloads files within a directory recursively from the ZeroGStorage network.
 // It first builds a file tree from the directory metadata, then downloads each file in the directory,
 // and finally seals the directory when the download is complete.
@@ -21,30 +66,82 @@ import (
 //   - root:       The root hash of the directory to be downloaded.
 //   - filename:   The name of the local directory to store the downloaded files.
 //   - withProof:  Whether to download the files with a Merkle proof for validation.
+//   - option:     Optional settings to download directory.
 //
 // Returns:
 //   - error: An error if any part of the download or file creation process fails.
-func DownloadDir(ctx context.Context, downloader IDownloader, root, filename string, withProof bool) error {
+func DownloadDir(ctx context.Context, downloader IDownloader, root, filename string, withProof bool, option ...DownloadDirOption) error {
+	var opt DownloadDirOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	// Lock the directory to prevent concurrent downloads by other processes.
+	lock, err := download.AcquireLock(ctx, download.LockPath(filename, opt.Lock), opt.Lock)
+	if err != nil {
+		return errors.WithMessage(err, "failed to lock downloading directory")
+	}
+	defer lock.Release()
+
+	if holder := lock.Stolen(); holder != nil {
+		logrus.WithFields(logrus.Fields{
+			"lock":   lock.Path(),
+			"pid":    holder.Pid,
+			"locked": holder.Time,
+		}).Warn("Stale lock of downloading directory stolen")
+	}
+
 	// Build a file tree from the directory metadata stored on the network.
-	tree, err := BuildFileTree(ctx, downloader, root, withProof)
+	tree, err := BuildFileTree(ctx, downloader, root, withProof, opt)
 	if err != nil {
 		return errors.WithMessage(err, "failed to build file tree")
 	}
 
+	// Flatten the file tree to get a list of nodes (files and directories) and their relative paths.
+	nodes, relpaths := tree.Flatten()
+
+	// Fail fast before the existing directory is moved for downloading, and files of the same size are verified
+	// once moved.
+	if !opt.Overwrite {
+		if err := checkDirSizes(filename, nodes, relpaths); err != nil {
+			return err
+		}
+	}
+
 	// Create or prepare the local directory where files will be downloaded.
 	folder, err := download.CreateDownloadingDir(filename)
 	if err != nil {
 		return errors.WithMessage(err, "failed to prepare downloading directory")
 	}
 
-	// Flatten the file tree to get a list of nodes (files and directories) and their relative paths.
-	nodes, relpaths := tree.Flatten()
+	// Remove the stale temporary files left by crashed downloads if required.
+	if opt.CleanOrphansOlderThan > 0 {
+		removed, err := download.CleanOrphans(folder.TmpDir(), opt.CleanOrphansOlderThan)
+		if err != nil {
+			return errors.WithMessage(err, "failed to clean orphan temporary files")
+		}
+
+		if len(removed) > 0 {
+			logrus.WithField("removed", removed).Info("Orphan temporary files removed")
+		}
+	}
+
 	for i := range nodes {
 		// Only download if it's a file and has content
 		var persist func(string) error
-		if nodes[i].Type == dir.FileTypeFile && nodes[i].Size > 0 {
+		if nodes[i].Embedded() {
+			// Materialize the file straight from the embedded content, which is verified upon decoding.
+			persist = embeddedPersistFunc(nodes[i].Data)
+		} else if nodes[i].Type == dir.FileTypeFile && nodes[i].Size > 0 {
 			// Generate a function to persist the file by downloading it.
 			persist = downloadPersistFunc(downloader, ctx, nodes[i].Root, withProof)
+		} else if nodes[i].Type == dir.FileTypeFile {
+			persist = embeddedPersistFunc(nil)
+		}
+
+		// Never overwrite the existing file that differs unless required.
+		if persist != nil {
+			persist = guardPersistFunc(nodes[i], opt.Overwrite, persist)
 		}
 
 		logrus.WithFields(logrus.Fields{
@@ -58,6 +155,13 @@ func DownloadDir(ctx context.Context, downloader IDownloader, root, filename str
 		}
 	}
 
+	// Restore permissions and timestamps if recorded, where entries are restored before their directory.
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if err := folder.RestoreMetadata(nodes[i], relpaths[i]); err != nil {
+			return errors.WithMessagef(err, "failed to restore metadata of `%s`", relpaths[i])
+		}
+	}
+
 	// Seal the folder by renaming the temporary downloading folder to its final name.
 	if err := folder.Seal(); err != nil {
 		return errors.WithMessage(err, "failed to seal folder")
@@ -75,36 +179,35 @@ func DownloadDir(ctx context.Context, downloader IDownloader, root, filename str
 //   - downloader: The interface responsible for downloading files from the ZeroGStorage network.
 //   - root:       The root hash of the directory's metadata.
 //   - proof:      Whether to download with Merkle proof validation.
+//   - option:     Optional settings, of which only ExpectedPublisher, Limits and ManifestAttempts apply.
 //
 // Returns:
 //   - *dir.FsNode: A pointer to the decoded file tree structure representing the directory.
 //   - error: An error if downloading or decoding the directory metadata fails.
-func BuildFileTree(ctx context.Context, downloader IDownloader, root string, proof bool) (*dir.FsNode, error) {
-	// Create a temporary path to store the downloaded metadata file.
-	metapath := filepath.Join(os.TempDir(), root+".zgdm")
-
-	logrus.WithFields(logrus.Fields{
-		"root":     root,
-		"filename": metapath,
-	}).Debug("Downloading directory metadata to build file tree")
-
-	// Download the directory metadata from the ZeroGStorage network.
-	// If the file already exists, skip re-downloading it.
-	err := downloader.Download(ctx, root, metapath, proof)
-	if err != nil && !errors.Is(err, ErrFileAlreadyExists) {
-		return nil, errors.WithMessage(err, "failed to download directory metadata")
+func BuildFileTree(ctx context.Context, downloader IDownloader, root string, proof bool, option ...DownloadDirOption) (*dir.FsNode, error) {
+	var opt DownloadDirOption
+	if len(option) > 0 {
+		opt = option[0]
 	}
-	defer os.Remove(metapath) // Ensure that the temporary file is deleted after usage.
 
-	// Read the downloaded metadata file from the temporary path.
-	metaData, err := os.ReadFile(metapath)
+	// Download the directory metadata from the ZeroGStorage network, which is verified against the root before
+	// parsed, and fetched again from alternate storage nodes if failed or mismatched.
+	metaData, err := fetchManifest(ctx, downloader, root, proof, opt.ManifestAttempts)
 	if err != nil {
-		return nil, errors.WithMessage(err, "failed to read directory metadata")
+		return nil, err
+	}
+
+	// Verify the publisher of metadata if required.
+	if opt.ExpectedPublisher != nil {
+		if _, err = dir.VerifyPublisher(metaData, *opt.ExpectedPublisher); err != nil {
+			err = errors.WithMessage(err, "failed to verify publisher of directory metadata")
+			return nil, zg_common.WithErrorClass(err, zg_common.ErrorClassVerification)
+		}
 	}
 
 	// Decode the metadata from binary format into an FsNode structure.
 	var tree dir.FsNode
-	if err := tree.UnmarshalBinary(metaData); err != nil {
+	if err := tree.UnmarshalBinaryWithLimits(metaData, opt.Limits); err != nil {
 		return nil, errors.WithMessage(err, "failed to decode directory metadata")
 	}
 
@@ -112,6 +215,52 @@ func BuildFileTree(ctx context.Context, downloader IDownloader, root string, pro
 	return &tree, nil
 }
 
+// BuildTreeSource downloads the directory metadata from the ZeroGStorage network as BuildFileTree does, and returns
+// the TreeSource to list directories page by page. If the manifest uploaded in chunks, only the manifest index is
+// kept in memory along with the locations of directories, and chunks are fetched on demand to list directories,
+// see dir.NewManifestSource. Otherwise, the directory tree is decoded in memory.
+//
+// Since the signature is verified against the whole manifest, chunks are assembled in memory if ExpectedPublisher
+// specified in option.
+func BuildTreeSource(ctx context.Context, downloader IDownloader, root string, proof bool, option ...DownloadDirOption) (dir.PagedTreeSource, error) {
+	var opt DownloadDirOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	if opt.ExpectedPublisher == nil {
+		data, err := fetchManifestFile(ctx, downloader, root, proof, opt.ManifestAttempts)
+		if err != nil {
+			return nil, err
+		}
+
+		if dir.IsManifestIndex(data) {
+			var index dir.ManifestIndex
+			if err := index.UnmarshalBinary(data); err != nil {
+				return nil, errors.WithMessage(err, "failed to decode manifest index")
+			}
+
+			return dir.NewManifestSource(ctx, &index, func(ctx context.Context, chunk common.Hash) ([]byte, error) {
+				return fetchManifestFile(ctx, downloader, chunk.Hex(), proof, opt.ManifestAttempts)
+			}, opt.Limits)
+		}
+
+		var tree dir.FsNode
+		if err := tree.UnmarshalBinaryWithLimits(data, opt.Limits); err != nil {
+			return nil, errors.WithMessage(err, "failed to decode directory metadata")
+		}
+
+		return dir.TreeSourceOf(&tree), nil
+	}
+
+	tree, err := BuildFileTree(ctx, downloader, root, proof, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return dir.TreeSourceOf(tree), nil
+}
+
 // downloadPersistFunc is a helper function that returns a function that downloads a file from ZeroGStorage network.
 func downloadPersistFunc(downloader IDownloader, ctx context.Context, root string, withProof bool) func(string) error {
 	return func(path string) error {
@@ -122,3 +271,98 @@ func downloadPersistFunc(downloader IDownloader, ctx context.Context, root strin
 		return nil
 	}
 }
+
+// checkDirSizes checks the sizes of existing files in the directory to download, and returns DestinationDiffersError
+// if any file differs in size.
+func checkDirSizes(filename string, nodes []*dir.FsNode, relpaths []string) error {
+	for i, node := range nodes {
+		if node.Type != dir.FileTypeFile {
+			continue
+		}
+
+		path := filepath.Join(filename, relpaths[i])
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.Size() == node.Size {
+			continue
+		}
+
+		return &DestinationDiffersError{
+			Path:          path,
+			ExpectedSize:  node.Size,
+			ActualSize:    info.Size(),
+			DiffersBefore: min(node.Size, info.Size()),
+		}
+	}
+
+	return nil
+}
+
+// guardPersistFunc is a helper function that returns a function that persists the file only if not exists, or
+// replaces the existing file that differs if overwrite specified.
+func guardPersistFunc(node *dir.FsNode, overwrite bool, persist func(string) error) func(string) error {
+	return func(path string) error {
+		err := checkDestination(path, node.Size, func(file *core.File) (int64, error) {
+			if node.Embedded() {
+				return firstDifference(file, node.Data)
+			}
+
+			fileRoot, err := core.MerkleRootData(file)
+			if err != nil {
+				return 0, errors.WithMessage(err, "failed to calculate file merkle root")
+			}
+
+			if fileRoot == common.HexToHash(node.Root) {
+				return -1, nil
+			}
+
+			return node.Size, nil
+		})
+
+		if errors.Is(err, ErrFileAlreadyExists) {
+			return nil
+		}
+
+		var differs *DestinationDiffersError
+		if overwrite && errors.As(err, &differs) {
+			logrus.WithFields(logrus.Fields{
+				"file":         path,
+				"expectedSize": differs.ExpectedSize,
+				"actualSize":   differs.ActualSize,
+			}).Warn("Existing file differs, and will be overwritten")
+			err = os.Remove(path)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return persist(path)
+	}
+}
+
+// firstDifference returns the offset of the first byte of file that differs from data of the same size, or -1 if
+// identical.
+func firstDifference(file *core.File, data []byte) (int64, error) {
+	buf := make([]byte, len(data))
+	if _, err := file.Read(buf, 0); err != nil {
+		return 0, errors.WithMessage(err, "failed to read file")
+	}
+
+	for i := range data {
+		if buf[i] != data[i] {
+			return int64(i), nil
+		}
+	}
+
+	return -1, nil
+}
+
+// embeddedPersistFunc is a helper function that returns a function that writes the embedded file content.
+func embeddedPersistFunc(data []byte) func(string) error {
+	return func(path string) error {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return errors.WithMessage(err, "failed to write embedded file")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// memDirDownloader serves file content from memory by root hash.
+type memDirDownloader map[string][]byte
+
+func (d memDirDownloader) add(t *testing.T, content []byte) string {
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(t, err)
+	d[tree.Root().Hex()] = content
+	return tree.Root().Hex()
+}
+
+func (d memDirDownloader) Download(ctx context.Context, root, filename string, withProof bool) error {
+	content, ok := d[root]
+	if !ok {
+		return os.ErrNotExist
+	}
+	return os.WriteFile(filename, content, 0644)
+}
+
+func (d memDirDownloader) DownloadFragments(ctx context.Context, roots []string, filename string, withProof bool) error {
+	return nil
+}
+
+func TestBuildFileTreeExpectedPublisher(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+	publisher := crypto.PubkeyToAddress(key.PublicKey)
+
+	tree := dir.NewDirFsNode("root", []*dir.FsNode{dir.NewSymbolicFsNode("link", "target")})
+	signed, err := dir.SignManifest(tree, key)
+	assert.NoError(t, err)
+	unsigned, err := dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+
+	downloader := memDirDownloader{}
+	signedRoot, unsignedRoot := downloader.add(t, signed), downloader.add(t, unsigned)
+
+	// verified
+	built, err := BuildFileTree(context.Background(), downloader, signedRoot, true, DownloadDirOption{ExpectedPublisher: &publisher})
+	assert.NoError(t, err)
+	assert.True(t, tree.Equal(built))
+
+	// not verified by default
+	_, err = BuildFileTree(context.Background(), downloader, unsignedRoot, true)
+	assert.NoError(t, err)
+
+	// unsigned or signed by others
+	_, err = BuildFileTree(context.Background(), downloader, unsignedRoot, true, DownloadDirOption{ExpectedPublisher: &publisher})
+	assert.ErrorIs(t, err, dir.ErrManifestUnsigned)
+	assert.Equal(t, zg_common.ErrorClassVerification, zg_common.ClassOf(err))
+
+	other := common.HexToAddress("0x01")
+	_, err = BuildFileTree(context.Background(), downloader, signedRoot, true, DownloadDirOption{ExpectedPublisher: &other})
+	assert.ErrorIs(t, err, dir.ErrPublisherMismatch)
+}
+
+func TestDownloadDirMetadata(t *testing.T) {
+	folder := t.TempDir()
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+	assert.NoError(t, os.Mkdir(filepath.Join(folder, "bin"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "bin", "run.sh"), []byte("#!/bin/sh"), 0644))
+	assert.NoError(t, os.Chmod(filepath.Join(folder, "bin", "run.sh"), 0750))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "empty"), nil, 0644))
+	assert.NoError(t, os.Chmod(filepath.Join(folder, "empty"), 0600))
+	for _, name := range []string{"bin/run.sh", "empty", "bin", "."} {
+		assert.NoError(t, os.Chtimes(filepath.Join(folder, name), modTime, modTime))
+	}
+
+	tree, err := dir.BuildFileTreeWithOption(folder, dir.BuildOption{}.WithMetadata())
+	assert.NoError(t, err)
+	manifest, err := dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+
+	downloader := memDirDownloader{}
+	downloader.add(t, []byte("#!/bin/sh"))
+	root := downloader.add(t, manifest)
+
+	target := filepath.Join(t.TempDir(), "target")
+	assert.NoError(t, DownloadDir(context.Background(), downloader, root, target, true))
+
+	for name, mode := range map[string]os.FileMode{"bin/run.sh": 0750, "empty": 0600, "bin": 0755} {
+		info, err := os.Stat(filepath.Join(target, name))
+		assert.NoError(t, err)
+		assert.Equal(t, mode, info.Mode().Perm(), name)
+		assert.True(t, modTime.Equal(info.ModTime()), name)
+	}
+
+	info, err := os.Stat(target)
+	assert.NoError(t, err)
+	assert.True(t, modTime.Equal(info.ModTime()))
+}
@@ -3,7 +3,11 @@ package transfer
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+	"sync/atomic"
 
+	zg_common "github.com/0glabs/0g-storage-client/common"
 	"github.com/0glabs/0g-storage-client/common/parallel"
 	"github.com/0glabs/0g-storage-client/common/shard"
 	"github.com/0glabs/0g-storage-client/core"
@@ -26,22 +30,55 @@ type segmentDownloader struct {
 	offset uint64
 
 	withProof bool
+	fallback  VerificationFallback
+	noProofs  []atomic.Bool // storage nodes that do not serve segment proofs, by index
+	fellBack  atomic.Bool   // any segment downloaded without proof, though required
 
 	numChunks uint64
 
+	preferred int   // index of storage node to download segments from at first, -1 if not specified
+	order     []int // indices of storage nodes to try in order for every segment, nil to spread across routines
+
 	routines int
+	pool     *parallel.PriorityPool
 
-	logger *logrus.Logger
+	logger   *logrus.Logger
+	warnings *Warnings
+	progress *progressTracker // segments downloaded to report, nil if not reported
 }
 
 var _ parallel.Interface = (*segmentDownloader)(nil)
 
-func newSegmentDownloader(downloader *Downloader, info *node.FileInfo, shardConfigs []*shard.ShardConfig, file *download.DownloadingFile, withProof bool) (*segmentDownloader, error) {
+func newSegmentDownloader(ctx context.Context, downloader *Downloader, info *node.FileInfo, shardConfigs []*shard.ShardConfig, file *download.DownloadingFile, withProof bool) (*segmentDownloader, error) {
 	startSegmentIndex := info.Tx.StartEntryIndex / core.DefaultSegmentMaxChunks
 	endSegmentIndex := (info.Tx.StartEntryIndex + core.NumSplits(int64(info.Tx.Size), core.DefaultChunkSize) - 1) / core.DefaultSegmentMaxChunks
 
 	offset := file.Metadata().Offset / core.DefaultSegmentSize
 
+	preferred := -1
+	for i, client := range downloader.clients {
+		if len(downloader.preferred) > 0 && client.URL() == downloader.preferred {
+			preferred = i
+			break
+		}
+	}
+
+	// storage node known to hold the file uploaded recently, while the others may not be synced yet
+	if len(downloader.preferred) == 0 {
+		for _, url := range downloader.hints.Nodes(info.Tx.DataMerkleRoot) {
+			if preferred = slices.IndexFunc(downloader.clients, func(client *node.ZgsClient) bool {
+				return client.URL() == url
+			}); preferred >= 0 {
+				break
+			}
+		}
+	}
+
+	var order []int
+	if downloader.consistent {
+		order = consistentOrder(info.Tx.DataMerkleRoot, downloader.clients, preferred)
+	}
+
 	return &segmentDownloader{
 		clients:      downloader.clients,
 		shardConfigs: shardConfigs,
@@ -54,22 +91,78 @@ func newSegmentDownloader(downloader *Downloader, info *node.FileInfo, shardConf
 		offset: uint64(offset),
 
 		withProof: withProof,
+		fallback:  downloader.fallback,
+		noProofs:  make([]atomic.Bool, len(downloader.clients)),
 
 		numChunks: core.NumSplits(int64(info.Tx.Size), core.DefaultChunkSize),
 
+		preferred: preferred,
+		order:     order,
+
 		routines: downloader.routines,
+		pool:     downloader.pool,
 
-		logger: downloader.logger,
+		logger:   downloader.logger,
+		warnings: downloader.warnings,
+		progress: progressTrackerFromContext(ctx),
 	}, nil
 }
 
+// nodeIndex returns the index of storage node to try for the i-th time in routine, where the preferred storage
+// node is tried at first, and the others in round robin, or in consistent order if specified.
+func (downloader *segmentDownloader) nodeIndex(routine, i int) int {
+	if downloader.order != nil {
+		return downloader.order[i]
+	}
+
+	n := len(downloader.shardConfigs)
+	if downloader.preferred < 0 {
+		return (routine + i) % n
+	}
+
+	if i == 0 {
+		return downloader.preferred
+	}
+
+	index := (routine + i - 1) % (n - 1)
+	if index >= downloader.preferred {
+		index++
+	}
+
+	return index
+}
+
+// consistentOrder returns the indices of storage nodes in rendezvous order for the file root, see
+// shard.RendezvousScore, where the preferred storage node is tried at first if specified.
+func consistentOrder(root common.Hash, clients []*node.ZgsClient, preferred int) []int {
+	scores := make([]uint64, len(clients))
+	order := make([]int, len(clients))
+	for i, client := range clients {
+		scores[i] = shard.RendezvousScore(root, client.URL())
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if (order[i] == preferred) != (order[j] == preferred) {
+			return order[i] == preferred
+		}
+
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	return order
+}
+
 // Download downloads segments in parallel.
 func (downloader *segmentDownloader) Download(ctx context.Context) error {
-	numTasks := downloader.endSegmentIndex - downloader.startSegmentIndex + 1 - downloader.offset
+	numSegments := downloader.endSegmentIndex - downloader.startSegmentIndex + 1
+	numTasks := numSegments - downloader.offset
+	downloader.progress.begin(numSegments, downloader.offset)
+
 	option := parallel.SerialOption{
 		Routines: downloader.routines,
 	}
-	return parallel.Serial(ctx, downloader, int(numTasks), option)
+	return parallel.Serial(ctx, withPool(downloader, downloader.pool), int(numTasks), option)
 }
 
 // ParallelDo implements the parallel.Interface interface.
@@ -85,19 +178,30 @@ func (downloader *segmentDownloader) ParallelDo(ctx context.Context, routine, ta
 	root := downloader.file.Metadata().Root
 
 	var (
-		segment []byte
-		err     error
+		segment     []byte
+		err         error
+		unsupported bool // any storage node does not serve segment proofs
 	)
 
 	for i := 0; i < len(downloader.shardConfigs); i += 1 {
-		nodeIndex := (routine + i) % len(downloader.shardConfigs)
+		nodeIndex := downloader.nodeIndex(routine, i)
 		if (downloader.startSegmentIndex+segmentIndex)%downloader.shardConfigs[nodeIndex].NumShard != downloader.shardConfigs[nodeIndex].ShardId {
 			continue
 		}
 		// try download from current node
-		if downloader.withProof {
-			segment, err = downloader.downloadWithProof(ctx, downloader.clients[nodeIndex], downloader.txSeq, root, startIndex, endIndex)
-		} else {
+		withProof := downloader.withProof && !downloader.noProofs[nodeIndex].Load()
+		if withProof {
+			segment, err = downloader.downloadWithProof(ctx, downloader.clients[nodeIndex], downloader.txSeq, root, startIndex)
+			if node.IsMethodNotFound(err) {
+				unsupported = true
+				if downloader.fallback == VerificationFallbackWholeFile {
+					downloader.disableProofs(nodeIndex)
+					withProof = false
+				}
+			}
+		}
+
+		if !withProof {
 			segment, err = downloader.clients[nodeIndex].DownloadSegmentByTxSeq(ctx, downloader.txSeq, startIndex, endIndex)
 		}
 
@@ -107,6 +211,11 @@ func (downloader *segmentDownloader) ParallelDo(ctx context.Context, routine, ta
 				"segment":    fmt.Sprintf("%v/(%v-%v)", downloader.startSegmentIndex+segmentIndex, downloader.startSegmentIndex, downloader.endSegmentIndex),
 				"chunks":     fmt.Sprintf("[%v, %v)", startIndex, endIndex),
 			}).Error("Failed to download segment")
+			downloader.warnings.Add(WarningSegmentRerouted, "Failed to download segment, try another storage node", map[string]interface{}{
+				"node":         downloader.clients[nodeIndex].URL(),
+				"segmentIndex": downloader.startSegmentIndex + segmentIndex,
+				"error":        err.Error(),
+			})
 			continue
 		}
 		if segment == nil {
@@ -133,6 +242,10 @@ func (downloader *segmentDownloader) ParallelDo(ctx context.Context, routine, ta
 			}).Debug("Succeeded to download segment")
 		}
 
+		if downloader.withProof && !withProof {
+			downloader.fellBack.Store(true)
+		}
+
 		// remove paddings for the last chunk
 		if downloader.startSegmentIndex+segmentIndex == downloader.endSegmentIndex {
 			fileSize := downloader.file.Metadata().Size
@@ -143,15 +256,38 @@ func (downloader *segmentDownloader) ParallelDo(ctx context.Context, routine, ta
 		}
 		return segment, nil
 	}
+	if unsupported && downloader.fallback != VerificationFallbackWholeFile {
+		return nil, errors.WithMessagef(ErrProofsUnsupported, "failed to download segment %v", segmentIndex)
+	}
+
 	return nil, fmt.Errorf("failed to download segment %v", segmentIndex)
 }
 
+// disableProofs downloads segments without proof from the storage node that does not serve segment proofs.
+func (downloader *segmentDownloader) disableProofs(nodeIndex int) {
+	if downloader.noProofs[nodeIndex].Swap(true) {
+		return
+	}
+
+	downloader.logger.WithField("node", downloader.clients[nodeIndex].URL()).Warn("Segment proofs not supported, fall back to verify the whole file")
+	downloader.warnings.Add(WarningProofsUnsupported, "Segment proofs not supported, fall back to verify the whole file", map[string]interface{}{
+		"node": downloader.clients[nodeIndex].URL(),
+	})
+}
+
 // ParallelCollect implements the parallel.Interface interface.
 func (downloader *segmentDownloader) ParallelCollect(result *parallel.Result) error {
-	return downloader.file.Write(result.Value.([]byte))
+	segment := result.Value.([]byte)
+	if err := downloader.file.Write(segment); err != nil {
+		return err
+	}
+
+	downloader.progress.add(1, int64(len(segment)))
+
+	return nil
 }
 
-func (downloader *segmentDownloader) downloadWithProof(ctx context.Context, client *node.ZgsClient, txSeq uint64, root common.Hash, startIndex, endIndex uint64) ([]byte, error) {
+func (downloader *segmentDownloader) downloadWithProof(ctx context.Context, client *node.ZgsClient, txSeq uint64, root common.Hash, startIndex uint64) ([]byte, error) {
 	segmentIndex := startIndex / core.DefaultSegmentMaxChunks
 
 	segment, err := client.DownloadSegmentWithProofByTxSeq(ctx, txSeq, segmentIndex)
@@ -162,14 +298,30 @@ func (downloader *segmentDownloader) downloadWithProof(ctx context.Context, clie
 		return nil, nil
 	}
 
-	if expectedDataLen := (endIndex - startIndex) * core.DefaultChunkSize; int(expectedDataLen) != len(segment.Data) {
-		return nil, errors.Errorf("Downloaded data length mismatch, expected = %v, actual = %v", expectedDataLen, len(segment.Data))
+	if err := validateSegment(root, downloader.file.Metadata().Size, segmentIndex, segment); err != nil {
+		return nil, err
 	}
 
-	segmentRootHash, numSegmentsFlowPadded := core.PaddedSegmentRoot(segmentIndex, segment.Data, downloader.file.Metadata().Size)
+	return segment.Data, nil
+}
+
+// validateSegment checks the data length and merkle proof of segment downloaded from storage node, where
+// segment index is relative to the file.
+func validateSegment(root common.Hash, fileSize int64, segmentIndex uint64, segment *node.SegmentWithProof) error {
+	_, expectedDataLen, err := core.SegmentToByteRange(segmentIndex, fileSize)
+	if err != nil {
+		return zg_common.WithErrorClass(err, zg_common.ErrorClassVerification)
+	}
+
+	if int(expectedDataLen) != len(segment.Data) {
+		err := errors.Errorf("Downloaded data length mismatch, expected = %v, actual = %v", expectedDataLen, len(segment.Data))
+		return zg_common.WithErrorClass(err, zg_common.ErrorClassVerification)
+	}
+
+	segmentRootHash, numSegmentsFlowPadded := core.PaddedSegmentRoot(segmentIndex, segment.Data, fileSize)
 	if err := segment.Proof.ValidateHash(root, segmentRootHash, segmentIndex, numSegmentsFlowPadded); err != nil {
-		return nil, errors.WithMessage(err, "Failed to validate proof")
+		return zg_common.WithErrorClass(errors.WithMessage(err, "Failed to validate proof"), zg_common.ErrorClassVerification)
 	}
 
-	return segment.Data, nil
+	return nil
 }
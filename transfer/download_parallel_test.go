@@ -0,0 +1,50 @@
+package transfer
+
+import (
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsistentOrder(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{NumShard: 1}, shard.ShardConfig{NumShard: 1},
+		shard.ShardConfig{NumShard: 1}, shard.ShardConfig{NumShard: 1})
+	root := common.HexToHash("0x1234")
+
+	urlsInOrder := func(preferred int) []string {
+		clients := network.ZgsClients()
+		var urls []string
+		for _, i := range consistentOrder(root, clients, preferred) {
+			urls = append(urls, clients[i].URL())
+		}
+		return urls
+	}
+
+	// stable across instantiations
+	expected := urlsInOrder(-1)
+	assert.Equal(t, 4, len(expected))
+	assert.Equal(t, expected, urlsInOrder(-1))
+
+	// order of the others kept when one removed
+	clients := network.ZgsClients()
+	var removed int
+	for i, client := range clients {
+		if client.URL() == expected[0] {
+			removed = i
+		}
+	}
+	remaining := append(clients[:removed:removed], clients[removed+1:]...)
+	var urls []string
+	for _, i := range consistentOrder(root, remaining, -1) {
+		urls = append(urls, remaining[i].URL())
+	}
+	assert.Equal(t, expected[1:], urls)
+
+	// preferred storage node tried at first
+	urls = urlsInOrder(2)
+	assert.Equal(t, network.ZgsClients()[2].URL(), urls[0])
+	assert.ElementsMatch(t, expected, urls)
+}
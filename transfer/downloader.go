@@ -8,6 +8,7 @@ import (
 	"runtime"
 
 	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/parallel"
 	"github.com/0glabs/0g-storage-client/core"
 	"github.com/0glabs/0g-storage-client/node"
 	"github.com/0glabs/0g-storage-client/transfer/download"
@@ -28,12 +29,29 @@ type IDownloader interface {
 }
 
 // Downloader downloader to download file to storage nodes
+//
+// Downloader is safe for concurrent downloads once configured, but the With* setters should be called before it
+// is shared across goroutines.
 type Downloader struct {
 	clients []*node.ZgsClient
 
 	routines int
-
-	logger *logrus.Logger
+	profile  *Profile
+	pool     *parallel.PriorityPool // shared pool to download segments, nil if not specified
+
+	verifyAgainstChain SubmissionQuerier // cross-check file info with the submission on chain if specified
+	preferred          string            // URL of storage node to download segments from at first if any
+	consistent         bool              // try storage nodes in rendezvous order of file root for every segment
+	hints              *UploadHints      // storage nodes holding files uploaded recently, preferred if any
+	fallback           VerificationFallback
+	writeOption        download.WriteOption
+	overwrite          bool             // replace the existing destination file that differs
+	onProgress         func(p Progress) // serialized callback to report the progress of downloads, nil if not reported
+
+	logger   *logrus.Logger
+	warnings *Warnings
+
+	lifecycle
 }
 
 // NewDownloader Initialize a new downloader.
@@ -42,8 +60,9 @@ func NewDownloader(clients []*node.ZgsClient, opts ...zg_common.LogOption) (*Dow
 		return nil, errors.New("storage node not specified")
 	}
 	downloader := &Downloader{
-		clients: clients,
-		logger:  zg_common.NewLogger(opts...),
+		clients:  clients,
+		logger:   zg_common.NewLogger(opts...),
+		warnings: NewWarnings(defaultMaxWarnings),
 	}
 	downloader.routines = runtime.GOMAXPROCS(0)
 	return downloader, nil
@@ -54,10 +73,128 @@ func (downloader *Downloader) WithRoutines(routines int) *Downloader {
 	return downloader
 }
 
+// WithProfile applies the transfer profile to downloader. Note, the merkle proof requirement of profile is
+// not applied automatically, since it is specified for each download.
+func (downloader *Downloader) WithProfile(profile Profile) *Downloader {
+	downloader.profile = &profile
+	return downloader.WithRoutines(profile.Routines)
+}
+
+// Profile returns the effective transfer profile, or nil if not specified.
+func (downloader *Downloader) Profile() *Profile {
+	if downloader.profile == nil {
+		return nil
+	}
+
+	profile := *downloader.profile
+	profile.Routines = downloader.routines
+
+	return &profile
+}
+
+// WithPool sets the pool shared with other transfers to download segments, so that downloads are scheduled by
+// the priority of context, see WithPriority. Note, the number of routines still limits each download.
+func (downloader *Downloader) WithPool(pool *parallel.PriorityPool) *Downloader {
+	downloader.pool = pool
+	return downloader
+}
+
+// WithVerifyAgainstChain enables to cross-check the root and size of file reported by storage nodes with the
+// submission on chain before downloading, which requires an extra read of the flow contract. Download fails
+// with ErrChainMismatch if any storage node disagrees with the chain. Passes nil to disable, which is default.
+func (downloader *Downloader) WithVerifyAgainstChain(querier SubmissionQuerier) *Downloader {
+	downloader.verifyAgainstChain = querier
+	return downloader
+}
+
+// WithPreferred sets the storage node to download segments from at first, e.g. the storage node co-located, and
+// other storage nodes are tried only if it does not hold the segment or fails. URL should be one of the storage
+// nodes of downloader, and passes empty to disable, which is default.
+func (downloader *Downloader) WithPreferred(url string) *Downloader {
+	downloader.preferred = url
+	return downloader
+}
+
+// WithUploadHints sets the hint store of files uploaded recently, so that the storage node known to hold the file is
+// tried at first to download segments, see UploadHints, unless specified by WithPreferred. Passes nil to disable,
+// which is default.
+func (downloader *Downloader) WithUploadHints(hints *UploadHints) *Downloader {
+	downloader.hints = hints
+	return downloader
+}
+
+// WithConsistentNodes sets whether to try storage nodes in rendezvous order of the file root for every segment,
+// see shard.RendezvousScore, so that the same file is always downloaded from the same storage nodes across
+// processes to keep their caches hot, while the other storage nodes are tried only if failed. By default, segments
+// are spread across storage nodes by routines. The preferred storage node if any is still tried at first.
+func (downloader *Downloader) WithConsistentNodes(enabled bool) *Downloader {
+	downloader.consistent = enabled
+	return downloader
+}
+
+// WithVerificationFallback sets the policy to download with proof from storage nodes that do not serve segment
+// proofs, see VerificationFallback. By default, download fails with ErrProofsUnsupported.
+func (downloader *Downloader) WithVerificationFallback(fallback VerificationFallback) *Downloader {
+	downloader.fallback = fallback
+	return downloader
+}
+
+// WithWriteOption sets the option to write downloaded files, e.g. to skip fsync or bypass the page cache for
+// high-throughput downloads, see download.WriteOption. By default, files are written with buffered I/O and
+// flushed to disk once downloaded.
+func (downloader *Downloader) WithWriteOption(option download.WriteOption) *Downloader {
+	downloader.writeOption = option
+	return downloader
+}
+
+// WithOverwrite sets whether to replace the existing destination file that differs from the content to download.
+// By default, download fails with DestinationDiffersError, so that a download pointed at a wrong destination does
+// not destroy data. Either way, the existing file of the same size and merkle root is not downloaded again.
+func (downloader *Downloader) WithOverwrite(overwrite bool) *Downloader {
+	downloader.overwrite = overwrite
+	return downloader
+}
+
+// WithProgress sets the callback to report the progress of each file downloaded, including directory manifest and
+// fragments which are identified by Progress.Root. Segments downloaded are reported at most once per
+// dir.DefaultProgressInterval, and the final progress is reported with ProgressCompleted once a file downloaded and
+// validated, even if completed in a single segment. Callbacks are serialized, so that need not be synchronized.
+// Passes nil to disable, which is default.
+func (downloader *Downloader) WithProgress(onProgress func(p Progress)) *Downloader {
+	downloader.onProgress = serializeProgress(onProgress)
+	return downloader
+}
+
+// WithWarningSink sets the callback to receive warnings as soon as they happen during downloading.
+func (downloader *Downloader) WithWarningSink(sink WarningSink) *Downloader {
+	downloader.warnings.SetSink(sink)
+	return downloader
+}
+
+// Warnings returns the non-fatal issues aggregated during downloading.
+func (downloader *Downloader) Warnings() *Warnings {
+	return downloader.warnings
+}
+
+// WithClientsOwned indicates whether the storage node clients are owned by downloader, which will be closed
+// along with downloader. By default, clients are owned by caller and will not be closed by downloader.
+func (downloader *Downloader) WithClientsOwned(owned bool) *Downloader {
+	downloader.ownsClients = owned
+	return downloader
+}
+
+// Close stops accepting new downloads, waits for in-flight downloads to complete, and closes the storage node
+// clients if owned. It is safe to close for multiple times.
+func (downloader *Downloader) Close() error {
+	return downloader.close(downloader.clients)
+}
+
+// DownloadFragments downloads fragments of file in order, and concatenates them into the destination file, which
+// is replaced if already exists only if overwrite specified, see WithOverwrite.
 func (downloader *Downloader) DownloadFragments(ctx context.Context, roots []string, filename string, withProof bool) error {
-	outFile, err := os.Create(filename)
+	outFile, err := CreateFragmentsFile(filename, downloader.overwrite)
 	if err != nil {
-		return errors.WithMessage(err, "failed to create output file")
+		return err
 	}
 	defer outFile.Close()
 
@@ -88,33 +225,61 @@ func (downloader *Downloader) DownloadFragments(ctx context.Context, roots []str
 
 // Download download data from storage nodes.
 func (downloader *Downloader) Download(ctx context.Context, root, filename string, withProof bool) error {
+	_, err := downloader.DownloadWithResult(ctx, root, filename, withProof)
+	return err
+}
+
+// DownloadWithResult downloads data from storage nodes as Download, and returns the verification actually
+// performed, so that callers could enforce policy when fell back from storage nodes that do not serve segment
+// proofs, see WithVerificationFallback.
+func (downloader *Downloader) DownloadWithResult(ctx context.Context, root, filename string, withProof bool) (*DownloadResult, error) {
+	if err := downloader.fallback.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := downloader.acquire(); err != nil {
+		return nil, err
+	}
+	defer downloader.release()
+
 	hash := common.HexToHash(root)
 
 	// Query file info from storage node
 	info, err := downloader.queryFile(ctx, hash)
 	if err != nil {
-		return errors.WithMessage(err, "Failed to query file info")
+		return nil, errors.WithMessage(err, "Failed to query file info")
 	}
 
 	// Check file existence before downloading
-	if err = downloader.checkExistence(filename, hash); err != nil {
-		return errors.WithMessage(err, "Failed to check file existence")
+	if err = downloader.checkExistence(filename, hash, int64(info.Tx.Size)); err != nil {
+		return nil, errors.WithMessage(err, "Failed to check file existence")
 	}
 
 	// Download segments
-	if err = downloader.downloadFile(ctx, filename, hash, info, withProof); err != nil {
-		return errors.WithMessage(err, "Failed to download file")
+	progress := newProgressTracker(downloader.onProgress, hash)
+	result, err := downloader.downloadFile(withProgressTracker(ctx, progress), filename, hash, info, withProof)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to download file")
 	}
 
-	// Validate the downloaded file
-	if err = downloader.validateDownloadFile(root, filename, int64(info.Tx.Size)); err != nil {
-		return errors.WithMessage(err, "Failed to validate downloaded file")
+	// Validate the downloaded file, unless validated before released
+	if !result.fellBack {
+		if err = downloader.validateDownloadFile(root, filename, int64(info.Tx.Size)); err != nil {
+			return nil, errors.WithMessage(err, "Failed to validate downloaded file")
+		}
 	}
 
-	return nil
+	progress.finish()
+
+	return &result.DownloadResult, nil
 }
 
 func (downloader *Downloader) queryFile(ctx context.Context, root common.Hash) (info *node.FileInfo, err error) {
+	var verifier *chainVerifier
+	if downloader.verifyAgainstChain != nil {
+		verifier = newChainVerifier(downloader.verifyAgainstChain)
+	}
+
 	// do not require file finalized
 	for _, v := range downloader.clients {
 		info, err = v.GetFileInfo(ctx, root)
@@ -125,6 +290,12 @@ func (downloader *Downloader) queryFile(ctx context.Context, root common.Hash) (
 		if info == nil {
 			return nil, fmt.Errorf("file not found on node %v", v.URL())
 		}
+
+		if verifier != nil {
+			if err = verifier.verify(ctx, v.URL(), root, info); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	downloader.logger.WithField("file", info).Debug("File found by root hash")
@@ -132,34 +303,45 @@ func (downloader *Downloader) queryFile(ctx context.Context, root common.Hash) (
 	return
 }
 
-func (downloader *Downloader) checkExistence(filename string, hash common.Hash) error {
-	file, err := core.Open(filename)
-	if os.IsNotExist(err) {
-		return nil
-	}
+// checkExistence checks the existing destination file, which is verified by merkle root if of the same size, and
+// replaced if differs only if overwrite specified.
+func (downloader *Downloader) checkExistence(filename string, hash common.Hash, size int64) error {
+	err := checkDestination(filename, size, func(file *core.File) (int64, error) {
+		fileRoot, err := core.MerkleRootData(file)
+		if err != nil {
+			return 0, errors.WithMessage(err, "Failed to calculate file merkle root")
+		}
 
-	if err != nil {
-		return errors.WithMessage(err, "Failed to open file")
-	}
+		if fileRoot == hash {
+			return -1, nil
+		}
 
-	defer file.Close()
+		return size, nil
+	})
 
-	tree, err := core.MerkleTree(file)
-	if err != nil {
-		return errors.WithMessage(err, "Failed to create file merkle tree")
+	var differs *DestinationDiffersError
+	if downloader.overwrite && errors.As(err, &differs) {
+		downloader.logger.WithFields(logrus.Fields{
+			"file":         filename,
+			"expectedSize": differs.ExpectedSize,
+			"actualSize":   differs.ActualSize,
+		}).Warn("Destination file differs, and will be overwritten")
+		return nil
 	}
 
-	if tree.Root().Hex() == hash.Hex() {
-		return ErrFileAlreadyExists
-	}
+	return err
+}
 
-	return errors.New("File already exists with different hash")
+// fileDownloadResult is the result of downloading file segments.
+type fileDownloadResult struct {
+	DownloadResult
+	fellBack bool // downloaded without proof from storage nodes that do not serve segment proofs
 }
 
-func (downloader *Downloader) downloadFile(ctx context.Context, filename string, root common.Hash, info *node.FileInfo, withProof bool) error {
-	file, err := download.CreateDownloadingFile(filename, root, int64(info.Tx.Size))
+func (downloader *Downloader) downloadFile(ctx context.Context, filename string, root common.Hash, info *node.FileInfo, withProof bool) (result fileDownloadResult, err error) {
+	file, err := download.CreateDownloadingFileWithOption(filename, root, int64(info.Tx.Size), downloader.writeOption)
 	if err != nil {
-		return errors.WithMessage(err, "Failed to create downloading file")
+		return result, errors.WithMessage(err, "Failed to create downloading file")
 	}
 	defer file.Close()
 
@@ -167,25 +349,41 @@ func (downloader *Downloader) downloadFile(ctx context.Context, filename string,
 
 	shardConfigs, err := getShardConfigs(ctx, downloader.clients)
 	if err != nil {
-		return err
+		return result, err
 	}
 
-	sd, err := newSegmentDownloader(downloader, info, shardConfigs, file, withProof)
+	sd, err := newSegmentDownloader(ctx, downloader, info, shardConfigs, file, withProof)
 	if err != nil {
-		return errors.WithMessage(err, "Failed to create segment downloader")
+		return result, errors.WithMessage(err, "Failed to create segment downloader")
 	}
 
 	if err = sd.Download(ctx); err != nil {
-		return errors.WithMessage(err, "Failed to download file")
+		return result, errors.WithMessage(err, "Failed to download file")
+	}
+	sd.progress.phase(ProgressFinalizing)
+
+	result.Verification = VerificationWholeFile
+	if withProof {
+		result.Verification = VerificationProof
 	}
 
-	if err := file.Seal(); err != nil {
-		return errors.WithMessage(err, "Failed to seal downloading file")
+	if sd.fellBack.Load() {
+		// verify the whole file before released, since segments downloaded without proof
+		result.Verification, result.fellBack = VerificationWholeFile, true
+		err = file.SealVerified(func(tmpFilename string) error {
+			return downloader.validateDownloadFile(root.Hex(), tmpFilename, int64(info.Tx.Size))
+		})
+	} else {
+		err = file.Seal()
 	}
 
-	downloader.logger.Info("Completed to download file")
+	if err != nil {
+		return result, errors.WithMessage(err, "Failed to seal downloading file")
+	}
 
-	return nil
+	downloader.logger.WithField("verification", result.Verification).Info("Completed to download file")
+
+	return result, nil
 }
 
 func (downloader *Downloader) validateDownloadFile(root, filename string, fileSize int64) error {
@@ -196,16 +394,18 @@ func (downloader *Downloader) validateDownloadFile(root, filename string, fileSi
 	defer file.Close()
 
 	if file.Size() != fileSize {
-		return errors.Errorf("File size mismatch: expected = %v, downloaded = %v", fileSize, file.Size())
+		err = errors.Errorf("File size mismatch: expected = %v, downloaded = %v", fileSize, file.Size())
+		return zg_common.WithErrorClass(err, zg_common.ErrorClassVerification)
 	}
 
-	tree, err := core.MerkleTree(file)
+	fileRoot, err := core.MerkleRootData(file)
 	if err != nil {
-		return errors.WithMessage(err, "Failed to create merkle tree")
+		return errors.WithMessage(err, "Failed to calculate merkle root")
 	}
 
-	if rootHex := tree.Root().Hex(); rootHex != root {
-		return errors.Errorf("Merkle root mismatch, downloaded = %v", rootHex)
+	if rootHex := fileRoot.Hex(); rootHex != root {
+		err = errors.Errorf("Merkle root mismatch, downloaded = %v", rootHex)
+		return zg_common.WithErrorClass(err, zg_common.ErrorClassVerification)
 	}
 
 	downloader.logger.Info("Succeeded to validate the downloaded file")
@@ -0,0 +1,248 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/contract"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/download"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestData(t *testing.T, size int) ([]byte, core.IterableData) {
+	content := make([]byte, size)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+
+	return content, data
+}
+
+func TestUploadDownloadE2E(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	content, data := newTestData(t, 2*core.DefaultSegmentSize+core.DefaultSegmentSize/2)
+	txHash, root, err := uploader.Upload(context.Background(), data)
+	assert.NoError(t, err)
+	assert.NotEqual(t, common.Hash{}, txHash)
+
+	// submitted on chain and finalized on storage node
+	submissions := network.Chain.Submissions()
+	assert.Equal(t, 1, len(submissions))
+	assert.Equal(t, root, submissions[0].Root())
+	info, err := network.Nodes[0].GetFileInfo(root)
+	assert.NoError(t, err)
+	assert.True(t, info.Finalized)
+
+	downloader, err := NewDownloader(network.ZgsClients())
+	assert.NoError(t, err)
+	defer downloader.Close()
+
+	dir := t.TempDir()
+	for _, withProof := range []bool{false, true} {
+		filename := filepath.Join(dir, root.Hex())
+		assert.NoError(t, downloader.Download(context.Background(), root.Hex(), filename, withProof))
+
+		downloaded, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, content, downloaded)
+		assert.NoError(t, os.Remove(filename))
+	}
+
+	// written with direct I/O and without fsync
+	downloader.WithWriteOption(download.WriteOption{NoSync: true, Direct: true})
+	filename := filepath.Join(dir, root.Hex())
+	assert.NoError(t, downloader.Download(context.Background(), root.Hex(), filename, true))
+	downloaded, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+
+	// submit log retrieved from chain
+	filter, err := NewFlowSubmitFilter(network.Web3(), testutil.FlowAddress)
+	assert.NoError(t, err)
+	latest, err := filter.LatestBlock(context.Background())
+	assert.NoError(t, err)
+	records, err := filter.FilterSubmits(context.Background(), 0, latest)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, root, records[0].Root)
+	assert.Equal(t, uint64(len(content)), records[0].Size)
+	assert.Equal(t, txHash, records[0].TxHash)
+}
+
+func TestBatchUploadE2E(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	_, dataA := newTestData(t, core.DefaultSegmentSize+1)
+	_, dataB := newTestData(t, 100)
+	_, roots, err := uploader.BatchUpload(context.Background(), []core.IterableData{dataA, dataB})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(roots))
+
+	// submitted in a single transaction
+	submissions := network.Chain.Submissions()
+	assert.Equal(t, 2, len(submissions))
+	for i, root := range roots {
+		assert.Equal(t, root, submissions[i].Root())
+
+		info, err := network.Nodes[0].GetFileInfo(root)
+		assert.NoError(t, err)
+		assert.True(t, info.Finalized)
+	}
+}
+
+func TestBatchUploadInvalidDataOption(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	// rejected before anything submitted
+	_, dataA := newTestData(t, 100)
+	_, dataB := newTestData(t, 200)
+	_, _, err = uploader.BatchUpload(context.Background(), []core.IterableData{dataA, dataB}, BatchUploadOption{
+		DataOptions: []UploadOption{{}, {ExpectedReplica: 1, MinReplica: 2}},
+	})
+
+	var optErr *zg_common.OptionError
+	assert.ErrorAs(t, err, &optErr)
+	assert.Equal(t, "MinReplica", optErr.Field)
+	assert.Contains(t, err.Error(), "invalid option of data 1")
+	assert.Empty(t, network.Chain.Submissions())
+}
+
+func TestUploadDataSourcesE2E(t *testing.T) {
+	content, inMem := newTestData(t, core.DefaultSegmentSize+core.DefaultChunkSize)
+	filename := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(filename, content, 0644))
+	file, err := core.Open(filename)
+	assert.NoError(t, err)
+	defer file.Close()
+	mmap, err := core.NewDataInFileMmap(filename)
+	assert.NoError(t, err)
+	defer mmap.Close()
+	readerAt, err := core.NewDataFromReaderAt(bytes.NewReader(content), int64(len(content)))
+	assert.NoError(t, err)
+
+	uploadData := func(data core.IterableData) func(uploader *Uploader) (common.Hash, error) {
+		return func(uploader *Uploader) (common.Hash, error) {
+			_, root, err := uploader.Upload(context.Background(), data)
+			return root, err
+		}
+	}
+
+	// file, memory-mapped, memory and reader backed data uploaded to separate networks identically
+	var submissions []contract.Submission
+	for _, upload := range []func(uploader *Uploader) (common.Hash, error){
+		uploadData(file), uploadData(mmap), uploadData(inMem), uploadData(readerAt),
+		func(uploader *Uploader) (common.Hash, error) {
+			_, root, err := uploader.WithMmap(true).UploadFile(context.Background(), filename)
+			return root, err
+		},
+	} {
+		network := testutil.NewNetwork(t)
+		uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+		assert.NoError(t, err)
+		defer uploader.Close()
+
+		root, err := upload(uploader)
+		assert.NoError(t, err)
+		info, err := network.Nodes[0].GetFileInfo(root)
+		assert.NoError(t, err)
+		assert.True(t, info.Finalized)
+
+		if assert.Len(t, network.Chain.Submissions(), 1) {
+			submission := network.Chain.Submissions()[0]
+			assert.Equal(t, root, submission.Root())
+			submissions = append(submissions, submission)
+		}
+	}
+
+	for _, submission := range submissions[1:] {
+		assert.Equal(t, submissions[0], submission)
+	}
+}
+
+func TestUploadShardedE2E(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{ShardId: 0, NumShard: 2}, shard.ShardConfig{ShardId: 1, NumShard: 2})
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	content, data := newTestData(t, 5*core.DefaultSegmentSize)
+	_, root, err := uploader.Upload(context.Background(), data, UploadOption{ExpectedReplica: 1})
+	assert.NoError(t, err)
+
+	// segments uploaded to storage nodes by shard, and downloaded from both
+	for _, service := range network.Nodes {
+		info, err := service.GetFileInfo(root)
+		assert.NoError(t, err)
+		assert.True(t, info.Finalized)
+	}
+
+	downloader, err := NewDownloader(network.ZgsClients())
+	assert.NoError(t, err)
+	defer downloader.Close()
+
+	filename := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, downloader.Download(context.Background(), root.Hex(), filename, true))
+	downloaded, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+}
+
+func TestUploadRevertedE2E(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	network.Chain.SetSubmitHook(func(sender common.Address, submission contract.Submission) error {
+		return testutil.ErrReverted
+	})
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	_, data := newTestData(t, 100)
+	_, _, err = uploader.Upload(context.Background(), data)
+	assert.Error(t, err)
+	assert.Empty(t, network.Chain.Submissions())
+}
+
+func TestUploadManualFinalizeE2E(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	network.Nodes[0].SetHooks(testutil.ZgsHooks{ManualFinalize: true})
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	// not finalized until the test finalizes the file on storage node
+	_, data := newTestData(t, 100)
+	_, root, err := uploader.Upload(context.Background(), data, UploadOption{FinalityRequired: TransactionPacked})
+	assert.NoError(t, err)
+	info, err := network.Nodes[0].GetFileInfo(root)
+	assert.NoError(t, err)
+	assert.False(t, info.Finalized)
+	assert.Equal(t, uint64(1), info.UploadedSegNum)
+
+	assert.NoError(t, network.Nodes[0].Finalize(root))
+	info, err = network.Nodes[0].GetFileInfo(root)
+	assert.NoError(t, err)
+	assert.True(t, info.Finalized)
+}
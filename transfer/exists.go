@@ -0,0 +1,571 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultExistenceBatchSize   = 100
+	defaultExistenceConcurrency = 4
+)
+
+// FileExistence is the existence of a file on the storage network.
+type FileExistence struct {
+	Root      common.Hash
+	Exists    bool
+	Finalized bool
+	Err       error // per-root error, e.g. invalid root in input or RPC failure
+}
+
+// ExistenceQuerier queries the existence of files in batch.
+type ExistenceQuerier interface {
+	// QueryExistence returns the existence of specified roots respectively. Per-root errors should be reported
+	// in FileExistence.Err, and error returned only if the whole batch failed.
+	QueryExistence(ctx context.Context, roots []common.Hash) ([]FileExistence, error)
+}
+
+// NodeExistenceQuerier queries the file existence from storage nodes. File exists if available on any node, and
+// finalized if finalized on any node.
+type NodeExistenceQuerier struct {
+	clients []*node.ZgsClient
+}
+
+var _ ExistenceQuerier = (*NodeExistenceQuerier)(nil)
+
+// NewNodeExistenceQuerier creates a new querier to query the file existence from storage nodes.
+func NewNodeExistenceQuerier(clients []*node.ZgsClient) *NodeExistenceQuerier {
+	return &NodeExistenceQuerier{clients}
+}
+
+// QueryExistence implements the ExistenceQuerier interface.
+func (querier *NodeExistenceQuerier) QueryExistence(ctx context.Context, roots []common.Hash) ([]FileExistence, error) {
+	result := make([]FileExistence, len(roots))
+	for i, root := range roots {
+		result[i].Root = root
+	}
+
+	// indices of roots to query, i.e. not finalized on any node yet
+	pending := make([]int, len(roots))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	succeeded := make([]bool, len(roots))
+
+	var lastErr error
+	for _, client := range querier.clients {
+		if len(pending) == 0 {
+			break
+		}
+
+		batch := make([]common.Hash, len(pending))
+		for i, index := range pending {
+			batch[i] = roots[index]
+		}
+
+		infos, errs, err := client.BatchGetFileInfo(ctx, batch)
+		if err != nil {
+			lastErr = errors.WithMessagef(err, "failed to query file info from node %v", client.URL())
+			continue
+		}
+
+		var next []int
+		for i, index := range pending {
+			if errs[i] != nil {
+				result[index].Err = errs[i]
+				next = append(next, index)
+				continue
+			}
+
+			succeeded[index] = true
+
+			if infos[i] != nil {
+				result[index].Exists = true
+				result[index].Finalized = infos[i].Finalized
+			}
+
+			if !result[index].Finalized {
+				next = append(next, index)
+			}
+		}
+
+		pending = next
+	}
+
+	var anySucceeded bool
+	for i := range result {
+		if succeeded[i] {
+			// available on some node though failed on others
+			result[i].Err = nil
+			anySucceeded = true
+		} else if result[i].Err == nil {
+			result[i].Err = lastErr
+		}
+	}
+
+	if !anySucceeded && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return result, nil
+}
+
+// RootSource provides roots in order to check existence.
+type RootSource interface {
+	// Next returns the next root, or io.EOF if no more roots. Other errors are treated as per-root errors,
+	// e.g. invalid root in input.
+	Next(ctx context.Context) (common.Hash, error)
+}
+
+type sliceRootSource struct {
+	roots []common.Hash
+}
+
+// RootsFromSlice returns a RootSource of the specified roots.
+func RootsFromSlice(roots []common.Hash) RootSource {
+	return &sliceRootSource{roots}
+}
+
+func (source *sliceRootSource) Next(ctx context.Context) (common.Hash, error) {
+	if len(source.roots) == 0 {
+		return common.Hash{}, io.EOF
+	}
+
+	root := source.roots[0]
+	source.roots = source.roots[1:]
+
+	return root, nil
+}
+
+type channelRootSource struct {
+	ch <-chan common.Hash
+}
+
+// RootsFromChannel returns a RootSource of roots received from the specified channel until closed.
+func RootsFromChannel(ch <-chan common.Hash) RootSource {
+	return &channelRootSource{ch}
+}
+
+func (source *channelRootSource) Next(ctx context.Context) (common.Hash, error) {
+	select {
+	case <-ctx.Done():
+		return common.Hash{}, ctx.Err()
+	case root, ok := <-source.ch:
+		if !ok {
+			return common.Hash{}, io.EOF
+		}
+		return root, nil
+	}
+}
+
+type readerRootSource struct {
+	scanner *bufio.Scanner
+}
+
+// RootsFromReader returns a RootSource of roots read from the specified reader, one hex encoded root per line.
+// Empty lines are ignored.
+func RootsFromReader(reader io.Reader) RootSource {
+	return &readerRootSource{bufio.NewScanner(reader)}
+}
+
+func (source *readerRootSource) Next(ctx context.Context) (common.Hash, error) {
+	for source.scanner.Scan() {
+		line := strings.TrimSpace(source.scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		var root common.Hash
+		if err := root.UnmarshalText([]byte(line)); err != nil {
+			return common.Hash{}, errors.WithMessagef(err, "invalid root %q", line)
+		}
+
+		return root, nil
+	}
+
+	if err := source.scanner.Err(); err != nil {
+		// failed to read input, which is not a per-root error
+		return common.Hash{}, &rootSourceError{err}
+	}
+
+	return common.Hash{}, io.EOF
+}
+
+// rootSourceError is a fatal error of root source, which aborts the existence check.
+type rootSourceError struct {
+	error
+}
+
+// ExistenceResult is the existence of a file along with its index in input.
+type ExistenceResult struct {
+	Index uint64
+	FileExistence
+}
+
+// ExistenceSink receives the existence check results in input order.
+type ExistenceSink interface {
+	// Write writes a batch of results, which should be persisted before return, so that the progress could be
+	// checkpointed. Error aborts the existence check.
+	Write(results []ExistenceResult) error
+}
+
+// CSVExistenceSink writes the existence check results in CSV format.
+type CSVExistenceSink struct {
+	writer *csv.Writer
+}
+
+var _ ExistenceSink = (*CSVExistenceSink)(nil)
+
+// NewCSVExistenceSink creates a sink to write results in CSV format, and writes header if required.
+func NewCSVExistenceSink(writer io.Writer, header bool) (*CSVExistenceSink, error) {
+	sink := &CSVExistenceSink{csv.NewWriter(writer)}
+
+	if header {
+		if err := sink.writer.Write([]string{"index", "root", "exists", "finalized", "error"}); err != nil {
+			return nil, err
+		}
+	}
+
+	return sink, nil
+}
+
+// Write implements the ExistenceSink interface.
+func (sink *CSVExistenceSink) Write(results []ExistenceResult) error {
+	for _, result := range results {
+		var errMsg string
+		if result.Err != nil {
+			errMsg = result.Err.Error()
+		}
+
+		if err := sink.writer.Write([]string{
+			strconv.FormatUint(result.Index, 10),
+			result.Root.Hex(),
+			strconv.FormatBool(result.Exists),
+			strconv.FormatBool(result.Finalized),
+			errMsg,
+		}); err != nil {
+			return err
+		}
+	}
+
+	sink.writer.Flush()
+
+	return sink.writer.Error()
+}
+
+// ExistenceCheckOption is the option to check file existence in bulk.
+type ExistenceCheckOption struct {
+	BatchSize         int     // number of roots to query in a single batch RPC, default 100
+	Concurrency       int     // number of batches to query in parallel, default 4
+	RequestsPerSecond float64 // max number of batches to query per second, 0 for unlimited
+	Checkpoint        string  // file to persist progress so as to resume after interruption, empty to disable
+}
+
+// Validate checks the option, and returns an error that names the invalid field if any.
+func (opt *ExistenceCheckOption) Validate() error {
+	return zg_common.FirstError(
+		zg_common.RequireNonNegative("BatchSize", opt.BatchSize),
+		zg_common.RequireNonNegative("Concurrency", opt.Concurrency),
+		zg_common.RequireNonNegative("RequestsPerSecond", opt.RequestsPerSecond),
+	)
+}
+
+// ExistenceStats is the statistics of bulk existence check.
+type ExistenceStats struct {
+	Resumed   uint64 `json:"resumed"` // number of roots skipped since checked before interruption
+	Checked   uint64 `json:"checked"`
+	Exists    uint64 `json:"exists"`
+	Finalized uint64 `json:"finalized"`
+	Failed    uint64 `json:"failed"`
+}
+
+type existenceCheckpoint struct {
+	Processed uint64 `json:"processed"`
+}
+
+func loadExistenceCheckpoint(path string) (uint64, error) {
+	if path == "" {
+		return 0, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to read checkpoint")
+	}
+
+	var checkpoint existenceCheckpoint
+	if err = json.Unmarshal(content, &checkpoint); err != nil {
+		return 0, errors.WithMessage(err, "failed to unmarshal checkpoint")
+	}
+
+	return checkpoint.Processed, nil
+}
+
+func saveExistenceCheckpoint(path string, processed uint64) error {
+	if path == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(existenceCheckpoint{processed})
+	if err != nil {
+		return err
+	}
+
+	// write to temporary file and rename, so that checkpoint will not be corrupted upon crash
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+type existenceBatch struct {
+	seq     uint64
+	index   uint64 // index of the first root in input
+	roots   []common.Hash
+	errs    []error // per-root errors of input
+	results []FileExistence
+}
+
+// CheckExistence checks whether the roots from source exist and finalized on storage network, and writes
+// the results to sink in input order. Roots are queried in batches with bounded concurrency and rate limit,
+// so that memory usage is constant regardless of the input size.
+//
+// Per-root errors are written to sink and do not abort the run. If checkpoint file specified, progress is
+// persisted once results written to sink, and roots that checked before interruption are skipped when resumed.
+func CheckExistence(ctx context.Context, querier ExistenceQuerier, source RootSource, sink ExistenceSink, option ...ExistenceCheckOption) (ExistenceStats, error) {
+	var opt ExistenceCheckOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	if err := opt.Validate(); err != nil {
+		return ExistenceStats{}, err
+	}
+
+	if opt.BatchSize == 0 {
+		opt.BatchSize = defaultExistenceBatchSize
+	}
+
+	if opt.Concurrency == 0 {
+		opt.Concurrency = defaultExistenceConcurrency
+	}
+
+	var stats ExistenceStats
+
+	processed, err := loadExistenceCheckpoint(opt.Checkpoint)
+	if err != nil {
+		return stats, err
+	}
+
+	// skip roots checked before interruption
+	for ; stats.Resumed < processed; stats.Resumed++ {
+		if _, err := source.Next(ctx); err == io.EOF {
+			return stats, nil
+		} else if errors.As(err, new(*rootSourceError)) || ctx.Err() != nil {
+			return stats, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// limits the batches in memory, including those queried but not written to sink yet
+	window := make(chan struct{}, opt.Concurrency*2)
+	batches := make(chan *existenceBatch, opt.Concurrency)
+	done := make(chan *existenceBatch, opt.Concurrency)
+
+	var limiter <-chan time.Time
+	if opt.RequestsPerSecond > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opt.RequestsPerSecond))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	// read roots from source in batches
+	sourceDone := make(chan existenceSourceResult, 1)
+	go func() {
+		defer close(batches)
+		numBatches, err := readExistenceBatches(ctx, source, processed, opt.BatchSize, window, batches)
+		sourceDone <- existenceSourceResult{numBatches, err}
+	}()
+
+	// query batches in parallel
+	for i := 0; i < opt.Concurrency; i++ {
+		go func() {
+			for batch := range batches {
+				if limiter != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-limiter:
+					}
+				}
+
+				batch.results = queryExistenceBatch(ctx, querier, batch)
+
+				select {
+				case <-ctx.Done():
+					return
+				case done <- batch:
+				}
+			}
+		}()
+	}
+
+	// write results in input order
+	pending := make(map[uint64]*existenceBatch)
+	var nextSeq uint64
+	numBatches := uint64(math.MaxUint64) // unknown until all roots read from source
+	for nextSeq < numBatches {
+		select {
+		case <-ctx.Done():
+			return stats, ctx.Err()
+		case result := <-sourceDone:
+			if result.err != nil {
+				return stats, result.err
+			}
+			numBatches = result.numBatches
+		case batch := <-done:
+			pending[batch.seq] = batch
+		}
+
+		for batch, ok := pending[nextSeq]; ok; batch, ok = pending[nextSeq] {
+			results := make([]ExistenceResult, len(batch.roots))
+			for i := range batch.roots {
+				results[i] = ExistenceResult{batch.index + uint64(i), batch.results[i]}
+
+				stats.Checked++
+				if results[i].Err != nil {
+					stats.Failed++
+				} else if results[i].Exists {
+					stats.Exists++
+					if results[i].Finalized {
+						stats.Finalized++
+					}
+				}
+			}
+
+			if err := sink.Write(results); err != nil {
+				return stats, errors.WithMessage(err, "failed to write results to sink")
+			}
+
+			if err := saveExistenceCheckpoint(opt.Checkpoint, batch.index+uint64(len(batch.roots))); err != nil {
+				return stats, errors.WithMessage(err, "failed to save checkpoint")
+			}
+
+			delete(pending, nextSeq)
+			nextSeq++
+			<-window
+		}
+	}
+
+	return stats, nil
+}
+
+type existenceSourceResult struct {
+	numBatches uint64
+	err        error
+}
+
+// readExistenceBatches reads roots from source in batches, and returns the number of batches read.
+func readExistenceBatches(ctx context.Context, source RootSource, index uint64, batchSize int, window chan struct{}, batches chan<- *existenceBatch) (uint64, error) {
+	var seq uint64
+
+	for eof := false; !eof; {
+		batch := existenceBatch{seq: seq, index: index}
+
+		for len(batch.roots) < batchSize {
+			root, err := source.Next(ctx)
+			if err == io.EOF {
+				eof = true
+				break
+			}
+
+			if errors.As(err, new(*rootSourceError)) || ctx.Err() != nil {
+				return seq, err
+			}
+
+			batch.roots = append(batch.roots, root)
+			batch.errs = append(batch.errs, err)
+		}
+
+		if len(batch.roots) == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return seq, ctx.Err()
+		case window <- struct{}{}:
+		}
+
+		select {
+		case <-ctx.Done():
+			return seq, ctx.Err()
+		case batches <- &batch:
+		}
+
+		seq++
+		index += uint64(len(batch.roots))
+	}
+
+	return seq, nil
+}
+
+// queryExistenceBatch queries the existence of valid roots in batch, and returns results of all roots.
+func queryExistenceBatch(ctx context.Context, querier ExistenceQuerier, batch *existenceBatch) []FileExistence {
+	results := make([]FileExistence, len(batch.roots))
+
+	var roots []common.Hash
+	var indices []int
+	for i, root := range batch.roots {
+		results[i].Root = root
+
+		if batch.errs[i] != nil {
+			results[i].Err = batch.errs[i]
+		} else {
+			roots = append(roots, root)
+			indices = append(indices, i)
+		}
+	}
+
+	if len(roots) == 0 {
+		return results
+	}
+
+	existences, err := querier.QueryExistence(ctx, roots)
+	if err == nil && len(existences) != len(roots) {
+		err = errors.Errorf("invalid number of results, expected = %v, actual = %v", len(roots), len(existences))
+	}
+
+	for i, index := range indices {
+		if err != nil {
+			results[index].Err = err
+		} else {
+			results[index] = existences[i]
+			results[index].Root = roots[i]
+		}
+	}
+
+	return results
+}
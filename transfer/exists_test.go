@@ -0,0 +1,139 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExistenceQuerier treats roots of odd number as existent, and roots that divisible by 10 as failed.
+type fakeExistenceQuerier struct {
+	batches atomic.Int32
+}
+
+func (querier *fakeExistenceQuerier) QueryExistence(ctx context.Context, roots []common.Hash) ([]FileExistence, error) {
+	querier.batches.Add(1)
+
+	// complete out of order
+	time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+
+	result := make([]FileExistence, len(roots))
+	for i, root := range roots {
+		n := root.Big().Uint64()
+		result[i].Root = root
+		result[i].Exists = n%2 == 1
+		result[i].Finalized = n%4 == 1
+		if n%10 == 0 {
+			result[i].Err = errors.New("rpc failure")
+		}
+	}
+
+	return result, nil
+}
+
+type memExistenceSink struct {
+	results []ExistenceResult
+	failAt  int // fails when writing the specified batch, 0 to never fail
+	writes  int
+}
+
+func (sink *memExistenceSink) Write(results []ExistenceResult) error {
+	sink.writes++
+	if sink.writes == sink.failAt {
+		return errors.New("disk full")
+	}
+
+	sink.results = append(sink.results, results...)
+	return nil
+}
+
+func testExistenceRoot(n int) common.Hash {
+	return common.BigToHash(big.NewInt(int64(n)))
+}
+
+func newExistenceInput(n int) string {
+	var lines []string
+	for i := 1; i <= n; i++ {
+		lines = append(lines, testExistenceRoot(i).Hex())
+		if i == 7 {
+			lines = append(lines, "", "invalid root")
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestCheckExistence(t *testing.T) {
+	querier := fakeExistenceQuerier{}
+	sink := memExistenceSink{}
+
+	stats, err := CheckExistence(context.Background(), &querier, RootsFromReader(strings.NewReader(newExistenceInput(100))), &sink, ExistenceCheckOption{
+		BatchSize:         7,
+		Concurrency:       3,
+		RequestsPerSecond: 1000,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ExistenceStats{Checked: 101, Exists: 50, Finalized: 25, Failed: 11}, stats)
+	assert.Equal(t, int32(15), querier.batches.Load())
+
+	// results in input order, and per-root errors not abort
+	assert.Len(t, sink.results, 101)
+	for i, result := range sink.results {
+		assert.Equal(t, uint64(i), result.Index)
+	}
+	assert.ErrorContains(t, sink.results[7].Err, "invalid root")
+	assert.Equal(t, testExistenceRoot(8), sink.results[8].Root)
+	assert.ErrorContains(t, sink.results[10].Err, "rpc failure")
+}
+
+func TestCheckExistenceResume(t *testing.T) {
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+	option := ExistenceCheckOption{BatchSize: 10, Concurrency: 2, Checkpoint: checkpoint}
+
+	// interrupted
+	sink := memExistenceSink{failAt: 4}
+	_, err := CheckExistence(context.Background(), &fakeExistenceQuerier{}, RootsFromReader(strings.NewReader(newExistenceInput(100))), &sink, option)
+	assert.ErrorContains(t, err, "disk full")
+	assert.Len(t, sink.results, 30)
+
+	// resumed
+	var buf bytes.Buffer
+	csvSink, err := NewCSVExistenceSink(&buf, false)
+	assert.NoError(t, err)
+	stats, err := CheckExistence(context.Background(), &fakeExistenceQuerier{}, RootsFromReader(strings.NewReader(newExistenceInput(100))), csvSink, option)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(30), stats.Resumed)
+	assert.Equal(t, uint64(71), stats.Checked)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 71)
+	assert.Equal(t, fmt.Sprintf("30,%v,false,false,", testExistenceRoot(30).Hex()), lines[0][:strings.LastIndex(lines[0], ",")+1])
+	assert.True(t, strings.HasPrefix(lines[70], "100,"))
+}
+
+func TestCheckExistenceFromChannel(t *testing.T) {
+	ch := make(chan common.Hash)
+	go func() {
+		defer close(ch)
+		for i := 1; i <= 20; i++ {
+			ch <- testExistenceRoot(i)
+		}
+	}()
+
+	sink := memExistenceSink{}
+	stats, err := CheckExistence(context.Background(), &fakeExistenceQuerier{}, RootsFromChannel(ch), &sink)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(20), stats.Checked)
+	assert.Len(t, sink.results, 20)
+}
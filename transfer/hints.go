@@ -0,0 +1,130 @@
+package transfer
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultUploadHintTTL is the default duration to keep the hint of uploaded file, after which the file is expected
+// to be synced by other storage nodes, or located by indexer.
+const DefaultUploadHintTTL = 10 * time.Minute
+
+// uploadHint is the storage nodes known to hold a recently uploaded file.
+type uploadHint struct {
+	urls     []string
+	recorded time.Time
+}
+
+// UploadHints records the storage nodes known to hold recently uploaded files, so that files could be downloaded
+// right after uploaded from the storage nodes uploaded to, rather than the others not synced yet, i.e. read your
+// writes. Hints expire after TTL, or once the file is broadly finalized, see Forget. It is safe for concurrent use,
+// and could be shared by uploaders and downloaders, e.g. indexer client and gateway handlers.
+type UploadHints struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[common.Hash]*uploadHint // file root -> hint
+	now     func() time.Time
+}
+
+// NewUploadHints creates a hint store of which hints expire after ttl, and DefaultUploadHintTTL if ttl is not
+// positive.
+func NewUploadHints(ttl time.Duration) *UploadHints {
+	if ttl <= 0 {
+		ttl = DefaultUploadHintTTL
+	}
+
+	return &UploadHints{
+		ttl:     ttl,
+		entries: make(map[common.Hash]*uploadHint),
+		now:     time.Now,
+	}
+}
+
+// Record records that the storage nodes of URLs hold the file of root, which are merged with the storage nodes
+// recorded before if not expired. It is a no-op on nil hints.
+func (hints *UploadHints) Record(root common.Hash, urls ...string) {
+	if hints == nil || len(urls) == 0 {
+		return
+	}
+
+	hints.mu.Lock()
+	defer hints.mu.Unlock()
+
+	now := hints.now()
+	hints.expire(now)
+
+	hint, ok := hints.entries[root]
+	if !ok {
+		hint = &uploadHint{}
+		hints.entries[root] = hint
+	}
+
+	for _, url := range urls {
+		if !slices.Contains(hint.urls, url) {
+			hint.urls = append(hint.urls, url)
+		}
+	}
+	hint.recorded = now
+}
+
+// Nodes returns the URLs of storage nodes known to hold the file of root in the order recorded, or nil if not
+// recorded or expired. It is safe to call on nil hints.
+func (hints *UploadHints) Nodes(root common.Hash) []string {
+	if hints == nil {
+		return nil
+	}
+
+	hints.mu.Lock()
+	defer hints.mu.Unlock()
+
+	hint, ok := hints.entries[root]
+	if !ok {
+		return nil
+	}
+
+	if hints.now().Sub(hint.recorded) >= hints.ttl {
+		delete(hints.entries, root)
+		return nil
+	}
+
+	return append([]string(nil), hint.urls...)
+}
+
+// Forget removes the hint of root, e.g. once the file is broadly finalized and could be located by indexer. It is
+// a no-op on nil hints.
+func (hints *UploadHints) Forget(root common.Hash) {
+	if hints == nil {
+		return
+	}
+
+	hints.mu.Lock()
+	defer hints.mu.Unlock()
+
+	delete(hints.entries, root)
+}
+
+// Len returns the number of hints not expired.
+func (hints *UploadHints) Len() int {
+	if hints == nil {
+		return 0
+	}
+
+	hints.mu.Lock()
+	defer hints.mu.Unlock()
+
+	hints.expire(hints.now())
+
+	return len(hints.entries)
+}
+
+// expire removes expired hints, so that memory is bounded by the upload rate within TTL.
+func (hints *UploadHints) expire(now time.Time) {
+	for root, hint := range hints.entries {
+		if now.Sub(hint.recorded) >= hints.ttl {
+			delete(hints.entries, root)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package transfer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadHints(t *testing.T) {
+	hints := NewUploadHints(time.Minute)
+	now := time.Now()
+	hints.now = func() time.Time { return now }
+
+	root1, root2 := common.HexToHash("0x1"), common.HexToHash("0x2")
+
+	// merged with storage nodes recorded before
+	hints.Record(root1, "http://a")
+	hints.Record(root1, "http://b", "http://a")
+	hints.Record(root2)
+	assert.Equal(t, []string{"http://a", "http://b"}, hints.Nodes(root1))
+	assert.Nil(t, hints.Nodes(root2))
+	assert.Equal(t, 1, hints.Len())
+
+	// expired after TTL
+	hints.Record(root2, "http://c")
+	now = now.Add(time.Minute - time.Second)
+	hints.Record(root1, "http://b")
+	now = now.Add(time.Second)
+	assert.Nil(t, hints.Nodes(root2))
+	assert.Equal(t, []string{"http://a", "http://b"}, hints.Nodes(root1))
+	assert.Equal(t, 1, hints.Len())
+
+	hints.Forget(root1)
+	assert.Nil(t, hints.Nodes(root1))
+	assert.Zero(t, hints.Len())
+
+	// no-op on nil hints
+	var disabled *UploadHints
+	disabled.Record(root1, "http://a")
+	disabled.Forget(root1)
+	assert.Nil(t, disabled.Nodes(root1))
+	assert.Zero(t, disabled.Len())
+}
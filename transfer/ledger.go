@@ -0,0 +1,455 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/transfer/download"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ledgerFileName     = "ledger.jsonl"
+	ledgerLockName     = "ledger.lock"
+	ledgerIdentityName = "identity"
+
+	// ledgerLockTimeout is the max time to wait for other processes writing to the same ledger.
+	ledgerLockTimeout = 10 * time.Second
+	// ledgerLockStaleAfter allows to steal the lock of ledger held by a crashed process.
+	ledgerLockStaleAfter = time.Minute
+)
+
+// DefaultLedgerDir returns the default directory of FileLedger in the home directory of current user.
+func DefaultLedgerDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.TempDir()
+	}
+
+	return filepath.Join(home, ".0g-storage-client", "ledger")
+}
+
+// LedgerKind is the kind of upload operation recorded in Ledger.
+type LedgerKind string
+
+const (
+	LedgerKindFile  LedgerKind = "file"  // single file or data, which may be uploaded in fragments
+	LedgerKindBatch LedgerKind = "batch" // multiple files in a single transaction
+	LedgerKindDir   LedgerKind = "dir"   // directory, where root is the storage root of directory metadata
+)
+
+// LedgerResult is the outcome of upload operation recorded in Ledger.
+type LedgerResult string
+
+const (
+	LedgerSucceeded LedgerResult = "succeeded"
+	LedgerFailed    LedgerResult = "failed"
+)
+
+// LedgerRecord is an upload operation performed by this client on the local machine, along with its outcome.
+type LedgerRecord struct {
+	Client     string        `json:"client"`             // persistent identity of client, see FileLedger.Identity
+	Host       string        `json:"host"`               // host name of local machine
+	Kind       LedgerKind    `json:"kind"`               // kind of operation
+	Roots      []common.Hash `json:"roots"`              // storage roots, multiple if uploaded in fragments or batch
+	TxHashes   []common.Hash `json:"txHashes,omitempty"` // transactions sent, empty if skipped
+	Size       int64         `json:"size"`               // total size of data in bytes
+	Paths      []string      `json:"paths,omitempty"`    // absolute local paths or URLs uploaded if any
+	Profile    string        `json:"profile,omitempty"`  // transfer profile used if any
+	Tenant     string        `json:"tenant,omitempty"`   // tenant uploaded on behalf of if any
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Result     LedgerResult  `json:"result"`
+	Error      string        `json:"error,omitempty"` // reason if failed
+
+	Agent *zg_common.ClientInfo `json:"agent,omitempty"` // client library that uploaded, nil for records of earlier versions
+}
+
+// LedgerFilter filters records of Ledger, and zero value matches all records.
+type LedgerFilter struct {
+	Since  time.Time    // records started since the time if specified
+	Root   common.Hash  // records that contain the storage root if specified
+	Path   string       // records of any path that contains the string if specified
+	Kind   LedgerKind   // records of the kind if specified
+	Result LedgerResult // records of the result if specified
+	Limit  int          // max number of latest records to return, 0 for unlimited
+}
+
+// Match returns whether the record matches the filter, regardless of Limit.
+func (filter LedgerFilter) Match(record *LedgerRecord) bool {
+	if !filter.Since.IsZero() && record.StartedAt.Before(filter.Since) {
+		return false
+	}
+
+	if filter.Root != (common.Hash{}) && !containsHash(record.Roots, filter.Root) {
+		return false
+	}
+
+	if len(filter.Path) > 0 && !containsSubstring(record.Paths, filter.Path) {
+		return false
+	}
+
+	if len(filter.Kind) > 0 && record.Kind != filter.Kind {
+		return false
+	}
+
+	return len(filter.Result) == 0 || record.Result == filter.Result
+}
+
+func containsHash(hashes []common.Hash, hash common.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsSubstring(values []string, substr string) bool {
+	for _, v := range values {
+		if strings.Contains(v, substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Ledger is a local record of upload operations, which Uploader writes to if enabled, see Uploader.WithLedger.
+type Ledger interface {
+	// Append writes the record durably, which is either written entirely or not at all.
+	Append(ctx context.Context, record LedgerRecord) error
+
+	// Query returns the records that match the filter in order of writing.
+	Query(ctx context.Context, filter LedgerFilter) ([]LedgerRecord, error)
+}
+
+// FileLedger is a Ledger of append-only JSON lines in a directory, which is shared by processes on the same machine
+// and written under a cross-process lock. Corrupted lines, e.g. written partially upon crash, are skipped with
+// warnings when queried.
+type FileLedger struct {
+	dir      string
+	identity string
+	logger   *logrus.Logger
+}
+
+var _ Ledger = (*FileLedger)(nil)
+
+// OpenFileLedger opens or creates a ledger in the specified directory, along with a persistent identity of client
+// that recorded in each record.
+func OpenFileLedger(ctx context.Context, dir string, opts ...zg_common.LogOption) (*FileLedger, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.WithMessage(err, "Failed to create ledger directory")
+	}
+
+	ledger := &FileLedger{dir: dir, logger: zg_common.NewLogger(opts...)}
+
+	err := ledger.locked(ctx, func() error {
+		identity, err := ledger.loadIdentity()
+		ledger.identity = identity
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ledger, nil
+}
+
+// Identity returns the persistent identity of client, which is generated randomly when the ledger created.
+func (ledger *FileLedger) Identity() string {
+	return ledger.identity
+}
+
+// Path returns the file of records.
+func (ledger *FileLedger) Path() string {
+	return filepath.Join(ledger.dir, ledgerFileName)
+}
+
+// loadIdentity loads the identity, or generates a new one if not exists or corrupted.
+func (ledger *FileLedger) loadIdentity() (string, error) {
+	path := filepath.Join(ledger.dir, ledgerIdentityName)
+
+	content, err := os.ReadFile(path)
+	if err == nil {
+		identity := strings.TrimSpace(string(content))
+		if decoded, err := hex.DecodeString(identity); err == nil && len(decoded) == 16 {
+			return identity, nil
+		}
+
+		ledger.logger.WithField("path", path).Warn("Ledger identity corrupted, and regenerated")
+	} else if !os.IsNotExist(err) {
+		return "", errors.WithMessage(err, "Failed to read ledger identity")
+	}
+
+	var buf [16]byte
+	if _, err = rand.Read(buf[:]); err != nil {
+		return "", errors.WithMessage(err, "Failed to generate ledger identity")
+	}
+
+	identity := hex.EncodeToString(buf[:])
+	if err = writeFileSync(path, []byte(identity+"\n")); err != nil {
+		return "", errors.WithMessage(err, "Failed to write ledger identity")
+	}
+
+	return identity, nil
+}
+
+// writeFileSync writes the file via a temporary file and renames, so that the file is never written partially.
+func writeFileSync(path string, content []byte) error {
+	tmp := path + ".tmp"
+
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(content); err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// locked calls the function with the cross-process lock of ledger held.
+func (ledger *FileLedger) locked(ctx context.Context, fn func() error) error {
+	lock, err := download.AcquireLock(ctx, filepath.Join(ledger.dir, ledgerLockName), download.LockOption{
+		Timeout:    ledgerLockTimeout,
+		StaleAfter: ledgerLockStaleAfter,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "Failed to lock ledger")
+	}
+	defer lock.Release()
+
+	return fn()
+}
+
+// Append implements the Ledger interface. The client identity is filled if not specified.
+func (ledger *FileLedger) Append(ctx context.Context, record LedgerRecord) error {
+	if len(record.Client) == 0 {
+		record.Client = ledger.identity
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to marshal ledger record")
+	}
+
+	return ledger.locked(ctx, func() error {
+		file, err := os.OpenFile(ledger.Path(), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return errors.WithMessage(err, "Failed to open ledger")
+		}
+		defer file.Close()
+
+		// terminate the line written partially by a crashed writer, so that only that line is corrupted
+		if terminated, err := endsWithNewline(file); err != nil {
+			return errors.WithMessage(err, "Failed to read ledger")
+		} else if !terminated {
+			line = append([]byte{'\n'}, line...)
+		}
+
+		if _, err = file.Write(append(line, '\n')); err != nil {
+			return errors.WithMessage(err, "Failed to write ledger")
+		}
+
+		return errors.WithMessage(file.Sync(), "Failed to sync ledger")
+	})
+}
+
+// endsWithNewline returns whether the file is empty or ends with a newline.
+func endsWithNewline(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return true, err
+	}
+
+	var last [1]byte
+	if _, err = file.ReadAt(last[:], info.Size()-1); err != nil {
+		return false, err
+	}
+
+	return last[0] == '\n', nil
+}
+
+// Query implements the Ledger interface. Corrupted lines are skipped with warnings.
+func (ledger *FileLedger) Query(ctx context.Context, filter LedgerFilter) ([]LedgerRecord, error) {
+	var content []byte
+
+	err := ledger.locked(ctx, func() error {
+		var err error
+		if content, err = os.ReadFile(ledger.Path()); os.IsNotExist(err) {
+			return nil
+		}
+
+		return errors.WithMessage(err, "Failed to read ledger")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := []LedgerRecord{}
+
+	reader := bufio.NewReader(bytes.NewReader(content))
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var record LedgerRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				ledger.logger.WithError(err).WithFields(logrus.Fields{
+					"path": ledger.Path(),
+					"line": lineNum,
+				}).Warn("Corrupted ledger record skipped")
+			} else if filter.Match(&record) {
+				records = append(records, record)
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+
+	return records, nil
+}
+
+// nonZeroHashes returns the hashes except zero ones, e.g. roots not calculated or transactions skipped.
+func nonZeroHashes(hashes []common.Hash) []common.Hash {
+	var result []common.Hash
+	for _, hash := range hashes {
+		if hash != (common.Hash{}) {
+			result = append(result, hash)
+		}
+	}
+
+	return result
+}
+
+// ledgerScopeKey is the context key to record nested upload operations once.
+type ledgerScopeKey struct{}
+
+// ledgerScope is the scope of upload operation to record.
+type ledgerScope struct {
+	paths    []string // local paths of data to upload
+	recorded bool     // whether being recorded, and nested operations are not recorded separately
+}
+
+// WithLedgerPaths returns a context that attaches the local paths or URLs of data to upload, which are recorded in
+// Ledger by the upload operation with the context, e.g. a file uploaded by Upload.
+func WithLedgerPaths(ctx context.Context, paths ...string) context.Context {
+	abs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if strings.Contains(path, "://") {
+			abs = append(abs, path)
+		} else if p, err := filepath.Abs(path); err == nil {
+			abs = append(abs, p)
+		} else {
+			abs = append(abs, path)
+		}
+	}
+
+	return context.WithValue(ctx, ledgerScopeKey{}, &ledgerScope{paths: abs})
+}
+
+// withLedgerPathsOnce attaches the local paths of data to upload unless in scope of another operation.
+func withLedgerPathsOnce(ctx context.Context, paths ...string) context.Context {
+	if ctx.Value(ledgerScopeKey{}) != nil {
+		return ctx
+	}
+
+	return WithLedgerPaths(ctx, paths...)
+}
+
+// ledgerEntry is the record of an upload operation in progress.
+type ledgerEntry struct {
+	uploader *Uploader
+	record   LedgerRecord
+}
+
+// beginRecord starts to record an upload operation if ledger enabled, and returns the context for nested
+// operations, which are not recorded separately. The entry is nil if not recorded.
+func (uploader *Uploader) beginRecord(ctx context.Context, kind LedgerKind, tenant string) (context.Context, *ledgerEntry) {
+	if uploader.ledger == nil {
+		return ctx, nil
+	}
+
+	scope, _ := ctx.Value(ledgerScopeKey{}).(*ledgerScope)
+	if scope != nil && scope.recorded {
+		return ctx, nil
+	}
+
+	entry := &ledgerEntry{uploader: uploader, record: LedgerRecord{
+		Kind:      kind,
+		Tenant:    tenantOf(ctx, tenant),
+		StartedAt: time.Now(),
+	}}
+	entry.record.Host, _ = os.Hostname()
+	if scope != nil {
+		entry.record.Paths = scope.paths
+	}
+	if uploader.profile != nil {
+		entry.record.Profile = uploader.profile.Name
+	}
+
+	ctx = zg_common.WithFeatureTracking(ctx)
+
+	return context.WithValue(ctx, ledgerScopeKey{}, &ledgerScope{recorded: true}), entry
+}
+
+// done writes the record with outcome of operation, and ledger failures are reported as warnings only.
+func (entry *ledgerEntry) done(ctx context.Context, size int64, txHashes, roots []common.Hash, err error) {
+	if entry == nil {
+		return
+	}
+
+	entry.record.Size = size
+	entry.record.Roots = nonZeroHashes(roots)
+	entry.record.TxHashes = nonZeroHashes(txHashes)
+	entry.record.FinishedAt = time.Now()
+	agent := zg_common.CurrentClient(zg_common.FeaturesInUse(ctx)...)
+	entry.record.Agent = &agent
+	entry.record.Result = LedgerSucceeded
+	if err != nil {
+		entry.record.Result = LedgerFailed
+		entry.record.Error = err.Error()
+	}
+
+	// write even if cancelled, so that the failure is recorded
+	if ledgerErr := entry.uploader.ledger.Append(context.WithoutCancel(ctx), entry.record); ledgerErr != nil {
+		entry.uploader.logger.WithError(ledgerErr).Warn("Failed to write ledger record")
+		entry.uploader.warnings.Add(WarningLedgerFailed, "Failed to write ledger record", map[string]interface{}{
+			"error": ledgerErr.Error(),
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileLedger(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ledger")
+	ledger, err := OpenFileLedger(context.Background(), dir)
+	assert.NoError(t, err)
+	assert.Len(t, ledger.Identity(), 32)
+
+	// identity persisted
+	reopened, err := OpenFileLedger(context.Background(), dir)
+	assert.NoError(t, err)
+	assert.Equal(t, ledger.Identity(), reopened.Identity())
+
+	records, err := ledger.Query(context.Background(), LedgerFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	start := time.Now()
+	assert.NoError(t, ledger.Append(context.Background(), LedgerRecord{
+		Kind:      LedgerKindFile,
+		Roots:     []common.Hash{{1}},
+		Paths:     []string{"/data/a.txt"},
+		StartedAt: start.Add(-time.Hour),
+		Result:    LedgerSucceeded,
+	}))
+	assert.NoError(t, reopened.Append(context.Background(), LedgerRecord{
+		Kind:      LedgerKindDir,
+		Roots:     []common.Hash{{2}},
+		Paths:     []string{"/data/photos"},
+		StartedAt: start,
+		Result:    LedgerFailed,
+		Error:     "failed",
+	}))
+
+	records, err = ledger.Query(context.Background(), LedgerFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, ledger.Identity(), records[1].Client)
+	assert.Equal(t, "/data/photos", records[1].Paths[0])
+
+	for _, c := range []struct {
+		filter   LedgerFilter
+		expected []common.Hash
+	}{
+		{LedgerFilter{Since: start.Add(-time.Minute)}, []common.Hash{{2}}},
+		{LedgerFilter{Root: common.Hash{1}}, []common.Hash{{1}}},
+		{LedgerFilter{Root: common.Hash{3}}, nil},
+		{LedgerFilter{Path: "photos"}, []common.Hash{{2}}},
+		{LedgerFilter{Kind: LedgerKindFile}, []common.Hash{{1}}},
+		{LedgerFilter{Result: LedgerFailed}, []common.Hash{{2}}},
+		{LedgerFilter{Limit: 1}, []common.Hash{{2}}},
+	} {
+		records, err = ledger.Query(context.Background(), c.filter)
+		assert.NoError(t, err)
+
+		var roots []common.Hash
+		for _, record := range records {
+			roots = append(roots, record.Roots...)
+		}
+		assert.Equal(t, c.expected, roots, "%+v", c.filter)
+	}
+
+	// record written partially by a crashed writer is skipped
+	file, err := os.OpenFile(ledger.Path(), os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	_, err = file.WriteString(`{"kind":"file","roo`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	assert.NoError(t, ledger.Append(context.Background(), LedgerRecord{Kind: LedgerKindBatch, Roots: []common.Hash{{3}}}))
+	records, err = ledger.Query(context.Background(), LedgerFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, LedgerKindBatch, records[2].Kind)
+
+	// corrupted identity regenerated
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ledgerIdentityName), []byte("corrupted"), 0644))
+	reopened, err = OpenFileLedger(context.Background(), dir)
+	assert.NoError(t, err)
+	assert.Len(t, reopened.Identity(), 32)
+	assert.NotEqual(t, ledger.Identity(), reopened.Identity())
+}
+
+func TestFileLedgerConcurrentWriters(t *testing.T) {
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		// separate instances as if in different processes
+		ledger, err := OpenFileLedger(context.Background(), dir)
+		assert.NoError(t, err)
+
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < 10; i++ {
+				assert.NoError(t, ledger.Append(context.Background(), LedgerRecord{
+					Kind:  LedgerKindFile,
+					Paths: []string{fmt.Sprintf("/writer%v/file%v", w, i)},
+				}))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	ledger, err := OpenFileLedger(context.Background(), dir)
+	assert.NoError(t, err)
+	records, err := ledger.Query(context.Background(), LedgerFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 40, len(records))
+
+	paths := make(map[string]bool)
+	for _, record := range records {
+		paths[record.Paths[0]] = true
+	}
+	assert.Equal(t, 40, len(paths))
+}
+
+func TestUploaderLedger(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	ledger, err := OpenFileLedger(context.Background(), t.TempDir())
+	assert.NoError(t, err)
+	uploader.WithLedger(ledger)
+
+	// file uploaded
+	folder := newTestFolder(t, 3)
+	txHash, root, err := uploader.UploadFile(context.Background(), filepath.Join(folder, "file0.txt"))
+	assert.NoError(t, err)
+
+	records, err := ledger.Query(context.Background(), LedgerFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, LedgerKindFile, records[0].Kind)
+	assert.Equal(t, []common.Hash{root}, records[0].Roots)
+	assert.Equal(t, []common.Hash{txHash}, records[0].TxHashes)
+	assert.Equal(t, []string{filepath.Join(folder, "file0.txt")}, records[0].Paths)
+	assert.Equal(t, int64(len("content of file 0")), records[0].Size)
+	assert.Equal(t, LedgerSucceeded, records[0].Result)
+	assert.Equal(t, ledger.Identity(), records[0].Client)
+	assert.False(t, records[0].FinishedAt.Before(records[0].StartedAt))
+	if assert.NotNil(t, records[0].Agent) {
+		assert.Equal(t, zg_common.ClientName, records[0].Agent.Name)
+		assert.Equal(t, zg_common.Version(), records[0].Agent.Version)
+	}
+
+	// files of directory not recorded separately
+	summary, err := uploader.UploadDirWithSummary(context.Background(), folder)
+	assert.NoError(t, err)
+
+	records, err = ledger.Query(context.Background(), LedgerFilter{Kind: LedgerKindDir})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, []common.Hash{summary.Root}, records[0].Roots)
+	assert.Equal(t, []string{folder}, records[0].Paths)
+	records, err = ledger.Query(context.Background(), LedgerFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+
+	// failure recorded
+	key, err := crypto.HexToECDSA(testutil.PrivateKey[2:])
+	assert.NoError(t, err)
+	network.Chain.SetBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(0))
+	_, data := newTestData(t, 1024)
+	_, _, uploadErr := uploader.Upload(context.Background(), data)
+	assert.ErrorIs(t, uploadErr, ErrInsufficientBalance)
+
+	records, err = ledger.Query(context.Background(), LedgerFilter{Result: LedgerFailed})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, uploadErr.Error(), records[0].Error)
+	assert.Empty(t, records[0].Paths)
+	if assert.NotNil(t, records[0].Agent) {
+		assert.Equal(t, zg_common.Version(), records[0].Agent.Version)
+	}
+
+	// ledger failures never fail uploads
+	network.Chain.SetBalance(crypto.PubkeyToAddress(key.PublicKey), testutil.DefaultBalance)
+	broken := t.TempDir()
+	ledger, err = OpenFileLedger(context.Background(), broken)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Mkdir(ledger.Path(), 0755))
+	uploader.WithLedger(ledger)
+
+	_, data = newTestData(t, 1024)
+	_, _, err = uploader.Upload(context.Background(), data)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, uploader.Warnings().Count(WarningLedgerFailed))
+}
+
+func TestLedgerEntryNotRecorded(t *testing.T) {
+	uploader := &Uploader{}
+	ctx, entry := uploader.beginRecord(context.Background(), LedgerKindFile, "")
+	assert.Nil(t, entry)
+	assert.Nil(t, ctx.Value(ledgerScopeKey{}))
+
+	// no panic
+	entry.done(ctx, 0, nil, nil, errors.New("failed"))
+}
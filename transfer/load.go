@@ -0,0 +1,220 @@
+package transfer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/node"
+)
+
+const (
+	// defaultLoadPause is the time to pause when storage node signals overload without retry hint.
+	defaultLoadPause = time.Second
+	// maxLoadPause is the max time to pause when storage node signals overload.
+	maxLoadPause = 10 * time.Second
+)
+
+// loadFetchTimeout is the deadline to query the load hint of a storage node, so that an unresponsive node
+// does not delay uploading.
+var loadFetchTimeout = 3 * time.Second
+
+// nodeLoadReport is the observed load hint of a storage node during uploading.
+type nodeLoadReport struct {
+	Hint   *node.LoadHint `json:"hint"`
+	Pauses int            `json:"pauses"`
+}
+
+// nodeLoads tracks the load hints advertised by storage nodes via status RPC, which is used to prefer less
+// loaded nodes and to pause briefly when a node signals overload. If storage nodes do not advertise any hint,
+// it changes nothing.
+//
+// Note, all methods are no-op on nil nodeLoads.
+type nodeLoads struct {
+	mu     sync.Mutex
+	fetch  func(ctx context.Context, clientIndex int) (*node.LoadHint, error)
+	hints  []*node.LoadHint
+	pauses []int
+}
+
+func newNodeLoads(ctx context.Context, clients []*node.ZgsClient) *nodeLoads {
+	return newNodeLoadsWithFetcher(ctx, len(clients), func(ctx context.Context, clientIndex int) (*node.LoadHint, error) {
+		status, err := clients[clientIndex].GetStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return status.Load, nil
+	})
+}
+
+func newNodeLoadsWithFetcher(ctx context.Context, numClients int, fetch func(context.Context, int) (*node.LoadHint, error)) *nodeLoads {
+	loads := &nodeLoads{
+		fetch:  fetch,
+		hints:  make([]*node.LoadHint, numClients),
+		pauses: make([]int, numClients),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < numClients; i++ {
+		wg.Add(1)
+		go func(clientIndex int) {
+			defer wg.Done()
+			loads.refresh(ctx, clientIndex)
+		}(i)
+	}
+	wg.Wait()
+
+	return loads
+}
+
+// refresh queries the latest load hint of the specified node within loadFetchTimeout. Previous hint is retained
+// upon failure.
+func (loads *nodeLoads) refresh(ctx context.Context, clientIndex int) *node.LoadHint {
+	if loads == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, loadFetchTimeout)
+	defer cancel()
+
+	hint, err := loads.fetch(ctx, clientIndex)
+
+	loads.mu.Lock()
+	defer loads.mu.Unlock()
+
+	if err == nil {
+		loads.hints[clientIndex] = hint
+	}
+
+	return loads.hints[clientIndex]
+}
+
+func (loads *nodeLoads) get(clientIndex int) *node.LoadHint {
+	if loads == nil {
+		return nil
+	}
+
+	loads.mu.Lock()
+	defer loads.mu.Unlock()
+	return loads.hints[clientIndex]
+}
+
+// utilizations returns the utilization of all nodes, or nil if no hint advertised.
+func (loads *nodeLoads) utilizations() []float64 {
+	if loads == nil {
+		return nil
+	}
+
+	loads.mu.Lock()
+	defer loads.mu.Unlock()
+
+	var result []float64
+	for i, hint := range loads.hints {
+		if hint == nil {
+			continue
+		}
+
+		if result == nil {
+			result = make([]float64, len(loads.hints))
+		}
+		result[i] = hint.Utilization()
+	}
+
+	return result
+}
+
+// retryAfter returns the retry time suggested by node, or fallback if not advertised.
+func (loads *nodeLoads) retryAfter(clientIndex int, fallback time.Duration) time.Duration {
+	retryAfter := loads.get(clientIndex).RetryAfter()
+	if retryAfter == 0 {
+		return fallback
+	}
+
+	return min(retryAfter, maxLoadPause)
+}
+
+// wait pauses briefly if the specified node signals overload, and then refreshes the load hint.
+func (loads *nodeLoads) wait(ctx context.Context, clientIndex int) error {
+	hint := loads.get(clientIndex)
+	if hint == nil || !hint.Overloaded {
+		return nil
+	}
+
+	loads.mu.Lock()
+	loads.pauses[clientIndex]++
+	loads.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(loads.retryAfter(clientIndex, defaultLoadPause)):
+	}
+
+	loads.refresh(ctx, clientIndex)
+
+	return nil
+}
+
+// report returns the observed load hints by node URL, or nil if no hint advertised.
+func (loads *nodeLoads) report(clients []*node.ZgsClient) map[string]nodeLoadReport {
+	if loads == nil {
+		return nil
+	}
+
+	loads.mu.Lock()
+	defer loads.mu.Unlock()
+
+	var result map[string]nodeLoadReport
+	for i, hint := range loads.hints {
+		if hint == nil {
+			continue
+		}
+
+		if result == nil {
+			result = make(map[string]nodeLoadReport)
+		}
+		result[clients[i].URL()] = nodeLoadReport{hint, loads.pauses[i]}
+	}
+
+	return result
+}
+
+// interleaveTasks interleaves the upload tasks of nodes round by round, so that all nodes are uploaded in
+// parallel. In each round, less loaded nodes are preferred if utilizations are available.
+func interleaveTasks(clientTasks [][]*uploadTask, utilizations []float64) []*uploadTask {
+	order := make([]int, len(clientTasks))
+	for i := range order {
+		order[i] = i
+	}
+
+	if utilizations != nil {
+		utilization := func(tasks []*uploadTask) float64 {
+			if len(tasks) == 0 {
+				return 0
+			}
+			return utilizations[tasks[0].clientIndex]
+		}
+
+		sort.SliceStable(order, func(i, j int) bool {
+			return utilization(clientTasks[order[i]]) < utilization(clientTasks[order[j]])
+		})
+	}
+
+	var rounds int
+	for _, tasks := range clientTasks {
+		rounds = max(rounds, len(tasks))
+	}
+
+	result := make([]*uploadTask, 0)
+	for round := 0; round < rounds; round++ {
+		for _, i := range order {
+			if round < len(clientTasks[i]) {
+				result = append(result, clientTasks[i][round])
+			}
+		}
+	}
+
+	return result
+}
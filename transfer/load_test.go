@@ -0,0 +1,115 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTasks(clientIndex, num int) []*uploadTask {
+	var tasks []*uploadTask
+	for i := 0; i < num; i++ {
+		tasks = append(tasks, &uploadTask{clientIndex: clientIndex, segIndex: uint64(i)})
+	}
+	return tasks
+}
+
+func taskClients(tasks []*uploadTask) []int {
+	var result []int
+	for _, task := range tasks {
+		result = append(result, task.clientIndex)
+	}
+	return result
+}
+
+func TestInterleaveTasks(t *testing.T) {
+	clientTasks := [][]*uploadTask{newTestTasks(0, 3), newTestTasks(1, 2), newTestTasks(2, 0), newTestTasks(3, 1)}
+
+	// without hints, round robin in original order
+	assert.Equal(t, []int{0, 1, 3, 0, 1, 0}, taskClients(interleaveTasks(clientTasks, nil)))
+
+	// less loaded nodes first in each round
+	assert.Equal(t, []int{3, 1, 0, 1, 0, 0}, taskClients(interleaveTasks(clientTasks, []float64{0.9, 0.5, 0, 0.1})))
+}
+
+func TestNodeLoadsWithoutHints(t *testing.T) {
+	var fetches atomic.Int32
+	loads := newNodeLoadsWithFetcher(context.Background(), 2, func(ctx context.Context, i int) (*node.LoadHint, error) {
+		fetches.Add(1)
+		if i == 0 {
+			return nil, errors.New("status unavailable")
+		}
+		return nil, nil
+	})
+
+	assert.Nil(t, loads.utilizations())
+	assert.Equal(t, 10*time.Second, loads.retryAfter(1, 10*time.Second))
+	assert.NoError(t, loads.wait(context.Background(), 1))
+	assert.Equal(t, int32(2), fetches.Load())
+	assert.Nil(t, loads.report(nil))
+
+	// nil loads changes nothing
+	var nilLoads *nodeLoads
+	assert.Nil(t, nilLoads.utilizations())
+	assert.NoError(t, nilLoads.wait(context.Background(), 0))
+}
+
+func TestNodeLoadsOverloaded(t *testing.T) {
+	hints := []*node.LoadHint{
+		{PendingSegments: 10, MaxPendingSegments: 40},
+		{Overloaded: true, RetryAfterMs: 20},
+	}
+
+	loads := newNodeLoadsWithFetcher(context.Background(), 2, func(ctx context.Context, i int) (*node.LoadHint, error) {
+		return hints[i], nil
+	})
+
+	assert.Equal(t, []float64{0.25, 1}, loads.utilizations())
+	assert.Equal(t, 20*time.Millisecond, loads.retryAfter(1, 10*time.Second))
+
+	// no pause if not overloaded
+	start := time.Now()
+	assert.NoError(t, loads.wait(context.Background(), 0))
+	assert.Less(t, time.Since(start), 20*time.Millisecond)
+
+	// pause briefly and refresh hint
+	hints[1] = &node.LoadHint{PendingSegments: 1, MaxPendingSegments: 40}
+	start = time.Now()
+	assert.NoError(t, loads.wait(context.Background(), 1))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.False(t, loads.get(1).Overloaded)
+
+	// cancelled during pause
+	hints[1] = &node.LoadHint{Overloaded: true, RetryAfterMs: 60000}
+	loads.refresh(context.Background(), 1)
+	assert.Equal(t, maxLoadPause, loads.retryAfter(1, time.Second))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, loads.wait(ctx, 1), context.Canceled)
+
+	assert.Equal(t, []int{0, 2}, loads.pauses)
+}
+
+func TestNodeLoadsUnresponsive(t *testing.T) {
+	defer func(timeout time.Duration) { loadFetchTimeout = timeout }(loadFetchTimeout)
+	loadFetchTimeout = 50 * time.Millisecond
+
+	// nodes queried in parallel, and unresponsive node bounded by deadline
+	start := time.Now()
+	loads := newNodeLoadsWithFetcher(context.Background(), 4, func(ctx context.Context, i int) (*node.LoadHint, error) {
+		if i == 0 {
+			return &node.LoadHint{PendingSegments: 10, MaxPendingSegments: 40}, nil
+		}
+
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	assert.Less(t, time.Since(start), 4*loadFetchTimeout)
+
+	assert.Equal(t, []float64{0.25, 0, 0, 0}, loads.utilizations())
+}
@@ -0,0 +1,195 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultManifestAttempts is the default max number of storage nodes to fetch directory manifest from.
+const defaultManifestAttempts = 3
+
+// ErrManifestMismatch is returned when the directory manifest downloaded mismatches the storage root of manifest.
+var ErrManifestMismatch = errors.New("directory manifest mismatches storage root")
+
+// NodeDownloaders is implemented by downloaders that could download from storage nodes one at a time, so that the
+// directory manifest is fetched again from alternate storage nodes once a storage node failed or served bad data,
+// see BuildFileTree. It is implemented by Downloader and indexer.Client.
+type NodeDownloaders interface {
+	// ForEachNode calls fn with the URL and a downloader of each storage node holding the file of root in turn,
+	// until fn returns false or all storage nodes tried. The downloader is valid only until fn returns.
+	ForEachNode(ctx context.Context, root string, fn func(url string, downloader IDownloader) bool) error
+}
+
+// ForEachNode implements the NodeDownloaders interface, where the storage nodes of downloader are tried in order.
+func (downloader *Downloader) ForEachNode(ctx context.Context, root string, fn func(url string, downloader IDownloader) bool) error {
+	if err := downloader.acquire(); err != nil {
+		return err
+	}
+	defer downloader.release()
+
+	for _, client := range downloader.clients {
+		if !fn(client.URL(), downloader.withClients([]*node.ZgsClient{client})) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// withClients returns a downloader of the same configuration that downloads from the specified storage nodes, which
+// are not owned by the returned downloader, and warnings are added to this downloader.
+func (downloader *Downloader) withClients(clients []*node.ZgsClient) *Downloader {
+	return &Downloader{
+		clients:            clients,
+		routines:           downloader.routines,
+		profile:            downloader.profile,
+		pool:               downloader.pool,
+		verifyAgainstChain: downloader.verifyAgainstChain,
+		consistent:         downloader.consistent,
+		fallback:           downloader.fallback,
+		writeOption:        downloader.writeOption,
+		overwrite:          downloader.overwrite,
+		onProgress:         downloader.onProgress,
+		logger:             downloader.logger,
+		warnings:           downloader.warnings,
+	}
+}
+
+// fetchManifest downloads the directory manifest of root, and verifies the manifest against root before parsed.
+// If downloader implements NodeDownloaders, the manifest is fetched from at most attempts storage nodes in turn
+// until verified, and storage nodes that failed or served bad data are reported.
+//
+// If the manifest uploaded in chunks, root is the storage root of manifest index, and chunks are fetched likewise
+// and assembled, see dir.ManifestIndex.
+func fetchManifest(ctx context.Context, downloader IDownloader, root string, proof bool, attempts int) ([]byte, error) {
+	data, err := fetchManifestFile(ctx, downloader, root, proof, attempts)
+	if err != nil {
+		return nil, err
+	}
+
+	return dir.AssembleManifest(data, func(chunk common.Hash) ([]byte, error) {
+		return fetchManifestFile(ctx, downloader, chunk.Hex(), proof, attempts)
+	})
+}
+
+// fetchManifestFile downloads the file of directory manifest, manifest index or manifest chunk of root, which is
+// verified against root.
+func fetchManifestFile(ctx context.Context, downloader IDownloader, root string, proof bool, attempts int) ([]byte, error) {
+	source, ok := downloader.(NodeDownloaders)
+	if !ok {
+		return downloadManifest(ctx, downloader, root, proof)
+	}
+
+	if attempts <= 0 {
+		attempts = defaultManifestAttempts
+	}
+
+	var data []byte
+	var failures []string
+	var mismatched bool
+	err := source.ForEachNode(ctx, root, func(url string, nodeDownloader IDownloader) bool {
+		var err error
+		if data, err = downloadManifest(ctx, nodeDownloader, root, proof); err == nil {
+			return false
+		}
+
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"root": root,
+			"node": url,
+		}).Warn("Failed to fetch directory manifest from storage node")
+
+		if reporter, ok := downloader.(interface{ Warnings() *Warnings }); ok {
+			reporter.Warnings().Add(WarningManifestRejected, "Directory manifest rejected", map[string]interface{}{
+				"root":   root,
+				"node":   url,
+				"reason": err.Error(),
+			})
+		}
+
+		failures = append(failures, fmt.Sprintf("%v: %v", url, err))
+		mismatched = mismatched || zg_common.ClassOf(err) == zg_common.ErrorClassVerification
+
+		return len(failures) < attempts && ctx.Err() == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if data != nil {
+		return data, nil
+	}
+
+	if len(failures) == 0 {
+		return nil, errors.New("no storage node holding directory manifest found")
+	}
+
+	err = errors.Errorf("failed to fetch directory manifest from %v storage nodes: %v", len(failures), strings.Join(failures, "; "))
+	if mismatched {
+		return nil, zg_common.WithErrorClass(err, zg_common.ErrorClassVerification)
+	}
+
+	return nil, err
+}
+
+// downloadManifest downloads the directory manifest of root to a temporary file, and verifies the manifest against
+// root regardless of the downloader implementation.
+func downloadManifest(ctx context.Context, downloader IDownloader, root string, proof bool) ([]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "zgdm-")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	metapath := filepath.Join(tmpDir, root+".zgdm")
+
+	logrus.WithFields(logrus.Fields{
+		"root":     root,
+		"filename": metapath,
+	}).Debug("Downloading directory metadata to build file tree")
+
+	if err = downloader.Download(ctx, root, metapath, proof); err != nil {
+		return nil, errors.WithMessage(err, "failed to download directory metadata")
+	}
+
+	data, err := os.ReadFile(metapath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read directory metadata")
+	}
+
+	if err = verifyManifest(data, common.HexToHash(root)); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// verifyManifest verifies the directory manifest against the storage root of manifest.
+func verifyManifest(data []byte, root common.Hash) error {
+	iterable, err := core.NewDataInMemory(data)
+	if err != nil {
+		return errors.WithMessage(err, "failed to read directory metadata")
+	}
+
+	actual, err := core.MerkleRootData(iterable)
+	if err != nil {
+		return errors.WithMessage(err, "failed to calculate merkle root of directory metadata")
+	}
+
+	if actual != root {
+		err = errors.WithMessagef(ErrManifestMismatch, "expected %v, actual %v", root, actual)
+		return zg_common.WithErrorClass(err, zg_common.ErrorClassVerification)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/chaos"
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildFileTreeTamperedManifest(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{NumShard: 1}, shard.ShardConfig{NumShard: 1})
+	clients := network.ZgsClients()
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	folder := newTestFolder(t, 2)
+	_, root, err := uploader.UploadDir(context.Background(), folder, UploadOption{ExpectedReplica: 2})
+	assert.NoError(t, err)
+
+	// the 1st storage node serves tampered manifest, which is not detected without proof
+	injector := chaos.MustNew(chaos.CorruptSegments())
+	injector.Inject(clients[0])
+
+	downloader, err := NewDownloader(clients)
+	assert.NoError(t, err)
+	defer downloader.Close()
+
+	// fetched from the 2nd storage node, and the 1st reported
+	tree, err := BuildFileTree(context.Background(), downloader, root.Hex(), false)
+	assert.NoError(t, err)
+	assert.NotZero(t, injector.Injected()[chaos.ActionCorrupt])
+	_, relpaths := tree.Flatten()
+	assert.Contains(t, relpaths, "/file0.txt")
+	assert.Contains(t, relpaths, "/file1.txt")
+
+	assert.Equal(t, 1, downloader.Warnings().Count(WarningManifestRejected))
+	for _, warning := range downloader.Warnings().List() {
+		if warning.Code == WarningManifestRejected {
+			assert.Equal(t, clients[0].URL(), warning.Fields["node"])
+		}
+	}
+
+	// failed if all storage nodes tampered
+	injector.Inject(clients[1])
+	_, err = BuildFileTree(context.Background(), downloader, root.Hex(), false)
+	assert.ErrorContains(t, err, clients[0].URL())
+	assert.ErrorContains(t, err, clients[1].URL())
+	assert.Equal(t, zg_common.ErrorClassVerification, zg_common.ClassOf(err))
+
+	// bounded by max attempts
+	_, err = BuildFileTree(context.Background(), downloader, root.Hex(), false, DownloadDirOption{ManifestAttempts: 1})
+	assert.ErrorContains(t, err, "from 1 storage nodes")
+	assert.NotContains(t, err.Error(), clients[1].URL())
+}
+
+func TestBuildFileTreeManifestMismatch(t *testing.T) {
+	tree := dir.NewDirFsNode("root", []*dir.FsNode{dir.NewSymbolicFsNode("link", "target")})
+	manifest, err := dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+
+	// served by downloader without storage nodes exposed
+	downloader := memDirDownloader{}
+	root := downloader.add(t, manifest)
+	tampered := append([]byte(nil), manifest...)
+	tampered[len(tampered)-1] ^= 0xff
+	downloader[root] = tampered
+
+	_, err = BuildFileTree(context.Background(), downloader, root, true)
+	assert.ErrorIs(t, err, ErrManifestMismatch)
+	assert.Equal(t, zg_common.ErrorClassVerification, zg_common.ClassOf(err))
+}
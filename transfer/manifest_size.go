@@ -0,0 +1,127 @@
+package transfer
+
+import (
+	"context"
+	"math/bits"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// defaultManifestSizeDivisor divides the max file size of network into the default max size of directory
+// manifest, so that a manifest growing with the directory fails early with a safe margin rather than at submission.
+const defaultManifestSizeDivisor = 2
+
+// ErrManifestTooLarge is returned before uploading directory if the size of serialized manifest exceeds the limit,
+// and chunked manifest not enabled, see WithManifestSizeLimit and WithManifestChunking.
+var ErrManifestTooLarge = errors.New("manifest too large")
+
+// WithManifestSizeLimit sets the max size of serialized directory manifest to upload as a single file, which is
+// half of the max file size of network by default, and not limited if the network does not advertise one.
+// Passes 0 to use the default.
+func (uploader *Uploader) WithManifestSizeLimit(limit int64) *Uploader {
+	uploader.manifestLimit = limit
+	return uploader
+}
+
+// WithManifestChunking enables to upload directory manifest in chunks of at most the size limit if the manifest
+// exceeds, see WithManifestSizeLimit, which is disabled by default so that the upload fails early instead. Chunk
+// boundaries are determined by content, so that unchanged chunks are reused to patch directory, see
+// dir.SplitManifest and UploadDirPatch. Chunks are followed by a manifest index, of which the storage root is returned as the directory root, so that the
+// directory is downloaded from a single root as usual, see dir.ManifestIndex. Roots of chunks are reported by
+// DirUploadSummary.ManifestChunks.
+func (uploader *Uploader) WithManifestChunking(enabled bool) *Uploader {
+	uploader.manifestChunking = enabled
+	return uploader
+}
+
+// manifestSizeLimit returns the max size of serialized directory manifest, and 0 if not limited.
+func (uploader *Uploader) manifestSizeLimit(ctx context.Context) (int64, error) {
+	if uploader.manifestLimit > 0 {
+		return uploader.manifestLimit, nil
+	}
+
+	maxSize, err := uploader.maxFileSize(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(maxSize / defaultManifestSizeDivisor), nil
+}
+
+// checkManifestSize checks the size of serialized directory manifest against the limit, and returns the size of
+// chunks to upload the manifest in if exceeded and chunking allowed, otherwise 0 to upload as a single file.
+func (uploader *Uploader) checkManifestSize(ctx context.Context, manifest core.IterableData, chunking bool) (int64, error) {
+	limit, err := uploader.manifestSizeLimit(ctx)
+	if err != nil || limit == 0 || manifest.Size() <= limit {
+		return 0, err
+	}
+
+	if !chunking {
+		err = errors.WithMessagef(ErrManifestTooLarge, "size %v of directory manifest exceeds limit %v, "+
+			"please enable chunked manifest, e.g. by --manifest-chunking or WithManifestChunking",
+			zg_common.ByteSize(manifest.Size()), zg_common.ByteSize(limit))
+		return 0, zg_common.WithErrorClass(err, zg_common.ErrorClassUsage)
+	}
+
+	// chunks are aligned to power of 2 not larger than the limit
+	chunkSize := max(core.DefaultChunkSize, int64(1)<<(bits.Len64(uint64(limit))-1))
+
+	uploader.logger.WithField("size", zg_common.ByteSize(manifest.Size())).
+		WithField("limit", zg_common.ByteSize(limit)).
+		Infof("Directory manifest exceeds limit, uploaded in %v chunks", core.NumSplits(manifest.Size(), int(chunkSize)))
+
+	return chunkSize, nil
+}
+
+// splitManifest splits the directory manifest into chunks of at most chunkSize, see dir.SplitManifest, and returns
+// the chunks along with their storage roots.
+func splitManifest(manifest core.IterableData, chunkSize int64) ([]core.IterableData, []common.Hash, error) {
+	content := make([]byte, manifest.Size())
+	if _, err := manifest.Read(content, 0); err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to read directory manifest")
+	}
+
+	var chunks []core.IterableData
+	var roots []common.Hash
+	for _, content := range dir.SplitManifest(content, chunkSize) {
+		chunk, err := core.NewDataInMemory(content)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "failed to create `IterableData` in memory")
+		}
+
+		root, err := core.MerkleRootData(chunk)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "failed to calculate merkle root of manifest chunk")
+		}
+
+		chunks = append(chunks, chunk)
+		roots = append(roots, root)
+	}
+
+	return chunks, roots, nil
+}
+
+// manifestChunkRoots returns the storage roots of chunks of the directory manifest uploaded in chunks of at most
+// chunkSize.
+func (uploader *Uploader) manifestChunkRoots(root *dir.FsNode, chunkSize int64) (map[common.Hash]bool, error) {
+	manifest, _, err := uploader.encodeManifest(root)
+	if err != nil {
+		return nil, err
+	}
+
+	_, roots, err := splitManifest(manifest, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[common.Hash]bool)
+	for _, root := range roots {
+		result[root] = true
+	}
+
+	return result, nil
+}
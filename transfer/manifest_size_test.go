@@ -0,0 +1,115 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadDirManifestTooLarge(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	// fails before any file uploaded
+	uploader.WithManifestSizeLimit(64)
+	summary, err := uploader.UploadDirWithSummary(context.Background(), newTestFolder(t, 3))
+	assert.ErrorIs(t, err, ErrManifestTooLarge)
+	assert.Equal(t, zg_common.ErrorClassUsage, zg_common.ClassOf(err))
+	assert.Contains(t, err.Error(), "chunked manifest")
+	assert.Empty(t, summary.Files)
+	assert.Empty(t, network.Chain.Submissions())
+
+	// uploaded as a single file within the limit
+	uploader.WithManifestSizeLimit(1 << 20)
+	summary, err = uploader.UploadDirWithSummary(context.Background(), newTestFolder(t, 3))
+	assert.NoError(t, err)
+	assert.Empty(t, summary.ManifestChunks)
+	assert.Equal(t, 4, len(network.Chain.Submissions()))
+}
+
+func TestUploadDirManifestChunking(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	network.Nodes[0].SetMaxFileSize(2048)
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	folder := newTestFolder(t, 10)
+	tree, err := dir.BuildFileTree(folder)
+	assert.NoError(t, err)
+	manifest, err := dir.CanonicalBytes(tree)
+	assert.NoError(t, err)
+	assert.Greater(t, len(manifest), 1024)
+
+	// limited to half of the max file size of network by default
+	summary, err := uploader.UploadDirWithSummary(context.Background(), folder)
+	assert.ErrorIs(t, err, ErrManifestTooLarge)
+	assert.Contains(t, err.Error(), "exceeds limit 1KiB")
+	assert.Empty(t, summary.Agent.Features)
+
+	// switched to chunks of the limit automatically
+	uploader.WithManifestChunking(true)
+	summary, err = uploader.UploadDirWithSummary(context.Background(), folder)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(summary.ManifestChunks), int((len(manifest)+1023)/1024))
+	assert.Equal(t, []string{zg_common.FeatureChunkedManifest}, summary.Agent.Features)
+	assert.NotEqual(t, common.Hash{}, summary.TxHash)
+
+	// chunks followed by manifest index
+	submissions := network.Chain.Submissions()
+	assert.Equal(t, summary.Root, submissions[len(submissions)-1].Root())
+
+	// manifest assembled by chunks from the root of manifest index
+	downloader, err := NewDownloader(network.ZgsClients())
+	assert.NoError(t, err)
+	downloaded, err := BuildFileTree(context.Background(), downloader, summary.Root.Hex(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, len(downloaded.Entries))
+
+	// directories paged by fetching chunks on demand
+	source, err := BuildTreeSource(context.Background(), downloader, summary.Root.Hex(), true)
+	assert.NoError(t, err)
+	page, total, err := source.ReadDirPage(context.Background(), "", nil, 3, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, total)
+	for i, entry := range page {
+		assert.Equal(t, downloaded.Entries[3+i].Name, entry.Name)
+		assert.Equal(t, downloaded.Entries[3+i].Root, entry.Root)
+	}
+
+	target := filepath.Join(t.TempDir(), "folder")
+	assert.NoError(t, DownloadDir(context.Background(), downloader, summary.Root.Hex(), target, true))
+	for _, entry := range tree.Entries {
+		expected, err := os.ReadFile(filepath.Join(folder, entry.Name))
+		assert.NoError(t, err)
+		actual, err := os.ReadFile(filepath.Join(target, entry.Name))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	// patched directory uploaded in chunks as well
+	patchFolder := t.TempDir()
+	content := []byte("new file")
+	assert.NoError(t, os.WriteFile(filepath.Join(patchFolder, "new.txt"), content, 0644))
+	root, err := core.MerkleRoot(filepath.Join(patchFolder, "new.txt"))
+	assert.NoError(t, err)
+	_, patchRoot, patched, err := uploader.UploadDirPatch(context.Background(), downloaded, patchFolder, []dir.PatchOp{
+		dir.Put("new.txt", dir.NewFileFsNode("", root, int64(len(content)))),
+	})
+	assert.NoError(t, err)
+	downloaded, err = BuildFileTree(context.Background(), downloader, patchRoot.Hex(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 11, len(downloaded.Entries))
+	assert.Equal(t, len(patched.Entries), len(downloaded.Entries))
+}
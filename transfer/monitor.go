@@ -0,0 +1,437 @@
+package transfer
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMonitorMinInterval = time.Second
+	defaultMonitorMaxInterval = time.Minute
+	defaultMonitorBatchSize   = 100
+	defaultMonitorWorkers     = 4
+
+	// monitorIntervalRatio is the ratio of poll interval to the age of tracked root, so that young submissions
+	// are polled frequently, and the interval grows as time goes by.
+	monitorIntervalRatio = 0.1
+)
+
+// FileStatus is the status of file on storage node.
+type FileStatus string
+
+const (
+	FileStatusUnknown   FileStatus = "unknown"   // not polled yet
+	FileStatusNotFound  FileStatus = "not_found" // log entry not synced by storage node yet
+	FileStatusAvailable FileStatus = "available" // log entry available, but not finalized yet
+	FileStatusFinalized FileStatus = "finalized" // all segments uploaded and finalized
+	FileStatusPruned    FileStatus = "pruned"    // file data pruned
+)
+
+// fileStatusOf returns the status of file by file info, which is nil if not available.
+func fileStatusOf(info *node.FileInfo) FileStatus {
+	switch {
+	case info == nil:
+		return FileStatusNotFound
+	case info.Finalized:
+		return FileStatusFinalized
+	case info.Pruned:
+		return FileStatusPruned
+	default:
+		return FileStatusAvailable
+	}
+}
+
+// terminal returns whether the status will not change any more, and the root is no longer tracked.
+func (status FileStatus) terminal() bool {
+	return status == FileStatusFinalized || status == FileStatusPruned
+}
+
+// MonitorEvent is the status transition of a tracked root.
+type MonitorEvent struct {
+	Root common.Hash
+	From FileStatus
+	To   FileStatus
+	Node string         // URL of storage node that reported the transition
+	Info *node.FileInfo // file info reported, nil if not found
+	Time time.Time
+}
+
+// MonitorOption is the option to monitor file status.
+type MonitorOption struct {
+	MinInterval time.Duration // min interval to poll a root, default 1 second
+	MaxInterval time.Duration // max interval to poll a root, default 1 minute
+	BatchSize   int           // number of roots to query in a single batch RPC, default 100
+	Workers     int           // max number of outstanding batch RPCs, default 4
+}
+
+// Validate checks the option, and returns an error that names the invalid field if any.
+func (opt *MonitorOption) Validate() error {
+	if err := zg_common.FirstError(
+		zg_common.RequireNonNegative("MinInterval", opt.MinInterval),
+		zg_common.RequireNonNegative("MaxInterval", opt.MaxInterval),
+		zg_common.RequireNonNegative("BatchSize", opt.BatchSize),
+		zg_common.RequireNonNegative("Workers", opt.Workers),
+	); err != nil {
+		return err
+	}
+
+	if opt.MaxInterval > 0 && opt.MaxInterval < opt.MinInterval {
+		return zg_common.NewOptionError("MaxInterval", "should not be less than MinInterval %v, got %v", opt.MinInterval, opt.MaxInterval)
+	}
+
+	return nil
+}
+
+// monitorEntry is a tracked root, which is scheduled by the next time to poll.
+type monitorEntry struct {
+	root   common.Hash
+	status FileStatus
+	added  time.Time
+	next   time.Time
+	index  int // index in schedule heap, -1 if being polled or removed
+}
+
+// monitorSchedule is a min-heap of tracked roots by the next time to poll.
+type monitorSchedule []*monitorEntry
+
+func (s monitorSchedule) Len() int           { return len(s) }
+func (s monitorSchedule) Less(i, j int) bool { return s[i].next.Before(s[j].next) }
+
+func (s monitorSchedule) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+	s[i].index, s[j].index = i, j
+}
+
+func (s *monitorSchedule) Push(x interface{}) {
+	entry := x.(*monitorEntry)
+	entry.index = len(*s)
+	*s = append(*s, entry)
+}
+
+func (s *monitorSchedule) Pop() interface{} {
+	old := *s
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.index = -1
+	*s = old[:len(old)-1]
+	return entry
+}
+
+// monitorOp is an add or remove of root to track.
+type monitorOp struct {
+	root   common.Hash
+	remove bool
+}
+
+// monitorBatch is a batch of roots to poll, along with the result.
+type monitorBatch struct {
+	entries []*monitorEntry
+	infos   []*node.FileInfo
+	errs    []error // per-root errors
+	node    string  // URL of storage node that responded
+	err     error   // batch error, e.g. all storage nodes unavailable
+}
+
+// Monitor tracks the status of a dynamic set of roots on storage nodes, and delivers status transitions, e.g.
+// when files become finalized, so that integrations need not write polling loops.
+//
+// Tracked roots are polled in batches at adaptive intervals, which is 1/10 of the time since added, bounded by
+// MinInterval and MaxInterval. So, young submissions are polled frequently, while the stale ones slowly. Batches
+// are polled by a bounded number of workers, which fail over to the next storage node upon RPC failure. Roots are
+// no longer tracked once finalized or pruned.
+//
+// Monitor is safe for concurrent use.
+type Monitor struct {
+	clients []*node.ZgsClient
+	opt     MonitorOption
+	events  chan MonitorEvent
+	now     func() time.Time
+
+	mu      sync.Mutex
+	ops     []monitorOp   // adds and removes in order, which are not applied by scheduler yet
+	tracked int           // number of roots tracked by scheduler
+	wake    chan struct{} // notifies the scheduler of adds and removes
+
+	preferred atomic.Int64 // index of storage node to poll first
+}
+
+// NewMonitor creates a monitor to poll the specified storage nodes. Call Run to start polling.
+func NewMonitor(clients []*node.ZgsClient, option ...MonitorOption) (*Monitor, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("storage node not specified")
+	}
+
+	var opt MonitorOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	if err := opt.Validate(); err != nil {
+		return nil, err
+	}
+
+	if opt.MinInterval == 0 {
+		opt.MinInterval = defaultMonitorMinInterval
+	}
+
+	if opt.MaxInterval == 0 {
+		opt.MaxInterval = max(defaultMonitorMaxInterval, opt.MinInterval)
+	}
+
+	if opt.BatchSize == 0 {
+		opt.BatchSize = defaultMonitorBatchSize
+	}
+
+	if opt.Workers == 0 {
+		opt.Workers = defaultMonitorWorkers
+	}
+
+	return &Monitor{
+		clients: clients,
+		opt:     opt,
+		events:  make(chan MonitorEvent),
+		now:     time.Now,
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// Events returns the channel to receive status transitions, which is closed once Run returns. Events are
+// buffered by monitor without blocking the polling, so consumers should keep receiving.
+func (monitor *Monitor) Events() <-chan MonitorEvent {
+	return monitor.events
+}
+
+// Add starts to track the specified roots, and roots already tracked are ignored.
+func (monitor *Monitor) Add(roots ...common.Hash) {
+	monitor.enqueue(roots, false)
+}
+
+// Remove stops tracking the specified roots, and no more events will be delivered for them, except those
+// already buffered.
+func (monitor *Monitor) Remove(roots ...common.Hash) {
+	monitor.enqueue(roots, true)
+}
+
+func (monitor *Monitor) enqueue(roots []common.Hash, remove bool) {
+	monitor.mu.Lock()
+	for _, root := range roots {
+		monitor.ops = append(monitor.ops, monitorOp{root, remove})
+	}
+	monitor.mu.Unlock()
+
+	// notifies the scheduler without blocking
+	select {
+	case monitor.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of roots tracked. Note, roots are added or removed asynchronously.
+func (monitor *Monitor) Len() int {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+
+	return monitor.tracked
+}
+
+// interval returns the interval to poll the root by its age.
+func (monitor *Monitor) interval(entry *monitorEntry, now time.Time) time.Duration {
+	interval := time.Duration(float64(now.Sub(entry.added)) * monitorIntervalRatio)
+	return min(max(interval, monitor.opt.MinInterval), monitor.opt.MaxInterval)
+}
+
+// Run polls the tracked roots until ctx done, and closes the events channel upon return.
+func (monitor *Monitor) Run(ctx context.Context) error {
+	defer close(monitor.events)
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	// workers poll batches, which bounds the outstanding RPCs
+	batches := make(chan *monitorBatch, monitor.opt.Workers)
+	results := make(chan *monitorBatch, monitor.opt.Workers)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	for i := 0; i < monitor.opt.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for batch := range batches {
+				monitor.poll(ctx, batch)
+				results <- batch
+			}
+		}()
+	}
+	defer close(batches)
+
+	entries := make(map[common.Hash]*monitorEntry)
+	var schedule monitorSchedule
+	var pending []MonitorEvent // events not delivered yet
+	var outstanding int
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		now := monitor.now()
+
+		monitor.apply(entries, &schedule, now)
+
+		// dispatch due roots in batches
+		for outstanding < monitor.opt.Workers && len(schedule) > 0 && !schedule[0].next.After(now) {
+			batch := &monitorBatch{}
+			for len(batch.entries) < monitor.opt.BatchSize && len(schedule) > 0 && !schedule[0].next.After(now) {
+				batch.entries = append(batch.entries, heap.Pop(&schedule).(*monitorEntry))
+			}
+
+			batches <- batch
+			outstanding++
+		}
+
+		// wait for the next due root, or wake up by others
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		var due <-chan time.Time
+		if outstanding < monitor.opt.Workers && len(schedule) > 0 {
+			timer.Reset(schedule[0].next.Sub(now))
+			due = timer.C
+		}
+
+		var events chan<- MonitorEvent
+		var event MonitorEvent
+		if len(pending) > 0 {
+			events, event = monitor.events, pending[0]
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-monitor.wake:
+		case <-due:
+		case events <- event:
+			pending[0] = MonitorEvent{}
+			pending = pending[1:]
+		case batch := <-results:
+			outstanding--
+			pending = monitor.handle(batch, entries, &schedule, pending)
+		}
+	}
+}
+
+// apply applies the adds and removes of roots to tracked entries.
+func (monitor *Monitor) apply(entries map[common.Hash]*monitorEntry, schedule *monitorSchedule, now time.Time) {
+	monitor.mu.Lock()
+	ops := monitor.ops
+	monitor.ops = nil
+	monitor.mu.Unlock()
+
+	if len(ops) == 0 {
+		return
+	}
+
+	for _, op := range ops {
+		entry, ok := entries[op.root]
+
+		if !op.remove && !ok {
+			entry = &monitorEntry{root: op.root, status: FileStatusUnknown, added: now, next: now}
+			entries[op.root] = entry
+			heap.Push(schedule, entry)
+		} else if op.remove && ok {
+			delete(entries, op.root)
+			if entry.index >= 0 {
+				heap.Remove(schedule, entry.index)
+			}
+		}
+	}
+
+	monitor.mu.Lock()
+	monitor.tracked = len(entries)
+	monitor.mu.Unlock()
+}
+
+// handle updates the tracked roots by polled batch, and returns the pending events appended with transitions.
+func (monitor *Monitor) handle(batch *monitorBatch, entries map[common.Hash]*monitorEntry, schedule *monitorSchedule, pending []MonitorEvent) []MonitorEvent {
+	now := monitor.now()
+
+	for i, entry := range batch.entries {
+		// removed while polling
+		if entries[entry.root] != entry {
+			continue
+		}
+
+		if batch.err == nil && batch.errs[i] == nil {
+			if status := fileStatusOf(batch.infos[i]); status != entry.status {
+				pending = append(pending, MonitorEvent{
+					Root: entry.root,
+					From: entry.status,
+					To:   status,
+					Node: batch.node,
+					Info: batch.infos[i],
+					Time: now,
+				})
+
+				entry.status = status
+			}
+		}
+
+		if entry.status.terminal() {
+			delete(entries, entry.root)
+			continue
+		}
+
+		entry.next = now.Add(monitor.interval(entry, now))
+		heap.Push(schedule, entry)
+	}
+
+	monitor.mu.Lock()
+	monitor.tracked = len(entries)
+	monitor.mu.Unlock()
+
+	return pending
+}
+
+// poll queries the file info of roots in batch, and fails over to the next storage node upon RPC failure.
+func (monitor *Monitor) poll(ctx context.Context, batch *monitorBatch) {
+	roots := make([]common.Hash, len(batch.entries))
+	for i, entry := range batch.entries {
+		roots[i] = entry.root
+	}
+
+	preferred := int(monitor.preferred.Load())
+
+	for i := range monitor.clients {
+		index := (preferred + i) % len(monitor.clients)
+		client := monitor.clients[index]
+
+		infos, errs, err := client.BatchGetFileInfo(ctx, roots)
+		if err != nil {
+			batch.err = errors.WithMessagef(err, "Failed to query file info from node %v", client.URL())
+			continue
+		}
+
+		batch.infos, batch.errs, batch.node, batch.err = infos, errs, client.URL(), nil
+
+		// prefer the available storage node in later polls
+		if index != preferred {
+			monitor.preferred.Store(int64(index))
+		}
+
+		return
+	}
+}
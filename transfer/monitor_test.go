@@ -0,0 +1,191 @@
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMonitorContent generates random content of the specified size, and returns the content and segments.
+func newMonitorContent(t *testing.T, size int) ([]byte, []node.SegmentWithProof) {
+	content := make([]byte, size)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(t, err)
+
+	var segments []node.SegmentWithProof
+	for i := 0; i*core.DefaultSegmentSize < size; i++ {
+		segments = append(segments, node.SegmentWithProof{
+			Root:     tree.Root(),
+			Data:     content[i*core.DefaultSegmentSize : min(size, (i+1)*core.DefaultSegmentSize)],
+			Index:    uint64(i),
+			Proof:    tree.ProofAt(i),
+			FileSize: uint64(size),
+		})
+	}
+
+	return content, segments
+}
+
+// runMonitor runs the monitor until test completed.
+func runMonitor(t *testing.T, monitor *Monitor) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- monitor.Run(ctx) }()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+}
+
+func receiveEvent(t *testing.T, monitor *Monitor) MonitorEvent {
+	select {
+	case event := <-monitor.Events():
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not received in time")
+		return MonitorEvent{}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	service := testutil.NewZgsService()
+	client := newMockZgsNode(t, service)
+
+	// first node is unavailable
+	dead := httptest.NewServer(nil)
+	dead.Close()
+	deadClient, err := node.NewZgsClient(dead.URL)
+	assert.NoError(t, err)
+	t.Cleanup(func() { deadClient.Close() })
+
+	monitor, err := NewMonitor([]*node.ZgsClient{deadClient, client}, MonitorOption{MinInterval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond})
+	assert.NoError(t, err)
+	runMonitor(t, monitor)
+
+	content1, segments1 := newMonitorContent(t, core.DefaultSegmentSize)
+	root1 := submit(t, service, content1)
+	content2, _ := newMonitorContent(t, core.DefaultChunkSize)
+	root2 := segmentsRoot(t, content2)
+
+	monitor.Add(root1)
+	event := receiveEvent(t, monitor)
+	assert.Equal(t, root1, event.Root)
+	assert.Equal(t, FileStatusUnknown, event.From)
+	assert.Equal(t, FileStatusAvailable, event.To)
+	assert.Equal(t, client.URL(), event.Node)
+
+	// not submitted yet
+	monitor.Add(root2, root2)
+	event = receiveEvent(t, monitor)
+	assert.Equal(t, root2, event.Root)
+	assert.Equal(t, FileStatusNotFound, event.To)
+	assert.Nil(t, event.Info)
+	assert.Eventually(t, func() bool { return monitor.Len() == 2 }, time.Second, time.Millisecond)
+
+	submit(t, service, content2)
+	event = receiveEvent(t, monitor)
+	assert.Equal(t, root2, event.Root)
+	assert.Equal(t, FileStatusNotFound, event.From)
+	assert.Equal(t, FileStatusAvailable, event.To)
+
+	// no longer tracked once finalized
+	_, err = service.UploadSegmentsByTxSeq(segments1, 0)
+	assert.NoError(t, err)
+	event = receiveEvent(t, monitor)
+	assert.Equal(t, root1, event.Root)
+	assert.Equal(t, FileStatusAvailable, event.From)
+	assert.Equal(t, FileStatusFinalized, event.To)
+	assert.True(t, event.Info.Finalized)
+	assert.Eventually(t, func() bool { return monitor.Len() == 1 }, time.Second, time.Millisecond)
+
+	// no more events once removed
+	monitor.Remove(root2)
+	assert.Eventually(t, func() bool { return monitor.Len() == 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, service.Prune(root2))
+	select {
+	case event = <-monitor.Events():
+		t.Fatalf("unexpected event %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// segmentsRoot returns the merkle root of content.
+func segmentsRoot(t *testing.T, content []byte) common.Hash {
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(t, err)
+
+	return tree.Root()
+}
+
+func TestMonitorInterval(t *testing.T) {
+	monitor, err := NewMonitor([]*node.ZgsClient{{}}, MonitorOption{MinInterval: time.Second, MaxInterval: time.Minute})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	entry := monitorEntry{added: now}
+	assert.Equal(t, time.Second, monitor.interval(&entry, now))
+	assert.Equal(t, time.Second, monitor.interval(&entry, now.Add(5*time.Second)))
+	assert.Equal(t, 3*time.Second, monitor.interval(&entry, now.Add(30*time.Second)))
+	assert.Equal(t, time.Minute, monitor.interval(&entry, now.Add(time.Hour)))
+
+	_, err = NewMonitor([]*node.ZgsClient{{}}, MonitorOption{MinInterval: time.Minute, MaxInterval: time.Second})
+	assert.Error(t, err)
+}
+
+func TestMonitorStress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+
+	const numRoots = 10_000
+
+	service := testutil.NewZgsService()
+	client := newMockZgsNode(t, service)
+
+	monitor, err := NewMonitor([]*node.ZgsClient{client}, MonitorOption{MinInterval: 50 * time.Millisecond, MaxInterval: 200 * time.Millisecond})
+	assert.NoError(t, err)
+	runMonitor(t, monitor)
+
+	roots := make([]common.Hash, numRoots)
+	for i := range roots {
+		content, _ := newMonitorContent(t, 64)
+		roots[i] = submit(t, service, content)
+	}
+	monitor.Add(roots...)
+
+	received := func(status FileStatus, n int) {
+		seen := make(map[common.Hash]bool)
+		for len(seen) < n {
+			event := receiveEvent(t, monitor)
+			assert.Equal(t, status, event.To)
+			seen[event.Root] = true
+		}
+	}
+
+	received(FileStatusAvailable, numRoots)
+	assert.Equal(t, numRoots, monitor.Len())
+
+	// half pruned
+	for _, root := range roots[:numRoots/2] {
+		assert.NoError(t, service.Prune(root))
+	}
+
+	received(FileStatusPruned, numRoots/2)
+	assert.Eventually(t, func() bool { return monitor.Len() == numRoots/2 }, time.Second, time.Millisecond)
+}
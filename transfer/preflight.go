@@ -0,0 +1,146 @@
+package transfer
+
+import (
+	"context"
+	"math/big"
+	"math/bits"
+	"strings"
+	"sync"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/pkg/errors"
+)
+
+// preflightGasLimit is the gas reserved to check balance of sender if blockchain.CustomGasLimit not specified,
+// which is far more than a flow submission costs.
+const preflightGasLimit = 1_000_000
+
+var (
+	// ErrFileTooLarge is returned before uploading if the padded size of file exceeds the max file size of network.
+	ErrFileTooLarge = errors.New("file too large")
+
+	// ErrInsufficientBalance is returned before sending transaction if the balance of sender is not enough to pay
+	// the storage fee and gas.
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
+// maxFileSizeCache caches the max file size of network, which is queried from storage nodes once.
+type maxFileSizeCache struct {
+	mu     sync.Mutex
+	loaded bool
+	size   uint64 // 0 if not advertised by any storage node
+}
+
+// maxFileSize returns the max padded size of file to submit, which is the min one advertised by storage nodes,
+// and 0 if not advertised by any.
+func (uploader *Uploader) maxFileSize(ctx context.Context) (uint64, error) {
+	cache := &uploader.maxSize
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.loaded {
+		return cache.size, nil
+	}
+
+	var size uint64
+	for _, client := range uploader.clients {
+		status, err := client.GetStatus(ctx)
+		if err != nil {
+			return 0, errors.WithMessagef(err, "Failed to get status from storage node %v", client.URL())
+		}
+
+		if status.MaxFileSize > 0 && (size == 0 || status.MaxFileSize < size) {
+			size = status.MaxFileSize
+		}
+	}
+
+	cache.loaded, cache.size = true, size
+
+	return size, nil
+}
+
+// checkFileSize checks the padded size of each data against the max file size of network.
+func (uploader *Uploader) checkFileSize(ctx context.Context, datas []core.IterableData) error {
+	maxSize, err := uploader.maxFileSize(ctx)
+	if err != nil || maxSize == 0 {
+		return err
+	}
+
+	// fragments are aligned to power of 2, whose padded size is the same as fragment size
+	maxFragmentSize := uint64(1) << (bits.Len64(maxSize) - 1)
+
+	for _, data := range datas {
+		if paddedSize := core.StorageFootprint(data.Size()).PaddedSize; paddedSize > maxSize {
+			err = errors.WithMessagef(ErrFileTooLarge, "padded size %v of file exceeds max file size %v of network, "+
+				"please split into fragments of at most %v, e.g. by --fragment-size or SplitableUpload",
+				zg_common.ByteSize(paddedSize), zg_common.ByteSize(maxSize), zg_common.ByteSize(maxFragmentSize))
+			return zg_common.WithErrorClass(err, zg_common.ErrorClassUsage)
+		}
+	}
+
+	return nil
+}
+
+// checkBalance checks the balance of sender against the storage fee of datas and the gas to submit, where fee
+// overrides the storage fee if specified.
+//
+// It is skipped if transactions are spread across SenderPool, which removes the account of insufficient funds
+// from rotation instead.
+func (uploader *Uploader) checkBalance(ctx context.Context, datas []core.IterableData, fee, nonce *big.Int) error {
+	if uploader.senders != nil && nonce == nil {
+		return nil
+	}
+
+	storageFee := fee
+	if storageFee == nil {
+		pricePerSector, err := uploader.market.PricePerSector(&bind.CallOpts{Context: ctx})
+		if err != nil {
+			return errors.WithMessage(err, "Failed to read price per sector")
+		}
+
+		var sectors uint64
+		for _, data := range datas {
+			sectors += core.StorageFootprint(data.Size()).Sectors
+		}
+		storageFee = new(big.Int).Mul(new(big.Int).SetUint64(sectors), pricePerSector)
+	}
+
+	gasPrice, err := uploader.flow.GasPrice(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to get gas price")
+	}
+
+	gasLimit := blockchain.CustomGasLimit
+	if gasLimit == 0 {
+		gasLimit = preflightGasLimit
+	}
+	gasFee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasLimit))
+
+	balance, err := uploader.flow.Balance(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to get balance of sender")
+	}
+
+	required := new(big.Int).Add(storageFee, gasFee)
+	if balance.Cmp(required) >= 0 {
+		return nil
+	}
+
+	err = errors.WithMessagef(ErrInsufficientBalance, "balance of account %v is %v a0gi, but %v a0gi required, "+
+		"including storage fee %v a0gi and gas up to %v a0gi, please fund the account with %v a0gi at least",
+		uploader.flow.Account(), formatA0GI(balance), formatA0GI(required), formatA0GI(storageFee), formatA0GI(gasFee),
+		formatA0GI(new(big.Int).Sub(required, balance)))
+
+	return zg_common.WithErrorClass(err, zg_common.ErrorClassTransaction)
+}
+
+// formatA0GI formats the amount in neuron to a0gi without precision loss, e.g. 0.0001.
+func formatA0GI(neuron *big.Int) string {
+	s := new(big.Rat).SetFrac(neuron, big.NewInt(1e18)).FloatString(18)
+	s = strings.TrimRight(s, "0")
+
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,115 @@
+package transfer
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreflightMaxFileSize(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	network.Nodes[0].SetMaxFileSize(core.DefaultSegmentSize)
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	// padded size equals to the max file size
+	_, data := newTestData(t, core.DefaultSegmentSize)
+	_, _, err = uploader.Upload(context.Background(), data)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(network.Chain.Submissions()))
+
+	// padded size exceeds the max file size by one more chunk
+	_, data = newTestData(t, core.DefaultSegmentSize+1)
+	_, _, err = uploader.Upload(context.Background(), data)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+	assert.Equal(t, zg_common.ErrorClassUsage, zg_common.ClassOf(err))
+	assert.Contains(t, err.Error(), "fragments of at most 256KiB")
+	assert.Equal(t, 1, len(network.Chain.Submissions()))
+
+	// uploaded in fragments
+	content, _ := newTestData(t, 2*core.DefaultSegmentSize)
+	path := filepath.Join(t.TempDir(), "data")
+	assert.NoError(t, os.WriteFile(path, content, 0644))
+	file, err := core.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	_, roots, err := uploader.SplitableUpload(context.Background(), file, core.DefaultSegmentSize)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(roots))
+
+	// max file size cached
+	network.Nodes[0].SetMaxFileSize(0)
+	_, _, err = uploader.Upload(context.Background(), data)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+
+	// skipped
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{SkipPreflight: true})
+	assert.NoError(t, err)
+}
+
+func TestPreflightBalance(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	key, err := crypto.HexToECDSA(testutil.PrivateKey[2:])
+	assert.NoError(t, err)
+	account := crypto.PubkeyToAddress(key.PublicKey)
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	// storage fee of 1024 sectors and gas of 1M at 1 gwei
+	_, data := newTestData(t, core.DefaultSegmentSize)
+	required := big.NewInt(1024*1_000_000_000 + 1_000_000*1_000_000_000)
+
+	network.Chain.SetBalance(account, big.NewInt(0))
+	_, _, err = uploader.Upload(context.Background(), data)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	assert.Equal(t, zg_common.ErrorClassTransaction, zg_common.ClassOf(err))
+	assert.Contains(t, err.Error(), "balance of account "+account.Hex()+" is 0 a0gi, but 0.001001024 a0gi required")
+	assert.Contains(t, err.Error(), "please fund the account with 0.001001024 a0gi at least")
+
+	// checked before submission if transaction may be skipped
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{SkipTx: true})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	// one neuron short
+	network.Chain.SetBalance(account, new(big.Int).Sub(required, big.NewInt(1)))
+	_, _, err = uploader.Upload(context.Background(), data)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	assert.Contains(t, err.Error(), "with 0.000000000000000001 a0gi at least")
+	_, _, err = uploader.BatchUpload(context.Background(), []core.IterableData{data})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	assert.Empty(t, network.Chain.Submissions())
+
+	// checked against the specified fee
+	network.Chain.SetBalance(account, required)
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{Fee: big.NewInt(1024*1_000_000_000 + 1)})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{Fee: big.NewInt(1024 * 1_000_000_000)})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(network.Chain.Submissions()))
+
+	// skipped
+	network.Chain.SetBalance(account, big.NewInt(0))
+	_, data = newTestData(t, core.DefaultSegmentSize)
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{SkipPreflight: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(network.Chain.Submissions()))
+
+	// balance exactly enough
+	network.Chain.SetBalance(account, required)
+	_, data = newTestData(t, core.DefaultSegmentSize)
+	_, _, err = uploader.Upload(context.Background(), data)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(network.Chain.Submissions()))
+}
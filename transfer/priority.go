@@ -0,0 +1,59 @@
+package transfer
+
+import (
+	"context"
+
+	"github.com/0glabs/0g-storage-client/common/parallel"
+)
+
+// Priority is the priority of transfer to run segment tasks in a shared pool, see Uploader.WithPool and
+// Downloader.WithPool. Higher priority tasks are scheduled first.
+type Priority int
+
+const (
+	PriorityBackground  Priority = -1 // e.g. repair and audit jobs
+	PriorityNormal      Priority = 0  // default
+	PriorityInteractive Priority = 1  // e.g. downloads requested by users
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of context that transfers with the specified priority.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the transfer priority of context, or PriorityNormal if not specified.
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+
+	return PriorityNormal
+}
+
+// pooledTasks runs each task in a slot of the shared pool by the priority of context.
+type pooledTasks struct {
+	parallel.Interface
+	pool *parallel.PriorityPool
+}
+
+// withPool returns the parallelizable that runs tasks in the pool, or as it is if pool not specified.
+func withPool(parallelizable parallel.Interface, pool *parallel.PriorityPool) parallel.Interface {
+	if pool == nil {
+		return parallelizable
+	}
+
+	return &pooledTasks{parallelizable, pool}
+}
+
+// ParallelDo implements the parallel.Interface interface.
+func (tasks *pooledTasks) ParallelDo(ctx context.Context, routine, task int) (interface{}, error) {
+	release, err := tasks.pool.Acquire(ctx, int(PriorityFromContext(ctx)))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return tasks.Interface.ParallelDo(ctx, routine, task)
+}
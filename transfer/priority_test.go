@@ -0,0 +1,75 @@
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/parallel"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, PriorityNormal, PriorityFromContext(ctx))
+	assert.Equal(t, PriorityBackground, PriorityFromContext(WithPriority(ctx, PriorityBackground)))
+}
+
+func TestDownloadWithPriority(t *testing.T) {
+	service := testutil.NewZgsService()
+	service.SetHooks(testutil.ZgsHooks{DownloadDelay: 50 * time.Millisecond})
+	client := newMockZgsNode(t, service)
+
+	submit := func(numSegments int) string {
+		content := make([]byte, numSegments*core.DefaultSegmentSize)
+		_, err := rand.Read(content)
+		assert.NoError(t, err)
+
+		root := submit(t, service, content)
+		data, err := core.NewDataInMemory(content)
+		assert.NoError(t, err)
+		uploader := &Uploader{clients: []*node.ZgsClient{client}, routines: 4, logger: zg_common.NewLogger(), warnings: NewWarnings(0)}
+		_, _, err = uploader.Upload(context.Background(), data, UploadOption{SkipTx: true})
+		assert.NoError(t, err)
+
+		return root.Hex()
+	}
+	large, small := submit(32), submit(2)
+
+	pool, err := parallel.NewPriorityPool(2, map[int]int{int(PriorityBackground): 1})
+	assert.NoError(t, err)
+	downloader, err := NewDownloader([]*node.ZgsClient{client})
+	assert.NoError(t, err)
+	downloader.WithRoutines(8).WithPool(pool)
+
+	// large background job saturates the pool
+	dir := t.TempDir()
+	var backgroundDone atomic.Bool
+	backgroundErr := make(chan error)
+	go func() {
+		ctx := WithPriority(context.Background(), PriorityBackground)
+		err := downloader.Download(ctx, large, filepath.Join(dir, "large"), false)
+		backgroundDone.Store(true)
+		backgroundErr <- err
+	}()
+	assert.Eventually(t, func() bool { return pool.QueueDepth(int(PriorityBackground)) > 0 }, time.Second, time.Millisecond)
+
+	// interactive request admitted promptly, rather than queued after the background job
+	ctx := WithPriority(context.Background(), PriorityInteractive)
+	assert.NoError(t, downloader.Download(ctx, small, filepath.Join(dir, "small"), false))
+	assert.False(t, backgroundDone.Load())
+
+	// background lane keeps running with floor
+	assert.NoError(t, <-backgroundErr)
+	for _, stats := range pool.Stats() {
+		assert.Zero(t, stats.Running)
+		assert.Zero(t, stats.Queued)
+	}
+}
@@ -0,0 +1,152 @@
+package transfer
+
+import (
+	"runtime"
+	"sort"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/pkg/errors"
+)
+
+// Names of predefined transfer profiles.
+const (
+	ProfileNameFast     = "fast"
+	ProfileNameReliable = "reliable"
+	ProfileNameCheap    = "cheap"
+)
+
+// Profile is a set of transfer settings, which could be started from a predefined profile and then overridden
+// as needed, e.g.
+//
+//	profile := transfer.ReliableProfile()
+//	profile.Upload.ExpectedReplica = 3
+//	uploader.WithProfile(profile)
+type Profile struct {
+	Name         string       `json:"name"`
+	Routines     int          `json:"routines"`     // number of go routines to transfer in parallel
+	Singleflight bool         `json:"singleflight"` // deduplicate concurrent uploads of the same data
+	BatchSize    uint         `json:"batchSize"`    // number of files submitted in a single transaction when uploading directory, 0 or 1 for individually
+	WithProof    bool         `json:"withProof"`    // verify downloaded segments with merkle proof
+	Upload       UploadOption `json:"upload"`       // default upload option if not specified
+}
+
+// defaultVerifySamples is the number of segments sampled on each storage node to verify upload in reliable profile.
+const defaultVerifySamples = 2
+
+var profiles = map[string]func() Profile{
+	ProfileNameFast:     FastProfile,
+	ProfileNameReliable: ReliableProfile,
+	ProfileNameCheap:    CheapProfile,
+}
+
+// FastProfile returns settings for lowest latency: high concurrency, only wait for transaction packed,
+// and no merkle proof verification on download.
+func FastProfile() Profile {
+	return Profile{
+		Name:         ProfileNameFast,
+		Routines:     runtime.GOMAXPROCS(0) * 2,
+		Singleflight: true,
+		Upload: UploadOption{
+			FinalityRequired: TransactionPacked,
+			TaskSize:         defaultTaskSize * 2,
+			ExpectedReplica:  1,
+			SkipTx:           true,
+		},
+	}
+}
+
+// ReliableProfile returns settings for durability: at least 2 replicas, wait for file finalized, sample
+// uploaded segments on each storage node to verify, and verify merkle proof on download.
+func ReliableProfile() Profile {
+	return Profile{
+		Name:         ProfileNameReliable,
+		Routines:     runtime.GOMAXPROCS(0),
+		Singleflight: true,
+		WithProof:    true,
+		Upload: UploadOption{
+			FinalityRequired: FileFinalized,
+			TaskSize:         defaultTaskSize,
+			ExpectedReplica:  2,
+			SkipTx:           true,
+			VerifySamples:    defaultVerifySamples,
+		},
+	}
+}
+
+// CheapProfile returns settings for lowest cost: skip transaction if data already exists, batch files
+// into a single transaction when uploading directory, and single replica.
+func CheapProfile() Profile {
+	return Profile{
+		Name:         ProfileNameCheap,
+		Routines:     max(runtime.GOMAXPROCS(0)/2, 1),
+		Singleflight: true,
+		BatchSize:    defaultBatchSize,
+		Upload: UploadOption{
+			FinalityRequired: TransactionPacked,
+			TaskSize:         defaultTaskSize,
+			ExpectedReplica:  1,
+			SkipTx:           true,
+		},
+	}
+}
+
+// Validate checks the profile, and returns an error that names the invalid field if any.
+func (profile *Profile) Validate() error {
+	return zg_common.FirstError(
+		zg_common.RequirePositive("Routines", profile.Routines),
+		profile.Upload.Validate(),
+	)
+}
+
+// LookupProfile returns the predefined profile of the specified name.
+func LookupProfile(name string) (Profile, error) {
+	factory, ok := profiles[name]
+	if !ok {
+		return Profile{}, errors.Errorf("unknown profile %q, expected one of %v", name, ProfileNames())
+	}
+
+	return factory(), nil
+}
+
+// ProfileNames returns the names of all predefined profiles.
+func ProfileNames() []string {
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// String implements the fmt.Stringer interface.
+func (f FinalityRequirement) String() string {
+	switch f {
+	case FileFinalized:
+		return "finalized"
+	case TransactionPacked:
+		return "packed"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, so that resolved settings are readable.
+func (f FinalityRequirement) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, so that settings could be loaded from file.
+func (f *FinalityRequirement) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case FileFinalized.String():
+		*f = FileFinalized
+	case TransactionPacked.String():
+		*f = TransactionPacked
+	default:
+		return errors.Errorf("unknown finality requirement %q, expected one of %v, %v", text, FileFinalized, TransactionPacked)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package transfer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupProfile(t *testing.T) {
+	assert.Equal(t, []string{ProfileNameCheap, ProfileNameFast, ProfileNameReliable}, ProfileNames())
+
+	for _, name := range ProfileNames() {
+		profile, err := LookupProfile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, profile.Name)
+		assert.Greater(t, profile.Routines, 0)
+	}
+
+	fast, _ := LookupProfile(ProfileNameFast)
+	assert.Equal(t, TransactionPacked, fast.Upload.FinalityRequired)
+	assert.False(t, fast.WithProof)
+
+	reliable, _ := LookupProfile(ProfileNameReliable)
+	assert.Equal(t, FileFinalized, reliable.Upload.FinalityRequired)
+	assert.GreaterOrEqual(t, reliable.Upload.ExpectedReplica, uint(2))
+	assert.True(t, reliable.WithProof)
+	assert.Greater(t, reliable.Upload.VerifySamples, uint(0))
+
+	cheap, _ := LookupProfile(ProfileNameCheap)
+	assert.True(t, cheap.Upload.SkipTx)
+	assert.Greater(t, cheap.BatchSize, uint(1))
+
+	_, err := LookupProfile("unknown")
+	assert.ErrorContains(t, err, "unknown profile")
+}
+
+func TestUploaderWithProfile(t *testing.T) {
+	uploader := &Uploader{routines: 1}
+	assert.Nil(t, uploader.Profile())
+
+	// start from profile and override
+	profile := ReliableProfile()
+	profile.Upload.ExpectedReplica = 3
+	uploader.WithProfile(profile).WithRoutines(7)
+
+	// changes to profile after applied have no effect
+	profile.Name = "changed"
+
+	resolved := uploader.Profile()
+	assert.Equal(t, ProfileNameReliable, resolved.Name)
+	assert.Equal(t, 7, resolved.Routines)
+	assert.True(t, resolved.Singleflight)
+	assert.Equal(t, uint(3), resolved.Upload.ExpectedReplica)
+
+	// resolved settings are inspectable
+	data, err := json.Marshal(resolved)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"name":"reliable"`)
+	assert.Contains(t, string(data), `"FinalityRequired":"finalized"`)
+
+	// resolved settings could be loaded back
+	var loaded Profile
+	assert.NoError(t, json.Unmarshal(data, &loaded))
+	assert.Equal(t, *resolved, loaded)
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"upload":{"FinalityRequired":"final"}}`), &loaded), "unknown finality requirement")
+}
+
+func TestDownloaderWithProfile(t *testing.T) {
+	downloader := &Downloader{routines: 1}
+	assert.Nil(t, downloader.Profile())
+
+	downloader.WithProfile(FastProfile())
+	assert.Equal(t, FastProfile().Routines, downloader.routines)
+	assert.Equal(t, ProfileNameFast, downloader.Profile().Name)
+}
+
+func TestProfileValidate(t *testing.T) {
+	for _, name := range ProfileNames() {
+		profile, err := LookupProfile(name)
+		assert.NoError(t, err)
+		assert.NoError(t, profile.Validate(), name)
+	}
+
+	profile := FastProfile()
+	profile.Routines = 0
+	assert.ErrorContains(t, profile.Validate(), "invalid option Routines")
+
+	profile = FastProfile()
+	profile.Upload.FinalityRequired = 5
+	assert.ErrorContains(t, profile.Validate(), "invalid option FinalityRequired")
+}
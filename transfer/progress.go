@@ -0,0 +1,191 @@
+package transfer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ProgressPhase is the phase of uploading or downloading a file, see Progress.
+type ProgressPhase string
+
+const (
+	ProgressSubmitting      ProgressPhase = "submitting"            // sending the transaction to submit log entry on chain
+	ProgressWaitingLogEntry ProgressPhase = "waiting for log entry" // waiting for storage nodes to sync the log entry
+	ProgressTransferring    ProgressPhase = "transferring"          // uploading or downloading segments
+	ProgressFinalizing      ProgressPhase = "finalizing"            // waiting for finality on storage nodes, or sealing downloaded file
+	ProgressCompleted       ProgressPhase = "completed"             // file uploaded or downloaded
+)
+
+// Progress is the progress of uploading or downloading a file, see Uploader.WithProgress and Downloader.WithProgress.
+type Progress struct {
+	Phase ProgressPhase
+	Root  common.Hash // merkle root of the file
+
+	// Segments to transfer, where segments uploaded to different storage nodes are counted separately, and segments
+	// already held by storage nodes are excluded. Segments acknowledged before an upload resumed, or downloaded before
+	// a download resumed, are counted as completed.
+	TotalSegments     uint64
+	CompletedSegments uint64
+	Bytes             int64 // bytes of segments transferred so far, excluding those resumed
+
+	Elapsed time.Duration // since the operation started
+	ETA     time.Duration // estimated remaining time to transfer segments by the rate so far, 0 if unknown
+}
+
+// Percent returns the percentage of segments completed, which is 100 if there is no segment to transfer.
+func (p Progress) Percent() float64 {
+	if p.TotalSegments == 0 {
+		return 100
+	}
+
+	return float64(p.CompletedSegments) * 100 / float64(p.TotalSegments)
+}
+
+// serializeProgress wraps the progress callback, so that callbacks of files transferred concurrently, e.g. files of
+// a directory, are serialized. Returns nil if onProgress is nil.
+func serializeProgress(onProgress func(p Progress)) func(p Progress) {
+	if onProgress == nil {
+		return nil
+	}
+
+	var mu sync.Mutex
+	return func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		onProgress(p)
+	}
+}
+
+// progressTracker tracks the progress of uploading or downloading a file, which is safe for concurrent use. Segments
+// completed are reported at most once per dir.DefaultProgressInterval, while phase changes are always reported.
+//
+// A nil tracker is valid and reports nothing, so that callers need not check whether progress is reported.
+type progressTracker struct {
+	onProgress func(p Progress)
+	interval   time.Duration
+
+	mu       sync.Mutex
+	progress Progress
+	started  time.Time // time that operation started
+	begun    time.Time // time that transferring segments began
+	resumed  uint64    // segments completed before transferring began
+	last     time.Time // last time that callback invoked
+	finished bool      // segments transferred in background afterwards are not reported
+}
+
+// newProgressTracker returns a tracker of the file of root, or nil if onProgress is nil.
+func newProgressTracker(onProgress func(p Progress), root common.Hash) *progressTracker {
+	if onProgress == nil {
+		return nil
+	}
+
+	return &progressTracker{
+		onProgress: onProgress,
+		interval:   dir.DefaultProgressInterval,
+		progress:   Progress{Root: root},
+		started:    time.Now(),
+	}
+}
+
+// phase reports the phase changed.
+func (tracker *progressTracker) phase(phase ProgressPhase) {
+	if tracker == nil {
+		return
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.progress.Phase = phase
+	tracker.reportLocked(time.Now())
+}
+
+// begin reports that transferring segments began, with total segments to transfer and those completed before.
+func (tracker *progressTracker) begin(total, completed uint64) {
+	if tracker == nil {
+		return
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	now := time.Now()
+	tracker.progress.Phase = ProgressTransferring
+	tracker.progress.TotalSegments = total
+	tracker.progress.CompletedSegments = completed
+	tracker.begun, tracker.resumed = now, completed
+	tracker.reportLocked(now)
+}
+
+// add tracks segments transferred, which is reported if the interval elapsed or all segments completed.
+func (tracker *progressTracker) add(segments uint64, bytes int64) {
+	if tracker == nil {
+		return
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	if tracker.finished {
+		return
+	}
+
+	tracker.progress.CompletedSegments += segments
+	tracker.progress.Bytes += bytes
+
+	if now := time.Now(); now.Sub(tracker.last) >= tracker.interval || tracker.progress.CompletedSegments >= tracker.progress.TotalSegments {
+		tracker.reportLocked(now)
+	}
+}
+
+// finish reports the final progress once completed, after which nothing is reported any more.
+func (tracker *progressTracker) finish() {
+	if tracker == nil {
+		return
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.progress.Phase = ProgressCompleted
+	tracker.reportLocked(time.Now())
+	tracker.finished = true
+}
+
+func (tracker *progressTracker) reportLocked(now time.Time) {
+	if tracker.finished {
+		return
+	}
+
+	progress := tracker.progress
+	progress.Elapsed = now.Sub(tracker.started)
+
+	// estimated by the rate of segments transferred in this run
+	if transferred := progress.CompletedSegments - tracker.resumed; progress.Phase == ProgressTransferring && transferred > 0 && progress.TotalSegments > progress.CompletedSegments {
+		remaining := progress.TotalSegments - progress.CompletedSegments
+		progress.ETA = time.Duration(float64(now.Sub(tracker.begun)) / float64(transferred) * float64(remaining))
+	}
+
+	tracker.last = now
+	tracker.onProgress(progress)
+}
+
+type progressTrackerKey struct{}
+
+func withProgressTracker(ctx context.Context, tracker *progressTracker) context.Context {
+	if tracker == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, progressTrackerKey{}, tracker)
+}
+
+func progressTrackerFromContext(ctx context.Context) *progressTracker {
+	tracker, _ := ctx.Value(progressTrackerKey{}).(*progressTracker)
+	return tracker
+}
@@ -0,0 +1,155 @@
+package transfer
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// progressRecorder records progress reported, and fails the test if callbacks invoked concurrently.
+type progressRecorder struct {
+	t        *testing.T
+	inflight atomic.Int32
+	reports  []Progress
+}
+
+func (recorder *progressRecorder) record(p Progress) {
+	if !assert.Equal(recorder.t, int32(1), recorder.inflight.Add(1), "callbacks invoked concurrently") {
+		return
+	}
+	defer recorder.inflight.Add(-1)
+
+	time.Sleep(time.Millisecond)
+	recorder.reports = append(recorder.reports, p)
+}
+
+func (recorder *progressRecorder) phases() []ProgressPhase {
+	var phases []ProgressPhase
+	for _, p := range recorder.reports {
+		if len(phases) == 0 || phases[len(phases)-1] != p.Phase {
+			phases = append(phases, p.Phase)
+		}
+	}
+
+	return phases
+}
+
+func (recorder *progressRecorder) last() Progress {
+	return recorder.reports[len(recorder.reports)-1]
+}
+
+func TestUploadProgressSingleSegment(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	recorder := progressRecorder{t: t}
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+	uploader.WithProgress(recorder.record)
+
+	_, data := newTestData(t, 1000)
+	_, root, err := uploader.Upload(context.Background(), data)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []ProgressPhase{ProgressSubmitting, ProgressWaitingLogEntry, ProgressTransferring, ProgressFinalizing, ProgressCompleted}, recorder.phases())
+
+	final := recorder.last()
+	assert.Equal(t, root, final.Root)
+	assert.Equal(t, uint64(1), final.TotalSegments)
+	assert.Equal(t, uint64(1), final.CompletedSegments)
+	assert.Equal(t, int64(1024), final.Bytes) // padded to chunks
+	assert.Zero(t, final.ETA)
+	assert.NotZero(t, final.Elapsed)
+}
+
+func TestUploadDownloadProgress(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{NumShard: 2}, shard.ShardConfig{ShardId: 1, NumShard: 2})
+	clients := network.ZgsClients()
+
+	// segments are counted for storage nodes of both shards
+	uploadRecorder := progressRecorder{t: t}
+	uploader, err := NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+	uploader.WithProgress(uploadRecorder.record)
+
+	content, data := newTestData(t, 5*core.DefaultSegmentSize)
+	_, root, err := uploader.Upload(context.Background(), data, UploadOption{TaskSize: 1})
+	assert.NoError(t, err)
+
+	final := uploadRecorder.last()
+	assert.Equal(t, ProgressCompleted, final.Phase)
+	assert.Equal(t, uint64(5), final.TotalSegments)
+	assert.Equal(t, uint64(5), final.CompletedSegments)
+	assert.Equal(t, int64(len(content)), final.Bytes)
+
+	// uploaded already, and nothing to transfer
+	uploadRecorder.reports = nil
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{SkipTx: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []ProgressPhase{ProgressTransferring, ProgressFinalizing, ProgressCompleted}, uploadRecorder.phases())
+	assert.Zero(t, uploadRecorder.last().TotalSegments)
+
+	downloadRecorder := progressRecorder{t: t}
+	downloader, err := NewDownloader(clients)
+	assert.NoError(t, err)
+	defer downloader.Close()
+	downloader.WithProgress(downloadRecorder.record)
+
+	assert.NoError(t, downloader.Download(context.Background(), root.Hex(), filepath.Join(t.TempDir(), "file"), true))
+	assert.Equal(t, []ProgressPhase{ProgressTransferring, ProgressFinalizing, ProgressCompleted}, downloadRecorder.phases())
+
+	final = downloadRecorder.last()
+	assert.Equal(t, root, final.Root)
+	assert.Equal(t, uint64(5), final.TotalSegments)
+	assert.Equal(t, uint64(5), final.CompletedSegments)
+	assert.Equal(t, int64(len(content)), final.Bytes)
+}
+
+func TestProgressTracker(t *testing.T) {
+	var reports []Progress
+	tracker := newProgressTracker(func(p Progress) { reports = append(reports, p) }, common.Hash{1})
+	tracker.interval = time.Hour
+
+	// resumed segments are counted as completed, but not in rate
+	tracker.begin(10, 4)
+	time.Sleep(10 * time.Millisecond)
+	tracker.add(2, 100)
+	tracker.add(2, 100)
+	assert.Equal(t, 1, len(reports))
+
+	// reported once interval elapsed
+	tracker.interval = 0
+	tracker.add(1, 100)
+	assert.Equal(t, uint64(9), reports[1].CompletedSegments)
+	assert.Equal(t, int64(300), reports[1].Bytes)
+	assert.Greater(t, reports[1].ETA, time.Duration(0))
+	assert.Less(t, reports[1].ETA, reports[1].Elapsed)
+
+	// reported once all segments completed, regardless of interval
+	tracker.interval = time.Hour
+	tracker.add(1, 100)
+	assert.Equal(t, uint64(10), reports[2].CompletedSegments)
+	assert.Zero(t, reports[2].ETA)
+
+	// nothing reported once finished
+	tracker.finish()
+	assert.Equal(t, ProgressCompleted, reports[3].Phase)
+	tracker.add(1, 100)
+	tracker.phase(ProgressFinalizing)
+	assert.Equal(t, 4, len(reports))
+
+	// nil tracker if not reported
+	tracker = newProgressTracker(nil, common.Hash{})
+	assert.Nil(t, tracker)
+	tracker.begin(1, 0)
+	tracker.add(1, 100)
+	tracker.finish()
+}
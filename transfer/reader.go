@@ -0,0 +1,482 @@
+package transfer
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Defaults of ReaderOption.
+const (
+	DefaultReaderCacheSegments = 16
+	DefaultPrefetchDepth       = 4
+)
+
+// ReaderOption configures the FileReader opened by Downloader.OpenReader.
+type ReaderOption struct {
+	CacheSegments  int // maximum number of segments cached once read, DefaultReaderCacheSegments if not positive
+	PrefetchDepth  int // number of segments fetched ahead of the access pattern, DefaultPrefetchDepth if zero, and disabled if negative
+	PrefetchBuffer int // maximum number of segments prefetched but not read yet, twice the prefetch depth if not positive
+}
+
+// ReaderStats is the statistics of segments read by FileReader.
+type ReaderStats struct {
+	Hits         uint64 // reads of segments cached once read
+	Misses       uint64 // reads of segments fetched on demand
+	Prefetched   uint64 // segments fetched ahead of reads
+	PrefetchHits uint64 // reads of segments prefetched, including those still in flight
+}
+
+var (
+	_ io.ReaderAt         = (*FileReader)(nil)
+	_ dir.RangeDownloader = (*Downloader)(nil)
+)
+
+// FileReader reads a file stored on storage nodes at random offsets, where segments are downloaded with merkle
+// proof validated on demand, and cached in LRU.
+//
+// Segments are prefetched asynchronously once reads follow a sequential or fixed-stride pattern of segments, or
+// ranges advised by Advise. Prefetched segments are kept in a separate buffer until read, so that mispredictions
+// never evict the cached segments read recently. FileReader is safe for concurrent use.
+type FileReader struct {
+	downloader   *Downloader
+	root         common.Hash
+	info         *node.FileInfo
+	shardConfigs []*shard.ShardConfig
+	numSegments  uint64
+
+	ctx       context.Context // cancelled when closed to stop prefetching
+	cancel    context.CancelFunc
+	depth     int
+	semaphore chan struct{} // limits the number of segments prefetched concurrently
+	wg        sync.WaitGroup
+
+	mu         sync.Mutex
+	cache      *segmentCache // segments read
+	prefetched *segmentCache // segments prefetched but not read yet
+	inflight   map[uint64]*segmentFetch
+	pattern    accessPattern
+	stats      ReaderStats
+	closed     bool
+}
+
+// segmentFetch is a segment being downloaded, which could be waited by concurrent reads.
+type segmentFetch struct {
+	done     chan struct{}
+	prefetch bool
+	data     []byte
+	err      error
+}
+
+// OpenReader opens the file of specified root for random access. The file is not required to be finalized on
+// storage nodes, as long as segments read are available.
+func (downloader *Downloader) OpenReader(ctx context.Context, root string, opt ReaderOption) (*FileReader, error) {
+	if err := downloader.acquire(); err != nil {
+		return nil, err
+	}
+	defer downloader.release()
+
+	hash := common.HexToHash(root)
+
+	info, err := downloader.queryFile(ctx, hash)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to query file info")
+	}
+
+	shardConfigs, err := getShardConfigs(ctx, downloader.clients)
+	if err != nil {
+		return nil, err
+	}
+
+	if opt.CacheSegments <= 0 {
+		opt.CacheSegments = DefaultReaderCacheSegments
+	}
+
+	if opt.PrefetchDepth == 0 {
+		opt.PrefetchDepth = DefaultPrefetchDepth
+	}
+
+	if opt.PrefetchBuffer <= 0 {
+		opt.PrefetchBuffer = 2 * max(opt.PrefetchDepth, 1)
+	}
+
+	readerCtx, cancel := context.WithCancel(context.Background())
+
+	return &FileReader{
+		downloader:   downloader,
+		root:         hash,
+		info:         info,
+		shardConfigs: shardConfigs,
+		numSegments:  core.NumSplits(int64(info.Tx.Size), core.DefaultSegmentSize),
+		ctx:          readerCtx,
+		cancel:       cancel,
+		depth:        max(opt.PrefetchDepth, 0),
+		semaphore:    make(chan struct{}, max(min(opt.PrefetchDepth, downloader.routines), 1)),
+		cache:        newSegmentCache(opt.CacheSegments),
+		prefetched:   newSegmentCache(opt.PrefetchBuffer),
+		inflight:     make(map[uint64]*segmentFetch),
+		pattern:      accessPattern{first: -1},
+	}, nil
+}
+
+// OpenRange opens the file of specified root for random access with the default ReaderOption, so that
+// dir.RemoteFS downloads only the segments read.
+func (downloader *Downloader) OpenRange(ctx context.Context, root string) (dir.RangeReader, error) {
+	reader, err := downloader.OpenReader(ctx, root, ReaderOption{})
+	if err != nil {
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+// Size returns the size of file in bytes.
+func (r *FileReader) Size() int64 {
+	return int64(r.info.Tx.Size)
+}
+
+// Stats returns the statistics of segments read so far.
+func (r *FileReader) Stats() ReaderStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.stats
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (r *FileReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.Errorf("invalid offset %v", off)
+	}
+
+	if off >= r.Size() {
+		return 0, io.EOF
+	}
+
+	n := int(min(int64(len(p)), r.Size()-off))
+	if n == 0 {
+		return 0, nil
+	}
+
+	first, last, _, _, err := core.ByteRangeToSegments(off, int64(n), r.Size())
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return 0, ErrClosed
+	}
+	if stride, ok := r.pattern.observe(first, last); ok {
+		r.predict(first, last, stride)
+	}
+	r.mu.Unlock()
+
+	read := 0
+	for index := first; index <= last; index++ {
+		data, err := r.segment(index)
+		if err != nil {
+			return read, err
+		}
+
+		start := int64(0)
+		if index == first {
+			start = off % core.DefaultSegmentSize
+		}
+
+		read += copy(p[read:n], data[start:])
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Advise hints that the range [offset, offset+length) will be read soon, so that segments of the range are
+// prefetched as many as the prefetch buffer could hold. The range is clamped to the file.
+func (r *FileReader) Advise(offset, length int64) {
+	offset, length = max(offset, 0), min(length, r.Size()-max(offset, 0))
+	if length <= 0 {
+		return
+	}
+
+	first, last, _, _, err := core.ByteRangeToSegments(offset, length, r.Size())
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for index := first; index <= last && index-first < uint64(r.prefetched.capacity); index++ {
+		r.prefetch(index)
+	}
+}
+
+// Close stops prefetching and releases the cached segments.
+func (r *FileReader) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return ErrClosed
+	}
+	r.closed = true
+	r.cancel()
+	r.mu.Unlock()
+
+	r.wg.Wait()
+
+	r.mu.Lock()
+	r.cache = newSegmentCache(r.cache.capacity)
+	r.prefetched = newSegmentCache(r.prefetched.capacity)
+	r.mu.Unlock()
+
+	return nil
+}
+
+// segment returns the data of segment from cache, prefetch buffer, or storage nodes on demand.
+func (r *FileReader) segment(index uint64) ([]byte, error) {
+	r.mu.Lock()
+
+	if data, ok := r.cache.get(index); ok {
+		r.stats.Hits++
+		r.mu.Unlock()
+		return data, nil
+	}
+
+	if data, ok := r.prefetched.remove(index); ok {
+		r.stats.PrefetchHits++
+		r.cache.add(index, data)
+		r.mu.Unlock()
+		return data, nil
+	}
+
+	if fetch, ok := r.inflight[index]; ok {
+		r.mu.Unlock()
+		<-fetch.done
+
+		// prefetch failures are retried on demand
+		if fetch.err == nil || !fetch.prefetch {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			if fetch.err != nil {
+				return nil, fetch.err
+			}
+
+			if fetch.prefetch {
+				r.stats.PrefetchHits++
+				r.prefetched.remove(index)
+			} else {
+				r.stats.Hits++
+			}
+			r.cache.add(index, fetch.data)
+
+			return fetch.data, nil
+		}
+
+		r.mu.Lock()
+	}
+
+	r.stats.Misses++
+	fetch := &segmentFetch{done: make(chan struct{})}
+	r.inflight[index] = fetch
+	r.mu.Unlock()
+
+	fetch.data, fetch.err = r.fetch(r.ctx, index)
+
+	r.mu.Lock()
+	delete(r.inflight, index)
+	if fetch.err == nil {
+		r.cache.add(index, fetch.data)
+	}
+	r.mu.Unlock()
+
+	close(fetch.done)
+
+	return fetch.data, fetch.err
+}
+
+// predict prefetches the segments of next reads following the stride, as many as the prefetch depth.
+func (r *FileReader) predict(first, last uint64, stride int64) {
+	for k, n := int64(1), 0; n < r.depth; k++ {
+		start, end := int64(first)+k*stride, int64(last)+k*stride
+		if start < 0 || end < 0 || uint64(start) >= r.numSegments {
+			return
+		}
+
+		for index := start; index <= end && n < r.depth; index++ {
+			r.prefetch(uint64(index))
+			n++
+		}
+	}
+}
+
+// prefetch downloads the segment asynchronously into prefetch buffer if not available yet. It must be called
+// with the lock held.
+func (r *FileReader) prefetch(index uint64) {
+	if r.closed || index >= r.numSegments || r.cache.contains(index) || r.prefetched.contains(index) {
+		return
+	}
+
+	if _, ok := r.inflight[index]; ok {
+		return
+	}
+
+	fetch := &segmentFetch{done: make(chan struct{}), prefetch: true}
+	r.inflight[index] = fetch
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+		defer close(fetch.done)
+
+		select {
+		case r.semaphore <- struct{}{}:
+			fetch.data, fetch.err = r.fetch(r.ctx, index)
+			<-r.semaphore
+		case <-r.ctx.Done():
+			fetch.err = r.ctx.Err()
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		delete(r.inflight, index)
+		if fetch.err == nil {
+			r.stats.Prefetched++
+			r.prefetched.add(index, fetch.data)
+		}
+	}()
+}
+
+// fetch downloads the segment with proof from storage nodes that hold it, and validates against the root.
+func (r *FileReader) fetch(ctx context.Context, index uint64) ([]byte, error) {
+	if err := r.downloader.acquire(); err != nil {
+		return nil, err
+	}
+	defer r.downloader.release()
+
+	startSegmentIndex := r.info.Tx.StartEntryIndex / core.DefaultSegmentMaxChunks
+
+	var lastErr error
+	for i, client := range r.downloader.clients {
+		if !r.shardConfigs[i].HasSegment(startSegmentIndex + index) {
+			continue
+		}
+
+		segment, err := r.downloader.downloadSegmentWithProof(ctx, client, r.root, r.info, index)
+		if err == nil {
+			return segment.Data, nil
+		}
+
+		lastErr = err
+		r.downloader.logger.WithError(err).WithFields(logrus.Fields{
+			"node":    client.URL(),
+			"segment": index,
+		}).Debug("Failed to download segment to read, try another storage node")
+	}
+
+	if lastErr == nil {
+		return nil, errors.Errorf("segment %v not found on any storage node", index)
+	}
+
+	return nil, errors.WithMessagef(lastErr, "Failed to download segment %v", index)
+}
+
+// accessPattern detects the stride between the first segments of consecutive reads, which is confirmed once the
+// same stride repeated. Reads within the same segment as the last one are not taken into account.
+type accessPattern struct {
+	first     int64 // first segment of the last read, -1 if not read yet
+	stride    int64
+	confirmed bool
+}
+
+// observe records the read of segments [first, last], and returns the stride if confirmed.
+func (p *accessPattern) observe(first, last uint64) (int64, bool) {
+	if int64(first) != p.first {
+		if p.first >= 0 {
+			stride := int64(first) - p.first
+			p.confirmed = stride == p.stride
+			p.stride = stride
+		}
+
+		p.first = int64(first)
+	}
+
+	return p.stride, p.confirmed
+}
+
+// segmentCache is a LRU cache of segment data by index, which is not safe for concurrent use.
+type segmentCache struct {
+	capacity int
+	entries  map[uint64]*list.Element
+	lru      *list.List // front is the most recently used
+}
+
+type segmentCacheEntry struct {
+	index uint64
+	data  []byte
+}
+
+func newSegmentCache(capacity int) *segmentCache {
+	return &segmentCache{
+		capacity: capacity,
+		entries:  make(map[uint64]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (cache *segmentCache) contains(index uint64) bool {
+	_, ok := cache.entries[index]
+	return ok
+}
+
+func (cache *segmentCache) get(index uint64) ([]byte, bool) {
+	elem, ok := cache.entries[index]
+	if !ok {
+		return nil, false
+	}
+
+	cache.lru.MoveToFront(elem)
+
+	return elem.Value.(*segmentCacheEntry).data, true
+}
+
+func (cache *segmentCache) add(index uint64, data []byte) {
+	if elem, ok := cache.entries[index]; ok {
+		elem.Value.(*segmentCacheEntry).data = data
+		cache.lru.MoveToFront(elem)
+		return
+	}
+
+	cache.entries[index] = cache.lru.PushFront(&segmentCacheEntry{index, data})
+
+	for cache.lru.Len() > cache.capacity {
+		cache.remove(cache.lru.Back().Value.(*segmentCacheEntry).index)
+	}
+}
+
+func (cache *segmentCache) remove(index uint64) ([]byte, bool) {
+	elem, ok := cache.entries[index]
+	if !ok {
+		return nil, false
+	}
+
+	delete(cache.entries, index)
+	cache.lru.Remove(elem)
+
+	return elem.Value.(*segmentCacheEntry).data, true
+}
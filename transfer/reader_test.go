@@ -0,0 +1,178 @@
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/stretchr/testify/assert"
+)
+
+// newReaderFixture uploads a file of the specified number of segments to mock storage node, and returns the
+// content along with a downloader of the storage node.
+func newReaderFixture(tb testing.TB, service *testutil.ZgsService, numSegments int) ([]byte, string, *Downloader) {
+	client, err := node.NewZgsClient(testutil.Serve(tb, map[string]interface{}{"zgs": service}))
+	assert.NoError(tb, err)
+	tb.Cleanup(func() { client.Close() })
+
+	content := make([]byte, numSegments*core.DefaultSegmentSize-100)
+	_, err = rand.Read(content)
+	assert.NoError(tb, err)
+
+	root, err := service.Submit(content, nil)
+	assert.NoError(tb, err)
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(tb, err)
+	uploader := &Uploader{clients: []*node.ZgsClient{client}, routines: 4, logger: zg_common.NewLogger(), warnings: NewWarnings(0)}
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{SkipTx: true})
+	assert.NoError(tb, err)
+
+	downloader, err := NewDownloader([]*node.ZgsClient{client})
+	assert.NoError(tb, err)
+
+	return content, root.Hex(), downloader
+}
+
+func TestFileReader(t *testing.T) {
+	service := testutil.NewZgsService()
+	content, root, downloader := newReaderFixture(t, service, 12)
+
+	reader, err := downloader.OpenReader(context.Background(), root, ReaderOption{PrefetchDepth: -1})
+	assert.NoError(t, err)
+	defer reader.Close()
+	assert.Equal(t, int64(len(content)), reader.Size())
+
+	// across segments, and beyond the end of file
+	buf := make([]byte, core.DefaultSegmentSize+200)
+	n, err := reader.ReadAt(buf, core.DefaultSegmentSize-100)
+	assert.NoError(t, err)
+	assert.Equal(t, content[core.DefaultSegmentSize-100:2*core.DefaultSegmentSize+100], buf[:n])
+
+	n, err = reader.ReadAt(buf, int64(len(content))-10)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, content[len(content)-10:], buf[:n])
+
+	_, err = reader.ReadAt(buf, int64(len(content)))
+	assert.Equal(t, io.EOF, err)
+
+	stats := reader.Stats()
+	assert.Equal(t, uint64(4), stats.Misses)
+	assert.Equal(t, uint64(0), stats.Prefetched)
+
+	// file not found
+	other, err := downloader.OpenReader(context.Background(), "0x0000000000000000000000000000000000000000000000000000000000000001", ReaderOption{})
+	assert.Error(t, err)
+	assert.Nil(t, other)
+}
+
+func TestFileReaderPrefetch(t *testing.T) {
+	service := testutil.NewZgsService()
+	content, root, downloader := newReaderFixture(t, service, 32)
+
+	reader, err := downloader.OpenReader(context.Background(), root, ReaderOption{CacheSegments: 2, PrefetchDepth: 2, PrefetchBuffer: 4})
+	assert.NoError(t, err)
+	defer reader.Close()
+
+	// strided reads of every third segment
+	buf := make([]byte, 100)
+	for index := int64(0); index < 30; index += 3 {
+		n, err := reader.ReadAt(buf, index*core.DefaultSegmentSize+10)
+		assert.NoError(t, err)
+		assert.Equal(t, content[index*core.DefaultSegmentSize+10:][:n], buf[:n])
+	}
+
+	// stride confirmed by the first three reads
+	stats := reader.Stats()
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.Equal(t, uint64(7), stats.PrefetchHits)
+
+	// mispredictions and advised ranges do not evict segments read recently
+	reader.Advise(13*core.DefaultSegmentSize, 2*core.DefaultSegmentSize)
+	time.Sleep(100 * time.Millisecond)
+	for _, index := range []int64{27, 24, 27} {
+		_, err := reader.ReadAt(buf, index*core.DefaultSegmentSize)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, stats.Hits+3, reader.Stats().Hits)
+
+	// advised segments prefetched
+	stats = reader.Stats()
+	_, err = reader.ReadAt(buf, 13*core.DefaultSegmentSize)
+	assert.NoError(t, err)
+	assert.Equal(t, stats.PrefetchHits+1, reader.Stats().PrefetchHits)
+
+	assert.NoError(t, reader.Close())
+	_, err = reader.ReadAt(buf, 0)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestAccessPattern(t *testing.T) {
+	tests := []struct {
+		reads     []uint64
+		stride    int64
+		confirmed bool
+	}{
+		{[]uint64{0}, 0, false},
+		{[]uint64{0, 0, 0}, 0, false},
+		{[]uint64{0, 1, 2}, 1, true},
+		{[]uint64{0, 0, 1, 1, 2}, 1, true},
+		{[]uint64{0, 4, 8}, 4, true},
+		{[]uint64{9, 6, 3}, -3, true},
+		{[]uint64{0, 4, 8, 9}, 1, false},
+	}
+
+	for _, tt := range tests {
+		pattern := accessPattern{first: -1}
+
+		var stride int64
+		var confirmed bool
+		for _, first := range tt.reads {
+			stride, confirmed = pattern.observe(first, first)
+		}
+
+		if tt.confirmed {
+			assert.Equal(t, tt.stride, stride, tt.reads)
+		}
+		assert.Equal(t, tt.confirmed, confirmed, tt.reads)
+	}
+}
+
+// BenchmarkFileReaderStrided reads every fourth segment of cold files from mock storage node of latency.
+func BenchmarkFileReaderStrided(b *testing.B) {
+	const numSegments, stride = 64, 4
+
+	service := testutil.NewZgsService()
+	_, root, downloader := newReaderFixture(b, service, numSegments)
+	service.SetHooks(testutil.ZgsHooks{DownloadDelay: 2 * time.Millisecond})
+
+	for _, depth := range []int{-1, DefaultPrefetchDepth} {
+		b.Run(fmt.Sprintf("prefetch=%v", depth), func(b *testing.B) {
+			buf := make([]byte, 4096)
+
+			for i := 0; i < b.N; i++ {
+				reader, err := downloader.OpenReader(context.Background(), root, ReaderOption{PrefetchDepth: depth})
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				for index := int64(0); index < numSegments; index += stride {
+					if _, err := reader.ReadAt(buf, index*core.DefaultSegmentSize); err != nil {
+						b.Fatal(err)
+					}
+
+					// simulates the computation on data read
+					time.Sleep(time.Millisecond)
+				}
+
+				reader.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,433 @@
+package transfer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/parallel"
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/core/merkle"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultReplicaDeadline is the default deadline to complete the expected replicas in background.
+const defaultReplicaDeadline = 10 * time.Minute
+
+// ReplicaStatus is the status of uploading to a storage node.
+type ReplicaStatus uint
+
+const (
+	ReplicaPending   ReplicaStatus = iota // still uploading
+	ReplicaUploaded                       // uploaded, or already finalized before uploading
+	ReplicaFailed                         // failed to upload
+	ReplicaCancelled                      // cancelled due to deadline exceeded, handle cancelled or uploader closed
+)
+
+func (status ReplicaStatus) String() string {
+	switch status {
+	case ReplicaPending:
+		return "pending"
+	case ReplicaUploaded:
+		return "uploaded"
+	case ReplicaFailed:
+		return "failed"
+	case ReplicaCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// ReplicaOutcome is the outcome of uploading to a storage node.
+type ReplicaOutcome struct {
+	Node     string        // storage node URL
+	Status   ReplicaStatus // upload status
+	Err      error         // error if failed or cancelled
+	Duration time.Duration // time elapsed to upload, 0 if pending
+}
+
+// replicaJob uploads file to a storage node.
+type replicaJob struct {
+	clientIndex int
+	node        string
+	shardConfig *shard.ShardConfig
+	upload      func(ctx context.Context) error // nil if already finalized on storage node
+}
+
+// ReplicaHandle tracks the replicas of an upload, which succeeds once the MinReplica of UploadOption reached,
+// while the remaining replicas are uploaded in background until the replica requirement, i.e. ExpectedReplica
+// and ShardReplicas, reached.
+//
+// Background goroutines terminate once all storage nodes completed, or any of the following happens:
+//   - the ReplicaDeadline of UploadOption exceeded after MinReplica reached.
+//   - Cancel is called.
+//   - Uploader is closed.
+//
+// Background goroutines never keep the process alive, i.e. process may exit without waiting for them, in
+// which case the remaining replicas will not be uploaded. Call Wait to ensure the best-effort replicas. Note, the
+// data to upload is read by background goroutines until Done closed, see Uploader.UploadWithHandle.
+type ReplicaHandle struct {
+	requirement shard.ReplicaRequirement
+	cancel      context.CancelFunc
+	done        chan struct{} // closed when all storage nodes completed
+	updated     chan struct{} // notified when any storage node completed
+
+	mu       sync.Mutex
+	jobs     []replicaJob
+	outcomes []ReplicaOutcome
+	deadline *time.Timer
+}
+
+func newReplicaHandle(jobs []replicaJob, requirement shard.ReplicaRequirement, cancel context.CancelFunc) *ReplicaHandle {
+	outcomes := make([]ReplicaOutcome, len(jobs))
+	for i, job := range jobs {
+		outcomes[i].Node = job.node
+	}
+
+	return &ReplicaHandle{
+		requirement: requirement,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		updated:     make(chan struct{}, 1),
+		jobs:        jobs,
+		outcomes:    outcomes,
+	}
+}
+
+// newCompletedReplicaHandle creates a completed handle for storage nodes that all uploaded except those failed.
+func newCompletedReplicaHandle(clients []*node.ZgsClient, shardConfigs []*shard.ShardConfig, requirement shard.ReplicaRequirement, failures *nodeFailures, duration time.Duration) *ReplicaHandle {
+	jobs := make([]replicaJob, len(clients))
+	for i, client := range clients {
+		jobs[i] = replicaJob{clientIndex: i, node: client.URL(), shardConfig: shardConfigs[i]}
+	}
+
+	handle := newReplicaHandle(jobs, requirement, func() {})
+	for i := range handle.outcomes {
+		handle.outcomes[i].Status = ReplicaUploaded
+		handle.outcomes[i].Duration = duration
+
+		if failures == nil {
+			continue
+		}
+
+		if err := failures.errOf(jobs[i].node); err != nil {
+			handle.outcomes[i].Status = ReplicaFailed
+			handle.outcomes[i].Err = err
+		}
+	}
+	close(handle.done)
+
+	return handle
+}
+
+// Done returns a channel that is closed when uploading to all storage nodes completed.
+func (handle *ReplicaHandle) Done() <-chan struct{} {
+	return handle.done
+}
+
+// Wait waits for uploading to all storage nodes completed, and returns the outcome of each storage node.
+// Note, it returns ctx error along with the outcomes so far if ctx done, and the background uploading
+// continues. Call Cancel to stop the background uploading.
+func (handle *ReplicaHandle) Wait(ctx context.Context) ([]ReplicaOutcome, error) {
+	select {
+	case <-handle.done:
+		return handle.Outcomes(), nil
+	case <-ctx.Done():
+		return handle.Outcomes(), ctx.Err()
+	}
+}
+
+// Cancel stops uploading in background. It is safe to cancel for multiple times.
+func (handle *ReplicaHandle) Cancel() {
+	handle.cancel()
+}
+
+// Outcomes returns a snapshot of the outcome of each storage node.
+func (handle *ReplicaHandle) Outcomes() []ReplicaOutcome {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+
+	return append([]ReplicaOutcome(nil), handle.outcomes...)
+}
+
+// Replicas returns the number of replicas uploaded so far, i.e. the min replicas of all segments.
+func (handle *ReplicaHandle) Replicas() uint {
+	return handle.Achieved().Default
+}
+
+// Achieved returns the replicas uploaded so far for all segments and each shard of the replica requirement.
+func (handle *ReplicaHandle) Achieved() shard.ReplicaRequirement {
+	return handle.requirement.Achieved(handle.uploadedShardConfigs())
+}
+
+// Complete returns whether the replica requirement of UploadOption reached.
+func (handle *ReplicaHandle) Complete() bool {
+	return shard.CheckRequirement(handle.uploadedShardConfigs(), handle.requirement) == nil
+}
+
+func (handle *ReplicaHandle) reached(replica uint) bool {
+	return shard.CheckReplica(handle.uploadedShardConfigs(), replica)
+}
+
+func (handle *ReplicaHandle) uploadedShardConfigs() []*shard.ShardConfig {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+
+	var configs []*shard.ShardConfig
+	for i, outcome := range handle.outcomes {
+		if outcome.Status == ReplicaUploaded {
+			configs = append(configs, handle.jobs[i].shardConfig)
+		}
+	}
+
+	return configs
+}
+
+// uploadedClients returns the storage node clients that uploaded so far.
+func (handle *ReplicaHandle) uploadedClients(clients []*node.ZgsClient) []*node.ZgsClient {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+
+	var result []*node.ZgsClient
+	for i, outcome := range handle.outcomes {
+		if outcome.Status == ReplicaUploaded {
+			result = append(result, clients[handle.jobs[i].clientIndex])
+		}
+	}
+
+	return result
+}
+
+// firstError returns the error of the first failed storage node if any.
+func (handle *ReplicaHandle) firstError() error {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+
+	for _, outcome := range handle.outcomes {
+		if outcome.Err != nil {
+			return errors.WithMessagef(outcome.Err, "Failed to upload to storage node %v", outcome.Node)
+		}
+	}
+
+	return nil
+}
+
+func (handle *ReplicaHandle) complete(i int, err error, duration time.Duration, cancelled bool) {
+	handle.mu.Lock()
+	outcome := &handle.outcomes[i]
+	outcome.Err = err
+	outcome.Duration = duration
+	switch {
+	case err == nil:
+		outcome.Status = ReplicaUploaded
+	case cancelled:
+		outcome.Status = ReplicaCancelled
+	default:
+		outcome.Status = ReplicaFailed
+	}
+	handle.mu.Unlock()
+
+	select {
+	case handle.updated <- struct{}{}:
+	default:
+	}
+}
+
+// startDeadline cancels the background uploading once the deadline exceeded.
+func (handle *ReplicaHandle) startDeadline(deadline time.Duration) {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+
+	select {
+	case <-handle.done:
+		// already completed
+	default:
+		handle.deadline = time.AfterFunc(deadline, handle.cancel)
+	}
+}
+
+// finish stops the deadline timer if any, and marks all storage nodes completed.
+func (handle *ReplicaHandle) finish() {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+
+	if handle.deadline != nil {
+		handle.deadline.Stop()
+	}
+
+	close(handle.done)
+}
+
+// runReplicas uploads to all storage nodes concurrently, and returns once minReplica reached. The remaining
+// storage nodes continue to upload in background until completed, deadline exceeded or stop closed, and
+// release is called after all background goroutines terminated.
+//
+// Note, uploading is cancelled if ctx done before minReplica reached, but not affected by ctx afterwards.
+func runReplicas(ctx context.Context, jobs []replicaJob, minReplica uint, requirement shard.ReplicaRequirement, deadline time.Duration, stop <-chan struct{}, release func(handle *ReplicaHandle)) (*ReplicaHandle, error) {
+	bgCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	handle := newReplicaHandle(jobs, requirement, cancel)
+
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, job replicaJob) {
+			defer wg.Done()
+
+			start := time.Now()
+			var err error
+			if job.upload != nil {
+				err = job.upload(bgCtx)
+			}
+
+			handle.complete(i, err, time.Since(start), bgCtx.Err() != nil)
+		}(i, job)
+	}
+
+	go func() {
+		wg.Wait()
+
+		cancel()
+		handle.finish()
+		release(handle)
+	}()
+
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-handle.done:
+		}
+	}()
+
+	for !handle.reached(minReplica) {
+		select {
+		case <-handle.updated:
+		case <-handle.done:
+			if !handle.reached(minReplica) {
+				err := errors.Errorf("Only %v replicas uploaded, but %v required", handle.Replicas(), minReplica)
+				if cause := handle.firstError(); cause != nil {
+					err = errors.WithMessage(cause, err.Error())
+				}
+				return handle, err
+			}
+		case <-ctx.Done():
+			cancel()
+			return handle, ctx.Err()
+		}
+	}
+
+	if deadline == 0 {
+		deadline = defaultReplicaDeadline
+	}
+	handle.startDeadline(deadline)
+
+	return handle, nil
+}
+
+// uploadReplicas uploads file to storage nodes, and returns once the MinReplica of option reached.
+func (uploader *Uploader) uploadReplicas(ctx context.Context, info *node.FileInfo, data core.IterableData, tree *merkle.Tree, opt UploadOption) (*ReplicaHandle, error) {
+	stageTimer := time.Now()
+
+	taskSize := opt.TaskSize
+	if taskSize == 0 {
+		taskSize = defaultTaskSize
+	}
+
+	uploader.logger.WithFields(logrus.Fields{
+		"segNum":          data.NumSegments(),
+		"nodeNum":         len(uploader.clients),
+		"minReplica":      opt.MinReplica,
+		"expectedReplica": opt.ExpectedReplica,
+		"shardReplicas":   opt.ShardReplicas,
+	}).Info("Begin to upload file")
+
+	jobs, err := uploader.newReplicaJobs(ctx, info, data, tree, opt.MinReplica, taskSize, uploader.uploadRoutines(opt))
+	if err != nil {
+		return nil, err
+	}
+
+	// background uploading is drained when uploader closed
+	if err = uploader.acquire(); err != nil {
+		return nil, err
+	}
+
+	handle, err := runReplicas(ctx, jobs, opt.MinReplica, opt.ReplicaRequirement(), opt.ReplicaDeadline, uploader.stopping(), func(handle *ReplicaHandle) {
+		uploader.logger.WithFields(logrus.Fields{
+			"root":     tree.Root(),
+			"replicas": handle.Replicas(),
+			"achieved": handle.Achieved(),
+			"complete": handle.Complete(),
+			"duration": time.Since(stageTimer),
+		}).Info("Completed to upload replicas in background")
+		uploader.release()
+	})
+	if err != nil {
+		return handle, err
+	}
+
+	uploader.logger.WithFields(logrus.Fields{
+		"duration": time.Since(stageTimer),
+		"replicas": handle.Replicas(),
+	}).Info("Completed to upload file with minimum replicas")
+
+	return handle, nil
+}
+
+// newReplicaJobs creates a job to upload segments for each storage node, so that storage nodes complete
+// independently. Storage nodes that already finalized the file are regarded as uploaded.
+func (uploader *Uploader) newReplicaJobs(ctx context.Context, info *node.FileInfo, data core.IterableData, tree *merkle.Tree, minReplica uint, taskSize uint, routines int) ([]replicaJob, error) {
+	shardConfigs, err := getShardConfigs(ctx, uploader.clients)
+	if err != nil {
+		return nil, err
+	}
+	if !shard.CheckReplica(shardConfigs, minReplica) {
+		return nil, errors.New("selected nodes cannot cover all shards")
+	}
+
+	startSegmentIndex, endSegmentIndex := core.SegmentRange(info.Tx.StartEntryIndex, info.Tx.Size)
+	loads := newNodeLoads(ctx, uploader.clients)
+	routines = max(1, routines/len(uploader.clients))
+	progress := progressTrackerFromContext(ctx)
+
+	var total uint64
+	jobs := make([]replicaJob, 0, len(uploader.clients))
+	for clientIndex, shardConfig := range shardConfigs {
+		job := replicaJob{
+			clientIndex: clientIndex,
+			node:        uploader.clients[clientIndex].URL(),
+			shardConfig: shardConfig,
+		}
+
+		// skip finalized nodes
+		if nodeInfo, _ := uploader.clients[clientIndex].GetFileInfo(ctx, tree.Root()); nodeInfo == nil || !nodeInfo.Finalized {
+			segmentUploader := &segmentUploader{
+				data:     data,
+				tree:     tree,
+				txSeq:    info.Tx.Seq,
+				clients:  uploader.clients,
+				tasks:    newUploadTasks(clientIndex, shardConfig, startSegmentIndex, endSegmentIndex, taskSize),
+				taskSize: taskSize,
+				logger:   uploader.logger,
+				warnings: uploader.warnings,
+				loads:    loads,
+				progress: progress,
+			}
+			segments, _ := segmentUploader.numSegments()
+			total += segments
+
+			job.upload = func(ctx context.Context) error {
+				return parallel.Serial(ctx, withPool(segmentUploader, uploader.pool), len(segmentUploader.tasks), parallel.SerialOption{Routines: routines})
+			}
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	progress.begin(total, 0)
+
+	return jobs, nil
+}
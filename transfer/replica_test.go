@@ -0,0 +1,235 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+// newTestReplicaJobs creates a job for each behavior, which stores full data: "ok" succeeds immediately,
+// "fail" fails immediately, and "slow" blocks until cancelled.
+func newTestReplicaJobs(behaviors ...string) []replicaJob {
+	jobs := make([]replicaJob, 0, len(behaviors))
+	for i, behavior := range behaviors {
+		job := replicaJob{
+			clientIndex: i,
+			node:        fmt.Sprintf("node-%v", i),
+			shardConfig: &shard.ShardConfig{NumShard: 1},
+		}
+
+		switch behavior {
+		case "ok":
+			job.upload = func(ctx context.Context) error { return nil }
+		case "fail":
+			job.upload = func(ctx context.Context) error { return errors.New("connection refused") }
+		case "slow":
+			job.upload = func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs
+}
+
+func statusesOf(outcomes []ReplicaOutcome) []ReplicaStatus {
+	var statuses []ReplicaStatus
+	for _, outcome := range outcomes {
+		statuses = append(statuses, outcome.Status)
+	}
+	return statuses
+}
+
+func TestRunReplicasMinReached(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	released := make(chan uint, 1)
+	release := func(handle *ReplicaHandle) { released <- handle.Replicas() }
+
+	handle, err := runReplicas(context.Background(), newTestReplicaJobs("ok", "slow", "ok"), 2, shard.UniformReplica(3), 50*time.Millisecond, nil, release)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), handle.Replicas())
+	assert.False(t, handle.Complete())
+
+	// slow node cancelled once deadline exceeded
+	outcomes, err := handle.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []ReplicaStatus{ReplicaUploaded, ReplicaCancelled, ReplicaUploaded}, statusesOf(outcomes))
+	assert.ErrorIs(t, outcomes[1].Err, context.Canceled)
+	assert.Equal(t, "node-1", outcomes[1].Node)
+	assert.False(t, handle.Complete())
+	assert.Equal(t, uint(2), <-released)
+}
+
+func TestRunReplicasTargetReached(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	jobs := newTestReplicaJobs("ok", "ok", "ok")
+	jobs[2].upload = nil // already finalized
+
+	handle, err := runReplicas(context.Background(), jobs, 2, shard.UniformReplica(3), 0, nil, func(*ReplicaHandle) {})
+	assert.NoError(t, err)
+
+	<-handle.Done()
+	assert.True(t, handle.Complete())
+	assert.Equal(t, uint(3), handle.Replicas())
+}
+
+func TestRunReplicasMinUnreached(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	handle, err := runReplicas(context.Background(), newTestReplicaJobs("ok", "fail", "fail"), 2, shard.UniformReplica(3), 0, nil, func(*ReplicaHandle) {})
+	assert.ErrorContains(t, err, "Only 1 replicas uploaded, but 2 required")
+	assert.ErrorContains(t, err, "node-1: connection refused")
+	assert.Equal(t, []ReplicaStatus{ReplicaUploaded, ReplicaFailed, ReplicaFailed}, statusesOf(handle.Outcomes()))
+}
+
+func TestRunReplicasCancel(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// cancelled by caller before minimum reached
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	handle, err := runReplicas(ctx, newTestReplicaJobs("ok", "slow", "slow"), 2, shard.UniformReplica(3), 0, nil, func(*ReplicaHandle) {})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	<-handle.Done()
+
+	// not affected by caller after minimum reached, but cancelled by handle
+	ctx, cancel = context.WithCancel(context.Background())
+	handle, err = runReplicas(ctx, newTestReplicaJobs("ok", "ok", "slow"), 2, shard.UniformReplica(3), time.Hour, nil, func(*ReplicaHandle) {})
+	assert.NoError(t, err)
+	cancel()
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer waitCancel()
+	_, err = handle.Wait(waitCtx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, ReplicaPending, handle.Outcomes()[2].Status)
+
+	handle.Cancel()
+	outcomes, err := handle.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, ReplicaCancelled, outcomes[2].Status)
+
+	// cancelled when stopped, e.g. uploader closed
+	stop := make(chan struct{})
+	handle, err = runReplicas(context.Background(), newTestReplicaJobs("ok", "slow"), 1, shard.UniformReplica(2), time.Hour, stop, func(*ReplicaHandle) {})
+	assert.NoError(t, err)
+	close(stop)
+	<-handle.Done()
+	assert.Equal(t, ReplicaCancelled, handle.Outcomes()[1].Status)
+}
+
+func TestUploadOptionMinReplica(t *testing.T) {
+	opt := UploadOption{ExpectedReplica: 3, MinReplica: 2}
+	assert.NoError(t, opt.Validate())
+	assert.True(t, opt.partialReplica())
+
+	opt.MinReplica = 3
+	assert.False(t, opt.partialReplica())
+
+	opt.MinReplica = 4
+	assert.ErrorContains(t, opt.Validate(), "invalid option MinReplica")
+
+	opt = UploadOption{ExpectedReplica: 3, ReplicaDeadline: -time.Second}
+	assert.ErrorContains(t, opt.Validate(), "invalid option ReplicaDeadline")
+}
+
+func TestRunReplicasShardRequirement(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	// 3 replicas for shard 1/2, and 1 elsewhere
+	requirement := shard.ReplicaRequirement{Default: 1, Shards: []shard.ShardReplica{{Shard: shard.ShardConfig{NumShard: 2, ShardId: 1}, Replica: 3}}}
+	jobs := newTestReplicaJobs("ok", "ok", "fail")
+	jobs[1].shardConfig = &shard.ShardConfig{NumShard: 2, ShardId: 1}
+	jobs[2].shardConfig = &shard.ShardConfig{NumShard: 2, ShardId: 1}
+
+	handle, err := runReplicas(context.Background(), jobs, 1, requirement, 0, nil, func(*ReplicaHandle) {})
+	assert.NoError(t, err)
+
+	<-handle.Done()
+	assert.False(t, handle.Complete())
+	assert.Equal(t, uint(1), handle.Replicas())
+	assert.Equal(t, shard.ReplicaRequirement{Default: 1, Shards: []shard.ShardReplica{{Shard: shard.ShardConfig{NumShard: 2, ShardId: 1}, Replica: 2}}}, handle.Achieved())
+}
+
+func TestUploadOptionShardReplicas(t *testing.T) {
+	opt := UploadOption{ExpectedReplica: 1, MinReplica: 1, ShardReplicas: []shard.ShardReplica{{Shard: shard.ShardConfig{NumShard: 4, ShardId: 1}, Replica: 3}}}
+	assert.NoError(t, opt.Validate())
+	assert.Equal(t, uint(3), opt.ReplicaRequirement().Max())
+	assert.True(t, opt.partialReplica())
+
+	opt.ShardReplicas[0].Shard.NumShard = 3
+	assert.ErrorContains(t, opt.Validate(), "invalid option ShardReplicas")
+
+	var optErr *zg_common.OptionError
+	assert.ErrorAs(t, opt.Validate(), &optErr)
+	assert.Equal(t, "ShardReplicas", optErr.Field)
+	assert.Contains(t, optErr.Reason, "power of 2")
+}
+
+func TestUploadWaitsForBackgroundReplicas(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{NumShard: 1}, shard.ShardConfig{NumShard: 1})
+	clients := network.ZgsClients()
+
+	// the second node blocks uploading until released
+	release := make(chan struct{})
+	clients[1].HookCallContext(func(handler providers.CallContextFunc) providers.CallContextFunc {
+		return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+			if method == "zgs_uploadSegmentsByTxSeq" {
+				select {
+				case <-release:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			return handler(ctx, result, method, args...)
+		}
+	})
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	opt := UploadOption{ExpectedReplica: 2, MinReplica: 1}
+
+	// returns once the minimum replicas reached along with the handle, which still reads data in background
+	_, data := newTestData(t, 1024)
+	_, _, handle, err := uploader.UploadWithHandle(context.Background(), data, opt)
+	assert.NoError(t, err)
+	assert.Equal(t, ReplicaPending, handle.Outcomes()[1].Status)
+
+	// data never read once returned
+	done := make(chan error)
+	_, data = newTestData(t, 2048)
+	go func() {
+		_, _, err := uploader.Upload(context.Background(), data, opt)
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("returned before background replicas completed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-done)
+
+	outcomes, err := handle.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []ReplicaStatus{ReplicaUploaded, ReplicaUploaded}, statusesOf(outcomes))
+}
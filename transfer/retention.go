@@ -0,0 +1,236 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+// ErrRetentionMismatch is matched by RetentionMismatchError with errors.Is.
+var ErrRetentionMismatch = errors.New("retention class mismatch")
+
+// RetentionMismatchError is returned when a storage node reported a retention class other than the policy expects
+// once file finalized, under the RetentionCheckFail policy.
+type RetentionMismatchError struct {
+	Node     string
+	Root     common.Hash
+	Policy   string // name of the retention policy
+	Expected string // retention class expected by the policy
+	Actual   string // retention class reported by the storage node, empty if not reported
+}
+
+func (e *RetentionMismatchError) Error() string {
+	return fmt.Sprintf("%v: node = %v, root = %v, policy = %v, expected = %q, actual = %q",
+		ErrRetentionMismatch, e.Node, e.Root, e.Policy, e.Expected, e.Actual)
+}
+
+func (e *RetentionMismatchError) Unwrap() error {
+	return ErrRetentionMismatch
+}
+
+// RetentionPolicy is the submission tags of a named retention policy, which storage nodes of private networks use to
+// decide the retention class of file, e.g. hot or cold.
+type RetentionPolicy struct {
+	Tags  hexutil.Bytes `json:"tags"`            // submission tags
+	Class string        `json:"class,omitempty"` // retention class reported by storage nodes once applied, the policy name if empty
+}
+
+// RetentionPolicies are retention policies by name, which are loaded from configuration so that uploads to public
+// network are not affected unless configured, e.g.
+//
+//	{
+//		"hot":  {"tags": "0x01"},
+//		"cold": {"tags": "0x02", "class": "archive"}
+//	}
+type RetentionPolicies map[string]RetentionPolicy
+
+// LoadRetentionPolicies loads retention policies from a JSON file, and validates the policies.
+func LoadRetentionPolicies(filename string) (RetentionPolicies, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read retention policies")
+	}
+
+	var policies RetentionPolicies
+	if err = json.Unmarshal(content, &policies); err != nil {
+		return nil, errors.WithMessage(err, "failed to decode retention policies")
+	}
+
+	if err = policies.Validate(); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// Validate checks that each policy is named and tags the submission.
+func (policies RetentionPolicies) Validate() error {
+	for _, name := range policies.Names() {
+		if len(name) == 0 {
+			return errors.New("retention policy name is empty")
+		}
+
+		if len(policies[name].Tags) == 0 {
+			return errors.Errorf("tags of retention policy %q are empty", name)
+		}
+	}
+
+	return nil
+}
+
+// Lookup returns the retention policy of the specified name.
+func (policies RetentionPolicies) Lookup(name string) (RetentionPolicy, error) {
+	policy, ok := policies[name]
+	if !ok {
+		return RetentionPolicy{}, errors.Errorf("unknown retention policy %q, expected one of %v", name, policies.Names())
+	}
+
+	return policy, nil
+}
+
+// Names returns the names of policies in order.
+func (policies RetentionPolicies) Names() []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// expectedClass returns the retention class expected to be reported by storage nodes.
+func (policy RetentionPolicy) expectedClass(name string) string {
+	if len(policy.Class) > 0 {
+		return policy.Class
+	}
+
+	return name
+}
+
+// RetentionCheck is the policy to check the retention class reported by storage nodes once file finalized.
+type RetentionCheck string
+
+const (
+	// RetentionCheckNone does not check the retention class, which is the default policy if not specified.
+	RetentionCheckNone RetentionCheck = "none"
+
+	// RetentionCheckWarn adds a WarningRetentionMismatch warning for each storage node that reported another
+	// retention class.
+	RetentionCheckWarn RetentionCheck = "warn"
+
+	// RetentionCheckFail fails the upload with RetentionMismatchError once a storage node reported another
+	// retention class.
+	RetentionCheckFail RetentionCheck = "fail"
+)
+
+// Validate checks the policy, and returns an error if unsupported.
+func (check RetentionCheck) Validate() error {
+	switch check {
+	case "", RetentionCheckNone, RetentionCheckWarn, RetentionCheckFail:
+		return nil
+	default:
+		return zg_common.NewOptionError("RetentionCheck", "unsupported policy %q", check)
+	}
+}
+
+func (check RetentionCheck) enabled() bool {
+	return check == RetentionCheckWarn || check == RetentionCheckFail
+}
+
+// validateRetention checks the retention settings of upload option regardless of the configured policies.
+func (opt *UploadOption) validateRetention() error {
+	if err := opt.RetentionCheck.Validate(); err != nil {
+		return err
+	}
+
+	if !opt.RetentionCheck.enabled() {
+		return nil
+	}
+
+	if len(opt.Retention) == 0 {
+		return zg_common.NewOptionError("RetentionCheck", "requires Retention specified")
+	}
+
+	if opt.FinalityRequired != FileFinalized {
+		return zg_common.NewOptionError("RetentionCheck", "requires FinalityRequired %v, got %v", FileFinalized, opt.FinalityRequired)
+	}
+
+	return nil
+}
+
+// resolveRetention tags the submission by the retention policy of option if specified, and fails if the policy is
+// not configured by WithRetentionPolicies.
+func (uploader *Uploader) resolveRetention(opt *UploadOption) error {
+	if len(opt.Retention) == 0 {
+		return nil
+	}
+
+	policy, err := uploader.retention.Lookup(opt.Retention)
+	if err != nil {
+		return zg_common.NewOptionError("Retention", "%v", err)
+	}
+
+	if len(opt.Tags) > 0 && !bytes.Equal(opt.Tags, policy.Tags) {
+		return zg_common.NewOptionError("Tags", "conflicts with tags %v of retention policy %q", policy.Tags, opt.Retention)
+	}
+
+	opt.Tags = policy.Tags
+
+	return nil
+}
+
+// checkRetention checks the retention class reported by the specified storage nodes once file finalized, as the
+// RetentionCheck of option requires.
+func (uploader *Uploader) checkRetention(ctx context.Context, clients []*node.ZgsClient, root common.Hash, opt UploadOption) error {
+	if !opt.RetentionCheck.enabled() {
+		return nil
+	}
+
+	policy, err := uploader.retention.Lookup(opt.Retention)
+	if err != nil {
+		return err
+	}
+	expected := policy.expectedClass(opt.Retention)
+
+	for _, client := range clients {
+		info, err := client.GetFileInfo(ctx, root)
+		if err != nil {
+			return errors.WithMessage(err, "Failed to get file info to check retention class")
+		}
+
+		var actual string
+		if info != nil {
+			actual = info.RetentionClass
+		}
+
+		if actual == expected {
+			continue
+		}
+
+		mismatch := &RetentionMismatchError{client.URL(), root, opt.Retention, expected, actual}
+		if opt.RetentionCheck == RetentionCheckFail {
+			return mismatch
+		}
+
+		uploader.warnings.Add(WarningRetentionMismatch, "Retention class mismatch", map[string]interface{}{
+			"node":     mismatch.Node,
+			"root":     root,
+			"policy":   opt.Retention,
+			"expected": expected,
+			"actual":   actual,
+		})
+	}
+
+	return nil
+}
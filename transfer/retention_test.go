@@ -0,0 +1,130 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRetentionPolicies = RetentionPolicies{
+	"hot":  {Tags: []byte{0x01}},
+	"cold": {Tags: []byte{0x02}, Class: "archive"},
+}
+
+// retentionClassByTags reports retention class as the storage nodes of private network do.
+func retentionClassByTags(tags []byte) string {
+	switch string(tags) {
+	case "\x01":
+		return "hot"
+	case "\x02":
+		return "archive"
+	default:
+		return ""
+	}
+}
+
+func TestLoadRetentionPolicies(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "retention.json")
+	assert.NoError(t, os.WriteFile(filename, []byte(`{"hot": {"tags": "0x01"}, "cold": {"tags": "0x02", "class": "archive"}}`), 0644))
+	policies, err := LoadRetentionPolicies(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, testRetentionPolicies, policies)
+	assert.Equal(t, []string{"cold", "hot"}, policies.Names())
+
+	assert.NoError(t, os.WriteFile(filename, []byte(`{"hot": {"class": "hot"}}`), 0644))
+	_, err = LoadRetentionPolicies(filename)
+	assert.Error(t, err)
+}
+
+func TestRetentionValidation(t *testing.T) {
+	uploader := &Uploader{logger: zg_common.NewLogger(), warnings: NewWarnings(0)}
+	_, data := newTestData(t, 100)
+
+	for field, opt := range map[string]UploadOption{
+		"RetentionCheck": {Retention: "hot", RetentionCheck: "strict"},
+		"Retention":      {Retention: "warm"},
+		"Tags":           {Retention: "hot", Tags: []byte{0x02}},
+	} {
+		_, _, err := uploader.WithRetentionPolicies(testRetentionPolicies).Upload(context.Background(), data, opt)
+		var optErr *zg_common.OptionError
+		if assert.True(t, errors.As(err, &optErr), field) {
+			assert.Equal(t, field, optErr.Field)
+		}
+	}
+
+	// checked only once finalized
+	opt := UploadOption{Retention: "hot", RetentionCheck: RetentionCheckWarn, FinalityRequired: TransactionPacked}
+	assert.Error(t, opt.Validate())
+	opt.FinalityRequired = FileFinalized
+	assert.NoError(t, opt.Validate())
+	opt.Retention = ""
+	assert.Error(t, opt.Validate())
+
+	// unknown policy fails before uploading if not configured
+	_, _, err := uploader.WithRetentionPolicies(nil).Upload(context.Background(), data, UploadOption{Retention: "hot"})
+	assert.Error(t, err)
+}
+
+func TestRetentionCheck(t *testing.T) {
+	service := testutil.NewZgsService()
+	service.SetHooks(testutil.ZgsHooks{RetentionClass: retentionClassByTags})
+	client := newMockZgsNode(t, service)
+	uploader := &Uploader{clients: []*node.ZgsClient{client}, routines: 2, logger: zg_common.NewLogger(), warnings: NewWarnings(0)}
+	uploader.WithRetentionPolicies(testRetentionPolicies)
+
+	upload := func(tags []byte, policy string, check RetentionCheck) error {
+		content, data := newTestData(t, core.DefaultSegmentSize+100)
+		_, err := service.Submit(content, tags)
+		assert.NoError(t, err)
+
+		_, _, err = uploader.Upload(context.Background(), data, UploadOption{
+			SkipTx:         true,
+			Retention:      policy,
+			RetentionCheck: check,
+		})
+		return err
+	}
+
+	// class applied as expected
+	assert.NoError(t, upload([]byte{0x02}, "cold", RetentionCheckFail))
+
+	// class applied other than expected, e.g. node not upgraded
+	err := upload(nil, "cold", RetentionCheckFail)
+	var mismatch *RetentionMismatchError
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, "archive", mismatch.Expected)
+		assert.Empty(t, mismatch.Actual)
+	}
+	assert.ErrorIs(t, err, ErrRetentionMismatch)
+
+	assert.NoError(t, upload([]byte{0x02}, "hot", RetentionCheckWarn))
+	assert.Equal(t, 1, uploader.Warnings().Count(WarningRetentionMismatch))
+}
+
+func TestRetentionTagsE2E(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	network.Nodes[0].SetHooks(testutil.ZgsHooks{RetentionClass: retentionClassByTags})
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+	uploader.WithRetentionPolicies(testRetentionPolicies)
+
+	// tags of policy submitted on chain
+	_, data := newTestData(t, 100)
+	_, root, err := uploader.Upload(context.Background(), data, UploadOption{Retention: "hot", RetentionCheck: RetentionCheckFail})
+	assert.NoError(t, err)
+
+	submissions := network.Chain.Submissions()
+	if assert.Len(t, submissions, 1) {
+		assert.Equal(t, root, submissions[0].Root())
+		assert.Equal(t, []byte{0x01}, submissions[0].Tags)
+	}
+}
@@ -0,0 +1,346 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/core/merkle"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// SegmentExportVersion is the version of files exported by ExportSegments. Files of other versions are rejected
+// by ImportSegments.
+const SegmentExportVersion = 1
+
+// SegmentManifestFileName is the name of the index manifest in the directory exported by ExportSegments.
+const SegmentManifestFileName = "manifest.json"
+
+// SegmentManifest is the index of segments exported by ExportSegments, which is persisted in JSON as
+// SegmentManifestFileName along with the exported segments in the same directory:
+//
+//   - segment-<index>.bin: raw segment data, where the last segment of file is zero-padded to chunks as stored
+//     on storage nodes.
+//   - segment-<index>.proof.json: SegmentProofFile, the merkle proof of segment against the file root.
+//
+// Segments could be verified offline by the proof against the root of file, without connection to any storage
+// node or blockchain.
+type SegmentManifest struct {
+	Version     int                   `json:"version"`
+	Root        common.Hash           `json:"root"`        // file merkle root
+	FileSize    uint64                `json:"fileSize"`    // file size in bytes
+	NumSegments uint64                `json:"numSegments"` // total number of segments of file
+	Segments    []SegmentManifestItem `json:"segments"`    // exported segments in order of index
+}
+
+// SegmentManifestItem is an exported segment in manifest, where file names are relative to the manifest.
+type SegmentManifestItem struct {
+	Index uint64 `json:"index"` // segment index relative to file
+	Size  int    `json:"size"`  // size of segment data in bytes
+	Data  string `json:"data"`  // file name of segment data
+	Proof string `json:"proof"` // file name of proof sidecar
+}
+
+// SegmentProofFile is the proof sidecar of an exported segment.
+type SegmentProofFile struct {
+	Version  int          `json:"version"`
+	Root     common.Hash  `json:"root"`     // file merkle root
+	Index    uint64       `json:"index"`    // segment index relative to file
+	FileSize uint64       `json:"fileSize"` // file size in bytes
+	Proof    merkle.Proof `json:"proof"`    // segment merkle proof
+}
+
+// SegmentImportResult is the result of ImportSegments.
+type SegmentImportResult struct {
+	Root     common.Hash    `json:"root"`
+	Verified int            `json:"verified"` // number of segments verified against the root
+	Pushed   map[string]int `json:"pushed"`   // number of segments pushed to each storage node
+	Skipped  []string       `json:"skipped"`  // storage nodes on which the file already finalized
+}
+
+func segmentDataFileName(index uint64) string {
+	return fmt.Sprintf("segment-%v.bin", index)
+}
+
+func segmentProofFileName(index uint64) string {
+	return fmt.Sprintf("segment-%v.proof.json", index)
+}
+
+// ExportSegments downloads the specified segments of file along with merkle proofs, and writes them into destDir,
+// so that they could be verified offline, or imported to storage nodes by ImportSegments. All segments are
+// exported if indices not specified, where indices are relative to the file. See SegmentManifest for the format.
+func (downloader *Downloader) ExportSegments(ctx context.Context, root string, indices []uint64, destDir string) (*SegmentManifest, error) {
+	if err := downloader.acquire(); err != nil {
+		return nil, err
+	}
+	defer downloader.release()
+
+	hash := common.HexToHash(root)
+
+	info, err := downloader.queryFile(ctx, hash)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to query file info")
+	}
+
+	shardConfigs, err := getShardConfigs(ctx, downloader.clients)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := SegmentManifest{
+		Version:     SegmentExportVersion,
+		Root:        hash,
+		FileSize:    info.Tx.Size,
+		NumSegments: core.NumSplits(int64(info.Tx.Size), core.DefaultSegmentSize),
+		Segments:    make([]SegmentManifestItem, 0),
+	}
+
+	if len(indices) == 0 {
+		for i := uint64(0); i < manifest.NumSegments; i++ {
+			indices = append(indices, i)
+		}
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, errors.WithMessage(err, "Failed to create directory")
+	}
+
+	startSegmentIndex := info.Tx.StartEntryIndex / core.DefaultSegmentMaxChunks
+	for _, index := range indices {
+		if index >= manifest.NumSegments {
+			return nil, errors.Errorf("segment index %v out of bound, number of segments = %v", index, manifest.NumSegments)
+		}
+
+		var segment *node.SegmentWithProof
+		for i, client := range downloader.clients {
+			if !shardConfigs[i].HasSegment(startSegmentIndex + index) {
+				continue
+			}
+
+			if segment, err = downloader.downloadSegmentWithProof(ctx, client, hash, info, index); err == nil {
+				break
+			}
+
+			downloader.logger.WithError(err).WithFields(logrus.Fields{
+				"node":    client.URL(),
+				"segment": index,
+			}).Warn("Failed to download segment to export, try another storage node")
+		}
+
+		if segment == nil {
+			return nil, errors.Errorf("failed to download segment %v", index)
+		}
+
+		item, err := writeSegment(destDir, segment)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Segments = append(manifest.Segments, item)
+	}
+
+	if err := writeJSONFile(filepath.Join(destDir, SegmentManifestFileName), &manifest); err != nil {
+		return nil, errors.WithMessage(err, "Failed to write manifest")
+	}
+
+	downloader.logger.WithFields(logrus.Fields{
+		"root":     hash,
+		"segments": len(manifest.Segments),
+		"dir":      destDir,
+	}).Info("Segments exported")
+
+	return &manifest, nil
+}
+
+// downloadSegmentWithProof downloads the segment with proof from storage node, and validates against the root.
+func (downloader *Downloader) downloadSegmentWithProof(ctx context.Context, client *node.ZgsClient, root common.Hash, info *node.FileInfo, index uint64) (*node.SegmentWithProof, error) {
+	segment, err := client.DownloadSegmentWithProofByTxSeq(ctx, info.Tx.Seq, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if segment == nil {
+		return nil, errors.New("segment not found")
+	}
+
+	if err := validateSegment(root, int64(info.Tx.Size), index, segment); err != nil {
+		return nil, err
+	}
+
+	segment.Root, segment.Index, segment.FileSize = root, index, info.Tx.Size
+
+	return segment, nil
+}
+
+// writeSegment writes the segment data and proof sidecar into dir.
+func writeSegment(dir string, segment *node.SegmentWithProof) (SegmentManifestItem, error) {
+	item := SegmentManifestItem{
+		Index: segment.Index,
+		Size:  len(segment.Data),
+		Data:  segmentDataFileName(segment.Index),
+		Proof: segmentProofFileName(segment.Index),
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, item.Data), segment.Data, 0644); err != nil {
+		return item, errors.WithMessagef(err, "Failed to write segment %v", segment.Index)
+	}
+
+	proof := SegmentProofFile{
+		Version:  SegmentExportVersion,
+		Root:     segment.Root,
+		Index:    segment.Index,
+		FileSize: segment.FileSize,
+		Proof:    segment.Proof,
+	}
+
+	if err := writeJSONFile(filepath.Join(dir, item.Proof), &proof); err != nil {
+		return item, errors.WithMessagef(err, "Failed to write proof of segment %v", segment.Index)
+	}
+
+	return item, nil
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
+
+func readJSONFile(path string, v interface{}) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, v)
+}
+
+// LoadSegments loads the segments exported by ExportSegments from srcDir, and verifies each one against the file
+// root in manifest.
+func LoadSegments(srcDir string) (*SegmentManifest, []node.SegmentWithProof, error) {
+	var manifest SegmentManifest
+	if err := readJSONFile(filepath.Join(srcDir, SegmentManifestFileName), &manifest); err != nil {
+		return nil, nil, errors.WithMessage(err, "Failed to read manifest")
+	}
+
+	if manifest.Version != SegmentExportVersion {
+		return nil, nil, errors.Errorf("unsupported manifest version %v, expected %v", manifest.Version, SegmentExportVersion)
+	}
+
+	segments := make([]node.SegmentWithProof, 0, len(manifest.Segments))
+	for _, item := range manifest.Segments {
+		data, err := os.ReadFile(filepath.Join(srcDir, item.Data))
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "Failed to read segment %v", item.Index)
+		}
+
+		var proof SegmentProofFile
+		if err := readJSONFile(filepath.Join(srcDir, item.Proof), &proof); err != nil {
+			return nil, nil, errors.WithMessagef(err, "Failed to read proof of segment %v", item.Index)
+		}
+
+		if proof.Version != SegmentExportVersion || proof.Root != manifest.Root || proof.Index != item.Index || proof.FileSize != manifest.FileSize {
+			return nil, nil, errors.Errorf("proof of segment %v mismatches manifest", item.Index)
+		}
+
+		segment := node.SegmentWithProof{
+			Root:     manifest.Root,
+			Data:     data,
+			Index:    item.Index,
+			Proof:    proof.Proof,
+			FileSize: manifest.FileSize,
+		}
+
+		if err := validateSegment(manifest.Root, int64(manifest.FileSize), item.Index, &segment); err != nil {
+			return nil, nil, errors.WithMessagef(err, "Invalid segment %v", item.Index)
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return &manifest, segments, nil
+}
+
+// ImportSegments pushes the segments exported by ExportSegments from srcDir to the target storage nodes, e.g. to
+// seed storage nodes of a new region by sneakernet. Each segment is verified against the file root before push,
+// and only segments in the shard of each storage node are pushed. The file should have been submitted on chain,
+// and storage nodes on which the file already finalized are skipped.
+func ImportSegments(ctx context.Context, srcDir string, targets []*node.ZgsClient) (*SegmentImportResult, error) {
+	manifest, segments, err := LoadSegments(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := SegmentImportResult{
+		Root:     manifest.Root,
+		Verified: len(segments),
+		Pushed:   make(map[string]int),
+		Skipped:  make([]string, 0),
+	}
+
+	for _, target := range targets {
+		info, err := target.GetFileInfo(ctx, manifest.Root)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Failed to get file info from node %v", target.URL())
+		}
+
+		if info == nil {
+			return nil, errors.Errorf("file not found on node %v", target.URL())
+		}
+
+		if info.Finalized {
+			result.Skipped = append(result.Skipped, target.URL())
+			continue
+		}
+
+		shardConfig, err := target.GetShardConfig(ctx)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Failed to get shard config from node %v", target.URL())
+		}
+
+		startSegmentIndex := info.Tx.StartEntryIndex / core.DefaultSegmentMaxChunks
+
+		var batch []node.SegmentWithProof
+		push := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+
+			if _, err := target.UploadSegmentsByTxSeq(ctx, batch, info.Tx.Seq); err != nil {
+				return errors.WithMessagef(err, "Failed to upload segments to node %v", target.URL())
+			}
+
+			result.Pushed[target.URL()] += len(batch)
+			batch = nil
+
+			return nil
+		}
+
+		for _, segment := range segments {
+			if !shardConfig.HasSegment(startSegmentIndex + segment.Index) {
+				continue
+			}
+
+			if batch = append(batch, segment); len(batch) >= int(defaultTaskSize) {
+				if err := push(); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if err := push(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &result, nil
+}
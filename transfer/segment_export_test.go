@@ -0,0 +1,76 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImportSegments(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{ShardId: 0, NumShard: 2}, shard.ShardConfig{ShardId: 1, NumShard: 2})
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	content, data := newTestData(t, 3*core.DefaultSegmentSize+100)
+	_, root, err := uploader.Upload(context.Background(), data)
+	assert.NoError(t, err)
+
+	downloader, err := NewDownloader(network.ZgsClients())
+	assert.NoError(t, err)
+	defer downloader.Close()
+
+	// export a part of segments
+	manifest, err := downloader.ExportSegments(context.Background(), root.Hex(), []uint64{1, 3}, t.TempDir())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), manifest.NumSegments)
+	assert.Equal(t, 2, len(manifest.Segments))
+
+	_, err = downloader.ExportSegments(context.Background(), root.Hex(), []uint64{4}, t.TempDir())
+	assert.ErrorContains(t, err, "out of bound")
+
+	// export all segments, and verified offline
+	exported := t.TempDir()
+	manifest, err = downloader.ExportSegments(context.Background(), root.Hex(), nil, exported)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(manifest.Segments))
+	_, segments, err := LoadSegments(exported)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(segments))
+
+	// wipe storage nodes, and import exported segments
+	for _, service := range network.Nodes {
+		assert.NoError(t, service.Wipe(root))
+	}
+
+	result, err := ImportSegments(context.Background(), exported, network.ZgsClients())
+	assert.NoError(t, err)
+	assert.Equal(t, 4, result.Verified)
+	assert.Equal(t, map[string]int{network.NodeURLs[0]: 2, network.NodeURLs[1]: 2}, result.Pushed)
+
+	filename := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, downloader.Download(context.Background(), root.Hex(), filename, true))
+	downloaded, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+
+	// skipped once finalized
+	result, err = ImportSegments(context.Background(), exported, network.ZgsClients())
+	assert.NoError(t, err)
+	assert.Equal(t, network.NodeURLs, result.Skipped)
+
+	// tampered segment rejected before push
+	segmentFile := filepath.Join(exported, manifest.Segments[2].Data)
+	tampered, err := os.ReadFile(segmentFile)
+	assert.NoError(t, err)
+	tampered[0] ^= 1
+	assert.NoError(t, os.WriteFile(segmentFile, tampered, 0644))
+	_, err = ImportSegments(context.Background(), exported, network.ZgsClients())
+	assert.ErrorContains(t, err, "Invalid segment 2")
+}
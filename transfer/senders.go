@@ -0,0 +1,211 @@
+package transfer
+
+import (
+	"math/big"
+	"strings"
+	"sync"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/contract"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/openweb3/web3go"
+	"github.com/pkg/errors"
+)
+
+// insufficientFundsError is the error of fullnode when the balance of sender is not enough to send transaction.
+const insufficientFundsError = "insufficient funds"
+
+func isInsufficientFundsError(msg string) bool {
+	return strings.Contains(msg, insufficientFundsError)
+}
+
+// ErrNoSenderAvailable is returned when all accounts of SenderPool are removed from rotation.
+var ErrNoSenderAvailable = errors.New("no sender available")
+
+// SenderStrategy is the strategy to select the account to send the next flow submission in SenderPool.
+type SenderStrategy string
+
+const (
+	SenderRoundRobin   SenderStrategy = "round-robin"   // accounts selected in turn
+	SenderLeastPending SenderStrategy = "least-pending" // account of the least pending transactions selected
+)
+
+// Validate checks the strategy, and returns an error if unsupported.
+func (strategy SenderStrategy) Validate() error {
+	switch strategy {
+	case SenderRoundRobin, SenderLeastPending:
+		return nil
+	default:
+		return zg_common.NewOptionError("SenderStrategy", "unsupported strategy %q", strategy)
+	}
+}
+
+// SenderStats is the statistics of an account in SenderPool.
+type SenderStats struct {
+	Address   common.Address `json:"address"`
+	Pending   int            `json:"pending"`         // submissions being sent or waiting for receipt
+	Submitted uint64         `json:"submitted"`       // transactions sent successfully
+	Files     uint64         `json:"files"`           // files submitted by the transactions
+	Value     *big.Int       `json:"value"`           // storage fee paid by the transactions
+	Removed   bool           `json:"removed"`         // removed from rotation
+	Error     string         `json:"error,omitempty"` // reason removed from rotation
+}
+
+// sender is an account in SenderPool.
+type sender struct {
+	client *web3go.Client
+	stats  SenderStats
+	flows  map[common.Address]*contract.FlowContract // flow contracts bound with the account by address
+}
+
+// SenderPool spreads flow submissions across multiple accounts, so that the throughput is not limited by the
+// nonce serialization of a single account. Transactions of each account are still sent in sequence with the
+// pending nonce, see Uploader.SubmitLogEntry.
+//
+// Accounts failed to send transactions due to insufficient funds are removed from rotation, and the submission is
+// sent by another account instead. It is safe for concurrent use.
+type SenderPool struct {
+	mu       sync.Mutex
+	senders  []*sender
+	strategy SenderStrategy
+	next     int // next account in round robin
+}
+
+// NewSenderPool creates a pool of accounts to send flow submissions, where each web3 client signs transactions by
+// a different account.
+func NewSenderPool(w3Clients []*web3go.Client, strategy SenderStrategy) (*SenderPool, error) {
+	if len(w3Clients) == 0 {
+		return nil, errors.New("sender not specified")
+	}
+
+	if err := strategy.Validate(); err != nil {
+		return nil, err
+	}
+
+	pool := SenderPool{strategy: strategy}
+	accounts := make(map[common.Address]bool)
+	for i, client := range w3Clients {
+		sm, err := client.GetSignerManager()
+		if err != nil || sm == nil || len(sm.List()) == 0 {
+			return nil, errors.Errorf("signer of sender %v not specified", i)
+		}
+
+		address := sm.List()[0].Address()
+		if accounts[address] {
+			return nil, errors.Errorf("duplicate sender %v", address)
+		}
+		accounts[address] = true
+
+		pool.senders = append(pool.senders, &sender{
+			client: client,
+			stats:  SenderStats{Address: address, Value: new(big.Int)},
+			flows:  make(map[common.Address]*contract.FlowContract),
+		})
+	}
+
+	return &pool, nil
+}
+
+// acquire selects an account by strategy to send a submission to the flow contract of specified address, which
+// should be released once the transaction sent.
+func (pool *SenderPool) acquire(flowAddress common.Address) (*sender, *contract.FlowContract, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var selected *sender
+	for i := range pool.senders {
+		candidate := pool.senders[(pool.next+i)%len(pool.senders)]
+		if candidate.stats.Removed {
+			continue
+		}
+
+		if selected == nil || (pool.strategy == SenderLeastPending && candidate.stats.Pending < selected.stats.Pending) {
+			selected = candidate
+		}
+
+		if pool.strategy == SenderRoundRobin {
+			break
+		}
+	}
+
+	if selected == nil {
+		return nil, nil, ErrNoSenderAvailable
+	}
+
+	flow, ok := selected.flows[flowAddress]
+	if !ok {
+		var err error
+		if flow, err = contract.NewFlowContract(flowAddress, selected.client); err != nil {
+			return nil, nil, errors.WithMessagef(err, "Failed to create flow contract for sender %v", selected.stats.Address)
+		}
+		selected.flows[flowAddress] = flow
+	}
+
+	for i, s := range pool.senders {
+		if s == selected {
+			pool.next = (i + 1) % len(pool.senders)
+		}
+	}
+
+	selected.stats.Pending++
+
+	return selected, flow, nil
+}
+
+// release releases the account once the transaction of files sent, along with the storage fee paid. If failed due
+// to insufficient funds, the account is removed from rotation, and true is returned to send by another account.
+func (pool *SenderPool) release(s *sender, files int, value *big.Int, err error) (removed bool) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	s.stats.Pending--
+
+	if err == nil {
+		s.stats.Submitted++
+		s.stats.Files += uint64(files)
+		if value != nil {
+			s.stats.Value.Add(s.stats.Value, value)
+		}
+
+		return false
+	}
+
+	if !isInsufficientFundsError(err.Error()) {
+		return false
+	}
+
+	s.stats.Removed = true
+	s.stats.Error = err.Error()
+
+	return true
+}
+
+// Len returns the number of accounts in rotation.
+func (pool *SenderPool) Len() int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var n int
+	for _, s := range pool.senders {
+		if !s.stats.Removed {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Stats returns the statistics of accounts in the order specified when created.
+func (pool *SenderPool) Stats() []SenderStats {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	stats := make([]SenderStats, 0, len(pool.senders))
+	for _, s := range pool.senders {
+		copied := s.stats
+		copied.Value = new(big.Int).Set(s.stats.Value)
+		stats = append(stats, copied)
+	}
+
+	return stats
+}
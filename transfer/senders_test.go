@@ -0,0 +1,134 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/blockchain"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/openweb3/web3go"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSenders creates web3 clients of n random accounts connected to the chain of network.
+func newTestSenders(t *testing.T, network *testutil.Network, n int) ([]*web3go.Client, []common.Address) {
+	var clients []*web3go.Client
+	var addresses []common.Address
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateKey()
+		assert.NoError(t, err)
+
+		client, err := blockchain.NewWeb3(network.ChainURL, hexutil.Encode(crypto.FromECDSA(key)))
+		assert.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+
+		clients = append(clients, client)
+		addresses = append(addresses, crypto.PubkeyToAddress(key.PublicKey))
+	}
+
+	return clients, addresses
+}
+
+// newTestFolder creates a folder of n small files with distinct content.
+func newTestFolder(t *testing.T, n int) string {
+	folder := t.TempDir()
+	for i := 0; i < n; i++ {
+		content := []byte(fmt.Sprintf("content of file %v", i))
+		assert.NoError(t, os.WriteFile(filepath.Join(folder, fmt.Sprintf("file%v.txt", i)), content, 0644))
+	}
+
+	return folder
+}
+
+func TestSenderPoolRoundRobin(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	clients, addresses := newTestSenders(t, network, 3)
+
+	_, err := NewSenderPool(clients, "random")
+	assert.Error(t, err)
+	_, err = NewSenderPool(append(clients, clients[0]), SenderRoundRobin)
+	assert.ErrorContains(t, err, "duplicate sender")
+
+	pool, err := NewSenderPool(clients, SenderRoundRobin)
+	assert.NoError(t, err)
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+	uploader.WithSenders(pool)
+
+	// 6 files and directory metadata submitted by 3 accounts in turn
+	summary, err := uploader.UploadDirWithSummary(context.Background(), newTestFolder(t, 6))
+	assert.NoError(t, err)
+	assert.Equal(t, 7, len(network.Chain.Submissions()))
+
+	signed := make(map[common.Address]int)
+	for _, file := range summary.Files {
+		assert.Contains(t, addresses, file.Sender)
+		signed[file.Sender]++
+	}
+	assert.Equal(t, 6, signed[addresses[0]]+signed[addresses[1]]+signed[addresses[2]])
+
+	var submitted []uint64
+	for i, stats := range pool.Stats() {
+		assert.Equal(t, addresses[i], stats.Address)
+		assert.Zero(t, stats.Pending)
+		assert.Equal(t, stats.Submitted, stats.Files)
+		assert.Positive(t, stats.Value.Sign())
+		submitted = append(submitted, stats.Submitted)
+	}
+	assert.ElementsMatch(t, []uint64{3, 2, 2}, submitted)
+}
+
+func TestSenderPoolInsufficientFunds(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	clients, addresses := newTestSenders(t, network, 3)
+
+	// the second account runs out of funds
+	network.Chain.SetSendHook(func(sender common.Address) error {
+		if sender == addresses[1] {
+			return errors.New("insufficient funds for gas * price + value")
+		}
+
+		return nil
+	})
+
+	pool, err := NewSenderPool(clients, SenderLeastPending)
+	assert.NoError(t, err)
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+	uploader.WithSenders(pool)
+
+	// batch not failed, but submitted by the other accounts
+	summary, err := uploader.UploadDirWithSummary(context.Background(), newTestFolder(t, 4))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, summary.Uploaded.Files)
+	for _, file := range summary.Files {
+		assert.NotEqual(t, addresses[1], file.Sender)
+	}
+
+	stats := pool.Stats()
+	assert.True(t, stats[1].Removed)
+	assert.Contains(t, stats[1].Error, "insufficient funds")
+	assert.Zero(t, stats[1].Submitted)
+	assert.Equal(t, uint64(5), stats[0].Submitted+stats[2].Submitted)
+	assert.Equal(t, 2, pool.Len())
+	assert.Equal(t, 1, uploader.Warnings().Count(WarningSenderRemoved))
+
+	// failed once all accounts removed
+	network.Chain.SetSendHook(func(sender common.Address) error {
+		return errors.New("insufficient funds for gas * price + value")
+	})
+	_, err = uploader.UploadDirWithSummary(context.Background(), newTestFolder(t, 1))
+	assert.ErrorIs(t, err, ErrNoSenderAvailable)
+	assert.Zero(t, pool.Len())
+}
@@ -0,0 +1,242 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+// errUploadAbandoned is returned when the shared upload reads data after all callers left.
+var errUploadAbandoned = errors.New("upload abandoned by all callers")
+
+// uploadCall is an in-flight or completed upload shared by concurrent callers.
+type uploadCall struct {
+	done   chan struct{}
+	txHash common.Hash
+	err    error
+
+	refs    int                // number of callers waiting for the result
+	cancel  context.CancelFunc // cancels the shared upload
+	sources *flightSources     // data of callers waiting for the result
+}
+
+// uploadFlights deduplicates concurrent uploads of the same data root and upload option within one process.
+//
+// The shared upload is executed in a context detached from any individual caller, and keeps running as long as any
+// caller is waiting for the result. Since callers upload the same data, the shared upload reads the data of any
+// caller still waiting, and a caller returns once its data is no longer read. The shared upload is cancelled once
+// all callers left.
+type uploadFlights struct {
+	mu    sync.Mutex
+	calls map[common.Hash]*uploadCall
+
+	coalesced atomic.Uint64 // number of calls that joined an in-flight upload
+}
+
+func newUploadFlights() *uploadFlights {
+	return &uploadFlights{
+		calls: make(map[common.Hash]*uploadCall),
+	}
+}
+
+// uploadFlightKey returns the key of upload flight, so that only uploads of the same data root with the same
+// upload option are coalesced.
+func uploadFlightKey(root common.Hash, opt UploadOption) common.Hash {
+	return crypto.Keccak256Hash(root.Bytes(), []byte(fmt.Sprintf("%+v", opt)))
+}
+
+// Do executes and returns the result of fn for the given key, making sure that only one execution is in-flight for
+// a given key at a time. If a duplicate call comes in, the duplicate caller waits for the original to complete and
+// receives the same result. The data passed to fn reads the data of callers still waiting, which must be the same
+// for the given key.
+func (flights *uploadFlights) Do(ctx context.Context, key common.Hash, data core.IterableData, fn func(context.Context, core.IterableData) (common.Hash, error)) (common.Hash, error) {
+	flights.mu.Lock()
+	if call, ok := flights.calls[key]; ok {
+		call.refs++
+		id := call.sources.add(data)
+		flights.mu.Unlock()
+		flights.coalesced.Add(1)
+
+		return flights.wait(ctx, key, call, id)
+	}
+
+	sharedCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	call := &uploadCall{
+		done:    make(chan struct{}),
+		refs:    1,
+		cancel:  cancel,
+		sources: newFlightSources(),
+	}
+	id := call.sources.add(data)
+	flights.calls[key] = call
+	flights.mu.Unlock()
+
+	shared := &flightData{sources: call.sources, size: data.Size(), paddedSize: data.PaddedSize()}
+
+	go func() {
+		defer cancel()
+
+		call.txHash, call.err = fn(sharedCtx, shared)
+
+		flights.mu.Lock()
+		if flights.calls[key] == call {
+			delete(flights.calls, key)
+		}
+		flights.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	return flights.wait(ctx, key, call, id)
+}
+
+// wait waits for the result of call. If ctx done before completed, the shared upload is cancelled only if no other
+// caller is waiting, and returns once the data of caller is no longer read.
+func (flights *uploadFlights) wait(ctx context.Context, key common.Hash, call *uploadCall, id int) (common.Hash, error) {
+	select {
+	case <-call.done:
+		return call.txHash, call.err
+	case <-ctx.Done():
+	}
+
+	flights.mu.Lock()
+	call.refs--
+	if call.refs == 0 {
+		// abort the shared upload, and later calls will not join it
+		call.cancel()
+		if flights.calls[key] == call {
+			delete(flights.calls, key)
+		}
+	}
+	flights.mu.Unlock()
+
+	call.sources.remove(id)
+
+	return common.Hash{}, ctx.Err()
+}
+
+// Coalesced returns the number of upload calls that joined an in-flight upload of the same root.
+func (flights *uploadFlights) Coalesced() uint64 {
+	return flights.coalesced.Load()
+}
+
+// flightSources is the data of callers waiting for a shared upload, of which one is read at a time.
+type flightSources struct {
+	mu      sync.RWMutex
+	datas   map[int]core.IterableData
+	current int // id of data read, or -1 if all callers left
+	nextId  int
+}
+
+func newFlightSources() *flightSources {
+	return &flightSources{
+		datas:   make(map[int]core.IterableData),
+		current: -1,
+	}
+}
+
+// add adds the data of caller, and returns the id to remove once the caller left.
+func (sources *flightSources) add(data core.IterableData) int {
+	sources.mu.Lock()
+	defer sources.mu.Unlock()
+
+	id := sources.nextId
+	sources.nextId++
+
+	sources.datas[id] = data
+	if sources.current < 0 {
+		sources.current = id
+	}
+
+	return id
+}
+
+// remove removes the data of caller, and switches to read data of the earliest caller still waiting if the data
+// removed is being read. It returns after reads of the data removed completed.
+func (sources *flightSources) remove(id int) {
+	sources.mu.Lock()
+	defer sources.mu.Unlock()
+
+	delete(sources.datas, id)
+	if sources.current != id {
+		return
+	}
+
+	sources.current = -1
+	for other := range sources.datas {
+		if sources.current < 0 || other < sources.current {
+			sources.current = other
+		}
+	}
+}
+
+func (sources *flightSources) read(buf []byte, offset int64) (int, error) {
+	sources.mu.RLock()
+	defer sources.mu.RUnlock()
+
+	if sources.current < 0 {
+		return 0, errUploadAbandoned
+	}
+
+	return sources.datas[sources.current].Read(buf, offset)
+}
+
+// flightData is the data of a shared upload, or a fragment of it, which reads the data of callers still waiting.
+type flightData struct {
+	sources    *flightSources
+	offset     int64
+	size       int64
+	paddedSize uint64
+}
+
+func (data *flightData) NumChunks() uint64 {
+	return core.NumSplits(data.size, core.DefaultChunkSize)
+}
+
+func (data *flightData) NumSegments() uint64 {
+	return core.NumSplits(data.size, core.DefaultSegmentSize)
+}
+
+func (data *flightData) Offset() int64 {
+	return data.offset
+}
+
+func (data *flightData) Size() int64 {
+	return data.size
+}
+
+func (data *flightData) PaddedSize() uint64 {
+	return data.paddedSize
+}
+
+func (data *flightData) Read(buf []byte, offset int64) (int, error) {
+	// never read beyond the data, e.g. data of the next fragment
+	if offset >= data.size {
+		return 0, nil
+	}
+	if remaining := data.size - offset; int64(len(buf)) > remaining {
+		buf = buf[:remaining]
+	}
+
+	return data.sources.read(buf, data.offset+offset)
+}
+
+func (data *flightData) Split(fragmentSize int64) []core.IterableData {
+	fragments := make([]core.IterableData, 0)
+	for offset := data.offset; offset < data.offset+data.size; offset += fragmentSize {
+		size := min(data.offset+data.size-offset, fragmentSize)
+		fragments = append(fragments, &flightData{
+			sources:    data.sources,
+			offset:     offset,
+			size:       size,
+			paddedSize: core.IteratorPaddedSize(size, true),
+		})
+	}
+	return fragments
+}
@@ -0,0 +1,244 @@
+package transfer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// readCountingData counts the reads of underlying data.
+type readCountingData struct {
+	core.IterableData
+	reads atomic.Int32
+}
+
+func (data *readCountingData) Read(buf []byte, offset int64) (int, error) {
+	data.reads.Add(1)
+	return data.IterableData.Read(buf, offset)
+}
+
+func newFlightTestData(t *testing.T) *readCountingData {
+	data, err := core.NewDataInMemory([]byte("hello, 0g storage"))
+	assert.NoError(t, err)
+	return &readCountingData{IterableData: data}
+}
+
+func TestUploadFlightsCoalesce(t *testing.T) {
+	flights := newUploadFlights()
+	root := common.HexToHash("0x01")
+	txHash := common.HexToHash("0x02")
+
+	var submissions atomic.Int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context, data core.IterableData) (common.Hash, error) {
+		submissions.Add(1)
+		<-release
+		return txHash, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]common.Hash, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hash, err := flights.Do(context.Background(), root, newFlightTestData(t), fn)
+			assert.NoError(t, err)
+			results[i] = hash
+		}(i)
+	}
+
+	// wait for both calls joined
+	assert.Eventually(t, func() bool { return flights.Coalesced() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), submissions.Load())
+	assert.Equal(t, []common.Hash{txHash, txHash}, results)
+}
+
+func TestUploadFlightsCancel(t *testing.T) {
+	flights := newUploadFlights()
+	root := common.HexToHash("0x01")
+
+	sharedCancelled := make(chan struct{})
+	fn := func(ctx context.Context, data core.IterableData) (common.Hash, error) {
+		<-ctx.Done()
+		close(sharedCancelled)
+		return common.Hash{}, ctx.Err()
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := flights.Do(ctx1, root, newFlightTestData(t), fn)
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool {
+		flights.mu.Lock()
+		defer flights.mu.Unlock()
+		return flights.calls[root] != nil
+	}, time.Second, time.Millisecond)
+	go func() {
+		_, err := flights.Do(ctx2, root, newFlightTestData(t), fn)
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return flights.Coalesced() == 1 }, time.Second, time.Millisecond)
+
+	// cancelling the first caller does not cancel the shared upload, since another caller is waiting
+	cancel1()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	select {
+	case <-sharedCancelled:
+		t.Fatal("shared upload cancelled while another caller waiting")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// cancelled once all callers left
+	cancel2()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	select {
+	case <-sharedCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("shared upload not cancelled")
+	}
+}
+
+func TestUploadFlightsFirstCallerLeaves(t *testing.T) {
+	flights := newUploadFlights()
+	root := common.HexToHash("0x01")
+	txHash := common.HexToHash("0x02")
+
+	// read data repeatedly until released
+	var submissions atomic.Int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context, data core.IterableData) (common.Hash, error) {
+		submissions.Add(1)
+		buf := make([]byte, data.Size())
+		for {
+			n, err := data.Read(buf, 0)
+			if err != nil {
+				return common.Hash{}, err
+			}
+			assert.Equal(t, "hello, 0g storage", string(buf[:n]))
+
+			select {
+			case <-release:
+				return txHash, nil
+			case <-ctx.Done():
+				return common.Hash{}, ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+
+	data1, data2 := newFlightTestData(t), newFlightTestData(t)
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := flights.Do(ctx1, root, data1, fn)
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return data1.reads.Load() > 0 }, time.Second, time.Millisecond)
+
+	results := make(chan common.Hash, 1)
+	go func() {
+		hash, err := flights.Do(context.Background(), root, data2, fn)
+		assert.NoError(t, err)
+		results <- hash
+	}()
+	assert.Eventually(t, func() bool { return flights.Coalesced() == 1 }, time.Second, time.Millisecond)
+
+	// data of the first caller never read once it returned, and the shared upload continues with the data of the
+	// joined caller
+	cancel1()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	reads := data1.reads.Load()
+	assert.Eventually(t, func() bool { return data2.reads.Load() > 0 }, time.Second, time.Millisecond)
+	close(release)
+
+	assert.Equal(t, txHash, <-results)
+	assert.Equal(t, reads, data1.reads.Load())
+	assert.Equal(t, int32(1), submissions.Load())
+}
+
+func TestFlightDataSplit(t *testing.T) {
+	content := make([]byte, 3*core.DefaultSegmentSize+100)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+
+	sources := newFlightSources()
+	sources.add(data)
+	shared := &flightData{sources: sources, size: data.Size(), paddedSize: data.PaddedSize()}
+
+	expected := data.Split(core.DefaultSegmentSize)
+	fragments := shared.Split(core.DefaultSegmentSize)
+	assert.Equal(t, len(expected), len(fragments))
+	for i, fragment := range fragments {
+		assert.Equal(t, expected[i].Size(), fragment.Size())
+		assert.Equal(t, expected[i].PaddedSize(), fragment.PaddedSize())
+		assert.Equal(t, expected[i].NumSegments(), fragment.NumSegments())
+
+		expectedRoot, err := core.MerkleRootData(expected[i])
+		assert.NoError(t, err)
+		root, err := core.MerkleRootData(fragment)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedRoot, root)
+	}
+}
+
+func TestUploadCoalescedE2E(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	// hold the shared upload until the other call joined
+	uploader.WithProgress(func(p Progress) {
+		if p.Phase == ProgressSubmitting {
+			assert.Eventually(t, func() bool { return uploader.CoalescedUploads() == 1 }, 5*time.Second, time.Millisecond)
+		}
+	})
+
+	content, _ := newTestData(t, 2*core.DefaultSegmentSize)
+
+	var wg sync.WaitGroup
+	roots := make([]common.Hash, 2)
+	for i := range roots {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data, err := core.NewDataInMemory(content)
+			assert.NoError(t, err)
+			_, roots[i], err = uploader.Upload(context.Background(), data)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, roots[0], roots[1])
+	assert.Equal(t, uint64(1), uploader.CoalescedUploads())
+	if assert.Len(t, network.Chain.Submissions(), 1) {
+		assert.Equal(t, roots[0], network.Chain.Submissions()[0].Root())
+	}
+}
+
+func TestUploadFlightKey(t *testing.T) {
+	root := common.HexToHash("0x01")
+
+	assert.Equal(t, uploadFlightKey(root, UploadOption{}), uploadFlightKey(root, UploadOption{}))
+	assert.NotEqual(t, uploadFlightKey(root, UploadOption{}), uploadFlightKey(common.HexToHash("0x02"), UploadOption{}))
+	assert.NotEqual(t, uploadFlightKey(root, UploadOption{}), uploadFlightKey(root, UploadOption{ExpectedReplica: 2}))
+}
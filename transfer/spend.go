@@ -0,0 +1,392 @@
+package transfer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/openweb3/web3go"
+	"github.com/pkg/errors"
+)
+
+// spendCostPrefix is the key prefix of cached transaction costs, which is followed by the tx hash.
+var spendCostPrefix = []byte("spend-cost-")
+
+// TxCost is the cost of a transaction that submits files to the flow contract.
+type TxCost struct {
+	GasUsed  uint64   `json:"gasUsed"`
+	GasPrice *big.Int `json:"gasPrice"` // effective gas price
+	Value    *big.Int `json:"value"`    // storage endowment transferred to the flow contract
+}
+
+// GasCost returns the gas fee paid for the transaction.
+func (cost *TxCost) GasCost() *big.Int {
+	if cost.GasPrice == nil {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Mul(new(big.Int).SetUint64(cost.GasUsed), cost.GasPrice)
+}
+
+// TxCostSource retrieves the cost of a transaction, which is implemented by ChainTxCostSource and TxCostCache.
+type TxCostSource interface {
+	TxCost(ctx context.Context, txHash common.Hash) (TxCost, error)
+}
+
+// ChainTxCostSource retrieves the transaction costs from receipts and transactions on blockchain.
+type ChainTxCostSource struct {
+	client *web3go.Client
+}
+
+var _ TxCostSource = (*ChainTxCostSource)(nil)
+
+// NewChainTxCostSource creates a source to retrieve transaction costs from blockchain.
+func NewChainTxCostSource(w3Client *web3go.Client) *ChainTxCostSource {
+	return &ChainTxCostSource{w3Client}
+}
+
+// TxCost implements the TxCostSource interface.
+func (source *ChainTxCostSource) TxCost(ctx context.Context, txHash common.Hash) (TxCost, error) {
+	receipt, err := source.client.WithContext(ctx).Eth.TransactionReceipt(txHash)
+	if err != nil {
+		return TxCost{}, errors.WithMessagef(err, "Failed to get receipt of tx %v", txHash)
+	}
+
+	if receipt == nil {
+		return TxCost{}, errors.Errorf("Receipt of tx %v not found", txHash)
+	}
+
+	tx, err := source.client.WithContext(ctx).Eth.TransactionByHash(txHash)
+	if err != nil {
+		return TxCost{}, errors.WithMessagef(err, "Failed to get tx %v", txHash)
+	}
+
+	if tx == nil {
+		return TxCost{}, errors.Errorf("Tx %v not found", txHash)
+	}
+
+	cost := TxCost{
+		GasUsed:  receipt.GasUsed,
+		GasPrice: new(big.Int).SetUint64(receipt.EffectiveGasPrice),
+		Value:    tx.Value,
+	}
+
+	// effective gas price is not available in receipts of legacy fullnodes
+	if receipt.EffectiveGasPrice == 0 && tx.GasPrice != nil {
+		cost.GasPrice = tx.GasPrice
+	}
+
+	if cost.Value == nil {
+		cost.Value = new(big.Int)
+	}
+
+	return cost, nil
+}
+
+// TxCostCache caches transaction costs retrieved from the underlying source in key-value store, so that
+// receipts need not be retrieved again when reporting on overlapped block ranges.
+type TxCostCache struct {
+	db     ethdb.KeyValueStore
+	source TxCostSource
+}
+
+var _ TxCostSource = (*TxCostCache)(nil)
+
+// NewTxCostCache creates a cache in the specified key-value store.
+func NewTxCostCache(db ethdb.KeyValueStore, source TxCostSource) *TxCostCache {
+	return &TxCostCache{db, source}
+}
+
+// OpenTxCostCache opens or creates a cache in LevelDB of the specified directory.
+func OpenTxCostCache(path string, source TxCostSource) (*TxCostCache, error) {
+	db, err := leveldb.New(path, 16, 16, "", false)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to open LevelDB")
+	}
+
+	return NewTxCostCache(db, source), nil
+}
+
+// Close closes the underlying key-value store.
+func (cache *TxCostCache) Close() error {
+	return cache.db.Close()
+}
+
+// TxCost implements the TxCostSource interface.
+func (cache *TxCostCache) TxCost(ctx context.Context, txHash common.Hash) (TxCost, error) {
+	key := append(append([]byte{}, spendCostPrefix...), txHash.Bytes()...)
+
+	var cost TxCost
+
+	if ok, err := cache.db.Has(key); err != nil {
+		return cost, errors.WithMessage(err, "Failed to read cache")
+	} else if ok {
+		value, err := cache.db.Get(key)
+		if err != nil {
+			return cost, errors.WithMessage(err, "Failed to read cache")
+		}
+
+		if err = json.Unmarshal(value, &cost); err != nil {
+			return cost, errors.WithMessage(err, "Failed to unmarshal cached tx cost")
+		}
+
+		return cost, nil
+	}
+
+	cost, err := cache.source.TxCost(ctx, txHash)
+	if err != nil {
+		return cost, err
+	}
+
+	value, err := json.Marshal(&cost)
+	if err != nil {
+		return cost, errors.WithMessage(err, "Failed to marshal tx cost")
+	}
+
+	if err = cache.db.Put(key, value); err != nil {
+		return cost, errors.WithMessage(err, "Failed to write cache")
+	}
+
+	return cost, nil
+}
+
+// SpendQuery is the query to report the storage spend of a sender.
+type SpendQuery struct {
+	Sender     common.Address
+	FromBlock  uint64 // inclusive
+	ToBlock    uint64 // inclusive, 0 for the latest block
+	BlockRange uint64 // number of blocks to retrieve logs in a single RPC, default 1000
+	ByTag      bool   // whether to group by file tags besides day
+}
+
+// SpendEntry is the storage spend of a group of files, e.g. submitted in a day.
+type SpendEntry struct {
+	Day       string        `json:"day,omitempty"`  // UTC date of block time in format YYYY-MM-DD
+	Tags      hexutil.Bytes `json:"tags,omitempty"` // only when grouped by tags
+	Files     uint64        `json:"files"`
+	Txs       uint64        `json:"txs"`
+	Size      uint64        `json:"size"`
+	GasUsed   uint64        `json:"gasUsed"`
+	GasCost   *big.Int      `json:"gasCost"`
+	Endowment *big.Int      `json:"endowment"`
+	Total     *big.Int      `json:"total"` // sum of gas cost and endowment
+}
+
+func newSpendEntry(day string, tags []byte) *SpendEntry {
+	return &SpendEntry{
+		Day:       day,
+		Tags:      tags,
+		GasCost:   new(big.Int),
+		Endowment: new(big.Int),
+		Total:     new(big.Int),
+	}
+}
+
+func (entry *SpendEntry) add(files, size, gasUsed uint64, gasCost, endowment *big.Int) {
+	entry.Files += files
+	entry.Txs++
+	entry.Size += size
+	entry.GasUsed += gasUsed
+	entry.GasCost.Add(entry.GasCost, gasCost)
+	entry.Endowment.Add(entry.Endowment, endowment)
+	entry.Total.Add(entry.GasCost, entry.Endowment)
+}
+
+// SpendReport is the storage spend of a sender in block range, grouped by day and optionally by tags.
+type SpendReport struct {
+	Sender    common.Address `json:"sender"`
+	FromBlock uint64         `json:"fromBlock"`
+	ToBlock   uint64         `json:"toBlock"`
+	Entries   []SpendEntry   `json:"entries"` // in order of day and tags
+	Total     SpendEntry     `json:"total"`
+}
+
+// ReportSpend reports the gas costs and storage endowments paid by the sender to submit files in block range
+// of query. Submitted files are scanned in block ranges as ListUploads, and the cost of each transaction is
+// retrieved from costs, which could be a TxCostCache to allow incremental re-runs.
+//
+// A transaction that submits files of different tags in batch is counted in each group of tags, and its cost is
+// allocated to groups in proportion to file sizes. So, the number of transactions in total may be less than the
+// sum of entries.
+func ReportSpend(ctx context.Context, filter SubmitLogFilter, costs TxCostSource, query SpendQuery) (*SpendReport, error) {
+	var records []UploadRecord
+	cursor, err := ListUploads(ctx, filter, UploadQuery{
+		Sender:     &query.Sender,
+		FromBlock:  query.FromBlock,
+		ToBlock:    query.ToBlock,
+		BlockRange: query.BlockRange,
+	}, func(record UploadRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Failed to list uploads, next block = %v", cursor.NextBlock)
+	}
+
+	report := SpendReport{
+		Sender:    query.Sender,
+		FromBlock: query.FromBlock,
+		ToBlock:   cursor.NextBlock - 1,
+		Total:     *newSpendEntry("", nil),
+	}
+
+	if cursor.NextBlock == 0 {
+		report.ToBlock = 0
+	}
+
+	// files submitted in batch share the same transaction, which are adjacent in order
+	groups := make(map[string]*SpendEntry)
+	for start := 0; start < len(records); {
+		end := start + 1
+		for end < len(records) && records[end].TxHash == records[start].TxHash {
+			end++
+		}
+
+		if err = report.addTx(ctx, costs, records[start:end], query.ByTag, groups); err != nil {
+			return nil, err
+		}
+
+		start = end
+	}
+
+	for _, entry := range groups {
+		report.Entries = append(report.Entries, *entry)
+	}
+
+	sort.Slice(report.Entries, func(i, j int) bool {
+		if report.Entries[i].Day != report.Entries[j].Day {
+			return report.Entries[i].Day < report.Entries[j].Day
+		}
+
+		return report.Entries[i].Tags.String() < report.Entries[j].Tags.String()
+	})
+
+	return &report, nil
+}
+
+// addTx adds the cost of a transaction that submits the specified files into the report.
+func (report *SpendReport) addTx(ctx context.Context, costs TxCostSource, records []UploadRecord, byTag bool, groups map[string]*SpendEntry) error {
+	cost, err := costs.TxCost(ctx, records[0].TxHash)
+	if err != nil {
+		return errors.WithMessagef(err, "Failed to get cost of tx %v", records[0].TxHash)
+	}
+
+	gasCost, endowment := cost.GasCost(), cost.Value
+	if endowment == nil {
+		endowment = new(big.Int)
+	}
+
+	day := records[0].BlockTime.UTC().Format("2006-01-02")
+
+	// group files of transaction by tags in order
+	var keys []string
+	files := make(map[string][]UploadRecord)
+	for _, record := range records {
+		var key string
+		if byTag {
+			key = record.Tags.String()
+		}
+
+		if _, ok := files[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		files[key] = append(files[key], record)
+	}
+
+	var totalSize uint64
+	for _, record := range records {
+		totalSize += record.Size
+	}
+
+	report.Total.add(uint64(len(records)), totalSize, cost.GasUsed, gasCost, endowment)
+
+	// allocate cost in proportion to file sizes, and the remainder to the last group
+	remainingGas, remainingGasCost, remainingEndowment := cost.GasUsed, new(big.Int).Set(gasCost), new(big.Int).Set(endowment)
+	for i, key := range keys {
+		var size uint64
+		for _, record := range files[key] {
+			size += record.Size
+		}
+
+		groupGas, groupGasCost, groupEndowment := remainingGas, remainingGasCost, remainingEndowment
+		if i < len(keys)-1 {
+			groupGas = prorate(new(big.Int).SetUint64(cost.GasUsed), size, totalSize, len(files[key]), len(records)).Uint64()
+			groupGasCost = prorate(gasCost, size, totalSize, len(files[key]), len(records))
+			groupEndowment = prorate(endowment, size, totalSize, len(files[key]), len(records))
+
+			remainingGas -= groupGas
+			remainingGasCost = new(big.Int).Sub(remainingGasCost, groupGasCost)
+			remainingEndowment = new(big.Int).Sub(remainingEndowment, groupEndowment)
+		}
+
+		groupKey := day + "/" + key
+		entry, ok := groups[groupKey]
+		if !ok {
+			entry = newSpendEntry(day, files[key][0].Tags)
+			if !byTag {
+				entry.Tags = nil
+			}
+			groups[groupKey] = entry
+		}
+
+		entry.add(uint64(len(files[key])), size, groupGas, groupGasCost, groupEndowment)
+	}
+
+	return nil
+}
+
+// prorate returns the share of amount in proportion to size, or to the number of files if sizes unavailable.
+func prorate(amount *big.Int, size, totalSize uint64, files, totalFiles int) *big.Int {
+	share := new(big.Int).Set(amount)
+
+	if totalSize == 0 {
+		share.Mul(share, big.NewInt(int64(files)))
+		return share.Div(share, big.NewInt(int64(totalFiles)))
+	}
+
+	share.Mul(share, new(big.Int).SetUint64(size))
+	return share.Div(share, new(big.Int).SetUint64(totalSize))
+}
+
+// WriteCSV writes the entries of report in CSV format along with header, and amounts are in wei.
+func (report *SpendReport) WriteCSV(writer io.Writer) error {
+	w := csv.NewWriter(writer)
+
+	if err := w.Write([]string{"day", "tags", "files", "txs", "size", "gas_used", "gas_cost", "endowment", "total"}); err != nil {
+		return err
+	}
+
+	for _, entry := range report.Entries {
+		var tags string
+		if entry.Tags != nil {
+			tags = entry.Tags.String()
+		}
+
+		if err := w.Write([]string{
+			entry.Day,
+			tags,
+			strconv.FormatUint(entry.Files, 10),
+			strconv.FormatUint(entry.Txs, 10),
+			strconv.FormatUint(entry.Size, 10),
+			strconv.FormatUint(entry.GasUsed, 10),
+			entry.GasCost.String(),
+			entry.Endowment.String(),
+			entry.Total.String(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
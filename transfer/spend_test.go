@@ -0,0 +1,152 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// staticSubmitLogFilter returns the specified files in block range.
+type staticSubmitLogFilter struct {
+	records []UploadRecord
+}
+
+func (filter *staticSubmitLogFilter) LatestBlock(ctx context.Context) (uint64, error) {
+	return filter.records[len(filter.records)-1].BlockNumber, nil
+}
+
+func (filter *staticSubmitLogFilter) FilterSubmits(ctx context.Context, fromBlock, toBlock uint64, senders ...common.Address) ([]UploadRecord, error) {
+	var records []UploadRecord
+	for _, record := range filter.records {
+		if record.BlockNumber >= fromBlock && record.BlockNumber <= toBlock {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+// fakeTxCostSource costs 100 gas at price 2 and endowment 1000 for each transaction.
+type fakeTxCostSource struct {
+	calls map[common.Hash]int
+}
+
+func (source *fakeTxCostSource) TxCost(ctx context.Context, txHash common.Hash) (TxCost, error) {
+	source.calls[txHash]++
+
+	if txHash == (common.Hash{}) {
+		return TxCost{}, errors.New("receipt not found")
+	}
+
+	return TxCost{GasUsed: 100, GasPrice: big.NewInt(2), Value: big.NewInt(1000)}, nil
+}
+
+func newSpendRecords() []UploadRecord {
+	day1 := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 5, 2, 1, 0, 0, 0, time.UTC)
+
+	return []UploadRecord{
+		// batch of 3 files in a tx, 2 tagged with 0x01
+		{TxSeq: 1, Size: 100, Tags: []byte{0x01}, Sender: testSenderA, BlockNumber: 1, BlockTime: day1, TxHash: common.HexToHash("0x1")},
+		{TxSeq: 2, Size: 100, Tags: []byte{0x02}, Sender: testSenderA, BlockNumber: 1, BlockTime: day1, TxHash: common.HexToHash("0x1")},
+		{TxSeq: 3, Size: 200, Tags: []byte{0x01}, Sender: testSenderA, BlockNumber: 1, BlockTime: day1, TxHash: common.HexToHash("0x1")},
+		// other sender
+		{TxSeq: 4, Size: 100, Tags: []byte{0x01}, Sender: testSenderB, BlockNumber: 2, BlockTime: day1},
+		// next day
+		{TxSeq: 5, Size: 100, Tags: []byte{0x02}, Sender: testSenderA, BlockNumber: 3, BlockTime: day2, TxHash: common.HexToHash("0x2")},
+	}
+}
+
+func TestReportSpend(t *testing.T) {
+	filter := staticSubmitLogFilter{newSpendRecords()}
+	costs := fakeTxCostSource{make(map[common.Hash]int)}
+
+	report, err := ReportSpend(context.Background(), &filter, &costs, SpendQuery{Sender: testSenderA, BlockRange: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), report.ToBlock)
+	assert.Equal(t, 1, costs.calls[common.HexToHash("0x1")])
+
+	assert.Equal(t, 2, len(report.Entries))
+	assert.Equal(t, "2024-05-01", report.Entries[0].Day)
+	assert.Nil(t, report.Entries[0].Tags)
+	assert.Equal(t, uint64(3), report.Entries[0].Files)
+	assert.Equal(t, uint64(1), report.Entries[0].Txs)
+	assert.Equal(t, uint64(400), report.Entries[0].Size)
+	assert.Equal(t, big.NewInt(200), report.Entries[0].GasCost)
+	assert.Equal(t, big.NewInt(1000), report.Entries[0].Endowment)
+	assert.Equal(t, big.NewInt(1200), report.Entries[0].Total)
+	assert.Equal(t, "2024-05-02", report.Entries[1].Day)
+
+	assert.Equal(t, uint64(4), report.Total.Files)
+	assert.Equal(t, uint64(2), report.Total.Txs)
+	assert.Equal(t, uint64(200), report.Total.GasUsed)
+	assert.Equal(t, big.NewInt(2400), report.Total.Total)
+}
+
+func TestReportSpendByTag(t *testing.T) {
+	filter := staticSubmitLogFilter{newSpendRecords()}
+	costs := fakeTxCostSource{make(map[common.Hash]int)}
+
+	report, err := ReportSpend(context.Background(), &filter, &costs, SpendQuery{Sender: testSenderA, ByTag: true})
+	assert.NoError(t, err)
+
+	// cost of batch allocated by file sizes, 3/4 to 0x01 and 1/4 to 0x02
+	assert.Equal(t, 3, len(report.Entries))
+	assert.Equal(t, hexutil.Bytes{0x01}, report.Entries[0].Tags)
+	assert.Equal(t, uint64(2), report.Entries[0].Files)
+	assert.Equal(t, uint64(75), report.Entries[0].GasUsed)
+	assert.Equal(t, big.NewInt(900), report.Entries[0].Total)
+	assert.Equal(t, hexutil.Bytes{0x02}, report.Entries[1].Tags)
+	assert.Equal(t, uint64(25), report.Entries[1].GasUsed)
+	assert.Equal(t, big.NewInt(300), report.Entries[1].Total)
+	assert.Equal(t, "2024-05-02", report.Entries[2].Day)
+
+	// batch counted in each group, but only once in total
+	assert.Equal(t, uint64(2), report.Total.Txs)
+	assert.Equal(t, big.NewInt(2400), report.Total.Total)
+
+	var buf bytes.Buffer
+	assert.NoError(t, report.WriteCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.Equal(t, "2024-05-01,0x01,2,1,300,75,150,750,900", lines[1])
+}
+
+func TestReportSpendError(t *testing.T) {
+	filter := staticSubmitLogFilter{newSpendRecords()}
+	costs := fakeTxCostSource{make(map[common.Hash]int)}
+
+	// tx of sender B not found
+	_, err := ReportSpend(context.Background(), &filter, &costs, SpendQuery{Sender: testSenderB})
+	assert.Error(t, err)
+}
+
+func TestTxCostCache(t *testing.T) {
+	source := fakeTxCostSource{make(map[common.Hash]int)}
+	cache := NewTxCostCache(memorydb.New(), &source)
+
+	for i := 0; i < 2; i++ {
+		cost, err := cache.TxCost(context.Background(), common.HexToHash("0x1"))
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(100), cost.GasUsed)
+		assert.Equal(t, big.NewInt(200), cost.GasCost())
+		assert.Equal(t, big.NewInt(1000), cost.Value)
+	}
+	assert.Equal(t, 1, source.calls[common.HexToHash("0x1")])
+
+	// failures not cached
+	for i := 0; i < 2; i++ {
+		_, err := cache.TxCost(context.Background(), common.Hash{})
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 2, source.calls[common.Hash{}])
+}
@@ -0,0 +1,346 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSyncDebounce is the default quiet period to coalesce bursts of changes before publishing.
+const DefaultSyncDebounce = time.Second
+
+// SyncOption is the option to keep a local directory published by Sync.
+type SyncOption struct {
+	Upload UploadOption // option to upload files and directory metadata
+
+	// Name or HEX tags with 0x prefix to publish the directory metadata under, which overrides the tags of
+	// Upload, so that the latest publish could be resolved by name, see dir.ResolveAt.
+	Name string
+
+	// Watch keeps watching the directory to publish changes once the initial upload completed, otherwise Sync
+	// returns after the initial upload.
+	Watch bool
+
+	// Quiet period to coalesce bursts of changes before publishing, DefaultSyncDebounce if 0.
+	Debounce time.Duration
+
+	// Interval to hash the whole directory again, so as to publish changes missed by watcher, 0 to disable.
+	VerifyInterval time.Duration
+
+	// AbortOnShutdown aborts the in-flight publish once the context of Sync cancelled, otherwise the publish
+	// completes before Sync returns. Changes not published yet are discarded either way.
+	AbortOnShutdown bool
+
+	// Watcher is the source of changed paths, which watches the directory by fsnotify if not specified.
+	Watcher SyncWatcher
+
+	// OnPublish is called after each publish, succeeded or not, in the order of publishes.
+	OnPublish func(result SyncResult)
+}
+
+// Validate checks the sync option, and returns an error that names the invalid field if any.
+func (opt *SyncOption) Validate() error {
+	if err := opt.Upload.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := dir.ParseManifestTag(opt.Name); err != nil {
+		return zg_common.NewOptionError("Name", "%v", err)
+	}
+
+	if err := zg_common.RequireNonNegative("Debounce", opt.Debounce); err != nil {
+		return err
+	}
+
+	return zg_common.RequireNonNegative("VerifyInterval", opt.VerifyInterval)
+}
+
+// SyncResult is the outcome of a publish by Sync.
+type SyncResult struct {
+	TxHash  common.Hash   // transaction hash of directory metadata
+	Root    common.Hash   // storage root of directory metadata
+	Tree    *dir.FsNode   // published directory, nil if failed
+	Ops     int           // number of operations to patch the directory published before
+	Full    bool          // whether the whole directory hashed, i.e. the initial upload or verification
+	Elapsed time.Duration // time to build and publish the directory
+	Err     error
+}
+
+// SyncWatcher watches a directory for changes.
+type SyncWatcher interface {
+	// Events returns the channel of paths changed, which are either absolute or relative to the directory.
+	Events() <-chan string
+
+	// Errors returns the channel of errors to watch, which are logged and do not stop Sync.
+	Errors() <-chan error
+
+	Close() error
+}
+
+// syncer keeps a local directory published. The dirty paths are only accessed by the goroutine of Sync, and
+// the published directory only by the in-flight publish.
+type syncer struct {
+	uploader *Uploader
+	folder   string
+	opt      SyncOption
+
+	published *dir.FsNode         // directory published the last time
+	dirty     map[string]struct{} // slash-separated paths changed since the last publish
+}
+
+// Sync publishes the local folder, and keeps it published if SyncOption.Watch specified: changed paths are
+// coalesced until quiet for the debounce period, and then only changed files are hashed again to patch the
+// directory published the last time, see UploadDirPatch.
+//
+// Sync returns once ctx is cancelled, along with the in-flight publish if any completed or aborted, see
+// SyncOption.AbortOnShutdown. Failures to publish changes are reported by SyncOption.OnPublish and retried with
+// later changes, but Sync fails if the initial upload failed.
+func Sync(ctx context.Context, uploader *Uploader, folder string, opt SyncOption) error {
+	if err := opt.Validate(); err != nil {
+		return err
+	}
+
+	if len(opt.Name) > 0 {
+		opt.Upload.Tags, _ = dir.ParseManifestTag(opt.Name)
+	}
+
+	if opt.Debounce == 0 {
+		opt.Debounce = DefaultSyncDebounce
+	}
+
+	// absolute, so that paths watched are relative to folder regardless of the working directory
+	folder, err := filepath.Abs(folder)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to get absolute path of directory")
+	}
+
+	s := &syncer{uploader: uploader, folder: folder, opt: opt, dirty: make(map[string]struct{})}
+
+	// watch before the initial upload, so that changes meanwhile are not missed
+	watcher := opt.Watcher
+	if opt.Watch && watcher == nil {
+		if watcher, err = NewSyncWatcher(folder, uploader.ignore); err != nil {
+			return err
+		}
+	}
+	if watcher != nil {
+		defer watcher.Close()
+	}
+
+	result := s.publish(ctx, nil, true)
+	if result.Err != nil {
+		return errors.WithMessage(result.Err, "Failed to publish directory")
+	}
+
+	if !opt.Watch {
+		return nil
+	}
+
+	return s.watch(ctx, watcher)
+}
+
+// watch publishes the changes reported by watcher until ctx cancelled.
+func (s *syncer) watch(ctx context.Context, watcher SyncWatcher) error {
+	debounce := time.NewTimer(s.opt.Debounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	var verify <-chan time.Time
+	if s.opt.VerifyInterval > 0 {
+		ticker := time.NewTicker(s.opt.VerifyInterval)
+		defer ticker.Stop()
+		verify = ticker.C
+	}
+
+	// at most one publish in flight, which completes even if ctx cancelled unless aborted on shutdown
+	publishCtx, abort := context.WithCancel(context.WithoutCancel(ctx))
+	defer abort()
+
+	var inflight chan SyncResult
+	var publishing map[string]struct{} // dirty paths of the in-flight publish
+	var quiet, verifying bool
+
+	for {
+		// publish once quiet or verification due, and any in-flight publish completed
+		if inflight == nil && (quiet && len(s.dirty) > 0 || verifying) {
+			dirty, full := s.dirty, verifying
+			s.dirty, quiet, verifying = make(map[string]struct{}), false, false
+
+			inflight, publishing = make(chan SyncResult, 1), dirty
+			go func(done chan<- SyncResult) {
+				done <- s.publish(publishCtx, dirty, full)
+			}(inflight)
+		}
+
+		select {
+		case <-ctx.Done():
+			if inflight != nil {
+				if s.opt.AbortOnShutdown {
+					abort()
+				}
+				s.completed(<-inflight, publishing)
+			}
+
+			if len(s.dirty) > 0 {
+				s.uploader.logger.WithField("paths", len(s.dirty)).Warn("Changes not published on shutdown")
+			}
+
+			return nil
+		case name := <-watcher.Events():
+			if relpath, ok := s.relpath(name); ok {
+				s.dirty[relpath] = struct{}{}
+				quiet = false
+				debounce.Reset(s.opt.Debounce)
+			}
+		case err := <-watcher.Errors():
+			s.uploader.logger.WithError(err).Warn("Failed to watch directory")
+		case <-debounce.C:
+			quiet = true
+		case <-verify:
+			verifying = true
+		case result := <-inflight:
+			inflight = nil
+			s.completed(result, publishing)
+		}
+	}
+}
+
+// completed marks the dirty paths of a failed publish to publish again along with later changes.
+func (s *syncer) completed(result SyncResult, dirty map[string]struct{}) {
+	if result.Err == nil {
+		return
+	}
+
+	for relpath := range dirty {
+		s.dirty[relpath] = struct{}{}
+	}
+
+	s.uploader.logger.WithError(result.Err).Warn("Failed to publish directory changes")
+}
+
+// relpath returns the slash-separated path relative to folder, and false if out of folder or ignored.
+func (s *syncer) relpath(name string) (string, bool) {
+	if filepath.IsAbs(name) {
+		rel, err := filepath.Rel(s.folder, name)
+		if err != nil {
+			return "", false
+		}
+		name = rel
+	}
+
+	name = filepath.ToSlash(filepath.Clean(name))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+
+	// ignored if any ancestor directory ignored
+	if ignore := s.uploader.ignore; ignore.Len() > 0 {
+		parts := strings.Split(name, "/")
+		for i := 1; i < len(parts); i++ {
+			if ignore.Match(path.Join(parts[:i]...), true) {
+				return "", false
+			}
+		}
+
+		info, err := os.Lstat(filepath.Join(s.folder, filepath.FromSlash(name)))
+		if ignore.Match(name, err == nil && info.IsDir()) {
+			return "", false
+		}
+	}
+
+	return name, true
+}
+
+// publish builds the directory, where only the dirty paths are hashed again unless full, and uploads the changes
+// against the directory published the last time. The published directory is updated once succeeded.
+func (s *syncer) publish(ctx context.Context, dirty map[string]struct{}, full bool) (result SyncResult) {
+	start := time.Now()
+	result.Full = full
+
+	defer func() {
+		result.Elapsed = time.Since(start)
+
+		if s.opt.OnPublish != nil {
+			s.opt.OnPublish(result)
+		}
+	}()
+
+	opt := s.uploader.buildOption()
+	if !full {
+		opt.KnownRoot = s.knownRoot(dirty)
+	}
+
+	tree, err := dir.BuildFileTreeWithOption(s.folder, opt)
+	if err != nil {
+		result.Err = errors.WithMessage(err, "failed to build file tree")
+		return
+	}
+
+	// root directory as built, e.g. name encoding and metadata, along with entries published
+	base := *tree
+	base.Entries = nil
+	if s.published != nil {
+		base.Entries = s.published.Entries
+	}
+
+	ops, err := dir.DiffOps(&base, tree)
+	if err != nil {
+		result.Err = errors.WithMessage(err, "failed to diff file tree")
+		return
+	}
+
+	result.Ops = len(ops)
+	if len(ops) == 0 && s.published != nil {
+		result.Tree = s.published
+		s.uploader.logger.Debug("Nothing changed to publish")
+		return
+	}
+
+	result.TxHash, result.Root, result.Tree, result.Err = s.uploader.UploadDirPatch(ctx, &base, s.folder, ops, s.opt.Upload)
+	if result.Err != nil {
+		result.Tree = nil
+		return
+	}
+
+	s.published = result.Tree
+
+	s.uploader.logger.WithFields(logrus.Fields{
+		"root": result.Root,
+		"ops":  result.Ops,
+		"full": result.Full,
+	}).Info("Directory published")
+
+	return
+}
+
+// knownRoot returns the merkle roots of files in the directory published the last time, unless the file or any
+// of its ancestors is dirty, or the size changed.
+func (s *syncer) knownRoot(dirty map[string]struct{}) func(relpath string, info os.FileInfo) (common.Hash, bool) {
+	return func(relpath string, info os.FileInfo) (common.Hash, bool) {
+		if s.published == nil {
+			return common.Hash{}, false
+		}
+
+		for p := relpath; p != "." && p != "/" && len(p) > 0; p = path.Dir(p) {
+			if _, ok := dirty[p]; ok {
+				return common.Hash{}, false
+			}
+		}
+
+		node, err := s.published.Locate(relpath)
+		if err != nil || node.Type != dir.FileTypeFile || node.Size != info.Size() {
+			return common.Hash{}, false
+		}
+
+		return common.HexToHash(node.Root), true
+	}
+}
@@ -0,0 +1,219 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeWatcher simulates the paths changed in directory.
+type fakeWatcher struct {
+	events chan string
+	errors chan error
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{events: make(chan string), errors: make(chan error)}
+}
+
+func (w *fakeWatcher) Events() <-chan string { return w.events }
+func (w *fakeWatcher) Errors() <-chan error  { return w.errors }
+func (w *fakeWatcher) Close() error          { return nil }
+
+// newSyncFixture returns an uploader of mock network, along with a local folder to sync. Files of the same
+// content are uploaded only once, so as to publish with fewer transactions.
+func newSyncFixture(t *testing.T) (*testutil.Network, *Uploader, string) {
+	network := testutil.NewNetwork(t)
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+
+	folder := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "a.txt"), []byte("file a"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "sub", "b.txt"), []byte("file a"), 0644))
+
+	return network, uploader, folder
+}
+
+// runSync starts Sync in background, and returns the channels of published results and the error of Sync.
+func runSync(ctx context.Context, uploader *Uploader, folder string, opt SyncOption) (<-chan SyncResult, <-chan error) {
+	results := make(chan SyncResult, 16)
+	opt.OnPublish = func(result SyncResult) { results <- result }
+
+	done := make(chan error, 1)
+	go func() { done <- Sync(ctx, uploader, folder, opt) }()
+
+	return results, done
+}
+
+func waitPublished(t *testing.T, results <-chan SyncResult) SyncResult {
+	select {
+	case result := <-results:
+		assert.NoError(t, result.Err)
+		return result
+	case <-time.After(time.Minute):
+		t.Fatal("Timeout to publish directory")
+		return SyncResult{}
+	}
+}
+
+func TestSyncWatch(t *testing.T) {
+	network, uploader, folder := newSyncFixture(t)
+	ignore, err := dir.ParseIgnorePatterns("tmp/")
+	assert.NoError(t, err)
+	uploader.WithIgnore(ignore)
+
+	// blocks uploads once armed until released
+	var armed atomic.Bool
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	network.Nodes[0].SetHooks(testutil.ZgsHooks{BeforeUpload: func(txSeq, index uint64) error {
+		if armed.CompareAndSwap(true, false) {
+			entered <- struct{}{}
+			<-release
+		}
+		return nil
+	}})
+
+	watcher := newFakeWatcher()
+	ctx, cancel := context.WithCancel(context.Background())
+	results, done := runSync(ctx, uploader, folder, SyncOption{
+		Name:     "site",
+		Watch:    true,
+		Debounce: 50 * time.Millisecond,
+		Watcher:  watcher,
+	})
+
+	initial := waitPublished(t, results)
+	assert.True(t, initial.Full)
+	assert.Equal(t, 2, initial.Ops)
+
+	// directory metadata submitted with the name as tags
+	submissions := network.Chain.Submissions()
+	assert.Equal(t, initial.Root, submissions[len(submissions)-1].Root())
+	assert.Equal(t, []byte("site"), submissions[len(submissions)-1].Tags)
+
+	// burst of changes coalesced, where ignored paths and files changed without events are not published
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "a.txt"), []byte("file A"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "sub", "b.txt"), []byte("file B"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub", "new"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "sub", "new", "c.txt"), []byte("file A"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "tmp"), 0755))
+	for _, name := range []string{"a.txt", filepath.Join(folder, "sub", "new"), "tmp/x", "a.txt", "../outside"} {
+		watcher.events <- name
+	}
+
+	result := waitPublished(t, results)
+	assert.False(t, result.Full)
+	assert.Equal(t, 2, result.Ops)
+	for relpath, content := range map[string]string{"a.txt": "file A", "sub/b.txt": "file a", "sub/new/c.txt": "file A"} {
+		node, err := result.Tree.Locate(relpath)
+		assert.NoError(t, err)
+
+		root, err := core.MerkleRootReader(strings.NewReader(content), int64(len(content)))
+		assert.NoError(t, err)
+		assert.Equal(t, root.Hex(), node.Root, relpath)
+	}
+
+	// ignored paths only
+	watcher.events <- "tmp/y"
+	select {
+	case result := <-results:
+		t.Fatalf("Unexpected publish: %+v", result)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// in-flight publish completes before Sync returns
+	armed.Store(true)
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "a.txt"), []byte("file a changed"), 0644))
+	watcher.events <- "a.txt"
+	<-entered
+
+	cancel()
+	select {
+	case err := <-done:
+		t.Fatalf("Sync returned before in-flight publish completed: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-done)
+	result = waitPublished(t, results)
+	assert.Equal(t, 1, result.Ops)
+
+	assert.Error(t, Sync(context.Background(), uploader, folder, SyncOption{Debounce: -1}))
+}
+
+func TestSyncVerify(t *testing.T) {
+	_, uploader, folder := newSyncFixture(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results, done := runSync(ctx, uploader, folder, SyncOption{
+		Watch:          true,
+		VerifyInterval: 200 * time.Millisecond,
+		Watcher:        newFakeWatcher(),
+	})
+	waitPublished(t, results)
+
+	// changes missed by watcher published by verification
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "sub", "b.txt"), []byte("changed b"), 0644))
+	for {
+		result := waitPublished(t, results)
+		assert.True(t, result.Full)
+		if result.Ops > 0 {
+			assert.Equal(t, 1, result.Ops)
+			break
+		}
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestSyncWatcher(t *testing.T) {
+	folder := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "tmp"), 0755))
+	ignore, err := dir.ParseIgnorePatterns("tmp/")
+	assert.NoError(t, err)
+
+	watcher, err := NewSyncWatcher(folder, ignore)
+	assert.NoError(t, err)
+	defer watcher.Close()
+
+	expectEvent := func(name string) {
+		for {
+			select {
+			case event := <-watcher.Events():
+				assert.NotEqual(t, filepath.Join(folder, "tmp", "x"), event)
+				if event == name {
+					return
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Timeout to watch %v", name)
+			}
+		}
+	}
+
+	// ignored directories not watched
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "tmp", "x"), []byte("x"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "a.txt"), []byte("a"), 0644))
+	expectEvent(filepath.Join(folder, "a.txt"))
+
+	// directories created watched as well
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub"), 0755))
+	expectEvent(filepath.Join(folder, "sub"))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "sub", "b.txt"), []byte("b"), 0644))
+	expectEvent(filepath.Join(folder, "sub", "b.txt"))
+
+	assert.NoError(t, watcher.Close())
+	assert.NoError(t, watcher.Close())
+}
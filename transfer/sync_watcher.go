@@ -0,0 +1,156 @@
+package transfer
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// fsWatcher watches a directory recursively by fsnotify, which reports absolute paths changed.
+type fsWatcher struct {
+	folder  string
+	ignore  *dir.IgnorePatterns
+	watcher *fsnotify.Watcher
+
+	events chan string
+	errors chan error
+	done   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// NewSyncWatcher watches the folder and all directories within recursively by fsnotify, except the ignored ones.
+// Directories created later are watched as well.
+func NewSyncWatcher(folder string, ignore *dir.IgnorePatterns) (SyncWatcher, error) {
+	folder, err := filepath.Abs(folder)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to get absolute path of directory")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create file system watcher")
+	}
+
+	w := &fsWatcher{
+		folder:  folder,
+		ignore:  ignore,
+		watcher: watcher,
+		events:  make(chan string),
+		errors:  make(chan error),
+		done:    make(chan struct{}),
+	}
+
+	if err := w.add(folder); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.run()
+
+	return w, nil
+}
+
+// add watches the directory and all directories within that not ignored.
+func (w *fsWatcher) add(root string) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if path != w.folder {
+			relpath, err := filepath.Rel(w.folder, path)
+			if err != nil {
+				return err
+			}
+
+			if w.ignore.Match(filepath.ToSlash(relpath), true) {
+				return filepath.SkipDir
+			}
+		}
+
+		if err := w.watcher.Add(path); err != nil {
+			return errors.WithMessagef(err, "Failed to watch directory %v", path)
+		}
+
+		return nil
+	})
+}
+
+func (w *fsWatcher) run() {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			// watch directories created, of which the entries are published along with the directory
+			if event.Op.Has(fsnotify.Create) {
+				if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+					if err := w.add(event.Name); err != nil {
+						w.send(nil, err)
+					}
+				}
+			}
+
+			if !w.send(&event.Name, nil) {
+				return
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			if !w.send(nil, err) {
+				return
+			}
+		}
+	}
+}
+
+// send delivers the path changed or error, and returns false if closed.
+func (w *fsWatcher) send(name *string, err error) bool {
+	if name != nil {
+		select {
+		case w.events <- *name:
+			return true
+		case <-w.done:
+			return false
+		}
+	}
+
+	select {
+	case w.errors <- err:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *fsWatcher) Events() <-chan string { return w.events }
+
+func (w *fsWatcher) Errors() <-chan error { return w.errors }
+
+// Close stops watching, and waits for the watching goroutine to exit.
+func (w *fsWatcher) Close() (err error) {
+	w.once.Do(func() {
+		close(w.done)
+		err = w.watcher.Close()
+		w.wg.Wait()
+	})
+
+	return err
+}
@@ -0,0 +1,382 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// quotaWindow is the window of tenant quota, which starts at midnight in UTC.
+const quotaWindow = 24 * time.Hour
+
+// ErrQuotaExceeded is matched by QuotaExceededError with errors.Is.
+var ErrQuotaExceeded = errors.New("tenant quota exceeded")
+
+// QuotaExceededError is returned when the bytes to upload by a tenant exceed the quota of the day.
+type QuotaExceededError struct {
+	Tenant    string
+	Quota     uint64    // max bytes to upload in a day
+	Used      uint64    // bytes reserved in the day before
+	Requested uint64    // bytes requested to upload
+	ResetAt   time.Time // when the quota is reset, i.e. the next midnight in UTC
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%v: tenant = %v, quota = %v, used = %v, requested = %v, reset at %v",
+		ErrQuotaExceeded, e.Tenant, e.Quota, e.Used, e.Requested, e.ResetAt)
+}
+
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of context that uploads on behalf of the specified tenant, see Uploader.WithTenants.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of context, or empty if not specified.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// tenantOf returns the tenant of option if specified, otherwise the tenant of context.
+func tenantOf(ctx context.Context, tenant string) string {
+	if len(tenant) > 0 {
+		return tenant
+	}
+
+	return TenantFromContext(ctx)
+}
+
+// QuotaStore persists the bytes uploaded by tenants in quota windows, which is implemented by MemoryQuotaStore
+// by default. It could be implemented by a shared backend, e.g. Redis, to enforce quotas across processes.
+type QuotaStore interface {
+	// Reserve adds bytes to the usage of tenant in the window atomically unless the quota is exceeded, and
+	// returns whether reserved along with the usage before. Usages of previous windows could be discarded.
+	Reserve(ctx context.Context, tenant string, window time.Time, bytes, quota uint64) (bool, uint64, error)
+
+	// Refund subtracts bytes from the usage of tenant in the window, e.g. upload failed before submission.
+	Refund(ctx context.Context, tenant string, window time.Time, bytes uint64) error
+}
+
+type memoryQuotaUsage struct {
+	window time.Time
+	bytes  uint64
+}
+
+// MemoryQuotaStore is a QuotaStore in memory, which only keeps usages of the current window.
+type MemoryQuotaStore struct {
+	mu     sync.Mutex
+	usages map[string]memoryQuotaUsage // tenant -> usage
+}
+
+var _ QuotaStore = (*MemoryQuotaStore)(nil)
+
+// NewMemoryQuotaStore creates a quota store in memory.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{usages: make(map[string]memoryQuotaUsage)}
+}
+
+// Reserve implements the QuotaStore interface.
+func (store *MemoryQuotaStore) Reserve(ctx context.Context, tenant string, window time.Time, bytes, quota uint64) (bool, uint64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	usage := store.usages[tenant]
+	if !usage.window.Equal(window) {
+		usage = memoryQuotaUsage{window: window}
+	}
+
+	if usage.bytes+bytes > quota {
+		return false, usage.bytes, nil
+	}
+
+	store.usages[tenant] = memoryQuotaUsage{window, usage.bytes + bytes}
+
+	return true, usage.bytes, nil
+}
+
+// Refund implements the QuotaStore interface.
+func (store *MemoryQuotaStore) Refund(ctx context.Context, tenant string, window time.Time, bytes uint64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	usage, ok := store.usages[tenant]
+	if !ok || !usage.window.Equal(window) {
+		return nil
+	}
+
+	usage.bytes -= min(usage.bytes, bytes)
+	store.usages[tenant] = usage
+
+	return nil
+}
+
+// TenantLimits are the limits of a tenant to upload.
+type TenantLimits struct {
+	MaxConcurrency int    // max number of concurrent uploads, including replicas in background, 0 for unlimited
+	MaxBytesPerDay uint64 // max bytes to upload in a day of UTC, 0 for unlimited
+}
+
+// TenantStats are the upload statistics of a tenant, e.g. to export as metrics labeled by tenant.
+type TenantStats struct {
+	Admitted uint64 // number of uploads admitted
+	Rejected uint64 // number of uploads rejected by quota
+	Refunded uint64 // number of uploads failed before submission, whose bytes are refunded
+	Bytes    uint64 // bytes of uploads admitted and not refunded
+	InFlight int    // number of uploads in progress
+	Waiting  int    // number of uploads waiting for concurrency slots
+}
+
+type tenantState struct {
+	released chan struct{} // closed and renewed once a slot released or limits changed, to wake up waiting uploads
+	stats    TenantStats
+}
+
+// Tenants isolates uploads of tenants that share an Uploader, so that the burst of one tenant does not starve
+// others. Each tenant is limited by the number of concurrent uploads, and the bytes to upload in a day, which
+// is reserved before submission and refunded if the upload fails before submission.
+//
+// Uploads without tenant are admitted as the anonymous tenant, i.e. empty tenant, which is limited by the default
+// limits as well, so that a tenant could not bypass its limits by omitting the tenant.
+//
+// Tenants could be shared across uploaders, and is safe for concurrent use.
+type Tenants struct {
+	store    QuotaStore
+	defaults TenantLimits
+
+	mu     sync.Mutex
+	limits map[string]TenantLimits // tenant -> limits that override defaults
+	states map[string]*tenantState // tenant -> state
+	now    func() time.Time
+}
+
+// NewTenants creates tenants with the default limits, and quotas are stored in memory if store not specified.
+func NewTenants(defaults TenantLimits, store QuotaStore) *Tenants {
+	if store == nil {
+		store = NewMemoryQuotaStore()
+	}
+
+	return &Tenants{
+		store:    store,
+		defaults: defaults,
+		limits:   make(map[string]TenantLimits),
+		states:   make(map[string]*tenantState),
+		now:      time.Now,
+	}
+}
+
+// SetLimits overrides the default limits of the specified tenant, which applies to uploads admitted later. Uploads
+// in progress are not affected, and still count towards the new concurrency limit until completed.
+func (tenants *Tenants) SetLimits(tenant string, limits TenantLimits) {
+	tenants.mu.Lock()
+	defer tenants.mu.Unlock()
+
+	tenants.limits[tenant] = limits
+
+	// wake up waiting uploads in case of concurrency limit raised
+	if state, ok := tenants.states[tenant]; ok {
+		state.wakeLocked()
+	}
+}
+
+// Stats returns the upload statistics of tenants that ever uploaded.
+func (tenants *Tenants) Stats() map[string]TenantStats {
+	tenants.mu.Lock()
+	defer tenants.mu.Unlock()
+
+	stats := make(map[string]TenantStats, len(tenants.states))
+	for tenant, state := range tenants.states {
+		stats[tenant] = state.stats
+	}
+
+	return stats
+}
+
+func (tenants *Tenants) limitsLocked(tenant string) TenantLimits {
+	if limits, ok := tenants.limits[tenant]; ok {
+		return limits
+	}
+
+	return tenants.defaults
+}
+
+func (tenants *Tenants) stateLocked(tenant string) *tenantState {
+	state, ok := tenants.states[tenant]
+	if !ok {
+		state = &tenantState{released: make(chan struct{})}
+		tenants.states[tenant] = state
+	}
+
+	return state
+}
+
+// wakeLocked wakes up uploads waiting for concurrency slots.
+func (state *tenantState) wakeLocked() {
+	close(state.released)
+	state.released = make(chan struct{})
+}
+
+// acquire waits until the number of uploads in progress is below the concurrency limit of tenant, and then admits
+// the upload of specified bytes. The limit is read each time woken up, so that it could be changed while waiting.
+func (tenants *Tenants) acquire(ctx context.Context, tenant string, bytes uint64) error {
+	tenants.mu.Lock()
+	defer tenants.mu.Unlock()
+
+	state := tenants.stateLocked(tenant)
+	state.stats.Waiting++
+	defer func() { state.stats.Waiting-- }()
+
+	for {
+		concurrency := tenants.limitsLocked(tenant).MaxConcurrency
+		if concurrency <= 0 || state.stats.InFlight < concurrency {
+			state.stats.Admitted++
+			state.stats.Bytes += bytes
+			state.stats.InFlight++
+			return nil
+		}
+
+		released := state.released
+
+		tenants.mu.Unlock()
+		select {
+		case <-released:
+		case <-ctx.Done():
+		}
+		tenants.mu.Lock()
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// tenantAdmission is an upload admitted for a tenant, which should be done once the upload completes.
+type tenantAdmission struct {
+	tenants  *Tenants
+	tenant   string
+	window   time.Time
+	bytes    uint64
+	reserved bool           // whether bytes reserved in quota store
+	logger   *logrus.Logger // logger of uploader
+}
+
+// admit reserves the quota of tenant for the bytes to upload, and then waits for a concurrency slot. Uploads
+// without tenant are admitted as the anonymous tenant, and failures to refund are logged by logger.
+func (tenants *Tenants) admit(ctx context.Context, tenant string, bytes uint64, logger *logrus.Logger) (*tenantAdmission, error) {
+	if tenants == nil {
+		return nil, nil
+	}
+
+	now := tenants.now().UTC()
+	admission := tenantAdmission{
+		tenants: tenants,
+		tenant:  tenant,
+		window:  now.Truncate(quotaWindow),
+		bytes:   bytes,
+		logger:  logger,
+	}
+
+	tenants.mu.Lock()
+	limits := tenants.limitsLocked(tenant)
+	tenants.mu.Unlock()
+
+	if limits.MaxBytesPerDay > 0 {
+		ok, used, err := tenants.store.Reserve(ctx, tenant, admission.window, bytes, limits.MaxBytesPerDay)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Failed to reserve quota of tenant %v", tenant)
+		}
+
+		if !ok {
+			tenants.mu.Lock()
+			tenants.stateLocked(tenant).stats.Rejected++
+			tenants.mu.Unlock()
+
+			return nil, &QuotaExceededError{tenant, limits.MaxBytesPerDay, used, bytes, admission.window.Add(quotaWindow)}
+		}
+
+		admission.reserved = true
+	}
+
+	if err := tenants.acquire(ctx, tenant, bytes); err != nil {
+		admission.refund(ctx)
+		return nil, errors.WithMessagef(err, "Failed to wait for upload slot of tenant %v", tenant)
+	}
+
+	return &admission, nil
+}
+
+// refund refunds the bytes reserved in quota store if any.
+func (admission *tenantAdmission) refund(ctx context.Context) {
+	if !admission.reserved {
+		return
+	}
+
+	// refund even if the upload is cancelled
+	err := admission.tenants.store.Refund(context.WithoutCancel(ctx), admission.tenant, admission.window, admission.bytes)
+	if err != nil {
+		admission.logger.WithError(err).WithField("tenant", admission.tenant).Warn("Failed to refund quota of tenant")
+	}
+}
+
+// done releases the concurrency slot, and refunds the quota if the upload failed before submission, i.e. no
+// transaction sent.
+func (admission *tenantAdmission) done(ctx context.Context, txHash common.Hash, err error) {
+	admission.doneWithReplicas(ctx, txHash, err, nil)
+}
+
+// doneWithReplicas refunds the quota as done does, while the concurrency slot is released only after the replicas
+// uploading in background completed if handle specified, so that tenants never exceed the concurrency limit by
+// returning early with MinReplica.
+func (admission *tenantAdmission) doneWithReplicas(ctx context.Context, txHash common.Hash, err error, handle *ReplicaHandle) {
+	if admission == nil {
+		return
+	}
+
+	if err != nil && txHash == (common.Hash{}) {
+		tenants := admission.tenants
+
+		tenants.mu.Lock()
+		state := tenants.stateLocked(admission.tenant)
+		state.stats.Refunded++
+		state.stats.Bytes -= admission.bytes
+		tenants.mu.Unlock()
+
+		admission.refund(ctx)
+	}
+
+	if handle == nil {
+		admission.release()
+		return
+	}
+
+	select {
+	case <-handle.Done():
+		admission.release()
+	default:
+		go func() {
+			<-handle.Done()
+			admission.release()
+		}()
+	}
+}
+
+// release releases the concurrency slot, and wakes up an upload waiting for the slot if any.
+func (admission *tenantAdmission) release() {
+	tenants := admission.tenants
+
+	tenants.mu.Lock()
+	state := tenants.stateLocked(admission.tenant)
+	state.stats.InFlight--
+	state.wakeLocked()
+	tenants.mu.Unlock()
+}
@@ -0,0 +1,211 @@
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantQuota(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tenants := NewTenants(TenantLimits{MaxBytesPerDay: 100}, nil)
+	tenants.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	admission, err := tenants.admit(ctx, "a", 60, logrus.StandardLogger())
+	assert.NoError(t, err)
+	admission.done(ctx, common.Hash{1}, nil)
+
+	// quota exceeded with reset time
+	_, err = tenants.admit(ctx, "a", 60, logrus.StandardLogger())
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	var quotaErr *QuotaExceededError
+	assert.True(t, errors.As(err, &quotaErr))
+	assert.Equal(t, uint64(60), quotaErr.Used)
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), quotaErr.ResetAt)
+
+	// other tenant unaffected
+	admission, err = tenants.admit(ctx, "b", 60, logrus.StandardLogger())
+	assert.NoError(t, err)
+
+	// refunded if failed before submission
+	admission.done(ctx, common.Hash{}, errors.New("failed"))
+	admission, err = tenants.admit(ctx, "b", 100, logrus.StandardLogger())
+	assert.NoError(t, err)
+	admission.done(ctx, common.Hash{1}, errors.New("failed after submission"))
+	_, err = tenants.admit(ctx, "b", 1, logrus.StandardLogger())
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// reset in the next day
+	now = now.Add(14 * time.Hour)
+	_, err = tenants.admit(ctx, "a", 100, logrus.StandardLogger())
+	assert.NoError(t, err)
+
+	// uploads without tenant limited by defaults
+	_, err = tenants.admit(ctx, "", 1000, logrus.StandardLogger())
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	admission, err = tenants.admit(ctx, "", 100, logrus.StandardLogger())
+	assert.NoError(t, err)
+	assert.NotNil(t, admission)
+
+	stats := tenants.Stats()
+	assert.Equal(t, TenantStats{Admitted: 2, Rejected: 1, Bytes: 160, InFlight: 1}, stats["a"])
+	assert.Equal(t, TenantStats{Admitted: 2, Rejected: 1, Refunded: 1, Bytes: 100}, stats["b"])
+	assert.Equal(t, TenantStats{Admitted: 1, Rejected: 1, Bytes: 100, InFlight: 1}, stats[""])
+}
+
+func TestTenantSetLimitsInFlight(t *testing.T) {
+	tenants := NewTenants(TenantLimits{MaxConcurrency: 1}, nil)
+	ctx := context.Background()
+
+	inflight, err := tenants.admit(ctx, "a", 0, logrus.StandardLogger())
+	assert.NoError(t, err)
+
+	admitted := make(chan *tenantAdmission)
+	go func() {
+		admission, err := tenants.admit(ctx, "a", 0, logrus.StandardLogger())
+		assert.NoError(t, err)
+		admitted <- admission
+	}()
+	assert.Eventually(t, func() bool { return tenants.Stats()["a"].Waiting == 1 }, 5*time.Second, time.Millisecond)
+
+	// waiting upload admitted once the limit raised
+	tenants.SetLimits("a", TenantLimits{MaxConcurrency: 2})
+	waiting := <-admitted
+	assert.Equal(t, TenantStats{Admitted: 2, InFlight: 2}, tenants.Stats()["a"])
+
+	// uploads in progress still count towards the lowered limit
+	tenants.SetLimits("a", TenantLimits{MaxConcurrency: 1})
+	inflight.done(ctx, common.Hash{1}, nil)
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = tenants.admit(timeout, "a", 0, logrus.StandardLogger())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	waiting.done(ctx, common.Hash{1}, nil)
+	admission, err := tenants.admit(ctx, "a", 0, logrus.StandardLogger())
+	assert.NoError(t, err)
+	admission.done(ctx, common.Hash{1}, nil)
+	assert.Equal(t, TenantStats{Admitted: 3}, tenants.Stats()["a"])
+}
+
+func TestTenantConcurrency(t *testing.T) {
+	service := testutil.NewZgsService()
+	client := newMockZgsNode(t, service)
+
+	datas := make([]core.IterableData, 2)
+	for i := range datas {
+		content := make([]byte, core.DefaultSegmentSize)
+		_, err := rand.Read(content)
+		assert.NoError(t, err)
+		submit(t, service, content)
+
+		datas[i], err = core.NewDataInMemory(content)
+		assert.NoError(t, err)
+	}
+
+	tenants := NewTenants(TenantLimits{MaxConcurrency: 1}, nil)
+	uploader := &Uploader{clients: []*node.ZgsClient{client}, routines: 4, logger: zg_common.NewLogger(), warnings: NewWarnings(0)}
+	uploader.WithTenants(tenants)
+
+	// tenant a saturates its concurrency limit with an upload in progress
+	inflight, err := tenants.admit(context.Background(), "a", 0, uploader.logger)
+	assert.NoError(t, err)
+
+	errs := make(chan error)
+	go func() {
+		_, _, err := uploader.Upload(context.Background(), datas[0], UploadOption{SkipTx: true, Tenant: "a"})
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return tenants.Stats()["a"].Waiting == 1 }, 5*time.Second, time.Millisecond)
+
+	// tenant b unaffected
+	_, _, err = uploader.Upload(WithTenant(context.Background(), "b"), datas[1], UploadOption{SkipTx: true})
+	assert.NoError(t, err)
+	assert.Equal(t, TenantStats{Admitted: 1, Bytes: uint64(datas[1].Size())}, tenants.Stats()["b"])
+	assert.Equal(t, 1, tenants.Stats()["a"].Waiting)
+
+	// waiting upload admitted once the slot released
+	inflight.done(context.Background(), common.Hash{1}, nil)
+	assert.NoError(t, <-errs)
+	assert.Equal(t, TenantStats{Admitted: 2, Bytes: uint64(datas[0].Size())}, tenants.Stats()["a"])
+
+	// cancelled while waiting
+	inflight, err = tenants.admit(context.Background(), "a", 0, uploader.logger)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(WithTenant(context.Background(), "a"), 10*time.Millisecond)
+	defer cancel()
+	_, _, err = uploader.Upload(ctx, datas[0], UploadOption{SkipTx: true})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	inflight.done(context.Background(), common.Hash{1}, nil)
+
+	// rejected before submission, since no flow contract to submit
+	tenants.SetLimits("a", TenantLimits{MaxBytesPerDay: 1})
+	_, _, err = uploader.Upload(WithTenant(context.Background(), "a"), datas[0])
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, uint64(1), tenants.Stats()["a"].Rejected)
+}
+
+func TestTenantSlotHeldByBackgroundReplicas(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{NumShard: 1}, shard.ShardConfig{NumShard: 1})
+	clients := network.ZgsClients()
+
+	// the second node blocks uploading until released
+	release := make(chan struct{})
+	clients[1].HookCallContext(func(handler providers.CallContextFunc) providers.CallContextFunc {
+		return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+			if method == "zgs_uploadSegmentsByTxSeq" {
+				select {
+				case <-release:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			return handler(ctx, result, method, args...)
+		}
+	})
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	tenants := NewTenants(TenantLimits{MaxConcurrency: 1}, nil)
+	uploader.WithTenants(tenants)
+
+	// returns once the minimum replicas reached, while the slot is held by replicas uploading in background
+	ctx := WithTenant(context.Background(), "a")
+	opt := UploadOption{ExpectedReplica: 2, MinReplica: 1}
+	_, data := newTestData(t, 1024)
+	_, _, handle, err := uploader.UploadWithHandle(ctx, data, opt)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, tenants.Stats()["a"].InFlight)
+
+	// the next upload of tenant waits for the slot
+	errs := make(chan error)
+	_, data = newTestData(t, 2048)
+	go func() {
+		_, _, err := uploader.Upload(ctx, data, UploadOption{})
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return tenants.Stats()["a"].Waiting == 1 }, 5*time.Second, time.Millisecond)
+
+	// slot released once background replicas completed
+	close(release)
+	<-handle.Done()
+	assert.NoError(t, <-errs)
+	assert.Eventually(t, func() bool { return tenants.Stats()["a"].InFlight == 0 }, 5*time.Second, time.Millisecond)
+}
@@ -0,0 +1,80 @@
+package transfer
+
+import "context"
+
+// TracePhase is a phase of upload traced by Tracer.
+type TracePhase string
+
+const (
+	TracePhaseUpload    TracePhase = "upload"     // whole upload of a file, parent of other phases
+	TracePhaseHash      TracePhase = "hash"       // calculate the merkle tree of data
+	TracePhaseSubmit    TracePhase = "submit"     // send the flow submission transaction and wait for receipt
+	TracePhaseEntryWait TracePhase = "wait-entry" // wait for storage nodes to sync the submission from chain
+	TracePhasePush      TracePhase = "push"       // upload segments to storage nodes, parent of segment batches
+	TracePhaseSegments  TracePhase = "segments"   // upload a batch of segments to a storage node
+	TracePhaseFinality  TracePhase = "finality"   // wait for the file finalized on storage nodes
+)
+
+// Keys of trace attributes.
+const (
+	TraceAttrRoot     = "zgs.root"          // merkle root of file
+	TraceAttrSize     = "zgs.size"          // size of file in bytes
+	TraceAttrTxHash   = "zgs.tx_hash"       // hash of flow submission transaction
+	TraceAttrTxSeq    = "zgs.tx_seq"        // sequence of flow submission
+	TraceAttrNodes    = "zgs.nodes"         // URLs of storage nodes
+	TraceAttrNode     = "zgs.node"          // URL of storage node
+	TraceAttrSegment  = "zgs.segment_index" // index of the first segment in batch
+	TraceAttrSegments = "zgs.segments"      // number of segments in batch
+)
+
+// TraceAttribute is a key-value pair attached to the span of phase.
+type TraceAttribute struct {
+	Key   string
+	Value interface{} // string, bool, int, int64, uint64 or []string
+}
+
+// Tracer instruments the phases of uploads for distributed tracing, e.g. by OpenTelemetry, see the
+// transfer/tracing package, which is a separate module, so that this module does not depend on any tracing library.
+//
+// The context returned by Start is used for the phase, including RPCs to storage nodes and nested phases. It
+// should be safe for concurrent use.
+type Tracer interface {
+	Start(ctx context.Context, phase TracePhase, attrs ...TraceAttribute) (context.Context, TraceSpan)
+}
+
+// TraceSpan is the span of a phase started by Tracer.
+type TraceSpan interface {
+	// SetAttributes attaches attributes known after the phase started, e.g. the transaction hash of submission.
+	SetAttributes(attrs ...TraceAttribute)
+
+	// End ends the phase with the error if failed.
+	End(err error)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...TraceAttribute) {}
+func (noopSpan) End(error)                       {}
+
+// startSpan starts the span of phase if tracer specified, and otherwise traces nothing without overhead.
+func startSpan(tracer Tracer, ctx context.Context, phase TracePhase, attrs ...TraceAttribute) (context.Context, TraceSpan) {
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	return tracer.Start(ctx, phase, attrs...)
+}
+
+// traceNodes returns the URLs of storage nodes as trace attribute, or nothing if not traced.
+func (uploader *Uploader) traceNodes() []TraceAttribute {
+	if uploader.tracer == nil {
+		return nil
+	}
+
+	urls := make([]string, 0, len(uploader.clients))
+	for _, client := range uploader.clients {
+		urls = append(urls, client.URL())
+	}
+
+	return []TraceAttribute{{TraceAttrNodes, urls}}
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer"
+	gorpc "github.com/openweb3/go-rpc-provider"
+	providers "github.com/openweb3/go-rpc-provider/provider_wrapper"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AttrRPCMethod is the attribute key of RPC method.
+const AttrRPCMethod = "rpc.method"
+
+// Instrument traces RPCs of the specified clients as child spans of the context of calls.
+func (t *Tracer) Instrument(clients ...*node.ZgsClient) {
+	if t.tracer == nil {
+		return
+	}
+
+	for _, client := range clients {
+		client.HookCallContext(t.middleware(client.URL()))
+	}
+}
+
+// Hook returns the middleware to trace RPCs sent to the specified URL, which could be set by rpc.SetCallHook to
+// trace all clients created afterwards.
+func (t *Tracer) Hook(url string) providers.CallContextMiddleware {
+	return t.middleware(url)
+}
+
+func (t *Tracer) middleware(url string) providers.CallContextMiddleware {
+	return func(handler providers.CallContextFunc) providers.CallContextFunc {
+		if t.tracer == nil {
+			return handler
+		}
+
+		return func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+			ctx, span := t.tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+				attribute.String(AttrRPCMethod, method),
+				attribute.String(transfer.TraceAttrNode, url),
+			))
+			defer span.End()
+
+			err := handler(ctx, result, method, args...)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			return err
+		}
+	}
+}
+
+var (
+	propagatorOnce sync.Once
+	propagator     propagation.TextMapPropagator
+)
+
+// RegisterPropagator injects the trace context of RPCs into HTTP headers by the specified propagator, or the global
+// propagator if nil, so that storage nodes could join the trace. It takes effect on all RPC clients, and only the
+// first registration matters.
+func RegisterPropagator(p propagation.TextMapPropagator) {
+	propagatorOnce.Do(func() {
+		if p == nil {
+			p = otel.GetTextMapPropagator()
+		}
+
+		propagator = p
+
+		gorpc.RegisterBeforeSendHttp(func(ctx context.Context, req *fasthttp.Request) error {
+			if trace.SpanContextFromContext(ctx).IsValid() {
+				propagator.Inject(ctx, headerCarrier{&req.Header})
+			}
+
+			return nil
+		})
+	})
+}
+
+// headerCarrier adapts the headers of fasthttp request to propagation.TextMapCarrier.
+type headerCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c headerCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	var keys []string
+	c.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+
+	return keys
+}
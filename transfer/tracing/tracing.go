@@ -0,0 +1,167 @@
+// Package tracing instruments uploads and RPCs to storage nodes with OpenTelemetry, which is kept out of the
+// transfer package in a separate module, so that applications not traced do not depend on OpenTelemetry.
+//
+// Phases of upload are traced as a span tree rooted at the upload span, where spans of waiting for the submission
+// synced and finalized on storage nodes link to the span of submission transaction:
+//
+//	upload
+//	├── hash
+//	├── submit
+//	├── wait-entry  (linked to submit)
+//	├── push
+//	│   └── segments  (for each batch of segments)
+//	└── finality  (linked to submit)
+//
+// RPCs to instrumented storage node clients are traced as child spans of the phase, and the trace context is
+// propagated to storage nodes over HTTP headers once RegisterPropagator called.
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/0glabs/0g-storage-client/transfer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// ScopeName is the instrumentation scope name of tracer.
+const ScopeName = "github.com/0glabs/0g-storage-client/transfer/tracing"
+
+var _ transfer.Tracer = (*Tracer)(nil)
+
+// Tracer implements transfer.Tracer with OpenTelemetry, and is safe for concurrent use.
+type Tracer struct {
+	tracer trace.Tracer // nil if the provider is no-op
+}
+
+// NewTracer creates a tracer of the specified provider, or the global provider if nil. Nothing is traced without
+// any overhead if the provider is no-op.
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	if _, ok := provider.(noop.TracerProvider); ok {
+		return &Tracer{}
+	}
+
+	return &Tracer{tracer: provider.Tracer(ScopeName)}
+}
+
+// submission is the span of submission transaction in upload, which is linked by the spans of later phases.
+type submission struct {
+	mu     sync.Mutex
+	span   trace.SpanContext
+	txHash string
+}
+
+type submissionKey struct{}
+
+// Start implements the transfer.Tracer interface.
+func (t *Tracer) Start(ctx context.Context, phase transfer.TracePhase, attrs ...transfer.TraceAttribute) (context.Context, transfer.TraceSpan) {
+	if t.tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	opts := []trace.SpanStartOption{trace.WithAttributes(convert(attrs)...)}
+
+	sub, _ := ctx.Value(submissionKey{}).(*submission)
+	switch phase {
+	case transfer.TracePhaseUpload:
+		sub = &submission{}
+		ctx = context.WithValue(ctx, submissionKey{}, sub)
+	case transfer.TracePhaseEntryWait, transfer.TracePhaseFinality:
+		if link, ok := sub.link(); ok {
+			opts = append(opts, trace.WithLinks(link))
+		}
+	}
+
+	ctx, span := t.tracer.Start(ctx, string(phase), opts...)
+	if phase != transfer.TracePhaseSubmit {
+		sub = nil
+	}
+
+	return ctx, &phaseSpan{span, sub}
+}
+
+// link returns the link to the span of submission transaction if submitted.
+func (sub *submission) link() (trace.Link, bool) {
+	if sub == nil {
+		return trace.Link{}, false
+	}
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if !sub.span.IsValid() || len(sub.txHash) == 0 {
+		return trace.Link{}, false
+	}
+
+	return trace.Link{
+		SpanContext: sub.span,
+		Attributes:  []attribute.KeyValue{attribute.String(transfer.TraceAttrTxHash, sub.txHash)},
+	}, true
+}
+
+// phaseSpan is the span of phase, which records the submission transaction if the submit phase.
+type phaseSpan struct {
+	span trace.Span
+	sub  *submission // nil unless the submit phase
+}
+
+func (s *phaseSpan) SetAttributes(attrs ...transfer.TraceAttribute) {
+	s.span.SetAttributes(convert(attrs)...)
+
+	if s.sub == nil {
+		return
+	}
+
+	for _, attr := range attrs {
+		if txHash, ok := attr.Value.(string); ok && attr.Key == transfer.TraceAttrTxHash {
+			s.sub.mu.Lock()
+			s.sub.span, s.sub.txHash = s.span.SpanContext(), txHash
+			s.sub.mu.Unlock()
+		}
+	}
+}
+
+func (s *phaseSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+
+	s.span.End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...transfer.TraceAttribute) {}
+func (noopSpan) End(error)                                {}
+
+// convert converts trace attributes to OpenTelemetry attributes, where values of unsupported types are ignored.
+func convert(attrs []transfer.TraceAttribute) []attribute.KeyValue {
+	result := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			result = append(result, attribute.String(attr.Key, v))
+		case bool:
+			result = append(result, attribute.Bool(attr.Key, v))
+		case int:
+			result = append(result, attribute.Int(attr.Key, v))
+		case int64:
+			result = append(result, attribute.Int64(attr.Key, v))
+		case uint64:
+			result = append(result, attribute.Int64(attr.Key, int64(v)))
+		case []string:
+			result = append(result, attribute.StringSlice(attr.Key, v))
+		}
+	}
+
+	return result
+}
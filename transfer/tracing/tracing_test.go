@@ -0,0 +1,124 @@
+package tracing_test
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/transfer"
+	"github.com/0glabs/0g-storage-client/transfer/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := tracing.NewTracer(provider)
+	tracing.RegisterPropagator(propagation.TraceContext{})
+
+	network := testutil.NewNetwork(t)
+	clients := network.ZgsClients()
+	tracer.Instrument(clients...)
+
+	uploader, err := transfer.NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+	uploader.WithTracer(tracer)
+
+	content := make([]byte, 2*core.DefaultSegmentSize+100)
+	_, err = rand.Read(content)
+	assert.NoError(t, err)
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+
+	txHash, root, err := uploader.Upload(context.Background(), data)
+	assert.NoError(t, err)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	var rpcs []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if _, ok := attrOf(span, tracing.AttrRPCMethod); ok {
+			rpcs = append(rpcs, span)
+		} else {
+			spans[span.Name()] = span
+		}
+	}
+
+	// span tree of phases
+	upload := spans[string(transfer.TracePhaseUpload)]
+	if !assert.NotNil(t, upload) {
+		return
+	}
+	assert.False(t, upload.Parent().IsValid())
+	assert.Equal(t, root.Hex(), mustAttrOf(t, upload, transfer.TraceAttrRoot).AsString())
+	assert.Equal(t, int64(len(content)), mustAttrOf(t, upload, transfer.TraceAttrSize).AsInt64())
+
+	for _, phase := range []transfer.TracePhase{
+		transfer.TracePhaseHash, transfer.TracePhaseSubmit, transfer.TracePhaseEntryWait,
+		transfer.TracePhasePush, transfer.TracePhaseFinality,
+	} {
+		span := spans[string(phase)]
+		if assert.NotNil(t, span, phase) {
+			assert.Equal(t, upload.SpanContext().SpanID(), span.Parent().SpanID(), phase)
+			assert.Equal(t, upload.SpanContext().TraceID(), span.SpanContext().TraceID(), phase)
+		}
+	}
+
+	segments := spans[string(transfer.TracePhaseSegments)]
+	if assert.NotNil(t, segments) {
+		assert.Equal(t, spans[string(transfer.TracePhasePush)].SpanContext().SpanID(), segments.Parent().SpanID())
+		assert.Equal(t, clients[0].URL(), mustAttrOf(t, segments, transfer.TraceAttrNode).AsString())
+	}
+
+	// linked to the submission transaction
+	submit := spans[string(transfer.TracePhaseSubmit)]
+	assert.Equal(t, txHash.Hex(), mustAttrOf(t, submit, transfer.TraceAttrTxHash).AsString())
+	for _, phase := range []transfer.TracePhase{transfer.TracePhaseEntryWait, transfer.TracePhaseFinality} {
+		links := spans[string(phase)].Links()
+		if assert.Len(t, links, 1, phase) {
+			assert.Equal(t, submit.SpanContext(), links[0].SpanContext)
+			assert.Contains(t, links[0].Attributes, attribute.String(transfer.TraceAttrTxHash, txHash.Hex()))
+		}
+	}
+
+	// RPCs to storage nodes traced in phases, except those out of upload, e.g. in NewUploader
+	parents := make(map[string]bool)
+	for _, span := range rpcs {
+		if span.Parent().IsValid() {
+			assert.Equal(t, upload.SpanContext().TraceID(), span.SpanContext().TraceID())
+			parents[span.Parent().SpanID().String()] = true
+		}
+	}
+	assert.True(t, parents[segments.SpanContext().SpanID().String()])
+	assert.True(t, parents[spans[string(transfer.TracePhaseFinality)].SpanContext().SpanID().String()])
+
+	// nothing traced by no-op provider
+	noopTracer := tracing.NewTracer(noop.NewTracerProvider())
+	ctx, span := noopTracer.Start(context.Background(), transfer.TracePhaseUpload)
+	assert.Equal(t, context.Background(), ctx)
+	span.End(nil)
+}
+
+func attrOf(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func mustAttrOf(t *testing.T, span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	value, ok := attrOf(span, key)
+	assert.True(t, ok, key)
+
+	return value
+}
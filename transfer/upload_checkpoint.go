@@ -0,0 +1,242 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// uploadCheckpointVersion is the version of checkpoint file format, and checkpoint of other versions is discarded.
+const uploadCheckpointVersion = 1
+
+// uploadCheckpointSyncInterval is the min interval to write acknowledged segments to checkpoint file, which is
+// always written once the upload of segments completed or failed.
+const uploadCheckpointSyncInterval = 5 * time.Second
+
+// uploadCheckpointState is the content of checkpoint file to resume upload, see UploadOption.ResumeFile.
+type uploadCheckpointState struct {
+	Version  int               `json:"version"`
+	Root     common.Hash       `json:"root"`     // file merkle root
+	Size     int64             `json:"size"`     // file size in bytes
+	TxSeq    *uint64           `json:"txSeq"`    // tx seq of submission, nil if not submitted yet
+	Segments uint64            `json:"segments"` // number of file segments
+	Nodes    map[string][]byte `json:"nodes"`    // storage node URL -> bitmap of acknowledged segments
+}
+
+// uploadCheckpoint records the progress of a file upload in checkpoint file, so that an interrupted upload could
+// be resumed without submitting again or uploading the acknowledged segments again. It is safe for concurrent use,
+// and all methods are no-op on nil checkpoint.
+type uploadCheckpoint struct {
+	mu     sync.Mutex
+	path   string
+	state  uploadCheckpointState
+	dirty  bool      // segments acknowledged since last written
+	synced time.Time // time of last written
+	logger *logrus.Logger
+}
+
+// openUploadCheckpoint loads the checkpoint of file from path, which is discarded if the file size or merkle root
+// changed. Returns nil if path is empty.
+func openUploadCheckpoint(path string, root common.Hash, size int64, segments uint64, logger *logrus.Logger) (*uploadCheckpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	checkpoint := uploadCheckpoint{
+		path: path,
+		state: uploadCheckpointState{
+			Version:  uploadCheckpointVersion,
+			Root:     root,
+			Size:     size,
+			Segments: segments,
+			Nodes:    make(map[string][]byte),
+		},
+		synced: time.Now(),
+		logger: logger,
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &checkpoint, nil
+	}
+
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to read upload checkpoint")
+	}
+
+	var state uploadCheckpointState
+	if err = json.Unmarshal(content, &state); err != nil || state.Version != uploadCheckpointVersion {
+		logger.WithField("path", path).Warn("Upload checkpoint corrupted, and discarded")
+		return &checkpoint, nil
+	}
+
+	if state.Root != root || state.Size != size || state.Segments != segments {
+		logger.WithFields(logrus.Fields{
+			"path":         path,
+			"root":         root,
+			"size":         size,
+			"previousRoot": state.Root,
+			"previousSize": state.Size,
+		}).Warn("File changed since upload checkpoint, and checkpoint discarded")
+		return &checkpoint, nil
+	}
+
+	if state.Nodes == nil {
+		state.Nodes = make(map[string][]byte)
+	}
+	checkpoint.state = state
+
+	logger.WithFields(logrus.Fields{
+		"path":  path,
+		"txSeq": state.TxSeq,
+	}).Info("Upload checkpoint loaded")
+
+	return &checkpoint, nil
+}
+
+// submitted returns whether the file has been submitted according to checkpoint.
+func (checkpoint *uploadCheckpoint) submitted() bool {
+	if checkpoint == nil {
+		return false
+	}
+
+	checkpoint.mu.Lock()
+	defer checkpoint.mu.Unlock()
+
+	return checkpoint.state.TxSeq != nil
+}
+
+// begin records the tx seq of submission to upload segments to, and writes checkpoint file immediately, so that the
+// file will not be submitted again once interrupted. Acknowledged segments are discarded if tx seq changed.
+func (checkpoint *uploadCheckpoint) begin(txSeq uint64) error {
+	if checkpoint == nil {
+		return nil
+	}
+
+	checkpoint.mu.Lock()
+	defer checkpoint.mu.Unlock()
+
+	if previous := checkpoint.state.TxSeq; previous != nil && *previous != txSeq {
+		checkpoint.logger.WithFields(logrus.Fields{
+			"path":          checkpoint.path,
+			"txSeq":         txSeq,
+			"previousTxSeq": *previous,
+		}).Warn("Tx seq changed since upload checkpoint, and acknowledged segments discarded")
+		checkpoint.state.Nodes = make(map[string][]byte)
+	}
+	checkpoint.state.TxSeq = &txSeq
+
+	return checkpoint.writeLocked()
+}
+
+// acked returns whether the segment has been acknowledged by the storage node.
+func (checkpoint *uploadCheckpoint) acked(node string, segIndex uint64) bool {
+	if checkpoint == nil {
+		return false
+	}
+
+	checkpoint.mu.Lock()
+	defer checkpoint.mu.Unlock()
+
+	bitmap := checkpoint.state.Nodes[node]
+
+	return segIndex/8 < uint64(len(bitmap)) && bitmap[segIndex/8]&(1<<(segIndex%8)) != 0
+}
+
+// ack records the segments acknowledged by the storage node, and writes checkpoint file periodically.
+func (checkpoint *uploadCheckpoint) ack(node string, segIndexes ...uint64) {
+	if checkpoint == nil {
+		return
+	}
+
+	checkpoint.mu.Lock()
+	defer checkpoint.mu.Unlock()
+
+	bitmap, ok := checkpoint.state.Nodes[node]
+	if !ok {
+		bitmap = make([]byte, (checkpoint.state.Segments+7)/8)
+		checkpoint.state.Nodes[node] = bitmap
+	}
+
+	for _, segIndex := range segIndexes {
+		if segIndex/8 < uint64(len(bitmap)) {
+			bitmap[segIndex/8] |= 1 << (segIndex % 8)
+		}
+	}
+	checkpoint.dirty = true
+
+	if time.Since(checkpoint.synced) >= uploadCheckpointSyncInterval {
+		if err := checkpoint.writeLocked(); err != nil {
+			checkpoint.logger.WithError(err).WithField("path", checkpoint.path).Warn("Failed to write upload checkpoint")
+		}
+	}
+}
+
+// sync writes the acknowledged segments to checkpoint file if any since last written.
+func (checkpoint *uploadCheckpoint) sync() error {
+	if checkpoint == nil {
+		return nil
+	}
+
+	checkpoint.mu.Lock()
+	defer checkpoint.mu.Unlock()
+
+	if !checkpoint.dirty {
+		return nil
+	}
+
+	return checkpoint.writeLocked()
+}
+
+// remove removes the checkpoint file once the upload completed.
+func (checkpoint *uploadCheckpoint) remove() error {
+	if checkpoint == nil {
+		return nil
+	}
+
+	checkpoint.mu.Lock()
+	defer checkpoint.mu.Unlock()
+
+	if err := os.Remove(checkpoint.path); err != nil && !os.IsNotExist(err) {
+		return errors.WithMessage(err, "Failed to remove upload checkpoint")
+	}
+
+	return nil
+}
+
+func (checkpoint *uploadCheckpoint) writeLocked() error {
+	content, err := json.Marshal(checkpoint.state)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to marshal upload checkpoint")
+	}
+
+	if err = writeFileSync(checkpoint.path, content); err != nil {
+		return errors.WithMessage(err, "Failed to write upload checkpoint")
+	}
+
+	checkpoint.dirty = false
+	checkpoint.synced = time.Now()
+
+	return nil
+}
+
+type uploadCheckpointKey struct{}
+
+func withUploadCheckpoint(ctx context.Context, checkpoint *uploadCheckpoint) context.Context {
+	if checkpoint == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, uploadCheckpointKey{}, checkpoint)
+}
+
+func uploadCheckpointFromContext(ctx context.Context) *uploadCheckpoint {
+	checkpoint, _ := ctx.Value(uploadCheckpointKey{}).(*uploadCheckpoint)
+	return checkpoint
+}
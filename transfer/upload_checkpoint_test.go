@@ -0,0 +1,124 @@
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// segmentRecorder records the indexes of segments uploaded to storage node, and cancels the upload once the
+// specified number of segments uploaded.
+type segmentRecorder struct {
+	mu       sync.Mutex
+	indexes  []uint64
+	cancelAt int
+	cancel   context.CancelFunc
+}
+
+func (recorder *segmentRecorder) beforeUpload(txSeq, index uint64) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if recorder.cancel != nil && len(recorder.indexes) == recorder.cancelAt {
+		recorder.cancel()
+		return context.Canceled
+	}
+
+	recorder.indexes = append(recorder.indexes, index)
+
+	return nil
+}
+
+func (recorder *segmentRecorder) reset(cancelAt int, cancel context.CancelFunc) []uint64 {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	indexes := recorder.indexes
+	recorder.indexes, recorder.cancelAt, recorder.cancel = nil, cancelAt, cancel
+
+	return indexes
+}
+
+func TestUploadResumeFromCheckpoint(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	var recorder segmentRecorder
+	network.Nodes[0].SetHooks(testutil.ZgsHooks{BeforeUpload: recorder.beforeUpload})
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+	uploader.WithRoutines(1)
+
+	content, data := newTestData(t, 8*core.DefaultSegmentSize)
+	opt := UploadOption{TaskSize: 1, ResumeFile: filepath.Join(t.TempDir(), "upload.checkpoint")}
+
+	// killed once 3 segments uploaded
+	ctx, cancel := context.WithCancel(context.Background())
+	recorder.reset(3, cancel)
+	_, _, err = uploader.Upload(ctx, data, opt)
+	assert.Error(t, err)
+	assert.Equal(t, []uint64{0, 1, 2}, recorder.reset(0, nil))
+
+	var state uploadCheckpointState
+	raw, err := os.ReadFile(opt.ResumeFile)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(raw, &state))
+	assert.NotNil(t, state.TxSeq)
+	assert.Equal(t, uint64(8), state.Segments)
+
+	// only the remaining segments uploaded without submitting again
+	_, root, err := uploader.Upload(context.Background(), data, opt)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{3, 4, 5, 6, 7}, recorder.reset(0, nil))
+	assert.Equal(t, 1, len(network.Chain.Submissions()))
+
+	downloaded, err := network.Nodes[0].DownloadSegment(root, 0, uint64(len(content)/core.DefaultChunkSize))
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+
+	// removed once completed
+	_, err = os.Stat(opt.ResumeFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUploadCheckpointInvalidated(t *testing.T) {
+	network := testutil.NewNetwork(t)
+	var recorder segmentRecorder
+	network.Nodes[0].SetHooks(testutil.ZgsHooks{BeforeUpload: recorder.beforeUpload})
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(t, err)
+	defer uploader.Close()
+	uploader.WithRoutines(1)
+
+	_, data := newTestData(t, 4*core.DefaultSegmentSize)
+	opt := UploadOption{TaskSize: 1, ResumeFile: filepath.Join(t.TempDir(), "upload.checkpoint")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	recorder.reset(2, cancel)
+	_, _, err = uploader.Upload(ctx, data, opt)
+	assert.Error(t, err)
+	recorder.reset(0, nil)
+
+	// file of another size and merkle root submitted and uploaded entirely
+	_, changed := newTestData(t, 3*core.DefaultSegmentSize)
+	_, _, err = uploader.Upload(context.Background(), changed, opt)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{0, 1, 2}, recorder.reset(0, nil))
+	assert.Equal(t, 2, len(network.Chain.Submissions()))
+}
+
+func TestUploadOptionResumeFile(t *testing.T) {
+	opt := UploadOption{ExpectedReplica: 2, MinReplica: 1, ResumeFile: "upload.checkpoint"}
+	assert.ErrorContains(t, opt.Validate(), "ResumeFile")
+
+	opt.MinReplica = 0
+	assert.NoError(t, opt.Validate())
+}
@@ -0,0 +1,162 @@
+package transfer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/pkg/errors"
+)
+
+// Key prefixes of upload index, which is followed by the sender address, or zero address for all senders.
+var (
+	uploadIndexCursorPrefix = []byte("uploads-cursor-")
+	uploadIndexRecordPrefix = []byte("uploads-record-") // followed by sender and tx seq in big endian
+)
+
+// UploadIndex is a local index of submitted files in key-value store, so that history need not be rescanned
+// on every query. Files are indexed by sender, and new blocks are scanned incrementally upon Update.
+type UploadIndex struct {
+	db ethdb.KeyValueStore
+}
+
+// NewUploadIndex creates an index in the specified key-value store.
+func NewUploadIndex(db ethdb.KeyValueStore) *UploadIndex {
+	return &UploadIndex{db}
+}
+
+// OpenUploadIndex opens or creates an index in LevelDB of the specified directory.
+func OpenUploadIndex(path string) (*UploadIndex, error) {
+	db, err := leveldb.New(path, 16, 16, "", false)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to open LevelDB")
+	}
+
+	return NewUploadIndex(db), nil
+}
+
+// Close closes the underlying key-value store.
+func (index *UploadIndex) Close() error {
+	return index.db.Close()
+}
+
+func uploadIndexScope(sender *common.Address) []byte {
+	if sender == nil {
+		return common.Address{}.Bytes()
+	}
+
+	return sender.Bytes()
+}
+
+func uploadIndexCursorKey(sender *common.Address) []byte {
+	return append(append([]byte{}, uploadIndexCursorPrefix...), uploadIndexScope(sender)...)
+}
+
+func uploadIndexRecordKey(sender *common.Address, txSeq uint64) []byte {
+	key := append(append([]byte{}, uploadIndexRecordPrefix...), uploadIndexScope(sender)...)
+	return binary.BigEndian.AppendUint64(key, txSeq)
+}
+
+// Cursor returns the position to continue scanning of the specified sender, or false if never scanned.
+func (index *UploadIndex) Cursor(sender *common.Address) (UploadCursor, bool, error) {
+	var cursor UploadCursor
+
+	key := uploadIndexCursorKey(sender)
+	if ok, err := index.db.Has(key); err != nil || !ok {
+		return cursor, false, err
+	}
+
+	value, err := index.db.Get(key)
+	if err != nil {
+		return cursor, false, err
+	}
+
+	if err = json.Unmarshal(value, &cursor); err != nil {
+		return cursor, false, errors.WithMessage(err, "Failed to unmarshal cursor")
+	}
+
+	return cursor, true, nil
+}
+
+// Update scans new blocks for files of query sender and indexes them. It continues from the last scanned
+// block if any, otherwise from the FromBlock of query. Note, the tag prefix of query is ignored, so that
+// all files of sender are indexed.
+//
+// Files and the cursor are written atomically for each block range, and files are keyed by tx seq. So,
+// it is idempotent to update again after interruption, and no file will be duplicated or missed.
+func (index *UploadIndex) Update(ctx context.Context, filter SubmitLogFilter, query UploadQuery) (UploadCursor, error) {
+	cursor, ok, err := index.Cursor(query.Sender)
+	if err != nil {
+		return cursor, errors.WithMessage(err, "Failed to read cursor")
+	}
+
+	if ok && cursor.NextBlock > query.FromBlock {
+		query.FromBlock = cursor.NextBlock
+	}
+
+	cursor.NextBlock = query.FromBlock
+
+	err = scanUploads(ctx, filter, query, func(records []UploadRecord, nextBlock uint64) error {
+		batch := index.db.NewBatch()
+
+		for i := range records {
+			value, err := json.Marshal(&records[i])
+			if err != nil {
+				return errors.WithMessage(err, "Failed to marshal file")
+			}
+
+			if err = batch.Put(uploadIndexRecordKey(query.Sender, records[i].TxSeq), value); err != nil {
+				return err
+			}
+		}
+
+		value, err := json.Marshal(UploadCursor{NextBlock: nextBlock})
+		if err != nil {
+			return errors.WithMessage(err, "Failed to marshal cursor")
+		}
+
+		if err = batch.Put(uploadIndexCursorKey(query.Sender), value); err != nil {
+			return err
+		}
+
+		if err = batch.Write(); err != nil {
+			return errors.WithMessage(err, "Failed to write index")
+		}
+
+		cursor.NextBlock = nextBlock
+
+		return nil
+	})
+
+	return cursor, err
+}
+
+// List returns the indexed files that match the query in order of tx seq. Note, Update should be called
+// before to index files of the query sender.
+func (index *UploadIndex) List(query UploadQuery) ([]UploadRecord, error) {
+	prefix := append(append([]byte{}, uploadIndexRecordPrefix...), uploadIndexScope(query.Sender)...)
+
+	iter := index.db.NewIterator(prefix, nil)
+	defer iter.Release()
+
+	var records []UploadRecord
+	for iter.Next() {
+		var record UploadRecord
+		if err := json.Unmarshal(iter.Value(), &record); err != nil {
+			return nil, errors.WithMessage(err, "Failed to unmarshal file")
+		}
+
+		if query.Match(&record) {
+			records = append(records, record)
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return nil, errors.WithMessage(err, "Failed to iterate index")
+	}
+
+	return records, nil
+}
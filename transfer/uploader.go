@@ -2,13 +2,16 @@ package transfer
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
+	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	zg_common "github.com/0glabs/0g-storage-client/common"
@@ -61,13 +64,70 @@ const (
 
 // UploadOption upload option for a file
 type UploadOption struct {
-	Tags             []byte              // transaction tags
-	FinalityRequired FinalityRequirement // finality setting
-	TaskSize         uint                // number of segment to upload in single rpc request
-	ExpectedReplica  uint                // expected number of replications
-	SkipTx           bool                // skip sending transaction on chain, this can set to true only if the data has already settled on chain before
-	Fee              *big.Int            // fee in neuron
-	Nonce            *big.Int            // nonce for transaction
+	Tags             []byte               // transaction tags
+	FinalityRequired FinalityRequirement  // finality setting
+	TaskSize         uint                 // number of segment to upload in single rpc request
+	Routines         int                  // number of segment upload tasks in parallel across storage nodes, 0 to use the routines of uploader
+	ExpectedReplica  uint                 // expected number of replications
+	ShardReplicas    []shard.ShardReplica // replications required for specific shards on top of ExpectedReplica, e.g. more replicas for hot data
+	MinReplica       uint                 // minimum number of replications to succeed, 0 to require ExpectedReplica
+	ReplicaDeadline  time.Duration        // deadline to complete replica requirement in background once MinReplica reached, default 10 minutes
+	SkipTx           bool                 // skip sending transaction on chain, this can set to true only if the data has already settled on chain before
+	SkipPreflight    bool                 // skip checks of max file size and balance before uploading, e.g. for offline workflows
+	Fee              *big.Int             // fee in neuron
+	Nonce            *big.Int             // nonce for transaction
+	Priority         Priority             // priority to upload segments in shared pool, overrides the priority of context if specified
+	Tenant           string               // tenant to upload on behalf of, overrides the tenant of context if specified, see Uploader.WithTenants
+	Retention        string               // retention policy to tag the submission, see Uploader.WithRetentionPolicies
+	RetentionCheck   RetentionCheck       // policy to check the retention class reported by storage nodes once finalized
+	VerifySamples    uint                 // number of segments sampled on each storage node to verify with merkle proof once uploaded, 0 to disable
+	ResumeFile       string               // checkpoint file to resume an interrupted upload of a single file, not supported with MinReplica
+}
+
+// Validate checks the upload option, and returns an error that names the invalid field if any.
+func (opt *UploadOption) Validate() error {
+	if opt.FinalityRequired > TransactionPacked {
+		return zg_common.NewOptionError("FinalityRequired", "unknown finality requirement %v", uint(opt.FinalityRequired))
+	}
+
+	if opt.MinReplica > opt.ExpectedReplica {
+		return zg_common.NewOptionError("MinReplica", "should not exceed ExpectedReplica %v, got %v", opt.ExpectedReplica, opt.MinReplica)
+	}
+
+	if err := opt.ReplicaRequirement().Validate(); err != nil {
+		// reported as the option field of upload rather than the replica requirement
+		var optErr *zg_common.OptionError
+		if errors.As(err, &optErr) {
+			return zg_common.NewOptionError("ShardReplicas", "%v", optErr.Reason)
+		}
+
+		return zg_common.NewOptionError("ShardReplicas", "%v", err)
+	}
+
+	if err := opt.validateRetention(); err != nil {
+		return err
+	}
+
+	// replicas uploaded in background are not recorded in checkpoint
+	if len(opt.ResumeFile) > 0 && opt.partialReplica() {
+		return zg_common.NewOptionError("ResumeFile", "not supported with MinReplica %v", opt.MinReplica)
+	}
+
+	return zg_common.FirstError(
+		zg_common.RequireNonNegative("Routines", opt.Routines),
+		zg_common.RequireNonNegative("ReplicaDeadline", opt.ReplicaDeadline),
+	)
+}
+
+// ReplicaRequirement returns the replications required by ExpectedReplica and ShardReplicas.
+func (opt *UploadOption) ReplicaRequirement() shard.ReplicaRequirement {
+	return shard.ReplicaRequirement{Default: opt.ExpectedReplica, Shards: opt.ShardReplicas}
+}
+
+// partialReplica returns whether the upload succeeds once MinReplica reached, and the replica requirement is
+// completed in background.
+func (opt *UploadOption) partialReplica() bool {
+	return opt.MinReplica > 0 && opt.MinReplica < opt.ReplicaRequirement().Max()
 }
 
 // BatchUploadOption upload option for a batching
@@ -75,16 +135,54 @@ type BatchUploadOption struct {
 	Fee         *big.Int       // fee in neuron
 	Nonce       *big.Int       // nonce for transaction
 	TaskSize    uint           // number of files to upload simutanously
-	DataOptions []UploadOption // upload option for single file, nonce and fee are ignored
+	Tenant      string         // tenant to upload on behalf of, overrides the tenant of context if specified
+	DataOptions []UploadOption // upload option for single file, nonce, fee, tenant and preflight are ignored
+
+	SkipPreflight bool // skip checks of max file size and balance before uploading, e.g. for offline workflows
 }
 
 // Uploader uploader to upload file to 0g storage, send on-chain transactions and transfer data to storage nodes.
+//
+// Uploader is safe for concurrent uploads once configured, but the With* setters should be called before it is
+// shared across goroutines. Transactions sent by the same account are serialized to avoid nonce conflicts.
 type Uploader struct {
-	flow     *contract.FlowContract // flow contract instance
-	market   *contract.Market       // market contract instance
-	clients  []*node.ZgsClient      // 0g storage clients
-	routines int                    // number of go routines for uploading
-	logger   *logrus.Logger         // logger
+	flow      *contract.FlowContract // flow contract instance
+	market    *contract.Market       // market contract instance
+	clients   []*node.ZgsClient      // 0g storage clients
+	routines  int                    // number of go routines for uploading
+	logger    *logrus.Logger         // logger
+	warnings  *Warnings              // non-fatal issues during uploading
+	flights   *uploadFlights         // deduplicates concurrent uploads of the same data, nil if disabled
+	profile   *Profile               // transfer profile, nil if not specified
+	embed     dir.EmbedOption        // option to embed small files in directory manifest, disabled by default
+	hash      core.HashOption        // option to read data when calculating merkle tree
+	mmap      bool                   // map files into memory to read when uploading files on disk
+	names     dir.NameEncoding       // policy to encode file names that are not valid UTF-8 in directory metadata
+	workers   int                    // number of files hashed concurrently when building directory tree
+	ignore    *dir.IgnorePatterns    // patterns of entries to ignore when uploading directory, nil if not ignored
+	symlinks  dir.SymlinkPolicy      // policy to handle symbolic links escaping the directory or forming a cycle
+	portable  dir.PortableNamePolicy // policy to handle file names not portable across download targets
+	special   dir.SpecialFilePolicy  // policy to handle special files, e.g. named pipes and device nodes
+	retention RetentionPolicies      // retention policies by name to tag submissions, empty if not configured
+	metadata  bool                   // record permissions and modification times in directory metadata
+	pool      *parallel.PriorityPool // shared pool to upload segments, nil if not specified
+	signer    *ecdsa.PrivateKey      // key to sign directory manifest, nil if not signed
+
+	manifestLimit    int64 // max size of directory manifest, 0 to use the default by max file size of network
+	manifestChunking bool  // upload directory manifest in chunks if exceeds the limit, otherwise fail early
+	continueOnError  bool  // continue uploading the other files of directory once any file failed
+
+	tenants *Tenants         // limits of tenants to upload, nil if not isolated
+	senders *SenderPool      // accounts to spread flow submissions, nil to send by the account of web3 client
+	maxSize maxFileSizeCache // max file size of network queried from storage nodes
+	ledger  Ledger           // local record of uploads, nil if disabled
+	tracer  Tracer           // instruments phases of uploads, nil if not traced
+	hints   *UploadHints     // storage nodes holding files uploaded recently, nil if not recorded
+
+	onProgress func(p Progress) // serialized callback to report the progress of uploads, nil if not reported
+
+	flowAddress common.Address // address of flow contract
+	lifecycle
 }
 
 func getShardConfigs(ctx context.Context, clients []*node.ZgsClient) ([]*shard.ShardConfig, error) {
@@ -133,10 +231,14 @@ func NewUploader(ctx context.Context, w3Client *web3go.Client, clients []*node.Z
 	}
 
 	uploader := &Uploader{
-		clients: clients,
-		logger:  zg_common.NewLogger(opts...),
-		flow:    flow,
-		market:  market,
+		clients:  clients,
+		logger:   zg_common.NewLogger(opts...),
+		flow:     flow,
+		market:   market,
+		warnings: NewWarnings(defaultMaxWarnings),
+		flights:  newUploadFlights(),
+
+		flowAddress: status.NetworkIdentity.FlowContractAddress,
 	}
 
 	return uploader, nil
@@ -163,6 +265,220 @@ func (uploader *Uploader) WithRoutines(routines int) *Uploader {
 	return uploader
 }
 
+// WithWarningSink sets the callback to receive warnings as soon as they happen during uploading.
+func (uploader *Uploader) WithWarningSink(sink WarningSink) *Uploader {
+	uploader.warnings.SetSink(sink)
+	return uploader
+}
+
+// Warnings returns the non-fatal issues aggregated during uploading.
+func (uploader *Uploader) Warnings() *Warnings {
+	return uploader.warnings
+}
+
+// WithSingleflight enables or disables the deduplication of concurrent uploads of the same data, which is
+// enabled by default. When enabled, concurrent Upload calls for an identical root and upload option share one
+// underlying upload, and all receive the same result. Note, the shared upload reads the data of the first call,
+// so it is aborted once the first call cancelled, and the other calls upload again with their own data.
+func (uploader *Uploader) WithSingleflight(enabled bool) *Uploader {
+	if !enabled {
+		uploader.flights = nil
+	} else if uploader.flights == nil {
+		uploader.flights = newUploadFlights()
+	}
+	return uploader
+}
+
+// CoalescedUploads returns the number of Upload calls that joined an in-flight upload of the same data.
+func (uploader *Uploader) CoalescedUploads() uint64 {
+	if uploader.flights == nil {
+		return 0
+	}
+	return uploader.flights.Coalesced()
+}
+
+// WithEmbedding enables to embed the content of small files in directory manifest when uploading directory,
+// so that those files need not be uploaded separately. Zero value option disables embedding.
+func (uploader *Uploader) WithEmbedding(opt dir.EmbedOption) *Uploader {
+	uploader.embed = opt
+	return uploader
+}
+
+// WithManifestSigner sets the key to sign the directory manifest when uploading directory, so that consumers
+// could verify the publisher of directory. Passes nil to disable, which is default. Note, the signed manifest has
+// a different storage root from the unsigned one.
+func (uploader *Uploader) WithManifestSigner(key *ecdsa.PrivateKey) *Uploader {
+	uploader.signer = key
+	return uploader
+}
+
+// WithHashOption sets the option to read data when calculating merkle tree of data to upload, e.g. larger
+// buffer and readahead for data on spinning disks.
+func (uploader *Uploader) WithHashOption(opt core.HashOption) *Uploader {
+	uploader.hash = opt
+	return uploader
+}
+
+// WithMmap enables to map files on disk into memory when uploading files and directories, which reads huge files
+// with fewer syscalls and copies, and falls back to regular file reads if not supported, see core.NewDataInFileMmap.
+func (uploader *Uploader) WithMmap(enabled bool) *Uploader {
+	uploader.mmap = enabled
+	return uploader
+}
+
+// WithNameEncoding sets the policy to encode file names that are not valid UTF-8 when uploading directory, which
+// are replaced with U+FFFD by default and reported as warnings. Specify dir.NameEncodingError to reject them.
+func (uploader *Uploader) WithNameEncoding(encoding dir.NameEncoding) *Uploader {
+	uploader.names = encoding
+	return uploader
+}
+
+// WithTreeWorkers sets the number of files hashed concurrently when building the file tree of directory to upload,
+// which are hashed one by one by default.
+func (uploader *Uploader) WithTreeWorkers(workers int) *Uploader {
+	uploader.workers = workers
+	return uploader
+}
+
+// WithContinueOnFileError enables to continue uploading the other files of directory once any file failed to
+// upload, which is disabled by default so that directory upload stops at the first failure, and files not uploaded
+// yet are reported as pending in DirUploadSummary. Either way, the directory metadata is not uploaded if any file
+// failed.
+func (uploader *Uploader) WithContinueOnFileError(enabled bool) *Uploader {
+	uploader.continueOnError = enabled
+	return uploader
+}
+
+// WithIgnore sets the gitignore-style patterns of entries not to upload when uploading directory, which neither
+// appear in the directory metadata, see dir.LoadIgnoreFile.
+func (uploader *Uploader) WithIgnore(ignore *dir.IgnorePatterns) *Uploader {
+	uploader.ignore = ignore
+	return uploader
+}
+
+// WithSymlinkPolicy sets the policy to handle symbolic links that escape the directory to upload or form a cycle,
+// which are rejected by default. Symbolic links skipped under the dir.SymlinkSkip policy are reported as warnings.
+func (uploader *Uploader) WithSymlinkPolicy(policy dir.SymlinkPolicy) *Uploader {
+	uploader.symlinks = policy
+	return uploader
+}
+
+// WithSpecialFilePolicy sets the policy to handle special files, e.g. named pipes, sockets and device nodes, when
+// uploading directory, which are skipped by default. Special files skipped are reported as warnings, and excluded in
+// the summary of directory upload.
+func (uploader *Uploader) WithSpecialFilePolicy(policy dir.SpecialFilePolicy) *Uploader {
+	uploader.special = policy
+	return uploader
+}
+
+// WithPortableNamePolicy sets the policy to handle file names that are not portable across common download targets
+// when uploading directory, which are rejected by default. File names kept under the dir.PortableNamesWarn policy
+// are reported as warnings.
+func (uploader *Uploader) WithPortableNamePolicy(policy dir.PortableNamePolicy) *Uploader {
+	uploader.portable = policy
+	return uploader
+}
+
+// WithMetadata records the permission bits and modification time of files and directories in the directory
+// metadata when uploading directory, which are restored by DownloadDir. Disabled by default.
+func (uploader *Uploader) WithMetadata(enabled bool) *Uploader {
+	uploader.metadata = enabled
+	return uploader
+}
+
+// WithSenders spreads flow submissions across the accounts of pool instead of the account of web3 client, unless
+// nonce specified in option. Besides, files of directory are uploaded concurrently by the number of accounts.
+func (uploader *Uploader) WithSenders(pool *SenderPool) *Uploader {
+	uploader.senders = pool
+	return uploader
+}
+
+// WithPool sets the pool shared with other transfers to upload segments, so that uploads are scheduled by the
+// priority of option or context, see WithPriority. Note, the number of routines still limits each upload.
+func (uploader *Uploader) WithPool(pool *parallel.PriorityPool) *Uploader {
+	uploader.pool = pool
+	return uploader
+}
+
+// WithTenants isolates uploads by the tenant of option or context, see WithTenant. Each tenant is limited by the
+// number of concurrent uploads and the bytes to upload in a day, and uploads exceeding the quota are rejected with
+// QuotaExceededError before submission. Uploads without tenant are limited as the anonymous tenant.
+//
+// Note, the concurrency slot is released once Upload returns, even if replicas are still uploading in background.
+func (uploader *Uploader) WithTenants(tenants *Tenants) *Uploader {
+	uploader.tenants = tenants
+	return uploader
+}
+
+// WithLedger records each upload operation along with its outcome in the local ledger, and files or directories
+// uploaded as part of another operation are not recorded separately. Failures to write ledger are reported as
+// warnings, which never fail the upload.
+func (uploader *Uploader) WithLedger(ledger Ledger) *Uploader {
+	uploader.ledger = ledger
+	return uploader
+}
+
+// WithTracer instruments the phases of Upload for distributed tracing, e.g. by OpenTelemetry, see Tracer. Note,
+// RPCs to storage nodes are traced only if the clients are instrumented as well. Passes nil to disable, which is
+// default.
+func (uploader *Uploader) WithTracer(tracer Tracer) *Uploader {
+	uploader.tracer = tracer
+	return uploader
+}
+
+// WithRetentionPolicies configures the retention policies that UploadOption.Retention refers to by name, which
+// are empty by default so that uploads with retention policy specified fail.
+func (uploader *Uploader) WithRetentionPolicies(policies RetentionPolicies) *Uploader {
+	uploader.retention = policies
+	return uploader
+}
+
+// WithProgress sets the callback to report the progress of each file uploaded, including files of directory and
+// batch which are identified by Progress.Root. Phase changes are always reported, and segments uploaded at most once
+// per dir.DefaultProgressInterval, and the final progress is reported with ProgressCompleted once a file uploaded,
+// even if completed in a single segment. Callbacks are serialized, so that need not be synchronized. Passes nil to
+// disable, which is default.
+//
+// If MinReplica of option specified, the file is regarded as completed once MinReplica reached, and the remaining
+// replicas uploaded in background are not reported.
+func (uploader *Uploader) WithProgress(onProgress func(p Progress)) *Uploader {
+	uploader.onProgress = serializeProgress(onProgress)
+	return uploader
+}
+
+// WithProfile applies the transfer profile, including routines, singleflight and directory batch size. Besides,
+// the upload option of profile is used by default if not specified when uploading.
+func (uploader *Uploader) WithProfile(profile Profile) *Uploader {
+	uploader.profile = &profile
+	return uploader.WithRoutines(profile.Routines).WithSingleflight(profile.Singleflight)
+}
+
+// Profile returns the effective transfer profile, or nil if not specified.
+func (uploader *Uploader) Profile() *Profile {
+	if uploader.profile == nil {
+		return nil
+	}
+
+	profile := *uploader.profile
+	profile.Routines = uploader.routines
+	profile.Singleflight = uploader.flights != nil
+
+	return &profile
+}
+
+// WithClientsOwned indicates whether the storage node clients are owned by uploader, which will be closed
+// along with uploader. By default, clients are owned by caller and will not be closed by uploader.
+func (uploader *Uploader) WithClientsOwned(owned bool) *Uploader {
+	uploader.ownsClients = owned
+	return uploader
+}
+
+// Close stops accepting new uploads, waits for in-flight uploads to complete, and closes the storage node
+// clients if owned. It is safe to close for multiple times.
+func (uploader *Uploader) Close() error {
+	return uploader.close(uploader.clients)
+}
+
 // SplitableUpload submit data to 0g storage contract and large data will be splited to reduce padding cost.
 func (uploader *Uploader) SplitableUpload(ctx context.Context, data core.IterableData, fragmentSize int64, option ...UploadOption) ([]common.Hash, []common.Hash, error) {
 	if fragmentSize < core.DefaultChunkSize {
@@ -172,6 +488,19 @@ func (uploader *Uploader) SplitableUpload(ctx context.Context, data core.Iterabl
 	fragmentSize = int64(core.NextPow2(uint64(fragmentSize)))
 	uploader.logger.Infof("fragment size: %v", fragmentSize)
 
+	var tenant string
+	if len(option) > 0 {
+		tenant = option[0].Tenant
+	}
+	ctx, entry := uploader.beginRecord(ctx, LedgerKindFile, tenant)
+	txHashes, rootHashes, err := uploader.splitableUpload(ctx, data, fragmentSize, option...)
+	entry.done(ctx, data.Size(), txHashes, rootHashes, err)
+
+	return txHashes, rootHashes, err
+}
+
+// splitableUpload uploads data in fragments of the aligned size if data is larger.
+func (uploader *Uploader) splitableUpload(ctx context.Context, data core.IterableData, fragmentSize int64, option ...UploadOption) ([]common.Hash, []common.Hash, error) {
 	txHashes := make([]common.Hash, 0)
 	rootHashes := make([]common.Hash, 0)
 	if data.Size() <= fragmentSize {
@@ -184,28 +513,41 @@ func (uploader *Uploader) SplitableUpload(ctx context.Context, data core.Iterabl
 	} else {
 		fragments := data.Split(fragmentSize)
 		uploader.logger.Infof("splitted origin file into %v fragments, %v bytes each.", len(fragments), fragmentSize)
-		var opt UploadOption
-		if len(option) > 0 {
-			opt = option[0]
-		}
-		for l := 0; l < len(fragments); l += int(defaultBatchSize) {
-			r := min(l+int(defaultBatchSize), len(fragments))
-			uploader.logger.Infof("batch submitting fragments %v to %v...", l, r)
-			opts := BatchUploadOption{
-				Fee:         nil,
-				Nonce:       nil,
-				DataOptions: make([]UploadOption, 0),
-			}
-			for i := l; i < r; i += 1 {
-				opts.DataOptions = append(opts.DataOptions, opt)
-			}
-			txHash, roots, err := uploader.BatchUpload(ctx, fragments[l:r], opts)
-			if err != nil {
-				return txHashes, rootHashes, err
-			}
-			txHashes = append(txHashes, txHash)
-			rootHashes = append(rootHashes, roots...)
+		return uploader.uploadFragments(ctx, fragments, option...)
+	}
+	return txHashes, rootHashes, nil
+}
+
+// uploadFragments uploads fragments in batches of defaultBatchSize, and returns the transaction hashes of batches
+// along with the roots of fragments uploaded.
+func (uploader *Uploader) uploadFragments(ctx context.Context, fragments []core.IterableData, option ...UploadOption) ([]common.Hash, []common.Hash, error) {
+	txHashes := make([]common.Hash, 0)
+	rootHashes := make([]common.Hash, 0)
+	var opt UploadOption
+	if len(option) > 0 {
+		opt = option[0]
+	}
+	for l := 0; l < len(fragments); l += int(defaultBatchSize) {
+		r := min(l+int(defaultBatchSize), len(fragments))
+		uploader.logger.Infof("batch submitting fragments %v to %v...", l, r)
+		opts := BatchUploadOption{
+			Fee:           nil,
+			Nonce:         nil,
+			DataOptions:   make([]UploadOption, 0),
+			SkipPreflight: opt.SkipPreflight,
+		}
+		for i := l; i < r; i += 1 {
+			opts.DataOptions = append(opts.DataOptions, opt)
+		}
+		txHash, roots, err := uploader.BatchUpload(ctx, fragments[l:r], opts)
+		if err != nil && l > 0 {
+			err = errors.WithMessagef(err, "Only %v of %v fragments uploaded", l, len(fragments))
+			return txHashes, rootHashes, zg_common.WithErrorClass(err, zg_common.ErrorClassPartial)
+		} else if err != nil {
+			return txHashes, rootHashes, err
 		}
+		txHashes = append(txHashes, txHash)
+		rootHashes = append(rootHashes, roots...)
 	}
 	return txHashes, rootHashes, nil
 }
@@ -213,7 +555,10 @@ func (uploader *Uploader) SplitableUpload(ctx context.Context, data core.Iterabl
 // BatchUpload submit multiple data to 0g storage contract batchly in single on-chain transaction, then transfer the data to the storage nodes.
 // The nonce for upload transaction will be the first non-nil nonce in given upload options, the protocol fee is the sum of fees in upload options.
 func (uploader *Uploader) BatchUpload(ctx context.Context, datas []core.IterableData, option ...BatchUploadOption) (common.Hash, []common.Hash, error) {
-	stageTimer := time.Now()
+	if err := uploader.acquire(); err != nil {
+		return common.Hash{}, nil, err
+	}
+	defer uploader.release()
 
 	n := len(datas)
 	if n == 0 {
@@ -234,10 +579,68 @@ func (uploader *Uploader) BatchUpload(ctx context.Context, datas []core.Iterable
 		return common.Hash{}, nil, errors.New("datas and tags length mismatch")
 	}
 
+	// tags resolved on a copy of data options, which are validated before anything submitted
+	opts.DataOptions = append([]UploadOption(nil), opts.DataOptions...)
+	for i := range opts.DataOptions {
+		if err := opts.DataOptions[i].Validate(); err != nil {
+			return common.Hash{}, nil, errors.WithMessagef(err, "invalid option of data %v", i)
+		}
+
+		if err := uploader.resolveRetention(&opts.DataOptions[i]); err != nil {
+			return common.Hash{}, nil, err
+		}
+	}
+
+	var size uint64
+	for _, data := range datas {
+		size += uint64(data.Size())
+	}
+
+	ctx, entry := uploader.beginRecord(ctx, LedgerKindBatch, opts.Tenant)
+	admission, err := uploader.tenants.admit(ctx, tenantOf(ctx, opts.Tenant), size, uploader.logger)
+	if err != nil {
+		entry.done(ctx, int64(size), nil, nil, err)
+		return common.Hash{}, nil, err
+	}
+
+	txHash, dataRoots, err := uploader.batchUpload(ctx, datas, opts)
+	admission.done(ctx, txHash, err)
+	entry.done(ctx, int64(size), []common.Hash{txHash}, dataRoots, err)
+
+	return txHash, dataRoots, err
+}
+
+// batchUpload submits multiple data in single on-chain transaction with the normalized option, then transfers
+// the data to the storage nodes.
+func (uploader *Uploader) batchUpload(ctx context.Context, datas []core.IterableData, opts BatchUploadOption) (common.Hash, []common.Hash, error) {
+	stageTimer := time.Now()
+
+	n := len(datas)
 	uploader.logger.WithFields(logrus.Fields{
 		"dataNum": n,
 	}).Info("Prepare to upload batchly")
 
+	trace := dirUploadTraceFromContext(ctx)
+
+	// Fail fast before hashing data
+	var preflightDatas []core.IterableData
+	if !opts.SkipPreflight {
+		if err := uploader.checkFileSize(ctx, datas); err != nil {
+			return common.Hash{}, nil, err
+		}
+
+		for i, opt := range opts.DataOptions {
+			if !opt.SkipTx {
+				preflightDatas = append(preflightDatas, datas[i])
+			}
+		}
+		if len(preflightDatas) > 0 {
+			if err := uploader.checkBalance(ctx, preflightDatas, opts.Fee, opts.Nonce); err != nil {
+				return common.Hash{}, nil, err
+			}
+		}
+	}
+
 	trees := make([]*merkle.Tree, n)
 	toSubmitDatas := make([]core.IterableData, 0)
 	toSubmitTags := make([][]byte, 0)
@@ -260,7 +663,9 @@ func (uploader *Uploader) BatchUpload(ctx context.Context, datas []core.Iterable
 			}).Info("Data prepared to upload")
 
 			// Calculate file merkle root.
-			tree, err := core.MerkleTree(data)
+			done := trace.begin(dirPhaseHashing)
+			tree, err := core.MerkleTree(data, uploader.hash)
+			done()
 			if err != nil {
 				errs <- errors.WithMessage(err, "Failed to create data merkle tree")
 				return
@@ -276,6 +681,7 @@ func (uploader *Uploader) BatchUpload(ctx context.Context, datas []core.Iterable
 				return
 			}
 			fileInfos[i] = info
+			trace.observe(tree.Root(), info, opts.DataOptions[i].SkipTx)
 		}(i)
 		if (i+1)%int(opts.TaskSize) == 0 || i == n-1 {
 			wg.Wait()
@@ -288,12 +694,18 @@ func (uploader *Uploader) BatchUpload(ctx context.Context, datas []core.Iterable
 			errs = make(chan error, opts.TaskSize)
 		}
 	}
+	progresses := make([]*progressTracker, n)
+	var submitProgresses []*progressTracker
 	for i := 0; i < n; i += 1 {
+		progresses[i] = newProgressTracker(uploader.onProgress, trees[i].Root())
 		opt := opts.DataOptions[i]
 		if !opt.SkipTx || fileInfos[i] == nil {
+			// file info of the new log entry is retrieved once submitted, as Upload does
+			fileInfos[i] = nil
 			toSubmitDatas = append(toSubmitDatas, datas[i])
 			toSubmitTags = append(toSubmitTags, opt.Tags)
 			lastTreeToSubmit = trees[i]
+			submitProgresses = append(submitProgresses, progresses[i])
 		}
 	}
 
@@ -301,12 +713,29 @@ func (uploader *Uploader) BatchUpload(ctx context.Context, datas []core.Iterable
 	var txHash common.Hash
 	var receipt *types.Receipt
 	if len(toSubmitDatas) > 0 {
+		// transaction of data with SkipTx not checked before
+		if !opts.SkipPreflight && len(toSubmitDatas) > len(preflightDatas) {
+			if err := uploader.checkBalance(ctx, toSubmitDatas, opts.Fee, opts.Nonce); err != nil {
+				return common.Hash{}, dataRoots, err
+			}
+		}
+
+		for _, progress := range submitProgresses {
+			progress.phase(ProgressSubmitting)
+		}
+		done := trace.begin(dirPhaseSubmission)
 		var err error
 		if txHash, receipt, err = uploader.SubmitLogEntry(ctx, toSubmitDatas, toSubmitTags, opts.Nonce, opts.Fee); err != nil {
-			return txHash, nil, errors.WithMessage(err, "Failed to submit log entry")
+			done()
+			return txHash, nil, zg_common.ClassifyError(errors.WithMessage(err, "Failed to submit log entry"), zg_common.ErrorClassTransaction)
 		}
 		// Wait for storage node to retrieve log entry from blockchain
-		if _, err := uploader.waitForLogEntry(ctx, lastTreeToSubmit.Root(), TransactionPacked, receipt); err != nil {
+		for _, progress := range submitProgresses {
+			progress.phase(ProgressWaitingLogEntry)
+		}
+		_, err = uploader.waitForLogEntry(ctx, lastTreeToSubmit.Root(), TransactionPacked, receipt)
+		done()
+		if err != nil {
 			return txHash, nil, errors.WithMessage(err, "Failed to check if log entry available on storage node")
 		}
 	}
@@ -325,16 +754,29 @@ func (uploader *Uploader) BatchUpload(ctx context.Context, datas []core.Iterable
 				}
 			}
 			// Upload file to storage node
-			if err := uploader.uploadFile(ctx, info, datas[i], trees[i], opts.DataOptions[i].ExpectedReplica, opts.DataOptions[i].TaskSize); err != nil {
+			done := trace.begin(dirPhasePushing)
+			handle, err := uploader.uploadFile(withProgressTracker(ctx, progresses[i]), info, datas[i], trees[i], opts.DataOptions[i])
+			done()
+			if err != nil {
 				errs <- errors.WithMessage(err, "Failed to upload file")
 				return
 			}
 
 			// Wait for transaction finality
-			if _, err := uploader.waitForLogEntry(ctx, trees[i].Root(), opts.DataOptions[i].FinalityRequired, receipt); err != nil {
+			progresses[i].phase(ProgressFinalizing)
+			done = trace.begin(dirPhaseFinalization)
+			uploaded := handle.uploadedClients(uploader.clients)
+			_, err = uploader.waitForLogEntryOn(ctx, uploaded, trees[i].Root(), opts.DataOptions[i].FinalityRequired, receipt)
+			done()
+			if err != nil {
 				errs <- errors.WithMessage(err, "Failed to wait for transaction finality on storage node")
 				return
 			}
+			if err = uploader.checkRetention(ctx, uploaded, trees[i].Root(), opts.DataOptions[i]); err != nil {
+				errs <- err
+				return
+			}
+			progresses[i].finish()
 			errs <- nil
 		}(i)
 		if (i+1)%int(opts.TaskSize) == 0 || i == n-1 {
@@ -356,127 +798,865 @@ func (uploader *Uploader) BatchUpload(ctx context.Context, datas []core.Iterable
 
 // Upload submit data to 0g storage contract, then transfer the data to the storage nodes.
 // returns the submission transaction hash and the hash will be zero if transaction is skipped.
+//
+// If MinReplica of option specified, it succeeds once MinReplica reached, but still returns after the remaining
+// replicas uploaded in background, since they read data, so that data is never read once returned and could be
+// closed by caller. The remaining replicas are cancelled if ctx done in the meantime. Use UploadWithHandle to
+// return once MinReplica reached instead.
 func (uploader *Uploader) Upload(ctx context.Context, data core.IterableData, option ...UploadOption) (common.Hash, common.Hash, error) {
-	stageTimer := time.Now()
+	txHash, root, handle, err := uploader.UploadWithHandle(ctx, data, option...)
+	if handle != nil {
+		select {
+		case <-handle.Done():
+		case <-ctx.Done():
+			handle.Cancel()
+			<-handle.Done()
+		}
+	}
+
+	return txHash, root, err
+}
+
+// UploadWithHandle is the same as Upload, but returns once MinReplica of option reached, along with a handle to
+// wait for the remaining replicas uploaded in background and report the outcome of each storage node.
+//
+// Note, the upload takes ownership of data until the handle done, i.e. data is still read by the background
+// replicas after returned, so caller should keep data readable, e.g. file not closed, until the handle done, or
+// cancel the handle and wait for it done before releasing data.
+//
+// If MinReplica not specified, the handle is already completed once uploaded. The handle is nil if the upload is
+// coalesced with another in-flight upload of the same data.
+func (uploader *Uploader) UploadWithHandle(ctx context.Context, data core.IterableData, option ...UploadOption) (common.Hash, common.Hash, *ReplicaHandle, error) {
+	if err := uploader.acquire(); err != nil {
+		return common.Hash{}, common.Hash{}, nil, err
+	}
+	defer uploader.release()
 
 	var opt UploadOption
 	if len(option) > 0 {
 		opt = option[0]
+	} else if uploader.profile != nil {
+		opt = uploader.profile.Upload
 	}
 
-	uploader.logger.WithFields(logrus.Fields{
+	if err := opt.Validate(); err != nil {
+		return common.Hash{}, common.Hash{}, nil, err
+	}
+
+	if err := uploader.resolveRetention(&opt); err != nil {
+		return common.Hash{}, common.Hash{}, nil, err
+	}
+
+	opt.Tenant = tenantOf(ctx, opt.Tenant)
+	ctx, entry := uploader.beginRecord(ctx, LedgerKindFile, opt.Tenant)
+	admission, err := uploader.tenants.admit(ctx, opt.Tenant, uint64(data.Size()), uploader.logger)
+	if err != nil {
+		entry.done(ctx, data.Size(), nil, nil, err)
+		return common.Hash{}, common.Hash{}, nil, err
+	}
+
+	ctx, span := startSpan(uploader.tracer, ctx, TracePhaseUpload, TraceAttribute{TraceAttrSize, data.Size()})
+	txHash, root, handle, err := uploader.uploadWithHandle(ctx, data, opt)
+	span.SetAttributes(TraceAttribute{TraceAttrRoot, root.Hex()})
+	span.End(err)
+
+	admission.doneWithReplicas(ctx, txHash, err, handle)
+	entry.done(ctx, data.Size(), []common.Hash{txHash}, []common.Hash{root}, err)
+
+	if err == nil && handle != nil {
+		uploader.recordHints(root, handle)
+	}
+
+	return txHash, root, handle, err
+}
+
+// WithUploadHints sets the hint store to record the storage nodes that uploaded files, so that files could be
+// downloaded right after uploaded from those storage nodes, see UploadHints. Passes nil to disable, which is default.
+func (uploader *Uploader) WithUploadHints(hints *UploadHints) *Uploader {
+	uploader.hints = hints
+	return uploader
+}
+
+// recordHints records the storage nodes uploaded so far, while the others uploading in background are not.
+func (uploader *Uploader) recordHints(root common.Hash, handle *ReplicaHandle) {
+	var urls []string
+	for _, outcome := range handle.Outcomes() {
+		if outcome.Status == ReplicaUploaded {
+			urls = append(urls, outcome.Node)
+		}
+	}
+
+	uploader.hints.Record(root, urls...)
+}
+
+// uploadWithHandle calculates the merkle tree of data, and uploads with the validated option.
+func (uploader *Uploader) uploadWithHandle(ctx context.Context, data core.IterableData, opt UploadOption) (common.Hash, common.Hash, *ReplicaHandle, error) {
+	stageTimer := time.Now()
+
+	fields := logrus.Fields{
 		"size":     data.Size(),
 		"chunks":   data.NumChunks(),
 		"segments": data.NumSegments(),
-	}).Info("Data prepared to upload")
+		"sectors":  core.StorageFootprint(data.Size()).Sectors,
+	}
+	if uploader.profile != nil {
+		fields["profile"] = uploader.profile.Name
+	}
+	if len(opt.Tenant) > 0 {
+		fields["tenant"] = opt.Tenant
+	}
+	uploader.logger.WithFields(fields).Info("Data prepared to upload")
+
+	// Fail fast before hashing data, and the balance is checked before submission if transaction may be skipped
+	if !opt.SkipPreflight {
+		if err := uploader.checkFileSize(ctx, []core.IterableData{data}); err != nil {
+			return common.Hash{}, common.Hash{}, nil, err
+		}
+
+		if !opt.SkipTx {
+			if err := uploader.checkBalance(ctx, []core.IterableData{data}, opt.Fee, opt.Nonce); err != nil {
+				return common.Hash{}, common.Hash{}, nil, err
+			}
+		}
+	}
 
 	// Calculate file merkle root.
-	tree, err := core.MerkleTree(data)
+	done := dirUploadTraceFromContext(ctx).begin(dirPhaseHashing)
+	_, span := startSpan(uploader.tracer, ctx, TracePhaseHash)
+	tree, err := core.MerkleTree(data, uploader.hash)
+	span.End(err)
+	done()
 	if err != nil {
-		return common.Hash{}, common.Hash{}, errors.WithMessage(err, "Failed to create data merkle tree")
+		return common.Hash{}, common.Hash{}, nil, errors.WithMessage(err, "Failed to create data merkle tree")
 	}
 	uploader.logger.WithField("root", tree.Root()).Info("Data merkle root calculated")
 
+	var txHash common.Hash
+	var handle *ReplicaHandle
+	if uploader.flights != nil {
+		// upload in another goroutine, which may complete after ctx done
+		var shared atomic.Pointer[ReplicaHandle]
+		txHash, err = uploader.flights.Do(ctx, uploadFlightKey(tree.Root(), opt), data, func(ctx context.Context, data core.IterableData) (common.Hash, error) {
+			txHash, handle, err := uploader.upload(ctx, data, tree, opt)
+			shared.Store(handle)
+			return txHash, err
+		})
+		handle = shared.Load()
+	} else {
+		txHash, handle, err = uploader.upload(ctx, data, tree, opt)
+	}
+	if err != nil {
+		return txHash, tree.Root(), handle, err
+	}
+
+	uploader.logger.WithField("duration", time.Since(stageTimer)).Info("upload took")
+
+	return txHash, tree.Root(), handle, nil
+}
+
+// upload submits the data with calculated merkle tree to 0g storage contract, then transfers the data to the storage nodes.
+func (uploader *Uploader) upload(ctx context.Context, data core.IterableData, tree *merkle.Tree, opt UploadOption) (common.Hash, *ReplicaHandle, error) {
+	if opt.Priority != PriorityNormal {
+		ctx = WithPriority(ctx, opt.Priority)
+	}
+
+	trace := dirUploadTraceFromContext(ctx)
+
 	// Check existance
 	info, err := checkLogExistance(ctx, uploader.clients, tree.Root())
 	if err != nil {
-		return common.Hash{}, tree.Root(), errors.WithMessage(err, "Failed to check if skipped log entry available on storage node")
+		return common.Hash{}, nil, errors.WithMessage(err, "Failed to check if skipped log entry available on storage node")
 	}
+	trace.observe(tree.Root(), info, opt.SkipTx)
+
+	// Resume from checkpoint if specified, and transaction is skipped if submitted before interruption
+	checkpoint, err := openUploadCheckpoint(opt.ResumeFile, tree.Root(), data.Size(), data.NumSegments(), uploader.logger)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	if checkpoint != nil {
+		zg_common.UseFeature(ctx, zg_common.FeatureResume)
+	}
+
+	progress := newProgressTracker(uploader.onProgress, tree.Root())
+	ctx = withProgressTracker(ctx, progress)
+
 	txHash := common.Hash{}
 	// Append log on blockchain
-	if !opt.SkipTx || info == nil {
+	if (!opt.SkipTx && !checkpoint.submitted()) || info == nil {
 		var receipt *types.Receipt
 
-		txHash, receipt, err = uploader.SubmitLogEntry(ctx, []core.IterableData{data}, [][]byte{opt.Tags}, opt.Nonce, opt.Fee)
+		if opt.SkipTx && !opt.SkipPreflight {
+			if err = uploader.checkBalance(ctx, []core.IterableData{data}, opt.Fee, opt.Nonce); err != nil {
+				return common.Hash{}, nil, err
+			}
+		}
+
+		progress.phase(ProgressSubmitting)
+		done := trace.begin(dirPhaseSubmission)
+		spanCtx, span := startSpan(uploader.tracer, ctx, TracePhaseSubmit)
+		txHash, receipt, err = uploader.SubmitLogEntry(spanCtx, []core.IterableData{data}, [][]byte{opt.Tags}, opt.Nonce, opt.Fee)
+		span.SetAttributes(TraceAttribute{TraceAttrTxHash, txHash.Hex()})
+		span.End(err)
 		if err != nil {
-			return txHash, tree.Root(), errors.WithMessage(err, "Failed to submit log entry")
+			done()
+			return txHash, nil, zg_common.ClassifyError(errors.WithMessage(err, "Failed to submit log entry"), zg_common.ErrorClassTransaction)
 		}
 
 		// Wait for storage node to retrieve log entry from blockchain
-		info, err = uploader.waitForLogEntry(ctx, tree.Root(), TransactionPacked, receipt)
+		progress.phase(ProgressWaitingLogEntry)
+		spanCtx, span = startSpan(uploader.tracer, ctx, TracePhaseEntryWait, TraceAttribute{TraceAttrTxHash, txHash.Hex()})
+		info, err = uploader.waitForLogEntry(spanCtx, tree.Root(), TransactionPacked, receipt)
+		if info != nil {
+			span.SetAttributes(TraceAttribute{TraceAttrTxSeq, info.Tx.Seq})
+		}
+		span.End(err)
+		done()
 		if err != nil {
-			return txHash, tree.Root(), errors.WithMessage(err, "Failed to check if log entry available on storage node")
+			return txHash, nil, errors.WithMessage(err, "Failed to check if log entry available on storage node")
 		}
 	}
+
 	// Upload file to storage node
-	if err := uploader.uploadFile(ctx, info, data, tree, opt.ExpectedReplica, opt.TaskSize); err != nil {
-		return txHash, tree.Root(), errors.WithMessage(err, "Failed to upload file")
-	}
+	if !opt.partialReplica() {
+		if err = checkpoint.begin(info.Tx.Seq); err != nil {
+			return txHash, nil, err
+		}
 
-	// Wait for transaction finality
-	if _, err = uploader.waitForLogEntry(ctx, tree.Root(), opt.FinalityRequired, nil); err != nil {
-		return txHash, tree.Root(), errors.WithMessage(err, "Failed to wait for transaction finality on storage node")
-	}
+		done := trace.begin(dirPhasePushing)
+		spanCtx, span := startSpan(uploader.tracer, ctx, TracePhasePush, uploader.traceNodes()...)
+		handle, err := uploader.uploadFile(withUploadCheckpoint(spanCtx, checkpoint), info, data, tree, opt)
+		span.End(err)
+		done()
+		if syncErr := checkpoint.sync(); syncErr != nil {
+			uploader.logger.WithError(syncErr).WithField("path", opt.ResumeFile).Warn("Failed to write upload checkpoint")
+		}
+		if err != nil {
+			return txHash, nil, errors.WithMessage(err, "Failed to upload file")
+		}
 
-	uploader.logger.WithField("duration", time.Since(stageTimer)).Info("upload took")
+		// Wait for transaction finality
+		progress.phase(ProgressFinalizing)
+		done = trace.begin(dirPhaseFinalization)
+		spanCtx, span = startSpan(uploader.tracer, ctx, TracePhaseFinality, TraceAttribute{TraceAttrTxHash, txHash.Hex()})
+		// storage nodes failed are skipped if the others met the replica requirement
+		uploaded := handle.uploadedClients(uploader.clients)
+		_, err = uploader.waitForLogEntryOn(spanCtx, uploaded, tree.Root(), opt.FinalityRequired, nil)
+		span.End(err)
+		done()
+		if err != nil {
+			return txHash, handle, errors.WithMessage(err, "Failed to wait for transaction finality on storage node")
+		}
 
-	return txHash, tree.Root(), nil
-}
+		if err = checkpoint.remove(); err != nil {
+			uploader.logger.WithError(err).WithField("path", opt.ResumeFile).Warn("Failed to remove upload checkpoint")
+		}
 
-func (uploader *Uploader) UploadDir(ctx context.Context, folder string, option ...UploadOption) (txnHash, rootHash common.Hash, _ error) {
-	// Build the file tree representation of the directory.
-	root, err := dir.BuildFileTree(folder)
-	if err != nil {
-		return txnHash, rootHash, errors.WithMessage(err, "failed to build file tree")
+		if err = uploader.checkRetention(ctx, uploaded, tree.Root(), opt); err != nil {
+			return txHash, handle, err
+		}
+
+		if err = uploader.verifyUpload(ctx, uploaded, tree.Root(), opt); err != nil {
+			return txHash, handle, err
+		}
+
+		progress.finish()
+
+		return txHash, handle, nil
 	}
 
-	tdata, err := root.MarshalBinary()
+	done := trace.begin(dirPhasePushing)
+	spanCtx, span := startSpan(uploader.tracer, ctx, TracePhasePush, uploader.traceNodes()...)
+	handle, err := uploader.uploadReplicas(spanCtx, info, data, tree, opt)
+	span.End(err)
+	done()
 	if err != nil {
-		return txnHash, rootHash, errors.WithMessage(err, "failed to encode file tree")
+		return txHash, handle, errors.WithMessage(err, "Failed to upload file")
 	}
 
-	// Create an in-memory data object from the encoded file tree.
-	iterdata, err := core.NewDataInMemory(tdata)
+	// Wait for transaction finality on storage nodes that uploaded, since others may still be in progress
+	progress.phase(ProgressFinalizing)
+	done = trace.begin(dirPhaseFinalization)
+	spanCtx, span = startSpan(uploader.tracer, ctx, TracePhaseFinality, TraceAttribute{TraceAttrTxHash, txHash.Hex()})
+	uploaded := handle.uploadedClients(uploader.clients)
+	_, err = uploader.waitForLogEntryOn(spanCtx, uploaded, tree.Root(), opt.FinalityRequired, nil)
+	span.End(err)
+	done()
 	if err != nil {
-		return txnHash, rootHash, errors.WithMessage(err, "failed to create `IterableData` in memory")
+		return txHash, handle, errors.WithMessage(err, "Failed to wait for transaction finality on storage node")
 	}
 
-	// Generate the Merkle tree from the in-memory data.
-	mtree, err := core.MerkleTree(iterdata)
-	if err != nil {
-		return txnHash, rootHash, errors.WithMessage(err, "failed to create merkle tree")
+	if err = uploader.checkRetention(ctx, uploaded, tree.Root(), opt); err != nil {
+		return txHash, handle, err
 	}
-	rootHash = mtree.Root()
 
-	// Flattening the file tree to get the list of files and their relative paths.
-	_, relPaths := root.Flatten(func(n *dir.FsNode) bool {
-		return n.Type == dir.FileTypeFile && n.Size > 0
-	})
+	if err = uploader.verifyUpload(ctx, uploaded, tree.Root(), opt); err != nil {
+		return txHash, handle, err
+	}
 
-	logrus.Infof("Total %d files to be uploaded", len(relPaths))
+	progress.finish()
 
-	// Upload each file to the storage network.
-	for i := range relPaths {
-		path := filepath.Join(folder, relPaths[i])
-		txhash, _, err := uploader.UploadFile(ctx, path, option...)
+	return txHash, handle, nil
+}
+
+// UploadDir uploads files in the folder separately, and then the directory metadata. Returns the transaction hash
+// and storage root of the directory metadata.
+func (uploader *Uploader) UploadDir(ctx context.Context, folder string, option ...UploadOption) (txnHash, rootHash common.Hash, _ error) {
+	summary, err := uploader.UploadDirWithSummary(ctx, folder, option...)
+	return summary.TxHash, summary.Root, err
+}
+
+// UploadDirWithSummary is the same as UploadDir, but returns the summary of how files are uploaded, which is
+// always returned even if failed. If any file failed to upload, the directory metadata is not uploaded, and the
+// other files not uploaded yet are not uploaded either unless WithContinueOnFileError enabled.
+func (uploader *Uploader) UploadDirWithSummary(ctx context.Context, folder string, option ...UploadOption) (*DirUploadSummary, error) {
+	start := time.Now()
+	trace := &dirUploadTrace{}
+	ctx = zg_common.WithFeatureTracking(withDirUploadTrace(ctx, trace))
+
+	summary := DirUploadSummary{Files: []DirFileResult{}}
+	defer func() {
+		summary.Phases = trace.phases()
+		summary.Elapsed = time.Since(start)
+		summary.Agent = zg_common.CurrentClient(zg_common.FeaturesInUse(ctx)...)
+	}()
+
+	var tenant string
+	if len(option) > 0 {
+		tenant = option[0].Tenant
+	}
+	ctx, entry := uploader.beginRecord(withLedgerPathsOnce(ctx, folder), LedgerKindDir, tenant)
+
+	var err error
+	summary.TxHash, summary.Root, err = uploader.uploadDir(ctx, folder, &summary, option...)
+
+	var size int64
+	for _, file := range summary.Files {
+		size += file.Size
+	}
+	entry.done(ctx, size, []common.Hash{summary.TxHash}, []common.Hash{summary.Root}, err)
+
+	return &summary, err
+}
+
+// uploadDir uploads the directory, and adds the outcome of files to summary.
+func (uploader *Uploader) uploadDir(ctx context.Context, folder string, summary *DirUploadSummary, option ...UploadOption) (txnHash, rootHash common.Hash, _ error) {
+	// Build the file tree representation of the directory.
+	done := dirUploadTraceFromContext(ctx).begin(dirPhaseHashing)
+	defer done()
+
+	opt := uploader.buildOption()
+	onSpecialFileSkipped := opt.OnSpecialFileSkipped
+	opt.OnSpecialFileSkipped = func(path, fileType string) {
+		onSpecialFileSkipped(path, fileType)
+		summary.Excluded = append(summary.Excluded, DirExcludedFile{Path: path, Type: fileType})
+	}
+
+	root, err := dir.BuildFileTreeWithOption(folder, opt)
+	if err != nil {
+		return txnHash, rootHash, errors.WithMessage(err, "failed to build file tree")
+	}
+
+	// Embed small files in the manifest if enabled, which need not be uploaded separately.
+	embedded, err := dir.EmbedSmallFiles(root, folder, uploader.embed)
+	if err != nil {
+		return txnHash, rootHash, errors.WithMessage(err, "failed to embed small files")
+	}
+
+	if embedded.Files > 0 {
+		uploader.logger.WithFields(logrus.Fields{
+			"files": embedded.Files,
+			"bytes": embedded.Bytes,
+		}).Info("Small files embedded in directory metadata")
+	}
+
+	iterdata, rootHash, err := uploader.encodeManifest(root)
+	if err != nil {
+		return txnHash, rootHash, err
+	}
+
+	chunkSize, err := uploader.checkManifestSize(ctx, iterdata, uploader.manifestChunking)
+	if err != nil {
+		return txnHash, rootHash, err
+	}
+	done()
+
+	// Flattening the file tree to get the list of files and their relative paths.
+	nodes, relPaths := root.Flatten(func(n *dir.FsNode) bool {
+		return n.Type == dir.FileTypeFile && n.Size > 0 && !n.Embedded()
+	})
+
+	root.ComputeAggregates()
+	summary.Footprint, summary.Footprints = root.Footprint(), root.TopLevelFootprints()
+	uploader.logger.WithFields(logrus.Fields{
+		"totalSize": root.TotalSize,
+		"footprint": summary.Footprint,
+	}).Infof("Total %d files to be uploaded", len(relPaths))
+
+	// Upload each file to the storage network, or in batches if specified by profile.
+	results, err := uploader.uploadTreeFiles(ctx, folder, nodes, relPaths, option...)
+	for _, result := range results {
+		summary.add(result)
+	}
+	if err != nil {
+		return txnHash, rootHash, err
+	}
+
+	// Finally, upload the directory metadata, in chunks if too large
+	return uploader.uploadManifest(ctx, iterdata, rootHash, chunkSize, nil, summary, option...)
+}
+
+// uploadManifest uploads the directory manifest of root as a single file if chunkSize is 0. Otherwise, the manifest
+// is uploaded in chunks of at most chunkSize along with the manifest index, and returns the storage root of manifest
+// index instead, from which the directory is downloaded, see dir.ManifestIndex. Chunks of which the roots are
+// reused, e.g. unchanged chunks of the original manifest to patch, are not uploaded again if finalized on storage
+// nodes. Roots of chunks are added to summary if not nil.
+func (uploader *Uploader) uploadManifest(ctx context.Context, manifest core.IterableData, root common.Hash, chunkSize int64, reused map[common.Hash]bool, summary *DirUploadSummary, option ...UploadOption) (common.Hash, common.Hash, error) {
+	if chunkSize == 0 {
+		txHash, _, err := uploader.Upload(ctx, manifest, option...)
+		if err != nil {
+			err = errors.WithMessage(err, "failed to upload directory metadata")
+		}
+
+		return txHash, root, err
+	}
+
+	zg_common.UseFeature(ctx, zg_common.FeatureChunkedManifest)
+
+	chunks, chunkRoots, err := splitManifest(manifest, chunkSize)
+	if err != nil {
+		return common.Hash{}, root, err
+	}
+
+	var fragments []core.IterableData
+	for i, chunk := range chunks {
+		if reused[chunkRoots[i]] {
+			info, err := checkLogExistance(ctx, uploader.clients, chunkRoots[i])
+			if err != nil {
+				return common.Hash{}, root, errors.WithMessage(err, "failed to check chunk of directory metadata")
+			}
+
+			if info != nil && info.Finalized {
+				continue
+			}
+		}
+
+		fragments = append(fragments, chunk)
+	}
+
+	uploader.logger.WithFields(logrus.Fields{
+		"chunks": len(chunks),
+		"reused": len(chunks) - len(fragments),
+	}).Info("Directory metadata split into chunks")
+
+	if len(fragments) > 0 {
+		if _, _, err = uploader.uploadFragments(ctx, fragments, option...); err != nil {
+			return common.Hash{}, root, errors.WithMessage(err, "failed to upload chunked directory metadata")
+		}
+	}
+
+	if summary != nil {
+		summary.ManifestChunks = chunkRoots
+	}
+
+	index := dir.ManifestIndex{Root: root, Size: manifest.Size(), Chunks: chunkRoots}
+	content, err := index.MarshalBinary()
+	if err != nil {
+		return common.Hash{}, root, err
+	}
+
+	data, err := core.NewDataInMemory(content)
+	if err != nil {
+		return common.Hash{}, root, errors.WithMessage(err, "failed to create `IterableData` in memory")
+	}
+
+	txHash, indexRoot, err := uploader.Upload(ctx, data, option...)
+	if err != nil {
+		return txHash, root, errors.WithMessage(err, "failed to upload directory manifest index")
+	}
+
+	return txHash, indexRoot, nil
+}
+
+// buildOption returns the option to build the file tree of directory to upload.
+func (uploader *Uploader) buildOption() dir.BuildOption {
+	return dir.BuildOption{
+		Hash:         uploader.hash,
+		NameEncoding: uploader.names,
+		Workers:      uploader.workers,
+		Ignore:       uploader.ignore,
+		Symlinks:     uploader.symlinks,
+		Metadata:     uploader.metadata,
+		OnNameReplaced: func(path string) {
+			uploader.warnings.Add(WarningNameReplaced, "File name is not valid UTF-8, and replaced", map[string]interface{}{
+				"path": fmt.Sprintf("%q", path),
+			})
+		},
+		OnSymlinkSkipped: func(path string, err error) {
+			uploader.warnings.Add(WarningSymlinkSkipped, "Symbolic link skipped", map[string]interface{}{
+				"path":   fmt.Sprintf("%q", path),
+				"reason": err.Error(),
+			})
+		},
+		SpecialFiles: uploader.special,
+		OnSpecialFileSkipped: func(path, fileType string) {
+			uploader.warnings.Add(WarningSpecialFileSkipped, "Special file skipped", map[string]interface{}{
+				"path": fmt.Sprintf("%q", path),
+				"type": fileType,
+			})
+		},
+		PortableNames: uploader.portable,
+		OnNonPortableName: func(issue dir.NameIssue) {
+			uploader.warnings.Add(WarningNameNotPortable, "File name not portable", map[string]interface{}{
+				"path":     fmt.Sprintf("%q", issue.Path),
+				"reason":   issue.Reason,
+				"conflict": issue.Conflict,
+			})
+		},
+	}
+}
+
+// UploadDirPatch patches the published directory with the specified operations, and uploads the new files put
+// by operations along with the patched directory metadata. Files are read from the local folder at the same
+// relative paths as in directory, and files of which the merkle root already exists in the original directory
+// are not uploaded again. Likewise, if the directory metadata is uploaded in chunks, see WithManifestChunking, only
+// the chunks changed by operations are uploaded, since the others are byte-identical to the chunks of the original
+// directory.
+//
+// Returns the transaction hash and storage root of the patched directory metadata, along with the patched
+// directory.
+func (uploader *Uploader) UploadDirPatch(ctx context.Context, base *dir.FsNode, folder string, ops []dir.PatchOp, option ...UploadOption) (txnHash, rootHash common.Hash, patched *dir.FsNode, err error) {
+	var tenant string
+	if len(option) > 0 {
+		tenant = option[0].Tenant
+	}
+	ctx, entry := uploader.beginRecord(withLedgerPathsOnce(ctx, folder), LedgerKindDir, tenant)
+
+	var size int64
+	defer func() {
+		entry.done(ctx, size, []common.Hash{txnHash}, []common.Hash{rootHash}, err)
+	}()
+
+	txnHash, rootHash, patched, size, err = uploader.uploadDirPatch(ctx, base, folder, ops, option...)
+
+	return txnHash, rootHash, patched, err
+}
+
+// uploadDirPatch uploads the patched directory, and returns the total size of files uploaded.
+func (uploader *Uploader) uploadDirPatch(ctx context.Context, base *dir.FsNode, folder string, ops []dir.PatchOp, option ...UploadOption) (txnHash, rootHash common.Hash, patched *dir.FsNode, size int64, _ error) {
+	patched, err := dir.Patch(base, ops)
+	if err != nil {
+		return txnHash, rootHash, nil, 0, errors.WithMessage(err, "failed to patch file tree")
+	}
+
+	iterdata, rootHash, err := uploader.encodeManifest(patched)
+	if err != nil {
+		return txnHash, rootHash, nil, 0, err
+	}
+
+	chunkSize, err := uploader.checkManifestSize(ctx, iterdata, uploader.manifestChunking)
+	if err != nil {
+		return txnHash, rootHash, nil, 0, err
+	}
+
+	// files already uploaded along with the original directory
+	uploaded := make(map[string]bool)
+	base.Traverse(func(node *dir.FsNode, _ string) error {
+		if node.Type == dir.FileTypeFile {
+			uploaded[node.Root] = true
+		}
+		return nil
+	})
+
+	// files put by operations, which are uploaded only once
+	var nodes []*dir.FsNode
+	var relPaths []string
+	for _, op := range ops {
+		if op.Type != dir.PatchOpPut {
+			continue
+		}
+
+		// entry may be removed by later operations
+		entry, err := patched.Locate(op.Path)
 		if err != nil {
-			return txnHash, rootHash, errors.WithMessagef(err, "failed to upload file %s", path)
+			continue
+		}
+
+		parent := path.Dir(path.Clean("/" + op.Path))
+		entry.Traverse(func(node *dir.FsNode, relPath string) error {
+			if node.Type == dir.FileTypeFile && node.Size > 0 && !node.Embedded() && !uploaded[node.Root] {
+				uploaded[node.Root] = true
+				nodes = append(nodes, node)
+				size += node.Size
+				relPaths = append(relPaths, path.Join(parent, relPath))
+			}
+			return nil
+		})
+	}
+
+	uploader.logger.WithFields(logrus.Fields{
+		"ops":   len(ops),
+		"files": len(relPaths),
+	}).Info("Directory patched to upload")
+
+	if _, err = uploader.uploadTreeFiles(ctx, folder, nodes, relPaths, option...); err != nil {
+		return txnHash, rootHash, patched, size, err
+	}
+
+	// unchanged chunks of the original directory metadata are reused
+	var reused map[common.Hash]bool
+	if chunkSize > 0 {
+		if reused, err = uploader.manifestChunkRoots(base, chunkSize); err != nil {
+			return txnHash, rootHash, patched, size, err
 		}
+	}
 
-		logrus.WithFields(logrus.Fields{
-			"txnHash": txhash,
-			"path":    path,
-		}).Info("File uploaded successfully")
+	txnHash, rootHash, err = uploader.uploadManifest(ctx, iterdata, rootHash, chunkSize, reused, nil, option...)
+
+	return txnHash, rootHash, patched, size, err
+}
+
+// encodeManifest encodes the file tree to upload as directory metadata, which is signed if signer specified,
+// and returns the storage root.
+func (uploader *Uploader) encodeManifest(root *dir.FsNode) (core.IterableData, common.Hash, error) {
+	var tdata []byte
+	var err error
+	if uploader.signer != nil {
+		tdata, err = dir.SignManifest(root, uploader.signer)
+	} else {
+		tdata, err = dir.CanonicalBytes(root)
+	}
+	if err != nil {
+		return nil, common.Hash{}, errors.WithMessage(err, "failed to encode file tree")
 	}
 
-	// Finally, upload the directory metadata
-	txnHash, _, err = uploader.Upload(ctx, iterdata, option...)
+	// Create an in-memory data object from the encoded file tree.
+	iterdata, err := core.NewDataInMemory(tdata)
+	if err != nil {
+		return nil, common.Hash{}, errors.WithMessage(err, "failed to create `IterableData` in memory")
+	}
+
+	manifestRoot, err := core.MerkleRootData(iterdata)
+	if err != nil {
+		return nil, common.Hash{}, errors.WithMessage(err, "failed to calculate merkle root")
+	}
+
+	return iterdata, manifestRoot, nil
+}
+
+// manifestUploader uploads directory metadata with the specified upload options.
+type manifestUploader struct {
+	uploader *Uploader
+	option   []UploadOption
+}
+
+// ManifestUploader returns a dir.ManifestUploader that uploads directory metadata only with the specified upload
+// options, e.g. to migrate published manifest by dir.Migrate.
+func (uploader *Uploader) ManifestUploader(option ...UploadOption) dir.ManifestUploader {
+	return &manifestUploader{uploader, option}
+}
+
+// UploadManifest implements the dir.ManifestUploader interface.
+func (m *manifestUploader) UploadManifest(ctx context.Context, root *dir.FsNode) (txHash, manifestRoot common.Hash, err error) {
+	iterdata, manifestRoot, err := m.uploader.encodeManifest(root)
+	if err != nil {
+		return txHash, manifestRoot, err
+	}
+
+	txHash, _, err = m.uploader.Upload(ctx, iterdata, m.option...)
 	if err != nil {
 		err = errors.WithMessage(err, "failed to upload directory metadata")
 	}
 
-	return txnHash, rootHash, err
+	return txHash, manifestRoot, err
+}
+
+// uploadTreeFiles uploads files of the specified relative paths in folder one by one, or in batches if specified
+// by profile. Files not uploaded yet are pending once any file failed, unless WithContinueOnFileError enabled, and
+// returns the outcome of each file along with the first error if any.
+func (uploader *Uploader) uploadTreeFiles(ctx context.Context, folder string, nodes []*dir.FsNode, relPaths []string, option ...UploadOption) ([]DirFileResult, error) {
+	trace := dirUploadTraceFromContext(ctx)
+	if trace == nil {
+		trace = &dirUploadTrace{}
+		ctx = withDirUploadTrace(ctx, trace)
+	}
+
+	results := make([]DirFileResult, len(relPaths))
+	for i := range relPaths {
+		results[i] = DirFileResult{
+			Path:      relPaths[i],
+			Root:      common.HexToHash(nodes[i].Root),
+			Size:      nodes[i].Size,
+			Footprint: core.StorageFootprint(nodes[i].Size),
+		}
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+	fail := func(results []DirFileResult, err error) {
+		for i := range results {
+			results[i].Status = DirFileFailed
+			results[i].Error = err.Error()
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	succeed := func(results []DirFileResult) {
+		for i := range results {
+			results[i].Status = trace.status(results[i].Root)
+			results[i].Sender = trace.sender(results[i].Root)
+		}
+	}
+
+	// files are uploaded one by one, or in batches if specified by profile
+	batchSize := 1
+	if uploader.profile != nil && uploader.profile.BatchSize > 1 {
+		batchSize = int(uploader.profile.BatchSize)
+	}
+
+	// batches are uploaded concurrently by accounts of sender pool if any
+	concurrency := 1
+	if uploader.senders != nil {
+		concurrency = max(1, uploader.senders.Len())
+	}
+
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for l := 0; l < len(relPaths); l += batchSize {
+		r := min(l+batchSize, len(relPaths))
+
+		slots <- struct{}{}
+		if !uploader.continueOnError && failed() {
+			<-slots
+			for i := l; i < len(relPaths); i++ {
+				results[i].Status = DirFilePending
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(l, r int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if batchSize > 1 {
+				if err := uploader.uploadFiles(ctx, folder, relPaths[l:r], option...); err != nil {
+					fail(results[l:r], err)
+				} else {
+					succeed(results[l:r])
+				}
+
+				return
+			}
+
+			path := filepath.Join(folder, relPaths[l])
+			txhash, _, err := uploader.UploadFile(ctx, path, option...)
+			if err != nil {
+				fail(results[l:r], errors.WithMessagef(err, "failed to upload file %s", path))
+				return
+			}
+
+			succeed(results[l:r])
+
+			uploader.logger.WithFields(logrus.Fields{
+				"txnHash": txhash,
+				"path":    path,
+				"status":  results[l].Status,
+			}).Info("File uploaded successfully")
+		}(l, r)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		var failed, pending int
+		for _, result := range results {
+			switch result.Status {
+			case DirFileFailed:
+				failed++
+			case DirFilePending:
+				pending++
+			}
+		}
+
+		if pending > 0 {
+			return results, errors.WithMessagef(firstErr, "failed to upload %v of %v files, and stopped before uploading the other %v files", failed, len(results), pending)
+		}
+
+		return results, errors.WithMessagef(firstErr, "failed to upload %v of %v files", failed, len(results))
+	}
+
+	return results, nil
+}
+
+// uploadFiles uploads files of the specified relative paths in a single transaction.
+func (uploader *Uploader) uploadFiles(ctx context.Context, folder string, relPaths []string, option ...UploadOption) error {
+	opt := uploader.profile.Upload
+	if len(option) > 0 {
+		opt = option[0]
+	}
+
+	opts := BatchUploadOption{
+		Fee:         opt.Fee,
+		Nonce:       opt.Nonce,
+		DataOptions: make([]UploadOption, 0, len(relPaths)),
+	}
+
+	datas := make([]core.IterableData, 0, len(relPaths))
+	for _, relPath := range relPaths {
+		path := filepath.Join(folder, relPath)
+		file, err := uploader.openFile(path)
+		if err != nil {
+			return errors.WithMessagef(err, "failed to open file %s", path)
+		}
+		defer file.Close()
+
+		datas = append(datas, file)
+		opts.DataOptions = append(opts.DataOptions, opt)
+	}
+
+	txhash, _, err := uploader.BatchUpload(ctx, datas, opts)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to upload files %v", relPaths)
+	}
+
+	uploader.logger.WithFields(logrus.Fields{
+		"txnHash": txhash,
+		"paths":   relPaths,
+	}).Info("Files uploaded successfully")
+
+	return nil
 }
 
 func (uploader *Uploader) UploadFile(ctx context.Context, path string, option ...UploadOption) (txnHash common.Hash, rootHash common.Hash, err error) {
-	file, err := core.Open(path)
+	file, err := uploader.openFile(path)
 	if err != nil {
 		err = errors.WithMessagef(err, "failed to open file %s", path)
 		return
 	}
 	defer file.Close()
 
-	return uploader.Upload(ctx, file, option...)
+	return uploader.Upload(withLedgerPathsOnce(ctx, path), file, option...)
+}
+
+// openFile opens the file on disk to upload, which is memory-mapped if enabled.
+func (uploader *Uploader) openFile(path string) (core.FileData, error) {
+	if uploader.mmap {
+		return core.NewDataInFileMmap(path)
+	}
+
+	return core.Open(path)
 }
 
 // SubmitLogEntry submit the data to 0g storage contract by sending a transaction
@@ -484,29 +1664,72 @@ func (uploader *Uploader) SubmitLogEntry(ctx context.Context, datas []core.Itera
 	// Construct submission
 	submissions := make([]contract.Submission, len(datas))
 	for i := 0; i < len(datas); i++ {
-		flow := core.NewFlow(datas[i], tags[i])
-		submission, err := flow.CreateSubmission()
+		submission, err := core.NewSubmission(datas[i], tags[i])
 		if err != nil {
 			return common.Hash{}, nil, errors.WithMessage(err, "Failed to create flow submission")
 		}
 		submissions[i] = *submission
 	}
 
+	if uploader.senders == nil || nonce != nil {
+		txHash, receipt, _, err := uploader.submitLogEntry(ctx, uploader.flow, submissions, nonce, fee)
+		return txHash, receipt, err
+	}
+
+	// spread across multiple accounts, and send by another account if any removed from rotation
+	for {
+		sender, flow, err := uploader.senders.acquire(uploader.flowAddress)
+		if err != nil {
+			return common.Hash{}, nil, err
+		}
+
+		txHash, receipt, value, err := uploader.submitLogEntry(ctx, flow, submissions, nil, fee)
+		if uploader.senders.release(sender, len(submissions), value, err) {
+			uploader.logger.WithError(err).WithField("sender", sender.stats.Address).Warn("Sender removed from rotation")
+			uploader.warnings.Add(WarningSenderRemoved, "Sender removed from rotation", map[string]interface{}{
+				"sender": sender.stats.Address,
+				"error":  err.Error(),
+			})
+			continue
+		}
+
+		if err == nil {
+			for _, submission := range submissions {
+				dirUploadTraceFromContext(ctx).sign(submission.Root(), sender.stats.Address)
+			}
+		}
+
+		return txHash, receipt, err
+	}
+}
+
+// submitLogEntry sends the transaction of submissions by the account of flow contract, and returns the storage
+// fee paid along with the receipt.
+func (uploader *Uploader) submitLogEntry(ctx context.Context, flow *contract.FlowContract, submissions []contract.Submission, nonce *big.Int, fee *big.Int) (common.Hash, *types.Receipt, *big.Int, error) {
 	// Submit log entry to smart contract.
-	opts, err := uploader.flow.CreateTransactOpts(ctx)
+	opts, err := flow.CreateTransactOpts(ctx)
 	if err != nil {
-		return common.Hash{}, nil, errors.WithMessage(err, "Failed to create opts to send transaction")
+		return common.Hash{}, nil, nil, errors.WithMessage(err, "Failed to create opts to send transaction")
 	}
 	if nonce != nil {
 		opts.Nonce = nonce
 	}
 
+	// Do not send transaction if aborted, e.g. client disconnected during data streaming
+	if err := ctx.Err(); err != nil {
+		return common.Hash{}, nil, nil, errors.WithMessage(err, "Aborted before sending transaction")
+	}
+
+	// pending nonce is assigned when sending transaction, so transactions of the same account are sent in sequence
+	unlock := lockSender(opts.From)
+	defer unlock()
+
 	var tx *types.Transaction
 	pricePerSector, err := uploader.market.PricePerSector(&bind.CallOpts{Context: ctx})
 	if err != nil {
-		return common.Hash{}, nil, errors.WithMessage(err, "Failed to read price per sector")
+		return common.Hash{}, nil, nil, errors.WithMessage(err, "Failed to read price per sector")
 	}
-	if len(datas) == 1 {
+	if len(submissions) == 1 {
 		if fee != nil {
 			opts.Value = fee
 		} else {
@@ -514,7 +1737,7 @@ func (uploader *Uploader) SubmitLogEntry(ctx context.Context, datas []core.Itera
 		}
 		uploader.logger.WithField("fee(neuron)", opts.Value).Info("submit with fee")
 		for attempt := 0; attempt < submitLogEntryRetries; attempt++ {
-			tx, err = uploader.flow.Submit(opts, submissions[0])
+			tx, err = flow.Submit(opts, submissions[0])
 			if err == nil || !isRetriableSubmitLogEntryError(err.Error()) || attempt >= submitLogEntryRetries-1 {
 				break
 			}
@@ -522,6 +1745,10 @@ func (uploader *Uploader) SubmitLogEntry(ctx context.Context, datas []core.Itera
 				"error":   err,
 				"attempt": attempt,
 			}).Warn("Failed to submit, retrying...")
+			uploader.warnings.Add(WarningSubmitRetried, "Failed to submit log entry, retrying", map[string]interface{}{
+				"error":   err.Error(),
+				"attempt": attempt,
+			})
 			time.Sleep(10 * time.Second)
 		}
 	} else {
@@ -535,7 +1762,7 @@ func (uploader *Uploader) SubmitLogEntry(ctx context.Context, datas []core.Itera
 		}
 		uploader.logger.WithField("fee(neuron)", opts.Value).Info("batch submit with fee")
 		for attempt := 0; attempt < submitLogEntryRetries; attempt++ {
-			tx, err = uploader.flow.BatchSubmit(opts, submissions)
+			tx, err = flow.BatchSubmit(opts, submissions)
 			if err == nil || !isRetriableSubmitLogEntryError(err.Error()) || attempt >= submitLogEntryRetries-1 {
 				break
 			}
@@ -543,22 +1770,45 @@ func (uploader *Uploader) SubmitLogEntry(ctx context.Context, datas []core.Itera
 				"error":   err,
 				"attempt": attempt,
 			}).Warn("Failed to submit, retrying...")
+			uploader.warnings.Add(WarningSubmitRetried, "Failed to submit log entry, retrying", map[string]interface{}{
+				"error":   err.Error(),
+				"attempt": attempt,
+			})
 			time.Sleep(10 * time.Second)
 		}
 	}
 	if err != nil {
-		return common.Hash{}, nil, errors.WithMessage(err, "Failed to send transaction to append log entry")
+		return common.Hash{}, nil, nil, errors.WithMessage(err, "Failed to send transaction to append log entry")
 	}
 
+	unlock()
 	uploader.logger.WithField("hash", tx.Hash().Hex()).Info("Succeeded to send transaction to append log entry")
 
 	// Wait for successful execution
-	receipt, err := uploader.flow.WaitForReceipt(ctx, tx.Hash(), true)
-	return tx.Hash(), receipt, err
+	receipt, err := flow.WaitForReceipt(ctx, tx.Hash(), true)
+	return tx.Hash(), receipt, opts.Value, err
+}
+
+// senderLocks holds a *sync.Mutex per account to send transactions in sequence.
+var senderLocks sync.Map
+
+// lockSender locks the specified account to send transactions, and returns the function to unlock, which could be
+// called more than once.
+func lockSender(sender common.Address) func() {
+	mu, _ := senderLocks.LoadOrStore(sender, new(sync.Mutex))
+	mu.(*sync.Mutex).Lock()
+
+	var once sync.Once
+	return func() { once.Do(mu.(*sync.Mutex).Unlock) }
 }
 
 // Wait for log entry ready on storage node.
 func (uploader *Uploader) waitForLogEntry(ctx context.Context, root common.Hash, finalityRequired FinalityRequirement, receipt *types.Receipt) (*node.FileInfo, error) {
+	return uploader.waitForLogEntryOn(ctx, uploader.clients, root, finalityRequired, receipt)
+}
+
+// waitForLogEntryOn waits for log entry ready on the specified storage nodes.
+func (uploader *Uploader) waitForLogEntryOn(ctx context.Context, clients []*node.ZgsClient, root common.Hash, finalityRequired FinalityRequirement, receipt *types.Receipt) (*node.FileInfo, error) {
 	uploader.logger.WithFields(logrus.Fields{
 		"root":     root,
 		"finality": finalityRequired,
@@ -573,7 +1823,7 @@ func (uploader *Uploader) waitForLogEntry(ctx context.Context, root common.Hash,
 		time.Sleep(time.Second)
 
 		ok := true
-		for _, client := range uploader.clients {
+		for _, client := range clients {
 			info, err = client.GetFileInfo(ctx, root)
 			if err != nil {
 				return nil, err
@@ -611,14 +1861,22 @@ func (uploader *Uploader) waitForLogEntry(ctx context.Context, root common.Hash,
 	return info, nil
 }
 
-func (uploader *Uploader) newSegmentUploader(ctx context.Context, info *node.FileInfo, data core.IterableData, tree *merkle.Tree, expectedReplica uint, taskSize uint) (*segmentUploader, error) {
-	shardConfigs, err := getShardConfigs(ctx, uploader.clients)
-	if err != nil {
-		return nil, err
-	}
-	if !shard.CheckReplica(shardConfigs, expectedReplica) {
-		return nil, fmt.Errorf("selected nodes cannot cover all shards")
+// newUploadTasks creates upload tasks of the specified storage node in flow segment range [start, end].
+func newUploadTasks(clientIndex int, shardConfig *shard.ShardConfig, startSegmentIndex, endSegmentIndex uint64, taskSize uint) []*uploadTask {
+	// segIndex % NumShard = shardId (in flow)
+	segIndex := shardConfig.NextSegmentIndex(startSegmentIndex)
+	tasks := make([]*uploadTask, 0)
+	for ; segIndex <= endSegmentIndex; segIndex += shardConfig.NumShard * uint64(taskSize) {
+		tasks = append(tasks, &uploadTask{
+			clientIndex: clientIndex,
+			segIndex:    segIndex - startSegmentIndex,
+			numShard:    shardConfig.NumShard,
+		})
 	}
+	return tasks
+}
+
+func (uploader *Uploader) newSegmentUploader(ctx context.Context, info *node.FileInfo, data core.IterableData, tree *merkle.Tree, shardConfigs []*shard.ShardConfig, taskSize uint) (*segmentUploader, error) {
 	// compute index in flow
 	startSegmentIndex, endSegmentIndex := core.SegmentRange(info.Tx.StartEntryIndex, info.Tx.Size)
 	clientTasks := make([][]*uploadTask, 0)
@@ -628,73 +1886,107 @@ func (uploader *Uploader) newSegmentUploader(ctx context.Context, info *node.Fil
 		if info != nil && info.Finalized {
 			continue
 		}
-		// create upload tasks
-		// segIndex % NumShard = shardId (in flow)
-		segIndex := shardConfig.NextSegmentIndex(startSegmentIndex)
-		tasks := make([]*uploadTask, 0)
-		for ; segIndex <= endSegmentIndex; segIndex += shardConfig.NumShard * uint64(taskSize) {
-			tasks = append(tasks, &uploadTask{
-				clientIndex: clientIndex,
-				segIndex:    segIndex - startSegmentIndex,
-				numShard:    shardConfig.NumShard,
-			})
-		}
-		clientTasks = append(clientTasks, tasks)
+		clientTasks = append(clientTasks, newUploadTasks(clientIndex, shardConfig, startSegmentIndex, endSegmentIndex, taskSize))
 	}
 	sort.SliceStable(clientTasks, func(i, j int) bool {
 		return len(clientTasks[i]) > len(clientTasks[j])
 	})
-	tasks := make([]*uploadTask, 0)
-	if len(clientTasks) > 0 {
-		for taskIndex := 0; taskIndex < len(clientTasks[0]); taskIndex += 1 {
-			for i := 0; i < len(clientTasks) && taskIndex < len(clientTasks[i]); i += 1 {
-				tasks = append(tasks, clientTasks[i][taskIndex])
-			}
-		}
-	}
+
+	// prefer less loaded nodes if storage nodes advertise load hints
+	loads := newNodeLoads(ctx, uploader.clients)
+	tasks := interleaveTasks(clientTasks, loads.utilizations())
 
 	return &segmentUploader{
-		data:     data,
-		tree:     tree,
-		txSeq:    info.Tx.Seq,
-		clients:  uploader.clients,
-		tasks:    tasks,
-		taskSize: taskSize,
-		logger:   uploader.logger,
+		data:       data,
+		tree:       tree,
+		txSeq:      info.Tx.Seq,
+		clients:    uploader.clients,
+		tasks:      tasks,
+		taskSize:   taskSize,
+		logger:     uploader.logger,
+		warnings:   uploader.warnings,
+		loads:      loads,
+		tracer:     uploader.tracer,
+		checkpoint: uploadCheckpointFromContext(ctx),
+		progress:   progressTrackerFromContext(ctx),
 	}, nil
 }
 
-func (uploader *Uploader) uploadFile(ctx context.Context, info *node.FileInfo, data core.IterableData, tree *merkle.Tree, expectedReplica uint, taskSize uint) error {
+// uploadFile uploads file to all storage nodes, and returns a completed handle to report the replicas achieved.
+// Segments are uploaded to storage nodes in parallel, and a storage node failed does not stop uploading to the
+// others. Once the others completed, the upload succeeds if the replica requirement is still met by the storage
+// nodes uploaded, otherwise NodeErrors is returned.
+func (uploader *Uploader) uploadFile(ctx context.Context, info *node.FileInfo, data core.IterableData, tree *merkle.Tree, opt UploadOption) (*ReplicaHandle, error) {
 	stageTimer := time.Now()
 
+	taskSize := opt.TaskSize
 	if taskSize == 0 {
 		taskSize = defaultTaskSize
 	}
+	requirement := opt.ReplicaRequirement()
 
 	uploader.logger.WithFields(logrus.Fields{
 		"segNum":  data.NumSegments(),
 		"nodeNum": len(uploader.clients),
 	}).Info("Begin to upload file")
 
-	segmentUploader, err := uploader.newSegmentUploader(ctx, info, data, tree, expectedReplica, taskSize)
+	shardConfigs, err := getShardConfigs(ctx, uploader.clients)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err = shard.CheckRequirement(shardConfigs, requirement); err != nil {
+		return nil, err
+	}
+
+	segmentUploader, err := uploader.newSegmentUploader(ctx, info, data, tree, shardConfigs, taskSize)
+	if err != nil {
+		return nil, err
 	}
+	segmentUploader.failures = &nodeFailures{}
+	segmentUploader.progress.begin(segmentUploader.numSegments())
 
-	opt := parallel.SerialOption{
-		Routines: uploader.routines,
+	serialOpt := parallel.SerialOption{
+		Routines: uploader.uploadRoutines(opt),
 	}
-	err = parallel.Serial(ctx, segmentUploader, len(segmentUploader.tasks), opt)
+	err = parallel.Serial(ctx, withPool(segmentUploader, uploader.pool), len(segmentUploader.tasks), serialOpt)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if err = segmentUploader.failures.err(); err != nil {
+		// succeed if the storage nodes uploaded still meet the replica requirement of each shard, and at least 1 replica
+		remaining := segmentUploader.failures.remaining(shardConfigs)
+		if shard.CheckRequirement(remaining, requirement.Merge(shard.UniformReplica(1))) != nil {
+			return nil, err
+		}
 
-	uploader.logger.WithFields(logrus.Fields{
+		uploader.warnings.Add(WarningNodeFailed, "Failed to upload segments to storage nodes, but replica requirement met by the others", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	fields := logrus.Fields{
 		"duration": time.Since(stageTimer),
 		"segNum":   data.NumSegments(),
-	}).Info("Completed to upload file")
+	}
+	if report := segmentUploader.loads.report(uploader.clients); report != nil {
+		fields["loadHints"] = report
+	}
+	if len(requirement.Shards) > 0 {
+		fields["achieved"] = requirement.Achieved(shardConfigs)
+	}
+	uploader.logger.WithFields(fields).Info("Completed to upload file")
 
-	return nil
+	return newCompletedReplicaHandle(uploader.clients, shardConfigs, requirement, segmentUploader.failures, time.Since(stageTimer)), nil
+}
+
+// uploadRoutines returns the number of segment upload tasks in parallel, which is the Routines of option if
+// specified, otherwise the routines of uploader.
+func (uploader *Uploader) uploadRoutines(opt UploadOption) int {
+	if opt.Routines > 0 {
+		return opt.Routines
+	}
+
+	return uploader.routines
 }
 
 // FileSegmentsWithProof wraps segments with proof and file info
@@ -704,19 +1996,40 @@ type FileSegmentsWithProof struct {
 }
 
 type FileSegmentUploader struct {
-	clients []*node.ZgsClient // 0g storage clients
-	logger  *logrus.Logger    // logger
+	clients  []*node.ZgsClient      // 0g storage clients
+	logger   *logrus.Logger         // logger
+	warnings *Warnings              // non-fatal issues during uploading
+	pool     *parallel.PriorityPool // shared pool to upload segments, nil if not specified
 }
 
 func NewFileSegementUploader(clients []*node.ZgsClient, opts ...zg_common.LogOption) *FileSegmentUploader {
 	return &FileSegmentUploader{
-		clients: clients,
-		logger:  zg_common.NewLogger(opts...),
+		clients:  clients,
+		logger:   zg_common.NewLogger(opts...),
+		warnings: NewWarnings(defaultMaxWarnings),
 	}
 }
 
+// WithPool sets the pool shared with other transfers to upload segments, so that uploads are scheduled by the
+// priority of context, see WithPriority.
+func (uploader *FileSegmentUploader) WithPool(pool *parallel.PriorityPool) *FileSegmentUploader {
+	uploader.pool = pool
+	return uploader
+}
+
+// WithWarningSink sets the callback to receive warnings as soon as they happen during uploading.
+func (uploader *FileSegmentUploader) WithWarningSink(sink WarningSink) *FileSegmentUploader {
+	uploader.warnings.SetSink(sink)
+	return uploader
+}
+
+// Warnings returns the non-fatal issues aggregated during uploading.
+func (uploader *FileSegmentUploader) Warnings() *Warnings {
+	return uploader.warnings
+}
+
 // Upload uploads file segments with proof to the storage nodes parallelly.
-// Note: only `ExpectedReplica` and `TaskSize` are used from UploadOption.
+// Note: only `ExpectedReplica`, `ShardReplicas` and `TaskSize` are used from UploadOption.
 func (uploader *FileSegmentUploader) Upload(ctx context.Context, fileSeg FileSegmentsWithProof, option ...UploadOption) error {
 	var opt UploadOption
 	if len(option) > 0 {
@@ -735,7 +2048,7 @@ func (uploader *FileSegmentUploader) Upload(ctx context.Context, fileSeg FileSeg
 		}).Debug("Begin to upload file segments with proof")
 	}
 
-	fsUploader, err := uploader.newFileSegmentUploader(ctx, fileSeg, opt.ExpectedReplica, opt.TaskSize)
+	fsUploader, err := uploader.newFileSegmentUploader(ctx, fileSeg, opt.ReplicaRequirement(), opt.TaskSize)
 	if err != nil {
 		return err
 	}
@@ -743,7 +2056,7 @@ func (uploader *FileSegmentUploader) Upload(ctx context.Context, fileSeg FileSeg
 	sopt := parallel.SerialOption{
 		Routines: min(runtime.GOMAXPROCS(0), len(uploader.clients)*5),
 	}
-	err = parallel.Serial(ctx, fsUploader, len(fsUploader.tasks), sopt)
+	err = parallel.Serial(ctx, withPool(fsUploader, uploader.pool), len(fsUploader.tasks), sopt)
 	if err != nil {
 		return err
 	}
@@ -759,7 +2072,7 @@ func (uploader *FileSegmentUploader) Upload(ctx context.Context, fileSeg FileSeg
 }
 
 func (uploader *FileSegmentUploader) newFileSegmentUploader(
-	ctx context.Context, fileSeg FileSegmentsWithProof, expectedReplica uint, taskSize uint) (*fileSegmentUploader, error) {
+	ctx context.Context, fileSeg FileSegmentsWithProof, requirement shard.ReplicaRequirement, taskSize uint) (*fileSegmentUploader, error) {
 
 	//  get shard configurations
 	shardConfigs, err := getShardConfigs(ctx, uploader.clients)
@@ -768,8 +2081,8 @@ func (uploader *FileSegmentUploader) newFileSegmentUploader(
 	}
 
 	// validate replica requirements
-	if !shard.CheckReplica(shardConfigs, expectedReplica) {
-		return nil, fmt.Errorf("selected nodes cannot cover all shards")
+	if err = shard.CheckRequirement(shardConfigs, requirement); err != nil {
+		return nil, err
 	}
 
 	// create upload tasks for each segment
@@ -826,5 +2139,6 @@ func (uploader *FileSegmentUploader) newFileSegmentUploader(
 		clients:               uploader.clients,
 		tasks:                 uploadTasks,
 		logger:                uploader.logger,
+		warnings:              uploader.warnings,
 	}, nil
 }
@@ -2,9 +2,13 @@ package transfer
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/0glabs/0g-storage-client/common/parallel"
+	"github.com/0glabs/0g-storage-client/common/shard"
 	"github.com/0glabs/0g-storage-client/core"
 	"github.com/0glabs/0g-storage-client/core/merkle"
 	"github.com/0glabs/0g-storage-client/node"
@@ -26,6 +30,136 @@ type segmentUploader struct {
 	tasks    []*uploadTask
 	taskSize uint
 	logger   *logrus.Logger
+	warnings *Warnings
+	loads    *nodeLoads
+	tracer   Tracer
+
+	checkpoint *uploadCheckpoint // acknowledged segments to skip and record, nil if not resumable
+	failures   *nodeFailures     // storage nodes failed to skip, nil to stop uploading once any failed
+	progress   *progressTracker  // segments uploaded to report, nil if not reported
+}
+
+// numSegments returns the number of segments to upload of all tasks, and those acknowledged before interruption.
+func (uploader *segmentUploader) numSegments() (total, acked uint64) {
+	numSegments := uploader.data.NumSegments()
+
+	for _, task := range uploader.tasks {
+		url := uploader.clients[task.clientIndex].URL()
+		segIndex := task.segIndex
+		for i := 0; i < int(uploader.taskSize) && segIndex < numSegments; i++ {
+			total++
+			if uploader.checkpoint.acked(url, segIndex) {
+				acked++
+			}
+			segIndex += task.numShard
+		}
+	}
+
+	return total, acked
+}
+
+// NodeError is the error of uploading segments to a storage node.
+type NodeError struct {
+	Node string // storage node URL
+	Err  error
+}
+
+// NodeErrors is returned when uploading segments failed on storage nodes, which does not stop uploading to the
+// other storage nodes, with the first error of each storage node failed in the order of failure.
+type NodeErrors []NodeError
+
+func (errs NodeErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = fmt.Sprintf("%v: %v", e.Node, e.Err)
+	}
+
+	return fmt.Sprintf("Failed to upload segments to %v storage nodes: %v", len(errs), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the error of each storage node, so that errors.Is and errors.As match any of them.
+func (errs NodeErrors) Unwrap() []error {
+	result := make([]error, len(errs))
+	for i, e := range errs {
+		result[i] = e.Err
+	}
+
+	return result
+}
+
+// nodeFailures records the storage nodes failed to upload segments, of which the remaining segments are skipped, so
+// that a dead storage node does not stall uploading to the others. It is safe for concurrent use.
+type nodeFailures struct {
+	mu      sync.Mutex
+	indexes map[int]struct{} // client index of storage nodes failed
+	errs    NodeErrors
+}
+
+// failed returns whether the storage node has failed.
+func (failures *nodeFailures) failed(clientIndex int) bool {
+	failures.mu.Lock()
+	defer failures.mu.Unlock()
+
+	_, ok := failures.indexes[clientIndex]
+
+	return ok
+}
+
+// add records the first error of storage node.
+func (failures *nodeFailures) add(clientIndex int, node string, err error) {
+	failures.mu.Lock()
+	defer failures.mu.Unlock()
+
+	if _, ok := failures.indexes[clientIndex]; ok {
+		return
+	}
+
+	if failures.indexes == nil {
+		failures.indexes = make(map[int]struct{})
+	}
+	failures.indexes[clientIndex] = struct{}{}
+	failures.errs = append(failures.errs, NodeError{node, err})
+}
+
+// remaining returns the shard configs of storage nodes not failed.
+func (failures *nodeFailures) remaining(shardConfigs []*shard.ShardConfig) []*shard.ShardConfig {
+	failures.mu.Lock()
+	defer failures.mu.Unlock()
+
+	var result []*shard.ShardConfig
+	for i, config := range shardConfigs {
+		if _, ok := failures.indexes[i]; !ok {
+			result = append(result, config)
+		}
+	}
+
+	return result
+}
+
+// errOf returns the error of storage node if failed, otherwise nil.
+func (failures *nodeFailures) errOf(node string) error {
+	failures.mu.Lock()
+	defer failures.mu.Unlock()
+
+	for _, e := range failures.errs {
+		if e.Node == node {
+			return e.Err
+		}
+	}
+
+	return nil
+}
+
+// err returns NodeErrors if any storage node failed, otherwise nil.
+func (failures *nodeFailures) err() error {
+	failures.mu.Lock()
+	defer failures.mu.Unlock()
+
+	if len(failures.errs) == 0 {
+		return nil
+	}
+
+	return append(NodeErrors(nil), failures.errs...)
 }
 
 var _ parallel.Interface = (*segmentUploader)(nil)
@@ -73,8 +207,21 @@ func (uploader *segmentUploader) ParallelDo(ctx context.Context, routine int, ta
 	uploadTask := uploader.tasks[task]
 	segIndex := uploadTask.segIndex
 	startSegIndex := segIndex
+	url := uploader.clients[uploadTask.clientIndex].URL()
+
+	// remaining segments of storage node failed are not uploaded
+	if uploader.failures != nil && uploader.failures.failed(uploadTask.clientIndex) {
+		return nil, nil
+	}
+
 	segments := make([]node.SegmentWithProof, 0)
 	for i := 0; i < int(uploader.taskSize); i++ {
+		// segments acknowledged before interruption are not uploaded again
+		if uploader.checkpoint.acked(url, segIndex) {
+			segIndex += uploadTask.numShard
+			continue
+		}
+
 		allDataUploaded, segWithProof, err := uploader.getSegment(segIndex)
 		if err != nil {
 			return nil, err
@@ -88,19 +235,36 @@ func (uploader *segmentUploader) ParallelDo(ctx context.Context, routine int, ta
 		segIndex += uploadTask.numShard
 	}
 
-	for i := 0; i < tooManyDataRetries; i++ {
-		_, err := uploader.clients[uploadTask.clientIndex].UploadSegmentsByTxSeq(ctx, segments, uploader.txSeq)
-		if err == nil || isDuplicateError(err.Error()) {
-			break
-		}
+	// nothing to upload if all segments acknowledged
+	if len(segments) == 0 {
+		return nil, nil
+	}
 
-		if isTooManyDataError(err.Error()) && i < tooManyDataRetries-1 {
-			time.Sleep(10 * time.Second)
-			continue
+	// pause briefly if storage node signals overload
+	if err := uploader.loads.wait(ctx, uploadTask.clientIndex); err != nil {
+		return nil, err
+	}
+
+	if err := uploader.uploadSegments(ctx, uploadTask, startSegIndex, segments); err != nil {
+		// continue to upload to the other storage nodes unless cancelled
+		if uploader.failures == nil || ctx.Err() != nil {
+			return nil, err
 		}
 
-		return nil, errors.WithMessage(err, "Failed to upload segment")
+		uploader.logger.WithError(err).WithField("node", url).Warn("Failed to upload segments, and storage node skipped")
+		uploader.failures.add(uploadTask.clientIndex, url, err)
+
+		return nil, nil
+	}
+
+	indexes := make([]uint64, len(segments))
+	var bytes int64
+	for i := range segments {
+		indexes[i] = segments[i].Index
+		bytes += int64(len(segments[i].Data))
 	}
+	uploader.checkpoint.ack(url, indexes...)
+	uploader.progress.add(uint64(len(segments)), bytes)
 
 	if uploader.logger.IsLevelEnabled(logrus.DebugLevel) {
 		uploader.logger.WithFields(logrus.Fields{
@@ -109,17 +273,51 @@ func (uploader *segmentUploader) ParallelDo(ctx context.Context, routine int, ta
 			"to_seg_index":   segIndex,
 			"step":           uploadTask.numShard,
 			"root":           core.SegmentRoot(segments[0].Data),
-			"to_node":        uploader.clients[uploadTask.clientIndex].URL(),
+			"to_node":        url,
 		}).Debug("Segments uploaded")
 	}
 	return nil, nil
 }
 
+// uploadSegments uploads a batch of segments to the storage node of task, and retries later if the storage node
+// is busy.
+func (uploader *segmentUploader) uploadSegments(ctx context.Context, uploadTask *uploadTask, startSegIndex uint64, segments []node.SegmentWithProof) (err error) {
+	ctx, span := startSpan(uploader.tracer, ctx, TracePhaseSegments,
+		TraceAttribute{TraceAttrNode, uploader.clients[uploadTask.clientIndex].URL()},
+		TraceAttribute{TraceAttrSegment, startSegIndex},
+		TraceAttribute{TraceAttrSegments, len(segments)},
+	)
+	defer func() { span.End(err) }()
+
+	for i := 0; i < tooManyDataRetries; i++ {
+		_, err := uploader.clients[uploadTask.clientIndex].UploadSegmentsByTxSeq(ctx, segments, uploader.txSeq)
+		if err == nil || isDuplicateError(err.Error()) {
+			return nil
+		}
+
+		if isTooManyDataError(err.Error()) && i < tooManyDataRetries-1 {
+			uploader.warnings.Add(WarningSegmentRetried, "Storage node is busy, retry to upload segments later", map[string]interface{}{
+				"node":         uploader.clients[uploadTask.clientIndex].URL(),
+				"segmentIndex": startSegIndex,
+				"attempt":      i,
+			})
+			uploader.loads.refresh(ctx, uploadTask.clientIndex)
+			time.Sleep(uploader.loads.retryAfter(uploadTask.clientIndex, 10*time.Second))
+			continue
+		}
+
+		return errors.WithMessage(err, "Failed to upload segment")
+	}
+
+	return nil
+}
+
 type fileSegmentUploader struct {
 	FileSegmentsWithProof
-	clients []*node.ZgsClient
-	tasks   [][]*uploadTask
-	logger  *logrus.Logger
+	clients  []*node.ZgsClient
+	tasks    [][]*uploadTask
+	logger   *logrus.Logger
+	warnings *Warnings
 }
 
 var _ parallel.Interface = (*fileSegmentUploader)(nil)
@@ -166,6 +364,11 @@ func (uploader *fileSegmentUploader) ParallelDo(ctx context.Context, routine int
 		}
 
 		if isTooManyDataError(err.Error()) && i < tooManyDataRetries-1 {
+			uploader.warnings.Add(WarningSegmentRetried, "Storage node is busy, retry to upload segments later", map[string]interface{}{
+				"node":    uploader.clients[clientIdx].URL(),
+				"taskId":  task,
+				"attempt": i,
+			})
 			time.Sleep(10 * time.Second)
 			continue
 		}
@@ -0,0 +1,151 @@
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/common/shard"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadNodeFailureIsolated(t *testing.T) {
+	network := testutil.NewNetwork(t, shard.ShardConfig{NumShard: 1}, shard.ShardConfig{NumShard: 1}, shard.ShardConfig{NumShard: 1})
+	clients := network.ZgsClients()
+
+	// the 1st storage node is dead, and the 2nd fails at the 3rd segment
+	network.Nodes[0].SetHooks(testutil.ZgsHooks{BeforeUpload: func(txSeq, index uint64) error {
+		return errors.New("node down")
+	}})
+	network.Nodes[1].SetHooks(testutil.ZgsHooks{BeforeUpload: func(txSeq, index uint64) error {
+		if index == 2 {
+			return errors.New("disk full")
+		}
+		return nil
+	}})
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	_, data := newTestData(t, 6*core.DefaultSegmentSize)
+	_, root, err := uploader.Upload(context.Background(), data, UploadOption{ExpectedReplica: 2, TaskSize: 1, Routines: 2})
+
+	// errors of all storage nodes failed aggregated, since the others do not meet the replica requirement
+	var nodeErrors NodeErrors
+	if assert.True(t, errors.As(err, &nodeErrors)) {
+		assert.Equal(t, 2, len(nodeErrors))
+		assert.ElementsMatch(t, []string{clients[0].URL(), clients[1].URL()}, []string{nodeErrors[0].Node, nodeErrors[1].Node})
+	}
+	assert.ErrorContains(t, err, "node down")
+	assert.ErrorContains(t, err, "disk full")
+
+	// the 3rd storage node uploaded regardless
+	info, err := network.Nodes[2].GetFileInfo(root)
+	assert.NoError(t, err)
+	assert.True(t, info.Finalized)
+}
+
+func TestUploadNodeFailureReplicaMet(t *testing.T) {
+	network := testutil.NewNetwork(t,
+		shard.ShardConfig{NumShard: 1},
+		shard.ShardConfig{ShardId: 0, NumShard: 2},
+		shard.ShardConfig{ShardId: 1, NumShard: 2},
+	)
+	clients := network.ZgsClients()
+
+	// storage node of shard 0/2 is dead
+	network.Nodes[1].SetHooks(testutil.ZgsHooks{BeforeUpload: func(txSeq, index uint64) error {
+		return errors.New("node down")
+	}})
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), clients)
+	assert.NoError(t, err)
+	defer uploader.Close()
+
+	// segments of shard 0/2 only replicated by the 1st storage node
+	_, data := newTestData(t, 4*core.DefaultSegmentSize)
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{ExpectedReplica: 2, TaskSize: 1})
+	assert.ErrorAs(t, err, &NodeErrors{})
+
+	// replica requirement met by the others
+	_, data = newTestData(t, 4*core.DefaultSegmentSize)
+	_, root, handle, err := uploader.UploadWithHandle(context.Background(), data, UploadOption{ExpectedReplica: 1, TaskSize: 1})
+	assert.NoError(t, err)
+	assert.True(t, uploader.Warnings().Has(WarningNodeFailed))
+
+	outcomes, err := handle.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []ReplicaStatus{ReplicaUploaded, ReplicaFailed, ReplicaUploaded},
+		[]ReplicaStatus{outcomes[0].Status, outcomes[1].Status, outcomes[2].Status})
+	assert.ErrorContains(t, outcomes[1].Err, "node down")
+
+	for _, i := range []int{0, 2} {
+		info, err := network.Nodes[i].GetFileInfo(root)
+		assert.NoError(t, err)
+		assert.True(t, info.Finalized)
+	}
+}
+
+func TestUploadOptionRoutines(t *testing.T) {
+	opt := UploadOption{Routines: -1}
+	assert.ErrorContains(t, opt.Validate(), "Routines")
+
+	opt.Routines = 4
+	assert.NoError(t, opt.Validate())
+}
+
+// BenchmarkUploadShards uploads segments to storage nodes of different shards, each of which ingests segments one
+// by one slowly, so that the speedup of uploading to storage nodes in parallel is measured.
+func BenchmarkUploadShards(b *testing.B) {
+	const numShard = 4
+
+	shards := make([]shard.ShardConfig, numShard)
+	for i := range shards {
+		shards[i] = shard.ShardConfig{ShardId: uint64(i), NumShard: numShard}
+	}
+	network := testutil.NewNetwork(b, shards...)
+	for _, node := range network.Nodes {
+		node.SetHooks(testutil.ZgsHooks{
+			ManualFinalize: true,
+			BeforeUpload: func(txSeq, index uint64) error {
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			},
+		})
+	}
+
+	uploader, err := NewUploader(context.Background(), network.Web3(), network.ZgsClients())
+	assert.NoError(b, err)
+	defer uploader.Close()
+
+	content := make([]byte, 4*numShard*core.DefaultSegmentSize)
+	_, err = rand.Read(content)
+	assert.NoError(b, err)
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(b, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(b, err)
+
+	// submitted once, and segments uploaded again in each iteration since never finalized
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{FinalityRequired: TransactionPacked, TaskSize: 1})
+	assert.NoError(b, err)
+	info, err := network.Nodes[0].GetFileInfo(tree.Root())
+	assert.NoError(b, err)
+
+	for _, routines := range []int{1, numShard} {
+		b.Run(fmt.Sprintf("routines=%v", routines), func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			for i := 0; i < b.N; i++ {
+				if _, err := uploader.uploadFile(context.Background(), info, data, tree, UploadOption{TaskSize: 1, Routines: routines}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
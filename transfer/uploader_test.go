@@ -0,0 +1,139 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadDirPatch(t *testing.T) {
+	service := testutil.NewZgsService()
+	client := newMockZgsNode(t, service)
+	uploader := &Uploader{clients: []*node.ZgsClient{client}, routines: 4, logger: zg_common.NewLogger(), warnings: NewWarnings(0)}
+
+	// local folder of the patched directory
+	folder := t.TempDir()
+	contentA, contentB := []byte("published file"), []byte("new file")
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "a.txt"), contentA, 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(folder, "sub"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(folder, "sub", "b.txt"), contentB, 0644))
+
+	// files of the published directory are submitted, but not necessary to upload again
+	rootA, rootB := submit(t, service, contentA), submit(t, service, contentB)
+	base := dir.NewDirFsNode("/", []*dir.FsNode{dir.NewFileFsNode("a.txt", rootA, int64(len(contentA)))})
+
+	ops := []dir.PatchOp{
+		dir.Mkdir("sub"),
+		dir.Put("sub/b.txt", dir.NewFileFsNode("", rootB, int64(len(contentB)))),
+		dir.Put("copy.txt", dir.NewFileFsNode("", rootA, int64(len(contentA)))),
+	}
+
+	expected, err := dir.Patch(base, ops)
+	assert.NoError(t, err)
+	manifest, err := dir.CanonicalBytes(expected)
+	assert.NoError(t, err)
+	manifestRoot := submit(t, service, manifest)
+
+	_, rootHash, patched, err := uploader.UploadDirPatch(context.Background(), base, folder, ops, UploadOption{SkipTx: true})
+	assert.NoError(t, err)
+	assert.Equal(t, manifestRoot, rootHash)
+	assert.True(t, expected.Equal(patched))
+
+	// only the new file and manifest uploaded
+	for root, finalized := range map[common.Hash]bool{rootA: false, rootB: true, manifestRoot: true} {
+		info, err := service.GetFileInfo(root)
+		assert.NoError(t, err)
+		assert.Equal(t, finalized, info.Finalized, root)
+	}
+
+	// directory metadata uploaded in chunks
+	uploader.WithManifestSizeLimit(1024).WithManifestChunking(true)
+
+	var entries []*dir.FsNode
+	for i := 0; i < 100; i++ {
+		entries = append(entries, dir.NewFileFsNode(fmt.Sprintf("file%03d", i), rootB, int64(len(contentB))))
+	}
+	base = dir.NewDirFsNode("/", entries)
+
+	// submits chunks not submitted yet along with the manifest index, and returns the tx seqs of them
+	submitManifest := func(tree *dir.FsNode) (chunkSeqs []uint64, indexSeq uint64) {
+		manifest, err := dir.CanonicalBytes(tree)
+		assert.NoError(t, err)
+
+		index := dir.ManifestIndex{Size: int64(len(manifest))}
+		index.Root, err = dir.ManifestRoot(tree)
+		assert.NoError(t, err)
+
+		for _, chunk := range dir.SplitManifest(manifest, 1024) {
+			data, err := core.NewDataInMemory(chunk)
+			assert.NoError(t, err)
+			root, err := core.MerkleRootData(data)
+			assert.NoError(t, err)
+
+			info, err := service.GetFileInfo(root)
+			assert.NoError(t, err)
+			if info == nil {
+				submit(t, service, chunk)
+				info, err = service.GetFileInfo(root)
+				assert.NoError(t, err)
+			}
+
+			index.Chunks = append(index.Chunks, root)
+			chunkSeqs = append(chunkSeqs, info.Tx.Seq)
+		}
+
+		content, err := index.MarshalBinary()
+		assert.NoError(t, err)
+		info, err := service.GetFileInfo(submit(t, service, content))
+		assert.NoError(t, err)
+
+		return chunkSeqs, info.Tx.Seq
+	}
+
+	// publish the original directory
+	baseSeqs, _ := submitManifest(base)
+	data, baseRoot, err := uploader.encodeManifest(base)
+	assert.NoError(t, err)
+	_, _, err = uploader.uploadManifest(context.Background(), data, baseRoot, 1024, nil, nil, UploadOption{SkipTx: true})
+	assert.NoError(t, err)
+
+	// patch a single entry
+	ops = []dir.PatchOp{dir.Delete("file050")}
+	expected, err = dir.Patch(base, ops)
+	assert.NoError(t, err)
+	chunkSeqs, indexSeq := submitManifest(expected)
+
+	var mu sync.Mutex
+	uploaded := make(map[uint64]bool)
+	service.SetHooks(testutil.ZgsHooks{BeforeUpload: func(txSeq, index uint64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		uploaded[txSeq] = true
+		return nil
+	}})
+
+	_, _, _, err = uploader.UploadDirPatch(context.Background(), base, folder, ops, UploadOption{SkipTx: true})
+	assert.NoError(t, err)
+
+	// only the changed chunks and manifest index uploaded, and the other chunks reused
+	changed := map[uint64]bool{indexSeq: true}
+	for _, seq := range chunkSeqs {
+		if !slices.Contains(baseSeqs, seq) {
+			changed[seq] = true
+		}
+	}
+	assert.Equal(t, changed, uploaded)
+	assert.Less(t, len(changed), len(chunkSeqs)/4)
+}
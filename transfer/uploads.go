@@ -0,0 +1,257 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/0glabs/0g-storage-client/contract"
+	"github.com/0glabs/0g-storage-client/transfer/dir"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/openweb3/web3go"
+	"github.com/pkg/errors"
+)
+
+// defaultUploadsBlockRange is the default number of blocks to retrieve logs in a single RPC.
+const defaultUploadsBlockRange = 1000
+
+// UploadRecord is a file submitted to the flow contract.
+type UploadRecord struct {
+	Root        common.Hash    `json:"root"`
+	TxSeq       uint64         `json:"txSeq"`
+	Size        uint64         `json:"size"`
+	Tags        hexutil.Bytes  `json:"tags"`
+	Sender      common.Address `json:"sender"`
+	BlockNumber uint64         `json:"blockNumber"`
+	BlockTime   time.Time      `json:"blockTime"`
+	TxHash      common.Hash    `json:"txHash"`
+}
+
+// SubmitLogFilter retrieves files submitted to the flow contract, which is implemented by FlowSubmitFilter.
+type SubmitLogFilter interface {
+	// LatestBlock returns the latest block number.
+	LatestBlock(ctx context.Context) (uint64, error)
+
+	// FilterSubmits returns files submitted in the specified block range (inclusive) in order, and files
+	// of all senders are returned if senders not specified.
+	FilterSubmits(ctx context.Context, fromBlock, toBlock uint64, senders ...common.Address) ([]UploadRecord, error)
+}
+
+// FlowSubmitFilter retrieves the Submit event logs of flow contract from blockchain.
+type FlowSubmitFilter struct {
+	flow    *contract.FlowFilterer
+	caller  *contract.FlowCaller
+	backend *web3go.ClientForContract
+}
+
+var _ SubmitLogFilter = (*FlowSubmitFilter)(nil)
+
+// NewFlowSubmitFilter creates a new filter of the specified flow contract, which requires no signer.
+func NewFlowSubmitFilter(w3Client *web3go.Client, flowAddress common.Address) (*FlowSubmitFilter, error) {
+	backend, _ := w3Client.ToClientForContract()
+
+	flow, err := contract.NewFlowFilterer(flowAddress, backend)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create flow contract filterer")
+	}
+
+	caller, err := contract.NewFlowCaller(flowAddress, backend)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to create flow contract caller")
+	}
+
+	return &FlowSubmitFilter{flow, caller, backend}, nil
+}
+
+// LatestBlock implements the SubmitLogFilter interface.
+func (filter *FlowSubmitFilter) LatestBlock(ctx context.Context) (uint64, error) {
+	header, err := filter.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return header.Number.Uint64(), nil
+}
+
+// FilterSubmits implements the SubmitLogFilter interface.
+func (filter *FlowSubmitFilter) FilterSubmits(ctx context.Context, fromBlock, toBlock uint64, senders ...common.Address) ([]UploadRecord, error) {
+	iter, err := filter.flow.FilterSubmit(&bind.FilterOpts{Start: fromBlock, End: &toBlock, Context: ctx}, senders, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to filter submit logs")
+	}
+	defer iter.Close()
+
+	var records []UploadRecord
+	blockTimes := make(map[uint64]time.Time)
+
+	for iter.Next() {
+		event := iter.Event
+
+		blockTime, ok := blockTimes[event.Raw.BlockNumber]
+		if !ok {
+			header, err := filter.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(event.Raw.BlockNumber))
+			if err != nil {
+				return nil, errors.WithMessagef(err, "Failed to get block %v", event.Raw.BlockNumber)
+			}
+
+			blockTime = time.Unix(int64(header.Time), 0).UTC()
+			blockTimes[event.Raw.BlockNumber] = blockTime
+		}
+
+		records = append(records, UploadRecord{
+			Root:        event.Submission.Root(),
+			TxSeq:       event.SubmissionIndex.Uint64(),
+			Size:        event.Submission.Length.Uint64(),
+			Tags:        event.Submission.Tags,
+			Sender:      event.Sender,
+			BlockNumber: event.Raw.BlockNumber,
+			BlockTime:   blockTime,
+			TxHash:      event.Raw.TxHash,
+		})
+	}
+
+	if err = iter.Error(); err != nil {
+		return nil, errors.WithMessage(err, "Failed to iterate submit logs")
+	}
+
+	return records, nil
+}
+
+// UploadQuery is the query to find submitted files.
+type UploadQuery struct {
+	Sender     *common.Address // files of any sender if not specified
+	TagPrefix  []byte          // files of any tags if empty
+	FromBlock  uint64          // inclusive
+	ToBlock    uint64          // inclusive, 0 for the latest block
+	BlockRange uint64          // number of blocks to retrieve logs in a single RPC, default 1000
+}
+
+// Match returns whether the file matches the query.
+func (query *UploadQuery) Match(record *UploadRecord) bool {
+	if query.Sender != nil && *query.Sender != record.Sender {
+		return false
+	}
+
+	if !bytes.HasPrefix(record.Tags, query.TagPrefix) {
+		return false
+	}
+
+	return record.BlockNumber >= query.FromBlock && (query.ToBlock == 0 || record.BlockNumber <= query.ToBlock)
+}
+
+func (query *UploadQuery) senders() []common.Address {
+	if query.Sender == nil {
+		return nil
+	}
+
+	return []common.Address{*query.Sender}
+}
+
+// UploadCursor is the position to resume scanning, e.g. after interruption or to scan new blocks later.
+type UploadCursor struct {
+	NextBlock uint64 `json:"nextBlock"` // the next block to scan
+}
+
+// ListUploads scans the submitted files in block range of query, and calls the handler for each file that
+// matches the query in order.
+//
+// The returned cursor is where to continue scanning even if error occurred, so that the scanning could be
+// resumed by querying again with FromBlock set to the NextBlock of cursor.
+func ListUploads(ctx context.Context, filter SubmitLogFilter, query UploadQuery, handler func(record UploadRecord) error) (UploadCursor, error) {
+	cursor := UploadCursor{NextBlock: query.FromBlock}
+
+	err := scanUploads(ctx, filter, query, func(records []UploadRecord, nextBlock uint64) error {
+		for i := range records {
+			if !query.Match(&records[i]) {
+				continue
+			}
+
+			if err := handler(records[i]); err != nil {
+				return err
+			}
+		}
+
+		cursor.NextBlock = nextBlock
+
+		return nil
+	})
+
+	return cursor, err
+}
+
+// scanUploads retrieves the submitted files of query sender in block ranges, and calls the handler for each
+// block range along with the next block to scan.
+func scanUploads(ctx context.Context, filter SubmitLogFilter, query UploadQuery, handler func(records []UploadRecord, nextBlock uint64) error) error {
+	toBlock := query.ToBlock
+	if toBlock == 0 {
+		latest, err := filter.LatestBlock(ctx)
+		if err != nil {
+			return errors.WithMessage(err, "Failed to get the latest block")
+		}
+
+		toBlock = latest
+	}
+
+	blockRange := query.BlockRange
+	if blockRange == 0 {
+		blockRange = defaultUploadsBlockRange
+	}
+
+	for from := query.FromBlock; from <= toBlock; from += blockRange {
+		to := min(from+blockRange-1, toBlock)
+
+		records, err := filter.FilterSubmits(ctx, from, to, query.senders()...)
+		if err != nil {
+			return errors.WithMessagef(err, "Failed to filter submitted files in blocks [%v, %v]", from, to)
+		}
+
+		if err = handler(records, to+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SubmissionLog adapts SubmitLogFilter to retrieve submissions of publisher to resolve directory manifests.
+type SubmissionLog struct {
+	filter SubmitLogFilter
+}
+
+var _ dir.SubmissionLog = (*SubmissionLog)(nil)
+
+// NewSubmissionLog creates a submission log with the specified filter.
+func NewSubmissionLog(filter SubmitLogFilter) *SubmissionLog {
+	return &SubmissionLog{filter}
+}
+
+// Submissions implements the dir.SubmissionLog interface.
+func (log *SubmissionLog) Submissions(ctx context.Context, publisher common.Address, fromBlock, toBlock uint64) ([]dir.Submission, error) {
+	records, err := log.filter.FilterSubmits(ctx, fromBlock, toBlock, publisher)
+	if err != nil {
+		return nil, err
+	}
+
+	submissions := make([]dir.Submission, 0, len(records))
+	for _, record := range records {
+		// filter may not support to filter by sender
+		if record.Sender != publisher {
+			continue
+		}
+
+		submissions = append(submissions, dir.Submission{
+			Root:        record.Root,
+			TxSeq:       record.TxSeq,
+			Size:        record.Size,
+			Tags:        record.Tags,
+			BlockNumber: record.BlockNumber,
+			BlockTime:   record.BlockTime,
+			TxHash:      record.TxHash,
+		})
+	}
+
+	return submissions, nil
+}
@@ -0,0 +1,180 @@
+package transfer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testSenderA = common.HexToAddress("0xa")
+	testSenderB = common.HexToAddress("0xb")
+)
+
+// fakeSubmitLogFilter submits a file in every block, by sender A in odd blocks and sender B in even blocks.
+// Besides, files in blocks divisible by 3 are tagged with 0xabcd.
+type fakeSubmitLogFilter struct {
+	latest   uint64
+	failFrom uint64 // fails to filter from the specified block, 0 to never fail
+	calls    int
+}
+
+func (filter *fakeSubmitLogFilter) LatestBlock(ctx context.Context) (uint64, error) {
+	return filter.latest, nil
+}
+
+func (filter *fakeSubmitLogFilter) FilterSubmits(ctx context.Context, fromBlock, toBlock uint64, senders ...common.Address) ([]UploadRecord, error) {
+	filter.calls++
+
+	if filter.failFrom > 0 && toBlock >= filter.failFrom {
+		return nil, errors.New("rpc failure")
+	}
+
+	var records []UploadRecord
+	for block := fromBlock; block <= toBlock; block++ {
+		record := UploadRecord{
+			Root:        common.BigToHash(big.NewInt(int64(block))),
+			TxSeq:       block,
+			Tags:        []byte{0x01},
+			Sender:      testSenderB,
+			BlockNumber: block,
+			BlockTime:   time.Unix(int64(block), 0).UTC(),
+		}
+
+		if block%2 == 1 {
+			record.Sender = testSenderA
+		}
+
+		if block%3 == 0 {
+			record.Tags = []byte{0xab, 0xcd, 0x01}
+		}
+
+		if len(senders) == 0 || senders[0] == record.Sender {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+func txSeqsOf(records []UploadRecord) []uint64 {
+	var seqs []uint64
+	for _, record := range records {
+		seqs = append(seqs, record.TxSeq)
+	}
+	return seqs
+}
+
+func TestListUploads(t *testing.T) {
+	filter := fakeSubmitLogFilter{latest: 20}
+
+	var records []UploadRecord
+	cursor, err := ListUploads(context.Background(), &filter, UploadQuery{
+		Sender:     &testSenderA,
+		TagPrefix:  []byte{0xab, 0xcd},
+		FromBlock:  2,
+		BlockRange: 5,
+	}, func(record UploadRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, UploadCursor{NextBlock: 21}, cursor)
+	assert.Equal(t, []uint64{3, 9, 15}, txSeqsOf(records))
+	assert.Equal(t, 4, filter.calls)
+}
+
+func TestListUploadsResume(t *testing.T) {
+	filter := fakeSubmitLogFilter{latest: 20, failFrom: 12}
+	query := UploadQuery{TagPrefix: []byte{0xab}, FromBlock: 1, BlockRange: 5}
+
+	var records []UploadRecord
+	handler := func(record UploadRecord) error {
+		records = append(records, record)
+		return nil
+	}
+
+	cursor, err := ListUploads(context.Background(), &filter, query, handler)
+	assert.ErrorContains(t, err, "rpc failure")
+	assert.Equal(t, UploadCursor{NextBlock: 11}, cursor)
+
+	filter.failFrom = 0
+	query.FromBlock = cursor.NextBlock
+	cursor, err = ListUploads(context.Background(), &filter, query, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, UploadCursor{NextBlock: 21}, cursor)
+	assert.Equal(t, []uint64{3, 6, 9, 12, 15, 18}, txSeqsOf(records))
+}
+
+func TestUploadIndex(t *testing.T) {
+	index := NewUploadIndex(memorydb.New())
+	defer index.Close()
+
+	filter := fakeSubmitLogFilter{latest: 10, failFrom: 8}
+	query := UploadQuery{Sender: &testSenderB, FromBlock: 1, BlockRange: 3}
+
+	// interrupted
+	cursor, err := index.Update(context.Background(), &filter, query)
+	assert.ErrorContains(t, err, "rpc failure")
+	assert.Equal(t, UploadCursor{NextBlock: 7}, cursor)
+
+	// resumed from cursor
+	filter.failFrom = 0
+	filter.calls = 0
+	cursor, err = index.Update(context.Background(), &filter, query)
+	assert.NoError(t, err)
+	assert.Equal(t, UploadCursor{NextBlock: 11}, cursor)
+	assert.Equal(t, 2, filter.calls)
+
+	records, err := index.List(query)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{2, 4, 6, 8, 10}, txSeqsOf(records))
+	assert.Equal(t, time.Unix(4, 0).UTC(), records[1].BlockTime)
+
+	// idempotent to rescan from the beginning
+	assert.NoError(t, index.db.Delete(uploadIndexCursorKey(&testSenderB)))
+	_, err = index.Update(context.Background(), &filter, query)
+	assert.NoError(t, err)
+	records, err = index.List(query)
+	assert.NoError(t, err)
+	assert.Len(t, records, 5)
+
+	// filtered by tags and blocks
+	records, err = index.List(UploadQuery{Sender: &testSenderB, TagPrefix: []byte{0xab, 0xcd}, FromBlock: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{6}, txSeqsOf(records))
+
+	// other senders not indexed yet
+	records, err = index.List(UploadQuery{Sender: &testSenderA})
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	// new blocks scanned incrementally
+	filter.latest = 14
+	filter.calls = 0
+	cursor, err = index.Update(context.Background(), &filter, query)
+	assert.NoError(t, err)
+	assert.Equal(t, UploadCursor{NextBlock: 15}, cursor)
+	assert.Equal(t, 2, filter.calls)
+}
+
+func TestSubmissionLog(t *testing.T) {
+	log := NewSubmissionLog(&fakeSubmitLogFilter{latest: 20})
+
+	submissions, err := log.Submissions(context.Background(), testSenderA, 5, 9)
+	assert.NoError(t, err)
+
+	var seqs []uint64
+	for _, submission := range submissions {
+		seqs = append(seqs, submission.TxSeq)
+		assert.Equal(t, submission.BlockNumber, submission.TxSeq)
+	}
+	assert.Equal(t, []uint64{5, 7, 9}, seqs)
+	assert.Equal(t, []byte{0xab, 0xcd, 0x01}, []byte(submissions[2].Tags))
+}
@@ -0,0 +1,51 @@
+package transfer
+
+import (
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/pkg/errors"
+)
+
+// ErrProofsUnsupported is returned when downloading with proof from storage nodes that do not serve segment proofs,
+// e.g. storage nodes of earlier versions, under the VerificationFallbackStrict policy.
+var ErrProofsUnsupported = errors.New("segment proofs not supported by storage node")
+
+// VerificationFallback is the policy to download with proof from storage nodes that do not serve segment proofs.
+type VerificationFallback string
+
+const (
+	// VerificationFallbackStrict fails to download with ErrProofsUnsupported, which is the default policy if not
+	// specified.
+	VerificationFallbackStrict VerificationFallback = "strict"
+
+	// VerificationFallbackWholeFile downloads segments without proof from such storage nodes, and verifies the merkle
+	// root of the whole file before the downloaded file released.
+	VerificationFallbackWholeFile VerificationFallback = "whole-file"
+)
+
+// Validate checks the policy, and returns an error if unsupported.
+func (fallback VerificationFallback) Validate() error {
+	switch fallback {
+	case "", VerificationFallbackStrict, VerificationFallbackWholeFile:
+		return nil
+	default:
+		return zg_common.NewOptionError("VerificationFallback", "unsupported policy %q", fallback)
+	}
+}
+
+// VerificationMode is the verification actually performed on the downloaded file.
+type VerificationMode string
+
+const (
+	// VerificationProof indicates that all segments were verified by merkle proofs once downloaded, along with the
+	// merkle root of the whole file.
+	VerificationProof VerificationMode = "proof"
+
+	// VerificationWholeFile indicates that only the merkle root of the whole file was verified, either downloaded
+	// without proof, or fell back from storage nodes that do not serve segment proofs.
+	VerificationWholeFile VerificationMode = "whole-file"
+)
+
+// DownloadResult is the result of downloading file.
+type DownloadResult struct {
+	Verification VerificationMode `json:"verification"`
+}
@@ -0,0 +1,70 @@
+package transfer
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/common/testutil"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationFallback(t *testing.T) {
+	service := testutil.NewZgsService()
+	client := newMockZgsNode(t, service)
+
+	content := make([]byte, 2*core.DefaultSegmentSize+100)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+	root := submit(t, service, content)
+
+	uploader := &Uploader{clients: []*node.ZgsClient{client}, routines: 2, logger: zg_common.NewLogger(), warnings: NewWarnings(0)}
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	_, _, err = uploader.Upload(context.Background(), data, UploadOption{SkipTx: true, ExpectedReplica: 1})
+	assert.NoError(t, err)
+
+	// storage node of earlier version that does not serve segment proofs
+	service.SetHooks(testutil.ZgsHooks{NoProofs: true})
+
+	downloader, err := NewDownloader([]*node.ZgsClient{client})
+	assert.NoError(t, err)
+	dir := t.TempDir()
+
+	// fails by default
+	filename := filepath.Join(dir, "strict")
+	_, err = downloader.DownloadWithResult(context.Background(), root.Hex(), filename, true)
+	assert.ErrorIs(t, err, ErrProofsUnsupported)
+	assert.NoFileExists(t, filename)
+
+	// falls back to verify the whole file
+	downloader.WithVerificationFallback(VerificationFallbackWholeFile)
+	filename = filepath.Join(dir, "fallback")
+	result, err := downloader.DownloadWithResult(context.Background(), root.Hex(), filename, true)
+	assert.NoError(t, err)
+	assert.Equal(t, VerificationWholeFile, result.Verification)
+	assert.Equal(t, 1, downloader.Warnings().Count(WarningProofsUnsupported))
+
+	downloaded, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+
+	// verified by proofs once supported
+	service.SetHooks(testutil.ZgsHooks{})
+	filename = filepath.Join(dir, "proof")
+	result, err = downloader.DownloadWithResult(context.Background(), root.Hex(), filename, true)
+	assert.NoError(t, err)
+	assert.Equal(t, VerificationProof, result.Verification)
+
+	result, err = downloader.DownloadWithResult(context.Background(), root.Hex(), filepath.Join(dir, "no-proof"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, VerificationWholeFile, result.Verification)
+
+	_, err = downloader.WithVerificationFallback("lenient").DownloadWithResult(context.Background(), root.Hex(), filepath.Join(dir, "invalid"), true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,190 @@
+package transfer
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// ErrChainMismatch is returned when file info reported by storage node disagrees with the submission on chain.
+var ErrChainMismatch = zg_common.WithErrorClass(errors.New("file info mismatch with on-chain submission"), zg_common.ErrorClassVerification)
+
+// SubmissionQuerier retrieves file submissions from the flow contract, which is implemented by FlowSubmitFilter.
+type SubmissionQuerier interface {
+	// QuerySubmission returns the file submitted with the specified tx seq, or nil if not submitted yet.
+	QuerySubmission(ctx context.Context, txSeq uint64) (*UploadRecord, error)
+}
+
+var _ SubmissionQuerier = (*FlowSubmitFilter)(nil)
+
+// submissionScanBlocks is the max number of latest blocks to filter submit logs, if history state is not available
+// to search the block of submission.
+var submissionScanBlocks uint64 = 1_000_000
+
+// QuerySubmission implements the SubmissionQuerier interface. It binary searches the block in which the file
+// submitted by the number of submissions at history blocks, which requires an archive fullnode. If history state
+// is not available, e.g. pruned on a full node, submit logs of the latest blocks are filtered backward instead,
+// so that files submitted long ago could only be queried on an archive fullnode.
+func (filter *FlowSubmitFilter) QuerySubmission(ctx context.Context, txSeq uint64) (*UploadRecord, error) {
+	latest, err := filter.LatestBlock(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Failed to get the latest block")
+	}
+
+	block, ok, err := searchSubmissionBlock(latest, txSeq, func(block uint64) (uint64, error) {
+		return filter.numSubmissionsAt(ctx, block)
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err == nil && !ok {
+		return nil, nil
+	}
+
+	if err != nil {
+		// history state not available
+		return scanSubmission(latest, txSeq, submissionScanBlocks, defaultUploadsBlockRange, func(fromBlock, toBlock uint64) ([]UploadRecord, error) {
+			return filter.FilterSubmits(ctx, fromBlock, toBlock)
+		})
+	}
+
+	records, err := filter.FilterSubmits(ctx, block, block)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		if records[i].TxSeq == txSeq {
+			return &records[i], nil
+		}
+	}
+
+	return nil, errors.Errorf("Submit log of tx seq %v not found in block %v", txSeq, block)
+}
+
+// numSubmissionsAt returns the number of submissions at the specified block, which is 0 if the flow contract not
+// deployed yet.
+func (filter *FlowSubmitFilter) numSubmissionsAt(ctx context.Context, block uint64) (uint64, error) {
+	num, err := filter.caller.NumSubmissions(&bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(block)})
+	if errors.Is(err, bind.ErrNoCode) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, errors.WithMessagef(err, "Failed to get number of submissions at block %v", block)
+	}
+
+	return num.Uint64(), nil
+}
+
+// scanSubmission filters submit logs backward from the latest block in ranges, until the file of specified tx seq
+// found or max blocks scanned.
+func scanSubmission(latest, txSeq, maxBlocks, blockRange uint64, filterSubmits func(fromBlock, toBlock uint64) ([]UploadRecord, error)) (*UploadRecord, error) {
+	for to, scanned := latest, uint64(0); scanned < maxBlocks; {
+		from := to - min(to, blockRange-1, maxBlocks-scanned-1)
+
+		records, err := filterSubmits(from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range records {
+			if records[i].TxSeq == txSeq {
+				return &records[i], nil
+			}
+		}
+
+		// submitted in earlier blocks than any submission of range
+		if len(records) > 0 && records[0].TxSeq < txSeq {
+			return nil, errors.Errorf("Submit log of tx seq %v not found", txSeq)
+		}
+
+		if from == 0 {
+			return nil, errors.Errorf("Submit log of tx seq %v not found", txSeq)
+		}
+
+		scanned += to - from + 1
+		to = from - 1
+	}
+
+	return nil, errors.Errorf("Submit log of tx seq %v not found in the latest %v blocks, and an archive fullnode is required to query earlier submissions", txSeq, maxBlocks)
+}
+
+// searchSubmissionBlock returns the first block in [0, latest] that the number of submissions exceeds the
+// specified tx seq, or false if not submitted yet.
+func searchSubmissionBlock(latest, txSeq uint64, numSubmissionsAt func(block uint64) (uint64, error)) (uint64, bool, error) {
+	num, err := numSubmissionsAt(latest)
+	if err != nil || num <= txSeq {
+		return 0, false, err
+	}
+
+	var searchErr error
+	block := sort.Search(int(latest), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+
+		num, searchErr = numSubmissionsAt(uint64(i))
+
+		return searchErr == nil && num > txSeq
+	})
+	if searchErr != nil {
+		return 0, false, searchErr
+	}
+
+	return uint64(block), true, nil
+}
+
+// chainVerifier cross-checks file info reported by storage nodes with the submission on chain.
+type chainVerifier struct {
+	querier     SubmissionQuerier
+	submissions map[uint64]*UploadRecord // cached by tx seq
+}
+
+func newChainVerifier(querier SubmissionQuerier) *chainVerifier {
+	return &chainVerifier{
+		querier:     querier,
+		submissions: make(map[uint64]*UploadRecord),
+	}
+}
+
+// verify checks the root and size of file reported by the specified storage node, and returns ErrChainMismatch
+// along with the disagreement if any.
+func (verifier *chainVerifier) verify(ctx context.Context, nodeURL string, root common.Hash, info *node.FileInfo) error {
+	if info.Tx.DataMerkleRoot != root {
+		return errors.WithMessagef(ErrChainMismatch, "storage node %v reports root %v, but %v requested", nodeURL, info.Tx.DataMerkleRoot, root)
+	}
+
+	submission, ok := verifier.submissions[info.Tx.Seq]
+	if !ok {
+		var err error
+		if submission, err = verifier.querier.QuerySubmission(ctx, info.Tx.Seq); err != nil {
+			return errors.WithMessagef(err, "Failed to query submission of tx seq %v on chain", info.Tx.Seq)
+		}
+
+		verifier.submissions[info.Tx.Seq] = submission
+	}
+
+	if submission == nil {
+		return errors.WithMessagef(ErrChainMismatch, "storage node %v reports tx seq %v, which is not submitted on chain", nodeURL, info.Tx.Seq)
+	}
+
+	if submission.Root != root {
+		return errors.WithMessagef(ErrChainMismatch, "storage node %v reports tx seq %v for root %v, but root %v submitted on chain",
+			nodeURL, info.Tx.Seq, root, submission.Root)
+	}
+
+	if submission.Size != info.Tx.Size {
+		return errors.WithMessagef(ErrChainMismatch, "storage node %v reports size %v of tx seq %v, but size %v submitted on chain",
+			nodeURL, info.Tx.Size, info.Tx.Seq, submission.Size)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+
+	zg_common "github.com/0glabs/0g-storage-client/common"
+	"github.com/0glabs/0g-storage-client/core"
+	"github.com/0glabs/0g-storage-client/node"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSubmissionQuerier struct {
+	submissions map[uint64]*UploadRecord
+	queries     int
+}
+
+func (querier *fakeSubmissionQuerier) QuerySubmission(ctx context.Context, txSeq uint64) (*UploadRecord, error) {
+	querier.queries++
+
+	if txSeq == 99 {
+		return nil, errors.New("rpc failure")
+	}
+
+	return querier.submissions[txSeq], nil
+}
+
+func TestChainVerifier(t *testing.T) {
+	root := common.HexToHash("0x1234")
+	querier := fakeSubmissionQuerier{submissions: map[uint64]*UploadRecord{
+		5: {Root: root, TxSeq: 5, Size: 100},
+		6: {Root: common.HexToHash("0x5678"), TxSeq: 6, Size: 100},
+	}}
+	verifier := newChainVerifier(&querier)
+
+	fileInfo := func(seq, size uint64) *node.FileInfo {
+		return &node.FileInfo{Tx: node.Transaction{DataMerkleRoot: root, Seq: seq, Size: size}}
+	}
+
+	// agreed, and submission queried only once
+	assert.NoError(t, verifier.verify(context.Background(), "node-0", root, fileInfo(5, 100)))
+	assert.NoError(t, verifier.verify(context.Background(), "node-1", root, fileInfo(5, 100)))
+	assert.Equal(t, 1, querier.queries)
+
+	// disagreed
+	for _, tc := range []struct {
+		info     *node.FileInfo
+		expected string
+	}{
+		{fileInfo(5, 120), "storage node node-2 reports size 120 of tx seq 5, but size 100 submitted on chain"},
+		{fileInfo(6, 100), "storage node node-2 reports tx seq 6 for root " + root.Hex() + ", but root " + querier.submissions[6].Root.Hex() + " submitted on chain"},
+		{fileInfo(7, 100), "storage node node-2 reports tx seq 7, which is not submitted on chain"},
+		{&node.FileInfo{}, "storage node node-2 reports root " + common.Hash{}.Hex() + ", but " + root.Hex() + " requested"},
+	} {
+		err := verifier.verify(context.Background(), "node-2", root, tc.info)
+		assert.ErrorIs(t, err, ErrChainMismatch)
+		assert.ErrorContains(t, err, tc.expected)
+		assert.Equal(t, zg_common.ErrorClassVerification, zg_common.ClassOf(err))
+	}
+
+	// failed to query chain
+	err := verifier.verify(context.Background(), "node-2", root, fileInfo(99, 100))
+	assert.NotErrorIs(t, err, ErrChainMismatch)
+	assert.ErrorContains(t, err, "rpc failure")
+}
+
+func TestSearchSubmissionBlock(t *testing.T) {
+	// one submission every 10 blocks from block 5, i.e. tx seq 0 in block 5, 1 in block 15 and so on
+	var calls int
+	numSubmissionsAt := func(block uint64) (uint64, error) {
+		calls++
+		if block < 5 {
+			return 0, nil
+		}
+		return (block-5)/10 + 1, nil
+	}
+
+	for txSeq, expected := range map[uint64]uint64{0: 5, 1: 15, 42: 425, 99: 995} {
+		block, ok, err := searchSubmissionBlock(1000, txSeq, numSubmissionsAt)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, expected, block)
+	}
+	assert.Less(t, calls, 4*12)
+
+	// not submitted yet
+	_, ok, err := searchSubmissionBlock(1000, 100, numSubmissionsAt)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestScanSubmission(t *testing.T) {
+	// one submission every 10 blocks from block 5, i.e. tx seq 0 in block 5, 1 in block 15 and so on
+	var ranges [][2]uint64
+	filterSubmits := func(fromBlock, toBlock uint64) ([]UploadRecord, error) {
+		ranges = append(ranges, [2]uint64{fromBlock, toBlock})
+
+		var records []UploadRecord
+		for block := fromBlock; block <= toBlock; block++ {
+			if block >= 5 && (block-5)%10 == 0 {
+				records = append(records, UploadRecord{TxSeq: (block - 5) / 10, BlockNumber: block})
+			}
+		}
+		return records, nil
+	}
+
+	// scanned backward in ranges
+	record, err := scanSubmission(1000, 95, 1000, 30, filterSubmits)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(955), record.BlockNumber)
+	assert.Equal(t, [][2]uint64{{971, 1000}, {941, 970}}, ranges)
+
+	record, err = scanSubmission(1000, 0, 1001, 300, filterSubmits)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), record.BlockNumber)
+
+	// submitted earlier than the max blocks to scan
+	ranges = nil
+	_, err = scanSubmission(1000, 3, 100, 30, filterSubmits)
+	assert.ErrorContains(t, err, "archive fullnode is required")
+	assert.Equal(t, [][2]uint64{{971, 1000}, {941, 970}, {911, 940}, {901, 910}}, ranges)
+}
+
+func TestValidateSegmentErrorClass(t *testing.T) {
+	content := make([]byte, core.DefaultSegmentSize*2)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	data, err := core.NewDataInMemory(content)
+	assert.NoError(t, err)
+	tree, err := core.MerkleTree(data)
+	assert.NoError(t, err)
+
+	segment := func(index int, modify func([]byte)) *node.SegmentWithProof {
+		seg := append([]byte{}, content[index*core.DefaultSegmentSize:(index+1)*core.DefaultSegmentSize]...)
+		modify(seg)
+		return &node.SegmentWithProof{Data: seg, Index: uint64(index), Proof: tree.ProofAt(index)}
+	}
+
+	assert.NoError(t, validateSegment(tree.Root(), data.Size(), 1, segment(1, func([]byte) {})))
+
+	// tampered data
+	err = validateSegment(tree.Root(), data.Size(), 1, segment(1, func(seg []byte) { seg[0]++ }))
+	assert.ErrorContains(t, err, "Failed to validate proof")
+	assert.Equal(t, zg_common.ErrorClassVerification, zg_common.ClassOf(err))
+
+	// truncated data
+	seg := segment(0, func([]byte) {})
+	seg.Data = seg.Data[:core.DefaultChunkSize]
+	err = validateSegment(tree.Root(), data.Size(), 0, seg)
+	assert.ErrorContains(t, err, "Downloaded data length mismatch")
+	assert.Equal(t, zg_common.ErrorClassVerification, zg_common.ClassOf(err))
+}
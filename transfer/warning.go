@@ -0,0 +1,197 @@
+package transfer
+
+import (
+	"fmt"
+	"sync"
+)
+
+// defaultMaxWarnings is the default maximum number of warnings retained by Warnings.
+const defaultMaxWarnings = 100
+
+// WarningCode is a stable identifier of a non-fatal issue that happened during transfers.
+type WarningCode string
+
+const (
+	// WarningSegmentRetried indicates that storage node was too busy to accept segments,
+	// and the segments upload was retried later.
+	WarningSegmentRetried WarningCode = "SEGMENT_RETRIED"
+
+	// WarningSubmitRetried indicates that the flow submission transaction failed with retriable
+	// error, and was sent again.
+	WarningSubmitRetried WarningCode = "SUBMIT_RETRIED"
+
+	// WarningSegmentRerouted indicates that a segment could not be downloaded from a storage node,
+	// and was rerouted to another storage node.
+	WarningSegmentRerouted WarningCode = "SEGMENT_REROUTED"
+
+	// WarningProofsUnsupported indicates that a storage node did not serve segment proofs when downloading with
+	// proof, and segments were downloaded without proof under the VerificationFallbackWholeFile policy.
+	WarningProofsUnsupported WarningCode = "PROOFS_UNSUPPORTED"
+
+	// WarningNameReplaced indicates that a file name was not valid UTF-8 when uploading directory, and the invalid
+	// bytes were replaced with U+FFFD in directory metadata.
+	WarningNameReplaced WarningCode = "NAME_REPLACED"
+
+	// WarningSenderRemoved indicates that an account of SenderPool failed to send transaction due to insufficient
+	// funds, and was removed from rotation.
+	WarningSenderRemoved WarningCode = "SENDER_REMOVED"
+
+	// WarningSymlinkSkipped indicates that a symbolic link escaped the root directory or formed a cycle when
+	// uploading directory, and was skipped under the dir.SymlinkSkip policy.
+	WarningSymlinkSkipped WarningCode = "SYMLINK_SKIPPED"
+
+	// WarningSpecialFileSkipped indicates that a special file, e.g. named pipe, socket or device node, was skipped
+	// when uploading directory under the dir.SpecialFilesSkip policy.
+	WarningSpecialFileSkipped WarningCode = "SPECIAL_FILE_SKIPPED"
+
+	// WarningNameNotPortable indicates that a file name was not portable across common download targets when
+	// uploading directory, e.g. collided case-insensitively, and was kept under the dir.PortableNamesWarn policy.
+	WarningNameNotPortable WarningCode = "NAME_NOT_PORTABLE"
+
+	// WarningLedgerFailed indicates that the record of upload could not be written to the local ledger, e.g.
+	// ledger corrupted or locked by another process for long, which does not fail the upload.
+	WarningLedgerFailed WarningCode = "LEDGER_FAILED"
+
+	// WarningRetentionMismatch indicates that a storage node reported a retention class other than the retention
+	// policy expects once file finalized, under the RetentionCheckWarn policy.
+	WarningRetentionMismatch WarningCode = "RETENTION_MISMATCH"
+
+	// WarningManifestRejected indicates that a storage node failed to serve the directory manifest, or served a
+	// manifest that mismatched the root, and the manifest was fetched from another storage node if any.
+	WarningManifestRejected WarningCode = "MANIFEST_REJECTED"
+
+	// WarningNodeFailed indicates that segments could not be uploaded to some storage nodes, but the upload
+	// succeeded since the replica requirement was met by the other storage nodes.
+	WarningNodeFailed WarningCode = "NODE_FAILED"
+)
+
+// Warning is a non-fatal issue that happened during transfers.
+type Warning struct {
+	Code    WarningCode            `json:"code"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (w Warning) String() string {
+	if len(w.Fields) == 0 {
+		return fmt.Sprintf("[%v] %v", w.Code, w.Message)
+	}
+
+	return fmt.Sprintf("[%v] %v %v", w.Code, w.Message, w.Fields)
+}
+
+// WarningSink is the callback to receive warnings as soon as they happen.
+type WarningSink func(Warning)
+
+// Warnings aggregates warnings that happened during transfers. At most limited number of warnings
+// are retained, and the number of discarded warnings is tracked as overflow.
+//
+// It is safe to use Warnings concurrently.
+type Warnings struct {
+	mu       sync.Mutex
+	sink     WarningSink
+	items    []Warning
+	counts   map[WarningCode]int
+	limit    int
+	overflow int
+}
+
+// NewWarnings creates a new Warnings that retains at most limit warnings. If limit is not positive,
+// the default value 100 is used.
+func NewWarnings(limit int) *Warnings {
+	if limit <= 0 {
+		limit = defaultMaxWarnings
+	}
+
+	return &Warnings{
+		counts: make(map[WarningCode]int),
+		limit:  limit,
+	}
+}
+
+// SetSink sets the callback to receive warnings as soon as they happen.
+func (w *Warnings) SetSink(sink WarningSink) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sink = sink
+}
+
+// Add reports a new warning. Note, all methods are no-op on nil Warnings.
+func (w *Warnings) Add(code WarningCode, message string, fields map[string]interface{}) {
+	if w == nil {
+		return
+	}
+
+	warning := Warning{Code: code, Message: message, Fields: fields}
+
+	w.mu.Lock()
+	w.counts[code]++
+	if len(w.items) < w.limit {
+		w.items = append(w.items, warning)
+	} else {
+		w.overflow++
+	}
+	sink := w.sink
+	w.mu.Unlock()
+
+	if sink != nil {
+		sink(warning)
+	}
+}
+
+// List returns a copy of retained warnings.
+func (w *Warnings) List() []Warning {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Warning(nil), w.items...)
+}
+
+// Overflow returns the number of warnings that are not retained due to limit.
+func (w *Warnings) Overflow() int {
+	if w == nil {
+		return 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.overflow
+}
+
+// Count returns the number of reported warnings of the specified code, including the overflowed ones.
+func (w *Warnings) Count(code WarningCode) int {
+	if w == nil {
+		return 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.counts[code]
+}
+
+// Reset discards all warnings reported, so that warnings of the next operation are aggregated separately.
+func (w *Warnings) Reset() {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.items = nil
+	w.counts = make(map[WarningCode]int)
+	w.overflow = 0
+}
+
+// Has returns whether any warning of the specified codes has been reported.
+func (w *Warnings) Has(codes ...WarningCode) bool {
+	for _, code := range codes {
+		if w.Count(code) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
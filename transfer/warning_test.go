@@ -0,0 +1,57 @@
+package transfer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarnings(t *testing.T) {
+	warnings := NewWarnings(2)
+
+	var received []Warning
+	warnings.SetSink(func(w Warning) {
+		received = append(received, w)
+	})
+
+	codes := []WarningCode{WarningSegmentRetried, WarningSubmitRetried, WarningSegmentRerouted}
+	for _, code := range codes {
+		warnings.Add(code, "test", map[string]interface{}{"code": code})
+	}
+
+	// all warnings delivered to sink
+	assert.Len(t, received, 3)
+	for i, code := range codes {
+		assert.Equal(t, code, received[i].Code)
+	}
+
+	// bounded warnings retained
+	assert.Len(t, warnings.List(), 2)
+	assert.Equal(t, 1, warnings.Overflow())
+
+	// overflowed warnings are still counted
+	for _, code := range codes {
+		assert.True(t, warnings.Has(code))
+		assert.Equal(t, 1, warnings.Count(code))
+	}
+	assert.False(t, warnings.Has("UNKNOWN"))
+
+	// warnings of the next operation aggregated separately
+	warnings.Reset()
+	assert.Empty(t, warnings.List())
+	assert.Equal(t, 0, warnings.Overflow())
+	assert.False(t, warnings.Has(codes...))
+	warnings.Add(codes[0], "test", nil)
+	assert.Equal(t, 1, warnings.Count(codes[0]))
+}
+
+func TestNilWarnings(t *testing.T) {
+	var warnings *Warnings
+
+	warnings.Add(WarningSegmentRetried, "test", nil)
+
+	assert.Nil(t, warnings.List())
+	assert.Equal(t, 0, warnings.Overflow())
+	assert.False(t, warnings.Has(WarningSegmentRetried))
+	warnings.Reset()
+}